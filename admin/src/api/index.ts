@@ -27,7 +27,8 @@ apiClient.interceptors.response.use(
     return response.data
   },
   (error) => {
-    if (error.response?.status === 401) {
+    // 登录接口的 401 表示认证失败或需要第二因素，由登录页处理
+    if (error.response?.status === 401 && !error.config?.url?.startsWith('/auth/login')) {
       // Token 过期或无效，清除 token 并跳转到登录页
       localStorage.removeItem('admin_token')
       window.location.href = '/admin/login'
@@ -40,6 +41,8 @@ export interface LoginRequest {
   email: string
   password: string
   totp_code?: string
+  webauthn_ceremony?: string
+  webauthn_response?: unknown
 }
 
 export interface LoginResponse {
@@ -85,6 +88,11 @@ export const api = {
     return apiClient.post('/auth/login', data)
   },
 
+  // 开始通行密钥登录（返回 ceremony_id 和传给浏览器的 options）
+  beginWebAuthnLogin: (data: { email: string; password: string }): Promise<{ ceremony_id: string; options: any }> => {
+    return apiClient.post('/auth/login/webauthn', data)
+  },
+
   // 初始化
   checkInit: (): Promise<{ needs_init: boolean }> => {
     return apiClient.get('/init/check')
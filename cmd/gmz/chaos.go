@@ -0,0 +1,34 @@
+//go:build chaos
+
+package main
+
+import (
+	"github.com/gomailzero/gmz/internal/chaos"
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// enableChaos 启用故障注入：包装存储驱动和外发 SMTP 连接的拨号器
+func enableChaos(cfg *config.ChaosConfig, driver storage.Driver) storage.Driver {
+	if !cfg.Enabled {
+		return driver
+	}
+	injector := chaos.New(chaos.Config{
+		Latency:          cfg.Latency,
+		ErrorRate:        cfg.ErrorRate,
+		PartialWriteRate: cfg.PartialWriteRate,
+		Seed:             cfg.Seed,
+	})
+	smtpclient.SetDialerWrapper(func(d smtpclient.ContextDialer) smtpclient.ContextDialer {
+		return chaos.WrapDialer(d, injector)
+	})
+	log.Warn().
+		Dur("latency", cfg.Latency).
+		Float64("error_rate", cfg.ErrorRate).
+		Float64("partial_write_rate", cfg.PartialWriteRate).
+		Uint64("seed", cfg.Seed).
+		Msg("故障注入已启用，不要在生产环境使用")
+	return chaos.WrapDriver(driver, injector)
+}
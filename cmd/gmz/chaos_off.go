@@ -0,0 +1,17 @@
+//go:build !chaos
+
+package main
+
+import (
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/rs/zerolog/log"
+)
+
+// enableChaos 正常构建不注入故障
+func enableChaos(cfg *config.ChaosConfig, driver storage.Driver) storage.Driver {
+	if cfg.Enabled {
+		log.Warn().Msg("配置了 chaos.enabled，但当前二进制未包含故障注入（使用 make build-chaos 构建），已忽略")
+	}
+	return driver
+}
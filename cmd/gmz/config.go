@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// handleConfigCommand 处理 config 子命令
+func handleConfigCommand(args []string, configPath string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: gmz config print [--redacted] | gmz config validate")
+	}
+
+	switch args[0] {
+	case "print":
+		fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+		redacted := fs.Bool("redacted", false, "隐藏密码、密钥等敏感字段")
+		path := fs.String("c", configPath, "配置文件路径")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return printConfig(*path, *redacted)
+	case "validate":
+		fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+		path := fs.String("c", configPath, "配置文件路径")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return validateConfig(*path)
+	default:
+		return fmt.Errorf("未知的 config 子命令: %s", args[0])
+	}
+}
+
+// printConfig 输出合并默认值、配置文件和环境变量后的生效配置
+func printConfig(configPath string, redacted bool) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if redacted {
+		cfg = cfg.Redacted()
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("输出配置失败: %w", err)
+	}
+	return encoder.Close()
+}
+
+// validateConfig 校验配置文件（未知键、取值范围、字段间依赖），供 CI/CD 部署前检查
+func validateConfig(configPath string) error {
+	if _, err := os.Stat(configPath); err != nil {
+		return fmt.Errorf("配置文件不可用: %w", err)
+	}
+	if _, err := config.Load(configPath); err != nil {
+		return fmt.Errorf("配置无效:\n%w", err)
+	}
+	fmt.Printf("配置有效: %s\n", configPath)
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const maildirUsage = "用法: gmz maildir reshard（先停止服务）"
+
+// handleMaildirCommand 处理 maildir 子命令：按 storage.maildir_sharding 移动已有的用户目录
+func handleMaildirCommand(args []string, configPath string) error {
+	if len(args) == 0 || args[0] != "reshard" {
+		return fmt.Errorf("%s", maildirUsage)
+	}
+
+	fs := flag.NewFlagSet("maildir "+args[0], flag.ContinueOnError)
+	path := fs.String("c", configPath, "配置文件路径")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+
+	maildir, err := storage.NewMaildir(cfg.Storage.MaildirRoot)
+	if err != nil {
+		return fmt.Errorf("初始化 Maildir 失败: %w", err)
+	}
+	maildir.SetSharding(cfg.Storage.MaildirSharding)
+
+	// 热备复制：记录目录移动，备节点随之移动
+	if cfg.Replication.Enabled {
+		if cfg.Storage.Driver != "sqlite" {
+			return fmt.Errorf("不支持的存储驱动: %s", cfg.Storage.Driver)
+		}
+		driver, err := storage.NewSQLiteDriver(cfg.Storage.DSN)
+		if err != nil {
+			return fmt.Errorf("初始化存储失败: %w", err)
+		}
+		defer driver.Close()
+		maildir.SetJournal(driver)
+	}
+
+	moved, err := maildir.Reshard()
+	fmt.Printf("已移动 %d 个用户目录（分片层数 %d）\n", moved, cfg.Storage.MaildirSharding)
+	return err
+}
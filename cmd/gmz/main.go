@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"flag"
 	"fmt"
@@ -11,19 +12,46 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/gomailzero/gmz/internal/acme"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/alias"
 	"github.com/gomailzero/gmz/internal/antispam"
 	"github.com/gomailzero/gmz/internal/api"
 	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/autoreply"
+	"github.com/gomailzero/gmz/internal/batv"
+	"github.com/gomailzero/gmz/internal/bounce"
 	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/contactform"
+	"github.com/gomailzero/gmz/internal/deliverylog"
+	"github.com/gomailzero/gmz/internal/digest"
+	"github.com/gomailzero/gmz/internal/dnscheck"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/fetchmail"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/identity"
 	"github.com/gomailzero/gmz/internal/imapd"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
 	"github.com/gomailzero/gmz/internal/metrics"
 	"github.com/gomailzero/gmz/internal/migrate"
+	"github.com/gomailzero/gmz/internal/newsletter"
+	"github.com/gomailzero/gmz/internal/provision"
+	"github.com/gomailzero/gmz/internal/quota"
+	"github.com/gomailzero/gmz/internal/replication"
+	"github.com/gomailzero/gmz/internal/saslauth"
 	"github.com/gomailzero/gmz/internal/smtpclient"
 	"github.com/gomailzero/gmz/internal/smtpd"
 	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/storagerpc"
+	"github.com/gomailzero/gmz/internal/terms"
+	"github.com/gomailzero/gmz/internal/testmail"
 	tlsconfig "github.com/gomailzero/gmz/internal/tls"
+	"github.com/gomailzero/gmz/internal/warmup"
 	"github.com/gomailzero/gmz/internal/web"
+	"github.com/gomailzero/gmz/internal/webpush"
+	"github.com/gomailzero/gmz/internal/zeroaccess"
 	"github.com/rs/zerolog/log"
 )
 
@@ -55,6 +83,15 @@ func main() {
 		os.Exit(0)
 	}
 
+	// 处理子命令
+	if args := flag.Args(); len(args) > 0 {
+		if err := handleSubcommand(args, *configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// 加载配置
 	cfg, err := config.Load(*configPath)
 	if err != nil {
@@ -73,10 +110,14 @@ func main() {
 		Str("build_time", BuildTime).
 		Msg("GoMailZero 启动")
 
-	// 创建上下文
+	// 创建上下文（取消时后台任务退出）
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// 停止时依次停止的协议前端和后台任务
+	var frontends []service
+	var background backgroundTasks
+
 	// 初始化存储
 	var storageDriver storage.Driver
 	if cfg.Storage.Driver == "sqlite" {
@@ -109,28 +150,461 @@ func main() {
 			}
 			log.Info().Msg("数据库初始化完成")
 		}
+	} else if cfg.Storage.Driver == "remote" {
+		// 协议前端：通过存储节点的 gRPC 存储服务访问数据库（迁移在存储节点上执行）
+		rpcTLS, err := grpcClientTLS(cfg.Storage.RPC.TLS, cfg.Storage.RPC.CAFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("加载存储服务 TLS 配置失败")
+		}
+		storageDriver, err = storagerpc.Dial(cfg.Storage.RPC.Address, cfg.Storage.RPC.Token, rpcTLS)
+		if err != nil {
+			log.Fatal().Err(err).Msg("初始化存储失败")
+		}
+		defer storageDriver.Close()
+		log.Info().Str("address", cfg.Storage.RPC.Address).Msg("使用远程存储节点")
 	} else {
 		log.Fatal().Str("driver", cfg.Storage.Driver).Msg("不支持的存储驱动")
 	}
 
+	// 应用初始化数据（声明式确保域名、用户、别名存在）
+	if cfg.Provisioning.Path != "" {
+		spec, err := provision.Load(cfg.Provisioning.Path)
+		if err != nil {
+			log.Fatal().Err(err).Str("path", cfg.Provisioning.Path).Msg("加载初始化数据失败")
+		}
+		if _, err := provision.Apply(ctx, storageDriver, spec); err != nil {
+			log.Fatal().Err(err).Str("path", cfg.Provisioning.Path).Msg("应用初始化数据失败")
+		}
+	}
+
+	// 每日用量汇总（用于 WebMail 用量趋势）
+	background.Go(func() { runUsageRollup(ctx, storageDriver) })
+	// 每日邮件统计汇总（用于 WebMail 统计页）
+	background.Go(func() { runStatsRollup(ctx, storageDriver) })
+	// 清理过期或作废的临时别名
+	background.Go(func() { runAliasCleanup(ctx, storageDriver) })
+
 	// 初始化 Maildir
 	maildir, err := storage.NewMaildir(cfg.Storage.MaildirRoot)
 	if err != nil {
 		log.Fatal().Err(err).Msg("初始化 Maildir 失败")
 	}
+	maildir.SetSharding(cfg.Storage.MaildirSharding)
+
+	// Maildir 热备复制：记录文件变更日志（需要在处理未完成投递的邮件之前设置）
+	if cfg.Replication.Enabled {
+		maildir.SetJournal(storageDriver)
+	}
+
+	// 零访问存储：按用户设置加密 Maildir 中的邮件文件（需要在处理未完成投递的邮件之前设置）
+	var keyring *zeroaccess.Keyring
+	if cfg.Storage.ZeroAccess.Enabled {
+		keyring = zeroaccess.New(storageDriver, cfg.Storage.ZeroAccess.UnlockTTL)
+		maildir.SetCipher(keyring)
+		log.Info().Dur("unlock_ttl", cfg.Storage.ZeroAccess.UnlockTTL).Msg("零访问存储已启用")
+	}
+
+	// 把旧版本以嵌套子目录保存的多级文件夹迁移为 Maildir++ 格式（需要在处理未完成投递的邮件之前完成）
+	if migrated, err := maildir.MigrateFolderLayout(); err != nil {
+		log.Warn().Err(err).Msg("迁移 Maildir 文件夹目录失败")
+	} else if migrated > 0 {
+		log.Info().Int("folders", migrated).Msg("已迁移 Maildir 文件夹目录")
+	}
+
+	// 处理上次异常退出时未完成投递的邮件（需要在开始接收邮件之前完成）
+	if recovered, removed, err := storage.NewMailStore(maildir, storageDriver).Recover(ctx); err != nil {
+		log.Warn().Err(err).Msg("恢复未完成投递的邮件失败")
+	} else if recovered > 0 || removed > 0 {
+		log.Info().Int("recovered", recovered).Int("removed", removed).Msg("已处理未完成投递的邮件")
+	}
+
+	// 故障注入测试：包装存储驱动和外发连接的拨号器（仅在 -tags chaos 构建时生效）
+	storageDriver = enableChaos(&cfg.Chaos, storageDriver)
+
+	// 创建指标导出器（TLS 握手等指标需要在服务启动前注册）
+	var exporter *metrics.Exporter
+	var tlsObserver tlsconfig.ConnectionObserver
+	var limitsObserver limits.Observer
+	var timeoutObserver drain.Observer
+	if cfg.Metrics.Enabled {
+		exporter = metrics.NewExporter()
+		tlsObserver = exporter
+		limitsObserver = exporter
+		timeoutObserver = exporter
+	}
 
 	// 加载 TLS 配置
-	var tlsConfig *tls.Config
+	var tlsConfig, mailTLSConfig, httpTLSConfig *tls.Config
 	if cfg.TLS.Enabled {
-		tlsConfig, err = tlsconfig.LoadTLSConfig(&cfg.TLS)
+		// 证书存储按 SNI 主机名选择证书，SMTP/IMAP/HTTP 共享
+		certStore, err := tlsconfig.NewCertStore(&cfg.TLS)
 		if err != nil {
-			log.Warn().Err(err).Msg("加载 TLS 配置失败，继续运行")
+			log.Warn().Err(err).Msg("加载 TLS 证书失败，继续运行")
+		} else {
+			// 没有任何证书来源时，生成自签名证书而不是退回明文
+			if cfg.TLS.SelfSigned.Enabled && certStore.Empty() {
+				cert, err := tlsconfig.LoadOrCreateSelfSigned(cfg.TLS.SelfSigned.Dir, []string{cfg.Domain, cfg.SMTP.Hostname})
+				if err != nil {
+					log.Fatal().Err(err).Msg("生成自签名证书失败")
+				}
+				certStore.SetDefault(cert)
+			}
+			// 证书文件更新后自动重新加载，所有监听无需重启
+			if cfg.TLS.WatchFiles {
+				if err := certStore.Watch(ctx); err != nil {
+					log.Warn().Err(err).Msg("监听证书文件失败，证书更新后需要重启")
+				}
+			}
+			tlsConfig, err = tlsconfig.LoadTLSConfig(&cfg.TLS, certStore)
+			if err != nil {
+				log.Warn().Err(err).Msg("加载 TLS 配置失败，继续运行")
+			}
+			if cfg.TLS.ACME.Enabled {
+				startACME(ctx, cfg, storageDriver, certStore)
+			}
+		}
+		// SMTP/IMAP 与 HTTP 使用独立的协议版本和加密套件策略
+		mailTLSConfig, err = tlsconfig.MailTLSConfig(tlsConfig, &cfg.TLS)
+		if err != nil {
+			log.Fatal().Err(err).Msg("加载邮件 TLS 策略失败")
+		}
+		httpTLSConfig, err = tlsconfig.HTTPTLSConfig(tlsConfig, &cfg.TLS)
+		if err != nil {
+			log.Fatal().Err(err).Msg("加载 HTTP TLS 策略失败")
+		}
+	}
+
+	// 启动 Maildir 复制服务（主节点）
+	if cfg.Replication.Enabled {
+		var replicationTLS *tls.Config
+		if cfg.Replication.TLS {
+			if tlsConfig == nil {
+				log.Fatal().Msg("Maildir 复制服务需要 TLS 证书（或设置 replication.tls: false）")
+			}
+			replicationTLS = tlsConfig
+		}
+		primary := &replication.Primary{
+			Storage: storageDriver,
+			Maildir: maildir,
+			Token:   cfg.Replication.Token,
+		}
+		background.Go(func() {
+			if err := primary.Serve(ctx, cfg.Replication.Listen, replicationTLS); err != nil {
+				log.Error().Err(err).Msg("Maildir 复制服务启动失败")
+			}
+		})
+		background.Go(func() { runJournalCleanup(ctx, storageDriver, cfg.Replication.Retention) })
+	}
+
+	// 启动存储服务（存储节点，协议前端使用 storage.driver: remote 连接）
+	if cfg.Storage.RPC.Enabled {
+		var rpcTLS *tls.Config
+		if cfg.Storage.RPC.TLS {
+			if tlsConfig == nil {
+				log.Fatal().Msg("存储服务需要 TLS 证书（或设置 storage.rpc.tls: false）")
+			}
+			rpcTLS = tlsConfig
 		}
+		storageServer := &storagerpc.Server{Storage: storageDriver, Token: cfg.Storage.RPC.Token}
+		background.Go(func() {
+			if err := storageServer.Serve(ctx, cfg.Storage.RPC.Listen, rpcTLS); err != nil {
+				log.Error().Err(err).Msg("存储服务启动失败")
+			}
+		})
+	}
+
+	// 外发连接使用的 TLS 配置模板（共享会话缓存）
+	clientTLSConfig, err := tlsconfig.ClientTLSConfig(&cfg.TLS)
+	if err != nil {
+		log.Fatal().Err(err).Msg("加载外发 TLS 策略失败")
 	}
+	clientTLSConfig = tlsconfig.WithObserver(clientTLSConfig, "smtp-client", tlsObserver)
 
 	// 创建认证器
+	// 连接减速（对多次认证失败或被拒收的 IP）
+	var tarpit *antispam.Tarpit
+	if cfg.AntiSpam.Tarpit.Enabled {
+		tarpit = antispam.NewTarpit(
+			cfg.AntiSpam.Tarpit.Threshold,
+			cfg.AntiSpam.Tarpit.Window,
+			cfg.AntiSpam.Tarpit.Delay,
+			cfg.AntiSpam.Tarpit.MaxDelay,
+		)
+		background.Go(func() { tarpit.Cleanup(ctx) })
+	}
+
+	// 按 IP 的连接数限制和认证失败封禁（SMTP、IMAP、WebMail 和管理 API 登录共享）
+	var limiter *limits.Limiter
+	if cfg.Limits.Enabled {
+		limiter, err = limits.New(limits.Config{
+			MaxConnections:  cfg.Limits.MaxConnectionsPerIP,
+			MaxAuthFailures: cfg.Limits.MaxAuthFailures,
+			Window:          cfg.Limits.Window,
+			BanDuration:     cfg.Limits.BanDuration,
+			Exempt:          cfg.Limits.Exempt,
+			Observer:        limitsObserver,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Msg("创建连接限制失败")
+		}
+		background.Go(func() { limiter.Cleanup(ctx) })
+	}
+
 	smtpAuth := smtpd.NewDefaultAuthenticator(storageDriver)
 
+	// 外部转发（SRS 密钥未配置时随机生成，重启后之前转发邮件的退信无法还原）
+	srsSecret := []byte(cfg.SMTP.SRSSecret)
+	if len(srsSecret) == 0 {
+		srsSecret = make([]byte, 32)
+		if _, err := rand.Read(srsSecret); err != nil {
+			log.Fatal().Err(err).Msg("生成 SRS 密钥失败")
+		}
+		log.Warn().Msg("未配置 smtp.srs_secret，使用随机 SRS 密钥")
+	}
+	// 投递审计日志（接收、投递到文件夹、外发、退信和推迟，供管理 API 追踪邮件去向）
+	deliveries := deliverylog.New(storageDriver)
+
+	forwarder := &forward.Forwarder{
+		Domain:     cfg.Domain,
+		Storage:    storageDriver,
+		SMTP:       &cfg.SMTP,
+		ClientTLS:  clientTLSConfig,
+		SRS:        forward.NewSRS(srsSecret, cfg.Domain),
+		Deliveries: deliveries,
+	}
+
+	// 垃圾邮件内容指纹（投递时提高相似内容的分数，用户移入垃圾邮件文件夹的邮件记为投诉）
+	var fingerprint *antispam.FingerprintRule
+	var spamReporter imapd.SpamReporter
+	if cfg.AntiSpam.Fingerprint.Enabled {
+		fingerprint = antispam.NewFingerprintRule(storageDriver, cfg.AntiSpam.Fingerprint.Threshold, cfg.AntiSpam.Fingerprint.Window)
+		spamReporter = fingerprint
+	}
+
+	// 垃圾邮件贝叶斯分类器（用户移入、移出垃圾邮件文件夹或在 WebMail 中标记时在后台训练）
+	var bayes *antispam.Bayes
+	var spamTrainer imapd.SpamTrainer
+	if cfg.AntiSpam.Bayes.Enabled {
+		bayes = antispam.NewBayes(storageDriver, cfg.AntiSpam.Bayes.MinTraining)
+		spamTrainer = bayes
+		background.Go(func() { bayes.Run(ctx) })
+	}
+
+	// 投递前的反垃圾检查（SMTP 收到的外部来信），没有配置检查规则时不检查
+	var spamEngine *antispam.Engine
+	if cfg.AntiSpam.Enabled {
+		var rules []antispam.Rule
+		if cfg.AntiSpam.RspamdURL != "" {
+			rules = append(rules, antispam.NewRspamdRule(cfg.AntiSpam.RspamdURL, cfg.AntiSpam.RspamdTimeout))
+		}
+		if fingerprint != nil {
+			rules = append(rules, fingerprint)
+		}
+		if bayes != nil {
+			rules = append(rules, bayes)
+		}
+		if len(rules) > 0 {
+			spamEngine = antispam.NewEngine(nil, nil, nil, nil, nil)
+			for _, rule := range rules {
+				spamEngine.AddRule(rule)
+			}
+			spamEngine.SetEventRecorder(deliveries)
+		}
+	}
+
+	// 退信记录（外发时被拒收的收件人和收到的退信报告，用于退信统计）
+	bounces := &bounce.Recorder{Storage: storageDriver}
+
+	// 退信地址验证（外发时签名信封发件人，发往未签名地址的退信在 RCPT TO 拒收）
+	batvSigner, err := newBATVSigner(&cfg.SMTP.BATV)
+	if err != nil {
+		log.Fatal().Err(err).Msg("BATV 配置无效")
+	}
+	var senderSigner smtpclient.SenderSigner
+	if batvSigner != nil {
+		senderSigner = batvSigner
+	}
+
+	// 加载 DKIM（如果配置了，启用中继时按中继的签名设置）
+	dkim, err := smtpclient.LoadOutboundDKIM(&cfg.SMTP, cfg.Domain, cfg.WorkDir)
+	if err != nil {
+		log.Warn().Err(err).Msg("加载 DKIM 失败，将发送未签名的邮件")
+	}
+	// 通过管理 API 为各域名生成的 DKIM 密钥，按发件域名签名
+	dkimSigners, err := smtpclient.LoadOutboundSigners(ctx, &cfg.SMTP, dkim, cfg.WorkDir, storageDriver)
+	if err != nil {
+		log.Warn().Err(err).Msg("加载域名 DKIM 密钥失败，只使用配置文件中的 DKIM 签名")
+		dkimSigners = antispam.NewDKIMSigners(dkim)
+	}
+
+	// 外发路径（提交端口、服务端退订、自动回复）
+	outbound := &smtpclient.Outbound{SMTP: &cfg.SMTP, ClientTLS: clientTLSConfig, Bounces: bounces, Deliveries: deliveries, Signer: senderSigner, DKIM: dkimSigners}
+
+	// 外发会话记录（发往指定域名或指定邮件的完整 SMTP 会话，收件方否认收到时举证）
+	var transcripts smtpclient.TranscriptRecorder
+	if cfg.SMTP.Transcripts.Enabled {
+		transcripts = deliverylog.NewTranscripts(storageDriver, cfg.SMTP.Transcripts.Domains, cfg.SMTP.Transcripts.MessageIDs)
+		outbound.Transcripts = transcripts
+		retention := time.Duration(cfg.SMTP.Transcripts.RetentionDays) * 24 * time.Hour
+		background.Go(func() { runTranscriptCleanup(ctx, storageDriver, retention) })
+	}
+
+	// 账户活动记录（登录历史和从账户发出的邮件，用户自行发现账户被盗用）
+	var accountActivity *activity.Recorder
+	if cfg.Activity.Enabled {
+		accountActivity = activity.New(storageDriver)
+		retention := time.Duration(cfg.Activity.RetentionDays) * 24 * time.Hour
+		background.Go(func() { runActivityCleanup(ctx, storageDriver, retention) })
+	}
+
+	// 登录提示和使用条款（WebMail 和管理后台登录共用）
+	loginTerms := terms.New(storageDriver, cfg.Banner.Notice, cfg.Banner.Terms, cfg.Banner.TermsVersion)
+
+	// 外发预热（预热期间每个目标服务商超过每日上限的收件人推迟到第二天发送）
+	var warmupScheduler *warmup.Scheduler
+	var warmupThrottle smtpclient.Throttle
+	if cfg.Warmup.Enabled {
+		start, err := warmup.ParseStart(cfg.Warmup.StartDate)
+		if err != nil {
+			log.Fatal().Err(err).Msg("外发预热配置无效")
+		}
+		// 推迟邮件到期后直接发出，不再经过限流
+		release := &smtpclient.Outbound{SMTP: &cfg.SMTP, ClientTLS: clientTLSConfig, Bounces: bounces, Deliveries: deliveries, Signer: senderSigner, Transcripts: transcripts, DKIM: dkimSigners}
+		warmupScheduler = warmup.New(storageDriver, release, start,
+			cfg.Warmup.Weeks, cfg.Warmup.InitialDailyLimit, cfg.Warmup.MaxDailyLimit, cfg.Warmup.Providers)
+		warmupThrottle = warmupScheduler
+		outbound.Warmup = warmupThrottle
+		forwarder.Warmup = warmupThrottle
+		background.Go(func() { warmupScheduler.Run(ctx, cfg.Warmup.CheckInterval) })
+	}
+
+	// Maildir 监视（索引外部投递代理写入的邮件，推送新邮件通知）
+	var newMail *maildirwatch.Watcher
+	if cfg.Storage.WatchMaildir {
+		newMail = maildirwatch.New(maildir, storageDriver)
+		background.Go(func() {
+			if err := newMail.Run(ctx); err != nil {
+				log.Error().Err(err).Msg("监视 Maildir 失败")
+			}
+		})
+	}
+
+	// WebPush 新邮件通知（浏览器和 PWA 订阅，收件箱收到新邮件时推送）
+	var pushNotifier *webpush.Notifier
+	if cfg.WebPush.Enabled && newMail != nil {
+		vapidKey, err := webpush.LoadOrCreateKey(cfg.WebPush.KeyFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("加载 VAPID 私钥失败")
+		}
+		pushNotifier = &webpush.Notifier{
+			Storage: storageDriver,
+			Key:     vapidKey,
+			Subject: cfg.WebPush.Subject,
+			Privacy: cfg.WebPush.Privacy,
+			TTL:     cfg.WebPush.TTL,
+		}
+		background.Go(func() { pushNotifier.Run(ctx, newMail) })
+	}
+
+	// 公开联系表单（网站表单通过人机验证后投递到配置的邮箱）
+	var contactForms *contactform.Service
+	if cfg.ContactForm.Enabled {
+		forms := make([]contactform.Form, 0, len(cfg.ContactForm.Forms))
+		for _, f := range cfg.ContactForm.Forms {
+			forms = append(forms, contactform.Form{
+				Domain:         f.Domain,
+				To:             f.To,
+				AllowedOrigins: f.AllowedOrigins,
+				Subject:        f.Subject,
+				RedirectURL:    f.RedirectURL,
+			})
+		}
+		verifier := contactform.NewSiteVerifier(cfg.ContactForm.Captcha, cfg.ContactForm.SecretKey, cfg.ContactForm.VerifyURL)
+		contactForms = contactform.New(storageDriver, maildir, verifier, forms,
+			cfg.ContactForm.IPLimit, cfg.ContactForm.DomainLimit, cfg.ContactForm.MaxMessageSize)
+		background.Go(func() { contactForms.Cleanup(ctx) })
+	}
+
+	// 外部邮箱拉取（定期从用户添加的 POP3/IMAP 账户拉取邮件）
+	var fetcher *fetchmail.Fetcher
+	if cfg.Fetchmail.Enabled {
+		fetcher = &fetchmail.Fetcher{
+			Storage:           storageDriver,
+			Maildir:           maildir,
+			Passphrase:        cfg.Fetchmail.EncryptionKey,
+			Hostname:          cfg.SMTP.Hostname,
+			Interval:          cfg.Fetchmail.Interval,
+			MaxMessages:       cfg.Fetchmail.MaxMessages,
+			TLSConfig:         clientTLSConfig,
+			AllowPrivateHosts: cfg.Fetchmail.AllowPrivateHosts,
+		}
+		background.Go(func() { fetcher.Run(ctx) })
+	}
+
+	// 配额预警（用量超过阈值时投递预警邮件，并导出超过阈值的用户数）
+	quotaWarner := &quota.Warner{
+		Storage:    storageDriver,
+		Maildir:    maildir,
+		Thresholds: cfg.Quota.WarningThresholds,
+	}
+	if exporter != nil {
+		quotaWarner.Observer = exporter
+	}
+	background.Go(func() { quotaWarner.Run(ctx, cfg.Quota.CheckInterval) })
+
+	// 邮件摘要（按用户设置每天或每周投递未读邮件和垃圾邮件的汇总）
+	if cfg.Digest.Enabled {
+		digestSender := &digest.Sender{
+			Storage: storageDriver,
+			Maildir: maildir,
+			Hour:    cfg.Digest.Hour,
+		}
+		if cfg.Digest.Template != "" {
+			tmpl, err := digest.LoadTemplate(cfg.Digest.Template)
+			if err != nil {
+				log.Fatal().Err(err).Msg("加载邮件摘要模板失败")
+			}
+			digestSender.Template = tmpl
+		}
+		background.Go(func() { digestSender.Run(ctx, cfg.Digest.CheckInterval) })
+	}
+
+	// 外部发件身份（以用户的外部地址发信时通过该地址的 SMTP 服务器提交）
+	var identities *identity.Manager
+	if cfg.SMTP.Identities.Enabled {
+		identities = &identity.Manager{
+			Storage:           storageDriver,
+			Passphrase:        cfg.SMTP.Identities.EncryptionKey,
+			Hostname:          cfg.SMTP.Hostname,
+			ClientTLS:         clientTLSConfig,
+			AllowPrivateHosts: cfg.SMTP.Identities.AllowPrivateHosts,
+		}
+	}
+
+	// SMTP 和 IMAP 在 PLAIN 之外的认证机制（令牌认证不接受默认 JWT 密钥签发的令牌）
+	saslConfig := saslauth.Config{
+		Storage:     storageDriver,
+		Hostname:    cfg.SMTP.Hostname,
+		CRAMMD5:     cfg.SASL.CRAMMD5,
+		OAuthBearer: cfg.SASL.OAuthBearer,
+		XOAuth2:     cfg.SASL.XOAuth2,
+	}
+	if cfg.Admin.JWTSecret != "" {
+		saslConfig.JWT = auth.NewJWTManager(cfg.Admin.JWTSecret, "gomailzero").UseSessions(storageDriver)
+	}
+	if cfg.SASL.OAuth.JWKSURL != "" {
+		saslConfig.OAuth = &auth.OAuthVerifier{
+			JWKSURL:       cfg.SASL.OAuth.JWKSURL,
+			Issuer:        cfg.SASL.OAuth.Issuer,
+			Audience:      cfg.SASL.OAuth.Audience,
+			UsernameClaim: cfg.SASL.OAuth.UsernameClaim,
+		}
+	}
+	saslMechanisms := saslauth.New(saslConfig)
+
+	// 协议服务器的连接管理，供管理 API 查看和断开在线连接
+	var connections []api.ConnectionManager
+
 	// 启动 SMTP 服务器
 	if cfg.SMTP.Enabled {
 		smtpServer := smtpd.NewServer(&smtpd.Config{
@@ -138,10 +612,33 @@ func main() {
 			Ports:    cfg.SMTP.Ports,
 			Hostname: cfg.SMTP.Hostname,
 			MaxSize:  parseSize(cfg.SMTP.MaxSize),
-			TLS:      tlsConfig,
+			TLS:      tlsconfig.WithObserver(mailTLSConfig, "smtp", tlsObserver),
 			Storage:  storageDriver,
 			Maildir:  maildir,
 			Auth:     smtpAuth,
+
+			AllowInsecureAuthLocalhost: cfg.SMTP.Auth.AllowInsecureLocalhost,
+			RequireTLSPorts:            cfg.SMTP.RequireTLSPorts,
+			Tarpit:                     tarpit,
+			Limiter:                    limiter,
+			Banner:                     cfg.SMTP.Banner,
+			VRFY:                       cfg.SMTP.VRFY,
+			Listeners:                  smtpListeners(&cfg.SMTP),
+			Forwarder:                  forwarder,
+			BATV:                       batvSigner,
+			SubmissionPorts:            cfg.SMTP.SubmissionPorts,
+			Outbound:                   outbound,
+			Identities:                 identities,
+			AutoResponder:              &autoreply.Responder{Storage: storageDriver, Outbound: outbound, Hostname: cfg.SMTP.Hostname},
+			SASL:                       saslMechanisms,
+			Bounces:                    bounces,
+			AntiSpam:                   spamEngine,
+			DeliveryLog:                deliveries,
+			CommandTimeout:             cfg.SMTP.Timeouts.Command,
+			DataTimeout:                cfg.SMTP.Timeouts.Data,
+			IdleTimeout:                cfg.SMTP.Timeouts.Idle,
+			TimeoutObserver:            timeoutObserver,
+			Activity:                   accountActivity,
 		})
 
 		go func() {
@@ -149,29 +646,96 @@ func main() {
 				log.Error().Err(err).Msg("SMTP 服务器启动失败")
 			}
 		}()
+		frontends = append(frontends, service{"smtp", smtpServer.Stop})
+		connections = append(connections, smtpServer)
+	}
+
+	// 启动 LMTP 服务器（外部 MTA 通过 LMTP 投递到本地邮箱，不需要启用 SMTP）
+	if cfg.LMTP.Enabled {
+		lmtpSize := sectionSize(cfg.LMTP.MaxSize)
+		if lmtpSize == 0 {
+			lmtpSize = parseSize(cfg.SMTP.MaxSize)
+		}
+		lmtpServer := smtpd.NewLMTPServer(&smtpd.LMTPConfig{
+			Socket:        cfg.LMTP.Socket,
+			Address:       cfg.LMTP.Address,
+			Hostname:      cfg.SMTP.Hostname,
+			MaxSize:       lmtpSize,
+			Storage:       storageDriver,
+			Maildir:       maildir,
+			Forwarder:     forwarder,
+			BATV:          batvSigner,
+			AutoResponder: &autoreply.Responder{Storage: storageDriver, Outbound: outbound, Hostname: cfg.SMTP.Hostname},
+			Bounces:       bounces,
+			DeliveryLog:   deliveries,
+		})
+		if err := lmtpServer.Start(ctx); err != nil {
+			log.Fatal().Err(err).Msg("LMTP 服务器启动失败")
+		}
+		frontends = append(frontends, service{"lmtp", lmtpServer.Stop})
+		connections = append(connections, lmtpServer)
 	}
 
 	// 启动 IMAP 服务器
 	if cfg.IMAP.Enabled {
 		// IMAP 服务器需要 TLS 配置（如果 TLS 已启用但加载失败，记录警告）
-		if cfg.TLS.Enabled && tlsConfig == nil {
+		if cfg.TLS.Enabled && mailTLSConfig == nil {
 			log.Warn().Msg("TLS 已启用但配置加载失败，IMAP 服务器将允许非安全连接（仅用于开发环境）")
 		}
 		
-		imapServer := imapd.NewServer(&imapd.Config{
+		imapConfig := &imapd.Config{
 			Enabled: cfg.IMAP.Enabled,
 			Port:    cfg.IMAP.Port,
-			TLS:     tlsConfig,
+			TLS:     tlsconfig.WithObserver(mailTLSConfig, "imap", tlsObserver),
 			Storage: storageDriver,
 			Maildir: maildir, // 传递 Maildir 实例以支持读取邮件体
 			Auth:    imapd.NewDefaultAuthenticator(storageDriver),
-		})
+
+			MaxAuthErrors: cfg.IMAP.MaxAuthErrors,
+			Limiter:       limiter,
+			SASL:          saslMechanisms,
+			SpamReporter:  spamReporter,
+			SpamTrainer:   spamTrainer,
+			NewMail:       newMail,
+
+			IdleTimeout:     cfg.IMAP.Timeouts.Idle,
+			LoginTimeout:    cfg.IMAP.Timeouts.Login,
+			TimeoutObserver: timeoutObserver,
+			Activity:        accountActivity,
+
+			SubmissionFolder: cfg.IMAP.SubmissionFolder,
+			Greeting:         cfg.IMAP.Greeting,
+			Forwarder:        forwarder,
+		}
+		if keyring != nil {
+			imapConfig.Keyring = keyring
+		}
+		imapServer := imapd.NewServer(imapConfig)
 
 		go func() {
 			if err := imapServer.Start(ctx); err != nil {
 				log.Error().Err(err).Msg("IMAP 服务器启动失败")
 			}
 		}()
+		frontends = append(frontends, service{"imap", imapServer.Stop})
+		connections = append(connections, imapServer)
+	}
+
+	// 通行密钥（WebMail 和管理后台登录共用，在 WebMail 中注册）
+	var webAuthn *auth.WebAuthnManager
+	if cfg.WebAuthn.Enabled {
+		rpID := cfg.WebAuthn.RPID
+		if rpID == "" {
+			rpID = cfg.Domain
+		}
+		webAuthn, err = auth.NewWebAuthnManager(auth.WebAuthnConfig{
+			RPID:          rpID,
+			RPOrigins:     cfg.WebAuthn.RPOrigins,
+			RPDisplayName: cfg.WebAuthn.RPDisplayName,
+		}, storageDriver)
+		if err != nil {
+			log.Fatal().Err(err).Msg("初始化 WebAuthn 失败")
+		}
 	}
 
 	// 启动管理 API
@@ -181,7 +745,7 @@ func main() {
 		if jwtSecret == "" {
 			jwtSecret = "change-me-in-production" // 默认密钥（生产环境必须更改）
 		}
-		jwtManager := auth.NewJWTManager(jwtSecret, "gomailzero")
+		jwtManager := auth.NewJWTManager(jwtSecret, "gomailzero").UseSessions(storageDriver)
 
 		// 创建 TOTP 管理器
 		totpManager := auth.NewTOTPManager(storageDriver)
@@ -193,6 +757,26 @@ func main() {
 			Storage:     storageDriver,
 			JWTManager:  jwtManager,
 			TOTPManager: totpManager,
+			WebAuthn:    webAuthn,
+			TLS:         tlsconfig.WithObserver(httpTLSConfig, "admin", tlsObserver),
+			Limiter:     limiter,
+			Maildir:     maildir,
+			Connections: connections,
+			Warmup:      warmupScheduler,
+			Activity:    accountActivity,
+			WorkDir:     cfg.WorkDir,
+			DKIM:        dkimSigners,
+			Terms:       loginTerms,
+			DNSCheck:    &dnscheck.Checker{Hostname: cfg.SMTP.Hostname},
+			TestMail: &testmail.Sender{
+				Domain:    cfg.Domain,
+				Storage:   storageDriver,
+				Maildir:   maildir,
+				SMTP:      &cfg.SMTP,
+				DKIM:      dkimSigners,
+				ClientTLS: clientTLSConfig,
+				Signer:    senderSigner,
+			},
 		})
 
 		go func() {
@@ -200,15 +784,16 @@ func main() {
 				log.Error().Err(err).Msg("管理 API 启动失败")
 			}
 		}()
+		frontends = append(frontends, service{"api", apiServer.Stop})
 	}
 
-	// 启动指标服务器
+	// 启动指标服务器（最后停止，停止过程中仍可观测）
+	var metricsServer *http.Server
 	if cfg.Metrics.Enabled {
-		exporter := metrics.NewExporter()
 		mux := http.NewServeMux()
 		mux.Handle(cfg.Metrics.Path, exporter.Handler())
 
-		metricsServer := &http.Server{
+		metricsServer = &http.Server{
 			Addr:              fmt.Sprintf(":%d", cfg.Metrics.Port),
 			Handler:           mux,
 			ReadHeaderTimeout: 5 * time.Second, // 防止 Slowloris 攻击
@@ -233,17 +818,6 @@ func main() {
 		// 创建 TOTP 管理器
 		totpManager := auth.NewTOTPManager(storageDriver)
 
-		// 加载 DKIM（如果配置了）
-		var dkim *antispam.DKIM
-		if cfg.SMTP.DKIM.Enabled {
-			dkimInstance, err := smtpclient.LoadDKIM(&cfg.SMTP.DKIM, cfg.Domain, cfg.WorkDir)
-			if err != nil {
-				log.Warn().Err(err).Msg("加载 DKIM 失败，将发送未签名的邮件")
-			} else {
-				dkim = dkimInstance
-			}
-		}
-
 		webServer := web.NewServer(&web.Config{
 			Path:        cfg.WebMail.Path,
 			Port:        cfg.WebMail.Port,
@@ -253,9 +827,32 @@ func main() {
 			JWTSecret:   jwtSecret,
 			JWTIssuer:   cfg.Domain,
 			TOTPManager: totpManager,
+			WebAuthn:    webAuthn,
 			AdminPort:   cfg.Admin.Port, // 管理 API 端口，用于代理管理界面
 			SMTPConfig:  &cfg.SMTP,      // SMTP 配置，用于外发邮件
-			DKIM:        dkim,           // DKIM 签名器
+			DKIM:        dkimSigners,    // DKIM 签名器（按发件域名选择）
+			TLS:         tlsconfig.WithObserver(httpTLSConfig, "webmail", tlsObserver),
+			ClientTLS:   clientTLSConfig,
+			Forwarder:   forwarder,
+
+			Unsubscriber: &newsletter.Unsubscriber{Sender: outbound},
+			Fetcher:      fetcher,
+			Identities:   identities,
+			Limiter:      limiter,
+			QuotaWarner:  quotaWarner,
+			Warmup:       warmupThrottle,
+			BATV:         senderSigner,
+			NewMail:      newMail,
+			Bayes:        bayes,
+			ZeroAccess:   keyring,
+			WebPush:      pushNotifier,
+			ContactForms: contactForms,
+			JMAP:         cfg.WebMail.JMAP,
+			Activity:     accountActivity,
+			Terms:        loginTerms,
+
+			AccessTokenTTL: cfg.WebMail.AccessTokenTTL,
+			SessionTTL:     cfg.WebMail.SessionTTL,
 		})
 
 		go func() {
@@ -263,6 +860,7 @@ func main() {
 				log.Error().Err(err).Msg("WebMail 服务器启动失败")
 			}
 		}()
+		frontends = append(frontends, service{"webmail", webServer.Stop})
 	}
 
 	log.Info().Msg("所有服务已启动")
@@ -278,9 +876,297 @@ func main() {
 		log.Info().Msg("上下文取消")
 	}
 
+	// 优雅停止（存储在 main 返回时关闭）
+	go func() {
+		<-sigChan
+		log.Warn().Msg("再次收到退出信号，立即退出")
+		os.Exit(1)
+	}()
+	log.Info().Dur("timeout", cfg.ShutdownTimeout).Msg("开始优雅停止")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+
+	// 1. 协议前端：停止接受新连接，等待进行中的 SMTP 事务、IMAP 命令和 HTTP 请求完成
+	stopServices(shutdownCtx, frontends)
+
+	// 2. 后台任务：等待正在进行的外部邮箱拉取、复制和汇总任务结束
+	cancel()
+	if !background.Wait(shutdownCtx) {
+		log.Warn().Msg("等待后台任务结束超时")
+	}
+
+	// 3. 指标服务器
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			log.Warn().Err(err).Msg("关闭指标服务器失败")
+		}
+	}
+
 	log.Info().Msg("GoMailZero 关闭")
 }
 
+// handleSubcommand 分发子命令
+func handleSubcommand(args []string, configPath string) error {
+	switch args[0] {
+	case "config":
+		return handleConfigCommand(args[1:], configPath)
+	case "sendtest":
+		return handleSendTestCommand(args[1:], configPath)
+	case "replication":
+		return handleReplicationCommand(args[1:], configPath)
+	case "users":
+		return handleUsersCommand(args[1:], configPath)
+	case "maildir":
+		return handleMaildirCommand(args[1:], configPath)
+	default:
+		return fmt.Errorf("未知命令: %s", args[0])
+	}
+}
+
+// newBATVSigner 按配置创建 BATV 签名器，未启用时返回 nil
+func newBATVSigner(batvCfg *config.BATVConfig) (*batv.Signer, error) {
+	if !batvCfg.Enabled {
+		return nil, nil
+	}
+	keys := make([]batv.Key, 0, len(batvCfg.Keys))
+	for _, key := range batvCfg.Keys {
+		keys = append(keys, batv.Key{ID: key.ID, Secret: []byte(key.Secret)})
+	}
+	return batv.NewSigner(keys, batvCfg.Domains)
+}
+
+// smtpListeners 将接收、提交端口的设置和按端口的监听配置转换为 smtpd 配置
+func smtpListeners(smtpCfg *config.SMTPConfig) map[int]smtpd.ListenerConfig {
+	result := make(map[int]smtpd.ListenerConfig)
+	if smtpCfg.Sectioned() {
+		inbound := smtpCfg.Inbound
+		for _, port := range inbound.Ports {
+			result[port] = smtpd.ListenerConfig{
+				MaxSize:          sectionSize(inbound.MaxSize),
+				DisableTLS:       !inbound.TLS,
+				DisableAuth:      !inbound.Auth,
+				DisableTarpit:    !inbound.AntiSpam,
+				DisableRateLimit: !inbound.RateLimit,
+			}
+		}
+		submission := smtpCfg.Submission
+		for _, port := range submission.Ports {
+			result[port] = smtpd.ListenerConfig{
+				MaxSize:          sectionSize(submission.MaxSize),
+				DisableTLS:       !submission.TLS,
+				DisableTarpit:    !submission.AntiSpam,
+				DisableRateLimit: !submission.RateLimit,
+			}
+		}
+	}
+
+	for _, l := range smtpCfg.Listeners {
+		listener := result[l.Port]
+		listener.Hostname, listener.Banner = l.Hostname, l.Banner
+		if len(l.TrustedUpstreams) > 0 {
+			upstream, err := antispam.NewTrustedUpstream(l.TrustedUpstreams, l.TrustedAuthServIDs)
+			if err != nil {
+				log.Warn().Err(err).Int("port", l.Port).Msg("受信任的上游网关配置无效，已忽略")
+			} else {
+				listener.Upstream = upstream
+			}
+		}
+		if l.GreetDelay > 0 {
+			greet, err := antispam.NewGreetDelay(l.GreetDelay, l.EarlyTalker != "junk", l.EarlyTalkerWhitelist)
+			if err != nil {
+				log.Warn().Err(err).Int("port", l.Port).Msg("欢迎横幅延迟配置无效，已忽略")
+			} else {
+				listener.GreetDelay = greet
+			}
+		}
+		result[l.Port] = listener
+	}
+	return result
+}
+
+// runUsageRollup 定期记录所有用户当天的用量快照（每小时刷新，当天最后一次为准）
+func runUsageRollup(ctx context.Context, storageDriver storage.Driver) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if n, err := storageDriver.RecordDailyUsage(ctx, time.Now()); err != nil {
+			log.Warn().Err(err).Msg("记录每日用量失败")
+		} else {
+			log.Debug().Int("users", n).Msg("每日用量已记录")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// statsBackfillDays 启动时重新汇总的邮件统计天数（补齐停机期间和首次启用前的数据）
+const statsBackfillDays = 30
+
+// runStatsRollup 定期汇总邮件统计：启动时补齐最近 statsBackfillDays 天，之后每小时刷新今天和昨天
+func runStatsRollup(ctx context.Context, storageDriver storage.Driver) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	days := statsBackfillDays
+	for {
+		now := time.Now()
+		for i := days - 1; i >= 0; i-- {
+			if _, err := storageDriver.RecordDailyStats(ctx, now.AddDate(0, 0, -i)); err != nil {
+				log.Warn().Err(err).Msg("汇总邮件统计失败")
+				break
+			}
+		}
+		log.Debug().Int("days", days).Msg("邮件统计已汇总")
+		days = 2
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runAliasCleanup 定期删除过期或作废超过保留期的临时别名
+func runAliasCleanup(ctx context.Context, storageDriver storage.Driver) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := storageDriver.DeleteInactiveDisposableAliases(ctx, time.Now().Add(-alias.DisposableRetention))
+			if err != nil {
+				log.Warn().Err(err).Msg("清理临时别名失败")
+			} else if n > 0 {
+				log.Info().Int("count", n).Msg("已清理过期的临时别名")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runJournalCleanup 定期清理超过保留时间的 Maildir 变更日志（落后更久的备节点需要全量同步）
+func runJournalCleanup(ctx context.Context, storageDriver storage.Driver, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := storageDriver.PruneMaildirJournal(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Warn().Err(err).Msg("清理 Maildir 变更日志失败")
+			} else if n > 0 {
+				log.Info().Int64("count", n).Msg("已清理过期的 Maildir 变更日志")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runTranscriptCleanup 定期清理超过保留时间的外发会话记录
+func runTranscriptCleanup(ctx context.Context, storageDriver storage.Driver, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := storageDriver.PruneSMTPTranscripts(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Warn().Err(err).Msg("清理外发会话记录失败")
+			} else if n > 0 {
+				log.Info().Int64("count", n).Msg("已清理过期的外发会话记录")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runActivityCleanup 定期清理超过保留时间的登录记录和发信记录
+func runActivityCleanup(ctx context.Context, storageDriver storage.Driver, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := storageDriver.PruneAccountActivity(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Warn().Err(err).Msg("清理账户活动记录失败")
+			} else if n > 0 {
+				log.Info().Int64("count", n).Msg("已清理过期的账户活动记录")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// startACME 启动 ACME 证书管理器，并将其注册为证书存储的 ACME 来源
+func startACME(ctx context.Context, cfg *config.Config, storageDriver storage.Driver, certStore *tlsconfig.CertStore) {
+	var hostnames []string
+	if cfg.SMTP.Hostname != "" {
+		hostnames = append(hostnames, cfg.SMTP.Hostname)
+	}
+	for _, l := range cfg.SMTP.Listeners {
+		if l.Hostname != "" {
+			hostnames = append(hostnames, l.Hostname)
+		}
+	}
+	certStore.SetACME(nil, hostnames)
+
+	domains := certStore.ACMEHostnames()
+	if len(domains) == 0 {
+		log.Warn().Msg("ACME 已启用但没有需要申请证书的主机名（设置 smtp.hostname 或 tls.sni[].acme）")
+		return
+	}
+
+	// 数据库存储让多个节点共享账户和证书，无需可写的证书目录
+	var store acme.Store
+	if cfg.TLS.ACME.Storage == "database" {
+		dbStore, err := acme.NewDBStore(storageDriver, cfg.TLS.ACME.Email, acme.DirectoryURL(cfg.TLS.ACME.Provider), cfg.TLS.ACME.EncryptionKey)
+		if err != nil {
+			log.Error().Err(err).Msg("创建 ACME 数据库存储失败")
+			return
+		}
+		store = dbStore
+	}
+
+	// 注册账户和申请证书需要访问网络，放到后台执行，避免阻塞启动
+	go func() {
+		manager, err := acme.NewManager(&cfg.TLS.ACME, store)
+		if err != nil {
+			log.Error().Err(err).Msg("创建 ACME 证书管理器失败")
+			return
+		}
+		// 先注册为证书来源：签发时 TLS-ALPN-01 验证连接需要由现有监听响应
+		certStore.SetACME(manager, nil)
+		if err := manager.Start(ctx, domains); err != nil {
+			log.Error().Err(err).Msg("启动 ACME 证书管理器失败")
+			return
+		}
+		log.Info().Strs("domains", domains).Msg("ACME 证书管理器已启动")
+	}()
+}
+
+// sectionSize 接收或提交端口的最大邮件大小（留空时返回 0，使用 smtp.max_size）
+func sectionSize(sizeStr string) int64 {
+	if sizeStr == "" {
+		return 0
+	}
+	return parseSize(sizeStr)
+}
+
 // parseSize 解析大小字符串（如 "50MB"）为字节数
 func parseSize(sizeStr string) int64 {
 	// 简化实现，仅支持 MB
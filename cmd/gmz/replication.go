@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/replication"
+)
+
+const replicationUsage = "用法: gmz replication follow | catchup | status | promote [--skip-catchup]"
+
+// handleReplicationCommand 处理 replication 子命令（在备节点上运行）
+func handleReplicationCommand(args []string, configPath string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", replicationUsage)
+	}
+
+	fs := flag.NewFlagSet("replication "+args[0], flag.ContinueOnError)
+	path := fs.String("c", configPath, "配置文件路径")
+	timeout := fs.Duration("timeout", 10*time.Minute, "catchup/promote 的同步超时时间")
+	skipCatchup := fs.Bool("skip-catchup", false, "promote 时不再从主节点同步（主节点已不可用时使用）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	logger.Init(logger.LogConfig{
+		Level:  cfg.Log.Level,
+		Format: cfg.Log.Format,
+		Output: cfg.Log.Output,
+	})
+
+	standby := &replication.Standby{
+		Root:     cfg.Storage.MaildirRoot,
+		StateDir: cfg.WorkDir,
+	}
+	connect := func() error {
+		if cfg.Replication.Primary == "" {
+			return fmt.Errorf("未配置主节点地址（replication.primary）")
+		}
+		tlsConfig, err := replicationClientTLS(&cfg.Replication)
+		if err != nil {
+			return err
+		}
+		conn, err := replication.Dial(cfg.Replication.Primary, cfg.Replication.Token, tlsConfig)
+		if err != nil {
+			return err
+		}
+		standby.Client = replication.NewClient(conn)
+		return nil
+	}
+
+	switch args[0] {
+	case "follow":
+		if err := connect(); err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		fmt.Printf("开始从 %s 复制 Maildir（Ctrl+C 停止）\n", cfg.Replication.Primary)
+		return standby.Follow(ctx)
+	case "catchup":
+		if err := connect(); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		applied, err := standby.Sync(ctx, false)
+		if err != nil {
+			return err
+		}
+		seq, _ := standby.Seq()
+		fmt.Printf("已追上主节点：应用 %d 条变更，当前日志序号 %d\n", applied, seq)
+		return nil
+	case "status":
+		seq, err := standby.Seq()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("本地日志序号: %d\n", seq)
+		if standby.Promoted() {
+			fmt.Println("本节点已提升为主节点")
+			return nil
+		}
+		if err := connect(); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		status, err := standby.Client.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("查询主节点状态失败: %w", err)
+		}
+		fmt.Printf("主节点日志序号: %d（保留 %d 起）\n落后: %d 条\n", status.Last, status.First, status.Last-seq)
+		if seq > 0 && seq+1 < status.First {
+			fmt.Println("本地进度早于主节点保留的日志，下次同步将全量同步")
+		}
+		return nil
+	case "promote":
+		if standby.Promoted() {
+			return fmt.Errorf("本节点已经提升为主节点")
+		}
+		if !*skipCatchup {
+			if err := connect(); err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			defer cancel()
+			applied, err := standby.Sync(ctx, false)
+			if err != nil && !errors.Is(err, replication.ErrPromoted) {
+				return fmt.Errorf("提升前同步失败（主节点已不可用时使用 --skip-catchup）: %w", err)
+			}
+			fmt.Printf("提升前已同步 %d 条变更\n", applied)
+		}
+		if err := standby.Promote(); err != nil {
+			return err
+		}
+		seq, _ := standby.Seq()
+		fmt.Printf("已提升为主节点（日志序号 %d）。请停止 gmz replication follow，确认数据库已切换后启动 gmz。\n", seq)
+		return nil
+	default:
+		return fmt.Errorf("未知的 replication 子命令: %s\n%s", args[0], replicationUsage)
+	}
+}
+
+// replicationClientTLS 备节点连接主节点的 TLS 配置（replication.tls 为 false 时返回 nil）
+func replicationClientTLS(cfg *config.ReplicationConfig) (*tls.Config, error) {
+	return grpcClientTLS(cfg.TLS, cfg.CAFile)
+}
+
+// grpcClientTLS 连接内部 gRPC 服务（复制、存储服务）的 TLS 配置，caFile 非空时只信任其中的证书
+func grpcClientTLS(enabled bool, caFile string) (*tls.Config, error) {
+	if !enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		// #nosec G304 -- CA 文件路径由配置决定
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 文件失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 文件中没有有效的证书: %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
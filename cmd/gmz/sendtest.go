@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/testmail"
+	tlsconfig "github.com/gomailzero/gmz/internal/tls"
+)
+
+// handleSendTestCommand 处理 sendtest 子命令：发送测试邮件并输出各阶段结果
+func handleSendTestCommand(args []string, configPath string) error {
+	fs := flag.NewFlagSet("sendtest", flag.ContinueOnError)
+	to := fs.String("to", "", "收件人地址")
+	from := fs.String("from", "", "发件人地址（默认 postmaster@<domain>）")
+	path := fs.String("c", configPath, "配置文件路径")
+	timeout := fs.Duration("timeout", 2*time.Minute, "投递超时时间")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" {
+		return fmt.Errorf("用法: gmz sendtest --to user@example.com [--from postmaster@example.com]")
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if cfg.Storage.Driver != "sqlite" {
+		return fmt.Errorf("不支持的存储驱动: %s", cfg.Storage.Driver)
+	}
+
+	driver, err := storage.NewSQLiteDriver(cfg.Storage.DSN)
+	if err != nil {
+		return fmt.Errorf("初始化存储失败: %w", err)
+	}
+	defer driver.Close()
+
+	maildir, err := storage.NewMaildir(cfg.Storage.MaildirRoot)
+	if err != nil {
+		return fmt.Errorf("初始化 Maildir 失败: %w", err)
+	}
+	maildir.SetSharding(cfg.Storage.MaildirSharding)
+
+	dkim, err := smtpclient.LoadOutboundDKIM(&cfg.SMTP, cfg.Domain, cfg.WorkDir)
+	if err != nil {
+		return fmt.Errorf("加载 DKIM 失败: %w", err)
+	}
+	signers, err := smtpclient.LoadOutboundSigners(context.Background(), &cfg.SMTP, dkim, cfg.WorkDir, driver)
+	if err != nil {
+		return fmt.Errorf("加载域名 DKIM 密钥失败: %w", err)
+	}
+
+	clientTLS, err := tlsconfig.ClientTLSConfig(&cfg.TLS)
+	if err != nil {
+		return fmt.Errorf("加载外发 TLS 配置失败: %w", err)
+	}
+
+	signer, err := newBATVSigner(&cfg.SMTP.BATV)
+	if err != nil {
+		return fmt.Errorf("加载 BATV 配置失败: %w", err)
+	}
+
+	sender := &testmail.Sender{
+		Domain:    cfg.Domain,
+		Storage:   driver,
+		Maildir:   maildir,
+		SMTP:      &cfg.SMTP,
+		DKIM:      signers,
+		ClientTLS: clientTLS,
+	}
+	if signer != nil {
+		sender.Signer = signer
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	report := sender.Send(ctx, *from, *to)
+	fmt.Printf("发件人: %s\n收件人: %s\nMessage-ID: %s\n\n", report.From, report.To, report.MessageID)
+	for _, stage := range report.Stages {
+		fmt.Printf("%-8s %-8s %5dms  %s\n", stage.Name, stage.Status, stage.DurationMS, stage.Detail)
+	}
+
+	if !report.Success {
+		return fmt.Errorf("\n测试邮件投递失败")
+	}
+	fmt.Println("\n测试邮件投递成功")
+	return nil
+}
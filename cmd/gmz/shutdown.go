@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// service 可以优雅停止的服务
+type service struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+// stopServices 并行停止一组服务，返回时所有服务都已停止（或 ctx 截止后被强制关闭）
+func stopServices(ctx context.Context, services []service) {
+	var wg sync.WaitGroup
+	for _, svc := range services {
+		wg.Add(1)
+		go func(svc service) {
+			defer wg.Done()
+			if err := svc.stop(ctx); err != nil {
+				log.Warn().Err(err).Str("service", svc.name).Msg("服务未能优雅停止")
+			}
+		}(svc)
+	}
+	wg.Wait()
+}
+
+// backgroundTasks 跟踪后台任务，停止时等待它们结束
+type backgroundTasks struct {
+	wg sync.WaitGroup
+}
+
+// Go 启动后台任务（任务应在 ctx 取消后返回）
+func (b *backgroundTasks) Go(fn func()) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		fn()
+	}()
+}
+
+// Wait 等待所有后台任务结束，ctx 截止时返回 false
+func (b *backgroundTasks) Wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
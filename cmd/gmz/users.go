@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/provision"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const usersUsage = "用法: gmz users import --file users.csv [--dry-run] | export [--output users.csv]"
+
+// handleUsersCommand 处理 users 子命令：CSV 批量导入和导出用户
+func handleUsersCommand(args []string, configPath string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", usersUsage)
+	}
+
+	fs := flag.NewFlagSet("users "+args[0], flag.ContinueOnError)
+	path := fs.String("c", configPath, "配置文件路径")
+	file := fs.String("file", "", "导入的 CSV 文件（- 表示标准输入）")
+	dryRun := fs.Bool("dry-run", false, "只校验，不创建用户")
+	output := fs.String("output", "", "导出的 CSV 文件（默认输出到标准输出）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		return fmt.Errorf("加载配置失败: %w", err)
+	}
+	if cfg.Storage.Driver != "sqlite" {
+		return fmt.Errorf("不支持的存储驱动: %s", cfg.Storage.Driver)
+	}
+
+	driver, err := storage.NewSQLiteDriver(cfg.Storage.DSN)
+	if err != nil {
+		return fmt.Errorf("初始化存储失败: %w", err)
+	}
+	defer driver.Close()
+
+	ctx := context.Background()
+	switch args[0] {
+	case "import":
+		if *file == "" {
+			return fmt.Errorf("%s", usersUsage)
+		}
+		var r io.Reader = os.Stdin
+		if *file != "-" {
+			// #nosec G304 -- 文件路径来自命令行参数
+			f, err := os.Open(*file)
+			if err != nil {
+				return fmt.Errorf("打开 CSV 文件失败: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		result, err := provision.ImportUsersCSV(ctx, driver, r, *dryRun)
+		if err != nil {
+			return err
+		}
+		printImportResult(result, *dryRun)
+		if result.Skipped > 0 {
+			return fmt.Errorf("%d 行未导入", result.Skipped)
+		}
+		return nil
+
+	case "export":
+		var w io.Writer = os.Stdout
+		if *output != "" {
+			// #nosec G304 -- 文件路径来自命令行参数
+			f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				return fmt.Errorf("创建 CSV 文件失败: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := provision.ExportUsersCSV(ctx, driver, w); err != nil {
+			return fmt.Errorf("导出用户失败: %w", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%s", usersUsage)
+	}
+}
+
+// printImportResult 输出逐行的导入结果（邀请用户的初始密码只在这里显示一次）
+func printImportResult(result *provision.ImportResult, dryRun bool) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "行\t邮箱\t状态\t初始密码\t错误")
+	for _, row := range result.Rows {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", row.Line, row.Email, row.Status, row.Password, row.Error)
+	}
+	tw.Flush()
+
+	if dryRun {
+		fmt.Printf("\n试运行：%d 行校验通过，%d 行未通过\n", result.Created, result.Skipped)
+		return
+	}
+	fmt.Printf("\n已创建 %d 个用户，%d 行未导入\n", result.Created, result.Skipped)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client 管理 API 客户端（API Key 或管理员 JWT 认证）
+type client struct {
+	baseURL string
+	apiKey  string
+	token   string
+	totp    string
+	http    *http.Client
+}
+
+// newClient 创建管理 API 客户端
+func newClient(baseURL, apiKey, token, totp string, timeout time.Duration) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		token:   token,
+		totp:    totp,
+		http:    &http.Client{Timeout: timeout},
+	}
+}
+
+// do 调用管理 API（path 相对于 /api/v1），body 不为空时以 JSON 发送，返回响应体；
+// 非 2xx 响应返回 API 的错误信息
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body interface{}) (json.RawMessage, error) {
+	if c.apiKey == "" && c.token == "" {
+		return nil, fmt.Errorf("需要 API Key（-api-key 或 GMZ_API_KEY）或登录令牌（-token 或 GMZ_TOKEN）")
+	}
+
+	u := c.baseURL + "/api/v1" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.totp != "" {
+		req.Header.Set("X-TOTP-Code", c.totp)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求管理 API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s（HTTP %d）", apiErr.Error, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("管理 API 返回 HTTP %d", resp.StatusCode)
+	}
+	return data, nil
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/search"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// app 命令执行环境
+type app struct {
+	client *client
+	out    *output
+	stdin  io.Reader
+}
+
+// run 执行命令
+func (a *app) run(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("缺少子命令，运行 gmzctl -h 查看用法")
+	}
+	ctx := context.Background()
+	cmd, rest := args[0]+" "+args[1], args[2:]
+	switch cmd {
+	case "user add":
+		return a.userAdd(ctx, rest)
+	case "user list":
+		return a.userList(ctx, rest)
+	case "user passwd":
+		return a.userPasswd(ctx, rest)
+	case "user delete":
+		return a.userDelete(ctx, rest)
+	case "domain add":
+		return a.domainAdd(ctx, rest)
+	case "alias add":
+		return a.aliasAdd(ctx, rest)
+	case "quota set":
+		return a.quotaSet(ctx, rest)
+	case "queue list":
+		return a.queueList(ctx, rest)
+	case "queue flush":
+		return a.queueFlush(ctx, rest)
+	case "mail search":
+		return a.mailSearch(ctx, rest)
+	case "dkim keygen":
+		return a.dkimKeygen(rest)
+	default:
+		return fmt.Errorf("未知命令: %s", cmd)
+	}
+}
+
+// parseArgs 解析参数，选项可以出现在位置参数之前或之后；位置参数数量不在 [min, max] 范围内时返回用法错误
+// （max 为 -1 表示不限）
+func parseArgs(fs *flag.FlagSet, args []string, usage string, min, max int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%v\n用法: gmzctl %s", err, usage)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < min || (max >= 0 && len(positional) > max) {
+		return nil, fmt.Errorf("用法: gmzctl %s", usage)
+	}
+	return positional, nil
+}
+
+// password 返回 -password 的值，未指定时从标准输入读取一行
+func (a *app) password(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	line, err := bufio.NewReader(a.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("读取密码失败: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("密码不能为空")
+	}
+	return password, nil
+}
+
+// parseQuota 解析配额大小（如 500M、2G，0 表示不限）
+func parseQuota(s string) (int64, error) {
+	if s == "0" {
+		return 0, nil
+	}
+	size, err := search.ParseSize(s)
+	if err != nil {
+		return 0, fmt.Errorf("无效的配额 %q: %w", s, err)
+	}
+	return size, nil
+}
+
+func (a *app) userAdd(ctx context.Context, args []string) error {
+	const usage = "user add <email> [-password 密码] [-quota 1G] [-admin] [-inactive]"
+	fs := flag.NewFlagSet("user add", flag.ContinueOnError)
+	password := fs.String("password", "", "密码（未指定时从标准输入读取）")
+	quota := fs.String("quota", "0", "配额（如 500M、2G，0 表示不限）")
+	admin := fs.Bool("admin", false, "管理员")
+	inactive := fs.Bool("inactive", false, "创建为停用状态")
+	positional, err := parseArgs(fs, args, usage, 1, 1)
+	if err != nil {
+		return err
+	}
+	size, err := parseQuota(*quota)
+	if err != nil {
+		return err
+	}
+	pw, err := a.password(*password)
+	if err != nil {
+		return err
+	}
+
+	data, err := a.client.do(ctx, http.MethodPost, "/users", nil, map[string]interface{}{
+		"email":    positional[0],
+		"password": pw,
+		"quota":    size,
+		"active":   !*inactive,
+		"is_admin": *admin,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.result(data, "已创建用户 "+positional[0])
+}
+
+func (a *app) userList(ctx context.Context, args []string) error {
+	const usage = "user list [-limit 100] [-offset 0]"
+	fs := flag.NewFlagSet("user list", flag.ContinueOnError)
+	limit := fs.Int("limit", 100, "最多列出的用户数")
+	offset := fs.Int("offset", 0, "跳过的用户数")
+	if _, err := parseArgs(fs, args, usage, 0, 0); err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(*limit)}, "offset": {strconv.Itoa(*offset)}}
+	data, err := a.client.do(ctx, http.MethodGet, "/users", query, nil)
+	if err != nil {
+		return err
+	}
+	if a.out.asJSON {
+		return a.out.json(data)
+	}
+	var resp struct {
+		Users []*storage.User `json:"users"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	rows := make([][]string, 0, len(resp.Users))
+	for _, u := range resp.Users {
+		rows = append(rows, []string{u.Email, formatSize(u.Quota), yesNo(u.Active), yesNo(u.IsAdmin), u.CreatedAt.Local().Format(time.DateTime)})
+	}
+	return a.out.table([]string{"EMAIL", "QUOTA", "ACTIVE", "ADMIN", "CREATED"}, rows)
+}
+
+func (a *app) userPasswd(ctx context.Context, args []string) error {
+	const usage = "user passwd <email> [-password 密码]"
+	fs := flag.NewFlagSet("user passwd", flag.ContinueOnError)
+	password := fs.String("password", "", "新密码（未指定时从标准输入读取）")
+	positional, err := parseArgs(fs, args, usage, 1, 1)
+	if err != nil {
+		return err
+	}
+	pw, err := a.password(*password)
+	if err != nil {
+		return err
+	}
+
+	// 更新接口会同时设置启用状态，先取出当前状态
+	path := "/users/" + url.PathEscape(positional[0])
+	data, err := a.client.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	var user storage.User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	data, err = a.client.do(ctx, http.MethodPut, path, nil, map[string]interface{}{
+		"password": pw,
+		"active":   user.Active,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.result(data, "已修改 "+positional[0]+" 的密码")
+}
+
+func (a *app) userDelete(ctx context.Context, args []string) error {
+	const usage = "user delete <email>"
+	positional, err := parseArgs(flag.NewFlagSet("user delete", flag.ContinueOnError), args, usage, 1, 1)
+	if err != nil {
+		return err
+	}
+	data, err := a.client.do(ctx, http.MethodDelete, "/users/"+url.PathEscape(positional[0]), nil, nil)
+	if err != nil {
+		return err
+	}
+	return a.out.result(data, "已删除用户 "+positional[0])
+}
+
+func (a *app) domainAdd(ctx context.Context, args []string) error {
+	const usage = "domain add <name> [-inactive]"
+	fs := flag.NewFlagSet("domain add", flag.ContinueOnError)
+	inactive := fs.Bool("inactive", false, "创建为停用状态")
+	positional, err := parseArgs(fs, args, usage, 1, 1)
+	if err != nil {
+		return err
+	}
+	data, err := a.client.do(ctx, http.MethodPost, "/domains", nil, map[string]interface{}{
+		"name":   positional[0],
+		"active": !*inactive,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.result(data, "已创建域名 "+positional[0])
+}
+
+func (a *app) aliasAdd(ctx context.Context, args []string) error {
+	const usage = "alias add <from> <to>..."
+	positional, err := parseArgs(flag.NewFlagSet("alias add", flag.ContinueOnError), args, usage, 2, -1)
+	if err != nil {
+		return err
+	}
+	from := positional[0]
+	_, domain, ok := strings.Cut(from, "@")
+	if !ok || domain == "" {
+		return fmt.Errorf("无效的别名地址: %s", from)
+	}
+	data, err := a.client.do(ctx, http.MethodPost, "/aliases", nil, map[string]interface{}{
+		"from":         from,
+		"destinations": positional[1:],
+		"domain":       domain,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.result(data, fmt.Sprintf("已创建别名 %s -> %s", from, strings.Join(positional[1:], ", ")))
+}
+
+func (a *app) quotaSet(ctx context.Context, args []string) error {
+	const usage = "quota set <email> <size>"
+	positional, err := parseArgs(flag.NewFlagSet("quota set", flag.ContinueOnError), args, usage, 2, 2)
+	if err != nil {
+		return err
+	}
+	size, err := parseQuota(positional[1])
+	if err != nil {
+		return err
+	}
+	if size == 0 {
+		return fmt.Errorf("配额必须大于 0")
+	}
+	data, err := a.client.do(ctx, http.MethodPut, "/users/"+url.PathEscape(positional[0])+"/quota", nil, map[string]interface{}{
+		"limit": size,
+	})
+	if err != nil {
+		return err
+	}
+	return a.out.result(data, fmt.Sprintf("已将 %s 的配额设置为 %s", positional[0], formatSize(size)))
+}
+
+func (a *app) queueList(ctx context.Context, args []string) error {
+	const usage = "queue list [-limit 100]"
+	fs := flag.NewFlagSet("queue list", flag.ContinueOnError)
+	limit := fs.Int("limit", 100, "最多列出的邮件数")
+	if _, err := parseArgs(fs, args, usage, 0, 0); err != nil {
+		return err
+	}
+	data, err := a.client.do(ctx, http.MethodGet, "/queue", url.Values{"limit": {strconv.Itoa(*limit)}}, nil)
+	if err != nil {
+		return err
+	}
+	if a.out.asJSON {
+		return a.out.json(data)
+	}
+	var resp struct {
+		Mails []*storage.DeferredMail `json:"mails"`
+		Total int                     `json:"total"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	rows := make([][]string, 0, len(resp.Mails))
+	for _, m := range resp.Mails {
+		rows = append(rows, []string{
+			strconv.FormatInt(m.ID, 10), m.Provider, m.Sender, strings.Join(m.Recipients, ","),
+			m.NotBefore.Local().Format(time.DateTime), strconv.Itoa(m.Attempts),
+		})
+	}
+	if err := a.out.table([]string{"ID", "PROVIDER", "SENDER", "RECIPIENTS", "NOT_BEFORE", "ATTEMPTS"}, rows); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(a.out.w, "共 %d 封\n", resp.Total)
+	return err
+}
+
+func (a *app) queueFlush(ctx context.Context, args []string) error {
+	const usage = "queue flush [-provider 服务商]"
+	fs := flag.NewFlagSet("queue flush", flag.ContinueOnError)
+	provider := fs.String("provider", "", "只重试发往该服务商的邮件")
+	if _, err := parseArgs(fs, args, usage, 0, 0); err != nil {
+		return err
+	}
+	var query url.Values
+	if *provider != "" {
+		query = url.Values{"provider": {*provider}}
+	}
+	data, err := a.client.do(ctx, http.MethodPost, "/queue/flush", query, nil)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Flushed int `json:"flushed"`
+	}
+	_ = json.Unmarshal(data, &resp)
+	return a.out.result(data, fmt.Sprintf("已安排立即重试 %d 封邮件", resp.Flushed))
+}
+
+func (a *app) mailSearch(ctx context.Context, args []string) error {
+	const usage = "mail search <email> <query> [-folder INBOX] [-limit 50]"
+	fs := flag.NewFlagSet("mail search", flag.ContinueOnError)
+	folder := fs.String("folder", "", "只搜索该文件夹（默认搜索全部文件夹）")
+	limit := fs.Int("limit", 50, "最多列出的邮件数")
+	positional, err := parseArgs(fs, args, usage, 2, -1)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"q": {strings.Join(positional[1:], " ")}, "limit": {strconv.Itoa(*limit)}}
+	if *folder != "" {
+		query.Set("folder", *folder)
+	}
+	data, err := a.client.do(ctx, http.MethodGet, "/users/"+url.PathEscape(positional[0])+"/mails", query, nil)
+	if err != nil {
+		return err
+	}
+	if a.out.asJSON {
+		return a.out.json(data)
+	}
+	var resp struct {
+		Mails []*storage.Mail `json:"mails"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	rows := make([][]string, 0, len(resp.Mails))
+	for _, m := range resp.Mails {
+		rows = append(rows, []string{m.ID, m.Folder, m.ReceivedAt.Local().Format(time.DateTime), m.From, formatSize(m.Size), m.Subject})
+	}
+	return a.out.table([]string{"ID", "FOLDER", "RECEIVED", "FROM", "SIZE", "SUBJECT"}, rows)
+}
+
+// dkimKeygen 在本地生成 DKIM 私钥文件（PKCS#8 PEM，可直接用于 smtp.dkim.private_key）并输出需要发布的 DNS 记录
+func (a *app) dkimKeygen(args []string) error {
+	const usage = "dkim keygen -domain example.com [-selector default] [-algorithm rsa|ed25519] [-out dkim.key]"
+	fs := flag.NewFlagSet("dkim keygen", flag.ContinueOnError)
+	domain := fs.String("domain", "", "签名域名")
+	selector := fs.String("selector", "default", "选择器")
+	algorithm := fs.String("algorithm", "rsa", "密钥算法（rsa 或 ed25519）")
+	out := fs.String("out", "dkim.key", "私钥文件路径（已存在时不覆盖）")
+	if _, err := parseArgs(fs, args, usage, 0, 0); err != nil {
+		return err
+	}
+	if *domain == "" {
+		return fmt.Errorf("用法: gmzctl %s", usage)
+	}
+
+	privateKey, publicKey, err := antispam.GenerateKeyPair(*algorithm)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("编码私钥失败: %w", err)
+	}
+	record, err := antispam.GetPublicKeyDNS(publicKey)
+	if err != nil {
+		return err
+	}
+
+	// #nosec G304 -- 文件路径来自命令行参数
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("创建私钥文件失败: %w", err)
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		return fmt.Errorf("写入私钥文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入私钥文件失败: %w", err)
+	}
+
+	name := *selector + "._domainkey." + *domain
+	if a.out.asJSON {
+		data, _ := json.Marshal(map[string]string{"private_key": *out, "dns_name": name, "dns_value": record})
+		return a.out.json(data)
+	}
+	_, err = fmt.Fprintf(a.out.w, "私钥已写入 %s\n请发布 DNS TXT 记录:\n%s. IN TXT %s\n", *out, name, antispam.QuoteTXT(record))
+	return err
+}
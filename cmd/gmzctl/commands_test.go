@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAPI 记录请求并返回固定响应的管理 API
+type fakeAPI struct {
+	requests []string
+	bodies   []map[string]interface{}
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-API-Key") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"error":"无效的 API Key"}`)
+		return
+	}
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.bodies = append(f.bodies, body)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method + " " + r.URL.Path {
+	case "GET /api/v1/users":
+		_, _ = io.WriteString(w, `{"users":[{"email":"alice@example.com","quota":1073741824,"active":true,"is_admin":false}]}`)
+	case "GET /api/v1/users/alice@example.com":
+		_, _ = io.WriteString(w, `{"email":"alice@example.com","active":false}`)
+	default:
+		_, _ = io.WriteString(w, `{"message":"ok"}`)
+	}
+}
+
+func newTestApp(t *testing.T, apiKey string, asJSON bool) (*app, *fakeAPI, *bytes.Buffer) {
+	t.Helper()
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	out := &bytes.Buffer{}
+	return &app{
+		client: newClient(server.URL+"/", apiKey, "", "", 5*time.Second),
+		out:    newOutput(out, asJSON),
+		stdin:  strings.NewReader("s3cret\n"),
+	}, api, out
+}
+
+func TestUserCommands(t *testing.T) {
+	a, api, out := newTestApp(t, "secret", false)
+
+	if err := a.run([]string{"user", "add", "bob@example.com", "-quota", "2G", "-admin"}); err != nil {
+		t.Fatal(err)
+	}
+	body := api.bodies[0]
+	if api.requests[0] != "POST /api/v1/users" || body["password"] != "s3cret" || body["quota"] != float64(2<<30) ||
+		body["is_admin"] != true || body["active"] != true {
+		t.Fatalf("创建用户请求 = %s %v", api.requests[0], body)
+	}
+
+	out.Reset()
+	if err := a.run([]string{"user", "list"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "EMAIL") || !strings.Contains(lines[1], "alice@example.com") || !strings.Contains(lines[1], "1.0G") {
+		t.Fatalf("用户列表输出 = %q", out.String())
+	}
+
+	// 修改密码保留原来的启用状态
+	if err := a.run([]string{"user", "passwd", "alice@example.com", "-password", "new"}); err != nil {
+		t.Fatal(err)
+	}
+	n := len(api.requests)
+	if api.requests[n-1] != "PUT /api/v1/users/alice@example.com" || api.bodies[n-1]["password"] != "new" || api.bodies[n-1]["active"] != false {
+		t.Fatalf("修改密码请求 = %s %v", api.requests[n-1], api.bodies[n-1])
+	}
+
+	if err := a.run([]string{"alias", "add", "info@example.com", "alice@example.com", "bob@example.org"}); err != nil {
+		t.Fatal(err)
+	}
+	n = len(api.requests)
+	if api.bodies[n-1]["domain"] != "example.com" || len(api.bodies[n-1]["destinations"].([]interface{})) != 2 {
+		t.Fatalf("创建别名请求 = %v", api.bodies[n-1])
+	}
+
+	if err := a.run([]string{"mail", "search", "alice@example.com", "from:carol", "has:attachment", "-limit", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	n = len(api.requests)
+	if api.requests[n-1] != "GET /api/v1/users/alice@example.com/mails?limit=5&q=from%3Acarol+has%3Aattachment" {
+		t.Fatalf("搜索请求 = %s", api.requests[n-1])
+	}
+
+	if err := a.run([]string{"user", "add"}); err == nil || !strings.Contains(err.Error(), "用法") {
+		t.Errorf("缺少参数 err = %v", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	a, _, _ := newTestApp(t, "wrong", true)
+	err := a.run([]string{"queue", "list"})
+	if err == nil || !strings.Contains(err.Error(), "无效的 API Key（HTTP 401）") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestDKIMKeygen(t *testing.T) {
+	a, _, out := newTestApp(t, "secret", false)
+	path := filepath.Join(t.TempDir(), "dkim.key")
+	if err := a.run([]string{"dkim", "keygen", "-domain", "example.com", "-selector", "mail", "-out", path}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block, _ := pem.Decode(data); block == nil || block.Type != "PRIVATE KEY" {
+		t.Fatalf("私钥文件 = %q", data)
+	}
+	if !strings.Contains(out.String(), `mail._domainkey.example.com. IN TXT "v=DKIM1; k=rsa; p=`) {
+		t.Fatalf("输出 = %q", out.String())
+	}
+	// 不覆盖已有的私钥
+	if err := a.run([]string{"dkim", "keygen", "-domain", "example.com", "-out", path}); err == nil {
+		t.Error("覆盖已有私钥文件应该失败")
+	}
+}
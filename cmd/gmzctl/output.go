@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// output 命令输出：表格（默认）或管理 API 返回的 JSON
+type output struct {
+	w      io.Writer
+	asJSON bool
+}
+
+// newOutput 创建输出
+func newOutput(w io.Writer, asJSON bool) *output {
+	return &output{w: w, asJSON: asJSON}
+}
+
+// json 缩进输出原始 JSON
+func (o *output) json(data json.RawMessage) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		_, err = o.w.Write(data)
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(o.w)
+	return err
+}
+
+// table 输出表格（第一行为表头）
+func (o *output) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// result 输出操作结果：-json 时输出响应，否则输出一行说明
+func (o *output) result(data json.RawMessage, message string) error {
+	if o.asJSON {
+		return o.json(data)
+	}
+	_, err := fmt.Fprintln(o.w, message)
+	return err
+}
+
+// yesNo 布尔值的表格显示
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// formatSize 字节数的表格显示（1024 进制，0 表示不限）
+func formatSize(n int64) string {
+	const unit = 1024
+	switch {
+	case n <= 0:
+		return "-"
+	case n < unit:
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(n)/float64(div), "KMGTPE"[exp])
+}
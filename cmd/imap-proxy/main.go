@@ -26,45 +26,76 @@ import (
 )
 
 var (
-	listenAddr     = flag.String("listen", ":1993", "监听地址（客户端连接地址）")
-	targetAddr     = flag.String("target", "localhost:993", "目标 IMAP 服务器地址")
+	protocolName   = flag.String("protocol", "imap", "代理的协议：imap、smtp 或 pop3（决定命令解析、隐藏的认证数据和 STARTTLS 命令）")
+	listenAddr     = flag.String("listen", "", "监听地址（客户端连接地址，默认 imap :1993、smtp :1465、pop3 :1995）")
+	targetAddr     = flag.String("target", "", "目标服务器地址（默认 imap localhost:993、smtp localhost:465、pop3 localhost:995）")
 	useTLS         = flag.Bool("tls", true, "是否使用 TLS 连接目标服务器")
+	startTLS       = flag.Bool("starttls", false, "以明文连接目标服务器后执行 STARTTLS（目标通常为 IMAP 143、SMTP 587、POP3 110 端口，忽略 -tls）")
 	clientTLS      = flag.Bool("client-tls", false, "是否接受客户端的 TLS 连接（TLS-in-TLS 模式）")
-	clientCertFile = flag.String("client-cert", "", "客户端 TLS 证书文件（用于 -client-tls）")
-	clientKeyFile  = flag.String("client-key", "", "客户端 TLS 密钥文件（用于 -client-tls）")
+	clientCertFile = flag.String("client-cert", "", "客户端 TLS 证书文件（用于 -client-tls 和 -client-starttls）")
+	clientKeyFile  = flag.String("client-key", "", "客户端 TLS 密钥文件（用于 -client-tls 和 -client-starttls）")
+	clientStartTLS = flag.Bool("client-starttls", false, "向明文客户端提供 STARTTLS（仅 IMAP；与 -client-tls 同时使用时同一端口接受隐式 TLS 和明文/STARTTLS 连接）")
 	insecureTLS    = flag.Bool("insecure", false, "跳过 TLS 证书验证（仅用于调试）")
-	logFile        = flag.String("log", "", "日志文件路径（留空自动生成：logs/imap-proxy-YYYYMMDD-HHMMSS.log）")
+	logFile        = flag.String("log", "", "日志文件路径（留空自动生成：logs/<协议>-proxy-YYYYMMDD-HHMMSS.log）")
 	logDir         = flag.String("log-dir", "logs", "日志目录（自动创建）")
 	autoLog        = flag.Bool("auto-log", true, "自动保存日志到文件（默认启用）")
 	verbose        = flag.Bool("v", false, "详细输出模式")
+	sessionDir     = flag.String("session-dir", "", "会话目录：每个连接写入一个 JSONL 会话文件（按命令和响应记录），并在 index.jsonl 中追加索引")
 )
 
 // Proxy 透传代理
 type Proxy struct {
+	protocol        *mailProtocol
 	listenAddr      string
 	targetAddr      string
 	useTLS          bool
+	startTLS        bool // 明文连接目标服务器后执行 STARTTLS
 	clientTLS       bool
+	clientStartTLS  bool // 向明文客户端提供 STARTTLS
 	clientTLSConfig *tls.Config
 	insecureTLS     bool
 	logFile         *os.File
 	logger          *log.Logger
 	verbose         bool
+	sessionDir      string     // 会话目录（为空时不记录会话文件）
+	indexMu         sync.Mutex // 保护会话索引文件的追加
 }
 
 // NewProxy 创建新的代理实例
 func NewProxy() (*Proxy, error) {
+	proto, err := lookupProtocol(*protocolName)
+	if err != nil {
+		return nil, err
+	}
+	if *clientStartTLS && proto.name != "imap" {
+		return nil, fmt.Errorf("-client-starttls 只支持 IMAP（%s 客户端请使用 -client-tls 或无加密连接）", proto.name)
+	}
 	p := &Proxy{
-		listenAddr:  *listenAddr,
-		targetAddr:  *targetAddr,
-		useTLS:      *useTLS,
-		clientTLS:   *clientTLS,
-		insecureTLS: *insecureTLS,
-		verbose:     *verbose,
+		protocol:       proto,
+		listenAddr:     *listenAddr,
+		targetAddr:     *targetAddr,
+		useTLS:         *useTLS && !*startTLS,
+		startTLS:       *startTLS,
+		clientTLS:      *clientTLS,
+		clientStartTLS: *clientStartTLS,
+		insecureTLS:    *insecureTLS,
+		verbose:        *verbose,
+		sessionDir:     filepath.Clean(*sessionDir),
+	}
+	if p.listenAddr == "" {
+		p.listenAddr = proto.listen
+	}
+	if p.targetAddr == "" {
+		p.targetAddr = proto.target
+	}
+	if *sessionDir == "" {
+		p.sessionDir = ""
+	} else if err := os.MkdirAll(p.sessionDir, 0750); err != nil {
+		return nil, fmt.Errorf("创建会话目录失败: %w", err)
 	}
 
-	// 如果启用客户端 TLS，加载证书
-	if p.clientTLS {
+	// 如果启用客户端 TLS 或 STARTTLS，加载证书
+	if p.clientTLS || p.clientStartTLS {
 		if *clientCertFile == "" || *clientKeyFile == "" {
 			// 尝试生成自签名证书
 			cert, err := generateSelfSignedCert()
@@ -102,7 +133,7 @@ func NewProxy() (*Proxy, error) {
 
 		// 生成带时间戳的日志文件名
 		timestamp := time.Now().Format("20060102-150405")
-		logPath = fmt.Sprintf("%s/imap-proxy-%s.log", *logDir, timestamp)
+		logPath = fmt.Sprintf("%s/%s-proxy-%s.log", *logDir, p.protocol.name, timestamp)
 	}
 
 	if logPath != "" {
@@ -151,27 +182,35 @@ func (p *Proxy) Start(ctx context.Context) error {
 	}
 	defer listener.Close()
 
-	// 如果启用客户端 TLS，包装为 TLS listener
-	if p.clientTLS && p.clientTLSConfig != nil {
+	// 如果只启用客户端 TLS，包装为 TLS listener；同时启用 STARTTLS 时在每个连接上探测
+	name := strings.ToUpper(p.protocol.name)
+	if p.clientTLS && p.clientStartTLS {
+		p.logger.Printf("%s 透传代理启动（双模式：隐式 TLS 和明文/STARTTLS）", name)
+	} else if p.clientTLS && p.clientTLSConfig != nil {
 		listener = tls.NewListener(listener, p.clientTLSConfig)
-		p.logger.Printf("IMAP 透传代理启动（客户端 TLS 模式）")
+		p.logger.Printf("%s 透传代理启动（客户端 TLS 模式）", name)
 	} else {
-		p.logger.Printf("IMAP 透传代理启动（普通 TCP 模式）")
+		p.logger.Printf("%s 透传代理启动（普通 TCP 模式）", name)
 	}
 
 	p.logger.Printf("监听地址: %s", p.listenAddr)
-	p.logger.Printf("目标服务器: %s (TLS: %v)", p.targetAddr, p.useTLS)
-	if p.clientTLS {
+	p.logger.Printf("目标服务器: %s (TLS: %v, STARTTLS: %v)", p.targetAddr, p.useTLS, p.startTLS)
+	switch {
+	case p.clientTLS && p.clientStartTLS:
+		p.logger.Printf("客户端连接: SSL/TLS 或 STARTTLS（也接受无加密）")
+	case p.clientTLS:
 		p.logger.Printf("客户端连接: TLS (需要客户端配置 SSL/TLS)")
-	} else {
-		p.logger.Printf("客户端连接: 普通 TCP (客户端应配置为无加密或 STARTTLS)")
+	case p.clientStartTLS:
+		p.logger.Printf("客户端连接: 普通 TCP，提供 STARTTLS (客户端应配置为 STARTTLS 或无加密)")
+	default:
+		p.logger.Printf("客户端连接: 普通 TCP (客户端应配置为无加密)")
 	}
 
 	// 显示日志文件路径
 	logPath := *logFile
 	if *autoLog && logPath == "" {
 		timestamp := time.Now().Format("20060102-150405")
-		logPath = fmt.Sprintf("%s/imap-proxy-%s.log", *logDir, timestamp)
+		logPath = fmt.Sprintf("%s/%s-proxy-%s.log", *logDir, p.protocol.name, timestamp)
 	}
 	if logPath != "" {
 		absPath, _ := filepath.Abs(logPath)
@@ -179,6 +218,10 @@ func (p *Proxy) Start(ctx context.Context) error {
 	} else {
 		p.logger.Printf("日志输出: 标准输出（未保存到文件）")
 	}
+	if p.sessionDir != "" {
+		absPath, _ := filepath.Abs(p.sessionDir)
+		p.logger.Printf("会话目录: %s（每个连接一个会话文件，索引见 %s）", absPath, sessionIndexFile)
+	}
 
 	p.logger.Printf("等待客户端连接...")
 	separator := strings.Repeat("=", 80)
@@ -260,6 +303,58 @@ func (p *Proxy) handleConnection(clientConn net.Conn) {
 	defer serverConn.Close()
 
 	p.logger.Printf("%s 已连接到目标服务器", connID)
+
+	// 目标服务器 STARTTLS：问候由代理读取，之后转发给客户端
+	var greeting string
+	if p.startTLS {
+		plainConn := serverConn
+		serverConn, greeting, err = p.upstreamStartTLS(plainConn)
+		if err != nil {
+			p.logger.Printf("%s 目标服务器 STARTTLS 失败: %v", connID, err)
+			return
+		}
+		defer serverConn.Close()
+		p.logger.Printf("%s 目标服务器 STARTTLS 完成", connID)
+	}
+
+	// 双模式监听：根据客户端是否立即发送 TLS 握手判断连接类型
+	clientPlain := !p.clientTLS
+	if p.clientTLS && p.clientStartTLS {
+		clientConn, clientPlain, err = p.sniffClientTLS(clientConn)
+		if err != nil {
+			p.logger.Printf("%s 探测客户端连接类型失败: %v", connID, err)
+			return
+		}
+		defer clientConn.Close()
+	}
+
+	clientReader := bufio.NewReader(clientConn)
+	serverReader := bufio.NewReader(serverConn)
+	if clientPlain && p.clientStartTLS {
+		if greeting == "" {
+			if greeting, err = serverReader.ReadString('\n'); err != nil {
+				p.logger.Printf("%s 读取服务器问候失败: %v", connID, err)
+				return
+			}
+		}
+		clientConn, clientReader, err = p.negotiateClientTLS(connID, clientConn, serverConn, serverReader, greeting)
+		if err != nil {
+			p.logger.Printf("%s 客户端 STARTTLS 协商失败: %v", connID, err)
+			return
+		}
+		defer clientConn.Close()
+	} else if greeting != "" {
+		if _, err := io.WriteString(clientConn, greeting); err != nil {
+			return
+		}
+	}
+
+	conn := p.protocol.newConn()
+	if p.sessionDir != "" {
+		p.recordSession(connID, clientAddr, conn, clientConn, serverConn, clientReader, serverReader)
+		return
+	}
+
 	p.logger.Printf("%s 开始双向转发数据...", connID)
 	p.logger.Printf("%s %s", connID, strings.Repeat("-", 80))
 
@@ -270,13 +365,13 @@ func (p *Proxy) handleConnection(clientConn net.Conn) {
 	// 客户端 -> 服务器
 	go func() {
 		defer wg.Done()
-		p.forwardData(connID, "C->S", clientConn, serverConn)
+		p.forwardData(connID, "C->S", conn, clientReader, serverConn)
 	}()
 
 	// 服务器 -> 客户端
 	go func() {
 		defer wg.Done()
-		p.forwardData(connID, "S->C", serverConn, clientConn)
+		p.forwardData(connID, "S->C", conn, serverReader, clientConn)
 	}()
 
 	// 等待转发完成
@@ -286,9 +381,9 @@ func (p *Proxy) handleConnection(clientConn net.Conn) {
 	p.logger.Printf("%s 连接已关闭", connID)
 }
 
-// forwardData 转发数据并记录
-func (p *Proxy) forwardData(connID, direction string, src, dst net.Conn) {
-	// 使用 bufio.Reader 按行读取（IMAP 使用 CRLF 作为行结束符）
+// forwardData 转发数据并记录（conn 跟踪连接的认证过程，在转发之前隐藏每一行中的认证数据）
+func (p *Proxy) forwardData(connID, direction string, conn protocolConn, src io.Reader, dst net.Conn) {
+	// 使用 bufio.Reader 按行读取（IMAP、SMTP 和 POP3 都使用 CRLF 作为行结束符；已经是 bufio.Reader 时直接使用，保留协商阶段缓冲的数据）
 	reader := bufio.NewReader(src)
 	lineNum := 0
 
@@ -317,8 +412,8 @@ func (p *Proxy) forwardData(connID, direction string, src, dst net.Conn) {
 		}
 
 		// 记录原始数据（隐藏敏感信息）
-		logLine := p.sanitizeLine(lineForLog)
-		p.logger.Printf("%s %s [%d] %s", connID, direction, lineNum, string(logLine))
+		logLine := conn.sanitize(direction, string(lineForLog))
+		p.logger.Printf("%s %s [%d] %s", connID, direction, lineNum, logLine)
 
 		// 转发原始数据（保持 CRLF）
 		if _, err := dst.Write(line); err != nil {
@@ -330,44 +425,19 @@ func (p *Proxy) forwardData(connID, direction string, src, dst net.Conn) {
 
 		// 如果是详细模式，解析并显示命令
 		if p.verbose {
-			p.parseAndLogCommand(connID, direction, lineForLog)
-		}
-	}
-}
-
-// sanitizeLine 清理敏感信息
-func (p *Proxy) sanitizeLine(line []byte) []byte {
-	lineStr := string(line)
-
-	// 隐藏 LOGIN 命令中的密码
-	if strings.HasPrefix(lineStr, "LOGIN ") {
-		parts := strings.Fields(lineStr)
-		if len(parts) >= 3 {
-			// 格式: LOGIN username password
-			return []byte(fmt.Sprintf("LOGIN %s ***", parts[1]))
+			p.parseAndLogCommand(connID, direction, []byte(logLine))
 		}
 	}
-
-	// 隐藏 AUTHENTICATE 命令中的密码（如果可见）
-	if strings.HasPrefix(lineStr, "AUTHENTICATE ") {
-		parts := strings.Fields(lineStr)
-		if len(parts) >= 2 {
-			// 只显示认证机制，隐藏后续数据
-			return []byte(fmt.Sprintf("AUTHENTICATE %s ***", parts[1]))
-		}
-	}
-
-	return line
 }
 
-// parseAndLogCommand 解析并记录 IMAP 命令
+// parseAndLogCommand 解析并记录命令（line 中的密码已经隐藏）
 func (p *Proxy) parseAndLogCommand(connID, direction string, line []byte) {
 	lineStr := strings.TrimSpace(string(line))
 	if len(lineStr) == 0 {
 		return
 	}
 
-	// 解析 IMAP 命令
+	// 解析命令
 	parts := strings.Fields(lineStr)
 	if len(parts) == 0 {
 		return
@@ -379,11 +449,6 @@ func (p *Proxy) parseAndLogCommand(connID, direction string, line []byte) {
 		args = strings.Join(parts[1:], " ")
 	}
 
-	// 隐藏敏感信息（密码）
-	if command == "LOGIN" && len(parts) >= 3 {
-		args = parts[1] + " ***"
-	}
-
 	// 记录命令摘要
 	if direction == "C->S" {
 		p.logger.Printf("%s >>> 命令: %s %s", connID, command, args)
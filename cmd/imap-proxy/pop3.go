@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// pop3Command 等待响应的 POP3 命令
+type pop3Command struct {
+	multiLine bool // 成功响应之后有以单独一行 "." 结束的多行内容
+	message   bool // 多行内容是邮件（RETR、TOP），只记录字节数
+}
+
+// pop3Conn POP3 连接的状态
+type pop3Conn struct {
+	mu sync.Mutex
+	// auth 进行中的 AUTH 命令：收到 +OK 或 -ERR 之前，客户端发送的行是认证数据
+	auth bool
+	// pending 等待响应的命令（客户端可以在 PIPELINING 时连续发送多个命令）
+	pending []pop3Command
+}
+
+// sanitize 隐藏 PASS 的密码、APOP 的摘要、AUTH 的初始响应和之后的认证数据
+func (c *pop3Conn) sanitize(direction, line string) string {
+	if direction == "C->S" {
+		return c.clientLine(line)
+	}
+	c.serverLine(line)
+	return line
+}
+
+// clientLine 处理客户端发送的一行，返回用于记录的文本；命令加入等待响应的队列
+func (c *pop3Conn) clientLine(line string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.auth {
+		// SASL 认证数据（base64 编码的凭据）
+		return hiddenText
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return line
+	}
+
+	var command pop3Command
+	switch strings.ToUpper(fields[0]) {
+	case "RETR", "TOP":
+		command = pop3Command{multiLine: true, message: true}
+	case "CAPA":
+		command.multiLine = true
+	case "LIST", "UIDL", "AUTH":
+		// 不带参数时返回所有邮件（AUTH 返回支持的认证机制）
+		command.multiLine = len(fields) == 1
+	}
+	c.pending = append(c.pending, command)
+
+	switch strings.ToUpper(fields[0]) {
+	case "PASS":
+		return fields[0] + " " + hiddenText
+	case "APOP":
+		if len(fields) >= 3 {
+			return fields[0] + " " + fields[1] + " " + hiddenText
+		}
+	case "AUTH":
+		if len(fields) >= 2 {
+			c.auth = true
+		}
+		if len(fields) >= 3 {
+			return fields[0] + " " + fields[1] + " " + hiddenText
+		}
+	}
+	return line
+}
+
+// serverLine 处理响应的第一行：+OK 和 -ERR 结束最早的等待响应的命令（认证过程中的 "+ " 续行不结束命令），
+// 返回对应的命令以及之后是否还有多行内容
+func (c *pop3Conn) serverLine(line string) (pop3Command, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ok := strings.HasPrefix(line, "+OK")
+	if !ok && !strings.HasPrefix(line, "-ERR") {
+		return pop3Command{}, false
+	}
+	c.auth = false
+	if len(c.pending) == 0 {
+		// 问候
+		return pop3Command{}, false
+	}
+	command := c.pending[0]
+	c.pending = c.pending[1:]
+	return command, ok && command.multiLine
+}
+
+// describe 解析客户端命令和服务器响应的状态（+OK、-ERR 或认证续行 +）
+func (c *pop3Conn) describe(direction string, event *sessionEvent) bool {
+	fields := strings.Fields(event.Text)
+	if len(fields) == 0 {
+		return false
+	}
+	if direction == "C->S" {
+		if event.Text == hiddenText {
+			return false
+		}
+		event.Command = strings.ToUpper(fields[0])
+		return true
+	}
+	event.Response = strings.ToUpper(fields[0])
+	return false
+}
+
+// reader 按完整的命令和（多行）响应读取
+func (c *pop3Conn) reader(direction string, src io.Reader) messageReader {
+	return &pop3Reader{r: bufio.NewReader(src), direction: direction, conn: c}
+}
+
+// pop3Reader 会话文件模式下读取 POP3 命令和响应
+type pop3Reader struct {
+	r         *bufio.Reader
+	direction string
+	conn      *pop3Conn
+}
+
+// next 读取一个命令或响应。多行响应到单独一行 "." 为止作为一条记录：邮件内容（RETR、TOP）只记录字节数，
+// 其他内容（CAPA、LIST、UIDL）的各行记录在文本中
+func (pr *pop3Reader) next(dst io.Writer) (logicalLine, error) {
+	var line logicalLine
+	if pr.direction == "C->S" {
+		_, text, err := forwardLine(pr.r, dst, &line, pr.conn.clientLine)
+		line.text = text
+		return line, err
+	}
+
+	var command pop3Command
+	var multiLine bool
+	_, text, err := forwardLine(pr.r, dst, &line, func(s string) string {
+		command, multiLine = pr.conn.serverLine(s)
+		return s
+	})
+	line.text = text
+	if err != nil || !multiLine {
+		return line, err
+	}
+
+	start := line.size
+	parts := []string{text}
+	for {
+		size := line.size
+		_, text, err = forwardLine(pr.r, dst, &line, func(s string) string { return s })
+		if err != nil || text == "." {
+			if command.message {
+				line.literals = []int{int(size - start)}
+			}
+			return line, err
+		}
+		if !command.message {
+			parts = append(parts, text)
+			line.text = strings.Join(parts, " ")
+		}
+	}
+}
+
+// pop3StartTLS 读取 POP3 问候并执行 STLS（RFC 2595），服务器在 TLS 之后不会重新发送问候，原问候转发给客户端
+func pop3StartTLS(r *bufio.Reader, w io.Writer) (string, error) {
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("读取服务器问候失败: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return "", fmt.Errorf("服务器问候异常: %s", strings.TrimRight(greeting, "\r\n"))
+	}
+	if _, err := io.WriteString(w, "STLS\r\n"); err != nil {
+		return "", fmt.Errorf("发送 STLS 失败: %w", err)
+	}
+	reply, err := r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("读取 STLS 响应失败: %w", err)
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return "", fmt.Errorf("服务器拒绝 STLS: %s", strings.TrimRight(reply, "\r\n"))
+	}
+	return greeting, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// hiddenText 隐藏的密码和认证数据在日志和会话文件中显示的文本
+const hiddenText = "***"
+
+// mailProtocol 代理的协议：决定 STARTTLS 的命令、命令和响应的解析以及需要隐藏的认证数据
+type mailProtocol struct {
+	name   string
+	listen string // 默认监听地址
+	target string // 默认目标服务器地址（隐式 TLS 端口）
+	// startTLS 在目标服务器的明文连接上读取问候并请求 STARTTLS，服务器同意后返回需要转发给客户端的问候
+	startTLS func(r *bufio.Reader, w io.Writer) (string, error)
+	// newConn 创建一个连接的协议状态
+	newConn func() protocolConn
+}
+
+// protocols 支持的协议（-protocol）
+var protocols = map[string]*mailProtocol{
+	"imap": {
+		name:     "imap",
+		listen:   ":1993",
+		target:   "localhost:993",
+		startTLS: imapStartTLS,
+		newConn:  func() protocolConn { return &imapConn{} },
+	},
+	"smtp": {
+		name:     "smtp",
+		listen:   ":1465",
+		target:   "localhost:465",
+		startTLS: smtpStartTLS,
+		newConn:  func() protocolConn { return &smtpConn{} },
+	},
+	"pop3": {
+		name:     "pop3",
+		listen:   ":1995",
+		target:   "localhost:995",
+		startTLS: pop3StartTLS,
+		newConn:  func() protocolConn { return &pop3Conn{} },
+	},
+}
+
+// lookupProtocol 按名称查找协议（不区分大小写）
+func lookupProtocol(name string) (*mailProtocol, error) {
+	if proto, ok := protocols[strings.ToLower(name)]; ok {
+		return proto, nil
+	}
+	names := make([]string, 0, len(protocols))
+	for name := range protocols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("不支持的协议 %q（可选值 %s）", name, strings.Join(names, ", "))
+}
+
+// protocolConn 一个代理连接的协议状态（两个方向的转发并发调用）
+type protocolConn interface {
+	// sanitize 返回用于记录的行（隐藏密码和认证数据），并根据命令和响应跟踪认证过程。
+	// 每一行在转发之前调用，使认证数据在对方回复之前就已经被识别
+	sanitize(direction, line string) string
+	// describe 解析一个完整的命令或响应（已经隐藏认证数据），填写事件的标签、命令和响应，返回是否为客户端命令
+	describe(direction string, event *sessionEvent) bool
+	// reader 返回会话文件模式下按完整的命令或响应读取的读取器
+	reader(direction string, src io.Reader) messageReader
+}
+
+// messageReader 按完整的命令或响应读取连接数据，读到的原始数据立即写入 dst
+type messageReader interface {
+	next(dst io.Writer) (logicalLine, error)
+}
+
+// imapConn IMAP 连接的状态
+type imapConn struct {
+	mu sync.Mutex
+	// authTag 进行中的 AUTHENTICATE 命令的标签：收到该标签的结果之前，客户端发送的行是认证数据
+	authTag string
+}
+
+// sanitize 隐藏 LOGIN 的密码参数、AUTHENTICATE 的初始响应和之后的认证数据
+func (c *imapConn) sanitize(direction, line string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fields := strings.Fields(line)
+	if direction != "C->S" {
+		if c.authTag != "" && len(fields) > 0 && fields[0] == c.authTag {
+			c.authTag = ""
+		}
+		return line
+	}
+	if c.authTag != "" {
+		// SASL 认证数据（base64 编码的凭据）
+		return hiddenText
+	}
+	if len(fields) < 2 {
+		return line
+	}
+	switch strings.ToUpper(fields[1]) {
+	case "LOGIN":
+		if len(fields) >= 4 {
+			return strings.Join(fields[:3], " ") + " " + hiddenText
+		}
+	case "AUTHENTICATE":
+		c.authTag = fields[0]
+		if len(fields) >= 4 {
+			return strings.Join(fields[:3], " ") + " " + hiddenText
+		}
+	}
+	return line
+}
+
+// describe 解析命令标签和命令（UID 命令包括子命令），以及响应的状态或类型
+func (c *imapConn) describe(direction string, event *sessionEvent) bool {
+	fields := strings.Fields(event.Text)
+	if direction == "C->S" {
+		if len(fields) < 2 {
+			return false
+		}
+		event.Tag = fields[0]
+		event.Command = strings.ToUpper(fields[1])
+		if event.Command == "UID" && len(fields) >= 3 {
+			event.Command += " " + strings.ToUpper(fields[2])
+		}
+		return true
+	}
+	if len(fields) >= 1 {
+		event.Tag = fields[0]
+	}
+	if len(fields) >= 2 {
+		event.Response = strings.ToUpper(fields[1])
+		// 带数字的未标记响应（* 12 EXISTS、* 3 FETCH）
+		if _, err := strconv.Atoi(fields[1]); err == nil && len(fields) >= 3 {
+			event.Response = strings.ToUpper(fields[2])
+		}
+	}
+	return false
+}
+
+// reader 按 IMAP 字面量读取完整的命令或响应
+func (c *imapConn) reader(direction string, src io.Reader) messageReader {
+	return newLiteralReader(src, direction, c)
+}
+
+// imapStartTLS 读取 IMAP 问候并执行 STARTTLS。
+// 服务器不会在 TLS 之后重新发送问候，返回的问候中去掉了明文阶段的能力列表（客户端需要时会重新查询 CAPABILITY）
+func imapStartTLS(r *bufio.Reader, w io.Writer) (string, error) {
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("读取服务器问候失败: %w", err)
+	}
+	if !strings.HasPrefix(strings.ToUpper(greeting), "* OK") {
+		return "", fmt.Errorf("服务器问候异常: %s", strings.TrimRight(greeting, "\r\n"))
+	}
+	if _, err := io.WriteString(w, starttlsTag+" STARTTLS\r\n"); err != nil {
+		return "", fmt.Errorf("发送 STARTTLS 失败: %w", err)
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", fmt.Errorf("读取 STARTTLS 响应失败: %w", err)
+		}
+		if !strings.HasPrefix(line, starttlsTag+" ") {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToUpper(line[len(starttlsTag)+1:]), "OK") {
+			return "", fmt.Errorf("服务器拒绝 STARTTLS: %s", strings.TrimRight(line, "\r\n"))
+		}
+		return stripCapabilityCode(greeting), nil
+	}
+}
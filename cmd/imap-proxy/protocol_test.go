@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// exchange 一次读取：方向和期望记录的文本、字面量
+type exchange struct {
+	dir, text string
+	literals  []int
+}
+
+// replay 按顺序从两个方向读取完整的命令和响应（与真实连接一样，一方的数据在收到对方的数据之后才读取），
+// 检查记录的文本以及转发的数据与原始数据相同
+func replay(t *testing.T, conn protocolConn, client, server string, script []exchange) {
+	t.Helper()
+	var toServer, toClient bytes.Buffer
+	readers := map[string]messageReader{
+		"C->S": conn.reader("C->S", strings.NewReader(client)),
+		"S->C": conn.reader("S->C", strings.NewReader(server)),
+	}
+	outputs := map[string]*bytes.Buffer{"C->S": &toServer, "S->C": &toClient}
+	for i, e := range script {
+		line, err := readers[e.dir].next(outputs[e.dir])
+		if err != nil {
+			t.Fatalf("第 %d 条（%s）: %v", i, e.dir, err)
+		}
+		if line.text != e.text || !slices.Equal(line.literals, e.literals) {
+			t.Errorf("第 %d 条（%s）= %q %v，期望 %q %v", i, e.dir, line.text, line.literals, e.text, e.literals)
+		}
+	}
+	if toServer.String() != client || toClient.String() != server {
+		t.Errorf("转发的数据与原始数据不同:\n%q\n%q", toServer.String(), toClient.String())
+	}
+}
+
+func TestSMTPSession(t *testing.T) {
+	body := "Subject: hi\r\n\r\nAUTH LOGIN\r\n..leading dot\r\n"
+	client := "EHLO client\r\n" +
+		"AUTH PLAIN AGFsaWNlAHNlY3JldA==\r\n" +
+		"AUTH LOGIN\r\nYWxpY2U=\r\nc2VjcmV0\r\n" +
+		"MAIL FROM:<alice@example.com>\r\n" +
+		"DATA\r\n" + body + ".\r\n" +
+		"BDAT 5 LAST\r\nhello" +
+		"QUIT\r\n"
+	server := "220 mx ready\r\n" +
+		"250-mx\r\n250 AUTH PLAIN LOGIN\r\n" +
+		"535 failed\r\n" +
+		"334 VXNlcm5hbWU6\r\n334 UGFzc3dvcmQ6\r\n235 ok\r\n" +
+		"250 ok\r\n" +
+		"354 go ahead\r\n250 queued\r\n" +
+		"250 chunk ok\r\n" +
+		"221 bye\r\n"
+
+	replay(t, &smtpConn{}, client, server, []exchange{
+		{"S->C", "220 mx ready", nil},
+		{"C->S", "EHLO client", nil},
+		{"S->C", "250-mx 250 AUTH PLAIN LOGIN", nil},
+		{"C->S", "AUTH PLAIN ***", nil},
+		{"S->C", "535 failed", nil},
+		{"C->S", "AUTH LOGIN", nil},
+		{"S->C", "334 VXNlcm5hbWU6", nil},
+		{"C->S", "***", nil},
+		{"S->C", "334 UGFzc3dvcmQ6", nil},
+		{"C->S", "***", nil},
+		{"S->C", "235 ok", nil},
+		{"C->S", "MAIL FROM:<alice@example.com>", nil},
+		{"S->C", "250 ok", nil},
+		{"C->S", "DATA", nil},
+		{"S->C", "354 go ahead", nil},
+		// 邮件内容中的 AUTH 不是命令
+		{"C->S", ".", []int{len(body)}},
+		{"S->C", "250 queued", nil},
+		{"C->S", "BDAT 5 LAST", []int{5}},
+		{"S->C", "250 chunk ok", nil},
+		{"C->S", "QUIT", nil},
+		{"S->C", "221 bye", nil},
+	})
+
+	conn := &smtpConn{}
+	for _, tt := range []struct {
+		dir, text string
+		command   bool
+		want      string
+	}{
+		{"C->S", "mail FROM:<a@example.com>", true, "MAIL"},
+		{"C->S", "***", false, ""},
+		{"C->S", ".", false, ""},
+		{"S->C", "250-mx.example.com", false, "250"},
+	} {
+		event := sessionEvent{Text: tt.text}
+		if got := conn.describe(tt.dir, &event); got != tt.command || event.Command+event.Response != tt.want {
+			t.Errorf("describe(%q) = %v %+v", tt.text, got, event)
+		}
+	}
+}
+
+func TestPOP3Session(t *testing.T) {
+	message := "Subject: hi\r\n\r\n..leading dot\r\n"
+	client := "USER alice\r\nPASS secret\r\n" +
+		"AUTH PLAIN\r\nAGFsaWNlAHNlY3JldA==\r\n" +
+		"APOP alice c4c9334bac560ecc979e58001b3e22fb\r\n" +
+		"CAPA\r\nLIST\r\nLIST 1\r\nRETR 1\r\nQUIT\r\n"
+	server := "+OK ready\r\n+OK\r\n-ERR invalid password\r\n" +
+		"+ \r\n+OK logged in\r\n" +
+		"-ERR already authenticated\r\n" +
+		"+OK capabilities\r\nUSER\r\nSASL PLAIN\r\n.\r\n" +
+		"+OK 1 messages\r\n1 120\r\n.\r\n" +
+		"+OK 1 120\r\n" +
+		"+OK 120 octets\r\n" + message + ".\r\n" +
+		"+OK bye\r\n"
+
+	replay(t, &pop3Conn{}, client, server, []exchange{
+		{"S->C", "+OK ready", nil},
+		{"C->S", "USER alice", nil},
+		{"S->C", "+OK", nil},
+		{"C->S", "PASS ***", nil},
+		{"S->C", "-ERR invalid password", nil},
+		{"C->S", "AUTH PLAIN", nil},
+		{"S->C", "+ ", nil},
+		{"C->S", "***", nil},
+		{"S->C", "+OK logged in", nil},
+		{"C->S", "APOP alice ***", nil},
+		{"S->C", "-ERR already authenticated", nil},
+		{"C->S", "CAPA", nil},
+		{"S->C", "+OK capabilities USER SASL PLAIN", nil},
+		{"C->S", "LIST", nil},
+		{"S->C", "+OK 1 messages 1 120", nil},
+		{"C->S", "LIST 1", nil},
+		{"S->C", "+OK 1 120", nil},
+		{"C->S", "RETR 1", nil},
+		{"S->C", "+OK 120 octets", []int{len(message)}},
+		{"C->S", "QUIT", nil},
+		{"S->C", "+OK bye", nil},
+	})
+}
+
+func TestProtocolStartTLS(t *testing.T) {
+	for _, tt := range []struct {
+		protocol, server, greeting, sent string
+		wantErr                          bool
+	}{
+		{"smtp", "220-mx\r\n220 ready\r\n250-mx\r\n250 STARTTLS\r\n220 go ahead\r\n", "220-mx\r\n220 ready\r\n", "EHLO *\r\nSTARTTLS\r\n", false},
+		{"smtp", "220 ready\r\n250 mx\r\n454 TLS not available\r\n", "", "EHLO *\r\nSTARTTLS\r\n", true},
+		{"pop3", "+OK ready <1.2@mx>\r\n+OK begin TLS\r\n", "+OK ready <1.2@mx>\r\n", "STLS\r\n", false},
+		{"pop3", "+OK ready\r\n-ERR not supported\r\n", "", "STLS\r\n", true},
+		{"imap", "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n" + starttlsTag + " OK begin\r\n", "* OK ready\r\n", starttlsTag + " STARTTLS\r\n", false},
+	} {
+		proto, err := lookupProtocol(tt.protocol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sent bytes.Buffer
+		greeting, err := proto.startTLS(bufio.NewReader(strings.NewReader(tt.server)), &sent)
+		if (err != nil) != tt.wantErr || greeting != tt.greeting {
+			t.Errorf("%s startTLS() = %q, %v", tt.protocol, greeting, err)
+		}
+		// EHLO 的主机名取决于运行环境
+		got := sent.String()
+		if strings.HasPrefix(got, "EHLO ") {
+			got = "EHLO *" + got[strings.Index(got, "\r\n"):]
+		}
+		if got != tt.sent {
+			t.Errorf("%s 发送 %q，期望 %q", tt.protocol, got, tt.sent)
+		}
+	}
+
+	if _, err := lookupProtocol("LMTP"); err == nil {
+		t.Error("不支持的协议应该返回错误")
+	}
+}
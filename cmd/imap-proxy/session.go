@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sessionIndexFile 会话目录中的索引文件（每个连接结束时追加一行）
+const sessionIndexFile = "index.jsonl"
+
+// sessionSeq 会话编号（同一毫秒内的多个连接使用不同的文件）
+var sessionSeq atomic.Uint64
+
+// sessionEvent 会话文件中的一条记录：一个完整的命令或响应（包括 IMAP 字面量、SMTP 和 POP3 的多行内容）
+type sessionEvent struct {
+	Seq       int       `json:"seq"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"dir"`                // C->S 或 S->C
+	Tag       string    `json:"tag,omitempty"`      // IMAP 命令标签，未标记的响应为 *，续行请求为 +
+	Command   string    `json:"command,omitempty"`  // 客户端命令（如 LOGIN、UID FETCH、MAIL、RETR）
+	Response  string    `json:"response,omitempty"` // 服务器响应（IMAP 的 OK/NO/BAD/BYE 或 EXISTS、FETCH 等，SMTP 响应码，POP3 的 +OK/-ERR）
+	Text      string    `json:"text"`               // 行文本（字面量和邮件内容不记录，只保留 {N} 标记；密码已隐藏）
+	Literals  []int     `json:"literals,omitempty"` // 字面量、SMTP 邮件内容（DATA、BDAT）和 POP3 邮件内容（RETR、TOP）的字节数
+	Bytes     int64     `json:"bytes"`              // 原始字节数（包括字面量和 CRLF）
+}
+
+// sessionIndexEntry 索引文件中的一条记录
+type sessionIndexEntry struct {
+	ID       string    `json:"id"`
+	File     string    `json:"file"`
+	Client   string    `json:"client"`
+	Target   string    `json:"target"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Commands int       `json:"commands"`  // 客户端命令数
+	BytesIn  int64     `json:"bytes_c2s"` // 客户端发送的字节数
+	BytesOut int64     `json:"bytes_s2c"` // 服务器发送的字节数
+	Error    string    `json:"error,omitempty"`
+}
+
+// sessionRecorder 把一个连接的双向数据按命令和响应写入 JSONL 会话文件
+type sessionRecorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	entry   sessionIndexEntry
+	seq     int
+	conn    protocolConn // 解析命令和响应
+}
+
+// newSessionRecorder 在会话目录中为连接创建会话文件
+func newSessionRecorder(dir, client, target string, conn protocolConn) (*sessionRecorder, error) {
+	start := time.Now()
+	id := fmt.Sprintf("%s-%d", start.Format("20060102-150405.000"), sessionSeq.Add(1))
+	name := "session-" + id + ".jsonl"
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600) // #nosec G304 -- 文件名由时间戳和编号生成
+	if err != nil {
+		return nil, fmt.Errorf("创建会话文件失败: %w", err)
+	}
+	return &sessionRecorder{
+		file:    file,
+		encoder: json.NewEncoder(file),
+		entry:   sessionIndexEntry{ID: id, File: name, Client: client, Target: target, Start: start},
+		conn:    conn,
+	}, nil
+}
+
+// record 记录一个命令或响应（text 中的密码和认证数据已经隐藏）
+func (r *sessionRecorder) record(direction, text string, literals []int, size int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	event := sessionEvent{Seq: r.seq, Time: time.Now(), Direction: direction, Text: text, Literals: literals, Bytes: size}
+	if direction == "C->S" {
+		r.entry.BytesIn += size
+	} else {
+		r.entry.BytesOut += size
+	}
+	if r.conn.describe(direction, &event) {
+		r.entry.Commands++
+	}
+	return r.encoder.Encode(&event)
+}
+
+// close 关闭会话文件，返回索引记录
+func (r *sessionRecorder) close(connErr error) sessionIndexEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entry.End = time.Now()
+	if connErr != nil {
+		r.entry.Error = connErr.Error()
+	}
+	_ = r.file.Close() // #nosec G104 -- 关闭失败不影响代理
+	return r.entry
+}
+
+// appendSessionIndex 向会话目录的索引文件追加一条记录
+func (p *Proxy) appendSessionIndex(entry sessionIndexEntry) error {
+	p.indexMu.Lock()
+	defer p.indexMu.Unlock()
+	file, err := os.OpenFile(filepath.Join(p.sessionDir, sessionIndexFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) // #nosec G304 -- 路径来自命令行参数
+	if err != nil {
+		return fmt.Errorf("打开会话索引失败: %w", err)
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(&entry)
+}
+
+// recordSession 双向转发连接数据并记录会话文件，连接关闭后追加索引。
+// clientReader 和 serverReader 从对应的连接读取（可能包含 STARTTLS 协商阶段缓冲的数据）
+func (p *Proxy) recordSession(connID, clientAddr string, conn protocolConn, clientConn, serverConn net.Conn, clientReader, serverReader io.Reader) {
+	rec, err := newSessionRecorder(p.sessionDir, clientAddr, p.targetAddr, conn)
+	if err != nil {
+		p.logger.Printf("%s %v", connID, err)
+		return
+	}
+	p.logger.Printf("%s 会话文件: %s", connID, rec.entry.File)
+
+	// 一个方向结束后关闭两个连接，使另一个方向的读取返回
+	var wg sync.WaitGroup
+	var once sync.Once
+	errs := make([]error, 2)
+	forward := func(i int, direction string, src io.Reader, dst net.Conn) {
+		defer wg.Done()
+		errs[i] = p.forwardSession(rec, direction, conn.reader(direction, src), dst)
+		once.Do(func() {
+			_ = clientConn.Close() // #nosec G104 -- 连接结束
+			_ = serverConn.Close() // #nosec G104 -- 连接结束
+		})
+	}
+	wg.Add(2)
+	go forward(0, "C->S", clientReader, serverConn)
+	go forward(1, "S->C", serverReader, clientConn)
+	wg.Wait()
+
+	entry := rec.close(errors.Join(errs[0], errs[1]))
+	if err := p.appendSessionIndex(entry); err != nil {
+		p.logger.Printf("%s %v", connID, err)
+	}
+	p.logger.Printf("%s 连接已关闭（%d 个命令）", connID, entry.Commands)
+}
+
+// forwardSession 转发数据并把每个完整的命令或响应记录到会话文件，返回读取或写入错误（连接正常关闭时为空）
+func (p *Proxy) forwardSession(rec *sessionRecorder, direction string, reader messageReader, dst net.Conn) error {
+	for {
+		line, err := reader.next(dst)
+		if line.size > 0 {
+			if recErr := rec.record(direction, line.text, line.literals, line.size); recErr != nil {
+				p.logger.Printf("%s 写入会话文件失败: %v", direction, recErr)
+			}
+		}
+		if err != nil {
+			// 对方关闭连接，或另一个方向结束后关闭了连接
+			if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// logicalLine 一个完整的命令或响应
+type logicalLine struct {
+	text     string // 去掉 CRLF 并隐藏认证数据的行文本，字面量内容不包括在内（各段之间以空格连接）
+	literals []int  // 字面量的字节数
+	size     int64  // 原始字节数
+}
+
+// forwardLine 读取一行并写入 dst，返回去掉 CRLF 的原始文本和用于记录的文本。
+// sanitize 在转发之前调用，使对方收到这一行之前协议状态（如认证过程）已经更新
+func forwardLine(r *bufio.Reader, dst io.Writer, line *logicalLine, sanitize func(string) string) (string, string, error) {
+	raw, err := r.ReadBytes('\n')
+	if len(raw) == 0 {
+		return "", "", err
+	}
+	line.size += int64(len(raw))
+	trimmed := string(bytes.TrimRight(raw, "\r\n"))
+	text := sanitize(trimmed)
+	if _, werr := dst.Write(raw); werr != nil {
+		return trimmed, text, werr
+	}
+	return trimmed, text, err
+}
+
+// literalReader 按 IMAP 的逻辑行读取：行尾为字面量标记（{N}、{N+}、{N-}、~{N}）时按字节数读取字面量，
+// 字面量之后的内容属于同一命令或响应，不按字面量中的换行拆分
+type literalReader struct {
+	r         *bufio.Reader
+	direction string
+	conn      protocolConn
+}
+
+func newLiteralReader(r io.Reader, direction string, conn protocolConn) *literalReader {
+	return &literalReader{r: bufio.NewReader(r), direction: direction, conn: conn}
+}
+
+// next 读取一个逻辑行，读到的原始数据立即写入 dst（同步字面量需要先转发行首，对方回复续行请求后才发送字面量）
+func (lr *literalReader) next(dst io.Writer) (logicalLine, error) {
+	var line logicalLine
+	var parts []string
+	sanitize := func(s string) string { return lr.conn.sanitize(lr.direction, s) }
+	for {
+		size := line.size
+		raw, text, err := forwardLine(lr.r, dst, &line, sanitize)
+		if line.size > size {
+			parts = append(parts, text)
+			line.text = strings.Join(parts, " ")
+		}
+		if err != nil {
+			return line, err
+		}
+
+		n, ok := literalSize([]byte(raw))
+		if !ok {
+			return line, nil
+		}
+		line.literals = append(line.literals, int(n))
+		copied, err := io.CopyN(dst, lr.r, n)
+		line.size += copied
+		if err != nil {
+			return line, err
+		}
+	}
+}
+
+// literalSize 解析行尾的字面量标记，返回字面量的字节数
+func literalSize(line []byte) (int64, bool) {
+	if len(line) < 3 || line[len(line)-1] != '}' {
+		return 0, false
+	}
+	open := bytes.LastIndexByte(line, '{')
+	if open < 0 {
+		return 0, false
+	}
+	digits := bytes.TrimRight(line[open+1:len(line)-1], "+-")
+	n, err := strconv.ParseInt(string(digits), 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLiteralReader(t *testing.T) {
+	body := "Subject: hi\r\n\r\nline 1\r\n{99}\r\n"
+	input := "* 1 FETCH (UID 7 BODY[] {" + strconv.Itoa(len(body)) + "}\r\n" + body + ")\r\n" +
+		"a1 OK FETCH completed\r\n"
+
+	var out bytes.Buffer
+	reader := newLiteralReader(strings.NewReader(input), "S->C", &imapConn{})
+	first, err := reader.next(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.text != "* 1 FETCH (UID 7 BODY[] {"+strconv.Itoa(len(body))+"} )" || !slices.Equal(first.literals, []int{len(body)}) {
+		t.Errorf("第一行 = %+v", first)
+	}
+	second, err := reader.next(&out)
+	if err != nil || second.text != "a1 OK FETCH completed" || second.literals != nil {
+		t.Errorf("第二行 = %+v, err = %v", second, err)
+	}
+	if out.String() != input {
+		t.Errorf("转发的数据与原始数据不同:\n%q", out.String())
+	}
+	if first.size+second.size != int64(len(input)) {
+		t.Errorf("字节数 = %d，期望 %d", first.size+second.size, len(input))
+	}
+}
+
+func TestSessionRecorder(t *testing.T) {
+	dir := t.TempDir()
+	conn := &imapConn{}
+	rec, err := newSessionRecorder(dir, "127.0.0.1:50000", "localhost:993", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct {
+		dir, text string
+		literals  []int
+	}{
+		{"S->C", "* OK IMAP4rev1 ready", nil},
+		{"C->S", "a1 LOGIN alice@example.com secret", nil},
+		{"C->S", "a2 LOGIN {17} {6}", []int{17, 6}},
+		{"C->S", "a3 AUTHENTICATE PLAIN", nil},
+		{"S->C", "+ ", nil},
+		{"C->S", "AGFsaWNlAHNlY3JldA==", nil},
+		{"S->C", "a3 OK authenticated", nil},
+		{"C->S", "a4 uid fetch 1:* (FLAGS)", nil},
+		{"S->C", "* 3 EXISTS", nil},
+	} {
+		if err := rec.record(e.dir, conn.sanitize(e.dir, e.text), e.literals, int64(len(e.text)+2)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry := rec.close(nil)
+
+	p := &Proxy{sessionDir: dir}
+	if err := p.appendSessionIndex(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, entry.File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "AGFsaWNl") {
+		t.Errorf("会话文件不应该包含密码:\n%s", data)
+	}
+	var events []sessionEvent
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var event sessionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 9 {
+		t.Fatalf("记录数 = %d，期望 9", len(events))
+	}
+	if e := events[7]; e.Tag != "a4" || e.Command != "UID FETCH" {
+		t.Errorf("UID 命令 = %+v", e)
+	}
+	if e := events[8]; e.Tag != "*" || e.Response != "EXISTS" {
+		t.Errorf("未标记响应 = %+v", e)
+	}
+	if e := events[2]; !slices.Equal(e.Literals, []int{17, 6}) {
+		t.Errorf("字面量 = %v", e.Literals)
+	}
+	if entry.Commands != 4 || entry.BytesIn == 0 || entry.BytesOut == 0 {
+		t.Errorf("索引记录 = %+v", entry)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, sessionIndexFile))
+	if err != nil || !strings.Contains(string(index), entry.File) {
+		t.Errorf("索引应该包含会话文件 %s: %s, err = %v", entry.File, index, err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// smtpConn SMTP 连接的状态
+type smtpConn struct {
+	mu sync.Mutex
+	// auth 进行中的 AUTH 命令：收到 334 以外的响应之前，客户端发送的行是认证数据
+	auth bool
+	// data 服务器已经用 354 接受 DATA：客户端之后发送的行是邮件内容，到单独一行 "." 为止
+	data bool
+}
+
+// sanitize 隐藏 AUTH 的初始响应和之后的认证数据（AUTH PLAIN/LOGIN 的 base64 凭据）
+func (c *smtpConn) sanitize(direction, line string) string {
+	if direction == "C->S" {
+		text, _ := c.clientLine(line)
+		return text
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.auth && !strings.HasPrefix(line, "334"):
+		c.auth = false
+	case strings.HasPrefix(line, "354"):
+		c.data = true
+	}
+	return line
+}
+
+// clientLine 处理客户端发送的一行，返回用于记录的文本和该行是否为邮件内容（包括结束的 "."）
+func (c *smtpConn) clientLine(line string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data {
+		if line == "." {
+			c.data = false
+		}
+		return line, true
+	}
+	if c.auth {
+		// SASL 认证数据（base64 编码的凭据）
+		return hiddenText, false
+	}
+	fields := strings.Fields(line)
+	if len(fields) >= 2 && strings.EqualFold(fields[0], "AUTH") {
+		c.auth = true
+		if len(fields) >= 3 {
+			return strings.Join(fields[:2], " ") + " " + hiddenText, false
+		}
+	}
+	return line, false
+}
+
+// describe 解析客户端命令（MAIL、RCPT 等第一个词）和服务器响应码
+func (c *smtpConn) describe(direction string, event *sessionEvent) bool {
+	fields := strings.Fields(event.Text)
+	if len(fields) == 0 {
+		return false
+	}
+	if direction == "C->S" {
+		// 认证数据和邮件内容不是命令
+		if event.Text == hiddenText || event.Text == "." {
+			return false
+		}
+		event.Command = strings.ToUpper(fields[0])
+		return true
+	}
+	if len(fields[0]) >= 3 {
+		event.Response = fields[0][:3]
+	}
+	return false
+}
+
+// reader 按完整的命令、多行响应和邮件内容读取
+func (c *smtpConn) reader(direction string, src io.Reader) messageReader {
+	return &smtpReader{r: bufio.NewReader(src), direction: direction, conn: c}
+}
+
+// smtpReader 会话文件模式下读取 SMTP 命令和响应
+type smtpReader struct {
+	r         *bufio.Reader
+	direction string
+	conn      *smtpConn
+}
+
+func (sr *smtpReader) next(dst io.Writer) (logicalLine, error) {
+	if sr.direction == "C->S" {
+		return sr.nextCommand(dst)
+	}
+	return sr.nextReply(dst)
+}
+
+// nextCommand 读取一个客户端命令。服务器接受 DATA 之后，到单独一行 "." 为止的邮件内容作为一条记录，
+// 内容不记录，只记录字节数；BDAT 命令之后的数据块按字节数读取
+func (sr *smtpReader) nextCommand(dst io.Writer) (logicalLine, error) {
+	var line logicalLine
+	var body bool
+	sanitize := func(s string) string {
+		text, isBody := sr.conn.clientLine(s)
+		body = isBody
+		return text
+	}
+	_, text, err := forwardLine(sr.r, dst, &line, sanitize)
+	if !body {
+		line.text = text
+		if err != nil {
+			return line, err
+		}
+		if n, ok := bdatSize(text); ok {
+			line.literals = []int{int(n)}
+			copied, err := io.CopyN(dst, sr.r, n)
+			line.size += copied
+			return line, err
+		}
+		return line, nil
+	}
+
+	content := int64(0)
+	for err == nil && text != "." {
+		content = line.size
+		_, text, err = forwardLine(sr.r, dst, &line, sanitize)
+	}
+	if text != "." {
+		content = line.size
+	}
+	line.text = "."
+	line.literals = []int{int(content)}
+	return line, err
+}
+
+// nextReply 读取一个完整的响应（多行响应除最后一行外以 "250-" 形式的响应码开头）
+func (sr *smtpReader) nextReply(dst io.Writer) (logicalLine, error) {
+	var line logicalLine
+	var parts []string
+	sanitize := func(s string) string { return sr.conn.sanitize(sr.direction, s) }
+	for {
+		size := line.size
+		_, text, err := forwardLine(sr.r, dst, &line, sanitize)
+		if line.size > size {
+			parts = append(parts, text)
+			line.text = strings.Join(parts, " ")
+		}
+		if err != nil || len(text) < 4 || text[3] != '-' {
+			return line, err
+		}
+	}
+}
+
+// bdatSize 解析 BDAT 命令的数据块字节数
+func bdatSize(command string) (int64, bool) {
+	fields := strings.Fields(command)
+	if len(fields) < 2 || !strings.EqualFold(fields[0], "BDAT") {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// smtpStartTLS 读取 SMTP 问候，发送 EHLO 和 STARTTLS。
+// 服务器不会在 TLS 之后重新发送问候，原问候转发给客户端；客户端之后的 EHLO 由 TLS 连接上的服务器回复
+func smtpStartTLS(r *bufio.Reader, w io.Writer) (string, error) {
+	greeting, err := readSMTPReply(r)
+	if err != nil {
+		return "", fmt.Errorf("读取服务器问候失败: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "220") {
+		return "", fmt.Errorf("服务器问候异常: %s", strings.TrimRight(greeting, "\r\n"))
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "localhost"
+	}
+	if _, err := fmt.Fprintf(w, "EHLO %s\r\n", hostname); err != nil {
+		return "", fmt.Errorf("发送 EHLO 失败: %w", err)
+	}
+	reply, err := readSMTPReply(r)
+	if err != nil {
+		return "", fmt.Errorf("读取 EHLO 响应失败: %w", err)
+	}
+	if !strings.HasPrefix(reply, "250") {
+		return "", fmt.Errorf("服务器拒绝 EHLO: %s", strings.TrimRight(reply, "\r\n"))
+	}
+
+	if _, err := io.WriteString(w, "STARTTLS\r\n"); err != nil {
+		return "", fmt.Errorf("发送 STARTTLS 失败: %w", err)
+	}
+	if reply, err = readSMTPReply(r); err != nil {
+		return "", fmt.Errorf("读取 STARTTLS 响应失败: %w", err)
+	}
+	if !strings.HasPrefix(reply, "220") {
+		return "", fmt.Errorf("服务器拒绝 STARTTLS: %s", strings.TrimRight(reply, "\r\n"))
+	}
+	return greeting, nil
+}
+
+// readSMTPReply 读取一个完整的（可能多行的）SMTP 响应
+func readSMTPReply(r *bufio.Reader) (string, error) {
+	var reply strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		reply.WriteString(line)
+		if len(line) < 4 || line[3] != '-' {
+			return reply.String(), nil
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// starttlsTag 代理向目标服务器发送 STARTTLS 和 CAPABILITY 时使用的标签
+	starttlsTag = "gmzproxy"
+	// negotiateTimeout STARTTLS 协商（问候、命令和 TLS 握手）的超时时间
+	negotiateTimeout = 10 * time.Second
+	// tlsSniffTimeout 双模式监听时等待客户端发送 TLS ClientHello 的时间，超时后作为明文连接
+	tlsSniffTimeout = 300 * time.Millisecond
+)
+
+// prefixConn 先返回已经读取的数据再从连接读取（双模式监听时用于放回探测的首字节）
+type prefixConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// sniffClientTLS 双模式监听：客户端连接后立即发送 TLS 握手记录（首字节 0x16）时作为隐式 TLS 连接；
+// 在 tlsSniffTimeout 内没有发送数据时作为明文连接（明文客户端等待服务器问候，之后可以使用 STARTTLS）。
+// 返回处理后的连接和客户端是否使用明文
+func (p *Proxy) sniffClientTLS(conn net.Conn) (net.Conn, bool, error) {
+	first := make([]byte, 1)
+	if err := conn.SetReadDeadline(time.Now().Add(tlsSniffTimeout)); err != nil {
+		return nil, false, err
+	}
+	n, err := conn.Read(first)
+	if resetErr := conn.SetReadDeadline(time.Time{}); resetErr != nil {
+		return nil, false, resetErr
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return conn, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	peeked := &prefixConn{Conn: conn, r: io.MultiReader(bytes.NewReader(first[:n]), conn)}
+	if first[0] == 0x16 {
+		return tls.Server(peeked, p.clientTLSConfig), false, nil
+	}
+	return peeked, true, nil
+}
+
+// upstreamStartTLS 以明文连接目标服务器后按协议执行 STARTTLS 并完成 TLS 握手，返回由代理转发给客户端的问候
+func (p *Proxy) upstreamStartTLS(conn net.Conn) (net.Conn, string, error) {
+	if err := conn.SetDeadline(time.Now().Add(negotiateTimeout)); err != nil {
+		return nil, "", err
+	}
+	reader := bufio.NewReader(conn)
+	greeting, err := p.protocol.startTLS(reader, conn)
+	if err != nil {
+		return nil, "", err
+	}
+	// TLS 握手之前收到的数据可能是中间人注入的明文，不能当作加密后的数据
+	if reader.Buffered() > 0 {
+		return nil, "", fmt.Errorf("STARTTLS 响应之后收到了多余的明文数据")
+	}
+
+	host, _, err := net.SplitHostPort(p.targetAddr)
+	if err != nil {
+		host = p.targetAddr
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: p.insecureTLS, // #nosec G402 -- 允许用户配置跳过验证（用于测试环境）
+		MinVersion:         tls.VersionTLS12,
+	})
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, "", fmt.Errorf("TLS 握手失败: %w", err)
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, "", err
+	}
+	return tlsConn, greeting, nil
+}
+
+// negotiateClientTLS 客户端使用明文连接时，在开始转发之前由代理处理问候、CAPABILITY 和 STARTTLS：
+// 问候和 CAPABILITY 响应中的能力列表加入 STARTTLS；客户端执行 STARTTLS 时由代理完成 TLS 握手。
+// 客户端发送其他命令（如明文 LOGIN）时把该命令转发给服务器，之后按普通连接透传。
+// 返回客户端连接（STARTTLS 之后为 TLS 连接）和对应的读取器
+func (p *Proxy) negotiateClientTLS(connID string, client net.Conn, server net.Conn, serverReader *bufio.Reader, greeting string) (net.Conn, *bufio.Reader, error) {
+	clientReader := bufio.NewReader(client)
+	if _, err := io.WriteString(client, injectStartTLS(greeting)); err != nil {
+		return nil, nil, err
+	}
+	for {
+		line, err := clientReader.ReadString('\n')
+		if err != nil {
+			return nil, nil, err
+		}
+		fields := strings.Fields(line)
+		command := ""
+		if len(fields) == 2 {
+			command = strings.ToUpper(fields[1])
+		}
+
+		switch command {
+		case "CAPABILITY":
+			if err := p.relayCapability(client, server, serverReader, fields[0]); err != nil {
+				return nil, nil, err
+			}
+		case "STARTTLS":
+			// 与服务器端相同：STARTTLS 之后、握手之前的数据不能被当作加密后的命令
+			if clientReader.Buffered() > 0 {
+				return nil, nil, fmt.Errorf("客户端在 STARTTLS 之后发送了多余的明文数据")
+			}
+			if _, err := io.WriteString(client, fields[0]+" OK Begin TLS negotiation now\r\n"); err != nil {
+				return nil, nil, err
+			}
+			tlsConn := tls.Server(client, p.clientTLSConfig)
+			if err := client.SetDeadline(time.Now().Add(negotiateTimeout)); err != nil {
+				return nil, nil, err
+			}
+			if err := tlsConn.Handshake(); err != nil {
+				return nil, nil, fmt.Errorf("客户端 TLS 握手失败: %w", err)
+			}
+			if err := client.SetDeadline(time.Time{}); err != nil {
+				return nil, nil, err
+			}
+			p.logger.Printf("%s 客户端 STARTTLS 完成", connID)
+			return tlsConn, bufio.NewReader(tlsConn), nil
+		default:
+			p.logger.Printf("%s 客户端未使用 STARTTLS，按明文连接转发", connID)
+			if _, err := io.WriteString(server, line); err != nil {
+				return nil, nil, err
+			}
+			// 转发的命令可能以字面量结尾，字面量由之后的透传从同一个读取器继续读取
+			return client, clientReader, nil
+		}
+	}
+}
+
+// relayCapability 向服务器查询 CAPABILITY，把响应转发给客户端（能力列表中加入 STARTTLS）
+func (p *Proxy) relayCapability(client io.Writer, server io.Writer, serverReader *bufio.Reader, tag string) error {
+	if _, err := io.WriteString(server, starttlsTag+" CAPABILITY\r\n"); err != nil {
+		return err
+	}
+	for {
+		line, err := serverReader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, starttlsTag+" ") {
+			line = tag + line[len(starttlsTag):]
+		}
+		if _, err := io.WriteString(client, injectStartTLS(line)); err != nil {
+			return err
+		}
+		if strings.HasPrefix(line, tag+" ") {
+			return nil
+		}
+	}
+}
+
+// injectStartTLS 在能力列表（* CAPABILITY 响应或 [CAPABILITY ...] 响应码）中加入 STARTTLS
+func injectStartTLS(line string) string {
+	upper := strings.ToUpper(line)
+	if strings.Contains(upper, " STARTTLS") {
+		return line
+	}
+	if strings.HasPrefix(upper, "* CAPABILITY ") {
+		body := strings.TrimRight(line, "\r\n")
+		return body + " STARTTLS" + line[len(body):]
+	}
+	start := strings.Index(upper, "[CAPABILITY ")
+	if start < 0 {
+		return line
+	}
+	end := strings.IndexByte(line[start:], ']')
+	if end < 0 {
+		return line
+	}
+	end += start
+	return line[:end] + " STARTTLS" + line[end:]
+}
+
+// stripCapabilityCode 去掉问候中的 [CAPABILITY ...] 响应码
+func stripCapabilityCode(greeting string) string {
+	start := strings.Index(strings.ToUpper(greeting), "[CAPABILITY ")
+	if start < 0 {
+		return greeting
+	}
+	end := strings.IndexByte(greeting[start:], ']')
+	if end < 0 {
+		return greeting
+	}
+	rest := strings.TrimLeft(greeting[start+end+1:], " ")
+	return greeting[:start] + rest
+}
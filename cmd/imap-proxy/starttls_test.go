@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestCapabilityRewrite(t *testing.T) {
+	for _, tt := range []struct {
+		line, injected, stripped string
+	}{
+		{"* CAPABILITY IMAP4rev1 AUTH=PLAIN\r\n", "* CAPABILITY IMAP4rev1 AUTH=PLAIN STARTTLS\r\n", "* CAPABILITY IMAP4rev1 AUTH=PLAIN\r\n"},
+		{"* OK [CAPABILITY IMAP4rev1 IDLE] ready\r\n", "* OK [CAPABILITY IMAP4rev1 IDLE STARTTLS] ready\r\n", "* OK ready\r\n"},
+		{"* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n", "* OK [CAPABILITY IMAP4rev1 STARTTLS] ready\r\n", "* OK ready\r\n"},
+		{"a1 OK done\r\n", "a1 OK done\r\n", "a1 OK done\r\n"},
+	} {
+		if got := injectStartTLS(tt.line); got != tt.injected {
+			t.Errorf("injectStartTLS(%q) = %q，期望 %q", tt.line, got, tt.injected)
+		}
+		if got := stripCapabilityCode(tt.line); got != tt.stripped {
+			t.Errorf("stripCapabilityCode(%q) = %q，期望 %q", tt.line, got, tt.stripped)
+		}
+	}
+}
+
+// startTLSServer 只支持 143+STARTTLS 的 IMAP 服务器：STARTTLS 之后回复 CAPABILITY 和其他命令
+func startTLSServer(t *testing.T, cert tls.Certificate) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.WriteString(conn, "* OK [CAPABILITY IMAP4rev1 STARTTLS LOGINDISABLED] ready\r\n")
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil || !strings.HasSuffix(line, " STARTTLS\r\n") {
+					return
+				}
+				tag := strings.Fields(line)[0]
+				_, _ = io.WriteString(conn, tag+" OK begin TLS\r\n")
+				tlsConn := tls.Server(conn, &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+				reader := bufio.NewReader(tlsConn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					if len(fields) < 2 {
+						continue
+					}
+					if strings.EqualFold(fields[1], "CAPABILITY") {
+						_, _ = io.WriteString(tlsConn, "* CAPABILITY IMAP4rev1 AUTH=PLAIN\r\n")
+					}
+					_, _ = io.WriteString(tlsConn, fields[0]+" OK "+fields[1]+" done\r\n")
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestStartTLSProxy(t *testing.T) {
+	cert, err := generateSelfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Proxy{
+		protocol:        protocols["imap"],
+		targetAddr:      startTLSServer(t, cert),
+		startTLS:        true,
+		clientTLS:       true,
+		clientStartTLS:  true,
+		clientTLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		insecureTLS:     true,
+		logger:          log.New(io.Discard, "", 0),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.handleConnection(conn)
+		}
+	}()
+
+	clientTLS := &tls.Config{InsecureSkipVerify: true} // #nosec G402 -- 测试使用自签名证书
+	expect := func(r *bufio.Reader, want string) {
+		t.Helper()
+		line, err := r.ReadString('\n')
+		if err != nil || line != want {
+			t.Fatalf("收到 %q (err = %v)，期望 %q", line, err, want)
+		}
+	}
+
+	t.Run("明文客户端 STARTTLS", func(t *testing.T) {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		// 明文阶段的能力列表不转发给客户端
+		expect(reader, "* OK ready\r\n")
+		_, _ = io.WriteString(conn, "a1 CAPABILITY\r\n")
+		expect(reader, "* CAPABILITY IMAP4rev1 AUTH=PLAIN STARTTLS\r\n")
+		expect(reader, "a1 OK CAPABILITY done\r\n")
+		_, _ = io.WriteString(conn, "a2 STARTTLS\r\n")
+		expect(reader, "a2 OK Begin TLS negotiation now\r\n")
+
+		tlsConn := tls.Client(conn, clientTLS)
+		reader = bufio.NewReader(tlsConn)
+		_, _ = io.WriteString(tlsConn, "a3 CAPABILITY\r\n")
+		expect(reader, "* CAPABILITY IMAP4rev1 AUTH=PLAIN\r\n")
+		expect(reader, "a3 OK CAPABILITY done\r\n")
+	})
+
+	t.Run("隐式 TLS 客户端", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", listener.Addr().String(), clientTLS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		expect(reader, "* OK ready\r\n")
+		_, _ = io.WriteString(conn, "a1 NOOP\r\n")
+		expect(reader, "a1 OK NOOP done\r\n")
+	})
+}
@@ -1,9 +1,11 @@
 package acme
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,8 +23,9 @@ type Manager struct {
 }
 
 // NewManager 创建证书管理器
-func NewManager(cfg *config.ACMEConfig) (*Manager, error) {
-	client, err := NewClient(cfg)
+// store 为空时使用证书目录存储
+func NewManager(cfg *config.ACMEConfig, store Store) (*Manager, error) {
+	client, err := NewClient(cfg, store)
 	if err != nil {
 		return nil, fmt.Errorf("创建 ACME 客户端失败: %w", err)
 	}
@@ -44,16 +47,7 @@ func NewManager(cfg *config.ACMEConfig) (*Manager, error) {
 // Start 启动证书管理器（自动续期）
 func (m *Manager) Start(ctx context.Context, domains []string) error {
 	// 初始获取证书
-	for _, domain := range domains {
-		cert, err := m.client.RenewCertificate(ctx, domain)
-		if err != nil {
-			logger.Warn().Err(err).Str("domain", domain).Msg("获取证书失败")
-			continue
-		}
-		m.mu.Lock()
-		m.certificates[domain] = cert
-		m.mu.Unlock()
-	}
+	m.renewAll(ctx, domains)
 
 	// 启动自动续期协程
 	go m.autoRenew(ctx, domains)
@@ -67,25 +61,35 @@ func (m *Manager) Stop() {
 }
 
 // GetCertificate 获取证书（实现 tls.Config.GetCertificate）
+// CA 的 TLS-ALPN-01 验证连接返回验证证书；其他连接返回已签发的证书（支持通配符），
+// 续期后新证书立即对新连接生效，无需重启 SMTP/IMAP/HTTP 监听
 func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
-	domain := hello.ServerName
+	if isChallengeHello(hello) {
+		return m.client.ChallengeCertificate(hello)
+	}
+
+	domain := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
 	if domain == "" {
 		return nil, fmt.Errorf("未指定服务器名称")
 	}
 
 	m.mu.RLock()
 	cert, ok := m.certificates[domain]
+	if !ok {
+		if i := strings.IndexByte(domain, '.'); i > 0 {
+			cert, ok = m.certificates["*"+domain[i:]]
+		}
+	}
 	m.mu.RUnlock()
 
 	if ok && cert != nil {
 		return cert, nil
 	}
 
-	// 证书不存在，尝试获取
-	ctx := context.Background()
-	newCert, err := m.client.RenewCertificate(ctx, domain)
+	// 尚未缓存时从存储加载（其他节点可能已签发），申请新证书由续期协程负责，不阻塞握手
+	newCert, err := m.client.GetCertificate(domain)
 	if err != nil {
-		return nil, fmt.Errorf("获取证书失败: %w", err)
+		return nil, fmt.Errorf("证书尚未签发: %w", err)
 	}
 
 	m.mu.Lock()
@@ -95,27 +99,19 @@ func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate,
 	return newCert, nil
 }
 
-// autoRenew 自动续期证书
+// autoRenew 定期检查并续期证书（签发失败的证书也在下次检查时重试）
 func (m *Manager) autoRenew(ctx context.Context, domains []string) {
-	ticker := time.NewTicker(24 * time.Hour) // 每天检查一次
+	interval := m.config.CheckInterval
+	if interval <= 0 {
+		interval = 12 * time.Hour
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			for _, domain := range domains {
-				cert, err := m.client.RenewCertificate(ctx, domain)
-				if err != nil {
-					logger.Error().Err(err).Str("domain", domain).Msg("续期证书失败")
-					continue
-				}
-
-				m.mu.Lock()
-				m.certificates[domain] = cert
-				m.mu.Unlock()
-
-				logger.Info().Str("domain", domain).Msg("证书续期成功")
-			}
+			m.renewAll(ctx, domains)
 		case <-m.stopCh:
 			return
 		case <-ctx.Done():
@@ -124,6 +120,34 @@ func (m *Manager) autoRenew(ctx context.Context, domains []string) {
 	}
 }
 
+// renewAll 检查所有域名的证书，即将过期或尚未签发时申请新证书，并替换正在使用的证书
+func (m *Manager) renewAll(ctx context.Context, domains []string) {
+	for _, domain := range domains {
+		cert, err := m.client.RenewCertificate(ctx, domain)
+		if err != nil {
+			logger.Error().Err(err).Str("domain", domain).Msg("续期证书失败")
+			continue
+		}
+
+		m.mu.Lock()
+		changed := m.certificates[domain] == nil || !sameCertificate(m.certificates[domain], cert)
+		m.certificates[domain] = cert
+		m.mu.Unlock()
+
+		if changed {
+			logger.Info().Str("domain", domain).Msg("证书已更新")
+		}
+	}
+}
+
+// sameCertificate 两个证书的叶子证书是否相同
+func sameCertificate(a, b *tls.Certificate) bool {
+	if len(a.Certificate) == 0 || len(b.Certificate) == 0 {
+		return false
+	}
+	return bytes.Equal(a.Certificate[0], b.Certificate[0])
+}
+
 // ReloadCertificate 重新加载证书（热更新）
 func (m *Manager) ReloadCertificate(domain string) error {
 	ctx := context.Background()
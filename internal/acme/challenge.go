@@ -0,0 +1,254 @@
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"golang.org/x/crypto/acme"
+)
+
+// solver 完成一种类型的 ACME 挑战
+type solver interface {
+	// Type 挑战类型（http-01、tls-alpn-01、dns-01）
+	Type() string
+	// Present 发布挑战响应，之后 CA 可以开始验证
+	Present(ctx context.Context, domain string, chal *acme.Challenge) error
+	// CleanUp 验证结束后撤销挑战响应
+	CleanUp(ctx context.Context, domain string, chal *acme.Challenge) error
+}
+
+// isChallengeHello 是否为 CA 发起的 TLS-ALPN-01 验证连接
+func isChallengeHello(hello *tls.ClientHelloInfo) bool {
+	return slices.Contains(hello.SupportedProtos, acme.ALPNProto)
+}
+
+// http01Solver 在验证期间临时监听 HTTP 端口，响应 /.well-known/acme-challenge/<token>
+type http01Solver struct {
+	client *acme.Client
+	addr   string
+
+	mu     sync.Mutex
+	tokens map[string]string // 路径 -> 响应内容
+	server *http.Server
+}
+
+func newHTTP01Solver(client *acme.Client, addr string) *http01Solver {
+	if addr == "" {
+		addr = ":80"
+	}
+	return &http01Solver{client: client, addr: addr, tokens: make(map[string]string)}
+}
+
+func (s *http01Solver) Type() string { return "http-01" }
+
+func (s *http01Solver) Present(ctx context.Context, domain string, chal *acme.Challenge) error {
+	response, err := s.client.HTTP01ChallengeResponse(chal.Token)
+	if err != nil {
+		return fmt.Errorf("生成 HTTP-01 响应失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[s.client.HTTP01ChallengePath(chal.Token)] = response
+	if s.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		delete(s.tokens, s.client.HTTP01ChallengePath(chal.Token))
+		return fmt.Errorf("监听 HTTP-01 验证端口 %s 失败（端口被占用时可改用 tls-alpn-01 或 dns-01）: %w", s.addr, err)
+	}
+	s.server = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Warn().Err(err).Str("addr", s.addr).Msg("HTTP-01 验证服务异常退出")
+		}
+	}(s.server)
+	return nil
+}
+
+func (s *http01Solver) CleanUp(ctx context.Context, domain string, chal *acme.Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, s.client.HTTP01ChallengePath(chal.Token))
+	if len(s.tokens) > 0 || s.server == nil {
+		return nil
+	}
+	err := s.server.Close()
+	s.server = nil
+	return err
+}
+
+// ServeHTTP 响应 CA 的 HTTP-01 验证请求
+func (s *http01Solver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	response, ok := s.tokens[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = w.Write([]byte(response))
+}
+
+// tlsALPN01Solver 提供 TLS-ALPN-01 验证证书
+//
+// 验证期间尝试临时监听 addr（默认 :443）；端口已被 gmz 的 HTTPS 监听占用时，
+// 由该监听通过证书存储的 GetConfigForClient 调用 ChallengeCertificate 响应验证。
+type tlsALPN01Solver struct {
+	client *acme.Client
+	addr   string
+
+	mu       sync.Mutex
+	certs    map[string]*tls.Certificate // 域名 -> 验证证书
+	listener net.Listener
+}
+
+func newTLSALPN01Solver(client *acme.Client, addr string) *tlsALPN01Solver {
+	if addr == "" {
+		addr = ":443"
+	}
+	return &tlsALPN01Solver{client: client, addr: addr, certs: make(map[string]*tls.Certificate)}
+}
+
+func (s *tlsALPN01Solver) Type() string { return "tls-alpn-01" }
+
+func (s *tlsALPN01Solver) Present(ctx context.Context, domain string, chal *acme.Challenge) error {
+	cert, err := s.client.TLSALPN01ChallengeCert(chal.Token, domain)
+	if err != nil {
+		return fmt.Errorf("生成 TLS-ALPN-01 验证证书失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs[domain] = &cert
+	if s.listener != nil {
+		return nil
+	}
+
+	listener, err := tls.Listen("tcp", s.addr, &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{acme.ALPNProto},
+		GetCertificate: s.ChallengeCertificate,
+	})
+	if err != nil {
+		logger.Info().Err(err).Str("addr", s.addr).Msg("无法监听 TLS-ALPN-01 验证端口，由已有的 TLS 监听响应验证")
+		return nil
+	}
+	s.listener = listener
+	go serveChallengeConns(listener)
+	return nil
+}
+
+func (s *tlsALPN01Solver) CleanUp(ctx context.Context, domain string, chal *acme.Challenge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.certs, domain)
+	if len(s.certs) > 0 || s.listener == nil {
+		return nil
+	}
+	err := s.listener.Close()
+	s.listener = nil
+	return err
+}
+
+// ChallengeCertificate 返回 CA 验证连接所请求域名的验证证书
+func (s *tlsALPN01Solver) ChallengeCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	cert, ok := s.certs[strings.ToLower(hello.ServerName)]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("没有 %q 的 TLS-ALPN-01 验证证书", hello.ServerName)
+	}
+	return cert, nil
+}
+
+// serveChallengeConns 完成验证连接的 TLS 握手后关闭连接（CA 只检查握手中的证书）
+func serveChallengeConns(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+			_ = conn.(*tls.Conn).Handshake()
+		}()
+	}
+}
+
+// dns01Solver 通过 DNS 提供商发布 _acme-challenge TXT 记录
+type dns01Solver struct {
+	client   *acme.Client
+	provider DNSProvider
+	timeout  time.Duration // 等待记录生效的最长时间
+	resolver *net.Resolver
+}
+
+func (s *dns01Solver) Type() string { return "dns-01" }
+
+func (s *dns01Solver) Present(ctx context.Context, domain string, chal *acme.Challenge) error {
+	value, err := s.client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return fmt.Errorf("生成 DNS-01 记录失败: %w", err)
+	}
+	fqdn := challengeFQDN(domain)
+	if err := s.provider.Present(ctx, fqdn, value); err != nil {
+		return fmt.Errorf("创建 TXT 记录 %s 失败: %w", fqdn, err)
+	}
+	s.waitPropagation(ctx, fqdn, value)
+	return nil
+}
+
+func (s *dns01Solver) CleanUp(ctx context.Context, domain string, chal *acme.Challenge) error {
+	value, err := s.client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return err
+	}
+	return s.provider.CleanUp(ctx, challengeFQDN(domain), value)
+}
+
+// waitPropagation 等待 TXT 记录可以被解析到，超时后仍继续（由 CA 的验证结果决定成败）
+func (s *dns01Solver) waitPropagation(ctx context.Context, fqdn, value string) {
+	if s.timeout <= 0 {
+		return
+	}
+	resolver := s.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	for {
+		records, _ := resolver.LookupTXT(ctx, fqdn)
+		if slices.Contains(records, value) {
+			return
+		}
+		if time.Now().After(deadline) {
+			logger.Warn().Str("fqdn", fqdn).Dur("timeout", s.timeout).Msg("等待 TXT 记录生效超时，继续验证")
+			return
+		}
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// challengeFQDN DNS-01 验证记录的完整域名（通配符证书使用基础域名）
+func challengeFQDN(domain string) string {
+	return "_acme-challenge." + strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".") + "."
+}
@@ -0,0 +1,262 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"golang.org/x/crypto/acme"
+)
+
+func newTestACMEClient(t *testing.T) *acme.Client {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &acme.Client{Key: key}
+}
+
+func TestHTTP01Solver(t *testing.T) {
+	client := newTestACMEClient(t)
+	solver := newHTTP01Solver(client, "127.0.0.1:0")
+	chal := &acme.Challenge{Type: "http-01", Token: "token123"}
+	if err := solver.Present(context.Background(), "mail.example.com", chal); err != nil {
+		t.Fatalf("Present() 失败: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	solver.ServeHTTP(w, httptest.NewRequest(http.MethodGet, client.HTTP01ChallengePath("token123"), nil))
+	want, _ := client.HTTP01ChallengeResponse("token123")
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Errorf("验证响应 = %d %q, want %q", w.Code, w.Body.String(), want)
+	}
+
+	if err := solver.CleanUp(context.Background(), "mail.example.com", chal); err != nil {
+		t.Fatalf("CleanUp() 失败: %v", err)
+	}
+	w = httptest.NewRecorder()
+	solver.ServeHTTP(w, httptest.NewRequest(http.MethodGet, client.HTTP01ChallengePath("token123"), nil))
+	if w.Code != http.StatusNotFound || solver.server != nil {
+		t.Errorf("清理后应该停止响应: %d", w.Code)
+	}
+}
+
+func TestTLSALPN01Solver(t *testing.T) {
+	client := newTestACMEClient(t)
+	solver := newTLSALPN01Solver(client, "127.0.0.1:0")
+	chal := &acme.Challenge{Type: "tls-alpn-01", Token: "token123"}
+	if err := solver.Present(context.Background(), "mail.example.com", chal); err != nil {
+		t.Fatalf("Present() 失败: %v", err)
+	}
+	defer solver.CleanUp(context.Background(), "mail.example.com", chal)
+
+	// 模拟 CA 的验证连接
+	conn, err := tls.Dial("tcp", solver.listener.Addr().String(), &tls.Config{
+		ServerName:         "mail.example.com",
+		NextProtos:         []string{acme.ALPNProto},
+		InsecureSkipVerify: true, // #nosec G402 -- 验证证书是自签名的
+	})
+	if err != nil {
+		t.Fatalf("验证连接握手失败: %v", err)
+	}
+	state := conn.ConnectionState()
+	conn.Close()
+	if state.NegotiatedProtocol != acme.ALPNProto {
+		t.Errorf("协商的协议 = %q", state.NegotiatedProtocol)
+	}
+	if names := state.PeerCertificates[0].DNSNames; len(names) != 1 || names[0] != "mail.example.com" {
+		t.Errorf("验证证书的域名 = %v", names)
+	}
+
+	// 管理器对验证连接返回验证证书
+	m := &Manager{client: &Client{solver: solver}, certificates: map[string]*tls.Certificate{}}
+	hello := &tls.ClientHelloInfo{ServerName: "mail.example.com", SupportedProtos: []string{acme.ALPNProto}}
+	if cert, err := m.GetCertificate(hello); err != nil || cert != solver.certs["mail.example.com"] {
+		t.Errorf("GetCertificate() = %v, %v", cert, err)
+	}
+}
+
+func TestManagerGetCertificateWildcard(t *testing.T) {
+	wildcard := &tls.Certificate{Certificate: [][]byte{{1}}}
+	m := &Manager{certificates: map[string]*tls.Certificate{"*.example.com": wildcard}}
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "Mail.Example.com."})
+	if err != nil || cert != wildcard {
+		t.Errorf("GetCertificate() = %v, %v", cert, err)
+	}
+}
+
+func TestChallengeFQDN(t *testing.T) {
+	for domain, want := range map[string]string{
+		"mail.example.com":  "_acme-challenge.mail.example.com.",
+		"*.example.com":     "_acme-challenge.example.com.",
+		"mail.example.com.": "_acme-challenge.mail.example.com.",
+	} {
+		if got := challengeFQDN(domain); got != want {
+			t.Errorf("challengeFQDN(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestCloudflareProvider(t *testing.T) {
+	var created, deleted string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"success":false,"errors":[{"message":"Invalid token"}]}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones":
+			// 只有 example.com 是区域
+			if r.URL.Query().Get("name") == "example.com" {
+				_, _ = io.WriteString(w, `{"success":true,"result":[{"id":"zone1"}]}`)
+			} else {
+				_, _ = io.WriteString(w, `{"success":true,"result":[]}`)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/zone1/dns_records":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			created = body["name"].(string) + "=" + body["content"].(string)
+			_, _ = io.WriteString(w, `{"success":true,"result":{"id":"rec1"}}`)
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			_, _ = io.WriteString(w, `{"success":true,"result":{"id":"rec1"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"success":false,"errors":[{"message":"not found"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	p := NewCloudflareProvider("test-token")
+	p.baseURL = server.URL
+	if err := p.Present(ctx, "_acme-challenge.mail.example.com.", "value"); err != nil {
+		t.Fatalf("Present() 失败: %v", err)
+	}
+	if created != "_acme-challenge.mail.example.com=value" {
+		t.Errorf("创建的记录 = %q", created)
+	}
+	if err := p.CleanUp(ctx, "_acme-challenge.mail.example.com.", "value"); err != nil {
+		t.Fatalf("CleanUp() 失败: %v", err)
+	}
+	if deleted != "/zones/zone1/dns_records/rec1" {
+		t.Errorf("删除的记录 = %q", deleted)
+	}
+
+	p.token = "wrong"
+	if err := p.Present(ctx, "_acme-challenge.mail.example.com.", "value"); err == nil || !strings.Contains(err.Error(), "Invalid token") {
+		t.Errorf("API 错误应该返回: %v", err)
+	}
+}
+
+func TestRFC2136Provider(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	p, err := NewRFC2136Provider(&config.RFC2136DNSConfig{
+		Server:     "127.0.0.1:0",
+		Zone:       "example.com",
+		TSIGKey:    "acme-key",
+		TSIGSecret: base64.StdEncoding.EncodeToString(secret),
+	})
+	if err != nil {
+		t.Fatalf("NewRFC2136Provider() 失败: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	p.server = listener.Addr().String()
+
+	requests := make(chan []byte, 2)
+	go func() {
+		for n := 1; ; n++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			_, _ = io.ReadFull(conn, length[:])
+			msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+			_, _ = io.ReadFull(conn, msg)
+			requests <- msg
+
+			// 回复 NOERROR（第二次请求回复 REFUSED）
+			resp := append([]byte(nil), msg[:12]...)
+			resp[2] |= 0x80
+			if n > 1 {
+				resp[3] = 5
+			}
+			_, _ = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+			conn.Close()
+		}
+	}()
+
+	ctx := context.Background()
+	if err := p.Present(ctx, "_acme-challenge.mail.example.com.", "value"); err != nil {
+		t.Fatalf("Present() 失败: %v", err)
+	}
+	msg := <-requests
+
+	// 报头：UPDATE、1 个区域、1 条更新、1 条附加（TSIG）
+	if opcode := msg[2] >> 3 & 0x0f; opcode != dnsOpcodeUpdate {
+		t.Errorf("opcode = %d", opcode)
+	}
+	if binary.BigEndian.Uint16(msg[4:]) != 1 || binary.BigEndian.Uint16(msg[8:]) != 1 || binary.BigEndian.Uint16(msg[10:]) != 1 {
+		t.Errorf("报头计数不正确: % x", msg[:12])
+	}
+	zone, _ := appendName(nil, "example.com.")
+	name, _ := appendName(nil, "_acme-challenge.mail.example.com.")
+	if !strings.Contains(string(msg), string(zone)) || !strings.Contains(string(msg), string(name)+"\x00\x10\x00\x01") {
+		t.Error("报文中缺少区域或 TXT 记录")
+	}
+
+	// 用 TSIG RDATA 中的时间重新计算 MAC
+	keyName, _ := appendName(nil, "acme-key.")
+	algorithm, _ := appendName(nil, "hmac-sha256.")
+	tsigStart := strings.LastIndex(string(msg), string(keyName)+"\x00\xfa")
+	if tsigStart < 0 {
+		t.Fatal("报文中缺少 TSIG 记录")
+	}
+	unsigned := append([]byte(nil), msg[:tsigStart]...)
+	binary.BigEndian.PutUint16(unsigned[10:], 0)
+	rdata := msg[tsigStart+len(keyName)+10:]
+	timers := rdata[len(algorithm) : len(algorithm)+8]
+	macSize := int(binary.BigEndian.Uint16(rdata[len(algorithm)+8:]))
+	gotMAC := rdata[len(algorithm)+10 : len(algorithm)+10+macSize]
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(unsigned)
+	mac.Write(keyName)
+	mac.Write([]byte{0, 0xff, 0, 0, 0, 0})
+	mac.Write(algorithm)
+	mac.Write(timers)
+	mac.Write([]byte{0, 0, 0, 0})
+	if !hmac.Equal(gotMAC, mac.Sum(nil)) {
+		t.Error("TSIG MAC 不正确")
+	}
+
+	// 服务器拒绝更新
+	if err := p.CleanUp(ctx, "_acme-challenge.mail.example.com.", "value"); err == nil || !strings.Contains(err.Error(), "REFUSED") {
+		t.Errorf("拒绝的更新应该返回错误: %v", err)
+	}
+	if msg := <-requests; !strings.Contains(string(msg), string(name)+"\x00\x10\x00\xfe") {
+		t.Error("删除记录应该使用 class NONE")
+	}
+}
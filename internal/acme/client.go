@@ -9,11 +9,9 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/gomailzero/gmz/internal/config"
@@ -21,53 +19,90 @@ import (
 	"golang.org/x/crypto/acme"
 )
 
+// defaultRenewBefore 未配置 renew_before 时，证书到期前开始续期的时间
+const defaultRenewBefore = 30 * 24 * time.Hour
+
 // Client ACME 客户端
 type Client struct {
 	config     *config.ACMEConfig
 	acmeClient *acme.Client
 	key        *ecdsa.PrivateKey
 	account    *acme.Account
+	store      Store
+	solver     solver
+}
+
+// DirectoryURL 返回 ACME 提供商的目录 URL
+func DirectoryURL(provider string) string {
+	switch provider {
+	case "zerossl":
+		return "https://acme.zerossl.com/v2/DV90"
+	default:
+		return acme.LetsEncryptURL
+	}
 }
 
 // NewClient 创建 ACME 客户端
-func NewClient(cfg *config.ACMEConfig) (*Client, error) {
-	// 生成账户密钥
-	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	if err != nil {
-		return nil, fmt.Errorf("生成账户密钥失败: %w", err)
+// store 为空时使用 cfg.Dir 目录存储账户密钥和证书
+func NewClient(cfg *config.ACMEConfig, store Store) (*Client, error) {
+	if store == nil {
+		fileStore, err := NewFileStore(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		store = fileStore
 	}
 
-	// 选择 ACME 目录 URL
-	var directoryURL string
-	switch cfg.Provider {
-	case "letsencrypt":
-		directoryURL = acme.LetsEncryptURL
-	case "zerossl":
-		directoryURL = "https://acme.zerossl.com/v2/DV90"
-	default:
-		directoryURL = acme.LetsEncryptURL
+	// 复用已保存的账户密钥，避免每次启动都注册新账户
+	key, err := store.LoadAccountKey(context.Background())
+	if errors.Is(err, ErrNotStored) {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("生成账户密钥失败: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("加载账户密钥失败: %w", err)
 	}
 
 	// 创建 ACME 客户端
 	client := &acme.Client{
 		Key:          key,
-		DirectoryURL: directoryURL,
+		DirectoryURL: DirectoryURL(cfg.Provider),
 		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
 	}
 
-	// 确保证书目录存在
-	// #nosec G301 -- 0755 权限允许组和其他用户读取证书，这是 ACME 证书的标准权限
-	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
-		return nil, fmt.Errorf("创建证书目录失败: %w", err)
+	solver, err := newSolver(cfg, client)
+	if err != nil {
+		return nil, err
 	}
 
 	return &Client{
 		config:     cfg,
 		acmeClient: client,
 		key:        key,
+		store:      store,
+		solver:     solver,
 	}, nil
 }
 
+// newSolver 根据配置的验证方式创建挑战处理器
+func newSolver(cfg *config.ACMEConfig, client *acme.Client) (solver, error) {
+	switch cfg.Challenge {
+	case "", "http-01":
+		return newHTTP01Solver(client, cfg.ChallengeAddr), nil
+	case "tls-alpn-01":
+		return newTLSALPN01Solver(client, cfg.ChallengeAddr), nil
+	case "dns-01":
+		provider, err := NewDNSProvider(&cfg.DNS)
+		if err != nil {
+			return nil, err
+		}
+		return &dns01Solver{client: client, provider: provider, timeout: cfg.DNS.PropagationTimeout}, nil
+	default:
+		return nil, fmt.Errorf("不支持的 ACME 验证方式: %q", cfg.Challenge)
+	}
+}
+
 // Register 注册 ACME 账户
 func (c *Client) Register(ctx context.Context) error {
 	account := &acme.Account{
@@ -84,45 +119,66 @@ func (c *Client) Register(ctx context.Context) error {
 				return fmt.Errorf("获取现有账户失败: %w", err)
 			}
 			logger.Info().Msg("使用现有 ACME 账户")
-			return nil
+			return c.store.SaveAccountKey(ctx, c.key, c.account.URI)
 		}
 		return fmt.Errorf("注册 ACME 账户失败: %w", err)
 	}
 
 	logger.Info().Msg("ACME 账户注册成功")
+	if err := c.store.SaveAccountKey(ctx, c.key, c.account.URI); err != nil {
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
 	return nil
 }
 
-// ObtainCertificate 获取证书
+// ObtainCertificate 获取证书，并记录订单状态
 func (c *Client) ObtainCertificate(ctx context.Context, domain string) (*tls.Certificate, error) {
-	// 创建证书请求
-	csr, key, err := c.createCSR(domain)
+	if err := c.store.SaveOrder(ctx, domain, "", "pending", nil); err != nil {
+		logger.Warn().Err(err).Str("domain", domain).Msg("保存 ACME 订单失败")
+	}
+
+	cert, orderURL, err := c.obtainCertificate(ctx, domain)
+
+	status := "valid"
 	if err != nil {
-		return nil, fmt.Errorf("创建证书请求失败: %w", err)
+		status = "invalid"
+	}
+	if saveErr := c.store.SaveOrder(ctx, domain, orderURL, status, err); saveErr != nil {
+		logger.Warn().Err(saveErr).Str("domain", domain).Msg("保存 ACME 订单失败")
 	}
+	return cert, err
+}
 
-	// 授权域名
-	authz, err := c.acmeClient.Authorize(ctx, domain)
+// obtainCertificate 通过 RFC 8555 订单流程完成挑战并申请证书，返回证书和订单 URL
+func (c *Client) obtainCertificate(ctx context.Context, domain string) (*tls.Certificate, string, error) {
+	// 创建订单
+	order, err := c.acmeClient.AuthorizeOrder(ctx, acme.DomainIDs(domain))
 	if err != nil {
-		return nil, fmt.Errorf("授权域名失败: %w", err)
+		return nil, "", fmt.Errorf("创建订单失败: %w", err)
 	}
+	orderURL := order.URI
 
-	// 完成 HTTP-01 挑战
-	if err := c.completeHTTP01Challenge(ctx, authz); err != nil {
-		return nil, fmt.Errorf("完成挑战失败: %w", err)
+	// 完成订单中每个待验证的授权
+	for _, authzURL := range order.AuthzURLs {
+		if err := c.authorize(ctx, authzURL); err != nil {
+			return nil, orderURL, err
+		}
 	}
 
-	// 等待授权生效
-	if _, err := c.acmeClient.WaitAuthorization(ctx, authz.URI); err != nil {
-		return nil, fmt.Errorf("等待授权失败: %w", err)
+	// 等待订单就绪
+	order, err = c.acmeClient.WaitOrder(ctx, orderURL)
+	if err != nil {
+		return nil, orderURL, fmt.Errorf("等待订单就绪失败: %w", err)
 	}
 
-	// 申请证书
-	// TODO: 迁移到 RFC 8555 的 CreateOrderCert API
-	// nolint:staticcheck // CreateCert 已弃用，但暂时保留以保持兼容性
-	certChain, _, err := c.acmeClient.CreateCert(ctx, csr, time.Hour*24*90, true)
+	// 创建证书请求并完成订单
+	csr, key, err := c.createCSR(domain)
+	if err != nil {
+		return nil, orderURL, fmt.Errorf("创建证书请求失败: %w", err)
+	}
+	certChain, _, err := c.acmeClient.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
 	if err != nil {
-		return nil, fmt.Errorf("申请证书失败: %w", err)
+		return nil, orderURL, fmt.Errorf("申请证书失败: %w", err)
 	}
 
 	// 合并证书链
@@ -136,44 +192,92 @@ func (c *Client) ObtainCertificate(ctx context.Context, domain string) (*tls.Cer
 	}
 
 	// 保存证书和密钥
-	if err := c.saveCertificate(domain, certPEM, key); err != nil {
-		return nil, fmt.Errorf("保存证书失败: %w", err)
+	if err := c.store.SaveCertificate(ctx, domain, certPEM, key); err != nil {
+		return nil, orderURL, fmt.Errorf("保存证书失败: %w", err)
 	}
 
 	// 加载证书
 	tlsCert, err := tls.X509KeyPair(certPEM, key)
 	if err != nil {
-		return nil, fmt.Errorf("加载证书失败: %w", err)
+		return nil, orderURL, fmt.Errorf("加载证书失败: %w", err)
 	}
 
-	logger.Info().Str("domain", domain).Msg("证书获取成功")
-	return &tlsCert, nil
+	logger.Info().Str("domain", domain).Str("challenge", c.solver.Type()).Msg("证书获取成功")
+	return &tlsCert, orderURL, nil
+}
+
+// authorize 使用配置的验证方式完成一个授权
+func (c *Client) authorize(ctx context.Context, authzURL string) error {
+	authz, err := c.acmeClient.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("获取授权失败: %w", err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	domain := authz.Identifier.Value
+	if authz.Wildcard {
+		domain = "*." + domain
+	}
+
+	var chal *acme.Challenge
+	for _, ch := range authz.Challenges {
+		if ch.Type == c.solver.Type() {
+			chal = ch
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("CA 没有为 %s 提供 %s 挑战", domain, c.solver.Type())
+	}
+
+	if err := c.solver.Present(ctx, authz.Identifier.Value, chal); err != nil {
+		return fmt.Errorf("准备 %s 挑战失败: %w", chal.Type, err)
+	}
+	defer func() {
+		if err := c.solver.CleanUp(context.WithoutCancel(ctx), authz.Identifier.Value, chal); err != nil {
+			logger.Warn().Err(err).Str("domain", domain).Msg("清理 ACME 挑战失败")
+		}
+	}()
+
+	// 接受挑战并等待授权生效
+	if _, err := c.acmeClient.Accept(ctx, chal); err != nil {
+		return fmt.Errorf("接受挑战失败: %w", err)
+	}
+	if _, err := c.acmeClient.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("等待 %s 授权失败: %w", domain, err)
+	}
+	return nil
 }
 
 // RenewCertificate 续期证书
 func (c *Client) RenewCertificate(ctx context.Context, domain string) (*tls.Certificate, error) {
-	certFile := filepath.Join(c.config.Dir, domain+".crt")
-	keyFile := filepath.Join(c.config.Dir, domain+".key")
-
-	// 检查证书是否存在
-	if _, err := os.Stat(certFile); os.IsNotExist(err) {
+	certPEM, keyPEM, err := c.store.LoadCertificate(ctx, domain)
+	if errors.Is(err, ErrNotStored) {
 		// 证书不存在，申请新证书
 		return c.ObtainCertificate(ctx, domain)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("加载证书失败: %w", err)
+	}
 
-	// 检查证书是否即将过期（30 天内）
-	cert, err := c.loadCertificate(certFile)
+	// 检查证书是否即将过期（默认 30 天内）
+	cert, err := parseCertificatePEM(certPEM)
 	if err != nil {
 		return nil, fmt.Errorf("加载证书失败: %w", err)
 	}
 
-	if time.Until(cert.NotAfter) < 30*24*time.Hour {
+	renewBefore := c.config.RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = defaultRenewBefore
+	}
+	if time.Until(cert.NotAfter) < renewBefore {
 		logger.Info().Str("domain", domain).Msg("证书即将过期，开始续期")
 		return c.ObtainCertificate(ctx, domain)
 	}
 
 	// 证书仍然有效，加载并返回
-	tlsCert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		return nil, fmt.Errorf("加载证书失败: %w", err)
 	}
@@ -216,79 +320,23 @@ func (c *Client) createCSR(domain string) ([]byte, []byte, error) {
 	return csr, keyPEM, nil
 }
 
-// completeHTTP01Challenge 完成 HTTP-01 挑战
-func (c *Client) completeHTTP01Challenge(ctx context.Context, authz *acme.Authorization) error {
-	// 找到 HTTP-01 挑战
-	var chal *acme.Challenge
-	for _, c := range authz.Challenges {
-		if c.Type == "http-01" {
-			chal = c
-			break
-		}
-	}
-
-	if chal == nil {
-		return fmt.Errorf("未找到 HTTP-01 挑战")
+// ChallengeCertificate 返回 TLS-ALPN-01 验证证书（未使用 TLS-ALPN-01 验证时返回错误）
+func (c *Client) ChallengeCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	solver, ok := c.solver.(*tlsALPN01Solver)
+	if !ok {
+		return nil, fmt.Errorf("未使用 TLS-ALPN-01 验证")
 	}
-
-	// 接受挑战
-	_, err := c.acmeClient.Accept(ctx, chal)
-	if err != nil {
-		return fmt.Errorf("接受挑战失败: %w", err)
-	}
-
-	return nil
-}
-
-// saveCertificate 保存证书
-func (c *Client) saveCertificate(domain string, cert []byte, key []byte) error {
-	certFile := filepath.Join(c.config.Dir, domain+".crt")
-	keyFile := filepath.Join(c.config.Dir, domain+".key")
-
-	// #nosec G306 -- 证书文件需要可读权限，0644 是标准权限
-	if err := os.WriteFile(certFile, cert, 0644); err != nil {
-		return fmt.Errorf("保存证书文件失败: %w", err)
-	}
-
-	if err := os.WriteFile(keyFile, key, 0600); err != nil {
-		return fmt.Errorf("保存密钥文件失败: %w", err)
-	}
-
-	return nil
-}
-
-// loadCertificate 加载证书
-func (c *Client) loadCertificate(certFile string) (*x509.Certificate, error) {
-	// 验证文件路径在证书目录下（防止路径遍历攻击）
-	if !strings.HasPrefix(certFile, c.config.Dir) {
-		return nil, fmt.Errorf("无效的证书文件路径")
-	}
-
-	// #nosec G304 -- certFile 已经通过 filepath.Join 和已验证的 c.config.Dir 构建，并且已经验证在 c.config.Dir 目录下，是安全的
-	certPEM, err := os.ReadFile(certFile)
-	if err != nil {
-		return nil, fmt.Errorf("读取证书文件失败: %w", err)
-	}
-
-	block, _ := pem.Decode(certPEM)
-	if block == nil {
-		return nil, fmt.Errorf("解析证书 PEM 失败")
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("解析证书失败: %w", err)
-	}
-
-	return cert, nil
+	return solver.ChallengeCertificate(hello)
 }
 
 // GetCertificate 获取证书（用于 TLS 配置）
 func (c *Client) GetCertificate(domain string) (*tls.Certificate, error) {
-	certFile := filepath.Join(c.config.Dir, domain+".crt")
-	keyFile := filepath.Join(c.config.Dir, domain+".key")
+	certPEM, keyPEM, err := c.store.LoadCertificate(context.Background(), domain)
+	if err != nil {
+		return nil, fmt.Errorf("加载证书失败: %w", err)
+	}
 
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
 		return nil, fmt.Errorf("加载证书失败: %w", err)
 	}
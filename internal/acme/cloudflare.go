@@ -0,0 +1,148 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cloudflareAPI Cloudflare API v4 地址
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// CloudflareProvider 通过 Cloudflare API 管理 TXT 记录（API Token 需要 Zone.DNS 编辑权限）
+type CloudflareProvider struct {
+	token      string
+	baseURL    string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	records map[string]cloudflareRecord // fqdn + 值 -> 已创建的记录
+}
+
+// cloudflareRecord 已创建的记录，删除时使用
+type cloudflareRecord struct {
+	zoneID, id string
+}
+
+// NewCloudflareProvider 创建 Cloudflare DNS 提供商
+func NewCloudflareProvider(token string) *CloudflareProvider {
+	return &CloudflareProvider{
+		token:      token,
+		baseURL:    cloudflareAPI,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		records:    make(map[string]cloudflareRecord),
+	}
+}
+
+// Present 创建 TXT 记录
+func (p *CloudflareProvider) Present(ctx context.Context, fqdn, value string) error {
+	zoneID, err := p.findZoneID(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	var record struct {
+		ID string `json:"id"`
+	}
+	body := map[string]any{
+		"type":    "TXT",
+		"name":    strings.TrimSuffix(fqdn, "."),
+		"content": value,
+		"ttl":     120,
+	}
+	if err := p.do(ctx, http.MethodPost, "/zones/"+zoneID+"/dns_records", body, &record); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.records[fqdn+" "+value] = cloudflareRecord{zoneID: zoneID, id: record.ID}
+	p.mu.Unlock()
+	return nil
+}
+
+// CleanUp 删除 Present 创建的 TXT 记录
+func (p *CloudflareProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	p.mu.Lock()
+	record, ok := p.records[fqdn+" "+value]
+	delete(p.records, fqdn+" "+value)
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return p.do(ctx, http.MethodDelete, "/zones/"+record.zoneID+"/dns_records/"+record.id, nil, nil)
+}
+
+// findZoneID 从 fqdn 逐级向上查找 Token 可以管理的区域
+func (p *CloudflareProvider) findZoneID(ctx context.Context, fqdn string) (string, error) {
+	name := strings.TrimSuffix(fqdn, ".")
+	for strings.Contains(name, ".") {
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		if err := p.do(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(name), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+		name = name[strings.IndexByte(name, '.')+1:]
+	}
+	return "", fmt.Errorf("Cloudflare 账户中找不到 %s 所在的区域", fqdn)
+}
+
+// do 调用 Cloudflare API，result 不为 nil 时解析响应中的 result 字段
+func (p *CloudflareProvider) do(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 Cloudflare API 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Success bool                       `json:"success"`
+		Errors  []struct{ Message string } `json:"errors"`
+		Result  json.RawMessage            `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&response); err != nil {
+		return fmt.Errorf("解析 Cloudflare API 响应失败（HTTP %d）: %w", resp.StatusCode, err)
+	}
+	if !response.Success {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("Cloudflare API 返回错误（HTTP %d）: %w", resp.StatusCode, errors.New(strings.Join(messages, "; ")))
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			return fmt.Errorf("解析 Cloudflare API 响应失败: %w", err)
+		}
+	}
+	return nil
+}
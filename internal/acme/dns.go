@@ -0,0 +1,44 @@
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/config"
+)
+
+// DNSProvider 为 DNS-01 验证创建和删除 TXT 记录
+// fqdn 为以点结尾的完整域名（如 _acme-challenge.mail.example.com.）
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// NewDNSProvider 根据配置创建 DNS 提供商
+func NewDNSProvider(cfg *config.ACMEDNSConfig) (DNSProvider, error) {
+	switch cfg.Provider {
+	case "cloudflare":
+		return NewCloudflareProvider(cfg.Cloudflare.APIToken), nil
+	case "rfc2136":
+		return NewRFC2136Provider(&cfg.RFC2136)
+	default:
+		return nil, fmt.Errorf("不支持的 DNS 提供商: %q", cfg.Provider)
+	}
+}
+
+// findZone 查找 fqdn 所在的 DNS 区域：从 fqdn 开始逐级向上，返回第一个有 NS 记录的域名（以点结尾）
+func findZone(ctx context.Context, resolver *net.Resolver, fqdn string) (string, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	name := strings.TrimSuffix(fqdn, ".")
+	for strings.Contains(name, ".") {
+		if ns, err := resolver.LookupNS(ctx, name+"."); err == nil && len(ns) > 0 {
+			return name + ".", nil
+		}
+		name = name[strings.IndexByte(name, '.')+1:]
+	}
+	return "", fmt.Errorf("找不到 %s 所在的 DNS 区域", fqdn)
+}
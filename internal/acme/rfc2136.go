@@ -0,0 +1,282 @@
+package acme
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- hmac-sha1 是 TSIG 标准算法之一，仅在管理员显式配置时使用
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/config"
+)
+
+// DNS 报文常量
+const (
+	dnsTypeSOA  = 6
+	dnsTypeTXT  = 16
+	dnsTypeTSIG = 250
+
+	dnsClassIN   = 1
+	dnsClassNone = 254
+	dnsClassAny  = 255
+
+	dnsOpcodeUpdate = 5
+	tsigFudge       = 300
+	challengeTTL    = 60
+)
+
+// dnsRcodes 常见的 DNS 响应码
+var dnsRcodes = map[int]string{
+	1: "FORMERR", 2: "SERVFAIL", 3: "NXDOMAIN", 4: "NOTIMP", 5: "REFUSED",
+	6: "YXDOMAIN", 7: "YXRRSET", 8: "NXRRSET", 9: "NOTAUTH", 10: "NOTZONE",
+}
+
+// RFC2136Provider 通过动态 DNS 更新（RFC 2136，可选 TSIG 签名）管理 TXT 记录
+type RFC2136Provider struct {
+	server    string
+	zone      string
+	keyName   string
+	secret    []byte
+	algorithm string
+	newHash   func() hash.Hash
+	resolver  *net.Resolver
+	timeout   time.Duration
+}
+
+// NewRFC2136Provider 创建 RFC 2136 DNS 提供商
+func NewRFC2136Provider(cfg *config.RFC2136DNSConfig) (*RFC2136Provider, error) {
+	server := cfg.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	p := &RFC2136Provider{server: server, timeout: 10 * time.Second}
+	if cfg.Zone != "" {
+		p.zone = canonicalName(cfg.Zone)
+	}
+
+	if cfg.TSIGKey != "" {
+		secret, err := base64.StdEncoding.DecodeString(cfg.TSIGSecret)
+		if err != nil {
+			return nil, fmt.Errorf("解析 TSIG 密钥失败: %w", err)
+		}
+		p.keyName = canonicalName(cfg.TSIGKey)
+		p.secret = secret
+		switch cfg.TSIGAlgorithm {
+		case "", "hmac-sha256":
+			p.algorithm, p.newHash = "hmac-sha256.", sha256.New
+		case "hmac-sha512":
+			p.algorithm, p.newHash = "hmac-sha512.", sha512.New
+		case "hmac-sha1":
+			p.algorithm, p.newHash = "hmac-sha1.", sha1.New
+		default:
+			return nil, fmt.Errorf("不支持的 TSIG 算法: %q", cfg.TSIGAlgorithm)
+		}
+	}
+	return p, nil
+}
+
+// Present 添加 TXT 记录
+func (p *RFC2136Provider) Present(ctx context.Context, fqdn, value string) error {
+	return p.update(ctx, fqdn, value, dnsClassIN, challengeTTL)
+}
+
+// CleanUp 删除 TXT 记录（只删除指定值的记录）
+func (p *RFC2136Provider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.update(ctx, fqdn, value, dnsClassNone, 0)
+}
+
+// update 发送添加（class IN）或删除（class NONE）单条 TXT 记录的 UPDATE 报文
+func (p *RFC2136Provider) update(ctx context.Context, fqdn, value string, class uint16, ttl uint32) error {
+	zone := p.zone
+	if zone == "" {
+		var err error
+		if zone, err = findZone(ctx, p.resolver, fqdn); err != nil {
+			return err
+		}
+	}
+
+	msg, err := p.buildUpdate(zone, canonicalName(fqdn), value, class, ttl, time.Now())
+	if err != nil {
+		return err
+	}
+	resp, err := p.exchange(ctx, msg)
+	if err != nil {
+		return err
+	}
+	if len(resp) < 12 || binary.BigEndian.Uint16(resp) != binary.BigEndian.Uint16(msg) {
+		return errors.New("DNS 服务器返回了无效的响应")
+	}
+	if rcode := int(resp[3] & 0x0f); rcode != 0 {
+		name, ok := dnsRcodes[rcode]
+		if !ok {
+			name = fmt.Sprintf("RCODE %d", rcode)
+		}
+		return fmt.Errorf("DNS 服务器拒绝了更新: %s", name)
+	}
+	return nil
+}
+
+// buildUpdate 生成 UPDATE 报文，配置了 TSIG 密钥时附加 TSIG 签名
+func (p *RFC2136Provider) buildUpdate(zone, name, value string, class uint16, ttl uint32, now time.Time) ([]byte, error) {
+	var id [2]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, 0, 512)
+	msg = append(msg, id[0], id[1])
+	msg = binary.BigEndian.AppendUint16(msg, dnsOpcodeUpdate<<11)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // ZOCOUNT
+	msg = binary.BigEndian.AppendUint16(msg, 0) // PRCOUNT
+	msg = binary.BigEndian.AppendUint16(msg, 1) // UPCOUNT
+	msg = binary.BigEndian.AppendUint16(msg, 0) // ADCOUNT
+
+	// 区域
+	var err error
+	if msg, err = appendName(msg, zone); err != nil {
+		return nil, err
+	}
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeSOA)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+
+	// 更新的记录
+	if msg, err = appendName(msg, name); err != nil {
+		return nil, err
+	}
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeTXT)
+	msg = binary.BigEndian.AppendUint16(msg, class)
+	msg = binary.BigEndian.AppendUint32(msg, ttl)
+	rdata := txtRData(value)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata))) // #nosec G115 -- TXT 值长度受 DNS-01 记录限制
+	msg = append(msg, rdata...)
+
+	if p.keyName == "" {
+		return msg, nil
+	}
+	return p.sign(msg, now)
+}
+
+// sign 按 RFC 8945 计算 TSIG 并附加到报文的附加区
+func (p *RFC2136Provider) sign(msg []byte, now time.Time) ([]byte, error) {
+	keyName, err := appendName(nil, p.keyName)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := appendName(nil, p.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	// #nosec G115 -- 48 位时间戳
+	timeSigned := uint64(now.Unix())
+
+	// TSIG 变量：密钥名、CLASS、TTL、算法名、签名时间、Fudge、Error、Other Len
+	mac := hmac.New(p.newHash, p.secret)
+	mac.Write(msg)
+	mac.Write(keyName)
+	variables := binary.BigEndian.AppendUint16(nil, dnsClassAny)
+	variables = binary.BigEndian.AppendUint32(variables, 0)
+	mac.Write(variables)
+	mac.Write(algorithm)
+	timers := appendUint48(nil, timeSigned)
+	timers = binary.BigEndian.AppendUint16(timers, tsigFudge)
+	mac.Write(timers)
+	mac.Write([]byte{0, 0, 0, 0}) // Error、Other Len
+	sum := mac.Sum(nil)
+
+	rdata := append([]byte(nil), algorithm...)
+	rdata = append(rdata, timers...)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum))) // #nosec G115 -- HMAC 长度
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1]) // Original ID
+	rdata = append(rdata, 0, 0, 0, 0)     // Error、Other Len
+
+	signed := append([]byte(nil), msg...)
+	binary.BigEndian.PutUint16(signed[10:], binary.BigEndian.Uint16(signed[10:])+1) // ADCOUNT
+	signed = append(signed, keyName...)
+	signed = binary.BigEndian.AppendUint16(signed, dnsTypeTSIG)
+	signed = binary.BigEndian.AppendUint16(signed, dnsClassAny)
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata))) // #nosec G115 -- TSIG RDATA 长度有限
+	signed = append(signed, rdata...)
+	return signed, nil
+}
+
+// exchange 通过 TCP 发送报文并读取响应
+func (p *RFC2136Provider) exchange(ctx context.Context, msg []byte) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.server)
+	if err != nil {
+		return nil, fmt.Errorf("连接 DNS 服务器 %s 失败: %w", p.server, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(p.timeout))
+
+	frame := binary.BigEndian.AppendUint16(nil, uint16(len(msg))) // #nosec G115 -- 报文长度远小于 64KiB
+	if _, err := conn.Write(append(frame, msg...)); err != nil {
+		return nil, fmt.Errorf("发送 DNS 更新失败: %w", err)
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, fmt.Errorf("读取 DNS 响应失败: %w", err)
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, fmt.Errorf("读取 DNS 响应失败: %w", err)
+	}
+	return resp, nil
+}
+
+// canonicalName 小写并以点结尾的域名
+func canonicalName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// appendName 以 DNS 报文格式（不压缩）追加域名
+func appendName(b []byte, name string) ([]byte, error) {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			if name == "." {
+				break
+			}
+			return nil, fmt.Errorf("无效的域名: %q", name)
+		}
+		if len(label) > 63 {
+			return nil, fmt.Errorf("域名标签过长: %q", label)
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0), nil
+}
+
+// txtRData TXT 记录的 RDATA（按 255 字节拆分为多个字符串）
+func txtRData(value string) []byte {
+	var b []byte
+	for len(value) > 255 {
+		b = append(b, 255)
+		b = append(b, value[:255]...)
+		value = value[255:]
+	}
+	b = append(b, byte(len(value)))
+	return append(b, value...)
+}
+
+// appendUint48 追加 48 位大端整数
+func appendUint48(b []byte, v uint64) []byte {
+	return append(b, byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
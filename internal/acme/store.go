@@ -0,0 +1,265 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// ErrNotStored 账户密钥或证书尚未保存
+var ErrNotStored = errors.New("not stored")
+
+// Store ACME 账户密钥、订单和证书的持久化接口
+type Store interface {
+	LoadAccountKey(ctx context.Context) (*ecdsa.PrivateKey, error)
+	SaveAccountKey(ctx context.Context, key *ecdsa.PrivateKey, accountURL string) error
+	SaveOrder(ctx context.Context, domain, orderURL, status string, orderErr error) error
+	LoadCertificate(ctx context.Context, domain string) (certPEM, keyPEM []byte, err error)
+	SaveCertificate(ctx context.Context, domain string, certPEM, keyPEM []byte) error
+}
+
+// FileStore 基于证书目录的存储（单机部署）
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore 创建基于目录的存储
+func NewFileStore(dir string) (*FileStore, error) {
+	// #nosec G301 -- 0755 权限允许组和其他用户读取证书，这是 ACME 证书的标准权限
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建证书目录失败: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// LoadAccountKey 加载账户密钥
+func (s *FileStore) LoadAccountKey(ctx context.Context) (*ecdsa.PrivateKey, error) {
+	keyPEM, err := s.readFile("account.key")
+	if err != nil {
+		return nil, err
+	}
+	return decodeECKey(keyPEM)
+}
+
+// SaveAccountKey 保存账户密钥
+func (s *FileStore) SaveAccountKey(ctx context.Context, key *ecdsa.PrivateKey, accountURL string) error {
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, "account.key"), keyPEM, 0600); err != nil {
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
+	return nil
+}
+
+// SaveOrder 保存订单状态（目录存储不记录订单）
+func (s *FileStore) SaveOrder(ctx context.Context, domain, orderURL, status string, orderErr error) error {
+	return nil
+}
+
+// LoadCertificate 加载证书和私钥
+func (s *FileStore) LoadCertificate(ctx context.Context, domain string) ([]byte, []byte, error) {
+	certPEM, err := s.readFile(domain + ".crt")
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := s.readFile(domain + ".key")
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// SaveCertificate 保存证书和私钥
+func (s *FileStore) SaveCertificate(ctx context.Context, domain string, certPEM, keyPEM []byte) error {
+	// #nosec G306 -- 证书文件需要可读权限，0644 是标准权限
+	if err := os.WriteFile(filepath.Join(s.dir, domain+".crt"), certPEM, 0644); err != nil {
+		return fmt.Errorf("保存证书文件失败: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, domain+".key"), keyPEM, 0600); err != nil {
+		return fmt.Errorf("保存密钥文件失败: %w", err)
+	}
+	return nil
+}
+
+// readFile 读取证书目录下的文件
+func (s *FileStore) readFile(name string) ([]byte, error) {
+	path := filepath.Join(s.dir, name)
+	// 验证文件路径在证书目录下（防止路径遍历攻击）
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return nil, fmt.Errorf("无效的证书文件路径")
+	}
+
+	// #nosec G304 -- path 已验证在证书目录下
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %w", name, err)
+	}
+	return data, nil
+}
+
+// DBStore 基于存储驱动的数据库存储（多节点/容器部署共享证书）
+// 私钥使用 XChaCha20-Poly1305 加密，密钥由 encryption_key 派生
+type DBStore struct {
+	driver       storage.Driver
+	email        string
+	directoryURL string
+	passphrase   string
+}
+
+// NewDBStore 创建数据库存储
+func NewDBStore(driver storage.Driver, email, directoryURL, passphrase string) (*DBStore, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("数据库存储 ACME 证书需要配置 tls.acme.encryption_key")
+	}
+	return &DBStore{
+		driver:       driver,
+		email:        email,
+		directoryURL: directoryURL,
+		passphrase:   passphrase,
+	}, nil
+}
+
+// LoadAccountKey 加载账户密钥
+func (s *DBStore) LoadAccountKey(ctx context.Context) (*ecdsa.PrivateKey, error) {
+	account, err := s.driver.GetACMEAccount(ctx, s.email, s.directoryURL)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := s.decrypt(account.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密账户密钥失败: %w", err)
+	}
+	return decodeECKey(keyPEM)
+}
+
+// SaveAccountKey 保存账户密钥
+func (s *DBStore) SaveAccountKey(ctx context.Context, key *ecdsa.PrivateKey, accountURL string) error {
+	keyPEM, err := encodeECKey(key)
+	if err != nil {
+		return err
+	}
+	encrypted, err := s.encrypt(keyPEM)
+	if err != nil {
+		return fmt.Errorf("加密账户密钥失败: %w", err)
+	}
+	return s.driver.SaveACMEAccount(ctx, &storage.ACMEAccount{
+		Email:        s.email,
+		DirectoryURL: s.directoryURL,
+		AccountURL:   accountURL,
+		PrivateKey:   encrypted,
+	})
+}
+
+// SaveOrder 保存订单状态
+func (s *DBStore) SaveOrder(ctx context.Context, domain, orderURL, status string, orderErr error) error {
+	order := &storage.ACMEOrder{
+		Domain:   domain,
+		OrderURL: orderURL,
+		Status:   status,
+	}
+	if orderErr != nil {
+		order.Error = orderErr.Error()
+	}
+	return s.driver.SaveACMEOrder(ctx, order)
+}
+
+// LoadCertificate 加载证书和私钥
+func (s *DBStore) LoadCertificate(ctx context.Context, domain string) ([]byte, []byte, error) {
+	cert, err := s.driver.GetACMECertificate(ctx, domain)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrNotStored
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := s.decrypt(cert.PrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("解密证书私钥失败: %w", err)
+	}
+	return cert.CertPEM, keyPEM, nil
+}
+
+// SaveCertificate 保存证书和私钥
+func (s *DBStore) SaveCertificate(ctx context.Context, domain string, certPEM, keyPEM []byte) error {
+	leaf, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return err
+	}
+	encrypted, err := s.encrypt(keyPEM)
+	if err != nil {
+		return fmt.Errorf("加密证书私钥失败: %w", err)
+	}
+	return s.driver.SaveACMECertificate(ctx, &storage.ACMECertificate{
+		Domain:     domain,
+		CertPEM:    certPEM,
+		PrivateKey: encrypted,
+		NotAfter:   leaf.NotAfter,
+	})
+}
+
+// encrypt 加密数据，输出格式为 salt || nonce || ciphertext
+func (s *DBStore) encrypt(plaintext []byte) ([]byte, error) {
+	return crypto.EncryptWithPassphrase(s.passphrase, plaintext)
+}
+
+// decrypt 解密 encrypt 生成的数据
+func (s *DBStore) decrypt(data []byte) ([]byte, error) {
+	return crypto.DecryptWithPassphrase(s.passphrase, data)
+}
+
+// encodeECKey 将 ECDSA 私钥编码为 PEM
+func encodeECKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("编码私钥失败: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: keyBytes,
+	}), nil
+}
+
+// decodeECKey 从 PEM 解析 ECDSA 私钥
+func decodeECKey(keyPEM []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("解析私钥 PEM 失败")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// parseCertificatePEM 解析证书链中的第一张证书
+func parseCertificatePEM(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("解析证书 PEM 失败")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %w", err)
+	}
+	return cert, nil
+}
@@ -0,0 +1,65 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestDBStore_AccountKeyEncrypted(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	store, err := NewDBStore(driver, "admin@example.com", DirectoryURL("letsencrypt"), "passphrase")
+	if err != nil {
+		t.Fatalf("创建数据库存储失败: %v", err)
+	}
+
+	if _, err := store.LoadAccountKey(ctx); !errors.Is(err, ErrNotStored) {
+		t.Fatalf("期望 ErrNotStored，得到 %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveAccountKey(ctx, key, "https://acme.test/acct/1"); err != nil {
+		t.Fatalf("保存账户密钥失败: %v", err)
+	}
+
+	// 数据库中只能存放密文
+	account, err := driver.GetACMEAccount(ctx, "admin@example.com", DirectoryURL("letsencrypt"))
+	if err != nil {
+		t.Fatalf("获取账户失败: %v", err)
+	}
+	if bytes.Contains(account.PrivateKey, []byte("PRIVATE KEY")) {
+		t.Error("账户私钥未加密")
+	}
+
+	loaded, err := store.LoadAccountKey(ctx)
+	if err != nil {
+		t.Fatalf("加载账户密钥失败: %v", err)
+	}
+	if !loaded.Equal(key) {
+		t.Error("加载的账户密钥与保存的不一致")
+	}
+
+	// 错误的口令无法解密
+	wrong, _ := NewDBStore(driver, "admin@example.com", DirectoryURL("letsencrypt"), "wrong")
+	if _, err := wrong.LoadAccountKey(ctx); err == nil {
+		t.Error("期望错误口令解密失败")
+	}
+}
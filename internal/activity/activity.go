@@ -0,0 +1,99 @@
+// Package activity 账户活动记录：用户的登录（WebMail、管理后台、IMAP、SMTP）和从账户发出的邮件，
+// 用户通过 GET /api/activity 查看自己的登录历史、设备和发信记录，自行发现账户被盗用。
+//
+// 邮件客户端每次连接都会重新登录，同一设备（协议、IP 和 User-Agent 相同）在 loginInterval 内的
+// 重复登录只记录一次。
+package activity
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// loginInterval 同一设备的重复登录只记录一次的时间间隔
+const loginInterval = 10 * time.Minute
+
+// 登录和发信的协议
+const (
+	ProtocolWebMail = "webmail"
+	ProtocolAdmin   = "admin"
+	ProtocolIMAP    = "imap"
+	ProtocolSMTP    = "smtp"
+)
+
+// Recorder 记录账户活动；为空时不记录
+type Recorder struct {
+	Storage storage.Driver
+
+	mu     sync.Mutex
+	recent map[string]time.Time // 设备 -> 最近一次记录登录的时间
+}
+
+// New 创建账户活动记录器
+func New(driver storage.Driver) *Recorder {
+	return &Recorder{Storage: driver}
+}
+
+// Login 记录一次成功的登录，失败时只记录日志（不影响登录）
+func (r *Recorder) Login(ctx context.Context, userEmail, protocol, remoteIP, userAgent string) {
+	if r == nil || r.Storage == nil {
+		return
+	}
+	if !r.due(strings.ToLower(userEmail)+"\x00"+protocol+"\x00"+remoteIP+"\x00"+userAgent, time.Now()) {
+		return
+	}
+	record := &storage.LoginRecord{
+		UserEmail: userEmail,
+		Protocol:  protocol,
+		RemoteIP:  remoteIP,
+		UserAgent: userAgent,
+	}
+	if err := r.Storage.RecordLogin(ctx, record); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("user", userEmail).Str("protocol", protocol).Msg("记录登录历史失败")
+	}
+}
+
+// due 设备距离上次记录是否超过 loginInterval，是则更新记录时间
+func (r *Recorder) due(key string, now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recent == nil {
+		r.recent = make(map[string]time.Time)
+	}
+	if last, ok := r.recent[key]; ok && now.Sub(last) < loginInterval {
+		return false
+	}
+	// 顺便清理过期的条目，避免长期运行后占用过多内存
+	for k, t := range r.recent {
+		if now.Sub(t) >= loginInterval {
+			delete(r.recent, k)
+		}
+	}
+	r.recent[key] = now
+	return true
+}
+
+// Sent 记录从账户发出的邮件（主题和 Message-ID 从邮件头读取），失败时只记录日志（不影响发信）
+func (r *Recorder) Sent(ctx context.Context, userEmail, protocol, remoteIP string, recipients []string, data []byte) {
+	if r == nil || r.Storage == nil {
+		return
+	}
+	record := &storage.SentRecord{
+		UserEmail:  userEmail,
+		Recipients: recipients,
+		Protocol:   protocol,
+		RemoteIP:   remoteIP,
+	}
+	if msg, err := mailparse.ParseHeader(data); err == nil {
+		record.MessageID, record.Subject = msg.MessageID, msg.Subject
+	}
+	if err := r.Storage.RecordSentMail(ctx, record); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("user", userEmail).Str("protocol", protocol).Msg("记录发信记录失败")
+	}
+}
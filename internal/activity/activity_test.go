@@ -0,0 +1,66 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// memoryStorage 记录保存的登录和发信记录
+type memoryStorage struct {
+	storage.Driver
+	logins []*storage.LoginRecord
+	sent   []*storage.SentRecord
+}
+
+func (m *memoryStorage) RecordLogin(ctx context.Context, record *storage.LoginRecord) error {
+	m.logins = append(m.logins, record)
+	return nil
+}
+
+func (m *memoryStorage) RecordSentMail(ctx context.Context, record *storage.SentRecord) error {
+	m.sent = append(m.sent, record)
+	return nil
+}
+
+func TestLoginDeduplicated(t *testing.T) {
+	store := &memoryStorage{}
+	r := New(store)
+	ctx := context.Background()
+
+	r.Login(ctx, "alice@example.com", ProtocolIMAP, "192.0.2.1", "")
+	r.Login(ctx, "Alice@example.com", ProtocolIMAP, "192.0.2.1", "")
+	r.Login(ctx, "alice@example.com", ProtocolIMAP, "198.51.100.7", "")
+	r.Login(ctx, "alice@example.com", ProtocolWebMail, "192.0.2.1", "Firefox")
+	if len(store.logins) != 3 {
+		t.Fatalf("记录了 %d 次登录, want 3（同一设备的重复登录只记录一次）", len(store.logins))
+	}
+
+	// 超过间隔后再次记录
+	r.recent["alice@example.com\x00"+ProtocolIMAP+"\x00192.0.2.1\x00"] = time.Now().Add(-loginInterval)
+	r.Login(ctx, "alice@example.com", ProtocolIMAP, "192.0.2.1", "")
+	if len(store.logins) != 4 {
+		t.Errorf("超过间隔后应该再次记录, 共 %d 次", len(store.logins))
+	}
+
+	// 为空时不记录
+	var none *Recorder
+	none.Login(ctx, "alice@example.com", ProtocolIMAP, "192.0.2.1", "")
+}
+
+func TestSent(t *testing.T) {
+	store := &memoryStorage{}
+	r := New(store)
+	data := []byte("Message-ID: <m1@example.com>\r\nSubject: =?UTF-8?B?5oql5Lu3?=\r\n\r\nbody\r\n")
+	r.Sent(context.Background(), "alice@example.com", ProtocolSMTP, "192.0.2.1", []string{"carol@remote.test"}, data)
+
+	if len(store.sent) != 1 {
+		t.Fatalf("发信记录 = %d 条, want 1", len(store.sent))
+	}
+	got := store.sent[0]
+	if got.MessageID != "m1@example.com" || got.Subject != "报价" || got.Protocol != ProtocolSMTP || got.RemoteIP != "192.0.2.1" {
+		t.Errorf("发信记录 = %+v", got)
+	}
+}
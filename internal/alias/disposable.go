@@ -0,0 +1,51 @@
+package alias
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDisposableTTL 临时别名的默认有效期
+	DefaultDisposableTTL = 7 * 24 * time.Hour
+	// MaxDisposableTTL 临时别名的最长有效期
+	MaxDisposableTTL = 90 * 24 * time.Hour
+	// DisposableRetention 临时别名过期或作废后保留的时间（便于查看统计），之后自动删除
+	DisposableRetention = 30 * 24 * time.Hour
+)
+
+// defaultLabel 未指定标签时使用的前缀
+const defaultLabel = "tmp"
+
+// suffixAlphabet 随机后缀使用的字符
+const suffixAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// NewDisposableAddress 生成临时别名地址，格式为 <标签>-<4 位随机字符>@<域名>（如 shop-x7f2@example.com）
+func NewDisposableAddress(label, domain string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		}
+		if b.Len() >= 20 {
+			break
+		}
+	}
+	label = strings.Trim(b.String(), "-")
+	if label == "" {
+		label = defaultLabel
+	}
+
+	suffix := make([]byte, 4)
+	for i := range suffix {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(suffixAlphabet))))
+		if err != nil {
+			return "", fmt.Errorf("生成随机后缀失败: %w", err)
+		}
+		suffix[i] = suffixAlphabet[n.Int64()]
+	}
+	return fmt.Sprintf("%s-%s@%s", label, suffix, strings.ToLower(domain)), nil
+}
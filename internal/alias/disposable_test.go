@@ -0,0 +1,33 @@
+package alias
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewDisposableAddress(t *testing.T) {
+	tests := []struct {
+		label  string
+		prefix string
+	}{
+		{"shop", "shop-"},
+		{"My Shop!", "myshop-"},
+		{"", "tmp-"},
+		{"---", "tmp-"},
+		{"averyveryverylonglabelthatkeepsgoing", "averyveryverylongla"},
+	}
+
+	for _, tt := range tests {
+		address, err := NewDisposableAddress(tt.label, "Example.com")
+		if err != nil {
+			t.Fatalf("生成临时别名失败: %v", err)
+		}
+		if !strings.HasPrefix(address, tt.prefix) || !strings.HasSuffix(address, "@example.com") {
+			t.Errorf("NewDisposableAddress(%q) = %q", tt.label, address)
+		}
+		local := address[:strings.Index(address, "@")]
+		if !localPartPattern.MatchString(local) {
+			t.Errorf("生成的地址包含无效字符: %q", address)
+		}
+	}
+}
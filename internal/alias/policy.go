@@ -0,0 +1,103 @@
+// Package alias 实现用户自助别名的策略检查
+package alias
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// UserPlaceholder 模式中代表用户本地部分的占位符
+const UserPlaceholder = "{user}"
+
+// DefaultPatterns 策略未配置模式时允许的别名（plus 地址，如 alice+shop@example.com）
+var DefaultPatterns = []string{UserPlaceholder + "+*"}
+
+var (
+	// ErrNotAllowed 域名未开放自助别名
+	ErrNotAllowed = errors.New("该域名不允许自助创建别名")
+	// ErrLimitReached 别名数量已达上限
+	ErrLimitReached = errors.New("别名数量已达上限")
+	// ErrPatternMismatch 别名不符合域名策略允许的模式
+	ErrPatternMismatch = errors.New("别名不符合域名策略")
+	// ErrInvalidAddress 别名地址无效
+	ErrInvalidAddress = errors.New("无效的别名地址")
+)
+
+// localPartPattern 允许的本地部分字符
+var localPartPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._+-]{0,63}$`)
+
+// ValidatePatterns 检查模式语法
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" || strings.Contains(pattern, ",") {
+			return fmt.Errorf("无效的别名模式 %q", pattern)
+		}
+		if _, err := path.Match(strings.ReplaceAll(pattern, UserPlaceholder, "user"), ""); err != nil {
+			return fmt.Errorf("无效的别名模式 %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Check 检查用户是否可以创建别名地址，owned 为用户已创建的别名数
+// 别名必须与用户在同一域名下，数量不超过策略上限，且本地部分匹配策略中的某个模式
+func Check(policy *storage.AliasPolicy, address, userEmail string, owned int) error {
+	local, domain, ok := split(address)
+	if !ok || !localPartPattern.MatchString(local) {
+		return ErrInvalidAddress
+	}
+	userLocal, userDomain, ok := split(userEmail)
+	if !ok || domain != userDomain {
+		return fmt.Errorf("%w: 只能在 %s 下创建别名", ErrInvalidAddress, userDomain)
+	}
+	if address == userEmail {
+		return fmt.Errorf("%w: 不能使用自己的地址", ErrInvalidAddress)
+	}
+
+	if policy == nil || policy.MaxAliases <= 0 {
+		return ErrNotAllowed
+	}
+	if owned >= policy.MaxAliases {
+		return fmt.Errorf("%w（%d）", ErrLimitReached, policy.MaxAliases)
+	}
+
+	patterns := policy.Patterns
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	for _, pattern := range patterns {
+		// 用户本地部分中的 glob 元字符需要转义
+		pattern = strings.ReplaceAll(pattern, UserPlaceholder, escape(userLocal))
+		if matched, err := path.Match(pattern, local); err == nil && matched {
+			return nil
+		}
+	}
+	return ErrPatternMismatch
+}
+
+// split 拆分并规范化邮箱地址
+func split(address string) (string, string, bool) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return "", "", false
+	}
+	return address[:at], address[at+1:], true
+}
+
+// escape 转义 glob 元字符
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package alias
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestCheck(t *testing.T) {
+	policy := &storage.AliasPolicy{Domain: "example.com", MaxAliases: 2}
+
+	tests := []struct {
+		name    string
+		policy  *storage.AliasPolicy
+		address string
+		owned   int
+		wantErr error
+	}{
+		{"plus address", policy, "alice+shop@example.com", 0, nil},
+		{"uppercase", policy, "Alice+News@Example.com", 1, nil},
+		{"no policy", nil, "alice+shop@example.com", 0, ErrNotAllowed},
+		{"disabled", &storage.AliasPolicy{MaxAliases: 0}, "alice+shop@example.com", 0, ErrNotAllowed},
+		{"limit reached", policy, "alice+shop@example.com", 2, ErrLimitReached},
+		{"other user's plus address", policy, "bob+shop@example.com", 0, ErrPatternMismatch},
+		{"free-form not allowed by default", policy, "sales@example.com", 0, ErrPatternMismatch},
+		{"other domain", policy, "alice+shop@other.com", 0, ErrInvalidAddress},
+		{"own address", policy, "alice@example.com", 0, ErrInvalidAddress},
+		{"invalid characters", policy, "alice+a b@example.com", 0, ErrInvalidAddress},
+		{"custom pattern", &storage.AliasPolicy{MaxAliases: 5, Patterns: []string{"{user}.*", "team-*"}}, "team-sales@example.com", 0, nil},
+		{"custom pattern mismatch", &storage.AliasPolicy{MaxAliases: 5, Patterns: []string{"{user}.*"}}, "alice+x@example.com", 0, ErrPatternMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.policy, tt.address, "alice@example.com", tt.owned)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	if err := ValidatePatterns([]string{"{user}+*", "team-*"}); err != nil {
+		t.Errorf("有效的模式不应该报错: %v", err)
+	}
+	for _, pattern := range []string{"", "a,b", "[abc"} {
+		if err := ValidatePatterns([]string{pattern}); err == nil {
+			t.Errorf("模式 %q 应该无效", pattern)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package antispam
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// AuthResults 上游网关验证的结果（Authentication-Results，RFC 8601）
+type AuthResults struct {
+	AuthServID string // 添加验证结果的主机（authserv-id）
+	SPF        Result
+	DKIM       Result // 任一签名验证通过为通过
+	DMARC      Result // 只区分通过和失败
+}
+
+// TrustedUpstream 受信任的上游网关：gmz 部署在过滤网关之后时，来自网关的邮件对网关 IP 检查 SPF 必然失败，
+// 改为使用网关添加的 Authentication-Results（没有时使用 ARC-Authentication-Results）
+type TrustedUpstream struct {
+	networks    []*net.IPNet
+	authServIDs map[string]bool // 信任的 authserv-id，为空时信任最上面的验证结果
+}
+
+// NewTrustedUpstream 创建受信任的上游网关，hosts 为网关的 IP 或网段，
+// authServIDs 为网关验证结果中的 authserv-id（通常是网关主机名，可为空）
+func NewTrustedUpstream(hosts, authServIDs []string) (*TrustedUpstream, error) {
+	networks, err := parseNetworks(hosts)
+	if err != nil {
+		return nil, err
+	}
+	t := &TrustedUpstream{networks: networks, authServIDs: make(map[string]bool)}
+	for _, id := range authServIDs {
+		t.authServIDs[strings.ToLower(id)] = true
+	}
+	return t, nil
+}
+
+// parseNetworks 解析 IP 地址或网段列表，单个 IP 作为只包含该地址的网段
+func parseNetworks(hosts []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, host := range hosts {
+		if !strings.Contains(host, "/") {
+			ip := net.ParseIP(host)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", host)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(host)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段: %s", host)
+		}
+		networks = append(networks, ipNet)
+	}
+	return networks, nil
+}
+
+// Contains IP 是否为受信任的网关
+func (t *TrustedUpstream) Contains(ip net.IP) bool {
+	for _, ipNet := range t.networks {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// trusts 是否信任 authserv-id 的验证结果
+func (t *TrustedUpstream) trusts(authServID string) bool {
+	return len(t.authServIDs) == 0 || t.authServIDs[strings.ToLower(authServID)]
+}
+
+// Results 从网关转来的邮件中取出网关的验证结果：优先使用最上面的受信任的 Authentication-Results，
+// 没有时使用实例号最大的受信任的 ARC-Authentication-Results；都没有时返回 nil
+func (t *TrustedUpstream) Results(raw []byte) *AuthResults {
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw))).ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return nil
+	}
+
+	for _, value := range header.Values("Authentication-Results") {
+		if results := ParseAuthResults(value); results != nil && t.trusts(results.AuthServID) {
+			return results
+		}
+		if len(t.authServIDs) == 0 {
+			// 没有配置 authserv-id 时只信任网关添加的（最上面的）验证结果
+			break
+		}
+	}
+
+	var best *AuthResults
+	bestInstance := 0
+	for _, value := range header.Values("Arc-Authentication-Results") {
+		instance, rest, ok := strings.Cut(value, ";")
+		if !ok {
+			continue
+		}
+		name, num, _ := strings.Cut(strings.TrimSpace(instance), "=")
+		i, err := strconv.Atoi(strings.TrimSpace(num))
+		if !strings.EqualFold(strings.TrimSpace(name), "i") || err != nil || i <= bestInstance {
+			continue
+		}
+		if results := ParseAuthResults(rest); results != nil && t.trusts(results.AuthServID) {
+			best, bestInstance = results, i
+		}
+	}
+	return best
+}
+
+// StripForged 删除带有受信任 authserv-id 的 Authentication-Results 头（来自其他来源的邮件中视为伪造，RFC 8601 第 5 节），
+// 没有配置 authserv-id 时原样返回
+func (t *TrustedUpstream) StripForged(raw []byte) []byte {
+	if len(t.authServIDs) == 0 {
+		return raw
+	}
+
+	var out bytes.Buffer
+	rest := raw
+	drop := false
+	for len(rest) > 0 {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		rest = rest[len(line):]
+
+		trimmed := bytes.TrimRight(line, "\r\n")
+		if len(trimmed) == 0 {
+			// 邮件头结束
+			out.Write(line)
+			out.Write(rest)
+			break
+		}
+		if trimmed[0] != ' ' && trimmed[0] != '\t' {
+			drop = false
+			if name, value, ok := bytes.Cut(trimmed, []byte(":")); ok && strings.EqualFold(string(name), "Authentication-Results") {
+				drop = t.authServIDs[strings.ToLower(authServID(unfoldHeader(value, rest)))]
+			}
+		}
+		if !drop {
+			out.Write(line)
+		}
+	}
+	return out.Bytes()
+}
+
+// unfoldHeader 取出邮件头第一行的值及其续行
+func unfoldHeader(value, rest []byte) string {
+	v := string(value)
+	for len(rest) > 0 && (rest[0] == ' ' || rest[0] == '\t') {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		rest = rest[len(line):]
+		v += " " + strings.TrimSpace(string(line))
+	}
+	return v
+}
+
+// ParseAuthResults 解析 Authentication-Results 头的值，如
+// "mx.example.com; spf=pass smtp.mailfrom=example.net; dkim=pass header.d=example.net"，格式无效时返回 nil
+func ParseAuthResults(value string) *AuthResults {
+	parts := strings.Split(stripComments(value), ";")
+	id := authServID(parts[0])
+	if id == "" {
+		return nil
+	}
+
+	results := &AuthResults{AuthServID: id}
+	dkim := ResultNone
+	for _, part := range parts[1:] {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			continue
+		}
+		method, _, _ = strings.Cut(strings.ToLower(method), "/") // 去掉方法版本号
+		r := parseAuthResult(result)
+		switch method {
+		case "spf":
+			results.SPF = r
+		case "dkim":
+			// 任一签名通过即为通过，否则以第一个失败为准
+			if r == ResultPass || (dkim != ResultPass && dkim != ResultFail) {
+				dkim = r
+			}
+		case "dmarc":
+			results.DMARC = r
+		}
+	}
+	results.DKIM = dkim
+	return results
+}
+
+// authServID 取出 authserv-id（去掉可选的版本号和之后的验证结果）
+func authServID(s string) string {
+	s, _, _ = strings.Cut(stripComments(s), ";")
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.Trim(fields[0], `"`)
+}
+
+// parseAuthResult 将验证结果转换为 Result
+func parseAuthResult(s string) Result {
+	switch strings.ToLower(s) {
+	case "pass":
+		return ResultPass
+	case "fail", "hardfail":
+		return ResultFail
+	case "softfail":
+		return ResultSoftFail
+	case "neutral", "policy":
+		return ResultNeutral
+	case "temperror":
+		return ResultTempError
+	case "permerror":
+		return ResultPermError
+	default:
+		return ResultNone
+	}
+}
+
+// stripComments 去掉邮件头中的注释（括号中的内容，可以嵌套）
+func stripComments(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,128 @@
+package antispam
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseAuthResults(t *testing.T) {
+	results := ParseAuthResults(`gw.example.com (gateway 1.0); spf=pass (sender permitted) smtp.mailfrom=example.net;
+	dkim=fail header.d=other.test; dkim=pass header.d=example.net; dmarc=pass header.from=example.net`)
+	if results == nil {
+		t.Fatal("解析失败")
+	}
+	if results.AuthServID != "gw.example.com" {
+		t.Errorf("AuthServID = %q", results.AuthServID)
+	}
+	if results.SPF != ResultPass || results.DKIM != ResultPass || results.DMARC != ResultPass {
+		t.Errorf("结果 = %v %v %v，应该都是 pass", results.SPF, results.DKIM, results.DMARC)
+	}
+
+	results = ParseAuthResults("gw.example.com 1; spf=softfail; dkim=fail")
+	if results.SPF != ResultSoftFail || results.DKIM != ResultFail || results.DMARC != ResultNone {
+		t.Errorf("结果 = %v %v %v", results.SPF, results.DKIM, results.DMARC)
+	}
+
+	if ParseAuthResults(" (comment only)") != nil {
+		t.Error("没有 authserv-id 时应该返回 nil")
+	}
+}
+
+func TestTrustedUpstream(t *testing.T) {
+	upstream, err := NewTrustedUpstream([]string{"192.0.2.10", "198.51.100.0/24"}, []string{"GW.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]bool{"192.0.2.10": true, "192.0.2.11": false, "198.51.100.200": true} {
+		if got := upstream.Contains(net.ParseIP(ip)); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", ip, got, want)
+		}
+	}
+	if _, err := NewTrustedUpstream([]string{"gw.example.com"}, nil); err == nil {
+		t.Error("无效的地址应该返回错误")
+	}
+
+	// 使用受信任的 authserv-id 的验证结果，跳过其他主机添加的结果
+	raw := []byte("Authentication-Results: mx.other.test; spf=fail\r\n" +
+		"Authentication-Results: gw.example.com;\r\n\tspf=pass smtp.mailfrom=example.net\r\n" +
+		"From: a@example.net\r\n\r\nbody\r\n")
+	results := upstream.Results(raw)
+	if results == nil || results.AuthServID != "gw.example.com" || results.SPF != ResultPass {
+		t.Fatalf("Results() = %+v", results)
+	}
+
+	// 没有 Authentication-Results 时使用实例号最大的 ARC-Authentication-Results
+	raw = []byte("ARC-Authentication-Results: i=1; gw.example.com; spf=fail\r\n" +
+		"ARC-Authentication-Results: i=2; gw.example.com; spf=pass; dkim=pass\r\n" +
+		"From: a@example.net\r\n\r\nbody\r\n")
+	results = upstream.Results(raw)
+	if results == nil || results.SPF != ResultPass || results.DKIM != ResultPass {
+		t.Fatalf("Results() = %+v", results)
+	}
+
+	if upstream.Results([]byte("From: a@example.net\r\n\r\nbody\r\n")) != nil {
+		t.Error("没有验证结果时应该返回 nil")
+	}
+}
+
+func TestTrustedUpstream_StripForged(t *testing.T) {
+	upstream, err := NewTrustedUpstream([]string{"192.0.2.10"}, []string{"gw.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := "Authentication-Results: gw.example.com;\r\n\tspf=pass\r\n" +
+		"Authentication-Results: mx.other.test; spf=fail\r\n" +
+		"Subject: test\r\n\r\n" +
+		"Authentication-Results: gw.example.com; spf=pass\r\n"
+	got := string(upstream.StripForged([]byte(raw)))
+	want := "Authentication-Results: mx.other.test; spf=fail\r\n" +
+		"Subject: test\r\n\r\n" +
+		"Authentication-Results: gw.example.com; spf=pass\r\n"
+	if got != want {
+		t.Errorf("StripForged() = %q, want %q", got, want)
+	}
+
+	// 没有配置 authserv-id 时无法区分伪造的结果，原样保留
+	upstream, _ = NewTrustedUpstream([]string{"192.0.2.10"}, nil)
+	if got := string(upstream.StripForged([]byte(raw))); got != raw {
+		t.Errorf("StripForged() = %q", got)
+	}
+}
+
+func TestRulesUseUpstreamResults(t *testing.T) {
+	ctx := context.Background()
+	req := &CheckRequest{
+		IP:       net.ParseIP("192.0.2.10"),
+		From:     "a@example.net",
+		Domain:   "example.net",
+		Upstream: &AuthResults{AuthServID: "gw.example.com", SPF: ResultPass, DKIM: ResultFail},
+	}
+
+	// 没有配置 SPF/DKIM 检查器时也使用网关的结果，不查询 DNS
+	result, err := NewSPFRule(nil).Check(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score >= 0 || !strings.Contains(result.Reason, "通过") {
+		t.Errorf("SPF 规则 = %+v，应该使用网关的通过结果", result)
+	}
+
+	result, err = NewDKIMRule(nil).Check(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score <= 0 || !strings.Contains(result.Reason, "失败") {
+		t.Errorf("DKIM 规则 = %+v，应该使用网关的失败结果", result)
+	}
+
+	req.Upstream.DKIM = ResultNone
+	result, err = NewDKIMRule(nil).Check(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Score != 0 {
+		t.Errorf("网关没有 DKIM 结果时不应该计分: %+v", result)
+	}
+}
@@ -0,0 +1,275 @@
+package antispam
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// DefaultBayesMinTraining 使用模型所需的最少垃圾邮件和正常邮件训练样本数（各自）
+const DefaultBayesMinTraining = 20
+
+const (
+	// bayesMaxTokens 每封邮件最多提取的特征数
+	bayesMaxTokens = 500
+	// bayesInteresting 计算概率时使用的最显著（概率离 0.5 最远）的特征数
+	bayesInteresting = 15
+	// bayesStrength 特征概率向 0.5 平滑的强度（Robinson），出现次数少的特征影响更小
+	bayesStrength = 1.0
+	// bayesQueueSize 等待训练的邮件队列长度，队列满时丢弃新的训练样本
+	bayesQueueSize = 256
+)
+
+// 用户标记邮件是否为垃圾邮件的 IMAP 关键字（RFC 5788 登记的关键字），WebMail 设置时训练分类器
+const (
+	JunkKeyword    = "$Junk"
+	NotJunkKeyword = "$NotJunk"
+)
+
+// 垃圾邮件概率达到 bayesSpamThreshold 时按概率加分（最多 bayesMaxScore，概率 0.92 以上的邮件单独达到隔离分数），
+// 不超过 bayesHamThreshold 时减分
+const (
+	bayesSpamThreshold = 0.9
+	bayesHamThreshold  = 0.1
+	bayesMaxScore      = 60
+	bayesHamScore      = -15
+)
+
+// Bayes 垃圾邮件贝叶斯分类器：用户把邮件移入垃圾邮件文件夹（或标记为 $Junk）时作为垃圾邮件训练，
+// 从垃圾邮件文件夹移出（或标记为 $NotJunk）时作为正常邮件训练；每次训练同时计入该用户的模型和全局模型，
+// 检查时优先使用收件人的模型，样本不足时使用全局模型
+type Bayes struct {
+	storage     storage.Driver
+	minTraining int
+	queue       chan bayesSample
+}
+
+// bayesSample 等待训练的邮件
+type bayesSample struct {
+	userEmail string
+	raw       []byte
+	spam      bool
+}
+
+// NewBayes 创建贝叶斯分类器，minTraining 为使用模型所需的最少样本数（0 时使用默认值）
+func NewBayes(driver storage.Driver, minTraining int) *Bayes {
+	if minTraining <= 0 {
+		minTraining = DefaultBayesMinTraining
+	}
+	return &Bayes{storage: driver, minTraining: minTraining, queue: make(chan bayesSample, bayesQueueSize)}
+}
+
+// Name 返回规则名称
+func (b *Bayes) Name() string {
+	return "bayes"
+}
+
+// Priority 返回优先级（与内容指纹相同，在认证检查之后、Rspamd 之前执行）
+func (b *Bayes) Priority() int {
+	return 6
+}
+
+// Check 计算邮件是垃圾邮件的概率
+func (b *Bayes) Check(ctx context.Context, req *CheckRequest) (*RuleResult, error) {
+	pass := &RuleResult{Action: ActionContinue, Continue: true}
+	tokens := BayesTokens(req.message())
+	if len(tokens) == 0 {
+		return pass, nil
+	}
+	probability, ok, err := b.Probability(ctx, strings.ToLower(req.To), tokens)
+	if err != nil || !ok {
+		return pass, err
+	}
+
+	result := &RuleResult{
+		Action:   ActionContinue,
+		Continue: true,
+		Headers:  map[string]string{"X-Spam-Bayes": fmt.Sprintf("%.2f", probability)},
+	}
+	switch {
+	case probability >= bayesSpamThreshold:
+		result.Score = int(math.Round((probability - 0.5) * 2 * bayesMaxScore))
+		result.Reason = fmt.Sprintf("贝叶斯分类：垃圾邮件概率 %.2f", probability)
+	case probability <= bayesHamThreshold:
+		result.Score = bayesHamScore
+		result.Reason = fmt.Sprintf("贝叶斯分类：正常邮件（垃圾邮件概率 %.2f）", probability)
+	}
+	return result, nil
+}
+
+// Probability 计算特征属于垃圾邮件的概率：优先使用用户的模型，样本不足时使用全局模型，都不足时返回 false
+func (b *Bayes) Probability(ctx context.Context, userEmail string, tokens []string) (float64, bool, error) {
+	owners := []string{""}
+	if userEmail != "" {
+		owners = []string{userEmail, ""}
+	}
+	for _, owner := range owners {
+		model, err := b.storage.GetSpamModel(ctx, owner, tokens)
+		if err != nil {
+			return 0, false, err
+		}
+		if model.SpamDocs >= b.minTraining && model.HamDocs >= b.minTraining {
+			return combine(model, tokens), true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// combine 合并最显著的特征的概率（假设特征相互独立）
+func combine(model *storage.SpamModel, tokens []string) float64 {
+	var probabilities []float64
+	for _, token := range tokens {
+		spam, ham := model.Spam[token], model.Ham[token]
+		if spam+ham == 0 {
+			continue
+		}
+		spamFreq := float64(spam) / float64(model.SpamDocs)
+		hamFreq := float64(ham) / float64(model.HamDocs)
+		p := spamFreq / (spamFreq + hamFreq)
+		n := float64(spam + ham)
+		p = (bayesStrength*0.5 + n*p) / (bayesStrength + n)
+		probabilities = append(probabilities, math.Min(math.Max(p, 0.01), 0.99))
+	}
+	if len(probabilities) == 0 {
+		return 0.5
+	}
+	sort.Slice(probabilities, func(i, j int) bool {
+		return math.Abs(probabilities[i]-0.5) > math.Abs(probabilities[j]-0.5)
+	})
+	if len(probabilities) > bayesInteresting {
+		probabilities = probabilities[:bayesInteresting]
+	}
+
+	// P = 1 / (1 + Π (1-p)/p)，按对数计算避免下溢
+	var eta float64
+	for _, p := range probabilities {
+		eta += math.Log(1-p) - math.Log(p)
+	}
+	return 1 / (1 + math.Exp(eta))
+}
+
+// Learn 把邮件加入训练队列（不阻塞调用方，队列满时丢弃）
+func (b *Bayes) Learn(userEmail string, raw []byte, spam bool) {
+	select {
+	case b.queue <- bayesSample{userEmail: userEmail, raw: raw, spam: spam}:
+	default:
+		logger.Warn().Str("user", userEmail).Msg("垃圾邮件训练队列已满，丢弃训练样本")
+	}
+}
+
+// Run 在后台依次训练队列中的邮件，直到 ctx 取消
+func (b *Bayes) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sample := <-b.queue:
+			if err := b.Train(ctx, sample.userEmail, sample.raw, sample.spam); err != nil {
+				logger.WarnCtx(ctx).Err(err).Str("user", sample.userEmail).Bool("spam", sample.spam).Msg("训练垃圾邮件分类器失败")
+			}
+		}
+	}
+}
+
+// Train 用一封邮件训练用户的模型和全局模型（同一封邮件重复训练时跳过，改判时撤销之前的样本）
+func (b *Bayes) Train(ctx context.Context, userEmail string, raw []byte, spam bool) error {
+	tokens := BayesTokens(raw)
+	if len(tokens) == 0 {
+		return nil
+	}
+	sum := sha256.Sum256(raw)
+	_, err := b.storage.TrainSpam(ctx, strings.ToLower(userEmail), hex.EncodeToString(sum[:]), tokens, spam)
+	return err
+}
+
+// BayesTokens 提取贝叶斯分类特征：发件域名、主题词、正文词和正文链接的域名；
+// 拉丁字母和数字按词（3 ~ 20 个字符，不包括纯数字），汉字等按相邻两字
+func BayesTokens(raw []byte) []string {
+	msg, err := mailparse.Parse(raw)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if seen[token] || len(tokens) >= bayesMaxTokens {
+			return
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	if msg.From != nil {
+		if at := strings.LastIndex(msg.From.Address, "@"); at >= 0 {
+			add("from:" + strings.ToLower(msg.From.Address[at+1:]))
+		}
+	}
+	for _, word := range bayesWords(msg.Subject) {
+		add("subject:" + word)
+	}
+
+	text := msg.Text
+	if strings.TrimSpace(text) == "" {
+		text = entityPattern.ReplaceAllString(tagPattern.ReplaceAllString(msg.HTML, " "), " ")
+	}
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		if u, err := url.Parse(link); err == nil && u.Hostname() != "" {
+			add("url:" + strings.ToLower(u.Hostname()))
+		}
+	}
+	text = urlPattern.ReplaceAllString(text, " ")
+	for _, word := range bayesWords(text) {
+		add(word)
+	}
+	return tokens
+}
+
+// bayesWords 切分文本：拉丁字母和数字按词，汉字、假名和谚文按相邻两字
+func bayesWords(text string) []string {
+	var words []string
+	var word, han []rune
+	flush := func() {
+		if len(word) >= 3 && len(word) <= 20 && strings.IndexFunc(string(word), unicode.IsLetter) >= 0 {
+			words = append(words, string(word))
+		}
+		if len(han) == 1 {
+			words = append(words, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			words = append(words, string(han[i:i+2]))
+		}
+		word, han = word[:0], han[:0]
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,114 @@
+package antispam
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// hamMail 正常邮件：团队内部的会议纪要
+func hamMail(id int) []byte {
+	return fmt.Appendf(nil, "From: alice@team.test\r\nSubject: 周会纪要 %d\r\n\r\n"+
+		"Hi team, attached are the minutes from the planning meeting #%d. Please review the action items\r\n"+
+		"and the release schedule before Friday. 下周继续讨论发布计划。\r\n", id, id)
+}
+
+func TestBayesTokens(t *testing.T) {
+	tokens := BayesTokens(campaign("a@spam.test", "bob@example.com", "12345"))
+	for _, want := range []string{"from:spam.test", "subject:limited", "url:promo.example", "congratulations", "prize"} {
+		if !slices.Contains(tokens, want) {
+			t.Errorf("特征应该包含 %q: %v", want, tokens)
+		}
+	}
+	for _, unwanted := range []string{"12345", "https", "subject:12345"} {
+		if slices.Contains(tokens, unwanted) {
+			t.Errorf("特征不应该包含 %q", unwanted)
+		}
+	}
+	if tokens := BayesTokens(hamMail(1)); !slices.Contains(tokens, "subject:周会") || !slices.Contains(tokens, "发布") {
+		t.Errorf("汉字应该按相邻两字切分: %v", tokens)
+	}
+}
+
+func TestBayes(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	bayes := NewBayes(driver, 3)
+
+	check := func(to string, raw []byte) *RuleResult {
+		t.Helper()
+		result, err := bayes.Check(ctx, &CheckRequest{From: "x@any.test", To: to, Raw: raw})
+		if err != nil {
+			t.Fatalf("检查失败: %v", err)
+		}
+		return result
+	}
+
+	// 样本不足时不评分
+	if result := check("bob@example.com", campaign("a@spam.test", "bob@example.com", "1")); result.Score != 0 || result.Headers != nil {
+		t.Errorf("样本不足时不应该评分: %+v", result)
+	}
+
+	// bob 训练后，bob 和样本不足的 carol（使用全局模型）都能识别
+	for i := range 3 {
+		if err := bayes.Train(ctx, "bob@example.com", campaign("a@spam.test", "bob@example.com", fmt.Sprint(i)), true); err != nil {
+			t.Fatal(err)
+		}
+		if err := bayes.Train(ctx, "bob@example.com", hamMail(i), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, to := range []string{"bob@example.com", "carol@example.com"} {
+		if result := check(to, campaign("b@spam.test", to, "99")); result.Score < 40 || result.Headers["X-Spam-Bayes"] == "" {
+			t.Errorf("%s: 垃圾邮件应该加分: %+v", to, result)
+		}
+		if result := check(to, hamMail(99)); result.Score != bayesHamScore {
+			t.Errorf("%s: 正常邮件应该减分: %+v", to, result)
+		}
+	}
+
+	// 同一封邮件重复训练时跳过，改判时撤销之前的样本
+	model, _ := driver.GetSpamModel(ctx, "bob@example.com", nil)
+	if err := bayes.Train(ctx, "bob@example.com", hamMail(0), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := bayes.Train(ctx, "bob@example.com", hamMail(1), true); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := driver.GetSpamModel(ctx, "bob@example.com", nil)
+	if after.SpamDocs != model.SpamDocs+1 || after.HamDocs != model.HamDocs-1 {
+		t.Errorf("训练样本数 = %d/%d，之前 %d/%d", after.SpamDocs, after.HamDocs, model.SpamDocs, model.HamDocs)
+	}
+	global, _ := driver.GetSpamModel(ctx, "", nil)
+	if global.SpamDocs != after.SpamDocs || global.HamDocs != after.HamDocs {
+		t.Errorf("全局模型样本数 = %d/%d，期望与用户模型相同", global.SpamDocs, global.HamDocs)
+	}
+
+	// 后台训练
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go bayes.Run(runCtx)
+	bayes.Learn("dave@example.com", campaign("a@spam.test", "dave@example.com", "7"), true)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		model, err := driver.GetSpamModel(ctx, "dave@example.com", nil)
+		if err == nil && model.SpamDocs == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("后台训练没有完成")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,26 +1,55 @@
 package antispam
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
-// DKIM DKIM 签名和验证
+// DKIM 规范化算法（RFC 6376 3.4）
+const (
+	CanonSimple  = "simple"
+	CanonRelaxed = "relaxed"
+)
+
+// dkimSignedHeaders 参与签名的邮件头（存在时才签名，From 必须存在）
+var dkimSignedHeaders = []string{
+	"From", "Reply-To", "Subject", "Date", "To", "Cc", "Message-ID",
+	"In-Reply-To", "References", "MIME-Version", "Content-Type", "Content-Transfer-Encoding",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// ErrDKIMSignature DKIM-Signature 头格式错误或不支持
+var ErrDKIMSignature = errors.New("无效的 DKIM 签名")
+
+// DKIM DKIM 签名和验证（RFC 6376，支持 rsa-sha256 和 ed25519-sha256，simple/relaxed 规范化）
 type DKIM struct {
-	privateKey crypto.PrivateKey
-	publicKey  crypto.PublicKey
-	selector   string
-	domain     string
+	privateKey  crypto.PrivateKey
+	publicKey   crypto.PublicKey
+	selector    string
+	domain      string
+	headerCanon string
+	bodyCanon   string
 }
 
-// NewDKIM 创建 DKIM 实例
+// headerField 原始邮件头字段（包含折行和结尾的 CRLF）
+type headerField struct {
+	name string
+	raw  string
+}
+
+// NewDKIM 创建 DKIM 实例（默认 relaxed/relaxed 规范化）
 func NewDKIM(domain, selector string, privateKey crypto.PrivateKey) (*DKIM, error) {
 	var publicKey crypto.PublicKey
 
@@ -34,166 +63,421 @@ func NewDKIM(domain, selector string, privateKey crypto.PrivateKey) (*DKIM, erro
 	}
 
 	return &DKIM{
-		privateKey: privateKey,
-		publicKey:  publicKey,
-		selector:   selector,
-		domain:     domain,
+		privateKey:  privateKey,
+		publicKey:   publicKey,
+		selector:    selector,
+		domain:      domain,
+		headerCanon: CanonRelaxed,
+		bodyCanon:   CanonRelaxed,
 	}, nil
 }
 
-// Sign 对邮件进行 DKIM 签名
+// SetCanonicalization 设置规范化算法（如 relaxed/relaxed、relaxed/simple、simple，省略正文算法时为 simple）
+func (d *DKIM) SetCanonicalization(c string) error {
+	header, body, err := parseCanonicalization(c)
+	if err != nil {
+		return err
+	}
+	d.headerCanon, d.bodyCanon = header, body
+	return nil
+}
+
+// Algorithm 返回签名算法（rsa-sha256 或 ed25519-sha256）
+func (d *DKIM) Algorithm() string {
+	if _, ok := d.privateKey.(ed25519.PrivateKey); ok {
+		return "ed25519-sha256"
+	}
+	return "rsa-sha256"
+}
+
+// Domain 返回签名域名（d=）
+func (d *DKIM) Domain() string {
+	return d.domain
+}
+
+// Selector 返回选择器（s=）
+func (d *DKIM) Selector() string {
+	return d.selector
+}
+
+// PublicKeyDNS 返回需要发布在 <selector>._domainkey.<domain> 的 TXT 记录
+func (d *DKIM) PublicKeyDNS() (string, error) {
+	return GetPublicKeyDNS(d.publicKey)
+}
+
+// Sign 对邮件进行 DKIM 签名，返回 DKIM-Signature 头的值
+// headers 按 "名称: 值\r\n" 的格式写出，body 为写出的正文
 func (d *DKIM) Sign(headers map[string]string, body []byte) (string, error) {
-	// 构建签名头
-	signature := d.buildSignature(headers, body)
+	return d.sign(mapHeaderFields(headers), body)
+}
 
-	// 计算签名
-	hash := sha256.New()
-	hash.Write([]byte(signature))
-	hashed := hash.Sum(nil)
+// SignMessage 对完整的原始邮件签名，返回在最前面加上 DKIM-Signature 头的邮件（换行统一为 CRLF）
+func (d *DKIM) SignMessage(raw []byte) ([]byte, error) {
+	raw = toCRLF(raw)
+	fields, body := splitMessage(raw)
+	value, err := d.sign(fields, body)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("DKIM-Signature: "+value+"\r\n"), raw...), nil
+}
+
+// HasSignature 邮件是否已经带有该签名器域名（d=）的 DKIM 签名（如外发前已签名、推迟后再次发送的邮件）
+func (d *DKIM) HasSignature(raw []byte) bool {
+	fields, _ := splitMessage(toCRLF(raw))
+	for _, f := range fields {
+		if !strings.EqualFold(f.name, "DKIM-Signature") {
+			continue
+		}
+		_, value, _ := strings.Cut(f.raw, ":")
+		if strings.EqualFold(parseDKIMTags(value)["d"], d.domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// sign 计算签名
+func (d *DKIM) sign(fields []headerField, body []byte) (string, error) {
+	var signed []string
+	hasFrom := false
+	for _, name := range dkimSignedHeaders {
+		for _, f := range fields {
+			if strings.EqualFold(f.name, name) {
+				signed = append(signed, strings.ToLower(name))
+				hasFrom = hasFrom || name == "From"
+			}
+		}
+	}
+	if !hasFrom {
+		return "", fmt.Errorf("签名失败: 缺少 From 头")
+	}
+
+	bodyHash := sha256.Sum256(canonicalizeBody(body, d.bodyCanon))
+	value := fmt.Sprintf("v=1; a=%s; c=%s/%s;\r\n\td=%s; s=%s; t=%d;\r\n\th=%s;\r\n\tbh=%s;\r\n\tb=",
+		d.Algorithm(), d.headerCanon, d.bodyCanon, d.domain, d.selector, time.Now().Unix(),
+		foldHeaderNames(signed),
+		base64.StdEncoding.EncodeToString(bodyHash[:]),
+	)
+
+	hashed := headerHash(fields, signed, "DKIM-Signature: "+value+"\r\n", d.headerCanon)
 
 	var signatureBytes []byte
 	var err error
-
 	switch key := d.privateKey.(type) {
 	case *rsa.PrivateKey:
 		signatureBytes, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed)
 	case ed25519.PrivateKey:
-		signatureBytes, err = key.Sign(rand.Reader, hashed, crypto.Hash(0))
+		// ed25519-sha256 对 SHA-256 摘要做 Ed25519 签名（RFC 8463）
+		signatureBytes = ed25519.Sign(key, hashed)
 	default:
 		return "", fmt.Errorf("不支持的密钥类型")
 	}
-
 	if err != nil {
 		return "", fmt.Errorf("签名失败: %w", err)
 	}
 
-	// 编码签名
-	signatureB64 := base64.StdEncoding.EncodeToString(signatureBytes)
-
-	// 构建完整的 DKIM-Signature 头
-	dkimHeader := fmt.Sprintf("v=1; a=rsa-sha256; c=relaxed/relaxed; d=%s; s=%s; t=%d; h=%s; bh=%s; b=%s",
-		d.domain,
-		d.selector,
-		time.Now().Unix(),
-		strings.Join(d.getSignedHeaders(headers), ":"),
-		base64.StdEncoding.EncodeToString(hashed),
-		signatureB64,
-	)
-
-	return dkimHeader, nil
+	return value + foldBase64(base64.StdEncoding.EncodeToString(signatureBytes)), nil
 }
 
-// Verify 验证 DKIM 签名
+// Verify 使用本实例的公钥验证 DKIM 签名（headers 和 body 格式同 Sign）
 func (d *DKIM) Verify(headers map[string]string, body []byte, dkimSignature string) (bool, error) {
-	// 解析 DKIM 签名
-	params := d.parseDKIMSignature(dkimSignature)
+	return verifyDKIM(mapHeaderFields(headers), body, "DKIM-Signature: "+dkimSignature+"\r\n", d.publicKey)
+}
 
-	// 提取签名值
-	signatureB64, ok := params["b"]
-	if !ok {
-		return false, fmt.Errorf("未找到签名值")
+// VerifyMessage 使用指定公钥验证原始邮件中的第一个 DKIM-Signature
+func VerifyMessage(raw []byte, publicKey crypto.PublicKey) (bool, error) {
+	fields, body := splitMessage(toCRLF(raw))
+	for _, f := range fields {
+		if strings.EqualFold(f.name, "DKIM-Signature") {
+			return verifyDKIM(fields, body, f.raw, publicKey)
+		}
 	}
+	return false, fmt.Errorf("%w: 未找到 DKIM-Signature 头", ErrDKIMSignature)
+}
 
-	signatureBytes, err := base64.StdEncoding.DecodeString(signatureB64)
+// verifyDKIM 验证签名头字段 sigField（原始格式）
+func verifyDKIM(fields []headerField, body []byte, sigField string, publicKey crypto.PublicKey) (bool, error) {
+	_, value, _ := strings.Cut(sigField, ":")
+	params := parseDKIMTags(value)
+
+	if params["v"] != "1" {
+		return false, fmt.Errorf("%w: 不支持的版本 %q", ErrDKIMSignature, params["v"])
+	}
+	if _, ok := params["l"]; ok {
+		return false, fmt.Errorf("%w: 不支持 l= 标签", ErrDKIMSignature)
+	}
+	headerCanon, bodyCanon, err := parseCanonicalization(params["c"])
 	if err != nil {
-		return false, fmt.Errorf("解码签名失败: %w", err)
+		return false, err
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["b"])
+	if err != nil || len(signature) == 0 {
+		return false, fmt.Errorf("%w: 解码签名失败", ErrDKIMSignature)
+	}
+	bodyHash, err := base64.StdEncoding.DecodeString(params["bh"])
+	if err != nil {
+		return false, fmt.Errorf("%w: 解码正文摘要失败", ErrDKIMSignature)
 	}
 
-	// 重建签名字符串
-	signature := d.buildSignature(headers, body)
+	var signed []string
+	for _, name := range strings.Split(params["h"], ":") {
+		if name = strings.TrimSpace(name); name != "" {
+			signed = append(signed, name)
+		}
+	}
+	hasFrom := false
+	for _, name := range signed {
+		hasFrom = hasFrom || strings.EqualFold(name, "From")
+	}
+	if !hasFrom {
+		return false, fmt.Errorf("%w: 签名未包含 From 头", ErrDKIMSignature)
+	}
 
-	// 计算哈希
-	hash := sha256.New()
-	hash.Write([]byte(signature))
-	hashed := hash.Sum(nil)
+	computed := sha256.Sum256(canonicalizeBody(body, bodyCanon))
+	if !bytes.Equal(computed[:], bodyHash) {
+		return false, nil
+	}
 
-	// 验证签名
-	switch key := d.publicKey.(type) {
-	case *rsa.PublicKey:
-		err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed, signatureBytes)
-	case ed25519.PublicKey:
-		if !ed25519.Verify(key, hashed, signatureBytes) {
-			err = fmt.Errorf("签名验证失败")
+	hashed := headerHash(fields, signed, stripSignature(sigField), headerCanon)
+	switch params["a"] {
+	case "rsa-sha256":
+		key, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return false, fmt.Errorf("%w: 公钥类型与算法不匹配", ErrDKIMSignature)
 		}
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed, signature) == nil, nil
+	case "ed25519-sha256":
+		key, ok := publicKey.(ed25519.PublicKey)
+		if !ok {
+			return false, fmt.Errorf("%w: 公钥类型与算法不匹配", ErrDKIMSignature)
+		}
+		return ed25519.Verify(key, hashed, signature), nil
 	default:
-		return false, fmt.Errorf("不支持的密钥类型")
+		return false, fmt.Errorf("%w: 不支持的算法 %q", ErrDKIMSignature, params["a"])
 	}
+}
 
-	if err != nil {
-		return false, nil
+// headerHash 计算签名头的摘要：按 h= 顺序的规范化邮件头，加上去掉 b= 值且不含结尾 CRLF 的 DKIM-Signature 头
+func headerHash(fields []headerField, signed []string, sigField, canon string) []byte {
+	hash := sha256.New()
+	// 同名头从下往上依次选取（RFC 6376 5.4.2），不存在的实例不参与
+	used := make(map[int]bool)
+	for _, name := range signed {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && strings.EqualFold(fields[i].name, name) {
+				used[i] = true
+				hash.Write([]byte(canonicalizeHeader(fields[i].raw, canon)))
+				break
+			}
+		}
 	}
+	sig := canonicalizeHeader(sigField, canon)
+	hash.Write([]byte(strings.TrimSuffix(sig, "\r\n")))
+	return hash.Sum(nil)
+}
 
-	return true, nil
+// canonicalizeHeader 规范化邮件头字段（raw 包含折行和结尾的 CRLF）
+func canonicalizeHeader(raw, canon string) string {
+	if canon == CanonSimple {
+		return raw
+	}
+	// relaxed：名称小写，展开折行，连续空白压缩为一个空格，去掉冒号两侧和值末尾的空白
+	name, value, _ := strings.Cut(raw, ":")
+	value = strings.NewReplacer("\r\n", "", "\n", "").Replace(value)
+	value = strings.Trim(collapseWSP(value), " ")
+	return strings.ToLower(strings.TrimRight(name, " \t")) + ":" + value + "\r\n"
 }
 
-// buildSignature 构建签名字符串
-func (d *DKIM) buildSignature(headers map[string]string, body []byte) string {
-	// 简化实现：仅包含关键头
-	signedHeaders := []string{"From", "To", "Subject", "Date"}
-	var headerLines []string
+// canonicalizeBody 规范化正文（换行统一为 CRLF）
+func canonicalizeBody(body []byte, canon string) []byte {
+	lines := strings.Split(string(toCRLF(body)), "\r\n")
+	if canon == CanonRelaxed {
+		// relaxed：连续空白压缩为一个空格，去掉行尾空白
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(collapseWSP(line), " ")
+		}
+	}
+	// 去掉末尾空行
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		// 空正文：simple 为一个 CRLF，relaxed 为空
+		if canon == CanonSimple {
+			return []byte("\r\n")
+		}
+		return nil
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
 
-	for _, h := range signedHeaders {
-		if val, ok := headers[h]; ok {
-			headerLines = append(headerLines, fmt.Sprintf("%s: %s", strings.ToLower(h), d.canonicalizeHeader(val)))
+// collapseWSP 将连续的空格和制表符压缩为一个空格
+func collapseWSP(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
 		}
+		b.WriteRune(r)
 	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
 
-	// 规范化邮件体
-	canonicalBody := d.canonicalizeBody(string(body))
+// stripSignature 删除签名头中 b= 的值（包括周围的空白），保留其他内容不变
+func stripSignature(sigField string) string {
+	field := strings.TrimSuffix(sigField, "\r\n")
+	colon := strings.Index(field, ":")
+	if colon < 0 {
+		return sigField
+	}
+	start := colon + 1
+	for start <= len(field) {
+		end := strings.IndexByte(field[start:], ';')
+		if end < 0 {
+			end = len(field)
+		} else {
+			end += start
+		}
+		tag := field[start:end]
+		if eq := strings.IndexByte(tag, '='); eq >= 0 && strings.TrimSpace(tag[:eq]) == "b" {
+			return field[:start+eq+1] + field[end:] + "\r\n"
+		}
+		start = end + 1
+	}
+	return sigField
+}
+
+// parseDKIMTags 解析标签列表（展开折行；b= 和 bh= 的值去掉所有空白）
+func parseDKIMTags(value string) map[string]string {
+	params := make(map[string]string)
+	value = strings.NewReplacer("\r\n", "", "\n", "").Replace(value)
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+		if key == "b" || key == "bh" {
+			val = strings.Join(strings.Fields(val), "")
+		}
+		params[key] = val
+	}
+	return params
+}
 
-	return strings.Join(headerLines, "\r\n") + "\r\n" + canonicalBody
+// parseCanonicalization 解析 c= 标签（为空时为 simple/simple）
+func parseCanonicalization(c string) (string, string, error) {
+	if c == "" {
+		return CanonSimple, CanonSimple, nil
+	}
+	header, body, ok := strings.Cut(strings.ToLower(strings.TrimSpace(c)), "/")
+	if !ok {
+		body = CanonSimple
+	}
+	for _, v := range []string{header, body} {
+		if v != CanonSimple && v != CanonRelaxed {
+			return "", "", fmt.Errorf("%w: 不支持的规范化算法 %q", ErrDKIMSignature, c)
+		}
+	}
+	return header, body, nil
 }
 
-// canonicalizeHeader 规范化邮件头
-func (d *DKIM) canonicalizeHeader(header string) string {
-	// relaxed 规范化：去除多余空格，转换为小写
-	header = strings.TrimSpace(header)
-	header = strings.ToLower(header)
-	return header
+// mapHeaderFields 将邮件头映射转换为字段（按名称排序，格式为 "名称: 值\r\n"）
+func mapHeaderFields(headers map[string]string) []headerField {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]headerField, 0, len(names))
+	for _, name := range names {
+		fields = append(fields, headerField{name: name, raw: name + ": " + headers[name] + "\r\n"})
+	}
+	return fields
 }
 
-// canonicalizeBody 规范化邮件体
-func (d *DKIM) canonicalizeBody(body string) string {
-	// relaxed 规范化：去除行尾空格，空行压缩
-	lines := strings.Split(body, "\n")
-	var canonicalLines []string
+// splitMessage 拆分原始邮件（CRLF 换行）为邮件头字段和正文
+func splitMessage(raw []byte) ([]headerField, []byte) {
+	header, body := raw, []byte(nil)
+	if bytes.HasPrefix(raw, []byte("\r\n")) {
+		header, body = nil, raw[2:]
+	} else if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		header, body = raw[:i+2], raw[i+4:]
+	}
 
-	for _, line := range lines {
-		line = strings.TrimRight(line, " \t")
+	var fields []headerField
+	for _, line := range strings.SplitAfter(string(header), "\r\n") {
 		if line == "" {
-			canonicalLines = append(canonicalLines, "")
-		} else {
-			canonicalLines = append(canonicalLines, line)
+			continue
+		}
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += line
+			continue
 		}
+		name, _, _ := strings.Cut(line, ":")
+		fields = append(fields, headerField{name: strings.TrimSpace(name), raw: line})
 	}
-
-	return strings.Join(canonicalLines, "\r\n")
+	return fields, body
 }
 
-// getSignedHeaders 获取已签名的头列表
-func (d *DKIM) getSignedHeaders(headers map[string]string) []string {
-	var signed []string
-	for h := range headers {
-		signed = append(signed, strings.ToLower(h))
+// toCRLF 将单独的 LF 换行转换为 CRLF
+func toCRLF(data []byte) []byte {
+	if !bytes.Contains(data, []byte("\n")) {
+		return data
 	}
-	return signed
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	for i, c := range data {
+		if c == '\n' && (i == 0 || data[i-1] != '\r') {
+			buf.WriteByte('\r')
+		}
+		buf.WriteByte(c)
+	}
+	return buf.Bytes()
 }
 
-// parseDKIMSignature 解析 DKIM 签名
-func (d *DKIM) parseDKIMSignature(signature string) map[string]string {
-	params := make(map[string]string)
-	parts := strings.Split(signature, ";")
-
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		if idx := strings.Index(part, "="); idx > 0 {
-			key := strings.TrimSpace(part[:idx])
-			val := strings.TrimSpace(part[idx+1:])
-			params[key] = val
+// foldHeaderNames 拼接 h= 的邮件头名称，超过一行时在冒号后折行
+func foldHeaderNames(names []string) string {
+	const width = 64
+	var b strings.Builder
+	line := 0
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(':')
+			line++
+			if line+len(name) > width {
+				b.WriteString("\r\n\t ")
+				line = 0
+			}
 		}
+		b.WriteString(name)
+		line += len(name)
 	}
+	return b.String()
+}
 
-	return params
+// foldBase64 将签名值按 72 个字符折行（避免邮件头行过长）
+func foldBase64(s string) string {
+	const width = 72
+	var b strings.Builder
+	for len(s) > width {
+		b.WriteString(s[:width])
+		b.WriteString("\r\n\t ")
+		s = s[width:]
+	}
+	b.WriteString(s)
+	return b.String()
 }
 
 // GenerateKeyPair 生成 DKIM 密钥对
@@ -216,8 +500,66 @@ func GenerateKeyPair(algorithm string) (crypto.PrivateKey, crypto.PublicKey, err
 	}
 }
 
-// GetPublicKeyDNS 获取公钥的 DNS TXT 记录格式
+// GetPublicKeyDNS 获取公钥的 DNS TXT 记录格式（发布在 <selector>._domainkey.<domain>）
 func GetPublicKeyDNS(publicKey crypto.PublicKey) (string, error) {
-	// TODO: 实现公钥到 DNS TXT 记录的转换
-	return "", fmt.Errorf("未实现")
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", fmt.Errorf("编码公钥失败: %w", err)
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der), nil
+	case ed25519.PublicKey:
+		// Ed25519 公钥直接使用 32 字节原始值（RFC 8463）
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(key), nil
+	default:
+		return "", fmt.Errorf("不支持的密钥类型")
+	}
+}
+
+// DKIMSigners 按发件域名选择 DKIM 签名器：已生成域名密钥的域名使用各自的签名器，
+// 其余使用配置文件中的全局签名器（可以为空）
+type DKIMSigners struct {
+	mu       sync.RWMutex
+	fallback *DKIM
+	domains  map[string]*DKIM
+}
+
+// NewDKIMSigners 创建签名器集合（fallback 为全局签名器，可以为 nil）
+func NewDKIMSigners(fallback *DKIM) *DKIMSigners {
+	return &DKIMSigners{fallback: fallback, domains: make(map[string]*DKIM)}
+}
+
+// Set 设置域名的签名器（替换已有的签名器）
+func (s *DKIMSigners) Set(domain string, signer *DKIM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.domains[strings.ToLower(domain)] = signer
+}
+
+// For 返回发件地址使用的签名器，域名没有单独的签名器时返回全局签名器；未启用签名时返回 nil
+func (s *DKIMSigners) For(address string) *DKIM {
+	if s == nil {
+		return nil
+	}
+	domain := address
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		domain = address[i+1:]
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if signer, ok := s.domains[strings.ToLower(domain)]; ok {
+		return signer
+	}
+	return s.fallback
+}
+
+// QuoteTXT 按区域文件格式引用 TXT 记录的值（单个字符串最长 255 字节，RSA 公钥需要拆分）
+func QuoteTXT(value string) string {
+	var parts []string
+	for len(value) > 255 {
+		parts = append(parts, `"`+value[:255]+`"`)
+		value = value[255:]
+	}
+	return strings.Join(append(parts, `"`+value+`"`), " ")
 }
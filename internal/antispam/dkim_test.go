@@ -0,0 +1,232 @@
+package antispam
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// rfc8463Message RFC 8463 附录 A 的示例邮件（Ed25519 签名，relaxed/relaxed）
+const rfc8463Message = "DKIM-Signature: v=1; a=ed25519-sha256; c=relaxed/relaxed;\r\n" +
+	" d=football.example.com; i=@football.example.com;\r\n" +
+	" q=dns/txt; s=brisbane; t=1528637909; h=from : to :\r\n" +
+	" subject : date : message-id : from : subject : date;\r\n" +
+	" bh=2jUSOH9NhtVGCQWNr9BrIAPreKQjO6Sn7XIkfJVOzv8=;\r\n" +
+	" b=/gCrinpcQOoIfuHNQIbq4pgh9kyIK3AQUdt9OdqQehSwhEIug4D11Bus\r\n" +
+	" Fa3bT3FY5OsU7ZbnKELq+eXdp1Q1Dw==\r\n" +
+	"From: Joe SixPack <joe@football.example.com>\r\n" +
+	"To: Suzie Q <suzie@shopping.example.net>\r\n" +
+	"Subject: Is dinner ready?\r\n" +
+	"Date: Fri, 11 Jul 2003 21:00:37 -0700 (PDT)\r\n" +
+	"Message-ID: <20030712040037.46341.5F8J@football.example.com>\r\n" +
+	"\r\n" +
+	"Hi.\r\n" +
+	"\r\n" +
+	"We lost the game.  Are you hungry yet?\r\n" +
+	"\r\n" +
+	"Joe.\r\n"
+
+func TestVerifyMessage_RFC8463(t *testing.T) {
+	seed, _ := base64.StdEncoding.DecodeString("nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A=")
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	if got := base64.StdEncoding.EncodeToString(publicKey); got != "11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo=" {
+		t.Fatalf("公钥 = %s", got)
+	}
+
+	valid, err := VerifyMessage([]byte(rfc8463Message), publicKey)
+	if err != nil || !valid {
+		t.Fatalf("RFC 8463 示例签名应该验证通过: %v, %v", valid, err)
+	}
+
+	tampered := strings.Replace(rfc8463Message, "Is dinner ready?", "Is lunch ready?", 1)
+	if valid, _ := VerifyMessage([]byte(tampered), publicKey); valid {
+		t.Error("修改主题后签名不应该通过")
+	}
+}
+
+func TestCanonicalization(t *testing.T) {
+	// RFC 6376 3.4.5 示例
+	if got := canonicalizeHeader("A: X\r\n", CanonRelaxed) + canonicalizeHeader("B : Y\t\r\n\tZ  \r\n", CanonRelaxed); got != "a:X\r\nb:Y Z\r\n" {
+		t.Errorf("relaxed 邮件头 = %q", got)
+	}
+	if got := canonicalizeHeader("B : Y\t\r\n\tZ  \r\n", CanonSimple); got != "B : Y\t\r\n\tZ  \r\n" {
+		t.Errorf("simple 邮件头 = %q", got)
+	}
+
+	body := []byte(" C \r\nD \t E\r\n\r\n\r\n")
+	if got := string(canonicalizeBody(body, CanonRelaxed)); got != " C\r\nD E\r\n" {
+		t.Errorf("relaxed 正文 = %q", got)
+	}
+	if got := string(canonicalizeBody(body, CanonSimple)); got != " C \r\nD \t E\r\n" {
+		t.Errorf("simple 正文 = %q", got)
+	}
+
+	// 空正文
+	if got := string(canonicalizeBody(nil, CanonSimple)); got != "\r\n" {
+		t.Errorf("simple 空正文 = %q", got)
+	}
+	if got := canonicalizeBody([]byte("\r\n\r\n"), CanonRelaxed); len(got) != 0 {
+		t.Errorf("relaxed 空正文 = %q", got)
+	}
+	// 单独的 LF 按 CRLF 处理
+	if got := string(canonicalizeBody([]byte("a\nb"), CanonSimple)); got != "a\r\nb\r\n" {
+		t.Errorf("LF 正文 = %q", got)
+	}
+}
+
+func TestDKIM_SignMessage(t *testing.T) {
+	raw := "From: Alice <alice@example.com>\r\n" +
+		"To: bob@example.net\r\n" +
+		"Subject: hello\r\n" +
+		"Date: Mon, 1 Jan 2024 10:00:00 +0000\r\n" +
+		"Received: by relay\r\n" +
+		"\r\n" +
+		"Hi  Bob,\r\n\r\nbye\r\n\r\n"
+
+	for _, algorithm := range []string{"rsa", "ed25519"} {
+		for _, canon := range []string{"relaxed/relaxed", "simple/simple", "relaxed/simple"} {
+			t.Run(algorithm+" "+canon, func(t *testing.T) {
+				privateKey, publicKey, err := GenerateKeyPair(algorithm)
+				if err != nil {
+					t.Fatal(err)
+				}
+				dkim, err := NewDKIM("example.com", "mail", privateKey)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := dkim.SetCanonicalization(canon); err != nil {
+					t.Fatal(err)
+				}
+
+				signed, err := dkim.SignMessage([]byte(raw))
+				if err != nil {
+					t.Fatalf("SignMessage() error = %v", err)
+				}
+				header := string(signed[:len(signed)-len(raw)])
+				for _, want := range []string{"a=" + dkim.Algorithm(), "c=" + canon, "d=example.com", "s=mail", "h=from:subject:date:to;"} {
+					if !strings.Contains(header, want) {
+						t.Errorf("签名头缺少 %q: %s", want, header)
+					}
+				}
+				for _, line := range strings.Split(header, "\r\n") {
+					if len(line) > 78 {
+						t.Errorf("签名头行过长: %q", line)
+					}
+				}
+
+				if valid, err := VerifyMessage(signed, publicKey); err != nil || !valid {
+					t.Fatalf("签名应该验证通过: %v, %v", valid, err)
+				}
+
+				// 未签名的头可以修改
+				changed := strings.Replace(string(signed), "Received: by relay", "Received: by other relay", 1)
+				if valid, _ := VerifyMessage([]byte(changed), publicKey); !valid {
+					t.Error("修改未签名的头不应该影响签名")
+				}
+
+				// relaxed 容忍空白变化，simple 不容忍
+				changed = strings.Replace(string(signed), "Subject: hello", "subject:   hello", 1)
+				valid, _ := VerifyMessage([]byte(changed), publicKey)
+				if valid != strings.HasPrefix(canon, "relaxed") {
+					t.Errorf("邮件头空白变化后验证结果 = %v", valid)
+				}
+				changed = strings.Replace(string(signed), "Hi  Bob,", "Hi Bob, ", 1)
+				valid, _ = VerifyMessage([]byte(changed), publicKey)
+				if valid != strings.HasSuffix(canon, "/relaxed") {
+					t.Errorf("正文空白变化后验证结果 = %v", valid)
+				}
+
+				changed = strings.Replace(string(signed), "bye", "BYE", 1)
+				if valid, _ := VerifyMessage([]byte(changed), publicKey); valid {
+					t.Error("修改正文后签名不应该通过")
+				}
+			})
+		}
+	}
+}
+
+func TestDKIM_SignAndVerify(t *testing.T) {
+	privateKey, _, err := GenerateKeyPair("rsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dkim, err := NewDKIM("example.com", "default", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := map[string]string{
+		"From":    "alice@example.com",
+		"To":      "bob@example.net",
+		"Subject": "Hello  World",
+		"X-Other": "not signed",
+	}
+	body := []byte("line 1\nline 2\n")
+	signature, err := dkim.Sign(headers, body)
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+	if strings.Contains(signature, "x-other") {
+		t.Errorf("不应该签名 X-Other: %s", signature)
+	}
+	if valid, err := dkim.Verify(headers, body, signature); err != nil || !valid {
+		t.Fatalf("签名应该验证通过: %v, %v", valid, err)
+	}
+
+	headers["Subject"] = "Hello World!"
+	if valid, _ := dkim.Verify(headers, body, signature); valid {
+		t.Error("修改主题后签名不应该通过")
+	}
+
+	if _, err := dkim.Sign(map[string]string{"Subject": "no from"}, body); err == nil {
+		t.Error("缺少 From 头时应该返回错误")
+	}
+	if _, err := dkim.Verify(headers, body, "v=1; a=rsa-sha256; b=!!"); err == nil {
+		t.Error("格式错误的签名应该返回错误")
+	}
+	if err := dkim.SetCanonicalization("loose/simple"); err == nil {
+		t.Error("未知的规范化算法应该返回错误")
+	}
+}
+
+func TestGetPublicKeyDNS(t *testing.T) {
+	_, publicKey, _ := GenerateKeyPair("ed25519")
+	record, err := GetPublicKeyDNS(publicKey)
+	if err != nil || record != "v=DKIM1; k=ed25519; p="+base64.StdEncoding.EncodeToString(publicKey.(ed25519.PublicKey)) {
+		t.Errorf("ed25519 记录 = %q, %v", record, err)
+	}
+
+	_, publicKey, _ = GenerateKeyPair("rsa")
+	record, err = GetPublicKeyDNS(publicKey)
+	if err != nil || !strings.HasPrefix(record, "v=DKIM1; k=rsa; p=MII") {
+		t.Errorf("rsa 记录 = %q, %v", record, err)
+	}
+}
+
+func TestDKIMSigners(t *testing.T) {
+	privateKey, _, _ := GenerateKeyPair("ed25519")
+	global, _ := NewDKIM("example.com", "default", privateKey)
+	other, _ := NewDKIM("other.org", "s1", privateKey)
+
+	var empty *DKIMSigners
+	if empty.For("alice@other.org") != nil {
+		t.Error("未启用签名时应该返回 nil")
+	}
+
+	signers := NewDKIMSigners(global)
+	if signers.For("alice@other.org") != global {
+		t.Error("没有域名签名器时应该使用全局签名器")
+	}
+	signers.Set("Other.org", other)
+	if signers.For("alice@OTHER.ORG") != other {
+		t.Error("应该使用域名的签名器（域名不区分大小写）")
+	}
+	if signers.For("bob@example.com") != global {
+		t.Error("其他域名应该使用全局签名器")
+	}
+
+	if NewDKIMSigners(nil).For("bob@example.com") != nil {
+		t.Error("没有全局签名器时应该返回 nil")
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
 )
 
 // Engine 反垃圾邮件引擎
@@ -18,6 +20,12 @@ type Engine struct {
 	ratelimit *RateLimiter
 	scorer    *Scorer
 	chain     *RuleChain
+	events    EventRecorder
+}
+
+// EventRecorder 记录检查结果（投递审计日志）
+type EventRecorder interface {
+	Record(ctx context.Context, e *storage.DeliveryEvent)
 }
 
 // NewEngine 创建反垃圾邮件引擎
@@ -53,10 +61,47 @@ func NewEngine(spf *SPF, dkim *DKIM, dmarc *DMARC, greylist *Greylist, ratelimit
 	return engine
 }
 
+// AddRule 向规则链添加规则（如 Rspamd 等外部检查器）
+func (e *Engine) AddRule(rule Rule) {
+	e.chain.AddRule(rule)
+}
+
+// SetEventRecorder 设置投递审计日志，每次检查后记录分数和决策（为空时不记录）
+func (e *Engine) SetEventRecorder(r EventRecorder) {
+	e.events = r
+}
+
+// spamReporter 记录被拒收邮件的规则（如内容指纹）
+type spamReporter interface {
+	Report(ctx context.Context, req *CheckRequest)
+}
+
 // Check 检查邮件（使用规则链）
 func (e *Engine) Check(ctx context.Context, req *CheckRequest) (*CheckResult, error) {
 	// 使用规则链执行检查
-	return e.chain.Execute(ctx, req)
+	result, err := e.chain.Execute(ctx, req)
+	if err != nil {
+		return result, err
+	}
+	if e.events != nil {
+		e.events.Record(ctx, &storage.DeliveryEvent{
+			MessageID: strings.Trim(req.Headers["Message-ID"], "<> "),
+			Event:     storage.DeliverySpamScored,
+			Sender:    req.From,
+			Recipient: req.To,
+			Detail:    strings.TrimSpace(fmt.Sprintf("score=%d decision=%s %s", result.Score, result.Decision, strings.Join(result.Reasons, "; "))),
+		})
+	}
+
+	// 分数达到拒收阈值时（按内容判定，不包括速率限制、灰名单等）通知需要记录拒收邮件的规则
+	if result.Decision == DecisionReject && result.Score >= 100 {
+		for _, rule := range e.chain.rules {
+			if reporter, ok := rule.(spamReporter); ok {
+				reporter.Report(ctx, req)
+			}
+		}
+	}
+	return result, nil
 }
 
 // CheckLegacy 检查邮件（旧版实现，保留用于兼容）
@@ -186,6 +231,8 @@ type CheckRequest struct {
 	Headers       map[string]string
 	Body          []byte
 	DKIMSignature string
+	Raw           []byte       // 完整邮件原文（提交给 Rspamd，为空时由 Headers 和 Body 组合）
+	Upstream      *AuthResults // 受信任的上游网关的验证结果，不为空时 SPF、DKIM 和 DMARC 规则使用网关的结果
 }
 
 // CheckResult 检查结果
@@ -193,6 +240,8 @@ type CheckResult struct {
 	Score    int      // 垃圾邮件分数（0-100）
 	Reasons  []string // 原因列表
 	Decision Decision // 决策
+	// Headers 接受邮件时应添加的邮件头（决策为接受或隔离时使用）
+	Headers map[string]string
 }
 
 // Decision 决策
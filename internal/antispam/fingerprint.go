@@ -0,0 +1,286 @@
+package antispam
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 内容指纹的默认参数
+const (
+	DefaultFingerprintThreshold = 90                  // 判定为同一活动的最低相似度（-128 ~ 128）
+	DefaultFingerprintWindow    = 30 * 24 * time.Hour // 指纹在最后一次出现后保持活跃的时间
+)
+
+// 命中指纹时的分数：首次出现的来源加 fingerprintBaseScore，此后每多一个来源加 fingerprintSourceScore
+const (
+	fingerprintBaseScore   = 20
+	fingerprintSourceScore = 15
+	fingerprintMaxScore    = 80
+)
+
+// fingerprintMinLength 规范化后正文的最短长度，过短的正文相似度不可靠，不计算指纹
+const fingerprintMinLength = 64
+
+// fingerprintMaxCandidates 每封邮件比较的最多活跃指纹数（按最后出现时间倒序）
+const fingerprintMaxCandidates = 1000
+
+// 指纹来源
+const (
+	FingerprintReasonRejected  = "rejected"  // 按内容判定被拒收
+	FingerprintReasonComplaint = "complaint" // 用户移入垃圾邮件文件夹
+)
+
+var (
+	urlPattern    = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+	emailPattern  = regexp.MustCompile(`\S+@\S+`)
+	digitPattern  = regexp.MustCompile(`[0-9]+`)
+	tagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+	entityPattern = regexp.MustCompile(`&[a-zA-Z0-9#]+;`)
+)
+
+// NormalizeBody 规范化邮件正文用于计算指纹：取纯文本正文（没有时使用去掉标签的 HTML 正文），
+// 转为小写，去掉链接、邮箱地址和数字（活动中每封邮件不同的跟踪参数、收件人和编号），合并空白
+func NormalizeBody(raw []byte) string {
+	msg, err := mailparse.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	text := msg.Text
+	if strings.TrimSpace(text) == "" {
+		text = entityPattern.ReplaceAllString(tagPattern.ReplaceAllString(msg.HTML, " "), " ")
+	}
+	text = strings.ToLower(text)
+	text = urlPattern.ReplaceAllString(text, " ")
+	text = emailPattern.ReplaceAllString(text, " ")
+	text = digitPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(spacePattern.ReplaceAllString(text, " "))
+}
+
+// nilsimsaTran Nilsimsa 的字节置换表
+var nilsimsaTran = func() [256]byte {
+	var tran [256]byte
+	used := make(map[int]bool)
+	j := 0
+	for i := range tran {
+		j = (j*53 + 1) & 255
+		j += j
+		if j > 255 {
+			j -= 255
+		}
+		for used[j] {
+			j = (j + 1) & 255
+		}
+		used[j] = true
+		tran[i] = byte(j)
+	}
+	return tran
+}()
+
+// tran3 把三元组映射到 256 个计数桶之一
+func tran3(a, b, c, n byte) byte {
+	t := &nilsimsaTran
+	return (t[a+n] ^ t[b]*(n+n+1)) + t[c^t[n]]
+}
+
+// Fingerprint 计算文本的 Nilsimsa 局部敏感哈希（256 位，十六进制），
+// 相似的文本得到相似的哈希；文本过短时返回空字符串
+func Fingerprint(text string) string {
+	if len(text) < fingerprintMinLength {
+		return ""
+	}
+
+	var acc [256]int
+	var w [4]byte // 最近的 4 个字节，w[0] 最近
+	total := 0
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		if i > 1 {
+			acc[tran3(ch, w[0], w[1], 0)]++
+			total++
+		}
+		if i > 2 {
+			acc[tran3(ch, w[0], w[2], 1)]++
+			acc[tran3(ch, w[1], w[2], 2)]++
+			total += 2
+		}
+		if i > 3 {
+			acc[tran3(ch, w[0], w[3], 3)]++
+			acc[tran3(ch, w[1], w[3], 4)]++
+			acc[tran3(ch, w[2], w[3], 5)]++
+			acc[tran3(w[3], w[0], ch, 6)]++
+			acc[tran3(w[3], w[2], ch, 7)]++
+			total += 5
+		}
+		w[3], w[2], w[1], w[0] = w[2], w[1], w[0], ch
+	}
+
+	threshold := total / 256
+	var digest [32]byte
+	for i, n := range acc {
+		if n > threshold {
+			digest[i>>3] |= 1 << (i & 7)
+		}
+	}
+	return hex.EncodeToString(digest[:])
+}
+
+// Similarity 比较两个指纹：相同的位数减去 128，范围 -128 ~ 128，无效的指纹返回 -128
+func Similarity(a, b string) int {
+	da, errA := hex.DecodeString(a)
+	db, errB := hex.DecodeString(b)
+	if errA != nil || errB != nil || len(da) != 32 || len(db) != 32 {
+		return -128
+	}
+	diff := 0
+	for i := range da {
+		diff += bits.OnesCount8(da[i] ^ db[i])
+	}
+	return 128 - diff
+}
+
+// FingerprintRule 内容指纹规则：被拒收或被用户投诉的邮件记录正文指纹，
+// 相似内容的邮件再次到达时提高垃圾邮件分数，来自的不同来源（发件域名）越多分数越高
+type FingerprintRule struct {
+	storage   storage.Driver
+	threshold int
+	window    time.Duration
+}
+
+// NewFingerprintRule 创建内容指纹规则，threshold 为判定相似的最低分数（0 时使用默认值），
+// window 为指纹保持活跃的时间（0 时使用默认值）
+func NewFingerprintRule(driver storage.Driver, threshold int, window time.Duration) *FingerprintRule {
+	if threshold <= 0 {
+		threshold = DefaultFingerprintThreshold
+	}
+	if window <= 0 {
+		window = DefaultFingerprintWindow
+	}
+	return &FingerprintRule{storage: driver, threshold: threshold, window: window}
+}
+
+// Name 返回规则名称
+func (r *FingerprintRule) Name() string {
+	return "fingerprint"
+}
+
+// Priority 返回优先级（在认证检查之后、Rspamd 之前执行）
+func (r *FingerprintRule) Priority() int {
+	return 6
+}
+
+// Check 与活跃的垃圾邮件指纹比较
+func (r *FingerprintRule) Check(ctx context.Context, req *CheckRequest) (*RuleResult, error) {
+	pass := &RuleResult{Action: ActionContinue, Continue: true}
+	hash := Fingerprint(NormalizeBody(req.message()))
+	if hash == "" {
+		return pass, nil
+	}
+
+	match, similarity, err := r.match(ctx, hash)
+	if err != nil || match == nil {
+		return pass, err
+	}
+	sources, err := r.storage.RecordSpamFingerprintHit(ctx, match.ID, fingerprintSource(req.From, req.IP))
+	if err != nil {
+		return pass, err
+	}
+
+	score := min(fingerprintBaseScore+fingerprintSourceScore*(sources-1), fingerprintMaxScore)
+	return &RuleResult{
+		Action:   ActionContinue,
+		Score:    score,
+		Reason:   fmt.Sprintf("内容指纹：与已知垃圾邮件活动相似（相似度 %d，%d 个来源）", similarity, sources),
+		Continue: true,
+		Headers:  map[string]string{"X-Spam-Fingerprint": match.Hash[:16]},
+	}, nil
+}
+
+// Report 记录被拒收邮件的指纹（由引擎在按内容拒收时调用）
+func (r *FingerprintRule) Report(ctx context.Context, req *CheckRequest) {
+	r.record(ctx, req.message(), fingerprintSource(req.From, req.IP), FingerprintReasonRejected)
+}
+
+// ReportComplaint 记录用户投诉（移入垃圾邮件文件夹）的邮件的指纹
+func (r *FingerprintRule) ReportComplaint(ctx context.Context, userEmail string, raw []byte) {
+	from := ""
+	if msg, err := mailparse.ParseHeader(raw); err == nil && msg.From != nil {
+		from = msg.From.Address
+	}
+	r.record(ctx, raw, fingerprintSource(from, nil), FingerprintReasonComplaint)
+}
+
+// record 保存指纹：与已有的活跃指纹相似时合并到已有指纹
+func (r *FingerprintRule) record(ctx context.Context, raw []byte, source, reason string) {
+	text := NormalizeBody(raw)
+	hash := Fingerprint(text)
+	if hash == "" {
+		return
+	}
+
+	fp := &storage.SpamFingerprint{Hash: hash, Reason: reason, Sample: sample(text)}
+	match, _, err := r.match(ctx, hash)
+	if err != nil {
+		logger.WarnCtx(ctx).Err(err).Msg("查询垃圾邮件指纹失败")
+	}
+	if match != nil {
+		fp.Hash = match.Hash
+	}
+	if err := r.storage.ReportSpamFingerprint(ctx, fp, source); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("reason", reason).Msg("记录垃圾邮件指纹失败")
+	}
+}
+
+// match 查找与 hash 最相似的活跃指纹，没有达到阈值的指纹时返回 nil
+func (r *FingerprintRule) match(ctx context.Context, hash string) (*storage.SpamFingerprint, int, error) {
+	candidates, err := r.storage.ListSpamFingerprints(ctx, time.Now().Add(-r.window), fingerprintMaxCandidates)
+	if err != nil {
+		return nil, 0, err
+	}
+	var best *storage.SpamFingerprint
+	bestScore := r.threshold - 1
+	for _, fp := range candidates {
+		if s := Similarity(hash, fp.Hash); s > bestScore {
+			best, bestScore = fp, s
+		}
+	}
+	if best == nil {
+		return nil, 0, nil
+	}
+	return best, bestScore, nil
+}
+
+// fingerprintSource 指纹的来源：发件人域名，空发件人时使用 IP
+func fingerprintSource(from string, ip net.IP) string {
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		return strings.ToLower(from[at+1:])
+	}
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// sample 正文摘录（管理界面展示）
+func sample(text string) string {
+	const maxSample = 120
+	if len(text) <= maxSample {
+		return text
+	}
+	cut := maxSample
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
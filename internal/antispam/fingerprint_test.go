@@ -0,0 +1,151 @@
+package antispam
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// campaign 同一垃圾邮件活动的一封邮件：收件人、链接和编号不同
+func campaign(from, to, id string) []byte {
+	return []byte("From: " + from + "\r\nTo: " + to + "\r\nSubject: Limited offer " + id + "\r\n\r\n" +
+		"Dear " + to + ",\r\n\r\nCongratulations! You have been selected to receive an exclusive reward.\r\n" +
+		"Claim your prize now at https://promo.example/claim?id=" + id + " before the offer expires.\r\n" +
+		"Order number " + id + ". Reply STOP to unsubscribe from these special promotions.\r\n")
+}
+
+func TestNormalizeBody(t *testing.T) {
+	got := NormalizeBody(campaign("a@spam.test", "bob@example.com", "12345"))
+	for _, removed := range []string{"https://", "bob@example.com", "12345"} {
+		if strings.Contains(got, removed) {
+			t.Errorf("规范化后不应该包含 %q: %q", removed, got)
+		}
+	}
+	if !strings.HasPrefix(got, "dear congratulations! you have been selected") {
+		t.Errorf("规范化结果不正确: %q", got)
+	}
+
+	html := []byte("From: a@spam.test\r\nContent-Type: text/html\r\n\r\n<p>Hello <b>World</b>&nbsp;123</p>")
+	if got := NormalizeBody(html); got != "hello world" {
+		t.Errorf("HTML 正文应该去掉标签: %q", got)
+	}
+}
+
+func TestFingerprintSimilarity(t *testing.T) {
+	a := Fingerprint(NormalizeBody(campaign("a@spam.test", "bob@example.com", "1")))
+	b := Fingerprint(NormalizeBody(campaign("x@other.test", "carol@example.org", "987654")))
+	if a == "" || len(a) != 64 {
+		t.Fatalf("指纹应该是 64 位十六进制: %q", a)
+	}
+	if s := Similarity(a, b); s != 128 {
+		t.Errorf("同一活动的邮件规范化后应该相同, similarity = %d", s)
+	}
+
+	edited := Fingerprint(NormalizeBody([]byte(strings.Replace(string(campaign("x@other.test", "dave@example.net", "42")),
+		"exclusive", "special", 1))))
+	if s := Similarity(a, edited); s < DefaultFingerprintThreshold {
+		t.Errorf("略有改动的邮件应该相似, similarity = %d", s)
+	}
+
+	other := Fingerprint(NormalizeBody([]byte("Subject: minutes\r\n\r\n" +
+		"Hi team, attached are the minutes from Tuesday's planning meeting. Please review the action items\r\n" +
+		"and let me know if anything is missing before we circulate them to the wider group on Friday.\r\n")))
+	if s := Similarity(a, other); s >= DefaultFingerprintThreshold {
+		t.Errorf("不同内容的邮件不应该相似, similarity = %d", s)
+	}
+
+	if Fingerprint("too short") != "" {
+		t.Error("过短的正文不应该计算指纹")
+	}
+	if Similarity(a, "invalid") != -128 {
+		t.Error("无效的指纹相似度应该为 -128")
+	}
+}
+
+func TestFingerprintRule(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	rule := NewFingerprintRule(driver, 0, 0)
+
+	check := func(from, id string) *RuleResult {
+		t.Helper()
+		result, err := rule.Check(ctx, &CheckRequest{IP: net.ParseIP("192.0.2.1"), From: from, Raw: campaign(from, "bob@example.com", id)})
+		if err != nil {
+			t.Fatalf("检查失败: %v", err)
+		}
+		return result
+	}
+
+	// 没有记录过的内容不加分
+	if result := check("a@spam.test", "1"); result.Score != 0 {
+		t.Errorf("未知内容不应该加分: %+v", result)
+	}
+
+	// 用户投诉后，同一来源再次到达加基础分，新的来源分数递增
+	rule.ReportComplaint(ctx, "bob@example.com", campaign("a@spam.test", "bob@example.com", "2"))
+	if result := check("b@spam.test", "3"); result.Score != fingerprintBaseScore {
+		t.Errorf("同一来源应该加 %d 分: %+v", fingerprintBaseScore, result)
+	}
+	result := check("c@other.test", "4")
+	if result.Score != fingerprintBaseScore+fingerprintSourceScore || result.Headers["X-Spam-Fingerprint"] == "" {
+		t.Errorf("新的来源应该提高分数: %+v", result)
+	}
+
+	// 被拒收的相似邮件合并到同一指纹
+	rule.Report(ctx, &CheckRequest{From: "d@third.test", Raw: campaign("d@third.test", "carol@example.com", "5")})
+	fingerprints, err := driver.ListSpamFingerprints(ctx, time.Now().Add(-time.Hour), 10)
+	if err != nil || len(fingerprints) != 1 {
+		t.Fatalf("相似邮件应该合并到同一指纹: %d, err = %v", len(fingerprints), err)
+	}
+	if fp := fingerprints[0]; fp.Reason != FingerprintReasonComplaint || fp.Reports != 2 || fp.Hits != 2 || fp.Sources != 3 {
+		t.Errorf("指纹统计不正确: %+v", fp)
+	}
+}
+
+func TestEngineReportsRejectedContent(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+
+	engine := NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(NewFingerprintRule(driver, 0, 0))
+	engine.AddRule(&staticRule{score: 120})
+
+	req := &CheckRequest{IP: net.ParseIP("192.0.2.1"), HELO: "mx.spam.test", From: "a@spam.test", Raw: campaign("a@spam.test", "bob@example.com", "1")}
+	result, err := engine.Check(ctx, req)
+	if err != nil || result.Decision != DecisionReject {
+		t.Fatalf("应该拒收: %+v, err = %v", result, err)
+	}
+	fingerprints, err := driver.ListSpamFingerprints(ctx, time.Now().Add(-time.Hour), 10)
+	if err != nil || len(fingerprints) != 1 || fingerprints[0].Reason != FingerprintReasonRejected {
+		t.Errorf("按内容拒收的邮件应该记录指纹: %+v, err = %v", fingerprints, err)
+	}
+}
+
+// staticRule 返回固定分数的规则
+type staticRule struct {
+	score int
+}
+
+func (r *staticRule) Name() string  { return "static" }
+func (r *staticRule) Priority() int { return 9 }
+func (r *staticRule) Check(ctx context.Context, req *CheckRequest) (*RuleResult, error) {
+	return &RuleResult{Action: ActionContinue, Score: r.score, Continue: true}, nil
+}
@@ -0,0 +1,147 @@
+package antispam
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// earlyTalkerReply 拒绝抢先发送的客户端时的回复（代替欢迎横幅）
+const earlyTalkerReply = "554 5.5.1 Protocol error: client sent data before greeting\r\n"
+
+// ErrEarlyTalker 客户端在欢迎横幅之前发送了数据
+var ErrEarlyTalker = errors.New("客户端在欢迎横幅之前发送数据")
+
+// GreetDelay 欢迎横幅延迟：连接建立后等待一段时间再发送横幅，期间发送数据的客户端（early talker）
+// 没有按协议等待横幅，几乎都是垃圾邮件程序。正常的邮件服务器会一直等待横幅，不受影响
+type GreetDelay struct {
+	Delay time.Duration
+	// Reject 拒绝抢先发送的客户端（回复 554 并断开），否则照常接收，由会话按 EarlyTalker 的结果处理
+	Reject    bool
+	whitelist []*net.IPNet
+}
+
+// NewGreetDelay 创建欢迎横幅延迟，whitelist 为不等待也不检测的客户端（IP 或网段，如已知不等待横幅的发件服务器）
+func NewGreetDelay(delay time.Duration, reject bool, whitelist []string) (*GreetDelay, error) {
+	networks, err := parseNetworks(whitelist)
+	if err != nil {
+		return nil, err
+	}
+	return &GreetDelay{Delay: delay, Reject: reject, whitelist: networks}, nil
+}
+
+// whitelisted IP 是否在白名单中
+func (g *GreetDelay) whitelisted(ip net.IP) bool {
+	for _, ipNet := range g.whitelist {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Listener 包装监听器，在写出欢迎横幅之前等待并检测抢先发送的客户端
+// （不能用于隐式 TLS 端口：TLS 握手由客户端先发送数据）
+func (g *GreetDelay) Listener(l net.Listener) net.Listener {
+	return &greetListener{Listener: l, greet: g}
+}
+
+// EarlyTalker 连接的客户端是否在欢迎横幅之前发送了数据（连接没有经过 GreetDelay 时为 false）
+func EarlyTalker(conn net.Conn) bool {
+	// STARTTLS 之后为 TLS 连接
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	c, ok := conn.(*greetConn)
+	return ok && c.early
+}
+
+// greetListener 延迟欢迎横幅的监听器
+type greetListener struct {
+	net.Listener
+	greet *GreetDelay
+}
+
+// Accept 接受连接，白名单中的客户端不等待
+func (l *greetListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(RemoteIP(conn.RemoteAddr()))
+	if ip != nil && l.greet.whitelisted(ip) {
+		return conn, nil
+	}
+	return &greetConn{Conn: conn, greet: l.greet}, nil
+}
+
+// greetConn 延迟欢迎横幅的连接：第一次写出（横幅）之前等待客户端数据，
+// 收到的数据保留下来，之后照常读出
+type greetConn struct {
+	net.Conn
+	greet   *GreetDelay
+	greeted bool
+	early   bool
+	pending []byte
+}
+
+// Write 写出数据，第一次写出前等待
+func (c *greetConn) Write(b []byte) (int, error) {
+	if !c.greeted {
+		c.greeted = true
+		if err := c.wait(); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(b)
+}
+
+// NetConn 返回包装的连接（连接跟踪等从包装中取出原始连接）
+func (c *greetConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// Read 先读出等待期间收到的数据
+func (c *greetConn) Read(b []byte) (int, error) {
+	if len(c.pending) > 0 {
+		n := copy(b, c.pending)
+		c.pending = c.pending[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// wait 等待 Delay，期间收到数据时标记为抢先发送（Reject 时回复 554 并断开，返回 ErrEarlyTalker）
+func (c *greetConn) wait() error {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.greet.Delay)); err != nil {
+		return nil
+	}
+	buf := make([]byte, 512)
+	n, err := c.Conn.Read(buf)
+	c.Conn.SetReadDeadline(time.Time{})
+
+	var netErr net.Error
+	switch {
+	case n > 0:
+		c.early = true
+		c.pending = buf[:n]
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return nil
+	default:
+		// 客户端在等待期间断开
+		return err
+	}
+
+	ip := RemoteIP(c.RemoteAddr())
+	if !c.greet.Reject {
+		logger.Info().Str("ip", ip).Msg("客户端在欢迎横幅之前发送数据，邮件将被标记为垃圾邮件")
+		return nil
+	}
+	logger.Warn().Str("ip", ip).Msg("拒绝在欢迎横幅之前发送数据的客户端")
+	c.Conn.Write([]byte(earlyTalkerReply))
+	c.Conn.Close()
+	return ErrEarlyTalker
+}
@@ -0,0 +1,135 @@
+package antispam
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// greetServer 启动经过 GreetDelay 的监听器，每个连接写出横幅后读一行并回显，返回地址和接受的连接
+func greetServer(t *testing.T, g *GreetDelay) (string, <-chan net.Conn) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	conns := make(chan net.Conn, 1)
+	listener := g.Listener(l)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				defer conn.Close()
+				if _, err := conn.Write([]byte("220 mx.example.com ESMTP\r\n")); err != nil {
+					return
+				}
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err != nil {
+					return
+				}
+				conn.Write([]byte("250 " + line))
+			}()
+		}
+	}()
+	return l.Addr().String(), conns
+}
+
+// talk 连接服务器，early 时在横幅之前发送 EHLO，返回收到的全部回复
+func talk(t *testing.T, addr string, early bool) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	var replies strings.Builder
+	if early {
+		conn.Write([]byte("EHLO spammer\r\n"))
+	} else {
+		banner, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("读取横幅失败: %v", err)
+		}
+		replies.WriteString(banner)
+		conn.Write([]byte("EHLO client\r\n"))
+	}
+	for {
+		line, err := r.ReadString('\n')
+		replies.WriteString(line)
+		if err != nil || strings.HasPrefix(line, "250 ") {
+			return replies.String()
+		}
+	}
+}
+
+func TestGreetDelay(t *testing.T) {
+	delay := 200 * time.Millisecond
+
+	t.Run("正常客户端等待横幅", func(t *testing.T) {
+		g, _ := NewGreetDelay(delay, true, nil)
+		addr, conns := greetServer(t, g)
+		start := time.Now()
+		got := talk(t, addr, false)
+		if !strings.HasPrefix(got, "220 ") || !strings.Contains(got, "250 EHLO client") {
+			t.Errorf("回复 = %q", got)
+		}
+		if elapsed := time.Since(start); elapsed < delay {
+			t.Errorf("横幅应该在 %v 之后发送, got %v", delay, elapsed)
+		}
+		if EarlyTalker(<-conns) {
+			t.Error("正常客户端不应该被标记")
+		}
+	})
+
+	t.Run("拒绝抢先发送的客户端", func(t *testing.T) {
+		g, _ := NewGreetDelay(delay, true, nil)
+		addr, _ := greetServer(t, g)
+		got := talk(t, addr, true)
+		if !strings.HasPrefix(got, "554 5.5.1") || strings.Contains(got, "220 ") {
+			t.Errorf("回复 = %q, want 554 且没有横幅", got)
+		}
+	})
+
+	t.Run("只标记抢先发送的客户端", func(t *testing.T) {
+		g, _ := NewGreetDelay(delay, false, nil)
+		addr, conns := greetServer(t, g)
+		got := talk(t, addr, true)
+		// 等待期间收到的命令照常处理
+		if !strings.HasPrefix(got, "220 ") || !strings.Contains(got, "250 EHLO spammer") {
+			t.Errorf("回复 = %q", got)
+		}
+		if !EarlyTalker(<-conns) {
+			t.Error("抢先发送的客户端应该被标记")
+		}
+	})
+
+	t.Run("白名单不等待", func(t *testing.T) {
+		g, err := NewGreetDelay(time.Minute, true, []string{"127.0.0.0/8"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, conns := greetServer(t, g)
+		got := talk(t, addr, true)
+		if !strings.HasPrefix(got, "220 ") {
+			t.Errorf("回复 = %q", got)
+		}
+		if EarlyTalker(<-conns) {
+			t.Error("白名单中的客户端不应该被标记")
+		}
+	})
+
+	if _, err := NewGreetDelay(delay, true, []string{"not-an-ip"}); err == nil {
+		t.Error("无效的白名单应该返回错误")
+	}
+}
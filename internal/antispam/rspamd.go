@@ -0,0 +1,181 @@
+package antispam
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// DefaultRspamdTimeout 单封邮件的 Rspamd 检查超时
+const DefaultRspamdTimeout = 5 * time.Second
+
+// defaultRspamdRequiredScore Rspamd 没有返回拒收阈值时使用的默认值（Rspamd 默认的 reject 阈值）
+const defaultRspamdRequiredScore = 15.0
+
+// RspamdRule 把邮件提交给 Rspamd（/checkv2）检查，Rspamd 的动作和分数映射为规则结果
+//
+// Rspamd 不可用（连接失败、超时、返回错误）时放行邮件（fail-open），不影响正常收信。
+type RspamdRule struct {
+	url     string
+	timeout time.Duration
+	client  *http.Client
+}
+
+// NewRspamdRule 创建 Rspamd 规则，url 为 Rspamd 普通 worker 的地址（如 http://127.0.0.1:11333），
+// timeout 为单封邮件的检查超时（0 时使用 DefaultRspamdTimeout）
+func NewRspamdRule(url string, timeout time.Duration) *RspamdRule {
+	if timeout <= 0 {
+		timeout = DefaultRspamdTimeout
+	}
+	return &RspamdRule{
+		url:     strings.TrimRight(url, "/"),
+		timeout: timeout,
+		client:  &http.Client{},
+	}
+}
+
+// Name 返回规则名称
+func (r *RspamdRule) Name() string {
+	return "rspamd"
+}
+
+// Priority 返回优先级（在本地规则之后执行）
+func (r *RspamdRule) Priority() int {
+	return 7
+}
+
+// rspamdResponse /checkv2 的响应
+type rspamdResponse struct {
+	IsSkipped     bool    `json:"is_skipped"`
+	Score         float64 `json:"score"`
+	RequiredScore float64 `json:"required_score"`
+	Action        string  `json:"action"`
+}
+
+// Check 提交邮件给 Rspamd 检查
+func (r *RspamdRule) Check(ctx context.Context, req *CheckRequest) (*RuleResult, error) {
+	resp, err := r.check(ctx, req)
+	if err != nil {
+		logger.Warn().Err(err).Str("from", req.From).Msg("Rspamd 检查失败，放行邮件")
+		return &RuleResult{Action: ActionContinue, Continue: true}, err
+	}
+	if resp.IsSkipped {
+		return &RuleResult{Action: ActionContinue, Continue: true}, nil
+	}
+	return rspamdResult(resp), nil
+}
+
+// check 发送 /checkv2 请求
+func (r *RspamdRule) check(ctx context.Context, req *CheckRequest) (*rspamdResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url+"/checkv2", bytes.NewReader(req.message()))
+	if err != nil {
+		return nil, fmt.Errorf("创建 Rspamd 请求失败: %w", err)
+	}
+	// 信封信息通过请求头传递（Rspamd 协议）
+	if req.IP != nil {
+		httpReq.Header.Set("IP", req.IP.String())
+	}
+	if req.From != "" {
+		httpReq.Header.Set("From", req.From)
+	}
+	if req.To != "" {
+		httpReq.Header.Set("Rcpt", req.To)
+	}
+	if req.HELO != "" {
+		httpReq.Header.Set("Helo", req.HELO)
+	}
+
+	httpResp, err := r.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Rspamd 失败: %w", err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(httpResp.Body, 512))
+		return nil, fmt.Errorf("Rspamd 返回 %s: %s", httpResp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var resp rspamdResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("解析 Rspamd 响应失败: %w", err)
+	}
+	return &resp, nil
+}
+
+// rspamdResult 把 Rspamd 的动作和分数映射为规则结果
+//
+// 分数按 Rspamd 的拒收阈值换算到本引擎的分数（阈值对应 100 分）；接受的邮件（no action、
+// add header、rewrite subject）带 X-Spam 头，供客户端和过滤器使用。
+func rspamdResult(resp *rspamdResponse) *RuleResult {
+	required := resp.RequiredScore
+	if required <= 0 {
+		required = defaultRspamdRequiredScore
+	}
+	result := &RuleResult{
+		Action:   ActionContinue,
+		Score:    int(math.Round(resp.Score / required * 100)),
+		Continue: true,
+	}
+
+	spam := false
+	switch resp.Action {
+	case "reject":
+		result.Action = ActionReject
+		result.Reason = fmt.Sprintf("Rspamd：拒绝（分数 %.2f）", resp.Score)
+		result.Continue = false
+		return result
+	case "soft reject", "greylist":
+		result.Action = ActionTempReject
+		result.Reason = fmt.Sprintf("Rspamd：临时拒绝（%s，分数 %.2f）", resp.Action, resp.Score)
+		result.Continue = false
+		return result
+	case "add header", "rewrite subject":
+		result.Action = ActionQuarantine
+		result.Reason = fmt.Sprintf("Rspamd：疑似垃圾邮件（分数 %.2f）", resp.Score)
+		spam = true
+	}
+
+	status := "No"
+	if spam {
+		status = "Yes"
+	}
+	result.Headers = map[string]string{
+		"X-Spam":        status,
+		"X-Spam-Score":  fmt.Sprintf("%.2f", resp.Score),
+		"X-Spam-Status": fmt.Sprintf("%s, score=%.2f required=%.2f", status, resp.Score, required),
+		"X-Spam-Action": resp.Action,
+	}
+	return result
+}
+
+// message 返回提交给外部检查器的邮件原文：优先使用 Raw，否则由 Headers 和 Body 组合
+func (req *CheckRequest) message() []byte {
+	if len(req.Raw) > 0 {
+		return req.Raw
+	}
+	names := make([]string, 0, len(req.Headers))
+	for name := range req.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, req.Headers[name])
+	}
+	buf.WriteString("\r\n")
+	buf.Write(req.Body)
+	return buf.Bytes()
+}
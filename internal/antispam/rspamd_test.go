@@ -0,0 +1,135 @@
+package antispam
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// rspamdServer 模拟 Rspamd：返回固定的动作和分数，记录收到的请求
+func rspamdServer(t *testing.T, action string, score float64, delay time.Duration) (*httptest.Server, chan *http.Request) {
+	t.Helper()
+	requests := make(chan *http.Request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Header.Set("X-Test-Body", string(body))
+		select {
+		case requests <- r:
+		default:
+		}
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"is_skipped":false,"score":%.2f,"required_score":15.0,"action":%q,"symbols":{}}`, score, action)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func rspamdRequest() *CheckRequest {
+	return &CheckRequest{
+		IP:   net.ParseIP("192.0.2.1"),
+		From: "sender@example.org",
+		To:   "alice@example.com",
+		HELO: "mail.example.org",
+		Raw:  []byte("From: sender@example.org\r\nSubject: hi\r\n\r\nbody\r\n"),
+	}
+}
+
+func TestRspamdRule(t *testing.T) {
+	tests := []struct {
+		action     string
+		score      float64
+		wantAction Action
+		wantScore  int
+		wantSpam   string // X-Spam 头，为空表示不添加
+	}{
+		{"no action", 1.5, ActionContinue, 10, "No"},
+		{"add header", 7.5, ActionQuarantine, 50, "Yes"},
+		{"rewrite subject", 9, ActionQuarantine, 60, "Yes"},
+		{"soft reject", 10, ActionTempReject, 67, ""},
+		{"greylist", 4, ActionTempReject, 27, ""},
+		{"reject", 18, ActionReject, 120, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			srv, requests := rspamdServer(t, tt.action, tt.score, 0)
+			rule := NewRspamdRule(srv.URL+"/", time.Second)
+
+			result, err := rule.Check(context.Background(), rspamdRequest())
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if result.Action != tt.wantAction || result.Score != tt.wantScore {
+				t.Errorf("结果 = %v %d, want %v %d", result.Action, result.Score, tt.wantAction, tt.wantScore)
+			}
+			if got := result.Headers["X-Spam"]; got != tt.wantSpam {
+				t.Errorf("X-Spam = %q, want %q", got, tt.wantSpam)
+			}
+
+			r := <-requests
+			if r.URL.Path != "/checkv2" || r.Header.Get("IP") != "192.0.2.1" || r.Header.Get("Rcpt") != "alice@example.com" ||
+				r.Header.Get("From") != "sender@example.org" || r.Header.Get("Helo") != "mail.example.org" {
+				t.Errorf("Rspamd 请求 = %s %v", r.URL.Path, r.Header)
+			}
+			if r.Header.Get("X-Test-Body") != string(rspamdRequest().Raw) {
+				t.Errorf("提交的邮件 = %q", r.Header.Get("X-Test-Body"))
+			}
+		})
+	}
+}
+
+func TestRspamdRuleFailOpen(t *testing.T) {
+	srv, _ := rspamdServer(t, "reject", 20, time.Second)
+	engine := NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(NewRspamdRule(srv.URL, 50*time.Millisecond))
+
+	start := time.Now()
+	result, err := engine.Check(context.Background(), rspamdRequest())
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("检查耗时 %v，超时没有生效", elapsed)
+	}
+	if result.Decision != DecisionAccept {
+		t.Errorf("Rspamd 超时时 Decision = %v, want accept", result.Decision)
+	}
+
+	// Rspamd 不可用
+	engine = NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(NewRspamdRule("http://127.0.0.1:1", time.Second))
+	if result, err := engine.Check(context.Background(), rspamdRequest()); err != nil || result.Decision != DecisionAccept {
+		t.Errorf("Rspamd 不可用时 = %+v, %v, want accept", result, err)
+	}
+}
+
+func TestRspamdEngineHeaders(t *testing.T) {
+	srv, _ := rspamdServer(t, "add header", 8, 0)
+	engine := NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(NewRspamdRule(srv.URL, time.Second))
+
+	req := rspamdRequest()
+	req.Raw = nil
+	req.Headers = map[string]string{"Subject": "hi"}
+	req.Body = []byte("body")
+	result, err := engine.Check(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if result.Decision != DecisionQuarantine {
+		t.Errorf("Decision = %v, want quarantine", result.Decision)
+	}
+	if result.Headers["X-Spam"] != "Yes" || result.Headers["X-Spam-Status"] != "Yes, score=8.00 required=15.00" {
+		t.Errorf("Headers = %v", result.Headers)
+	}
+}
@@ -24,6 +24,8 @@ type RuleResult struct {
 	Score    int    // 分数调整
 	Reason   string // 原因
 	Continue bool   // 是否继续执行下一个规则
+	// Headers 接受邮件时添加的邮件头（如 Rspamd 的 X-Spam 头）
+	Headers map[string]string
 }
 
 // Action 动作
@@ -95,6 +97,12 @@ func (r *RuleChain) Execute(ctx context.Context, req *CheckRequest) (*CheckResul
 		if ruleResult.Reason != "" {
 			result.Reasons = append(result.Reasons, ruleResult.Reason)
 		}
+		for name, value := range ruleResult.Headers {
+			if result.Headers == nil {
+				result.Headers = make(map[string]string)
+			}
+			result.Headers[name] = value
+		}
 
 		// 根据动作决定是否继续
 		switch ruleResult.Action {
@@ -117,13 +125,11 @@ func (r *RuleChain) Execute(ctx context.Context, req *CheckRequest) (*CheckResul
 		}
 	}
 
-	// 根据最终分数决定
+	// 根据最终分数决定（内容分数在重试时不会改变，临时拒绝只由规则的动作决定，如灰名单、Rspamd 的 soft reject）
 	if result.Score >= 100 {
 		result.Decision = DecisionReject
 	} else if result.Score >= 50 {
 		result.Decision = DecisionQuarantine
-	} else if result.Score >= 30 {
-		result.Decision = DecisionTempReject
 	}
 
 	return result, nil
@@ -243,11 +249,11 @@ func (r *SPFRule) Priority() int {
 
 // Check 检查 SPF
 func (r *SPFRule) Check(ctx context.Context, req *CheckRequest) (*RuleResult, error) {
-	if r.spf == nil || req.Domain == "" {
+	if req.Upstream == nil && (r.spf == nil || req.Domain == "") {
 		return &RuleResult{Action: ActionContinue, Continue: true}, nil
 	}
 
-	spfResult, err := r.spf.Check(req.IP, req.Domain, req.HELO)
+	spfResult, err := upstreamSPF(req, r.spf)
 	if err != nil {
 		return &RuleResult{Action: ActionContinue, Continue: true}, err
 	}
@@ -303,11 +309,16 @@ func (r *DKIMRule) Priority() int {
 
 // Check 检查 DKIM
 func (r *DKIMRule) Check(ctx context.Context, req *CheckRequest) (*RuleResult, error) {
-	if r.dkim == nil || req.DKIMSignature == "" {
+	if req.Upstream != nil {
+		// 来自受信任的上游网关：转发可能改动邮件导致签名失效，使用网关的验证结果
+		if req.Upstream.DKIM != ResultPass && req.Upstream.DKIM != ResultFail {
+			return &RuleResult{Action: ActionContinue, Continue: true}, nil
+		}
+	} else if r.dkim == nil || req.DKIMSignature == "" {
 		return &RuleResult{Action: ActionContinue, Continue: true}, nil
 	}
 
-	valid, err := r.dkim.Verify(req.Headers, req.Body, req.DKIMSignature)
+	valid, err := upstreamDKIM(req, r.dkim)
 	if err != nil {
 		return &RuleResult{Action: ActionContinue, Continue: true}, err
 	}
@@ -363,14 +374,14 @@ func (r *DMARCRule) Check(ctx context.Context, req *CheckRequest) (*RuleResult,
 
 	// 获取 SPF 结果
 	spfResult := ResultNone
-	if r.spf != nil {
-		spfResult, _ = r.spf.Check(req.IP, req.Domain, req.HELO)
+	if r.spf != nil || req.Upstream != nil {
+		spfResult, _ = upstreamSPF(req, r.spf)
 	}
 
 	// 获取 DKIM 结果
 	dkimValid := false
-	if r.dkim != nil && req.DKIMSignature != "" {
-		dkimValid, _ = r.dkim.Verify(req.Headers, req.Body, req.DKIMSignature)
+	if (r.dkim != nil && req.DKIMSignature != "") || req.Upstream != nil {
+		dkimValid, _ = upstreamDKIM(req, r.dkim)
 	}
 
 	policy, err := r.dmarc.Check(req.Domain, spfResult, dkimValid)
@@ -438,3 +449,19 @@ func (r *HELORule) Check(ctx context.Context, req *CheckRequest) (*RuleResult, e
 
 	return &RuleResult{Action: ActionContinue, Continue: true}, nil
 }
+
+// upstreamSPF 来自受信任的上游网关时使用网关的 SPF 结果（对网关 IP 检查 SPF 必然失败），否则检查 SPF
+func upstreamSPF(req *CheckRequest, spf *SPF) (Result, error) {
+	if req.Upstream != nil {
+		return req.Upstream.SPF, nil
+	}
+	return spf.Check(req.IP, req.Domain, req.HELO)
+}
+
+// upstreamDKIM 来自受信任的上游网关时使用网关的 DKIM 结果，否则验证签名
+func upstreamDKIM(req *CheckRequest, dkim *DKIM) (bool, error) {
+	if req.Upstream != nil {
+		return req.Upstream.DKIM == ResultPass, nil
+	}
+	return dkim.Verify(req.Headers, req.Body, req.DKIMSignature)
+}
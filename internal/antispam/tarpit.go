@@ -0,0 +1,156 @@
+package antispam
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// Tarpit 连接减速：IP 在窗口内失败次数达到阈值后，后续连接的每次响应都会被延迟
+// 与直接拒绝相比，减速可以显著提高字典攻击的成本，同时不会暴露判定结果
+type Tarpit struct {
+	threshold int           // 触发减速的失败次数
+	window    time.Duration // 失败次数统计窗口
+	delay     time.Duration // 达到阈值时的延迟，之后每次失败递增
+	maxDelay  time.Duration // 延迟上限
+	offenders map[string]*offense
+	mu        sync.Mutex
+	now       func() time.Time
+}
+
+// offense IP 的失败记录
+type offense struct {
+	count int
+	first time.Time
+	last  time.Time
+}
+
+// NewTarpit 创建连接减速器
+func NewTarpit(threshold int, window, delay, maxDelay time.Duration) *Tarpit {
+	if maxDelay < delay {
+		maxDelay = delay
+	}
+	return &Tarpit{
+		threshold: threshold,
+		window:    window,
+		delay:     delay,
+		maxDelay:  maxDelay,
+		offenders: make(map[string]*offense),
+		now:       time.Now,
+	}
+}
+
+// RecordFailure 记录一次失败（认证失败、被拒收等）
+func (t *Tarpit) RecordFailure(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	o, ok := t.offenders[ip]
+	if !ok || now.Sub(o.first) > t.window {
+		o = &offense{first: now}
+		t.offenders[ip] = o
+	}
+	o.count++
+	o.last = now
+
+	if o.count == t.threshold {
+		logger.Warn().Str("ip", ip).Int("failures", o.count).Msg("IP 失败次数过多，开始减速连接")
+	}
+}
+
+// Reset 清除 IP 的失败记录（如认证成功后）
+func (t *Tarpit) Reset(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.offenders, ip)
+}
+
+// Delay 返回该 IP 当前每次响应的延迟，未达到阈值时为 0
+func (t *Tarpit) Delay(ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	o, ok := t.offenders[ip]
+	if !ok || o.count < t.threshold || t.now().Sub(o.first) > t.window {
+		return 0
+	}
+
+	delay := t.delay * time.Duration(o.count-t.threshold+1)
+	if delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+	return delay
+}
+
+// Listener 包装监听器，被减速 IP 的连接在每次写出响应（包括欢迎横幅）前等待
+func (t *Tarpit) Listener(l net.Listener) net.Listener {
+	return &tarpitListener{Listener: l, tarpit: t}
+}
+
+// Cleanup 定期清理过期的失败记录
+func (t *Tarpit) Cleanup(ctx context.Context) {
+	ticker := time.NewTicker(t.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.mu.Lock()
+			now := t.now()
+			for ip, o := range t.offenders {
+				if now.Sub(o.first) > t.window {
+					delete(t.offenders, ip)
+				}
+			}
+			t.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// tarpitListener 减速监听器
+type tarpitListener struct {
+	net.Listener
+	tarpit *Tarpit
+}
+
+// Accept 接受连接
+func (l *tarpitListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &tarpitConn{Conn: conn, tarpit: l.tarpit, ip: RemoteIP(conn.RemoteAddr())}, nil
+}
+
+// tarpitConn 减速连接，每次写出前按当前失败次数等待（会话中的新失败立即生效）
+type tarpitConn struct {
+	net.Conn
+	tarpit *Tarpit
+	ip     string
+}
+
+// Write 写出数据
+func (c *tarpitConn) Write(b []byte) (int, error) {
+	if delay := c.tarpit.Delay(c.ip); delay > 0 {
+		time.Sleep(delay)
+	}
+	return c.Conn.Write(b)
+}
+
+// RemoteIP 返回地址中的 IP 部分
+func RemoteIP(addr net.Addr) string {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
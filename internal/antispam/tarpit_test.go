@@ -0,0 +1,53 @@
+package antispam
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTarpitDelay(t *testing.T) {
+	now := time.Now()
+	tarpit := NewTarpit(3, 10*time.Minute, time.Second, 3*time.Second)
+	tarpit.now = func() time.Time { return now }
+
+	ip := "192.0.2.1"
+	for i := 0; i < 2; i++ {
+		tarpit.RecordFailure(ip)
+	}
+	if d := tarpit.Delay(ip); d != 0 {
+		t.Errorf("未达到阈值时不应该减速, got %v", d)
+	}
+
+	tarpit.RecordFailure(ip)
+	if d := tarpit.Delay(ip); d != time.Second {
+		t.Errorf("达到阈值时延迟应该为 1s, got %v", d)
+	}
+
+	// 之后每次失败递增，但不超过上限
+	for i := 0; i < 5; i++ {
+		tarpit.RecordFailure(ip)
+	}
+	if d := tarpit.Delay(ip); d != 3*time.Second {
+		t.Errorf("延迟应该被限制为 3s, got %v", d)
+	}
+
+	if d := tarpit.Delay("192.0.2.2"); d != 0 {
+		t.Errorf("其他 IP 不应该被减速, got %v", d)
+	}
+
+	// 超出统计窗口后恢复
+	now = now.Add(11 * time.Minute)
+	if d := tarpit.Delay(ip); d != 0 {
+		t.Errorf("超出窗口后不应该减速, got %v", d)
+	}
+
+	// 重置后恢复
+	now = time.Now()
+	for i := 0; i < 3; i++ {
+		tarpit.RecordFailure(ip)
+	}
+	tarpit.Reset(ip)
+	if d := tarpit.Delay(ip); d != 0 {
+		t.Errorf("重置后不应该减速, got %v", d)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 退信统计接口的参数限制
+const (
+	defaultBounceDays  = 7
+	maxBounceDays      = 90 // 与 storage.BounceRetention 一致
+	defaultBounceLimit = 20
+	maxBounceLimit     = 100
+)
+
+// bounceStatsHandler 统计最近 days 天的退信：按原因分类，以及退信最多的目标域名和发件人
+func bounceStatsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultBounceDays)))
+		if err != nil || days < 1 || days > maxBounceDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days 需要是 1 ~ 90 的整数",
+			})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultBounceLimit)))
+		if limit <= 0 || limit > maxBounceLimit {
+			limit = defaultBounceLimit
+		}
+
+		stats, err := driver.GetBounceStats(c.Request.Context(), time.Now().AddDate(0, 0, -days), limit)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// 没有退信的分类也返回 0，便于前端展示
+		for _, category := range bounce.Categories {
+			if _, ok := stats.Categories[category]; !ok {
+				stats.Categories[category] = 0
+			}
+		}
+		c.JSON(http.StatusOK, stats)
+	}
+}
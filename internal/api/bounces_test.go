@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestBounceStatsHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.RecordBounce(ctx, &storage.Bounce{Sender: "alice@example.com", Recipient: "bob@example.org", Category: "user_unknown", Status: "5.1.1", Source: "smtp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/stats/bounces", bounceStatsHandler(driver))
+
+	w := serve(router, http.MethodGet, "/stats/bounces?days=30", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("退信统计 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var stats storage.BounceStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 1 || len(stats.Domains) != 1 || stats.Domains[0].Key != "example.org" || stats.Senders[0].Key != "alice@example.com" {
+		t.Errorf("退信统计 = %+v", stats)
+	}
+	// 没有退信的分类也返回
+	if count, ok := stats.Categories["mailbox_full"]; !ok || count != 0 || stats.Categories["user_unknown"] != 1 {
+		t.Errorf("分类 = %v", stats.Categories)
+	}
+
+	for _, days := range []string{"0", "91", "x"} {
+		if w := serve(router, http.MethodGet, "/stats/bounces?days="+days, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("days=%s status = %d, want 400", days, w.Code)
+		}
+	}
+}
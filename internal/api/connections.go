@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// ConnectionManager 协议服务器的连接管理（smtpd.Server、imapd.Server）
+type ConnectionManager interface {
+	Connections() []drain.Info
+	Disconnect(id uint64) bool
+}
+
+// listConnectionsHandler 列出在线的 SMTP/IMAP 连接（用户、IP、状态、时长、命令数），按连接时间排序
+// 可以用 protocol 和 user 查询参数过滤
+func listConnectionsHandler(managers []ConnectionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		protocol := c.Query("protocol")
+		user := c.Query("user")
+
+		conns := []drain.Info{}
+		for _, m := range managers {
+			for _, info := range m.Connections() {
+				if protocol != "" && info.Protocol != protocol {
+					continue
+				}
+				if user != "" && info.User != user {
+					continue
+				}
+				conns = append(conns, info)
+			}
+		}
+		sort.Slice(conns, func(i, j int) bool { return conns[i].ID < conns[j].ID })
+
+		c.JSON(http.StatusOK, gin.H{
+			"connections": conns,
+			"total":       len(conns),
+		})
+	}
+}
+
+// disconnectHandler 强制断开连接（进行中的命令或邮件事务被中断）
+func disconnectHandler(managers []ConnectionManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的连接 ID",
+			})
+			return
+		}
+
+		for _, m := range managers {
+			if m.Disconnect(id) {
+				logger.InfoCtx(c.Request.Context()).Uint64("connection_id", id).Msg("管理员断开了连接")
+				c.JSON(http.StatusOK, gin.H{
+					"message": "连接已断开",
+				})
+				return
+			}
+		}
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "连接不存在",
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/drain"
+)
+
+// fakeConnections 固定连接列表的 ConnectionManager
+type fakeConnections struct {
+	conns        []drain.Info
+	disconnected []uint64
+}
+
+func (f *fakeConnections) Connections() []drain.Info {
+	return f.conns
+}
+
+func (f *fakeConnections) Disconnect(id uint64) bool {
+	for _, info := range f.conns {
+		if info.ID == id {
+			f.disconnected = append(f.disconnected, id)
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnectionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	smtp := &fakeConnections{conns: []drain.Info{{ID: 3, Protocol: "smtp", State: "mail"}}}
+	imap := &fakeConnections{conns: []drain.Info{{ID: 1, Protocol: "imap", User: "alice@example.com", State: "authenticated"}}}
+	managers := []ConnectionManager{smtp, imap}
+
+	router := gin.New()
+	router.GET("/connections", listConnectionsHandler(managers))
+	router.DELETE("/connections/:id", disconnectHandler(managers))
+
+	list := func(query string) []drain.Info {
+		t.Helper()
+		w := serve(router, http.MethodGet, "/connections"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("列出连接 status = %d, body = %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Connections []drain.Info `json:"connections"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Connections
+	}
+	if conns := list(""); len(conns) != 2 || conns[0].ID != 1 || conns[1].ID != 3 {
+		t.Errorf("连接列表 = %+v, want 按 ID 排序的 2 个连接", conns)
+	}
+	if conns := list("?protocol=smtp"); len(conns) != 1 || conns[0].ID != 3 {
+		t.Errorf("按协议过滤 = %+v", conns)
+	}
+	if conns := list("?user=alice@example.com"); len(conns) != 1 || conns[0].ID != 1 {
+		t.Errorf("按用户过滤 = %+v", conns)
+	}
+
+	if w := serve(router, http.MethodDelete, "/connections/3", nil); w.Code != http.StatusOK {
+		t.Errorf("断开连接 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if len(smtp.disconnected) != 1 || len(imap.disconnected) != 0 {
+		t.Errorf("断开的连接 smtp = %v, imap = %v", smtp.disconnected, imap.disconnected)
+	}
+	if w := serve(router, http.MethodDelete, "/connections/42", nil); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的连接 status = %d", w.Code)
+	}
+	if w := serve(router, http.MethodDelete, "/connections/abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("无效的连接 ID status = %d", w.Code)
+	}
+}
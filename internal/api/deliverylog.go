@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 投递日志查询接口的参数限制
+const (
+	defaultDeliveryLogDays  = 7
+	maxDeliveryLogDays      = 30 // 与 storage.DeliveryLogRetention 一致
+	defaultDeliveryLogLimit = 100
+	maxDeliveryLogLimit     = 1000
+)
+
+// searchDeliveryLogHandler 查询最近 days 天的投递日志：query 为地址的一部分、队列 ID 或 Message-ID，为空时返回全部（最新的在前）
+func searchDeliveryLogHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultDeliveryLogDays)))
+		if err != nil || days < 1 || days > maxDeliveryLogDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days 需要是 1 ~ 30 的整数",
+			})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveryLogLimit)))
+		if limit <= 0 || limit > maxDeliveryLogLimit {
+			limit = defaultDeliveryLogLimit
+		}
+
+		events, err := driver.SearchDeliveryLog(c.Request.Context(), c.Query("query"), time.Now().AddDate(0, 0, -days), limit)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"events": events,
+			"total":  len(events),
+		})
+	}
+}
+
+// deliveryTraceHandler 按队列 ID 或 Message-ID 追踪一封邮件经过的所有阶段（按时间顺序）
+func deliveryTraceHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		events, err := driver.GetDeliveryTrace(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"id":     c.Param("id"),
+			"events": events,
+		})
+	}
+}
+
+// searchTranscriptsHandler 查询外发会话记录：query 为队列 ID、Message-ID、收件人域名或地址的一部分，
+// 为空时返回全部（最新的在前，不含会话内容）
+func searchTranscriptsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultDeliveryLogLimit)))
+		if limit <= 0 || limit > maxDeliveryLogLimit {
+			limit = defaultDeliveryLogLimit
+		}
+
+		transcripts, err := driver.SearchSMTPTranscripts(c.Request.Context(), c.Query("query"), limit)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"transcripts": transcripts,
+			"total":       len(transcripts),
+		})
+	}
+}
+
+// getTranscriptHandler 获取一条外发会话记录（包含完整的命令和回复）
+func getTranscriptHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的会话记录 ID",
+			})
+			return
+		}
+		transcript, err := driver.GetSMTPTranscript(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, transcript)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestDeliveryLogHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	for _, e := range []*storage.DeliveryEvent{
+		{QueueID: "Q1", MessageID: "m1@example.com", Event: storage.DeliveryReceived, Sender: "alice@example.org", Recipient: "bob@example.com", Code: 250},
+		{QueueID: "Q1", MessageID: "m1@example.com", Event: storage.DeliveryDelivered, Sender: "alice@example.org", Recipient: "bob@example.com", Folder: "INBOX"},
+		{QueueID: "Q2", MessageID: "m2@example.com", Event: storage.DeliveryReceived, Sender: "carol@example.net", Recipient: "bob@example.com", Code: 250},
+	} {
+		if err := driver.RecordDeliveryEvent(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/logs/messages", searchDeliveryLogHandler(driver))
+	router.GET("/logs/messages/:id", deliveryTraceHandler(driver))
+
+	w := serve(router, http.MethodGet, "/logs/messages?query=alice", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("查询投递日志 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Events []*storage.DeliveryEvent `json:"events"`
+		Total  int                      `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || result.Events[0].Event != storage.DeliveryDelivered {
+		t.Errorf("查询结果 = %+v", result)
+	}
+	for _, days := range []string{"0", "31", "x"} {
+		if w := serve(router, http.MethodGet, "/logs/messages?days="+days, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("days=%s status = %d, want 400", days, w.Code)
+		}
+	}
+
+	// 按 Message-ID 追踪，按时间顺序返回
+	w = serve(router, http.MethodGet, "/logs/messages/m1@example.com", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("追踪邮件 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	result.Events = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Events) != 2 || result.Events[0].Event != storage.DeliveryReceived || result.Events[1].Folder != "INBOX" {
+		t.Errorf("追踪结果 = %+v", result.Events)
+	}
+	if w := serve(router, http.MethodGet, "/logs/messages/missing", nil); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的邮件 status = %d, want 404", w.Code)
+	}
+}
+
+func TestTranscriptHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	transcript := &storage.SMTPTranscript{QueueID: "Q1", MessageID: "m1@example.com", Sender: "alice@example.com",
+		Domain: "gmail.test", Recipients: []string{"carol@gmail.test"}, Transcript: "   0.010s S: 250 2.0.0 OK queued\n", StartedAt: time.Now()}
+	if err := driver.SaveSMTPTranscript(ctx, transcript); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/logs/transcripts", searchTranscriptsHandler(driver))
+	router.GET("/logs/transcripts/:id", getTranscriptHandler(driver))
+
+	w := serve(router, http.MethodGet, "/logs/transcripts?query=gmail.test", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("查询会话记录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var result struct {
+		Transcripts []*storage.SMTPTranscript `json:"transcripts"`
+		Total       int                       `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 || result.Transcripts[0].Transcript != "" {
+		t.Errorf("查询结果应该有 1 条不含会话内容的记录: %+v", result)
+	}
+
+	w = serve(router, http.MethodGet, fmt.Sprintf("/logs/transcripts/%d", transcript.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("获取会话记录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var got storage.SMTPTranscript
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Transcript != transcript.Transcript {
+		t.Errorf("会话内容 = %q", got.Transcript)
+	}
+	for path, want := range map[string]int{"/logs/transcripts/999": http.StatusNotFound, "/logs/transcripts/x": http.StatusBadRequest} {
+		if w := serve(router, http.MethodGet, path, nil); w.Code != want {
+			t.Errorf("%s status = %d, want %d", path, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// dkimKeyDir 域名 DKIM 私钥文件所在目录（相对于工作目录）
+const dkimKeyDir = "dkim"
+
+// dnsNamePattern 域名和选择器（一个或多个 DNS 标签）
+var dnsNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+
+// dkimRecord 域名的 DKIM 密钥和需要发布的 DNS 记录
+type dkimRecord struct {
+	*storage.DKIMKey
+	DNSName  string `json:"dns_name"`  // <selector>._domainkey.<domain>
+	DNSType  string `json:"dns_type"`  // TXT
+	DNSValue string `json:"dns_value"` // 记录的完整值
+	DNSZone  string `json:"dns_zone"`  // 区域文件格式的记录（值超过 255 字节时拆分为多个字符串）
+	Active   bool   `json:"active"`    // 是否已用于外发签名（通过中继外发并由中继签名时为 false）
+}
+
+// newDKIMRecord 根据公钥生成 DNS 记录
+func newDKIMRecord(key *storage.DKIMKey, publicKey crypto.PublicKey, active bool) (*dkimRecord, error) {
+	value, err := antispam.GetPublicKeyDNS(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	name := key.Selector + "._domainkey." + key.Domain
+	return &dkimRecord{
+		DKIMKey:  key,
+		DNSName:  name,
+		DNSType:  "TXT",
+		DNSValue: value,
+		DNSZone:  name + ". IN TXT " + antispam.QuoteTXT(value),
+		Active:   active,
+	}, nil
+}
+
+// generateDKIMHandler 为域名生成 DKIM 密钥（已有密钥时替换），私钥保存在工作目录的 dkim 目录下，
+// 立即用于该域名的外发签名，返回需要发布的 DNS TXT 记录
+func generateDKIMHandler(driver storage.Driver, workDir string, signers *antispam.DKIMSigners) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Selector  string `json:"selector"`  // 默认为 default
+			Algorithm string `json:"algorithm"` // rsa（默认）或 ed25519
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if req.Selector == "" {
+			req.Selector = "default"
+		}
+		if req.Algorithm == "" {
+			req.Algorithm = "rsa"
+		}
+		if !dnsNamePattern.MatchString(req.Selector) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("无效的选择器: %s", req.Selector),
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		domain, err := driver.GetDomain(ctx, c.Param("name"))
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		name := strings.ToLower(domain.Name)
+		if !dnsNamePattern.MatchString(name) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("无效的域名: %s", domain.Name),
+			})
+			return
+		}
+
+		privateKey, publicKey, err := antispam.GenerateKeyPair(req.Algorithm)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		signer, err := antispam.NewDKIM(name, req.Selector, privateKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		key := &storage.DKIMKey{
+			Domain:    name,
+			Selector:  req.Selector,
+			Algorithm: req.Algorithm,
+			KeyFile:   filepath.Join(dkimKeyDir, name+"."+req.Selector+".key"),
+		}
+		if err := writeDKIMKey(filepath.Join(workDir, key.KeyFile), privateKey); err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("domain", name).Msg("保存 DKIM 私钥失败")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := driver.SaveDKIMKey(ctx, key); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		if signers != nil {
+			signers.Set(name, signer)
+		}
+		logger.InfoCtx(ctx).
+			Str("domain", name).
+			Str("selector", key.Selector).
+			Str("algorithm", signer.Algorithm()).
+			Bool("active", signers != nil).
+			Msg("已生成域名 DKIM 密钥")
+
+		record, err := newDKIMRecord(key, publicKey, signers != nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, record)
+	}
+}
+
+// getDKIMHandler 查询域名的 DKIM 密钥和需要发布的 DNS TXT 记录
+func getDKIMHandler(driver storage.Driver, workDir string, signers *antispam.DKIMSigners) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, err := driver.GetDKIMKey(c.Request.Context(), c.Param("name"))
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		privateKey, err := smtpclient.LoadPrivateKey(key.KeyFile, workDir)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		record, err := newDKIMRecord(key, privateKey.(crypto.Signer).Public(), signers != nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, record)
+	}
+}
+
+// writeDKIMKey 以 PKCS#8 PEM 格式写入私钥文件（目录权限 0700，文件权限 0600）
+func writeDKIMKey(path string, privateKey crypto.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("编码私钥失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建 DKIM 密钥目录失败: %w", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("写入私钥文件失败: %w", err)
+	}
+	return nil
+}
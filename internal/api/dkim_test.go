@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestDKIMHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	workDir := t.TempDir()
+	signers := antispam.NewDKIMSigners(nil)
+	router := gin.New()
+	router.GET("/domains/:name/dkim", getDKIMHandler(driver, workDir, signers))
+	router.POST("/domains/:name/dkim", generateDKIMHandler(driver, workDir, signers))
+
+	if w := serve(router, http.MethodGet, "/domains/example.com/dkim", nil); w.Code != http.StatusNotFound {
+		t.Errorf("未生成密钥时 status = %d, want 404", w.Code)
+	}
+
+	// 默认选择器和算法（请求体可以为空）
+	w := serve(router, http.MethodPost, "/domains/example.com/dkim", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("生成密钥 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var record dkimRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Selector != "default" || record.Algorithm != "rsa" || record.DNSName != "default._domainkey.example.com" ||
+		record.DNSType != "TXT" || !strings.HasPrefix(record.DNSValue, "v=DKIM1; k=rsa; p=") || !record.Active {
+		t.Errorf("生成密钥 = %s", w.Body.String())
+	}
+	// RSA 公钥超过 255 字节，区域文件格式拆分为多个字符串
+	if !strings.HasPrefix(record.DNSZone, "default._domainkey.example.com. IN TXT \"v=DKIM1") || !strings.Contains(record.DNSZone, "\" \"") {
+		t.Errorf("区域文件记录 = %s", record.DNSZone)
+	}
+	if signers.For("alice@example.com") == nil {
+		t.Error("生成密钥后应该立即用于签名")
+	}
+
+	info, err := os.Stat(filepath.Join(workDir, record.KeyFile))
+	if err != nil {
+		t.Fatalf("私钥文件不存在: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("私钥文件权限 = %v, want 0600", info.Mode().Perm())
+	}
+
+	// 重新生成时替换，查询返回新密钥的记录
+	w = serve(router, http.MethodPost, "/domains/example.com/dkim", []byte(`{"selector":"s2024","algorithm":"ed25519"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("重新生成密钥 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var replaced dkimRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &replaced); err != nil {
+		t.Fatal(err)
+	}
+	w = serve(router, http.MethodGet, "/domains/example.com/dkim", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("查询密钥 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var got dkimRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Selector != "s2024" || got.DNSName != "s2024._domainkey.example.com" || got.DNSValue != replaced.DNSValue ||
+		!strings.HasPrefix(got.DNSValue, "v=DKIM1; k=ed25519; p=") {
+		t.Errorf("查询密钥 = %s", w.Body.String())
+	}
+	if signer := signers.For("alice@example.com"); signer == nil || signer.Algorithm() != "ed25519-sha256" {
+		t.Error("重新生成后应该使用新密钥签名")
+	}
+
+	for _, tt := range []struct {
+		path string
+		body string
+		want int
+	}{
+		{"/domains/unknown.org/dkim", `{}`, http.StatusNotFound},
+		{"/domains/example.com/dkim", `{"algorithm":"dsa"}`, http.StatusBadRequest},
+		{"/domains/example.com/dkim", `{"selector":"../../etc"}`, http.StatusBadRequest},
+	} {
+		if w := serve(router, http.MethodPost, tt.path, []byte(tt.body)); w.Code != tt.want {
+			t.Errorf("POST %s %s status = %d, want %d", tt.path, tt.body, w.Code, tt.want)
+		}
+	}
+
+	// 由中继签名时只保存密钥，不用于签名
+	relayed := gin.New()
+	relayed.POST("/domains/:name/dkim", generateDKIMHandler(driver, workDir, nil))
+	w = serve(relayed, http.MethodPost, "/domains/example.com/dkim", []byte(`{"algorithm":"ed25519"}`))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), `"active":true`) {
+		t.Errorf("未启用签名时 status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
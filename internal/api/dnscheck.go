@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/dnscheck"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// dnsCheckTimeout DNS 检查的最长时间
+const dnsCheckTimeout = 20 * time.Second
+
+// dnsCheckHandler 检查域名的 DNS 设置（MX、SPF、DKIM、DMARC、反向解析），返回逐项的检查报告；
+// DKIM 检查该域名外发签名使用的密钥（没有签名时跳过）
+func dnsCheckHandler(driver storage.Driver, checker *dnscheck.Checker, signers *antispam.DKIMSigners) gin.HandlerFunc {
+	if checker == nil {
+		checker = &dnscheck.Checker{}
+	}
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		domain, err := driver.GetDomain(ctx, c.Param("name"))
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		var dkim *dnscheck.DKIMRecord
+		if signer := signers.For("postmaster@" + domain.Name); signer != nil {
+			value, err := signer.PublicKeyDNS()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			dkim = &dnscheck.DKIMRecord{Domain: signer.Domain(), Selector: signer.Selector(), Value: value}
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, dnsCheckTimeout)
+		defer cancel()
+		c.JSON(http.StatusOK, checker.Check(ctx, domain.Name, dkim))
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/dnscheck"
+)
+
+// emptyResolver 没有任何 DNS 记录
+type emptyResolver struct{}
+
+var errNoHost = errors.New("no such host")
+
+func (emptyResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return nil, errNoHost
+}
+
+func (emptyResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return nil, errNoHost
+}
+
+func (emptyResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return nil, errNoHost
+}
+
+func (emptyResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return nil, errNoHost
+}
+
+func TestDNSCheckHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	privateKey, _, _ := antispam.GenerateKeyPair("ed25519")
+	signer, _ := antispam.NewDKIM("example.com", "s1", privateKey)
+	signers := antispam.NewDKIMSigners(nil)
+	signers.Set("example.com", signer)
+
+	checker := &dnscheck.Checker{Resolver: emptyResolver{}, Hostname: "mail.example.com"}
+	router := gin.New()
+	router.GET("/domains/:name/dns-check", dnsCheckHandler(&MockStorageDriver{}, checker, signers))
+
+	w := serve(router, http.MethodGet, "/domains/example.com/dns-check", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("DNS 检查 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var report dnscheck.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Passed || report.Hostname != "mail.example.com" || len(report.Checks) != 5 {
+		t.Fatalf("DNS 检查报告 = %s", w.Body.String())
+	}
+	for _, check := range report.Checks {
+		if check.Status != dnscheck.StatusFail {
+			t.Errorf("%s = %s, want fail", check.Name, check.Status)
+		}
+		if check.Name == dnscheck.CheckDKIM && check.Expected == "" {
+			t.Error("DKIM 检查应该给出签名密钥对应的记录")
+		}
+	}
+
+	// 域名没有签名密钥时跳过 DKIM 检查
+	router = gin.New()
+	router.GET("/domains/:name/dns-check", dnsCheckHandler(&MockStorageDriver{}, checker, nil))
+	w = serve(router, http.MethodGet, "/domains/example.com/dns-check", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	for _, check := range report.Checks {
+		if check.Name == dnscheck.CheckDKIM && check.Status != dnscheck.StatusSkip {
+			t.Errorf("没有签名密钥时 DKIM = %s, want skip", check.Status)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 垃圾邮件指纹接口的参数限制
+const (
+	defaultFingerprintDays  = 7
+	maxFingerprintDays      = 90 // 与 storage.SpamFingerprintRetention 一致
+	defaultFingerprintLimit = 50
+	maxFingerprintLimit     = 500
+)
+
+// listFingerprintsHandler 列出最近 days 天内出现过的垃圾邮件指纹（活跃的垃圾邮件活动）
+func listFingerprintsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultFingerprintDays)))
+		if err != nil || days < 1 || days > maxFingerprintDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "days 需要是 1 ~ 90 的整数",
+			})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultFingerprintLimit)))
+		if limit <= 0 || limit > maxFingerprintLimit {
+			limit = defaultFingerprintLimit
+		}
+
+		fingerprints, err := driver.ListSpamFingerprints(c.Request.Context(), time.Now().AddDate(0, 0, -days), limit)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if fingerprints == nil {
+			fingerprints = []*storage.SpamFingerprint{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"fingerprints": fingerprints,
+			"total":        len(fingerprints),
+		})
+	}
+}
+
+// deleteFingerprintHandler 删除垃圾邮件指纹（误判的正常邮件）
+func deleteFingerprintHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的指纹 ID",
+			})
+			return
+		}
+
+		if err := driver.DeleteSpamFingerprint(c.Request.Context(), id); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "指纹已删除",
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestFingerprintHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	fp := &storage.SpamFingerprint{Hash: "aa", Reason: "rejected", Sample: "claim your prize"}
+	if err := driver.ReportSpamFingerprint(ctx, fp, "spam.test"); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/antispam/fingerprints", listFingerprintsHandler(driver))
+	router.DELETE("/antispam/fingerprints/:id", deleteFingerprintHandler(driver))
+
+	w := serve(router, http.MethodGet, "/antispam/fingerprints?days=7", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("列出指纹 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Fingerprints []*storage.SpamFingerprint `json:"fingerprints"`
+		Total        int                        `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 1 || resp.Fingerprints[0].ID != fp.ID || resp.Fingerprints[0].Sources != 1 {
+		t.Errorf("列出指纹 = %+v", resp)
+	}
+
+	if w := serve(router, http.MethodGet, "/antispam/fingerprints?days=365", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("days 超出范围 status = %d, want 400", w.Code)
+	}
+
+	path := "/antispam/fingerprints/" + strconv.FormatInt(fp.ID, 10)
+	if w := serve(router, http.MethodDelete, path, nil); w.Code != http.StatusOK {
+		t.Fatalf("删除指纹 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodDelete, path, nil); w.Code != http.StatusNotFound {
+		t.Errorf("重复删除 status = %d, want 404", w.Code)
+	}
+	if w := serve(router, http.MethodDelete, "/antispam/fingerprints/abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("无效 ID status = %d, want 400", w.Code)
+	}
+}
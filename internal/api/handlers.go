@@ -1,16 +1,22 @@
 package api
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/alias"
 	"github.com/gomailzero/gmz/internal/auth"
 	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/provision"
 	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/testmail"
 )
 
 // listDomainsHandler 列出域名
@@ -37,6 +43,10 @@ func createDomainHandler(driver storage.Driver) gin.HandlerFunc {
 		var req struct {
 			Name   string `json:"name" binding:"required"`
 			Active bool   `json:"active"`
+			// 配额预警阈值（用量百分比），为空时使用全局默认值
+			QuotaWarningThresholds []int `json:"quota_warning_thresholds"`
+			// 内部邮件回执策略：为空时不记录，delivered 只记录投递时间，read 同时记录首次阅读时间
+			Receipts string `json:"receipts"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -46,8 +56,10 @@ func createDomainHandler(driver storage.Driver) gin.HandlerFunc {
 		}
 
 		domain := &storage.Domain{
-			Name:   req.Name,
-			Active: req.Active,
+			Name:                   req.Name,
+			Active:                 req.Active,
+			QuotaWarningThresholds: req.QuotaWarningThresholds,
+			Receipts:               req.Receipts,
 		}
 		// 设置默认值
 		if !req.Active {
@@ -56,7 +68,7 @@ func createDomainHandler(driver storage.Driver) gin.HandlerFunc {
 
 		ctx := c.Request.Context()
 		if err := driver.CreateDomain(ctx, domain); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -89,8 +101,13 @@ func updateDomainHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		name := c.Param("name")
 		var req struct {
-			Name   string `json:"name"`
-			Active bool   `json:"active"`
+			Name               string `json:"name"`
+			Active             bool   `json:"active"`
+			ForwardingDisabled *bool  `json:"forwarding_disabled"` // 为空时保持不变
+			// 配额预警阈值（用量百分比），为空时保持不变，空数组表示使用全局默认值
+			QuotaWarningThresholds *[]int `json:"quota_warning_thresholds"`
+			// 内部邮件回执策略，省略时保持不变
+			Receipts *string `json:"receipts"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -113,9 +130,18 @@ func updateDomainHandler(driver storage.Driver) gin.HandlerFunc {
 			domain.Name = req.Name
 		}
 		domain.Active = req.Active
+		if req.ForwardingDisabled != nil {
+			domain.ForwardingDisabled = *req.ForwardingDisabled
+		}
+		if req.QuotaWarningThresholds != nil {
+			domain.QuotaWarningThresholds = *req.QuotaWarningThresholds
+		}
+		if req.Receipts != nil {
+			domain.Receipts = *req.Receipts
+		}
 
 		if err := driver.UpdateDomain(ctx, domain); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -132,7 +158,7 @@ func deleteDomainHandler(driver storage.Driver) gin.HandlerFunc {
 		ctx := c.Request.Context()
 
 		if err := driver.DeleteDomain(ctx, name); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -206,7 +232,7 @@ func createUserHandler(driver storage.Driver) gin.HandlerFunc {
 
 		ctx := c.Request.Context()
 		if err := driver.CreateUser(ctx, user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -285,7 +311,7 @@ func updateUserHandler(driver storage.Driver) gin.HandlerFunc {
 		}
 
 		if err := driver.UpdateUser(ctx, user); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -303,7 +329,7 @@ func deleteUserHandler(driver storage.Driver) gin.HandlerFunc {
 		ctx := c.Request.Context()
 
 		if err := driver.DeleteUser(ctx, email); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -339,9 +365,10 @@ func listAliasesHandler(driver storage.Driver) gin.HandlerFunc {
 func createAliasHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			From   string `json:"from" binding:"required"`
-			To     string `json:"to" binding:"required"`
-			Domain string `json:"domain" binding:"required"`
+			From         string   `json:"from" binding:"required"`
+			To           string   `json:"to"`
+			Destinations []string `json:"destinations"` // 多个目标地址（本地或外部），优先于 to
+			Domain       string   `json:"domain" binding:"required"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -349,16 +376,23 @@ func createAliasHandler(driver storage.Driver) gin.HandlerFunc {
 			})
 			return
 		}
+		if req.To == "" && len(req.Destinations) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "to 和 destinations 不能同时为空",
+			})
+			return
+		}
 
 		alias := &storage.Alias{
-			From:   req.From,
-			To:     req.To,
-			Domain: req.Domain,
+			From:         req.From,
+			To:           req.To,
+			Destinations: req.Destinations,
+			Domain:       req.Domain,
 		}
 
 		ctx := c.Request.Context()
 		if err := driver.CreateAlias(ctx, alias); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -368,6 +402,39 @@ func createAliasHandler(driver storage.Driver) gin.HandlerFunc {
 	}
 }
 
+// updateAliasDestinationsHandler 更新别名的目标地址
+func updateAliasDestinationsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from := c.Param("from")
+		var req struct {
+			Destinations []string `json:"destinations" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := driver.UpdateAliasDestinations(ctx, from, req.Destinations); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		alias, err := driver.GetAlias(ctx, from)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, alias)
+	}
+}
+
 // deleteAliasHandler 删除别名
 func deleteAliasHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -375,7 +442,7 @@ func deleteAliasHandler(driver storage.Driver) gin.HandlerFunc {
 		ctx := c.Request.Context()
 
 		if err := driver.DeleteAlias(ctx, from); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -387,6 +454,200 @@ func deleteAliasHandler(driver storage.Driver) gin.HandlerFunc {
 	}
 }
 
+// getAliasPolicyHandler 获取域名的自助别名策略（未配置时返回不允许自助创建的默认策略）
+func getAliasPolicyHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		ctx := c.Request.Context()
+
+		policy, err := driver.GetAliasPolicy(ctx, name)
+		if errors.Is(err, storage.ErrNotFound) {
+			policy = &storage.AliasPolicy{Domain: name}
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, policy)
+	}
+}
+
+// updateAliasPolicyHandler 更新域名的自助别名策略
+func updateAliasPolicyHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		var req struct {
+			MaxAliases int      `json:"max_aliases"`
+			Patterns   []string `json:"patterns"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if req.MaxAliases < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "max_aliases 不能为负数",
+			})
+			return
+		}
+		if err := alias.ValidatePatterns(req.Patterns); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		if _, err := driver.GetDomain(ctx, name); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "域名不存在",
+			})
+			return
+		}
+
+		policy := &storage.AliasPolicy{
+			Domain:     name,
+			MaxAliases: req.MaxAliases,
+			Patterns:   req.Patterns,
+		}
+		if err := driver.SaveAliasPolicy(ctx, policy); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, policy)
+	}
+}
+
+// getDomainDefaultsHandler 获取域名的默认账户设置（未配置时返回空设置，全部使用系统默认值）
+func getDomainDefaultsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		defaults, err := driver.GetDomainDefaults(c.Request.Context(), name)
+		if errors.Is(err, storage.ErrNotFound) {
+			defaults = &storage.DomainDefaults{Domain: name}
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, defaults)
+	}
+}
+
+// updateDomainDefaultsHandler 更新域名的默认账户设置（整体替换，省略的字段恢复为系统默认值）
+func updateDomainDefaultsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		var defaults storage.DomainDefaults
+		if err := c.ShouldBindJSON(&defaults); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		if _, err := driver.GetDomain(ctx, name); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		defaults.Domain = name
+		if err := driver.SaveDomainDefaults(ctx, &defaults); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, defaults)
+	}
+}
+
+// getUserSettingsHandler 获取用户对域名默认设置的覆盖（未覆盖时返回空设置）
+func getUserSettingsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		ctx := c.Request.Context()
+		if _, err := driver.GetUser(ctx, email); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		settings, err := driver.GetUserSettings(ctx, email)
+		if errors.Is(err, storage.ErrNotFound) {
+			settings = &storage.UserSettings{UserEmail: email}
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+// updateUserSettingsHandler 更新用户对域名默认设置的覆盖（整体替换，省略的字段继承域名默认设置）
+func updateUserSettingsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		var settings storage.UserSettings
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		if _, err := driver.GetUser(ctx, email); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		settings.UserEmail = email
+		if err := driver.SaveUserSettings(ctx, &settings); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, settings)
+	}
+}
+
+// getEffectiveSettingsHandler 获取账户的生效设置及每项设置的来源
+func getEffectiveSettingsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		effective, err := storage.LoadEffectiveSettings(c.Request.Context(), driver, c.Param("email"))
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, effective)
+	}
+}
+
 // getQuotaHandler 获取配额
 func getQuotaHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -426,7 +687,7 @@ func updateQuotaHandler(driver storage.Driver) gin.HandlerFunc {
 
 		ctx := c.Request.Context()
 		if err := driver.UpdateQuota(ctx, email, quota); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": err.Error(),
 			})
 			return
@@ -436,6 +697,107 @@ func updateQuotaHandler(driver storage.Driver) gin.HandlerFunc {
 	}
 }
 
+// maxImportSize CSV 导入文件的最大大小
+const maxImportSize = 10 << 20
+
+// importUsersHandler 从 CSV 批量导入用户，返回逐行的校验和创建结果
+// 请求体可以是 CSV 原文（text/csv）或 multipart 表单中的 file 字段；?dry_run=true 时只校验
+func importUsersHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+		var body io.Reader = c.Request.Body
+		if strings.HasPrefix(c.ContentType(), "multipart/") {
+			file, err := c.FormFile("file")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "缺少 CSV 文件（file 字段）",
+				})
+				return
+			}
+			f, err := file.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			defer f.Close()
+			body = f
+		}
+
+		dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+		result, err := provision.ImportUsersCSV(c.Request.Context(), driver, body, dryRun)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": "CSV 文件过大",
+				})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run": dryRun,
+			"created": result.Created,
+			"skipped": result.Skipped,
+			"rows":    result.Rows,
+		})
+	}
+}
+
+// exportUsersHandler 以 CSV 导出所有用户及其配额和用量
+func exportUsersHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var buf bytes.Buffer
+		if err := provision.ExportUsersCSV(c.Request.Context(), driver, &buf); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		filename := fmt.Sprintf("users-%s.csv", time.Now().Format("20060102"))
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	}
+}
+
+// testMailHandler 发送测试邮件并返回各阶段的投递结果
+func testMailHandler(sender *testmail.Sender) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sender == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "测试邮件未配置",
+			})
+			return
+		}
+
+		var req struct {
+			To   string `json:"to" binding:"required"`
+			From string `json:"from"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		report := sender.Send(c.Request.Context(), req.From, req.To)
+		status := http.StatusOK
+		if !report.Success {
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, report)
+	}
+}
+
 // checkInitHandler 检查系统是否需要初始化
 func checkInitHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -525,7 +887,7 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		}
 
 		if err := driver.CreateUser(ctx, adminUser); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			c.JSON(storageStatus(err), gin.H{
 				"error": fmt.Sprintf("创建用户失败: %v", err),
 			})
 			return
@@ -557,7 +919,7 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		}
 
 		// 生成 JWT token（自动登录）
-		token, err := jwtManager.GenerateToken(adminUser.Email, adminUser.ID, false, 24*time.Hour)
+		token, err := jwtManager.IssueToken(ctx, adminUser, false, 24*time.Hour, sessionInfo(c))
 		if err != nil {
 			// Token 生成失败不影响初始化，但需要用户手动登录
 			token = ""
@@ -575,3 +937,19 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		})
 	}
 }
+
+// storageStatus 把存储错误映射为 HTTP 状态码
+func storageStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusInternalServerError
+	}
+}
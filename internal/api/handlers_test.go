@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/storage"
 )
 
@@ -120,6 +123,14 @@ func TestCreateUserHandler(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "用户已存在",
+			body: map[string]interface{}{
+				"email":    "exists@example.com",
+				"password": "password123",
+			},
+			wantStatus: http.StatusConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -141,10 +152,42 @@ func TestCreateUserHandler(t *testing.T) {
 	}
 }
 
+func TestLoginHandlerBan(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	limiter, err := limits.New(limits.Config{MaxAuthFailures: 2, Window: time.Minute, BanDuration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟驱动返回的用户没有密码，任何密码都认证失败
+	handler := loginHandler(&MockStorageDriver{}, nil, nil, nil, limiter, nil, nil)
+
+	for i, wantStatus := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		bodyBytes, _ := json.Marshal(map[string]string{"email": "admin@example.com", "password": "wrong"})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(bodyBytes))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		handler(c)
+
+		if w.Code != wantStatus {
+			t.Errorf("第 %d 次登录 status = %d, want %d", i+1, w.Code, wantStatus)
+		}
+	}
+}
+
 // MockStorageDriver 模拟存储驱动
-type MockStorageDriver struct{}
+type MockStorageDriver struct {
+	// 嵌入接口：未显式模拟的方法在测试中不会被调用
+	storage.Driver
+}
 
 func (m *MockStorageDriver) CreateUser(ctx context.Context, user *storage.User) error {
+	if user.Email == "exists@example.com" {
+		return fmt.Errorf("创建用户失败: %w", storage.ErrAlreadyExists)
+	}
 	return nil
 }
 
@@ -230,7 +273,7 @@ func (m *MockStorageDriver) UpdateMailFlags(ctx context.Context, id string, flag
 	return nil
 }
 
-func (m *MockStorageDriver) SearchMails(ctx context.Context, userEmail string, query string, folder string, limit, offset int) ([]*storage.Mail, error) {
+func (m *MockStorageDriver) SearchMails(ctx context.Context, userEmail string, query *storage.SearchQuery, folder string, limit, offset int) ([]*storage.Mail, error) {
 	return []*storage.Mail{}, nil
 }
 
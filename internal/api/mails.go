@@ -0,0 +1,282 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/search"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 邮箱检查接口的分页限制
+const (
+	defaultMailPageSize = 50
+	maxMailPageSize     = 500
+)
+
+// adminRequiredMiddleware 只允许管理员访问（API Key 认证视为管理员）
+func adminRequiredMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("user_email"); exists && !c.GetBool("is_admin") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "需要管理员权限",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// userMail 获取属于 :email 的邮件 :id，失败时写入响应并返回 nil
+func userMail(c *gin.Context, driver storage.Driver) *storage.Mail {
+	mail, err := driver.GetMail(c.Request.Context(), c.Param("id"))
+	if err == nil && !strings.EqualFold(mail.UserEmail, c.Param("email")) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		status := storageStatus(err)
+		if status == http.StatusNotFound {
+			c.JSON(status, gin.H{
+				"error": "邮件不存在",
+			})
+		} else {
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return nil
+	}
+	return mail
+}
+
+// listUserMailsHandler 列出或搜索（q）用户某个文件夹中的邮件（元数据），同时返回用户的文件夹列表
+func listUserMailsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		folder := c.DefaultQuery("folder", "INBOX")
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultMailPageSize)))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit <= 0 || limit > maxMailPageSize {
+			limit = defaultMailPageSize
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		ctx := c.Request.Context()
+		if _, err := driver.GetUser(ctx, email); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		// q 为搜索语法（与 WebMail 相同），指定 q 且没有指定 folder 时搜索全部文件夹
+		var mails []*storage.Mail
+		var err error
+		if q := c.Query("q"); q != "" {
+			query, parseErr := search.Parse(q)
+			if parseErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": parseErr.Error(),
+				})
+				return
+			}
+			folder = c.Query("folder")
+			mails, err = driver.SearchMails(ctx, email, query, folder, limit, offset)
+		} else {
+			mails, err = driver.ListMails(ctx, email, folder, limit, offset)
+		}
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		folders, err := driver.ListFolders(ctx, email)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"folder":  folder,
+			"folders": folders,
+			"mails":   mails,
+			"limit":   limit,
+			"offset":  offset,
+		})
+	}
+}
+
+// getUserMailRawHandler 返回邮件原文（message/rfc822）
+func getUserMailRawHandler(driver storage.Driver, maildir *storage.Maildir) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maildir == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Maildir 未配置",
+			})
+			return
+		}
+		mail := userMail(c, driver)
+		if mail == nil {
+			return
+		}
+
+		raw := readMailFile(c, maildir, mail)
+		if raw == nil {
+			return
+		}
+
+		c.Header("X-Mail-Folder", mail.Folder)
+		c.Header("X-Mail-UID", strconv.FormatUint(uint64(mail.UID), 10))
+		c.Data(http.StatusOK, "message/rfc822", raw)
+	}
+}
+
+// deleteUserMailHandler 删除邮件（数据库记录和 Maildir 文件）
+func deleteUserMailHandler(driver storage.Driver, maildir *storage.Maildir) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mail := userMail(c, driver)
+		if mail == nil {
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := driver.DeleteMail(ctx, mail.ID); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		removeMailFile(c, maildir, mail)
+
+		logger.InfoCtx(ctx).Str("user", mail.UserEmail).Str("mail_id", mail.ID).Str("folder", mail.Folder).Msg("管理员删除了邮件")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "邮件已删除",
+		})
+	}
+}
+
+// redeliverUserMailHandler 重新投递邮件：把邮件原文作为新邮件投递到目标文件夹（默认 INBOX），再删除原邮件
+// 用于处理客户端没有同步到的邮件或被归错文件夹的邮件；新邮件分配新的 UID 并带 \Recent 标志
+func redeliverUserMailHandler(driver storage.Driver, maildir *storage.Maildir) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Folder string `json:"folder"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+		if req.Folder == "" {
+			req.Folder = "INBOX"
+		}
+		if maildir == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Maildir 未配置",
+			})
+			return
+		}
+		old := userMail(c, driver)
+		if old == nil {
+			return
+		}
+
+		ctx := c.Request.Context()
+		raw := readMailFile(c, maildir, old)
+		if raw == nil {
+			return
+		}
+
+		// 保留关键字（分类、订阅邮件等），系统标志重置为新邮件
+		flags := []string{"\\Recent"}
+		for _, flag := range old.Flags {
+			if !strings.HasPrefix(flag, "\\") {
+				flags = append(flags, flag)
+			}
+		}
+		mail := &storage.Mail{
+			UserEmail:   old.UserEmail,
+			Folder:      req.Folder,
+			From:        old.From,
+			To:          old.To,
+			Cc:          old.Cc,
+			Bcc:         old.Bcc,
+			Subject:     old.Subject,
+			Size:        int64(len(raw)),
+			Flags:       flags,
+			ReceivedAt:  time.Now(),
+			CreatedAt:   time.Now(),
+			Attachments: search.ExtractAttachments(raw),
+			ThreadID:    old.ThreadID,
+		}
+		if unsubscribe, err := driver.GetUnsubscribe(ctx, old.ID); err == nil {
+			mail.Unsubscribe = unsubscribe
+		}
+
+		// 先投递新邮件再删除原邮件，任一步失败都不会丢信
+		if err := storage.NewMailStore(maildir, driver).Deliver(ctx, mail, raw); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err := driver.DeleteMail(ctx, old.ID); err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("user", old.UserEmail).Str("mail_id", old.ID).Msg("重新投递后删除原邮件失败")
+		} else {
+			removeMailFile(c, maildir, old)
+		}
+
+		logger.InfoCtx(ctx).
+			Str("user", old.UserEmail).
+			Str("mail_id", old.ID).
+			Str("new_mail_id", mail.ID).
+			Str("folder", mail.Folder).
+			Msg("管理员重新投递了邮件")
+		c.JSON(http.StatusOK, mail)
+	}
+}
+
+// readMailFile 读取邮件原文，失败时写出错误响应并返回 nil。
+// 零访问存储加密的邮件返回 423（管理员只能看到密文，用户解锁期间也不解密）
+func readMailFile(c *gin.Context, maildir *storage.Maildir, mail *storage.Mail) []byte {
+	raw, err := maildir.ReadMail(mail.UserEmail, mail.Folder, mail.ID)
+	if errors.Is(err, storage.ErrMailboxLocked) {
+		c.JSON(http.StatusLocked, gin.H{
+			"error": "邮件已加密（零访问存储），管理员无法读取",
+		})
+		return nil
+	}
+	if err != nil {
+		logger.WarnCtx(c.Request.Context()).Err(err).Str("user", mail.UserEmail).Str("mail_id", mail.ID).Msg("读取邮件文件失败")
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "邮件文件不存在",
+		})
+		return nil
+	}
+	return raw
+}
+
+// removeMailFile 删除邮件的 Maildir 文件（文件已不存在时忽略）
+func removeMailFile(c *gin.Context, maildir *storage.Maildir, mail *storage.Mail) {
+	if maildir == nil {
+		return
+	}
+	filename, _, _ := strings.Cut(mail.ID, ":")
+	if err := maildir.DeleteMail(mail.UserEmail, mail.Folder, filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.WarnCtx(c.Request.Context()).Err(err).Str("user", mail.UserEmail).Str("mail_id", mail.ID).Msg("删除邮件文件失败")
+	}
+}
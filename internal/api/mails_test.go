@@ -0,0 +1,219 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/zeroaccess"
+)
+
+// newMailTestRouter 创建带一封 Archive 邮件的路由（不经过认证中间件）
+func newMailTestRouter(t *testing.T) (*gin.Engine, *storage.SQLiteDriver, *storage.Maildir, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := []byte("From: carol@example.org\r\nTo: alice@example.com\r\nSubject: hello\r\n\r\nbody\r\n")
+	mail := &storage.Mail{
+		UserEmail:  "alice@example.com",
+		Folder:     "Archive",
+		From:       "carol@example.org",
+		To:         []string{"alice@example.com"},
+		Subject:    "hello",
+		Flags:      []string{"\\Seen", "$Important"},
+		ReceivedAt: time.Now(),
+		CreatedAt:  time.Now(),
+	}
+	if err := storage.NewMailStore(maildir, driver).Deliver(ctx, mail, raw); err != nil {
+		t.Fatalf("投递邮件失败: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/users/:email/mails", listUserMailsHandler(driver))
+	router.GET("/users/:email/mails/:id", getUserMailRawHandler(driver, maildir))
+	router.DELETE("/users/:email/mails/:id", deleteUserMailHandler(driver, maildir))
+	router.POST("/users/:email/mails/:id/redeliver", redeliverUserMailHandler(driver, maildir))
+	return router, driver, maildir, mail.ID
+}
+
+func serve(router *gin.Engine, method, path string, body []byte) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserMailHandlers(t *testing.T) {
+	router, driver, maildir, id := newMailTestRouter(t)
+
+	w := serve(router, http.MethodGet, "/users/alice@example.com/mails?folder=Archive", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("列出邮件 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var list struct {
+		Mails []*storage.Mail `json:"mails"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Mails) != 1 || list.Mails[0].ID != id {
+		t.Fatalf("列出的邮件 = %+v", list.Mails)
+	}
+	if w := serve(router, http.MethodGet, "/users/nobody@example.com/mails", nil); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的用户 status = %d", w.Code)
+	}
+
+	// 搜索（没有指定文件夹时搜索全部文件夹）
+	for q, want := range map[string]int{"from:carol": 1, "subject:nothing": 0} {
+		w := serve(router, http.MethodGet, "/users/alice@example.com/mails?q="+url.QueryEscape(q), nil)
+		list.Mails = nil
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK || len(list.Mails) != want {
+			t.Errorf("搜索 %q status = %d, body = %s", q, w.Code, w.Body.String())
+		}
+	}
+	if w := serve(router, http.MethodGet, "/users/alice@example.com/mails?q=before:bad", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("无效的搜索 status = %d", w.Code)
+	}
+
+	w = serve(router, http.MethodGet, "/users/alice@example.com/mails/"+id, nil)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte("Subject: hello")) {
+		t.Fatalf("读取原文 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "message/rfc822" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	// 其他用户的邮件按不存在处理
+	if w := serve(router, http.MethodGet, "/users/bob@example.com/mails/"+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("读取其他用户的邮件 status = %d", w.Code)
+	}
+
+	// 重新投递到 INBOX：保留关键字，系统标志重置
+	w = serve(router, http.MethodPost, "/users/alice@example.com/mails/"+id+"/redeliver", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("重新投递 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var redelivered storage.Mail
+	if err := json.Unmarshal(w.Body.Bytes(), &redelivered); err != nil {
+		t.Fatal(err)
+	}
+	if redelivered.ID == id || redelivered.Folder != "INBOX" {
+		t.Errorf("重新投递的邮件 = %+v", redelivered)
+	}
+	ctx := context.Background()
+	if _, err := driver.GetMail(ctx, id); err == nil {
+		t.Error("重新投递后原邮件应该被删除")
+	}
+	mail, err := driver.GetMail(ctx, redelivered.ID)
+	if err != nil {
+		t.Fatalf("获取重新投递的邮件失败: %v", err)
+	}
+	if !hasFlag(mail.Flags, "$Important") || hasFlag(mail.Flags, "\\Seen") {
+		t.Errorf("重新投递后的标志 = %v", mail.Flags)
+	}
+	if _, err := maildir.ReadMail("alice@example.com", "Archive", id); err == nil {
+		t.Error("重新投递后原邮件文件应该被删除")
+	}
+
+	w = serve(router, http.MethodDelete, "/users/alice@example.com/mails/"+redelivered.ID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("删除邮件 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if _, err := maildir.ReadMail("alice@example.com", "INBOX", redelivered.ID); err == nil {
+		t.Error("删除后邮件文件应该被删除")
+	}
+	if w := serve(router, http.MethodDelete, "/users/alice@example.com/mails/"+redelivered.ID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("重复删除 status = %d", w.Code)
+	}
+}
+
+func TestUserMailZeroAccess(t *testing.T) {
+	router, driver, maildir, id := newMailTestRouter(t)
+	keyring := zeroaccess.New(driver, 0)
+	maildir.SetCipher(keyring)
+	if _, err := keyring.Enable(context.Background(), "alice@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := maildir.SealExisting("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !keyring.Unlocked("alice@example.com") {
+		t.Fatal("启用后私钥应该处于解锁状态")
+	}
+
+	// 用户解锁期间管理员也不能读取明文
+	w := serve(router, http.MethodGet, "/users/alice@example.com/mails/"+id, nil)
+	if w.Code != http.StatusLocked || bytes.Contains(w.Body.Bytes(), []byte("Subject: hello")) {
+		t.Errorf("读取加密的邮件 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	w = serve(router, http.MethodPost, "/users/alice@example.com/mails/"+id+"/redeliver", nil)
+	if w.Code != http.StatusLocked {
+		t.Errorf("重新投递加密的邮件 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if _, err := driver.GetMail(context.Background(), id); err != nil {
+		t.Errorf("重新投递失败时原邮件应该保留: %v", err)
+	}
+}
+
+func TestAdminRequiredMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		set        func(c *gin.Context)
+		wantStatus int
+	}{
+		{"API Key", func(c *gin.Context) {}, http.StatusOK},
+		{"管理员", func(c *gin.Context) { c.Set("user_email", "admin@example.com"); c.Set("is_admin", true) }, http.StatusOK},
+		{"普通用户", func(c *gin.Context) { c.Set("user_email", "alice@example.com"); c.Set("is_admin", false) }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) { tt.set(c) }, adminRequiredMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+			if w := serve(router, http.MethodGet, "/", nil); w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 推迟发送队列的查询限制
+const (
+	defaultQueuePageSize = 100
+	maxQueueSize         = 10000
+)
+
+// queueEnd 列出队列时使用的时间上限（包括尚未到期的邮件）
+var queueEnd = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// listQueueHandler 列出推迟发送队列（外发预热超出配额的邮件）和各目标服务商的邮件数
+func listQueueHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultQueuePageSize)))
+		if limit <= 0 || limit > maxQueueSize {
+			limit = defaultQueuePageSize
+		}
+
+		ctx := c.Request.Context()
+		mails, err := driver.ListDueDeferredMails(ctx, queueEnd, limit)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		counts, err := driver.CountDeferredMails(ctx)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		total := 0
+		for _, n := range counts {
+			total += n
+		}
+		if mails == nil {
+			mails = []*storage.DeferredMail{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mails":     mails,
+			"providers": counts,
+			"total":     total,
+		})
+	}
+}
+
+// flushQueueHandler 立即重试推迟发送的邮件（provider 为空时重试全部）：把最早发送时间改为现在，
+// 由预热调度器在下一轮发送，仍然受当天配额限制
+func flushQueueHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider := c.Query("provider")
+		ctx := c.Request.Context()
+		now := time.Now()
+		mails, err := driver.ListDueDeferredMails(ctx, queueEnd, maxQueueSize)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		flushed := 0
+		for _, m := range mails {
+			if (provider != "" && m.Provider != provider) || !m.NotBefore.After(now) {
+				continue
+			}
+			if err := driver.RescheduleDeferredMail(ctx, m.ID, now); err != nil {
+				c.JSON(storageStatus(err), gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			flushed++
+		}
+		logger.InfoCtx(ctx).Str("provider", provider).Int("flushed", flushed).Msg("立即重试推迟发送的邮件")
+
+		c.JSON(http.StatusOK, gin.H{
+			"flushed": flushed,
+		})
+	}
+}
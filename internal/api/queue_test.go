@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestQueueHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	tomorrow := time.Now().Add(24 * time.Hour)
+	for _, provider := range []string{"gmail", "gmail", "outlook"} {
+		if err := driver.DeferMail(ctx, &storage.DeferredMail{
+			Sender: "alice@example.com", Recipients: []string{"bob@" + provider + ".com"}, Provider: provider,
+			Data: []byte("hello"), NotBefore: tomorrow,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/queue", listQueueHandler(driver))
+	router.POST("/queue/flush", flushQueueHandler(driver))
+
+	w := serve(router, http.MethodGet, "/queue", nil)
+	var queue struct {
+		Mails     []*storage.DeferredMail `json:"mails"`
+		Providers map[string]int          `json:"providers"`
+		Total     int                     `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &queue); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || len(queue.Mails) != 3 || queue.Total != 3 || queue.Providers["gmail"] != 2 {
+		t.Fatalf("队列 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, http.MethodPost, "/queue/flush?provider=gmail", nil)
+	if w.Code != http.StatusOK || w.Body.String() != `{"flushed":2}` {
+		t.Fatalf("重试 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	due, err := driver.ListDueDeferredMails(ctx, time.Now().Add(time.Second), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(due) != 2 || due[0].Provider != "gmail" || due[1].Provider != "gmail" {
+		t.Errorf("重试后到期的邮件 = %+v", due)
+	}
+}
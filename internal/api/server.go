@@ -2,6 +2,9 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -9,10 +12,17 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/antispam"
 	"github.com/gomailzero/gmz/internal/auth"
 	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/dnscheck"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
 	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/terms"
+	"github.com/gomailzero/gmz/internal/testmail"
+	"github.com/gomailzero/gmz/internal/warmup"
 )
 
 // Server API 服务器
@@ -36,6 +46,26 @@ type Config struct {
 	Storage     storage.Driver
 	JWTManager  *auth.JWTManager
 	TOTPManager *auth.TOTPManager
+	TLS         *tls.Config      // HTTPS 配置（为空时使用 HTTP）
+	TestMail    *testmail.Sender // 测试邮件发送器（为空时测试邮件接口不可用）
+	Limiter     *limits.Limiter  // 登录失败次数过多时封禁 IP（为空时不限制）
+	Maildir     *storage.Maildir // 邮件文件存储（为空时无法读取邮件原文和重新投递）
+	// Connections 协议服务器（SMTP、IMAP），用于查看和断开在线连接（为空时连接列表为空）
+	Connections []ConnectionManager
+	// Warmup 外发预热（为空时进度接口返回未启用）
+	Warmup *warmup.Scheduler
+	// Activity 账户活动记录（为空时不记录管理后台登录）
+	Activity *activity.Recorder
+	// WorkDir 工作目录，生成的域名 DKIM 私钥保存在其中的 dkim 目录下
+	WorkDir string
+	// DKIM 外发签名器（为空时生成的域名 DKIM 密钥只保存，不用于签名）
+	DKIM *antispam.DKIMSigners
+	// Terms 登录提示和使用条款（为空时不要求接受条款）
+	Terms *terms.Gate
+	// DNSCheck 域名 DNS 设置检查（为空时使用系统 DNS 和系统主机名）
+	DNSCheck *dnscheck.Checker
+	// WebAuthn 通行密钥（为空时登录不能使用通行密钥，通行密钥在 WebMail 中注册）
+	WebAuthn *auth.WebAuthnManager
 }
 
 // NewServer 创建 API 服务器
@@ -62,10 +92,12 @@ func NewServer(cfg *Config) *Server {
 	// 健康检查
 	router.GET("/health", healthHandler)
 
-	// 公开端点：初始化和登录
+	// 公开端点：初始化、登录提示和登录
 	router.GET("/api/v1/init/check", checkInitHandler(cfg.Storage))
 	router.POST("/api/v1/init", initSystemHandler(cfg.Storage, cfg.JWTManager, cfg.Domain))
-	router.POST("/api/v1/auth/login", loginHandler(cfg.Storage, cfg.JWTManager, cfg.TOTPManager))
+	router.GET("/api/v1/banner", bannerHandler(cfg.Terms))
+	router.POST("/api/v1/auth/login", loginHandler(cfg.Storage, cfg.JWTManager, cfg.TOTPManager, cfg.WebAuthn, cfg.Limiter, cfg.Activity, cfg.Terms))
+	router.POST("/api/v1/auth/login/webauthn", beginWebAuthnLoginHandler(cfg.Storage, cfg.WebAuthn, cfg.Limiter))
 
 	// API 路由组
 	api := router.Group("/api/v1")
@@ -78,6 +110,15 @@ func NewServer(cfg *Config) *Server {
 	api.GET("/domains/:name", getDomainHandler(cfg.Storage))
 	api.PUT("/domains/:name", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), updateDomainHandler(cfg.Storage))
 	api.DELETE("/domains/:name", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), deleteDomainHandler(cfg.Storage))
+	api.GET("/domains/:name/alias-policy", getAliasPolicyHandler(cfg.Storage))
+	api.PUT("/domains/:name/alias-policy", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), updateAliasPolicyHandler(cfg.Storage))
+	api.GET("/domains/:name/defaults", getDomainDefaultsHandler(cfg.Storage))
+	api.PUT("/domains/:name/defaults", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), updateDomainDefaultsHandler(cfg.Storage))
+	// 域名 DKIM 密钥（仅管理员，生成需要 TOTP）
+	api.GET("/domains/:name/dkim", adminRequiredMiddleware(), getDKIMHandler(cfg.Storage, cfg.WorkDir, cfg.DKIM))
+	api.POST("/domains/:name/dkim", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), generateDKIMHandler(cfg.Storage, cfg.WorkDir, cfg.DKIM))
+	// 域名 DNS 设置检查（部署检查清单）
+	api.GET("/domains/:name/dns-check", dnsCheckHandler(cfg.Storage, cfg.DNSCheck, cfg.DKIM))
 
 	// 用户管理
 	api.GET("/users", listUsersHandler(cfg.Storage))
@@ -87,16 +128,67 @@ func NewServer(cfg *Config) *Server {
 	// 更新和删除用户需要 TOTP（如果启用）
 	api.PUT("/users/:email", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), updateUserHandler(cfg.Storage))
 	api.DELETE("/users/:email", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), deleteUserHandler(cfg.Storage))
+	// CSV 批量导入（需要 TOTP）和导出
+	api.POST("/users/import", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), importUsersHandler(cfg.Storage))
+	api.GET("/users/export", exportUsersHandler(cfg.Storage))
 
 	// 别名管理
 	api.GET("/aliases", listAliasesHandler(cfg.Storage))
 	api.POST("/aliases", createAliasHandler(cfg.Storage))
+	api.PUT("/aliases/:from", updateAliasDestinationsHandler(cfg.Storage))
 	api.DELETE("/aliases/:from", deleteAliasHandler(cfg.Storage))
 
 	// 配额管理
 	api.GET("/users/:email/quota", getQuotaHandler(cfg.Storage))
 	api.PUT("/users/:email/quota", updateQuotaHandler(cfg.Storage))
 
+	// 账户设置（用户覆盖和生效设置）
+	api.GET("/users/:email/settings", getUserSettingsHandler(cfg.Storage))
+	api.PUT("/users/:email/settings", totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), updateUserSettingsHandler(cfg.Storage))
+	api.GET("/users/:email/effective-settings", getEffectiveSettingsHandler(cfg.Storage))
+
+	// 使用条款接受记录（仅管理员）
+	api.GET("/users/:email/terms", adminRequiredMiddleware(), listTermsAcceptancesHandler(cfg.Storage, cfg.Terms))
+
+	// 邮箱检查和邮件管理（仅管理员，需要 TOTP）
+	api.GET("/users/:email/mails", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), listUserMailsHandler(cfg.Storage))
+	api.GET("/users/:email/mails/:id", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), getUserMailRawHandler(cfg.Storage, cfg.Maildir))
+	api.DELETE("/users/:email/mails/:id", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), deleteUserMailHandler(cfg.Storage, cfg.Maildir))
+	api.POST("/users/:email/mails/:id/redeliver", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), redeliverUserMailHandler(cfg.Storage, cfg.Maildir))
+
+	// 登录会话和个人访问令牌（仅管理员，吊销需要 TOTP）
+	api.GET("/users/:email/sessions", adminRequiredMiddleware(), listUserSessionsHandler(cfg.Storage))
+	api.DELETE("/users/:email/sessions/:id", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), revokeUserSessionHandler(cfg.Storage))
+	api.DELETE("/users/:email/tokens/:id", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), revokeUserTokenHandler(cfg.Storage))
+	api.POST("/users/:email/sessions/revoke-all", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), revokeAllUserSessionsHandler(cfg.Storage))
+
+	// 在线连接（仅管理员，断开连接需要 TOTP）
+	api.GET("/connections", adminRequiredMiddleware(), listConnectionsHandler(cfg.Connections))
+	api.DELETE("/connections/:id", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), disconnectHandler(cfg.Connections))
+
+	// 退信统计和外发预热进度（仅管理员）
+	api.GET("/stats/bounces", adminRequiredMiddleware(), bounceStatsHandler(cfg.Storage))
+	api.GET("/stats/warmup", adminRequiredMiddleware(), warmupProgressHandler(cfg.Warmup))
+
+	// 推迟发送队列（外发预热超出配额的邮件）
+	api.GET("/queue", adminRequiredMiddleware(), listQueueHandler(cfg.Storage))
+	api.POST("/queue/flush", adminRequiredMiddleware(), flushQueueHandler(cfg.Storage))
+
+	// 投递日志查询和邮件追踪（仅管理员）
+	api.GET("/logs/messages", adminRequiredMiddleware(), searchDeliveryLogHandler(cfg.Storage))
+	api.GET("/logs/messages/:id", adminRequiredMiddleware(), deliveryTraceHandler(cfg.Storage))
+
+	// 外发会话记录（仅管理员，收件方否认收到邮件时查询对方服务器当时的回复）
+	api.GET("/logs/transcripts", adminRequiredMiddleware(), searchTranscriptsHandler(cfg.Storage))
+	api.GET("/logs/transcripts/:id", adminRequiredMiddleware(), getTranscriptHandler(cfg.Storage))
+
+	// 垃圾邮件内容指纹（仅管理员，删除需要 TOTP）
+	api.GET("/antispam/fingerprints", adminRequiredMiddleware(), listFingerprintsHandler(cfg.Storage))
+	api.DELETE("/antispam/fingerprints/:id", adminRequiredMiddleware(), totpRequiredMiddleware(cfg.TOTPManager, cfg.Storage), deleteFingerprintHandler(cfg.Storage))
+
+	// 测试邮件（验证新部署的完整投递流程）
+	api.POST("/test-mail", testMailHandler(cfg.TestMail))
+
 	// 管理界面路由（SPA）
 	router.GET("/admin", func(c *gin.Context) {
 		data, err := staticFiles.ReadFile("static/index.html")
@@ -147,9 +239,16 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:       60 * time.Second,
 	}
 
-	logger.Info().Int("port", s.config.Port).Msg("管理 API 服务器启动")
+	logger.Info().Int("port", s.config.Port).Bool("tls", s.config.TLS != nil).Msg("管理 API 服务器启动")
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if s.config.TLS != nil {
+		s.server.TLSConfig = s.config.TLS
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("API 服务器错误: %w", err)
 	}
 
@@ -217,12 +316,13 @@ func authMiddleware(apiKey string, jwtManager *auth.JWTManager) gin.HandlerFunc
 			if authHeader != "" {
 				parts := strings.Split(authHeader, " ")
 				if len(parts) == 2 && parts[0] == "Bearer" {
-					claims, err := jwtManager.ValidateToken(parts[1])
+					claims, err := jwtManager.Validate(c.Request.Context(), parts[1])
 					if err == nil {
 						// JWT 认证成功，将用户信息存储到上下文
 						c.Set("user_email", claims.Email)
 						c.Set("user_id", claims.UserID)
 						c.Set("is_admin", claims.IsAdmin)
+						c.Set("session_id", claims.ID)
 						c.Next()
 						return
 					}
@@ -306,12 +406,16 @@ func totpRequiredMiddleware(totpManager *auth.TOTPManager, storage storage.Drive
 }
 
 // loginHandler 登录处理器
-func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManager *auth.TOTPManager) gin.HandlerFunc {
+func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManager *auth.TOTPManager, webAuthn *auth.WebAuthnManager, limiter *limits.Limiter, recorder *activity.Recorder, gate *terms.Gate) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			Email    string `json:"email" binding:"required"`
-			Password string `json:"password" binding:"required"`
-			TOTPCode string `json:"totp_code"`
+			Email       string `json:"email" binding:"required"`
+			Password    string `json:"password" binding:"required"`
+			TOTPCode    string `json:"totp_code"`
+			AcceptTerms string `json:"accept_terms"` // 用户接受的使用条款版本（见 GET /api/v1/banner）
+			// 通行密钥登录：POST /api/v1/auth/login/webauthn 返回的流程 ID 和浏览器返回的断言（代替 TOTP 代码）
+			WebAuthnCeremony string          `json:"webauthn_ceremony"`
+			WebAuthnResponse json.RawMessage `json:"webauthn_response"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -321,9 +425,19 @@ func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManage
 			return
 		}
 
+		// 认证失败次数过多的 IP 被临时封禁
+		ip := c.ClientIP()
+		if err := limiter.Allow("admin", ip); err != nil {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "登录失败次数过多，请稍后再试",
+			})
+			return
+		}
+
 		ctx := c.Request.Context()
 		user, err := driver.GetUser(ctx, req.Email)
 		if err != nil {
+			limiter.Failure("admin", ip)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "认证失败",
 			})
@@ -333,35 +447,64 @@ func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManage
 		// 验证密码
 		valid, err := crypto.VerifyPassword(req.Password, user.PasswordHash)
 		if err != nil || !valid {
+			limiter.Failure("admin", ip)
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "认证失败",
 			})
 			return
 		}
 
-		// 检查是否启用了 TOTP
+		// 验证第二因素（启用了 TOTP 或注册了通行密钥时需要其中之一）
+		totpEnabled := false
 		if totpManager != nil {
-			totpEnabled, err := totpManager.IsEnabled(ctx, req.Email)
-			if err == nil && totpEnabled {
-				// 如果启用了 TOTP，必须提供 TOTP 代码
-				if req.TOTPCode == "" {
-					c.JSON(http.StatusUnauthorized, gin.H{
-						"error":        "需要 TOTP 代码",
-						"requires_2fa": true,
-					})
-					return
-				}
-
-				// 验证 TOTP 代码
-				valid, err := totpManager.Verify(ctx, req.Email, req.TOTPCode)
-				if err != nil || !valid {
-					c.JSON(http.StatusUnauthorized, gin.H{
-						"error": "TOTP 代码错误",
-					})
-					return
-				}
+			enabled, err := totpManager.IsEnabled(ctx, req.Email)
+			totpEnabled = err == nil && enabled
+		}
+		hasPasskeys := false
+		if webAuthn != nil {
+			hasPasskeys, err = webAuthn.HasCredentials(ctx, user.Email)
+			if err != nil {
+				c.JSON(storageStatus(err), gin.H{
+					"error": err.Error(),
+				})
+				return
 			}
 		}
+		switch {
+		case hasPasskeys && req.WebAuthnCeremony != "":
+			// 验证通行密钥的断言
+			if err := webAuthn.FinishLogin(ctx, user.Email, req.WebAuthnCeremony, req.WebAuthnResponse); err != nil {
+				logger.WarnCtx(ctx).Err(err).Str("user", user.Email).Msg("通行密钥登录失败")
+				limiter.Failure("admin", ip)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "通行密钥验证失败",
+				})
+				return
+			}
+		case totpEnabled && req.TOTPCode != "":
+			// 验证 TOTP 代码
+			valid, err := totpManager.Verify(ctx, req.Email, req.TOTPCode)
+			if err != nil || !valid {
+				limiter.Failure("admin", ip)
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": "TOTP 代码错误",
+				})
+				return
+			}
+		case totpEnabled || hasPasskeys:
+			// 需要第二因素，返回可用的方式
+			msg := "需要 TOTP 代码"
+			if !totpEnabled {
+				msg = "需要使用通行密钥验证"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":        msg,
+				"requires_2fa": true,
+				"totp":         totpEnabled,
+				"webauthn":     hasPasskeys,
+			})
+			return
+		}
 
 		// 检查用户是否是管理员（只有管理员才能登录管理后台）
 		if !user.IsAdmin {
@@ -371,6 +514,22 @@ func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManage
 			return
 		}
 
+		// 配置了使用条款时，需要接受当前版本才签发令牌
+		if err := gate.Check(ctx, user.Email, req.AcceptTerms, ip); err != nil {
+			if errors.Is(err, terms.ErrAcceptanceRequired) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":          "需要接受使用条款才能登录",
+					"requires_terms": true,
+					"terms_version":  gate.Banner().TermsVersion,
+				})
+				return
+			}
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
 		// 生成 JWT token
 		if jwtManager == nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -379,7 +538,7 @@ func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManage
 			return
 		}
 
-		token, err := jwtManager.GenerateToken(user.Email, user.ID, user.IsAdmin, 24*time.Hour)
+		token, err := jwtManager.IssueToken(ctx, user, user.IsAdmin, 24*time.Hour, sessionInfo(c))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "生成令牌失败",
@@ -387,6 +546,8 @@ func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManage
 			return
 		}
 
+		limiter.Success(ip)
+		recorder.Login(ctx, user.Email, activity.ProtocolAdmin, ip, c.Request.UserAgent())
 		c.JSON(http.StatusOK, gin.H{
 			"token": token,
 			"user": gin.H{
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// sessionInfo 登录会话的来源信息（管理后台登录）
+func sessionInfo(c *gin.Context) auth.SessionInfo {
+	return auth.SessionInfo{
+		Source:    "admin",
+		RemoteIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// listUserSessionsHandler 列出用户的登录会话和个人访问令牌（包括最近活动时间）
+func listUserSessionsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		ctx := c.Request.Context()
+		if _, err := driver.GetUser(ctx, email); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		sessions, err := driver.ListSessions(ctx, email)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		tokens, err := driver.ListAPITokens(ctx, email)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if sessions == nil {
+			sessions = []*storage.Session{}
+		}
+		if tokens == nil {
+			tokens = []*storage.APIToken{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sessions": sessions,
+			"tokens":   tokens,
+		})
+	}
+}
+
+// revokeUserSessionHandler 吊销用户的登录会话（对应的 JWT 立即失效）
+func revokeUserSessionHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		ctx := c.Request.Context()
+		if err := driver.DeleteSession(ctx, email, c.Param("id")); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		logger.InfoCtx(ctx).Str("user", email).Str("session_id", c.Param("id")).Msg("管理员吊销了登录会话")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "会话已吊销",
+		})
+	}
+}
+
+// revokeUserTokenHandler 吊销用户的个人访问令牌
+func revokeUserTokenHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "无效的令牌 ID",
+			})
+			return
+		}
+
+		email := c.Param("email")
+		ctx := c.Request.Context()
+		if err := driver.DeleteAPIToken(ctx, email, id); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		logger.InfoCtx(ctx).Str("user", email).Int64("token_id", id).Msg("管理员吊销了访问令牌")
+		c.JSON(http.StatusOK, gin.H{
+			"message": "访问令牌已吊销",
+		})
+	}
+}
+
+// revokeAllUserSessionsHandler 在所有地方退出登录：吊销用户的全部登录会话和个人访问令牌
+func revokeAllUserSessionsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		ctx := c.Request.Context()
+		if _, err := driver.GetUser(ctx, email); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		sessions, err := driver.DeleteUserSessions(ctx, email)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		tokens, err := driver.DeleteUserAPITokens(ctx, email)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		logger.InfoCtx(ctx).Str("user", email).Int64("sessions", sessions).Int64("tokens", tokens).Msg("管理员吊销了用户的全部会话和访问令牌")
+		c.JSON(http.StatusOK, gin.H{
+			"sessions": sessions,
+			"tokens":   tokens,
+		})
+	}
+}
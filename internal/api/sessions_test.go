@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestUserSessionHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"s1", "s2"} {
+		if err := driver.CreateSession(ctx, &storage.Session{ID: id, UserEmail: "alice@example.com", Source: "webmail", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"backup", "ci"} {
+		if err := driver.CreateAPIToken(ctx, &storage.APIToken{UserEmail: "alice@example.com", Name: name, Prefix: "gmz_" + name, TokenHash: name, Scope: "read"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/users/:email/sessions", listUserSessionsHandler(driver))
+	router.DELETE("/users/:email/sessions/:id", revokeUserSessionHandler(driver))
+	router.DELETE("/users/:email/tokens/:id", revokeUserTokenHandler(driver))
+	router.POST("/users/:email/sessions/revoke-all", revokeAllUserSessionsHandler(driver))
+
+	var list struct {
+		Sessions []*storage.Session  `json:"sessions"`
+		Tokens   []*storage.APIToken `json:"tokens"`
+	}
+	w := serve(router, http.MethodGet, "/users/alice@example.com/sessions", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("列出会话 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Sessions) != 2 || len(list.Tokens) != 2 {
+		t.Fatalf("会话和令牌 = %+v", list)
+	}
+	if w := serve(router, http.MethodGet, "/users/nobody@example.com/sessions", nil); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的用户 status = %d", w.Code)
+	}
+
+	if w := serve(router, http.MethodDelete, "/users/alice@example.com/sessions/s1", nil); w.Code != http.StatusOK {
+		t.Errorf("吊销会话 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodDelete, "/users/alice@example.com/sessions/s1", nil); w.Code != http.StatusNotFound {
+		t.Errorf("重复吊销会话 status = %d", w.Code)
+	}
+	tokenPath := "/users/alice@example.com/tokens/" + strconv.FormatInt(list.Tokens[0].ID, 10)
+	if w := serve(router, http.MethodDelete, tokenPath, nil); w.Code != http.StatusOK {
+		t.Errorf("吊销访问令牌 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodDelete, "/users/alice@example.com/tokens/abc", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("无效的令牌 ID status = %d", w.Code)
+	}
+
+	w = serve(router, http.MethodPost, "/users/alice@example.com/sessions/revoke-all", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("吊销全部 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var revoked struct {
+		Sessions int64 `json:"sessions"`
+		Tokens   int64 `json:"tokens"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &revoked); err != nil {
+		t.Fatal(err)
+	}
+	if revoked.Sessions != 1 || revoked.Tokens != 1 {
+		t.Errorf("吊销全部 = %+v, want 1 个会话和 1 个令牌", revoked)
+	}
+	if sessions, _ := driver.ListSessions(ctx, "alice@example.com"); len(sessions) != 0 {
+		t.Errorf("吊销全部后仍有会话: %+v", sessions)
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/terms"
+)
+
+// bannerHandler 登录前显示的提示和使用条款（不需要认证）
+func bannerHandler(gate *terms.Gate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gate.Banner())
+	}
+}
+
+// listTermsAcceptancesHandler 查询用户接受使用条款的记录（每个版本的接受时间和来源 IP），
+// accepted 表示是否已接受当前版本
+func listTermsAcceptancesHandler(driver storage.Driver, gate *terms.Gate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := c.Param("email")
+		ctx := c.Request.Context()
+		if _, err := driver.GetUser(ctx, email); err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		acceptances, err := driver.ListTermsAcceptances(ctx, email)
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		banner := gate.Banner()
+		accepted := !banner.RequiresAcceptance
+		for _, a := range acceptances {
+			if a.Version == banner.TermsVersion {
+				accepted = true
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"current_version": banner.TermsVersion,
+			"accepted":        accepted,
+			"acceptances":     acceptances,
+		})
+	}
+}
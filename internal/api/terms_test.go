@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/terms"
+)
+
+func TestLoginTermsAcceptance(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	hash, err := crypto.HashPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "admin@example.com", PasswordHash: hash, Active: true, IsAdmin: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	gate := terms.New(driver, "仅限授权人员使用", "条款全文", "2024-06")
+	router := gin.New()
+	router.GET("/banner", bannerHandler(gate))
+	router.POST("/login", loginHandler(driver, auth.NewJWTManager("secret", "test"), nil, nil, nil, nil, gate))
+	router.GET("/users/:email/terms", listTermsAcceptancesHandler(driver, gate))
+
+	w := serve(router, http.MethodGet, "/banner", nil)
+	var banner terms.Banner
+	if err := json.Unmarshal(w.Body.Bytes(), &banner); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !banner.RequiresAcceptance || banner.TermsVersion != "2024-06" || banner.Notice != "仅限授权人员使用" {
+		t.Errorf("登录提示 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 未接受条款时不签发令牌
+	w = serve(router, http.MethodPost, "/login", []byte(`{"email":"admin@example.com","password":"s3cret"}`))
+	var denied struct {
+		RequiresTerms bool   `json:"requires_terms"`
+		TermsVersion  string `json:"terms_version"`
+		Token         string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &denied); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusForbidden || !denied.RequiresTerms || denied.TermsVersion != "2024-06" || denied.Token != "" {
+		t.Errorf("未接受条款时 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	// 密码错误时不提示条款
+	if w := serve(router, http.MethodPost, "/login", []byte(`{"email":"admin@example.com","password":"wrong","accept_terms":"2024-06"}`)); w.Code != http.StatusUnauthorized {
+		t.Errorf("密码错误 status = %d, want 401", w.Code)
+	}
+
+	if w := serve(router, http.MethodPost, "/login", []byte(`{"email":"admin@example.com","password":"s3cret","accept_terms":"2024-06"}`)); w.Code != http.StatusOK {
+		t.Fatalf("接受条款后登录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodPost, "/login", []byte(`{"email":"admin@example.com","password":"s3cret"}`)); w.Code != http.StatusOK {
+		t.Errorf("已接受条款后登录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = serve(router, http.MethodGet, "/users/admin@example.com/terms", nil)
+	var resp struct {
+		CurrentVersion string                     `json:"current_version"`
+		Accepted       bool                       `json:"accepted"`
+		Acceptances    []*storage.TermsAcceptance `json:"acceptances"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !resp.Accepted || len(resp.Acceptances) != 1 || resp.Acceptances[0].Version != "2024-06" {
+		t.Errorf("接受记录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if w := serve(router, http.MethodGet, "/users/nobody@example.com/terms", nil); w.Code != http.StatusNotFound {
+		t.Errorf("用户不存在 status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/warmup"
+)
+
+// warmupProgressHandler 查询外发预热进度：当前周、每日上限，以及当天各目标服务商的外发数量和推迟邮件数
+func warmupProgressHandler(scheduler *warmup.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scheduler == nil {
+			c.JSON(http.StatusOK, &warmup.Progress{Providers: []*warmup.ProviderProgress{}})
+			return
+		}
+
+		progress, err := scheduler.Progress(c.Request.Context())
+		if err != nil {
+			c.JSON(storageStatus(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if progress.Providers == nil {
+			progress.Providers = []*warmup.ProviderProgress{}
+		}
+		c.JSON(http.StatusOK, progress)
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/warmup"
+)
+
+func TestWarmupProgressHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	// 未启用
+	router := gin.New()
+	router.GET("/stats/warmup", warmupProgressHandler(nil))
+	w := serve(router, http.MethodGet, "/stats/warmup", nil)
+	var progress warmup.Progress
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || progress.Enabled {
+		t.Errorf("未启用预热时 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	scheduler := warmup.New(driver, nil, time.Now(), 4, 10, 100, nil)
+	if _, err := scheduler.Admit(ctx, "alice@example.com", []string{"bob@gmail.com"}, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	router = gin.New()
+	router.GET("/stats/warmup", warmupProgressHandler(scheduler))
+	w = serve(router, http.MethodGet, "/stats/warmup", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("预热进度 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	progress = warmup.Progress{}
+	if err := json.Unmarshal(w.Body.Bytes(), &progress); err != nil {
+		t.Fatal(err)
+	}
+	if !progress.Enabled || progress.Week != 1 || progress.DailyLimit != 10 || len(progress.Providers) != 1 || progress.Providers[0].Sent != 1 {
+		t.Errorf("预热进度 = %+v", progress)
+	}
+}
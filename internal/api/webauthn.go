@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/limits"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// beginWebAuthnLoginHandler 开始通行密钥登录（公开端点）：验证密码后返回流程 ID 和传给 navigator.credentials.get() 的参数，
+// 浏览器返回的断言随 POST /api/v1/auth/login 提交（webauthn_ceremony 和 webauthn_response），代替 TOTP 代码。
+// 通行密钥在 WebMail 中注册
+func beginWebAuthnLoginHandler(driver storage.Driver, manager *auth.WebAuthnManager, limiter *limits.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if manager == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "未启用通行密钥",
+			})
+			return
+		}
+
+		var req struct {
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		// 与登录共享认证失败封禁
+		ip := c.ClientIP()
+		if err := limiter.Allow("admin", ip); err != nil {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "登录失败次数过多，请稍后再试",
+			})
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := driver.GetUser(ctx, req.Email)
+		if err != nil {
+			limiter.Failure("admin", ip)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "认证失败",
+			})
+			return
+		}
+		valid, err := crypto.VerifyPassword(req.Password, user.PasswordHash)
+		if err != nil || !valid {
+			limiter.Failure("admin", ip)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "认证失败",
+			})
+			return
+		}
+
+		id, options, err := manager.BeginLogin(ctx, user.Email)
+		if errors.Is(err, auth.ErrNoWebAuthnCredentials) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("user", user.Email).Msg("开始通行密钥登录失败")
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "开始通行密钥登录失败",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"ceremony_id": id,
+			"options":     options,
+		})
+	}
+}
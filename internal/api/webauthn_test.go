@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestLoginWebAuthnSecondFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	hash, err := crypto.HashPassword("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "admin@example.com", PasswordHash: hash, Active: true, IsAdmin: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := auth.NewWebAuthnManager(auth.WebAuthnConfig{RPID: "example.com", RPOrigins: []string{"https://mail.example.com"}, RPDisplayName: "Test"}, driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.POST("/login", loginHandler(driver, auth.NewJWTManager("secret", "test"), nil, manager, nil, nil, nil))
+	router.POST("/login/webauthn", beginWebAuthnLoginHandler(driver, manager, nil))
+
+	// 未注册通行密钥时只需要密码
+	if w := serve(router, http.MethodPost, "/login/webauthn", []byte(`{"email":"admin@example.com","password":"s3cret"}`)); w.Code != http.StatusBadRequest {
+		t.Errorf("未注册通行密钥时开始登录 status = %d, want 400", w.Code)
+	}
+	if w := serve(router, http.MethodPost, "/login", []byte(`{"email":"admin@example.com","password":"s3cret"}`)); w.Code != http.StatusOK {
+		t.Fatalf("登录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	if err := driver.CreateWebAuthnCredential(ctx, &storage.WebAuthnCredential{
+		UserEmail: "admin@example.com", CredentialID: []byte{1, 2, 3}, Data: []byte(`{"id":"AQID","publicKey":"AA=="}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 注册通行密钥后需要第二因素
+	w := serve(router, http.MethodPost, "/login", []byte(`{"email":"admin@example.com","password":"s3cret"}`))
+	var denied struct {
+		Requires2FA bool   `json:"requires_2fa"`
+		TOTP        bool   `json:"totp"`
+		WebAuthn    bool   `json:"webauthn"`
+		Token       string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &denied); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnauthorized || !denied.Requires2FA || denied.TOTP || !denied.WebAuthn || denied.Token != "" {
+		t.Errorf("未验证通行密钥时 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 开始登录需要正确的密码
+	if w := serve(router, http.MethodPost, "/login/webauthn", []byte(`{"email":"admin@example.com","password":"wrong"}`)); w.Code != http.StatusUnauthorized {
+		t.Errorf("密码错误 status = %d, want 401", w.Code)
+	}
+	w = serve(router, http.MethodPost, "/login/webauthn", []byte(`{"email":"admin@example.com","password":"s3cret"}`))
+	var begin struct {
+		CeremonyID string `json:"ceremony_id"`
+		Options    struct {
+			PublicKey struct {
+				Challenge        string `json:"challenge"`
+				AllowCredentials []struct {
+					ID string `json:"id"`
+				} `json:"allowCredentials"`
+			} `json:"publicKey"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &begin); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || begin.CeremonyID == "" || begin.Options.PublicKey.Challenge == "" ||
+		len(begin.Options.PublicKey.AllowCredentials) != 1 || begin.Options.PublicKey.AllowCredentials[0].ID != "AQID" {
+		t.Fatalf("开始通行密钥登录 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 无效的断言不能登录，流程随之失效
+	body := []byte(`{"email":"admin@example.com","password":"s3cret","webauthn_ceremony":"` + begin.CeremonyID + `","webauthn_response":{}}`)
+	if w := serve(router, http.MethodPost, "/login", body); w.Code != http.StatusUnauthorized {
+		t.Errorf("无效的断言 status = %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 个人访问令牌的权限范围
+const (
+	ScopeRead = "read" // 只读：只能调用 GET 接口
+	ScopeSend = "send" // 只能发送邮件
+	ScopeFull = "full" // 除管理访问令牌以外的所有接口
+)
+
+const (
+	// APITokenPrefix 个人访问令牌的前缀（用于与 JWT 区分，也便于密钥扫描工具识别）
+	APITokenPrefix = "gmz_"
+	// apiTokenDisplayLen 列表中显示的令牌开头长度
+	apiTokenDisplayLen = 12
+	// apiTokenTouchInterval 最近使用时间的最小更新间隔（避免每个请求都写数据库）
+	apiTokenTouchInterval = time.Minute
+)
+
+// ErrInvalidScope 无效的令牌权限范围
+var ErrInvalidScope = errors.New("无效的权限范围（可选值 read, send, full）")
+
+// APITokenManager 个人访问令牌管理器
+type APITokenManager struct {
+	storage storage.Driver
+}
+
+// NewAPITokenManager 创建个人访问令牌管理器
+func NewAPITokenManager(storage storage.Driver) *APITokenManager {
+	return &APITokenManager{
+		storage: storage,
+	}
+}
+
+// ValidScope 是否为有效的权限范围
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeSend, ScopeFull:
+		return true
+	}
+	return false
+}
+
+// IsAPIToken 是否为个人访问令牌（否则按 JWT 处理）
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// ScopeAllows 权限范围是否允许访问接口（path 为路由模式，如 /api/mails/:id）
+// 访问令牌不能管理访问令牌本身和登录会话，避免泄露的令牌创建新令牌或踢出用户
+func ScopeAllows(scope, method, path string) bool {
+	if path == "/api/tokens" || strings.HasPrefix(path, "/api/tokens/") ||
+		path == "/api/sessions" || strings.HasPrefix(path, "/api/sessions/") {
+		return false
+	}
+	switch scope {
+	case ScopeFull:
+		return true
+	case ScopeRead:
+		return method == http.MethodGet || method == http.MethodHead
+	case ScopeSend:
+		return (method == http.MethodPost && path == "/api/mails") || (method == http.MethodGet && path == "/api/me")
+	}
+	return false
+}
+
+// hashAPIToken 令牌的 SHA-256 哈希（令牌为高熵随机数，不需要加盐的慢哈希）
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create 为用户创建个人访问令牌，返回令牌明文（只在创建时返回一次）
+func (m *APITokenManager) Create(ctx context.Context, userEmail, name, scope string, expiresAt *time.Time) (string, *storage.APIToken, error) {
+	if !ValidScope(scope) {
+		return "", nil, ErrInvalidScope
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("生成访问令牌失败: %w", err)
+	}
+	plaintext := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := &storage.APIToken{
+		UserEmail: userEmail,
+		Name:      name,
+		Prefix:    plaintext[:apiTokenDisplayLen],
+		TokenHash: hashAPIToken(plaintext),
+		Scope:     scope,
+		ExpiresAt: expiresAt,
+	}
+	if err := m.storage.CreateAPIToken(ctx, token); err != nil {
+		return "", nil, err
+	}
+	return plaintext, token, nil
+}
+
+// Authenticate 验证个人访问令牌，返回令牌记录
+func (m *APITokenManager) Authenticate(ctx context.Context, plaintext string) (*storage.APIToken, error) {
+	if !IsAPIToken(plaintext) {
+		return nil, ErrInvalidToken
+	}
+	token, err := m.storage.GetAPITokenByHash(ctx, hashAPIToken(plaintext))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && !now.Before(*token.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenTouchInterval {
+		if err := m.storage.TouchAPIToken(ctx, token.ID, now); err != nil {
+			logger.WarnCtx(ctx).Err(err).Int64("token_id", token.ID).Msg("更新访问令牌使用时间失败")
+		}
+	}
+	return token, nil
+}
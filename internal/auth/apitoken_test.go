@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestAPITokenManager(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewAPITokenManager(driver)
+	if _, _, err := manager.Create(ctx, "alice@example.com", "bad", "admin", nil); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("无效的权限范围应该返回 ErrInvalidScope, got %v", err)
+	}
+
+	plaintext, token, err := manager.Create(ctx, "alice@example.com", "backup script", ScopeRead, nil)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if !IsAPIToken(plaintext) || token.Prefix != plaintext[:len(token.Prefix)] || token.TokenHash == plaintext {
+		t.Errorf("令牌格式不正确: %s %+v", plaintext, token)
+	}
+
+	got, err := manager.Authenticate(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if got.UserEmail != "alice@example.com" || got.Scope != ScopeRead {
+		t.Errorf("令牌记录不正确: %+v", got)
+	}
+	if got, _ := driver.GetAPITokenByHash(ctx, token.TokenHash); got.LastUsedAt == nil {
+		t.Error("认证后应该记录最近使用时间")
+	}
+	if _, err := manager.Authenticate(ctx, plaintext+"x"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("错误的令牌应该返回 ErrInvalidToken, got %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	expired, _, err := manager.Create(ctx, "alice@example.com", "old", ScopeFull, &past)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Authenticate(ctx, expired); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("过期令牌应该返回 ErrExpiredToken, got %v", err)
+	}
+
+	// 吊销后不能再使用
+	if err := driver.DeleteAPIToken(ctx, "bob@example.com", token.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("不能吊销其他用户的令牌, got %v", err)
+	}
+	if err := driver.DeleteAPIToken(ctx, "alice@example.com", token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Authenticate(ctx, plaintext); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("已吊销的令牌应该返回 ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope, method, path string
+		want                bool
+	}{
+		{ScopeRead, http.MethodGet, "/api/mails", true},
+		{ScopeRead, http.MethodPost, "/api/mails", false},
+		{ScopeRead, http.MethodDelete, "/api/mails/:id", false},
+		{ScopeSend, http.MethodPost, "/api/mails", true},
+		{ScopeSend, http.MethodGet, "/api/me", true},
+		{ScopeSend, http.MethodGet, "/api/mails", false},
+		{ScopeFull, http.MethodDelete, "/api/mails/:id", true},
+		{ScopeFull, http.MethodGet, "/api/tokens", false},
+		{ScopeFull, http.MethodDelete, "/api/tokens/:id", false},
+		{ScopeFull, http.MethodGet, "/api/sessions", false},
+		{ScopeFull, http.MethodPost, "/api/sessions/revoke-all", false},
+		{"admin", http.MethodGet, "/api/mails", false},
+	}
+	for _, tt := range tests {
+		if got := ScopeAllows(tt.scope, tt.method, tt.path); got != tt.want {
+			t.Errorf("ScopeAllows(%s, %s, %s) = %v, want %v", tt.scope, tt.method, tt.path, got, tt.want)
+		}
+	}
+}
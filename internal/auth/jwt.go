@@ -1,12 +1,23 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
 )
 
+// sessionTouchInterval 会话最近活动时间的最小更新间隔（避免每个请求都写数据库）
+const sessionTouchInterval = time.Minute
+
 var (
 	ErrInvalidToken = errors.New("无效的令牌")
 	ErrExpiredToken = errors.New("令牌已过期")
@@ -16,6 +27,7 @@ var (
 type JWTManager struct {
 	secretKey []byte
 	issuer    string
+	sessions  storage.Driver // 会话存储（为空时令牌无状态，不能吊销）
 }
 
 // NewJWTManager 创建 JWT 管理器
@@ -34,14 +46,181 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成 JWT 令牌
+// UseSessions 启用会话跟踪：IssueToken 签发的令牌对应一条会话记录（jti 为会话 ID），
+// Validate 要求会话仍然存在，会话被吊销后令牌立即失效。启用后不带会话的令牌（GenerateToken 签发）不再有效
+func (m *JWTManager) UseSessions(driver storage.Driver) *JWTManager {
+	m.sessions = driver
+	return m
+}
+
+// SessionInfo 登录会话的来源信息
+type SessionInfo struct {
+	Source    string // webmail 或 admin
+	RemoteIP  string
+	UserAgent string
+}
+
+// IssueToken 为登录签发令牌，启用会话跟踪时同时创建会话记录
+func (m *JWTManager) IssueToken(ctx context.Context, user *storage.User, isAdmin bool, expiry time.Duration, info SessionInfo) (string, error) {
+	if m.sessions == nil {
+		return m.GenerateToken(user.Email, user.ID, isAdmin, expiry)
+	}
+	session, err := m.createSession(ctx, user, expiry, info, "")
+	if err != nil {
+		return "", err
+	}
+	return m.generateToken(session.ID, user.Email, user.ID, isAdmin, expiry)
+}
+
+// TokenPair 登录签发的访问令牌和刷新令牌
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string        // 为空表示不能刷新（未启用会话跟踪）
+	ExpiresIn    time.Duration // 访问令牌的有效期
+}
+
+// IssueSession 为登录签发短期访问令牌（accessTTL）和刷新令牌，会话在 sessionTTL 后过期，
+// 期间用 Refresh 换取新的访问令牌；未启用会话跟踪时只签发有效期为 sessionTTL 的访问令牌
+func (m *JWTManager) IssueSession(ctx context.Context, user *storage.User, isAdmin bool, accessTTL, sessionTTL time.Duration, info SessionInfo) (*TokenPair, error) {
+	if m.sessions == nil {
+		token, err := m.GenerateToken(user.Email, user.ID, isAdmin, sessionTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &TokenPair{AccessToken: token, ExpiresIn: sessionTTL}, nil
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+	session, err := m.createSession(ctx, user, sessionTTL, info, hashRefreshSecret(secret))
+	if err != nil {
+		return nil, err
+	}
+	token, err := m.generateToken(session.ID, user.Email, user.ID, isAdmin, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: token, RefreshToken: session.ID + "." + secret, ExpiresIn: accessTTL}, nil
+}
+
+// Refresh 用刷新令牌换取新的访问令牌，同时轮换刷新令牌（旧的刷新令牌随即失效）。
+// 会话已吊销或过期、刷新令牌已使用过、用户已删除或禁用时返回 ErrInvalidToken；
+// 刷新得到的访问令牌不具有管理员权限
+func (m *JWTManager) Refresh(ctx context.Context, refreshToken string, accessTTL time.Duration) (*TokenPair, error) {
+	id, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || id == "" || secret == "" || m.sessions == nil {
+		return nil, ErrInvalidToken
+	}
+	session, err := m.sessions.GetSession(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	user, err := m.sessions.GetUser(ctx, session.UserEmail)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && !user.Active) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := randomHex(32)
+	if err != nil {
+		return nil, fmt.Errorf("生成刷新令牌失败: %w", err)
+	}
+	if err := m.sessions.RotateSessionRefresh(ctx, session.ID, hashRefreshSecret(secret), hashRefreshSecret(next)); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	token, err := m.generateToken(session.ID, user.Email, user.ID, false, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: token, RefreshToken: session.ID + "." + next, ExpiresIn: accessTTL}, nil
+}
+
+// createSession 创建会话记录，refreshHash 为空时会话不能刷新
+func (m *JWTManager) createSession(ctx context.Context, user *storage.User, expiry time.Duration, info SessionInfo, refreshHash string) (*storage.Session, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, fmt.Errorf("生成会话 ID 失败: %w", err)
+	}
+	session := &storage.Session{
+		ID:          id,
+		UserEmail:   user.Email,
+		Source:      info.Source,
+		RemoteIP:    info.RemoteIP,
+		UserAgent:   info.UserAgent,
+		ExpiresAt:   time.Now().Add(expiry),
+		RefreshHash: refreshHash,
+	}
+	if err := m.sessions.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// randomHex 生成 n 个随机字节的十六进制字符串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashRefreshSecret 刷新令牌中随机部分的 SHA-256 哈希（数据库只保存哈希）
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Validate 验证令牌；启用会话跟踪时会话必须存在（未吊销、未过期），并记录会话的最近活动时间
+func (m *JWTManager) Validate(ctx context.Context, tokenString string) (*Claims, error) {
+	claims, err := m.ValidateToken(tokenString)
+	if err != nil || m.sessions == nil {
+		return claims, err
+	}
+	if claims.ID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	session, err := m.sessions.GetSession(ctx, claims.ID)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && session.UserEmail != claims.Email) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if session.LastUsedAt == nil || now.Sub(*session.LastUsedAt) >= sessionTouchInterval {
+		if err := m.sessions.TouchSession(ctx, session.ID, now); err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("session_id", session.ID).Msg("更新会话活动时间失败")
+		}
+	}
+	return claims, nil
+}
+
+// GenerateToken 生成 JWT 令牌（不对应会话，不能吊销）
 func (m *JWTManager) GenerateToken(email string, userID int64, isAdmin bool, expiry time.Duration) (string, error) {
+	return m.generateToken("", email, userID, isAdmin, expiry)
+}
+
+// generateToken 生成 JWT 令牌，id 为会话 ID（jti）
+func (m *JWTManager) generateToken(id, email string, userID int64, isAdmin bool, expiry time.Duration) (string, error) {
 	now := time.Now()
 	claims := &Claims{
 		Email:   email,
 		UserID:  userID,
 		IsAdmin: isAdmin,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
 			Issuer:    m.issuer,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
@@ -83,6 +262,6 @@ func (m *JWTManager) RefreshToken(tokenString string, expiry time.Duration) (str
 		return "", err
 	}
 
-	// 生成新令牌
-	return m.GenerateToken(claims.Email, claims.UserID, claims.IsAdmin, expiry)
+	// 生成新令牌（沿用原令牌的会话）
+	return m.generateToken(claims.ID, claims.Email, claims.UserID, claims.IsAdmin, expiry)
 }
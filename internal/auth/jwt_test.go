@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestJWTSessions(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	user := &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}
+	if err := driver.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewJWTManager("secret", "test").UseSessions(driver)
+	token, err := manager.IssueToken(ctx, user, false, time.Hour, SessionInfo{Source: "webmail", RemoteIP: "192.0.2.1", UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	claims, err := manager.Validate(ctx, token)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	session, err := driver.GetSession(ctx, claims.ID)
+	if err != nil {
+		t.Fatalf("获取会话失败: %v", err)
+	}
+	if session.UserEmail != user.Email || session.Source != "webmail" || session.RemoteIP != "192.0.2.1" || session.LastUsedAt == nil {
+		t.Errorf("会话记录 = %+v", session)
+	}
+
+	// 不对应会话的令牌在启用会话跟踪后无效
+	stateless, err := manager.GenerateToken(user.Email, user.ID, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Validate(ctx, stateless); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("不带会话的令牌应该返回 ErrInvalidToken, got %v", err)
+	}
+	if _, err := NewJWTManager("secret", "test").Validate(ctx, stateless); err != nil {
+		t.Errorf("未启用会话跟踪时 Validate() error = %v", err)
+	}
+
+	// 吊销后令牌立即失效
+	if err := driver.DeleteSession(ctx, user.Email, claims.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Validate(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("吊销的会话应该返回 ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestJWTRefresh(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	user := &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}
+	if err := driver.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewJWTManager("secret", "test").UseSessions(driver)
+	tokens, err := manager.IssueSession(ctx, user, false, time.Minute, time.Hour, SessionInfo{Source: "webmail"})
+	if err != nil {
+		t.Fatalf("IssueSession() error = %v", err)
+	}
+	if tokens.RefreshToken == "" || tokens.ExpiresIn != time.Minute {
+		t.Fatalf("IssueSession() = %+v", tokens)
+	}
+	claims, err := manager.Validate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if time.Until(claims.ExpiresAt.Time) > time.Minute {
+		t.Errorf("访问令牌有效期 = %v，应该使用 accessTTL", time.Until(claims.ExpiresAt.Time))
+	}
+
+	// 刷新得到同一会话的新访问令牌，旧的刷新令牌失效
+	refreshed, err := manager.Refresh(ctx, tokens.RefreshToken, time.Minute)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("刷新后应该轮换刷新令牌")
+	}
+	refreshedClaims, err := manager.Validate(ctx, refreshed.AccessToken)
+	if err != nil || refreshedClaims.ID != claims.ID {
+		t.Errorf("刷新后的访问令牌 = %+v, %v", refreshedClaims, err)
+	}
+	if _, err := manager.Refresh(ctx, tokens.RefreshToken, time.Minute); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("使用过的刷新令牌应该返回 ErrInvalidToken, got %v", err)
+	}
+	for _, invalid := range []string{"", "garbage", claims.ID + ".", "unknown.secret"} {
+		if _, err := manager.Refresh(ctx, invalid, time.Minute); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Refresh(%q) error = %v, want ErrInvalidToken", invalid, err)
+		}
+	}
+
+	// 禁用用户或吊销会话后不能刷新
+	user, err = driver.GetUser(ctx, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Active = false
+	if err := driver.UpdateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Refresh(ctx, refreshed.RefreshToken, time.Minute); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("禁用的用户刷新 error = %v, want ErrInvalidToken", err)
+	}
+	user.Active = true
+	if err := driver.UpdateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.DeleteSession(ctx, user.Email, claims.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Refresh(ctx, refreshed.RefreshToken, time.Minute); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("吊销的会话刷新 error = %v, want ErrInvalidToken", err)
+	}
+
+	// 未启用会话跟踪时只签发访问令牌
+	stateless, err := NewJWTManager("secret", "test").IssueSession(ctx, user, false, time.Minute, time.Hour, SessionInfo{})
+	if err != nil || stateless.RefreshToken != "" || stateless.ExpiresIn != time.Hour {
+		t.Errorf("未启用会话跟踪时 IssueSession() = %+v, %v", stateless, err)
+	}
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksRefreshInterval 公钥的缓存时间
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval 遇到未知 kid 时重新获取公钥的最小间隔（防止伪造令牌触发大量请求）
+	jwksMinRefreshInterval = 30 * time.Second
+	// maxJWKSSize JWKS 响应的最大长度
+	maxJWKSSize = 1 << 20
+)
+
+// oauthSigningMethods 外部令牌允许的签名算法（只接受非对称算法，防止以公钥作为 HMAC 密钥伪造令牌）
+var oauthSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OAuthVerifier 验证外部身份提供方（OpenID Connect）签发的 JWT 访问令牌：
+// 从 JWKS 地址获取签名公钥，校验签名、有效期、签发者和受众，返回令牌中的用户邮箱
+type OAuthVerifier struct {
+	JWKSURL       string
+	Issuer        string       // 要求的签发者（iss，为空时不校验）
+	Audience      string       // 要求的受众（aud，为空时不校验）
+	UsernameClaim string       // 用户邮箱所在的声明（默认 email）
+	Client        *http.Client // 获取 JWKS 的 HTTP 客户端（为空时使用 10 秒超时的默认客户端）
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// Verify 验证令牌，返回用户邮箱（小写）
+func (v *OAuthVerifier) Verify(ctx context.Context, tokenString string) (string, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(oauthSigningMethods), jwt.WithExpirationRequired()}
+	if v.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.Issuer))
+	}
+	if v.Audience != "" {
+		opts = append(opts, jwt.WithAudience(v.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	}, opts...)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return "", ErrExpiredToken
+		}
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claim := v.UsernameClaim
+	if claim == "" {
+		claim = "email"
+	}
+	username, _ := claims[claim].(string)
+	if !strings.Contains(username, "@") {
+		return "", fmt.Errorf("%w: 令牌中没有有效的 %s 声明", ErrInvalidToken, claim)
+	}
+	return strings.ToLower(username), nil
+}
+
+// key 按 kid 查找公钥，缓存过期或找不到时重新获取 JWKS
+func (v *OAuthVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	age := time.Since(v.fetched)
+	key, ok := v.lookup(kid)
+	if ok && age < jwksRefreshInterval {
+		return key, nil
+	}
+	if v.keys == nil || age >= jwksMinRefreshInterval {
+		keys, err := v.fetch(ctx)
+		if err != nil {
+			// 获取失败时继续使用缓存的公钥
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		v.keys, v.fetched = keys, time.Now()
+		key, ok = v.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("未知的签名密钥 %q", kid)
+	}
+	return key, nil
+}
+
+// lookup 查找缓存的公钥；令牌没有 kid 且 JWKS 中只有一个密钥时使用该密钥
+func (v *OAuthVerifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// jwk JSON Web Key（RFC 7517），只解析 RSA 和 EC 公钥
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch 获取并解析 JWKS（跳过无法解析和用于加密的密钥）
+func (v *OAuthVerifier) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取 JWKS 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取 JWKS 失败: HTTP %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSSize)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("解析 JWKS 失败: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey 转换为公钥
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("无效的 RSA 指数")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("不支持的曲线 %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("不支持的密钥类型 %q", k.Kty)
+}
+
+// decodeBigInt 解码 base64url 编码的大整数
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("无效的密钥参数")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
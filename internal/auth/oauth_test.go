@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOAuthVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+				{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+				{"kty": "RSA", "kid": "enc1", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+			},
+		})
+	}))
+	defer server.Close()
+
+	verifier := &OAuthVerifier{JWKSURL: server.URL, Issuer: "https://idp.example.com", Audience: "gmz"}
+	sign := func(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+		t.Helper()
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   "https://idp.example.com",
+			"aud":   "gmz",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"email": "Alice@Example.com",
+		}
+	}
+	ctx := context.Background()
+
+	for _, token := range []string{
+		sign(jwt.SigningMethodRS256, "rsa1", rsaKey, claims()),
+		sign(jwt.SigningMethodES256, "ec1", ecKey, claims()),
+	} {
+		email, err := verifier.Verify(ctx, token)
+		if err != nil || email != "alice@example.com" {
+			t.Errorf("Verify() = %q, %v", email, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("公钥应该被缓存, JWKS 请求次数 = %d", n)
+	}
+
+	wrongIssuer := claims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+	wrongAudience := claims()
+	wrongAudience["aud"] = "other"
+	noEmail := claims()
+	delete(noEmail, "email")
+	noExpiry := claims()
+	delete(noExpiry, "exp")
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	invalid := map[string]string{
+		"wrong issuer":   sign(jwt.SigningMethodRS256, "rsa1", rsaKey, wrongIssuer),
+		"wrong audience": sign(jwt.SigningMethodRS256, "rsa1", rsaKey, wrongAudience),
+		"no email":       sign(jwt.SigningMethodRS256, "rsa1", rsaKey, noEmail),
+		"no expiry":      sign(jwt.SigningMethodRS256, "rsa1", rsaKey, noExpiry),
+		"wrong key":      sign(jwt.SigningMethodRS256, "rsa1", otherKey, claims()),
+		"encryption key": sign(jwt.SigningMethodRS256, "enc1", rsaKey, claims()),
+		"hmac":           sign(jwt.SigningMethodHS256, "rsa1", []byte("secret"), claims()),
+	}
+	for name, token := range invalid {
+		if _, err := verifier.Verify(ctx, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: 应该返回 ErrInvalidToken, got %v", name, err)
+		}
+	}
+
+	expired := claims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	if _, err := verifier.Verify(ctx, sign(jwt.SigningMethodRS256, "rsa1", rsaKey, expired)); !errors.Is(err, ErrExpiredToken) {
+		t.Errorf("过期令牌应该返回 ErrExpiredToken, got %v", err)
+	}
+
+	// 未知 kid 在最小间隔内不会重复请求 JWKS
+	before := requests.Load()
+	for i := 0; i < 3; i++ {
+		_, _ = verifier.Verify(ctx, sign(jwt.SigningMethodRS256, "unknown", rsaKey, claims()))
+	}
+	if n := requests.Load() - before; n != 0 {
+		t.Errorf("最小间隔内不应该重新获取 JWKS, 请求次数 = %d", n)
+	}
+}
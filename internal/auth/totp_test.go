@@ -166,7 +166,10 @@ func TestTOTPManager_GenerateRecoveryCodes(t *testing.T) {
 }
 
 // MockStorage 模拟存储
-type MockStorage struct{}
+type MockStorage struct {
+	// 嵌入接口：未显式模拟的方法在测试中不会被调用
+	storage.Driver
+}
 
 func (m *MockStorage) CreateUser(ctx context.Context, user *storage.User) error {
 	return nil
@@ -248,7 +251,7 @@ func (m *MockStorage) UpdateMailFlags(ctx context.Context, id string, flags []st
 	return nil
 }
 
-func (m *MockStorage) SearchMails(ctx context.Context, userEmail string, query string, folder string, limit, offset int) ([]*storage.Mail, error) {
+func (m *MockStorage) SearchMails(ctx context.Context, userEmail string, query *storage.SearchQuery, folder string, limit, offset int) ([]*storage.Mail, error) {
 	return nil, nil
 }
 
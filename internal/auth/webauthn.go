@@ -3,8 +3,11 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
@@ -12,32 +15,47 @@ import (
 	"github.com/gomailzero/gmz/internal/storage"
 )
 
+// ceremonyTTL 注册或登录流程从 Begin 到 Finish 的最长时间
+const ceremonyTTL = 5 * time.Minute
+
+var (
+	// ErrNoWebAuthnCredentials 用户未注册 WebAuthn 凭证
+	ErrNoWebAuthnCredentials = errors.New("用户未注册 WebAuthn 凭证")
+	// ErrInvalidCeremony 注册或登录流程不存在、已过期或不属于该用户
+	ErrInvalidCeremony = errors.New("WebAuthn 流程不存在或已过期")
+)
+
 // WebAuthnManager WebAuthn 管理器
 type WebAuthnManager struct {
-	webauthn *webauthn.WebAuthn
-	storage  storage.Driver
+	webauthn   *webauthn.WebAuthn
+	storage    storage.Driver
+	ceremonies *ceremonyCache
 }
 
 // WebAuthnConfig WebAuthn 配置
 type WebAuthnConfig struct {
-	RPID          string // Relying Party ID（通常是域名）
-	RPOrigin      string // Relying Party Origin（例如：https://mail.example.com）
-	RPDisplayName string // Relying Party Display Name
+	RPID          string   // Relying Party ID（通常是域名）
+	RPOrigins     []string // 允许的 Origin（例如：https://mail.example.com，WebMail 和管理后台不同源时都要列出）
+	RPDisplayName string   // Relying Party Display Name
 }
 
 // NewWebAuthnManager 创建 WebAuthn 管理器
 func NewWebAuthnManager(cfg WebAuthnConfig, storage storage.Driver) (*WebAuthnManager, error) {
 	// 解析 Origin URL
-	originURL, err := url.Parse(cfg.RPOrigin)
-	if err != nil {
-		return nil, fmt.Errorf("解析 RPOrigin 失败: %w", err)
+	origins := make([]string, 0, len(cfg.RPOrigins))
+	for _, origin := range cfg.RPOrigins {
+		originURL, err := url.Parse(origin)
+		if err != nil {
+			return nil, fmt.Errorf("解析 RPOrigin 失败: %w", err)
+		}
+		origins = append(origins, originURL.String())
 	}
 
 	// 创建 WebAuthn 实例
 	w, err := webauthn.New(&webauthn.Config{
 		RPDisplayName: cfg.RPDisplayName,
 		RPID:          cfg.RPID,
-		RPOrigins:     []string{originURL.String()},
+		RPOrigins:     origins,
 		// 使用默认的挑战超时时间（60秒）
 		// 使用默认的认证器选择器
 	})
@@ -46,8 +64,9 @@ func NewWebAuthnManager(cfg WebAuthnConfig, storage storage.Driver) (*WebAuthnMa
 	}
 
 	return &WebAuthnManager{
-		webauthn: w,
-		storage:  storage,
+		webauthn:   w,
+		storage:    storage,
+		ceremonies: &ceremonyCache{entries: make(map[string]*ceremony)},
 	}, nil
 }
 
@@ -83,133 +102,181 @@ func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
 	return u.Credentials
 }
 
-// BeginRegistration 开始注册流程
-func (m *WebAuthnManager) BeginRegistration(ctx context.Context, userEmail string) (*webauthn.SessionData, *protocol.CredentialCreation, error) {
-	// 获取用户
+// ceremony 进行中的注册或登录流程
+type ceremony struct {
+	userEmail string
+	session   webauthn.SessionData
+	expires   time.Time
+}
+
+// ceremonyCache 进行中的注册和登录流程（内存缓存，按随机 ID 查找，每个流程只能完成一次）
+type ceremonyCache struct {
+	mu      sync.Mutex
+	entries map[string]*ceremony
+}
+
+// put 保存流程的会话数据，返回流程 ID，同时清理已过期的流程
+func (c *ceremonyCache) put(userEmail string, session *webauthn.SessionData) (string, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return "", fmt.Errorf("生成流程 ID 失败: %w", err)
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[id] = &ceremony{userEmail: userEmail, session: *session, expires: now.Add(ceremonyTTL)}
+	return id, nil
+}
+
+// take 取出流程的会话数据（取出后删除），流程不存在、已过期或不属于该用户时返回 ErrInvalidCeremony
+func (c *ceremonyCache) take(id, userEmail string) (*webauthn.SessionData, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[id]
+	delete(c.entries, id)
+	c.mu.Unlock()
+
+	if !ok || entry.userEmail != userEmail || time.Now().After(entry.expires) {
+		return nil, ErrInvalidCeremony
+	}
+	return &entry.session, nil
+}
+
+// webAuthnUser 加载用户和已注册的凭证
+func (m *WebAuthnManager) webAuthnUser(ctx context.Context, userEmail string) (*WebAuthnUser, error) {
 	user, err := m.storage.GetUser(ctx, userEmail)
 	if err != nil {
-		return nil, nil, fmt.Errorf("获取用户失败: %w", err)
+		return nil, fmt.Errorf("获取用户失败: %w", err)
 	}
 
-	// 加载用户的现有凭证
 	credentials, err := m.loadCredentials(ctx, userEmail)
 	if err != nil {
-		logger.Warn().Err(err).Str("user", userEmail).Msg("加载 WebAuthn 凭证失败，继续注册")
-		credentials = []webauthn.Credential{}
+		return nil, fmt.Errorf("加载凭证失败: %w", err)
 	}
 
-	// 创建 WebAuthn 用户
-	webauthnUser := &WebAuthnUser{
+	return &WebAuthnUser{
 		ID:          []byte(fmt.Sprintf("%d", user.ID)),
 		Email:       user.Email,
 		Credentials: credentials,
+	}, nil
+}
+
+// HasCredentials 检查用户是否注册了 WebAuthn 凭证
+func (m *WebAuthnManager) HasCredentials(ctx context.Context, userEmail string) (bool, error) {
+	creds, err := m.storage.ListWebAuthnCredentials(ctx, userEmail)
+	if err != nil {
+		return false, err
 	}
+	return len(creds) > 0, nil
+}
 
-	// 开始注册
-	options, session, err := m.webauthn.BeginRegistration(webauthnUser)
+// BeginRegistration 开始注册流程，返回流程 ID 和传给浏览器 navigator.credentials.create() 的参数
+func (m *WebAuthnManager) BeginRegistration(ctx context.Context, userEmail string) (string, *protocol.CredentialCreation, error) {
+	webauthnUser, err := m.webAuthnUser(ctx, userEmail)
 	if err != nil {
-		return nil, nil, fmt.Errorf("开始注册失败: %w", err)
+		return "", nil, err
 	}
 
-	// TODO: 保存 session 到临时存储（Redis 或内存缓存）
-	// 这里简化实现，返回 session 给调用者保存
+	// 开始注册（排除已注册的认证器，避免重复注册）
+	options, session, err := m.webauthn.BeginRegistration(webauthnUser,
+		webauthn.WithExclusions(webauthn.Credentials(webauthnUser.Credentials).CredentialDescriptors()))
+	if err != nil {
+		return "", nil, fmt.Errorf("开始注册失败: %w", err)
+	}
 
-	return session, options, nil
+	id, err := m.ceremonies.put(userEmail, session)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, options, nil
 }
 
-// FinishRegistration 完成注册流程
-func (m *WebAuthnManager) FinishRegistration(ctx context.Context, userEmail string, session *webauthn.SessionData, response *protocol.ParsedCredentialCreationData) (*webauthn.Credential, error) {
-	// 获取用户
-	user, err := m.storage.GetUser(ctx, userEmail)
+// FinishRegistration 完成注册流程：验证浏览器返回的凭证（JSON）并保存
+func (m *WebAuthnManager) FinishRegistration(ctx context.Context, userEmail, ceremonyID, name string, response []byte) (*storage.WebAuthnCredential, error) {
+	session, err := m.ceremonies.take(ceremonyID, userEmail)
 	if err != nil {
-		return nil, fmt.Errorf("获取用户失败: %w", err)
+		return nil, err
 	}
 
-	// 创建 WebAuthn 用户
-	webauthnUser := &WebAuthnUser{
-		ID:          []byte(fmt.Sprintf("%d", user.ID)),
-		Email:       user.Email,
-		Credentials: []webauthn.Credential{}, // 注册时还没有凭证
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, fmt.Errorf("解析注册响应失败: %w", err)
+	}
+
+	webauthnUser, err := m.webAuthnUser(ctx, userEmail)
+	if err != nil {
+		return nil, err
 	}
 
 	// 完成注册
-	// 注意：FinishRegistration 需要 *http.Request，这里需要调用 CreateCredential
-	credential, err := m.webauthn.CreateCredential(webauthnUser, *session, response)
+	credential, err := m.webauthn.CreateCredential(webauthnUser, *session, parsed)
 	if err != nil {
 		return nil, fmt.Errorf("完成注册失败: %w", err)
 	}
 
 	// 保存凭证到数据库
-	if err := m.saveCredential(ctx, userEmail, credential); err != nil {
+	saved, err := m.saveCredential(ctx, userEmail, name, credential)
+	if err != nil {
 		return nil, fmt.Errorf("保存凭证失败: %w", err)
 	}
 
 	logger.Info().Str("user", userEmail).Msg("WebAuthn 凭证注册成功")
-	return credential, nil
+	return saved, nil
 }
 
-// BeginLogin 开始登录流程
-func (m *WebAuthnManager) BeginLogin(ctx context.Context, userEmail string) (*webauthn.SessionData, *protocol.CredentialAssertion, error) {
-	// 获取用户
-	user, err := m.storage.GetUser(ctx, userEmail)
-	if err != nil {
-		return nil, nil, fmt.Errorf("获取用户失败: %w", err)
-	}
-
-	// 加载用户的凭证
-	credentials, err := m.loadCredentials(ctx, userEmail)
+// BeginLogin 开始登录流程，返回流程 ID 和传给浏览器 navigator.credentials.get() 的参数。
+// 用户未注册凭证时返回 ErrNoWebAuthnCredentials
+func (m *WebAuthnManager) BeginLogin(ctx context.Context, userEmail string) (string, *protocol.CredentialAssertion, error) {
+	webauthnUser, err := m.webAuthnUser(ctx, userEmail)
 	if err != nil {
-		return nil, nil, fmt.Errorf("加载凭证失败: %w", err)
+		return "", nil, err
 	}
-
-	if len(credentials) == 0 {
-		return nil, nil, fmt.Errorf("用户未注册 WebAuthn 凭证")
-	}
-
-	// 创建 WebAuthn 用户
-	webauthnUser := &WebAuthnUser{
-		ID:          []byte(fmt.Sprintf("%d", user.ID)),
-		Email:       user.Email,
-		Credentials: credentials,
+	if len(webauthnUser.Credentials) == 0 {
+		return "", nil, ErrNoWebAuthnCredentials
 	}
 
 	// 开始登录
 	options, session, err := m.webauthn.BeginLogin(webauthnUser)
 	if err != nil {
-		return nil, nil, fmt.Errorf("开始登录失败: %w", err)
+		return "", nil, fmt.Errorf("开始登录失败: %w", err)
 	}
 
-	// TODO: 保存 session 到临时存储
-
-	return session, options, nil
+	id, err := m.ceremonies.put(userEmail, session)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, options, nil
 }
 
-// FinishLogin 完成登录流程
-func (m *WebAuthnManager) FinishLogin(ctx context.Context, userEmail string, session *webauthn.SessionData, response *protocol.ParsedCredentialAssertionData) (*webauthn.Credential, error) {
-	// 获取用户
-	user, err := m.storage.GetUser(ctx, userEmail)
+// FinishLogin 完成登录流程：验证浏览器返回的断言（JSON），成功后更新凭证的签名计数
+func (m *WebAuthnManager) FinishLogin(ctx context.Context, userEmail, ceremonyID string, response []byte) error {
+	session, err := m.ceremonies.take(ceremonyID, userEmail)
 	if err != nil {
-		return nil, fmt.Errorf("获取用户失败: %w", err)
+		return err
 	}
 
-	// 加载用户的凭证
-	credentials, err := m.loadCredentials(ctx, userEmail)
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
 	if err != nil {
-		return nil, fmt.Errorf("加载凭证失败: %w", err)
+		return fmt.Errorf("解析登录响应失败: %w", err)
 	}
 
-	// 创建 WebAuthn 用户
-	webauthnUser := &WebAuthnUser{
-		ID:          []byte(fmt.Sprintf("%d", user.ID)),
-		Email:       user.Email,
-		Credentials: credentials,
+	webauthnUser, err := m.webAuthnUser(ctx, userEmail)
+	if err != nil {
+		return err
 	}
 
 	// 完成登录
-	// 注意：FinishLogin 需要 *http.Request，这里需要调用 ValidateLogin
-	credential, err := m.webauthn.ValidateLogin(webauthnUser, *session, response)
+	credential, err := m.webauthn.ValidateLogin(webauthnUser, *session, parsed)
 	if err != nil {
-		return nil, fmt.Errorf("完成登录失败: %w", err)
+		return fmt.Errorf("完成登录失败: %w", err)
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.Warn().Str("user", userEmail).Msg("WebAuthn 签名计数没有增加，认证器可能被克隆")
 	}
 
 	// 更新凭证的签名计数
@@ -218,46 +285,51 @@ func (m *WebAuthnManager) FinishLogin(ctx context.Context, userEmail string, ses
 	}
 
 	logger.Info().Str("user", userEmail).Msg("WebAuthn 登录成功")
-	return credential, nil
+	return nil
 }
 
 // loadCredentials 从数据库加载用户的 WebAuthn 凭证
 func (m *WebAuthnManager) loadCredentials(ctx context.Context, userEmail string) ([]webauthn.Credential, error) {
-	// TODO: 从数据库加载凭证
-	// 当前简化实现，返回空列表
-	// 需要实现：
-	// 1. 在 storage 接口中添加 GetWebAuthnCredentials 方法
-	// 2. 在 SQLite 驱动中实现该方法
-	// 3. 从数据库读取并反序列化凭证
-
-	return []webauthn.Credential{}, nil
+	creds, err := m.storage.ListWebAuthnCredentials(ctx, userEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, 0, len(creds))
+	for _, cred := range creds {
+		var credential webauthn.Credential
+		if err := json.Unmarshal(cred.Data, &credential); err != nil {
+			return nil, fmt.Errorf("解析凭证 %d 失败: %w", cred.ID, err)
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, nil
 }
 
 // saveCredential 保存凭证到数据库
-func (m *WebAuthnManager) saveCredential(ctx context.Context, userEmail string, credential *webauthn.Credential) error {
-	// TODO: 保存凭证到数据库
-	// 需要实现：
-	// 1. 在 storage 接口中添加 SaveWebAuthnCredential 方法
-	// 2. 在 SQLite 驱动中实现该方法
-	// 3. 序列化凭证并保存到数据库
-
-	// 临时实现：记录日志
-	credJSON, _ := json.Marshal(credential)
-	logger.Info().
-		Str("user", userEmail).
-		RawJSON("credential", credJSON).
-		Msg("WebAuthn 凭证（待保存到数据库）")
+func (m *WebAuthnManager) saveCredential(ctx context.Context, userEmail, name string, credential *webauthn.Credential) (*storage.WebAuthnCredential, error) {
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return nil, fmt.Errorf("序列化凭证失败: %w", err)
+	}
 
-	return nil
+	cred := &storage.WebAuthnCredential{
+		UserEmail:    userEmail,
+		CredentialID: credential.ID,
+		Name:         name,
+		Data:         data,
+	}
+	if err := m.storage.CreateWebAuthnCredential(ctx, cred); err != nil {
+		return nil, err
+	}
+	return cred, nil
 }
 
 // updateCredential 更新凭证（主要是签名计数）
 func (m *WebAuthnManager) updateCredential(ctx context.Context, userEmail string, credential *webauthn.Credential) error {
-	// TODO: 更新凭证到数据库
-	// 需要实现：
-	// 1. 在 storage 接口中添加 UpdateWebAuthnCredential 方法
-	// 2. 在 SQLite 驱动中实现该方法
-	// 3. 更新凭证的签名计数
-
-	return nil
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return fmt.Errorf("序列化凭证失败: %w", err)
+	}
+	return m.storage.UpdateWebAuthnCredential(ctx, userEmail, credential.ID, data)
 }
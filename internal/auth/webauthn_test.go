@@ -1,26 +1,227 @@
 package auth
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://mail.example.com"
 )
 
-func TestWebAuthnManager_BeginRegistration(t *testing.T) {
-	// TODO: 实现 WebAuthn 注册流程测试
-	// 需要 mock webauthn 库
-	t.Skip("WebAuthn 测试待实现")
+// softAuthenticator 测试用的软件认证器（ES256，none 证明）
+type softAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	id        []byte
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, id: id}
 }
 
-func TestWebAuthnManager_FinishRegistration(t *testing.T) {
-	// TODO: 实现 WebAuthn 注册完成测试
-	t.Skip("WebAuthn 测试待实现")
+var b64 = base64.RawURLEncoding
+
+// clientData 浏览器生成的 clientDataJSON
+func clientData(typ string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": b64.EncodeToString(challenge),
+		"origin":    testOrigin,
+	})
+	return data
 }
 
-func TestWebAuthnManager_BeginLogin(t *testing.T) {
-	// TODO: 实现 WebAuthn 登录流程测试
-	t.Skip("WebAuthn 测试待实现")
+// authData 认证器数据：RP ID 哈希、标志（UP|UV，注册时加 AT）、签名计数和注册时的凭证数据
+func (a *softAuthenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte{}, rpHash[:]...)
+	flags := byte(0x05)
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if !attested {
+		return data
+	}
+
+	key, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{KeyType: int64(webauthncose.EllipticKey), Algorithm: int64(webauthncose.AlgES256)},
+		Curve:         1,
+		XCoord:        a.key.X.FillBytes(make([]byte, 32)),
+		YCoord:        a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, make([]byte, 16)...) // AAGUID
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.id)))
+	data = append(data, a.id...)
+	return append(data, key...)
 }
 
-func TestWebAuthnManager_FinishLogin(t *testing.T) {
-	// TODO: 实现 WebAuthn 登录完成测试
-	t.Skip("WebAuthn 测试待实现")
+// create 模拟 navigator.credentials.create() 的返回值
+func (a *softAuthenticator) create(t *testing.T, challenge []byte) []byte {
+	t.Helper()
+	object, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, _ := json.Marshal(map[string]any{
+		"id":    b64.EncodeToString(a.id),
+		"rawId": b64.EncodeToString(a.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64.EncodeToString(clientData("webauthn.create", challenge)),
+			"attestationObject": b64.EncodeToString(object),
+		},
+	})
+	return response
+}
+
+// get 模拟 navigator.credentials.get() 的返回值
+func (a *softAuthenticator) get(t *testing.T, challenge []byte) []byte {
+	t.Helper()
+	a.signCount++
+	authData := a.authData(t, false)
+	client := clientData("webauthn.get", challenge)
+	clientHash := sha256.Sum256(client)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, _ := json.Marshal(map[string]any{
+		"id":    b64.EncodeToString(a.id),
+		"rawId": b64.EncodeToString(a.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64.EncodeToString(client),
+			"authenticatorData": b64.EncodeToString(authData),
+			"signature":         b64.EncodeToString(signature),
+		},
+	})
+	return response
+}
+
+func TestWebAuthnManager(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	manager, err := NewWebAuthnManager(WebAuthnConfig{RPID: testRPID, RPOrigins: []string{testOrigin}, RPDisplayName: "Test"}, driver)
+	if err != nil {
+		t.Fatalf("NewWebAuthnManager() error = %v", err)
+	}
+
+	// 未注册凭证时不能开始登录
+	if _, _, err := manager.BeginLogin(ctx, "alice@example.com"); !errors.Is(err, ErrNoWebAuthnCredentials) {
+		t.Fatalf("BeginLogin() error = %v, want ErrNoWebAuthnCredentials", err)
+	}
+
+	authenticator := newSoftAuthenticator(t)
+	ceremonyID, creation, err := manager.BeginRegistration(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	response := authenticator.create(t, creation.Response.Challenge)
+
+	// 流程属于发起的用户
+	if _, err := manager.FinishRegistration(ctx, "bob@example.com", ceremonyID, "", response); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("其他用户完成注册 error = %v, want ErrInvalidCeremony", err)
+	}
+	// 校验失败后流程已失效，需要重新开始
+	if _, err := manager.FinishRegistration(ctx, "alice@example.com", ceremonyID, "", response); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("重复使用流程 error = %v, want ErrInvalidCeremony", err)
+	}
+	ceremonyID, creation, err = manager.BeginRegistration(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := manager.FinishRegistration(ctx, "alice@example.com", ceremonyID, "YubiKey", authenticator.create(t, creation.Response.Challenge))
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	if cred.ID == 0 || cred.Name != "YubiKey" || string(cred.CredentialID) != string(authenticator.id) {
+		t.Errorf("FinishRegistration() = %+v", cred)
+	}
+	if ok, err := manager.HasCredentials(ctx, "alice@example.com"); err != nil || !ok {
+		t.Errorf("HasCredentials() = %v, %v", ok, err)
+	}
+
+	// 再次注册时排除已注册的认证器
+	_, creation, err = manager.BeginRegistration(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creation.Response.CredentialExcludeList) != 1 {
+		t.Errorf("CredentialExcludeList = %v", creation.Response.CredentialExcludeList)
+	}
+
+	ceremonyID, assertion, err := manager.BeginLogin(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+	if err := manager.FinishLogin(ctx, "alice@example.com", ceremonyID, authenticator.get(t, assertion.Response.Challenge)); err != nil {
+		t.Fatalf("FinishLogin() error = %v", err)
+	}
+
+	// 签名计数已保存
+	creds, err := driver.ListWebAuthnCredentials(ctx, "alice@example.com")
+	if err != nil || len(creds) != 1 || creds[0].LastUsedAt == nil {
+		t.Fatalf("ListWebAuthnCredentials() = %+v, %v", creds, err)
+	}
+	loaded, err := manager.loadCredentials(ctx, "alice@example.com")
+	if err != nil || loaded[0].Authenticator.SignCount != 1 {
+		t.Errorf("签名计数 = %+v, %v", loaded, err)
+	}
+
+	// 挑战不匹配（使用其他流程的挑战）时登录失败
+	ceremonyID, _, err = manager.BeginLogin(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.FinishLogin(ctx, "alice@example.com", ceremonyID, authenticator.get(t, assertion.Response.Challenge)); err == nil {
+		t.Error("挑战不匹配时 FinishLogin() 应该失败")
+	}
+	if err := manager.FinishLogin(ctx, "alice@example.com", "unknown", nil); !errors.Is(err, ErrInvalidCeremony) {
+		t.Errorf("未知流程 error = %v, want ErrInvalidCeremony", err)
+	}
 }
@@ -0,0 +1,198 @@
+// Package autoreply 投递时按用户的自动回复设置回复发件人（RFC 3834），
+// 不回复邮件列表、退信和其他自动发送的邮件，同一发件人在间隔天数内只回复一次
+package autoreply
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// subjectPrefix 未设置回复主题时加在原邮件主题前的前缀
+const subjectPrefix = "自动回复: "
+
+// Sender 外发邮件（中继或直接投递）
+type Sender interface {
+	Send(ctx context.Context, from string, to []string, data []byte) error
+}
+
+// Responder 自动回复
+//
+// 方法可以在 nil 上调用（不回复），调用方不需要判断是否启用。
+type Responder struct {
+	Storage  storage.Driver
+	Outbound Sender
+	Hostname string // Message-ID 使用的主机名（为空时使用收件人的域名）
+
+	now func() time.Time // 测试时替换当前时间
+}
+
+// automatedSenders 自动发送邮件的发件人本地部分（RFC 5230 第 4.6 节）
+var automatedSenders = []string{"mailer-daemon", "listserv", "majordomo", "noreply", "no-reply", "do-not-reply", "donotreply"}
+
+// Suppressed 返回不应自动回复的原因，应该回复时返回空字符串
+//
+// recipients 为邮件投递到的地址（收件地址和别名解析后的用户邮箱），
+// 只有其中之一出现在 To 或 Cc 中时才回复，避免回复密送和邮件列表转发的邮件。
+func Suppressed(sender string, recipients []string, header message.Header) string {
+	sender = strings.ToLower(strings.Trim(sender, "<> "))
+	at := strings.LastIndex(sender, "@")
+	if at <= 0 {
+		return "空发件人或退信"
+	}
+	local := sender[:at]
+	for _, name := range automatedSenders {
+		if local == name {
+			return "自动发送的发件人"
+		}
+	}
+	if strings.HasPrefix(local, "owner-") || strings.HasSuffix(local, "-request") {
+		return "邮件列表管理地址"
+	}
+	for _, recipient := range recipients {
+		if strings.EqualFold(sender, recipient) {
+			return "发件人是收件人自己"
+		}
+	}
+
+	if v := strings.ToLower(strings.TrimSpace(header.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return "自动发送的邮件"
+	}
+	for _, key := range []string{"List-Id", "List-Post", "List-Unsubscribe"} {
+		if header.Has(key) {
+			return "邮件列表邮件"
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(header.Get("Precedence"))) {
+	case "bulk", "list", "junk":
+		return "群发邮件"
+	}
+	for _, v := range strings.Split(header.Get("X-Auto-Response-Suppress"), ",") {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "oof", "all":
+			return "发件人要求不自动回复"
+		}
+	}
+
+	h := mail.Header{Header: header}
+	for _, key := range []string{"To", "Cc"} {
+		addrs, err := h.AddressList(key)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			for _, recipient := range recipients {
+				if strings.EqualFold(addr.Address, recipient) {
+					return ""
+				}
+			}
+		}
+	}
+	return "收件人不在 To 或 Cc 中"
+}
+
+// Respond mailbox 用户在 recipient 地址（可能是别名）收到 sender 的邮件后按其设置自动回复，返回是否已发送
+func (r *Responder) Respond(ctx context.Context, mailbox, recipient, sender string, header message.Header) (bool, error) {
+	if r == nil || r.Outbound == nil {
+		return false, nil
+	}
+	now := time.Now()
+	if r.now != nil {
+		now = r.now()
+	}
+
+	responder, err := r.Storage.GetAutoResponder(ctx, mailbox)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !responder.Active(now) {
+		return false, nil
+	}
+	if reason := Suppressed(sender, []string{recipient, mailbox}, header); reason != "" {
+		logger.DebugCtx(ctx).
+			Str("user", mailbox).
+			Str("sender", sender).
+			Str("reason", reason).
+			Msg("不发送自动回复")
+		return false, nil
+	}
+
+	sender = strings.Trim(sender, "<> ")
+	first, err := r.Storage.RecordAutoReply(ctx, mailbox, sender, responder.Interval(), now)
+	if err != nil || !first {
+		return false, err
+	}
+
+	data, err := r.build(responder, recipient, sender, header, now)
+	if err != nil {
+		return false, err
+	}
+	// 自动回复使用空的信封发件人，对方的自动回复和退信不会再回到这里（RFC 3834 第 3.3 节）
+	if err := r.Outbound.Send(ctx, "", []string{sender}, data); err != nil {
+		return false, fmt.Errorf("发送自动回复失败: %w", err)
+	}
+	logger.InfoCtx(ctx).
+		Str("user", mailbox).
+		Str("to", sender).
+		Msg("已发送自动回复")
+	return true, nil
+}
+
+// build 生成自动回复邮件（纯文本，引用原邮件的 Message-ID）
+func (r *Responder) build(responder *storage.AutoResponder, recipient, sender string, original message.Header, now time.Time) ([]byte, error) {
+	orig := mail.Header{Header: original}
+	subject := strings.TrimSpace(responder.Subject)
+	if subject == "" {
+		origSubject, err := orig.Subject()
+		if err != nil {
+			origSubject = orig.Get("Subject")
+		}
+		subject = subjectPrefix + origSubject
+	}
+	hostname := r.Hostname
+	if hostname == "" {
+		hostname = recipient[strings.LastIndex(recipient, "@")+1:]
+	}
+
+	var h mail.Header
+	h.SetDate(now)
+	h.SetAddressList("From", []*mail.Address{{Address: recipient}})
+	h.SetAddressList("To", []*mail.Address{{Address: sender}})
+	h.SetSubject(subject)
+	if err := h.GenerateMessageIDWithHostname(hostname); err != nil {
+		return nil, fmt.Errorf("生成 Message-ID 失败: %w", err)
+	}
+	if id, err := orig.MessageID(); err == nil && id != "" {
+		h.SetMsgIDList("In-Reply-To", []string{id})
+		references, _ := orig.MsgIDList("References")
+		h.SetMsgIDList("References", append(references, id))
+	}
+	h.Set("Auto-Submitted", "auto-replied")
+	h.Set("MIME-Version", "1.0")
+	h.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	var buf bytes.Buffer
+	w, err := message.CreateWriter(&buf, h.Header)
+	if err != nil {
+		return nil, fmt.Errorf("生成自动回复失败: %w", err)
+	}
+	if _, err := w.Write([]byte(responder.Body)); err != nil {
+		return nil, fmt.Errorf("生成自动回复失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("生成自动回复失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
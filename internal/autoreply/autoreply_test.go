@@ -0,0 +1,163 @@
+package autoreply
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// fakeSender 记录外发的邮件
+type fakeSender struct {
+	sent []sentMail
+}
+
+type sentMail struct {
+	from string
+	to   []string
+	data []byte
+}
+
+func (f *fakeSender) Send(ctx context.Context, from string, to []string, data []byte) error {
+	f.sent = append(f.sent, sentMail{from: from, to: to, data: data})
+	return nil
+}
+
+// parseHeader 解析测试邮件头
+func parseHeader(t *testing.T, raw string) message.Header {
+	t.Helper()
+	entity, err := message.Read(bufio.NewReader(strings.NewReader(strings.ReplaceAll(raw, "\n", "\r\n") + "\r\nbody\r\n")))
+	if err != nil {
+		t.Fatalf("解析邮件头失败: %v", err)
+	}
+	return entity.Header
+}
+
+func TestSuppressed(t *testing.T) {
+	recipients := []string{"alice@example.com"}
+	tests := []struct {
+		name     string
+		sender   string
+		header   string
+		suppress bool
+	}{
+		{"normal", "bob@example.net", "To: Alice <alice@example.com>\n", false},
+		{"cc", "bob@example.net", "To: carol@example.net\nCc: ALICE@example.com\n", false},
+		{"null sender", "", "To: alice@example.com\n", true},
+		{"mailer daemon", "MAILER-DAEMON@example.net", "To: alice@example.com\n", true},
+		{"list owner", "owner-dev@lists.example.net", "To: alice@example.com\n", true},
+		{"list request", "dev-request@lists.example.net", "To: alice@example.com\n", true},
+		{"noreply", "noreply@example.net", "To: alice@example.com\n", true},
+		{"self", "alice@example.com", "To: alice@example.com\n", true},
+		{"auto submitted", "bob@example.net", "To: alice@example.com\nAuto-Submitted: auto-replied\n", true},
+		{"auto submitted no", "bob@example.net", "To: alice@example.com\nAuto-Submitted: no\n", false},
+		{"list id", "bob@example.net", "To: alice@example.com\nList-Id: <dev.lists.example.net>\n", true},
+		{"list unsubscribe", "bob@example.net", "To: alice@example.com\nList-Unsubscribe: <mailto:u@example.net>\n", true},
+		{"precedence bulk", "bob@example.net", "To: alice@example.com\nPrecedence: bulk\n", true},
+		{"exchange suppress", "bob@example.net", "To: alice@example.com\nX-Auto-Response-Suppress: DR, OOF\n", true},
+		{"bcc", "bob@example.net", "To: carol@example.net\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := Suppressed(tt.sender, recipients, parseHeader(t, tt.header))
+			if (reason != "") != tt.suppress {
+				t.Errorf("Suppressed() = %q, 期望不回复 = %v", reason, tt.suppress)
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 7, 1, 9, 0, 0, 0, time.UTC)
+	sender := &fakeSender{}
+	r := &Responder{Storage: driver, Outbound: sender, Hostname: "mx.example.com", now: func() time.Time { return now }}
+	header := parseHeader(t, "From: Bob <bob@example.net>\nTo: sales@example.com\nSubject: =?UTF-8?B?5oql5Lu3?=\nMessage-ID: <1@example.net>\n")
+
+	// 没有设置自动回复时不回复
+	if sent, err := r.Respond(ctx, "alice@example.com", "sales@example.com", "bob@example.net", header); err != nil || sent {
+		t.Fatalf("未设置自动回复时不应该回复: %v, %v", sent, err)
+	}
+
+	if err := driver.SaveAutoResponder(ctx, &storage.AutoResponder{
+		UserEmail: "alice@example.com",
+		Enabled:   true,
+		Body:      "我在休假，7 月 10 日回来。",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 发往别名的邮件以别名地址回复，空信封发件人
+	if sent, err := r.Respond(ctx, "alice@example.com", "sales@example.com", "bob@example.net", header); err != nil || !sent {
+		t.Fatalf("应该发送自动回复: %v, %v", sent, err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("应该发送 1 封自动回复, got %d", len(sender.sent))
+	}
+	reply := sender.sent[0]
+	if reply.from != "" || len(reply.to) != 1 || reply.to[0] != "bob@example.net" {
+		t.Errorf("自动回复的信封不正确: from=%q to=%v", reply.from, reply.to)
+	}
+	entity, err := message.Read(bytes.NewReader(reply.data))
+	if err != nil {
+		t.Fatalf("解析自动回复失败: %v", err)
+	}
+	h := mail.Header{Header: entity.Header}
+	if subject, _ := h.Subject(); subject != "自动回复: 报价" {
+		t.Errorf("自动回复主题 = %q", subject)
+	}
+	if from, _ := h.AddressList("From"); len(from) != 1 || from[0].Address != "sales@example.com" {
+		t.Errorf("自动回复发件人 = %v", from)
+	}
+	if got := h.Get("Auto-Submitted"); got != "auto-replied" {
+		t.Errorf("Auto-Submitted = %q", got)
+	}
+	if ids, _ := h.MsgIDList("In-Reply-To"); len(ids) != 1 || ids[0] != "1@example.net" {
+		t.Errorf("In-Reply-To = %v", ids)
+	}
+	body, _ := io.ReadAll(entity.Body)
+	if !strings.Contains(string(body), "7 月 10 日回来") {
+		t.Errorf("自动回复正文不正确: %q", body)
+	}
+
+	// 间隔内同一发件人不再回复，超过间隔后再次回复
+	now = now.Add(24 * time.Hour)
+	if sent, err := r.Respond(ctx, "alice@example.com", "sales@example.com", "bob@example.net", header); err != nil || sent {
+		t.Errorf("间隔内不应该再次回复: %v, %v", sent, err)
+	}
+	now = now.Add(7 * 24 * time.Hour)
+	if sent, err := r.Respond(ctx, "alice@example.com", "sales@example.com", "bob@example.net", header); err != nil || !sent {
+		t.Errorf("超过间隔后应该再次回复: %v, %v", sent, err)
+	}
+
+	// 邮件列表邮件不回复
+	list := parseHeader(t, "From: dev@lists.example.net\nTo: alice@example.com\nList-Id: <dev.lists.example.net>\n")
+	if sent, err := r.Respond(ctx, "alice@example.com", "alice@example.com", "carol@example.net", list); err != nil || sent {
+		t.Errorf("邮件列表邮件不应该回复: %v, %v", sent, err)
+	}
+
+	// nil 不回复
+	var disabled *Responder
+	if sent, err := disabled.Respond(ctx, "alice@example.com", "alice@example.com", "bob@example.net", header); err != nil || sent {
+		t.Errorf("未启用时不应该回复: %v, %v", sent, err)
+	}
+}
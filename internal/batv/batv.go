@@ -0,0 +1,147 @@
+// Package batv 实现退信地址验证（Bounce Address Tag Validation，prvs 格式）：
+// 外发邮件的信封发件人签名为 prvs=KDDDSSSSSS=local@domain，真正的退信发往签名地址，
+// 伪造本域发件人产生的反向散射（发往未签名地址的退信）可以在 RCPT TO 拒收
+package batv
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 -- BATV 规范使用 HMAC-SHA1 生成短哈希，不用于签名或加密
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// prefix 签名地址的本地部分前缀
+	prefix = "prvs="
+	// maxAgeDays 签名地址的有效天数（退信通常在几天内返回）
+	maxAgeDays = 7
+	// daySlots 日期取模的周期（3 位数字）
+	daySlots = 1000
+	// tagLen 标签长度：密钥编号 1 位、日期 3 位、哈希 6 位
+	tagLen = 10
+)
+
+var (
+	// ErrNotSigned 地址不是 BATV 签名地址
+	ErrNotSigned = errors.New("不是 BATV 签名地址")
+	// ErrInvalid 签名地址的哈希、密钥编号或日期无效
+	ErrInvalid = errors.New("无效的 BATV 签名地址")
+)
+
+// Key 签名密钥：编号写入签名地址，轮换密钥后旧密钥签名的地址在有效期内仍可验证
+type Key struct {
+	ID     int // 0 ~ 9
+	Secret []byte
+}
+
+// Signer BATV 签名器：只对启用的域名签名和要求签名
+type Signer struct {
+	keys    []Key // 第一个密钥用于签名，全部密钥用于验证
+	domains map[string]bool
+	now     func() time.Time
+}
+
+// NewSigner 创建签名器，keys 的第一个密钥用于签名（至少需要一个），domains 为启用 BATV 的域名
+func NewSigner(keys []Key, domains []string) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("没有 BATV 签名密钥")
+	}
+	seen := make(map[int]bool, len(keys))
+	for _, key := range keys {
+		if key.ID < 0 || key.ID > 9 {
+			return nil, fmt.Errorf("无效的 BATV 密钥编号 %d（需要 0 ~ 9）", key.ID)
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("BATV 密钥编号 %d 重复", key.ID)
+		}
+		if len(key.Secret) == 0 {
+			return nil, fmt.Errorf("BATV 密钥 %d 为空", key.ID)
+		}
+		seen[key.ID] = true
+	}
+	s := &Signer{keys: keys, domains: make(map[string]bool, len(domains)), now: time.Now}
+	for _, domain := range domains {
+		s.domains[strings.ToLower(domain)] = true
+	}
+	return s, nil
+}
+
+// Enabled 域名是否启用 BATV
+func (s *Signer) Enabled(domain string) bool {
+	return s.domains[strings.ToLower(domain)]
+}
+
+// Sign 签名信封发件人；空发件人（退信）、未启用 BATV 的域名和已签名的地址原样返回
+func (s *Signer) Sign(sender string) string {
+	local, domain, ok := split(sender)
+	if !ok || !s.Enabled(domain) || hasPrefix(local) {
+		return sender
+	}
+	key := s.keys[0]
+	day := s.day()
+	return fmt.Sprintf("%s%d%03d%s=%s@%s", prefix, key.ID, day, s.hash(key, day, local+"@"+domain), local, domain)
+}
+
+// Verify 验证签名地址并返回原地址，未签名的地址返回 ErrNotSigned
+func (s *Signer) Verify(address string) (string, error) {
+	local, domain, ok := split(address)
+	if !ok || !hasPrefix(local) {
+		return "", ErrNotSigned
+	}
+	tag, original, found := strings.Cut(local[len(prefix):], "=")
+	if !found || len(tag) != tagLen || original == "" {
+		return "", ErrInvalid
+	}
+	id, err := strconv.Atoi(tag[:1])
+	if err != nil {
+		return "", ErrInvalid
+	}
+	day, err := strconv.Atoi(tag[1:4])
+	if err != nil {
+		return "", ErrInvalid
+	}
+
+	for _, key := range s.keys {
+		if key.ID != id {
+			continue
+		}
+		if !hmac.Equal([]byte(strings.ToLower(tag[4:])), []byte(s.hash(key, day, original+"@"+domain))) {
+			return "", ErrInvalid
+		}
+		if (s.day()-day+daySlots)%daySlots > maxAgeDays {
+			return "", fmt.Errorf("%w: 已过期", ErrInvalid)
+		}
+		return original + "@" + domain, nil
+	}
+	return "", fmt.Errorf("%w: 未知的密钥编号 %d", ErrInvalid, id)
+}
+
+// hash 计算密钥编号、日期和原地址的短哈希（地址不区分大小写）
+func (s *Signer) hash(key Key, day int, address string) string {
+	mac := hmac.New(sha1.New, key.Secret)
+	fmt.Fprintf(mac, "%d%03d%s", key.ID, day, strings.ToLower(address))
+	return hex.EncodeToString(mac.Sum(nil)[:3])
+}
+
+// day 以天为单位的日期，取模后为 3 位数字
+func (s *Signer) day() int {
+	return int((s.now().Unix() / 86400) % daySlots)
+}
+
+// split 拆分地址的本地部分和域名
+func split(address string) (local, domain string, ok bool) {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return "", "", false
+	}
+	return address[:at], address[at+1:], true
+}
+
+// hasPrefix 本地部分是否带有签名前缀（不区分大小写）
+func hasPrefix(local string) bool {
+	return len(local) >= len(prefix) && strings.EqualFold(local[:len(prefix)], prefix)
+}
@@ -0,0 +1,76 @@
+package batv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer, err := NewSigner([]Key{{ID: 1, Secret: []byte("secret")}}, []string{"Example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := signer.Sign("Alice@example.com")
+	if !strings.HasPrefix(signed, "prvs=1") || !strings.HasSuffix(signed, "=Alice@example.com") {
+		t.Fatalf("Sign() = %q", signed)
+	}
+	original, err := signer.Verify(signed)
+	if err != nil || original != "Alice@example.com" {
+		t.Errorf("Verify() = %q, %v", original, err)
+	}
+	// 中间服务器改变大小写后仍然可以验证
+	if _, err := signer.Verify(strings.ToUpper(signed)); err != nil {
+		t.Errorf("大写地址应该可以验证: %v", err)
+	}
+
+	// 空发件人、未启用的域名和已签名的地址不签名
+	for _, sender := range []string{"", "bob@other.org", signed} {
+		if got := signer.Sign(sender); got != sender {
+			t.Errorf("Sign(%q) = %q，不应该签名", sender, got)
+		}
+	}
+}
+
+func TestVerifyInvalid(t *testing.T) {
+	signer, _ := NewSigner([]Key{{ID: 1, Secret: []byte("secret")}}, []string{"example.com"})
+	signed := signer.Sign("alice@example.com")
+
+	if _, err := signer.Verify("alice@example.com"); !errors.Is(err, ErrNotSigned) {
+		t.Errorf("未签名的地址应该返回 ErrNotSigned, got %v", err)
+	}
+	for name, address := range map[string]string{
+		"篡改原地址": strings.Replace(signed, "=alice@", "=mallory@", 1),
+		"格式错误":  "prvs=123=alice@example.com",
+		"未知密钥":  "prvs=2" + signed[6:],
+	} {
+		if _, err := signer.Verify(address); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: 应该无效, got %v", name, err)
+		}
+	}
+
+	signer.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+	if _, err := signer.Verify(signed); !errors.Is(err, ErrInvalid) {
+		t.Errorf("过期的地址应该无效, got %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := NewSigner([]Key{{ID: 1, Secret: []byte("old")}}, []string{"example.com"})
+	signed := old.Sign("alice@example.com")
+
+	// 新密钥放在最前面用于签名，旧密钥保留用于验证之前签名的地址
+	rotated, _ := NewSigner([]Key{{ID: 2, Secret: []byte("new")}, {ID: 1, Secret: []byte("old")}}, []string{"example.com"})
+	if _, err := rotated.Verify(signed); err != nil {
+		t.Errorf("旧密钥签名的地址应该可以验证: %v", err)
+	}
+	if got := rotated.Sign("alice@example.com"); !strings.HasPrefix(got, "prvs=2") {
+		t.Errorf("应该使用新密钥签名: %q", got)
+	}
+
+	if _, err := NewSigner([]Key{{ID: 1, Secret: []byte("a")}, {ID: 1, Secret: []byte("b")}}, nil); err == nil {
+		t.Error("重复的密钥编号应该报错")
+	}
+}
@@ -0,0 +1,286 @@
+// Package bounce 退信分析：把外发时被拒收的收件人和收到的退信报告（DSN，RFC 3464）
+// 按原因分类（收件人不存在、邮箱已满、被判为垃圾邮件、DNS 错误）并记录，
+// 供管理 API 按目标域名和发件人统计投递问题
+//
+// 只记录永久失败：5xx 拒收、域名不存在或没有 MX 记录，以及 Action 为 failed 的退信报告；
+// 临时错误（4xx、连接失败）由发件客户端重试，不计为退信。
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 退信原因分类
+const (
+	CategoryUserUnknown = "user_unknown" // 收件人不存在
+	CategoryMailboxFull = "mailbox_full" // 收件人邮箱已满
+	CategorySpamBlock   = "spam_block"   // 被判为垃圾邮件或被策略拦截（黑名单、信誉、认证失败）
+	CategoryDNSError    = "dns_error"    // 收件人域名不存在或没有 MX 记录
+	CategoryOther       = "other"
+)
+
+// Categories 所有退信原因分类
+var Categories = []string{CategoryUserUnknown, CategoryMailboxFull, CategorySpamBlock, CategoryDNSError, CategoryOther}
+
+// 退信来源
+const (
+	SourceSMTP = "smtp" // 外发时被对方服务器拒收
+	SourceDSN  = "dsn"  // 收到的退信报告
+)
+
+// ErrNotDSN 邮件不是退信报告
+var ErrNotDSN = errors.New("不是退信报告")
+
+// enhancedStatus 匹配增强状态码（RFC 3463，如 5.1.1）
+var enhancedStatus = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+
+// keywords 按诊断信息中的关键字分类（增强状态码无法确定分类时使用，按顺序匹配）
+var keywords = []struct {
+	category string
+	words    []string
+}{
+	{CategoryMailboxFull, []string{"mailbox full", "mailbox is full", "over quota", "quota exceeded", "exceeded storage", "insufficient storage"}},
+	{CategoryUserUnknown, []string{"user unknown", "unknown user", "no such user", "does not exist", "doesn't exist", "mailbox unavailable", "mailbox not found", "invalid recipient", "recipient address rejected", "address rejected", "no mailbox"}},
+	{CategorySpamBlock, []string{"spam", "blocked", "blacklist", "blocklist", "block list", "listed", "reputation", "rbl", "dnsbl", "policy", "dmarc", "spf", "dkim"}},
+	{CategoryDNSError, []string{"host not found", "no such host", "domain not found", "no mx", "mx record", "name or service not known", "nxdomain"}},
+}
+
+// Classify 根据增强状态码和诊断信息判断退信原因
+func Classify(status, diagnostic string) string {
+	if m := enhancedStatus.FindStringSubmatch(status); m != nil {
+		switch subject, detail := m[2], m[3]; {
+		case subject == "1" && (detail == "1" || detail == "6"):
+			return CategoryUserUnknown // 5.1.1 地址不存在、5.1.6 已迁移
+		case subject == "1" && (detail == "2" || detail == "10"):
+			return CategoryDNSError // 5.1.2 目标系统不存在、5.1.10 Null MX
+		case subject == "2" && detail == "2":
+			return CategoryMailboxFull
+		case subject == "4" && (detail == "3" || detail == "4"):
+			return CategoryDNSError // 4.4.3 目录服务器失败、5.4.4 无法路由
+		case subject == "7":
+			return CategorySpamBlock
+		}
+	}
+
+	text := strings.ToLower(diagnostic)
+	for _, k := range keywords {
+		for _, word := range k.words {
+			if strings.Contains(text, word) {
+				return k.category
+			}
+		}
+	}
+	return CategoryOther
+}
+
+// FromError 把外发错误转换为退信记录，只包含永久失败，返回的记录没有设置发件人
+//
+// 错误由 smtpclient 按域名组合时，每个域名的收件人使用各自的错误；否则 to 中的收件人都使用 err。
+// 已通过 OnReject 报告的拒收（smtpclient.ErrRecipientRejected）不重复记录。
+func FromError(err error, to []string) []*storage.Bounce {
+	if err == nil {
+		return nil
+	}
+
+	var bounces []*storage.Bounce
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			var delivery *smtpclient.DeliveryError
+			if errors.As(e, &delivery) {
+				bounces = append(bounces, FromError(delivery.Err, delivery.Recipients)...)
+			} else {
+				bounces = append(bounces, FromError(e, to)...)
+			}
+		}
+		return bounces
+	}
+	if errors.Is(err, smtpclient.ErrRecipientRejected) {
+		return nil
+	}
+
+	_, status, diagnostic, permanent := Describe(err)
+	if !permanent {
+		return nil
+	}
+	category := Classify(status, diagnostic)
+	for _, recipient := range to {
+		bounces = append(bounces, &storage.Bounce{
+			Recipient:  recipient,
+			Category:   category,
+			Status:     status,
+			Diagnostic: diagnostic,
+			Source:     SourceSMTP,
+		})
+	}
+	return bounces
+}
+
+// Describe 从外发错误中提取 SMTP 响应码（没有时为 0）、状态码和诊断信息，并判断是否为永久失败
+func Describe(err error) (code int, status, diagnostic string, permanent bool) {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		status = strconv.Itoa(reply.Code)
+		if m := enhancedStatus.FindString(reply.Msg); m != "" {
+			status = m
+		}
+		return reply.Code, status, reply.Msg, reply.Code >= 500 && reply.Code < 600
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return 0, "", dnsErr.Error(), dnsErr.IsNotFound
+	}
+	if errors.Is(err, smtpclient.ErrNoMX) {
+		return 0, "", err.Error(), true
+	}
+	return 0, "", err.Error(), false
+}
+
+// Report 退信报告中一个收件人的投递结果
+type Report struct {
+	Recipient  string
+	Action     string // failed、delayed、delivered、relayed、expanded
+	Status     string // 增强状态码
+	Diagnostic string
+}
+
+// ParseDSN 解析退信报告（multipart/report; report-type=delivery-status），
+// 返回每个收件人的投递结果；邮件不是退信报告时返回 ErrNotDSN
+func ParseDSN(raw []byte) ([]*Report, error) {
+	entity, err := message.Read(bytes.NewReader(raw))
+	if err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err) {
+		return nil, fmt.Errorf("解析退信报告失败: %w", err)
+	}
+	mediaType, params, _ := entity.Header.ContentType()
+	if mediaType != "multipart/report" || !strings.EqualFold(params["report-type"], "delivery-status") {
+		return nil, ErrNotDSN
+	}
+
+	mr := entity.MultipartReader()
+	if mr == nil {
+		return nil, ErrNotDSN
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, ErrNotDSN
+		}
+		if err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err) {
+			return nil, fmt.Errorf("解析退信报告失败: %w", err)
+		}
+		switch t, _, _ := part.Header.ContentType(); t {
+		case "message/delivery-status", "message/global-delivery-status":
+			return parseDeliveryStatus(part.Body)
+		}
+	}
+}
+
+// parseDeliveryStatus 解析 delivery-status 部分：第一组字段描述报告本身，之后每组字段描述一个收件人
+func parseDeliveryStatus(body io.Reader) ([]*Report, error) {
+	r := textproto.NewReader(bufio.NewReader(body))
+	var reports []*Report
+	for first := true; ; first = false {
+		fields, err := r.ReadMIMEHeader()
+		if len(fields) > 0 && !first {
+			if report := newReport(fields); report.Recipient != "" {
+				reports = append(reports, report)
+			}
+		}
+		if err == io.EOF {
+			return reports, nil
+		}
+		if err != nil {
+			return reports, fmt.Errorf("解析退信报告失败: %w", err)
+		}
+	}
+}
+
+// newReport 从收件人字段生成投递结果
+func newReport(fields textproto.MIMEHeader) *Report {
+	recipient := fields.Get("Final-Recipient")
+	if recipient == "" {
+		recipient = fields.Get("Original-Recipient")
+	}
+	// 地址类型前缀，如 rfc822; alice@example.com
+	if _, addr, ok := strings.Cut(recipient, ";"); ok {
+		recipient = addr
+	}
+	recipient = strings.Trim(strings.TrimSpace(recipient), "<>")
+
+	diagnostic := fields.Get("Diagnostic-Code")
+	if _, text, ok := strings.Cut(diagnostic, ";"); ok {
+		diagnostic = text
+	}
+	return &Report{
+		Recipient:  recipient,
+		Action:     strings.ToLower(strings.TrimSpace(fields.Get("Action"))),
+		Status:     strings.TrimSpace(fields.Get("Status")),
+		Diagnostic: strings.TrimSpace(diagnostic),
+	}
+}
+
+// Recorder 记录退信
+type Recorder struct {
+	Storage storage.Driver
+}
+
+// RecordError 记录外发失败中被拒收的收件人（实现 smtpclient.BounceRecorder）
+func (r *Recorder) RecordError(ctx context.Context, from string, to []string, err error) {
+	if from == "" {
+		return // 自动回复等空发件人的邮件
+	}
+	for _, b := range FromError(err, to) {
+		b.Sender = strings.ToLower(from)
+		r.record(ctx, b)
+	}
+}
+
+// RecordDSN 解析本地用户收到的退信报告并记录失败的收件人，返回记录的数量
+func (r *Recorder) RecordDSN(ctx context.Context, userEmail string, raw []byte) int {
+	reports, err := ParseDSN(raw)
+	if err != nil {
+		if !errors.Is(err, ErrNotDSN) {
+			logger.WarnCtx(ctx).Err(err).Str("user", userEmail).Msg("解析退信报告失败")
+		}
+		return 0
+	}
+	n := 0
+	for _, report := range reports {
+		if report.Action != "failed" {
+			continue
+		}
+		r.record(ctx, &storage.Bounce{
+			Sender:     strings.ToLower(userEmail),
+			Recipient:  report.Recipient,
+			Category:   Classify(report.Status, report.Diagnostic),
+			Status:     report.Status,
+			Diagnostic: report.Diagnostic,
+			Source:     SourceDSN,
+		})
+		n++
+	}
+	return n
+}
+
+// record 保存退信记录，失败时只记录日志（不影响邮件收发）
+func (r *Recorder) record(ctx context.Context, b *storage.Bounce) {
+	b.Recipient = strings.ToLower(b.Recipient)
+	if err := r.Storage.RecordBounce(ctx, b); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("sender", b.Sender).Str("recipient", b.Recipient).Msg("记录退信失败")
+	}
+}
@@ -0,0 +1,159 @@
+package bounce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status, diagnostic string
+		want               string
+	}{
+		{"5.1.1", "550 5.1.1 <bob@example.org>: Recipient address rejected", CategoryUserUnknown},
+		{"5.2.2", "552 5.2.2 Mailbox full", CategoryMailboxFull},
+		{"5.7.1", "550 5.7.1 Message rejected", CategorySpamBlock},
+		{"5.1.10", "556 5.1.10 Recipient address has null MX", CategoryDNSError},
+		{"5.4.4", "Unable to route", CategoryDNSError},
+		{"550", "Requested action not taken: mailbox unavailable", CategoryUserUnknown},
+		{"554", "Service unavailable; client host blocked using zen.spamhaus.org", CategorySpamBlock},
+		{"552", "Quota exceeded for this user", CategoryMailboxFull},
+		{"5.0.0", "Message too large", CategoryOther},
+		{"", "", CategoryOther},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.status, tt.diagnostic); got != tt.want {
+			t.Errorf("Classify(%q, %q) = %s, want %s", tt.status, tt.diagnostic, got, tt.want)
+		}
+	}
+}
+
+func TestFromError(t *testing.T) {
+	// 按域名组合的错误：永久拒收、域名不存在、临时错误、已通过 OnReject 报告的拒收
+	err := errors.Join(
+		&smtpclient.DeliveryError{Domain: "full.test", Recipients: []string{"a@full.test", "b@full.test"},
+			Err: fmt.Errorf("完成发送失败: %w", &textproto.Error{Code: 552, Msg: "5.2.2 Mailbox full"})},
+		&smtpclient.DeliveryError{Domain: "nowhere.test", Recipients: []string{"c@nowhere.test"},
+			Err: fmt.Errorf("查找 MX 记录失败: %w", &net.DNSError{Err: "no such host", Name: "nowhere.test", IsNotFound: true})},
+		&smtpclient.DeliveryError{Domain: "slow.test", Recipients: []string{"d@slow.test"},
+			Err: &textproto.Error{Code: 451, Msg: "4.3.0 Try again later"}},
+		&smtpclient.DeliveryError{Domain: "strict.test", Recipients: []string{"e@strict.test"},
+			Err: fmt.Errorf("全部 1 个%w", smtpclient.ErrRecipientRejected)},
+	)
+	bounces := FromError(err, nil)
+	got := make(map[string]string)
+	for _, b := range bounces {
+		got[b.Recipient] = b.Category + " " + b.Status
+	}
+	want := map[string]string{
+		"a@full.test":    "mailbox_full 5.2.2",
+		"b@full.test":    "mailbox_full 5.2.2",
+		"c@nowhere.test": "dns_error ",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("FromError() = %v, want %v", got, want)
+	}
+
+	// 单个收件人的拒收（OnReject）
+	bounces = FromError(&textproto.Error{Code: 550, Msg: "5.1.1 User unknown"}, []string{"x@example.org"})
+	if len(bounces) != 1 || bounces[0].Category != CategoryUserUnknown || bounces[0].Source != SourceSMTP {
+		t.Errorf("FromError(RCPT) = %+v", bounces)
+	}
+	if bounces := FromError(errors.New("连接 MX 服务器失败: timeout"), []string{"x@example.org"}); len(bounces) != 0 {
+		t.Errorf("连接失败不应该计为退信: %+v", bounces)
+	}
+}
+
+const dsn = "From: MAILER-DAEMON@mx.example.org\r\n" +
+	"To: alice@example.com\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Delivery failed.\r\n" +
+	"--b1\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx.example.org\r\n" +
+	"Arrival-Date: Mon, 9 Mar 2026 08:00:00 +0000\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; <Bob@Example.org>\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 User unknown\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; carol@example.org\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.2.2\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"Subject: hi\r\n" +
+	"\r\n" +
+	"hi\r\n" +
+	"--b1--\r\n"
+
+func TestParseDSN(t *testing.T) {
+	reports, err := ParseDSN([]byte(dsn))
+	if err != nil {
+		t.Fatalf("ParseDSN() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("reports = %d, want 2", len(reports))
+	}
+	if r := reports[0]; r.Recipient != "Bob@Example.org" || r.Action != "failed" || r.Status != "5.1.1" || r.Diagnostic != "550 5.1.1 User unknown" {
+		t.Errorf("reports[0] = %+v", r)
+	}
+	if reports[1].Action != "delayed" {
+		t.Errorf("reports[1] = %+v", reports[1])
+	}
+
+	if _, err := ParseDSN([]byte("Subject: hi\r\n\r\nbody\r\n")); !errors.Is(err, ErrNotDSN) {
+		t.Errorf("普通邮件 error = %v, want ErrNotDSN", err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+
+	r := &Recorder{Storage: driver}
+	if n := r.RecordDSN(ctx, "alice@example.com", []byte(dsn)); n != 1 {
+		t.Errorf("RecordDSN() = %d, want 1（只记录 failed）", n)
+	}
+	r.RecordError(ctx, "Alice@example.com", []string{"dave@spam.test"}, &textproto.Error{Code: 554, Msg: "5.7.1 Blocked by policy"})
+	r.RecordError(ctx, "", []string{"dave@spam.test"}, &textproto.Error{Code: 550, Msg: "5.1.1 User unknown"})
+
+	stats, err := driver.GetBounceStats(ctx, time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 2 || stats.Categories[CategoryUserUnknown] != 1 || stats.Categories[CategorySpamBlock] != 1 {
+		t.Errorf("退信统计 = %+v", stats)
+	}
+	if len(stats.Senders) != 1 || stats.Senders[0].Key != "alice@example.com" || stats.Senders[0].Total != 2 {
+		t.Errorf("按发件人统计 = %+v", stats.Senders)
+	}
+	if len(stats.Domains) != 2 || stats.Domains[0].Key != "example.org" {
+		t.Errorf("按域名统计 = %+v", stats.Domains)
+	}
+}
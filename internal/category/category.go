@@ -0,0 +1,327 @@
+// Package category 收件箱自动分类（主要/社交/推广/通知/论坛）
+//
+// 分类结果以 IMAP 关键字标志保存在邮件上。新邮件先用用户的朴素贝叶斯模型分类，
+// 模型样本不足或结果不够确定时使用邮件头规则；用户手动调整分类时训练该用户的模型。
+package category
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"strings"
+	"unicode"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 分类
+const (
+	Primary    = "primary"
+	Social     = "social"
+	Promotions = "promotions"
+	Updates    = "updates"
+	Forums     = "forums"
+)
+
+// All 所有分类
+var All = []string{Primary, Social, Promotions, Updates, Forums}
+
+// keywords 分类 -> IMAP 关键字标志
+var keywords = map[string]string{
+	Primary:    "$Primary",
+	Social:     "$Social",
+	Promotions: "$Promotions",
+	Updates:    "$Updates",
+	Forums:     "$Forums",
+}
+
+const (
+	// minTrainingDocs 使用贝叶斯模型所需的最少训练样本数
+	minTrainingDocs = 10
+	// minConfidence 采用贝叶斯结果的最低后验概率
+	minConfidence = 0.8
+	// maxTokens 每封邮件最多提取的特征数
+	maxTokens = 64
+)
+
+// ErrInvalid 未知的分类
+var ErrInvalid = errors.New("无效的分类")
+
+// socialDomains 社交网络通知的发件域名
+var socialDomains = []string{
+	"facebookmail.com", "linkedin.com", "twitter.com", "x.com", "instagram.com",
+	"pinterest.com", "tiktok.com", "weibo.com", "weibo.cn", "douban.com", "zhihu.com",
+	"mastodon.social", "discord.com", "slack.com",
+}
+
+// Valid 是否为已知分类
+func Valid(category string) bool {
+	_, ok := keywords[category]
+	return ok
+}
+
+// Keyword 返回分类对应的 IMAP 关键字标志
+func Keyword(category string) string {
+	return keywords[category]
+}
+
+// FromFlags 从邮件标志中取出分类，没有分类标志时返回空字符串
+func FromFlags(flags []string) string {
+	for _, flag := range flags {
+		for category, keyword := range keywords {
+			if strings.EqualFold(flag, keyword) {
+				return category
+			}
+		}
+	}
+	return ""
+}
+
+// SetFlags 将标志中的分类关键字替换为指定分类
+func SetFlags(flags []string, category string) []string {
+	result := make([]string, 0, len(flags)+1)
+	for _, flag := range flags {
+		if FromFlags([]string{flag}) == "" {
+			result = append(result, flag)
+		}
+	}
+	return append(result, Keyword(category))
+}
+
+// Classifier 邮件分类器
+type Classifier struct {
+	storage storage.Driver
+}
+
+// NewClassifier 创建分类器
+func NewClassifier(driver storage.Driver) *Classifier {
+	return &Classifier{storage: driver}
+}
+
+// Classify 为用户的新邮件分类，返回分类和提取的特征（投递后通过 SaveMailCategory 保存）
+func (c *Classifier) Classify(ctx context.Context, userEmail string, h message.Header) (string, []string) {
+	tokens := Tokens(h)
+	if category, ok := c.bayes(ctx, userEmail, tokens); ok {
+		return category, tokens
+	}
+	return Heuristic(h), tokens
+}
+
+// bayes 用用户的模型分类（多项式朴素贝叶斯，拉普拉斯平滑），样本不足或不够确定时返回 false
+func (c *Classifier) bayes(ctx context.Context, userEmail string, tokens []string) (string, bool) {
+	if len(tokens) == 0 {
+		return "", false
+	}
+	model, err := c.storage.GetCategoryModel(ctx, userEmail, tokens)
+	if err != nil {
+		return "", false
+	}
+	total := 0
+	for _, docs := range model.Docs {
+		total += docs
+	}
+	if total < minTrainingDocs {
+		return "", false
+	}
+
+	vocabulary := float64(model.Vocabulary + 1)
+	scores := make(map[string]float64)
+	best, bestScore := "", math.Inf(-1)
+	for _, category := range All {
+		docs := model.Docs[category]
+		if docs == 0 {
+			continue
+		}
+		score := math.Log(float64(docs) / float64(total))
+		denominator := float64(model.Tokens[category]) + vocabulary
+		for _, token := range tokens {
+			score += math.Log(float64(model.Counts[category][token]+1) / denominator)
+		}
+		scores[category] = score
+		if score > bestScore {
+			best, bestScore = category, score
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+
+	// 后验概率 = 1 / Σ exp(score_i - score_best)
+	var sum float64
+	for _, score := range scores {
+		sum += math.Exp(score - bestScore)
+	}
+	if 1/sum < minConfidence {
+		return "", false
+	}
+	return best, true
+}
+
+// Recategorize 记录用户对邮件的手动分类并训练该用户的模型（之前手动分类过时先撤销旧样本）
+func (c *Classifier) Recategorize(ctx context.Context, userEmail, mailID, category string) error {
+	if !Valid(category) {
+		return ErrInvalid
+	}
+	current, err := c.storage.GetMailCategory(ctx, mailID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		// 分类功能启用前的邮件没有特征，只记录分类
+		current = &storage.MailCategory{MailID: mailID}
+	}
+	if current.Trained && current.Category == category {
+		return nil
+	}
+
+	if current.Trained {
+		if err := c.storage.TrainCategory(ctx, userEmail, current.Category, current.Tokens, -1); err != nil {
+			return err
+		}
+	}
+	if len(current.Tokens) > 0 {
+		if err := c.storage.TrainCategory(ctx, userEmail, category, current.Tokens, 1); err != nil {
+			return err
+		}
+	}
+
+	current.Category = category
+	current.Trained = len(current.Tokens) > 0
+	if err := c.storage.SaveMailCategory(ctx, current); err != nil {
+		return fmt.Errorf("保存邮件分类失败: %w", err)
+	}
+	return nil
+}
+
+// Heuristic 根据邮件头规则分类
+func Heuristic(h message.Header) string {
+	domain := senderDomain(h)
+	for _, d := range socialDomains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return Social
+		}
+	}
+
+	// 讨论型邮件列表（可以回复到列表）
+	if h.Get("List-Id") != "" && h.Get("List-Post") != "" {
+		return Forums
+	}
+
+	autoSubmitted := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted")))
+	if autoSubmitted != "" && autoSubmitted != "no" {
+		return Updates
+	}
+
+	precedence := strings.ToLower(strings.TrimSpace(h.Get("Precedence")))
+	if h.Get("List-Unsubscribe") != "" || precedence == "bulk" ||
+		h.Get("Feedback-ID") != "" || h.Get("X-Campaign") != "" || h.Get("X-Mailchimp-Campaign") != "" {
+		return Promotions
+	}
+
+	if local := senderLocal(h); strings.Contains(local, "noreply") || strings.Contains(local, "no-reply") ||
+		strings.Contains(local, "notification") || strings.Contains(local, "alert") {
+		return Updates
+	}
+	return Primary
+}
+
+// Tokens 提取分类特征：发件人、发件域名、列表和群发相关邮件头、主题词
+func Tokens(h message.Header) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	add := func(token string) {
+		if token == "" || seen[token] || len(tokens) >= maxTokens {
+			return
+		}
+		seen[token] = true
+		tokens = append(tokens, token)
+	}
+
+	if addr := senderAddress(h); addr != "" {
+		add("from:" + addr)
+		add("domain:" + senderDomain(h))
+	}
+	for _, name := range []string{"List-Id", "List-Post", "List-Unsubscribe", "Feedback-ID", "Auto-Submitted", "X-Campaign"} {
+		if h.Get(name) != "" {
+			add("header:" + strings.ToLower(name))
+		}
+	}
+	if precedence := strings.ToLower(strings.TrimSpace(h.Get("Precedence"))); precedence != "" {
+		add("precedence:" + precedence)
+	}
+
+	subject, err := h.Text("Subject")
+	if err != nil {
+		subject = h.Get("Subject")
+	}
+	for _, word := range subjectWords(subject) {
+		add("subject:" + word)
+	}
+	return tokens
+}
+
+// subjectWords 切分主题：拉丁字母和数字按词（至少 2 个字符），汉字等按相邻两字
+func subjectWords(subject string) []string {
+	var words []string
+	var word, han []rune
+	flush := func() {
+		if len(word) >= 2 {
+			words = append(words, string(word))
+		}
+		if len(han) == 1 {
+			words = append(words, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			words = append(words, string(han[i:i+2]))
+		}
+		word, han = word[:0], han[:0]
+	}
+	for _, r := range strings.ToLower(subject) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// senderAddress 发件人地址（小写）
+func senderAddress(h message.Header) string {
+	from := strings.TrimSpace(h.Get("From"))
+	if from == "" {
+		return ""
+	}
+	if addr, err := mail.ParseAddress(from); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.Trim(from, "<>"))
+}
+
+// senderDomain 发件人域名
+func senderDomain(h message.Header) string {
+	addr := senderAddress(h)
+	return addr[strings.LastIndex(addr, "@")+1:]
+}
+
+// senderLocal 发件人地址的本地部分
+func senderLocal(h message.Header) string {
+	addr := senderAddress(h)
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[:i]
+	}
+	return addr
+}
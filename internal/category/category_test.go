@@ -0,0 +1,131 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func header(t *testing.T, raw string) message.Header {
+	t.Helper()
+	msg, err := message.Read(strings.NewReader(raw + "\r\nbody\r\n"))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+	return msg.Header
+}
+
+func TestHeuristic(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"普通邮件", "From: Alice <alice@example.com>\r\nSubject: lunch?\r\n", Primary},
+		{"社交网络", "From: LinkedIn <messages-noreply@linkedin.com>\r\nList-Unsubscribe: <https://linkedin.com/u>\r\n", Social},
+		{"讨论列表", "From: bob@example.com\r\nList-Id: <dev.lists.example.org>\r\nList-Post: <mailto:dev@lists.example.org>\r\nList-Unsubscribe: <mailto:dev-leave@lists.example.org>\r\n", Forums},
+		{"自动通知", "From: ci@example.com\r\nAuto-Submitted: auto-generated\r\n", Updates},
+		{"推广", "From: deals@shop.test\r\nList-Unsubscribe: <https://shop.test/u>\r\n", Promotions},
+		{"群发", "From: news@shop.test\r\nPrecedence: bulk\r\n", Promotions},
+		{"noreply 发件人", "From: no-reply@bank.test\r\nSubject: 账单\r\n", Updates},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Heuristic(header(t, tt.raw)); got != tt.want {
+				t.Errorf("Heuristic() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tokens := Tokens(header(t, "From: Shop <Deals@Shop.test>\r\nList-Unsubscribe: <https://shop.test/u>\r\nSubject: =?UTF-8?B?5Y+M5Y2B5LiA5aSn5L+D?= Sale 50% a\r\n"))
+	want := []string{"from:deals@shop.test", "domain:shop.test", "header:list-unsubscribe",
+		"subject:双十", "subject:十一", "subject:一大", "subject:大促", "subject:sale", "subject:50"}
+	if strings.Join(tokens, " ") != strings.Join(want, " ") {
+		t.Errorf("Tokens() = %v, want %v", tokens, want)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	flags := SetFlags([]string{"\\Seen", "$Promotions", "work"}, Primary)
+	if strings.Join(flags, " ") != "\\Seen work $Primary" {
+		t.Errorf("SetFlags() = %v", flags)
+	}
+	if got := FromFlags(flags); got != Primary {
+		t.Errorf("FromFlags() = %q", got)
+	}
+	if got := FromFlags([]string{"\\Seen"}); got != "" {
+		t.Errorf("FromFlags() = %q", got)
+	}
+}
+
+func TestClassifierTraining(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+
+	c := NewClassifier(driver)
+	const user = "me@example.com"
+	// 没有 List-Unsubscribe 的群组通知，规则判断为主要邮件
+	club := header(t, "From: Club <club@hiking.test>\r\nSubject: weekend hike plan\r\n")
+	if got, _ := c.Classify(ctx, user, club); got != Primary {
+		t.Fatalf("训练前 Classify() = %s, want %s", got, Primary)
+	}
+
+	// 用户把俱乐部邮件和好友邮件手动分类，训练模型
+	for i := 0; i < minTrainingDocs; i++ {
+		from, subject, want := "club@hiking.test", fmt.Sprintf("hike %d plan", i), Social
+		if i%2 == 1 {
+			from, subject, want = "alice@example.com", fmt.Sprintf("dinner %d", i), Primary
+		}
+		h := header(t, fmt.Sprintf("From: %s\r\nSubject: %s\r\n", from, subject))
+		id := fmt.Sprintf("m%d", i)
+		cat, tokens := c.Classify(ctx, user, h)
+		if err := driver.StoreMail(ctx, &storage.Mail{ID: id, UserEmail: user, Folder: "INBOX", From: from, Subject: subject,
+			Flags: []string{Keyword(cat)}, ReceivedAt: time.Now()}); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+		if err := driver.SaveMailCategory(ctx, &storage.MailCategory{MailID: id, Category: cat, Tokens: tokens}); err != nil {
+			t.Fatalf("保存邮件分类失败: %v", err)
+		}
+		if err := c.Recategorize(ctx, user, id, want); err != nil {
+			t.Fatalf("Recategorize() error = %v", err)
+		}
+	}
+
+	if got, _ := c.Classify(ctx, user, club); got != Social {
+		t.Errorf("训练后 Classify() = %s, want %s", got, Social)
+	}
+	// 其他用户的模型不受影响
+	if got, _ := c.Classify(ctx, "other@example.com", club); got != Primary {
+		t.Errorf("其他用户 Classify() = %s, want %s", got, Primary)
+	}
+
+	// 重新分类时撤销旧样本
+	if err := c.Recategorize(ctx, user, "m0", Updates); err != nil {
+		t.Fatalf("Recategorize() error = %v", err)
+	}
+	model, err := driver.GetCategoryModel(ctx, user, nil)
+	if err != nil {
+		t.Fatalf("GetCategoryModel() error = %v", err)
+	}
+	if model.Docs[Social] != minTrainingDocs/2-1 || model.Docs[Updates] != 1 {
+		t.Errorf("模型样本数不正确: %v", model.Docs)
+	}
+
+	if err := c.Recategorize(ctx, user, "m0", "spam"); err != ErrInvalid {
+		t.Errorf("未知分类应该返回 ErrInvalid, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Package chaos 故障注入：包装存储驱动和外发连接的拨号器，按配置注入延迟、错误和部分写入，
+// 用于验证投递、IMAP 等上层在存储和网络故障下能否正确恢复。
+//
+// 包装只在使用 -tags chaos 构建并启用 chaos 配置时安装（见 cmd/gmz/chaos.go），
+// 正常构建不会注入任何故障。
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInjected 注入的故障（可用 errors.Is 判断）
+var ErrInjected = errors.New("注入的故障")
+
+// Config 故障注入配置
+type Config struct {
+	Latency          time.Duration // 每次操作随机增加 0 ~ Latency 的延迟
+	ErrorRate        float64       // 操作失败的概率（0 ~ 1），失败时不执行操作
+	PartialWriteRate float64       // 部分写入的概率（0 ~ 1）：存储写操作已执行但返回错误，网络写入只写出一部分后断开
+	Seed             uint64        // 随机种子（0 时每次运行随机）
+}
+
+// Injector 按配置决定每次操作是否注入故障，可在多个协程中使用
+type Injector struct {
+	cfg     Config
+	enabled atomic.Bool
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// New 创建故障注入器（创建后即启用）
+func New(cfg Config) *Injector {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	in := &Injector{
+		cfg: cfg,
+		rnd: rand.New(rand.NewPCG(seed, seed>>1|1)),
+	}
+	in.enabled.Store(true)
+	return in
+}
+
+// SetEnabled 启用或暂停故障注入（测试中用于验证故障消失后能否恢复）
+func (in *Injector) SetEnabled(enabled bool) {
+	in.enabled.Store(enabled)
+}
+
+// float 返回 [0, 1) 的随机数
+func (in *Injector) float() float64 {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.rnd.Float64()
+}
+
+// delay 注入随机延迟（ctx 取消时提前返回 ctx 的错误）
+func (in *Injector) delay(ctx context.Context) error {
+	if in.cfg.Latency <= 0 {
+		return nil
+	}
+	d := time.Duration(in.float() * float64(in.cfg.Latency))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Inject 在操作 op 之前调用：注入延迟，并按 ErrorRate 返回注入的错误
+func (in *Injector) Inject(ctx context.Context, op string) error {
+	if !in.enabled.Load() {
+		return nil
+	}
+	if err := in.delay(ctx); err != nil {
+		return err
+	}
+	if in.cfg.ErrorRate > 0 && in.float() < in.cfg.ErrorRate {
+		return fmt.Errorf("%s: %w", op, ErrInjected)
+	}
+	return nil
+}
+
+// Partial 是否对本次写操作注入部分写入
+func (in *Injector) Partial() bool {
+	return in.enabled.Load() && in.cfg.PartialWriteRate > 0 && in.float() < in.cfg.PartialWriteRate
+}
+
+// partialError 部分写入返回的错误
+func partialError(op string) error {
+	return fmt.Errorf("%s: 部分写入: %w", op, ErrInjected)
+}
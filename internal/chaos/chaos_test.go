@@ -0,0 +1,120 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestInjector(t *testing.T) {
+	ctx := context.Background()
+
+	always := New(Config{ErrorRate: 1, Seed: 1})
+	if err := always.Inject(ctx, "op"); !errors.Is(err, ErrInjected) {
+		t.Errorf("ErrorRate 为 1 时应该返回注入的错误, got %v", err)
+	}
+	always.SetEnabled(false)
+	if err := always.Inject(ctx, "op"); err != nil {
+		t.Errorf("暂停后不应该注入故障, got %v", err)
+	}
+
+	never := New(Config{Seed: 1})
+	for i := 0; i < 100; i++ {
+		if err := never.Inject(ctx, "op"); err != nil {
+			t.Fatalf("ErrorRate 为 0 时不应该注入错误, got %v", err)
+		}
+	}
+
+	// 相同种子的故障序列相同
+	a, b := New(Config{ErrorRate: 0.5, Seed: 42}), New(Config{ErrorRate: 0.5, Seed: 42})
+	for i := 0; i < 50; i++ {
+		if (a.Inject(ctx, "op") == nil) != (b.Inject(ctx, "op") == nil) {
+			t.Fatal("相同种子应该产生相同的故障序列")
+		}
+	}
+
+	// 注入的延迟不超过 ctx 的期限
+	slow := New(Config{Latency: time.Hour, Seed: 1})
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := slow.Inject(timeout, "op"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ctx 超时后应该返回 DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWrapDriver(t *testing.T) {
+	ctx := context.Background()
+	base, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer base.Close()
+	if err := base.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	mail := func(id string) *storage.Mail {
+		return &storage.Mail{ID: id, UserEmail: "alice@example.com", Folder: "INBOX", From: "bob@example.com", ReceivedAt: time.Now()}
+	}
+
+	failing := WrapDriver(base, New(Config{ErrorRate: 1, Seed: 1}))
+	if err := failing.StoreMail(ctx, mail("failed")); !errors.Is(err, ErrInjected) {
+		t.Errorf("StoreMail() error = %v, want ErrInjected", err)
+	}
+	if _, err := base.GetMail(ctx, "failed"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("注入错误时不应该执行写入, got %v", err)
+	}
+
+	// 部分写入：已经写入但返回错误
+	partial := WrapDriver(base, New(Config{PartialWriteRate: 1, Seed: 1}))
+	if err := partial.StoreMail(ctx, mail("partial")); !errors.Is(err, ErrInjected) {
+		t.Errorf("StoreMail() error = %v, want ErrInjected", err)
+	}
+	if _, err := base.GetMail(ctx, "partial"); err != nil {
+		t.Errorf("部分写入时记录应该已经写入: %v", err)
+	}
+
+	// 没有注入故障的操作直接调用原驱动
+	if _, err := failing.ListDomains(ctx); err != nil {
+		t.Errorf("ListDomains() error = %v", err)
+	}
+}
+
+func TestWrapDialer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	d := WrapDialer(&net.Dialer{}, New(Config{PartialWriteRate: 1, Seed: 1}))
+	conn, err := d.DialContext(context.Background(), "tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := conn.Write([]byte("0123456789")); !errors.Is(err, ErrInjected) || n != 5 {
+		t.Errorf("Write() = %d, %v, want 5, ErrInjected", n, err)
+	}
+	if data := <-received; string(data) != "01234" {
+		t.Errorf("对端收到 %q, want %q", data, "01234")
+	}
+
+	refused := WrapDialer(&net.Dialer{}, New(Config{ErrorRate: 1, Seed: 1}))
+	if _, err := refused.DialContext(context.Background(), "tcp", lis.Addr().String()); !errors.Is(err, ErrInjected) {
+		t.Errorf("DialContext() error = %v, want ErrInjected", err)
+	}
+}
@@ -0,0 +1,67 @@
+package chaos
+
+import (
+	"context"
+	"net"
+)
+
+// ContextDialer 拨号器（与 smtpclient.ContextDialer 相同）
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// dialer 注入故障的拨号器
+type dialer struct {
+	next ContextDialer
+	in   *Injector
+}
+
+// WrapDialer 包装拨号器：拨号可能失败，建立的连接读写时注入延迟、错误和部分写入
+func WrapDialer(d ContextDialer, in *Injector) ContextDialer {
+	return &dialer{next: d, in: in}
+}
+
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if err := d.in.Inject(ctx, "dial "+address); err != nil {
+		return nil, err
+	}
+	c, err := d.next.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, in: d.in}, nil
+}
+
+// conn 注入故障的连接
+type conn struct {
+	net.Conn
+	in *Injector
+}
+
+// inject 读写前注入故障，注入错误时关闭连接（模拟连接被重置）
+func (c *conn) inject(op string) error {
+	if err := c.in.Inject(context.Background(), op); err != nil {
+		_ = c.Conn.Close()
+		return err
+	}
+	return nil
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if err := c.inject("read"); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	if err := c.inject("write"); err != nil {
+		return 0, err
+	}
+	if len(b) > 1 && c.in.Partial() {
+		n, _ := c.Conn.Write(b[:len(b)/2])
+		_ = c.Conn.Close()
+		return n, partialError("write")
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,110 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// driver 注入故障的存储驱动：邮件投递和读取路径上的操作注入故障，其余操作直接调用原驱动
+type driver struct {
+	storage.Driver
+	in *Injector
+}
+
+// WrapDriver 包装存储驱动
+func WrapDriver(d storage.Driver, in *Injector) storage.Driver {
+	return &driver{Driver: d, in: in}
+}
+
+// write 执行写操作：可能在执行前失败，或执行后仍返回错误（调用方无法确定是否已写入）
+func (d *driver) write(ctx context.Context, op string, fn func() error) error {
+	if err := d.in.Inject(ctx, op); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	if d.in.Partial() {
+		return partialError(op)
+	}
+	return nil
+}
+
+func (d *driver) GetUser(ctx context.Context, email string) (*storage.User, error) {
+	if err := d.in.Inject(ctx, "GetUser"); err != nil {
+		return nil, err
+	}
+	return d.Driver.GetUser(ctx, email)
+}
+
+func (d *driver) StoreMail(ctx context.Context, mail *storage.Mail) error {
+	return d.write(ctx, "StoreMail", func() error { return d.Driver.StoreMail(ctx, mail) })
+}
+
+func (d *driver) GetMail(ctx context.Context, id string) (*storage.Mail, error) {
+	if err := d.in.Inject(ctx, "GetMail"); err != nil {
+		return nil, err
+	}
+	return d.Driver.GetMail(ctx, id)
+}
+
+func (d *driver) GetMailBody(ctx context.Context, userEmail, folder, mailID string) ([]byte, error) {
+	if err := d.in.Inject(ctx, "GetMailBody"); err != nil {
+		return nil, err
+	}
+	return d.Driver.GetMailBody(ctx, userEmail, folder, mailID)
+}
+
+func (d *driver) ListMails(ctx context.Context, userEmail, folder string, limit, offset int) ([]*storage.Mail, error) {
+	if err := d.in.Inject(ctx, "ListMails"); err != nil {
+		return nil, err
+	}
+	return d.Driver.ListMails(ctx, userEmail, folder, limit, offset)
+}
+
+func (d *driver) CountMails(ctx context.Context, userEmail, folder string) (int, error) {
+	if err := d.in.Inject(ctx, "CountMails"); err != nil {
+		return 0, err
+	}
+	return d.Driver.CountMails(ctx, userEmail, folder)
+}
+
+func (d *driver) ListMailIDs(ctx context.Context, userEmail, folder string, afterUID uint32, limit int) ([]*storage.Mail, error) {
+	if err := d.in.Inject(ctx, "ListMailIDs"); err != nil {
+		return nil, err
+	}
+	return d.Driver.ListMailIDs(ctx, userEmail, folder, afterUID, limit)
+}
+
+func (d *driver) DeleteMail(ctx context.Context, id string) error {
+	return d.write(ctx, "DeleteMail", func() error { return d.Driver.DeleteMail(ctx, id) })
+}
+
+func (d *driver) UpdateMailFlags(ctx context.Context, id string, flags []string) error {
+	return d.write(ctx, "UpdateMailFlags", func() error { return d.Driver.UpdateMailFlags(ctx, id, flags) })
+}
+
+func (d *driver) MoveMail(ctx context.Context, id, folder string) (uint32, error) {
+	var uid uint32
+	err := d.write(ctx, "MoveMail", func() error {
+		var err error
+		uid, err = d.Driver.MoveMail(ctx, id, folder)
+		return err
+	})
+	return uid, err
+}
+
+func (d *driver) AllocateUID(ctx context.Context, userEmail, folder string) (uint32, error) {
+	if err := d.in.Inject(ctx, "AllocateUID"); err != nil {
+		return 0, err
+	}
+	return d.Driver.AllocateUID(ctx, userEmail, folder)
+}
+
+func (d *driver) GetMailboxUIDs(ctx context.Context, userEmail, folder string) (*storage.MailboxUIDs, error) {
+	if err := d.in.Inject(ctx, "GetMailboxUIDs"); err != nil {
+		return nil, err
+	}
+	return d.Driver.GetMailboxUIDs(ctx, userEmail, folder)
+}
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/viper"
@@ -23,6 +29,38 @@ type Config struct {
 	Admin    AdminConfig    `yaml:"admin" mapstructure:"admin"`
 	Log      LogConfig      `yaml:"log" mapstructure:"log"`
 	Metrics  MetricsConfig  `yaml:"metrics" mapstructure:"metrics"`
+	// Fetchmail 定期从用户的外部 POP3/IMAP 账户拉取邮件
+	Fetchmail FetchmailConfig `yaml:"fetchmail" mapstructure:"fetchmail"`
+	// Provisioning 启动时应用的声明式初始化数据（域名、用户、别名、配额）
+	Provisioning ProvisioningConfig `yaml:"provisioning" mapstructure:"provisioning"`
+	// LMTP 本地投递（Postfix、Exim 等外部 MTA 负责收发，通过 LMTP 投递到 gmz 的邮箱）
+	LMTP LMTPConfig `yaml:"lmtp" mapstructure:"lmtp"`
+	// Replication Maildir 热备复制（主备模式，不需要共享存储）
+	Replication ReplicationConfig `yaml:"replication" mapstructure:"replication"`
+	// Limits 按 IP 的连接数限制和认证失败封禁（SMTP、IMAP、WebMail 和管理 API 登录共享）
+	Limits LimitsConfig `yaml:"limits" mapstructure:"limits"`
+	// SASL SMTP 和 IMAP 在 PLAIN 之外提供的认证机制
+	SASL SASLConfig `yaml:"sasl" mapstructure:"sasl"`
+	// Quota 配额预警（用量超过阈值时向用户发送预警邮件）
+	Quota QuotaConfig `yaml:"quota" mapstructure:"quota"`
+	// Digest 邮件摘要（按用户设置每天或每周投递未读邮件和垃圾邮件的汇总）
+	Digest DigestConfig `yaml:"digest" mapstructure:"digest"`
+	// Warmup 外发预热（新 IP 或域名按周逐步提高每个目标邮件服务商的每日外发上限）
+	Warmup WarmupConfig `yaml:"warmup" mapstructure:"warmup"`
+	// WebPush 新邮件推送通知（浏览器和 PWA 订阅后，收件箱收到新邮件时推送加密的通知）
+	WebPush WebPushConfig `yaml:"webpush" mapstructure:"webpush"`
+	// ContactForm 公开联系表单（网站的联系表单提交到 POST /api/public/contact/:domain，通过人机验证后投递到配置的邮箱）
+	ContactForm ContactFormConfig `yaml:"contact_form" mapstructure:"contact_form"`
+	// Activity 账户活动记录（登录历史和从账户发出的邮件），用户通过 GET /api/activity 查看
+	Activity ActivityConfig `yaml:"activity" mapstructure:"activity"`
+	// Banner 登录提示和使用条款（WebMail 和管理后台登录前显示，配置条款时需要接受当前版本才能登录）
+	Banner BannerConfig `yaml:"banner" mapstructure:"banner"`
+	// WebAuthn 通行密钥和安全密钥（用户在 WebMail 中注册后，WebMail 和管理后台登录时可以代替 TOTP 作为第二因素）
+	WebAuthn WebAuthnConfig `yaml:"webauthn" mapstructure:"webauthn"`
+	// ShutdownTimeout 优雅停止的最长时间（等待进行中的 SMTP 事务、IMAP 命令和后台任务完成）
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" mapstructure:"shutdown_timeout"`
+	// Chaos 故障注入测试（仅在 -tags chaos 构建时生效，不要在生产环境启用）
+	Chaos ChaosConfig `yaml:"chaos" mapstructure:"chaos"`
 }
 
 // TLSConfig TLS 配置
@@ -32,6 +70,47 @@ type TLSConfig struct {
 	KeyFile    string     `yaml:"key_file" mapstructure:"key_file"`
 	ACME       ACMEConfig `yaml:"acme" mapstructure:"acme"`
 	MinVersion string     `yaml:"min_version" mapstructure:"min_version"`
+	// 会话恢复：服务端发放 session ticket，客户端缓存会话以便复用握手
+	SessionTickets         bool `yaml:"session_tickets" mapstructure:"session_tickets"`
+	ClientSessionCacheSize int  `yaml:"client_session_cache_size" mapstructure:"client_session_cache_size"`
+	// EarlyData TLS 1.3 0-RTT（Go 标准库暂不支持，启用时仅记录警告）
+	EarlyData bool `yaml:"early_data" mapstructure:"early_data"`
+	// Mail SMTP/IMAP 使用的 TLS 策略（留空则继承 min_version）
+	Mail TLSPolicyConfig `yaml:"mail" mapstructure:"mail"`
+	// HTTP WebMail/管理 API 使用的 TLS 策略
+	HTTP HTTPTLSConfig `yaml:"http" mapstructure:"http"`
+	// SelfSigned 未配置证书和 ACME 时自动生成自签名证书（仅用于实验环境）
+	SelfSigned SelfSignedConfig `yaml:"self_signed" mapstructure:"self_signed"`
+	// SNI 按主机名选择证书（多域名共用同一监听端口）
+	SNI []SNICertConfig `yaml:"sni" mapstructure:"sni"`
+	// WatchFiles 监听证书文件（cert_file 和 sni 中的证书）变化，更新后无需重启即可生效
+	WatchFiles bool `yaml:"watch_files" mapstructure:"watch_files"`
+}
+
+// SelfSignedConfig 自签名证书配置
+type SelfSignedConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	Dir     string `yaml:"dir" mapstructure:"dir"` // 证书保存目录（重启后复用）
+}
+
+// SNICertConfig 单个主机名的证书配置
+type SNICertConfig struct {
+	Hostname string `yaml:"hostname" mapstructure:"hostname"` // 主机名，支持 *.example.com 通配
+	CertFile string `yaml:"cert_file" mapstructure:"cert_file"`
+	KeyFile  string `yaml:"key_file" mapstructure:"key_file"`
+	ACME     bool   `yaml:"acme" mapstructure:"acme"` // 通过 ACME 自动申请（需启用 tls.acme）
+}
+
+// TLSPolicyConfig TLS 协议版本和加密套件策略
+type TLSPolicyConfig struct {
+	MinVersion   string   `yaml:"min_version" mapstructure:"min_version"`     // 1.0, 1.1, 1.2, 1.3
+	CipherSuites []string `yaml:"cipher_suites" mapstructure:"cipher_suites"` // Go 标准套件名，留空使用默认列表
+}
+
+// HTTPTLSConfig HTTP 服务 TLS 配置
+type HTTPTLSConfig struct {
+	Enabled         bool `yaml:"enabled" mapstructure:"enabled"` // WebMail 和管理 API 是否直接提供 HTTPS
+	TLSPolicyConfig `yaml:",inline" mapstructure:",squash"`
 }
 
 // ACMEConfig ACME 配置
@@ -40,6 +119,44 @@ type ACMEConfig struct {
 	Email    string `yaml:"email" mapstructure:"email"`
 	Dir      string `yaml:"dir" mapstructure:"dir"`
 	Provider string `yaml:"provider" mapstructure:"provider"` // letsencrypt, zerossl
+	// Storage 账户密钥和证书的存储位置：file（dir 目录）或 database（存储驱动，多节点共享）
+	Storage string `yaml:"storage" mapstructure:"storage"`
+	// EncryptionKey 数据库存储时用于加密私钥的口令
+	EncryptionKey string `yaml:"encryption_key" mapstructure:"encryption_key" redact:"true"`
+	// Challenge 域名验证方式：http-01、tls-alpn-01 或 dns-01（通配符证书必须使用 dns-01）
+	Challenge string `yaml:"challenge" mapstructure:"challenge"`
+	// ChallengeAddr HTTP-01/TLS-ALPN-01 验证期间临时监听的地址（默认 :80 / :443）
+	// TLS-ALPN-01 的端口已被 gmz 自己的 HTTPS 监听占用时，由该监听响应验证
+	ChallengeAddr string `yaml:"challenge_addr" mapstructure:"challenge_addr"`
+	// RenewBefore 证书到期前多久开始续期
+	RenewBefore time.Duration `yaml:"renew_before" mapstructure:"renew_before"`
+	// CheckInterval 续期检查间隔（失败后在下次检查时重试）
+	CheckInterval time.Duration `yaml:"check_interval" mapstructure:"check_interval"`
+	DNS           ACMEDNSConfig `yaml:"dns" mapstructure:"dns"`
+}
+
+// ACMEDNSConfig DNS-01 验证配置
+type ACMEDNSConfig struct {
+	Provider string `yaml:"provider" mapstructure:"provider"` // cloudflare, rfc2136
+	// PropagationTimeout 等待 TXT 记录生效的最长时间
+	PropagationTimeout time.Duration       `yaml:"propagation_timeout" mapstructure:"propagation_timeout"`
+	Cloudflare         CloudflareDNSConfig `yaml:"cloudflare" mapstructure:"cloudflare"`
+	RFC2136            RFC2136DNSConfig    `yaml:"rfc2136" mapstructure:"rfc2136"`
+}
+
+// CloudflareDNSConfig Cloudflare DNS 配置
+type CloudflareDNSConfig struct {
+	// APIToken 需要 Zone.DNS 编辑权限
+	APIToken string `yaml:"api_token" mapstructure:"api_token" redact:"true"`
+}
+
+// RFC2136DNSConfig 动态 DNS 更新（RFC 2136）配置，适用于 BIND、Knot、PowerDNS 等
+type RFC2136DNSConfig struct {
+	Server        string `yaml:"server" mapstructure:"server"`                         // 主 DNS 服务器地址（host:port，默认端口 53）
+	Zone          string `yaml:"zone" mapstructure:"zone"`                             // 区域名，留空时根据 NS 记录自动查找
+	TSIGKey       string `yaml:"tsig_key" mapstructure:"tsig_key"`                     // TSIG 密钥名称
+	TSIGSecret    string `yaml:"tsig_secret" mapstructure:"tsig_secret" redact:"true"` // Base64 编码的 TSIG 密钥
+	TSIGAlgorithm string `yaml:"tsig_algorithm" mapstructure:"tsig_algorithm"`         // hmac-sha256（默认）、hmac-sha512、hmac-sha1
 }
 
 // StorageConfig 存储配置
@@ -47,7 +164,40 @@ type StorageConfig struct {
 	Driver      string `yaml:"driver" mapstructure:"driver"` // sqlite, postgres
 	DSN         string `yaml:"dsn" mapstructure:"dsn"`
 	MaildirRoot string `yaml:"maildir_root" mapstructure:"maildir_root"`
-	AutoMigrate bool   `yaml:"auto_migrate" mapstructure:"auto_migrate"`
+	// MaildirSharding 用户目录的分片层数：0 为不分片（默认），1-3 层时按用户邮箱的哈希值分为多级子目录
+	// （2 层为 maildir_root/ab/cd/user@example.com），修改后用 gmz maildir reshard 迁移已有的用户目录
+	MaildirSharding int  `yaml:"maildir_sharding" mapstructure:"maildir_sharding"`
+	AutoMigrate     bool `yaml:"auto_migrate" mapstructure:"auto_migrate"`
+	// WatchMaildir 监视 Maildir：索引外部投递代理（procmail、OpenSMTPD 等）直接写入的邮件，
+	// 并向 IMAP IDLE 和 WebMail 推送新邮件通知
+	WatchMaildir bool `yaml:"watch_maildir" mapstructure:"watch_maildir"`
+	// ZeroAccess 零访问存储：用户在 WebMail 中启用后，邮件用只有用户密码（或恢复密钥）能解开的密钥加密保存
+	ZeroAccess ZeroAccessConfig `yaml:"zero_access" mapstructure:"zero_access"`
+	// RPC 拆分部署：存储节点通过 gRPC 提供存储服务，协议前端（driver: remote）连接存储节点
+	RPC StorageRPCConfig `yaml:"rpc" mapstructure:"rpc"`
+}
+
+// StorageRPCConfig 存储服务配置（前端和存储节点挂载同一个 maildir_root）
+type StorageRPCConfig struct {
+	// Enabled 存储节点：通过 gRPC 向协议前端提供存储服务
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	Listen  string `yaml:"listen" mapstructure:"listen"` // 存储服务监听地址
+	// Address 协议前端：driver 为 remote 时连接的存储节点地址（host:port）
+	Address string `yaml:"address" mapstructure:"address"`
+	// Token 存储节点和前端共享的认证令牌
+	Token  string `yaml:"token" mapstructure:"token" redact:"true"`
+	TLS    bool   `yaml:"tls" mapstructure:"tls"`         // 使用 TLS（存储节点使用服务器证书）
+	CAFile string `yaml:"ca_file" mapstructure:"ca_file"` // 前端校验存储节点证书的 CA（存储节点使用自签名证书时配置）
+}
+
+// ZeroAccessConfig 零访问存储配置
+//
+// 服务器只在用户用密码登录 IMAP 或 WebMail 之后的 UnlockTTL 内持有解密邮件的私钥；邮件正文不能在服务器端
+// 处理（垃圾邮件训练、附件索引等），管理员重置密码后用户需要用恢复密钥找回邮件
+type ZeroAccessConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// UnlockTTL 解锁的私钥在最后一次使用后保留的时间（默认 1h）
+	UnlockTTL time.Duration `yaml:"unlock_ttl" mapstructure:"unlock_ttl"`
 }
 
 // SMTPConfig SMTP 配置
@@ -60,6 +210,147 @@ type SMTPConfig struct {
 	Relay RelayConfig `yaml:"relay" mapstructure:"relay"`
 	// DKIM 配置（用于直接投递时提高发送成功率）
 	DKIM DKIMConfig `yaml:"dkim" mapstructure:"dkim"`
+	// Auth 认证策略（AUTH 只在加密连接上提供）
+	Auth SMTPAuthConfig `yaml:"auth" mapstructure:"auth"`
+	// RequireTLSPorts 要求先执行 STARTTLS 才能 MAIL FROM 的端口（通常为提交端口 587）
+	RequireTLSPorts []int `yaml:"require_tls_ports" mapstructure:"require_tls_ports"`
+	// SubmissionPorts 提交端口（必须认证，发件人必须属于认证用户，外部收件人经外发路径投递，默认 465 和 587）
+	SubmissionPorts []int `yaml:"submission_ports" mapstructure:"submission_ports"`
+	// Banner 欢迎横幅文本（跟在主机名之后，如 "Example Mail"）
+	Banner string `yaml:"banner" mapstructure:"banner"`
+	// Listeners 按端口覆盖主机名和横幅（同一台服务器服务多个品牌时使用）
+	Listeners []SMTPListenerConfig `yaml:"listeners" mapstructure:"listeners"`
+	// VRFY VRFY/EXPN 策略：deny（默认，始终返回 252）或 admin（认证的管理员可以查询地址和展开别名）
+	VRFY string `yaml:"vrfy" mapstructure:"vrfy"`
+	// BATV 退信地址验证：外发邮件的信封发件人签名，发往启用域名的退信必须使用签名地址
+	BATV BATVConfig `yaml:"batv" mapstructure:"batv"`
+	// SRSSecret 外部转发时 SRS 发件人重写的密钥（多节点部署时必须一致，留空时每次启动随机生成）
+	SRSSecret string `yaml:"srs_secret" mapstructure:"srs_secret" redact:"true"`
+	// Identities 外部发件身份（用户以外部地址发信时通过该地址的 SMTP 服务器提交）
+	Identities IdentitiesConfig `yaml:"identities" mapstructure:"identities"`
+	// Inbound 接收外部邮件（MX）的端口及其 TLS、认证、大小、反垃圾和限流设置
+	Inbound SMTPInboundConfig `yaml:"inbound" mapstructure:"inbound"`
+	// Submission 用户提交邮件的端口及其设置；inbound 或 submission 配置了端口时，
+	// 监听端口由这两部分决定，忽略 ports、submission_ports 和 require_tls_ports（旧配置方式）
+	Submission SMTPSubmissionConfig `yaml:"submission" mapstructure:"submission"`
+	// Transcripts 外发会话记录：保留发往指定域名或指定邮件的完整 SMTP 会话，收件方否认收到时举证
+	Transcripts TranscriptConfig `yaml:"transcripts" mapstructure:"transcripts"`
+	// Timeouts 会话超时：超时的连接被断开（包括客户端已经消失的半开连接）
+	Timeouts SMTPTimeoutsConfig `yaml:"timeouts" mapstructure:"timeouts"`
+}
+
+// SMTPTimeoutsConfig SMTP 会话超时（0 不限制）
+type SMTPTimeoutsConfig struct {
+	// Command 等待客户端下一条命令的时间（默认 5m，RFC 5321 4.5.3.2 建议至少 5 分钟）
+	Command time.Duration `yaml:"command" mapstructure:"command"`
+	// Data DATA 期间等待下一块数据的时间（默认 3m）
+	Data time.Duration `yaml:"data" mapstructure:"data"`
+	// Idle 连接没有开始邮件事务的最长时间（默认 10m），期间的 NOOP、RSET 等保活命令不会延长
+	Idle time.Duration `yaml:"idle" mapstructure:"idle"`
+}
+
+// TranscriptConfig 外发会话记录配置（只记录直接投递到 MX 的会话，邮件内容不记录）
+type TranscriptConfig struct {
+	Enabled    bool     `yaml:"enabled" mapstructure:"enabled"`
+	Domains    []string `yaml:"domains" mapstructure:"domains"`         // 记录发往这些收件人域名的会话
+	MessageIDs []string `yaml:"message_ids" mapstructure:"message_ids"` // 记录这些 Message-ID 的邮件的会话
+	// RetentionDays 会话记录保留天数
+	RetentionDays int `yaml:"retention_days" mapstructure:"retention_days"`
+}
+
+// SMTPInboundConfig 接收外部邮件（MX）的端口配置
+type SMTPInboundConfig struct {
+	Ports      []int  `yaml:"ports" mapstructure:"ports"`             // 监听端口（通常为 25）
+	TLS        bool   `yaml:"tls" mapstructure:"tls"`                 // 提供 STARTTLS（需要启用 tls.enabled）
+	RequireTLS bool   `yaml:"require_tls" mapstructure:"require_tls"` // 要求先执行 STARTTLS 才能 MAIL FROM
+	Auth       bool   `yaml:"auth" mapstructure:"auth"`               // 提供 AUTH（MX 端口通常不需要）
+	MaxSize    string `yaml:"max_size" mapstructure:"max_size"`       // 最大邮件大小（留空使用 smtp.max_size）
+	AntiSpam   bool   `yaml:"antispam" mapstructure:"antispam"`       // 对失败的连接减速（antispam.tarpit 启用时生效）
+	RateLimit  bool   `yaml:"rate_limit" mapstructure:"rate_limit"`   // 按 IP 限制连接数并拒绝被封禁的 IP（limits 启用时生效）
+}
+
+// SMTPSubmissionConfig 用户提交邮件的端口配置（必须认证，发件人必须属于认证用户）
+type SMTPSubmissionConfig struct {
+	Ports      []int  `yaml:"ports" mapstructure:"ports"`             // 监听端口（通常为 465 和 587，465 使用隐式 TLS）
+	TLS        bool   `yaml:"tls" mapstructure:"tls"`                 // 提供 TLS（需要启用 tls.enabled）
+	RequireTLS bool   `yaml:"require_tls" mapstructure:"require_tls"` // 要求先执行 STARTTLS 才能 MAIL FROM
+	MaxSize    string `yaml:"max_size" mapstructure:"max_size"`       // 最大邮件大小（留空使用 smtp.max_size）
+	AntiSpam   bool   `yaml:"antispam" mapstructure:"antispam"`       // 对认证失败的连接减速（antispam.tarpit 启用时生效）
+	RateLimit  bool   `yaml:"rate_limit" mapstructure:"rate_limit"`   // 按 IP 限制连接数并拒绝被封禁的 IP（limits 启用时生效）
+}
+
+// Sectioned 是否使用 inbound 和 submission 配置监听端口
+func (c *SMTPConfig) Sectioned() bool {
+	return len(c.Inbound.Ports) > 0 || len(c.Submission.Ports) > 0
+}
+
+// applySections 使用 inbound 和 submission 时，由这两部分生成监听端口、提交端口和要求 STARTTLS 的端口，
+// 其余代码只需要处理按端口的配置
+func (c *SMTPConfig) applySections() {
+	if !c.Sectioned() {
+		return
+	}
+	c.Ports = append(append([]int(nil), c.Inbound.Ports...), c.Submission.Ports...)
+	c.SubmissionPorts = append([]int(nil), c.Submission.Ports...)
+	c.RequireTLSPorts = nil
+	if c.Inbound.RequireTLS {
+		c.RequireTLSPorts = append(c.RequireTLSPorts, c.Inbound.Ports...)
+	}
+	if c.Submission.RequireTLS {
+		c.RequireTLSPorts = append(c.RequireTLSPorts, c.Submission.Ports...)
+	}
+}
+
+// BATVConfig 退信地址验证（BATV，prvs=KDDDSSSSSS=local@domain）配置
+type BATVConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// Domains 启用 BATV 的域名：外发时签名这些域名的发件人，发往这些域名的退信（空发件人）
+	// 不是有效的签名地址时在 RCPT TO 拒收
+	Domains []string `yaml:"domains" mapstructure:"domains"`
+	// Keys 签名密钥（多节点部署时必须一致）：第一个密钥用于签名，其余密钥只用于验证；
+	// 轮换时把新密钥放在最前面，旧密钥保留到之前签名的地址过期（7 天）
+	Keys []BATVKeyConfig `yaml:"keys" mapstructure:"keys"`
+}
+
+// BATVKeyConfig BATV 签名密钥
+type BATVKeyConfig struct {
+	ID     int    `yaml:"id" mapstructure:"id"`                       // 密钥编号（0 ~ 9，写入签名地址）
+	Secret string `yaml:"secret" mapstructure:"secret" redact:"true"` // HMAC 密钥
+}
+
+// IdentitiesConfig 外部发件身份配置
+type IdentitiesConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// EncryptionKey 加密外部 SMTP 密码的口令（更改后已保存的身份需要重新填写密码）
+	EncryptionKey string `yaml:"encryption_key" mapstructure:"encryption_key" redact:"true"`
+	// AllowPrivateHosts 允许连接内网地址的 SMTP 服务器（默认只允许公网地址）
+	AllowPrivateHosts bool `yaml:"allow_private_hosts" mapstructure:"allow_private_hosts"`
+}
+
+// SMTPListenerConfig 单个 SMTP 监听端口的配置
+type SMTPListenerConfig struct {
+	Port     int    `yaml:"port" mapstructure:"port"`         // 监听端口（必须在 smtp.ports 中）
+	Hostname string `yaml:"hostname" mapstructure:"hostname"` // 横幅、EHLO 和 Received 头使用的主机名（留空使用 smtp.hostname）
+	Banner   string `yaml:"banner" mapstructure:"banner"`     // 横幅文本（留空使用 smtp.banner）
+	// TrustedUpstreams 受信任的上游过滤网关（IP 或网段）：来自这些地址的邮件使用网关添加的
+	// Authentication-Results（或 ARC-Authentication-Results）验证结果，不再对网关 IP 检查 SPF
+	TrustedUpstreams []string `yaml:"trusted_upstreams" mapstructure:"trusted_upstreams"`
+	// TrustedAuthServIDs 网关验证结果中的 authserv-id（通常是网关主机名）；
+	// 配置后只使用带有这些 ID 的验证结果，并删除其他来源的邮件中伪造的同名验证结果
+	TrustedAuthServIDs []string `yaml:"trusted_authserv_ids" mapstructure:"trusted_authserv_ids"`
+	// GreetDelay 发送欢迎横幅前的等待时间（如 5s，0 不等待，只用于 MX 端口）：期间发送数据的客户端
+	// 没有按协议等待横幅（early talker），按 EarlyTalker 处理
+	GreetDelay time.Duration `yaml:"greet_delay" mapstructure:"greet_delay"`
+	// EarlyTalker 抢先发送的客户端的处理方式：reject（默认，回复 554 并断开）或 junk（接收但标记为垃圾邮件）
+	EarlyTalker string `yaml:"early_talker" mapstructure:"early_talker"`
+	// EarlyTalkerWhitelist 不等待也不检测的客户端（IP 或网段，如已知不等待横幅的发件服务器）
+	EarlyTalkerWhitelist []string `yaml:"early_talker_whitelist" mapstructure:"early_talker_whitelist"`
+}
+
+// SMTPAuthConfig SMTP 认证配置
+type SMTPAuthConfig struct {
+	// AllowInsecureLocalhost 允许来自本机（回环地址）的明文连接认证
+	AllowInsecureLocalhost bool `yaml:"allow_insecure_localhost" mapstructure:"allow_insecure_localhost"`
 }
 
 // DKIMConfig DKIM 配置
@@ -68,16 +359,26 @@ type DKIMConfig struct {
 	Selector   string `yaml:"selector" mapstructure:"selector"`       // DKIM 选择器（如 default）
 	PrivateKey string `yaml:"private_key" mapstructure:"private_key"` // DKIM 私钥文件路径（相对于 workdir）
 	Domain     string `yaml:"domain" mapstructure:"domain"`           // 签名域名（留空使用主域名）
+	// Canonicalization 规范化算法（邮件头/正文，simple 或 relaxed，默认 relaxed/relaxed）
+	Canonicalization string `yaml:"canonicalization" mapstructure:"canonicalization"`
 }
 
 // RelayConfig SMTP 中继配置
 type RelayConfig struct {
 	Enabled  bool   `yaml:"enabled" mapstructure:"enabled"`
-	Host     string `yaml:"host" mapstructure:"host"`         // 中继服务器地址（如 smtp.qq.com）
-	Port     int    `yaml:"port" mapstructure:"port"`         // 中继服务器端口（如 587）
-	Username string `yaml:"username" mapstructure:"username"` // 邮箱账号
-	Password string `yaml:"password" mapstructure:"password"` // 邮箱密码或授权码
-	UseTLS   bool   `yaml:"use_tls" mapstructure:"use_tls"`   // 是否使用 TLS（端口 587 通常需要）
+	Host     string `yaml:"host" mapstructure:"host"`                       // 中继服务器地址（如 smtp.qq.com）
+	Port     int    `yaml:"port" mapstructure:"port"`                       // 中继服务器端口（如 587）
+	Username string `yaml:"username" mapstructure:"username"`               // 邮箱账号
+	Password string `yaml:"password" mapstructure:"password" redact:"true"` // 邮箱密码或授权码
+	UseTLS   bool   `yaml:"use_tls" mapstructure:"use_tls"`                 // 是否使用 TLS（端口 587 通常需要）
+	// ClientCert、ClientKey 客户端证书和私钥文件（相对于 workdir），中继要求双向 TLS 认证时在 TLS 握手中出示，
+	// 可以代替用户名和密码（需要启用 use_tls）
+	ClientCert string `yaml:"client_cert" mapstructure:"client_cert"`
+	ClientKey  string `yaml:"client_key" mapstructure:"client_key"`
+	// DKIM 通过中继外发时使用的 DKIM 签名，启用时代替 smtp.dkim（如中继只接受其授权的域名或选择器的签名）
+	DKIM DKIMConfig `yaml:"dkim" mapstructure:"dkim"`
+	// SkipDKIM 通过中继外发时不签名（由中继服务器签名）
+	SkipDKIM bool `yaml:"skip_dkim" mapstructure:"skip_dkim"`
 }
 
 // IMAPConfig IMAP 配置
@@ -85,15 +386,61 @@ type IMAPConfig struct {
 	Enabled       bool `yaml:"enabled" mapstructure:"enabled"`
 	Port          int  `yaml:"port" mapstructure:"port"`
 	MaxAuthErrors int  `yaml:"max_auth_errors" mapstructure:"max_auth_errors"`
+	// Timeouts 会话超时：超时的连接被断开（包括客户端已经消失的半开连接）
+	Timeouts IMAPTimeoutsConfig `yaml:"timeouts" mapstructure:"timeouts"`
+	// SubmissionFolder 提交文件夹：客户端 APPEND 到该文件夹的邮件投递给本地收件人（为空时 APPEND 只保存邮件）。
+	// 不能是 INBOX，客户端保存草稿、已发送邮件和在邮箱之间复制邮件都使用 APPEND
+	SubmissionFolder string `yaml:"submission_folder" mapstructure:"submission_folder"`
+	// Greeting 欢迎信息文本（替换 "IMAP4rev1 Service Ready"，如 "Example Mail IMAP"，留空使用默认）
+	Greeting string `yaml:"greeting" mapstructure:"greeting"`
+}
+
+// IMAPTimeoutsConfig IMAP 会话超时（0 不限制）
+type IMAPTimeoutsConfig struct {
+	// Idle 客户端没有活动的最长时间，超时自动登出（默认 30m，RFC 3501 5.4 要求至少 30 分钟）；
+	// NOOP 和 IDLE 中的客户端重新发送 IDLE 都会延长
+	Idle time.Duration `yaml:"idle" mapstructure:"idle"`
+	// Login 连接后完成认证的最长时间（默认 1m），期间的 NOOP 等命令不会延长
+	Login time.Duration `yaml:"login" mapstructure:"login"`
 }
 
 // AntiSpamConfig 反垃圾配置
 type AntiSpamConfig struct {
 	Enabled   bool   `yaml:"enabled" mapstructure:"enabled"`
 	RspamdURL string `yaml:"rspamd_url" mapstructure:"rspamd_url"`
-	ClamAVURL string `yaml:"clamav_url" mapstructure:"clamav_url"`
-	Greylist  bool   `yaml:"greylist" mapstructure:"greylist"`
-	RateLimit bool   `yaml:"rate_limit" mapstructure:"rate_limit"`
+	// RspamdTimeout 单封邮件的 Rspamd 检查超时（默认 5s），超时或 Rspamd 不可用时放行邮件
+	RspamdTimeout time.Duration `yaml:"rspamd_timeout" mapstructure:"rspamd_timeout"`
+	ClamAVURL     string        `yaml:"clamav_url" mapstructure:"clamav_url"`
+	Greylist      bool          `yaml:"greylist" mapstructure:"greylist"`
+	RateLimit     bool          `yaml:"rate_limit" mapstructure:"rate_limit"`
+	// Tarpit 对多次认证失败或被拒收的 IP 减速后续连接
+	Tarpit TarpitConfig `yaml:"tarpit" mapstructure:"tarpit"`
+	// Fingerprint 记录被拒收或被投诉的垃圾邮件的正文指纹，相似内容再次到达时提高垃圾邮件分数
+	Fingerprint FingerprintConfig `yaml:"fingerprint" mapstructure:"fingerprint"`
+	// Bayes 用用户移入、移出垃圾邮件文件夹的邮件训练贝叶斯分类器，按收件人的模型（样本不足时使用全局模型）评分
+	Bayes BayesConfig `yaml:"bayes" mapstructure:"bayes"`
+}
+
+// TarpitConfig 连接减速配置
+type TarpitConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`
+	Threshold int           `yaml:"threshold" mapstructure:"threshold"` // 窗口内失败次数达到此值后开始减速
+	Window    time.Duration `yaml:"window" mapstructure:"window"`       // 失败次数统计窗口
+	Delay     time.Duration `yaml:"delay" mapstructure:"delay"`         // 每次响应的延迟，之后每次失败递增
+	MaxDelay  time.Duration `yaml:"max_delay" mapstructure:"max_delay"` // 延迟上限
+}
+
+// FingerprintConfig 垃圾邮件内容指纹配置
+type FingerprintConfig struct {
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`
+	Threshold int           `yaml:"threshold" mapstructure:"threshold"` // 判定为同一活动的最低相似度（1 ~ 128）
+	Window    time.Duration `yaml:"window" mapstructure:"window"`       // 指纹在最后一次出现后保持活跃的时间
+}
+
+// BayesConfig 垃圾邮件贝叶斯分类器配置
+type BayesConfig struct {
+	Enabled     bool `yaml:"enabled" mapstructure:"enabled"`
+	MinTraining int  `yaml:"min_training" mapstructure:"min_training"` // 使用模型所需的最少垃圾邮件和正常邮件样本数（各自）
 }
 
 // WebMailConfig WebMail 配置
@@ -101,12 +448,18 @@ type WebMailConfig struct {
 	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
 	Path    string `yaml:"path" mapstructure:"path"`
 	Port    int    `yaml:"port" mapstructure:"port"`
+	// JMAP 在 WebMail 端口上提供 JMAP 接口（/.well-known/jmap），使用 WebMail 登录令牌或个人访问令牌认证
+	JMAP bool `yaml:"jmap" mapstructure:"jmap"`
+	// AccessTokenTTL 登录签发的访问令牌有效期（默认 15m），过期后客户端用刷新令牌换取新的访问令牌
+	AccessTokenTTL time.Duration `yaml:"access_token_ttl" mapstructure:"access_token_ttl"`
+	// SessionTTL 登录会话（刷新令牌）的有效期（默认 720h），过期后需要重新登录
+	SessionTTL time.Duration `yaml:"session_ttl" mapstructure:"session_ttl"`
 }
 
 // AdminConfig 管理配置
 type AdminConfig struct {
-	APIKey    string `yaml:"api_key" mapstructure:"api_key"`
-	JWTSecret string `yaml:"jwt_secret" mapstructure:"jwt_secret"`
+	APIKey    string `yaml:"api_key" mapstructure:"api_key" redact:"true"`
+	JWTSecret string `yaml:"jwt_secret" mapstructure:"jwt_secret" redact:"true"`
 	Port      int    `yaml:"port" mapstructure:"port"`
 }
 
@@ -124,6 +477,178 @@ type MetricsConfig struct {
 	Port    int    `yaml:"port" mapstructure:"port"`
 }
 
+// FetchmailConfig 外部邮箱拉取配置
+type FetchmailConfig struct {
+	Enabled     bool          `yaml:"enabled" mapstructure:"enabled"`
+	Interval    time.Duration `yaml:"interval" mapstructure:"interval"`         // 拉取间隔
+	MaxMessages int           `yaml:"max_messages" mapstructure:"max_messages"` // 每个账户每次最多拉取的邮件数
+	// EncryptionKey 加密远程账户密码的口令（更改后已保存的账户需要重新填写密码）
+	EncryptionKey string `yaml:"encryption_key" mapstructure:"encryption_key" redact:"true"`
+	// AllowPrivateHosts 允许连接内网地址的远程服务器（默认只允许公网地址）
+	AllowPrivateHosts bool `yaml:"allow_private_hosts" mapstructure:"allow_private_hosts"`
+}
+
+// ReplicationConfig Maildir 热备复制配置
+type ReplicationConfig struct {
+	// Enabled 主节点：记录 Maildir 变更日志，并通过 gRPC 提供给备节点
+	Enabled   bool          `yaml:"enabled" mapstructure:"enabled"`
+	Listen    string        `yaml:"listen" mapstructure:"listen"`       // 复制服务监听地址
+	Retention time.Duration `yaml:"retention" mapstructure:"retention"` // 变更日志保留时间（备节点落后更久时需要全量同步）
+	// Primary 备节点：gmz replication 命令连接的主节点地址（host:port）
+	Primary string `yaml:"primary" mapstructure:"primary"`
+	// Token 主备节点共享的认证令牌
+	Token  string `yaml:"token" mapstructure:"token" redact:"true"`
+	TLS    bool   `yaml:"tls" mapstructure:"tls"`         // 使用 TLS（主节点使用服务器证书）
+	CAFile string `yaml:"ca_file" mapstructure:"ca_file"` // 备节点校验主节点证书的 CA（主节点使用自签名证书时配置）
+}
+
+// LMTPConfig LMTP 监听配置（RFC 2033，没有认证，只应监听 Unix 套接字、本机或内网地址）
+type LMTPConfig struct {
+	Enabled bool   `yaml:"enabled" mapstructure:"enabled"`
+	Socket  string `yaml:"socket" mapstructure:"socket"`     // Unix 套接字路径（留空不监听）
+	Address string `yaml:"address" mapstructure:"address"`   // TCP 监听地址（如 127.0.0.1:24，留空不监听）
+	MaxSize string `yaml:"max_size" mapstructure:"max_size"` // 最大邮件大小（留空使用 smtp.max_size）
+}
+
+// LimitsConfig 按 IP 的连接数和认证失败限制
+type LimitsConfig struct {
+	Enabled             bool          `yaml:"enabled" mapstructure:"enabled"`
+	MaxConnectionsPerIP int           `yaml:"max_connections_per_ip" mapstructure:"max_connections_per_ip"` // 每个 IP 的 SMTP/IMAP 并发连接数上限（0 不限制）
+	MaxAuthFailures     int           `yaml:"max_auth_failures" mapstructure:"max_auth_failures"`           // 窗口内认证失败达到该次数后封禁 IP
+	Window              time.Duration `yaml:"window" mapstructure:"window"`                                 // 认证失败统计窗口
+	BanDuration         time.Duration `yaml:"ban_duration" mapstructure:"ban_duration"`                     // 封禁时长
+	Exempt              []string      `yaml:"exempt" mapstructure:"exempt"`                                 // 不受限制的 IP 或网段
+}
+
+// SASLConfig SMTP 和 IMAP 的额外认证机制（PLAIN 始终可用）
+type SASLConfig struct {
+	CRAMMD5     bool        `yaml:"cram_md5" mapstructure:"cram_md5"`       // CRAM-MD5（旧客户端），用户用明文密码登录一次后可用
+	OAuthBearer bool        `yaml:"oauthbearer" mapstructure:"oauthbearer"` // OAUTHBEARER（RFC 7628）
+	XOAuth2     bool        `yaml:"xoauth2" mapstructure:"xoauth2"`         // XOAUTH2
+	OAuth       OAuthConfig `yaml:"oauth" mapstructure:"oauth"`
+}
+
+// OAuthConfig 令牌认证接受的外部身份提供方（不配置时只接受内置 JWT 管理器签发的令牌）
+type OAuthConfig struct {
+	JWKSURL       string `yaml:"jwks_url" mapstructure:"jwks_url"`             // 签名公钥地址
+	Issuer        string `yaml:"issuer" mapstructure:"issuer"`                 // 要求的签发者（为空时不校验）
+	Audience      string `yaml:"audience" mapstructure:"audience"`             // 要求的受众（为空时不校验）
+	UsernameClaim string `yaml:"username_claim" mapstructure:"username_claim"` // 用户邮箱所在的声明
+}
+
+// QuotaConfig 配额预警配置
+type QuotaConfig struct {
+	// WarningThresholds 预警阈值（用量百分比），域名可以单独设置；为空时只有设置了阈值的域名预警
+	WarningThresholds []int         `yaml:"warning_thresholds" mapstructure:"warning_thresholds"`
+	CheckInterval     time.Duration `yaml:"check_interval" mapstructure:"check_interval"` // 检查用量的间隔
+}
+
+// DigestConfig 邮件摘要配置（用户在 WebMail 设置中选择频率，域名可以设置默认频率）
+type DigestConfig struct {
+	Enabled       bool          `yaml:"enabled" mapstructure:"enabled"`
+	Hour          int           `yaml:"hour" mapstructure:"hour"`                     // 发送时间（用户时区的整点，每周摘要在周一发送）
+	CheckInterval time.Duration `yaml:"check_interval" mapstructure:"check_interval"` // 检查到期摘要的间隔
+	Template      string        `yaml:"template" mapstructure:"template"`             // 自定义模板文件（为空时使用内置模板）
+}
+
+// WarmupConfig 外发预热配置：从 start_date 开始的 weeks 周内，每个目标邮件服务商的每日外发上限
+// 从 initial_daily_limit 按周增长到 max_daily_limit，预热结束后不再限制
+type WarmupConfig struct {
+	Enabled           bool                `yaml:"enabled" mapstructure:"enabled"`
+	StartDate         string              `yaml:"start_date" mapstructure:"start_date"`                   // 预热开始日期（YYYY-MM-DD，UTC）
+	Weeks             int                 `yaml:"weeks" mapstructure:"weeks"`                             // 预热周数
+	InitialDailyLimit int                 `yaml:"initial_daily_limit" mapstructure:"initial_daily_limit"` // 第一周每个服务商的每日上限
+	MaxDailyLimit     int                 `yaml:"max_daily_limit" mapstructure:"max_daily_limit"`         // 最后一周每个服务商的每日上限
+	CheckInterval     time.Duration       `yaml:"check_interval" mapstructure:"check_interval"`           // 检查推迟邮件的间隔
+	Providers         map[string][]string `yaml:"providers" mapstructure:"providers"`                     // 额外的服务商及其域名（补充内置列表）
+}
+
+// WebPushConfig WebPush 通知配置（新邮件事件来自 Maildir 监视器，需要启用 storage.watch_maildir）
+type WebPushConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// Subject VAPID 联系方式（mailto: 地址或 https: 网址），推送服务在出现问题时联系
+	Subject string `yaml:"subject" mapstructure:"subject"`
+	// KeyFile VAPID 私钥文件（PEM），不存在时自动生成；更换密钥后已有的订阅失效
+	KeyFile string `yaml:"key_file" mapstructure:"key_file"`
+	// Privacy 订阅没有指定时通知包含的内容：full（发件人和主题）、sender（只有发件人）、none（只提示有新邮件）
+	Privacy string        `yaml:"privacy" mapstructure:"privacy"`
+	TTL     time.Duration `yaml:"ttl" mapstructure:"ttl"` // 设备离线时推送服务保存通知的时长
+}
+
+// ActivityConfig 账户活动记录配置
+type ActivityConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// RetentionDays 登录记录和发信记录的保留天数
+	RetentionDays int `yaml:"retention_days" mapstructure:"retention_days"`
+}
+
+// BannerConfig 登录提示和使用条款配置
+type BannerConfig struct {
+	// Notice 登录页面显示的提示（为空时不显示）
+	Notice string `yaml:"notice" mapstructure:"notice"`
+	// Terms 使用条款全文（为空时不要求接受）
+	Terms string `yaml:"terms" mapstructure:"terms"`
+	// TermsVersion 使用条款版本，修改条款后更新版本，所有用户在下次登录时需要重新接受
+	TermsVersion string `yaml:"terms_version" mapstructure:"terms_version"`
+}
+
+// WebAuthnConfig WebAuthn 配置
+type WebAuthnConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// RPID Relying Party ID，凭证绑定到该域名及其子域名（为空时使用 domain）
+	RPID string `yaml:"rp_id" mapstructure:"rp_id"`
+	// RPDisplayName 浏览器注册凭证时显示的名称
+	RPDisplayName string `yaml:"rp_display_name" mapstructure:"rp_display_name"`
+	// RPOrigins 允许的页面 Origin（如 https://mail.example.com），WebMail 和管理后台不同源时都要列出
+	RPOrigins []string `yaml:"rp_origins" mapstructure:"rp_origins"`
+}
+
+// ContactFormConfig 公开联系表单配置
+type ContactFormConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// Captcha 人机验证服务：turnstile（Cloudflare Turnstile）或 hcaptcha
+	Captcha   string `yaml:"captcha" mapstructure:"captcha"`
+	SecretKey string `yaml:"secret_key" mapstructure:"secret_key" redact:"true"` // 人机验证的服务端密钥
+	// VerifyURL 验证地址，为空时使用服务的默认地址
+	VerifyURL string `yaml:"verify_url" mapstructure:"verify_url"`
+	// IPLimit 每个 IP 每小时最多提交次数
+	IPLimit int `yaml:"ip_limit" mapstructure:"ip_limit"`
+	// DomainLimit 每个域名每小时最多投递的留言数
+	DomainLimit int `yaml:"domain_limit" mapstructure:"domain_limit"`
+	// MaxMessageSize 留言的最大字节数
+	MaxMessageSize int               `yaml:"max_message_size" mapstructure:"max_message_size"`
+	Forms          []ContactFormSite `yaml:"forms" mapstructure:"forms"`
+}
+
+// ContactFormSite 一个域名的联系表单
+type ContactFormSite struct {
+	Domain string `yaml:"domain" mapstructure:"domain"` // 请求路径中的域名
+	To     string `yaml:"to" mapstructure:"to"`         // 投递留言的本地邮箱
+	// AllowedOrigins 允许提交的网站来源（如 https://www.example.com，浏览器跨域请求时检查）
+	AllowedOrigins []string `yaml:"allowed_origins" mapstructure:"allowed_origins"`
+	// Subject 留言邮件的主题前缀（默认 [联系表单]）
+	Subject string `yaml:"subject" mapstructure:"subject"`
+	// RedirectURL 普通 HTML 表单提交成功后跳转的页面（为空时返回 JSON）
+	RedirectURL string `yaml:"redirect_url" mapstructure:"redirect_url"`
+}
+
+// ChaosConfig 故障注入配置：对邮件存储操作和外发 SMTP 连接注入延迟、错误和部分写入
+type ChaosConfig struct {
+	Enabled          bool          `yaml:"enabled" mapstructure:"enabled"`
+	Latency          time.Duration `yaml:"latency" mapstructure:"latency"`                       // 每次操作随机增加的最大延迟
+	ErrorRate        float64       `yaml:"error_rate" mapstructure:"error_rate"`                 // 操作失败的概率（0 ~ 1）
+	PartialWriteRate float64       `yaml:"partial_write_rate" mapstructure:"partial_write_rate"` // 部分写入的概率（0 ~ 1）
+	Seed             uint64        `yaml:"seed" mapstructure:"seed"`                             // 随机种子（0 时每次运行随机，固定后可复现）
+}
+
+// ProvisioningConfig 初始化数据配置
+type ProvisioningConfig struct {
+	Path string `yaml:"path" mapstructure:"path"` // YAML 文件或目录（目录下所有 *.yml/*.yaml），相对于 workdir
+}
+
+// sizePattern 大小配置格式（如 50MB）
+var sizePattern = regexp.MustCompile(`^[0-9]+(KB|MB|GB)$`)
+
 // Load 加载配置
 func Load(path string) (*Config, error) {
 	v := viper.New()
@@ -132,24 +657,29 @@ func Load(path string) (*Config, error) {
 	v.SetConfigFile(path)
 	v.SetConfigType("yaml")
 
-	// 设置环境变量前缀
-	v.SetEnvPrefix("GMZ")
+	// 设置环境变量前缀，并为所有嵌套键显式绑定环境变量（容器中可仅用环境变量配置）
+	v.SetEnvPrefix(envPrefix)
 	v.AutomaticEnv()
+	if err := bindEnvs(v); err != nil {
+		return nil, fmt.Errorf("绑定环境变量失败: %w", err)
+	}
 
 	// 设置默认值
 	setDefaults(v)
 
-	// 读取配置文件
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+	// 读取配置文件（配置文件不存在时使用默认值和环境变量）
+	if _, err := os.Stat(path); err == nil {
+		if err := v.ReadInConfig(); err != nil {
 			return nil, fmt.Errorf("读取配置文件失败: %w", err)
 		}
-		// 配置文件不存在时使用默认值
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
+	// 严格解析：配置文件中的未知键（通常是拼写错误）直接报错，而不是被静默忽略
 	var cfg Config
-	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("解析配置失败: %w", err)
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return nil, fmt.Errorf("解析配置失败（请检查是否有拼写错误的配置键）: %w", err)
 	}
 
 	// 解析工作目录和相对路径
@@ -157,6 +687,9 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("解析路径失败: %w", err)
 	}
 
+	// 按 inbound 和 submission 生成按端口的 SMTP 配置
+	cfg.SMTP.applySections()
+
 	// 验证配置
 	if err := validate(&cfg); err != nil {
 		return nil, fmt.Errorf("配置验证失败: %w", err)
@@ -207,6 +740,19 @@ func resolvePaths(cfg *Config) error {
 	cfg.TLS.CertFile = resolvePath(cfg.TLS.CertFile)
 	cfg.TLS.KeyFile = resolvePath(cfg.TLS.KeyFile)
 	cfg.TLS.ACME.Dir = resolvePath(cfg.TLS.ACME.Dir)
+	cfg.TLS.SelfSigned.Dir = resolvePath(cfg.TLS.SelfSigned.Dir)
+	for i := range cfg.TLS.SNI {
+		cfg.TLS.SNI[i].CertFile = resolvePath(cfg.TLS.SNI[i].CertFile)
+		cfg.TLS.SNI[i].KeyFile = resolvePath(cfg.TLS.SNI[i].KeyFile)
+	}
+
+	cfg.Provisioning.Path = resolvePath(cfg.Provisioning.Path)
+	cfg.Replication.CAFile = resolvePath(cfg.Replication.CAFile)
+	cfg.Storage.RPC.CAFile = resolvePath(cfg.Storage.RPC.CAFile)
+	cfg.LMTP.Socket = resolvePath(cfg.LMTP.Socket)
+	cfg.WebPush.KeyFile = resolvePath(cfg.WebPush.KeyFile)
+	cfg.SMTP.Relay.ClientCert = resolvePath(cfg.SMTP.Relay.ClientCert)
+	cfg.SMTP.Relay.ClientKey = resolvePath(cfg.SMTP.Relay.ClientKey)
 
 	// 解析日志输出路径（如果不是 stdout）
 	if cfg.Log.Output != "" && cfg.Log.Output != "stdout" && cfg.Log.Output != "stderr" {
@@ -222,40 +768,84 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("node_id", "mx1")
 	v.SetDefault("domain", "example.com")
 	v.SetDefault("workdir", "") // 默认使用当前工作目录
+	v.SetDefault("shutdown_timeout", "30s")
 
 	// TLS 配置
 	v.SetDefault("tls.enabled", true)
 	v.SetDefault("tls.min_version", "1.3")
+	v.SetDefault("tls.session_tickets", true)
+	v.SetDefault("tls.client_session_cache_size", 256)
+	v.SetDefault("tls.early_data", false)
+	v.SetDefault("tls.http.enabled", false)
 	v.SetDefault("tls.acme.enabled", true)
 	v.SetDefault("tls.acme.provider", "letsencrypt")
 	v.SetDefault("tls.acme.dir", "/var/lib/gmz/certs")
+	v.SetDefault("tls.acme.storage", "file")
+	v.SetDefault("tls.acme.challenge", "http-01")
+	v.SetDefault("tls.acme.renew_before", "720h")
+	v.SetDefault("tls.acme.check_interval", "12h")
+	v.SetDefault("tls.acme.dns.propagation_timeout", "2m")
+	v.SetDefault("tls.acme.dns.rfc2136.tsig_algorithm", "hmac-sha256")
+	v.SetDefault("tls.self_signed.enabled", false)
+	v.SetDefault("tls.self_signed.dir", "certs")
+	v.SetDefault("tls.watch_files", true)
 
 	// 存储配置
 	v.SetDefault("storage.driver", "sqlite")
 	v.SetDefault("storage.dsn", "/var/lib/gmz/data.db")
 	v.SetDefault("storage.maildir_root", "/var/lib/gmz/mail")
 	v.SetDefault("storage.auto_migrate", true)
+	v.SetDefault("storage.zero_access.unlock_ttl", "1h")
+	v.SetDefault("storage.rpc.enabled", false)
+	v.SetDefault("storage.rpc.listen", ":7421")
+	v.SetDefault("storage.rpc.tls", true)
 
 	// SMTP 配置
 	v.SetDefault("smtp.enabled", true)
 	v.SetDefault("smtp.ports", []int{25, 465, 587})
 	v.SetDefault("smtp.max_size", "50MB")
 	v.SetDefault("smtp.hostname", "")
+	v.SetDefault("smtp.auth.allow_insecure_localhost", false)
+	v.SetDefault("smtp.submission_ports", []int{465, 587})
+	v.SetDefault("smtp.inbound.tls", true)
+	v.SetDefault("smtp.inbound.antispam", true)
+	v.SetDefault("smtp.inbound.rate_limit", true)
+	v.SetDefault("smtp.submission.tls", true)
+	v.SetDefault("smtp.submission.antispam", true)
+	v.SetDefault("smtp.submission.rate_limit", true)
+	v.SetDefault("smtp.transcripts.retention_days", 90)
+	v.SetDefault("smtp.timeouts.command", "5m")
+	v.SetDefault("smtp.timeouts.data", "3m")
+	v.SetDefault("smtp.timeouts.idle", "10m")
 
 	// IMAP 配置
 	v.SetDefault("imap.enabled", true)
 	v.SetDefault("imap.port", 993)
 	v.SetDefault("imap.max_auth_errors", 5)
+	v.SetDefault("imap.timeouts.idle", "30m")
+	v.SetDefault("imap.timeouts.login", "1m")
 
 	// 反垃圾配置
 	v.SetDefault("antispam.enabled", true)
 	v.SetDefault("antispam.greylist", true)
 	v.SetDefault("antispam.rate_limit", true)
+	v.SetDefault("antispam.tarpit.enabled", false)
+	v.SetDefault("antispam.tarpit.threshold", 5)
+	v.SetDefault("antispam.tarpit.window", "10m")
+	v.SetDefault("antispam.tarpit.delay", "5s")
+	v.SetDefault("antispam.tarpit.max_delay", "30s")
+	v.SetDefault("antispam.fingerprint.enabled", false)
+	v.SetDefault("antispam.fingerprint.threshold", 90)
+	v.SetDefault("antispam.fingerprint.window", "720h")
+	v.SetDefault("antispam.bayes.enabled", false)
+	v.SetDefault("antispam.bayes.min_training", 20)
 
 	// WebMail 配置
 	v.SetDefault("webmail.enabled", true)
 	v.SetDefault("webmail.path", "/webmail")
 	v.SetDefault("webmail.port", 8080)
+	v.SetDefault("webmail.access_token_ttl", 15*time.Minute)
+	v.SetDefault("webmail.session_ttl", 720*time.Hour)
 
 	// 管理配置
 	v.SetDefault("admin.port", 8081)
@@ -269,32 +859,719 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("metrics.enabled", true)
 	v.SetDefault("metrics.path", "/metrics")
 	v.SetDefault("metrics.port", 9090)
+
+	// 外部邮箱拉取配置
+	v.SetDefault("fetchmail.enabled", false)
+	v.SetDefault("fetchmail.interval", "10m")
+	v.SetDefault("fetchmail.max_messages", 100)
+
+	// LMTP 配置
+	v.SetDefault("lmtp.enabled", false)
+	v.SetDefault("lmtp.socket", "")
+	v.SetDefault("lmtp.address", "")
+
+	// 热备复制配置
+	v.SetDefault("replication.enabled", false)
+	v.SetDefault("replication.listen", ":7420")
+	v.SetDefault("replication.retention", "168h")
+	v.SetDefault("replication.tls", true)
+
+	// 连接和登录限制配置
+	v.SetDefault("limits.enabled", true)
+	v.SetDefault("limits.max_connections_per_ip", 20)
+	v.SetDefault("limits.max_auth_failures", 10)
+	v.SetDefault("limits.window", "10m")
+	v.SetDefault("limits.ban_duration", "15m")
+	v.SetDefault("limits.exempt", []string{"127.0.0.1", "::1"})
+
+	// SASL 认证机制配置
+	v.SetDefault("sasl.cram_md5", false)
+	v.SetDefault("sasl.oauthbearer", false)
+	v.SetDefault("sasl.xoauth2", false)
+	v.SetDefault("sasl.oauth.username_claim", "email")
+
+	// 配额预警配置
+	v.SetDefault("quota.warning_thresholds", []int{80, 95})
+	v.SetDefault("quota.check_interval", "10m")
+
+	// 邮件摘要配置
+	v.SetDefault("digest.enabled", false)
+	v.SetDefault("digest.hour", 8)
+	v.SetDefault("digest.check_interval", "10m")
+
+	// 外发预热配置
+	v.SetDefault("warmup.enabled", false)
+	v.SetDefault("warmup.weeks", 6)
+	v.SetDefault("warmup.initial_daily_limit", 50)
+	v.SetDefault("warmup.max_daily_limit", 5000)
+	v.SetDefault("warmup.check_interval", "10m")
+
+	// WebPush 通知配置
+	v.SetDefault("webpush.enabled", false)
+	v.SetDefault("webpush.key_file", "webpush/vapid.key")
+	v.SetDefault("webpush.privacy", "sender")
+	v.SetDefault("webpush.ttl", "24h")
+
+	// 联系表单配置
+	v.SetDefault("contact_form.enabled", false)
+	v.SetDefault("contact_form.captcha", "turnstile")
+	v.SetDefault("contact_form.ip_limit", 5)
+	v.SetDefault("contact_form.domain_limit", 100)
+	v.SetDefault("contact_form.max_message_size", 10000)
+	v.SetDefault("webauthn.enabled", false)
+	v.SetDefault("webauthn.rp_display_name", "GoMailZero")
+	v.SetDefault("activity.enabled", true)
+	v.SetDefault("activity.retention_days", 90)
+
+	// 故障注入配置
+	v.SetDefault("chaos.enabled", false)
 }
 
-// validate 验证配置
+// validate 验证配置，一次返回所有问题，并提示对应的配置键和环境变量
 func validate(cfg *Config) error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		errs = append(errs, fmt.Errorf("%s: %s（配置键 %s，环境变量 %s）", key, msg, key, EnvName(key)))
+	}
+
+	// DKIM：启用后必须有选择器和私钥
+	validateDKIM := func(key string, dkim *DKIMConfig) {
+		if !dkim.Enabled {
+			return
+		}
+		if dkim.Selector == "" {
+			fail(key+".selector", "启用 DKIM 时必须配置选择器")
+		}
+		if dkim.PrivateKey == "" {
+			fail(key+".private_key", "启用 DKIM 时必须配置私钥文件")
+		}
+		switch dkim.Canonicalization {
+		case "", "simple", "relaxed", "simple/simple", "simple/relaxed", "relaxed/simple", "relaxed/relaxed":
+		default:
+			fail(key+".canonicalization", "无效的规范化算法 %q（邮件头/正文，simple 或 relaxed）", dkim.Canonicalization)
+		}
+	}
+
 	if cfg.Domain == "" {
-		return fmt.Errorf("domain 不能为空")
+		fail("domain", "不能为空")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		fail("shutdown_timeout", "必须大于 0")
+	}
+
+	if cfg.Storage.Driver != "sqlite" && cfg.Storage.Driver != "postgres" && cfg.Storage.Driver != "remote" {
+		fail("storage.driver", "不支持的存储驱动 %q（可选值 sqlite, postgres, remote）", cfg.Storage.Driver)
+	}
+	if cfg.Storage.Driver == "remote" || cfg.Storage.RPC.Enabled {
+		if len(cfg.Storage.RPC.Token) < 16 {
+			fail("storage.rpc.token", "使用存储服务时必须配置，且不少于 16 个字符")
+		}
+	}
+	if cfg.Storage.Driver == "remote" {
+		if cfg.Storage.RPC.Address == "" {
+			fail("storage.rpc.address", "存储驱动为 remote 时必须配置存储节点地址")
+		}
+		if cfg.Storage.RPC.Enabled {
+			fail("storage.rpc.enabled", "存储驱动为 remote 的前端不能再提供存储服务")
+		}
+	}
+	if cfg.Storage.RPC.Enabled {
+		if cfg.Storage.RPC.Listen == "" {
+			fail("storage.rpc.listen", "启用存储服务时必须配置")
+		}
+		if cfg.Storage.RPC.TLS && !cfg.TLS.Enabled {
+			fail("storage.rpc.tls", "需要启用 tls.enabled 以提供服务器证书（或设置 storage.rpc.tls: false，仅用于内网）")
+		}
+	}
+	if cfg.Storage.MaildirSharding < 0 || cfg.Storage.MaildirSharding > 3 {
+		fail("storage.maildir_sharding", "必须在 0 到 3 之间")
+	}
+	if cfg.Storage.ZeroAccess.Enabled && cfg.Storage.ZeroAccess.UnlockTTL <= 0 {
+		fail("storage.zero_access.unlock_ttl", "必须大于 0（如 1h）")
 	}
 
-	if cfg.Storage.Driver != "sqlite" && cfg.Storage.Driver != "postgres" {
-		return fmt.Errorf("不支持的存储驱动: %s", cfg.Storage.Driver)
+	for _, item := range []struct{ key, version string }{
+		{"tls.min_version", cfg.TLS.MinVersion},
+		{"tls.mail.min_version", cfg.TLS.Mail.MinVersion},
+		{"tls.http.min_version", cfg.TLS.HTTP.MinVersion},
+	} {
+		switch item.version {
+		case "", "1.0", "1.1", "1.2", "1.3":
+		default:
+			fail(item.key, "无效的 TLS 版本 %q（可选值 1.0, 1.1, 1.2, 1.3）", item.version)
+		}
 	}
 
-	if cfg.TLS.Enabled && !cfg.TLS.ACME.Enabled {
-		if cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "" {
-			return fmt.Errorf("TLS 已启用但未配置证书文件")
+	switch cfg.TLS.ACME.Storage {
+	case "", "file":
+	case "database":
+		if cfg.TLS.ACME.EncryptionKey == "" {
+			fail("tls.acme.encryption_key", "tls.acme.storage 为 database 时必须配置")
+		}
+	default:
+		fail("tls.acme.storage", "不支持的 ACME 存储 %q（可选值 file, database）", cfg.TLS.ACME.Storage)
+	}
+	if acme := &cfg.TLS.ACME; acme.Enabled {
+		switch acme.Challenge {
+		case "http-01", "tls-alpn-01":
+		case "dns-01":
+			switch acme.DNS.Provider {
+			case "cloudflare":
+				if acme.DNS.Cloudflare.APIToken == "" {
+					fail("tls.acme.dns.cloudflare.api_token", "DNS 提供商为 cloudflare 时必须配置")
+				}
+			case "rfc2136":
+				if acme.DNS.RFC2136.Server == "" {
+					fail("tls.acme.dns.rfc2136.server", "DNS 提供商为 rfc2136 时必须配置")
+				}
+				if (acme.DNS.RFC2136.TSIGKey == "") != (acme.DNS.RFC2136.TSIGSecret == "") {
+					fail("tls.acme.dns.rfc2136.tsig_secret", "tsig_key 和 tsig_secret 需要同时配置")
+				}
+				switch acme.DNS.RFC2136.TSIGAlgorithm {
+				case "", "hmac-sha1", "hmac-sha256", "hmac-sha512":
+				default:
+					fail("tls.acme.dns.rfc2136.tsig_algorithm", "不支持的 TSIG 算法 %q（可选值 hmac-sha1, hmac-sha256, hmac-sha512）", acme.DNS.RFC2136.TSIGAlgorithm)
+				}
+			default:
+				fail("tls.acme.dns.provider", "不支持的 DNS 提供商 %q（可选值 cloudflare, rfc2136）", acme.DNS.Provider)
+			}
+		default:
+			fail("tls.acme.challenge", "不支持的验证方式 %q（可选值 http-01, tls-alpn-01, dns-01）", acme.Challenge)
 		}
-		// 检查证书文件是否存在
-		if _, err := os.Stat(cfg.TLS.CertFile); err != nil {
-			return fmt.Errorf("证书文件不存在: %w", err)
+		if acme.CheckInterval < time.Minute {
+			fail("tls.acme.check_interval", "不能小于 1m")
 		}
-		if _, err := os.Stat(cfg.TLS.KeyFile); err != nil {
-			return fmt.Errorf("密钥文件不存在: %w", err)
+		if acme.RenewBefore <= 0 {
+			fail("tls.acme.renew_before", "必须大于 0")
 		}
 	}
 
-	return nil
+	for i, sni := range cfg.TLS.SNI {
+		key := fmt.Sprintf("tls.sni[%d]", i)
+		if sni.Hostname == "" {
+			errs = append(errs, fmt.Errorf("%s: 缺少 hostname", key))
+			continue
+		}
+		if sni.ACME {
+			if !cfg.TLS.ACME.Enabled {
+				errs = append(errs, fmt.Errorf("%s: %s 使用 ACME 但 tls.acme.enabled 未启用", key, sni.Hostname))
+			} else if strings.HasPrefix(sni.Hostname, "*.") && cfg.TLS.ACME.Challenge != "dns-01" {
+				errs = append(errs, fmt.Errorf("%s: 通配符证书 %s 需要 tls.acme.challenge 为 dns-01", key, sni.Hostname))
+			}
+			continue
+		}
+		if sni.CertFile == "" || sni.KeyFile == "" {
+			errs = append(errs, fmt.Errorf("%s: %s 未配置 cert_file/key_file", key, sni.Hostname))
+		}
+	}
+
+	// 端口范围和冲突检查
+	ports := make(map[int]string)
+	checkPort := func(key string, port int) {
+		if port < 1 || port > 65535 {
+			fail(key, "端口 %d 超出范围（1-65535）", port)
+			return
+		}
+		if other, ok := ports[port]; ok {
+			fail(key, "端口 %d 与 %s 冲突", port, other)
+			return
+		}
+		ports[port] = key
+	}
+	if cfg.SMTP.Enabled {
+		if len(cfg.SMTP.Ports) == 0 {
+			fail("smtp.ports", "SMTP 已启用但未配置监听端口")
+		}
+		for _, port := range cfg.SMTP.Ports {
+			checkPort("smtp.ports", port)
+		}
+	}
+	if cfg.IMAP.Enabled {
+		checkPort("imap.port", cfg.IMAP.Port)
+	}
+	if cfg.WebMail.Enabled {
+		checkPort("webmail.port", cfg.WebMail.Port)
+		if cfg.WebMail.AccessTokenTTL <= 0 {
+			fail("webmail.access_token_ttl", "必须大于 0（如 15m）")
+		}
+		if cfg.WebMail.SessionTTL < cfg.WebMail.AccessTokenTTL {
+			fail("webmail.session_ttl", "不能小于 webmail.access_token_ttl")
+		}
+	}
+	if cfg.Admin.APIKey != "" {
+		checkPort("admin.port", cfg.Admin.Port)
+	}
+	if cfg.Metrics.Enabled {
+		checkPort("metrics.port", cfg.Metrics.Port)
+	}
+
+	// 要求 STARTTLS 的端口必须是 SMTP 监听端口，且必须启用 TLS
+	for _, port := range cfg.SMTP.RequireTLSPorts {
+		found := false
+		for _, p := range cfg.SMTP.Ports {
+			found = found || p == port
+		}
+		if !found {
+			fail("smtp.require_tls_ports", "端口 %d 不在 smtp.ports 中", port)
+		}
+	}
+	if len(cfg.SMTP.RequireTLSPorts) > 0 && !cfg.TLS.Enabled {
+		fail("smtp.require_tls_ports", "要求 STARTTLS 时必须启用 tls.enabled")
+	}
+
+	// 提交端口不能是 MX 接收端口 25（不在 smtp.ports 中的提交端口不生效）
+	for _, port := range cfg.SMTP.SubmissionPorts {
+		if port == 25 {
+			fail("smtp.submission_ports", "端口 25 用于接收外部邮件，不能作为提交端口")
+		}
+	}
+
+	// 分开配置接收和提交端口时：要求 STARTTLS 必须提供 TLS，大小格式必须有效
+	if cfg.SMTP.Sectioned() {
+		if cfg.SMTP.Inbound.RequireTLS && !cfg.SMTP.Inbound.TLS {
+			fail("smtp.inbound.require_tls", "要求 STARTTLS 时必须启用 smtp.inbound.tls")
+		}
+		if cfg.SMTP.Submission.RequireTLS && !cfg.SMTP.Submission.TLS {
+			fail("smtp.submission.require_tls", "要求 STARTTLS 时必须启用 smtp.submission.tls")
+		}
+		if size := cfg.SMTP.Inbound.MaxSize; size != "" && !sizePattern.MatchString(size) {
+			fail("smtp.inbound.max_size", "无效的大小 %q（格式如 50MB、512KB、1GB）", size)
+		}
+		if size := cfg.SMTP.Submission.MaxSize; size != "" && !sizePattern.MatchString(size) {
+			fail("smtp.submission.max_size", "无效的大小 %q（格式如 50MB、512KB、1GB）", size)
+		}
+	}
+
+	// 按端口的监听配置必须对应 SMTP 监听端口，且每个端口只能配置一次
+	listenerPorts := make(map[int]bool)
+	for i, l := range cfg.SMTP.Listeners {
+		key := fmt.Sprintf("smtp.listeners[%d]", i)
+		found := false
+		for _, p := range cfg.SMTP.Ports {
+			found = found || p == l.Port
+		}
+		if !found {
+			fail(key, "端口 %d 不在 smtp.ports 中", l.Port)
+		}
+		if listenerPorts[l.Port] {
+			fail(key, "端口 %d 重复配置", l.Port)
+		}
+		listenerPorts[l.Port] = true
+		for _, entry := range l.TrustedUpstreams {
+			if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+				fail(key+".trusted_upstreams", "无效的 IP 地址或网段 %q", entry)
+			}
+		}
+		// 客户端最多等待横幅 5 分钟（RFC 5321 4.5.3.2）
+		if l.GreetDelay < 0 || l.GreetDelay >= 5*time.Minute {
+			fail(key+".greet_delay", "必须在 0 到 5m 之间")
+		}
+		if l.GreetDelay > 0 && l.Port == 465 {
+			fail(key+".greet_delay", "隐式 TLS 端口 465 不能使用（TLS 握手由客户端先发送）")
+		}
+		if l.EarlyTalker != "" && l.EarlyTalker != "reject" && l.EarlyTalker != "junk" {
+			fail(key+".early_talker", "必须是 reject 或 junk")
+		}
+		for _, entry := range l.EarlyTalkerWhitelist {
+			if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+				fail(key+".early_talker_whitelist", "无效的 IP 地址或网段 %q", entry)
+			}
+		}
+	}
+
+	if cfg.SMTP.VRFY != "" && cfg.SMTP.VRFY != "deny" && cfg.SMTP.VRFY != "admin" {
+		fail("smtp.vrfy", "必须是 deny 或 admin")
+	}
+
+	if cfg.SMTP.MaxSize != "" && !sizePattern.MatchString(cfg.SMTP.MaxSize) {
+		fail("smtp.max_size", "无效的大小 %q（格式如 50MB、512KB、1GB）", cfg.SMTP.MaxSize)
+	}
+
+	// 外发中继：启用后必须有主机和端口
+	if cfg.SMTP.Relay.Enabled {
+		if cfg.SMTP.Relay.Host == "" {
+			fail("smtp.relay.host", "启用中继时必须配置中继服务器地址")
+		}
+		if cfg.SMTP.Relay.Port < 1 || cfg.SMTP.Relay.Port > 65535 {
+			fail("smtp.relay.port", "启用中继时必须配置有效端口（通常为 587 或 465）")
+		}
+		if (cfg.SMTP.Relay.Username == "") != (cfg.SMTP.Relay.Password == "") {
+			fail("smtp.relay.password", "中继用户名和密码必须同时配置")
+		}
+		if (cfg.SMTP.Relay.ClientCert == "") != (cfg.SMTP.Relay.ClientKey == "") {
+			fail("smtp.relay.client_key", "中继客户端证书和私钥必须同时配置")
+		} else if cfg.SMTP.Relay.ClientCert != "" {
+			if !cfg.SMTP.Relay.UseTLS {
+				fail("smtp.relay.use_tls", "使用客户端证书认证时必须启用 TLS")
+			}
+			if _, err := os.Stat(cfg.SMTP.Relay.ClientCert); err != nil {
+				fail("smtp.relay.client_cert", "证书文件不存在: %v", err)
+			}
+			if _, err := os.Stat(cfg.SMTP.Relay.ClientKey); err != nil {
+				fail("smtp.relay.client_key", "私钥文件不存在: %v", err)
+			}
+		}
+		if cfg.SMTP.Relay.SkipDKIM && cfg.SMTP.Relay.DKIM.Enabled {
+			fail("smtp.relay.skip_dkim", "不能同时启用 smtp.relay.dkim")
+		}
+		validateDKIM("smtp.relay.dkim", &cfg.SMTP.Relay.DKIM)
+	}
+
+	if cfg.AntiSpam.Tarpit.Enabled {
+		if cfg.AntiSpam.Tarpit.Threshold < 1 {
+			fail("antispam.tarpit.threshold", "必须大于 0")
+		}
+		if cfg.AntiSpam.Tarpit.Window <= 0 {
+			fail("antispam.tarpit.window", "必须大于 0（如 10m）")
+		}
+		if cfg.AntiSpam.Tarpit.Delay <= 0 {
+			fail("antispam.tarpit.delay", "必须大于 0（如 5s）")
+		}
+		if cfg.AntiSpam.Tarpit.MaxDelay < cfg.AntiSpam.Tarpit.Delay {
+			fail("antispam.tarpit.max_delay", "不能小于 antispam.tarpit.delay")
+		}
+	}
+	if cfg.AntiSpam.Fingerprint.Enabled {
+		if cfg.AntiSpam.Fingerprint.Threshold < 1 || cfg.AntiSpam.Fingerprint.Threshold > 128 {
+			fail("antispam.fingerprint.threshold", "无效的相似度 %d（需要 1 ~ 128）", cfg.AntiSpam.Fingerprint.Threshold)
+		}
+		if cfg.AntiSpam.Fingerprint.Window <= 0 {
+			fail("antispam.fingerprint.window", "必须大于 0（如 720h）")
+		}
+	}
+	if cfg.AntiSpam.Bayes.Enabled && cfg.AntiSpam.Bayes.MinTraining < 1 {
+		fail("antispam.bayes.min_training", "必须大于 0")
+	}
+
+	if cfg.AntiSpam.RspamdURL != "" {
+		if u, err := url.Parse(cfg.AntiSpam.RspamdURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fail("antispam.rspamd_url", "必须是 http(s) URL（如 http://127.0.0.1:11333）")
+		}
+	}
+	if cfg.AntiSpam.RspamdTimeout < 0 {
+		fail("antispam.rspamd_timeout", "不能为负数")
+	}
+
+	validateDKIM("smtp.dkim", &cfg.SMTP.DKIM)
+
+	if cfg.SMTP.BATV.Enabled {
+		if len(cfg.SMTP.BATV.Domains) == 0 {
+			fail("smtp.batv.domains", "启用 BATV 时必须配置至少一个域名")
+		}
+		if len(cfg.SMTP.BATV.Keys) == 0 {
+			fail("smtp.batv.keys", "启用 BATV 时必须配置至少一个签名密钥")
+		}
+		keyIDs := make(map[int]bool)
+		for i, key := range cfg.SMTP.BATV.Keys {
+			field := fmt.Sprintf("smtp.batv.keys[%d]", i)
+			if key.ID < 0 || key.ID > 9 {
+				fail(field+".id", "无效的密钥编号 %d（需要 0 ~ 9）", key.ID)
+			} else if keyIDs[key.ID] {
+				fail(field+".id", "密钥编号 %d 重复", key.ID)
+			}
+			keyIDs[key.ID] = true
+			if key.Secret == "" {
+				fail(field+".secret", "密钥不能为空")
+			}
+		}
+	}
+
+	if cfg.SMTP.Transcripts.Enabled {
+		if len(cfg.SMTP.Transcripts.Domains) == 0 && len(cfg.SMTP.Transcripts.MessageIDs) == 0 {
+			fail("smtp.transcripts.domains", "启用外发会话记录时必须配置 domains 或 message_ids")
+		}
+		if cfg.SMTP.Transcripts.RetentionDays < 1 {
+			fail("smtp.transcripts.retention_days", "必须大于 0")
+		}
+		if cfg.SMTP.Relay.Enabled {
+			fail("smtp.transcripts.enabled", "只能记录直接投递到 MX 的会话，不能与 smtp.relay 同时启用")
+		}
+	}
+
+	for _, timeout := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"smtp.timeouts.command", cfg.SMTP.Timeouts.Command},
+		{"smtp.timeouts.data", cfg.SMTP.Timeouts.Data},
+		{"smtp.timeouts.idle", cfg.SMTP.Timeouts.Idle},
+	} {
+		if timeout.d < 0 || (timeout.d > 0 && timeout.d < time.Minute) {
+			fail(timeout.key, "必须为 0（不限制）或至少 1m")
+		}
+	}
+
+	if cfg.SMTP.Identities.Enabled && cfg.SMTP.Identities.EncryptionKey == "" {
+		fail("smtp.identities.encryption_key", "启用外部发件身份时必须配置（用于加密外部 SMTP 密码）")
+	}
+
+	if cfg.Fetchmail.Enabled {
+		if cfg.Fetchmail.EncryptionKey == "" {
+			fail("fetchmail.encryption_key", "启用外部邮箱拉取时必须配置（用于加密远程账户密码）")
+		}
+		if cfg.Fetchmail.Interval < time.Minute {
+			fail("fetchmail.interval", "不能小于 1m")
+		}
+		if cfg.Fetchmail.MaxMessages < 1 {
+			fail("fetchmail.max_messages", "必须大于 0")
+		}
+	}
+
+	if cfg.LMTP.Enabled {
+		if cfg.LMTP.Socket == "" && cfg.LMTP.Address == "" {
+			fail("lmtp.socket", "启用 LMTP 时必须配置 socket 或 address")
+		}
+		if cfg.LMTP.Address != "" {
+			if _, _, err := net.SplitHostPort(cfg.LMTP.Address); err != nil {
+				fail("lmtp.address", "无效的监听地址 %q: %v", cfg.LMTP.Address, err)
+			}
+		}
+	}
+
+	if cfg.Replication.Enabled || cfg.Replication.Primary != "" {
+		if len(cfg.Replication.Token) < 16 {
+			fail("replication.token", "启用热备复制时必须配置，且不少于 16 个字符")
+		}
+	}
+	if cfg.Replication.Enabled {
+		if cfg.Replication.Listen == "" {
+			fail("replication.listen", "启用热备复制时必须配置")
+		}
+		if cfg.Replication.Retention < time.Hour {
+			fail("replication.retention", "不能小于 1h")
+		}
+		if cfg.Replication.TLS && !cfg.TLS.Enabled {
+			fail("replication.tls", "需要启用 tls.enabled 以提供服务器证书（或设置 replication.tls: false，仅用于内网）")
+		}
+	}
+
+	if cfg.Limits.Enabled {
+		if cfg.Limits.MaxConnectionsPerIP < 0 {
+			fail("limits.max_connections_per_ip", "不能为负数（0 不限制）")
+		}
+		if cfg.Limits.MaxAuthFailures < 1 {
+			fail("limits.max_auth_failures", "必须大于 0")
+		}
+		if cfg.Limits.Window <= 0 {
+			fail("limits.window", "必须大于 0（如 10m）")
+		}
+		if cfg.Limits.BanDuration <= 0 {
+			fail("limits.ban_duration", "必须大于 0（如 15m）")
+		}
+		for _, entry := range cfg.Limits.Exempt {
+			if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+				fail("limits.exempt", "无效的 IP 地址或网段 %q", entry)
+			}
+		}
+	}
+	if cfg.IMAP.MaxAuthErrors < 0 {
+		fail("imap.max_auth_errors", "不能为负数（0 不限制）")
+	}
+	if d := cfg.IMAP.Timeouts.Idle; d < 0 || (d > 0 && d < 30*time.Minute) {
+		fail("imap.timeouts.idle", "必须为 0（不限制）或至少 30m（RFC 3501 5.4）")
+	}
+	if d := cfg.IMAP.Timeouts.Login; d < 0 || (d > 0 && d < 10*time.Second) {
+		fail("imap.timeouts.login", "必须为 0（不限制）或至少 10s")
+	}
+	if strings.EqualFold(cfg.IMAP.SubmissionFolder, "INBOX") {
+		fail("imap.submission_folder", "不能是 INBOX")
+	}
+	if strings.ContainsAny(cfg.IMAP.Greeting, "\r\n") {
+		fail("imap.greeting", "不能包含换行")
+	}
+
+	if cfg.SASL.OAuth.JWKSURL != "" {
+		if u, err := url.Parse(cfg.SASL.OAuth.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fail("sasl.oauth.jwks_url", "无效的地址 %q（需要 http:// 或 https://）", cfg.SASL.OAuth.JWKSURL)
+		}
+	}
+	if cfg.SASL.OAuthBearer || cfg.SASL.XOAuth2 {
+		// 默认的 JWT 密钥是公开的，不能用来验证令牌
+		if cfg.Admin.JWTSecret == "" && cfg.SASL.OAuth.JWKSURL == "" {
+			fail("sasl.oauthbearer", "令牌认证需要设置 admin.jwt_secret 或 sasl.oauth.jwks_url")
+		}
+	}
+
+	for _, t := range cfg.Quota.WarningThresholds {
+		if t < 1 || t > 100 {
+			fail("quota.warning_thresholds", "无效的阈值 %d（需要 1 ~ 100 的用量百分比）", t)
+		}
+	}
+	if cfg.Quota.CheckInterval < time.Minute {
+		fail("quota.check_interval", "不能小于 1m")
+	}
+
+	if cfg.Digest.Enabled {
+		if cfg.Digest.Hour < 0 || cfg.Digest.Hour > 23 {
+			fail("digest.hour", "无效的时间 %d（需要 0 ~ 23）", cfg.Digest.Hour)
+		}
+		if cfg.Digest.CheckInterval < time.Minute {
+			fail("digest.check_interval", "不能小于 1m")
+		}
+	}
+
+	if cfg.Warmup.Enabled {
+		if _, err := time.Parse(time.DateOnly, cfg.Warmup.StartDate); err != nil {
+			fail("warmup.start_date", "无效的日期 %q（需要 YYYY-MM-DD）", cfg.Warmup.StartDate)
+		}
+		if cfg.Warmup.Weeks < 1 {
+			fail("warmup.weeks", "必须大于 0")
+		}
+		if cfg.Warmup.InitialDailyLimit < 1 {
+			fail("warmup.initial_daily_limit", "必须大于 0")
+		}
+		if cfg.Warmup.MaxDailyLimit < cfg.Warmup.InitialDailyLimit {
+			fail("warmup.max_daily_limit", "不能小于 initial_daily_limit")
+		}
+		if cfg.Warmup.CheckInterval < time.Minute {
+			fail("warmup.check_interval", "不能小于 1m")
+		}
+	}
+
+	if cfg.WebPush.Enabled {
+		if !cfg.Storage.WatchMaildir {
+			fail("webpush.enabled", "需要启用 storage.watch_maildir（新邮件事件来自 Maildir 监视器）")
+		}
+		if !strings.HasPrefix(cfg.WebPush.Subject, "mailto:") && !strings.HasPrefix(cfg.WebPush.Subject, "https://") {
+			fail("webpush.subject", "需要 mailto: 地址或 https:// 网址（如 mailto:postmaster@%s）", cfg.Domain)
+		}
+		if cfg.WebPush.KeyFile == "" {
+			fail("webpush.key_file", "不能为空")
+		}
+		switch cfg.WebPush.Privacy {
+		case "full", "sender", "none":
+		default:
+			fail("webpush.privacy", "未知的通知内容 %q（可选: full, sender, none）", cfg.WebPush.Privacy)
+		}
+		if cfg.WebPush.TTL <= 0 {
+			fail("webpush.ttl", "必须大于 0（如 24h）")
+		}
+	}
+
+	if cfg.ContactForm.Enabled {
+		if !cfg.WebMail.Enabled {
+			fail("contact_form.enabled", "需要启用 webmail（表单提交到 WebMail 的 /api/public/contact/:domain）")
+		}
+		switch cfg.ContactForm.Captcha {
+		case "turnstile", "hcaptcha":
+		default:
+			fail("contact_form.captcha", "未知的人机验证服务 %q（可选: turnstile, hcaptcha）", cfg.ContactForm.Captcha)
+		}
+		if cfg.ContactForm.SecretKey == "" {
+			fail("contact_form.secret_key", "不能为空（人机验证服务的服务端密钥）")
+		}
+		if cfg.ContactForm.VerifyURL != "" && !strings.HasPrefix(cfg.ContactForm.VerifyURL, "https://") {
+			fail("contact_form.verify_url", "必须是 https:// 网址")
+		}
+		if cfg.ContactForm.IPLimit <= 0 {
+			fail("contact_form.ip_limit", "必须大于 0")
+		}
+		if cfg.ContactForm.DomainLimit <= 0 {
+			fail("contact_form.domain_limit", "必须大于 0")
+		}
+		if cfg.ContactForm.MaxMessageSize <= 0 {
+			fail("contact_form.max_message_size", "必须大于 0")
+		}
+		if len(cfg.ContactForm.Forms) == 0 {
+			fail("contact_form.forms", "至少需要一个表单")
+		}
+		seen := make(map[string]bool)
+		for i, form := range cfg.ContactForm.Forms {
+			key := fmt.Sprintf("contact_form.forms[%d]", i)
+			domain := strings.ToLower(form.Domain)
+			if domain == "" {
+				fail(key+".domain", "不能为空")
+			} else if seen[domain] {
+				fail(key+".domain", "域名 %s 重复", form.Domain)
+			}
+			seen[domain] = true
+			if !strings.Contains(form.To, "@") {
+				fail(key+".to", "需要投递留言的本地邮箱地址")
+			}
+			for _, origin := range form.AllowedOrigins {
+				if !strings.HasPrefix(origin, "https://") && !strings.HasPrefix(origin, "http://") {
+					fail(key+".allowed_origins", "来源 %q 需要以 https:// 或 http:// 开头", origin)
+				}
+			}
+			if form.RedirectURL != "" && !strings.HasPrefix(form.RedirectURL, "https://") && !strings.HasPrefix(form.RedirectURL, "http://") {
+				fail(key+".redirect_url", "需要以 https:// 或 http:// 开头")
+			}
+		}
+	}
+
+	if cfg.Activity.Enabled && cfg.Activity.RetentionDays < 1 {
+		fail("activity.retention_days", "必须大于 0")
+	}
+
+	if cfg.Banner.Terms != "" && cfg.Banner.TermsVersion == "" {
+		fail("banner.terms_version", "配置使用条款时必须指定版本")
+	}
+	if cfg.Banner.Terms == "" && cfg.Banner.TermsVersion != "" {
+		fail("banner.terms", "指定了版本但没有配置使用条款")
+	}
+
+	if cfg.WebAuthn.Enabled {
+		if cfg.WebAuthn.RPID == "" && cfg.Domain == "" {
+			fail("webauthn.rp_id", "启用 WebAuthn 时必须配置 rp_id 或 domain")
+		}
+		if len(cfg.WebAuthn.RPOrigins) == 0 {
+			fail("webauthn.rp_origins", "启用 WebAuthn 时必须配置至少一个 Origin")
+		}
+		for _, origin := range cfg.WebAuthn.RPOrigins {
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				fail("webauthn.rp_origins", "无效的 Origin %q（如 https://mail.example.com）", origin)
+			}
+		}
+	}
+
+	if cfg.Chaos.Enabled {
+		if cfg.Chaos.Latency < 0 {
+			fail("chaos.latency", "不能为负数")
+		}
+		if cfg.Chaos.ErrorRate < 0 || cfg.Chaos.ErrorRate > 1 {
+			fail("chaos.error_rate", "必须在 0 到 1 之间")
+		}
+		if cfg.Chaos.PartialWriteRate < 0 || cfg.Chaos.PartialWriteRate > 1 {
+			fail("chaos.partial_write_rate", "必须在 0 到 1 之间")
+		}
+	}
+
+	if cfg.Provisioning.Path != "" {
+		if _, err := os.Stat(cfg.Provisioning.Path); err != nil {
+			fail("provisioning.path", "初始化数据文件不可用: %v", err)
+		}
+	}
+
+	switch cfg.Log.Level {
+	case "", "trace", "debug", "info", "warn", "error", "fatal", "panic":
+	default:
+		fail("log.level", "无效的日志级别 %q（可选值 trace, debug, info, warn, error, fatal, panic）", cfg.Log.Level)
+	}
+	switch cfg.Log.Format {
+	case "", "json", "text":
+	default:
+		fail("log.format", "无效的日志格式 %q（可选值 json, text）", cfg.Log.Format)
+	}
+
+	// 启用自签名模式时，未配置证书文件会自动生成证书
+	selfSignedFallback := cfg.TLS.SelfSigned.Enabled && cfg.TLS.CertFile == "" && cfg.TLS.KeyFile == ""
+	if cfg.TLS.Enabled && !cfg.TLS.ACME.Enabled && len(cfg.TLS.SNI) == 0 && !selfSignedFallback {
+		if cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "" {
+			fail("tls.cert_file", "TLS 已启用但未配置证书文件（可配置 tls.cert_file/tls.key_file、启用 tls.acme，实验环境可启用 tls.self_signed）")
+		} else {
+			// 检查证书文件是否存在
+			if _, err := os.Stat(cfg.TLS.CertFile); err != nil {
+				fail("tls.cert_file", "证书文件不存在: %v", err)
+			}
+			if _, err := os.Stat(cfg.TLS.KeyFile); err != nil {
+				fail("tls.key_file", "密钥文件不存在: %v", err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
 }
 
 // Watch 监听配置文件变化
@@ -302,8 +1579,12 @@ func Watch(path string, callback func(*Config) error) error {
 	v := viper.New()
 	v.SetConfigFile(path)
 	v.SetConfigType("yaml")
-	v.SetEnvPrefix("GMZ")
+	v.SetEnvPrefix(envPrefix)
 	v.AutomaticEnv()
+	if err := bindEnvs(v); err != nil {
+		return fmt.Errorf("绑定环境变量失败: %w", err)
+	}
+	setDefaults(v)
 
 	if err := v.ReadInConfig(); err != nil {
 		return fmt.Errorf("读取配置文件失败: %w", err)
@@ -312,7 +1593,7 @@ func Watch(path string, callback func(*Config) error) error {
 	v.WatchConfig()
 	v.OnConfigChange(func(e fsnotify.Event) {
 		var cfg Config
-		if err := v.Unmarshal(&cfg); err != nil {
+		if err := v.UnmarshalExact(&cfg); err != nil {
 			// 使用标准输出记录错误（避免循环依赖）
 			fmt.Fprintf(os.Stderr, "配置热更新失败: 解析错误: %v\n", err)
 			return
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -120,6 +122,22 @@ func TestSetDefaults(t *testing.T) {
 	if !cfg.IMAP.Enabled {
 		t.Error("IMAP.Enabled 应该默认为 true")
 	}
+	if !cfg.TLS.SessionTickets {
+		t.Error("TLS.SessionTickets 应该默认为 true")
+	}
+	if !cfg.TLS.WatchFiles {
+		t.Error("TLS.WatchFiles 应该默认为 true")
+	}
+	if cfg.TLS.HTTP.Enabled {
+		t.Error("TLS.HTTP.Enabled 应该默认为 false")
+	}
+	if cfg.TLS.ACME.Challenge != "http-01" || cfg.TLS.ACME.RenewBefore != 30*24*time.Hour || cfg.TLS.ACME.CheckInterval != 12*time.Hour {
+		t.Errorf("ACME 默认值不正确: challenge = %q, renew_before = %v, check_interval = %v",
+			cfg.TLS.ACME.Challenge, cfg.TLS.ACME.RenewBefore, cfg.TLS.ACME.CheckInterval)
+	}
+	if len(cfg.Quota.WarningThresholds) != 2 || cfg.Quota.WarningThresholds[0] != 80 || cfg.Quota.WarningThresholds[1] != 95 {
+		t.Errorf("Quota.WarningThresholds = %v, want [80 95]", cfg.Quota.WarningThresholds)
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -129,35 +147,1004 @@ func TestValidate(t *testing.T) {
 		wantError bool
 	}{
 		{
-			name: "valid config",
+			name: "valid config",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  dsn: /tmp/test.db
+`,
+			wantError: false,
+		},
+		{
+			name: "empty domain",
+			config: `
+domain: ""
+storage:
+  driver: sqlite
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid driver",
+			config: `
+domain: example.com
+storage:
+  driver: invalid
+`,
+			wantError: true,
+		},
+		{
+			name: "TLS enabled without cert files",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  acme:
+    enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "TLS enabled with self-signed bootstrap",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  acme:
+    enabled: false
+  self_signed:
+    enabled: true
+`,
+			wantError: false,
+		},
+		{
+			name: "invalid mail TLS min version",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  mail:
+    min_version: "1.4"
+`,
+			wantError: true,
+		},
+		{
+			name: "unknown key",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+smtpp:
+  enabled: true
+`,
+			wantError: true,
+		},
+		{
+			name: "relay enabled without host",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  relay:
+    enabled: true
+    port: 587
+`,
+			wantError: true,
+		},
+		{
+			name: "relay client certificate without key",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  relay:
+    enabled: true
+    host: relay.example.net
+    port: 587
+    use_tls: true
+    client_cert: relay.crt
+`,
+			wantError: true,
+		},
+		{
+			name: "relay DKIM override without private key",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  relay:
+    enabled: true
+    host: relay.example.net
+    port: 587
+    dkim:
+      enabled: true
+      selector: relay
+`,
+			wantError: true,
+		},
+		{
+			name: "BATV duplicate key id",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  batv:
+    enabled: true
+    domains: [example.com]
+    keys:
+      - id: 1
+        secret: new
+      - id: 1
+        secret: old
+`,
+			wantError: true,
+		},
+		{
+			name: "valid SMTP transcripts",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  transcripts:
+    enabled: true
+    message_ids: ["<m1@example.com>"]
+`,
+			wantError: false,
+		},
+		{
+			name: "SMTP transcripts without domains",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  transcripts:
+    enabled: true
+`,
+			wantError: true,
+		},
+		{
+			name: "SMTP transcripts with relay",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  relay:
+    enabled: true
+    host: smtp.relay.test
+    port: 587
+  transcripts:
+    enabled: true
+    domains: [gmail.com]
+`,
+			wantError: true,
+		},
+		{
+			name: "port conflict",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+imap:
+  port: 8080
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid DKIM canonicalization",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  dkim:
+    enabled: true
+    selector: mail
+    private_key: dkim.pem
+    canonicalization: relaxed/loose
+`,
+			wantError: true,
+		},
+		{
+			name: "require STARTTLS without TLS",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  require_tls_ports: [587]
+`,
+			wantError: true,
+		},
+		{
+			name: "submission on MX port",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  submission_ports: [25, 587]
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid max size",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  max_size: 50M
+`,
+			wantError: true,
+		},
+		{
+			name: "identities without encryption key",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  identities:
+    enabled: true
+`,
+			wantError: true,
+		},
+		{
+			name: "replication without tls certificate",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+replication:
+  enabled: true
+  token: 0123456789abcdef
+`,
+			wantError: true,
+		},
+		{
+			name: "replication standby with short token",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+replication:
+  primary: mail1.example.com:7420
+  token: short
+`,
+			wantError: true,
+		},
+		{
+			name: "limits with invalid exempt entry",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+limits:
+  exempt: ["localhost"]
+`,
+			wantError: true,
+		},
+		{
+			name: "sasl oauthbearer without jwt secret or jwks url",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+sasl:
+  oauthbearer: true
+`,
+			wantError: true,
+		},
+		{
+			name: "sasl xoauth2 with jwks url",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+sasl:
+  xoauth2: true
+  oauth:
+    jwks_url: https://idp.example.com/.well-known/jwks.json
+`,
+			wantError: false,
+		},
+		{
+			name: "sasl with invalid jwks url",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+sasl:
+  oauth:
+    jwks_url: idp.example.com/jwks
+`,
+			wantError: true,
+		},
+		{
+			name: "antispam with invalid rspamd url",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+antispam:
+  rspamd_url: 127.0.0.1:11333
+`,
+			wantError: true,
+		},
+		{
+			name: "antispam with rspamd",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+antispam:
+  rspamd_url: http://127.0.0.1:11333
+  rspamd_timeout: 2s
+`,
+			wantError: false,
+		},
+		{
+			name: "chaos with invalid error rate",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+chaos:
+  enabled: true
+  error_rate: 1.5
+`,
+			wantError: true,
+		},
+		{
+			name: "ACME dns-01 without provider",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  acme:
+    enabled: true
+    challenge: dns-01
+`,
+			wantError: true,
+		},
+		{
+			name: "ACME dns-01 with rfc2136",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  acme:
+    enabled: true
+    challenge: dns-01
+    dns:
+      provider: rfc2136
+      rfc2136:
+        server: ns1.example.com
+        tsig_key: acme
+        tsig_secret: c2VjcmV0
+  sni:
+    - hostname: "*.example.com"
+      acme: true
+`,
+			wantError: false,
+		},
+		{
+			name: "ACME wildcard without dns-01",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  acme:
+    enabled: true
+    challenge: tls-alpn-01
+  sni:
+    - hostname: "*.example.com"
+      acme: true
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid quota warning threshold",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+quota:
+  warning_thresholds: [80, 120]
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid digest hour",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+digest:
+  enabled: true
+  hour: 24
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid fingerprint threshold",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+antispam:
+  fingerprint:
+    enabled: true
+    threshold: 200
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid bayes min training",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+antispam:
+  bayes:
+    enabled: true
+    min_training: 0
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid warmup start date",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+warmup:
+  enabled: true
+  start_date: 2026/01/05
+`,
+			wantError: true,
+		},
+		{
+			name: "zero access with invalid unlock ttl",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  zero_access:
+    enabled: true
+    unlock_ttl: 0s
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "webpush enabled",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  watch_maildir: true
+webpush:
+  enabled: true
+  subject: mailto:postmaster@example.com
+tls:
+  enabled: false
+`,
+			wantError: false,
+		},
+		{
+			name: "webpush without maildir watcher",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+webpush:
+  enabled: true
+  subject: mailto:postmaster@example.com
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "webpush with invalid privacy",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  watch_maildir: true
+webpush:
+  enabled: true
+  subject: https://example.com/contact
+  privacy: everything
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "contact form enabled",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+contact_form:
+  enabled: true
+  secret_key: "0x4AAAAAAA"
+  forms:
+    - domain: example.com
+      to: info@example.com
+      allowed_origins: ["https://www.example.com"]
+tls:
+  enabled: false
+`,
+			wantError: false,
+		},
+		{
+			name: "contact form with duplicate domain",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+contact_form:
+  enabled: true
+  secret_key: "0x4AAAAAAA"
+  forms:
+    - domain: example.com
+      to: info@example.com
+    - domain: Example.com
+      to: sales@example.com
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "contact form without secret key",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+contact_form:
+  enabled: true
+  captcha: hcaptcha
+  forms:
+    - domain: example.com
+      to: info@example.com
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "fetchmail without encryption key",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+fetchmail:
+  enabled: true
+  interval: 5m
+`,
+			wantError: true,
+		},
+		{
+			name: "listener on unknown port",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25]
+  listeners:
+    - port: 587
+      hostname: mail.brand-a.com
+`,
+			wantError: true,
+		},
+		{
+			name: "listener with trusted upstreams",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25]
+  listeners:
+    - port: 25
+      trusted_upstreams: [192.0.2.10, 198.51.100.0/24]
+      trusted_authserv_ids: [gateway.example.com]
+`,
+			wantError: false,
+		},
+		{
+			name: "listener with invalid trusted upstream",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25]
+  listeners:
+    - port: 25
+      trusted_upstreams: [gateway.example.com]
+`,
+			wantError: true,
+		},
+		{
+			name: "listener with greet delay",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25, 465]
+  listeners:
+    - port: 25
+      greet_delay: 5s
+      early_talker: junk
+      early_talker_whitelist: [192.0.2.10, 198.51.100.0/24]
+`,
+			wantError: false,
+		},
+		{
+			name: "greet delay on implicit TLS port",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25, 465]
+  listeners:
+    - port: 465
+      greet_delay: 5s
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid early talker action",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25, 465]
+  listeners:
+    - port: 25
+      greet_delay: 5s
+      early_talker: drop
+`,
+			wantError: true,
+		},
+		{
+			name: "invalid vrfy policy",
 			config: `
 domain: example.com
 storage:
   driver: sqlite
-  dsn: /tmp/test.db
+tls:
+  enabled: false
+smtp:
+  vrfy: allow
+`,
+			wantError: true,
+		},
+		{
+			name: "greet delay too long",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  ports: [25, 465]
+  listeners:
+    - port: 25
+      greet_delay: 10m
+`,
+			wantError: true,
+		},
+		{
+			name: "session timeouts",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  timeouts:
+    command: 10m
+    data: 5m
+    idle: 0s
+imap:
+  timeouts:
+    idle: 45m
+    login: 30s
 `,
 			wantError: false,
 		},
 		{
-			name: "empty domain",
+			name: "smtp command timeout too short",
 			config: `
-domain: ""
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  timeouts:
+    command: 10s
+`,
+			wantError: true,
+		},
+		{
+			name: "imap idle timeout below RFC minimum",
+			config: `
+domain: example.com
 storage:
   driver: sqlite
+tls:
+  enabled: false
+imap:
+  timeouts:
+    idle: 10m
 `,
 			wantError: true,
 		},
 		{
-			name: "invalid driver",
+			name: "activity without retention",
 			config: `
 domain: example.com
 storage:
-  driver: invalid
+  driver: sqlite
+tls:
+  enabled: false
+activity:
+  retention_days: 0
 `,
 			wantError: true,
 		},
 		{
-			name: "TLS enabled without cert files",
+			name: "terms without version",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+banner:
+  terms: 仅限授权人员使用
+`,
+			wantError: true,
+		},
+		{
+			name: "terms with version",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+banner:
+  notice: 本系统仅限授权人员使用
+  terms: 使用本系统即表示同意遵守信息安全管理规定
+  terms_version: "2024-06"
+`,
+			wantError: false,
+		},
+		{
+			name: "maildir sharding out of range",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  maildir_sharding: 4
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "maildir sharding",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  maildir_sharding: 2
+tls:
+  enabled: false
+`,
+			wantError: false,
+		},
+		{
+			name: "webauthn without origins",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+webauthn:
+  enabled: true
+`,
+			wantError: true,
+		},
+		{
+			name: "webauthn origin with path",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+webauthn:
+  enabled: true
+  rp_origins: ["https://mail.example.com/webmail"]
+`,
+			wantError: true,
+		},
+		{
+			name: "webauthn",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+webauthn:
+  enabled: true
+  rp_origins: ["https://mail.example.com", "https://admin.example.com:8081"]
+`,
+			wantError: false,
+		},
+		{
+			name: "imap submission folder is inbox",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+imap:
+  submission_folder: inbox
+`,
+			wantError: true,
+		},
+		{
+			name: "imap submission folder",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+imap:
+  submission_folder: Outbox
+`,
+			wantError: false,
+		},
+		{
+			name: "imap greeting with newline",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+imap:
+  greeting: "Example Mail\r\n* OK fake"
+`,
+			wantError: true,
+		},
+		{
+			name: "inbound and submission share a port",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: false
+smtp:
+  inbound:
+    ports: [25, 587]
+  submission:
+    ports: [587]
+`,
+			wantError: true,
+		},
+		{
+			name: "submission requires tls without tls",
 			config: `
 domain: example.com
 storage:
@@ -165,10 +1152,109 @@ storage:
 tls:
   enabled: true
   acme:
-    enabled: false
+    enabled: true
+    email: admin@example.com
+smtp:
+  submission:
+    ports: [587]
+    tls: false
+    require_tls: true
+`,
+			wantError: true,
+		},
+		{
+			name: "lmtp without socket or address",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+lmtp:
+  enabled: true
+`,
+			wantError: true,
+		},
+		{
+			name: "lmtp on unix socket",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+lmtp:
+  enabled: true
+  socket: /var/run/gmz/lmtp.sock
+`,
+			wantError: false,
+		},
+		{
+			name: "remote storage without address",
+			config: `
+domain: example.com
+storage:
+  driver: remote
+  rpc:
+    token: storage-token-0123456789
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "remote storage with short token",
+			config: `
+domain: example.com
+storage:
+  driver: remote
+  rpc:
+    address: store.internal:7421
+    token: short
+tls:
+  enabled: false
+`,
+			wantError: true,
+		},
+		{
+			name: "remote storage",
+			config: `
+domain: example.com
+storage:
+  driver: remote
+  rpc:
+    address: store.internal:7421
+    token: storage-token-0123456789
+tls:
+  enabled: false
+`,
+			wantError: false,
+		},
+		{
+			name: "storage service tls without certificate",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  rpc:
+    enabled: true
+    token: storage-token-0123456789
+tls:
+  enabled: false
 `,
 			wantError: true,
 		},
+		{
+			name: "storage service without tls",
+			config: `
+domain: example.com
+storage:
+  driver: sqlite
+  rpc:
+    enabled: true
+    token: storage-token-0123456789
+    tls: false
+tls:
+  enabled: false
+`,
+			wantError: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -193,3 +1279,97 @@ tls:
 		})
 	}
 }
+
+func TestLoadSMTPSections(t *testing.T) {
+	path := t.TempDir() + "/gmz.yml"
+	config := `
+domain: example.com
+storage:
+  driver: sqlite
+tls:
+  enabled: true
+  acme:
+    enabled: true
+    email: admin@example.com
+smtp:
+  inbound:
+    ports: [25]
+    max_size: 20MB
+  submission:
+    ports: [465, 587]
+    require_tls: true
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() 失败: %v", err)
+	}
+
+	// 接收和提交端口生成按端口的配置，替代默认的 ports、submission_ports 和 require_tls_ports
+	if !reflect.DeepEqual(cfg.SMTP.Ports, []int{25, 465, 587}) {
+		t.Errorf("Ports = %v", cfg.SMTP.Ports)
+	}
+	if !reflect.DeepEqual(cfg.SMTP.SubmissionPorts, []int{465, 587}) {
+		t.Errorf("SubmissionPorts = %v", cfg.SMTP.SubmissionPorts)
+	}
+	if !reflect.DeepEqual(cfg.SMTP.RequireTLSPorts, []int{465, 587}) {
+		t.Errorf("RequireTLSPorts = %v", cfg.SMTP.RequireTLSPorts)
+	}
+	// 未配置的设置使用默认值：提供 TLS，接收端口不提供 AUTH，启用反垃圾和限流
+	in := cfg.SMTP.Inbound
+	if !in.TLS || in.Auth || !in.AntiSpam || !in.RateLimit || in.MaxSize != "20MB" {
+		t.Errorf("Inbound = %+v", in)
+	}
+
+	// 不配置接收和提交端口时保持旧的配置方式
+	if err := os.WriteFile(path, []byte("domain: example.com\ntls:\n  enabled: false\nsmtp:\n  ports: [2525]\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() 失败: %v", err)
+	}
+	if cfg.SMTP.Sectioned() || !reflect.DeepEqual(cfg.SMTP.Ports, []int{2525}) {
+		t.Errorf("旧配置方式的 Ports = %v", cfg.SMTP.Ports)
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("GMZ_DOMAIN", "env.example.com")
+	t.Setenv("GMZ_TLS_ENABLED", "false")
+	t.Setenv("GMZ_SMTP_RELAY_PASSWORD", "relay-secret")
+	t.Setenv("GMZ_SMTP_RELAY_PORT", "587")
+	t.Setenv("GMZ_ADMIN_API_KEY", "api-key")
+
+	// 配置文件不存在时仅使用默认值和环境变量
+	cfg, err := Load(t.TempDir() + "/missing.yml")
+	if err != nil {
+		t.Fatalf("Load() 失败: %v", err)
+	}
+
+	if cfg.Domain != "env.example.com" {
+		t.Errorf("Domain = %v, want env.example.com", cfg.Domain)
+	}
+	if cfg.SMTP.Relay.Password != "relay-secret" {
+		t.Errorf("SMTP.Relay.Password = %v, want relay-secret", cfg.SMTP.Relay.Password)
+	}
+	if cfg.SMTP.Relay.Port != 587 {
+		t.Errorf("SMTP.Relay.Port = %v, want 587", cfg.SMTP.Relay.Port)
+	}
+
+	redacted := cfg.Redacted()
+	if redacted.SMTP.Relay.Password == "relay-secret" || redacted.Admin.APIKey == "api-key" {
+		t.Error("Redacted() 应该隐藏敏感字段")
+	}
+	if cfg.SMTP.Relay.Password != "relay-secret" {
+		t.Error("Redacted() 不应该修改原配置")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("smtp.relay.password"); got != "GMZ_SMTP_RELAY_PASSWORD" {
+		t.Errorf("EnvName() = %v, want GMZ_SMTP_RELAY_PASSWORD", got)
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// envPrefix 环境变量前缀
+const envPrefix = "GMZ"
+
+// redactedValue 脱敏后显示的值
+const redactedValue = "******"
+
+// EnvName 返回配置键对应的环境变量名（如 smtp.relay.password -> GMZ_SMTP_RELAY_PASSWORD）
+func EnvName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// bindEnvs 为所有配置键显式绑定环境变量
+// viper 的 AutomaticEnv 只对已知键生效，嵌套键（没有默认值且不在配置文件中）需要显式绑定才能从环境变量读取
+func bindEnvs(v *viper.Viper) error {
+	for _, key := range configKeys(reflect.TypeOf(Config{}), "") {
+		if err := v.BindEnv(key, EnvName(key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configKeys 根据 mapstructure 标签列出结构体的所有叶子配置键
+func configKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, squash := mapstructureName(field)
+		if name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		if squash {
+			key = prefix
+		}
+
+		// 结构体递归展开；切片和基本类型作为叶子键（切片可用逗号分隔的环境变量值）
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, configKeys(field.Type, key)...)
+			continue
+		}
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			// 结构体列表（如 tls.sni）无法用单个环境变量表达
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// mapstructureName 解析字段的 mapstructure 名称和是否内联
+func mapstructureName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("mapstructure")
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "squash" {
+			return "", true
+		}
+	}
+	if parts[0] == "" {
+		return strings.ToLower(field.Name), false
+	}
+	return parts[0], false
+}
+
+// Redacted 返回敏感字段（带 redact:"true" 标签）已脱敏的配置副本
+func (c *Config) Redacted() *Config {
+	cfg := *c
+	cfg.TLS.SNI = append([]SNICertConfig(nil), c.TLS.SNI...)
+	redact(reflect.ValueOf(&cfg).Elem())
+	return &cfg
+}
+
+// redact 递归将带 redact 标签的非空字符串字段替换为占位符
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			redact(field)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.Struct {
+				for j := 0; j < field.Len(); j++ {
+					redact(field.Index(j))
+				}
+			}
+		case reflect.String:
+			if t.Field(i).Tag.Get("redact") == "true" && field.String() != "" {
+				field.SetString(redactedValue)
+			}
+		}
+	}
+}
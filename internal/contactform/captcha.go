@@ -0,0 +1,86 @@
+package contactform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 人机验证服务的默认验证地址
+const (
+	TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+	HCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+)
+
+// maxVerifyResponse 验证响应的最大字节数
+const maxVerifyResponse = 64 << 10
+
+// Verifier 验证访客提交的人机验证令牌
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// SiteVerifier 通过 siteverify 接口验证令牌（Cloudflare Turnstile 和 hCaptcha 的接口相同）
+type SiteVerifier struct {
+	URL    string
+	Secret string
+	// Client 为空时使用 10 秒超时的默认客户端
+	Client *http.Client
+}
+
+// NewSiteVerifier 创建人机验证服务的验证器（captcha 为 turnstile 或 hcaptcha），verifyURL 为空时使用服务的默认地址
+func NewSiteVerifier(captcha, secret, verifyURL string) *SiteVerifier {
+	if verifyURL == "" {
+		verifyURL = TurnstileVerifyURL
+		if captcha == "hcaptcha" {
+			verifyURL = HCaptchaVerifyURL
+		}
+	}
+	return &SiteVerifier{URL: verifyURL, Secret: secret}
+}
+
+// Verify 验证令牌，令牌无效时返回 ErrCaptcha，验证服务不可用时返回其他错误
+func (v *SiteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return fmt.Errorf("%w: 缺少验证令牌", ErrCaptcha)
+	}
+	form := url.Values{"secret": {v.Secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("创建人机验证请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := v.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("人机验证服务不可用: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("人机验证服务返回 %s", resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxVerifyResponse)).Decode(&result); err != nil {
+		return fmt.Errorf("解析人机验证结果失败: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrCaptcha, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+// Package contactform 公开联系表单：网站的联系表单提交到 POST /api/public/contact/:domain，
+// 通过人机验证（Cloudflare Turnstile 或 hCaptcha）后作为邮件投递到配置的本地邮箱，
+// 使用 gmz 的小网站不需要另外的表单转邮件服务。
+//
+// 每个 IP 和每个域名的提交次数按小时限制（令牌桶），访客的邮箱地址作为留言邮件的 Reply-To，
+// 收件人可以直接回复。
+package contactform
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	netmail "net/mail"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 字段长度上限（字符数）
+const (
+	maxNameLength    = 100
+	maxEmailLength   = 254
+	maxSubjectLength = 200
+	maxPageLength    = 2048
+)
+
+// defaultSubject 表单没有设置时的主题前缀
+const defaultSubject = "[联系表单]"
+
+// limitWindow 提交次数的统计窗口
+const limitWindow = time.Hour
+
+var (
+	// ErrUnknownForm 域名没有配置联系表单
+	ErrUnknownForm = errors.New("联系表单不存在")
+	// ErrInvalid 留言内容无效（缺少字段或超过长度）
+	ErrInvalid = errors.New("留言内容无效")
+	// ErrCaptcha 人机验证失败
+	ErrCaptcha = errors.New("人机验证失败")
+	// ErrRateLimited 提交过于频繁
+	ErrRateLimited = errors.New("提交过于频繁，请稍后再试")
+)
+
+// Form 一个域名的联系表单
+type Form struct {
+	Domain         string
+	To             string   // 投递留言的本地邮箱
+	AllowedOrigins []string // 允许提交的网站来源，为空时不限制
+	Subject        string   // 主题前缀
+	RedirectURL    string   // 普通 HTML 表单提交成功后跳转的页面
+}
+
+// AllowOrigin 是否允许来源为 origin 的网站提交（浏览器跨域请求的 Origin 头）
+func (f *Form) AllowOrigin(origin string) bool {
+	if len(f.AllowedOrigins) == 0 {
+		return true
+	}
+	for _, allowed := range f.AllowedOrigins {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Submission 访客提交的留言
+type Submission struct {
+	Name         string
+	Email        string
+	Subject      string
+	Message      string
+	Page         string // 提交留言的页面（Referer）
+	RemoteIP     string
+	CaptchaToken string
+}
+
+// Service 联系表单服务
+type Service struct {
+	Storage  storage.Driver
+	Maildir  *storage.Maildir
+	Verifier Verifier
+	// IPLimit 每个 IP 每小时最多提交次数，DomainLimit 每个域名每小时最多投递的留言数
+	IPLimit     int
+	DomainLimit int
+	// MaxMessageSize 留言的最大字节数
+	MaxMessageSize int
+
+	forms   map[string]*Form
+	limiter *antispam.RateLimiter
+}
+
+// New 创建联系表单服务
+func New(driver storage.Driver, maildir *storage.Maildir, verifier Verifier, forms []Form, ipLimit, domainLimit, maxMessageSize int) *Service {
+	s := &Service{
+		Storage:        driver,
+		Maildir:        maildir,
+		Verifier:       verifier,
+		IPLimit:        ipLimit,
+		DomainLimit:    domainLimit,
+		MaxMessageSize: maxMessageSize,
+		forms:          make(map[string]*Form, len(forms)),
+		limiter:        antispam.NewRateLimiter(),
+	}
+	for i := range forms {
+		form := forms[i]
+		form.Domain = strings.ToLower(form.Domain)
+		if form.Subject == "" {
+			form.Subject = defaultSubject
+		}
+		s.forms[form.Domain] = &form
+	}
+	return s
+}
+
+// Form 获取域名的联系表单
+func (s *Service) Form(domain string) (*Form, bool) {
+	form, ok := s.forms[strings.ToLower(domain)]
+	return form, ok
+}
+
+// Cleanup 定期清理长时间没有提交的 IP 和域名的计数，直到 ctx 取消
+func (s *Service) Cleanup(ctx context.Context) {
+	s.limiter.Cleanup(ctx)
+}
+
+// Submit 验证并投递留言：先检查字段和 IP 的提交次数，再进行人机验证（避免无效请求消耗验证服务的调用），
+// 最后检查域名的投递数
+func (s *Service) Submit(ctx context.Context, domain string, sub *Submission) error {
+	form, ok := s.Form(domain)
+	if !ok {
+		return ErrUnknownForm
+	}
+	if err := s.validate(sub); err != nil {
+		return err
+	}
+	if !s.limiter.CheckIP(sub.RemoteIP, s.IPLimit, limitWindow) {
+		return ErrRateLimited
+	}
+	if err := s.Verifier.Verify(ctx, sub.CaptchaToken, sub.RemoteIP); err != nil {
+		return err
+	}
+	if !s.limiter.CheckUser(form.Domain, s.DomainLimit, limitWindow) {
+		logger.WarnCtx(ctx).Str("domain", form.Domain).Msg("联系表单投递数超过上限")
+		return ErrRateLimited
+	}
+
+	if err := s.deliver(ctx, form, sub, time.Now()); err != nil {
+		return err
+	}
+	logger.InfoCtx(ctx).
+		Str("domain", form.Domain).
+		Str("to", form.To).
+		Str("ip", sub.RemoteIP).
+		Msg("已投递联系表单留言")
+	return nil
+}
+
+// validate 检查并规范化留言字段
+func (s *Service) validate(sub *Submission) error {
+	sub.Name = singleLine(sub.Name)
+	sub.Email = strings.TrimSpace(sub.Email)
+	sub.Subject = singleLine(sub.Subject)
+	sub.Page = singleLine(sub.Page)
+	sub.Message = strings.TrimSpace(sub.Message)
+
+	switch {
+	case sub.Email == "":
+		return fmt.Errorf("%w: 缺少邮箱地址", ErrInvalid)
+	case sub.Message == "":
+		return fmt.Errorf("%w: 缺少留言内容", ErrInvalid)
+	case utf8.RuneCountInString(sub.Name) > maxNameLength:
+		return fmt.Errorf("%w: 姓名超过 %d 个字符", ErrInvalid, maxNameLength)
+	case len(sub.Email) > maxEmailLength:
+		return fmt.Errorf("%w: 邮箱地址过长", ErrInvalid)
+	case utf8.RuneCountInString(sub.Subject) > maxSubjectLength:
+		return fmt.Errorf("%w: 主题超过 %d 个字符", ErrInvalid, maxSubjectLength)
+	case len(sub.Message) > s.MaxMessageSize:
+		return fmt.Errorf("%w: 留言超过 %d 字节", ErrInvalid, s.MaxMessageSize)
+	case !utf8.ValidString(sub.Message):
+		return fmt.Errorf("%w: 留言不是有效的 UTF-8 文本", ErrInvalid)
+	}
+	addr, err := netmail.ParseAddress(sub.Email)
+	if err != nil || addr.Name != "" {
+		return fmt.Errorf("%w: 邮箱地址格式错误", ErrInvalid)
+	}
+	sub.Email = addr.Address
+	if len(sub.Page) > maxPageLength {
+		sub.Page = ""
+	}
+	return nil
+}
+
+// deliver 把留言投递到表单的收件邮箱
+func (s *Service) deliver(ctx context.Context, form *Form, sub *Submission, now time.Time) error {
+	user, err := s.Storage.GetUser(ctx, form.To)
+	if err != nil {
+		return fmt.Errorf("联系表单的收件邮箱 %s 不存在: %w", form.To, err)
+	}
+	if !user.Active {
+		return fmt.Errorf("联系表单的收件邮箱 %s 已停用", form.To)
+	}
+
+	data, subject, err := buildMessage(form, sub, now)
+	if err != nil {
+		return err
+	}
+	m := &storage.Mail{
+		ID:         fmt.Sprintf("contact-%d", now.UnixNano()),
+		UserEmail:  user.Email,
+		Folder:     "INBOX",
+		From:       sender(form),
+		To:         []string{user.Email},
+		Subject:    subject,
+		Size:       int64(len(data)),
+		Flags:      []string{"\\Recent"},
+		ReceivedAt: now,
+		CreatedAt:  now,
+	}
+	return storage.NewMailStore(s.Maildir, s.Storage).Deliver(ctx, m, data)
+}
+
+// sender 留言邮件的发件地址（收件邮箱所在域名的 noreply，访客地址作为 Reply-To）
+func sender(form *Form) string {
+	return "noreply@" + form.To[strings.LastIndex(form.To, "@")+1:]
+}
+
+// buildMessage 生成留言邮件，返回邮件和主题
+func buildMessage(form *Form, sub *Submission, now time.Time) ([]byte, string, error) {
+	subject := sub.Subject
+	if subject == "" {
+		who := sub.Name
+		if who == "" {
+			who = sub.Email
+		}
+		subject = fmt.Sprintf("来自 %s 的留言", who)
+	}
+	subject = form.Subject + " " + subject
+
+	var body strings.Builder
+	body.WriteString(strings.ReplaceAll(sub.Message, "\r\n", "\n"))
+	body.WriteString("\n\n-- \n")
+	if sub.Name != "" {
+		fmt.Fprintf(&body, "姓名: %s\n", sub.Name)
+	}
+	fmt.Fprintf(&body, "邮箱: %s\n", sub.Email)
+	if sub.Page != "" {
+		fmt.Fprintf(&body, "页面: %s\n", sub.Page)
+	}
+	fmt.Fprintf(&body, "IP: %s\n", sub.RemoteIP)
+	fmt.Fprintf(&body, "表单: %s\n", form.Domain)
+
+	from := sender(form)
+	var h mail.Header
+	h.SetDate(now)
+	h.SetAddressList("From", []*mail.Address{{Name: sub.Name, Address: from}})
+	h.SetAddressList("To", []*mail.Address{{Address: form.To}})
+	h.SetAddressList("Reply-To", []*mail.Address{{Name: sub.Name, Address: sub.Email}})
+	h.SetSubject(subject)
+	if err := h.GenerateMessageIDWithHostname(from[strings.LastIndex(from, "@")+1:]); err != nil {
+		return nil, "", fmt.Errorf("生成 Message-ID 失败: %w", err)
+	}
+	h.Set("X-Contact-Form", form.Domain)
+	h.Set("MIME-Version", "1.0")
+	h.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	var buf bytes.Buffer
+	mw, err := message.CreateWriter(&buf, h.Header)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成留言邮件失败: %w", err)
+	}
+	if _, err := mw.Write([]byte(strings.ReplaceAll(body.String(), "\n", "\r\n"))); err != nil {
+		return nil, "", fmt.Errorf("生成留言邮件失败: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", fmt.Errorf("生成留言邮件失败: %w", err)
+	}
+	return buf.Bytes(), subject, nil
+}
+
+// singleLine 去掉控制字符（包括换行）和首尾空白，用于写入邮件头的字段
+func singleLine(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
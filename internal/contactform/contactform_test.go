@@ -0,0 +1,202 @@
+package contactform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// fakeVerifier 只接受令牌 ok
+type fakeVerifier struct {
+	calls int
+}
+
+func (v *fakeVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	v.calls++
+	if token != "ok" {
+		return fmt.Errorf("%w: invalid-input-response", ErrCaptcha)
+	}
+	return nil
+}
+
+func newTestService(t *testing.T, ipLimit, domainLimit int) (*Service, *storage.SQLiteDriver, *storage.Maildir, *fakeVerifier) {
+	t.Helper()
+	ctx := context.Background()
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "info@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := &fakeVerifier{}
+	forms := []Form{{Domain: "Example.com", To: "info@example.com", AllowedOrigins: []string{"https://www.example.com/"}}}
+	return New(driver, maildir, verifier, forms, ipLimit, domainLimit, 1000), driver, maildir, verifier
+}
+
+func TestSubmit(t *testing.T) {
+	ctx := context.Background()
+	s, driver, maildir, _ := newTestService(t, 10, 10)
+
+	err := s.Submit(ctx, "example.com", &Submission{
+		Name:         "张三\r\nBcc: victim@example.net",
+		Email:        "visitor@example.org",
+		Message:      "你好，想咨询一下价格。",
+		Page:         "https://www.example.com/contact",
+		RemoteIP:     "203.0.113.7",
+		CaptchaToken: "ok",
+	})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	mails, err := driver.ListMails(ctx, "info@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("收件箱应该有 1 封留言: %d, %v", len(mails), err)
+	}
+	if want := "[联系表单] 来自 张三  Bcc: victim@example.net 的留言"; mails[0].Subject != want {
+		t.Errorf("主题 = %q, want %q", mails[0].Subject, want)
+	}
+	data, err := maildir.ReadMail("info@example.com", "INBOX", mails[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := string(data)
+	header := raw[:strings.Index(raw, "\r\n\r\n")]
+	if !strings.Contains(header, "Reply-To:") || !strings.Contains(header, "<visitor@example.org>") {
+		t.Errorf("应该以访客地址作为 Reply-To:\n%s", header)
+	}
+	if strings.Contains(header, "\r\nBcc:") {
+		t.Errorf("姓名中的换行不应该产生新的邮件头:\n%s", header)
+	}
+	if !strings.Contains(header, "X-Contact-Form: example.com") {
+		t.Errorf("缺少 X-Contact-Form 头:\n%s", header)
+	}
+}
+
+func TestSubmitRejects(t *testing.T) {
+	ctx := context.Background()
+	valid := func() *Submission {
+		return &Submission{Email: "visitor@example.org", Message: "hi", RemoteIP: "203.0.113.7", CaptchaToken: "ok"}
+	}
+
+	tests := []struct {
+		name   string
+		domain string
+		modify func(*Submission)
+		want   error
+	}{
+		{"未配置的域名", "other.com", func(*Submission) {}, ErrUnknownForm},
+		{"缺少邮箱", "example.com", func(s *Submission) { s.Email = "" }, ErrInvalid},
+		{"邮箱格式错误", "example.com", func(s *Submission) { s.Email = "not an address" }, ErrInvalid},
+		{"缺少留言", "example.com", func(s *Submission) { s.Message = "  " }, ErrInvalid},
+		{"留言过长", "example.com", func(s *Submission) { s.Message = strings.Repeat("a", 1001) }, ErrInvalid},
+		{"人机验证失败", "example.com", func(s *Submission) { s.CaptchaToken = "bad" }, ErrCaptcha},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, _, _ := newTestService(t, 10, 10)
+			sub := valid()
+			tt.modify(sub)
+			if err := s.Submit(ctx, tt.domain, sub); !errors.Is(err, tt.want) {
+				t.Errorf("Submit() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// 同一 IP 超过上限后不再调用人机验证服务
+	s, _, _, verifier := newTestService(t, 2, 10)
+	for i := 0; i < 3; i++ {
+		err := s.Submit(ctx, "example.com", &Submission{Email: "v@example.org", Message: "hi", RemoteIP: "203.0.113.7", CaptchaToken: "ok"})
+		if i < 2 && err != nil {
+			t.Fatalf("第 %d 次提交失败: %v", i+1, err)
+		}
+		if i == 2 && !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("超过 IP 上限应该返回 ErrRateLimited: %v", err)
+		}
+	}
+	if verifier.calls != 2 {
+		t.Errorf("人机验证调用次数 = %d, want 2", verifier.calls)
+	}
+
+	// 不同 IP 也受域名的投递数限制
+	s, _, _, _ = newTestService(t, 10, 2)
+	for i := 0; i < 3; i++ {
+		err := s.Submit(ctx, "example.com", &Submission{Email: "v@example.org", Message: "hi", RemoteIP: fmt.Sprintf("203.0.113.%d", i), CaptchaToken: "ok"})
+		if i == 2 && !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("超过域名上限应该返回 ErrRateLimited: %v", err)
+		}
+	}
+}
+
+func TestAllowOrigin(t *testing.T) {
+	s, _, _, _ := newTestService(t, 10, 10)
+	form, ok := s.Form("EXAMPLE.COM")
+	if !ok {
+		t.Fatal("域名应该不区分大小写")
+	}
+	if !form.AllowOrigin("https://www.example.com") {
+		t.Error("应该允许配置的来源")
+	}
+	if form.AllowOrigin("https://evil.example.net") {
+		t.Error("不应该允许其他来源")
+	}
+	if !(&Form{}).AllowOrigin("https://any.example.net") {
+		t.Error("没有配置来源时不限制")
+	}
+}
+
+func TestSiteVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if r.PostForm.Get("secret") != "secret" || r.PostForm.Get("remoteip") != "203.0.113.7" {
+			t.Errorf("验证请求参数错误: %v", r.PostForm)
+		}
+		if r.PostForm.Get("response") == "ok" {
+			fmt.Fprint(w, `{"success": true}`)
+			return
+		}
+		fmt.Fprint(w, `{"success": false, "error-codes": ["invalid-input-response"]}`)
+	}))
+	defer server.Close()
+
+	v := NewSiteVerifier("turnstile", "secret", server.URL)
+	v.Client = &http.Client{Timeout: time.Second}
+	ctx := context.Background()
+	if err := v.Verify(ctx, "ok", "203.0.113.7"); err != nil {
+		t.Errorf("有效令牌验证失败: %v", err)
+	}
+	if err := v.Verify(ctx, "bad", "203.0.113.7"); !errors.Is(err, ErrCaptcha) {
+		t.Errorf("无效令牌应该返回 ErrCaptcha: %v", err)
+	}
+	if err := v.Verify(ctx, "", "203.0.113.7"); !errors.Is(err, ErrCaptcha) {
+		t.Errorf("缺少令牌应该返回 ErrCaptcha: %v", err)
+	}
+
+	if got := NewSiteVerifier("hcaptcha", "secret", "").URL; got != HCaptchaVerifyURL {
+		t.Errorf("hcaptcha 默认地址 = %s", got)
+	}
+}
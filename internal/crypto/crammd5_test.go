@@ -0,0 +1,45 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestCRAMMD5(t *testing.T) {
+	// RFC 2195 中的示例
+	const challenge = "<1896.697170952@postoffice.reston.mci.net>"
+	secret, err := CRAMMD5Secret("tanstaaftanstaaf")
+	if err != nil {
+		t.Fatalf("计算 CRAM-MD5 密钥失败: %v", err)
+	}
+	if strings.Contains(secret, "tanstaaf") {
+		t.Error("密钥不应该包含明文密码")
+	}
+
+	ok, err := VerifyCRAMMD5(secret, challenge, "b913a602c7eda7a495b4e6e7334d3890")
+	if err != nil || !ok {
+		t.Errorf("RFC 2195 示例应该验证通过: %v, %v", ok, err)
+	}
+	if ok, _ := VerifyCRAMMD5(secret, challenge, "00000000000000000000000000000000"); ok {
+		t.Error("错误的摘要不应该验证通过")
+	}
+
+	// 超过块大小的密码按 RFC 2104 先做摘要，结果与标准 HMAC 一致
+	long := strings.Repeat("p", 100)
+	secret, err = CRAMMD5Secret(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac := hmac.New(md5.New, []byte(long))
+	mac.Write([]byte(challenge))
+	if ok, err := VerifyCRAMMD5(secret, challenge, hex.EncodeToString(mac.Sum(nil))); err != nil || !ok {
+		t.Errorf("长密码应该验证通过: %v, %v", ok, err)
+	}
+
+	if _, err := VerifyCRAMMD5("invalid", challenge, "00"); err == nil {
+		t.Error("无效的密钥应该返回错误")
+	}
+}
@@ -62,3 +62,34 @@ func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
 
 	return plaintext, nil
 }
+
+// EncryptWithPassphrase 使用口令派生的密钥加密数据（每次使用新的 salt），
+// 输出格式为 salt || nonce || ciphertext
+func EncryptWithPassphrase(passphrase string, plaintext []byte) ([]byte, error) {
+	salt, err := GenerateSalt()
+	if err != nil {
+		return nil, err
+	}
+	key, err := DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := Encrypt(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(salt, ciphertext...), nil
+}
+
+// DecryptWithPassphrase 解密 EncryptWithPassphrase 生成的数据
+func DecryptWithPassphrase(passphrase string, data []byte) ([]byte, error) {
+	if len(data) < saltSize {
+		return nil, errors.New("密文太短")
+	}
+	salt, ciphertext := data[:saltSize], data[saltSize:]
+	key, err := DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(key, ciphertext)
+}
@@ -71,3 +71,27 @@ func TestEncryptDecrypt_InvalidCiphertext(t *testing.T) {
 		t.Error("解密无效密文应该失败")
 	}
 }
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	plaintext := []byte("remote-password")
+
+	ciphertext, err := EncryptWithPassphrase("passphrase", plaintext)
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+
+	decrypted, err := DecryptWithPassphrase("passphrase", ciphertext)
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if string(decrypted) != string(plaintext) {
+		t.Errorf("解密结果不匹配: got %s, want %s", decrypted, plaintext)
+	}
+
+	if _, err := DecryptWithPassphrase("other", ciphertext); err == nil {
+		t.Error("使用错误口令解密应该失败")
+	}
+	if _, err := DecryptWithPassphrase("passphrase", []byte("short")); err == nil {
+		t.Error("解密过短的数据应该失败")
+	}
+}
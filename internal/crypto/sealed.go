@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// sealedKeyInfo 派生每条数据密钥时的 HKDF info 前缀
+const sealedKeyInfo = "gmz sealed v1"
+
+// GenerateKeyPair 生成 X25519 密钥对（零访问存储：公钥加密投递的邮件，私钥由用户密码保护）
+func GenerateKeyPair() (publicKey, privateKey []byte, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成密钥对失败: %w", err)
+	}
+	return key.PublicKey().Bytes(), key.Bytes(), nil
+}
+
+// SealToPublicKey 用接收方的公钥加密数据：每次生成临时 X25519 密钥，与接收方公钥协商后经 HKDF 派生
+// 本条数据专用的密钥，输出格式为 临时公钥 || nonce || ciphertext。加密只需要公钥，不需要用户密码
+func SealToPublicKey(publicKey, plaintext []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("公钥无效: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成临时密钥失败: %w", err)
+	}
+	key, err := sealedKey(ephemeral, recipient, ephemeral.PublicKey().Bytes(), publicKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := Encrypt(key, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeral.PublicKey().Bytes(), ciphertext...), nil
+}
+
+// OpenWithPrivateKey 解密 SealToPublicKey 生成的数据
+func OpenWithPrivateKey(privateKey, data []byte) ([]byte, error) {
+	private, err := ecdh.X25519().NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("私钥无效: %w", err)
+	}
+	if len(data) < 32+nonceSize {
+		return nil, errors.New("密文太短")
+	}
+	peer, err := ecdh.X25519().NewPublicKey(data[:32])
+	if err != nil {
+		return nil, fmt.Errorf("临时公钥无效: %w", err)
+	}
+	key, err := sealedKey(private, peer, data[:32], private.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(key, data[32:])
+}
+
+// sealedKey 协商共享密钥并派生数据密钥（info 包含临时公钥和接收方公钥，绑定本条数据和接收方）
+func sealedKey(private *ecdh.PrivateKey, peer *ecdh.PublicKey, ephemeralPublic, recipientPublic []byte) ([]byte, error) {
+	shared, err := private.ECDH(peer)
+	if err != nil {
+		return nil, fmt.Errorf("密钥协商失败: %w", err)
+	}
+	info := sealedKeyInfo + string(ephemeralPublic) + string(recipientPublic)
+	return hkdf.Key(sha256.New, shared, nil, info, argon2KeyLen)
+}
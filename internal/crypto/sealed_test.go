@@ -0,0 +1,38 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	public, private, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("Subject: hi\r\n\r\nsecret body\r\n")
+
+	first, err := SealToPublicKey(public, plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := SealToPublicKey(public, plaintext)
+	// 每条数据使用不同的临时密钥
+	if bytes.Equal(first[:32], second[:32]) || bytes.Contains(first, []byte("secret body")) {
+		t.Error("每次加密应该使用新的临时密钥，且不包含明文")
+	}
+
+	opened, err := OpenWithPrivateKey(private, first)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("OpenWithPrivateKey() = %q, %v", opened, err)
+	}
+
+	_, otherPrivate, _ := GenerateKeyPair()
+	if _, err := OpenWithPrivateKey(otherPrivate, first); err == nil {
+		t.Error("其他私钥不应该能解密")
+	}
+	first[len(first)-1] ^= 1
+	if _, err := OpenWithPrivateKey(private, first); err == nil {
+		t.Error("篡改的密文不应该能解密")
+	}
+}
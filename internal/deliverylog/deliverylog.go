@@ -0,0 +1,147 @@
+// Package deliverylog 投递审计日志：记录每封邮件经过的各个阶段（接收、反垃圾评分、投递到文件夹、
+// 外发、退信、推迟），管理员可以按地址、队列 ID 或 Message-ID 查询邮件的去向，不需要检索运行日志
+//
+// 同一次 SMTP 事务（或 WebMail 提交）的记录使用相同的队列 ID，由 WithQueueID 放在 context 中
+// 传给反垃圾检查和外发路径；预热推迟后再发出的记录没有队列 ID，按 Message-ID 关联。
+package deliverylog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// queueIDKey 用于在 context 中存储队列 ID 的键
+type queueIDKey struct{}
+
+// NewQueueID 生成队列 ID（12 位大写十六进制，与常见 MTA 日志中的队列 ID 形式一致）
+func NewQueueID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%X", b)
+}
+
+// WithQueueID 将队列 ID 添加到 context
+func WithQueueID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, queueIDKey{}, id)
+}
+
+// QueueID 从 context 中获取队列 ID
+func QueueID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(queueIDKey{}).(string)
+	return id
+}
+
+// MessageID 取出邮件的 Message-ID（不带尖括号），没有时返回空字符串
+func MessageID(data []byte) string {
+	header, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(data))).ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(header.Get("Message-Id")), "<>")
+}
+
+// Log 投递审计日志
+type Log struct {
+	Storage storage.Driver
+}
+
+// New 创建投递审计日志
+func New(driver storage.Driver) *Log {
+	return &Log{Storage: driver}
+}
+
+// Record 保存一条记录（为空时不记录），没有队列 ID 时使用 context 中的队列 ID；
+// 失败时只记录日志（不影响邮件收发）
+func (l *Log) Record(ctx context.Context, e *storage.DeliveryEvent) {
+	if l == nil || l.Storage == nil {
+		return
+	}
+	if e.QueueID == "" {
+		e.QueueID = QueueID(ctx)
+	}
+	if err := l.Storage.RecordDeliveryEvent(ctx, e); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("queue_id", e.QueueID).Str("event", e.Event).Msg("记录投递日志失败")
+	}
+}
+
+// RecordDelivery 记录外发结果（实现 smtpclient.DeliveryRecorder）：err 为空时所有收件人已发出；
+// 否则永久失败的收件人记为退信，临时失败的记为推迟，没有包含在错误中的收件人（其他域名）记为已发出
+func (l *Log) RecordDelivery(ctx context.Context, from string, to []string, data []byte, err error) {
+	if l == nil || len(to) == 0 {
+		return
+	}
+	messageID := MessageID(data)
+	// pending 尚未记录的收件人（错误中可能包含调用方已逐个记录的被拒收的收件人）
+	pending := make(map[string]bool, len(to))
+	for _, recipient := range to {
+		pending[strings.ToLower(recipient)] = true
+	}
+	if err != nil {
+		l.recordError(ctx, from, messageID, to, err, pending)
+	}
+	for _, recipient := range to {
+		if !pending[strings.ToLower(recipient)] {
+			continue
+		}
+		l.Record(ctx, &storage.DeliveryEvent{
+			MessageID: messageID,
+			Event:     storage.DeliveryRelayed,
+			Sender:    from,
+			Recipient: recipient,
+			Code:      250,
+		})
+	}
+}
+
+// recordError 按错误记录 pending 中失败的收件人，记录后从 pending 中删除
+func (l *Log) recordError(ctx context.Context, from, messageID string, to []string, err error, pending map[string]bool) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			var delivery *smtpclient.DeliveryError
+			if errors.As(e, &delivery) {
+				l.recordError(ctx, from, messageID, delivery.Recipients, delivery.Err, pending)
+			} else {
+				l.recordError(ctx, from, messageID, to, e, pending)
+			}
+		}
+		return
+	}
+
+	event := storage.DeliveryDeferred
+	code, status, detail, permanent := bounce.Describe(err)
+	if errors.Is(err, smtpclient.ErrRecipientRejected) {
+		// 被拒收的收件人已逐个记录，其余收件人随事务中止没有发出
+		code, status, detail = 0, "", "其他收件人被拒收，邮件没有发出"
+	} else if permanent {
+		event = storage.DeliveryBounced
+	}
+	for _, recipient := range to {
+		if !pending[strings.ToLower(recipient)] {
+			continue
+		}
+		delete(pending, strings.ToLower(recipient))
+		l.Record(ctx, &storage.DeliveryEvent{
+			MessageID: messageID,
+			Event:     event,
+			Sender:    from,
+			Recipient: recipient,
+			Code:      code,
+			Status:    status,
+			Detail:    detail,
+		})
+	}
+}
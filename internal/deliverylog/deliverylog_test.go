@@ -0,0 +1,85 @@
+package deliverylog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestRecordDelivery(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	log := New(driver)
+	ctx = WithQueueID(ctx, "Q1")
+	data := []byte("Message-ID: <m1@example.com>\r\nSubject: test\r\n\r\nbody\r\n")
+
+	// 按域名组合的错误：永久拒收记为退信、临时错误记为推迟、已逐个记录的拒收跳过，其他收件人已发出
+	err = errors.Join(
+		&smtpclient.DeliveryError{Domain: "full.test", Recipients: []string{"a@full.test"},
+			Err: fmt.Errorf("完成发送失败: %w", &textproto.Error{Code: 552, Msg: "5.2.2 Mailbox full"})},
+		&smtpclient.DeliveryError{Domain: "slow.test", Recipients: []string{"b@slow.test"},
+			Err: &textproto.Error{Code: 451, Msg: "4.3.0 Try again later"}},
+		&smtpclient.DeliveryError{Domain: "strict.test", Recipients: []string{"c@strict.test"},
+			Err: fmt.Errorf("全部 1 个%w", smtpclient.ErrRecipientRejected)},
+	)
+	log.RecordDelivery(ctx, "alice@example.com", []string{"a@full.test", "b@slow.test", "d@ok.test"}, data, err)
+
+	events, err := driver.GetDeliveryTrace(ctx, "Q1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, e := range events {
+		if e.MessageID != "m1@example.com" {
+			t.Errorf("Message-ID = %q", e.MessageID)
+		}
+		got[e.Recipient] = fmt.Sprintf("%s %d %s", e.Event, e.Code, e.Status)
+	}
+	want := map[string]string{
+		"a@full.test": "bounced 552 5.2.2",
+		"b@slow.test": "deferred 451 4.3.0",
+		"d@ok.test":   "relayed 250 ",
+	}
+	if len(got) != len(want) {
+		t.Errorf("记录 = %v, want %v", got, want)
+	}
+	for recipient, w := range want {
+		if got[recipient] != w {
+			t.Errorf("%s = %q, want %q", recipient, got[recipient], w)
+		}
+	}
+
+	// 没有队列 ID 的记录（预热推迟后发出）按 Message-ID 关联
+	log.RecordDelivery(context.Background(), "alice@example.com", []string{"b@slow.test"}, data, nil)
+	if events, err := driver.SearchDeliveryLog(ctx, "<m1@example.com>", time.Now().Add(-time.Hour), 10); err != nil || len(events) != 4 {
+		t.Errorf("按 Message-ID 查询应该返回 4 条: %d, %v", len(events), err)
+	}
+
+	// 为空时不记录
+	var none *Log
+	none.Record(ctx, &storage.DeliveryEvent{Event: storage.DeliveryReceived})
+	none.RecordDelivery(ctx, "alice@example.com", []string{"x@example.com"}, data, nil)
+}
+
+func TestQueueID(t *testing.T) {
+	id := NewQueueID()
+	if len(id) != 12 || id == NewQueueID() {
+		t.Errorf("NewQueueID() = %q", id)
+	}
+	if QueueID(context.Background()) != "" || QueueID(WithQueueID(context.Background(), id)) != id {
+		t.Error("context 中的队列 ID 不正确")
+	}
+}
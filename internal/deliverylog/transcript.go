@@ -0,0 +1,66 @@
+package deliverylog
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// Transcripts 外发会话记录：保存发往指定域名或指定 Message-ID 的邮件的完整 SMTP 会话，
+// 收件方否认收到邮件时管理员可以查询对方服务器当时的回复（实现 smtpclient.TranscriptRecorder）
+type Transcripts struct {
+	Storage    storage.Driver
+	domains    map[string]bool
+	messageIDs map[string]bool
+}
+
+// NewTranscripts 创建外发会话记录：记录发往 domains 的会话和 Message-ID 在 messageIDs 中的邮件的会话
+func NewTranscripts(driver storage.Driver, domains, messageIDs []string) *Transcripts {
+	t := &Transcripts{
+		Storage:    driver,
+		domains:    make(map[string]bool, len(domains)),
+		messageIDs: make(map[string]bool, len(messageIDs)),
+	}
+	for _, domain := range domains {
+		t.domains[strings.ToLower(strings.TrimSpace(domain))] = true
+	}
+	for _, id := range messageIDs {
+		t.messageIDs[strings.Trim(strings.TrimSpace(id), "<>")] = true
+	}
+	return t
+}
+
+// Capture 是否记录发往 domain 的会话
+func (t *Transcripts) Capture(ctx context.Context, domain string, data []byte) bool {
+	if t.domains[strings.ToLower(domain)] {
+		return true
+	}
+	return len(t.messageIDs) > 0 && t.messageIDs[MessageID(data)]
+}
+
+// SaveTranscript 保存会话记录，失败时只记录日志（不影响邮件发送）
+func (t *Transcripts) SaveTranscript(ctx context.Context, from string, data []byte, tr *smtpclient.Transcript) {
+	record := &storage.SMTPTranscript{
+		QueueID:    QueueID(ctx),
+		MessageID:  MessageID(data),
+		Sender:     from,
+		Domain:     tr.Domain,
+		Recipients: tr.Recipients,
+		MXHost:     tr.MXHost,
+		RemoteAddr: tr.RemoteAddr,
+		TLSVersion: tr.TLSVersion,
+		TLSCipher:  tr.TLSCipher,
+		Transcript: tr.Log,
+		StartedAt:  tr.StartedAt,
+		DurationMs: tr.Duration.Milliseconds(),
+	}
+	if tr.Err != nil {
+		record.Error = tr.Err.Error()
+	}
+	if err := t.Storage.SaveSMTPTranscript(ctx, record); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("domain", tr.Domain).Str("message_id", record.MessageID).Msg("保存外发会话记录失败")
+	}
+}
@@ -0,0 +1,281 @@
+// Package digest 邮件摘要：按用户设置（每天或每周）向收件箱投递一封摘要邮件，
+// 汇总收件箱中的未读邮件和垃圾邮件文件夹中新收到的邮件
+//
+// 摘要在用户时区的 Hour 点之后发送（每周摘要在周一发送），每个用户记录最近一次发送的时间，
+// 同一周期只发送一次；周期内没有需要汇总的邮件时不发送，但仍然记录。
+package digest
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// DefaultHour 默认的发送时间（用户时区的整点）
+const DefaultHour = 8
+
+// maxMails 摘要中每个文件夹列出的最多邮件数
+const maxMails = 10
+
+// pageSize 遍历用户时的分页大小
+const pageSize = 500
+
+// spamFolder 垃圾邮件文件夹
+const spamFolder = "Spam"
+
+//go:embed templates/digest.txt
+var templates embed.FS
+
+// funcs 模板函数
+var funcs = template.FuncMap{
+	"datetime": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+	"sub":      func(a, b int) int { return a - b },
+}
+
+// DefaultTemplate 内置的摘要模板
+var DefaultTemplate = template.Must(template.New("digest.txt").Funcs(funcs).ParseFS(templates, "templates/digest.txt"))
+
+// LoadTemplate 加载自定义摘要模板，模板需要定义 subject 和 body
+func LoadTemplate(path string) (*template.Template, error) {
+	tmpl, err := template.New("digest").Funcs(funcs).ParseFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("加载摘要模板失败: %w", err)
+	}
+	for _, name := range []string{"subject", "body"} {
+		if tmpl.Lookup(name) == nil {
+			return nil, fmt.Errorf("摘要模板 %s 缺少 %q 定义", path, name)
+		}
+	}
+	return tmpl, nil
+}
+
+// Sender 邮件摘要发送器
+type Sender struct {
+	Storage storage.Driver
+	Maildir *storage.Maildir
+	// Hour 发送时间（用户时区的整点，0-23）
+	Hour int
+	// Template 摘要模板，为 nil 时使用 DefaultTemplate
+	Template *template.Template
+}
+
+// Data 摘要模板的数据
+type Data struct {
+	User  string
+	Title string // 每日摘要 / 每周摘要
+	Since time.Time
+	Now   time.Time
+	// Unread 收件箱中的未读邮件
+	Unread *storage.FolderDigest
+	// Spam 周期内进入垃圾邮件文件夹的邮件
+	Spam *storage.FolderDigest
+}
+
+// Run 定期检查需要发送摘要的用户，直到 ctx 取消
+func (s *Sender) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Check(ctx); err != nil {
+			logger.Warn().Err(err).Msg("检查邮件摘要失败")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check 遍历开启了摘要的用户，向到期的用户发送摘要
+func (s *Sender) Check(ctx context.Context) error {
+	for offset := 0; ; offset += pageSize {
+		users, err := s.Storage.ListUsers(ctx, pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if !user.Active {
+				continue
+			}
+			if err := s.check(ctx, user.Email); err != nil {
+				logger.Warn().Err(err).Str("user", user.Email).Msg("发送邮件摘要失败")
+			}
+		}
+		if len(users) < pageSize {
+			return nil
+		}
+	}
+}
+
+// check 用户的摘要到期时生成并投递摘要
+func (s *Sender) check(ctx context.Context, userEmail string) error {
+	effective, err := storage.LoadEffectiveSettings(ctx, s.Storage, userEmail)
+	if err != nil {
+		return err
+	}
+	if effective.Digest == storage.DigestOff {
+		return nil
+	}
+
+	var last time.Time
+	switch last, err = s.Storage.GetDigestSentAt(ctx, userEmail); {
+	case errors.Is(err, storage.ErrNotFound):
+		last = time.Time{}
+	case err != nil:
+		return err
+	}
+
+	// 周期按用户设置的时区计算，邮件中的时间也按该时区显示
+	now := time.Now().In(effective.Location())
+	ok, since := due(effective.Digest, s.Hour, last, now)
+	if !ok {
+		return nil
+	}
+
+	data := &Data{User: userEmail, Title: title(effective.Digest), Since: since, Now: now}
+	if data.Unread, err = s.Storage.DigestFolder(ctx, userEmail, "INBOX", true, time.Time{}, maxMails); err != nil {
+		return err
+	}
+	if data.Spam, err = s.Storage.DigestFolder(ctx, userEmail, spamFolder, false, since, maxMails); err != nil {
+		return err
+	}
+	localize(data, now.Location())
+
+	if data.Unread.Count > 0 || data.Spam.Count > 0 {
+		if err := s.deliver(ctx, data); err != nil {
+			var exceeded *storage.QuotaExceededError
+			if !errors.As(err, &exceeded) {
+				return err
+			}
+		}
+		logger.InfoCtx(ctx).
+			Str("user", userEmail).
+			Str("frequency", effective.Digest).
+			Int("unread", data.Unread.Count).
+			Int("spam", data.Spam.Count).
+			Msg("已发送邮件摘要")
+	}
+	return s.Storage.SaveDigestSentAt(ctx, userEmail, now)
+}
+
+// deliver 向用户的收件箱投递摘要邮件
+func (s *Sender) deliver(ctx context.Context, data *Data) error {
+	tmpl := s.Template
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+	raw, subject, err := build(tmpl, data)
+	if err != nil {
+		return err
+	}
+	m := &storage.Mail{
+		ID:         fmt.Sprintf("digest-%d", time.Now().UnixNano()),
+		UserEmail:  data.User,
+		Folder:     "INBOX",
+		From:       postmaster(data.User),
+		To:         []string{data.User},
+		Subject:    subject,
+		Size:       int64(len(raw)),
+		Flags:      []string{"\\Recent"},
+		ReceivedAt: time.Now(),
+		CreatedAt:  time.Now(),
+	}
+	return storage.NewMailStore(s.Maildir, s.Storage).Deliver(ctx, m, raw)
+}
+
+// due 判断摘要是否到期，返回本次摘要的起始时间
+//
+// 每日摘要的周期从每天 hour 点开始，每周摘要从周一 hour 点开始（按 now 的时区）；
+// 上次发送早于当前周期的开始时间时到期。没有发送记录时从上一个周期开始汇总。
+func due(frequency string, hour int, last, now time.Time) (bool, time.Time) {
+	start := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	days := 1
+	if frequency == storage.DigestWeekly {
+		days = 7
+		start = start.AddDate(0, 0, -(int(now.Weekday())+6)%7) // 本周一
+	}
+	if now.Before(start) {
+		start = start.AddDate(0, 0, -days)
+	}
+	if !last.IsZero() && !last.Before(start) {
+		return false, time.Time{}
+	}
+	if last.IsZero() {
+		return true, start.AddDate(0, 0, -days)
+	}
+	return true, last.In(now.Location())
+}
+
+// title 摘要的标题
+func title(frequency string) string {
+	if frequency == storage.DigestWeekly {
+		return "每周邮件摘要"
+	}
+	return "每日邮件摘要"
+}
+
+// localize 把邮件时间转换到用户的时区
+func localize(data *Data, loc *time.Location) {
+	for _, digest := range []*storage.FolderDigest{data.Unread, data.Spam} {
+		for _, m := range digest.Mails {
+			m.ReceivedAt = m.ReceivedAt.In(loc)
+		}
+	}
+}
+
+// postmaster 摘要邮件的发件地址
+func postmaster(userEmail string) string {
+	return "postmaster@" + userEmail[strings.LastIndex(userEmail, "@")+1:]
+}
+
+// build 渲染模板并生成摘要邮件，返回邮件和主题
+func build(tmpl *template.Template, data *Data) ([]byte, string, error) {
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, "", fmt.Errorf("渲染摘要主题失败: %w", err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, "", fmt.Errorf("渲染摘要内容失败: %w", err)
+	}
+
+	from := postmaster(data.User)
+	var h mail.Header
+	h.SetDate(data.Now)
+	h.SetAddressList("From", []*mail.Address{{Name: "Postmaster", Address: from}})
+	h.SetAddressList("To", []*mail.Address{{Address: data.User}})
+	h.SetSubject(strings.TrimSpace(subject.String()))
+	if err := h.GenerateMessageIDWithHostname(from[strings.LastIndex(from, "@")+1:]); err != nil {
+		return nil, "", fmt.Errorf("生成 Message-ID 失败: %w", err)
+	}
+	h.Set("Auto-Submitted", "auto-generated")
+	h.Set("MIME-Version", "1.0")
+	h.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	var buf bytes.Buffer
+	mw, err := message.CreateWriter(&buf, h.Header)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成摘要邮件失败: %w", err)
+	}
+	text := strings.ReplaceAll(body.String(), "\n", "\r\n")
+	if _, err := mw.Write([]byte(text)); err != nil {
+		return nil, "", fmt.Errorf("生成摘要邮件失败: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", fmt.Errorf("生成摘要邮件失败: %w", err)
+	}
+	return buf.Bytes(), strings.TrimSpace(subject.String()), nil
+}
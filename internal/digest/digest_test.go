@@ -0,0 +1,144 @@
+package digest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestDue(t *testing.T) {
+	// 2026-03-11 是周三
+	at := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name      string
+		frequency string
+		last, now time.Time
+		want      bool
+		wantSince time.Time
+	}{
+		{"每日首次", storage.DigestDaily, time.Time{}, at(11, 9), true, at(10, 8)},
+		{"每日未到发送时间", storage.DigestDaily, at(10, 8), at(11, 7), false, time.Time{}},
+		{"每日到期", storage.DigestDaily, at(10, 8), at(11, 8), true, at(10, 8)},
+		{"每日已发送", storage.DigestDaily, at(11, 8), at(11, 20), false, time.Time{}},
+		{"每周首次", storage.DigestWeekly, time.Time{}, at(11, 9), true, at(2, 8)},
+		{"每周已发送", storage.DigestWeekly, at(9, 9), at(15, 23), false, time.Time{}},
+		{"每周周一到期", storage.DigestWeekly, at(9, 9), at(16, 8), true, at(9, 9)},
+		{"每周周一发送时间前", storage.DigestWeekly, time.Time{}, at(16, 7), true, at(2, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, since := due(tt.frequency, 8, tt.last, tt.now)
+			if got != tt.want || !since.Equal(tt.wantSince) {
+				t.Errorf("due() = %v %v, want %v %v", got, since, tt.want, tt.wantSince)
+			}
+		})
+	}
+}
+
+func TestSender(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	daily := storage.DigestDaily
+	if err := driver.SaveUserSettings(ctx, &storage.UserSettings{
+		UserEmail:       "alice@example.com",
+		AccountSettings: storage.AccountSettings{Digest: &daily},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	for _, m := range []*storage.Mail{
+		{ID: "unread", Folder: "INBOX", From: "carol@example.org", Subject: "周报", ReceivedAt: now.Add(-time.Hour)},
+		{ID: "read", Folder: "INBOX", From: "carol@example.org", Subject: "已读", Flags: []string{"\\Seen"}, ReceivedAt: now.Add(-time.Hour)},
+		{ID: "spam", Folder: "Spam", From: "spammer@example.net", Subject: "中奖通知", ReceivedAt: now.Add(-time.Hour)},
+		{ID: "old-spam", Folder: "Spam", From: "spammer@example.net", Subject: "旧广告", ReceivedAt: now.AddDate(0, 0, -10)},
+	} {
+		for _, user := range []string{"alice@example.com", "bob@example.com"} {
+			copied := *m
+			copied.ID = user + "-" + m.ID
+			copied.UserEmail = user
+			if err := driver.StoreMail(ctx, &copied); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	digests := func(user string) []*storage.Mail {
+		t.Helper()
+		mails, err := driver.ListMails(ctx, user, "INBOX", 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []*storage.Mail
+		for _, m := range mails {
+			if strings.HasPrefix(m.ID, "digest-") {
+				found = append(found, m)
+			}
+		}
+		return found
+	}
+
+	s := &Sender{Storage: driver, Hour: 0}
+	for i := 0; i < 2; i++ {
+		if err := s.Check(ctx); err != nil {
+			t.Fatalf("检查邮件摘要失败: %v", err)
+		}
+	}
+	got := digests("alice@example.com")
+	if len(got) != 1 {
+		t.Fatalf("应该发送一次摘要: %d", len(got))
+	}
+	if got[0].Subject != "每日邮件摘要：1 封未读邮件，1 封垃圾邮件" || got[0].From != "postmaster@example.com" {
+		t.Errorf("摘要邮件 = %q from %q", got[0].Subject, got[0].From)
+	}
+	if _, err := driver.GetDigestSentAt(ctx, "alice@example.com"); err != nil {
+		t.Errorf("应该记录发送时间: %v", err)
+	}
+	if got := digests("bob@example.com"); len(got) != 0 {
+		t.Errorf("没有开启摘要的用户不应该收到摘要: %d", len(got))
+	}
+}
+
+func TestBuild(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	data := &Data{
+		User:   "alice@example.com",
+		Title:  title(storage.DigestWeekly),
+		Since:  time.Date(2026, 3, 2, 8, 0, 0, 0, loc),
+		Now:    time.Date(2026, 3, 9, 8, 0, 0, 0, loc),
+		Unread: &storage.FolderDigest{Folder: "INBOX", Count: 12, Mails: []*storage.Mail{{From: "carol@example.org", ReceivedAt: time.Date(2026, 3, 8, 1, 0, 0, 0, time.UTC)}}},
+		Spam:   &storage.FolderDigest{Folder: "Spam", Mails: []*storage.Mail{}},
+	}
+	localize(data, loc)
+	raw, subject, err := build(DefaultTemplate, data)
+	if err != nil {
+		t.Fatalf("生成摘要失败: %v", err)
+	}
+	if subject != "每周邮件摘要：12 封未读邮件" {
+		t.Errorf("subject = %q", subject)
+	}
+	msg := string(raw)
+	for _, want := range []string{"Auto-Submitted: auto-generated", "Date: Mon, 09 Mar 2026 08:00:00 +0800"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("摘要邮件缺少 %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "=E5=9E=83=E5=9C=BE=E9=82=AE=E4=BB=B6=E6=96=87=E4=BB=B6=E5=A4=B9") {
+		t.Errorf("没有垃圾邮件时不应该列出垃圾邮件文件夹:\n%s", msg)
+	}
+}
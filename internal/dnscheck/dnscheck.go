@@ -0,0 +1,424 @@
+// Package dnscheck 检查域名的邮件 DNS 设置：MX 指向本服务器、SPF 授权本服务器的 IP、
+// DKIM 选择器的 TXT 记录与签名使用的公钥一致、DMARC 记录存在、反向解析与 SMTP 主机名一致。
+// 结果为逐项的通过/失败报告，管理后台显示为部署检查清单。
+package dnscheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/antispam"
+)
+
+// 检查项状态
+const (
+	StatusPass = "pass" // 通过
+	StatusWarn = "warn" // 可以工作，但建议修改
+	StatusFail = "fail" // 失败
+	StatusSkip = "skip" // 未检查（如没有配置 DKIM 签名）
+)
+
+// 检查项
+const (
+	CheckMX    = "mx"
+	CheckSPF   = "spf"
+	CheckDKIM  = "dkim"
+	CheckDMARC = "dmarc"
+	CheckPTR   = "ptr"
+)
+
+// maxSPFLookups SPF 检查中 include、redirect、a、mx 的 DNS 查询次数上限（RFC 7208 第 4.6.4 节）
+const maxSPFLookups = 10
+
+// Resolver DNS 查询（*net.Resolver 实现了该接口）
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// DKIMRecord 签名使用的 DKIM 公钥记录
+type DKIMRecord struct {
+	Domain   string // 签名域名（d=）
+	Selector string
+	Value    string // 应发布的 TXT 记录值
+}
+
+// Check 一个检查项的结果
+type Check struct {
+	Name     string   `json:"name"` // mx、spf、dkim、dmarc 或 ptr
+	Status   string   `json:"status"`
+	Message  string   `json:"message"`
+	Records  []string `json:"records,omitempty"`  // 查询到的记录
+	Expected string   `json:"expected,omitempty"` // 建议发布的记录（未通过时）
+}
+
+// Report 域名的 DNS 检查报告
+type Report struct {
+	Domain    string    `json:"domain"`
+	Hostname  string    `json:"hostname"`   // SMTP 主机名
+	ServerIPs []string  `json:"server_ips"` // 主机名解析到的 IP
+	Passed    bool      `json:"passed"`     // 没有失败的检查项
+	Checks    []*Check  `json:"checks"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Checker DNS 设置检查
+type Checker struct {
+	Resolver Resolver // 为空时使用 net.DefaultResolver
+	Hostname string   // SMTP 主机名（为空时使用系统主机名）
+}
+
+// Check 检查域名的 DNS 设置；dkim 为空时跳过 DKIM 检查
+func (c *Checker) Check(ctx context.Context, domain string, dkim *DKIMRecord) *Report {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	report := &Report{
+		Domain:    domain,
+		Hostname:  c.hostname(),
+		ServerIPs: []string{},
+		CheckedAt: time.Now().UTC(),
+	}
+
+	addrs, err := c.resolver().LookupIPAddr(ctx, report.Hostname)
+	var ips []net.IP
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+		report.ServerIPs = append(report.ServerIPs, addr.IP.String())
+	}
+	var hostErr error
+	if err != nil {
+		hostErr = fmt.Errorf("无法解析 SMTP 主机名 %s: %w", report.Hostname, err)
+	} else if len(ips) == 0 {
+		hostErr = fmt.Errorf("SMTP 主机名 %s 没有 A 或 AAAA 记录", report.Hostname)
+	}
+
+	report.Checks = []*Check{
+		c.checkMX(ctx, domain, report.Hostname, ips),
+		c.checkSPF(ctx, domain, ips, hostErr),
+		c.checkDKIM(ctx, dkim),
+		c.checkDMARC(ctx, domain),
+		c.checkPTR(ctx, report.Hostname, ips, hostErr),
+	}
+	report.Passed = true
+	for _, check := range report.Checks {
+		if check.Status == StatusFail {
+			report.Passed = false
+		}
+	}
+	return report
+}
+
+// checkMX MX 记录指向本服务器（主机名相同或解析到本服务器的 IP）
+func (c *Checker) checkMX(ctx context.Context, domain, hostname string, ips []net.IP) *Check {
+	check := &Check{Name: CheckMX, Expected: fmt.Sprintf("%s. IN MX 10 %s.", domain, hostname)}
+	mxs, err := c.resolver().LookupMX(ctx, domain)
+	if err != nil || len(mxs) == 0 {
+		return check.fail("没有 MX 记录")
+	}
+	for _, mx := range mxs {
+		check.Records = append(check.Records, fmt.Sprintf("%d %s", mx.Pref, mx.Host))
+	}
+	for _, mx := range mxs {
+		host := normalizeHost(mx.Host)
+		if host == hostname {
+			return check.pass(fmt.Sprintf("MX 指向 %s", hostname))
+		}
+		if addrs, err := c.resolver().LookupIPAddr(ctx, host); err == nil {
+			for _, addr := range addrs {
+				if containsIP(ips, addr.IP) {
+					return check.pass(fmt.Sprintf("MX %s 解析到本服务器的 IP %s", host, addr.IP))
+				}
+			}
+		}
+	}
+	return check.fail("MX 记录没有指向本服务器")
+}
+
+// checkSPF SPF 记录授权本服务器的所有 IP
+func (c *Checker) checkSPF(ctx context.Context, domain string, ips []net.IP, hostErr error) *Check {
+	check := &Check{Name: CheckSPF, Expected: expectedSPF(ips)}
+	records := c.spfRecords(ctx, domain)
+	check.Records = records
+	switch {
+	case len(records) == 0:
+		return check.fail("没有 SPF 记录")
+	case len(records) > 1:
+		return check.fail("存在多条 SPF 记录（只能有一条）")
+	case hostErr != nil:
+		return check.fail(hostErr.Error())
+	}
+
+	var missing []string
+	for _, ip := range ips {
+		lookups := 0
+		if !c.spfAuthorizes(ctx, domain, records[0], ip, &lookups) {
+			missing = append(missing, ip.String())
+		}
+	}
+	switch {
+	case len(missing) == 0:
+		return check.pass("SPF 授权本服务器的 IP")
+	case len(missing) == len(ips):
+		return check.fail("SPF 没有授权本服务器的 IP")
+	default:
+		return check.warn(fmt.Sprintf("SPF 没有授权 %s", strings.Join(missing, "、")))
+	}
+}
+
+// checkDKIM 选择器的 TXT 记录与签名使用的公钥一致
+func (c *Checker) checkDKIM(ctx context.Context, dkim *DKIMRecord) *Check {
+	check := &Check{Name: CheckDKIM}
+	if dkim == nil {
+		check.Status, check.Message = StatusSkip, "未配置 DKIM 签名"
+		return check
+	}
+	name := dkim.Selector + "._domainkey." + dkim.Domain
+	check.Expected = name + ". IN TXT " + antispam.QuoteTXT(dkim.Value)
+	txts, err := c.resolver().LookupTXT(ctx, name)
+	if err != nil || len(txts) == 0 {
+		return check.fail(fmt.Sprintf("%s 没有 TXT 记录", name))
+	}
+	check.Records = txts
+	want := dkimPublicKey(dkim.Value)
+	for _, txt := range txts {
+		if dkimPublicKey(txt) == want {
+			return check.pass(fmt.Sprintf("%s 的公钥与签名密钥一致", name))
+		}
+	}
+	return check.fail(fmt.Sprintf("%s 的公钥与签名密钥不一致", name))
+}
+
+// checkDMARC DMARC 记录存在（p=none 时提示只监控不处理）
+func (c *Checker) checkDMARC(ctx context.Context, domain string) *Check {
+	name := "_dmarc." + domain
+	check := &Check{Name: CheckDMARC, Expected: fmt.Sprintf("%s. IN TXT \"v=DMARC1; p=quarantine; rua=mailto:postmaster@%s\"", name, domain)}
+	txts, _ := c.resolver().LookupTXT(ctx, name)
+	for _, txt := range txts {
+		if strings.HasPrefix(strings.ToLower(txt), "v=dmarc1") {
+			check.Records = append(check.Records, txt)
+		}
+	}
+	switch {
+	case len(check.Records) == 0:
+		return check.fail("没有 DMARC 记录")
+	case len(check.Records) > 1:
+		return check.fail("存在多条 DMARC 记录（只能有一条）")
+	}
+	for _, tag := range strings.Split(check.Records[0], ";") {
+		key, value, _ := strings.Cut(tag, "=")
+		if strings.TrimSpace(key) == "p" && strings.EqualFold(strings.TrimSpace(value), "none") {
+			return check.warn("DMARC 策略为 none，只监控不处理伪造的邮件")
+		}
+	}
+	return check.pass("DMARC 记录存在")
+}
+
+// checkPTR 本服务器每个 IP 的反向解析与 SMTP 主机名一致
+func (c *Checker) checkPTR(ctx context.Context, hostname string, ips []net.IP, hostErr error) *Check {
+	check := &Check{Name: CheckPTR, Expected: hostname + "."}
+	if hostErr != nil {
+		return check.fail(hostErr.Error())
+	}
+	var mismatched []string
+	for _, ip := range ips {
+		names, _ := c.resolver().LookupAddr(ctx, ip.String())
+		matched := false
+		for _, name := range names {
+			check.Records = append(check.Records, ip.String()+" "+name)
+			if normalizeHost(name) == hostname {
+				matched = true
+			}
+		}
+		if !matched {
+			mismatched = append(mismatched, ip.String())
+		}
+	}
+	if len(mismatched) > 0 {
+		return check.fail(fmt.Sprintf("%s 的反向解析不是 %s", strings.Join(mismatched, "、"), hostname))
+	}
+	return check.pass(fmt.Sprintf("反向解析与 %s 一致", hostname))
+}
+
+// spfRecords 查询域名的 SPF 记录
+func (c *Checker) spfRecords(ctx context.Context, domain string) []string {
+	txts, _ := c.resolver().LookupTXT(ctx, domain)
+	var records []string
+	for _, txt := range txts {
+		if lower := strings.ToLower(txt); lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			records = append(records, txt)
+		}
+	}
+	return records
+}
+
+// spfAuthorizes 判断 SPF 记录是否以 pass 授权 ip（支持 ip4、ip6、a、mx、include 和 redirect，
+// 不展开宏；DNS 查询超过 maxSPFLookups 次时视为未授权）
+func (c *Checker) spfAuthorizes(ctx context.Context, domain, record string, ip net.IP, lookups *int) bool {
+	redirect := ""
+	for _, term := range strings.Fields(record)[1:] {
+		term = strings.ToLower(term)
+		if target, ok := strings.CutPrefix(term, "redirect="); ok {
+			redirect = target
+			continue
+		}
+		qualifier := byte('+')
+		if strings.ContainsRune("+-~?", rune(term[0])) {
+			qualifier, term = term[0], term[1:]
+		}
+		mechanism, value, _ := strings.Cut(term, ":")
+		var matched bool
+		switch mechanism {
+		case "all":
+			matched = true
+		case "ip4", "ip6":
+			matched = matchCIDR(value, ip)
+		case "a", "mx":
+			if *lookups++; *lookups > maxSPFLookups {
+				return false
+			}
+			target, prefix := domain, ""
+			if value != "" {
+				target = value
+			}
+			if i := strings.Index(target, "/"); i >= 0 {
+				target, prefix = target[:i], target[i:]
+			}
+			matched = c.spfHostMatches(ctx, mechanism, target, prefix, ip)
+		case "include":
+			if *lookups++; *lookups > maxSPFLookups {
+				return false
+			}
+			if records := c.spfRecords(ctx, value); len(records) == 1 {
+				matched = c.spfAuthorizes(ctx, value, records[0], ip, lookups)
+			}
+		}
+		if matched {
+			return qualifier == '+'
+		}
+	}
+	if redirect != "" {
+		if *lookups++; *lookups > maxSPFLookups {
+			return false
+		}
+		if records := c.spfRecords(ctx, redirect); len(records) == 1 {
+			return c.spfAuthorizes(ctx, redirect, records[0], ip, lookups)
+		}
+	}
+	return false
+}
+
+// spfHostMatches a 和 mx 机制：target（mx 为其 MX 主机）解析到的地址是否包含 ip；
+// prefix 为双 CIDR 前缀（如 /24、//64、/24//64），IPv4 和 IPv6 地址分别使用各自的前缀长度
+func (c *Checker) spfHostMatches(ctx context.Context, mechanism, target, prefix string, ip net.IP) bool {
+	prefix4, prefix6, _ := strings.Cut(prefix, "//")
+	if prefix6 != "" {
+		prefix6 = "/" + prefix6
+	}
+	hosts := []string{target}
+	if mechanism == "mx" {
+		hosts = nil
+		mxs, _ := c.resolver().LookupMX(ctx, target)
+		for _, mx := range mxs {
+			hosts = append(hosts, normalizeHost(mx.Host))
+		}
+	}
+	for _, host := range hosts {
+		addrs, _ := c.resolver().LookupIPAddr(ctx, host)
+		for _, addr := range addrs {
+			if (addr.IP.To4() == nil) != (ip.To4() == nil) {
+				continue
+			}
+			cidr := prefix4
+			if addr.IP.To4() == nil {
+				cidr = prefix6
+			}
+			if matchCIDR(addr.IP.String()+cidr, ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolver 返回使用的 DNS 解析器
+func (c *Checker) resolver() Resolver {
+	if c.Resolver != nil {
+		return c.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// hostname 返回 SMTP 主机名
+func (c *Checker) hostname() string {
+	if c.Hostname != "" {
+		return normalizeHost(c.Hostname)
+	}
+	name, _ := os.Hostname()
+	return normalizeHost(name)
+}
+
+// matchCIDR 判断 ip 是否属于 IP 地址或 CIDR 网段 value
+func matchCIDR(value string, ip net.IP) bool {
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network.Contains(ip)
+	}
+	return net.ParseIP(value).Equal(ip)
+}
+
+// dkimPublicKey 提取 DKIM 记录的公钥（p= 标签，去掉空白）
+func dkimPublicKey(record string) string {
+	for _, tag := range strings.Split(record, ";") {
+		key, value, ok := strings.Cut(tag, "=")
+		if ok && strings.TrimSpace(key) == "p" {
+			return strings.Join(strings.Fields(value), "")
+		}
+	}
+	return ""
+}
+
+// expectedSPF 授权 ips 的 SPF 记录
+func expectedSPF(ips []net.IP) string {
+	record := "v=spf1 mx"
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			record += " ip4:" + ip.String()
+		} else {
+			record += " ip6:" + ip.String()
+		}
+	}
+	return record + " ~all"
+}
+
+// containsIP 判断 ips 是否包含 ip
+func containsIP(ips []net.IP, ip net.IP) bool {
+	return slices.ContainsFunc(ips, ip.Equal)
+}
+
+// normalizeHost 主机名转为小写并去掉结尾的点
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// pass 标记为通过（不需要建议的记录）
+func (c *Check) pass(message string) *Check {
+	c.Status, c.Message, c.Expected = StatusPass, message, ""
+	return c
+}
+
+// warn 标记为警告
+func (c *Check) warn(message string) *Check {
+	c.Status, c.Message = StatusWarn, message
+	return c
+}
+
+// fail 标记为失败
+func (c *Check) fail(message string) *Check {
+	c.Status, c.Message = StatusFail, message
+	return c
+}
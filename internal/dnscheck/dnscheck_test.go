@@ -0,0 +1,154 @@
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeResolver 固定的 DNS 记录
+type fakeResolver struct {
+	mx  map[string][]*net.MX
+	txt map[string][]string
+	ip  map[string][]string
+	ptr map[string][]string
+}
+
+var errNoRecord = errors.New("no such host")
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if mx, ok := r.mx[name]; ok {
+		return mx, nil
+	}
+	return nil, errNoRecord
+}
+
+func (r *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := r.txt[name]; ok {
+		return txt, nil
+	}
+	return nil, errNoRecord
+}
+
+func (r *fakeResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r.ip[host]
+	if !ok {
+		return nil, errNoRecord
+	}
+	var addrs []net.IPAddr
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+func (r *fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	if names, ok := r.ptr[addr]; ok {
+		return names, nil
+	}
+	return nil, errNoRecord
+}
+
+// goodResolver 设置正确的域名
+func goodResolver() *fakeResolver {
+	return &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com": {{Host: "mail.example.com.", Pref: 10}},
+		},
+		txt: map[string][]string{
+			"example.com":                    {"google-site-verification=abc", "v=spf1 mx include:_spf.example.net -all"},
+			"_spf.example.net":               {"v=spf1 ip6:2001:db8::/32 -all"},
+			"default._domainkey.example.com": {"v=DKIM1; k=ed25519; p=AAAA BBBB"},
+			"_dmarc.example.com":             {"v=DMARC1; p=reject; rua=mailto:dmarc@example.com"},
+		},
+		ip: map[string][]string{
+			"mail.example.com": {"192.0.2.10", "2001:db8::10"},
+		},
+		ptr: map[string][]string{
+			"192.0.2.10":   {"mail.example.com."},
+			"2001:db8::10": {"MAIL.example.com."},
+		},
+	}
+}
+
+func statuses(report *Report) map[string]string {
+	m := make(map[string]string)
+	for _, check := range report.Checks {
+		m[check.Name] = check.Status
+	}
+	return m
+}
+
+func TestCheckPass(t *testing.T) {
+	checker := &Checker{Resolver: goodResolver(), Hostname: "mail.example.com"}
+	report := checker.Check(context.Background(), "Example.com.", &DKIMRecord{Domain: "example.com", Selector: "default", Value: "v=DKIM1; k=ed25519; p=AAAABBBB"})
+
+	if !report.Passed || report.Domain != "example.com" || len(report.ServerIPs) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	for _, check := range report.Checks {
+		if check.Status != StatusPass || check.Expected != "" {
+			t.Errorf("%s = %s (%s), expected %q", check.Name, check.Status, check.Message, check.Expected)
+		}
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	r := goodResolver()
+	delete(r.mx, "example.com")
+	r.txt["example.com"] = []string{"v=spf1 ip4:198.51.100.0/24 ~all"}
+	r.txt["_dmarc.example.com"] = []string{"v=DMARC1; p=none"}
+	r.ptr["2001:db8::10"] = []string{"host.isp.example."}
+
+	checker := &Checker{Resolver: r, Hostname: "mail.example.com"}
+	report := checker.Check(context.Background(), "example.com", &DKIMRecord{Domain: "example.com", Selector: "default", Value: "v=DKIM1; k=ed25519; p=CCCC"})
+	got := statuses(report)
+	want := map[string]string{
+		CheckMX:    StatusFail,
+		CheckSPF:   StatusFail,
+		CheckDKIM:  StatusFail,
+		CheckDMARC: StatusWarn,
+		CheckPTR:   StatusFail,
+	}
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%s = %s, want %s", name, got[name], status)
+		}
+	}
+	if report.Passed {
+		t.Error("有失败的检查项时 Passed 应该为 false")
+	}
+	for _, check := range report.Checks {
+		if check.Name == CheckSPF && check.Expected != "v=spf1 mx ip4:192.0.2.10 ip6:2001:db8::10 ~all" {
+			t.Errorf("建议的 SPF 记录 = %q", check.Expected)
+		}
+	}
+}
+
+func TestCheckSPFPartial(t *testing.T) {
+	r := goodResolver()
+	// redirect 到另一个域名，a 机制的 /24 只作用于 IPv4 地址，IPv6 地址不在授权范围内
+	r.txt["example.com"] = []string{"v=spf1 redirect=_spf.example.org"}
+	r.txt["_spf.example.org"] = []string{"v=spf1 a:relay.example.org/24 -all"}
+	r.ip["relay.example.org"] = []string{"192.0.2.1", "2001:db8::1"}
+
+	checker := &Checker{Resolver: r, Hostname: "mail.example.com"}
+	report := checker.Check(context.Background(), "example.com", nil)
+	got := statuses(report)
+	if got[CheckSPF] != StatusWarn || got[CheckDKIM] != StatusSkip {
+		t.Errorf("statuses = %v", got)
+	}
+	if !report.Passed {
+		t.Error("只有警告和跳过时 Passed 应该为 true")
+	}
+}
+
+func TestCheckUnresolvableHostname(t *testing.T) {
+	checker := &Checker{Resolver: goodResolver(), Hostname: "mx.unknown.test"}
+	report := checker.Check(context.Background(), "example.com", nil)
+	got := statuses(report)
+	if got[CheckMX] != StatusFail || got[CheckSPF] != StatusFail || got[CheckPTR] != StatusFail {
+		t.Errorf("statuses = %v", got)
+	}
+}
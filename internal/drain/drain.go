@@ -0,0 +1,466 @@
+// Package drain 跟踪协议服务器的连接，用于优雅停止
+//
+// 开始排空后，监听器关闭，空闲的连接（等待客户端发送下一条命令）在读取时返回 io.EOF，
+// 由协议库按客户端断开处理；正在处理命令或被标记为忙碌（如 SMTP 邮件事务进行中）的连接
+// 在完成后才断开，因此进行中的投递不会被中途切断。
+//
+// Tracker 同时记录每个连接的用户、状态和命令数，供管理接口查看和强制断开，
+// 并按连接状态断开超时的连接（客户端已经消失的半开连接、只发送 NOOP 保活的连接）。
+package drain
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// 连接的初始状态
+const StateConnected = "connected"
+
+// 超时断开的原因
+const (
+	// TimeoutIdle 连接在当前状态下长时间没有收发数据
+	TimeoutIdle = "idle"
+	// TimeoutState 连接在当前状态停留过久（如一直没有认证，或只发送保活命令而不开始邮件事务）
+	TimeoutState = "state"
+)
+
+// Observer 记录超时断开的连接（由指标导出器实现）
+type Observer interface {
+	ObserveTimeout(protocol, state, reason string)
+}
+
+// nextID 连接编号，所有 Tracker 共用，SMTP 和 IMAP 的连接编号不重复
+var nextID atomic.Uint64
+
+// Tracker 跟踪通过 Listener 接受的连接
+type Tracker struct {
+	// Protocol 协议名（smtp、imap），用于连接列表
+	Protocol string
+	// IdleTimeouts 按连接状态的空闲超时：在该状态下这么长时间没有收发任何数据时断开，
+	// 没有配置的状态不限制
+	IdleTimeouts map[string]time.Duration
+	// StateTimeouts 按连接状态的最长停留时间，期间的保活命令不会延长；忙碌的连接不受限制
+	StateTimeouts map[string]time.Duration
+	// Observer 超时断开计数（可选）
+	Observer Observer
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	conns     map[*conn]struct{}
+	draining  bool
+	idle      chan struct{} // 排空后所有连接关闭时关闭
+	closed    bool          // idle 已关闭
+}
+
+// Listener 包装监听器，跟踪接受的连接（应在 TLS 之下包装），排空时关闭监听器
+func (t *Tracker) Listener(l net.Listener) net.Listener {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		_ = l.Close()
+	}
+	t.listeners = append(t.listeners, l)
+	return &listener{Listener: l, tracker: t}
+}
+
+// Draining 是否已经开始排空
+func (t *Tracker) Draining() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.draining
+}
+
+// Active 返回仍然打开的连接数
+func (t *Tracker) Active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// Drain 开始排空：关闭监听器，空闲的连接立即断开，忙碌的连接在空闲后断开
+func (t *Tracker) Drain() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return
+	}
+	t.draining = true
+	t.idle = make(chan struct{})
+	for _, l := range t.listeners {
+		_ = l.Close()
+	}
+	for c := range t.conns {
+		c.wake()
+	}
+	t.checkIdleLocked()
+}
+
+// Wait 等待排空完成（所有连接关闭），ctx 取消时返回 ctx 的错误
+func (t *Tracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	idle := t.idle
+	t.mu.Unlock()
+	if idle == nil {
+		return errors.New("未开始排空")
+	}
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Info 连接信息
+type Info struct {
+	ID          uint64    `json:"id"`
+	Protocol    string    `json:"protocol"`
+	RemoteAddr  string    `json:"remote_addr"`
+	User        string    `json:"user,omitempty"`
+	State       string    `json:"state"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Duration    int64     `json:"duration"` // 连接时长（秒）
+	// Commands 客户端发送的命令数（按请求-应答轮次统计，流水线发送的多条命令计为一次）
+	Commands int64 `json:"commands"`
+}
+
+// List 返回仍然打开的连接，按连接编号排序
+func (t *Tracker) List() []Info {
+	t.mu.Lock()
+	conns := make([]*conn, 0, len(t.conns))
+	for c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.mu.Unlock()
+
+	now := time.Now()
+	infos := make([]Info, 0, len(conns))
+	for _, c := range conns {
+		user, state := c.status()
+		infos = append(infos, Info{
+			ID:          c.id,
+			Protocol:    t.Protocol,
+			RemoteAddr:  c.RemoteAddr().String(),
+			User:        user,
+			State:       state,
+			ConnectedAt: c.connectedAt.UTC(),
+			Duration:    int64(now.Sub(c.connectedAt) / time.Second),
+			Commands:    c.commands.Load(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// Disconnect 强制关闭连接（不等待进行中的命令），连接不存在时返回 false
+func (t *Tracker) Disconnect(id uint64) bool {
+	t.mu.Lock()
+	var target *conn
+	for c := range t.conns {
+		if c.id == id {
+			target = c
+			break
+		}
+	}
+	t.mu.Unlock()
+	if target == nil {
+		return false
+	}
+	_ = target.Close()
+	return true
+}
+
+// SetUserByAddr 按远端地址设置连接的用户（协议库只提供连接地址时使用，如 go-imap 的 Login），
+// 状态设置为 authenticated；t 为空或找不到连接时忽略
+func (t *Tracker) SetUserByAddr(remote net.Addr, user string) {
+	if t == nil || remote == nil {
+		return
+	}
+	key := remote.String()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for c := range t.conns {
+		if c.RemoteAddr().String() == key {
+			c.setStatus(user, "authenticated")
+			return
+		}
+	}
+}
+
+// hasTimeouts 是否配置了超时
+func (t *Tracker) hasTimeouts() bool {
+	for _, timeouts := range []map[string]time.Duration{t.IdleTimeouts, t.StateTimeouts} {
+		for _, d := range timeouts {
+			if d > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *Tracker) add(c *conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.draining {
+		return false
+	}
+	if t.conns == nil {
+		t.conns = make(map[*conn]struct{})
+	}
+	t.conns[c] = struct{}{}
+	return true
+}
+
+func (t *Tracker) remove(c *conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, c)
+	t.checkIdleLocked()
+}
+
+func (t *Tracker) checkIdleLocked() {
+	if t.draining && len(t.conns) == 0 && !t.closed {
+		close(t.idle)
+		t.closed = true
+	}
+}
+
+// SetBusy 标记连接是否忙碌（忙碌期间排空不会断开连接）。c 可以是 Listener 接受的连接，
+// 或包装它的 TLS 连接；不是跟踪的连接时忽略
+func SetBusy(c net.Conn, busy bool) {
+	tc := unwrap(c)
+	if tc == nil {
+		return
+	}
+	tc.busy.Store(busy)
+	if !busy && tc.tracker.Draining() {
+		tc.wake()
+	}
+}
+
+// SetUser 设置连接认证的用户，状态设置为 authenticated；不是跟踪的连接时忽略
+func SetUser(c net.Conn, user string) {
+	if tc := unwrap(c); tc != nil {
+		tc.setStatus(user, "authenticated")
+	}
+}
+
+// SetState 设置连接的协议状态（如 SMTP 的 mail、data）；不是跟踪的连接时忽略
+func SetState(c net.Conn, state string) {
+	if tc := unwrap(c); tc != nil {
+		user, _ := tc.status()
+		tc.setStatus(user, state)
+	}
+}
+
+// unwrap 从 TLS 等包装中取出跟踪的连接
+func unwrap(c net.Conn) *conn {
+	for c != nil {
+		switch x := c.(type) {
+		case *conn:
+			return x
+		case interface{ NetConn() net.Conn }:
+			c = x.NetConn()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+type listener struct {
+	net.Listener
+	tracker *Tracker
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		tc := &conn{
+			Conn:        c,
+			tracker:     l.tracker,
+			id:          nextID.Add(1),
+			connectedAt: now,
+			state:       StateConnected,
+			stateSince:  now,
+		}
+		tc.wrote.Store(true)
+		tc.active.Store(now.UnixNano())
+		if l.tracker.add(tc) {
+			tc.startTimer()
+			return tc, nil
+		}
+		// 排空开始后不再接受新连接
+		_ = c.Close()
+	}
+}
+
+// conn 跟踪的连接
+type conn struct {
+	net.Conn
+	tracker *Tracker
+	busy    atomic.Bool
+	closed  sync.Once
+
+	id          uint64
+	connectedAt time.Time
+	commands    atomic.Int64
+	wrote       atomic.Bool  // 上次读取之后是否写过数据（用于统计请求-应答轮次，连接后的首次读取同样计数）
+	active      atomic.Int64 // 最后一次收发数据的时间（UnixNano）
+	timedOut    atomic.Bool
+
+	mu         sync.Mutex
+	user       string
+	state      string
+	stateSince time.Time   // 进入当前状态的时间
+	timer      *time.Timer // 超时检查，没有配置超时时为空
+}
+
+func (c *conn) status() (string, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.user, c.state
+}
+
+func (c *conn) setStatus(user, state string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user = user
+	if state != c.state {
+		c.state, c.stateSince = state, time.Now()
+		// 新状态的超时可能更短，立即重新检查
+		if c.timer != nil {
+			c.timer.Reset(0)
+		}
+	}
+}
+
+// startTimer 配置了超时时启动超时检查
+func (c *conn) startTimer() {
+	if !c.tracker.hasTimeouts() {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer = time.AfterFunc(0, c.checkTimeout)
+}
+
+// checkTimeout 超时时断开连接，否则在最近的超时时间再次检查
+func (c *conn) checkTimeout() {
+	now := time.Now()
+	c.mu.Lock()
+	state, since := c.state, c.stateSince
+	c.mu.Unlock()
+
+	var wait time.Duration
+	reason := ""
+	if d := c.tracker.IdleTimeouts[state]; d > 0 {
+		elapsed := now.Sub(time.Unix(0, c.active.Load()))
+		if elapsed >= d {
+			reason = TimeoutIdle
+		} else {
+			wait = d - elapsed
+		}
+	}
+	if d := c.tracker.StateTimeouts[state]; d > 0 && reason == "" {
+		elapsed := now.Sub(since)
+		switch {
+		case c.busy.Load():
+			// 忙碌期间不限制，状态不变时稍后再检查
+			if wait == 0 || d < wait {
+				wait = d
+			}
+		case elapsed >= d:
+			reason = TimeoutState
+		case wait == 0 || d-elapsed < wait:
+			wait = d - elapsed
+		}
+	}
+
+	if reason == "" {
+		// 当前状态没有超时限制时不再检查，状态改变时重新开始
+		if wait > 0 {
+			c.mu.Lock()
+			c.timer.Reset(wait)
+			c.mu.Unlock()
+		}
+		return
+	}
+	if !c.timedOut.CompareAndSwap(false, true) {
+		return
+	}
+	logger.Info().
+		Str("protocol", c.tracker.Protocol).
+		Str("remote_addr", c.RemoteAddr().String()).
+		Str("state", state).
+		Str("reason", reason).
+		Msg("连接超时，断开")
+	if c.tracker.Observer != nil {
+		c.tracker.Observer.ObserveTimeout(c.tracker.Protocol, state, reason)
+	}
+	_ = c.Close()
+}
+
+// wake 唤醒阻塞在读取上的空闲连接，让它返回 io.EOF
+func (c *conn) wake() {
+	if !c.busy.Load() {
+		_ = c.Conn.SetReadDeadline(time.Now())
+	}
+}
+
+// draining 连接是否应该断开（已开始排空且连接空闲）
+func (c *conn) draining() bool {
+	return !c.busy.Load() && c.tracker.Draining()
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if c.draining() {
+		return 0, io.EOF
+	}
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.active.Store(time.Now().UnixNano())
+		if c.wrote.Swap(false) {
+			c.commands.Add(1)
+		}
+	}
+	if err != nil && errors.Is(err, os.ErrDeadlineExceeded) && c.draining() {
+		return n, io.EOF
+	}
+	return n, err
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	c.wrote.Store(true)
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.active.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
+func (c *conn) Close() error {
+	c.closed.Do(func() {
+		c.mu.Lock()
+		if c.timer != nil {
+			c.timer.Stop()
+		}
+		c.mu.Unlock()
+		c.tracker.remove(c)
+	})
+	return c.Conn.Close()
+}
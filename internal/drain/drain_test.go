@@ -0,0 +1,247 @@
+package drain
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoServer 按行回显，返回每个连接的读取结果
+func echoServer(t *testing.T, tracker *Tracker) (string, chan error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l = tracker.Listener(l)
+	results := make(chan error, 10)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						results <- err
+						return
+					}
+					if line == "busy\n" {
+						SetBusy(c, true)
+					}
+					if line == "done\n" {
+						SetBusy(c, false)
+					}
+					if user, ok := strings.CutPrefix(line, "user "); ok {
+						SetUser(c, strings.TrimSpace(user))
+					}
+					if state, ok := strings.CutPrefix(line, "state "); ok {
+						SetState(c, strings.TrimSpace(state))
+					}
+					if _, err := c.Write([]byte(line)); err != nil {
+						results <- err
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String(), results
+}
+
+func send(t *testing.T, c net.Conn, line string) {
+	t.Helper()
+	if _, err := c.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(line))
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatalf("读取回显失败: %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tracker := &Tracker{}
+	addr, results := echoServer(t, tracker)
+
+	idle, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+	busy, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	send(t, idle, "hello\n")
+	send(t, busy, "busy\n")
+
+	tracker.Drain()
+
+	// 空闲连接在读取时得到 io.EOF
+	if err := <-results; !errors.Is(err, io.EOF) {
+		t.Errorf("空闲连接应该读到 io.EOF, got %v", err)
+	}
+	if _, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		t.Error("排空后不应该接受新连接")
+	}
+
+	// 忙碌的连接继续工作，直到标记为空闲
+	short, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tracker.Wait(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("忙碌的连接完成之前 Wait 应该超时, got %v", err)
+	}
+	send(t, busy, "more\n")
+	send(t, busy, "done\n")
+	if err := <-results; !errors.Is(err, io.EOF) {
+		t.Errorf("忙碌的连接空闲后应该读到 io.EOF, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracker.Wait(ctx); err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+	if n := tracker.Active(); n != 0 {
+		t.Errorf("Active() = %d, want 0", n)
+	}
+}
+
+func TestConnections(t *testing.T) {
+	tracker := &Tracker{Protocol: "smtp"}
+	addr, results := echoServer(t, tracker)
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	other, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	send(t, c, "hello\n")
+	send(t, c, "user alice@example.com\n")
+	send(t, c, "state data\n")
+	send(t, other, "hello\n")
+
+	conns := tracker.List()
+	if len(conns) != 2 {
+		t.Fatalf("List() = %+v, want 2 个连接", conns)
+	}
+	info := conns[0]
+	if info.Protocol != "smtp" || info.User != "alice@example.com" || info.State != "data" || info.Commands != 3 {
+		t.Errorf("连接信息 = %+v", info)
+	}
+	if info.RemoteAddr != c.LocalAddr().String() || info.ConnectedAt.IsZero() {
+		t.Errorf("连接信息 = %+v", info)
+	}
+	if conns[1].User != "" || conns[1].State != StateConnected || conns[1].Commands != 1 {
+		t.Errorf("未认证连接的信息 = %+v", conns[1])
+	}
+
+	// 按远端地址设置用户（go-imap 的 Login 只提供地址）
+	tracker.SetUserByAddr(other.LocalAddr(), "bob@example.com")
+	if conns := tracker.List(); conns[1].User != "bob@example.com" || conns[1].State != "authenticated" {
+		t.Errorf("按地址设置用户后 = %+v", conns[1])
+	}
+
+	// 强制断开连接
+	if !tracker.Disconnect(info.ID) {
+		t.Fatal("Disconnect() = false, want true")
+	}
+	if err := <-results; err == nil {
+		t.Error("断开的连接读取应该失败")
+	}
+	if tracker.Disconnect(info.ID) {
+		t.Error("重复断开应该返回 false")
+	}
+	if conns := tracker.List(); len(conns) != 1 || conns[0].ID == info.ID {
+		t.Errorf("断开后 List() = %+v", conns)
+	}
+}
+
+type timeoutRecorder struct {
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *timeoutRecorder) ObserveTimeout(protocol, state, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, protocol+"/"+state+"/"+reason)
+}
+
+func (r *timeoutRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.reasons...)
+}
+
+func TestTimeouts(t *testing.T) {
+	recorder := &timeoutRecorder{}
+	tracker := &Tracker{
+		Protocol:      "smtp",
+		IdleTimeouts:  map[string]time.Duration{"authenticated": 100 * time.Millisecond},
+		StateTimeouts: map[string]time.Duration{StateConnected: 300 * time.Millisecond},
+		Observer:      recorder,
+	}
+	addr, results := echoServer(t, tracker)
+
+	// 只发送保活命令也会在状态超时后断开
+	keepalive, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer keepalive.Close()
+	// 忙碌的连接不受状态超时限制
+	busy, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	send(t, busy, "busy\n")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := keepalive.Write([]byte("noop\n")); err != nil {
+			break
+		}
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(keepalive, buf); err != nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := <-results; err == nil {
+		t.Fatal("状态超时的连接读取应该失败")
+	}
+	if got := recorder.get(); len(got) != 1 || got[0] != "smtp/connected/state" {
+		t.Errorf("超时记录 = %v", got)
+	}
+	if n := tracker.Active(); n != 1 {
+		t.Errorf("忙碌的连接不应该断开, Active() = %d", n)
+	}
+
+	// 认证后没有收发数据时按空闲超时断开（忙碌的连接同样受空闲超时限制）
+	send(t, busy, "user alice@example.com\n")
+	if err := <-results; err == nil {
+		t.Fatal("空闲超时的连接读取应该失败")
+	}
+	if got := recorder.get(); len(got) != 2 || got[1] != "smtp/authenticated/idle" {
+		t.Errorf("超时记录 = %v", got)
+	}
+}
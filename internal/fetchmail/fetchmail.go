@@ -0,0 +1,405 @@
+// Package fetchmail 定期从用户的外部 POP3/IMAP 账户拉取邮件到本地文件夹
+//
+// 远程账户密码以口令派生的密钥加密后存储。已拉取的邮件按 POP3 UIDL 或
+// IMAP UIDVALIDITY:UID 记录，保留远程邮件时不会重复拉取。
+package fetchmail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/netguard"
+	"github.com/gomailzero/gmz/internal/newsletter"
+	"github.com/gomailzero/gmz/internal/search"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 协议
+const (
+	ProtocolPOP3 = "pop3"
+	ProtocolIMAP = "imap"
+)
+
+const (
+	// defaultMaxMessages 每次拉取的默认最大邮件数
+	defaultMaxMessages = 100
+	// defaultTimeout 每次拉取会话的默认超时
+	defaultTimeout = 5 * time.Minute
+	// dialTimeout 连接远程服务器的超时
+	dialTimeout = 30 * time.Second
+)
+
+var (
+	// ErrBusy 账户正在拉取
+	ErrBusy = errors.New("该账户正在拉取邮件")
+	// ErrInvalidAccount 账户设置无效
+	ErrInvalidAccount = errors.New("无效的外部邮箱账户设置")
+)
+
+// remoteMailbox 远程邮箱（POP3 或 IMAP）
+type remoteMailbox interface {
+	// List 返回远程邮件的唯一标识（按远程顺序）
+	List() ([]string, error)
+	// Retrieve 获取邮件原文
+	Retrieve(uid string) ([]byte, error)
+	// Delete 删除远程邮件（Close 时生效）
+	Delete(uid string) error
+	// Close 结束会话
+	Close() error
+}
+
+// Fetcher 外部邮箱拉取器
+type Fetcher struct {
+	Storage     storage.Driver
+	Maildir     *storage.Maildir
+	Passphrase  string        // 加密远程账户密码的口令
+	Hostname    string        // Received 头中的本机主机名
+	Interval    time.Duration // 定期拉取间隔
+	MaxMessages int           // 每个账户每次最多拉取的邮件数（为 0 时使用默认值）
+	Timeout     time.Duration // 每次拉取会话的超时（为 0 时使用默认值）
+	TLSConfig   *tls.Config   // 连接远程服务器的 TLS 配置模板（可选）
+
+	// AllowPrivateHosts 允许连接内网地址（远程地址由用户填写，默认只允许公网地址）
+	AllowPrivateHosts bool
+
+	mu      sync.Mutex
+	running map[int64]bool
+}
+
+// Validate 校验并补全账户设置（未指定端口时使用协议的默认端口，未指定文件夹时投递到收件箱）
+func Validate(account *storage.ExternalAccount) error {
+	account.Protocol = strings.ToLower(strings.TrimSpace(account.Protocol))
+	account.Host = strings.TrimSpace(account.Host)
+	account.Folder = strings.TrimSpace(account.Folder)
+
+	switch account.Protocol {
+	case ProtocolPOP3, ProtocolIMAP:
+	default:
+		return fmt.Errorf("%w: 不支持的协议 %q（可选值 pop3, imap）", ErrInvalidAccount, account.Protocol)
+	}
+	if account.Host == "" || strings.ContainsAny(account.Host, "/\\ ") {
+		return fmt.Errorf("%w: 无效的服务器地址", ErrInvalidAccount)
+	}
+	if account.Port == 0 {
+		account.Port = defaultPort(account.Protocol, account.TLS)
+	}
+	if account.Port < 1 || account.Port > 65535 {
+		return fmt.Errorf("%w: 端口超出范围（1-65535）", ErrInvalidAccount)
+	}
+	if account.Username == "" {
+		return fmt.Errorf("%w: 用户名不能为空", ErrInvalidAccount)
+	}
+	if account.Folder == "" || strings.EqualFold(account.Folder, "INBOX") {
+		account.Folder = "INBOX"
+	} else if len(account.Folder) > 64 || strings.HasPrefix(account.Folder, ".") ||
+		strings.ContainsAny(account.Folder, "/\\\x00") || strings.Contains(account.Folder, "..") {
+		return fmt.Errorf("%w: 无效的文件夹名称", ErrInvalidAccount)
+	}
+	return nil
+}
+
+// defaultPort 协议的默认端口
+func defaultPort(protocol string, useTLS bool) int {
+	switch {
+	case protocol == ProtocolPOP3 && useTLS:
+		return 995
+	case protocol == ProtocolPOP3:
+		return 110
+	case useTLS:
+		return 993
+	default:
+		return 143
+	}
+}
+
+// EncryptPassword 加密远程账户密码
+func (f *Fetcher) EncryptPassword(password string) ([]byte, error) {
+	encrypted, err := crypto.EncryptWithPassphrase(f.Passphrase, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("加密密码失败: %w", err)
+	}
+	return encrypted, nil
+}
+
+// decryptPassword 解密 EncryptPassword 生成的数据
+func (f *Fetcher) decryptPassword(data []byte) (string, error) {
+	plaintext, err := crypto.DecryptWithPassphrase(f.Passphrase, data)
+	if err != nil {
+		return "", fmt.Errorf("解密账户密码失败（加密口令是否已更改？）: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Run 定期拉取所有启用的账户，直到 ctx 取消
+func (f *Fetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.Interval)
+	defer ticker.Stop()
+
+	for {
+		f.fetchAll(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fetchAll 依次拉取所有启用的账户（错误记录在账户状态中）
+func (f *Fetcher) fetchAll(ctx context.Context) {
+	accounts, err := f.Storage.ListExternalAccounts(ctx, "")
+	if err != nil {
+		logger.Warn().Err(err).Msg("列出外部邮箱账户失败")
+		return
+	}
+	for _, account := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if !account.Enabled {
+			continue
+		}
+		if _, err := f.Fetch(ctx, account); err != nil && !errors.Is(err, ErrBusy) {
+			logger.Warn().Err(err).
+				Str("user", account.UserEmail).
+				Int64("account_id", account.ID).
+				Str("host", account.Host).
+				Msg("拉取外部邮箱失败")
+		}
+	}
+}
+
+// Fetch 拉取一个账户的新邮件并记录拉取状态，返回拉取的邮件数
+func (f *Fetcher) Fetch(ctx context.Context, account *storage.ExternalAccount) (int, error) {
+	if !f.acquire(account.ID) {
+		return 0, ErrBusy
+	}
+	defer f.release(account.ID)
+	return f.fetchAndRecord(ctx, account)
+}
+
+// FetchNow 在后台立即拉取一个账户（结果记录在账户状态中），账户正在拉取时返回 ErrBusy
+func (f *Fetcher) FetchNow(ctx context.Context, account *storage.ExternalAccount) error {
+	if !f.acquire(account.ID) {
+		return ErrBusy
+	}
+	go func() {
+		defer f.release(account.ID)
+		if _, err := f.fetchAndRecord(ctx, account); err != nil {
+			logger.WarnCtx(ctx).Err(err).Int64("account_id", account.ID).Msg("拉取外部邮箱失败")
+		}
+	}()
+	return nil
+}
+
+// fetchAndRecord 拉取并记录拉取状态
+func (f *Fetcher) fetchAndRecord(ctx context.Context, account *storage.ExternalAccount) (int, error) {
+	count, err := f.fetch(ctx, account)
+	fetchErr := ""
+	if err != nil {
+		fetchErr = err.Error()
+	}
+	if recordErr := f.Storage.RecordExternalFetch(ctx, account.ID, time.Now(), count, fetchErr); recordErr != nil {
+		logger.Warn().Err(recordErr).Int64("account_id", account.ID).Msg("记录拉取状态失败")
+	}
+	if count > 0 {
+		logger.Info().
+			Str("user", account.UserEmail).
+			Int64("account_id", account.ID).
+			Str("host", account.Host).
+			Int("count", count).
+			Msg("已拉取外部邮箱邮件")
+	}
+	return count, err
+}
+
+// acquire 标记账户正在拉取，已在拉取时返回 false
+func (f *Fetcher) acquire(id int64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.running == nil {
+		f.running = make(map[int64]bool)
+	}
+	if f.running[id] {
+		return false
+	}
+	f.running[id] = true
+	return true
+}
+
+// release 清除账户的拉取标记
+func (f *Fetcher) release(id int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.running, id)
+}
+
+// fetch 连接远程服务器并投递新邮件
+func (f *Fetcher) fetch(ctx context.Context, account *storage.ExternalAccount) (int, error) {
+	password, err := f.decryptPassword(account.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := f.dial(ctx, account)
+	if err != nil {
+		return 0, err
+	}
+	timeout := f.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	var mbox remoteMailbox
+	switch account.Protocol {
+	case ProtocolPOP3:
+		mbox, err = openPOP3(conn, account.Username, password)
+	case ProtocolIMAP:
+		mbox, err = openIMAP(conn, account.Username, password, !account.KeepRemote)
+	default:
+		err = fmt.Errorf("不支持的协议: %s", account.Protocol)
+	}
+	if err != nil {
+		conn.Close()
+		return 0, err
+	}
+	defer func() {
+		if err := mbox.Close(); err != nil {
+			logger.Debug().Err(err).Int64("account_id", account.ID).Msg("关闭远程会话失败")
+		}
+	}()
+
+	uids, err := mbox.List()
+	if err != nil {
+		return 0, err
+	}
+	fetched, err := f.Storage.ListFetchedUIDs(ctx, account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	maxMessages := f.MaxMessages
+	if maxMessages <= 0 {
+		maxMessages = defaultMaxMessages
+	}
+	count := 0
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			return count, ctx.Err()
+		}
+		if !fetched[uid] {
+			if count >= maxMessages {
+				break
+			}
+			data, err := mbox.Retrieve(uid)
+			if err != nil {
+				return count, err
+			}
+			if err := f.deliver(ctx, account, data); err != nil {
+				return count, err
+			}
+			if err := f.Storage.AddFetchedUID(ctx, account.ID, uid); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		// 不保留远程邮件时删除（包括之前删除失败的邮件）
+		if !account.KeepRemote {
+			if err := mbox.Delete(uid); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// dial 连接远程服务器（默认只允许公网地址）
+func (f *Fetcher) dial(ctx context.Context, account *storage.ExternalAccount) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if !f.AllowPrivateHosts {
+		dialer.Control = netguard.PublicOnly
+	}
+
+	addr := net.JoinHostPort(account.Host, strconv.Itoa(account.Port))
+	var conn net.Conn
+	var err error
+	if account.TLS {
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if f.TLSConfig != nil {
+			cfg = f.TLSConfig.Clone()
+		}
+		cfg.ServerName = account.Host
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: cfg}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接 %s 失败: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// deliver 将拉取的邮件投递到用户的本地文件夹（与 SMTP 投递一样识别订阅邮件并自动分类）
+func (f *Fetcher) deliver(ctx context.Context, account *storage.ExternalAccount, data []byte) error {
+	received := fmt.Sprintf("Received: from %s\r\n\tby %s with %s id %d; %s\r\n",
+		account.Host, f.Hostname, strings.ToUpper(account.Protocol), account.ID, time.Now().Format(time.RFC1123Z))
+	data = append([]byte(received), data...)
+
+	userEmail := account.UserEmail
+
+	// 无法解析的邮件仍然投递，只是没有元数据
+	var header message.Header
+	if msg, err := message.Read(bytes.NewReader(data)); err == nil || message.IsUnknownCharset(err) {
+		header = msg.Header
+	}
+
+	flags := []string{"\\Recent"}
+	unsubscribe := newsletter.Detect(header)
+	if unsubscribe != nil {
+		flags = append(flags, newsletter.Keyword)
+	}
+	cat, tokens := category.NewClassifier(f.Storage).Classify(ctx, userEmail, header)
+
+	to := []string{userEmail}
+	if toHeader := header.Get("To"); toHeader != "" {
+		to = []string{toHeader}
+	}
+	now := time.Now()
+	mail := &storage.Mail{
+		UserEmail:  userEmail,
+		Folder:     account.Folder,
+		From:       header.Get("From"),
+		To:         to,
+		Subject:    header.Get("Subject"),
+		Size:       int64(len(data)),
+		Flags:      append(flags, category.Keyword(cat)),
+		ReceivedAt: now,
+		CreatedAt:  now,
+
+		Attachments: search.ExtractAttachments(data),
+		Unsubscribe: unsubscribe,
+	}
+	// 失败时文件和数据库记录一起回滚，下次拉取时重新投递
+	if err := storage.NewMailStore(f.Maildir, f.Storage).Deliver(ctx, mail, data); err != nil {
+		return err
+	}
+	if err := f.Storage.SaveMailCategory(ctx, &storage.MailCategory{MailID: mail.ID, Category: cat, Tokens: tokens}); err != nil {
+		logger.Warn().Err(err).Str("user", userEmail).Msg("保存邮件分类失败")
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package fetchmail
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// fakePOP3 只实现拉取所需命令的 POP3 服务器
+type fakePOP3 struct {
+	mu       sync.Mutex
+	messages map[string]string // UIDL -> 邮件原文
+	order    []string
+}
+
+func (s *fakePOP3) serve(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (s *fakePOP3) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "+OK ready\r\n")
+
+	var order []string
+	deleted := make(map[string]bool)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch strings.ToUpper(cmd) {
+		case "USER":
+			fmt.Fprint(conn, "+OK\r\n")
+		case "PASS":
+			if arg != "secret" {
+				fmt.Fprint(conn, "-ERR invalid password\r\n")
+				continue
+			}
+			s.mu.Lock()
+			order = append([]string(nil), s.order...)
+			s.mu.Unlock()
+			fmt.Fprint(conn, "+OK\r\n")
+		case "UIDL":
+			fmt.Fprint(conn, "+OK\r\n")
+			for i, uid := range order {
+				fmt.Fprintf(conn, "%d %s\r\n", i+1, uid)
+			}
+			fmt.Fprint(conn, ".\r\n")
+		case "RETR":
+			var n int
+			fmt.Sscanf(arg, "%d", &n)
+			s.mu.Lock()
+			body := s.messages[order[n-1]]
+			s.mu.Unlock()
+			fmt.Fprint(conn, "+OK\r\n")
+			for _, l := range strings.Split(strings.TrimSuffix(body, "\r\n"), "\r\n") {
+				if strings.HasPrefix(l, ".") {
+					l = "." + l
+				}
+				fmt.Fprintf(conn, "%s\r\n", l)
+			}
+			fmt.Fprint(conn, ".\r\n")
+		case "DELE":
+			var n int
+			fmt.Sscanf(arg, "%d", &n)
+			deleted[order[n-1]] = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case "QUIT":
+			s.mu.Lock()
+			remaining := s.order[:0]
+			for _, uid := range s.order {
+				if !deleted[uid] {
+					remaining = append(remaining, uid)
+				}
+			}
+			s.order = remaining
+			s.mu.Unlock()
+			fmt.Fprint(conn, "+OK bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "-ERR unknown command\r\n")
+		}
+	}
+}
+
+func newTestFetcher(t *testing.T) (*Fetcher, storage.Driver) {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 Maildir 失败: %v", err)
+	}
+	return &Fetcher{
+		Storage:           driver,
+		Maildir:           maildir,
+		Passphrase:        "test-passphrase",
+		Hostname:          "mx.example.com",
+		AllowPrivateHosts: true,
+	}, driver
+}
+
+func newTestAccount(t *testing.T, f *Fetcher, protocol, addr, username, password string, keepRemote bool) *storage.ExternalAccount {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(addr)
+	account := &storage.ExternalAccount{
+		UserEmail: "alice@example.com", Protocol: protocol, Host: host, Username: username,
+		Folder: "Fetched", KeepRemote: keepRemote, Enabled: true,
+	}
+	fmt.Sscanf(port, "%d", &account.Port)
+	if err := Validate(account); err != nil {
+		t.Fatalf("账户设置无效: %v", err)
+	}
+	encrypted, err := f.EncryptPassword(password)
+	if err != nil {
+		t.Fatalf("加密密码失败: %v", err)
+	}
+	account.Password = encrypted
+	if err := f.Storage.CreateExternalAccount(context.Background(), account); err != nil {
+		t.Fatalf("创建外部邮箱账户失败: %v", err)
+	}
+	return account
+}
+
+func TestFetchPOP3(t *testing.T) {
+	f, driver := newTestFetcher(t)
+	ctx := context.Background()
+	pop := &fakePOP3{
+		messages: map[string]string{
+			"u1": "From: bob@remote.test\r\nSubject: first\r\n\r\n.hidden dot\r\n",
+			"u2": "From: shop@remote.test\r\nSubject: sale\r\nList-Unsubscribe: <https://remote.test/u>\r\n\r\nbuy\r\n",
+		},
+		order: []string{"u1", "u2"},
+	}
+	addr := pop.serve(t)
+
+	account := newTestAccount(t, f, ProtocolPOP3, addr, "alice", "secret", true)
+	count, err := f.Fetch(ctx, account)
+	if err != nil || count != 2 {
+		t.Fatalf("拉取失败: %d %v", count, err)
+	}
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "Fetched", 10, 0)
+	if err != nil || len(mails) != 2 {
+		t.Fatalf("应该投递 2 封邮件: %d %v", len(mails), err)
+	}
+	data, err := f.Maildir.ReadMail("alice@example.com", "Fetched", mails[len(mails)-1].ID)
+	if err != nil {
+		t.Fatalf("读取邮件失败: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "Received: from 127.0.0.1") {
+		t.Errorf("应该添加 Received 头: %q", data)
+	}
+
+	// 保留远程邮件时不重复拉取
+	if count, err := f.Fetch(ctx, account); err != nil || count != 0 {
+		t.Errorf("再次拉取不应该有新邮件: %d %v", count, err)
+	}
+	got, _ := driver.GetExternalAccount(ctx, account.ID)
+	if got.TotalCount != 2 || got.LastError != "" || got.LastSuccessAt == nil {
+		t.Errorf("拉取状态不正确: %+v", got)
+	}
+
+	// 不保留远程邮件时拉取后删除
+	account.KeepRemote = false
+	if _, err := f.Fetch(ctx, account); err != nil {
+		t.Fatalf("拉取失败: %v", err)
+	}
+	if len(pop.order) != 0 {
+		t.Errorf("远程邮件应该已删除: %v", pop.order)
+	}
+}
+
+func TestFetchPOP3Error(t *testing.T) {
+	f, driver := newTestFetcher(t)
+	ctx := context.Background()
+	addr := (&fakePOP3{}).serve(t)
+
+	account := newTestAccount(t, f, ProtocolPOP3, addr, "alice", "wrong", true)
+	if _, err := f.Fetch(ctx, account); err == nil {
+		t.Fatal("密码错误时应该失败")
+	}
+	got, _ := driver.GetExternalAccount(ctx, account.ID)
+	if !strings.Contains(got.LastError, "invalid password") || got.LastFetchAt == nil || got.LastSuccessAt != nil {
+		t.Errorf("错误状态不正确: %+v", got)
+	}
+
+	// 默认不允许连接内网地址
+	f.AllowPrivateHosts = false
+	if _, err := f.Fetch(ctx, account); err == nil || !strings.Contains(err.Error(), "非公网地址") {
+		t.Errorf("应该拒绝连接回环地址, got %v", err)
+	}
+}
+
+func TestFetchIMAP(t *testing.T) {
+	f, driver := newTestFetcher(t)
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	s := server.New(memory.New())
+	s.AllowInsecureAuth = true
+	go s.Serve(listener)
+	defer s.Close()
+
+	account := newTestAccount(t, f, ProtocolIMAP, listener.Addr().String(), "username", "password", true)
+	count, err := f.Fetch(ctx, account)
+	if err != nil || count != 1 {
+		t.Fatalf("拉取失败: %d %v", count, err)
+	}
+	mails, _ := driver.ListMails(ctx, "alice@example.com", "Fetched", 10, 0)
+	if len(mails) != 1 || mails[0].Subject != "A little message, just for you" {
+		t.Fatalf("投递的邮件不正确: %+v", mails)
+	}
+	if count, err := f.Fetch(ctx, account); err != nil || count != 0 {
+		t.Errorf("再次拉取不应该有新邮件: %d %v", count, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	account := &storage.ExternalAccount{Protocol: "POP3", Host: "pop.example.net", Username: "a", TLS: true}
+	if err := Validate(account); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	if account.Protocol != ProtocolPOP3 || account.Port != 995 || account.Folder != "INBOX" {
+		t.Errorf("默认值不正确: %+v", account)
+	}
+
+	for _, bad := range []*storage.ExternalAccount{
+		{Protocol: "smtp", Host: "h", Username: "a"},
+		{Protocol: "imap", Host: "", Username: "a"},
+		{Protocol: "imap", Host: "h", Username: ""},
+		{Protocol: "imap", Host: "h", Username: "a", Port: 70000},
+		{Protocol: "imap", Host: "h", Username: "a", Folder: "../x"},
+	} {
+		if err := Validate(bad); !errors.Is(err, ErrInvalidAccount) {
+			t.Errorf("Validate(%+v) 应该返回 ErrInvalidAccount, got %v", bad, err)
+		}
+	}
+}
+
+func TestPasswordEncryption(t *testing.T) {
+	f := &Fetcher{Passphrase: "one"}
+	encrypted, err := f.EncryptPassword("s3cret")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if strings.Contains(string(encrypted), "s3cret") {
+		t.Error("密文不应该包含明文")
+	}
+	if got, err := f.decryptPassword(encrypted); err != nil || got != "s3cret" {
+		t.Errorf("解密结果不正确: %q %v", got, err)
+	}
+	if _, err := (&Fetcher{Passphrase: "two"}).decryptPassword(encrypted); err == nil {
+		t.Error("使用其他口令解密应该失败")
+	}
+}
@@ -0,0 +1,112 @@
+package fetchmail
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// imapMailbox IMAP 会话，拉取远程收件箱，按 UIDVALIDITY:UID 标识邮件
+type imapMailbox struct {
+	client      *client.Client
+	uidValidity uint32
+	deleted     bool
+}
+
+// openIMAP 在已建立的连接上登录 IMAP 服务器并选择收件箱（不删除远程邮件时只读打开）
+func openIMAP(conn net.Conn, username, password string, writable bool) (*imapMailbox, error) {
+	c, err := client.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("IMAP 服务器不可用: %w", err)
+	}
+	if err := c.Login(username, password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("IMAP 登录失败: %w", err)
+	}
+	status, err := c.Select("INBOX", !writable)
+	if err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("打开远程收件箱失败: %w", err)
+	}
+	return &imapMailbox{client: c, uidValidity: status.UidValidity}, nil
+}
+
+// List 返回所有邮件的 UIDVALIDITY:UID
+func (m *imapMailbox) List() ([]string, error) {
+	uids, err := m.client.UidSearch(imap.NewSearchCriteria())
+	if err != nil {
+		return nil, fmt.Errorf("IMAP 搜索失败: %w", err)
+	}
+	result := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		result = append(result, fmt.Sprintf("%d:%d", m.uidValidity, uid))
+	}
+	return result, nil
+}
+
+// parseUID 从 UIDVALIDITY:UID 中取出 UID
+func (m *imapMailbox) parseUID(key string) (*imap.SeqSet, error) {
+	validity, uid, ok := strings.Cut(key, ":")
+	n, err := strconv.ParseUint(uid, 10, 32)
+	if !ok || err != nil || validity != strconv.FormatUint(uint64(m.uidValidity), 10) {
+		return nil, fmt.Errorf("远程邮件不存在: %s", key)
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uint32(n))
+	return seqSet, nil
+}
+
+// Retrieve 获取邮件原文（BODY.PEEK[]，不修改远程 \Seen 标志）
+func (m *imapMailbox) Retrieve(key string) ([]byte, error) {
+	seqSet, err := m.parseUID(key)
+	if err != nil {
+		return nil, err
+	}
+
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, 1)
+	if err := m.client.UidFetch(seqSet, []imap.FetchItem{section.FetchItem()}, messages); err != nil {
+		return nil, fmt.Errorf("IMAP 获取邮件失败: %w", err)
+	}
+	msg := <-messages
+	if msg == nil {
+		return nil, fmt.Errorf("远程邮件不存在: %s", key)
+	}
+	body := msg.GetBody(section)
+	if body == nil {
+		return nil, fmt.Errorf("远程服务器未返回邮件内容: %s", key)
+	}
+	return io.ReadAll(body)
+}
+
+// Delete 标记删除邮件（Close 时 EXPUNGE）
+func (m *imapMailbox) Delete(key string) error {
+	seqSet, err := m.parseUID(key)
+	if err != nil {
+		return err
+	}
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	if err := m.client.UidStore(seqSet, item, []interface{}{imap.DeletedFlag}, nil); err != nil {
+		return fmt.Errorf("IMAP 删除邮件失败: %w", err)
+	}
+	m.deleted = true
+	return nil
+}
+
+// Close 清除已删除的邮件并退出
+func (m *imapMailbox) Close() error {
+	var err error
+	if m.deleted {
+		err = m.client.Expunge(nil)
+	}
+	if logoutErr := m.client.Logout(); err == nil {
+		err = logoutErr
+	}
+	return err
+}
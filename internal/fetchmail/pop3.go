@@ -0,0 +1,139 @@
+package fetchmail
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// pop3Mailbox POP3 会话（RFC 1939），按 UIDL 标识邮件
+type pop3Mailbox struct {
+	text    *textproto.Conn
+	numbers map[string]int // UIDL -> 邮件序号
+}
+
+// openPOP3 在已建立的连接上登录 POP3 服务器（USER/PASS）
+func openPOP3(conn net.Conn, username, password string) (*pop3Mailbox, error) {
+	p := &pop3Mailbox{text: textproto.NewConn(conn)}
+	if _, err := p.readResponse(); err != nil {
+		p.text.Close()
+		return nil, fmt.Errorf("POP3 服务器不可用: %w", err)
+	}
+	if _, err := p.cmd("USER %s", username); err != nil {
+		p.text.Close()
+		return nil, fmt.Errorf("POP3 登录失败: %w", err)
+	}
+	if _, err := p.cmd("PASS %s", password); err != nil {
+		p.text.Close()
+		return nil, fmt.Errorf("POP3 登录失败: %w", err)
+	}
+	return p, nil
+}
+
+// cmd 发送命令并读取单行响应
+func (p *pop3Mailbox) cmd(format string, args ...any) (string, error) {
+	if err := p.text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	return p.readResponse()
+}
+
+// readResponse 读取 +OK / -ERR 响应
+func (p *pop3Mailbox) readResponse() (string, error) {
+	line, err := p.text.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case strings.HasPrefix(line, "+OK"):
+		return strings.TrimSpace(line[3:]), nil
+	case strings.HasPrefix(line, "-ERR"):
+		return "", fmt.Errorf("服务器返回错误: %s", strings.TrimSpace(line[4:]))
+	default:
+		return "", fmt.Errorf("无效的 POP3 响应: %q", line)
+	}
+}
+
+// readLines 读取多行响应（以 "." 结束，去除行首的点填充）
+func (p *pop3Mailbox) readLines() ([]string, error) {
+	var lines []string
+	for {
+		line, err := p.text.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "." {
+			return lines, nil
+		}
+		if strings.HasPrefix(line, ".") {
+			line = line[1:]
+		}
+		lines = append(lines, line)
+	}
+}
+
+// List 返回所有邮件的 UIDL
+func (p *pop3Mailbox) List() ([]string, error) {
+	if _, err := p.cmd("UIDL"); err != nil {
+		return nil, fmt.Errorf("POP3 UIDL 失败（服务器需要支持 UIDL）: %w", err)
+	}
+	lines, err := p.readLines()
+	if err != nil {
+		return nil, err
+	}
+
+	p.numbers = make(map[string]int, len(lines))
+	uids := make([]string, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		p.numbers[fields[1]] = n
+		uids = append(uids, fields[1])
+	}
+	return uids, nil
+}
+
+// Retrieve 获取邮件原文（RETR）
+func (p *pop3Mailbox) Retrieve(uid string) ([]byte, error) {
+	n, ok := p.numbers[uid]
+	if !ok {
+		return nil, fmt.Errorf("远程邮件不存在: %s", uid)
+	}
+	if _, err := p.cmd("RETR %d", n); err != nil {
+		return nil, fmt.Errorf("POP3 RETR 失败: %w", err)
+	}
+	lines, err := p.readLines()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(lines, "\r\n") + "\r\n"), nil
+}
+
+// Delete 标记删除邮件（DELE，QUIT 后生效）
+func (p *pop3Mailbox) Delete(uid string) error {
+	n, ok := p.numbers[uid]
+	if !ok {
+		return fmt.Errorf("远程邮件不存在: %s", uid)
+	}
+	if _, err := p.cmd("DELE %d", n); err != nil {
+		return fmt.Errorf("POP3 DELE 失败: %w", err)
+	}
+	return nil
+}
+
+// Close 结束会话（QUIT 后服务器才真正删除邮件）
+func (p *pop3Mailbox) Close() error {
+	_, err := p.cmd("QUIT")
+	if closeErr := p.text.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
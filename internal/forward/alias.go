@@ -0,0 +1,64 @@
+package forward
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// ResolveAlias 展开有效别名 address 的全部目标（见 Destinations）并记录别名投递次数；
+// address 不是有效别名（不存在、已过期或已作废）时 ok 为 false
+// SMTP 投递、WebMail 发送和 IMAP 提交文件夹使用相同的展开规则
+func ResolveAlias(ctx context.Context, driver storage.Driver, address string) (mailboxes, external []string, ok bool) {
+	alias, err := driver.GetAlias(ctx, strings.ToLower(address))
+	if err != nil || !alias.Active(time.Now()) {
+		return nil, nil, false
+	}
+	if err := driver.RecordAliasDelivery(ctx, alias.From); err != nil {
+		logger.Warn().Err(err).Str("alias", alias.From).Msg("记录别名投递失败")
+	}
+	mailboxes, external = Destinations(ctx, driver, alias)
+	return mailboxes, external, true
+}
+
+// Destinations 将别名的目标分为本地邮箱（目标地址属于本地域名）和外部地址
+func Destinations(ctx context.Context, driver storage.Driver, alias *storage.Alias) (mailboxes, external []string) {
+	for _, to := range alias.Destinations {
+		if _, err := driver.GetDomain(ctx, to[strings.LastIndex(to, "@")+1:]); err == nil {
+			mailboxes = append(mailboxes, to)
+		} else {
+			external = append(external, to)
+		}
+	}
+	return mailboxes, external
+}
+
+// ForwardAlias 将发往别名 alias 的邮件经 SRS 重写后转发到别名的外部目标（邮件前添加 Delivered-To: alias，
+// 转发回来时据此发现循环）；未配置转发（f 为空）或别名所在域名禁止外部转发时不转发，返回转发成功的目标数
+func (f *Forwarder) ForwardAlias(ctx context.Context, alias, sender string, external []string, data []byte) int {
+	if len(external) == 0 {
+		return 0
+	}
+	if f == nil {
+		logger.Warn().Str("alias", alias).Strs("to", external).Msg("未配置外部转发，忽略别名的外部目标")
+		return 0
+	}
+	if allowed, err := f.Allowed(ctx, alias); err != nil || !allowed {
+		logger.Warn().Err(err).Str("alias", alias).Strs("to", external).Msg("别名所在域名禁止外部转发，忽略外部目标")
+		return 0
+	}
+	data = append([]byte("Delivered-To: "+alias+"\r\n"), data...)
+	forwarded := 0
+	for _, to := range external {
+		if err := f.ForwardTo(ctx, sender, to, data); err != nil {
+			logger.Warn().Err(err).Str("alias", alias).Str("to", to).Msg("别名转发到外部地址失败")
+			continue
+		}
+		forwarded++
+		logger.Info().Str("alias", alias).Str("to", to).Msg("别名邮件已转发到外部地址")
+	}
+	return forwarded
+}
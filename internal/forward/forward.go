@@ -0,0 +1,223 @@
+// Package forward 实现用户到外部地址的邮件转发（目标地址验证、条件匹配和 SRS 发件人重写）
+package forward
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// codeTTL 验证码有效期
+	codeTTL = 30 * time.Minute
+	// maxAttempts 验证码最多尝试次数，超过后需要重新发送
+	maxAttempts = 5
+)
+
+var (
+	// ErrDisabled 用户所在域名禁止外部转发
+	ErrDisabled = errors.New("该域名已禁止转发到外部地址")
+	// ErrInvalidAddress 转发目标地址无效
+	ErrInvalidAddress = errors.New("无效的转发地址")
+	// ErrNoPending 没有待验证的转发规则
+	ErrNoPending = errors.New("没有待验证的转发地址")
+	// ErrCodeExpired 验证码已过期或尝试次数过多
+	ErrCodeExpired = errors.New("验证码已失效，请重新发送")
+	// ErrCodeInvalid 验证码错误
+	ErrCodeInvalid = errors.New("验证码错误")
+)
+
+// Forwarder 外部转发
+type Forwarder struct {
+	Domain    string              // 主域名（验证邮件发件人）
+	Storage   storage.Driver      // 存储驱动
+	SMTP      *config.SMTPConfig  // 外发配置（中继或直接投递）
+	ClientTLS *tls.Config         // 外发 TLS 配置模板（可选）
+	SRS       *SRS                // 发件人重写
+	Warmup    smtpclient.Throttle // 外发预热限流（可选）
+	// Deliveries 投递审计日志（可选）
+	Deliveries smtpclient.DeliveryRecorder
+
+	sendFunc func(ctx context.Context, from, to string, data []byte) error // 测试时替换外发
+}
+
+// Allowed 用户所在域名是否允许外部转发
+func (f *Forwarder) Allowed(ctx context.Context, userEmail string) (bool, error) {
+	domain, err := f.Storage.GetDomain(ctx, userEmail[strings.LastIndex(userEmail, "@")+1:])
+	if err != nil {
+		return false, err
+	}
+	return !domain.ForwardingDisabled, nil
+}
+
+// Request 保存转发规则；目标地址变化或尚未验证时生成验证码并发送到目标地址，规则在验证后才生效
+// 返回是否发送了验证码
+func (f *Forwarder) Request(ctx context.Context, rule *storage.ForwardingRule) (bool, error) {
+	rule.Address = strings.ToLower(strings.TrimSpace(rule.Address))
+	at := strings.LastIndex(rule.Address, "@")
+	if at <= 0 || at == len(rule.Address)-1 || strings.ContainsAny(rule.Address, " \r\n<>") {
+		return false, ErrInvalidAddress
+	}
+	if rule.Address == strings.ToLower(rule.UserEmail) {
+		return false, fmt.Errorf("%w: 不能转发给自己", ErrInvalidAddress)
+	}
+	allowed, err := f.Allowed(ctx, rule.UserEmail)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		return false, ErrDisabled
+	}
+
+	// 目标地址未变且已验证时只更新条件
+	existing, err := f.Storage.GetForwardingRule(ctx, rule.UserEmail)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return false, err
+	}
+	if existing != nil && existing.Verified && existing.Address == rule.Address {
+		rule.Verified = true
+		return false, f.Storage.SaveForwardingRule(ctx, rule)
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return false, err
+	}
+	rule.Verified = false
+	rule.CodeHash = hashCode(code)
+	rule.CodeExpiresAt = time.Now().Add(codeTTL)
+	rule.Attempts = 0
+	if err := f.Storage.SaveForwardingRule(ctx, rule); err != nil {
+		return false, err
+	}
+
+	if err := f.sendCode(ctx, rule, code); err != nil {
+		return false, fmt.Errorf("发送验证码失败: %w", err)
+	}
+	return true, nil
+}
+
+// Verify 校验验证码，成功后转发规则生效
+func (f *Forwarder) Verify(ctx context.Context, userEmail, code string) error {
+	rule, err := f.Storage.GetForwardingRule(ctx, userEmail)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNoPending
+	}
+	if err != nil {
+		return err
+	}
+	if rule.Verified {
+		return nil
+	}
+	if rule.CodeHash == "" {
+		return ErrNoPending
+	}
+	if rule.Attempts >= maxAttempts || time.Now().After(rule.CodeExpiresAt) {
+		return ErrCodeExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashCode(strings.TrimSpace(code))), []byte(rule.CodeHash)) != 1 {
+		rule.Attempts++
+		if err := f.Storage.SaveForwardingRule(ctx, rule); err != nil {
+			return err
+		}
+		return ErrCodeInvalid
+	}
+
+	rule.Verified = true
+	rule.CodeHash = ""
+	rule.Attempts = 0
+	return f.Storage.SaveForwardingRule(ctx, rule)
+}
+
+// Match 邮件是否满足转发规则的条件（条件为空表示不限，不区分大小写）
+func Match(rule *storage.ForwardingRule, from, subject string) bool {
+	if rule.MatchFrom != "" && !strings.Contains(strings.ToLower(from), strings.ToLower(rule.MatchFrom)) {
+		return false
+	}
+	if rule.MatchSubject != "" && !strings.Contains(strings.ToLower(subject), strings.ToLower(rule.MatchSubject)) {
+		return false
+	}
+	return true
+}
+
+// Forward 将邮件转发到规则的目标地址，信封发件人经过 SRS 重写
+func (f *Forwarder) Forward(ctx context.Context, rule *storage.ForwardingRule, sender string, data []byte) error {
+	if !rule.Verified {
+		return fmt.Errorf("转发地址尚未验证")
+	}
+	return f.ForwardTo(ctx, sender, rule.Address, data)
+}
+
+// ForwardTo 将邮件转发到外部地址（如别名的外部目标），信封发件人经过 SRS 重写
+func (f *Forwarder) ForwardTo(ctx context.Context, sender, to string, data []byte) error {
+	from := sender
+	if f.SRS != nil {
+		rewritten, err := f.SRS.Forward(sender)
+		if err != nil {
+			return err
+		}
+		from = rewritten
+	}
+	return f.send(ctx, from, to, data)
+}
+
+// Bounce 将发往 SRS 地址的退信还原并投递给原发件人，非 SRS 地址返回 ErrNotSRS
+func (f *Forwarder) Bounce(ctx context.Context, address string, data []byte) error {
+	if f.SRS == nil {
+		return ErrNotSRS
+	}
+	original, err := f.SRS.Reverse(address)
+	if err != nil {
+		return err
+	}
+	// 退信使用空发件人，避免循环
+	return f.send(ctx, "", original, data)
+}
+
+// sendCode 发送验证码邮件
+func (f *Forwarder) sendCode(ctx context.Context, rule *storage.ForwardingRule, code string) error {
+	from := "postmaster@" + f.Domain
+	now := time.Now()
+	body := fmt.Sprintf("%s 请求将邮件转发到此地址。\r\n\r\n验证码: %s\r\n\r\n验证码 %d 分钟内有效。如果这不是您的操作，请忽略此邮件。\r\n",
+		rule.UserEmail, code, int(codeTTL/time.Minute))
+	data := []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: 转发地址验证码\r\nDate: %s\r\nMessage-ID: <%d.forward@%s>\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nAuto-Submitted: auto-generated\r\n\r\n%s",
+		from, rule.Address, now.Format(time.RFC1123Z), now.UnixNano(), f.Domain, body))
+	return f.send(ctx, from, rule.Address, data)
+}
+
+// send 通过中继（如果配置）或直接投递发送邮件
+func (f *Forwarder) send(ctx context.Context, from, to string, data []byte) error {
+	if f.sendFunc != nil {
+		return f.sendFunc(ctx, from, to, data)
+	}
+	outbound := &smtpclient.Outbound{SMTP: f.SMTP, ClientTLS: f.ClientTLS, Warmup: f.Warmup, Deliveries: f.Deliveries}
+	return outbound.Send(ctx, from, []string{to}, data)
+}
+
+// newCode 生成 6 位数字验证码
+func newCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("生成验证码失败: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashCode 验证码哈希（只存储哈希）
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,171 @@
+package forward
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// sentMail 记录的外发邮件
+type sentMail struct {
+	from, to string
+	data     []byte
+}
+
+func newTestForwarder(t *testing.T) (*Forwarder, *[]sentMail) {
+	t.Helper()
+
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	for _, name := range []string{"example.com", "locked.com"} {
+		if err := driver.CreateDomain(ctx, &storage.Domain{Name: name, Active: true, ForwardingDisabled: name == "locked.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, email := range []string{"alice@example.com", "bob@locked.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sent []sentMail
+	f := &Forwarder{
+		Domain:  "example.com",
+		Storage: driver,
+		SRS:     NewSRS([]byte("secret"), "example.com"),
+		sendFunc: func(ctx context.Context, from, to string, data []byte) error {
+			sent = append(sent, sentMail{from, to, data})
+			return nil
+		},
+	}
+	return f, &sent
+}
+
+var codePattern = regexp.MustCompile(`验证码: (\d{6})`)
+
+func TestRequestAndVerify(t *testing.T) {
+	f, sent := newTestForwarder(t)
+	ctx := context.Background()
+
+	rule := &storage.ForwardingRule{UserEmail: "alice@example.com", Address: "Alice@Gmail.test", KeepCopy: true}
+	codeSent, err := f.Request(ctx, rule)
+	if err != nil || !codeSent {
+		t.Fatalf("Request() = %v, %v", codeSent, err)
+	}
+	if len(*sent) != 1 || (*sent)[0].to != "alice@gmail.test" {
+		t.Fatalf("验证码应该发送到转发地址: %+v", *sent)
+	}
+	code := codePattern.FindStringSubmatch(string((*sent)[0].data))[1]
+
+	// 验证前不转发
+	if err := f.Forward(ctx, rule, "shop@store.test", []byte("x")); err == nil {
+		t.Error("未验证的地址不应该转发")
+	}
+
+	// 错误的验证码
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if err := f.Verify(ctx, "alice@example.com", wrong); !errors.Is(err, ErrCodeInvalid) {
+		t.Errorf("错误的验证码应该返回 ErrCodeInvalid, got %v", err)
+	}
+
+	if err := f.Verify(ctx, "alice@example.com", code); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	rule, err = f.Storage.GetForwardingRule(ctx, "alice@example.com")
+	if err != nil || !rule.Verified {
+		t.Fatalf("转发规则应该已验证: %+v, %v", rule, err)
+	}
+
+	// 只修改条件不需要重新验证
+	rule.MatchSubject = "invoice"
+	if codeSent, err := f.Request(ctx, rule); err != nil || codeSent {
+		t.Errorf("只修改条件不应该重新发送验证码: %v, %v", codeSent, err)
+	}
+
+	// 转发时信封发件人经过 SRS 重写
+	if err := f.Forward(ctx, rule, "shop@store.test", []byte("x")); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	last := (*sent)[len(*sent)-1]
+	if !strings.HasPrefix(last.from, "SRS0=") || last.to != "alice@gmail.test" {
+		t.Errorf("转发邮件不正确: from = %q, to = %q", last.from, last.to)
+	}
+
+	// 退信还原到原发件人，使用空发件人
+	if err := f.Bounce(ctx, last.from, []byte("bounce")); err != nil {
+		t.Fatalf("Bounce() error = %v", err)
+	}
+	last = (*sent)[len(*sent)-1]
+	if last.from != "" || last.to != "shop@store.test" {
+		t.Errorf("退信不正确: from = %q, to = %q", last.from, last.to)
+	}
+}
+
+func TestVerifyTooManyAttempts(t *testing.T) {
+	f, _ := newTestForwarder(t)
+	ctx := context.Background()
+
+	if _, err := f.Request(ctx, &storage.ForwardingRule{UserEmail: "alice@example.com", Address: "alice@gmail.test"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxAttempts; i++ {
+		_ = f.Verify(ctx, "alice@example.com", "bad")
+	}
+	if err := f.Verify(ctx, "alice@example.com", "bad"); !errors.Is(err, ErrCodeExpired) {
+		t.Errorf("尝试次数过多后应该返回 ErrCodeExpired, got %v", err)
+	}
+}
+
+func TestRequestDisabledDomain(t *testing.T) {
+	f, sent := newTestForwarder(t)
+
+	_, err := f.Request(context.Background(), &storage.ForwardingRule{UserEmail: "bob@locked.com", Address: "bob@gmail.test"})
+	if !errors.Is(err, ErrDisabled) {
+		t.Errorf("禁止转发的域名应该返回 ErrDisabled, got %v", err)
+	}
+	if len(*sent) != 0 {
+		t.Error("禁止转发时不应该发送验证码")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	rule := &storage.ForwardingRule{MatchFrom: "@bank.test", MatchSubject: "Statement"}
+	if !Match(rule, "Bank <alerts@bank.test>", "Your statement is ready") {
+		t.Error("满足条件的邮件应该匹配")
+	}
+	if Match(rule, "alerts@bank.test", "Newsletter") {
+		t.Error("主题不满足时不应该匹配")
+	}
+	if !Match(&storage.ForwardingRule{}, "anyone@x.test", "") {
+		t.Error("无条件规则应该匹配所有邮件")
+	}
+}
+
+func TestForwardTo(t *testing.T) {
+	f, sent := newTestForwarder(t)
+
+	if err := f.ForwardTo(context.Background(), "shop@store.test", "friend@other.test", []byte("hello")); err != nil {
+		t.Fatalf("转发失败: %v", err)
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("应该外发一封邮件, got %d", len(*sent))
+	}
+	m := (*sent)[0]
+	if m.to != "friend@other.test" || !strings.HasPrefix(m.from, "SRS0=") || !strings.HasSuffix(m.from, "@example.com") {
+		t.Errorf("信封发件人应该经过 SRS 重写: from = %q, to = %q", m.from, m.to)
+	}
+}
@@ -0,0 +1,114 @@
+package forward
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 -- SRS 规范使用 HMAC-SHA1 生成短哈希，不用于签名或加密
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// srsMaxAgeDays SRS 地址的最长有效天数（退信通常在几天内返回）
+	srsMaxAgeDays = 21
+	// srsTimeSlots 时间戳取模的周期（2 个 base32 字符）
+	srsTimeSlots = 1024
+	// srsHashLen 哈希截取长度
+	srsHashLen = 4
+	// srsAlphabet 时间戳使用的 base32 字母表
+	srsAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+)
+
+var (
+	// ErrNotSRS 地址不是 SRS 地址
+	ErrNotSRS = errors.New("不是 SRS 地址")
+	// ErrSRSInvalid SRS 地址的哈希或时间戳无效
+	ErrSRSInvalid = errors.New("无效的 SRS 地址")
+)
+
+// SRS 发件人重写（Sender Rewriting Scheme）
+// 转发时将信封发件人改写为本域地址，使 SPF 检查通过，退信可以通过 Reverse 还原到原发件人
+type SRS struct {
+	secret []byte // HMAC 密钥（多节点部署时必须一致）
+	domain string // 重写后使用的域名
+	now    func() time.Time
+}
+
+// NewSRS 创建 SRS 重写器
+func NewSRS(secret []byte, domain string) *SRS {
+	return &SRS{secret: secret, domain: strings.ToLower(domain), now: time.Now}
+}
+
+// Forward 改写发件人地址，格式为 SRS0=HHHH=TT=domain=local@本域
+// 空发件人（退信）和本域发件人保持不变
+func (s *SRS) Forward(sender string) (string, error) {
+	if sender == "" {
+		return "", nil
+	}
+	at := strings.LastIndex(sender, "@")
+	if at <= 0 || at == len(sender)-1 {
+		return "", fmt.Errorf("无效的发件人地址: %q", sender)
+	}
+	local, domain := sender[:at], sender[at+1:]
+	if strings.EqualFold(domain, s.domain) {
+		return sender, nil
+	}
+
+	timestamp := s.timestamp()
+	return fmt.Sprintf("SRS0=%s=%s=%s=%s@%s", s.hash(timestamp, domain, local), timestamp, domain, local, s.domain), nil
+}
+
+// Reverse 还原 SRS 地址为原发件人，非 SRS 地址返回 ErrNotSRS
+func (s *SRS) Reverse(address string) (string, error) {
+	at := strings.LastIndex(address, "@")
+	if at <= 0 {
+		return "", ErrNotSRS
+	}
+	local := address[:at]
+	if len(local) < 5 || !strings.EqualFold(local[:5], "SRS0=") {
+		return "", ErrNotSRS
+	}
+
+	parts := strings.SplitN(local[5:], "=", 4)
+	if len(parts) != 4 {
+		return "", ErrSRSInvalid
+	}
+	hash, timestamp, domain, origLocal := parts[0], parts[1], parts[2], parts[3]
+	if !hmac.Equal([]byte(strings.ToLower(hash)), []byte(s.hash(timestamp, domain, origLocal))) {
+		return "", ErrSRSInvalid
+	}
+	if !s.timestampValid(timestamp) {
+		return "", fmt.Errorf("%w: 已过期", ErrSRSInvalid)
+	}
+	return origLocal + "@" + domain, nil
+}
+
+// hash 计算时间戳和原地址的短哈希（使用小写 base32，中间服务器改变大小写时仍可验证）
+func (s *SRS) hash(timestamp, domain, local string) string {
+	mac := hmac.New(sha1.New, s.secret)
+	mac.Write([]byte(strings.ToLower(timestamp + domain + local)))
+	return strings.ToLower(base32.StdEncoding.EncodeToString(mac.Sum(nil))[:srsHashLen])
+}
+
+// timestamp 以天为单位的时间戳，取模后编码为 2 个 base32 字符
+func (s *SRS) timestamp() string {
+	days := (s.now().Unix() / 86400) % srsTimeSlots
+	return string([]byte{srsAlphabet[days>>5], srsAlphabet[days&31]})
+}
+
+// timestampValid 时间戳是否在有效期内
+func (s *SRS) timestampValid(timestamp string) bool {
+	if len(timestamp) != 2 {
+		return false
+	}
+	hi := strings.IndexByte(srsAlphabet, strings.ToUpper(timestamp)[0])
+	lo := strings.IndexByte(srsAlphabet, strings.ToUpper(timestamp)[1])
+	if hi < 0 || lo < 0 {
+		return false
+	}
+	today := (s.now().Unix() / 86400) % srsTimeSlots
+	age := (today - int64(hi<<5|lo) + srsTimeSlots) % srsTimeSlots
+	return age <= srsMaxAgeDays
+}
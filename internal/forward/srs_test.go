@@ -0,0 +1,65 @@
+package forward
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSRSRoundTrip(t *testing.T) {
+	srs := NewSRS([]byte("secret"), "example.com")
+
+	rewritten, err := srs.Forward("Alice@Sender.org")
+	if err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if !strings.HasPrefix(rewritten, "SRS0=") || !strings.HasSuffix(rewritten, "@example.com") {
+		t.Errorf("Forward() = %q", rewritten)
+	}
+
+	original, err := srs.Reverse(rewritten)
+	if err != nil || original != "Alice@Sender.org" {
+		t.Errorf("Reverse() = %q, %v", original, err)
+	}
+
+	// 中间服务器改变大小写后仍然可以还原
+	if _, err := srs.Reverse(strings.ToLower(rewritten)); err != nil {
+		t.Errorf("小写地址应该可以还原: %v", err)
+	}
+
+	// 空发件人和本域发件人不改写
+	if got, _ := srs.Forward(""); got != "" {
+		t.Errorf("空发件人不应该改写, got %q", got)
+	}
+	if got, _ := srs.Forward("bob@example.com"); got != "bob@example.com" {
+		t.Errorf("本域发件人不应该改写, got %q", got)
+	}
+}
+
+func TestSRSReverseInvalid(t *testing.T) {
+	srs := NewSRS([]byte("secret"), "example.com")
+	rewritten, _ := srs.Forward("alice@sender.org")
+
+	if _, err := srs.Reverse("alice@example.com"); !errors.Is(err, ErrNotSRS) {
+		t.Errorf("普通地址应该返回 ErrNotSRS, got %v", err)
+	}
+
+	// 篡改原地址
+	tampered := strings.Replace(rewritten, "=alice@", "=mallory@", 1)
+	if _, err := srs.Reverse(tampered); !errors.Is(err, ErrSRSInvalid) {
+		t.Errorf("篡改的地址应该无效, got %v", err)
+	}
+
+	// 不同密钥
+	other := NewSRS([]byte("other"), "example.com")
+	if _, err := other.Reverse(rewritten); !errors.Is(err, ErrSRSInvalid) {
+		t.Errorf("其他密钥生成的地址应该无效, got %v", err)
+	}
+
+	// 超过有效期
+	srs.now = func() time.Time { return time.Now().Add(30 * 24 * time.Hour) }
+	if _, err := srs.Reverse(rewritten); !errors.Is(err, ErrSRSInvalid) {
+		t.Errorf("过期的地址应该无效, got %v", err)
+	}
+}
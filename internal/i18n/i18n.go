@@ -0,0 +1,122 @@
+// Package i18n 提供按错误码索引的消息目录和 Accept-Language 语言协商
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage 客户端未指定或不支持其语言时使用的语言
+const DefaultLanguage = "zh-CN"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// catalogs 语言标签 -> 错误码 -> 消息（消息可以包含 fmt 占位符）
+var catalogs = loadCatalogs()
+
+// loadCatalogs 加载内嵌的消息目录（locales/<语言标签>.json）
+func loadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("读取消息目录失败: %v", err))
+	}
+	catalogs := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("读取消息目录 %s 失败: %v", entry.Name(), err))
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("解析消息目录 %s 失败: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return catalogs
+}
+
+// Languages 返回支持的语言标签（已排序）
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for language := range catalogs {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// Message 返回错误码在指定语言中的消息，args 用于填充占位符
+// 该语言缺少此错误码时使用默认语言，都没有时返回错误码本身
+func Message(language, code string, args ...any) string {
+	message, ok := catalogs[language][code]
+	if !ok {
+		if message, ok = catalogs[DefaultLanguage][code]; !ok {
+			return code
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Negotiate 按 Accept-Language 请求头（RFC 9110）选择支持的语言
+// 依次按权重尝试完整标签和主语言（如 en-US 匹配 en，zh-TW 匹配 zh-CN），都不匹配时返回默认语言
+func Negotiate(acceptLanguage string) string {
+	type candidate struct {
+		tag    string
+		weight float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			w, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = w
+		}
+		if weight > 0 {
+			candidates = append(candidates, candidate{tag, weight})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].weight > candidates[j].weight })
+
+	for _, c := range candidates {
+		if c.tag == "*" {
+			return DefaultLanguage
+		}
+		if language, ok := match(c.tag); ok {
+			return language
+		}
+	}
+	return DefaultLanguage
+}
+
+// match 查找与语言标签匹配的目录：先比较完整标签，再比较主语言
+func match(tag string) (string, bool) {
+	for language := range catalogs {
+		if strings.EqualFold(language, tag) {
+			return language, true
+		}
+	}
+	primary, _, _ := strings.Cut(tag, "-")
+	for _, language := range Languages() {
+		if p, _, _ := strings.Cut(language, "-"); strings.EqualFold(p, primary) {
+			return language, true
+		}
+	}
+	return "", false
+}
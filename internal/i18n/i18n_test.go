@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	if len(catalogs) < 2 {
+		t.Fatalf("消息目录数量 = %d", len(catalogs))
+	}
+	verbs := regexp.MustCompile(`%[a-z]`)
+	base := catalogs[DefaultLanguage]
+	for language, messages := range catalogs {
+		for code, message := range base {
+			translated, ok := messages[code]
+			if !ok {
+				t.Errorf("%s 缺少 %s", language, code)
+				continue
+			}
+			// 占位符必须一致，否则 Message 会格式化出错
+			if got, want := verbs.FindAllString(translated, -1), verbs.FindAllString(message, -1); len(got) != len(want) {
+				t.Errorf("%s 的 %s 占位符 = %v, want %v", language, code, got, want)
+			}
+		}
+		for code := range messages {
+			if _, ok := base[code]; !ok {
+				t.Errorf("%s 中的 %s 在默认语言中不存在", language, code)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", DefaultLanguage},
+		{"en", "en"},
+		{"en-US,en;q=0.9", "en"},
+		{"zh-TW,zh;q=0.9,en;q=0.8", "zh-CN"},
+		{"fr-FR,fr;q=0.9,en;q=0.5", "en"},
+		{"en;q=0.3,zh-CN;q=0.8", "zh-CN"},
+		{"en;q=0,fr", DefaultLanguage},
+		{"de, *;q=0.1", DefaultLanguage},
+		{"EN-gb", "en"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message("en", "unauthorized"); got != "Unauthorized" {
+		t.Errorf("Message(en) = %q", got)
+	}
+	if got := Message("zh-CN", "unauthorized"); got != "未授权" {
+		t.Errorf("Message(zh-CN) = %q", got)
+	}
+	if got := Message("en", "invalid_days", 90); got != "days must be between 1 and 90" {
+		t.Errorf("格式化后的消息 = %q", got)
+	}
+	if got := Message("fr", "unauthorized"); got != "未授权" {
+		t.Errorf("不支持的语言应该使用默认语言: %q", got)
+	}
+	if got := Message("en", "no_such_code"); got != "no_such_code" {
+		t.Errorf("未知错误码应该返回错误码本身: %q", got)
+	}
+}
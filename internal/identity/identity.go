@@ -0,0 +1,113 @@
+// Package identity 管理用户的外部发件身份
+//
+// 用户以外部地址（如 Gmail 地址）发信时，邮件通过该地址所属服务商的 SMTP
+// 服务器提交，而不是由本服务器直接投递，避免 SPF/DKIM/DMARC 校验失败。
+// SMTP 密码以口令派生的密钥加密后存储。
+package identity
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// defaultPort 未指定端口时使用的提交端口（STARTTLS）
+const defaultPort = 587
+
+var (
+	// ErrInvalidIdentity 发件身份设置无效
+	ErrInvalidIdentity = errors.New("无效的发件身份设置")
+	// ErrLocalAddress 地址属于本服务器的域名（本地地址不需要也不允许配置外部发件身份）
+	ErrLocalAddress = errors.New("本服务器域名的地址不能作为外部发件身份")
+	// ErrNotFound 用户没有该地址的发件身份
+	ErrNotFound = errors.New("发件身份不存在")
+)
+
+// Manager 发件身份管理器
+type Manager struct {
+	Storage    storage.Driver
+	Passphrase string      // 加密 SMTP 密码的口令
+	Hostname   string      // EHLO 主机名
+	ClientTLS  *tls.Config // 连接外部 SMTP 服务器的 TLS 配置模板（可选）
+
+	// AllowPrivateHosts 允许连接内网地址（SMTP 服务器由用户填写，默认只允许公网地址）
+	AllowPrivateHosts bool
+}
+
+// Validate 校验并补全发件身份设置（地址转为小写，未指定端口时使用 587，未指定用户名时使用地址）
+func (m *Manager) Validate(ctx context.Context, identity *storage.Identity) error {
+	addr, err := mail.ParseAddress(strings.TrimSpace(identity.Address))
+	if err != nil {
+		return fmt.Errorf("%w: 无效的发件地址", ErrInvalidIdentity)
+	}
+	identity.Address = strings.ToLower(addr.Address)
+	identity.DisplayName = strings.TrimSpace(identity.DisplayName)
+	identity.SMTPHost = strings.TrimSpace(identity.SMTPHost)
+	identity.SMTPUsername = strings.TrimSpace(identity.SMTPUsername)
+
+	domain := identity.Address[strings.LastIndex(identity.Address, "@")+1:]
+	if _, err := m.Storage.GetDomain(ctx, domain); err == nil {
+		return ErrLocalAddress
+	}
+	if identity.SMTPHost == "" || strings.ContainsAny(identity.SMTPHost, "/\\ ") {
+		return fmt.Errorf("%w: 无效的 SMTP 服务器地址", ErrInvalidIdentity)
+	}
+	if identity.SMTPPort == 0 {
+		identity.SMTPPort = defaultPort
+	}
+	if identity.SMTPPort < 1 || identity.SMTPPort > 65535 {
+		return fmt.Errorf("%w: 端口超出范围（1-65535）", ErrInvalidIdentity)
+	}
+	if identity.SMTPUsername == "" {
+		identity.SMTPUsername = identity.Address
+	}
+	return nil
+}
+
+// EncryptPassword 加密 SMTP 密码
+func (m *Manager) EncryptPassword(password string) ([]byte, error) {
+	encrypted, err := crypto.EncryptWithPassphrase(m.Passphrase, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("加密密码失败: %w", err)
+	}
+	return encrypted, nil
+}
+
+// Find 查找用户的发件身份（地址不区分大小写）
+func (m *Manager) Find(ctx context.Context, userEmail, address string) (*storage.Identity, error) {
+	identities, err := m.Storage.ListIdentities(ctx, userEmail)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		if strings.EqualFold(identity.Address, address) {
+			return identity, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Send 通过发件身份的 SMTP 服务器发送邮件（信封发件人为身份地址）
+func (m *Manager) Send(ctx context.Context, identity *storage.Identity, to []string, data []byte) error {
+	password, err := crypto.DecryptWithPassphrase(m.Passphrase, identity.SMTPPassword)
+	if err != nil {
+		return fmt.Errorf("解密 SMTP 密码失败（加密口令是否已更改？）: %w", err)
+	}
+
+	client := smtpclient.NewClientWithTLS(m.Hostname, m.ClientTLS)
+	if !m.AllowPrivateHosts {
+		client = client.PublicOnly()
+	}
+	if err := client.SendMailToRelay(ctx, identity.SMTPHost, identity.SMTPPort, identity.SMTPUsername,
+		string(password), true, identity.Address, to, data); err != nil {
+		return fmt.Errorf("通过 %s 发送失败: %w", identity.SMTPHost, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// relayBackend 记录收到的邮件的测试 SMTP 服务器
+type relayBackend struct {
+	username, password string
+	from               string
+	to                 []string
+	data               []byte
+}
+
+func (b *relayBackend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	return &relaySession{backend: b}, nil
+}
+
+type relaySession struct {
+	backend *relayBackend
+	authed  bool
+}
+
+func (s *relaySession) AuthMechanisms() []string {
+	return []string{sasl.Plain}
+}
+
+func (s *relaySession) Auth(mech string) (sasl.Server, error) {
+	return sasl.NewPlainServer(func(identity, username, password string) error {
+		if username != s.backend.username || password != s.backend.password {
+			return errors.New("认证失败")
+		}
+		s.authed = true
+		return nil
+	}), nil
+}
+
+func (s *relaySession) Mail(from string, opts *smtp.MailOptions) error {
+	if !s.authed {
+		return smtp.ErrAuthRequired
+	}
+	s.backend.from = from
+	return nil
+}
+
+func (s *relaySession) Rcpt(to string, opts *smtp.RcptOptions) error {
+	s.backend.to = append(s.backend.to, to)
+	return nil
+}
+
+func (s *relaySession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	s.backend.data = data
+	return err
+}
+
+func (s *relaySession) Reset()        {}
+func (s *relaySession) Logout() error { return nil }
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatalf("创建域名失败: %v", err)
+	}
+	return &Manager{Storage: driver, Passphrase: "test-passphrase", Hostname: "mail.example.com"}
+}
+
+func TestValidate(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	identity := &storage.Identity{Address: " Alice <Alice@Gmail.com>", SMTPHost: "smtp.gmail.com"}
+	if err := m.Validate(ctx, identity); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if identity.Address != "alice@gmail.com" || identity.SMTPPort != 587 || identity.SMTPUsername != "alice@gmail.com" {
+		t.Errorf("补全后的发件身份不正确: %+v", identity)
+	}
+
+	if err := m.Validate(ctx, &storage.Identity{Address: "bob@example.com", SMTPHost: "smtp.gmail.com"}); !errors.Is(err, ErrLocalAddress) {
+		t.Errorf("本地域名的地址应该返回 ErrLocalAddress, got %v", err)
+	}
+	if err := m.Validate(ctx, &storage.Identity{Address: "alice@gmail.com"}); !errors.Is(err, ErrInvalidIdentity) {
+		t.Errorf("缺少 SMTP 服务器应该返回 ErrInvalidIdentity, got %v", err)
+	}
+	if err := m.Validate(ctx, &storage.Identity{Address: "not-an-address", SMTPHost: "smtp.gmail.com"}); !errors.Is(err, ErrInvalidIdentity) {
+		t.Errorf("无效地址应该返回 ErrInvalidIdentity, got %v", err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	backend := &relayBackend{username: "alice@gmail.com", password: "app-password"}
+	server := smtp.NewServer(backend)
+	server.AllowInsecureAuth = true
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	m := newTestManager(t)
+	ctx := context.Background()
+	if err := m.Storage.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "test_hash", Active: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	encrypted, err := m.EncryptPassword("app-password")
+	if err != nil {
+		t.Fatalf("加密密码失败: %v", err)
+	}
+	identity := &storage.Identity{
+		UserEmail: "alice@example.com", Address: "alice@gmail.com", SMTPHost: "127.0.0.1", SMTPPort: port,
+		SMTPUsername: "alice@gmail.com", SMTPPassword: encrypted,
+	}
+	if err := m.Storage.CreateIdentity(ctx, identity); err != nil {
+		t.Fatalf("创建发件身份失败: %v", err)
+	}
+
+	found, err := m.Find(ctx, "alice@example.com", "Alice@Gmail.com")
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if _, err := m.Find(ctx, "alice@example.com", "other@gmail.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未配置的地址应该返回 ErrNotFound, got %v", err)
+	}
+
+	data := []byte("From: alice@gmail.com\r\nTo: bob@remote.test\r\nSubject: hi\r\n\r\nhello\r\n")
+	if err := m.Send(ctx, found, []string{"bob@remote.test"}, data); err == nil {
+		t.Error("默认应该拒绝连接内网地址")
+	}
+
+	m.AllowPrivateHosts = true
+	if err := m.Send(ctx, found, []string{"bob@remote.test"}, data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if backend.from != "alice@gmail.com" || len(backend.to) != 1 || backend.to[0] != "bob@remote.test" {
+		t.Errorf("信封不正确: from=%s to=%v", backend.from, backend.to)
+	}
+	if len(backend.data) == 0 {
+		t.Error("应该收到邮件内容")
+	}
+}
@@ -0,0 +1,490 @@
+package imapd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/server"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 注解属性（RFC 5257），value 和 size 同时表示 .priv 和 .shared
+const (
+	attribValuePriv   = "value.priv"
+	attribValueShared = "value.shared"
+	attribSizePriv    = "size.priv"
+	attribSizeShared  = "size.shared"
+)
+
+// AnnotateExtension IMAP ANNOTATE 扩展（RFC 5257），为邮件提供颜色标签、备注等注解，
+// 通过 FETCH/STORE 的 ANNOTATION 项读写，保存在数据库中，跨客户端保留
+type AnnotateExtension struct {
+	storage storage.Driver
+}
+
+// NewAnnotateExtension 创建 ANNOTATE 扩展
+func NewAnnotateExtension(driver storage.Driver) *AnnotateExtension {
+	return &AnnotateExtension{storage: driver}
+}
+
+// Capabilities 返回扩展提供的能力
+func (ext *AnnotateExtension) Capabilities(c server.Conn) []string {
+	return []string{"ANNOTATE-EXPERIMENT-1"}
+}
+
+// Command 返回命令处理器（覆盖内置的 SELECT/EXAMINE/FETCH/STORE，非注解部分仍由内置实现处理）
+func (ext *AnnotateExtension) Command(name string) server.HandlerFactory {
+	switch name {
+	case "SELECT", "EXAMINE":
+		return func() server.Handler {
+			cmd := &annotateSelect{}
+			cmd.ReadOnly = name == "EXAMINE"
+			return cmd
+		}
+	case "FETCH":
+		return func() server.Handler {
+			return &annotateFetch{ext: ext}
+		}
+	case "STORE":
+		return func() server.Handler {
+			return &annotateStore{ext: ext}
+		}
+	}
+	return nil
+}
+
+// annotateSelect 选择邮箱成功后返回 ANNOTATIONS 响应码，告知客户端注解值的大小上限
+type annotateSelect struct {
+	server.Select
+}
+
+// Handle 处理命令
+func (cmd *annotateSelect) Handle(conn server.Conn) error {
+	err := cmd.Select.Handle(conn)
+	var statusErr *imap.ErrStatusResp
+	if errors.As(err, &statusErr) && statusErr.Resp != nil && statusErr.Resp.Type == imap.StatusRespOk {
+		if writeErr := conn.WriteResp(&imap.StatusResp{
+			Type:      imap.StatusRespOk,
+			Code:      "ANNOTATIONS",
+			Arguments: []interface{}{uint32(storage.MaxAnnotationSize)},
+			Info:      "Annotations supported",
+		}); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
+
+// annotateFetch FETCH，额外支持 ANNOTATION (entries attribs) 项
+type annotateFetch struct {
+	server.Fetch
+	ext      *AnnotateExtension
+	annotate bool
+	entries  []string // 请求的条目，可包含通配符 * 和 %
+	attribs  []string
+}
+
+// Parse 解析命令参数，取出 ANNOTATION 项，其余项交给内置 FETCH 解析
+func (cmd *annotateFetch) Parse(fields []interface{}) error {
+	if len(fields) < 2 {
+		return errors.New("FETCH 参数数量不正确")
+	}
+	items, ok := fields[1].([]interface{})
+	if !ok {
+		return cmd.Fetch.Parse(fields)
+	}
+
+	rest := make([]interface{}, 0, len(items))
+	for i := 0; i < len(items); i++ {
+		if name, ok := items[i].(string); ok && strings.EqualFold(name, "ANNOTATION") {
+			if i+1 >= len(items) {
+				return errors.New("ANNOTATION 缺少参数")
+			}
+			if err := cmd.parseAnnotation(items[i+1]); err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+		rest = append(rest, items[i])
+	}
+	return cmd.Fetch.Parse([]interface{}{fields[0], rest})
+}
+
+// parseAnnotation 解析 (entries attribs)，entries 和 attribs 可以是单个字符串或列表
+func (cmd *annotateFetch) parseAnnotation(f interface{}) error {
+	args, ok := f.([]interface{})
+	if !ok || len(args) != 2 {
+		return errors.New("ANNOTATION 参数格式不正确")
+	}
+
+	entries, err := parseStringOrList(args[0])
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		entry = strings.ToLower(entry)
+		if !strings.HasPrefix(entry, "/") && !strings.HasPrefix(entry, "*") && !strings.HasPrefix(entry, "%") {
+			return fmt.Errorf("注解条目名称无效: %s", entry)
+		}
+		cmd.entries = append(cmd.entries, entry)
+	}
+
+	attribs, err := parseStringOrList(args[1])
+	if err != nil {
+		return err
+	}
+	for _, attrib := range attribs {
+		switch strings.ToLower(attrib) {
+		case "value":
+			cmd.attribs = append(cmd.attribs, attribValuePriv, attribValueShared)
+		case "size":
+			cmd.attribs = append(cmd.attribs, attribSizePriv, attribSizeShared)
+		case attribValuePriv, attribValueShared, attribSizePriv, attribSizeShared:
+			cmd.attribs = append(cmd.attribs, strings.ToLower(attrib))
+		default:
+			return fmt.Errorf("不支持的注解属性: %s", attrib)
+		}
+	}
+
+	if len(cmd.entries) == 0 || len(cmd.attribs) == 0 {
+		return errors.New("ANNOTATION 参数不能为空")
+	}
+	cmd.annotate = true
+	return nil
+}
+
+// Handle 处理命令
+func (cmd *annotateFetch) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID FETCH
+func (cmd *annotateFetch) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *annotateFetch) handle(uid bool, conn server.Conn) error {
+	if !cmd.annotate || len(cmd.Items) > 0 {
+		var err error
+		if uid {
+			err = cmd.Fetch.UidHandle(conn)
+		} else {
+			err = cmd.Fetch.Handle(conn)
+		}
+		if err != nil || !cmd.annotate {
+			return err
+		}
+	}
+
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	for _, msg := range mbox.selectMessages(uid, cmd.SeqSet) {
+		annotations, err := cmd.ext.storage.ListAnnotations(ctx, msg.mail.ID)
+		if err != nil {
+			return err
+		}
+
+		items := []interface{}{}
+		if uid {
+			items = append(items, imap.RawString("UID"), msg.uid)
+		}
+		items = append(items, imap.RawString("ANNOTATION"), annotationResponse(annotations, cmd.entries, cmd.attribs))
+		if err := conn.WriteResp(&imap.DataResp{
+			Fields: []interface{}{msg.seqNum, imap.RawString("FETCH"), items},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annotateStore STORE，额外支持 STORE seq ANNOTATION (entry (attrib value ...) ...)
+type annotateStore struct {
+	server.Store
+	ext     *AnnotateExtension
+	changes []annotationChange
+}
+
+// annotationChange 单个注解的修改
+type annotationChange struct {
+	entry  string
+	shared bool
+	value  *string // nil 表示删除
+}
+
+// Parse 解析命令参数
+func (cmd *annotateStore) Parse(fields []interface{}) error {
+	if err := cmd.Store.Parse(fields); err != nil {
+		return err
+	}
+	if cmd.Item != "ANNOTATION" {
+		return nil
+	}
+
+	list, ok := cmd.Value.([]interface{})
+	if !ok || len(list) == 0 || len(list)%2 != 0 {
+		return errors.New("ANNOTATION 参数格式不正确")
+	}
+	for i := 0; i < len(list); i += 2 {
+		entry, err := imap.ParseString(list[i])
+		if err != nil {
+			return err
+		}
+		entry = strings.ToLower(entry)
+		if !storage.ValidAnnotationEntry(entry) {
+			return fmt.Errorf("注解条目名称无效: %s", entry)
+		}
+
+		attribs, ok := list[i+1].([]interface{})
+		if !ok || len(attribs) == 0 || len(attribs)%2 != 0 {
+			return errors.New("注解属性列表格式不正确")
+		}
+		for j := 0; j < len(attribs); j += 2 {
+			attrib, err := imap.ParseString(attribs[j])
+			if err != nil {
+				return err
+			}
+			var shared bool
+			switch strings.ToLower(attrib) {
+			case attribValuePriv:
+			case attribValueShared:
+				shared = true
+			default:
+				return fmt.Errorf("只能设置 value.priv 或 value.shared: %s", attrib)
+			}
+
+			change := annotationChange{entry: entry, shared: shared}
+			if attribs[j+1] != nil {
+				value, err := imap.ParseString(attribs[j+1])
+				if err != nil {
+					return err
+				}
+				change.value = &value
+			}
+			cmd.changes = append(cmd.changes, change)
+		}
+	}
+	return nil
+}
+
+// Handle 处理命令
+func (cmd *annotateStore) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID STORE
+func (cmd *annotateStore) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *annotateStore) handle(uid bool, conn server.Conn) error {
+	if cmd.Item != "ANNOTATION" {
+		if uid {
+			return cmd.Store.UidHandle(conn)
+		}
+		return cmd.Store.Handle(conn)
+	}
+
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+	if conn.Context().MailboxReadOnly {
+		return server.ErrMailboxReadOnly
+	}
+	for _, change := range cmd.changes {
+		if change.value != nil && len(*change.value) > storage.MaxAnnotationSize {
+			return server.ErrStatusResp(&imap.StatusResp{
+				Type:      imap.StatusRespNo,
+				Code:      "ANNOTATE",
+				Arguments: []interface{}{imap.RawString("TOOBIG")},
+				Info:      "注解值过大",
+			})
+		}
+	}
+
+	ctx := context.Background()
+	for _, msg := range mbox.selectMessages(uid, cmd.SeqSet) {
+		for _, change := range cmd.changes {
+			if change.value == nil {
+				if err := cmd.ext.storage.DeleteAnnotation(ctx, msg.mail.ID, change.entry, change.shared); err != nil {
+					return err
+				}
+				continue
+			}
+			annotation := &storage.Annotation{
+				MailID: msg.mail.ID,
+				Entry:  change.entry,
+				Shared: change.shared,
+				Value:  *change.value,
+			}
+			if err := cmd.ext.storage.SetAnnotation(ctx, annotation); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// selectedMessage 按序列号或 UID 选中的邮件
+type selectedMessage struct {
+	seqNum uint32
+	uid    uint32
+	mail   *storage.Mail
+}
+
+// selectMessages 按序列号或 UID 集合选出邮件
+func (m *Mailbox) selectMessages(uid bool, seqSet *imap.SeqSet) []selectedMessage {
+	m.sync()
+	var selected []selectedMessage
+	for i, mail := range m.mails {
+		// #nosec G115 -- 循环索引 i 在合理范围内，不会溢出 uint32
+		seqNum := uint32(i + 1)
+		mailUID := mail.UID
+		if mailUID == 0 {
+			mailUID = seqNum
+		}
+
+		checkNum := seqNum
+		if uid {
+			checkNum = mailUID
+		}
+		if seqSet != nil && !seqSet.Contains(checkNum) {
+			continue
+		}
+		selected = append(selected, selectedMessage{seqNum: seqNum, uid: mailUID, mail: mail})
+	}
+	return selected
+}
+
+// selectedMailbox 返回当前选中的邮箱
+func selectedMailbox(conn server.Conn) (*Mailbox, error) {
+	ctx := conn.Context()
+	if ctx.Mailbox == nil {
+		return nil, server.ErrNoMailboxSelected
+	}
+	mbox, ok := ctx.Mailbox.(*Mailbox)
+	if !ok {
+		return nil, errors.New("邮箱不支持注解")
+	}
+	return mbox, nil
+}
+
+// annotationResponse 生成 FETCH 响应中 ANNOTATION 的内容：(entry (attrib value ...) ...)
+func annotationResponse(annotations []*storage.Annotation, patterns, attribs []string) []interface{} {
+	type values struct {
+		priv, shared *string
+	}
+	var names []string
+	byEntry := make(map[string]*values)
+	for _, annotation := range annotations {
+		v, ok := byEntry[annotation.Entry]
+		if !ok {
+			v = &values{}
+			byEntry[annotation.Entry] = v
+			names = append(names, annotation.Entry)
+		}
+		value := annotation.Value
+		if annotation.Shared {
+			v.shared = &value
+		} else {
+			v.priv = &value
+		}
+	}
+
+	// 不含通配符的条目即使不存在也返回 NIL
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, "*%") {
+			if _, ok := byEntry[pattern]; !ok {
+				byEntry[pattern] = &values{}
+				names = append(names, pattern)
+			}
+		}
+	}
+
+	result := []interface{}{}
+	for _, name := range names {
+		matched := false
+		for _, pattern := range patterns {
+			if matchEntry(pattern, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		v := byEntry[name]
+		fields := make([]interface{}, 0, len(attribs)*2)
+		for _, attrib := range attribs {
+			value := v.priv
+			if attrib == attribValueShared || attrib == attribSizeShared {
+				value = v.shared
+			}
+			fields = append(fields, attrib)
+			switch {
+			case attrib == attribSizePriv || attrib == attribSizeShared:
+				size := 0
+				if value != nil {
+					size = len(*value)
+				}
+				fields = append(fields, strconv.Itoa(size))
+			case value == nil:
+				fields = append(fields, nil)
+			default:
+				fields = append(fields, formatMetadataValue(*value))
+			}
+		}
+		result = append(result, name, fields)
+	}
+	return result
+}
+
+// matchEntry 匹配注解条目名称，* 匹配任意字符，% 匹配除 / 以外的任意字符
+func matchEntry(pattern, name string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*', '%':
+			wildcard := pattern[0]
+			pattern = pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchEntry(pattern, name[i:]) {
+					return true
+				}
+				if i < len(name) && wildcard == '%' && name[i] == '/' {
+					return false
+				}
+			}
+			return false
+		default:
+			if len(name) == 0 || name[0] != pattern[0] {
+				return false
+			}
+			pattern = pattern[1:]
+			name = name[1:]
+		}
+	}
+	return len(name) == 0
+}
+
+// parseStringOrList 解析单个字符串或字符串列表
+func parseStringOrList(f interface{}) ([]string, error) {
+	if list, ok := f.([]interface{}); ok {
+		return imap.ParseStringList(list)
+	}
+	s, err := imap.ParseString(f)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
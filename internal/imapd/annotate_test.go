@@ -0,0 +1,132 @@
+package imapd
+
+import (
+	"testing"
+
+	"github.com/emersion/go-imap"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestAnnotateFetchParse(t *testing.T) {
+	cmd := &annotateFetch{}
+	fields := []interface{}{
+		"1:*",
+		[]interface{}{"FLAGS", "ANNOTATION", []interface{}{[]interface{}{"/comment", "/vendor/*"}, "value"}},
+	}
+	if err := cmd.Parse(fields); err != nil {
+		t.Fatalf("解析 FETCH 失败: %v", err)
+	}
+	if !cmd.annotate || len(cmd.Items) != 1 || cmd.Items[0] != imap.FetchFlags {
+		t.Errorf("FETCH 项不正确: %+v", cmd.Items)
+	}
+	if len(cmd.entries) != 2 || len(cmd.attribs) != 2 || cmd.attribs[1] != attribValueShared {
+		t.Errorf("ANNOTATION 参数不正确: %v %v", cmd.entries, cmd.attribs)
+	}
+
+	// 不含 ANNOTATION 时交给内置 FETCH
+	cmd = &annotateFetch{}
+	if err := cmd.Parse([]interface{}{"1", "FAST"}); err != nil {
+		t.Fatalf("解析 FETCH 失败: %v", err)
+	}
+	if cmd.annotate || len(cmd.Items) != 3 {
+		t.Errorf("FETCH 宏展开不正确: %+v", cmd.Items)
+	}
+
+	if err := (&annotateFetch{}).Parse([]interface{}{"1", []interface{}{"ANNOTATION", []interface{}{"/comment", "value.bad"}}}); err == nil {
+		t.Error("不支持的属性应该解析失败")
+	}
+}
+
+func TestAnnotateStoreParse(t *testing.T) {
+	cmd := &annotateStore{}
+	fields := []interface{}{
+		"1",
+		"ANNOTATION",
+		[]interface{}{"/Comment", []interface{}{"value.priv", "note", "value.shared", nil}},
+	}
+	if err := cmd.Parse(fields); err != nil {
+		t.Fatalf("解析 STORE 失败: %v", err)
+	}
+	if len(cmd.changes) != 2 {
+		t.Fatalf("应该有 2 个修改, got %d", len(cmd.changes))
+	}
+	if cmd.changes[0].entry != "/comment" || cmd.changes[0].shared || *cmd.changes[0].value != "note" {
+		t.Errorf("第一个修改不正确: %+v", cmd.changes[0])
+	}
+	if !cmd.changes[1].shared || cmd.changes[1].value != nil {
+		t.Errorf("第二个修改不正确: %+v", cmd.changes[1])
+	}
+
+	// 普通的 FLAGS 修改保持不变
+	cmd = &annotateStore{}
+	if err := cmd.Parse([]interface{}{"1", "+FLAGS", []interface{}{`\Seen`}}); err != nil {
+		t.Fatalf("解析 STORE 失败: %v", err)
+	}
+	if cmd.Item != "+FLAGS" || len(cmd.changes) != 0 {
+		t.Errorf("STORE 参数不正确: %+v", cmd)
+	}
+
+	if err := (&annotateStore{}).Parse([]interface{}{"1", "ANNOTATION", []interface{}{"/comment", []interface{}{"size.priv", "1"}}}); err == nil {
+		t.Error("不能设置 size 属性")
+	}
+}
+
+func TestMatchEntry(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"/comment", "/comment", true},
+		{"/comment", "/comments", false},
+		{"*", "/vendor/gomailzero/color", true},
+		{"/vendor/*", "/vendor/gomailzero/color", true},
+		{"/vendor/%", "/vendor/gomailzero/color", false},
+		{"/vendor/%", "/vendor/gomailzero", true},
+		{"/%/color", "/x/color", true},
+	}
+	for _, tt := range tests {
+		if got := matchEntry(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchEntry(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotationResponse(t *testing.T) {
+	annotations := []*storage.Annotation{
+		{Entry: "/comment", Value: "note"},
+		{Entry: "/vendor/gomailzero/color", Shared: true, Value: "red"},
+	}
+
+	result := annotationResponse(annotations, []string{"/comment", "/altsubject"}, []string{attribValuePriv, attribSizeShared})
+	if len(result) != 4 || result[0] != "/comment" || result[2] != "/altsubject" {
+		t.Fatalf("ANNOTATION 响应不正确: %v", result)
+	}
+	attrs := result[1].([]interface{})
+	if attrs[1] != "note" || attrs[3] != "0" {
+		t.Errorf("/comment 属性不正确: %v", attrs)
+	}
+	if attrs := result[3].([]interface{}); attrs[1] != nil {
+		t.Errorf("不存在的条目应该返回 NIL: %v", attrs)
+	}
+
+	result = annotationResponse(annotations, []string{"/vendor/*"}, []string{attribValueShared})
+	if len(result) != 2 || result[1].([]interface{})[1] != "red" {
+		t.Errorf("通配符 ANNOTATION 响应不正确: %v", result)
+	}
+}
+
+func TestSelectMessages(t *testing.T) {
+	mbox := &Mailbox{mails: []*storage.Mail{{ID: "a", UID: 10}, {ID: "b", UID: 12}, {ID: "c"}}}
+
+	seqSet, _ := imap.ParseSeqSet("2:3")
+	selected := mbox.selectMessages(false, seqSet)
+	if len(selected) != 2 || selected[0].mail.ID != "b" || selected[0].uid != 12 || selected[1].uid != 3 {
+		t.Errorf("按序列号选择不正确: %+v", selected)
+	}
+
+	uidSet, _ := imap.ParseSeqSet("10:11")
+	selected = mbox.selectMessages(true, uidSet)
+	if len(selected) != 1 || selected[0].mail.ID != "a" || selected[0].seqNum != 1 {
+		t.Errorf("按 UID 选择不正确: %+v", selected)
+	}
+}
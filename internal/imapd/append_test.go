@@ -0,0 +1,128 @@
+package imapd
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// appendMessage 测试用的邮件：me@example.com 发给本地用户 bob@example.com
+const appendMessage = "From: me@example.com\r\nTo: bob@example.com\r\nSubject: hello\r\n\r\nbody\r\n"
+
+func appendMail(t *testing.T, driver storage.Driver, folder string) *storage.Mail {
+	t.Helper()
+	mails, err := driver.ListMails(context.Background(), "me@example.com", folder, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mail := range mails {
+		if mail.Subject == "hello" {
+			return mail
+		}
+	}
+	t.Fatalf("%s 中没有 APPEND 的邮件", folder)
+	return nil
+}
+
+func TestAppendKeepsMailboxFlagsAndDate(t *testing.T) {
+	c, driver, maildir := newTestIMAP(t)
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &storage.User{Email: "bob@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// APPEND 到 INBOX 的邮件留在 INBOX（例如在邮箱之间复制邮件）
+	if err := c.Append("INBOX", nil, time.Time{}, bytes.NewBufferString(appendMessage)); err != nil {
+		t.Fatalf("APPEND INBOX 失败: %v", err)
+	}
+	appendMail(t, driver, "INBOX")
+	if n := maildirFiles(t, maildir, "Sent"); n != 0 {
+		t.Errorf("APPEND INBOX 后 Sent 中有 %d 封邮件", n)
+	}
+
+	// 草稿保留客户端指定的标志和内部日期
+	date := time.Date(2024, 3, 1, 9, 30, 0, 0, time.FixedZone("", 8*3600))
+	if err := c.Append("Drafts", []string{imap.SeenFlag, imap.DraftFlag}, date, bytes.NewBufferString(appendMessage)); err != nil {
+		t.Fatalf("APPEND Drafts 失败: %v", err)
+	}
+	draft := appendMail(t, driver, "Drafts")
+	if !draft.ReceivedAt.Equal(date) {
+		t.Errorf("ReceivedAt = %v, want %v", draft.ReceivedAt, date)
+	}
+	if !slices.Contains(draft.Flags, imap.SeenFlag) || !slices.Contains(draft.Flags, imap.DraftFlag) {
+		t.Errorf("Flags = %v, want \\Seen \\Draft", draft.Flags)
+	}
+	if _, err := maildir.ReadMail("me@example.com", "Drafts", draft.ID); err != nil {
+		t.Errorf("读取已读草稿失败: %v", err)
+	}
+
+	// 没有配置提交文件夹时，APPEND 到 Sent 不投递
+	if err := c.Append("Sent", []string{imap.SeenFlag}, time.Time{}, bytes.NewBufferString(appendMessage)); err != nil {
+		t.Fatalf("APPEND Sent 失败: %v", err)
+	}
+	if mails, _ := driver.ListMails(ctx, "bob@example.com", "INBOX", 100, 0); len(mails) != 0 {
+		t.Errorf("APPEND Sent 后收件人收到 %d 封邮件", len(mails))
+	}
+}
+
+func TestAppendSubmissionFolderDelivers(t *testing.T) {
+	c, driver, _ := newTestIMAP(t, func(b *Backend) { b.submission = "Outbox" })
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &storage.User{Email: "bob@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("Outbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 提交文件夹中的草稿不投递
+	if err := c.Append("Outbox", []string{imap.DraftFlag}, time.Time{}, bytes.NewBufferString(appendMessage)); err != nil {
+		t.Fatalf("APPEND 草稿失败: %v", err)
+	}
+	if mails, _ := driver.ListMails(ctx, "bob@example.com", "INBOX", 100, 0); len(mails) != 0 {
+		t.Fatalf("草稿不应该投递，收件人收到 %d 封邮件", len(mails))
+	}
+
+	if err := c.Append("Outbox", nil, time.Time{}, bytes.NewBufferString(appendMessage)); err != nil {
+		t.Fatalf("APPEND 失败: %v", err)
+	}
+	mails, err := driver.ListMails(ctx, "bob@example.com", "INBOX", 100, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("收件人应该收到 1 封邮件: %d, %v", len(mails), err)
+	}
+}
+
+func TestAppendSubmissionFolderAlias(t *testing.T) {
+	c, driver, _ := newTestIMAP(t, func(b *Backend) { b.submission = "Outbox" })
+	ctx := context.Background()
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := driver.CreateAlias(ctx, &storage.Alias{From: "team@example.com", Destinations: []string{"bob@example.com", "carol@example.com"}, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Create("Outbox"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 发往多目标别名的邮件投递到全部本地目标
+	message := "From: me@example.com\r\nTo: team@example.com\r\nSubject: hello team\r\n\r\nbody\r\n"
+	if err := c.Append("Outbox", nil, time.Time{}, bytes.NewBufferString(message)); err != nil {
+		t.Fatalf("APPEND 失败: %v", err)
+	}
+	for _, email := range []string{"bob@example.com", "carol@example.com"} {
+		if mails, err := driver.ListMails(ctx, email, "INBOX", 100, 0); err != nil || len(mails) != 1 {
+			t.Errorf("%s 应该收到别名邮件: %d, %v", email, len(mails), err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package imapd
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// authErrors 按连接统计认证失败次数，达到上限（imap.max_auth_errors）后断开连接
+//
+// go-imap 的 Login 只提供连接的地址，因此按远端地址（IP:端口）找到连接。
+type authErrors struct {
+	max   int
+	mu    sync.Mutex
+	conns map[string]*authConn
+}
+
+// newAuthErrors 创建统计器，max 为 0 时不限制
+func newAuthErrors(max int) *authErrors {
+	return &authErrors{max: max, conns: make(map[string]*authConn)}
+}
+
+// Listener 包装监听器，跟踪连接以便统计认证失败
+func (a *authErrors) Listener(l net.Listener) net.Listener {
+	if a.max <= 0 {
+		return l
+	}
+	return &authListener{Listener: l, errors: a}
+}
+
+// fail 记录一次认证失败，返回连接是否已达到上限（达到后连接在回复之后断开）
+func (a *authErrors) fail(remote net.Addr) bool {
+	if a.max <= 0 || remote == nil {
+		return false
+	}
+	a.mu.Lock()
+	c, ok := a.conns[remote.String()]
+	a.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if c.errors.Add(1) >= int32(a.max) {
+		c.exceeded.Store(true)
+		return true
+	}
+	return false
+}
+
+// exceeded 连接的认证失败次数是否已达到上限
+func (a *authErrors) exceeded(remote net.Addr) bool {
+	if a.max <= 0 || remote == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c, ok := a.conns[remote.String()]
+	return ok && c.exceeded.Load()
+}
+
+type authListener struct {
+	net.Listener
+	errors *authErrors
+}
+
+func (l *authListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	c := &authConn{Conn: conn, tracker: l.errors, key: conn.RemoteAddr().String()}
+	l.errors.mu.Lock()
+	l.errors.conns[c.key] = c
+	l.errors.mu.Unlock()
+	return c, nil
+}
+
+// authConn 统计认证失败的连接，达到上限后读取返回 EOF，服务器在写出当前回复后关闭连接
+type authConn struct {
+	net.Conn
+	tracker  *authErrors
+	key      string
+	errors   atomic.Int32
+	exceeded atomic.Bool
+	once     sync.Once
+}
+
+func (c *authConn) Read(b []byte) (int, error) {
+	if c.exceeded.Load() {
+		return 0, io.EOF
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *authConn) Close() error {
+	c.once.Do(func() {
+		c.tracker.mu.Lock()
+		if c.tracker.conns[c.key] == c {
+			delete(c.tracker.conns, c.key)
+		}
+		c.tracker.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,87 @@
+package imapd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/limits"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestAuthLimits(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	hash, err := crypto.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "me@example.com", PasswordHash: hash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter, err := limits.New(limits.Config{MaxAuthFailures: 3, Window: time.Minute, BanDuration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bkd := NewBackend(driver, nil, NewDefaultAuthenticator(driver))
+	bkd.limiter = limiter
+	bkd.authErrors = newAuthErrors(2)
+	s := server.New(bkd)
+	s.AllowInsecureAuth = true
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(bkd.authErrors.Listener(limiter.Listener("imap", listener)))
+	t.Cleanup(func() { s.Close() })
+
+	dial := func() *client.Client {
+		t.Helper()
+		c, err := client.Dial(listener.Addr().String())
+		if err != nil {
+			t.Fatalf("连接 IMAP 服务器失败: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	// 单个连接达到 max_auth_errors 后断开
+	c := dial()
+	for i := 0; i < 2; i++ {
+		if err := c.Login("me@example.com", "wrong"); err == nil {
+			t.Fatal("错误的密码应该登录失败")
+		}
+	}
+	select {
+	case <-c.LoggedOut():
+	case <-time.After(time.Second):
+		t.Fatal("认证失败次数达到上限后应该断开连接")
+	}
+
+	// 第三次失败（换一个连接）后 IP 被封禁，正确的密码也被拒绝
+	c = dial()
+	if err := c.Login("me@example.com", "wrong"); err == nil {
+		t.Fatal("错误的密码应该登录失败")
+	}
+	if err := c.Login("me@example.com", "secret"); err == nil {
+		t.Fatal("IP 被封禁后应该拒绝登录")
+	}
+
+	// 被封禁 IP 的新连接直接关闭
+	if c, err := client.Dial(listener.Addr().String()); err == nil {
+		c.Close()
+		t.Fatal("被封禁 IP 的新连接应该被关闭")
+	}
+}
@@ -1,20 +1,31 @@
 package imapd
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
-	"sort"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/backend"
-	"github.com/emersion/go-message"
+	"github.com/emersion/go-imap/server"
+	"github.com/emersion/go-sasl"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/receipts"
+	"github.com/gomailzero/gmz/internal/saslauth"
+	"github.com/gomailzero/gmz/internal/search"
 	"github.com/gomailzero/gmz/internal/storage"
 )
 
@@ -23,6 +34,21 @@ type Backend struct {
 	storage storage.Driver
 	maildir *storage.Maildir // Maildir 实例，用于读取邮件体
 	auth    Authenticator
+
+	limiter    *limits.Limiter      // 认证失败次数过多时封禁 IP（为空时不限制）
+	authErrors *authErrors          // 单个连接的认证失败次数上限
+	sasl       *saslauth.Mechanisms // PLAIN 以外的认证机制（可选）
+	conns      *drain.Tracker       // 服务器的连接跟踪，认证成功后记录连接的用户（可选）
+	spam       SpamReporter         // 记录用户移入垃圾邮件文件夹的邮件（可选）
+	trainer    SpamTrainer          // 用移入、移出垃圾邮件文件夹的邮件训练垃圾邮件分类器（可选）
+	keyring    Keyring              // 零访问存储的密钥（可选）
+	headers    *headerCache         // 邮件头缓存（所有连接共享）
+	activity   *activity.Recorder   // 账户活动记录：登录历史（可选）
+	submission string               // APPEND 到该文件夹的邮件投递给本地收件人（为空时 APPEND 只保存邮件）
+	forwarder  *forward.Forwarder   // 提交文件夹中发往别名的邮件转发到别名的外部目标（可选）
+
+	updates     chan backend.Update // 推送给客户端的新邮件通知（配置了 Maildir 监视器时）
+	generations *generations        // 每个邮箱的新邮件计数，已选择的邮箱据此加载新邮件
 }
 
 // NewBackend 创建后端
@@ -31,25 +57,138 @@ func NewBackend(storage storage.Driver, maildir *storage.Maildir, auth Authentic
 		storage: storage,
 		maildir: maildir,
 		auth:    auth,
+
+		authErrors: newAuthErrors(0),
+		headers:    newHeaderCache(headerCacheSize),
 	}
 }
 
-// Login 登录
+// Login 登录：IP 被封禁时直接拒绝，单个连接认证失败次数达到上限后断开连接
 func (b *Backend) Login(conn *imap.ConnInfo, username, password string) (backend.User, error) {
+	if err := b.allowLogin(conn); err != nil {
+		return nil, err
+	}
+
 	ctx := context.Background()
 	user, err := b.auth.Authenticate(ctx, username, password)
+	if err := b.finishLogin(conn, user, err); err != nil {
+		return nil, err
+	}
+	b.sasl.RememberPassword(ctx, user, password)
+	b.unlockKeyring(ctx, user, password)
+
+	return b.newUser(user), nil
+}
+
+// unlockKeyring 密码登录成功后解锁零访问存储的私钥（失败时仍然允许登录，只是无法读取加密的邮件）
+func (b *Backend) unlockKeyring(ctx context.Context, user *storage.User, password string) {
+	if b.keyring == nil {
+		return
+	}
+	if err := b.keyring.Unlock(ctx, user.Email, password); err != nil {
+		logger.Warn().Err(err).Str("user", user.Email).Msg("解锁零访问存储密钥失败，加密的邮件无法读取")
+	}
+}
+
+// newUser 创建认证成功的用户
+func (b *Backend) newUser(user *storage.User) *User {
+	u := NewUser(b.storage, b.maildir, user)
+	if b.keyring != nil {
+		// 只有认证后的会话解密零访问存储的邮件
+		u.maildir = b.maildir.WithOpener(b.keyring)
+	}
+	u.spam = b.spam
+	u.trainer = b.trainer
+	u.keyring = b.keyring
+	u.headers = b.headers
+	u.generations = b.generations
+	u.submission = b.submission
+	u.forwarder = b.forwarder
+	return u
+}
+
+// allowLogin 连接的认证失败次数达到上限或 IP 被封禁时拒绝认证
+func (b *Backend) allowLogin(conn *imap.ConnInfo) error {
+	if b.authErrors.exceeded(conn.RemoteAddr) {
+		return errTooManyAuthErrors
+	}
+	return b.limiter.Allow("imap", antispam.RemoteIP(conn.RemoteAddr))
+}
+
+// finishLogin 记录认证结果（所有认证机制共用）
+func (b *Backend) finishLogin(conn *imap.ConnInfo, user *storage.User, err error) error {
+	ip := antispam.RemoteIP(conn.RemoteAddr)
 	if err != nil {
-		return nil, fmt.Errorf("认证失败")
+		b.limiter.Failure("imap", ip)
+		if b.authErrors.fail(conn.RemoteAddr) {
+			logger.Warn().Str("remote", conn.RemoteAddr.String()).Msg("IMAP 认证失败次数过多，断开连接")
+			return errTooManyAuthErrors
+		}
+		return fmt.Errorf("认证失败")
 	}
+	b.limiter.Success(ip)
+	b.conns.SetUserByAddr(conn.RemoteAddr, user.Email)
+	b.activity.Login(context.Background(), user.Email, activity.ProtocolIMAP, ip, "")
+	return nil
+}
 
-	return NewUser(b.storage, b.maildir, user), nil
+// saslServer 创建 AUTHENTICATE 使用的 SASL 服务端：认证成功后直接进入已认证状态
+func (b *Backend) saslServer(mech string, conn server.Conn) sasl.Server {
+	info := conn.Info()
+	return b.sasl.NewServer(mech, func(user *storage.User, err error) error {
+		if err := b.allowLogin(info); err != nil {
+			return err
+		}
+		if err := b.finishLogin(info, user, err); err != nil {
+			return err
+		}
+		ctx := conn.Context()
+		ctx.State = imap.AuthenticatedState
+		ctx.User = b.newUser(user)
+		return nil
+	})
 }
 
+// storageError 超出配额时返回 NO [OVERQUOTA]（RFC 9208），其他错误原样返回
+func storageError(err error) error {
+	if errors.Is(err, storage.ErrQuotaExceeded) {
+		return server.ErrStatusResp(&imap.StatusResp{
+			Type: imap.StatusRespNo,
+			Code: "OVERQUOTA",
+			Info: "Quota exceeded",
+		})
+	}
+	return err
+}
+
+// errTooManyAuthErrors 连接上的认证失败次数达到上限
+var errTooManyAuthErrors = errors.New("认证失败次数过多")
+
+// errMailboxLocked 邮件已加密（零访问存储）而用户的私钥未解锁（如使用令牌登录或超过保留时间），
+// 返回 NO [UNAVAILABLE]（RFC 5530），避免客户端缓存空的邮件内容
+var errMailboxLocked = server.ErrStatusResp(&imap.StatusResp{
+	Type: imap.StatusRespNo,
+	Code: "UNAVAILABLE",
+	Info: "Mailbox is encrypted, log in with password to unlock",
+})
+
 // User IMAP 用户
 type User struct {
 	storage storage.Driver
 	maildir *storage.Maildir
 	user    *storage.User
+	spam    SpamReporter
+	trainer SpamTrainer
+	keyring Keyring
+	headers *headerCache // 邮件头缓存（为空时不缓存）
+
+	generations *generations       // 新邮件计数（为空时不加载选择邮箱之后到达的邮件）
+	submission  string             // 提交文件夹（见 Backend.submission）
+	forwarder   *forward.Forwarder // 别名外部目标的转发（见 Backend.forwarder）
+
+	// 连接启用的扩展（RFC 7162），User 在每次登录时创建，因此按连接记录
+	condstore bool // 之后的 FETCH 响应包含 FLAGS 时同时返回 MODSEQ
+	qresync   bool // 删除邮件时返回 VANISHED 而不是 EXPUNGE
 }
 
 // NewUser 创建用户
@@ -71,7 +210,7 @@ func (u *User) ListMailboxes(subscribed bool) ([]backend.Mailbox, error) {
 	ctx := context.Background()
 
 	// 列出所有文件夹
-	folders, err := u.storage.ListFolders(ctx, u.user.Email)
+	folders, err := u.folders(ctx)
 	if err != nil {
 		logger.Warn().Err(err).Str("user", u.user.Email).Msg("列出文件夹失败，返回空列表")
 		folders = []string{}
@@ -89,29 +228,93 @@ func (u *User) ListMailboxes(subscribed bool) ([]backend.Mailbox, error) {
 		folders = append([]string{"INBOX"}, folders...)
 	}
 
-	// 创建邮箱列表
-	mailboxes := make([]backend.Mailbox, 0, len(folders))
+	// 上级文件夹不存在时（如只创建了 Projects/2024/ClientA）同样列出，标记为 \Noselect，客户端据此显示层级
+	names := slices.Clone(folders)
 	for _, folder := range folders {
+		for _, parent := range storage.ParentFolders(folder) {
+			if !slices.Contains(names, parent) {
+				names = append(names, parent)
+			}
+		}
+	}
+	// LSUB 只列出订阅的邮箱，已订阅但不存在的邮箱（如被删除）标记为 \Noselect
+	if subscribed {
+		subscriptions, err := u.storage.ListSubscriptions(ctx, u.user.Email)
+		if err != nil {
+			return nil, fmt.Errorf("查询订阅列表失败: %w", err)
+		}
+		names = subscriptions
+	}
+
+	// 创建邮箱列表
+	mailboxes := make([]backend.Mailbox, 0, len(names))
+	for _, folder := range names {
 		// 标准化文件夹名称
 		normalizedName := folder
 		if strings.EqualFold(folder, "INBOX") {
 			normalizedName = "INBOX"
 		}
 
-		// 列出邮件
-		mails, err := u.storage.ListMails(ctx, u.user.Email, normalizedName, 1000, 0)
+		// 列出邮件（先为没有 UID 的旧邮件分配 UID）
+		if _, err := u.storage.GetMailboxUIDs(ctx, u.user.Email, normalizedName); err != nil {
+			logger.Warn().Err(err).Str("user", u.user.Email).Str("folder", normalizedName).Msg("初始化邮箱 UID 状态失败")
+		}
+		mails, err := listAllMails(ctx, u.storage, u.user.Email, normalizedName)
 		if err != nil {
 			logger.Warn().Err(err).Str("user", u.user.Email).Str("folder", normalizedName).Msg("列出邮件失败，使用空列表")
 			mails = []*storage.Mail{}
 		}
 
 		mailbox := NewMailbox(u.storage, u.maildir, u.user.Email, normalizedName, mails)
+		mailbox.spam = u.spam
+		mailbox.trainer = u.trainer
+		mailbox.keyring = u.keyring
+		mailbox.noSelect = !slices.Contains(folders, normalizedName)
+		for _, other := range folders {
+			if storage.IsSubfolder(other, normalizedName) {
+				mailbox.hasChildren = true
+				break
+			}
+		}
 		mailboxes = append(mailboxes, mailbox)
 	}
 
 	return mailboxes, nil
 }
 
+// folders 列出用户的文件夹：数据库中有邮件的文件夹（包括默认文件夹）和 Maildir 中的文件夹目录（包括空文件夹）
+func (u *User) folders(ctx context.Context) ([]string, error) {
+	folders, err := u.storage.ListFolders(ctx, u.user.Email)
+	if err != nil {
+		return nil, err
+	}
+	if u.maildir == nil {
+		return folders, nil
+	}
+	dirs, err := u.maildir.ListFolders(u.user.Email)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if !slices.Contains(folders, dir) {
+			folders = append(folders, dir)
+		}
+	}
+	return folders, nil
+}
+
+// mailboxExists 邮箱是否存在（INBOX 始终存在）
+func (u *User) mailboxExists(ctx context.Context, name string) (bool, error) {
+	if strings.EqualFold(name, "INBOX") {
+		return true, nil
+	}
+	folders, err := u.folders(ctx)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(folders, name), nil
+}
+
 // GetMailbox 获取邮箱
 func (u *User) GetMailbox(name string) (backend.Mailbox, error) {
 	ctx := context.Background()
@@ -122,8 +325,16 @@ func (u *User) GetMailbox(name string) (backend.Mailbox, error) {
 		normalizedName = "INBOX"
 	}
 
+	// 初始化邮箱 UID 状态（为没有 UID 的旧邮件补分配 UID，保证 UID 在会话之间稳定）
+	if _, err := u.storage.GetMailboxUIDs(ctx, u.user.Email, normalizedName); err != nil {
+		logger.Warn().Err(err).Str("user", u.user.Email).Str("folder", normalizedName).Msg("初始化邮箱 UID 状态失败")
+	}
+
+	// 在读取邮件之前记录新邮件计数，读取期间到达的邮件由 Mailbox.sync 补上
+	generation := u.generations.get(u.user.Email, normalizedName)
+
 	// 列出邮件（从数据库读取）
-	mails, err := u.storage.ListMails(ctx, u.user.Email, normalizedName, 1000, 0)
+	mails, err := listAllMails(ctx, u.storage, u.user.Email, normalizedName)
 	if err != nil {
 		// 如果查询失败，返回空邮箱而不是错误
 		logger.Warn().Err(err).Str("user", u.user.Email).Str("folder", name).Str("normalized", normalizedName).Msg("查询邮件列表失败，返回空邮箱")
@@ -138,459 +349,16 @@ func (u *User) GetMailbox(name string) (backend.Mailbox, error) {
 			Msg("IMAP GetMailbox: 从数据库读取邮件")
 	}
 
-	// 如果 Maildir 可用，检查文件系统状态并同步
-	if u.maildir != nil {
-		userDir := u.maildir.GetUserMaildir(u.user.Email)
-		var curDir string
-		var newDir string
-		if normalizedName == "INBOX" || normalizedName == "" {
-			curDir = filepath.Join(userDir, "cur")
-			newDir = filepath.Join(userDir, "new")
-		} else {
-			curDir = filepath.Join(userDir, "."+normalizedName, "cur")
-			newDir = filepath.Join(userDir, "."+normalizedName, "new")
-		}
-
-		// 构建数据库中已有的邮件 ID 映射
-		mailIDMap := make(map[string]bool)
-		for _, mail := range mails {
-			baseID := mail.ID
-			if idx := strings.Index(mail.ID, ":"); idx >= 0 {
-				baseID = mail.ID[:idx]
-			}
-			mailIDMap[baseID] = true
-			mailIDMap[mail.ID] = true
-		}
-
-		// 检查 cur 目录中的文件，同步缺失的邮件到数据库
-		curEntries, err := os.ReadDir(curDir)
-		if err == nil {
-			for _, entry := range curEntries {
-				if entry.IsDir() {
-					continue
-				}
-				filename := entry.Name()
-				baseID := filename
-				if idx := strings.Index(filename, ":"); idx >= 0 {
-					baseID = filename[:idx]
-				}
-
-				// 如果文件不在数据库中，尝试同步
-				if !mailIDMap[baseID] && !mailIDMap[filename] {
-					logger.Debug().
-						Str("user", u.user.Email).
-						Str("folder", normalizedName).
-						Str("filename", filename).
-						Msg("IMAP GetMailbox: 发现 Maildir 中的邮件未同步到数据库，尝试同步")
-
-					// 读取邮件文件
-					mailData, err := u.maildir.ReadMail(u.user.Email, normalizedName, baseID)
-					if err == nil {
-						var fromHeader, toHeader, subject string
-						var bodyBytes []byte
-
-						// 尝试使用 message.Read 解析
-						msg, err := message.Read(bytes.NewReader(mailData))
-						if err == nil {
-							header := msg.Header
-							fromHeader = header.Get("From")
-							toHeader = header.Get("To")
-							subject = header.Get("Subject")
-
-							// 读取邮件体
-							if msg.Body != nil {
-								bodyBytes, _ = io.ReadAll(msg.Body)
-							}
-						}
-
-						// 如果 message.Read 解析失败或邮件头为空，尝试手动解析
-						// 检查是否以 "This is a multi-part message" 开头（缺少邮件头）
-						mailDataStr := string(mailData)
-						if fromHeader == "" && strings.HasPrefix(mailDataStr, "This is a multi-part message") {
-							// 这种格式的邮件缺少邮件头，尝试从文件名或其他方式推断
-							// 或者使用默认值
-							logger.Debug().
-								Str("user", u.user.Email).
-								Str("folder", normalizedName).
-								Str("mail_id", baseID).
-								Msg("IMAP GetMailbox: 邮件缺少标准邮件头，使用默认值")
-							fromHeader = "unknown@unknown"
-							toHeader = u.user.Email
-							subject = "(无主题)"
-							bodyBytes = mailData
-						} else if fromHeader == "" {
-							// 尝试手动解析邮件头（如果 message.Read 失败但文件有邮件头）
-							lines := strings.Split(mailDataStr, "\n")
-							for i, line := range lines {
-								line = strings.TrimSpace(line)
-								if strings.HasPrefix(strings.ToLower(line), "from:") {
-									fromHeader = strings.TrimSpace(line[5:])
-								} else if strings.HasPrefix(strings.ToLower(line), "to:") {
-									toHeader = strings.TrimSpace(line[3:])
-								} else if strings.HasPrefix(strings.ToLower(line), "subject:") {
-									subject = strings.TrimSpace(line[8:])
-								} else if line == "" && i > 0 {
-									// 空行表示邮件头结束
-									// 邮件体从下一行开始
-									if i+1 < len(lines) {
-										bodyBytes = []byte(strings.Join(lines[i+1:], "\n"))
-									}
-									break
-								}
-							}
-							if fromHeader == "" {
-								fromHeader = "unknown@unknown"
-							}
-							if toHeader == "" {
-								toHeader = u.user.Email
-							}
-							if subject == "" {
-								subject = "(无主题)"
-							}
-							if len(bodyBytes) == 0 {
-								bodyBytes = mailData
-							}
-						}
-
-						// 解析 From 地址
-						fromAddr := fromHeader
-						if fromAddr == "" {
-							fromAddr = "unknown@unknown"
-						}
-						// 清理 From 地址
-						fromAddr = strings.TrimSpace(fromAddr)
-						if idx := strings.Index(fromAddr, "<"); idx >= 0 {
-							if idx2 := strings.Index(fromAddr, ">"); idx2 > idx {
-								fromAddr = fromAddr[idx+1 : idx2]
-							}
-						}
-						fromAddr = strings.Trim(fromAddr, "\"")
-						fromAddr = strings.TrimSpace(fromAddr)
-						if fromAddr == "" || fromAddr == "<>" {
-							fromAddr = "unknown@unknown"
-						}
-
-						// 解析 To 地址
-						toAddrs := []string{}
-						if toHeader != "" {
-							// 简单的地址解析（支持多个地址，用逗号分隔）
-							parts := strings.Split(toHeader, ",")
-							for _, part := range parts {
-								addr := strings.TrimSpace(part)
-								// 提取邮箱地址
-								if idx := strings.Index(addr, "<"); idx >= 0 {
-									if idx2 := strings.Index(addr, ">"); idx2 > idx {
-										addr = addr[idx+1 : idx2]
-									}
-								}
-								addr = strings.Trim(addr, "\"")
-								addr = strings.TrimSpace(addr)
-								if addr != "" {
-									toAddrs = append(toAddrs, addr)
-								}
-							}
-						}
-						if len(toAddrs) == 0 {
-							toAddrs = []string{u.user.Email}
-						}
-
-						// 确定标志（如果文件在 cur 目录且有 :2,S 后缀，说明已读）
-						var flags []string
-						if strings.Contains(filename, ":2,S") || strings.Contains(filename, ":2,RS") {
-							flags = []string{"\\Seen"}
-						} else {
-							flags = []string{"\\Recent"}
-						}
-
-						// 获取文件修改时间作为接收时间
-						fileInfo, err := entry.Info()
-						receivedAt := time.Now()
-						if err == nil {
-							receivedAt = fileInfo.ModTime()
-						}
-
-						// 创建邮件记录
-						syncMail := &storage.Mail{
-							ID:         baseID,
-							UserEmail:  u.user.Email,
-							Folder:     normalizedName,
-							From:       fromAddr,
-							To:         toAddrs,
-							Subject:    subject,
-							Body:       bodyBytes,
-							Size:       int64(len(mailData)),
-							Flags:      flags,
-							ReceivedAt: receivedAt,
-							CreatedAt:  receivedAt,
-						}
-
-						// 存储到数据库
-						if err := u.storage.StoreMail(ctx, syncMail); err != nil {
-							logger.Warn().Err(err).
-								Str("user", u.user.Email).
-								Str("folder", normalizedName).
-								Str("mail_id", baseID).
-								Msg("同步邮件到数据库失败")
-						} else {
-							// 添加到邮件列表
-							mails = append(mails, syncMail)
-							mailIDMap[baseID] = true
-							logger.Info().
-								Str("user", u.user.Email).
-								Str("folder", normalizedName).
-								Str("mail_id", baseID).
-								Str("from", fromAddr).
-								Str("subject", subject).
-								Msg("IMAP GetMailbox: 成功同步邮件到数据库")
-						}
-					}
-				}
-			}
-		}
-
-		// 检查 new 目录中的文件，同步缺失的邮件到数据库
-		newEntries, err := os.ReadDir(newDir)
-		if err == nil {
-			newFileMap := make(map[string]bool)
-			for _, entry := range newEntries {
-				if !entry.IsDir() {
-					filename := entry.Name()
-					baseID := filename
-					if idx := strings.Index(filename, ":"); idx >= 0 {
-						baseID = filename[:idx]
-					}
-					newFileMap[baseID] = true
-
-					// 如果文件不在数据库中，尝试同步
-					if !mailIDMap[baseID] && !mailIDMap[filename] {
-						logger.Debug().
-							Str("user", u.user.Email).
-							Str("folder", normalizedName).
-							Str("filename", filename).
-							Msg("IMAP GetMailbox: 发现 new 目录中的邮件未同步到数据库，尝试同步")
-
-						// 读取邮件文件
-						mailData, err := u.maildir.ReadMail(u.user.Email, normalizedName, baseID)
-						if err == nil {
-							var fromHeader, toHeader, subject string
-							var bodyBytes []byte
-
-							// 尝试使用 message.Read 解析
-							msg, err := message.Read(bytes.NewReader(mailData))
-							if err == nil {
-								header := msg.Header
-								fromHeader = header.Get("From")
-								toHeader = header.Get("To")
-								subject = header.Get("Subject")
-
-								// 读取邮件体
-								if msg.Body != nil {
-									bodyBytes, _ = io.ReadAll(msg.Body)
-								}
-							}
-
-							// 如果 message.Read 解析失败或邮件头为空，尝试手动解析
-							// 检查是否以 "This is a multi-part message" 开头（缺少邮件头）
-							mailDataStr := string(mailData)
-							if fromHeader == "" && strings.HasPrefix(mailDataStr, "This is a multi-part message") {
-								// 这种格式的邮件缺少邮件头，尝试从文件名或其他方式推断
-								// 或者使用默认值
-								logger.Debug().
-									Str("user", u.user.Email).
-									Str("folder", normalizedName).
-									Str("mail_id", baseID).
-									Msg("IMAP GetMailbox: 邮件缺少标准邮件头，使用默认值（new）")
-								fromHeader = "unknown@unknown"
-								toHeader = u.user.Email
-								subject = "(无主题)"
-								bodyBytes = mailData
-							} else if fromHeader == "" {
-								// 尝试手动解析邮件头（如果 message.Read 失败但文件有邮件头）
-								lines := strings.Split(mailDataStr, "\n")
-								for i, line := range lines {
-									line = strings.TrimSpace(line)
-									if strings.HasPrefix(strings.ToLower(line), "from:") {
-										fromHeader = strings.TrimSpace(line[5:])
-									} else if strings.HasPrefix(strings.ToLower(line), "to:") {
-										toHeader = strings.TrimSpace(line[3:])
-									} else if strings.HasPrefix(strings.ToLower(line), "subject:") {
-										subject = strings.TrimSpace(line[8:])
-									} else if line == "" && i > 0 {
-										// 空行表示邮件头结束
-										// 邮件体从下一行开始
-										if i+1 < len(lines) {
-											bodyBytes = []byte(strings.Join(lines[i+1:], "\n"))
-										}
-										break
-									}
-								}
-								if fromHeader == "" {
-									fromHeader = "unknown@unknown"
-								}
-								if toHeader == "" {
-									toHeader = u.user.Email
-								}
-								if subject == "" {
-									subject = "(无主题)"
-								}
-								if len(bodyBytes) == 0 {
-									bodyBytes = mailData
-								}
-							}
-
-							// 解析 From 地址
-							fromAddr := fromHeader
-							if fromAddr == "" {
-								fromAddr = "unknown@unknown"
-							}
-							// 清理 From 地址
-							fromAddr = strings.TrimSpace(fromAddr)
-							if idx := strings.Index(fromAddr, "<"); idx >= 0 {
-								if idx2 := strings.Index(fromAddr, ">"); idx2 > idx {
-									fromAddr = fromAddr[idx+1 : idx2]
-								}
-							}
-							fromAddr = strings.Trim(fromAddr, "\"")
-							fromAddr = strings.TrimSpace(fromAddr)
-							if fromAddr == "" || fromAddr == "<>" {
-								fromAddr = "unknown@unknown"
-							}
-
-							// 解析 To 地址
-							toAddrs := []string{}
-							if toHeader != "" {
-								parts := strings.Split(toHeader, ",")
-								for _, part := range parts {
-									addr := strings.TrimSpace(part)
-									if idx := strings.Index(addr, "<"); idx >= 0 {
-										if idx2 := strings.Index(addr, ">"); idx2 > idx {
-											addr = addr[idx+1 : idx2]
-										}
-									}
-									addr = strings.Trim(addr, "\"")
-									addr = strings.TrimSpace(addr)
-									if addr != "" {
-										toAddrs = append(toAddrs, addr)
-									}
-								}
-							}
-							if len(toAddrs) == 0 {
-								toAddrs = []string{u.user.Email}
-							}
-
-							// 获取文件修改时间作为接收时间
-							fileInfo, err := entry.Info()
-							receivedAt := time.Now()
-							if err == nil {
-								receivedAt = fileInfo.ModTime()
-							}
-
-							// 创建邮件记录（new 目录中的邮件是未读的）
-							syncMail := &storage.Mail{
-								ID:         baseID,
-								UserEmail:  u.user.Email,
-								Folder:     normalizedName,
-								From:       fromAddr,
-								To:         toAddrs,
-								Subject:    subject,
-								Body:       bodyBytes,
-								Size:       int64(len(mailData)),
-								Flags:      []string{"\\Recent"}, // new 目录中的邮件是未读的
-								ReceivedAt: receivedAt,
-								CreatedAt:  receivedAt,
-							}
-
-							// 存储到数据库
-							if err := u.storage.StoreMail(ctx, syncMail); err != nil {
-								logger.Warn().Err(err).
-									Str("user", u.user.Email).
-									Str("folder", normalizedName).
-									Str("mail_id", baseID).
-									Msg("同步邮件到数据库失败")
-							} else {
-								// 添加到邮件列表
-								mails = append(mails, syncMail)
-								mailIDMap[baseID] = true
-								logger.Info().
-									Str("user", u.user.Email).
-									Str("folder", normalizedName).
-									Str("mail_id", baseID).
-									Str("from", fromAddr).
-									Str("subject", subject).
-									Msg("IMAP GetMailbox: 成功同步邮件到数据库（new）")
-							}
-						}
-					}
-				}
-			}
-
-			// 检查数据库中的邮件，如果文件在 new 目录中但标志有 \Seen，需要修复
-			for _, mail := range mails {
-				baseID := mail.ID
-				if idx := strings.Index(mail.ID, ":"); idx >= 0 {
-					baseID = mail.ID[:idx]
-				}
-
-				// 如果文件在 new 目录中，但标志有 \Seen，这是不一致的
-				if newFileMap[baseID] {
-					hasSeen := false
-					hasRecent := false
-					for _, flag := range mail.Flags {
-						if flag == imap.SeenFlag || flag == "\\Seen" {
-							hasSeen = true
-						}
-						if flag == imap.RecentFlag || flag == "\\Recent" {
-							hasRecent = true
-						}
-					}
-
-					// 如果文件在 new 目录中，但标志有 \Seen，移除 \Seen 标志
-					if hasSeen {
-						logger.Debug().
-							Str("user", u.user.Email).
-							Str("folder", normalizedName).
-							Str("mail_id", baseID).
-							Msg("IMAP GetMailbox: 发现文件在 new 目录但标志有 \\Seen，修复标志")
-
-						// 移除 \Seen 标志，保留 \Recent
-						newFlags := make([]string, 0)
-						for _, flag := range mail.Flags {
-							if flag != imap.SeenFlag && flag != "\\Seen" {
-								newFlags = append(newFlags, flag)
-							}
-						}
-						// 确保有 \Recent 标志
-						if !hasRecent {
-							newFlags = append(newFlags, imap.RecentFlag)
-						}
-
-						if err := u.storage.UpdateMailFlags(ctx, mail.ID, newFlags); err != nil {
-							logger.Warn().Err(err).
-								Str("user", u.user.Email).
-								Str("folder", normalizedName).
-								Str("mail_id", baseID).
-								Msg("修复邮件标志失败")
-						} else {
-							mail.Flags = newFlags
-							logger.Debug().
-								Str("user", u.user.Email).
-								Str("folder", normalizedName).
-								Str("mail_id", baseID).
-								Strs("new_flags", newFlags).
-								Msg("IMAP GetMailbox: 已修复邮件标志")
-						}
-					}
-				}
-			}
-		}
-
-		// 重新排序邮件（按接收时间降序）
-		if len(mails) > 0 {
-			sort.Slice(mails, func(i, j int) bool {
-				return mails[i].ReceivedAt.After(mails[j].ReceivedAt)
-			})
-		}
-	}
+	// 使用原始名称创建邮箱（保持客户端请求的名称）
+	mailbox := NewMailbox(u.storage, u.maildir, u.user.Email, normalizedName, mails)
+	mailbox.spam = u.spam
+	mailbox.trainer = u.trainer
+	mailbox.keyring = u.keyring
+	mailbox.headers = u.headers
+	mailbox.generations = u.generations
+	mailbox.generation = generation
+	mailbox.submission = u.submission
+	mailbox.forwarder = u.forwarder
 
 	// 如果邮件既没有 \Seen 也没有 \Recent 标志（旧邮件），自动设置 \Seen 标志（兼容 Foxmail）
 	// 这会在 GetMailbox 时自动处理，即使客户端只调用 Status 命令
@@ -605,72 +373,85 @@ func (u *User) GetMailbox(name string) (backend.Mailbox, error) {
 				hasRecent = true
 			}
 		}
-		// 如果邮件没有 \Seen 标志，且没有 \Recent 标志，自动设置 \Seen 标志（兼容 Foxmail）
-		if !hasSeen && !hasRecent {
-			newFlags := append(mail.Flags, imap.SeenFlag)
-
-			// 如果邮件被标记为已读，且之前未读，需要从 new 移动到 cur
-			if u.maildir != nil {
-				// 去除可能的标志后缀（如 :2,S）
-				baseID := mail.ID
-				if idx := strings.Index(mail.ID, ":"); idx >= 0 {
-					baseID = mail.ID[:idx]
-				}
-
-				// 检查文件是否在 new 目录中
-				userDir := u.maildir.GetUserMaildir(u.user.Email)
-				var newDir string
-				if normalizedName == "INBOX" || normalizedName == "" {
-					newDir = filepath.Join(userDir, "new")
-				} else {
-					newDir = filepath.Join(userDir, "."+normalizedName, "new")
-				}
-
-				newPath := filepath.Join(newDir, baseID)
-				if _, err := os.Stat(newPath); err == nil {
-					// 文件在 new 目录中，移动到 cur
-					if err := u.maildir.MoveToCur(u.user.Email, normalizedName, baseID, newFlags); err != nil {
-						logger.Warn().Err(err).
-							Str("user", u.user.Email).
-							Str("folder", normalizedName).
-							Str("mail_id", baseID).
-							Msg("移动邮件从 new 到 cur 失败（GetMailbox）")
-					} else {
-						logger.Debug().
-							Str("user", u.user.Email).
-							Str("folder", normalizedName).
-							Str("mail_id", baseID).
-							Msg("IMAP GetMailbox: 邮件已从 new 移动到 cur")
-					}
-				}
-			}
-
-			if err := u.storage.UpdateMailFlags(ctx, mail.ID, newFlags); err != nil {
-				logger.Warn().Err(err).Str("mail_id", mail.ID).Msg("自动设置 \\Seen 标志失败（GetMailbox）")
-			} else {
-				// 更新内存中的标志
-				mail.Flags = newFlags
-				logger.Debug().
-					Str("user", u.user.Email).
-					Str("folder", normalizedName).
-					Str("mail_id", mail.ID).
-					Msg("IMAP GetMailbox: 自动设置 \\Seen 标志（兼容 Foxmail）")
-			}
+		if hasSeen || hasRecent {
+			continue
+		}
+		// 与 STORE 相同的路径：邮件文件从 new 移动到 cur 并更新数据库标志
+		if err := mailbox.updateMailFlagsAndMove(ctx, mail, append(mail.Flags, imap.SeenFlag)); err != nil {
+			logger.Warn().Err(err).Str("mail_id", mail.ID).Msg("自动设置 \\Seen 标志失败（GetMailbox）")
+			continue
 		}
+		logger.Debug().
+			Str("user", u.user.Email).
+			Str("folder", normalizedName).
+			Str("mail_id", mail.ID).
+			Msg("IMAP GetMailbox: 自动设置 \\Seen 标志（兼容 Foxmail）")
 	}
-
-	// 使用原始名称创建邮箱（保持客户端请求的名称）
-	return NewMailbox(u.storage, u.maildir, u.user.Email, normalizedName, mails), nil
+	return mailbox, nil
 }
 
-// CreateMailbox 创建邮箱
+// CreateMailbox 创建邮箱（Maildir++ 文件夹目录），同时创建不存在的上级邮箱
 func (u *User) CreateMailbox(name string) error {
+	ctx := context.Background()
+
+	// 名称末尾的层级分隔符表示之后要在其下创建子邮箱，创建的仍是同一个邮箱（RFC 3501）
+	name = strings.TrimSuffix(name, storage.FolderDelimiter)
+	if strings.EqualFold(name, "INBOX") {
+		return errMailboxExists
+	}
+	if err := storage.ValidateFolderName(name); err != nil {
+		return err
+	}
+	exists, err := u.mailboxExists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errMailboxExists
+	}
+	if u.maildir == nil {
+		return errors.New("邮件存储不可用")
+	}
+
+	parts := strings.Split(name, storage.FolderDelimiter)
+	for i := range parts {
+		if err := u.maildir.CreateFolder(u.user.Email, strings.Join(parts[:i+1], storage.FolderDelimiter)); err != nil {
+			return err
+		}
+	}
+	logger.Info().Str("user", u.user.Email).Str("folder", name).Msg("IMAP 创建邮箱")
 	return nil
 }
 
-// DeleteMailbox 删除邮箱
+// DeleteMailbox 删除邮箱及其中的邮件（不能删除 INBOX 和有子邮箱的邮箱）
 func (u *User) DeleteMailbox(name string) error {
-	// TODO: 实现删除邮箱功能
+	ctx := context.Background()
+
+	if strings.EqualFold(name, "INBOX") {
+		return errors.New("不能删除收件箱")
+	}
+	folders, err := u.folders(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(folders, name) {
+		return backend.ErrNoSuchMailbox
+	}
+	for _, folder := range folders {
+		if storage.IsSubfolder(folder, name) {
+			return errors.New("邮箱包含子邮箱，请先删除子邮箱")
+		}
+	}
+
+	if err := u.storage.DeleteFolder(ctx, u.user.Email, name); err != nil {
+		return err
+	}
+	if u.maildir != nil {
+		if err := u.maildir.DeleteFolder(u.user.Email, name); err != nil {
+			return err
+		}
+	}
+	logger.Info().Str("user", u.user.Email).Str("folder", name).Msg("IMAP 删除邮箱")
 	return nil
 }
 
@@ -679,19 +460,96 @@ func (u *User) Logout() error {
 	return nil
 }
 
-// RenameMailbox 重命名邮箱
+// RenameMailbox 重命名邮箱，子邮箱随之重命名；重命名 INBOX 时把其中的邮件移动到新邮箱，INBOX 保留为空
 func (u *User) RenameMailbox(existingName, newName string) error {
-	// TODO: 实现重命名邮箱功能
+	ctx := context.Background()
+
+	if strings.EqualFold(existingName, "INBOX") {
+		existingName = "INBOX"
+	}
+	newName = strings.TrimSuffix(newName, storage.FolderDelimiter)
+	if strings.EqualFold(newName, "INBOX") {
+		return errMailboxExists
+	}
+	if err := storage.ValidateFolderName(newName); err != nil {
+		return err
+	}
+	if existingName != "INBOX" && (newName == existingName || storage.IsSubfolder(newName, existingName)) {
+		return errors.New("不能把邮箱重命名为自己的子邮箱")
+	}
+	exists, err := u.mailboxExists(ctx, existingName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return backend.ErrNoSuchMailbox
+	}
+	if exists, err = u.mailboxExists(ctx, newName); err != nil {
+		return err
+	} else if exists {
+		return errMailboxExists
+	}
+	if u.maildir == nil {
+		return errors.New("邮件存储不可用")
+	}
+
+	// 补建新名称的上级邮箱
+	parts := strings.Split(newName, storage.FolderDelimiter)
+	for i := range parts[:len(parts)-1] {
+		if err := u.maildir.CreateFolder(u.user.Email, strings.Join(parts[:i+1], storage.FolderDelimiter)); err != nil {
+			return err
+		}
+	}
+	// 先移动文件，再更新数据库记录；数据库更新失败时把文件夹目录改回原名
+	if err := u.maildir.RenameFolder(u.user.Email, existingName, newName); err != nil {
+		return err
+	}
+	if err := u.storage.RenameFolder(ctx, u.user.Email, existingName, newName); err != nil {
+		if existingName != "INBOX" {
+			if rerr := u.maildir.RenameFolder(u.user.Email, newName, existingName); rerr != nil {
+				logger.Error().Err(rerr).Str("user", u.user.Email).Str("folder", newName).Msg("恢复文件夹名称失败")
+			}
+		}
+		return err
+	}
+	logger.Info().Str("user", u.user.Email).Str("from", existingName).Str("to", newName).Msg("IMAP 重命名邮箱")
 	return nil
 }
 
+// errMailboxExists 创建或重命名的目标邮箱已存在
+var errMailboxExists = errors.New("邮箱已存在")
+
 // Mailbox 邮箱
 type Mailbox struct {
 	storage   storage.Driver
 	maildir   *storage.Maildir // Maildir 实例，用于读取邮件体
 	userEmail string
 	name      string
-	mails     []*storage.Mail
+	mails     []*storage.Mail // 邮件元数据（不包括邮件内容，内容在 FETCH 时按需读取）
+	spam      SpamReporter    // 记录移入垃圾邮件文件夹的邮件（可选）
+	trainer   SpamTrainer     // 训练垃圾邮件分类器（可选）
+	keyring   Keyring         // 零访问存储的密钥（可选）
+	headers   *headerCache    // 邮件头缓存（为空时不缓存）
+
+	submission string             // 提交文件夹：APPEND 到该文件夹时投递给本地收件人（为空时不投递）
+	forwarder  *forward.Forwarder // 别名外部目标的转发（可选）
+
+	hasChildren bool // 有子邮箱（LIST 时设置）
+	noSelect    bool // 已订阅但不存在的邮箱（LSUB 时设置）
+
+	generations *generations // 新邮件计数（见 sync）
+	generation  uint64       // 读取 mails 时的新邮件计数
+}
+
+// checkUnlocked 读取邮件内容之前检查零访问存储的私钥是否已解锁
+func (m *Mailbox) checkUnlocked(ctx context.Context) error {
+	if m.keyring == nil || m.keyring.Unlocked(m.userEmail) {
+		return nil
+	}
+	if enabled, err := m.keyring.Enabled(ctx, m.userEmail); err != nil || !enabled {
+		return nil
+	}
+	return errMailboxLocked
 }
 
 // NewMailbox 创建邮箱
@@ -737,7 +595,7 @@ func (m *Mailbox) updateMailFlagsAndMove(ctx context.Context, mail *storage.Mail
 		if m.name == "INBOX" || m.name == "" {
 			newDir = filepath.Join(userDir, "new")
 		} else {
-			newDir = filepath.Join(userDir, "."+m.name, "new")
+			newDir = filepath.Join(userDir, storage.FolderDirName(m.name), "new")
 		}
 
 		newPath := filepath.Join(newDir, baseID)
@@ -764,8 +622,11 @@ func (m *Mailbox) updateMailFlagsAndMove(ctx context.Context, mail *storage.Mail
 		return fmt.Errorf("更新邮件标志失败: %w", err)
 	}
 
-	// 更新内存中的标志
+	// 更新内存中的标志和修改序号（数据库触发器在标志变化时递增修改序号）
 	mail.Flags = newFlags
+	if updated, err := m.storage.GetMail(ctx, mail.ID); err == nil {
+		mail.ModSeq = updated.ModSeq
+	}
 	return nil
 }
 
@@ -774,17 +635,37 @@ func (m *Mailbox) Name() string {
 	return m.name
 }
 
+// specialUse 特殊用途文件夹的属性（RFC 6154），客户端据此选择删除、归档、发件等操作的目标文件夹
+var specialUse = map[string]string{
+	"Sent":    imap.SentAttr,
+	"Drafts":  imap.DraftsAttr,
+	"Trash":   imap.TrashAttr,
+	"Spam":    imap.JunkAttr,
+	"Archive": imap.ArchiveAttr,
+}
+
 // Info 返回邮箱信息
 func (m *Mailbox) Info() (*imap.MailboxInfo, error) {
+	attributes := []string{imap.HasNoChildrenAttr}
+	if m.hasChildren {
+		attributes = []string{imap.HasChildrenAttr}
+	}
+	if attr, ok := specialUse[m.name]; ok {
+		attributes = append(attributes, attr)
+	}
+	if m.noSelect {
+		attributes = append(attributes, imap.NoSelectAttr)
+	}
 	return &imap.MailboxInfo{
-		Attributes: []string{imap.NoInferiorsAttr},
-		Delimiter:  "/",
+		Attributes: attributes,
+		Delimiter:  storage.FolderDelimiter,
 		Name:       m.name,
 	}, nil
 }
 
 // Status 返回邮箱状态
 func (m *Mailbox) Status(items []imap.StatusItem) (*imap.MailboxStatus, error) {
+	m.sync()
 	status := &imap.MailboxStatus{
 		Name:  m.name,
 		Items: make(map[imap.StatusItem]interface{}),
@@ -883,32 +764,38 @@ func (m *Mailbox) Status(items []imap.StatusItem) (*imap.MailboxStatus, error) {
 				Uint32("uid_next", status.UidNext).
 				Msg("IMAP Status: UidNext")
 		case imap.StatusUidValidity:
-			// 使用邮箱名称和用户邮箱的哈希值作为 UidValidity
-			// 这样可以确保在邮箱结构改变时（如邮件被删除或移动），UidValidity 会改变
-			// 使用简单的哈希算法：将邮箱名称和用户邮箱组合后计算哈希
-			hash := uint32(0)
-			for _, c := range m.userEmail + ":" + m.name {
-				hash = hash*31 + uint32(c)
-			}
-			// 确保 UidValidity 不为 0（IMAP 规范要求）
-			if hash == 0 {
-				hash = 1
+			// UidValidity 在邮箱初始化时确定并持久化，之后保持不变，客户端可以按 UID 缓存邮件
+			ctx := context.Background()
+			uids, err := m.storage.GetMailboxUIDs(ctx, m.userEmail, m.name)
+			if err != nil {
+				return nil, fmt.Errorf("获取 UIDVALIDITY 失败: %w", err)
 			}
-			status.UidValidity = hash
+			status.UidValidity = uids.UIDValidity
 			logger.Debug().
 				Str("user", m.userEmail).
 				Str("folder", m.name).
 				Uint32("uid_validity", status.UidValidity).
 				Msg("IMAP Status: UidValidity")
+		case statusHighestModSeq:
+			// CONDSTORE：文件夹的最大修改序号（go-imap 只能输出 uint32，按原样输出）
+			ctx := context.Background()
+			highest, err := m.storage.GetHighestModSeq(ctx, m.userEmail, m.name)
+			if err != nil {
+				return nil, fmt.Errorf("获取 HIGHESTMODSEQ 失败: %w", err)
+			}
+			status.Items[item] = imap.RawString(strconv.FormatUint(highest, 10))
 		}
 	}
 
 	return status, nil
 }
 
-// SetSubscribed 设置订阅状态
+// SetSubscribed 设置订阅状态（SUBSCRIBE/UNSUBSCRIBE）
 func (m *Mailbox) SetSubscribed(subscribed bool) error {
-	// TODO: 实现订阅功能
+	if err := m.storage.SetSubscribed(context.Background(), m.userEmail, m.name, subscribed); err != nil {
+		logger.Warn().Err(err).Str("user", m.userEmail).Str("folder", m.name).Msg("设置订阅状态失败")
+		return err
+	}
 	return nil
 }
 
@@ -921,6 +808,7 @@ func (m *Mailbox) Check() error {
 // ListMessages 列出邮件
 func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.FetchItem, ch chan<- *imap.Message) error {
 	defer close(ch)
+	m.sync()
 
 	// 记录调试信息
 	itemNames := make([]string, len(items))
@@ -957,6 +845,18 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 			hasEnvelopeRequest = true
 		}
 	}
+	// 零访问存储加密的邮件不缓存明文邮件头（私钥锁定之后仍然可以从缓存读取）
+	cacheable := m.keyring == nil
+	if hasBodyRequest {
+		ctx := context.Background()
+		if err := m.checkUnlocked(ctx); err != nil {
+			return err
+		}
+		if !cacheable {
+			enabled, err := m.keyring.Enabled(ctx, m.userEmail)
+			cacheable = err == nil && !enabled
+		}
+	}
 	if hasBodyRequest && !hasEnvelopeRequest {
 		logger.Debug().
 			Str("user", m.userEmail).
@@ -1006,6 +906,9 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 			continue
 		}
 
+		// 邮件内容在请求时才读取，每封邮件处理完后不保留
+		content := &mailContent{m: m, mail: mail, cacheable: cacheable}
+
 		msg := &imap.Message{
 			SeqNum: seqNum,
 			Items:  make(map[imap.FetchItem]interface{}),
@@ -1322,28 +1225,14 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 					Str("mail_id", mail.ID).
 					Uint32("uid", msg.Uid).
 					Msg("IMAP ListMessages: 填充 Uid")
+			case fetchModSeq:
+				// CONDSTORE：MODSEQ (n)
+				msg.Items[item] = []interface{}{imap.RawString(strconv.FormatUint(mail.ModSeq, 10))}
 			case imap.FetchBody, imap.FetchBodyStructure:
 				// go-imap 库从 msg.BodyStructure 字段读取，需要初始化
-				if msg.BodyStructure == nil {
-					// 从邮件头中解析 Content-Type 以确定 MIME 类型
-					var bodyData []byte
-					if m.maildir != nil {
-						body, err := m.maildir.ReadMail(m.userEmail, m.name, mail.ID)
-						if err == nil {
-							bodyData = body
-						} else if len(mail.Body) > 0 {
-							bodyData = mail.Body
-						}
-					} else if len(mail.Body) > 0 {
-						bodyData = mail.Body
-					}
-
-					// 解析 Content-Type
-					mimeType, mimeSubType := "text", "plain"
-					if len(bodyData) > 0 {
-						mimeType, mimeSubType = parseContentType(bodyData)
-					}
-
+				// BODY 和 BODYSTRUCTURE 的扩展字段不同（包括嵌套的部分），不一致时重新生成
+				extended := item == imap.FetchBodyStructure
+				if msg.BodyStructure == nil || msg.BodyStructure.Extended != extended {
 					// #nosec G115 -- 检查溢出，如果超过 uint32 最大值则使用最大值
 					var size uint32
 					if mail.Size > 0 && mail.Size <= int64(^uint32(0)) {
@@ -1351,13 +1240,9 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 					} else if mail.Size > int64(^uint32(0)) {
 						size = ^uint32(0)
 					}
-					msg.BodyStructure = &imap.BodyStructure{
-						MIMEType:    mimeType,
-						MIMESubType: mimeSubType,
-						Size:        size,
-					}
+					// 从实际的 MIME 树生成（多部分邮件、HTML 正文和附件），解析失败时退回 text/plain
+					msg.BodyStructure = content.bodyStructure(size, extended)
 				}
-				msg.BodyStructure.Extended = item == imap.FetchBodyStructure
 				msg.Items[item] = msg.BodyStructure
 				logger.Debug().
 					Str("user", m.userEmail).
@@ -1367,163 +1252,14 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 					Str("mime_type", msg.BodyStructure.MIMEType).
 					Str("mime_subtype", msg.BodyStructure.MIMESubType).
 					Msg("IMAP ListMessages: 填充 BodyStructure")
-			case imap.FetchRFC822, imap.FetchRFC822Text:
-				// 从 Maildir 读取邮件体
-				if m.maildir != nil {
-					body, err := m.maildir.ReadMail(m.userEmail, m.name, mail.ID)
-					if err == nil {
-						msg.Items[item] = body
-
-						// 根据 IMAP 规范，如果客户端使用 FETCH（不是 PEEK）获取邮件体，自动设置 \Seen 标志
-						// FetchRFC822 不是 PEEK，所以需要设置 \Seen
-						hasSeen := false
-						hasRecent := false
-						for _, flag := range mail.Flags {
-							if flag == imap.SeenFlag {
-								hasSeen = true
-							}
-							if flag == imap.RecentFlag {
-								hasRecent = true
-							}
-						}
-						if !hasSeen {
-							// 自动设置 \Seen 标志
-							ctx := context.Background()
-							newFlags := append(mail.Flags, imap.SeenFlag)
-							// 移除 \Recent 标志（如果存在）
-							if hasRecent {
-								flagMap := make(map[string]bool)
-								for _, f := range newFlags {
-									if f != imap.RecentFlag {
-										flagMap[f] = true
-									}
-								}
-								newFlags = make([]string, 0, len(flagMap))
-								for f := range flagMap {
-									newFlags = append(newFlags, f)
-								}
-							}
-							if err := m.updateMailFlagsAndMove(ctx, mail, newFlags); err != nil {
-								logger.Warn().Err(err).Str("mail_id", mail.ID).Msg("自动设置 \\Seen 标志失败")
-							} else {
-								logger.Debug().
-									Str("user", m.userEmail).
-									Str("folder", m.name).
-									Str("mail_id", mail.ID).
-									Msg("IMAP ListMessages: 自动设置 \\Seen 标志（FetchRFC822）")
-							}
-						} else if hasRecent {
-							// 如果邮件已经有 \Seen 标志，但还有 \Recent 标志，移除 \Recent 标志
-							ctx := context.Background()
-							flagMap := make(map[string]bool)
-							for _, f := range mail.Flags {
-								if f != imap.RecentFlag {
-									flagMap[f] = true
-								}
-							}
-							newFlags := make([]string, 0, len(flagMap))
-							for f := range flagMap {
-								newFlags = append(newFlags, f)
-							}
-							if err := m.storage.UpdateMailFlags(ctx, mail.ID, newFlags); err != nil {
-								logger.Warn().Err(err).Str("mail_id", mail.ID).Msg("移除 \\Recent 标志失败")
-							} else {
-								mail.Flags = newFlags
-							}
-						}
-
-						logger.Debug().
-							Str("user", m.userEmail).
-							Str("folder", m.name).
-							Str("mail_id", mail.ID).
-							Int("body_size", len(body)).
-							Str("item", string(item)).
-							Msg("IMAP ListMessages: 从 Maildir 读取邮件体成功")
-					} else {
-						logger.Warn().Err(err).Str("mail_id", mail.ID).Str("item", string(item)).Msg("读取邮件体失败")
-						// 如果读取失败，尝试使用数据库中的 Body 字段（如果有）
-						if len(mail.Body) > 0 {
-							msg.Items[item] = mail.Body
-							logger.Debug().
-								Str("user", m.userEmail).
-								Str("folder", m.name).
-								Str("mail_id", mail.ID).
-								Int("body_size", len(mail.Body)).
-								Msg("IMAP ListMessages: 使用数据库中的邮件体")
-						}
-					}
-				} else if len(mail.Body) > 0 {
-					// 如果没有 Maildir，使用数据库中的 Body 字段
-					msg.Items[item] = mail.Body
-					logger.Debug().
-						Str("user", m.userEmail).
-						Str("folder", m.name).
-						Str("mail_id", mail.ID).
-						Int("body_size", len(mail.Body)).
-						Msg("IMAP ListMessages: 使用数据库中的邮件体（无 Maildir）")
-				} else {
-					logger.Warn().
-						Str("user", m.userEmail).
-						Str("folder", m.name).
-						Str("mail_id", mail.ID).
-						Str("item", string(item)).
-						Msg("IMAP ListMessages: 无法获取邮件体（Maildir 为空且数据库 Body 为空）")
-				}
 			default:
-				// 尝试解析为 BodySectionName（如 BODY.PEEK[1], BODY[1] 等）
+				// 尝试解析为 BodySectionName（如 BODY.PEEK[1], BODY[1] 等，RFC822、RFC822.HEADER 和 RFC822.TEXT
+				// 分别解析为 BODY[]、BODY.PEEK[HEADER] 和 BODY[TEXT]）
 				section, err := imap.ParseBodySectionName(imap.FetchItem(item))
 				if err == nil {
-					// 从 Maildir 读取邮件体
-					var bodyData []byte
-					if m.maildir != nil {
-						body, err := m.maildir.ReadMail(m.userEmail, m.name, mail.ID)
-						if err == nil {
-							bodyData = body
-						} else {
-							logger.Warn().Err(err).Str("mail_id", mail.ID).Str("item", string(item)).Msg("读取邮件体失败")
-							if len(mail.Body) > 0 {
-								bodyData = mail.Body
-							}
-						}
-					} else if len(mail.Body) > 0 {
-						bodyData = mail.Body
-					}
-
-					if len(bodyData) > 0 {
-						// 根据 section 提取相应的部分
-						// 如果 section.Specifier 为空，返回整个邮件体
-						// 如果 section.Specifier 为 "TEXT"，返回邮件正文
-						// 如果 section.Specifier 为 "HEADER"，返回邮件头
-						var literalData []byte
-						if section.Specifier == "" {
-							// BODY[1] 或 BODY.PEEK[1] - 返回整个邮件体
-							literalData = bodyData
-						} else if section.Specifier == "TEXT" {
-							// BODY[1.TEXT] - 返回邮件正文（不包括头）
-							// 查找第一个空行（分隔头和正文）
-							if idx := bytes.Index(bodyData, []byte("\r\n\r\n")); idx >= 0 {
-								literalData = bodyData[idx+4:]
-							} else if idx := bytes.Index(bodyData, []byte("\n\n")); idx >= 0 {
-								literalData = bodyData[idx+2:]
-							} else {
-								literalData = bodyData
-							}
-						} else if section.Specifier == "HEADER" {
-							// BODY[1.HEADER] - 返回邮件头
-							if idx := bytes.Index(bodyData, []byte("\r\n\r\n")); idx >= 0 {
-								literalData = bodyData[:idx+2]
-							} else if idx := bytes.Index(bodyData, []byte("\n\n")); idx >= 0 {
-								literalData = bodyData[:idx+1]
-							} else {
-								literalData = bodyData
-							}
-						} else {
-							// 其他情况，返回整个邮件体
-							literalData = bodyData
-						}
-
-						// 创建 Literal 并存储到 msg.Body
-						literal := bytes.NewReader(literalData)
+					// 根据 section 读取相应的部分（BODY[2]、BODY[1.MIME]、HEADER.FIELDS 等）
+					literal, err := content.section(section)
+					if err == nil {
 						msg.Body[section] = literal
 						msg.Items[item] = literal
 
@@ -1622,15 +1358,16 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 							Str("item", string(item)).
 							Str("specifier", string(section.Specifier)).
 							Bool("peek", section.Peek).
-							Int("body_size", len(literalData)).
+							Int("body_size", literal.Len()).
 							Msg("IMAP ListMessages: 填充 BodySection")
 					} else {
 						logger.Warn().
+							Err(err).
 							Str("user", m.userEmail).
 							Str("folder", m.name).
 							Str("mail_id", mail.ID).
 							Str("item", string(item)).
-							Msg("IMAP ListMessages: 无法获取邮件体（Maildir 为空且数据库 Body 为空）")
+							Msg("IMAP ListMessages: 读取邮件体失败")
 					}
 				} else {
 					logger.Debug().
@@ -1675,6 +1412,7 @@ func (m *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fetch
 
 // SearchMessages 搜索邮件
 func (m *Mailbox) SearchMessages(uid bool, criteria *imap.SearchCriteria) ([]uint32, error) {
+	m.sync()
 	var results []uint32
 
 	for i, mail := range m.mails {
@@ -1895,7 +1633,9 @@ func contains(s, substr string) bool {
 	return strings.Contains(sLower, substrLower)
 }
 
-// CreateMessage 创建邮件（用于 IMAP APPEND 命令，发送邮件）
+// CreateMessage 创建邮件（用于 IMAP APPEND 命令）：邮件保存到当前邮箱，保留客户端指定的标志和内部日期
+// （客户端保存草稿、保存已发送邮件或在邮箱之间复制邮件）。只有当前邮箱是配置的提交文件夹时，
+// 才把邮件投递给本地收件人
 func (m *Mailbox) CreateMessage(flags []string, date time.Time, body imap.Literal) error {
 	ctx := context.Background()
 
@@ -1915,65 +1655,48 @@ func (m *Mailbox) CreateMessage(flags []string, date time.Time, body imap.Litera
 		}
 	}
 
-	// 解析邮件头
-	msg, err := message.Read(bytes.NewReader(bodyData))
+	// 解析邮件（地址、主题和正文都已解码）
+	msg, err := mailparse.Parse(bodyData)
 	if err != nil {
 		return fmt.Errorf("解析邮件失败: %w", err)
 	}
 
-	header := msg.Header
-	from := header.Get("From")
-	toStr := header.Get("To")
-	ccStr := header.Get("Cc")
-	bccStr := header.Get("Bcc")
-	subject := header.Get("Subject")
+	from := mailparse.FormatAddress(msg.From)
+	to := mailparse.Emails(msg.To)
+	cc := mailparse.Emails(msg.Cc)
+	bcc := mailparse.Emails(msg.Bcc)
+	subject := msg.Subject
 
-	// 解析收件人列表
-	var to, cc, bcc []string
-	if toStr != "" {
-		to = parseAddressList(toStr)
-	}
-	if ccStr != "" {
-		cc = parseAddressList(ccStr)
-	}
-	if bccStr != "" {
-		bcc = parseAddressList(bccStr)
-	}
-
-	// 读取邮件正文
-	bodyText := ""
-	if msg.Body != nil {
-		bodyBytes, err := io.ReadAll(msg.Body)
-		if err == nil {
-			bodyText = string(bodyBytes)
-		}
+	// 邮件正文（没有纯文本时使用 HTML）
+	bodyText := msg.Text
+	if bodyText == "" {
+		bodyText = msg.HTML
 	}
 
-	// 确定文件夹（Sent 或当前文件夹）
 	folder := m.name
-	if folder == "INBOX" {
-		folder = "Sent" // 如果从 INBOX 发送，存储到 Sent
-	}
 
-	// 存储到 Maildir
-	var mailID string
-	if m.maildir != nil {
-		if err := m.maildir.EnsureUserMaildir(m.userEmail); err != nil {
-			return fmt.Errorf("创建用户 Maildir 失败: %w", err)
+	// 没有指定内部日期时使用当前时间（RFC 3501 6.3.11）
+	if date.IsZero() {
+		date = time.Now()
+	}
+	// \Recent 由服务器设置，APPEND 的邮件总是带有 \Recent（RFC 3501 6.3.11），客户端指定的标志原样保存
+	mailFlags := []string{imap.RecentFlag}
+	draft := false
+	for _, flag := range flags {
+		if strings.EqualFold(flag, imap.RecentFlag) || slices.Contains(mailFlags, flag) {
+			continue
 		}
-		filename, err := m.maildir.StoreMail(m.userEmail, folder, bodyData)
-		if err != nil {
-			return fmt.Errorf("存储邮件到 Maildir 失败: %w", err)
+		if flag == imap.DraftFlag {
+			draft = true
 		}
-		mailID = filename
-	} else {
-		// 如果没有 Maildir，使用时间戳作为 ID
-		mailID = fmt.Sprintf("%s-%d", folder, time.Now().UnixNano())
+		mailFlags = append(mailFlags, flag)
 	}
 
-	// 存储邮件元数据到数据库
+	// Maildir 文件和数据库记录一起写入，文件名作为邮件 ID
+	// （如果没有 Maildir，使用时间戳作为 ID）
+	mailStore := storage.NewMailStore(m.maildir, m.storage)
 	mail := &storage.Mail{
-		ID:         mailID,
+		ID:         fmt.Sprintf("%s-%d", folder, time.Now().UnixNano()),
 		UserEmail:  m.userEmail,
 		Folder:     folder,
 		From:       from,
@@ -1983,58 +1706,73 @@ func (m *Mailbox) CreateMessage(flags []string, date time.Time, body imap.Litera
 		Subject:    subject,
 		Body:       []byte(bodyText),
 		Size:       int64(len(bodyData)),
-		Flags:      flags,
-		ReceivedAt: date,
+		Flags:      mailFlags,
+		ReceivedAt: date.UTC(),
 		CreatedAt:  time.Now(),
+
+		Attachments: search.ExtractAttachments(bodyData),
+	}
+
+	if err := mailStore.Deliver(ctx, mail, bodyData); err != nil {
+		return storageError(err)
+	}
+	// 已读的邮件与 STORE 相同，文件从 new 移动到 cur 并在文件名中记录标志
+	if m.maildir != nil && slices.Contains(mailFlags, imap.SeenFlag) {
+		if err := m.maildir.MoveToCur(m.userEmail, folder, mail.ID, mailFlags); err != nil {
+			logger.Warn().Err(err).Str("user", m.userEmail).Str("folder", folder).Str("mail_id", mail.ID).Msg("移动邮件从 new 到 cur 失败")
+		}
 	}
 
-	if err := m.storage.StoreMail(ctx, mail); err != nil {
-		return fmt.Errorf("存储邮件元数据失败: %w", err)
+	// SMTP 提交后客户端保存到 Sent 的副本：补记已投递给本地收件人的回执
+	if folder == "Sent" && folder != m.submission && !draft {
+		receipts.New(m.storage).Saved(ctx, mail)
 	}
 
-	// 如果是发送邮件（Sent 文件夹），需要投递到收件人
-	if folder == "Sent" {
+	// 提交文件夹中的邮件投递给本地收件人（草稿除外）
+	if m.submission != "" && folder == m.submission && !draft {
 		// 收集所有收件人
 		allRecipients := make([]string, 0)
 		allRecipients = append(allRecipients, to...)
 		allRecipients = append(allRecipients, cc...)
 		allRecipients = append(allRecipients, bcc...)
 
-		// 投递到本地收件人
+		// 投递到本地收件人：别名投递到全部本地目标，外部目标经 SRS 重写后转发（与 SMTP 投递相同）
 		for _, recipient := range allRecipients {
-			user, err := m.storage.GetUser(ctx, recipient)
-			if err != nil {
-				// 检查别名
-				alias, err := m.storage.GetAlias(ctx, recipient)
-				if err != nil {
-					continue // 不是本地用户，跳过
-				}
-				user, err = m.storage.GetUser(ctx, alias.To)
+			mailboxes := []string{recipient}
+			if _, err := m.storage.GetUser(ctx, recipient); err != nil {
+				local, external, ok := forward.ResolveAlias(ctx, m.storage, recipient)
+				if !ok {
+					continue // 不是本地用户（或别名已失效），跳过
+				}
+				mailboxes = local
+				m.forwarder.ForwardAlias(ctx, recipient, m.userEmail, external, bodyData)
+			}
+
+			for _, mailbox := range mailboxes {
+				user, err := m.storage.GetUser(ctx, mailbox)
 				if err != nil {
 					continue // 别名目标不存在，跳过
 				}
-			}
 
-			// 投递到收件人的 INBOX
-			if m.maildir != nil {
-				if err := m.maildir.EnsureUserMaildir(user.Email); err == nil {
-					filename, err := m.maildir.StoreMail(user.Email, "INBOX", bodyData)
-					if err == nil {
-						inboxMail := &storage.Mail{
-							ID:         filename,
-							UserEmail:  user.Email,
-							Folder:     "INBOX",
-							From:       from,
-							To:         []string{recipient},
-							Cc:         cc,
-							Bcc:        bcc,
-							Subject:    subject,
-							Size:       int64(len(bodyData)),
-							Flags:      []string{"\\Recent"}, // 新邮件设置 \Recent 标志
-							ReceivedAt: time.Now(),
-							CreatedAt:  time.Now(),
-						}
-						_ = m.storage.StoreMail(ctx, inboxMail) // 忽略错误，继续投递其他收件人
+				// 投递到收件人的 INBOX
+				if m.maildir != nil {
+					inboxMail := &storage.Mail{
+						UserEmail:  user.Email,
+						Folder:     "INBOX",
+						From:       from,
+						To:         []string{recipient},
+						Cc:         cc,
+						Bcc:        bcc,
+						Subject:    subject,
+						Size:       int64(len(bodyData)),
+						Flags:      []string{"\\Recent"}, // 新邮件设置 \Recent 标志
+						ReceivedAt: time.Now(),
+						CreatedAt:  time.Now(),
+
+						Attachments: mail.Attachments,
+					}
+					if err := mailStore.Deliver(ctx, inboxMail, bodyData); err == nil { // 忽略错误，继续投递其他收件人
+						receipts.New(m.storage).Delivered(ctx, mail, inboxMail)
 					}
 				}
 			}
@@ -2045,33 +1783,12 @@ func (m *Mailbox) CreateMessage(flags []string, date time.Time, body imap.Litera
 		Str("user", m.userEmail).
 		Str("folder", folder).
 		Str("from", from).
+		Strs("flags", mailFlags).
 		Msg("IMAP 创建邮件成功")
 
 	return nil
 }
 
-// parseAddressList 解析地址列表（简化实现）
-func parseAddressList(addrList string) []string {
-	// 简单的解析：按逗号分割
-	addresses := strings.Split(addrList, ",")
-	result := make([]string, 0, len(addresses))
-	for _, addr := range addresses {
-		addr = strings.TrimSpace(addr)
-		// 提取邮箱地址（去除显示名称）
-		if idx := strings.LastIndex(addr, "<"); idx >= 0 {
-			addr = addr[idx+1:]
-			if idx := strings.Index(addr, ">"); idx >= 0 {
-				addr = addr[:idx]
-			}
-		}
-		addr = strings.TrimSpace(addr)
-		if addr != "" {
-			result = append(result, addr)
-		}
-	}
-	return result
-}
-
 // AddFlags 添加标志
 func (m *Mailbox) AddFlags(uid bool, seqSet *imap.SeqSet, flags []string) error {
 	return m.UpdateMessagesFlags(uid, seqSet, imap.AddFlags, flags)
@@ -2090,6 +1807,7 @@ func (m *Mailbox) StoreFlags(uid bool, seqSet *imap.SeqSet, flags []string, op i
 // UpdateMessagesFlags 更新消息标志
 func (m *Mailbox) UpdateMessagesFlags(uid bool, seqSet *imap.SeqSet, op imap.FlagsOp, flags []string) error {
 	ctx := context.Background()
+	m.sync()
 
 	logger.Debug().
 		Str("user", m.userEmail).
@@ -2181,12 +1899,16 @@ func (m *Mailbox) UpdateMessagesFlags(uid bool, seqSet *imap.SeqSet, op imap.Fla
 // CopyMessages 复制邮件到目标邮箱
 func (m *Mailbox) CopyMessages(uid bool, seqSet *imap.SeqSet, dest string) error {
 	ctx := context.Background()
+	m.sync()
+	// 复制需要解密后用目标邮件的新密钥重新加密
+	if err := m.checkUnlocked(ctx); err != nil {
+		return err
+	}
 
-	// 获取目标邮箱的邮件列表
-	destMails, err := m.storage.ListMails(ctx, m.userEmail, dest, 1000, 0)
+	// 目标邮箱的邮件数（用于生成新邮件 ID，目标邮箱不存在时为 0）
+	destCount, err := m.storage.CountMails(ctx, m.userEmail, dest)
 	if err != nil {
-		// 如果目标邮箱不存在，创建空列表
-		destMails = []*storage.Mail{}
+		destCount = 0
 	}
 
 	// 复制选中的邮件
@@ -2233,26 +1955,160 @@ func (m *Mailbox) CopyMessages(uid bool, seqSet *imap.SeqSet, dest string) error
 			Flags:      []string{}, // 新邮件没有标志
 			ReceivedAt: mail.ReceivedAt,
 			CreatedAt:  time.Now(),
+
+			HasAttachment: mail.HasAttachment,
+
+			// 副本属于同一会话
+			MessageID:  mail.MessageID,
+			InReplyTo:  mail.InReplyTo,
+			References: mail.References,
+			ThreadID:   mail.ThreadID,
 		}
 
-		// 生成新 ID
-		newMail.ID = fmt.Sprintf("%s-%d", dest, len(destMails)+1)
+		// 生成新 ID（有 Maildir 文件时由 MailStore 使用新的文件名作为 ID）
+		newMail.ID = fmt.Sprintf("%s-%d-%d", dest, time.Now().UnixNano(), destCount+1)
 
 		// 为新邮件分配 UID（StoreMail 会自动分配，但这里显式设置为 0 以确保自动分配）
 		newMail.UID = 0
 
-		// 存储到目标邮箱（StoreMail 会自动分配新的 UID）
-		if err := m.storage.StoreMail(ctx, newMail); err != nil {
-			return fmt.Errorf("复制邮件失败: %w", err)
+		// 附件元数据随邮件一起复制
+		if attachments, err := m.storage.ListMailAttachments(ctx, mail.ID); err == nil {
+			newMail.Attachments = attachments
+		}
+
+		// 存储到目标邮箱（有邮件文件时文件和数据库记录一起复制，如网页端保存的草稿只有数据库记录；
+		// StoreMail 会自动分配新的 UID）
+		var data []byte
+		if m.maildir != nil {
+			data, _ = m.maildir.ReadMail(m.userEmail, m.name, mail.ID)
+		}
+		if data != nil {
+			err = storage.NewMailStore(m.maildir, m.storage).Deliver(ctx, newMail, data)
+		} else {
+			err = m.storage.StoreMail(ctx, newMail)
+		}
+		if err != nil {
+			return storageError(fmt.Errorf("复制邮件失败: %w", err))
+		}
+		if m.complaint(dest) && data != nil {
+			m.spam.ReportComplaint(ctx, m.userEmail, data)
 		}
+		if spam, ok := m.training(dest); ok && data != nil {
+			m.trainer.Learn(m.userEmail, data, spam)
+		}
+		destCount++
 	}
 
 	return nil
 }
 
+// MoveMessages 将邮件移动到目标邮箱（RFC 6851），Maildir 文件和数据库记录一起移动，
+// 返回已移动邮件移动前的序列号（升序）
+func (m *Mailbox) MoveMessages(uid bool, seqSet *imap.SeqSet, dest string) ([]uint32, error) {
+	ctx := context.Background()
+	if strings.EqualFold(dest, "INBOX") {
+		dest = "INBOX"
+	}
+	if dest == m.name {
+		return nil, errors.New("不能移动到当前邮箱")
+	}
+
+	var seqNums []uint32
+	movedIDs := make(map[string]bool)
+	var moveErr error
+	for _, msg := range m.selectMessages(uid, seqSet) {
+		mail := msg.mail
+		baseID := mail.ID
+		if idx := strings.Index(mail.ID, ":"); idx >= 0 {
+			baseID = mail.ID[:idx]
+		}
+
+		// 移入或移出垃圾邮件文件夹时在移动前读取原文，用于记录投诉和训练垃圾邮件分类器
+		var raw []byte
+		spam, train := m.training(dest)
+		if (m.complaint(dest) || train) && m.maildir != nil {
+			raw, _ = m.maildir.ReadMail(m.userEmail, m.name, mail.ID)
+		}
+
+		// 先移动文件，数据库更新失败时移回；没有文件的邮件（如 COPY 产生的副本）只移动记录
+		fileMoved := false
+		if m.maildir != nil {
+			err := m.maildir.MoveMail(m.userEmail, m.name, dest, baseID)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				moveErr = err
+				break
+			}
+			fileMoved = err == nil
+		}
+
+		if _, err := m.storage.MoveMail(ctx, mail.ID, dest); err != nil {
+			if fileMoved {
+				if rollbackErr := m.maildir.MoveMail(m.userEmail, dest, m.name, baseID); rollbackErr != nil {
+					logger.Warn().Err(rollbackErr).
+						Str("user", m.userEmail).
+						Str("mail_id", mail.ID).
+						Msg("移动邮件失败后恢复邮件文件失败")
+				}
+			}
+			moveErr = fmt.Errorf("移动邮件失败: %w", err)
+			break
+		}
+
+		seqNums = append(seqNums, msg.seqNum)
+		movedIDs[mail.ID] = true
+		if raw != nil && m.complaint(dest) {
+			m.spam.ReportComplaint(ctx, m.userEmail, raw)
+		}
+		if raw != nil && train {
+			m.trainer.Learn(m.userEmail, raw, spam)
+		}
+	}
+
+	// 从内存中移除已移动的邮件
+	if len(movedIDs) > 0 {
+		remaining := m.mails[:0]
+		for _, mail := range m.mails {
+			if !movedIDs[mail.ID] {
+				remaining = append(remaining, mail)
+			}
+		}
+		m.mails = remaining
+
+		logger.Debug().
+			Str("user", m.userEmail).
+			Str("folder", m.name).
+			Str("dest", dest).
+			Int("count", len(movedIDs)).
+			Msg("IMAP MOVE: 邮件已移动")
+	}
+	return seqNums, moveErr
+}
+
+// complaint 邮件从其他文件夹移入或复制到垃圾邮件文件夹时视为用户投诉
+func (m *Mailbox) complaint(dest string) bool {
+	return m.spam != nil && strings.EqualFold(dest, "Spam") && !strings.EqualFold(m.name, "Spam")
+}
+
+// training 复制或移动到 dest 时是否训练垃圾邮件分类器：移入垃圾邮件文件夹时作为垃圾邮件，
+// 从垃圾邮件文件夹移到其他文件夹（已删除邮件除外）时作为正常邮件
+func (m *Mailbox) training(dest string) (spam, ok bool) {
+	if m.trainer == nil {
+		return false, false
+	}
+	toSpam, fromSpam := strings.EqualFold(dest, "Spam"), strings.EqualFold(m.name, "Spam")
+	switch {
+	case toSpam && !fromSpam:
+		return true, true
+	case fromSpam && !toSpam && !strings.EqualFold(dest, "Trash"):
+		return false, true
+	}
+	return false, false
+}
+
 // Expunge 删除邮件（标记为 \Deleted 的邮件）
 func (m *Mailbox) Expunge() error {
 	ctx := context.Background()
+	m.sync()
 
 	var toDelete []string
 	for _, mail := range m.mails {
@@ -2265,11 +2121,20 @@ func (m *Mailbox) Expunge() error {
 		}
 	}
 
-	// 删除邮件
+	// 删除邮件（同时删除 Maildir 文件，否则下次打开邮箱时会重新同步回来）
 	for _, id := range toDelete {
 		if err := m.storage.DeleteMail(ctx, id); err != nil {
 			return fmt.Errorf("删除邮件失败: %w", err)
 		}
+		if m.maildir != nil {
+			baseID := id
+			if idx := strings.Index(id, ":"); idx >= 0 {
+				baseID = id[:idx]
+			}
+			if err := m.maildir.DeleteMail(m.userEmail, m.name, baseID); err != nil && !errors.Is(err, os.ErrNotExist) {
+				logger.Warn().Err(err).Str("user", m.userEmail).Str("mail_id", id).Msg("删除邮件文件失败")
+			}
+		}
 	}
 
 	// 从内存中移除
@@ -2306,57 +2171,3 @@ func parseEmailAddress(email string) (mailbox, host string) {
 	host = email[idx+1:]
 	return mailbox, host
 }
-
-// parseContentType 从邮件头中解析 Content-Type，返回 MIME 类型和子类型
-// 如果解析失败，返回默认值 "text/plain"
-func parseContentType(bodyData []byte) (mimeType, mimeSubType string) {
-	// 默认值
-	mimeType = "text"
-	mimeSubType = "plain"
-
-	// 尝试使用 message.Read 解析邮件头
-	msg, err := message.Read(bytes.NewReader(bodyData))
-	if err == nil {
-		contentType := msg.Header.Get("Content-Type")
-		if contentType != "" {
-			// 解析 Content-Type，格式通常是 "text/plain" 或 "text/html; charset=UTF-8"
-			// 提取 MIME 类型和子类型
-			parts := strings.Split(contentType, ";")
-			if len(parts) > 0 {
-				mimeParts := strings.Split(strings.TrimSpace(parts[0]), "/")
-				if len(mimeParts) == 2 {
-					mimeType = strings.ToLower(strings.TrimSpace(mimeParts[0]))
-					mimeSubType = strings.ToLower(strings.TrimSpace(mimeParts[1]))
-				}
-			}
-		}
-		return
-	}
-
-	// 如果 message.Read 失败，尝试手动解析邮件头
-	bodyStr := string(bodyData)
-	lines := strings.Split(bodyStr, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(line), "content-type:") {
-			// 提取 Content-Type 值
-			contentType := strings.TrimSpace(line[len("content-type:"):])
-			// 解析 MIME 类型和子类型
-			parts := strings.Split(contentType, ";")
-			if len(parts) > 0 {
-				mimeParts := strings.Split(strings.TrimSpace(parts[0]), "/")
-				if len(mimeParts) == 2 {
-					mimeType = strings.ToLower(strings.TrimSpace(mimeParts[0]))
-					mimeSubType = strings.ToLower(strings.TrimSpace(mimeParts[1]))
-				}
-			}
-			break
-		}
-		// 如果遇到空行，说明邮件头结束
-		if line == "" {
-			break
-		}
-	}
-
-	return
-}
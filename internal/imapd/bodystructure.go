@@ -0,0 +1,70 @@
+package imapd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/backendutil"
+	"github.com/emersion/go-message/textproto"
+)
+
+// bodyStructure 从邮件原文的 MIME 树生成 BODYSTRUCTURE
+//
+// extended 为 true 时包含扩展字段（BODYSTRUCTURE），否则为基本结构（BODY）。
+// 解析失败时退回到 text/plain 单部分结构，保证客户端仍能获取整封邮件。
+func bodyStructure(raw []byte, size uint32, extended bool) *imap.BodyStructure {
+	if len(raw) == 0 {
+		return plainBodyStructure(size, extended)
+	}
+	return readBodyStructure(bufio.NewReader(bytes.NewReader(raw)), size, extended)
+}
+
+// readBodyStructure 与 bodyStructure 相同，从邮件文件流式读取（只统计各部分的大小和行数，不保留内容）
+func readBodyStructure(br *bufio.Reader, size uint32, extended bool) *imap.BodyStructure {
+	header, err := textproto.ReadHeader(br)
+	if err == nil {
+		bs, err := backendutil.FetchBodyStructure(header, br, extended)
+		if err == nil {
+			return bs
+		}
+	}
+	return plainBodyStructure(size, extended)
+}
+
+// plainBodyStructure 无法解析时使用的 text/plain 单部分结构
+func plainBodyStructure(size uint32, extended bool) *imap.BodyStructure {
+	return &imap.BodyStructure{
+		MIMEType:    "text",
+		MIMESubType: "plain",
+		Params:      map[string]string{"charset": "utf-8"},
+		Size:        size,
+		Extended:    extended,
+	}
+}
+
+// bodySection 从邮件原文中提取 BODY[section] 的内容
+//
+// 支持按部分编号寻址（如 BODY[2]、BODY[1.2]）、HEADER、HEADER.FIELDS、
+// HEADER.FIELDS.NOT、TEXT、MIME 以及 <offset.count> 部分读取。
+func bodySection(raw []byte, section *imap.BodySectionName) ([]byte, error) {
+	return readBodySection(bufio.NewReader(bytes.NewReader(raw)), section)
+}
+
+// readBodySection 与 bodySection 相同，从邮件文件流式读取（只保留请求的部分）
+func readBodySection(br *bufio.Reader, section *imap.BodySectionName) ([]byte, error) {
+	header, err := textproto.ReadHeader(br)
+	if err != nil {
+		return nil, fmt.Errorf("解析邮件头失败: %w", err)
+	}
+	literal, err := backendutil.FetchBodySection(header, br, section)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(literal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package imapd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap"
+)
+
+const multipartMail = "From: alice@example.com\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: report\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"alt\"\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"\r\n" +
+	"hello\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>hello</p>\r\n" +
+	"--alt--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"report.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERg==\r\n" +
+	"--outer--\r\n"
+
+func TestBodyStructure(t *testing.T) {
+	bs := bodyStructure([]byte(multipartMail), uint32(len(multipartMail)), true)
+	if bs.MIMEType != "multipart" || bs.MIMESubType != "mixed" || len(bs.Parts) != 2 {
+		t.Fatalf("顶层结构不正确: %s/%s, %d 个部分", bs.MIMEType, bs.MIMESubType, len(bs.Parts))
+	}
+	alt := bs.Parts[0]
+	if alt.MIMESubType != "alternative" || len(alt.Parts) != 2 || alt.Parts[1].MIMESubType != "html" {
+		t.Errorf("multipart/alternative 结构不正确: %+v", alt)
+	}
+	att := bs.Parts[1]
+	if att.MIMEType != "application" || att.MIMESubType != "pdf" || att.Encoding != "base64" {
+		t.Errorf("附件结构不正确: %+v", att)
+	}
+	if att.Disposition != "attachment" || att.DispositionParams["filename"] != "report.pdf" {
+		t.Errorf("附件 Content-Disposition 不正确: %q %v", att.Disposition, att.DispositionParams)
+	}
+
+	// BODY 不包含扩展字段（包括嵌套的部分）
+	if bs := bodyStructure([]byte(multipartMail), 0, false); bs.Extended || bs.Parts[1].Extended {
+		t.Error("BODY 不应该包含扩展字段")
+	}
+
+	// 无法解析时退回 text/plain
+	if bs := bodyStructure(nil, 42, true); bs.MIMEType != "text" || bs.MIMESubType != "plain" || bs.Size != 42 {
+		t.Errorf("退回的结构不正确: %+v", bs)
+	}
+}
+
+func TestBodySection(t *testing.T) {
+	cases := map[string]string{
+		"BODY.PEEK[2]":                      "JVBERg==",
+		"BODY[1.2]":                         "<p>hello</p>",
+		"BODY[1.1]<0.3>":                    "hel",
+		"BODY[2.MIME]":                      "Content-Disposition: attachment; filename=\"report.pdf\"\r\n",
+		"BODY[HEADER.FIELDS (SUBJECT)]":     "Subject: report\r\n\r\n",
+		"BODY[HEADER.FIELDS.NOT (SUBJECT)]": "From: alice@example.com\r\n",
+		"BODY[TEXT]":                        "--outer\r\n",
+	}
+	for name, want := range cases {
+		section, err := imap.ParseBodySectionName(imap.FetchItem(name))
+		if err != nil {
+			t.Fatalf("解析 %s 失败: %v", name, err)
+		}
+		got, err := bodySection([]byte(multipartMail), section)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !strings.Contains(string(got), want) {
+			t.Errorf("%s = %q, 应该包含 %q", name, got, want)
+		}
+	}
+
+	// 不存在的部分
+	section, _ := imap.ParseBodySectionName("BODY[3]")
+	if _, err := bodySection([]byte(multipartMail), section); err == nil {
+		t.Error("不存在的部分应该返回错误")
+	}
+
+	// HEADER.FIELDS 只返回请求的字段
+	section, _ = imap.ParseBodySectionName("BODY[HEADER.FIELDS (SUBJECT)]")
+	if got, _ := bodySection([]byte(multipartMail), section); strings.Contains(string(got), "From:") {
+		t.Errorf("HEADER.FIELDS 不应该包含其他字段: %q", got)
+	}
+
+	// 单部分邮件的 BODY[1] 是正文本身
+	section, _ = imap.ParseBodySectionName("BODY[1]")
+	if got, err := bodySection([]byte("Subject: hi\r\n\r\nbody\r\n"), section); err != nil || string(got) != "body\r\n" {
+		t.Errorf("BODY[1] = %q, err = %v", got, err)
+	}
+}
@@ -0,0 +1,783 @@
+package imapd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/server"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// CONDSTORE 新增的 STATUS 和 FETCH 项
+const (
+	statusHighestModSeq imap.StatusItem = "HIGHESTMODSEQ"
+	fetchModSeq         imap.FetchItem  = "MODSEQ"
+)
+
+// CondStoreExtension IMAP CONDSTORE 和 QRESYNC 扩展（RFC 7162），客户端按修改序号只同步变化的部分：
+// FETCH (CHANGEDSINCE n) 只返回标志变化过的邮件，UID FETCH (CHANGEDSINCE n VANISHED) 和
+// SELECT (QRESYNC ...) 同时返回期间删除的 UID，不必每次重新下载整个邮箱的标志
+//
+// 修改序号保存在 mails.modseq 中，由数据库触发器在新增、标志变化和移动时递增；
+// 删除和移出的邮件记录在 mail_expunges 中
+type CondStoreExtension struct {
+	storage storage.Driver
+	next    []server.Extension // 同样覆盖了这些命令的扩展，非 CONDSTORE 部分交给它们处理
+}
+
+// NewCondStoreExtension 创建 CONDSTORE 扩展，next 为同样覆盖 SELECT/FETCH/STORE 的扩展
+// （需要在这些扩展之前启用），没有覆盖的命令交给内置实现处理
+func NewCondStoreExtension(driver storage.Driver, next ...server.Extension) *CondStoreExtension {
+	return &CondStoreExtension{storage: driver, next: next}
+}
+
+// Capabilities 返回扩展提供的能力
+func (ext *CondStoreExtension) Capabilities(c server.Conn) []string {
+	return []string{"CONDSTORE", "QRESYNC", "ENABLE"}
+}
+
+// Command 返回命令处理器
+func (ext *CondStoreExtension) Command(name string) server.HandlerFactory {
+	switch name {
+	case "ENABLE":
+		return func() server.Handler {
+			return &enableCommand{}
+		}
+	case "SELECT", "EXAMINE":
+		return func() server.Handler {
+			return &condstoreSelect{ext: ext, inner: ext.handler(name)}
+		}
+	case "FETCH":
+		return func() server.Handler {
+			return &condstoreFetch{ext: ext, inner: ext.handler(name)}
+		}
+	case "STORE":
+		return func() server.Handler {
+			return &condstoreStore{ext: ext, inner: ext.handler(name)}
+		}
+	case "SEARCH":
+		return func() server.Handler {
+			return &condstoreSearch{}
+		}
+	case "EXPUNGE":
+		return func() server.Handler {
+			return &condstoreExpunge{}
+		}
+	}
+	return nil
+}
+
+// handler 创建被覆盖的命令处理器：优先使用 next 中的扩展，否则使用内置实现
+func (ext *CondStoreExtension) handler(name string) server.Handler {
+	for _, next := range ext.next {
+		if factory := next.Command(name); factory != nil {
+			return factory()
+		}
+	}
+	switch name {
+	case "SELECT", "EXAMINE":
+		cmd := &server.Select{}
+		cmd.ReadOnly = name == "EXAMINE"
+		return cmd
+	case "FETCH":
+		return &server.Fetch{}
+	case "STORE":
+		return &server.Store{}
+	}
+	return nil
+}
+
+// connUser 返回连接登录的用户
+func connUser(conn server.Conn) (*User, error) {
+	u, ok := conn.Context().User.(*User)
+	if !ok || u == nil {
+		return nil, server.ErrNotAuthenticated
+	}
+	return u, nil
+}
+
+// handleCommand 执行命令处理器，uid 为 true 时执行 UID 版本
+func handleCommand(h server.Handler, uid bool, conn server.Conn) error {
+	if !uid {
+		return h.Handle(conn)
+	}
+	uh, ok := h.(server.UidHandler)
+	if !ok {
+		return errors.New("命令不支持 UID")
+	}
+	return uh.UidHandle(conn)
+}
+
+// enableCommand ENABLE（RFC 5161），启用 CONDSTORE 和 QRESYNC（QRESYNC 同时启用 CONDSTORE）
+type enableCommand struct {
+	caps []string
+}
+
+// Parse 解析命令参数
+func (cmd *enableCommand) Parse(fields []interface{}) error {
+	if len(fields) == 0 {
+		return errors.New("ENABLE 缺少参数")
+	}
+	for _, f := range fields {
+		name, ok := f.(string)
+		if !ok {
+			return errors.New("ENABLE 参数格式不正确")
+		}
+		cmd.caps = append(cmd.caps, strings.ToUpper(name))
+	}
+	return nil
+}
+
+// Handle 处理命令，不支持的扩展忽略，只在 ENABLED 响应中返回实际启用的扩展
+func (cmd *enableCommand) Handle(conn server.Conn) error {
+	u, err := connUser(conn)
+	if err != nil {
+		return err
+	}
+
+	fields := []interface{}{imap.RawString("ENABLED")}
+	for _, name := range cmd.caps {
+		switch name {
+		case "CONDSTORE":
+			u.condstore = true
+		case "QRESYNC":
+			u.condstore = true
+			u.qresync = true
+		default:
+			continue
+		}
+		fields = append(fields, imap.RawString(name))
+	}
+	return conn.WriteResp(&imap.DataResp{Fields: fields})
+}
+
+// qresyncParams SELECT (QRESYNC (uidvalidity modseq [known-uids])) 的参数
+type qresyncParams struct {
+	uidValidity uint32
+	modSeq      uint64
+	knownUIDs   *imap.SeqSet // 客户端已知的 UID，为 nil 时表示全部
+}
+
+// condstoreSelect SELECT/EXAMINE，选择成功后返回 HIGHESTMODSEQ，
+// 带 QRESYNC 参数时同时返回客户端上次同步以来删除的 UID 和变化的邮件
+type condstoreSelect struct {
+	ext       *CondStoreExtension
+	inner     server.Handler
+	condstore bool
+	qresync   *qresyncParams
+}
+
+// Parse 解析命令参数，邮箱名称交给被覆盖的 SELECT 解析
+func (cmd *condstoreSelect) Parse(fields []interface{}) error {
+	if len(fields) > 1 {
+		params, ok := fields[1].([]interface{})
+		if !ok {
+			return errors.New("SELECT 参数格式不正确")
+		}
+		for i := 0; i < len(params); i++ {
+			name, _ := params[i].(string)
+			switch strings.ToUpper(name) {
+			case "CONDSTORE":
+				cmd.condstore = true
+			case "QRESYNC":
+				if i+1 >= len(params) {
+					return errors.New("QRESYNC 缺少参数")
+				}
+				qresync, err := parseQresyncParams(params[i+1])
+				if err != nil {
+					return err
+				}
+				cmd.qresync = qresync
+				i++
+			default:
+				return fmt.Errorf("不支持的 SELECT 参数: %v", params[i])
+			}
+		}
+	}
+	return cmd.inner.Parse(fields[:min(len(fields), 1)])
+}
+
+// parseQresyncParams 解析 (uidvalidity modseq [known-uids [seq-match-data]])，seq-match-data 忽略
+func parseQresyncParams(f interface{}) (*qresyncParams, error) {
+	list, ok := f.([]interface{})
+	if !ok || len(list) < 2 {
+		return nil, errors.New("QRESYNC 参数格式不正确")
+	}
+	uidValidity, err := imap.ParseNumber(list[0])
+	if err != nil {
+		return nil, fmt.Errorf("QRESYNC UIDVALIDITY 无效: %w", err)
+	}
+	modSeq, err := parseModSeq(list[1])
+	if err != nil {
+		return nil, err
+	}
+	params := &qresyncParams{uidValidity: uidValidity, modSeq: modSeq}
+	if len(list) > 2 {
+		if s, ok := list[2].(string); ok {
+			if params.knownUIDs, err = imap.ParseSeqSet(s); err != nil {
+				return nil, fmt.Errorf("QRESYNC 已知 UID 无效: %w", err)
+			}
+		}
+	}
+	return params, nil
+}
+
+// parseModSeq 解析修改序号
+func parseModSeq(f interface{}) (uint64, error) {
+	s, ok := f.(string)
+	if !ok {
+		return 0, errors.New("修改序号格式不正确")
+	}
+	modSeq, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("修改序号无效: %s", s)
+	}
+	return modSeq, nil
+}
+
+// Handle 处理命令
+func (cmd *condstoreSelect) Handle(conn server.Conn) error {
+	u, err := connUser(conn)
+	if err != nil {
+		return err
+	}
+	if cmd.qresync != nil && !u.qresync {
+		return errors.New("需要先 ENABLE QRESYNC")
+	}
+
+	err = cmd.inner.Handle(conn)
+	var statusErr *imap.ErrStatusResp
+	if !errors.As(err, &statusErr) || statusErr.Resp == nil || statusErr.Resp.Type != imap.StatusRespOk {
+		return err
+	}
+	if cmd.condstore {
+		u.condstore = true
+	}
+
+	mbox, selErr := selectedMailbox(conn)
+	if selErr != nil {
+		return selErr
+	}
+	ctx := context.Background()
+	highest, hErr := cmd.ext.storage.GetHighestModSeq(ctx, mbox.userEmail, mbox.name)
+	if hErr != nil {
+		return hErr
+	}
+	if writeErr := conn.WriteResp(&imap.StatusResp{
+		Type:      imap.StatusRespOk,
+		Code:      "HIGHESTMODSEQ",
+		Arguments: []interface{}{imap.RawString(strconv.FormatUint(highest, 10))},
+		Info:      "Highest",
+	}); writeErr != nil {
+		return writeErr
+	}
+
+	if cmd.qresync != nil {
+		if rErr := cmd.resync(ctx, conn, mbox); rErr != nil {
+			return rErr
+		}
+	}
+	return err
+}
+
+// resync 快速重新同步：UIDVALIDITY 未变化时返回 VANISHED (EARLIER) 和修改序号之后变化的邮件
+func (cmd *condstoreSelect) resync(ctx context.Context, conn server.Conn, mbox *Mailbox) error {
+	uids, err := cmd.ext.storage.GetMailboxUIDs(ctx, mbox.userEmail, mbox.name)
+	if err != nil {
+		return err
+	}
+	if uids.UIDValidity != cmd.qresync.uidValidity {
+		return nil
+	}
+
+	if err := writeVanished(ctx, conn, cmd.ext.storage, mbox, cmd.qresync.modSeq, cmd.qresync.knownUIDs); err != nil {
+		return err
+	}
+	for _, msg := range mbox.selectMessages(true, cmd.qresync.knownUIDs) {
+		if msg.mail.ModSeq <= cmd.qresync.modSeq {
+			continue
+		}
+		if err := writeModSeqFetch(conn, msg, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeVanished 返回修改序号 since 之后删除的、在 uidSet 中（为 nil 时不限）的 UID
+func writeVanished(ctx context.Context, conn server.Conn, driver storage.Driver, mbox *Mailbox, since uint64, uidSet *imap.SeqSet) error {
+	expunged, err := driver.ListExpungedUIDs(ctx, mbox.userEmail, mbox.name, since)
+	if err != nil {
+		return err
+	}
+	var vanished imap.SeqSet
+	for _, uid := range expunged {
+		if uidSet == nil || uidSet.Contains(uid) {
+			vanished.AddNum(uid)
+		}
+	}
+	if vanished.Empty() {
+		return nil
+	}
+	return conn.WriteResp(&imap.DataResp{Fields: []interface{}{
+		imap.RawString("VANISHED"),
+		[]interface{}{imap.RawString("EARLIER")},
+		imap.RawString(vanished.String()),
+	}})
+}
+
+// writeModSeqFetch 返回邮件的 FETCH (UID n FLAGS (...) MODSEQ (m)) 响应
+func writeModSeqFetch(conn server.Conn, msg selectedMessage, uid bool) error {
+	flags := make([]interface{}, len(msg.mail.Flags))
+	for i, flag := range msg.mail.Flags {
+		flags[i] = imap.RawString(flag)
+	}
+	var items []interface{}
+	if uid {
+		items = append(items, imap.RawString("UID"), msg.uid)
+	}
+	items = append(items,
+		imap.RawString("FLAGS"), flags,
+		imap.RawString("MODSEQ"), []interface{}{imap.RawString(strconv.FormatUint(msg.mail.ModSeq, 10))},
+	)
+	return conn.WriteResp(&imap.DataResp{
+		Fields: []interface{}{msg.seqNum, imap.RawString("FETCH"), items},
+	})
+}
+
+// condstoreFetch FETCH，支持 MODSEQ 项和 (CHANGEDSINCE n [VANISHED]) 修饰符
+type condstoreFetch struct {
+	ext          *CondStoreExtension
+	inner        server.Handler
+	fields       []interface{} // 去掉修饰符后的参数
+	modSeq       bool          // 请求了 MODSEQ 项
+	flags        bool          // 请求了 FLAGS 项
+	changedSince *uint64
+	vanished     bool
+}
+
+// Parse 解析命令参数
+func (cmd *condstoreFetch) Parse(fields []interface{}) error {
+	if len(fields) < 2 {
+		return errors.New("FETCH 参数数量不正确")
+	}
+	cmd.fields = fields[:2]
+	for _, item := range fetchItemNames(fields[1]) {
+		switch item {
+		case fetchModSeq:
+			cmd.modSeq = true
+		case imap.FetchFlags:
+			cmd.flags = true
+		}
+	}
+
+	if len(fields) > 2 {
+		modifiers, ok := fields[2].([]interface{})
+		if !ok {
+			return errors.New("FETCH 修饰符格式不正确")
+		}
+		for i := 0; i < len(modifiers); i++ {
+			name, _ := modifiers[i].(string)
+			switch strings.ToUpper(name) {
+			case "CHANGEDSINCE":
+				if i+1 >= len(modifiers) {
+					return errors.New("CHANGEDSINCE 缺少参数")
+				}
+				since, err := parseModSeq(modifiers[i+1])
+				if err != nil {
+					return err
+				}
+				cmd.changedSince = &since
+				i++
+			case "VANISHED":
+				cmd.vanished = true
+			default:
+				return fmt.Errorf("不支持的 FETCH 修饰符: %v", modifiers[i])
+			}
+		}
+		if cmd.vanished && cmd.changedSince == nil {
+			return errors.New("VANISHED 必须与 CHANGEDSINCE 一起使用")
+		}
+	}
+	return cmd.inner.Parse(cmd.fields)
+}
+
+// fetchItemNames 展开 FETCH 项（宏或列表）
+func fetchItemNames(f interface{}) []imap.FetchItem {
+	var names []interface{}
+	switch v := f.(type) {
+	case string:
+		names = []interface{}{v}
+	case []interface{}:
+		names = v
+	}
+	var items []imap.FetchItem
+	for _, name := range names {
+		s, _ := name.(string)
+		items = append(items, imap.FetchItem(strings.ToUpper(s)).Expand()...)
+	}
+	return items
+}
+
+// Handle 处理命令
+func (cmd *condstoreFetch) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID FETCH
+func (cmd *condstoreFetch) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *condstoreFetch) handle(uid bool, conn server.Conn) error {
+	u, err := connUser(conn)
+	if err != nil {
+		return err
+	}
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+	if cmd.vanished && (!uid || !u.qresync) {
+		return errors.New("VANISHED 只能用于启用 QRESYNC 后的 UID FETCH")
+	}
+	if cmd.modSeq || cmd.changedSince != nil {
+		u.condstore = true
+	}
+
+	// 需要修改序列集或追加 MODSEQ 项时重新创建被覆盖的 FETCH
+	seqSet, items := cmd.fields[0], cmd.fields[1]
+	rebuild := false
+	if cmd.changedSince != nil {
+		set, err := imap.ParseSeqSet(seqSet.(string))
+		if err != nil {
+			return err
+		}
+		if cmd.vanished {
+			if err := writeVanished(context.Background(), conn, cmd.ext.storage, mbox, *cmd.changedSince, set); err != nil {
+				return err
+			}
+		}
+
+		changed := changedSince(mbox.selectMessages(uid, set), *cmd.changedSince, uid)
+		if changed.Empty() {
+			return nil
+		}
+		seqSet, rebuild = changed.String(), true
+	}
+	// CHANGEDSINCE 隐含 MODSEQ 项；启用 CONDSTORE 后返回 FLAGS 时也要返回 MODSEQ
+	if !cmd.modSeq && (cmd.changedSince != nil || (u.condstore && cmd.flags)) {
+		items, rebuild = appendFetchItem(items, string(fetchModSeq)), true
+	}
+
+	inner := cmd.inner
+	if rebuild {
+		inner = cmd.ext.handler("FETCH")
+		if err := inner.Parse([]interface{}{seqSet, items}); err != nil {
+			return err
+		}
+	}
+	return handleCommand(inner, uid, conn)
+}
+
+// changedSince 选出修改序号大于 since 的邮件，返回它们的序列号或 UID
+func changedSince(messages []selectedMessage, since uint64, uid bool) *imap.SeqSet {
+	set := &imap.SeqSet{}
+	for _, msg := range messages {
+		if msg.mail.ModSeq <= since {
+			continue
+		}
+		if uid {
+			set.AddNum(msg.uid)
+		} else {
+			set.AddNum(msg.seqNum)
+		}
+	}
+	return set
+}
+
+// appendFetchItem 在 FETCH 项（宏或列表）后追加一项
+func appendFetchItem(f interface{}, item string) []interface{} {
+	switch v := f.(type) {
+	case []interface{}:
+		return append(slices.Clone(v), item)
+	default:
+		return []interface{}{v, item}
+	}
+}
+
+// condstoreStore STORE，支持 (UNCHANGEDSINCE n) 修饰符：修改序号大于 n 的邮件不修改，
+// 在 OK [MODIFIED set] 中返回；启用 CONDSTORE 后 FETCH 响应包含 MODSEQ
+type condstoreStore struct {
+	ext            *CondStoreExtension
+	inner          server.Handler
+	fields         []interface{} // 去掉修饰符后的参数
+	unchangedSince *uint64
+	flagsOp        bool // 修改标志（而不是注解等其他项）
+	silent         bool
+}
+
+// Parse 解析命令参数
+func (cmd *condstoreStore) Parse(fields []interface{}) error {
+	if len(fields) < 3 {
+		return errors.New("STORE 参数数量不正确")
+	}
+	cmd.fields = fields
+	if modifiers, ok := fields[1].([]interface{}); ok {
+		for i := 0; i < len(modifiers); i++ {
+			name, _ := modifiers[i].(string)
+			if !strings.EqualFold(name, "UNCHANGEDSINCE") || i+1 >= len(modifiers) {
+				return fmt.Errorf("不支持的 STORE 修饰符: %v", modifiers[i])
+			}
+			since, err := parseModSeq(modifiers[i+1])
+			if err != nil {
+				return err
+			}
+			cmd.unchangedSince = &since
+			i++
+		}
+		cmd.fields = append([]interface{}{fields[0]}, fields[2:]...)
+		if len(cmd.fields) < 3 {
+			return errors.New("STORE 参数数量不正确")
+		}
+	}
+
+	item, _ := cmd.fields[1].(string)
+	item = strings.ToUpper(item)
+	cmd.flagsOp = strings.TrimLeft(strings.TrimSuffix(item, ".SILENT"), "+-") == "FLAGS"
+	cmd.silent = strings.HasSuffix(item, ".SILENT")
+	return cmd.inner.Parse(cmd.fields)
+}
+
+// Handle 处理命令
+func (cmd *condstoreStore) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID STORE
+func (cmd *condstoreStore) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *condstoreStore) handle(uid bool, conn server.Conn) error {
+	u, err := connUser(conn)
+	if err != nil {
+		return err
+	}
+	if cmd.unchangedSince != nil {
+		u.condstore = true
+	}
+	if cmd.unchangedSince == nil && (!u.condstore || !cmd.flagsOp) {
+		return handleCommand(cmd.inner, uid, conn)
+	}
+
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+	if conn.Context().MailboxReadOnly {
+		return server.ErrMailboxReadOnly
+	}
+	set, err := imap.ParseSeqSet(cmd.fields[0].(string))
+	if err != nil {
+		return err
+	}
+
+	// 选出修改序号没有变化的邮件
+	var allowed, modified imap.SeqSet
+	for _, msg := range mbox.selectMessages(uid, set) {
+		num := msg.seqNum
+		if uid {
+			num = msg.uid
+		}
+		if cmd.unchangedSince != nil && msg.mail.ModSeq > *cmd.unchangedSince {
+			modified.AddNum(num)
+		} else {
+			allowed.AddNum(num)
+		}
+	}
+
+	if !allowed.Empty() {
+		// 标志的 FETCH 响应由这里返回（包含 MODSEQ），被覆盖的 STORE 静默执行
+		fields := slices.Clone(cmd.fields)
+		fields[0] = allowed.String()
+		if cmd.flagsOp && !cmd.silent {
+			fields[1] = fmt.Sprintf("%v.SILENT", fields[1])
+		}
+		inner := cmd.ext.handler("STORE")
+		if err := inner.Parse(fields); err != nil {
+			return err
+		}
+		if err := handleCommand(inner, uid, conn); err != nil {
+			return err
+		}
+
+		if cmd.flagsOp && !cmd.silent {
+			for _, msg := range mbox.selectMessages(uid, &allowed) {
+				if err := writeModSeqFetch(conn, msg, uid); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !modified.Empty() {
+		return server.ErrStatusResp(&imap.StatusResp{
+			Type:      imap.StatusRespOk,
+			Code:      "MODIFIED",
+			Arguments: []interface{}{imap.RawString(modified.String())},
+			Info:      "Conditional STORE failed",
+		})
+	}
+	return nil
+}
+
+// condstoreSearch SEARCH，支持 MODSEQ n 条件（只能出现在顶层），结果中返回匹配邮件的最大修改序号
+type condstoreSearch struct {
+	server.Search
+	modSeq *uint64
+}
+
+// Parse 解析命令参数，取出 MODSEQ 条件，其余条件交给内置 SEARCH 解析
+func (cmd *condstoreSearch) Parse(fields []interface{}) error {
+	rest := make([]interface{}, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		name, ok := fields[i].(string)
+		if !ok || !strings.EqualFold(name, "MODSEQ") {
+			rest = append(rest, fields[i])
+			continue
+		}
+		// MODSEQ [entry-name entry-type] n，按条目区分的修改序号不支持，忽略条目
+		if i+1 < len(fields) {
+			if _, err := parseModSeq(fields[i+1]); err != nil {
+				i += 2
+			}
+		}
+		if i+1 >= len(fields) {
+			return errors.New("MODSEQ 缺少参数")
+		}
+		since, err := parseModSeq(fields[i+1])
+		if err != nil {
+			return err
+		}
+		cmd.modSeq = &since
+		i++
+	}
+	if cmd.modSeq != nil && searchCriteriaEmpty(rest) {
+		rest = append(rest, "ALL")
+	}
+	return cmd.Search.Parse(rest)
+}
+
+// searchCriteriaEmpty 除 CHARSET 外没有其他搜索条件
+func searchCriteriaEmpty(fields []interface{}) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	name, _ := fields[0].(string)
+	return len(fields) == 2 && strings.EqualFold(name, "CHARSET")
+}
+
+// Handle 处理命令
+func (cmd *condstoreSearch) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID SEARCH
+func (cmd *condstoreSearch) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *condstoreSearch) handle(uid bool, conn server.Conn) error {
+	if cmd.modSeq == nil {
+		if uid {
+			return cmd.Search.UidHandle(conn)
+		}
+		return cmd.Search.Handle(conn)
+	}
+	u, err := connUser(conn)
+	if err != nil {
+		return err
+	}
+	u.condstore = true
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+
+	ids, err := mbox.SearchMessages(uid, cmd.Criteria)
+	if err != nil {
+		return err
+	}
+	modSeqs := make(map[uint32]uint64, len(mbox.mails))
+	for _, msg := range mbox.selectMessages(uid, nil) {
+		num := msg.seqNum
+		if uid {
+			num = msg.uid
+		}
+		modSeqs[num] = msg.mail.ModSeq
+	}
+
+	fields := []interface{}{imap.RawString("SEARCH")}
+	var highest uint64
+	for _, id := range ids {
+		if modSeq := modSeqs[id]; modSeq >= *cmd.modSeq {
+			fields = append(fields, id)
+			highest = max(highest, modSeq)
+		}
+	}
+	if highest > 0 {
+		fields = append(fields, []interface{}{imap.RawString("MODSEQ"), imap.RawString(strconv.FormatUint(highest, 10))})
+	}
+	return conn.WriteResp(&imap.DataResp{Fields: fields})
+}
+
+// condstoreExpunge EXPUNGE，启用 QRESYNC 后返回 VANISHED（删除邮件的 UID）而不是 EXPUNGE
+type condstoreExpunge struct {
+	server.Expunge
+}
+
+// Handle 处理命令
+func (cmd *condstoreExpunge) Handle(conn server.Conn) error {
+	u, err := connUser(conn)
+	if err != nil {
+		return err
+	}
+	if !u.qresync {
+		return cmd.Expunge.Handle(conn)
+	}
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+	if conn.Context().MailboxReadOnly {
+		return server.ErrMailboxReadOnly
+	}
+
+	var deleted imap.SeqSet
+	for _, msg := range mbox.selectMessages(true, nil) {
+		if slices.Contains(msg.mail.Flags, imap.DeletedFlag) {
+			deleted.AddNum(msg.uid)
+		}
+	}
+	if err := mbox.Expunge(); err != nil {
+		return err
+	}
+	return writeVanishedUIDs(conn, &deleted)
+}
+
+// writeVanishedUIDs 返回 VANISHED uid-set（邮件刚被删除，不带 EARLIER）
+func writeVanishedUIDs(conn server.Conn, uids *imap.SeqSet) error {
+	if uids.Empty() {
+		return nil
+	}
+	return conn.WriteResp(&imap.DataResp{Fields: []interface{}{
+		imap.RawString("VANISHED"),
+		imap.RawString(uids.String()),
+	}})
+}
@@ -0,0 +1,163 @@
+package imapd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// rawCommand 原样发送的 IMAP 命令
+type rawCommand struct {
+	name string
+	args []interface{}
+}
+
+func (cmd *rawCommand) Command() *imap.Command {
+	return &imap.Command{Name: cmd.name, Arguments: cmd.args}
+}
+
+// responseRecorder 记录命令的未标记响应
+type responseRecorder struct {
+	lines []string
+}
+
+func (r *responseRecorder) Handle(resp imap.Resp) error {
+	switch resp := resp.(type) {
+	case *imap.DataResp:
+		r.lines = append(r.lines, strings.TrimSpace(fmt.Sprintln(resp.Fields...)))
+	case *imap.StatusResp:
+		r.lines = append(r.lines, fmt.Sprint(resp.Code, resp.Arguments))
+	}
+	return nil
+}
+
+// execute 执行命令，返回未标记响应和标记响应
+func execute(t *testing.T, c *client.Client, name string, args ...interface{}) ([]string, *imap.StatusResp) {
+	t.Helper()
+	rec := &responseRecorder{}
+	status, err := c.Execute(&rawCommand{name: name, args: args}, rec)
+	if err != nil {
+		t.Fatalf("%s 失败: %v", name, err)
+	}
+	return rec.lines, status
+}
+
+// hasLine 是否有包含 substr 的响应
+func hasLine(lines []string, substr string) bool {
+	for _, line := range lines {
+		if strings.Contains(line, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCondStore(t *testing.T) {
+	c, driver, _ := newTestIMAP(t)
+
+	for _, capability := range []string{"CONDSTORE", "QRESYNC", "ENABLE"} {
+		if ok, _ := c.Support(capability); !ok {
+			t.Fatalf("应该支持 %s", capability)
+		}
+	}
+	lines, _ := execute(t, c, "ENABLE", imap.RawString("QRESYNC"), imap.RawString("UNKNOWN"))
+	if len(lines) != 1 || lines[0] != "ENABLED QRESYNC" {
+		t.Fatalf("ENABLE 响应不正确: %v", lines)
+	}
+
+	// 打开邮箱时没有 \Seen 的旧邮件会自动设置 \Seen（兼容 Foxmail），修改序号为 3 和 4
+	lines, status := execute(t, c, "SELECT", imap.RawString("INBOX"), []interface{}{imap.RawString("CONDSTORE")})
+	if status.Type != imap.StatusRespOk || !hasLine(lines, "HIGHESTMODSEQ[4]") {
+		t.Fatalf("SELECT 应该返回 HIGHESTMODSEQ 4: %v", lines)
+	}
+
+	// 修改标志后 FETCH 响应包含新的修改序号
+	lines, _ = execute(t, c, "UID STORE", imap.RawString("1"), imap.RawString("+FLAGS"), []interface{}{imap.RawString(imap.FlaggedFlag)})
+	if len(lines) != 1 || !strings.Contains(lines[0], "UID 1") || !strings.Contains(lines[0], "MODSEQ [5]") {
+		t.Fatalf("STORE 的 FETCH 响应不正确: %v", lines)
+	}
+
+	// CHANGEDSINCE 只返回变化的邮件
+	lines, _ = execute(t, c, "UID FETCH", imap.RawString("1:*"), []interface{}{imap.RawString("FLAGS")},
+		[]interface{}{imap.RawString("CHANGEDSINCE"), imap.RawString("4")})
+	if len(lines) != 1 || !strings.Contains(lines[0], "MODSEQ [5]") {
+		t.Fatalf("CHANGEDSINCE 应该只返回 1 封邮件: %v", lines)
+	}
+
+	// UNCHANGEDSINCE：修改序号已变化的邮件不修改
+	lines, status = execute(t, c, "UID STORE", imap.RawString("1:2"),
+		[]interface{}{imap.RawString("UNCHANGEDSINCE"), imap.RawString("4")},
+		imap.RawString("+FLAGS.SILENT"), []interface{}{imap.RawString(imap.SeenFlag)})
+	if status.Code != "MODIFIED" || fmt.Sprint(status.Arguments...) != "1" || len(lines) != 0 {
+		t.Fatalf("条件 STORE 应该返回 MODIFIED 1: %+v %v", status, lines)
+	}
+
+	// SEARCH MODSEQ
+	lines, _ = execute(t, c, "UID SEARCH", imap.RawString("MODSEQ"), imap.RawString("6"))
+	if len(lines) != 1 || lines[0] != "SEARCH 2 [MODSEQ 6]" {
+		t.Fatalf("SEARCH MODSEQ 结果不正确: %v", lines)
+	}
+
+	// 启用 QRESYNC 后 MOVE 返回 VANISHED，之后的 CHANGEDSINCE VANISHED 返回已移出的 UID
+	lines, _ = execute(t, c, "UID MOVE", imap.RawString("2"), imap.RawString("Archive"))
+	if len(lines) != 1 || lines[0] != "VANISHED 2" {
+		t.Fatalf("MOVE 应该返回 VANISHED 2: %v", lines)
+	}
+	lines, _ = execute(t, c, "UID FETCH", imap.RawString("1:*"), []interface{}{imap.RawString("FLAGS")},
+		[]interface{}{imap.RawString("CHANGEDSINCE"), imap.RawString("5"), imap.RawString("VANISHED")})
+	if len(lines) != 1 || lines[0] != "VANISHED [EARLIER] 2" {
+		t.Fatalf("CHANGEDSINCE VANISHED 结果不正确: %v", lines)
+	}
+
+	lines, _ = execute(t, c, "STATUS", imap.RawString("INBOX"), []interface{}{imap.RawString("HIGHESTMODSEQ")})
+	if !hasLine(lines, "HIGHESTMODSEQ 7") {
+		t.Fatalf("STATUS HIGHESTMODSEQ 不正确: %v", lines)
+	}
+
+	// QRESYNC 重新选择：返回期间删除的 UID 和变化的邮件
+	uids, err := driver.GetMailboxUIDs(t.Context(), "me@example.com", "INBOX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines, _ = execute(t, c, "SELECT", imap.RawString("INBOX"), []interface{}{imap.RawString("QRESYNC"),
+		[]interface{}{imap.RawString(fmt.Sprint(uids.UIDValidity)), imap.RawString("4"), imap.RawString("1:2")}})
+	if !hasLine(lines, "HIGHESTMODSEQ[7]") || !hasLine(lines, "VANISHED [EARLIER] 2") || !hasLine(lines, "MODSEQ [5]") {
+		t.Fatalf("QRESYNC 结果不正确: %v", lines)
+	}
+}
+
+func TestCondStoreExpungeVanished(t *testing.T) {
+	c, _, _ := newTestIMAP(t)
+
+	execute(t, c, "ENABLE", imap.RawString("QRESYNC"))
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(1)
+	if err := c.Store(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.DeletedFlag}, nil); err != nil {
+		t.Fatal(err)
+	}
+	lines, _ := execute(t, c, "EXPUNGE")
+	if len(lines) != 1 || lines[0] != "VANISHED 1" {
+		t.Fatalf("启用 QRESYNC 后 EXPUNGE 应该返回 VANISHED 1: %v", lines)
+	}
+}
+
+func TestParseQresyncParams(t *testing.T) {
+	params, err := parseQresyncParams([]interface{}{"67890007", "20050715194045000", "41,43:211", []interface{}{"1", "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.uidValidity != 67890007 || params.modSeq != 20050715194045000 || !params.knownUIDs.Contains(100) || params.knownUIDs.Contains(42) {
+		t.Errorf("参数解析不正确: %+v", params)
+	}
+	for _, bad := range []interface{}{"1", []interface{}{"1"}, []interface{}{"x", "1"}, []interface{}{"1", "-1"}} {
+		if _, err := parseQresyncParams(bad); err == nil {
+			t.Errorf("%v 应该解析失败", bad)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package imapd
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/emersion/go-imap"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// mailPageSize 从数据库分页读取邮件元数据的每页数量
+	mailPageSize = 1000
+	// headerCacheSize 邮件头缓存的总大小上限（所有连接共享）
+	headerCacheSize = 16 << 20
+)
+
+// listAllMails 按 UID 分页读取邮箱中所有邮件的元数据（不包括邮件内容），邮箱中的邮件数量没有上限。
+// 没有 UID 的旧邮件需要先调用 GetMailboxUIDs 分配
+func listAllMails(ctx context.Context, driver storage.Driver, userEmail, folder string) ([]*storage.Mail, error) {
+	var mails []*storage.Mail
+	var afterUID uint32
+	for {
+		page, err := driver.ListMailIDs(ctx, userEmail, folder, afterUID, mailPageSize)
+		if err != nil {
+			return nil, err
+		}
+		mails = append(mails, page...)
+		if len(page) < mailPageSize {
+			return mails, nil
+		}
+		afterUID = page[len(page)-1].UID
+	}
+}
+
+// headerCache 邮件头缓存：客户端刷新邮件列表时反复请求 BODY.PEEK[HEADER.FIELDS (...)]，
+// 缓存之后不需要每次都打开邮件文件。按总字节数淘汰最久未使用的邮件头，为空时不缓存
+type headerCache struct {
+	mu      sync.Mutex
+	maxSize int
+	size    int
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+type headerEntry struct {
+	key    string
+	header []byte
+}
+
+func newHeaderCache(maxSize int) *headerCache {
+	return &headerCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// key 用户和邮件 ID 组成的键（邮件 ID 是 Maildir 文件名，移动文件夹和修改标志时不变）
+func (c *headerCache) key(userEmail, mailID string) string {
+	return userEmail + "\x00" + mailID
+}
+
+func (c *headerCache) get(userEmail, mailID string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[c.key(userEmail, mailID)]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*headerEntry).header, true
+}
+
+func (c *headerCache) add(userEmail, mailID string, header []byte) {
+	if c == nil || len(header) > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := c.key(userEmail, mailID)
+	if elem, ok := c.entries[key]; ok {
+		c.size -= len(elem.Value.(*headerEntry).header)
+		c.order.Remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&headerEntry{key: key, header: header})
+	c.size += len(header)
+	for c.size > c.maxSize {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*headerEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.size -= len(entry.header)
+	}
+}
+
+// mailLiteral 从邮件文件流式发送的字面量（整封邮件或从 offset 开始的一段），不把邮件读入内存。
+// 读到结尾时关闭文件；响应没有发送完（连接断开）时由 os.File 的 finalizer 关闭
+type mailLiteral struct {
+	rc     io.ReadCloser
+	r      io.Reader
+	offset int64
+	size   int
+	err    error
+}
+
+func (l *mailLiteral) Len() int {
+	return l.size
+}
+
+func (l *mailLiteral) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.r == nil {
+		if _, err := io.CopyN(io.Discard, l.rc, l.offset); err != nil {
+			l.rc.Close()
+			l.err = fmt.Errorf("读取邮件文件失败: %w", err)
+			return 0, l.err
+		}
+		l.r = io.LimitReader(l.rc, int64(l.size))
+	}
+	n, err := l.r.Read(p)
+	if err != nil {
+		l.rc.Close()
+		l.err = err
+	}
+	return n, err
+}
+
+// mailContent 按需读取一封邮件的内容：邮件头优先从缓存读取，BODYSTRUCTURE 和其他部分从邮件文件流式解析，
+// 整封邮件（BODY[]、RFC822）直接从文件发送
+type mailContent struct {
+	m         *Mailbox
+	mail      *storage.Mail
+	cacheable bool // 邮件头可以缓存（零访问存储加密的邮件不缓存明文邮件头）
+}
+
+// open 打开邮件文件
+func (c *mailContent) open() (io.ReadCloser, int64, error) {
+	if c.m.maildir == nil {
+		return nil, 0, errors.New("没有配置 Maildir，无法读取邮件内容")
+	}
+	return c.m.maildir.OpenMail(c.m.userEmail, c.m.name, c.mail.ID)
+}
+
+// header 返回邮件头原文（包括结束的空行）
+func (c *mailContent) header() ([]byte, error) {
+	if header, ok := c.m.headers.get(c.m.userEmail, c.mail.ID); ok {
+		return header, nil
+	}
+	if c.m.maildir == nil {
+		return nil, errors.New("没有配置 Maildir，无法读取邮件内容")
+	}
+	header, err := c.m.maildir.ReadMailHeader(c.m.userEmail, c.m.name, c.mail.ID)
+	if err != nil {
+		return nil, err
+	}
+	if c.cacheable {
+		c.m.headers.add(c.m.userEmail, c.mail.ID, header)
+	}
+	return header, nil
+}
+
+// bodyStructure 从邮件文件流式解析 BODY 或 BODYSTRUCTURE，读取失败时退回 text/plain（见 bodyStructure）
+func (c *mailContent) bodyStructure(size uint32, extended bool) *imap.BodyStructure {
+	rc, _, err := c.open()
+	if err != nil {
+		return bodyStructure(nil, size, extended)
+	}
+	defer rc.Close()
+	return readBodyStructure(bufio.NewReader(rc), size, extended)
+}
+
+// section 返回 BODY[section] 的内容：整封邮件从文件流式发送，顶层邮件头从缓存读取，
+// 其他部分从邮件文件流式解析后返回。只在无法读取邮件时返回错误，不存在的部分按 RFC 3501 返回空字符串
+func (c *mailContent) section(section *imap.BodySectionName) (imap.Literal, error) {
+	if len(section.Path) == 0 && section.Specifier == imap.EntireSpecifier {
+		rc, size, err := c.open()
+		if err != nil {
+			return nil, err
+		}
+		offset, length := int64(0), size
+		if len(section.Partial) == 2 {
+			offset = min(int64(section.Partial[0]), size)
+			length = min(int64(section.Partial[1]), size-offset)
+		}
+		return &mailLiteral{rc: rc, offset: offset, size: int(length)}, nil
+	}
+
+	if len(section.Path) == 0 && section.Specifier == imap.HeaderSpecifier {
+		header, err := c.header()
+		if err != nil {
+			return nil, err
+		}
+		return partLiteral(bodySection(header, section)), nil
+	}
+
+	rc, _, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return partLiteral(readBodySection(bufio.NewReader(rc), section)), nil
+}
+
+// partLiteral 提取的邮件部分，提取失败（部分不存在）时为空字符串
+func partLiteral(data []byte, err error) imap.Literal {
+	if err != nil {
+		return bytes.NewReader(nil)
+	}
+	return bytes.NewReader(data)
+}
@@ -0,0 +1,144 @@
+package imapd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestFetchContent(t *testing.T) {
+	c, _, maildir := newTestIMAP(t)
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	raw := "From: a@example.net\r\nTo: me@example.com\r\nSubject: first\r\n\r\nbody\r\n"
+
+	fetch := func(items ...string) map[string]string {
+		t.Helper()
+		seqSet := new(imap.SeqSet)
+		seqSet.AddNum(1)
+		fetchItems := make([]imap.FetchItem, len(items))
+		for i, item := range items {
+			fetchItems[i] = imap.FetchItem(item)
+		}
+		ch := make(chan *imap.Message, 1)
+		if err := c.Fetch(seqSet, fetchItems, ch); err != nil {
+			t.Fatalf("FETCH %v 失败: %v", items, err)
+		}
+		msg := <-ch
+		got := make(map[string]string)
+		for _, item := range items {
+			section, err := imap.ParseBodySectionName(imap.FetchItem(item))
+			if err != nil {
+				continue
+			}
+			if literal := msg.GetBody(section); literal != nil {
+				data, _ := io.ReadAll(literal)
+				got[item] = string(data)
+			}
+		}
+		return got
+	}
+
+	got := fetch("BODY.PEEK[]", "BODY.PEEK[]<4.10>", "BODY.PEEK[HEADER.FIELDS (SUBJECT)]", "BODY.PEEK[TEXT]")
+	for item, want := range map[string]string{
+		"BODY.PEEK[]":                        raw,
+		"BODY.PEEK[]<4.10>":                  raw[4:14],
+		"BODY.PEEK[HEADER.FIELDS (SUBJECT)]": "Subject: first\r\n\r\n",
+		"BODY.PEEK[TEXT]":                    "body\r\n",
+	} {
+		if got[item] != want {
+			t.Errorf("%s = %q，期望 %q", item, got[item], want)
+		}
+	}
+	// RFC822 在客户端与 BODY[] 对应同一个 section，单独获取
+	if got := fetch("RFC822"); got["RFC822"] != raw {
+		t.Errorf("RFC822 = %q，期望 %q", got["RFC822"], raw)
+	}
+
+	// 邮件头已经缓存，不再读取邮件文件
+	dir := maildir.GetUserMaildir("me@example.com")
+	files, _ := filepath.Glob(filepath.Join(dir, "*", "*"))
+	for _, file := range files {
+		if filepath.Base(filepath.Dir(file)) != "tmp" {
+			os.Remove(file)
+		}
+	}
+	got = fetch("BODY.PEEK[HEADER.FIELDS (SUBJECT)]", "BODY.PEEK[TEXT]")
+	if got["BODY.PEEK[HEADER.FIELDS (SUBJECT)]"] != "Subject: first\r\n\r\n" {
+		t.Errorf("邮件头应该从缓存读取: %q", got)
+	}
+	if _, ok := got["BODY.PEEK[TEXT]"]; ok {
+		t.Errorf("邮件文件不存在时不应该返回正文: %q", got)
+	}
+}
+
+func TestHeaderCache(t *testing.T) {
+	cache := newHeaderCache(10)
+	cache.add("me@example.com", "a", []byte("aaaa"))
+	cache.add("me@example.com", "b", []byte("bbbb"))
+	cache.get("me@example.com", "a")
+	// 超过总大小时淘汰最久未使用的 b
+	cache.add("me@example.com", "c", []byte("cccc"))
+	if _, ok := cache.get("me@example.com", "b"); ok {
+		t.Error("b 应该被淘汰")
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := cache.get("me@example.com", id); !ok {
+			t.Errorf("%s 应该在缓存中", id)
+		}
+	}
+	if _, ok := cache.get("other@example.com", "a"); ok {
+		t.Error("缓存应该按用户区分")
+	}
+	// 超过上限的邮件头不缓存
+	cache.add("me@example.com", "d", []byte("ddddddddddddd"))
+	if _, ok := cache.get("me@example.com", "d"); ok || cache.size != 8 {
+		t.Errorf("过大的邮件头不应该缓存，size = %d", cache.size)
+	}
+
+	var empty *headerCache
+	empty.add("me@example.com", "a", []byte("a"))
+	if _, ok := empty.get("me@example.com", "a"); ok {
+		t.Error("为空时不缓存")
+	}
+}
+
+func TestListAllMails(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过一页的邮件全部读取
+	total := mailPageSize + 5
+	for i := 0; i < total; i++ {
+		if err := driver.StoreMail(ctx, &storage.Mail{ID: fmt.Sprintf("mail-%d", i), UserEmail: "me@example.com",
+			Folder: "INBOX", Subject: "hi", ReceivedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mails, err := listAllMails(ctx, driver, "me@example.com", "INBOX")
+	if err != nil || len(mails) != total {
+		t.Fatalf("listAllMails() = %d 封, %v，期望 %d 封", len(mails), err, total)
+	}
+	seen := make(map[string]bool)
+	for _, mail := range mails {
+		seen[mail.ID] = true
+	}
+	if len(seen) != total {
+		t.Errorf("分页读取的邮件有重复: %d", len(seen))
+	}
+}
@@ -0,0 +1,179 @@
+package imapd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// listMailboxes 返回 LIST 的邮箱名称和属性
+func listMailboxes(t *testing.T, c *client.Client) map[string][]string {
+	t.Helper()
+	ch := make(chan *imap.MailboxInfo, 32)
+	if err := c.List("", "*", ch); err != nil {
+		t.Fatalf("LIST 失败: %v", err)
+	}
+	result := make(map[string][]string)
+	for info := range ch {
+		if info.Delimiter != "/" {
+			t.Errorf("邮箱 %s 的层级分隔符 = %q", info.Name, info.Delimiter)
+		}
+		result[info.Name] = info.Attributes
+	}
+	return result
+}
+
+func TestMailboxManagement(t *testing.T) {
+	c, driver, maildir := newTestIMAP(t)
+
+	// 创建子邮箱时自动创建上级邮箱
+	if err := c.Create("Projects/2026"); err != nil {
+		t.Fatalf("CREATE 失败: %v", err)
+	}
+	mailboxes := listMailboxes(t, c)
+	if !slices.Contains(mailboxes["Projects"], imap.HasChildrenAttr) {
+		t.Errorf("Projects 属性 = %v，期望 \\HasChildren", mailboxes["Projects"])
+	}
+	if !slices.Contains(mailboxes["Projects/2026"], imap.HasNoChildrenAttr) {
+		t.Errorf("Projects/2026 属性 = %v，期望 \\HasNoChildren", mailboxes["Projects/2026"])
+	}
+	if err := c.Create("Projects"); err == nil {
+		t.Error("创建已存在的邮箱应失败")
+	}
+	if err := c.Create("Releases/v1.0"); err != nil {
+		t.Fatalf("CREATE 名称包含 . 的邮箱失败: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(maildir.GetUserMaildir("me@example.com"), ".Releases.v1%2E0", "cur")); err != nil {
+		t.Errorf("名称中的 . 没有转义: %v", err)
+	}
+
+	// 只有子邮箱目录时（如外部工具直接创建），上级邮箱以 \Noselect 列出
+	if err := maildir.CreateFolder("me@example.com", "Clients/2024/Acme"); err != nil {
+		t.Fatal(err)
+	}
+	mailboxes = listMailboxes(t, c)
+	for _, name := range []string{"Clients", "Clients/2024"} {
+		if !slices.Contains(mailboxes[name], imap.NoSelectAttr) || !slices.Contains(mailboxes[name], imap.HasChildrenAttr) {
+			t.Errorf("%s 属性 = %v，期望 \\Noselect \\HasChildren", name, mailboxes[name])
+		}
+	}
+	if _, ok := mailboxes["Clients/2024/Acme"]; !ok || slices.Contains(mailboxes["Clients/2024/Acme"], imap.NoSelectAttr) {
+		t.Errorf("Clients/2024/Acme 属性 = %v", mailboxes["Clients/2024/Acme"])
+	}
+
+	// 复制一封邮件到子邮箱，重命名上级邮箱后邮件随之移动
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(1)
+	if err := c.Copy(seqset, "Projects/2026"); err != nil {
+		t.Fatalf("COPY 失败: %v", err)
+	}
+	if err := c.Rename("Projects", "Work"); err != nil {
+		t.Fatalf("RENAME 失败: %v", err)
+	}
+	mailboxes = listMailboxes(t, c)
+	if _, ok := mailboxes["Projects/2026"]; ok {
+		t.Error("重命名后旧的子邮箱仍然存在")
+	}
+	if _, ok := mailboxes["Work/2026"]; !ok {
+		t.Errorf("重命名后的邮箱 = %v", mailboxes)
+	}
+	status, err := c.Select("Work/2026", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Messages != 1 {
+		t.Errorf("Work/2026 邮件数 = %d，期望 1", status.Messages)
+	}
+	if got := maildirFiles(t, maildir, "Work/2026"); got != 1 {
+		t.Errorf("Work/2026 目录中的邮件文件数 = %d，期望 1", got)
+	}
+
+	// 不能删除 INBOX 和有子邮箱的邮箱
+	if err := c.Delete("INBOX"); err == nil {
+		t.Error("删除 INBOX 应失败")
+	}
+	if err := c.Delete("Work"); err == nil {
+		t.Error("删除有子邮箱的邮箱应失败")
+	}
+	if err := c.Delete("Work/2026"); err != nil {
+		t.Fatalf("DELETE 失败: %v", err)
+	}
+	if err := c.Delete("Work/2026"); err == nil {
+		t.Error("删除不存在的邮箱应失败")
+	}
+	folders, err := driver.ListFolders(t.Context(), "me@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(folders, "Work/2026") {
+		t.Errorf("删除后数据库中仍有邮件: %v", folders)
+	}
+}
+
+// lsubMailboxes 返回 LSUB 的邮箱名称和属性
+func lsubMailboxes(t *testing.T, c *client.Client) map[string][]string {
+	t.Helper()
+	ch := make(chan *imap.MailboxInfo, 32)
+	if err := c.Lsub("", "*", ch); err != nil {
+		t.Fatalf("LSUB 失败: %v", err)
+	}
+	result := make(map[string][]string)
+	for info := range ch {
+		result[info.Name] = info.Attributes
+	}
+	return result
+}
+
+func TestSubscriptions(t *testing.T) {
+	c, _, _ := newTestIMAP(t)
+
+	// 新用户默认订阅 INBOX 和特殊文件夹
+	subscribed := lsubMailboxes(t, c)
+	for _, folder := range []string{"INBOX", "Sent", "Drafts", "Trash", "Spam"} {
+		if _, ok := subscribed[folder]; !ok {
+			t.Errorf("默认应订阅 %s: %v", folder, subscribed)
+		}
+	}
+	if _, ok := subscribed["Archive"]; ok {
+		t.Error("Archive 不应默认订阅")
+	}
+
+	if err := c.Create("Projects"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Subscribe("Projects"); err != nil {
+		t.Fatalf("SUBSCRIBE 失败: %v", err)
+	}
+	if err := c.Unsubscribe("Spam"); err != nil {
+		t.Fatalf("UNSUBSCRIBE 失败: %v", err)
+	}
+	subscribed = lsubMailboxes(t, c)
+	if _, ok := subscribed["Projects"]; !ok {
+		t.Errorf("订阅后 LSUB 应包含 Projects: %v", subscribed)
+	}
+	if _, ok := subscribed["Spam"]; ok {
+		t.Errorf("取消订阅后 LSUB 不应包含 Spam: %v", subscribed)
+	}
+
+	// 重命名时订阅随之移动，删除后仍保留订阅（标记为 \Noselect）
+	if err := c.Rename("Projects", "Work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete("Work"); err != nil {
+		t.Fatal(err)
+	}
+	subscribed = lsubMailboxes(t, c)
+	if _, ok := subscribed["Projects"]; ok {
+		t.Errorf("重命名后旧名称仍在订阅列表中: %v", subscribed)
+	}
+	if !slices.Contains(subscribed["Work"], imap.NoSelectAttr) {
+		t.Errorf("已删除的订阅邮箱属性 = %v，期望 \\Noselect", subscribed["Work"])
+	}
+}
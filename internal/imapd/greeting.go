@@ -0,0 +1,79 @@
+package imapd
+
+import (
+	"bytes"
+	"net"
+	"sync"
+)
+
+// greetingListener 替换欢迎信息文本的监听器
+//
+// go-imap 固定写出 "* OK [CAPABILITY ...] IMAP4rev1 Service Ready"，没有扩展点，
+// 因此在连接层改写服务器的第一行：保留状态和 CAPABILITY 响应码，只替换文本
+type greetingListener struct {
+	net.Listener
+	greeting string
+}
+
+// Accept 接受连接
+func (l *greetingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &greetingConn{Conn: conn, greeting: l.greeting}, nil
+}
+
+// greetingConn 改写第一行回复的连接
+type greetingConn struct {
+	net.Conn
+	greeting string
+
+	mu      sync.Mutex
+	line    []byte // 尚未写完的欢迎信息
+	greeted bool   // 已写出欢迎信息，之后原样写出
+}
+
+// NetConn 返回包装的连接
+func (c *greetingConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// Write 写出服务器数据，第一行替换为配置的欢迎信息
+func (c *greetingConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.greeted {
+		c.mu.Unlock()
+		return c.Conn.Write(b)
+	}
+	c.line = append(c.line, b...)
+	end := bytes.IndexByte(c.line, '\n')
+	if end < 0 {
+		c.mu.Unlock()
+		return len(b), nil
+	}
+	out := append(rewriteGreeting(c.line[:end+1], c.greeting), c.line[end+1:]...)
+	c.line = nil
+	c.greeted = true
+	c.mu.Unlock()
+
+	if _, err := c.Conn.Write(out); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// rewriteGreeting 将欢迎信息 line 的文本替换为 greeting（响应码 "[...]" 保持不变）
+func rewriteGreeting(line []byte, greeting string) []byte {
+	prefix := []byte("* OK ")
+	if !bytes.HasPrefix(line, prefix) {
+		return line
+	}
+	if bytes.HasPrefix(line[len(prefix):], []byte("[")) {
+		if end := bytes.Index(line, []byte("] ")); end >= 0 {
+			prefix = line[:end+2]
+		}
+	}
+	out := append([]byte{}, prefix...)
+	return append(append(out, greeting...), "\r\n"...)
+}
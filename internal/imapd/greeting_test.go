@@ -0,0 +1,102 @@
+package imapd
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+	tlsconfig "github.com/gomailzero/gmz/internal/tls"
+)
+
+func TestGreeting(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	hash, err := crypto.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "me@example.com", PasswordHash: hash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tlsconfig.LoadOrCreateSelfSigned(t.TempDir(), []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{*cert}}
+
+	s := NewServer(&Config{
+		TLS:      tlsConfig,
+		Storage:  driver,
+		Auth:     NewDefaultAuthenticator(driver),
+		Greeting: "Example Mail IMAP",
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 与 Start 相同：欢迎信息的包装在隐式 TLS 之上
+	go s.server.Serve(&greetingListener{Listener: tls.NewListener(listener, tlsConfig), greeting: "Example Mail IMAP"})
+	t.Cleanup(func() { s.server.Close() })
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("连接 IMAP 服务器失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	r := bufio.NewReader(conn)
+
+	greeting, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(greeting, "* OK [CAPABILITY IMAP4rev1") || !strings.HasSuffix(greeting, "] Example Mail IMAP\r\n") {
+		t.Fatalf("欢迎信息 = %q", greeting)
+	}
+	// 隐式 TLS 连接可以认证，不提供 STARTTLS
+	if strings.Contains(greeting, "LOGINDISABLED") || strings.Contains(greeting, "STARTTLS") {
+		t.Errorf("TLS 连接的能力不正确: %q", greeting)
+	}
+	if _, err := conn.Write([]byte("a1 LOGIN me@example.com secret\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "a1 ") {
+			if !strings.HasPrefix(line, "a1 OK") {
+				t.Errorf("LOGIN 失败: %q", line)
+			}
+			break
+		}
+	}
+}
+
+func TestRewriteGreeting(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"* OK [CAPABILITY IMAP4rev1 AUTH=PLAIN] IMAP4rev1 Service Ready\r\n", "* OK [CAPABILITY IMAP4rev1 AUTH=PLAIN] Brand\r\n"},
+		{"* OK IMAP4rev1 Service Ready\r\n", "* OK Brand\r\n"},
+		{"* BYE Too many connections\r\n", "* BYE Too many connections\r\n"},
+	}
+	for _, tt := range tests {
+		if got := string(rewriteGreeting([]byte(tt.line), "Brand")); got != tt.want {
+			t.Errorf("rewriteGreeting(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package imapd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/server"
+	"github.com/emersion/go-imap/utf7"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// VacationEntryPrefix 同域用户外出状态的服务器级只读条目前缀，后接用户邮箱
+	// （如 /shared/vendor/gomailzero/vacation/bob@example.com）
+	VacationEntryPrefix = "/shared/vendor/gomailzero/vacation"
+
+	// MaxMetadataSize 单个 METADATA 条目值的最大字节数
+	MaxMetadataSize = 64 * 1024
+	// MaxMetadataEntries 每个邮箱（或服务器级）最多保存的条目数
+	MaxMetadataEntries = 100
+)
+
+// metadataDepth GETMETADATA 的 DEPTH 选项
+type metadataDepth int
+
+const (
+	depthZero     metadataDepth = iota // 只返回请求的条目
+	depthOne                           // 同时返回直接子条目
+	depthInfinity                      // 同时返回所有子条目
+)
+
+// MetadataExtension IMAP METADATA 扩展（RFC 5464），条目保存在数据库中，
+// 服务器级条目额外包含同域用户的外出状态（只读）
+type MetadataExtension struct {
+	storage storage.Driver
+}
+
+// NewMetadataExtension 创建 METADATA 扩展
+func NewMetadataExtension(driver storage.Driver) *MetadataExtension {
+	return &MetadataExtension{storage: driver}
+}
+
+// Capabilities 返回扩展提供的能力
+func (ext *MetadataExtension) Capabilities(c server.Conn) []string {
+	return []string{"METADATA"}
+}
+
+// Command 返回命令处理器
+func (ext *MetadataExtension) Command(name string) server.HandlerFactory {
+	switch name {
+	case "GETMETADATA":
+		return func() server.Handler {
+			return &getMetadata{ext: ext}
+		}
+	case "SETMETADATA":
+		return func() server.Handler {
+			return &setMetadata{ext: ext}
+		}
+	}
+	return nil
+}
+
+// getMetadata GETMETADATA [options] mailbox entries
+type getMetadata struct {
+	ext     *MetadataExtension
+	mailbox string
+	entries []string
+	maxSize int // 0 表示不限制
+	depth   metadataDepth
+}
+
+// Parse 解析命令参数
+func (cmd *getMetadata) Parse(fields []interface{}) error {
+	if len(fields) >= 3 {
+		if options, ok := fields[0].([]interface{}); ok {
+			if err := cmd.parseOptions(options); err != nil {
+				return err
+			}
+			fields = fields[1:]
+		}
+	}
+	if len(fields) != 2 {
+		return errors.New("GETMETADATA 参数数量不正确")
+	}
+
+	mailbox, err := parseMetadataMailbox(fields[0])
+	if err != nil {
+		return err
+	}
+	cmd.mailbox = mailbox
+
+	var names []interface{}
+	if list, ok := fields[1].([]interface{}); ok {
+		names = list
+	} else {
+		names = []interface{}{fields[1]}
+	}
+	if len(names) == 0 {
+		return errors.New("GETMETADATA 缺少条目名称")
+	}
+	for _, f := range names {
+		name, err := imap.ParseString(f)
+		if err != nil {
+			return err
+		}
+		if name, err = normalizeEntryName(name); err != nil {
+			return err
+		}
+		cmd.entries = append(cmd.entries, name)
+	}
+	return nil
+}
+
+// parseOptions 解析 (MAXSIZE n DEPTH 0|1|infinity)
+func (cmd *getMetadata) parseOptions(options []interface{}) error {
+	if len(options)%2 != 0 {
+		return errors.New("GETMETADATA 选项格式不正确")
+	}
+	for i := 0; i < len(options); i += 2 {
+		key, err := imap.ParseString(options[i])
+		if err != nil {
+			return err
+		}
+		switch strings.ToUpper(key) {
+		case "MAXSIZE":
+			n, err := imap.ParseNumber(options[i+1])
+			if err != nil {
+				return err
+			}
+			cmd.maxSize = int(n)
+		case "DEPTH":
+			value, err := imap.ParseString(options[i+1])
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(value) {
+			case "0":
+				cmd.depth = depthZero
+			case "1":
+				cmd.depth = depthOne
+			case "infinity":
+				cmd.depth = depthInfinity
+			default:
+				return fmt.Errorf("不支持的 DEPTH: %s", value)
+			}
+		default:
+			return fmt.Errorf("不支持的 GETMETADATA 选项: %s", key)
+		}
+	}
+	return nil
+}
+
+// Handle 处理命令
+func (cmd *getMetadata) Handle(conn server.Conn) error {
+	ctx := conn.Context()
+	if ctx.User == nil {
+		return server.ErrNotAuthenticated
+	}
+	email := ctx.User.Username()
+
+	if err := cmd.ext.checkMailbox(context.Background(), ctx.User, cmd.mailbox); err != nil {
+		return err
+	}
+	values, err := cmd.ext.values(context.Background(), email, cmd.mailbox, time.Now())
+	if err != nil {
+		return err
+	}
+
+	result, longest := selectMetadata(values, cmd.entries, cmd.depth, cmd.maxSize)
+	if len(result) > 0 {
+		mailbox, err := utf7.Encoding.NewEncoder().String(cmd.mailbox)
+		if err != nil {
+			return err
+		}
+		if err := conn.WriteResp(&imap.DataResp{
+			Fields: []interface{}{imap.RawString("METADATA"), imap.FormatMailboxName(mailbox), result},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if longest > 0 {
+		return server.ErrStatusResp(&imap.StatusResp{
+			Type:      imap.StatusRespOk,
+			Code:      "METADATA",
+			Arguments: []interface{}{imap.RawString("LONGENTRIES"), uint32(longest)},
+			Info:      "GETMETADATA completed",
+		})
+	}
+	return nil
+}
+
+// setMetadata SETMETADATA mailbox (entry value ...)
+type setMetadata struct {
+	ext     *MetadataExtension
+	mailbox string
+	names   []string
+	values  []*string // nil 表示删除条目
+}
+
+// Parse 解析命令参数
+func (cmd *setMetadata) Parse(fields []interface{}) error {
+	if len(fields) != 2 {
+		return errors.New("SETMETADATA 参数数量不正确")
+	}
+
+	mailbox, err := parseMetadataMailbox(fields[0])
+	if err != nil {
+		return err
+	}
+	cmd.mailbox = mailbox
+
+	list, ok := fields[1].([]interface{})
+	if !ok || len(list) == 0 || len(list)%2 != 0 {
+		return errors.New("SETMETADATA 条目列表格式不正确")
+	}
+	for i := 0; i < len(list); i += 2 {
+		name, err := imap.ParseString(list[i])
+		if err != nil {
+			return err
+		}
+		if name, err = normalizeEntryName(name); err != nil {
+			return err
+		}
+
+		var value *string
+		if list[i+1] != nil {
+			v, err := imap.ParseString(list[i+1])
+			if err != nil {
+				return err
+			}
+			value = &v
+		}
+		cmd.names = append(cmd.names, name)
+		cmd.values = append(cmd.values, value)
+	}
+	return nil
+}
+
+// Handle 处理命令
+func (cmd *setMetadata) Handle(conn server.Conn) error {
+	ctx := conn.Context()
+	if ctx.User == nil {
+		return server.ErrNotAuthenticated
+	}
+	email := ctx.User.Username()
+	bgCtx := context.Background()
+
+	if err := cmd.ext.checkMailbox(bgCtx, ctx.User, cmd.mailbox); err != nil {
+		return err
+	}
+
+	existing, err := cmd.ext.storage.ListMetadata(bgCtx, email, cmd.mailbox)
+	if err != nil {
+		return err
+	}
+	count := len(existing)
+	stored := make(map[string]bool, count)
+	for _, entry := range existing {
+		stored[entry.Name] = true
+	}
+
+	// 先整体校验，避免部分条目写入后失败
+	for i, name := range cmd.names {
+		if name == "/private" || name == "/shared" {
+			return fmt.Errorf("不能设置顶层条目: %s", name)
+		}
+		if isVacationEntry(name) {
+			return errors.New("外出状态条目只读，请在 WebMail 中设置外出状态")
+		}
+		value := cmd.values[i]
+		if value == nil {
+			if stored[name] {
+				stored[name] = false
+				count--
+			}
+			continue
+		}
+		if len(*value) > MaxMetadataSize {
+			return server.ErrStatusResp(&imap.StatusResp{
+				Type:      imap.StatusRespNo,
+				Code:      "METADATA",
+				Arguments: []interface{}{imap.RawString("MAXSIZE"), uint32(MaxMetadataSize)},
+				Info:      "条目值过大",
+			})
+		}
+		if !stored[name] {
+			stored[name] = true
+			count++
+		}
+	}
+	if count > MaxMetadataEntries {
+		return server.ErrStatusResp(&imap.StatusResp{
+			Type:      imap.StatusRespNo,
+			Code:      "METADATA",
+			Arguments: []interface{}{imap.RawString("TOOMANY")},
+			Info:      "条目数量超过限制",
+		})
+	}
+
+	for i, name := range cmd.names {
+		if cmd.values[i] == nil {
+			if err := cmd.ext.storage.DeleteMetadata(bgCtx, email, cmd.mailbox, name); err != nil {
+				return err
+			}
+			continue
+		}
+		entry := &storage.MetadataEntry{
+			UserEmail: email,
+			Mailbox:   cmd.mailbox,
+			Name:      name,
+			Value:     *cmd.values[i],
+		}
+		if err := cmd.ext.storage.SetMetadata(bgCtx, entry); err != nil {
+			return err
+		}
+	}
+
+	logger.Debug().Str("user", email).Str("mailbox", cmd.mailbox).Int("entries", len(cmd.names)).Msg("SETMETADATA 完成")
+	return nil
+}
+
+// values 返回邮箱的全部条目值（服务器级条目包含同域用户的外出状态）
+func (ext *MetadataExtension) values(ctx context.Context, email, mailbox string, now time.Time) (map[string]string, error) {
+	entries, err := ext.storage.ListMetadata(ctx, email, mailbox)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		values[entry.Name] = entry.Value
+	}
+
+	if mailbox == "" {
+		vacations, err := ext.storage.ListVacations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		domain := emailDomain(email)
+		for _, vacation := range vacations {
+			if emailDomain(vacation.UserEmail) != domain || !vacation.Away(now) {
+				continue
+			}
+			values[VacationEntryPrefix+"/"+strings.ToLower(vacation.UserEmail)] = FormatVacation(vacation)
+		}
+	}
+	return values, nil
+}
+
+// checkMailbox 检查邮箱是否存在（空字符串表示服务器级条目），包括还没有邮件的文件夹
+func (ext *MetadataExtension) checkMailbox(ctx context.Context, user backend.User, mailbox string) error {
+	if mailbox == "" || mailbox == "INBOX" {
+		return nil
+	}
+	if u, ok := user.(*User); ok {
+		exists, err := u.mailboxExists(ctx, mailbox)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return backend.ErrNoSuchMailbox
+		}
+		return nil
+	}
+	folders, err := ext.storage.ListFolders(ctx, user.Username())
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		if folder == mailbox {
+			return nil
+		}
+	}
+	return backend.ErrNoSuchMailbox
+}
+
+// selectMetadata 按请求的条目和深度选出条目，返回 METADATA 响应的条目列表，
+// 以及因超过 maxSize 被省略的最长条目长度（0 表示没有省略）
+func selectMetadata(values map[string]string, requested []string, depth metadataDepth, maxSize int) ([]interface{}, int) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []interface{}
+	longest := 0
+	seen := make(map[string]bool)
+	add := func(name string, value *string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if value == nil {
+			result = append(result, name, nil)
+			return
+		}
+		if maxSize > 0 && len(*value) > maxSize {
+			if len(*value) > longest {
+				longest = len(*value)
+			}
+			return
+		}
+		result = append(result, name, formatMetadataValue(*value))
+	}
+
+	for _, entry := range requested {
+		if value, ok := values[entry]; ok {
+			add(entry, &value)
+		} else if depth == depthZero {
+			add(entry, nil)
+		}
+		if depth == depthZero {
+			continue
+		}
+		prefix := entry + "/"
+		for _, name := range names {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if depth == depthOne && strings.Contains(name[len(prefix):], "/") {
+				continue
+			}
+			value := values[name]
+			add(name, &value)
+		}
+	}
+	return result, longest
+}
+
+// formatMetadataValue 包含换行的值使用字面量发送（带引号字符串不能包含换行）
+func formatMetadataValue(value string) interface{} {
+	if strings.ContainsAny(value, "\r\n") {
+		return bytes.NewBufferString(value)
+	}
+	return value
+}
+
+// FormatVacation 格式化外出状态（如 "away until 2026-01-02T09:00:00Z: 出差"）
+func FormatVacation(vacation *storage.Vacation) string {
+	s := "away"
+	if vacation.EndsAt != nil {
+		s += " until " + vacation.EndsAt.UTC().Format(time.RFC3339)
+	}
+	if message := strings.Join(strings.Fields(vacation.Message), " "); message != "" {
+		s += ": " + message
+	}
+	return s
+}
+
+// parseMetadataMailbox 解析邮箱名称（空字符串表示服务器级条目）
+func parseMetadataMailbox(f interface{}) (string, error) {
+	mailbox, err := imap.ParseString(f)
+	if err != nil {
+		return "", err
+	}
+	if mailbox == "" {
+		return "", nil
+	}
+	if mailbox, err = utf7.Encoding.NewDecoder().String(mailbox); err != nil {
+		return "", err
+	}
+	return imap.CanonicalMailboxName(mailbox), nil
+}
+
+// normalizeEntryName 校验并规范化条目名称（条目名称大小写不敏感，统一为小写）
+func normalizeEntryName(name string) (string, error) {
+	name = strings.ToLower(name)
+	// GETMETADATA 可以用 /private 或 /shared 配合 DEPTH 列出全部条目
+	if name == "/private" || name == "/shared" {
+		return name, nil
+	}
+	if !strings.HasPrefix(name, "/private/") && !strings.HasPrefix(name, "/shared/") {
+		return "", fmt.Errorf("条目名称必须以 /private/ 或 /shared/ 开头: %s", name)
+	}
+	if strings.HasSuffix(name, "/") || strings.Contains(name, "//") || strings.ContainsAny(name, "*%") {
+		return "", fmt.Errorf("条目名称无效: %s", name)
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return "", fmt.Errorf("条目名称无效: %s", name)
+		}
+	}
+	return name, nil
+}
+
+// isVacationEntry 是否为外出状态条目（或其父条目）
+func isVacationEntry(name string) bool {
+	return name == VacationEntryPrefix || strings.HasPrefix(name, VacationEntryPrefix+"/")
+}
+
+// emailDomain 返回邮箱地址的域名部分（小写）
+func emailDomain(email string) string {
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		return strings.ToLower(email[i+1:])
+	}
+	return ""
+}
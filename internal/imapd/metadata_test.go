@@ -0,0 +1,123 @@
+package imapd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestGetMetadataParse(t *testing.T) {
+	cmd := &getMetadata{}
+	fields := []interface{}{
+		[]interface{}{imap.RawString("MAXSIZE"), imap.RawString("1024"), imap.RawString("DEPTH"), imap.RawString("infinity")},
+		"inbox",
+		[]interface{}{"/Private/Comment", "/shared/comment"},
+	}
+	if err := cmd.Parse(fields); err != nil {
+		t.Fatalf("解析 GETMETADATA 失败: %v", err)
+	}
+	if cmd.mailbox != "INBOX" || cmd.maxSize != 1024 || cmd.depth != depthInfinity {
+		t.Errorf("GETMETADATA 参数不正确: %+v", cmd)
+	}
+	if len(cmd.entries) != 2 || cmd.entries[0] != "/private/comment" {
+		t.Errorf("条目名称不正确: %v", cmd.entries)
+	}
+
+	// 单个条目、服务器级
+	cmd = &getMetadata{}
+	if err := cmd.Parse([]interface{}{"", "/shared/comment"}); err != nil {
+		t.Fatalf("解析 GETMETADATA 失败: %v", err)
+	}
+	if cmd.mailbox != "" || len(cmd.entries) != 1 {
+		t.Errorf("GETMETADATA 参数不正确: %+v", cmd)
+	}
+
+	for _, fields := range [][]interface{}{
+		{"INBOX"},
+		{"INBOX", "/comment"},
+		{[]interface{}{imap.RawString("DEPTH"), imap.RawString("2")}, "INBOX", "/shared/comment"},
+	} {
+		if err := (&getMetadata{}).Parse(fields); err == nil {
+			t.Errorf("参数 %v 应该解析失败", fields)
+		}
+	}
+}
+
+func TestSetMetadataParse(t *testing.T) {
+	cmd := &setMetadata{}
+	fields := []interface{}{"INBOX", []interface{}{"/private/comment", "hello", "/shared/comment", nil}}
+	if err := cmd.Parse(fields); err != nil {
+		t.Fatalf("解析 SETMETADATA 失败: %v", err)
+	}
+	if len(cmd.names) != 2 || cmd.values[0] == nil || *cmd.values[0] != "hello" || cmd.values[1] != nil {
+		t.Errorf("SETMETADATA 参数不正确: %+v", cmd)
+	}
+
+	if err := (&setMetadata{}).Parse([]interface{}{"INBOX", []interface{}{"/private/comment"}}); err == nil {
+		t.Error("缺少条目值时应该解析失败")
+	}
+}
+
+func TestNormalizeEntryName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"/private/comment", "/private/comment", false},
+		{"/SHARED/Vendor/x", "/shared/vendor/x", false},
+		{"/comment", "", true},
+		{"/private/comment/", "", true},
+		{"/private//comment", "", true},
+		{"/private/*", "", true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeEntryName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("normalizeEntryName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeEntryName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectMetadata(t *testing.T) {
+	values := map[string]string{
+		"/private/comment":   "c",
+		"/shared/vendor/a":   "1",
+		"/shared/vendor/a/b": "2",
+		"/shared/vendor/big": "0123456789",
+	}
+
+	// 不存在的条目返回 NIL
+	result, longest := selectMetadata(values, []string{"/private/comment", "/private/missing"}, depthZero, 0)
+	if len(result) != 4 || result[1] != "c" || result[3] != nil || longest != 0 {
+		t.Errorf("DEPTH 0 结果不正确: %v", result)
+	}
+
+	// DEPTH 1 只包含直接子条目，MAXSIZE 省略过大的条目
+	result, longest = selectMetadata(values, []string{"/shared/vendor"}, depthOne, 5)
+	if len(result) != 2 || result[0] != "/shared/vendor/a" || longest != 10 {
+		t.Errorf("DEPTH 1 结果不正确: %v, longest %d", result, longest)
+	}
+
+	result, _ = selectMetadata(values, []string{"/shared/vendor"}, depthInfinity, 0)
+	if len(result) != 6 {
+		t.Errorf("DEPTH infinity 结果不正确: %v", result)
+	}
+}
+
+func TestFormatVacation(t *testing.T) {
+	endsAt := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	vacation := &storage.Vacation{Enabled: true, Message: "出差\n请联系 bob", EndsAt: &endsAt}
+	if got := FormatVacation(vacation); got != "away until 2026-01-02T09:00:00Z: 出差 请联系 bob" {
+		t.Errorf("FormatVacation() = %q", got)
+	}
+	if got := FormatVacation(&storage.Vacation{Enabled: true}); got != "away" {
+		t.Errorf("FormatVacation() = %q", got)
+	}
+}
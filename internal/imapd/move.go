@@ -0,0 +1,102 @@
+package imapd
+
+import (
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/commands"
+	"github.com/emersion/go-imap/responses"
+	"github.com/emersion/go-imap/server"
+)
+
+// MoveExtension IMAP MOVE 扩展（RFC 6851），客户端移到废纸篓、拖拽移动文件夹时
+// 一次完成移动，不再使用 COPY + STORE \Deleted + EXPUNGE 留下副本
+//
+// go-imap 内置的 MOVE 只调用 backend.MoveMailbox，不发送 EXPUNGE 响应，
+// 而且 Server.Enable 会忽略提供 MOVE 命令的扩展，因此用 Register 注册
+type MoveExtension struct {
+	registered bool
+}
+
+// NewMoveExtension 创建 MOVE 扩展
+func NewMoveExtension() *MoveExtension {
+	return &MoveExtension{}
+}
+
+// Register 在服务器上启用扩展，覆盖内置的 MOVE 命令
+func (ext *MoveExtension) Register(s *server.Server) {
+	// Enable 时 Command("MOVE") 返回 nil，扩展才会被加入；之后再接管 MOVE
+	s.Enable(ext)
+	ext.registered = true
+}
+
+// Capabilities 返回扩展提供的能力（MOVE 已在内置能力中）
+func (ext *MoveExtension) Capabilities(c server.Conn) []string {
+	return nil
+}
+
+// Command 返回命令处理器
+func (ext *MoveExtension) Command(name string) server.HandlerFactory {
+	if !ext.registered || name != "MOVE" {
+		return nil
+	}
+	return func() server.Handler {
+		return &moveCommand{}
+	}
+}
+
+// moveCommand MOVE / UID MOVE
+type moveCommand struct {
+	commands.Move
+}
+
+// Handle 处理命令
+func (cmd *moveCommand) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID MOVE
+func (cmd *moveCommand) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *moveCommand) handle(uid bool, conn server.Conn) error {
+	if conn.Context().MailboxReadOnly {
+		return server.ErrMailboxReadOnly
+	}
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+
+	// 启用 QRESYNC 后返回 VANISHED，需要在移动前记录序列号对应的 UID
+	u, _ := conn.Context().User.(*User)
+	qresync := u != nil && u.qresync
+	uids := make(map[uint32]uint32)
+	if qresync {
+		for _, msg := range mbox.selectMessages(uid, cmd.SeqSet) {
+			uids[msg.seqNum] = msg.uid
+		}
+	}
+
+	// 部分邮件移动失败时，已移动的邮件仍然要通知客户端
+	moved, err := mbox.MoveMessages(uid, cmd.SeqSet, cmd.Mailbox)
+	if len(moved) > 0 && qresync {
+		var vanished imap.SeqSet
+		for _, seqNum := range moved {
+			vanished.AddNum(uids[seqNum])
+		}
+		if writeErr := writeVanishedUIDs(conn, &vanished); writeErr != nil {
+			return writeErr
+		}
+	} else if len(moved) > 0 {
+		ch := make(chan uint32, len(moved))
+		// 从后往前发送 EXPUNGE，前面的序列号不受影响
+		for i := len(moved) - 1; i >= 0; i-- {
+			ch <- moved[i]
+		}
+		close(ch)
+		if writeErr := conn.WriteResp(&responses.Expunge{SeqNums: ch}); writeErr != nil {
+			return writeErr
+		}
+	}
+	return err
+}
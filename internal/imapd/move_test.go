@@ -0,0 +1,323 @@
+package imapd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// newTestIMAP 启动带 MOVE 和 CONDSTORE 扩展的测试 IMAP 服务器，收件箱中有两封邮件，opts 用于调整后端
+func newTestIMAP(t *testing.T, opts ...func(*Backend)) (*client.Client, storage.Driver, *storage.Maildir) {
+	t.Helper()
+
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := crypto.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "me@example.com", PasswordHash: hash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, subject := range []string{"first", "second"} {
+		raw := []byte("From: a@example.net\r\nTo: me@example.com\r\nSubject: " + subject + "\r\n\r\nbody\r\n")
+		filename, err := maildir.StoreMail("me@example.com", "INBOX", raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := driver.StoreMail(ctx, &storage.Mail{ID: filename, UserEmail: "me@example.com", Folder: "INBOX",
+			From: "a@example.net", Subject: subject, Size: int64(len(raw)), ReceivedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		// 保证两封邮件的接收时间和文件名不同
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	bkd := NewBackend(driver, maildir, NewDefaultAuthenticator(driver))
+	for _, opt := range opts {
+		opt(bkd)
+	}
+	s := server.New(bkd)
+	s.AllowInsecureAuth = true
+	s.Enable(NewCondStoreExtension(driver))
+	NewMoveExtension().Register(s)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Close() })
+
+	c, err := client.Dial(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("连接 IMAP 服务器失败: %v", err)
+	}
+	t.Cleanup(func() { c.Logout() })
+	if err := c.Login("me@example.com", "secret"); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	return c, driver, maildir
+}
+
+// maildirFiles 返回文件夹 cur 和 new 中的文件数
+func maildirFiles(t *testing.T, maildir *storage.Maildir, folder string) int {
+	t.Helper()
+	dir := maildir.GetUserMaildir("me@example.com")
+	if folder != "INBOX" {
+		dir = filepath.Join(dir, storage.FolderDirName(folder))
+	}
+	count := 0
+	for _, sub := range []string{"cur", "new"} {
+		entries, _ := os.ReadDir(filepath.Join(dir, sub))
+		count += len(entries)
+	}
+	return count
+}
+
+func TestMove(t *testing.T) {
+	c, driver, maildir := newTestIMAP(t)
+	ctx := context.Background()
+
+	if ok, _ := c.Support("MOVE"); !ok {
+		t.Fatal("应该支持 MOVE")
+	}
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := make(chan client.Update, 10)
+	c.Updates = updates
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(1)
+	if err := c.Move(seqSet, "Trash"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+	c.Updates = nil
+	close(updates)
+	var expunged []uint32
+	for update := range updates {
+		if u, ok := update.(*client.ExpungeUpdate); ok {
+			expunged = append(expunged, u.SeqNum)
+		}
+	}
+	if len(expunged) != 1 || expunged[0] != 1 {
+		t.Errorf("应该收到 * 1 EXPUNGE, got %v", expunged)
+	}
+
+	inbox, _ := driver.ListMails(ctx, "me@example.com", "INBOX", 10, 0)
+	trash, _ := driver.ListMails(ctx, "me@example.com", "Trash", 10, 0)
+	if len(inbox) != 1 || len(trash) != 1 || trash[0].Subject != "first" || trash[0].UID != 1 {
+		t.Fatalf("移动后收件箱 %d 封，废纸篓 %d 封", len(inbox), len(trash))
+	}
+	if maildirFiles(t, maildir, "INBOX") != 1 || maildirFiles(t, maildir, "Trash") != 1 {
+		t.Errorf("邮件文件应该一起移动")
+	}
+
+	// 重新打开收件箱不会从 Maildir 同步回已移动的邮件
+	status, err := c.Select("INBOX", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Messages != 1 {
+		t.Errorf("收件箱应该只有 1 封邮件, got %d", status.Messages)
+	}
+
+	// UID MOVE 到自定义文件夹
+	uidSet := new(imap.SeqSet)
+	uidSet.AddNum(inbox[0].UID)
+	if err := c.UidMove(uidSet, "Archive"); err != nil {
+		t.Fatalf("UID MOVE 失败: %v", err)
+	}
+	archive, _ := driver.ListMails(ctx, "me@example.com", "Archive", 10, 0)
+	if len(archive) != 1 || maildirFiles(t, maildir, "Archive") != 1 || maildirFiles(t, maildir, "INBOX") != 0 {
+		t.Errorf("UID MOVE 后归档 %d 封", len(archive))
+	}
+}
+
+// fakeSpamReporter 记录用户投诉的邮件
+type fakeSpamReporter struct {
+	mu       sync.Mutex
+	subjects []string
+}
+
+func (f *fakeSpamReporter) ReportComplaint(ctx context.Context, userEmail string, raw []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if msg, err := mailparse.ParseHeader(raw); err == nil && userEmail == "me@example.com" {
+		f.subjects = append(f.subjects, msg.Subject)
+	}
+}
+
+func TestMoveToSpamReportsComplaint(t *testing.T) {
+	reporter := &fakeSpamReporter{}
+	c, _, _ := newTestIMAP(t, func(b *Backend) { b.spam = reporter })
+
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(1)
+	if err := c.Copy(seqSet, "Spam"); err != nil {
+		t.Fatalf("COPY 失败: %v", err)
+	}
+	if err := c.Move(seqSet, "Spam"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+	if err := c.Move(seqSet, "Trash"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+
+	// 垃圾邮件文件夹中的邮件移出时不记录
+	if _, err := c.Select("Spam", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Move(seqSet, "INBOX"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	if len(reporter.subjects) != 2 || reporter.subjects[0] != "first" || reporter.subjects[1] != "first" {
+		t.Errorf("复制和移入垃圾邮件文件夹应该各记录一次投诉: %v", reporter.subjects)
+	}
+}
+
+// fakeSpamTrainer 记录训练样本（主题和是否为垃圾邮件）
+type fakeSpamTrainer struct {
+	mu      sync.Mutex
+	samples []string
+}
+
+func (f *fakeSpamTrainer) Learn(userEmail string, raw []byte, spam bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if msg, err := mailparse.ParseHeader(raw); err == nil && userEmail == "me@example.com" {
+		f.samples = append(f.samples, fmt.Sprintf("%s:%t", msg.Subject, spam))
+	}
+}
+
+func TestSpamFolderTraining(t *testing.T) {
+	trainer := &fakeSpamTrainer{}
+	c, _, _ := newTestIMAP(t, func(b *Backend) { b.trainer = trainer })
+
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(1)
+	if err := c.Move(seqSet, "Spam"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+	if err := c.Copy(seqSet, "Archive"); err != nil {
+		t.Fatalf("COPY 失败: %v", err)
+	}
+
+	// 从垃圾邮件文件夹移回作为正常邮件训练，移到已删除邮件不训练
+	if _, err := c.Select("Spam", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Move(seqSet, "INBOX"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet = new(imap.SeqSet)
+	seqSet.AddNum(2)
+	if err := c.Move(seqSet, "Spam"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+	if _, err := c.Select("Spam", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet = new(imap.SeqSet)
+	seqSet.AddNum(1)
+	if err := c.Move(seqSet, "Trash"); err != nil {
+		t.Fatalf("MOVE 失败: %v", err)
+	}
+
+	trainer.mu.Lock()
+	defer trainer.mu.Unlock()
+	want := []string{"first:true", "first:false", "first:true"}
+	if !slices.Equal(trainer.samples, want) {
+		t.Errorf("训练样本 = %v，期望 %v", trainer.samples, want)
+	}
+}
+
+func TestExpungeDeletesFile(t *testing.T) {
+	c, driver, maildir := newTestIMAP(t)
+
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(2)
+	if err := c.Store(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.DeletedFlag}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Expunge(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := maildirFiles(t, maildir, "INBOX"); n != 1 {
+		t.Errorf("删除后应该只剩 1 个邮件文件, got %d", n)
+	}
+	status, err := c.Select("INBOX", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Messages != 1 {
+		t.Errorf("重新打开收件箱后应该只有 1 封邮件, got %d", status.Messages)
+	}
+	mails, _ := driver.ListMails(context.Background(), "me@example.com", "INBOX", 10, 0)
+	if len(mails) != 1 || mails[0].Subject != "first" {
+		t.Errorf("剩余邮件不正确: %d", len(mails))
+	}
+}
+
+func TestSpecialUse(t *testing.T) {
+	c, _, _ := newTestIMAP(t)
+
+	ch := make(chan *imap.MailboxInfo, 20)
+	if err := c.List("", "*", ch); err != nil {
+		t.Fatal(err)
+	}
+	attrs := make(map[string][]string)
+	for info := range ch {
+		attrs[info.Name] = info.Attributes
+	}
+	for name, want := range map[string]string{"Trash": imap.TrashAttr, "Archive": imap.ArchiveAttr, "Spam": imap.JunkAttr, "Sent": imap.SentAttr} {
+		found := false
+		for _, attr := range attrs[name] {
+			found = found || attr == want
+		}
+		if !found {
+			t.Errorf("%s 应该有 %s 属性: %v", name, want, attrs[name])
+		}
+	}
+}
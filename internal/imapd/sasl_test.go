@@ -0,0 +1,129 @@
+package imapd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/saslauth"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// cramMD5Client CRAM-MD5 客户端（go-sasl 没有提供）
+type cramMD5Client struct {
+	username, password string
+}
+
+func (c *cramMD5Client) Start() (string, []byte, error) {
+	return saslauth.CRAMMD5, nil, nil
+}
+
+func (c *cramMD5Client) Next(challenge []byte) ([]byte, error) {
+	mac := hmac.New(md5.New, []byte(c.password))
+	mac.Write(challenge)
+	return []byte(c.username + " " + hex.EncodeToString(mac.Sum(nil))), nil
+}
+
+// xoauth2Client XOAUTH2 客户端
+type xoauth2Client struct {
+	username, token string
+}
+
+func (c *xoauth2Client) Start() (string, []byte, error) {
+	return saslauth.XOAuth2, []byte("user=" + c.username + "\x01auth=Bearer " + c.token + "\x01\x01"), nil
+}
+
+func (c *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+func TestAuthenticateSASL(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	hash, err := crypto.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "me@example.com", PasswordHash: hash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := auth.NewJWTManager("test-secret", "example.com")
+	s := NewServer(&Config{
+		Storage:       driver,
+		Auth:          NewDefaultAuthenticator(driver),
+		MaxAuthErrors: 5,
+		SASL:          saslauth.New(saslauth.Config{Storage: driver, CRAMMD5: true, XOAuth2: true, JWT: jwt}),
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.server.Serve(listener)
+	t.Cleanup(func() { s.server.Close() })
+
+	dial := func() *client.Client {
+		t.Helper()
+		c, err := client.Dial(listener.Addr().String())
+		if err != nil {
+			t.Fatalf("连接 IMAP 服务器失败: %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	c := dial()
+	for _, mech := range []string{saslauth.CRAMMD5, saslauth.XOAuth2} {
+		if ok, err := c.SupportAuth(mech); err != nil || !ok {
+			t.Errorf("应该提供 AUTH=%s: %v", mech, err)
+		}
+	}
+	if ok, _ := c.SupportAuth(saslauth.OAuthBearer); ok {
+		t.Error("未启用的机制不应该提供")
+	}
+
+	// LOGIN 成功后保存 CRAM-MD5 密钥
+	if err := c.Authenticate(&cramMD5Client{"me@example.com", "secret"}); err == nil {
+		t.Fatal("没有密钥时 CRAM-MD5 应该失败")
+	}
+	if err := c.Login("me@example.com", "secret"); err != nil {
+		t.Fatalf("LOGIN 失败: %v", err)
+	}
+	c = dial()
+	if err := c.Authenticate(&cramMD5Client{"me@example.com", "secret"}); err != nil {
+		t.Fatalf("CRAM-MD5 认证失败: %v", err)
+	}
+	if c.State() != imap.AuthenticatedState {
+		t.Errorf("认证后应该进入已认证状态, got %v", c.State())
+	}
+
+	token, err := jwt.GenerateToken("me@example.com", 1, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = dial()
+	if err := c.Authenticate(&xoauth2Client{"me@example.com", "invalid"}); err == nil {
+		t.Error("无效的令牌不应该认证成功")
+	}
+	if err := c.Authenticate(&xoauth2Client{"me@example.com", token}); err != nil {
+		t.Fatalf("XOAUTH2 认证失败: %v", err)
+	}
+	if c.State() != imap.AuthenticatedState {
+		t.Errorf("认证后应该进入已认证状态, got %v", c.State())
+	}
+}
@@ -5,9 +5,18 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"time"
 
+	"github.com/emersion/go-imap/backend"
 	"github.com/emersion/go-imap/server"
+	"github.com/emersion/go-sasl"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+	"github.com/gomailzero/gmz/internal/saslauth"
 	"github.com/gomailzero/gmz/internal/storage"
 )
 
@@ -16,6 +25,7 @@ type Server struct {
 	config  *Config
 	backend *Backend
 	server  *server.Server
+	conns   drain.Tracker // 跟踪连接，停止时等待正在执行的命令完成
 }
 
 // Config IMAP 配置
@@ -26,17 +36,80 @@ type Config struct {
 	Storage storage.Driver
 	Maildir *storage.Maildir // Maildir 实例，用于读取邮件体
 	Auth    Authenticator
+	// MaxAuthErrors 单个连接的认证失败次数上限，达到后断开连接（0 不限制）
+	MaxAuthErrors int
+	// Limiter 按 IP 限制并发连接数，认证失败次数过多时封禁 IP（为空时不限制）
+	Limiter *limits.Limiter
+	// SASL PLAIN 以外的认证机制：CRAM-MD5、OAUTHBEARER、XOAUTH2（为空时只支持 PLAIN）
+	SASL *saslauth.Mechanisms
+	// SpamReporter 记录用户移入垃圾邮件文件夹的邮件（如内容指纹，为空时不记录）
+	SpamReporter SpamReporter
+	// SpamTrainer 用用户移入、移出垃圾邮件文件夹的邮件训练垃圾邮件分类器（为空时不训练）
+	SpamTrainer SpamTrainer
+	// Keyring 零访问存储的密钥（为空时未启用）：密码登录后解锁用户的私钥，未解锁时拒绝读取邮件内容
+	Keyring Keyring
+	// NewMail Maildir 监视器：新邮件到达时向选择了该邮箱的客户端（包括 IDLE 中的）推送 EXISTS（为空时不推送）
+	NewMail *maildirwatch.Watcher
+	// IdleTimeout 客户端没有活动（没有收发数据）的最长时间，超时自动登出（0 不限制）
+	IdleTimeout time.Duration
+	// LoginTimeout 连接后完成认证的最长时间，期间的 NOOP 等命令不会延长（0 不限制）
+	LoginTimeout time.Duration
+	// TimeoutObserver 超时断开的会话计数（可选）
+	TimeoutObserver drain.Observer
+	// Activity 账户活动记录：登录历史（为空时不记录）
+	Activity *activity.Recorder
+	// SubmissionFolder APPEND 到该文件夹的邮件投递给本地收件人（为空时 APPEND 只保存邮件）
+	SubmissionFolder string
+	// Greeting 欢迎信息文本（替换 "IMAP4rev1 Service Ready"，同一台服务器服务多个品牌时使用，为空时不替换）
+	Greeting string
+	// Forwarder 提交文件夹中发往别名的邮件转发到别名的外部目标（为空时不转发）
+	Forwarder *forward.Forwarder
+}
+
+// SpamReporter 记录用户投诉为垃圾邮件的邮件
+type SpamReporter interface {
+	ReportComplaint(ctx context.Context, userEmail string, raw []byte)
+}
+
+// SpamTrainer 垃圾邮件分类器训练（在后台进行，不阻塞 IMAP 命令）
+type SpamTrainer interface {
+	Learn(userEmail string, raw []byte, spam bool)
+}
+
+// Keyring 零访问存储的密钥状态，Open 在用户的会话中解密邮件
+type Keyring interface {
+	Unlock(ctx context.Context, email, password string) error
+	Enabled(ctx context.Context, email string) (bool, error)
+	Unlocked(email string) bool
+	Open(email string, data []byte) ([]byte, error)
 }
 
 // NewServer 创建 IMAP 服务器
 func NewServer(cfg *Config) *Server {
 	bkd := NewBackend(cfg.Storage, cfg.Maildir, cfg.Auth)
+	bkd.limiter = cfg.Limiter
+	bkd.authErrors = newAuthErrors(cfg.MaxAuthErrors)
+	bkd.sasl = cfg.SASL
+	bkd.spam = cfg.SpamReporter
+	bkd.trainer = cfg.SpamTrainer
+	bkd.keyring = cfg.Keyring
+	bkd.activity = cfg.Activity
+	bkd.submission = cfg.SubmissionFolder
+	bkd.forwarder = cfg.Forwarder
+	if cfg.NewMail != nil {
+		bkd.updates = make(chan backend.Update)
+		bkd.generations = newGenerations()
+	}
 
 	s := server.New(bkd)
 	s.Addr = fmt.Sprintf(":%d", cfg.Port)
-	
+
 	// 如果配置了 TLS，强制使用 TLS；否则允许非安全连接（仅用于开发环境）
-	if cfg.TLS != nil {
+	if cfg.TLS != nil && cfg.Greeting != "" {
+		// 欢迎信息的包装在 TLS 之上，go-imap 检测不到 TLS 连接；
+		// 所有连接都经过隐式 TLS 监听器（见 Start），因此允许认证且不提供 STARTTLS
+		s.AllowInsecureAuth = true
+	} else if cfg.TLS != nil {
 		s.AllowInsecureAuth = false // 强制 TLS
 		s.TLSConfig = cfg.TLS
 	} else {
@@ -45,11 +118,39 @@ func NewServer(cfg *Config) *Server {
 		s.AllowInsecureAuth = true
 	}
 
-	return &Server{
+	// METADATA 扩展（RFC 5464），服务器级条目包含同域用户的外出状态
+	s.Enable(NewMetadataExtension(cfg.Storage))
+	// CONDSTORE/QRESYNC 扩展（RFC 7162），按修改序号增量同步标志和删除的邮件；
+	// 覆盖的 SELECT/FETCH/STORE 非 CONDSTORE 部分交给 ANNOTATE 处理，因此先启用
+	annotate := NewAnnotateExtension(cfg.Storage)
+	s.Enable(NewCondStoreExtension(cfg.Storage, annotate))
+	// ANNOTATE 扩展（RFC 5257），邮件注解（颜色标签、备注）
+	s.Enable(annotate)
+	// THREAD 扩展（RFC 5256），按 References/In-Reply-To 组织会话
+	s.Enable(NewThreadExtension())
+	// MOVE 扩展（RFC 6851），邮件文件和数据库记录一起移动
+	NewMoveExtension().Register(s)
+
+	for _, mech := range cfg.SASL.Names() {
+		s.EnableAuth(mech, func(conn server.Conn) sasl.Server {
+			return bkd.saslServer(mech, conn)
+		})
+	}
+
+	srv := &Server{
 		config:  cfg,
 		backend: bkd,
 		server:  s,
 	}
+	srv.conns.Protocol = "imap"
+	srv.conns.IdleTimeouts = map[string]time.Duration{
+		drain.StateConnected: cfg.IdleTimeout,
+		"authenticated":      cfg.IdleTimeout,
+	}
+	srv.conns.StateTimeouts = map[string]time.Duration{drain.StateConnected: cfg.LoginTimeout}
+	srv.conns.Observer = cfg.TimeoutObserver
+	bkd.conns = &srv.conns
+	return srv
 }
 
 // Start 启动服务器
@@ -63,10 +164,14 @@ func (s *Server) Start(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("监听端口失败: %w", err)
 	}
+	// 拒绝被封禁 IP 和超过并发上限的连接
+	listener = s.config.Limiter.Listener("imap", listener)
+	listener = s.backend.authErrors.Listener(listener)
+	listener = s.conns.Listener(listener)
 
 	// 使用 TLS（如果已配置）
 	if s.config.TLS != nil {
-		if len(s.config.TLS.Certificates) == 0 {
+		if len(s.config.TLS.Certificates) == 0 && s.config.TLS.GetCertificate == nil {
 			return fmt.Errorf("IMAP 服务器 TLS 已启用但未配置证书，请检查 TLS 配置")
 		}
 		listener = tls.NewListener(listener, s.config.TLS)
@@ -74,23 +179,47 @@ func (s *Server) Start(ctx context.Context) error {
 	} else {
 		logger.Warn().Msg("IMAP 服务器未使用 TLS（仅用于开发环境）")
 	}
+	if s.config.Greeting != "" {
+		listener = &greetingListener{Listener: listener, greeting: s.config.Greeting}
+	}
 
 	logger.Info().Int("port", s.config.Port).Msg("IMAP 服务器启动")
 
-	if err := s.server.Serve(listener); err != nil {
+	if s.config.NewMail != nil {
+		go s.backend.watchNewMail(ctx, s.config.NewMail)
+	}
+
+	if err := s.server.Serve(listener); err != nil && !s.conns.Draining() {
 		return fmt.Errorf("IMAP 服务器错误: %w", err)
 	}
 
 	return nil
 }
 
-// Stop 停止服务器
+// Connections 返回当前的 IMAP 连接
+func (s *Server) Connections() []drain.Info {
+	return s.conns.List()
+}
+
+// Disconnect 强制断开 IMAP 连接（正在执行的命令被中断），连接不存在时返回 false
+func (s *Server) Disconnect(id uint64) bool {
+	return s.conns.Disconnect(id)
+}
+
+// Stop 停止服务器：不再接受新连接，空闲连接（包括 IDLE）立即断开，正在执行命令的连接在命令完成后断开；
+// ctx 截止时仍未完成的连接被强制关闭
 func (s *Server) Stop(ctx context.Context) error {
-	if err := s.server.Close(); err != nil {
-		logger.Error().Err(err).Msg("关闭 IMAP 服务器失败")
-		return err
+	s.conns.Drain()
+	err := s.conns.Wait(ctx)
+	if err != nil {
+		logger.Warn().Int("connections", s.conns.Active()).Msg("等待 IMAP 命令完成超时，强制关闭连接")
+		err = fmt.Errorf("等待 IMAP 命令完成超时: %w", err)
+	}
+
+	if closeErr := s.server.Close(); closeErr != nil {
+		logger.Error().Err(closeErr).Msg("关闭 IMAP 服务器失败")
 	}
 
 	logger.Info().Msg("IMAP 服务器已停止")
-	return nil
+	return err
 }
@@ -0,0 +1,401 @@
+package imapd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/server"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// ThreadExtension IMAP THREAD 扩展（RFC 5256），支持 REFERENCES 算法：
+// 按 Message-ID、References 和 In-Reply-To（投递时保存在数据库中）把选中邮箱中
+// 符合搜索条件的邮件组织成会话树，再把基础主题相同的会话合并
+type ThreadExtension struct{}
+
+// NewThreadExtension 创建 THREAD 扩展
+func NewThreadExtension() *ThreadExtension {
+	return &ThreadExtension{}
+}
+
+// Capabilities 返回扩展提供的能力
+func (ext *ThreadExtension) Capabilities(c server.Conn) []string {
+	return []string{"THREAD=REFERENCES"}
+}
+
+// Command 返回命令处理器
+func (ext *ThreadExtension) Command(name string) server.HandlerFactory {
+	if name != "THREAD" {
+		return nil
+	}
+	return func() server.Handler {
+		return &threadCommand{}
+	}
+}
+
+// threadCommand THREAD algorithm charset search-criteria
+type threadCommand struct {
+	server.Search
+}
+
+// Parse 解析命令参数，搜索条件由内置 SEARCH 解析
+func (cmd *threadCommand) Parse(fields []interface{}) error {
+	if len(fields) < 3 {
+		return errors.New("THREAD 参数数量不正确")
+	}
+	algorithm, ok := fields[0].(string)
+	if !ok || !strings.EqualFold(algorithm, "REFERENCES") {
+		return fmt.Errorf("不支持的会话算法: %v", fields[0])
+	}
+	if _, ok := fields[1].(string); !ok {
+		return errors.New("THREAD 字符集格式不正确")
+	}
+	return cmd.Search.Parse(append([]interface{}{"CHARSET", fields[1]}, fields[2:]...))
+}
+
+// Handle 处理命令
+func (cmd *threadCommand) Handle(conn server.Conn) error {
+	return cmd.handle(false, conn)
+}
+
+// UidHandle 处理 UID THREAD
+func (cmd *threadCommand) UidHandle(conn server.Conn) error {
+	return cmd.handle(true, conn)
+}
+
+func (cmd *threadCommand) handle(uid bool, conn server.Conn) error {
+	mbox, err := selectedMailbox(conn)
+	if err != nil {
+		return err
+	}
+	seqNums, err := mbox.SearchMessages(false, cmd.Criteria)
+	if err != nil {
+		return err
+	}
+
+	messages := make([]*threadMessage, 0, len(seqNums))
+	for _, seqNum := range seqNums {
+		mail := mbox.mails[seqNum-1]
+		num := seqNum
+		if uid && mail.UID != 0 {
+			num = mail.UID
+		}
+		messages = append(messages, newThreadMessage(num, mail))
+	}
+
+	fields := []interface{}{imap.RawString("THREAD")}
+	if threads := formatThreads(buildThreads(messages)); threads != "" {
+		fields = append(fields, imap.RawString(threads))
+	}
+	return conn.WriteResp(&imap.DataResp{Fields: fields})
+}
+
+// threadMessage 参与会话排序的邮件
+type threadMessage struct {
+	num        uint32 // 序列号或 UID
+	messageID  string
+	references []string // References，没有时为 In-Reply-To
+	subject    string
+	date       time.Time
+}
+
+// newThreadMessage 从邮件记录创建（没有保存发送日期，使用接收时间）
+func newThreadMessage(num uint32, mail *storage.Mail) *threadMessage {
+	msg := &threadMessage{
+		num:        num,
+		messageID:  mail.MessageID,
+		references: mail.References,
+		subject:    mail.Subject,
+		date:       mail.ReceivedAt,
+	}
+	if len(msg.references) == 0 && mail.InReplyTo != "" {
+		msg.references = []string{mail.InReplyTo}
+	}
+	return msg
+}
+
+// threadNode 会话树的节点，message 为 nil 表示只被引用、不在结果中的邮件（或合并主题时的虚拟根节点）
+type threadNode struct {
+	message  *threadMessage
+	parent   *threadNode
+	children []*threadNode
+}
+
+// isAncestorOf n 是否为 other 或其祖先
+func (n *threadNode) isAncestorOf(other *threadNode) bool {
+	for p := other; p != nil; p = p.parent {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+// setParent 把 n 移到 parent 下（parent 为 nil 时成为根节点）
+func (n *threadNode) setParent(parent *threadNode) {
+	if n.parent != nil {
+		siblings := n.parent.children
+		for i, child := range siblings {
+			if child == n {
+				n.parent.children = append(siblings[:i:i], siblings[i+1:]...)
+				break
+			}
+		}
+	}
+	n.parent = parent
+	if parent != nil {
+		parent.children = append(parent.children, n)
+	}
+}
+
+// date 节点的排序日期，虚拟节点使用第一个子节点的日期
+func (n *threadNode) date() (time.Time, uint32) {
+	if n.message != nil {
+		return n.message.date, n.message.num
+	}
+	if len(n.children) > 0 {
+		return n.children[0].date()
+	}
+	return time.Time{}, 0
+}
+
+// buildThreads RFC 5256 REFERENCES 算法，返回按日期排序的会话根节点
+func buildThreads(messages []*threadMessage) []*threadNode {
+	// 1. 按 Message-ID 和引用关系建立父子关系
+	nodes := make(map[string]*threadNode)
+	var order []*threadNode // 节点创建顺序，保证结果稳定
+	get := func(id string) *threadNode {
+		n, ok := nodes[id]
+		if !ok {
+			n = &threadNode{}
+			nodes[id] = n
+			order = append(order, n)
+		}
+		return n
+	}
+	for i, msg := range messages {
+		id := msg.messageID
+		if id == "" || (nodes[id] != nil && nodes[id].message != nil) {
+			// 没有或重复的 Message-ID 视为唯一的邮件
+			id = "\x00" + strconv.Itoa(i)
+		}
+		node := get(id)
+		node.message = msg
+
+		var prev *threadNode
+		for _, ref := range msg.references {
+			refNode := get(ref)
+			if prev != nil && refNode.parent == nil && !refNode.isAncestorOf(prev) {
+				refNode.setParent(prev)
+			}
+			prev = refNode
+		}
+		// 已有的父节点可能来自其他邮件被截断的 References，以本邮件的最后一个引用为准
+		if prev != nil && node.isAncestorOf(prev) {
+			prev = nil
+		}
+		node.setParent(prev)
+	}
+
+	// 2. 收集根节点并去掉空节点
+	var roots []*threadNode
+	for _, n := range order {
+		if n.parent == nil {
+			roots = append(roots, n)
+		}
+	}
+	roots = pruneThreads(nil, roots)
+
+	// 3. 子节点按日期排序
+	var sortTree func(list []*threadNode)
+	sortTree = func(list []*threadNode) {
+		for _, n := range list {
+			sortTree(n.children)
+		}
+		sortThreadNodes(list)
+	}
+	sortTree(roots)
+
+	// 4. 合并基础主题相同的会话
+	roots = groupBySubject(roots)
+	sortThreadNodes(roots)
+	return roots
+}
+
+// pruneThreads 去掉没有邮件的节点：没有子节点的删除，有子节点的由子节点代替
+// （根节点只有一个子节点时才代替，多个子节点保留为虚拟根节点）
+func pruneThreads(parent *threadNode, list []*threadNode) []*threadNode {
+	var result []*threadNode
+	for _, n := range list {
+		n.children = pruneThreads(n, n.children)
+		if n.message != nil {
+			result = append(result, n)
+			continue
+		}
+		if parent == nil && len(n.children) > 1 {
+			result = append(result, n)
+			continue
+		}
+		for _, child := range n.children {
+			child.parent = parent
+			result = append(result, child)
+		}
+	}
+	return result
+}
+
+// sortThreadNodes 按日期排序，日期相同时按编号
+func sortThreadNodes(list []*threadNode) {
+	sort.SliceStable(list, func(i, j int) bool {
+		di, ni := list[i].date()
+		dj, nj := list[j].date()
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return ni < nj
+	})
+}
+
+// groupBySubject 合并基础主题相同的会话：虚拟节点或非回复的会话优先作为合并目标
+func groupBySubject(roots []*threadNode) []*threadNode {
+	subjectOf := func(n *threadNode) (string, bool) {
+		if n.message == nil {
+			n = n.children[0]
+		}
+		return baseSubject(n.message.subject)
+	}
+
+	table := make(map[string]*threadNode)
+	for _, n := range roots {
+		subject, reply := subjectOf(n)
+		if subject == "" {
+			continue
+		}
+		old, ok := table[subject]
+		if !ok {
+			table[subject] = n
+			continue
+		}
+		_, oldReply := subjectOf(old)
+		if (old.message != nil && n.message == nil) || (old.message != nil && n.message != nil && oldReply && !reply) {
+			table[subject] = n
+		}
+	}
+
+	var result []*threadNode
+	for _, n := range roots {
+		subject, reply := subjectOf(n)
+		target, ok := table[subject]
+		if subject == "" || !ok || target == n {
+			result = append(result, n)
+			continue
+		}
+
+		_, targetReply := subjectOf(target)
+		switch {
+		case target.message == nil && n.message == nil:
+			for _, child := range n.children {
+				child.parent = target
+			}
+			target.children = append(target.children, n.children...)
+		case target.message == nil:
+			n.setParent(target)
+		case reply && !targetReply:
+			n.setParent(target)
+		default:
+			// 两个会话都不是回复（或都是回复）时作为新的虚拟根节点的子节点
+			dummy := &threadNode{}
+			copied := &threadNode{message: target.message, children: target.children}
+			for _, child := range copied.children {
+				child.parent = copied
+			}
+			copied.setParent(dummy)
+			n.setParent(dummy)
+			*target = *dummy
+			for _, child := range target.children {
+				child.parent = target
+			}
+		}
+		sortThreadNodes(target.children)
+	}
+	return result
+}
+
+var (
+	subjectTrailer = regexp.MustCompile(`(?i)(\s|\(fwd\))+$`)
+	subjectLeader  = regexp.MustCompile(`(?i)^\s*(re|fwd?)\s*(\[[^\[\]]*\]\s*)?:\s*`)
+	subjectBlob    = regexp.MustCompile(`^\s*\[[^\[\]]*\]\s*`)
+	subjectFwd     = regexp.MustCompile(`(?i)^\[fwd:\s*(.*)\]$`)
+	spaces         = regexp.MustCompile(`\s+`)
+)
+
+// baseSubject 基础主题（RFC 5256 第 2.1 节）：去掉 Re:、Fwd:、[列表名] 等前缀和 (fwd) 后缀，
+// 返回小写的基础主题和是否去掉过回复或转发标记
+func baseSubject(subject string) (string, bool) {
+	s := strings.ToLower(spaces.ReplaceAllString(strings.TrimSpace(subject), " "))
+	reply := false
+	for {
+		trimmed := subjectTrailer.ReplaceAllString(s, "")
+		if trimmed != s && strings.HasSuffix(strings.TrimSpace(s), "(fwd)") {
+			reply = true
+		}
+		s = trimmed
+
+		for {
+			if loc := subjectLeader.FindStringIndex(s); loc != nil {
+				s, reply = s[loc[1]:], true
+				continue
+			}
+			// 只有去掉后还有内容时才去掉 [列表名]
+			if loc := subjectBlob.FindStringIndex(s); loc != nil && loc[1] < len(s) {
+				s = s[loc[1]:]
+				continue
+			}
+			break
+		}
+
+		if m := subjectFwd.FindStringSubmatch(s); m != nil {
+			s, reply = m[1], true
+			continue
+		}
+		return s, reply
+	}
+}
+
+// formatThreads 格式化 THREAD 响应中的会话列表，如 (2)(3 6 (4 23)(44 7 96))
+func formatThreads(roots []*threadNode) string {
+	var b strings.Builder
+	for _, n := range roots {
+		b.WriteByte('(')
+		writeThread(&b, n)
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// writeThread 只有一个子节点时直接跟在节点之后，多个子节点各自加括号
+func writeThread(b *strings.Builder, n *threadNode) {
+	if n.message != nil {
+		b.WriteString(strconv.FormatUint(uint64(n.message.num), 10))
+		switch len(n.children) {
+		case 0:
+			return
+		case 1:
+			b.WriteByte(' ')
+			writeThread(b, n.children[0])
+			return
+		}
+		b.WriteByte(' ')
+	}
+	for _, child := range n.children {
+		b.WriteByte('(')
+		writeThread(b, child)
+		b.WriteByte(')')
+	}
+}
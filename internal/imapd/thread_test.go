@@ -0,0 +1,89 @@
+package imapd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+func TestBaseSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+		reply   bool
+	}{
+		{"Hello", "hello", false},
+		{"Re: Hello", "hello", true},
+		{"RE:  re: Fwd: Hello  ", "hello", true},
+		{"[list] Re: Hello", "hello", true},
+		{"Re [list]: Hello (fwd)", "hello", true},
+		{"[Fwd: Hello]", "hello", true},
+		{"[list]", "[list]", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, reply := baseSubject(tt.subject)
+		if got != tt.want || reply != tt.reply {
+			t.Errorf("baseSubject(%q) = %q, %v, want %q, %v", tt.subject, got, reply, tt.want, tt.reply)
+		}
+	}
+}
+
+func TestBuildThreads(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := func(num uint32, id, subject string, refs ...string) *threadMessage {
+		return &threadMessage{num: num, messageID: id, references: refs, subject: subject, date: base.Add(time.Duration(num) * time.Minute)}
+	}
+
+	messages := []*threadMessage{
+		msg(1, "a", "Meeting"),
+		msg(2, "b", "Re: Meeting", "a"),
+		msg(3, "c", "Re: Meeting", "a", "b"),
+		msg(4, "d", "Re: Meeting", "a"),
+		// 父邮件不在结果中，兄弟邮件在虚拟根节点下
+		msg(5, "e", "Lunch", "x"),
+		msg(6, "f", "Re: Lunch", "x"),
+		// 没有引用但主题相同的回复归入原会话
+		msg(7, "g", "Re: Meeting"),
+		// 没有 Message-ID 的独立邮件
+		msg(8, "", "Other"),
+		// 回复先于原邮件出现在结果中
+		msg(9, "i", "Re: Report", "h"),
+		msg(10, "h", "Report"),
+	}
+	got := formatThreads(buildThreads(messages))
+	want := "(1 (2 3)(4)(7))((5)(6))(8)(10 9)"
+	if got != want {
+		t.Errorf("THREAD = %s, want %s", got, want)
+	}
+
+	// 引用形成环时不会死循环
+	loop := []*threadMessage{msg(1, "a", "x", "b"), msg(2, "b", "y", "a")}
+	if got := formatThreads(buildThreads(loop)); got != "(2 1)" && got != "(1 2)" {
+		t.Errorf("THREAD = %s", got)
+	}
+	if got := formatThreads(buildThreads(nil)); got != "" {
+		t.Errorf("空结果 = %q", got)
+	}
+}
+
+func TestThreadParse(t *testing.T) {
+	cmd := &threadCommand{}
+	if err := cmd.Parse([]interface{}{"REFERENCES", "UTF-8", "ALL"}); err != nil {
+		t.Fatalf("解析 THREAD 失败: %v", err)
+	}
+	if cmd.Criteria == nil {
+		t.Fatal("应该解析搜索条件")
+	}
+	cmd = &threadCommand{}
+	if err := cmd.Parse([]interface{}{"references", "US-ASCII", "UNSEEN"}); err != nil || len(cmd.Criteria.WithoutFlags) != 1 || cmd.Criteria.WithoutFlags[0] != imap.SeenFlag {
+		t.Errorf("搜索条件不正确: %+v, err = %v", cmd.Criteria, err)
+	}
+	if err := (&threadCommand{}).Parse([]interface{}{"ORDEREDSUBJECT", "UTF-8", "ALL"}); err == nil {
+		t.Error("不支持的算法应该解析失败")
+	}
+	if err := (&threadCommand{}).Parse([]interface{}{"REFERENCES", "UTF-8"}); err == nil {
+		t.Error("缺少搜索条件应该解析失败")
+	}
+}
@@ -0,0 +1,116 @@
+package imapd
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+)
+
+// generations 每个邮箱收到新邮件的次数：Mailbox 保存的是选择邮箱时的邮件列表，
+// 计数变化后在下一条命令之前把新邮件追加到末尾（见 Mailbox.sync）
+type generations struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func newGenerations() *generations {
+	return &generations{counts: make(map[string]uint64)}
+}
+
+// key 用户和邮箱名称组成的键
+func (g *generations) key(userEmail, folder string) string {
+	return strings.ToLower(userEmail) + "\x00" + folder
+}
+
+// get 返回邮箱的新邮件计数（为空时始终为 0）
+func (g *generations) get(userEmail, folder string) uint64 {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.counts[g.key(userEmail, folder)]
+}
+
+// bump 邮箱收到新邮件
+func (g *generations) bump(userEmail, folder string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.counts[g.key(userEmail, folder)]++
+}
+
+// Updates 返回推送给客户端的通知（实现 backend.BackendUpdater，没有配置 Maildir 监视器时不会有通知）
+func (b *Backend) Updates() <-chan backend.Update {
+	return b.updates
+}
+
+// watchNewMail 把 Maildir 监视器的新邮件事件转换为 EXISTS 通知，推送给选择了该邮箱的连接（包括 IDLE 中的连接），
+// 直到 ctx 取消
+func (b *Backend) watchNewMail(ctx context.Context, watcher *maildirwatch.Watcher) {
+	events, cancel := watcher.Subscribe("")
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			b.generations.bump(e.UserEmail, e.Folder)
+
+			count, err := b.storage.CountMails(ctx, e.UserEmail, e.Folder)
+			if err != nil {
+				logger.Warn().Err(err).Str("user", e.UserEmail).Str("folder", e.Folder).Msg("统计邮件数失败，不推送新邮件通知")
+				continue
+			}
+			status := imap.NewMailboxStatus(e.Folder, []imap.StatusItem{imap.StatusMessages})
+			// #nosec G115 -- 邮件数不会超过 uint32
+			status.Messages = uint32(count)
+			update := &backend.MailboxUpdate{
+				Update:        backend.NewUpdate(e.UserEmail, e.Folder),
+				MailboxStatus: status,
+			}
+			select {
+			case b.updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sync 邮箱在选择之后收到新邮件时，把新邮件（UID 大于已有邮件）追加到末尾，已有邮件的序号不变
+func (m *Mailbox) sync() {
+	generation := m.generations.get(m.userEmail, m.name)
+	if generation == m.generation {
+		return
+	}
+
+	var maxUID uint32
+	for _, mail := range m.mails {
+		maxUID = max(maxUID, mail.UID)
+	}
+	// 只读取 UID 大于已有邮件的新邮件
+	for {
+		mails, err := m.storage.ListMailIDs(context.Background(), m.userEmail, m.name, maxUID, mailPageSize)
+		if err != nil {
+			logger.Warn().Err(err).Str("user", m.userEmail).Str("folder", m.name).Msg("加载新邮件失败")
+			return
+		}
+		m.mails = append(m.mails, mails...)
+		if len(mails) < mailPageSize {
+			break
+		}
+		maxUID = mails[len(mails)-1].UID
+	}
+	m.generation = generation
+}
+
+// Poll 加载选择邮箱之后收到的新邮件（NOOP 时调用，实现 backend.MailboxPoller）
+func (m *Mailbox) Poll() error {
+	m.sync()
+	return nil
+}
@@ -0,0 +1,71 @@
+package imapd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/client"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+)
+
+func TestNewMailUpdates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var watcher *maildirwatch.Watcher
+	c, driver, maildir := newTestIMAP(t, func(b *Backend) {
+		b.updates = make(chan backend.Update, 16)
+		b.generations = newGenerations()
+		watcher = maildirwatch.New(b.maildir, b.storage)
+		go func() { _ = watcher.Run(ctx) }()
+		go b.watchNewMail(ctx, watcher)
+	})
+	updates := make(chan client.Update, 16)
+	c.Updates = updates
+	if _, err := c.Select("INBOX", false); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond) // 等待建立监视
+
+	// 外部投递代理写入新邮件
+	userDir := maildir.GetUserMaildir("me@example.com")
+	tmp := filepath.Join(userDir, "tmp", "1700000000.ext.host")
+	if err := os.WriteFile(tmp, []byte("From: b@example.net\r\nSubject: third\r\n\r\nbody\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(userDir, "new", "1700000000.ext.host")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已选择邮箱的连接收到 EXISTS，之后可以读取新邮件
+	deadline := time.After(5 * time.Second)
+	for exists := uint32(0); exists != 3; {
+		select {
+		case u := <-updates:
+			if mu, ok := u.(*client.MailboxUpdate); ok {
+				exists = mu.Mailbox.Messages
+			}
+		case <-deadline:
+			t.Fatal("没有收到 EXISTS 通知")
+		}
+	}
+	if _, err := driver.GetMail(ctx, "1700000000.ext.host"); err != nil {
+		t.Fatalf("外部写入的邮件没有索引: %v", err)
+	}
+
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(3)
+	messages := make(chan *imap.Message, 1)
+	if err := c.Fetch(seqset, []imap.FetchItem{imap.FetchEnvelope}, messages); err != nil {
+		t.Fatalf("读取新邮件失败: %v", err)
+	}
+	msg := <-messages
+	if msg == nil || msg.Envelope == nil || msg.Envelope.Subject != "third" {
+		t.Errorf("新邮件 = %+v", msg)
+	}
+}
@@ -0,0 +1,89 @@
+package jmapd
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// handleDownload 下载 blob（RFC 8620 6.2）：blobId 为邮件 ID（完整邮件原文）
+// 或 邮件 ID/部分（text、html 正文或附件的部分编号）
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(ctx)
+	if r.PathValue("accountId") != accountID(user) {
+		writeProblem(w, http.StatusNotFound, "", "账户不存在")
+		return
+	}
+	blobID, ok := decodeID(r.PathValue("blobId"))
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "", "blob 不存在")
+		return
+	}
+	mailID, part, _ := strings.Cut(blobID, "/")
+
+	m, err := s.storage.GetMail(ctx, mailID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logger.ErrorCtx(ctx).Err(err).Str("mail_id", mailID).Msg("JMAP 查询邮件失败")
+		writeProblem(w, http.StatusInternalServerError, "", "查询邮件失败")
+		return
+	}
+	if m == nil || m.UserEmail != user.Email || s.maildir == nil {
+		writeProblem(w, http.StatusNotFound, "", "blob 不存在")
+		return
+	}
+	raw, err := s.maildir.ReadMail(m.UserEmail, m.Folder, m.ID)
+	if errors.Is(err, storage.ErrMailboxLocked) {
+		writeProblem(w, http.StatusServiceUnavailable, "", "邮箱已锁定")
+		return
+	}
+	if err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("mail_id", m.ID).Msg("JMAP 读取邮件原文失败")
+		writeProblem(w, http.StatusNotFound, "", "blob 不存在")
+		return
+	}
+
+	contentType, data := "message/rfc822", raw
+	if part != "" {
+		msg, err := mailparse.Parse(raw)
+		if err != nil {
+			writeProblem(w, http.StatusNotFound, "", "blob 不存在")
+			return
+		}
+		switch part {
+		case "text":
+			contentType, data = "text/plain; charset=utf-8", []byte(msg.Text)
+		case "html":
+			contentType, data = "text/html; charset=utf-8", []byte(msg.HTML)
+		default:
+			found := false
+			for _, p := range append(msg.Attachments, msg.Inline...) {
+				if p.Number == part {
+					contentType, data, found = p.ContentType, p.Data, true
+					break
+				}
+			}
+			if !found {
+				writeProblem(w, http.StatusNotFound, "", "blob 不存在")
+				return
+			}
+		}
+	}
+	// 客户端可以通过 accept 参数指定返回的类型
+	if accept := r.URL.Query().Get("accept"); accept != "" {
+		if _, _, err := mime.ParseMediaType(accept); err == nil {
+			contentType = accept
+		}
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": r.PathValue("name")}))
+	w.Header().Set("Cache-Control", "private, immutable, max-age=31536000")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,827 @@
+package jmapd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// Email/query 的结果数限制
+const (
+	defaultQueryLimit = 256
+	maxQueryLimit     = 1000
+	// maxQueryResults 一次查询最多匹配的邮件数（排序和分页在内存中进行）
+	maxQueryResults = 10000
+)
+
+// maxPreviewLength 邮件预览的最大字符数（RFC 8621 4.1.4）
+const maxPreviewLength = 256
+
+// systemKeywords JMAP 关键字与 IMAP 系统标志的对应关系
+var systemKeywords = map[string]string{
+	"$seen":     `\Seen`,
+	"$flagged":  `\Flagged`,
+	"$answered": `\Answered`,
+	"$draft":    `\Draft`,
+}
+
+// keywordOf 标志对应的 JMAP 关键字（小写），\Recent 和 \Deleted 等没有对应的关键字
+func keywordOf(flag string) (string, bool) {
+	for keyword, system := range systemKeywords {
+		if strings.EqualFold(flag, system) {
+			return keyword, true
+		}
+	}
+	if strings.HasPrefix(flag, `\`) {
+		return "", false
+	}
+	return strings.ToLower(flag), true
+}
+
+// validKeyword 关键字是否有效（RFC 8621 4.1.1，与 IMAP 的 flag-keyword 相同）
+func validKeyword(keyword string) bool {
+	if keyword == "" || len(keyword) > 255 {
+		return false
+	}
+	for i := 0; i < len(keyword); i++ {
+		b := keyword[i]
+		if b < 0x21 || b > 0x7e || strings.IndexByte(`(){]%*"\`, b) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// keywords 邮件的 JMAP 关键字
+func keywords(flags []string) map[string]bool {
+	result := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		if keyword, ok := keywordOf(flag); ok {
+			result[keyword] = true
+		}
+	}
+	return result
+}
+
+// applyKeywords 按新的关键字集合生成标志：保留没有对应关键字的标志，已有标志保持原来的大小写
+func applyKeywords(flags []string, set map[string]bool) []string {
+	result := []string{}
+	existing := make(map[string]string)
+	for _, flag := range flags {
+		if keyword, ok := keywordOf(flag); ok {
+			existing[keyword] = flag
+		} else {
+			result = append(result, flag)
+		}
+	}
+	for keyword := range set {
+		switch {
+		case existing[keyword] != "":
+			result = append(result, existing[keyword])
+		case systemKeywords[keyword] != "":
+			result = append(result, systemKeywords[keyword])
+		default:
+			result = append(result, keyword)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// state 用户邮件的状态（修改序号，邮件新增、标志变化、移动和删除时递增；邮箱状态使用相同的值）
+func (s *Server) state(c *call) (string, error) {
+	// limit 为 0 时只返回当前的修改序号
+	changes, err := s.storage.GetMailChanges(c.ctx, c.user.Email, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatUint(changes.ModSeq, 10), nil
+}
+
+// ownMail 获取当前用户的邮件（ID 无效、不存在或属于其他用户时返回 nil）
+func (s *Server) ownMail(c *call, id string) (*storage.Mail, error) {
+	mailID, ok := decodeID(id)
+	if !ok {
+		return nil, nil
+	}
+	m, err := s.storage.GetMail(c.ctx, mailID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.UserEmail != c.user.Email {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// emailFilter Email/query 的过滤条件（RFC 8621 4.4.1，不支持 FilterOperator 和正文搜索）
+type emailFilter struct {
+	InMailbox          string     `json:"inMailbox"`
+	InMailboxOtherThan []string   `json:"inMailboxOtherThan"`
+	Before             *time.Time `json:"before"`
+	After              *time.Time `json:"after"`
+	MinSize            int64      `json:"minSize"`
+	MaxSize            int64      `json:"maxSize"`
+	HasKeyword         string     `json:"hasKeyword"`
+	NotKeyword         string     `json:"notKeyword"`
+	HasAttachment      *bool      `json:"hasAttachment"`
+	Text               string     `json:"text"`
+	From               string     `json:"from"`
+	To                 string     `json:"to"`
+	Cc                 string     `json:"cc"`
+	Bcc                string     `json:"bcc"`
+	Subject            string     `json:"subject"`
+}
+
+// comparator 排序条件
+type comparator struct {
+	Property    string `json:"property"`
+	IsAscending *bool  `json:"isAscending"`
+	Collation   string `json:"collation"`
+}
+
+// queryArgs Email/query 的参数
+type queryArgs struct {
+	AccountID       string          `json:"accountId"`
+	Filter          json.RawMessage `json:"filter"`
+	Sort            []comparator    `json:"sort"`
+	Position        int             `json:"position"`
+	Anchor          *string         `json:"anchor"`
+	AnchorOffset    int             `json:"anchorOffset"`
+	Limit           *int            `json:"limit"`
+	CalculateTotal  bool            `json:"calculateTotal"`
+	CollapseThreads bool            `json:"collapseThreads"`
+}
+
+// parseFilter 解析过滤条件
+func parseFilter(raw json.RawMessage) (*emailFilter, error) {
+	filter := &emailFilter{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return filter, nil
+	}
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, invalidArguments("filter 必须是对象")
+	}
+	if _, ok := probe["operator"]; ok {
+		return nil, &methodError{Type: "unsupportedFilter", Description: "不支持 FilterOperator"}
+	}
+	if err := decodeArgs(raw, filter); err != nil {
+		return nil, &methodError{Type: "unsupportedFilter", Description: err.Error()}
+	}
+	return filter, nil
+}
+
+// searchQuery 过滤条件中数据库可以直接查询的部分，返回查询和文件夹（inMailbox）
+func (f *emailFilter) searchQuery() (*storage.SearchQuery, string, error) {
+	query := &storage.SearchQuery{Before: f.Before, After: f.After}
+	if f.Text != "" {
+		query.Text = []string{f.Text}
+	}
+	if f.From != "" {
+		query.From = []string{f.From}
+	}
+	if f.To != "" {
+		query.To = []string{f.To}
+	}
+	if f.Subject != "" {
+		query.Subject = []string{f.Subject}
+	}
+	if f.HasAttachment != nil && *f.HasAttachment {
+		query.HasAttachment = true
+	}
+	if f.MinSize > 0 {
+		query.Larger = f.MinSize - 1
+	}
+	if f.MaxSize > 0 {
+		query.Smaller = f.MaxSize
+	}
+	folder := ""
+	if f.InMailbox != "" {
+		var ok bool
+		if folder, ok = decodeID(f.InMailbox); !ok {
+			return nil, "", invalidArguments("无效的邮箱 ID %s", f.InMailbox)
+		}
+	}
+	return query, folder, nil
+}
+
+// match 检查数据库查询之外的条件
+func (f *emailFilter) match(m *storage.Mail) bool {
+	for _, id := range f.InMailboxOtherThan {
+		if folder, ok := decodeID(id); ok && folder == m.Folder {
+			return false
+		}
+	}
+	kw := keywords(m.Flags)
+	if f.HasKeyword != "" && !kw[strings.ToLower(f.HasKeyword)] {
+		return false
+	}
+	if f.NotKeyword != "" && kw[strings.ToLower(f.NotKeyword)] {
+		return false
+	}
+	if f.HasAttachment != nil && !*f.HasAttachment && m.HasAttachment {
+		return false
+	}
+	contains := func(list []string, s string) bool {
+		return strings.Contains(strings.ToLower(strings.Join(list, ", ")), strings.ToLower(s))
+	}
+	if f.Cc != "" && !contains(m.Cc, f.Cc) {
+		return false
+	}
+	if f.Bcc != "" && !contains(m.Bcc, f.Bcc) {
+		return false
+	}
+	return true
+}
+
+// queryEmails Email/query：按条件查询邮件 ID，支持按接收时间或大小排序
+func (s *Server) queryEmails(c *call, rawArgs json.RawMessage) (interface{}, error) {
+	var args queryArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	filter, err := parseFilter(args.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if args.CollapseThreads {
+		return nil, invalidArguments("不支持 collapseThreads")
+	}
+	limit := defaultQueryLimit
+	if args.Limit != nil {
+		if *args.Limit < 0 {
+			return nil, invalidArguments("limit 不能为负数")
+		}
+		limit = *args.Limit
+	}
+	limitCapped := limit > maxQueryLimit
+	if limitCapped {
+		limit = maxQueryLimit
+	}
+
+	// 默认按接收时间倒序（最新的在前）
+	less := func(a, b *storage.Mail) bool { return a.ReceivedAt.After(b.ReceivedAt) }
+	if len(args.Sort) > 1 {
+		return nil, &methodError{Type: "unsupportedSort", Description: "只支持一个排序条件"}
+	}
+	for _, cmp := range args.Sort {
+		ascending := cmp.IsAscending == nil || *cmp.IsAscending
+		switch cmp.Property {
+		case "receivedAt":
+			less = func(a, b *storage.Mail) bool { return a.ReceivedAt.Before(b.ReceivedAt) == ascending }
+		case "size":
+			less = func(a, b *storage.Mail) bool { return (a.Size < b.Size) == ascending }
+		default:
+			return nil, &methodError{Type: "unsupportedSort", Description: "不支持按 " + cmp.Property + " 排序"}
+		}
+	}
+
+	state, err := s.state(c)
+	if err != nil {
+		return nil, err
+	}
+	query, folder, err := filter.searchQuery()
+	if err != nil {
+		return nil, err
+	}
+	found, err := s.storage.SearchMails(c.ctx, c.user.Email, query, folder, maxQueryResults, 0)
+	if err != nil {
+		return nil, err
+	}
+	mails := make([]*storage.Mail, 0, len(found))
+	for _, m := range found {
+		if filter.match(m) {
+			mails = append(mails, m)
+		}
+	}
+	sort.SliceStable(mails, func(i, j int) bool { return less(mails[i], mails[j]) })
+
+	total := len(mails)
+	position := args.Position
+	if args.Anchor != nil {
+		position = -1
+		for i, m := range mails {
+			if encodeID(m.ID) == *args.Anchor {
+				position = i + args.AnchorOffset
+				break
+			}
+		}
+		if position < 0 && args.AnchorOffset >= 0 {
+			return nil, &methodError{Type: "anchorNotFound"}
+		}
+	}
+	if position < 0 {
+		position = max(total+position, 0)
+	}
+	position = min(position, total)
+	end := min(position+limit, total)
+
+	ids := make([]string, 0, end-position)
+	for _, m := range mails[position:end] {
+		ids = append(ids, encodeID(m.ID))
+	}
+	result := map[string]interface{}{
+		"accountId":           args.AccountID,
+		"queryState":          state,
+		"canCalculateChanges": false,
+		"position":            position,
+		"ids":                 ids,
+	}
+	if args.CalculateTotal {
+		result["total"] = total
+	}
+	if limitCapped {
+		result["limit"] = limit
+	}
+	return result, nil
+}
+
+// emailGetArgs Email/get 的参数
+type emailGetArgs struct {
+	AccountID           string    `json:"accountId"`
+	IDs                 *[]string `json:"ids"`
+	Properties          *[]string `json:"properties"`
+	BodyProperties      *[]string `json:"bodyProperties"`
+	FetchTextBodyValues bool      `json:"fetchTextBodyValues"`
+	FetchHTMLBodyValues bool      `json:"fetchHTMLBodyValues"`
+	FetchAllBodyValues  bool      `json:"fetchAllBodyValues"`
+	MaxBodyValueBytes   int       `json:"maxBodyValueBytes"`
+}
+
+// defaultEmailProperties Email/get 没有指定属性时返回的属性（RFC 8621 4.2）
+var defaultEmailProperties = []string{
+	"id", "blobId", "threadId", "mailboxIds", "keywords", "size", "receivedAt",
+	"messageId", "inReplyTo", "references", "sender", "from", "to", "cc", "bcc", "replyTo", "subject", "sentAt",
+	"hasAttachment", "preview", "bodyValues", "textBody", "htmlBody", "attachments",
+}
+
+// metadataProperties 直接来自数据库的属性，其余属性需要读取邮件原文
+var metadataProperties = map[string]bool{
+	"id": true, "blobId": true, "threadId": true, "mailboxIds": true, "keywords": true,
+	"size": true, "receivedAt": true, "subject": true, "hasAttachment": true,
+}
+
+// getEmails Email/get：邮件元数据来自数据库，邮件头、正文和附件从 Maildir 中的原文解析
+func (s *Server) getEmails(c *call, rawArgs json.RawMessage) (interface{}, error) {
+	var args emailGetArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	if args.IDs == nil {
+		return nil, &methodError{Type: "requestTooLarge", Description: "必须指定 ids"}
+	}
+	if len(*args.IDs) > maxObjectsInGet {
+		return nil, &methodError{Type: "requestTooLarge"}
+	}
+	properties := defaultEmailProperties
+	if args.Properties != nil {
+		properties = *args.Properties
+	}
+	needRaw := false
+	for _, property := range properties {
+		needRaw = needRaw || !metadataProperties[property]
+	}
+
+	state, err := s.state(c)
+	if err != nil {
+		return nil, err
+	}
+	list := []map[string]interface{}{}
+	notFound := []string{}
+	for _, id := range *args.IDs {
+		m, err := s.ownMail(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			notFound = append(notFound, id)
+			continue
+		}
+		var msg *mailparse.Message
+		if needRaw && s.maildir != nil {
+			raw, err := s.maildir.ReadMail(m.UserEmail, m.Folder, m.ID)
+			if err != nil {
+				// 零访问存储锁定或文件缺失时只返回元数据
+				logger.DebugCtx(c.ctx).Err(err).Str("mail_id", m.ID).Msg("JMAP 读取邮件原文失败")
+			} else if parsed, err := mailparse.Parse(raw); err == nil {
+				msg = parsed
+			}
+		}
+		email := emailObject(m, msg, &args)
+		filtered := map[string]interface{}{"id": email["id"]}
+		for _, property := range properties {
+			if value, ok := email[property]; ok {
+				filtered[property] = value
+			}
+		}
+		list = append(list, filtered)
+	}
+	return map[string]interface{}{
+		"accountId": args.AccountID,
+		"state":     state,
+		"list":      list,
+		"notFound":  notFound,
+	}, nil
+}
+
+// emailObject 邮件对应的 Email 对象（msg 为空时原文相关的属性为 null）
+func emailObject(m *storage.Mail, msg *mailparse.Message, args *emailGetArgs) map[string]interface{} {
+	threadID := m.ThreadID
+	if threadID == "" {
+		threadID = m.ID
+	}
+	email := map[string]interface{}{
+		"id":            encodeID(m.ID),
+		"blobId":        encodeID(m.ID),
+		"threadId":      encodeID(threadID),
+		"mailboxIds":    map[string]bool{encodeID(m.Folder): true},
+		"keywords":      keywords(m.Flags),
+		"size":          m.Size,
+		"receivedAt":    m.ReceivedAt.UTC().Format(time.RFC3339),
+		"subject":       m.Subject,
+		"hasAttachment": m.HasAttachment,
+	}
+	for _, property := range []string{"messageId", "inReplyTo", "references", "sender", "from", "to", "cc", "bcc",
+		"replyTo", "sentAt", "preview", "bodyValues", "textBody", "htmlBody", "attachments"} {
+		email[property] = nil
+	}
+	if msg == nil {
+		return email
+	}
+
+	if msg.Subject != "" {
+		email["subject"] = msg.Subject
+	}
+	if msg.MessageID != "" {
+		email["messageId"] = []string{msg.MessageID}
+	}
+	if msg.InReplyTo != "" {
+		email["inReplyTo"] = []string{msg.InReplyTo}
+	}
+	if len(msg.References) > 0 {
+		email["references"] = msg.References
+	}
+	if msg.From != nil {
+		email["from"] = addresses([]*mail.Address{msg.From})
+	}
+	email["to"] = addresses(msg.To)
+	email["cc"] = addresses(msg.Cc)
+	email["bcc"] = addresses(msg.Bcc)
+	email["replyTo"] = addresses(msg.ReplyTo)
+	if !msg.Date.IsZero() {
+		email["sentAt"] = msg.Date.Format(time.RFC3339)
+	}
+	email["preview"] = preview(msg)
+
+	// 正文只有第一个纯文本和第一个 HTML 部分，partId 分别为 text 和 html
+	blobID := func(part string) string { return encodeID(m.ID + "/" + part) }
+	textBody, htmlBody := []map[string]interface{}{}, []map[string]interface{}{}
+	bodyValues := map[string]interface{}{}
+	addBody := func(partID, mimeType, value string, fetch bool) map[string]interface{} {
+		if fetch {
+			truncated := false
+			if args.MaxBodyValueBytes > 0 && len(value) > args.MaxBodyValueBytes {
+				value, truncated = truncateUTF8(value, args.MaxBodyValueBytes), true
+			}
+			bodyValues[partID] = map[string]interface{}{"value": value, "isEncodingProblem": false, "isTruncated": truncated}
+		}
+		return map[string]interface{}{"partId": partID, "blobId": blobID(partID), "size": len(value), "type": mimeType, "charset": "utf-8"}
+	}
+	if msg.Text != "" {
+		part := addBody("text", "text/plain", msg.Text, args.FetchTextBodyValues || args.FetchAllBodyValues)
+		textBody = append(textBody, part)
+		if msg.HTML == "" {
+			htmlBody = append(htmlBody, part)
+		}
+	}
+	if msg.HTML != "" {
+		part := addBody("html", "text/html", msg.HTML, args.FetchHTMLBodyValues || args.FetchAllBodyValues)
+		htmlBody = append(htmlBody, part)
+		if msg.Text == "" {
+			textBody = append(textBody, part)
+		}
+	}
+	email["textBody"] = textBody
+	email["htmlBody"] = htmlBody
+	email["bodyValues"] = bodyValues
+
+	attachments := []map[string]interface{}{}
+	for _, parts := range []struct {
+		list        []*mailparse.Part
+		disposition string
+	}{{msg.Attachments, "attachment"}, {msg.Inline, "inline"}} {
+		for _, part := range parts.list {
+			attachment := map[string]interface{}{
+				"partId":      part.Number,
+				"blobId":      blobID(part.Number),
+				"size":        len(part.Data),
+				"type":        part.ContentType,
+				"name":        nil,
+				"cid":         nil,
+				"disposition": parts.disposition,
+			}
+			if part.Filename != "" {
+				attachment["name"] = part.Filename
+			}
+			if part.ContentID != "" {
+				attachment["cid"] = part.ContentID
+			}
+			attachments = append(attachments, attachment)
+		}
+	}
+	email["attachments"] = attachments
+	return email
+}
+
+// addresses 转换为 EmailAddress 列表，空列表为 null
+func addresses(list []*mail.Address) interface{} {
+	if len(list) == 0 {
+		return nil
+	}
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, a := range list {
+		address := map[string]interface{}{"name": nil, "email": a.Address}
+		if a.Name != "" {
+			address["name"] = a.Name
+		}
+		result = append(result, address)
+	}
+	return result
+}
+
+// preview 邮件正文的预览（纯文本正文，没有时使用去掉标签的 HTML 正文，空白合并为一个空格）
+func preview(msg *mailparse.Message) string {
+	text := msg.Text
+	if text == "" {
+		text = stripTags(msg.HTML)
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxPreviewLength {
+		text = string(runes[:maxPreviewLength])
+	}
+	return text
+}
+
+// stripTags 去掉 HTML 标签（只用于预览）
+func stripTags(html string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range html {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>':
+			inTag = false
+			b.WriteByte(' ')
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// truncateUTF8 截断到最多 n 字节，不截断多字节字符
+func truncateUTF8(s string, n int) string {
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+// setArgs Email/set 的参数
+type setArgs struct {
+	AccountID string                                `json:"accountId"`
+	IfInState *string                               `json:"ifInState"`
+	Create    map[string]json.RawMessage            `json:"create"`
+	Update    map[string]map[string]json.RawMessage `json:"update"`
+	Destroy   []string                              `json:"destroy"`
+}
+
+// setEmails Email/set：更新关键字和所属邮箱（移动到其他文件夹），删除邮件；不支持创建
+func (s *Server) setEmails(c *call, rawArgs json.RawMessage) (interface{}, error) {
+	var args setArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Create)+len(args.Update)+len(args.Destroy) > maxObjectsInSet {
+		return nil, &methodError{Type: "requestTooLarge"}
+	}
+	oldState, err := s.state(c)
+	if err != nil {
+		return nil, err
+	}
+	if args.IfInState != nil && *args.IfInState != oldState {
+		return nil, &methodError{Type: "stateMismatch"}
+	}
+
+	notCreated := map[string]interface{}{}
+	for key := range args.Create {
+		notCreated[key] = &methodError{Type: "forbidden", Description: "不支持创建邮件，请使用 WebMail 发送或保存草稿"}
+	}
+
+	updated := map[string]interface{}{}
+	notUpdated := map[string]interface{}{}
+	for id, patch := range args.Update {
+		m, err := s.ownMail(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			notUpdated[id] = &methodError{Type: "notFound"}
+			continue
+		}
+		if err := s.updateEmail(c, m, patch); err != nil {
+			var merr *methodError
+			if !errors.As(err, &merr) {
+				logger.WarnCtx(c.ctx).Err(err).Str("mail_id", m.ID).Msg("JMAP 更新邮件失败")
+				merr = &methodError{Type: "serverFail", Description: err.Error()}
+			}
+			notUpdated[id] = merr
+			continue
+		}
+		updated[id] = nil
+	}
+
+	destroyed := []string{}
+	notDestroyed := map[string]interface{}{}
+	for _, id := range args.Destroy {
+		m, err := s.ownMail(c, id)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			notDestroyed[id] = &methodError{Type: "notFound"}
+			continue
+		}
+		if err := s.destroyEmail(c, m); err != nil {
+			logger.WarnCtx(c.ctx).Err(err).Str("mail_id", m.ID).Msg("JMAP 删除邮件失败")
+			notDestroyed[id] = &methodError{Type: "serverFail", Description: err.Error()}
+			continue
+		}
+		destroyed = append(destroyed, id)
+	}
+
+	newState, err := s.state(c)
+	if err != nil {
+		return nil, err
+	}
+	orNull := func(m map[string]interface{}) interface{} {
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	}
+	return map[string]interface{}{
+		"accountId":    args.AccountID,
+		"oldState":     oldState,
+		"newState":     newState,
+		"created":      nil,
+		"updated":      orNull(updated),
+		"destroyed":    destroyed,
+		"notCreated":   orNull(notCreated),
+		"notUpdated":   orNull(notUpdated),
+		"notDestroyed": orNull(notDestroyed),
+	}, nil
+}
+
+// updateEmail 按补丁（RFC 8620 5.3 PatchObject）更新邮件的关键字和所属邮箱
+func (s *Server) updateEmail(c *call, m *storage.Mail, patch map[string]json.RawMessage) error {
+	invalid := func(description string) error {
+		return &methodError{Type: "invalidProperties", Description: description}
+	}
+	set := keywords(m.Flags)
+	folders := map[string]bool{m.Folder: true}
+	for path, value := range patch {
+		property, key, hasKey := strings.Cut(path, "/")
+		key = strings.NewReplacer("~1", "/", "~0", "~").Replace(key)
+		switch {
+		case property == "keywords" && !hasKey:
+			var all map[string]bool
+			if err := json.Unmarshal(value, &all); err != nil {
+				return invalid("keywords 必须是对象")
+			}
+			set = make(map[string]bool, len(all))
+			for keyword, on := range all {
+				if !on || !validKeyword(keyword) {
+					return invalid("无效的关键字 " + keyword)
+				}
+				set[strings.ToLower(keyword)] = true
+			}
+		case property == "keywords":
+			if !validKeyword(key) {
+				return invalid("无效的关键字 " + key)
+			}
+			if string(value) == "null" {
+				delete(set, strings.ToLower(key))
+			} else {
+				set[strings.ToLower(key)] = true
+			}
+		case property == "mailboxIds" && !hasKey:
+			var all map[string]bool
+			if err := json.Unmarshal(value, &all); err != nil {
+				return invalid("mailboxIds 必须是对象")
+			}
+			folders = make(map[string]bool, len(all))
+			for id := range all {
+				folder, ok := decodeID(id)
+				if !ok {
+					return invalid("无效的邮箱 ID " + id)
+				}
+				folders[folder] = true
+			}
+		case property == "mailboxIds":
+			folder, ok := decodeID(key)
+			if !ok {
+				return invalid("无效的邮箱 ID " + key)
+			}
+			if string(value) == "null" {
+				delete(folders, folder)
+			} else {
+				folders[folder] = true
+			}
+		default:
+			return invalid("不能修改属性 " + path)
+		}
+	}
+
+	// 每封邮件只属于一个邮箱：添加一个邮箱时必须同时移除原来的邮箱
+	if len(folders) != 1 {
+		return invalid("邮件必须属于且只属于一个邮箱")
+	}
+	var folder string
+	for f := range folders {
+		folder = f
+	}
+
+	flags := applyKeywords(m.Flags, set)
+	if strings.Join(flags, " ") != strings.Join(applyKeywords(m.Flags, keywords(m.Flags)), " ") {
+		if err := s.storage.UpdateMailFlags(c.ctx, m.ID, flags); err != nil {
+			return err
+		}
+	}
+	if folder != m.Folder {
+		return s.moveEmail(c, m, folder)
+	}
+	return nil
+}
+
+// moveEmail 移动邮件到其他文件夹：先移动文件，数据库更新失败时移回
+func (s *Server) moveEmail(c *call, m *storage.Mail, folder string) error {
+	exists := false
+	folders, err := s.storage.ListFolders(c.ctx, c.user.Email)
+	if err != nil {
+		return err
+	}
+	for _, f := range folders {
+		exists = exists || f == folder
+	}
+	if !exists {
+		return &methodError{Type: "invalidProperties", Description: "邮箱不存在"}
+	}
+
+	baseID, _, _ := strings.Cut(m.ID, ":")
+	fileMoved := false
+	if s.maildir != nil {
+		err := s.maildir.MoveMail(m.UserEmail, m.Folder, folder, baseID)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		fileMoved = err == nil
+	}
+	if _, err := s.storage.MoveMail(c.ctx, m.ID, folder); err != nil {
+		if fileMoved {
+			if rollbackErr := s.maildir.MoveMail(m.UserEmail, folder, m.Folder, baseID); rollbackErr != nil {
+				logger.WarnCtx(c.ctx).Err(rollbackErr).Str("mail_id", m.ID).Msg("移动邮件失败后恢复邮件文件失败")
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// destroyEmail 删除邮件（同时删除 Maildir 文件）
+func (s *Server) destroyEmail(c *call, m *storage.Mail) error {
+	if err := s.storage.DeleteMail(c.ctx, m.ID); err != nil {
+		return err
+	}
+	if s.maildir != nil {
+		baseID, _, _ := strings.Cut(m.ID, ":")
+		if err := s.maildir.DeleteMail(m.UserEmail, m.Folder, baseID); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.WarnCtx(c.ctx).Err(err).Str("mail_id", m.ID).Msg("删除邮件文件失败")
+		}
+	}
+	return nil
+}
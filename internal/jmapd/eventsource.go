@@ -0,0 +1,135 @@
+package jmapd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+)
+
+// eventSourceKeepAlive 没有 ping 时发送注释行和检查状态的间隔（避免代理断开空闲连接）
+const eventSourceKeepAlive = 30 * time.Second
+
+// ping 间隔的范围（秒）
+const (
+	minPingInterval = 5
+	maxPingInterval = 300
+)
+
+// handleEventSource 推送状态变化（RFC 8620 7.3）：新邮件到达时立即检查，
+// 其他变化（IMAP 或 WebMail 修改标志、移动和删除）在 ping 或保活时检查
+func (s *Server) handleEventSource(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(ctx)
+	query := r.URL.Query()
+
+	// types 为 * 或逗号分隔的类型，只支持 Email 和 Mailbox（两者状态相同）
+	wanted := false
+	for _, t := range strings.Split(query.Get("types"), ",") {
+		wanted = wanted || t == "*" || t == "Email" || t == "Mailbox"
+	}
+	closeAfterState := query.Get("closeafter") == "state"
+	ping := 0
+	if p := query.Get("ping"); p != "" {
+		var err error
+		if ping, err = strconv.Atoi(p); err != nil || ping < 0 {
+			writeProblem(w, http.StatusBadRequest, "", "无效的 ping 参数")
+			return
+		}
+		if ping > 0 {
+			ping = min(max(ping, minPingInterval), maxPingInterval)
+		}
+	}
+
+	var events <-chan maildirwatch.Event
+	if s.newMail != nil && wanted {
+		var cancel func()
+		events, cancel = s.newMail.Subscribe(user.Email)
+		defer cancel()
+	}
+
+	interval := eventSourceKeepAlive
+	if ping > 0 {
+		interval = time.Duration(ping) * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// 长连接不受服务器写超时限制，每次写入前延长写入期限
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event string, data interface{}) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(interval + 10*time.Second))
+		var err error
+		if event == "" {
+			_, err = io.WriteString(w, ": keepalive\n\n")
+		} else {
+			b, _ := json.Marshal(data)
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+		}
+		return err == nil && rc.Flush() == nil
+	}
+
+	c := &call{ctx: ctx, user: user}
+	lastState := ""
+	// sendState 状态与上次推送的不同时推送，返回是否继续
+	sendState := func() bool {
+		if !wanted {
+			return true
+		}
+		state, err := s.state(c)
+		if err != nil || state == lastState {
+			return true
+		}
+		lastState = state
+		if !write("state", map[string]interface{}{
+			"@type":   "StateChange",
+			"changed": map[string]interface{}{accountID(user): map[string]string{"Email": state, "Mailbox": state}},
+		}) {
+			return false
+		}
+		return !closeAfterState
+	}
+
+	// 连接建立时推送当前状态，客户端据此判断是否需要同步；
+	// closeafter=state（长轮询）时只记录当前状态，等到下一次变化
+	if closeAfterState {
+		if state, err := s.state(c); err == nil {
+			lastState = state
+		}
+	} else if !sendState() {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-events:
+			if !sendState() {
+				return
+			}
+		case <-ticker.C:
+			if !sendState() {
+				return
+			}
+			ok := false
+			if ping > 0 {
+				ok = write("ping", map[string]int{"interval": ping})
+			} else {
+				ok = write("", nil)
+			}
+			if !ok {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Package jmapd JMAP 服务（RFC 8620 核心协议和 RFC 8621 邮件）：在现有的 storage.Driver 和 Maildir 之上
+// 提供 Mailbox/get、Email/query、Email/get、Email/set 和 EventSource 推送，
+// 挂载在 WebMail 服务器上（/.well-known/jmap 和 /jmap/），与 WebMail 共用 JWT 和个人访问令牌认证。
+//
+// 邮箱对应文件夹，邮件对应一封已存储的邮件；JMAP 的 ID 只能包含 URL 安全字符，
+// 文件夹名和邮件 ID（Maildir 文件名）使用 base64url 编码。每封邮件只属于一个邮箱。
+// 不支持创建邮件和上传（发送邮件使用 WebMail 接口）。
+package jmapd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 支持的能力
+const (
+	capabilityCore = "urn:ietf:params:jmap:core"
+	capabilityMail = "urn:ietf:params:jmap:mail"
+)
+
+// 请求限制（在会话资源中告知客户端）
+const (
+	maxSizeRequest    = 10 << 20
+	maxCallsInRequest = 32
+	maxObjectsInGet   = 500
+	maxObjectsInSet   = 500
+)
+
+// sessionState 会话资源的状态（能力和账户不会在运行期间变化）
+const sessionState = "0"
+
+// Config JMAP 服务配置
+type Config struct {
+	Storage   storage.Driver
+	Maildir   *storage.Maildir
+	JWT       *auth.JWTManager
+	APITokens *auth.APITokenManager
+	// NewMail 新邮件事件（为空时 EventSource 只发送 ping）
+	NewMail *maildirwatch.Watcher
+}
+
+// Server JMAP 服务（http.Handler）
+type Server struct {
+	storage storage.Driver
+	maildir *storage.Maildir
+	jwt     *auth.JWTManager
+	tokens  *auth.APITokenManager
+	newMail *maildirwatch.Watcher
+	mux     *http.ServeMux
+}
+
+// New 创建 JMAP 服务
+func New(cfg *Config) *Server {
+	s := &Server{
+		storage: cfg.Storage,
+		maildir: cfg.Maildir,
+		jwt:     cfg.JWT,
+		tokens:  cfg.APITokens,
+		newMail: cfg.NewMail,
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /.well-known/jmap", s.authenticated(s.handleSession))
+	s.mux.HandleFunc("GET /jmap/session", s.authenticated(s.handleSession))
+	s.mux.HandleFunc("POST /jmap/api", s.authenticated(s.handleAPI))
+	s.mux.HandleFunc("GET /jmap/download/{accountId}/{blobId}/{name}", s.authenticated(s.handleDownload))
+	s.mux.HandleFunc("POST /jmap/upload/{accountId}", s.authenticated(s.handleUpload))
+	s.mux.HandleFunc("GET /jmap/eventsource", s.authenticated(s.handleEventSource))
+	return s
+}
+
+// ServeHTTP 处理 JMAP 请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// userKey 请求上下文中的当前用户
+type userKey struct{}
+
+// currentUser 返回请求的当前用户
+func currentUser(ctx context.Context) *storage.User {
+	user, _ := ctx.Value(userKey{}).(*storage.User)
+	return user
+}
+
+// authenticated 要求 Bearer 认证（WebMail 登录令牌或个人访问令牌），用户必须存在且未停用
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="jmap"`)
+			writeProblem(w, http.StatusUnauthorized, "", "需要 Bearer 认证")
+			return
+		}
+
+		var email string
+		if auth.IsAPIToken(token) {
+			apiToken, err := s.tokens.Authenticate(ctx, token)
+			if err != nil {
+				writeProblem(w, http.StatusUnauthorized, "", "无效的访问令牌")
+				return
+			}
+			// 只读令牌只能获取会话资源、下载和订阅推送
+			if !auth.ScopeAllows(apiToken.Scope, r.Method, r.URL.Path) {
+				writeProblem(w, http.StatusForbidden, "", "访问令牌的权限范围不允许该操作")
+				return
+			}
+			email = apiToken.UserEmail
+		} else {
+			claims, err := s.jwt.Validate(ctx, token)
+			if err != nil {
+				writeProblem(w, http.StatusUnauthorized, "", "无效的令牌")
+				return
+			}
+			email = claims.Email
+		}
+
+		user, err := s.storage.GetUser(ctx, email)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				writeProblem(w, http.StatusUnauthorized, "", "用户不存在")
+				return
+			}
+			logger.ErrorCtx(ctx).Err(err).Str("user", email).Msg("JMAP 查询用户失败")
+			writeProblem(w, http.StatusInternalServerError, "", "查询用户失败")
+			return
+		}
+		if !user.Active {
+			writeProblem(w, http.StatusForbidden, "", "用户已停用")
+			return
+		}
+		next(w, r.WithContext(context.WithValue(ctx, userKey{}, user)))
+	}
+}
+
+// accountID 用户的 JMAP 账户 ID（每个用户只有自己的一个账户）
+func accountID(user *storage.User) string {
+	return fmt.Sprintf("a%d", user.ID)
+}
+
+// handleSession 返回会话资源（RFC 8620 2）
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r.Context())
+	base := baseURL(r)
+	id := accountID(user)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			capabilityCore: map[string]interface{}{
+				"maxSizeUpload":         0,
+				"maxConcurrentUpload":   1,
+				"maxSizeRequest":        maxSizeRequest,
+				"maxConcurrentRequests": 4,
+				"maxCallsInRequest":     maxCallsInRequest,
+				"maxObjectsInGet":       maxObjectsInGet,
+				"maxObjectsInSet":       maxObjectsInSet,
+				"collationAlgorithms":   []string{"i;ascii-casemap"},
+			},
+			capabilityMail: map[string]interface{}{},
+		},
+		"accounts": map[string]interface{}{
+			id: map[string]interface{}{
+				"name":       user.Email,
+				"isPersonal": true,
+				"isReadOnly": false,
+				"accountCapabilities": map[string]interface{}{
+					capabilityMail: map[string]interface{}{
+						"maxMailboxesPerEmail":       1,
+						"maxMailboxDepth":            nil,
+						"maxSizeMailboxName":         255,
+						"maxSizeAttachmentsPerEmail": 0,
+						"emailQuerySortOptions":      []string{"receivedAt", "size"},
+						"mayCreateTopLevelMailbox":   false,
+					},
+				},
+			},
+		},
+		"primaryAccounts": map[string]string{
+			capabilityCore: id,
+			capabilityMail: id,
+		},
+		"username":       user.Email,
+		"apiUrl":         base + "/jmap/api",
+		"downloadUrl":    base + "/jmap/download/{accountId}/{blobId}/{name}?accept={type}",
+		"uploadUrl":      base + "/jmap/upload/{accountId}",
+		"eventSourceUrl": base + "/jmap/eventsource?types={types}&closeafter={closeafter}&ping={ping}",
+		"state":          sessionState,
+	})
+}
+
+// handleUpload 不支持上传（没有可以引用上传内容的创建操作）
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, http.StatusNotImplemented, "", "不支持上传")
+}
+
+// baseURL 客户端访问服务器使用的地址（反向代理之后使用 X-Forwarded-Proto）
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// encodeID 把文件夹名或邮件 ID 编码为 JMAP ID（只包含 A-Z a-z 0-9 _ -）
+func encodeID(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// decodeID 解码 JMAP ID
+func decodeID(id string) (string, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil || len(b) == 0 {
+		return "", false
+	}
+	return string(b), true
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warn().Err(err).Msg("写入 JMAP 响应失败")
+	}
+}
+
+// writeProblem 写入请求级错误（RFC 7807 problem details，errType 为空时只有状态码和说明）
+func writeProblem(w http.ResponseWriter, status int, errType, detail string) {
+	problem := map[string]interface{}{"status": status, "detail": detail}
+	if errType != "" {
+		problem["type"] = errType
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
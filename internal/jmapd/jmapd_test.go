@@ -0,0 +1,271 @@
+package jmapd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// newTestServer 创建带两封 INBOX 邮件的 JMAP 服务，返回服务、驱动、alice 的令牌和邮件 ID（按接收时间从早到晚）
+func newTestServer(t *testing.T) (*Server, *storage.SQLiteDriver, string, []string) {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storage.NewMailStore(maildir, driver)
+	var ids []string
+	for i, subject := range []string{"first", "second"} {
+		raw := []byte("From: Carol <carol@example.org>\r\nTo: alice@example.com\r\nSubject: " + subject +
+			"\r\nMessage-ID: <" + subject + "@example.org>\r\nDate: Mon, 02 Jan 2006 15:04:05 +0000\r\n\r\nbody of " + subject + "\r\n")
+		mail := &storage.Mail{
+			UserEmail:  "alice@example.com",
+			Folder:     "INBOX",
+			From:       "carol@example.org",
+			To:         []string{"alice@example.com"},
+			Subject:    subject,
+			Flags:      []string{},
+			ReceivedAt: time.Now().Add(time.Duration(i-2) * time.Hour),
+			CreatedAt:  time.Now(),
+		}
+		if err := store.Deliver(ctx, mail, raw); err != nil {
+			t.Fatalf("投递邮件失败: %v", err)
+		}
+		ids = append(ids, mail.ID)
+	}
+
+	jwtManager := auth.NewJWTManager("test-secret", "gmz-test")
+	user, err := driver.GetUser(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwtManager.GenerateToken(user.Email, user.ID, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(&Config{Storage: driver, Maildir: maildir, JWT: jwtManager, APITokens: auth.NewAPITokenManager(driver)})
+	return s, driver, token, ids
+}
+
+func request(s *Server, token, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+// apiCall 执行方法调用，返回各调用的 [名称, 参数]
+func apiCall(t *testing.T, s *Server, token string, calls ...[]interface{}) [][2]interface{} {
+	t.Helper()
+	w := request(s, token, http.MethodPost, "/jmap/api", map[string]interface{}{
+		"using":       []string{capabilityCore, capabilityMail},
+		"methodCalls": calls,
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("API status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		MethodResponses [][]json.RawMessage `json:"methodResponses"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var out [][2]interface{}
+	for _, r := range resp.MethodResponses {
+		var name string
+		var args interface{}
+		_ = json.Unmarshal(r[0], &name)
+		_ = json.Unmarshal(r[1], &args)
+		out = append(out, [2]interface{}{name, args})
+	}
+	return out
+}
+
+func TestSessionAndAuth(t *testing.T) {
+	s, _, token, _ := newTestServer(t)
+
+	if w := request(s, "", http.MethodGet, "/.well-known/jmap", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("没有令牌 status = %d", w.Code)
+	}
+	if w := request(s, "bad", http.MethodGet, "/jmap/session", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("无效令牌 status = %d", w.Code)
+	}
+
+	w := request(s, token, http.MethodGet, "/.well-known/jmap", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("会话资源 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var session struct {
+		Username        string            `json:"username"`
+		APIURL          string            `json:"apiUrl"`
+		PrimaryAccounts map[string]string `json:"primaryAccounts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil {
+		t.Fatal(err)
+	}
+	if session.Username != "alice@example.com" || session.APIURL != "http://example.com/jmap/api" || session.PrimaryAccounts[capabilityMail] == "" {
+		t.Fatalf("会话资源 = %+v", session)
+	}
+
+	// 未声明的能力
+	w = request(s, token, http.MethodPost, "/jmap/api", map[string]interface{}{
+		"using": []string{"urn:example:unknown"}, "methodCalls": []interface{}{},
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), errUnknownCapability) {
+		t.Fatalf("未知能力 status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMailboxAndEmailQuery(t *testing.T) {
+	s, driver, token, ids := newTestServer(t)
+	user, _ := driver.GetUser(context.Background(), "alice@example.com")
+	account := accountID(user)
+
+	responses := apiCall(t, s, token,
+		[]interface{}{"Mailbox/get", map[string]interface{}{"accountId": account, "ids": []string{encodeID("INBOX")}}, "0"},
+		[]interface{}{"Email/query", map[string]interface{}{
+			"accountId": account, "filter": map[string]interface{}{"inMailbox": encodeID("INBOX")}, "calculateTotal": true,
+		}, "1"},
+		[]interface{}{"Email/get", map[string]interface{}{
+			"accountId":  account,
+			"#ids":       map[string]string{"resultOf": "1", "name": "Email/query", "path": "/ids"},
+			"properties": []string{"subject", "from", "mailboxIds", "keywords", "preview", "messageId"},
+		}, "2"},
+		[]interface{}{"Email/get", map[string]interface{}{"accountId": "other", "ids": []string{}}, "3"},
+	)
+
+	mailbox := responses[0][1].(map[string]interface{})["list"].([]interface{})[0].(map[string]interface{})
+	if mailbox["role"] != "inbox" || mailbox["totalEmails"] != float64(2) || mailbox["unreadEmails"] != float64(2) {
+		t.Fatalf("INBOX = %v", mailbox)
+	}
+
+	query := responses[1][1].(map[string]interface{})
+	// 默认按接收时间倒序
+	if query["total"] != float64(2) || !slices.Equal(toStrings(query["ids"]), []string{encodeID(ids[1]), encodeID(ids[0])}) {
+		t.Fatalf("Email/query = %v", query)
+	}
+
+	list := responses[2][1].(map[string]interface{})["list"].([]interface{})
+	if len(list) != 2 {
+		t.Fatalf("Email/get list = %v", list)
+	}
+	email := list[0].(map[string]interface{})
+	from := email["from"].([]interface{})[0].(map[string]interface{})
+	if email["subject"] != "second" || from["name"] != "Carol" || from["email"] != "carol@example.org" ||
+		email["preview"] != "body of second" || !slices.Equal(toStrings(email["messageId"]), []string{"second@example.org"}) {
+		t.Fatalf("Email/get = %v", email)
+	}
+
+	if responses[3][0] != "error" || responses[3][1].(map[string]interface{})["type"] != "accountNotFound" {
+		t.Fatalf("其他账户 = %v", responses[3])
+	}
+}
+
+func TestEmailSetAndDownload(t *testing.T) {
+	s, driver, token, ids := newTestServer(t)
+	ctx := context.Background()
+	user, _ := driver.GetUser(ctx, "alice@example.com")
+	account := accountID(user)
+	first, second := encodeID(ids[0]), encodeID(ids[1])
+
+	responses := apiCall(t, s, token,
+		[]interface{}{"Email/set", map[string]interface{}{
+			"accountId": account,
+			"update": map[string]interface{}{
+				first: map[string]interface{}{
+					"keywords/$seen":                    true,
+					"keywords/work":                     true,
+					"mailboxIds/" + encodeID("INBOX"):   nil,
+					"mailboxIds/" + encodeID("Archive"): true,
+				},
+			},
+			"destroy": []string{second, encodeID("missing")},
+		}, "0"},
+	)
+	result := responses[0][1].(map[string]interface{})
+	if result["oldState"] == result["newState"] || result["updated"] == nil ||
+		!slices.Equal(toStrings(result["destroyed"]), []string{second}) || result["notDestroyed"] == nil {
+		t.Fatalf("Email/set = %v", result)
+	}
+
+	m, err := driver.GetMail(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Folder != "Archive" || !slices.Contains(m.Flags, `\Seen`) || !slices.Contains(m.Flags, "work") {
+		t.Fatalf("更新后的邮件 folder = %s, flags = %v", m.Folder, m.Flags)
+	}
+	if _, err := driver.GetMail(ctx, ids[1]); err == nil {
+		t.Fatal("删除的邮件仍然存在")
+	}
+
+	// 移动后仍然可以下载原文和正文
+	w := request(s, token, http.MethodGet, "/jmap/download/"+account+"/"+first+"/mail.eml", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Subject: first") {
+		t.Fatalf("下载原文 status = %d, body = %s", w.Code, w.Body.String())
+	}
+	w = request(s, token, http.MethodGet, "/jmap/download/"+account+"/"+encodeID(ids[0]+"/text")+"/body.txt", nil)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != "body of first" {
+		t.Fatalf("下载正文 status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	// 其他用户的令牌看不到 alice 的邮件
+	bob, _ := driver.GetUser(ctx, "bob@example.com")
+	bobToken, _ := s.jwt.GenerateToken(bob.Email, bob.ID, false, time.Hour)
+	if w := request(s, bobToken, http.MethodGet, "/jmap/download/"+accountID(bob)+"/"+first+"/mail.eml", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("下载其他用户的邮件 status = %d", w.Code)
+	}
+	responses = apiCall(t, s, bobToken,
+		[]interface{}{"Email/get", map[string]interface{}{"accountId": accountID(bob), "ids": []string{first}}, "0"},
+	)
+	if got := toStrings(responses[0][1].(map[string]interface{})["notFound"]); !slices.Equal(got, []string{first}) {
+		t.Fatalf("其他用户 Email/get notFound = %v", got)
+	}
+}
+
+func toStrings(v interface{}) []string {
+	var out []string
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		s, _ := item.(string)
+		out = append(out, s)
+	}
+	return out
+}
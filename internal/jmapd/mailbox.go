@@ -0,0 +1,149 @@
+package jmapd
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// mailboxRoles 特殊用途文件夹的角色（RFC 8621 2，与 IMAP 的特殊用途属性相同）
+var mailboxRoles = map[string]string{
+	"INBOX":   "inbox",
+	"Sent":    "sent",
+	"Drafts":  "drafts",
+	"Trash":   "trash",
+	"Spam":    "junk",
+	"Junk":    "junk",
+	"Archive": "archive",
+}
+
+// mailboxRights 当前用户对邮箱的权限（不支持 Mailbox/set，不能创建、重命名和删除邮箱）
+var mailboxRights = map[string]bool{
+	"mayReadItems":   true,
+	"mayAddItems":    true,
+	"mayRemoveItems": true,
+	"maySetSeen":     true,
+	"maySetKeywords": true,
+	"mayCreateChild": false,
+	"mayRename":      false,
+	"mayDelete":      false,
+	"maySubmit":      false,
+}
+
+// getArgs /get 方法的参数（RFC 8620 5.1）
+type getArgs struct {
+	AccountID  string    `json:"accountId"`
+	IDs        *[]string `json:"ids"`
+	Properties *[]string `json:"properties"`
+}
+
+// getMailboxes Mailbox/get：邮箱对应用户的文件夹，邮件数和未读数来自数据库
+func (s *Server) getMailboxes(c *call, rawArgs json.RawMessage) (interface{}, error) {
+	var args getArgs
+	if err := decodeArgs(rawArgs, &args); err != nil {
+		return nil, err
+	}
+	if args.IDs != nil && len(*args.IDs) > maxObjectsInGet {
+		return nil, &methodError{Type: "requestTooLarge"}
+	}
+
+	state, err := s.state(c)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := s.storage.ListFolders(c.ctx, c.user.Email)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		exists[folder] = true
+	}
+
+	wanted := folders
+	notFound := []string{}
+	if args.IDs != nil {
+		wanted = nil
+		for _, id := range *args.IDs {
+			if folder, ok := decodeID(id); ok && exists[folder] {
+				wanted = append(wanted, folder)
+			} else {
+				notFound = append(notFound, id)
+			}
+		}
+	}
+
+	list := make([]map[string]interface{}, 0, len(wanted))
+	for _, folder := range wanted {
+		mailbox, err := s.mailbox(c, folder, exists)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, filterProperties(mailbox, args.Properties))
+	}
+	return map[string]interface{}{
+		"accountId": args.AccountID,
+		"state":     state,
+		"list":      list,
+		"notFound":  notFound,
+	}, nil
+}
+
+// mailbox 文件夹对应的 Mailbox 对象（会话数按邮件数计算）
+func (s *Server) mailbox(c *call, folder string, exists map[string]bool) (map[string]interface{}, error) {
+	total, err := s.storage.CountMails(c.ctx, c.user.Email, folder)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.storage.DigestFolder(c.ctx, c.user.Email, folder, true, time.Time{}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	name := folder
+	var parentID interface{}
+	if i := strings.LastIndex(folder, storage.FolderDelimiter); i > 0 {
+		name = folder[i+1:]
+		if parent := folder[:i]; exists[parent] {
+			parentID = encodeID(parent)
+		}
+	}
+	var role interface{}
+	if r, ok := mailboxRoles[folder]; ok {
+		role = r
+	}
+	sortOrder := 10
+	if folder == "INBOX" {
+		sortOrder = 0
+	}
+
+	return map[string]interface{}{
+		"id":            encodeID(folder),
+		"name":          name,
+		"parentId":      parentID,
+		"role":          role,
+		"sortOrder":     sortOrder,
+		"totalEmails":   total,
+		"unreadEmails":  unread.Count,
+		"totalThreads":  total,
+		"unreadThreads": unread.Count,
+		"myRights":      mailboxRights,
+		"isSubscribed":  true,
+	}, nil
+}
+
+// filterProperties 只保留请求的属性（id 始终返回），properties 为空时返回全部
+func filterProperties(object map[string]interface{}, properties *[]string) map[string]interface{} {
+	if properties == nil {
+		return object
+	}
+	filtered := map[string]interface{}{"id": object["id"]}
+	for _, property := range *properties {
+		if value, ok := object[property]; ok {
+			filtered[property] = value
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,323 @@
+package jmapd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 请求级错误类型（RFC 8620 3.6.1）
+const (
+	errUnknownCapability = "urn:ietf:params:jmap:error:unknownCapability"
+	errNotJSON           = "urn:ietf:params:jmap:error:notJSON"
+	errNotRequest        = "urn:ietf:params:jmap:error:notRequest"
+	errLimit             = "urn:ietf:params:jmap:error:limit"
+)
+
+// apiRequest API 请求（RFC 8620 3.3）
+type apiRequest struct {
+	Using       []string          `json:"using"`
+	MethodCalls []json.RawMessage `json:"methodCalls"`
+	CreatedIDs  map[string]string `json:"createdIds,omitempty"`
+}
+
+// invocation 方法调用或响应：[名称, 参数, 调用 ID]
+type invocation struct {
+	Name   string
+	Args   interface{}
+	CallID string
+}
+
+// MarshalJSON 编码为三元数组
+func (i invocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{i.Name, i.Args, i.CallID})
+}
+
+// methodError 方法级错误（RFC 8620 3.6.2）
+type methodError struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// Error 实现 error
+func (e *methodError) Error() string {
+	if e.Description == "" {
+		return e.Type
+	}
+	return e.Type + ": " + e.Description
+}
+
+// invalidArguments 参数无效
+func invalidArguments(format string, args ...interface{}) *methodError {
+	return &methodError{Type: "invalidArguments", Description: fmt.Sprintf(format, args...)}
+}
+
+// call 一次方法调用的上下文
+type call struct {
+	ctx  context.Context
+	user *storage.User
+}
+
+// method 方法实现：解析参数并返回响应参数
+type method func(s *Server, c *call, args json.RawMessage) (interface{}, error)
+
+// methods 支持的方法及其需要的能力
+var methods = map[string]struct {
+	capability string
+	fn         method
+}{
+	"Core/echo":   {capabilityCore, (*Server).echo},
+	"Mailbox/get": {capabilityMail, (*Server).getMailboxes},
+	"Email/query": {capabilityMail, (*Server).queryEmails},
+	"Email/get":   {capabilityMail, (*Server).getEmails},
+	"Email/set":   {capabilityMail, (*Server).setEmails},
+}
+
+// handleAPI 执行 API 请求中的方法调用（按顺序执行，后面的调用可以引用前面的结果）
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSizeRequest+1))
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, errNotRequest, "读取请求失败")
+		return
+	}
+	if len(body) > maxSizeRequest {
+		writeProblem(w, http.StatusBadRequest, errLimit, "请求超过 maxSizeRequest")
+		return
+	}
+	if !json.Valid(body) {
+		writeProblem(w, http.StatusBadRequest, errNotJSON, "请求不是有效的 JSON")
+		return
+	}
+	var req apiRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.Using == nil || req.MethodCalls == nil {
+		writeProblem(w, http.StatusBadRequest, errNotRequest, "请求不是有效的 JMAP 请求")
+		return
+	}
+	if len(req.MethodCalls) > maxCallsInRequest {
+		writeProblem(w, http.StatusBadRequest, errLimit, "方法调用数超过 maxCallsInRequest")
+		return
+	}
+	using := make(map[string]bool, len(req.Using))
+	for _, capability := range req.Using {
+		if capability != capabilityCore && capability != capabilityMail {
+			writeProblem(w, http.StatusBadRequest, errUnknownCapability, "不支持的能力: "+capability)
+			return
+		}
+		using[capability] = true
+	}
+
+	c := &call{ctx: r.Context(), user: currentUser(r.Context())}
+	responses := make([]invocation, 0, len(req.MethodCalls))
+	// 之前的响应（解码为通用结构，供结果引用）
+	var results []resolvedResponse
+	for _, raw := range req.MethodCalls {
+		var parts []json.RawMessage
+		var name, callID string
+		if err := json.Unmarshal(raw, &parts); err != nil || len(parts) != 3 ||
+			json.Unmarshal(parts[0], &name) != nil || json.Unmarshal(parts[2], &callID) != nil {
+			writeProblem(w, http.StatusBadRequest, errNotRequest, "方法调用必须是 [名称, 参数, 调用 ID]")
+			return
+		}
+
+		response := s.invoke(c, using, name, parts[1], results)
+		response.CallID = callID
+		responses = append(responses, response)
+		results = append(results, resolve(response))
+	}
+
+	result := map[string]interface{}{
+		"methodResponses": responses,
+		"sessionState":    sessionState,
+	}
+	if req.CreatedIDs != nil {
+		result["createdIds"] = req.CreatedIDs
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// invoke 执行一次方法调用，失败时返回 error 响应
+func (s *Server) invoke(c *call, using map[string]bool, name string, rawArgs json.RawMessage, results []resolvedResponse) invocation {
+	fail := func(err error) invocation {
+		merr, ok := err.(*methodError)
+		if !ok {
+			merr = &methodError{Type: "serverFail", Description: err.Error()}
+		}
+		return invocation{Name: "error", Args: merr}
+	}
+
+	m, ok := methods[name]
+	if !ok {
+		return fail(&methodError{Type: "unknownMethod"})
+	}
+	if !using[m.capability] {
+		return fail(&methodError{Type: "unknownMethod", Description: "请求没有声明能力 " + m.capability})
+	}
+	args, err := resolveReferences(rawArgs, results)
+	if err != nil {
+		return fail(err)
+	}
+	if name != "Core/echo" {
+		var account struct {
+			AccountID string `json:"accountId"`
+		}
+		if err := json.Unmarshal(args, &account); err != nil {
+			return fail(invalidArguments("参数必须是对象"))
+		}
+		if account.AccountID != accountID(c.user) {
+			return fail(&methodError{Type: "accountNotFound"})
+		}
+	}
+
+	response, err := m.fn(s, c, args)
+	if err != nil {
+		return fail(err)
+	}
+	return invocation{Name: name, Args: response}
+}
+
+// echo Core/echo：原样返回参数
+func (s *Server) echo(c *call, args json.RawMessage) (interface{}, error) {
+	return args, nil
+}
+
+// resolvedResponse 之前的方法响应（名称、调用 ID 和解码后的参数）
+type resolvedResponse struct {
+	name   string
+	callID string
+	args   interface{}
+}
+
+// resolve 把响应参数解码为通用结构
+func resolve(response invocation) resolvedResponse {
+	var args interface{}
+	if b, err := json.Marshal(response.Args); err == nil {
+		_ = json.Unmarshal(b, &args)
+	}
+	return resolvedResponse{name: response.Name, callID: response.CallID, args: args}
+}
+
+// resultReference 结果引用（RFC 8620 3.7）
+type resultReference struct {
+	ResultOf string `json:"resultOf"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+}
+
+// resolveReferences 把参数中以 # 开头的结果引用替换为之前响应中的值
+func resolveReferences(rawArgs json.RawMessage, results []resolvedResponse) (json.RawMessage, error) {
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(rawArgs, &args); err != nil {
+		return rawArgs, nil
+	}
+	changed := false
+	for key, value := range args {
+		if !strings.HasPrefix(key, "#") {
+			continue
+		}
+		plain := key[1:]
+		if _, ok := args[plain]; ok {
+			return nil, invalidArguments("参数 %s 同时使用了值和结果引用", plain)
+		}
+		var ref resultReference
+		if err := json.Unmarshal(value, &ref); err != nil {
+			return nil, &methodError{Type: "invalidResultReference", Description: err.Error()}
+		}
+		resolved, err := ref.evaluate(results)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(resolved)
+		if err != nil {
+			return nil, err
+		}
+		delete(args, key)
+		args[plain] = b
+		changed = true
+	}
+	if !changed {
+		return rawArgs, nil
+	}
+	return json.Marshal(args)
+}
+
+// evaluate 在引用的响应中按 JSON Pointer 取值（路径中的 * 展开数组，结果为数组时合并）
+func (ref *resultReference) evaluate(results []resolvedResponse) (interface{}, error) {
+	for _, result := range results {
+		if result.callID != ref.ResultOf {
+			continue
+		}
+		if result.name != ref.Name {
+			return nil, &methodError{Type: "invalidResultReference", Description: "引用的方法名不一致"}
+		}
+		value, err := evaluatePointer(result.args, ref.Path)
+		if err != nil {
+			return nil, &methodError{Type: "invalidResultReference", Description: err.Error()}
+		}
+		return value, nil
+	}
+	return nil, &methodError{Type: "invalidResultReference", Description: "没有找到引用的调用 " + ref.ResultOf}
+}
+
+// evaluatePointer 按 JSON Pointer（RFC 6901，支持 * 通配）取值
+func evaluatePointer(value interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return value, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("无效的路径 %q", path)
+	}
+	token, rest, more := strings.Cut(path[1:], "/")
+	if more {
+		rest = "/" + rest
+	}
+	token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[token]
+		if !ok {
+			return nil, fmt.Errorf("路径中的 %q 不存在", token)
+		}
+		return evaluatePointer(child, rest)
+	case []interface{}:
+		if token == "*" {
+			out := make([]interface{}, 0, len(v))
+			for _, item := range v {
+				child, err := evaluatePointer(item, rest)
+				if err != nil {
+					return nil, err
+				}
+				if list, ok := child.([]interface{}); ok {
+					out = append(out, list...)
+				} else {
+					out = append(out, child)
+				}
+			}
+			return out, nil
+		}
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, fmt.Errorf("无效的数组下标 %q", token)
+		}
+		return evaluatePointer(v[i], rest)
+	default:
+		return nil, fmt.Errorf("路径中的 %q 不是对象或数组", token)
+	}
+}
+
+// decodeArgs 解析方法参数，未知字段视为参数错误
+func decodeArgs(args json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return invalidArguments("%v", err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+// Package limits 按 IP 限制并发连接数，并在认证失败次数过多时临时封禁 IP
+//
+// SMTP、IMAP、WebMail 和管理 API 登录共享同一个 Limiter，
+// 攻击者换用其他协议继续尝试密码时仍然计入同一个失败计数。
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// ErrBanned IP 因认证失败次数过多被临时封禁
+var ErrBanned = errors.New("认证失败次数过多，IP 已被临时封禁")
+
+// 拒绝原因（指标标签）
+const (
+	ReasonBanned      = "banned"      // IP 被封禁
+	ReasonConnections = "connections" // 并发连接数超过上限
+)
+
+// Observer 记录被拒绝的连接和登录（由指标导出器实现）
+type Observer interface {
+	ObserveRejection(protocol, reason string)
+}
+
+// Config 限制配置
+type Config struct {
+	MaxConnections  int           // 每个 IP 的并发连接数上限（0 不限制）
+	MaxAuthFailures int           // 窗口内认证失败达到该次数后封禁 IP（0 不封禁）
+	Window          time.Duration // 认证失败统计窗口
+	BanDuration     time.Duration // 封禁时长
+	Exempt          []string      // 不受限制的 IP 或网段（如 127.0.0.1、10.0.0.0/8）
+	Observer        Observer      // 拒绝计数（可选）
+}
+
+// Limiter 按 IP 的连接数限制和认证失败封禁
+//
+// 方法可以在 nil 上调用（不限制），调用方不需要判断是否启用。
+type Limiter struct {
+	config  Config
+	exempt  []*net.IPNet
+	clients map[string]*client
+	mu      sync.Mutex
+	now     func() time.Time
+}
+
+// client IP 的状态
+type client struct {
+	conns       int       // 当前连接数
+	failures    int       // 窗口内的认证失败次数
+	first       time.Time // 窗口内第一次失败的时间
+	bannedUntil time.Time
+}
+
+// New 创建限制器
+func New(cfg Config) (*Limiter, error) {
+	exempt, err := ParseExempt(cfg.Exempt)
+	if err != nil {
+		return nil, err
+	}
+	return &Limiter{
+		config:  cfg,
+		exempt:  exempt,
+		clients: make(map[string]*client),
+		now:     time.Now,
+	}, nil
+}
+
+// ParseExempt 解析 IP 或网段列表（单个 IP 视为 /32 或 /128）
+func ParseExempt(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的 IP 地址: %s", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段: %s", entry)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// exempted IP 是否不受限制
+func (l *Limiter) exempted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range l.exempt {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// reject 记录一次拒绝
+func (l *Limiter) reject(protocol, reason string) {
+	if l.config.Observer != nil {
+		l.config.Observer.ObserveRejection(protocol, reason)
+	}
+}
+
+// banned IP 是否处于封禁中（调用方持有锁）
+func (l *Limiter) banned(ip string) bool {
+	c, ok := l.clients[ip]
+	return ok && l.now().Before(c.bannedUntil)
+}
+
+// Allow 认证前检查 IP 是否被封禁，被封禁时返回 ErrBanned
+func (l *Limiter) Allow(protocol, ip string) error {
+	if l == nil || l.exempted(ip) {
+		return nil
+	}
+	l.mu.Lock()
+	banned := l.banned(ip)
+	l.mu.Unlock()
+	if banned {
+		l.reject(protocol, ReasonBanned)
+		return ErrBanned
+	}
+	return nil
+}
+
+// Failure 记录一次认证失败，窗口内失败次数达到上限时封禁 IP
+func (l *Limiter) Failure(protocol, ip string) {
+	if l == nil || l.config.MaxAuthFailures <= 0 || l.exempted(ip) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &client{}
+		l.clients[ip] = c
+	}
+	if c.failures == 0 || now.Sub(c.first) > l.config.Window {
+		c.failures = 0
+		c.first = now
+	}
+	c.failures++
+
+	if c.failures >= l.config.MaxAuthFailures {
+		c.failures = 0
+		c.bannedUntil = now.Add(l.config.BanDuration)
+		logger.Warn().
+			Str("ip", ip).
+			Str("protocol", protocol).
+			Dur("ban", l.config.BanDuration).
+			Msg("认证失败次数过多，临时封禁 IP")
+	}
+}
+
+// Success 认证成功后清除 IP 的失败计数（不解除已有的封禁）
+func (l *Limiter) Success(ip string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.clients[ip]; ok {
+		c.failures = 0
+	}
+}
+
+// acquire 接受连接前检查封禁和连接数，允许时占用一个连接名额
+func (l *Limiter) acquire(ip string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.banned(ip) {
+		return ReasonBanned, false
+	}
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &client{}
+		l.clients[ip] = c
+	}
+	if l.config.MaxConnections > 0 && c.conns >= l.config.MaxConnections {
+		return ReasonConnections, false
+	}
+	c.conns++
+	return "", true
+}
+
+// release 释放连接名额
+func (l *Limiter) release(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.clients[ip]; ok && c.conns > 0 {
+		c.conns--
+	}
+}
+
+// Listener 包装监听器：被封禁 IP 的连接和超过并发上限的连接在接受后立即关闭
+func (l *Limiter) Listener(protocol string, ln net.Listener) net.Listener {
+	if l == nil {
+		return ln
+	}
+	return &listener{Listener: ln, limiter: l, protocol: protocol}
+}
+
+// Cleanup 定期清理没有连接、不在封禁中且失败窗口已过期的记录
+func (l *Limiter) Cleanup(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	interval := l.config.Window
+	if interval <= 0 || (l.config.BanDuration > 0 && l.config.BanDuration < interval) {
+		interval = l.config.BanDuration
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			now := l.now()
+			for ip, c := range l.clients {
+				if c.conns == 0 && !now.Before(c.bannedUntil) && (c.failures == 0 || now.Sub(c.first) > l.config.Window) {
+					delete(l.clients, ip)
+				}
+			}
+			l.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// listener 限制连接的监听器
+type listener struct {
+	net.Listener
+	limiter  *Limiter
+	protocol string
+}
+
+// Accept 接受连接，被拒绝的连接直接关闭，继续等待下一个连接
+func (l *listener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := antispam.RemoteIP(conn.RemoteAddr())
+		if l.limiter.exempted(ip) {
+			return conn, nil
+		}
+		if reason, ok := l.limiter.acquire(ip); !ok {
+			l.limiter.reject(l.protocol, reason)
+			logger.Debug().
+				Str("ip", ip).
+				Str("protocol", l.protocol).
+				Str("reason", reason).
+				Msg("拒绝连接")
+			conn.Close()
+			continue
+		}
+		return &limitedConn{Conn: conn, limiter: l.limiter, ip: ip}, nil
+	}
+}
+
+// limitedConn 占用连接名额的连接，关闭时释放
+type limitedConn struct {
+	net.Conn
+	limiter *Limiter
+	ip      string
+	once    sync.Once
+}
+
+// Close 关闭连接
+func (c *limitedConn) Close() error {
+	c.once.Do(func() { c.limiter.release(c.ip) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,220 @@
+package limits
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingObserver 记录拒绝次数
+type countingObserver struct {
+	mu         sync.Mutex
+	rejections map[string]int
+}
+
+func (o *countingObserver) ObserveRejection(protocol, reason string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rejections[protocol+"/"+reason]++
+}
+
+func (o *countingObserver) count(key string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.rejections[key]
+}
+
+func TestLimiterBan(t *testing.T) {
+	now := time.Now()
+	observer := &countingObserver{rejections: make(map[string]int)}
+	limiter, err := New(Config{
+		MaxAuthFailures: 3,
+		Window:          10 * time.Minute,
+		BanDuration:     15 * time.Minute,
+		Exempt:          []string{"127.0.0.1", "10.0.0.0/8"},
+		Observer:        observer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter.now = func() time.Time { return now }
+
+	ip := "192.0.2.1"
+	limiter.Failure("imap", ip)
+	limiter.Failure("smtp", ip)
+	if err := limiter.Allow("imap", ip); err != nil {
+		t.Errorf("未达到上限时不应该封禁: %v", err)
+	}
+
+	// 不同协议的失败计入同一个计数
+	limiter.Failure("webmail", ip)
+	if err := limiter.Allow("imap", ip); !errors.Is(err, ErrBanned) {
+		t.Errorf("达到上限后应该封禁, got %v", err)
+	}
+	if n := observer.count("imap/banned"); n != 1 {
+		t.Errorf("imap/banned 拒绝次数 = %d, 期望 1", n)
+	}
+	if err := limiter.Allow("imap", "192.0.2.2"); err != nil {
+		t.Errorf("其他 IP 不应该被封禁: %v", err)
+	}
+
+	// 认证成功不解除封禁，封禁到期后恢复
+	limiter.Success(ip)
+	if err := limiter.Allow("smtp", ip); !errors.Is(err, ErrBanned) {
+		t.Errorf("认证成功不应该解除封禁, got %v", err)
+	}
+	now = now.Add(16 * time.Minute)
+	if err := limiter.Allow("smtp", ip); err != nil {
+		t.Errorf("封禁到期后应该允许: %v", err)
+	}
+
+	// 超出窗口的失败重新计数
+	limiter.Failure("imap", ip)
+	limiter.Failure("imap", ip)
+	now = now.Add(11 * time.Minute)
+	limiter.Failure("imap", ip)
+	if err := limiter.Allow("imap", ip); err != nil {
+		t.Errorf("窗口外的失败不应该累计: %v", err)
+	}
+
+	// 认证成功清除失败计数
+	limiter.Failure("imap", ip)
+	limiter.Success(ip)
+	limiter.Failure("imap", ip)
+	limiter.Failure("imap", ip)
+	if err := limiter.Allow("imap", ip); err != nil {
+		t.Errorf("认证成功后应该重新计数: %v", err)
+	}
+
+	// 豁免地址不受限制
+	for _, exempt := range []string{"127.0.0.1", "10.1.2.3"} {
+		for i := 0; i < 5; i++ {
+			limiter.Failure("imap", exempt)
+		}
+		if err := limiter.Allow("imap", exempt); err != nil {
+			t.Errorf("%s 是豁免地址，不应该被封禁: %v", exempt, err)
+		}
+	}
+}
+
+func TestLimiterNil(t *testing.T) {
+	var limiter *Limiter
+	limiter.Failure("imap", "192.0.2.1")
+	limiter.Success("192.0.2.1")
+	if err := limiter.Allow("imap", "192.0.2.1"); err != nil {
+		t.Errorf("未启用时不应该限制: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if limiter.Listener("imap", ln) != ln {
+		t.Error("未启用时不应该包装监听器")
+	}
+}
+
+func TestParseExempt(t *testing.T) {
+	if _, err := ParseExempt([]string{"127.0.0.1", "::1", "10.0.0.0/8", "fd00::/8"}); err != nil {
+		t.Errorf("应该解析成功: %v", err)
+	}
+	for _, invalid := range []string{"localhost", "10.0.0.0/33"} {
+		if _, err := ParseExempt([]string{invalid}); err == nil {
+			t.Errorf("%s 应该解析失败", invalid)
+		}
+	}
+}
+
+func TestListener(t *testing.T) {
+	observer := &countingObserver{rejections: make(map[string]int)}
+	limiter, err := New(Config{
+		MaxConnections:  2,
+		MaxAuthFailures: 1,
+		Window:          time.Minute,
+		BanDuration:     time.Minute,
+		Observer:        observer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := limiter.Listener("smtp", raw)
+	defer ln.Close()
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	dial := func() net.Conn {
+		t.Helper()
+		conn, err := net.Dial("tcp", raw.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	// closedByServer 连接是否被服务端关闭
+	closedByServer := func(conn net.Conn) bool {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err := conn.Read(make([]byte, 1))
+		var netErr net.Error
+		return !(errors.As(err, &netErr) && netErr.Timeout())
+	}
+
+	dial()
+	dial()
+	first := <-accepted
+	<-accepted
+
+	// 超过并发上限的连接被关闭
+	if !closedByServer(dial()) {
+		t.Error("超过并发上限的连接应该被关闭")
+	}
+	if n := observer.count("smtp/connections"); n != 1 {
+		t.Errorf("smtp/connections 拒绝次数 = %d, 期望 1", n)
+	}
+
+	// 关闭一个连接后释放名额（重复关闭只释放一次）
+	first.Close()
+	first.Close()
+	dial()
+	if !waitAccepted(accepted) {
+		t.Fatal("释放名额后应该接受新连接")
+	}
+	if !closedByServer(dial()) {
+		t.Error("重复关闭不应该多释放名额")
+	}
+
+	// 被封禁 IP 的连接被关闭
+	limiter.Failure("imap", "127.0.0.1")
+	if !closedByServer(dial()) {
+		t.Error("被封禁 IP 的连接应该被关闭")
+	}
+	if n := observer.count("smtp/banned"); n != 1 {
+		t.Errorf("smtp/banned 拒绝次数 = %d, 期望 1", n)
+	}
+}
+
+// waitAccepted 等待监听器接受一个连接
+func waitAccepted(accepted <-chan net.Conn) bool {
+	select {
+	case conn := <-accepted:
+		return conn != nil
+	case <-time.After(time.Second):
+		return false
+	}
+}
@@ -0,0 +1,320 @@
+// Package maildirwatch 监视 Maildir 目录：外部投递代理（procmail、OpenSMTPD 等）直接写入用户 Maildir 的邮件
+// 被解析并索引到数据库，所有新邮件（包括 gmz 自己投递的）都会发出新邮件事件，供 IMAP IDLE 和 WebMail 推送使用
+//
+// gmz 自己投递的邮件先写入数据库记录再重命名到 new/（见 storage.MailStore.Deliver），
+// 因此文件出现时没有对应数据库记录的就是外部写入的邮件。
+package maildirwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/search"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// settleDelay 文件出现后等待的时间，合并同一文件的多次事件
+const settleDelay = 500 * time.Millisecond
+
+// Event 新邮件事件
+type Event struct {
+	UserEmail string `json:"user_email"`
+	Folder    string `json:"folder"`
+	MailID    string `json:"id"`
+	External  bool   `json:"external"` // 外部投递代理写入（已补建索引）
+}
+
+// Watcher Maildir 监视器
+type Watcher struct {
+	maildir *storage.Maildir
+	storage storage.Driver
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+	indexMu     sync.Mutex // 串行处理文件，避免同一文件重复索引
+}
+
+// subscriber 事件订阅者（userEmail 为空时接收所有用户的事件）
+type subscriber struct {
+	userEmail string
+	ch        chan Event
+}
+
+// New 创建 Maildir 监视器
+func New(maildir *storage.Maildir, driver storage.Driver) *Watcher {
+	return &Watcher{
+		maildir:     maildir,
+		storage:     driver,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe 订阅新邮件事件（userEmail 为空时订阅所有用户），返回的函数取消订阅；
+// 订阅者处理不及时时丢弃事件，不阻塞投递
+func (w *Watcher) Subscribe(userEmail string) (<-chan Event, func()) {
+	sub := &subscriber{userEmail: strings.ToLower(userEmail), ch: make(chan Event, 64)}
+	w.mu.Lock()
+	w.subscribers[sub] = struct{}{}
+	w.mu.Unlock()
+	return sub.ch, func() {
+		w.mu.Lock()
+		delete(w.subscribers, sub)
+		w.mu.Unlock()
+	}
+}
+
+// publish 发送事件给订阅者
+func (w *Watcher) publish(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for sub := range w.subscribers {
+		if sub.userEmail != "" && sub.userEmail != strings.ToLower(e.UserEmail) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			logger.Warn().Str("user", e.UserEmail).Msg("新邮件事件订阅者处理不及时，丢弃事件")
+		}
+	}
+}
+
+// Run 索引启动前外部写入的邮件，然后监视 Maildir 直到 ctx 取消
+func (w *Watcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建 Maildir 监视失败: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := w.addTree(watcher, w.maildir.Root()); err != nil {
+		return err
+	}
+	if indexed := w.Scan(ctx); indexed > 0 {
+		logger.Info().Int("indexed", indexed).Msg("已索引外部写入的邮件")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue // 重命名的旧路径，或已被删除
+			}
+			if info.IsDir() {
+				// 新用户或新文件夹，同时处理监视建立之前写入的邮件
+				if err := w.addTree(watcher, event.Name); err != nil {
+					logger.Warn().Err(err).Str("path", event.Name).Msg("监视 Maildir 目录失败")
+				}
+				w.scanDir(ctx, event.Name)
+				continue
+			}
+			path := event.Name
+			time.AfterFunc(settleDelay, func() { w.handle(ctx, path, true) })
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warn().Err(err).Msg("监视 Maildir 出错")
+		}
+	}
+}
+
+// addTree 监视目录及其子目录（tmp/ 中的文件还没有投递完成，不监视）
+func (w *Watcher) addTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if d.Name() == "tmp" {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("监视 Maildir 目录 %s 失败: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// Scan 索引所有没有数据库记录的邮件文件，返回索引的数量
+func (w *Watcher) Scan(ctx context.Context) int {
+	return w.scanDir(ctx, w.maildir.Root())
+}
+
+// scanDir 索引目录下没有数据库记录的邮件文件（不发出事件），返回索引的数量
+func (w *Watcher) scanDir(ctx context.Context, root string) int {
+	indexed := 0
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // 扫描期间被删除的文件或目录
+		}
+		if d.IsDir() {
+			if d.Name() == "tmp" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if w.handle(ctx, path, false) {
+			indexed++
+		}
+		return nil
+	})
+	return indexed
+}
+
+// handle 处理出现在 new/ 或 cur/ 中的文件：没有数据库记录时建立索引，返回是否建立了索引；
+// notify 为 true 时对 new/ 中的邮件发出新邮件事件
+func (w *Watcher) handle(ctx context.Context, path string, notify bool) bool {
+	userEmail, folder, sub, filename, ok := w.locate(path)
+	if !ok || ctx.Err() != nil {
+		return false
+	}
+	// 文件名中 : 之后为标志（如 :2,S），数据库记录的 ID 为 : 之前的部分
+	id, info, _ := strings.Cut(filename, ":")
+
+	w.indexMu.Lock()
+	defer w.indexMu.Unlock()
+
+	if _, err := os.Stat(path); err != nil {
+		return false // 已被移动或删除
+	}
+	_, err := w.storage.GetMail(ctx, id)
+	if err == nil {
+		if notify && sub == "new" {
+			w.publish(Event{UserEmail: userEmail, Folder: folder, MailID: id})
+		}
+		return false
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		logger.Warn().Err(err).Str("path", path).Msg("查询邮件记录失败")
+		return false
+	}
+	if _, err := w.storage.GetUser(ctx, userEmail); err != nil {
+		return false // 不是本地用户的目录
+	}
+
+	mail, err := w.index(ctx, path, userEmail, folder, sub, id, info)
+	if err != nil {
+		logger.Warn().Err(err).Str("path", path).Msg("索引外部写入的邮件失败")
+		return false
+	}
+	w.maildir.RecordExternal(path)
+	logger.Info().
+		Str("user", userEmail).
+		Str("folder", folder).
+		Str("mail_id", mail.ID).
+		Msg("已索引外部写入的邮件")
+	if notify {
+		w.publish(Event{UserEmail: userEmail, Folder: folder, MailID: mail.ID, External: true})
+	}
+	return true
+}
+
+// locate 从文件路径解析用户、文件夹（见 storage.Maildir.ParsePath）、new/cur 和文件名
+func (w *Watcher) locate(path string) (userEmail, folder, sub, filename string, ok bool) {
+	rel, err := filepath.Rel(w.maildir.Root(), path)
+	if err != nil {
+		return "", "", "", "", false
+	}
+	userEmail, folder, rest, ok := w.maildir.ParsePath(rel)
+	if !ok || len(rest) != 2 {
+		return "", "", "", "", false
+	}
+	sub, filename = rest[0], rest[1]
+	if (sub != "new" && sub != "cur") || strings.HasPrefix(filename, ".") {
+		return "", "", "", "", false
+	}
+	return userEmail, folder, sub, filename, true
+}
+
+// index 解析邮件文件并写入数据库记录
+func (w *Watcher) index(ctx context.Context, path, userEmail, folder, sub, id, info string) (*storage.Mail, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- 路径来自 Maildir 根目录下的监视事件
+	if err != nil {
+		return nil, fmt.Errorf("读取邮件文件失败: %w", err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取邮件文件失败: %w", err)
+	}
+
+	parsed, err := mailparse.ParseHeader(data)
+	if err != nil {
+		// 无法解析的邮件仍然索引，只是没有元数据
+		parsed = &mailparse.Message{}
+	}
+	flags := maildirFlags(info)
+	if sub == "new" {
+		flags = append(flags, "\\Recent")
+	}
+	mail := &storage.Mail{
+		ID:         id,
+		UserEmail:  userEmail,
+		Folder:     folder,
+		From:       mailparse.FormatAddress(parsed.From),
+		To:         mailparse.Emails(parsed.To),
+		Cc:         mailparse.Emails(parsed.Cc),
+		Subject:    parsed.Subject,
+		Size:       int64(len(data)),
+		Flags:      flags,
+		ReceivedAt: stat.ModTime(),
+		CreatedAt:  time.Now(),
+
+		Attachments: search.ExtractAttachments(data),
+		MessageID:   parsed.MessageID,
+		InReplyTo:   parsed.InReplyTo,
+		References:  parsed.References,
+	}
+	if err := w.storage.StoreMail(ctx, mail); err != nil {
+		return nil, fmt.Errorf("存储邮件元数据失败: %w", err)
+	}
+	return mail, nil
+}
+
+// maildirFlags 将文件名中的 Maildir 标志（:2, 之后的字母）转换为 IMAP 标志
+func maildirFlags(info string) []string {
+	letters, ok := strings.CutPrefix(info, "2,")
+	if !ok {
+		return []string{}
+	}
+	flags := []string{}
+	for _, c := range letters {
+		switch c {
+		case 'S':
+			flags = append(flags, "\\Seen")
+		case 'R':
+			flags = append(flags, "\\Answered")
+		case 'F':
+			flags = append(flags, "\\Flagged")
+		case 'T':
+			flags = append(flags, "\\Deleted")
+		case 'D':
+			flags = append(flags, "\\Draft")
+		}
+	}
+	return flags
+}
@@ -0,0 +1,143 @@
+package maildirwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func newTestWatcher(t *testing.T) (*Watcher, *storage.Maildir, storage.Driver) {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := maildir.EnsureUserMaildir("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	return New(maildir, driver), maildir, driver
+}
+
+func writeMail(t *testing.T, path string) {
+	t.Helper()
+	data := "From: Bob <bob@example.org>\r\nTo: alice@example.com\r\nSubject: procmail\r\nMessage-ID: <ext1@example.org>\r\n\r\nbody\r\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanIndexesExternalMail(t *testing.T) {
+	w, maildir, driver := newTestWatcher(t)
+	ctx := context.Background()
+	userDir := maildir.GetUserMaildir("alice@example.com")
+	writeMail(t, filepath.Join(userDir, "new", "1700000000.ext1.host"))
+	writeMail(t, filepath.Join(userDir, ".Archive", "cur", "1700000000.ext2.host:2,SF"))
+
+	// gmz 自己投递的邮件已有数据库记录，不重复索引
+	store := storage.NewMailStore(maildir, driver)
+	if err := store.Deliver(ctx, &storage.Mail{UserEmail: "alice@example.com", Folder: "INBOX", Flags: []string{}}, []byte("Subject: own\r\n\r\nbody\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if indexed := w.Scan(ctx); indexed != 2 {
+		t.Fatalf("索引数量 = %d，期望 2", indexed)
+	}
+	if indexed := w.Scan(ctx); indexed != 0 {
+		t.Fatalf("再次扫描索引数量 = %d，期望 0", indexed)
+	}
+
+	mail, err := driver.GetMail(ctx, "1700000000.ext1.host")
+	if err != nil {
+		t.Fatalf("外部写入的邮件没有索引: %v", err)
+	}
+	if mail.Folder != "INBOX" || mail.Subject != "procmail" || mail.MessageID != "ext1@example.org" {
+		t.Errorf("邮件元数据 = %q %q %q", mail.Folder, mail.Subject, mail.MessageID)
+	}
+	if !slices.Contains(mail.Flags, "\\Recent") {
+		t.Errorf("new/ 中的邮件应带 \\Recent 标志，实际 %v", mail.Flags)
+	}
+
+	archived, err := driver.GetMail(ctx, "1700000000.ext2.host")
+	if err != nil {
+		t.Fatalf("外部写入的邮件没有索引: %v", err)
+	}
+	if archived.Folder != "Archive" || !reflect.DeepEqual(archived.Flags, []string{"\\Seen", "\\Flagged"}) {
+		t.Errorf("文件夹 = %q，标志 = %v", archived.Folder, archived.Flags)
+	}
+}
+
+func TestRunPublishesNewMail(t *testing.T) {
+	w, maildir, driver := newTestWatcher(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, unsubscribe := w.Subscribe("Alice@example.com")
+	defer unsubscribe()
+	others, unsubscribeOthers := w.Subscribe("bob@example.com")
+	defer unsubscribeOthers()
+
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	time.Sleep(100 * time.Millisecond) // 等待建立监视
+
+	userDir := maildir.GetUserMaildir("alice@example.com")
+	tmp := filepath.Join(userDir, "tmp", "1700000001.ext3.host")
+	writeMail(t, tmp)
+	if err := os.Rename(tmp, filepath.Join(userDir, "new", "1700000001.ext3.host")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.MailID != "1700000001.ext3.host" || e.Folder != "INBOX" || !e.External {
+			t.Errorf("事件 = %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到新邮件事件")
+	}
+	if _, err := driver.GetMail(ctx, "1700000001.ext3.host"); err != nil {
+		t.Errorf("外部写入的邮件没有索引: %v", err)
+	}
+	select {
+	case e := <-others:
+		t.Errorf("其他用户收到了事件: %+v", e)
+	default:
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run 返回错误: %v", err)
+	}
+}
+
+func TestMaildirFlags(t *testing.T) {
+	tests := map[string][]string{
+		"":      {},
+		"2,":    {},
+		"2,DRT": {"\\Draft", "\\Answered", "\\Deleted"},
+		"1,S":   {},
+	}
+	for info, want := range tests {
+		if got := maildirFlags(info); !reflect.DeepEqual(got, want) {
+			t.Errorf("maildirFlags(%q) = %v，期望 %v", info, got, want)
+		}
+	}
+}
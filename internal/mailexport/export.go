@@ -0,0 +1,154 @@
+// Package mailexport 导出单封邮件：用于打印和归档的 PDF（邮件头、正文和附件列表）以及导出文件名。
+//
+// PDF 只包含文字：HTML 正文转为纯文本后写入，不执行脚本、不加载外部资源（远程图片、样式），
+// 附件只列出文件名、类型和大小，内容不包括在 PDF 中（需要原样保存时导出 EML）。
+package mailexport
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/mailparse"
+)
+
+// 字号
+const (
+	subjectSize = 14.0
+	headerSize  = 9.0
+	bodySize    = 10.0
+)
+
+// maxFilenameRunes 导出文件名（不含扩展名）的最大字符数
+const maxFilenameRunes = 80
+
+var (
+	hiddenPattern   = regexp.MustCompile(`(?is)<(script|style|head|title)\b.*?</(script|style|head|title)\s*>`)
+	commentPattern  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	breakPattern    = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|tr|h[1-6]|blockquote|pre|table|ul|ol)\s*>`)
+	listItemPattern = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	tagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankPattern    = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+	spacePattern    = regexp.MustCompile(`[ \t\r]+`)
+)
+
+// PDF 把邮件渲染为 PDF：主题、发件人、收件人、日期（按 loc 显示）等邮件头，纯文本正文
+// （只有 HTML 正文时转为纯文本）和附件列表
+func PDF(msg *mailparse.Message, loc *time.Location, now time.Time) []byte {
+	if loc == nil {
+		loc = time.UTC
+	}
+	w := &pdfWriter{}
+
+	subject := msg.Subject
+	if subject == "" {
+		subject = "(no subject)"
+	}
+	w.text(subject, subjectSize)
+	w.space(4)
+
+	header := func(name, value string) {
+		if value != "" {
+			w.text(name+": "+value, headerSize)
+		}
+	}
+	if msg.From != nil {
+		header("From", mailparse.FormatAddress(msg.From))
+	}
+	header("To", formatAddresses(msg.To))
+	header("Cc", formatAddresses(msg.Cc))
+	header("Bcc", formatAddresses(msg.Bcc))
+	header("Reply-To", formatAddresses(msg.ReplyTo))
+	if !msg.Date.IsZero() {
+		header("Date", msg.Date.In(loc).Format("2006-01-02 15:04:05 -0700 (MST)"))
+	}
+	if msg.MessageID != "" {
+		header("Message-ID", "<"+msg.MessageID+">")
+	}
+	w.rule()
+
+	body := msg.Text
+	if strings.TrimSpace(body) == "" && msg.HTML != "" {
+		body = HTMLToText(msg.HTML)
+	}
+	w.text(strings.TrimRight(body, "\r\n \t"), bodySize)
+
+	if len(msg.Attachments) > 0 {
+		w.rule()
+		w.text(fmt.Sprintf("Attachments (%d):", len(msg.Attachments)), headerSize)
+		for _, att := range msg.Attachments {
+			name := att.Filename
+			if name == "" {
+				name = "attachment-" + att.Number
+			}
+			w.text(fmt.Sprintf("  %s  (%s, %s)", name, att.ContentType, formatSize(len(att.Data))), headerSize)
+		}
+	}
+	return w.bytes(subject, now)
+}
+
+// HTMLToText 把 HTML 正文转为纯文本：去掉脚本、样式和注释，块级元素和 <br> 转为换行，
+// 解码字符实体，合并多余的空白和空行
+func HTMLToText(s string) string {
+	s = hiddenPattern.ReplaceAllString(s, "")
+	s = commentPattern.ReplaceAllString(s, "")
+	s = strings.NewReplacer("\r\n", " ", "\n", " ").Replace(s)
+	s = listItemPattern.ReplaceAllString(s, "\n- ")
+	s = breakPattern.ReplaceAllString(s, "\n")
+	s = tagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+	}
+	s = strings.Join(lines, "\n")
+	s = blankPattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// Filename 根据主题生成导出文件名：去掉路径分隔符和控制字符，过长时截断，主题为空时使用 message
+func Filename(subject, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, subject)
+	name = strings.Trim(strings.TrimSpace(name), ".")
+	if runes := []rune(name); len(runes) > maxFilenameRunes {
+		name = strings.TrimSpace(string(runes[:maxFilenameRunes]))
+	}
+	if name == "" {
+		name = "message"
+	}
+	return name + "." + ext
+}
+
+func formatAddresses(list []*mail.Address) string {
+	parts := make([]string, 0, len(list))
+	for _, a := range list {
+		parts = append(parts, mailparse.FormatAddress(a))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSize 格式化字节数（B、KB、MB）
+func formatSize(n int) string {
+	switch {
+	case n < 1024:
+		return fmt.Sprintf("%d B", n)
+	case n < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	}
+}
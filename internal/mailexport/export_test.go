@@ -0,0 +1,124 @@
+package mailexport
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/mailparse"
+)
+
+const sample = "From: =?UTF-8?B?5byg5LiJ?= <zhang@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: =?UTF-8?B?5pyI5bqm5oql5ZGK?= (Q3)\r\n" +
+	"Date: Mon, 05 Jan 2026 09:30:00 +0000\r\n" +
+	"Message-ID: <report-1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=XYZ\r\n" +
+	"\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<html><head><style>p{color:red}</style></head><body><p>Hello &amp; welcome</p><script>alert(1)</script></body></html>\r\n" +
+	"--XYZ\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"report (final).pdf\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERg==\r\n" +
+	"--XYZ--\r\n"
+
+func TestPDF(t *testing.T) {
+	msg, err := mailparse.Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shanghai := time.FixedZone("CST", 8*3600)
+	data := PDF(msg, shanghai, time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC))
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("PDF 文件头或结尾错误")
+	}
+	checkXref(t, data)
+
+	for _, want := range []string{
+		"<6708" + "5EA6" + "62A5" + "544A> Tj", // 月度报告（UTF-16BE）
+		"(Date: 2026-01-05 17:30:00 +0800 \\(CST\\)) Tj",
+		"(Hello & welcome) Tj",
+		"(  report \\(final\\).pdf  \\(application/pdf, 4 B\\)) Tj",
+		"(1 / 1) Tj",
+		"/Title <FEFF6708",
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("PDF 应该包含 %q", want)
+		}
+	}
+	if bytes.Contains(data, []byte("alert")) || bytes.Contains(data, []byte("color:red")) {
+		t.Error("PDF 不应该包含脚本和样式")
+	}
+
+	// 长正文分页，每一行都在页面宽度以内
+	msg = &mailparse.Message{Subject: "long", Text: strings.Repeat("word ", 4000) + strings.Repeat("中", 200)}
+	data = PDF(msg, nil, time.Now())
+	checkXref(t, data)
+	pages := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(data)
+	if n, _ := strconv.Atoi(string(pages[1])); n < 3 {
+		t.Errorf("页数 = %d，期望分页", n)
+	}
+}
+
+// checkXref 检查交叉引用表中每个对象的偏移量
+func checkXref(t *testing.T, data []byte) {
+	t.Helper()
+	start := bytes.LastIndex(data, []byte("startxref\n"))
+	offset, err := strconv.Atoi(strings.Fields(string(data[start+len("startxref\n"):]))[0])
+	if err != nil || !bytes.HasPrefix(data[offset:], []byte("xref\n")) {
+		t.Fatalf("startxref 错误: %v", err)
+	}
+	lines := strings.Split(string(data[offset:]), "\n")
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for i := 1; i < count; i++ {
+		entry, _ := strconv.Atoi(strings.Fields(lines[2+i])[0])
+		if !bytes.HasPrefix(data[entry:], []byte(fmt.Sprintf("%d 0 obj\n", i))) {
+			t.Errorf("对象 %d 的偏移量 %d 错误", i, entry)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap("alpha beta gamma\n中文中文中文", 10, 60)
+	want := []string{"alpha beta", "gamma", "中文中文中文"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("wrap() = %q", lines)
+	}
+	// 没有空格时在宽度用尽处断开
+	if lines := wrap(strings.Repeat("x", 25), 10, 60); len(lines) != 3 || lines[0] != "xxxxxxxxxx" {
+		t.Errorf("wrap() = %q", lines)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	in := "<div>Hi&nbsp;there,</div><!-- hidden --><ul><li>one</li><li>two</li></ul>\n<p>a<br>b</p>\n\n\n<p>end</p>"
+	want := "Hi there,\n\n- one\n- two\na\nb\nend"
+	if got := HTMLToText(in); got != want {
+		t.Errorf("HTMLToText() = %q，期望 %q", got, want)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	for _, tt := range []struct{ subject, want string }{
+		{"月度报告 (Q3)", "月度报告 (Q3).eml"},
+		{"../etc/passwd", "_etc_passwd.eml"},
+		{"a\r\nb: c?", "ab_ c_.eml"},
+		{"  ", "message.eml"},
+		{strings.Repeat("长", 100), strings.Repeat("长", 80) + ".eml"},
+	} {
+		if got := Filename(tt.subject, "eml"); got != tt.want {
+			t.Errorf("Filename(%q) = %q，期望 %q", tt.subject, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package mailexport
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// PDF 页面布局（A4，单位为点）
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+	lineFactor = 1.4 // 行距与字号的比例
+)
+
+// pdfWriter 生成只包含文字的 PDF，不嵌入字体：Latin-1 字符使用标准字体 Courier（等宽，字宽 0.6 em），
+// 其他字符（中文等）使用阅读器内置的 STSong-Light（Adobe-GB1 字符集，字宽 1 em）。
+// 字宽固定，折行不需要字体度量数据
+type pdfWriter struct {
+	pages [][]byte
+	page  bytes.Buffer
+	y     float64 // 下一行的基线位置
+	open  bool
+}
+
+// text 按页面宽度折行写入文字，页面写满时换页
+func (w *pdfWriter) text(s string, size float64) {
+	for _, line := range wrap(cleanText(s), size, pageWidth-2*margin) {
+		w.ensure(size * lineFactor)
+		w.y -= size * lineFactor
+		w.showLine(line, size)
+	}
+}
+
+// space 留出空白
+func (w *pdfWriter) space(height float64) {
+	w.ensure(height)
+	w.y -= height
+}
+
+// rule 画一条横线
+func (w *pdfWriter) rule() {
+	w.space(6)
+	fmt.Fprintf(&w.page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, w.y, pageWidth-margin, w.y)
+	w.y -= 6
+}
+
+// ensure 当前页剩余高度不足时换页
+func (w *pdfWriter) ensure(height float64) {
+	if w.open && w.y-height >= margin {
+		return
+	}
+	w.endPage()
+	w.open = true
+	w.y = pageHeight - margin
+}
+
+func (w *pdfWriter) endPage() {
+	if w.open {
+		w.pages = append(w.pages, bytes.Clone(w.page.Bytes()))
+		w.page.Reset()
+		w.open = false
+	}
+}
+
+// showLine 在当前位置写入一行，按字符所属的字体分段
+func (w *pdfWriter) showLine(line string, size float64) {
+	fmt.Fprintf(&w.page, "BT 1 0 0 1 %.2f %.2f Tm\n", margin, w.y)
+	runes := []rune(line)
+	for start := 0; start < len(runes); {
+		latin := isLatin1(runes[start])
+		end := start + 1
+		for end < len(runes) && isLatin1(runes[end]) == latin {
+			end++
+		}
+		if latin {
+			fmt.Fprintf(&w.page, "/F1 %.1f Tf (%s) Tj\n", size, latin1String(runes[start:end]))
+		} else {
+			fmt.Fprintf(&w.page, "/F2 %.1f Tf <%s> Tj\n", size, utf16Hex(runes[start:end]))
+		}
+		start = end
+	}
+	w.page.WriteString("ET\n")
+}
+
+// bytes 生成完整的 PDF 文件（每页底部加页码）
+func (w *pdfWriter) bytes(title string, created time.Time) []byte {
+	w.ensure(0)
+	w.endPage()
+
+	var buf bytes.Buffer
+	var offsets []int
+	begin := func() int {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n", len(offsets))
+		return len(offsets)
+	}
+	object := func(body string) {
+		begin()
+		buf.WriteString(body + "\nendobj\n")
+	}
+
+	// 固定对象 1-7，之后每页两个对象（页面和内容流）
+	const firstPage = 8
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UTF16-H /DescendantFonts [5 0 R] >>")
+	object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >> /FontDescriptor 6 0 R /DW 1000 >>")
+	object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	object(fmt.Sprintf("<< /Title <FEFF%s> /Producer (GoMailZero) /CreationDate (D:%s) >>",
+		utf16Hex([]rune(cleanText(title))), created.UTC().Format("20060102150405Z")))
+
+	for i, content := range w.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, firstPage+2*i+1))
+		footer := fmt.Sprintf("BT /F1 8.0 Tf 1 0 0 1 %.2f %.2f Tm (%d / %d) Tj ET\n", pageWidth/2-12, margin/2, i+1, len(w.pages))
+		begin()
+		fmt.Fprintf(&buf, "<< /Length %d >>\nstream\n", len(content)+len(footer))
+		buf.Write(content)
+		buf.WriteString(footer)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 7 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// wrap 按宽度折行：优先在空格处断开，一行中没有空格（如中文或很长的链接）时在宽度用尽处断开
+func wrap(s string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		runes := []rune(paragraph)
+		for {
+			used, cut, space := 0.0, len(runes), -1
+			for i, r := range runes {
+				used += runeWidth(r) * size
+				if used > width {
+					cut = i
+					break
+				}
+				if r == ' ' {
+					space = i
+				}
+			}
+			if cut == len(runes) {
+				lines = append(lines, string(runes))
+				break
+			}
+			if runes[cut] != ' ' && space > 0 {
+				cut = space + 1
+			}
+			cut = max(cut, 1)
+			lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+			// 断开处的空格不放到下一行行首
+			for cut < len(runes) && runes[cut] == ' ' {
+				cut++
+			}
+			runes = runes[cut:]
+		}
+	}
+	return lines
+}
+
+// cleanText 统一换行符，制表符展开为空格，去掉其他控制字符
+func cleanText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return r
+		}
+		if r < 0x20 || (r >= 0x7f && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isLatin1 字符是否使用 Courier（WinAnsiEncoding 中 0xA0 以上与 Latin-1 相同）
+func isLatin1(r rune) bool {
+	return r < 0x100
+}
+
+func runeWidth(r rune) float64 {
+	if isLatin1(r) {
+		return 0.6
+	}
+	return 1
+}
+
+// latin1String 编码为 PDF 字符串（转义括号和反斜杠，非 ASCII 字符使用八进制转义）
+func latin1String(runes []rune) string {
+	var b strings.Builder
+	for _, r := range runes {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x80:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// utf16Hex 编码为 UTF-16BE 的十六进制字符串（UniGB-UTF16-H 编码）
+func utf16Hex(runes []rune) string {
+	var b strings.Builder
+	for _, u := range utf16.Encode(runes) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	return b.String()
+}
@@ -0,0 +1,293 @@
+// Package mailparse 解析 RFC 5322 / RFC 2045 邮件，供 IMAP、SMTP 和 WebMail 共用
+//
+// 解析结果包含解码后的常用邮件头（编码字、字符集）、纯文本和 HTML 正文、附件，
+// 以及通过 Content-ID 引用的内嵌资源（如 HTML 正文中的 cid: 图片）。
+// 未知的字符集或传输编码不会导致解析失败，对应部分保留原始内容。
+package mailparse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message"
+	_ "github.com/emersion/go-message/charset" // 注册常见字符集（GBK、Big5、ISO-8859-x 等）
+	"github.com/emersion/go-message/mail"
+	"github.com/emersion/go-message/textproto"
+)
+
+// Message 解析后的邮件
+type Message struct {
+	Header message.Header
+
+	// 顶层 Content-Type（小写，缺失或无效时为 text/plain）
+	MIMEType    string
+	MIMESubType string
+
+	Subject   string
+	From      *mail.Address // 第一个发件人，缺失时为 nil
+	To        []*mail.Address
+	Cc        []*mail.Address
+	Bcc       []*mail.Address
+	ReplyTo   []*mail.Address
+	Date      time.Time // 缺失或无效时为零值
+	MessageID string    // 不带尖括号
+
+	// 会话相关的 Message-ID（不带尖括号）
+	InReplyTo  string   // 回复的邮件（In-Reply-To 中的第一个）
+	References []string // 会话中之前的邮件（References，从早到晚）
+
+	Text string // 第一个纯文本正文（已解码为 UTF-8）
+	HTML string // 第一个 HTML 正文（已解码为 UTF-8）
+
+	Attachments []*Part
+	Inline      []*Part // 带 Content-ID 的内嵌资源
+}
+
+// Part 附件或内嵌资源
+type Part struct {
+	Number      string // IMAP 部分编号（单部分邮件为 1）
+	ContentType string // 小写，如 image/png
+	Filename    string
+	ContentID   string // 不带尖括号
+	Data        []byte // 解码传输编码后的内容
+}
+
+// Parse 解析完整邮件，只有邮件头无法解析时返回错误
+func Parse(raw []byte) (*Message, error) {
+	entity, err := message.Read(bytes.NewReader(raw))
+	if entity == nil || (err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err)) {
+		return nil, fmt.Errorf("解析邮件头失败: %w", err)
+	}
+
+	m := newMessage(entity.Header)
+	// 多部分结构损坏时保留已解析的部分
+	_ = entity.Walk(func(path []int, e *message.Entity, err error) error {
+		if err == nil || message.IsUnknownCharset(err) || message.IsUnknownEncoding(err) {
+			m.addPart(path, e)
+		}
+		return nil
+	})
+	return m, nil
+}
+
+// ParseHeader 只解析邮件头（不读取正文）
+func ParseHeader(raw []byte) (*Message, error) {
+	h, err := textproto.ReadHeader(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("解析邮件头失败: %w", err)
+	}
+	return newMessage(message.Header{Header: h}), nil
+}
+
+// ContentType 返回邮件顶层的 MIME 类型和子类型，无法解析时返回 text/plain
+func ContentType(raw []byte) (mimeType, mimeSubType string) {
+	h, err := textproto.ReadHeader(bufio.NewReader(bytes.NewReader(raw)))
+	if err != nil {
+		return "text", "plain"
+	}
+	return splitMediaType(message.Header{Header: h})
+}
+
+// ParseAddressList 解析地址列表，返回邮箱地址
+// 不符合 RFC 5322 的列表按逗号分割，尽量提取地址而不是整体丢弃
+func ParseAddressList(s string) []string {
+	return Emails(addressList(s))
+}
+
+// Emails 提取地址列表中的邮箱地址
+func Emails(list []*mail.Address) []string {
+	emails := make([]string, 0, len(list))
+	for _, a := range list {
+		if a.Address != "" {
+			emails = append(emails, a.Address)
+		}
+	}
+	return emails
+}
+
+// FormatAddress 格式化为 "显示名称 <地址>"（显示名称不编码），nil 时返回空字符串
+func FormatAddress(a *mail.Address) string {
+	switch {
+	case a == nil:
+		return ""
+	case a.Name == "":
+		return a.Address
+	default:
+		return a.Name + " <" + a.Address + ">"
+	}
+}
+
+// FindBoundary 从缺少邮件头的多部分正文中找出边界
+// 边界是第一个以 "--" 开头、且后面有对应结束行 "--边界--" 的行
+func FindBoundary(body []byte) string {
+	lines := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if !strings.HasPrefix(line, "--") || len(line) <= 2 {
+			continue
+		}
+		boundary := line[2:]
+		for _, next := range lines[i+1:] {
+			if strings.TrimRight(next, " \t") == "--"+boundary+"--" {
+				return boundary
+			}
+		}
+	}
+	return ""
+}
+
+// newMessage 解码常用邮件头
+func newMessage(h message.Header) *Message {
+	mh := mail.Header{Header: h}
+	m := &Message{Header: h}
+	m.MIMEType, m.MIMESubType = splitMediaType(h)
+
+	if subject, err := mh.Subject(); err == nil {
+		m.Subject = subject
+	} else {
+		m.Subject = h.Get("Subject")
+	}
+	if from := headerAddressList(mh, "From"); len(from) > 0 {
+		m.From = from[0]
+	}
+	m.To = headerAddressList(mh, "To")
+	m.Cc = headerAddressList(mh, "Cc")
+	m.Bcc = headerAddressList(mh, "Bcc")
+	m.ReplyTo = headerAddressList(mh, "Reply-To")
+	m.Date, _ = mh.Date()
+	m.MessageID, _ = mh.MessageID()
+	if ids, err := mh.MsgIDList("In-Reply-To"); err == nil && len(ids) > 0 {
+		m.InReplyTo = ids[0]
+	}
+	m.References, _ = mh.MsgIDList("References")
+	return m
+}
+
+// headerAddressList 解析地址类邮件头
+func headerAddressList(h mail.Header, key string) []*mail.Address {
+	if list, err := h.AddressList(key); err == nil {
+		return list
+	}
+	return addressList(h.Get(key))
+}
+
+// addressList 解析地址列表，失败时按逗号分割并提取尖括号中的地址
+func addressList(s string) []*mail.Address {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if list, err := mail.ParseAddressList(s); err == nil {
+		return list
+	}
+
+	dec := mime.WordDecoder{CharsetReader: message.CharsetReader}
+	var list []*mail.Address
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		name, addr := "", item
+		if idx := strings.LastIndex(item, "<"); idx >= 0 {
+			name, addr = strings.TrimSpace(item[:idx]), item[idx+1:]
+			if end := strings.Index(addr, ">"); end >= 0 {
+				addr = addr[:end]
+			}
+		}
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if decoded, err := dec.DecodeHeader(strings.Trim(name, `"`)); err == nil {
+			name = decoded
+		}
+		list = append(list, &mail.Address{Name: name, Address: addr})
+	}
+	return list
+}
+
+// splitMediaType 解析 Content-Type 为小写的类型和子类型，缺失或无效时返回 text/plain
+func splitMediaType(h message.Header) (string, string) {
+	mediaType, _, err := h.ContentType()
+	if err != nil || mediaType == "" {
+		return "text", "plain"
+	}
+	typ, sub, ok := strings.Cut(strings.ToLower(mediaType), "/")
+	if !ok || typ == "" || sub == "" {
+		return "text", "plain"
+	}
+	return typ, sub
+}
+
+// addPart 按类型和 Content-Disposition 归类单个 MIME 部分
+func (m *Message) addPart(path []int, e *message.Entity) {
+	mediaType, params, err := e.Header.ContentType()
+	if err != nil || mediaType == "" {
+		mediaType = "text/plain"
+	}
+	mediaType = strings.ToLower(mediaType)
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return
+	}
+
+	disposition, _, _ := e.Header.ContentDisposition()
+	disposition = strings.ToLower(disposition)
+	ah := mail.AttachmentHeader{Header: e.Header}
+	filename, _ := ah.Filename()
+	if filename == "" && params["name"] != "" {
+		dec := mime.WordDecoder{CharsetReader: message.CharsetReader}
+		if filename, err = dec.DecodeHeader(params["name"]); err != nil {
+			filename = params["name"]
+		}
+	}
+
+	data, err := io.ReadAll(e.Body)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return
+	}
+
+	// 没有文件名且不是附件的文本部分作为正文
+	if disposition != "attachment" && filename == "" {
+		switch mediaType {
+		case "text/plain":
+			if m.Text == "" {
+				m.Text = string(data)
+				return
+			}
+		case "text/html":
+			if m.HTML == "" {
+				m.HTML = string(data)
+				return
+			}
+		}
+	}
+
+	part := &Part{
+		Number:      partNumber(path),
+		ContentType: mediaType,
+		Filename:    filename,
+		ContentID:   strings.Trim(strings.TrimSpace(e.Header.Get("Content-Id")), "<>"),
+		Data:        data,
+	}
+	if part.ContentID != "" && disposition != "attachment" {
+		m.Inline = append(m.Inline, part)
+	} else {
+		m.Attachments = append(m.Attachments, part)
+	}
+}
+
+// partNumber 将 Walk 路径转换为 IMAP 部分编号（单部分邮件为 1）
+func partNumber(path []int) string {
+	if len(path) == 0 {
+		return "1"
+	}
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strconv.Itoa(p + 1)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,181 @@
+package mailparse
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const multipartMail = "From: =?UTF-8?B?5byg5LiJ?= <zhang@example.com>\r\n" +
+	"To: \"Bob\" <bob@example.com>, carol@example.com\r\n" +
+	"Cc: dave@example.com\r\n" +
+	"Subject: =?GBK?B?xOO6ww==?= world\r\n" +
+	"Date: Mon, 02 Jan 2006 15:04:05 +0800\r\n" +
+	"Message-ID: <abc@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/related; boundary=\"rel\"\r\n" +
+	"\r\n" +
+	"--rel\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"alt\"\r\n" +
+	"\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/plain; charset=ISO-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"caf=E9\r\n" +
+	"--alt\r\n" +
+	"Content-Type: text/html; charset=UTF-8\r\n" +
+	"\r\n" +
+	"<p>caf\xc3\xa9 <img src=\"cid:logo@example.com\"></p>\r\n" +
+	"--alt--\r\n" +
+	"--rel\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-ID: <logo@example.com>\r\n" +
+	"Content-Disposition: inline\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw==\r\n" +
+	"--rel--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"=?UTF-8?B?5oql5ZGKLnBkZg==?=\"\r\n" +
+	"Content-Disposition: attachment\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERg==\r\n" +
+	"--outer--\r\n"
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(multipartMail))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	if m.MIMEType != "multipart" || m.MIMESubType != "mixed" {
+		t.Errorf("Content-Type = %s/%s", m.MIMEType, m.MIMESubType)
+	}
+	if m.Subject != "你好 world" {
+		t.Errorf("Subject = %q", m.Subject)
+	}
+	if FormatAddress(m.From) != "张三 <zhang@example.com>" {
+		t.Errorf("From = %q", FormatAddress(m.From))
+	}
+	if got := Emails(m.To); !reflect.DeepEqual(got, []string{"bob@example.com", "carol@example.com"}) {
+		t.Errorf("To = %v", got)
+	}
+	if got := Emails(m.Cc); !reflect.DeepEqual(got, []string{"dave@example.com"}) {
+		t.Errorf("Cc = %v", got)
+	}
+	if m.MessageID != "abc@example.com" || m.Date.IsZero() {
+		t.Errorf("Message-ID = %q, Date = %v", m.MessageID, m.Date)
+	}
+
+	if strings.TrimSpace(m.Text) != "café" {
+		t.Errorf("Text = %q", m.Text)
+	}
+	if !strings.Contains(m.HTML, "cid:logo@example.com") {
+		t.Errorf("HTML = %q", m.HTML)
+	}
+
+	if len(m.Inline) != 1 {
+		t.Fatalf("内嵌资源数量 = %d", len(m.Inline))
+	}
+	if img := m.Inline[0]; img.ContentID != "logo@example.com" || img.ContentType != "image/png" || img.Number != "1.2" || string(img.Data) != "\x89PNG" {
+		t.Errorf("内嵌资源 = %+v", img)
+	}
+
+	if len(m.Attachments) != 1 {
+		t.Fatalf("附件数量 = %d", len(m.Attachments))
+	}
+	if att := m.Attachments[0]; att.Filename != "报告.pdf" || att.ContentType != "application/pdf" || att.Number != "2" || string(att.Data) != "%PDF" {
+		t.Errorf("附件 = %+v", att)
+	}
+}
+
+func TestParseSinglePart(t *testing.T) {
+	raw := "From: alice@example.com\r\n" +
+		"Subject: plain\r\n" +
+		"Content-Type: text/plain; charset=x-unknown\r\n" +
+		"\r\n" +
+		"hello\r\n"
+	m, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("未知字符集不应该导致解析失败: %v", err)
+	}
+	if m.Text != "hello\r\n" || m.HTML != "" || len(m.Attachments) != 0 {
+		t.Errorf("解析结果错误: %+v", m)
+	}
+
+	// 没有 Content-Type 时默认为 text/plain
+	m, err = Parse([]byte("Subject: none\r\n\r\nbody"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MIMEType != "text" || m.MIMESubType != "plain" || m.Text != "body" {
+		t.Errorf("默认类型错误: %s/%s %q", m.MIMEType, m.MIMESubType, m.Text)
+	}
+}
+
+func TestParseHeaderThreading(t *testing.T) {
+	raw := "Message-ID: <c@example.com>\r\n" +
+		"In-Reply-To: <b@example.com>\r\n" +
+		"References: <a@example.com>\r\n <b@example.com>\r\n" +
+		"\r\n"
+	m, err := ParseHeader([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MessageID != "c@example.com" || m.InReplyTo != "b@example.com" {
+		t.Errorf("Message-ID = %q, In-Reply-To = %q", m.MessageID, m.InReplyTo)
+	}
+	if !reflect.DeepEqual(m.References, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("References = %v", m.References)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	cases := map[string][2]string{
+		"Content-Type: TEXT/HTML; charset=UTF-8\r\n\r\n<p>": {"text", "html"},
+		"Content-Type: invalid\r\n\r\nbody":                 {"text", "plain"},
+		"Subject: none\r\n\r\nbody":                         {"text", "plain"},
+		"no header line":                                    {"text", "plain"},
+	}
+	for raw, want := range cases {
+		if typ, sub := ContentType([]byte(raw)); typ != want[0] || sub != want[1] {
+			t.Errorf("ContentType(%q) = %s/%s, want %s/%s", raw, typ, sub, want[0], want[1])
+		}
+	}
+}
+
+func TestParseAddressList(t *testing.T) {
+	cases := map[string][]string{
+		"":                                     {},
+		"a@example.com, B <b@example.com>":     {"a@example.com", "b@example.com"},
+		"=?UTF-8?B?5byg5LiJ?= <z@example.com>": {"z@example.com"},
+		// 不符合 RFC 5322（显示名称中有未加引号的特殊字符）时按逗号分割
+		"Broken [x] <x@example.com>, y@example.com": {"x@example.com", "y@example.com"},
+	}
+	for s, want := range cases {
+		if got := ParseAddressList(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseAddressList(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestFindBoundary(t *testing.T) {
+	body := "This is a multi-part message in MIME format.\r\n" +
+		"\r\n" +
+		"------=_001_NextPart123_=----\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"hi\r\n" +
+		"------=_001_NextPart123_=------\r\n"
+	if got := FindBoundary([]byte(body)); got != "----=_001_NextPart123_=----" {
+		t.Errorf("FindBoundary = %q", got)
+	}
+	if got := FindBoundary([]byte("-- \r\nsignature\r\n")); got != "" {
+		t.Errorf("没有结束行时不应该找到边界: %q", got)
+	}
+}
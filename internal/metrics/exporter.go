@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"crypto/tls"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -32,10 +34,20 @@ type Exporter struct {
 	tlsHandshakes      prometheus.Counter
 	tlsHandshakeErrors prometheus.Counter
 	tlsCertExpiry      prometheus.Gauge
+	tlsConnections     *prometheus.CounterVec
+
+	// 连接和登录限制指标
+	limitRejections *prometheus.CounterVec
+
+	// 会话超时指标
+	sessionTimeouts *prometheus.CounterVec
 
 	// 存储指标
 	storageSize prometheus.Gauge
 	mailCount   prometheus.Gauge
+
+	// 配额指标
+	quotaUsersOverThreshold *prometheus.GaugeVec
 }
 
 // NewExporter 创建指标导出器
@@ -104,6 +116,22 @@ func NewExporter() *Exporter {
 			Name: "gmz_tls_cert_expiry_seconds",
 			Help: "TLS 证书过期时间（秒）",
 		}),
+		tlsConnections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gmz_tls_connections_total",
+			Help: "按协议、TLS 版本和加密套件统计的 TLS 连接数",
+		}, []string{"protocol", "version", "cipher", "resumed"}),
+
+		// 连接和登录限制指标
+		limitRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gmz_limits_rejections_total",
+			Help: "按协议和原因（banned、connections）统计的被拒绝的连接和登录数",
+		}, []string{"protocol", "reason"}),
+
+		// 会话超时指标
+		sessionTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gmz_session_timeouts_total",
+			Help: "按协议、连接状态和原因（idle、state）统计的超时断开的会话数",
+		}, []string{"protocol", "state", "reason"}),
 
 		// 存储指标
 		storageSize: prometheus.NewGauge(prometheus.GaugeOpts{
@@ -114,6 +142,12 @@ func NewExporter() *Exporter {
 			Name: "gmz_mail_count",
 			Help: "邮件总数",
 		}),
+
+		// 配额指标
+		quotaUsersOverThreshold: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "gmz_quota_users_over_threshold",
+			Help: "按预警阈值（用量百分比）统计的用量达到该阈值的用户数",
+		}, []string{"threshold"}),
 	}
 
 	// 注册指标
@@ -131,8 +165,12 @@ func NewExporter() *Exporter {
 		exporter.tlsHandshakes,
 		exporter.tlsHandshakeErrors,
 		exporter.tlsCertExpiry,
+		exporter.tlsConnections,
+		exporter.limitRejections,
+		exporter.sessionTimeouts,
 		exporter.storageSize,
 		exporter.mailCount,
+		exporter.quotaUsersOverThreshold,
 	)
 
 	return exporter
@@ -215,6 +253,27 @@ func (e *Exporter) IncTLSHandshakeErrors() {
 	e.tlsHandshakeErrors.Inc()
 }
 
+// ObserveTLSConnection 记录一次 TLS 握手协商出的版本和加密套件
+func (e *Exporter) ObserveTLSConnection(protocol string, state tls.ConnectionState) {
+	e.tlsHandshakes.Inc()
+	e.tlsConnections.WithLabelValues(
+		protocol,
+		tls.VersionName(state.Version),
+		tls.CipherSuiteName(state.CipherSuite),
+		strconv.FormatBool(state.DidResume),
+	).Inc()
+}
+
+// ObserveRejection 记录一次因 IP 被封禁或连接数超限而拒绝的连接或登录
+func (e *Exporter) ObserveRejection(protocol, reason string) {
+	e.limitRejections.WithLabelValues(protocol, reason).Inc()
+}
+
+// ObserveTimeout 记录一次因空闲或在同一状态停留过久而断开的会话
+func (e *Exporter) ObserveTimeout(protocol, state, reason string) {
+	e.sessionTimeouts.WithLabelValues(protocol, state, reason).Inc()
+}
+
 // SetTLSCertExpiry 设置 TLS 证书过期时间
 func (e *Exporter) SetTLSCertExpiry(expiry time.Time) {
 	e.tlsCertExpiry.Set(float64(expiry.Unix()))
@@ -229,3 +288,11 @@ func (e *Exporter) SetStorageSize(size float64) {
 func (e *Exporter) SetMailCount(count float64) {
 	e.mailCount.Set(count)
 }
+
+// SetQuotaUsersOverThreshold 设置用量达到各预警阈值的用户数（不再使用的阈值被移除）
+func (e *Exporter) SetQuotaUsersOverThreshold(counts map[int]int) {
+	e.quotaUsersOverThreshold.Reset()
+	for threshold, count := range counts {
+		e.quotaUsersOverThreshold.WithLabelValues(strconv.Itoa(threshold)).Set(float64(count))
+	}
+}
@@ -0,0 +1,29 @@
+// Package netguard 限制服务端代替用户发起的连接只访问公网地址
+//
+// 退订链接、外部邮箱和外部 SMTP 服务器的地址来自邮件或用户输入，
+// 连接前检查解析后的 IP，防止借服务器访问内网服务。
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// PublicIP 是否为公网地址
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() || ip.IsInterfaceLocalMulticast())
+}
+
+// PublicOnly 用作 net.Dialer.Control，拒绝连接非公网地址（在 DNS 解析之后检查）
+func PublicOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return fmt.Errorf("拒绝连接非公网地址 %s", host)
+	}
+	return nil
+}
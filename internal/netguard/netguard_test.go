@@ -0,0 +1,26 @@
+package netguard
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.0.0.1", "192.168.1.1", "169.254.169.254", "::1", "fd00::1"} {
+		if PublicIP(net.ParseIP(ip)) {
+			t.Errorf("%s 不应该是公网地址", ip)
+		}
+	}
+	if !PublicIP(net.ParseIP("93.184.216.34")) {
+		t.Error("93.184.216.34 应该是公网地址")
+	}
+}
+
+func TestPublicOnly(t *testing.T) {
+	if err := PublicOnly("tcp", "127.0.0.1:25", nil); err == nil {
+		t.Error("应该拒绝回环地址")
+	}
+	if err := PublicOnly("tcp", "93.184.216.34:25", nil); err != nil {
+		t.Errorf("应该允许公网地址: %v", err)
+	}
+}
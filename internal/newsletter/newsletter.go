@@ -0,0 +1,177 @@
+// Package newsletter 识别订阅邮件（List-Unsubscribe，RFC 2369）并在服务端执行退订（mailto 或 RFC 8058 一键退订）
+package newsletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/netguard"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// Keyword 订阅邮件的 IMAP 关键字标志
+const Keyword = "$Newsletter"
+
+// oneClickBody 一键退订的 POST 内容（RFC 8058）
+const oneClickBody = "List-Unsubscribe=One-Click"
+
+var (
+	// ErrManual 只有普通退订链接，需要用户在浏览器中打开
+	ErrManual = errors.New("该邮件需要在浏览器中打开退订链接")
+	// ErrNoMethod 没有可用的退订方式
+	ErrNoMethod = errors.New("该邮件没有可用的退订方式")
+	// ErrInvalidFolder 归档文件夹名称无效
+	ErrInvalidFolder = errors.New("无效的文件夹名称")
+)
+
+// Sender 外发邮件（mailto 退订）
+type Sender interface {
+	Send(ctx context.Context, from string, to []string, data []byte) error
+}
+
+// Detect 从邮件头识别订阅邮件，不是订阅邮件时返回 nil
+func Detect(h message.Header) *storage.Unsubscribe {
+	value := h.Get("List-Unsubscribe")
+	if value == "" {
+		return nil
+	}
+
+	u := &storage.Unsubscribe{ListID: strings.TrimSpace(h.Get("List-Id"))}
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if !strings.HasPrefix(part, "<") || !strings.HasSuffix(part, ">") {
+			continue
+		}
+		link := strings.TrimSpace(part[1 : len(part)-1])
+		switch lower := strings.ToLower(link); {
+		case strings.HasPrefix(lower, "mailto:") && u.Mailto == "":
+			u.Mailto = link
+		case strings.HasPrefix(lower, "https://") && u.URL == "":
+			u.URL = link
+		}
+	}
+	if u.Mailto == "" && u.URL == "" {
+		return nil
+	}
+	// 一键退订要求 HTTPS 链接和 List-Unsubscribe-Post 头（RFC 8058）
+	u.OneClick = u.URL != "" && strings.EqualFold(strings.TrimSpace(h.Get("List-Unsubscribe-Post")), oneClickBody)
+	return u
+}
+
+// ValidFolder 校验归档文件夹名称（不能包含路径分隔符或以 . 开头，不能是收件箱）
+func ValidFolder(folder string) error {
+	if folder == "" {
+		return nil
+	}
+	if len(folder) > 64 || strings.EqualFold(folder, "INBOX") || strings.HasPrefix(folder, ".") ||
+		strings.ContainsAny(folder, "/\\\x00") || strings.Contains(folder, "..") {
+		return ErrInvalidFolder
+	}
+	return nil
+}
+
+// Unsubscriber 在服务端执行退订
+type Unsubscriber struct {
+	Sender     Sender       // mailto 退订使用的外发路径
+	HTTPClient *http.Client // 一键退订使用的 HTTP 客户端（为空时使用只允许公网地址的默认客户端）
+}
+
+// Unsubscribe 以用户身份退订，优先使用一键退订，其次 mailto，返回使用的方式
+func (u *Unsubscriber) Unsubscribe(ctx context.Context, userEmail string, info *storage.Unsubscribe) (string, error) {
+	switch {
+	case info.OneClick:
+		return "one-click", u.oneClick(ctx, info.URL)
+	case info.Mailto != "" && u.Sender != nil:
+		return "mailto", u.mailto(ctx, userEmail, info.Mailto)
+	case info.URL != "":
+		return "", ErrManual
+	default:
+		return "", ErrNoMethod
+	}
+}
+
+// oneClick 发送一键退订请求
+func (u *Unsubscriber) oneClick(ctx context.Context, link string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, link, strings.NewReader(oneClickBody))
+	if err != nil {
+		return fmt.Errorf("创建退订请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := u.HTTPClient
+	if client == nil {
+		client = publicClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送退订请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("退订请求失败: HTTP %d", resp.StatusCode)
+	}
+	logger.InfoCtx(ctx).Str("url", link).Msg("一键退订成功")
+	return nil
+}
+
+// mailto 发送退订邮件（支持 subject 和 body 查询参数）
+func (u *Unsubscriber) mailto(ctx context.Context, userEmail, link string) error {
+	parsed, err := url.Parse(link)
+	if err != nil || parsed.Opaque == "" {
+		return fmt.Errorf("无效的退订地址: %s", link)
+	}
+	to, err := url.PathUnescape(parsed.Opaque)
+	if err != nil || !strings.Contains(to, "@") {
+		return fmt.Errorf("无效的退订地址: %s", link)
+	}
+	query := parsed.Query()
+	subject := query.Get("subject")
+	if subject == "" {
+		subject = "unsubscribe"
+	}
+	body := query.Get("body")
+	if body == "" {
+		body = "unsubscribe"
+	}
+
+	now := time.Now()
+	domain := userEmail[strings.LastIndex(userEmail, "@")+1:]
+	data := []byte(fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMessage-ID: <%d.unsubscribe@%s>\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\nAuto-Submitted: auto-generated\r\n\r\n%s\r\n",
+		userEmail, to, subject, now.Format(time.RFC1123Z), now.UnixNano(), domain, body))
+	if err := u.Sender.Send(ctx, userEmail, []string{to}, data); err != nil {
+		return fmt.Errorf("发送退订邮件失败: %w", err)
+	}
+	logger.InfoCtx(ctx).Str("user", userEmail).Str("to", to).Msg("已发送退订邮件")
+	return nil
+}
+
+// publicClient 只允许连接公网地址的 HTTP 客户端（退订链接来自外部邮件，防止访问内网服务）
+var publicClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: netguard.PublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	// 重定向到非 HTTPS 地址时停止
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 || req.URL.Scheme != "https" {
+			return http.ErrUseLastResponse
+		}
+		return nil
+	},
+}
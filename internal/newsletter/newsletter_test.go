@@ -0,0 +1,124 @@
+package newsletter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func header(t *testing.T, raw string) message.Header {
+	t.Helper()
+	msg, err := message.Read(strings.NewReader(raw + "\r\nbody\r\n"))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+	return msg.Header
+}
+
+func TestDetect(t *testing.T) {
+	h := header(t, "From: news@shop.test\r\n"+
+		"List-Id: Shop News <news.shop.test>\r\n"+
+		"List-Unsubscribe: <mailto:unsub@shop.test?subject=stop>, <https://shop.test/u/123>\r\n"+
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	u := Detect(h)
+	if u == nil {
+		t.Fatal("应该识别为订阅邮件")
+	}
+	if u.Mailto != "mailto:unsub@shop.test?subject=stop" || u.URL != "https://shop.test/u/123" || !u.OneClick {
+		t.Errorf("退订信息不正确: %+v", u)
+	}
+	if u.ListID != "Shop News <news.shop.test>" {
+		t.Errorf("ListID = %q", u.ListID)
+	}
+
+	// 没有 List-Unsubscribe-Post 时不是一键退订；HTTP 链接不接受
+	u = Detect(header(t, "List-Unsubscribe: <http://shop.test/u/1>, <https://shop.test/u/2>\r\n"))
+	if u == nil || u.URL != "https://shop.test/u/2" || u.OneClick {
+		t.Errorf("退订信息不正确: %+v", u)
+	}
+
+	if Detect(header(t, "Subject: hi\r\n")) != nil {
+		t.Error("普通邮件不应该识别为订阅邮件")
+	}
+	if Detect(header(t, "List-Unsubscribe: <http://shop.test/u/1>\r\n")) != nil {
+		t.Error("只有 HTTP 链接时不应该识别为可退订")
+	}
+}
+
+func TestValidFolder(t *testing.T) {
+	for _, folder := range []string{"", "Newsletters", "订阅"} {
+		if err := ValidFolder(folder); err != nil {
+			t.Errorf("ValidFolder(%q) = %v", folder, err)
+		}
+	}
+	for _, folder := range []string{"INBOX", "../other", "a/b", ".hidden"} {
+		if err := ValidFolder(folder); err == nil {
+			t.Errorf("ValidFolder(%q) 应该返回错误", folder)
+		}
+	}
+}
+
+func TestUnsubscribeOneClick(t *testing.T) {
+	var body string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = r.Method + " " + string(data)
+	}))
+	defer server.Close()
+
+	u := &Unsubscriber{HTTPClient: server.Client()}
+	method, err := u.Unsubscribe(context.Background(), "me@example.com", &storage.Unsubscribe{URL: server.URL, OneClick: true})
+	if err != nil || method != "one-click" {
+		t.Fatalf("一键退订失败: %s %v", method, err)
+	}
+	if body != "POST List-Unsubscribe=One-Click" {
+		t.Errorf("退订请求不正确: %q", body)
+	}
+
+	// 默认客户端拒绝访问内网地址
+	u = &Unsubscriber{}
+	if _, err := u.Unsubscribe(context.Background(), "me@example.com", &storage.Unsubscribe{URL: server.URL, OneClick: true}); err == nil {
+		t.Error("默认客户端不应该访问回环地址")
+	}
+}
+
+type fakeSender struct {
+	from string
+	to   []string
+	data []byte
+}
+
+func (f *fakeSender) Send(ctx context.Context, from string, to []string, data []byte) error {
+	f.from, f.to, f.data = from, to, data
+	return nil
+}
+
+func TestUnsubscribeMailto(t *testing.T) {
+	sender := &fakeSender{}
+	u := &Unsubscriber{Sender: sender}
+	method, err := u.Unsubscribe(context.Background(), "me@example.com",
+		&storage.Unsubscribe{Mailto: "mailto:unsub@shop.test?subject=stop%20please", URL: "https://shop.test/u/1"})
+	if err != nil || method != "mailto" {
+		t.Fatalf("mailto 退订失败: %s %v", method, err)
+	}
+	if sender.from != "me@example.com" || len(sender.to) != 1 || sender.to[0] != "unsub@shop.test" {
+		t.Errorf("退订邮件地址不正确: %s %v", sender.from, sender.to)
+	}
+	if !bytes.Contains(sender.data, []byte("Subject: stop please\r\n")) {
+		t.Errorf("退订邮件主题不正确: %q", sender.data)
+	}
+
+	// 只有普通链接时需要用户手动打开
+	_, err = u.Unsubscribe(context.Background(), "me@example.com", &storage.Unsubscribe{URL: "https://shop.test/u/1"})
+	if !errors.Is(err, ErrManual) {
+		t.Errorf("应该返回 ErrManual, got %v", err)
+	}
+}
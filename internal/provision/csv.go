@@ -0,0 +1,276 @@
+package provision
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// CSV 导入的逐行状态
+const (
+	RowCreated = "created" // 已创建
+	RowValid   = "valid"   // 校验通过（试运行，未创建）
+	RowExists  = "exists"  // 用户已存在，未修改
+	RowInvalid = "invalid" // 校验失败
+	RowFailed  = "failed"  // 创建失败
+)
+
+// ImportInvite password 列为空或为该值时生成随机初始密码（邀请）
+const ImportInvite = "invite"
+
+// csvExportHeader 导出的列（导出的文件可以直接导入，quota 和 used 为字节数）
+var csvExportHeader = []string{"email", "quota", "used", "active", "admin", "created_at"}
+
+// ImportRow 单行导入结果
+type ImportRow struct {
+	Line   int    `json:"line"` // CSV 文件中的行号（表头为第 1 行）
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Password 邀请用户生成的初始密码，只在导入结果中出现一次，需要转交给用户
+	Password string `json:"password,omitempty"`
+}
+
+// ImportResult CSV 导入结果
+type ImportResult struct {
+	Created int         `json:"created"` // 试运行时为校验通过的行数
+	Skipped int         `json:"skipped"` // 已存在、校验失败或创建失败的行数
+	Rows    []ImportRow `json:"rows"`
+}
+
+// ImportUsersCSV 从 CSV 批量导入用户
+//
+// 第一行为表头，支持的列（不区分大小写，顺序任意）：email（必需）、password、quota、active、admin。
+// password 为空或为 invite 时生成随机初始密码并在结果中返回；quota 格式同 ParseSize；
+// active 留空为 true，导出文件中的 used、created_at 列会被忽略。已存在的用户不会被修改。每行单独校验，某一行失败不影响其他行。
+// dryRun 为 true 时只校验，不创建用户。
+func ImportUsersCSV(ctx context.Context, driver storage.Driver, r io.Reader, dryRun bool) (*ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取 CSV 表头失败: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "email", "password", "quota", "active", "admin":
+			columns[name] = i
+		case "used", "created_at":
+			// 导出文件中的只读列
+		default:
+			return nil, fmt.Errorf("未知的 CSV 列 %q（支持 email、password、quota、active、admin）", name)
+		}
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("CSV 缺少 email 列")
+	}
+
+	result := &ImportResult{Rows: []ImportRow{}}
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			result.Rows = append(result.Rows, ImportRow{Line: parseErr.StartLine, Status: RowInvalid, Error: parseErr.Err.Error()})
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 CSV 失败: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		row := importUser(ctx, driver, field, seen, line, dryRun)
+		if row.Status == RowCreated || row.Status == RowValid {
+			result.Created++
+		} else {
+			result.Skipped++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if !dryRun {
+		logger.Info().
+			Int("created", result.Created).
+			Int("skipped", result.Skipped).
+			Msg("CSV 用户导入完成")
+	}
+	return result, nil
+}
+
+// importUser 校验并创建一行中的用户
+func importUser(ctx context.Context, driver storage.Driver, field func(string) string, seen map[string]int, line int, dryRun bool) ImportRow {
+	row := ImportRow{Line: line, Email: strings.ToLower(field("email"))}
+	invalid := func(format string, args ...any) ImportRow {
+		row.Status = RowInvalid
+		row.Error = fmt.Sprintf(format, args...)
+		return row
+	}
+
+	addr, err := mail.ParseAddress(row.Email)
+	if err != nil || addr.Address != row.Email || !strings.Contains(row.Email, "@") {
+		return invalid("无效的邮箱地址 %q", field("email"))
+	}
+	if first, ok := seen[row.Email]; ok {
+		return invalid("与第 %d 行重复", first)
+	}
+	seen[row.Email] = line
+
+	password := field("password")
+	invite := password == "" || strings.EqualFold(password, ImportInvite)
+	if !invite && len(password) < 8 {
+		return invalid("密码长度至少为 8 位")
+	}
+	quota, err := ParseSize(field("quota"))
+	if err != nil {
+		return invalid("%v", err)
+	}
+	active, err := parseBool(field("active"), true)
+	if err != nil {
+		return invalid("active: %v", err)
+	}
+	admin, err := parseBool(field("admin"), false)
+	if err != nil {
+		return invalid("admin: %v", err)
+	}
+
+	if _, err := driver.GetUser(ctx, row.Email); err == nil {
+		row.Status = RowExists
+		row.Error = "用户已存在"
+		return row
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		row.Status = RowFailed
+		row.Error = err.Error()
+		return row
+	}
+	if dryRun {
+		row.Status = RowValid
+		return row
+	}
+
+	if invite {
+		if password, err = randomPassword(16); err != nil {
+			row.Status = RowFailed
+			row.Error = err.Error()
+			return row
+		}
+		row.Password = password
+	}
+	passwordHash, err := crypto.HashPassword(password)
+	if err != nil {
+		row.Status = RowFailed
+		row.Error = fmt.Sprintf("密码哈希失败: %v", err)
+		row.Password = ""
+		return row
+	}
+	if err := driver.CreateUser(ctx, &storage.User{
+		Email:        row.Email,
+		PasswordHash: passwordHash,
+		Quota:        quota,
+		Active:       active,
+		IsAdmin:      admin,
+	}); err != nil {
+		row.Status = RowFailed
+		row.Error = err.Error()
+		row.Password = ""
+		return row
+	}
+	row.Status = RowCreated
+	return row
+}
+
+// ExportUsersCSV 导出所有用户及其配额和用量
+func ExportUsersCSV(ctx context.Context, driver storage.Driver, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvExportHeader); err != nil {
+		return err
+	}
+
+	const pageSize = 500
+	for offset := 0; ; offset += pageSize {
+		users, err := driver.ListUsers(ctx, pageSize, offset)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			var used int64
+			if quota, err := driver.GetQuota(ctx, user.Email); err == nil {
+				used = quota.Used
+			} else {
+				logger.Warn().Err(err).Str("user", user.Email).Msg("查询用户用量失败")
+			}
+			if err := writer.Write([]string{
+				user.Email,
+				strconv.FormatInt(user.Quota, 10),
+				strconv.FormatInt(used, 10),
+				strconv.FormatBool(user.Active),
+				strconv.FormatBool(user.IsAdmin),
+				user.CreatedAt.UTC().Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+		}
+		if len(users) < pageSize {
+			break
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseBool 解析布尔值（true/false、yes/no、1/0），空字符串返回默认值
+func parseBool(raw string, def bool) (bool, error) {
+	switch strings.ToLower(raw) {
+	case "":
+		return def, nil
+	case "true", "yes", "y", "1":
+		return true, nil
+	case "false", "no", "n", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("无效的布尔值 %q", raw)
+	}
+}
+
+// randomPassword 生成随机密码（不含易混淆的字符）
+func randomPassword(n int) (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+	b := make([]byte, n)
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", fmt.Errorf("生成随机密码失败: %w", err)
+		}
+		b[i] = alphabet[idx.Int64()]
+	}
+	return string(b), nil
+}
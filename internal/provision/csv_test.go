@@ -0,0 +1,133 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const usersCSV = "Email,Password,Quota,Admin\n" +
+	"alice@example.com,secret123,1GB,yes\n" +
+	"bob@example.com,invite,,\n" +
+	"carol@example.com,,500MB,\n" +
+	"not-an-email,secret123,,\n" +
+	"alice@example.com,secret123,,\n" +
+	"dave@example.com,short,,\n" +
+	"erin@example.com,secret123,lots,\n" +
+	"exists@example.com,secret123,,\n"
+
+func TestImportUsersCSV(t *testing.T) {
+	driver := newTestDriver(t)
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &storage.User{Email: "exists@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 试运行只校验
+	result, err := ImportUsersCSV(ctx, driver, strings.NewReader(usersCSV), true)
+	if err != nil {
+		t.Fatalf("ImportUsersCSV() 失败: %v", err)
+	}
+	if result.Created != 3 || result.Skipped != 5 {
+		t.Errorf("试运行 created = %d, skipped = %d", result.Created, result.Skipped)
+	}
+	if _, err := driver.GetUser(ctx, "alice@example.com"); err == nil {
+		t.Error("试运行不应该创建用户")
+	}
+
+	result, err = ImportUsersCSV(ctx, driver, strings.NewReader(usersCSV), false)
+	if err != nil {
+		t.Fatalf("ImportUsersCSV() 失败: %v", err)
+	}
+	want := []struct {
+		line   int
+		status string
+	}{
+		{2, RowCreated}, {3, RowCreated}, {4, RowCreated},
+		{5, RowInvalid}, {6, RowInvalid}, {7, RowInvalid}, {8, RowInvalid}, {9, RowExists},
+	}
+	if len(result.Rows) != len(want) {
+		t.Fatalf("结果行数 = %d, want %d: %+v", len(result.Rows), len(want), result.Rows)
+	}
+	for i, w := range want {
+		if row := result.Rows[i]; row.Line != w.line || row.Status != w.status {
+			t.Errorf("第 %d 行 = %+v, want line %d status %s", i, row, w.line, w.status)
+		}
+	}
+
+	alice, err := driver.GetUser(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("获取用户失败: %v", err)
+	}
+	if !alice.IsAdmin || !alice.Active || alice.Quota != 1<<30 {
+		t.Errorf("alice = %+v", alice)
+	}
+	if result.Rows[0].Password != "" {
+		t.Error("指定密码的用户不应该返回初始密码")
+	}
+
+	// 邀请用户使用生成的初始密码
+	bob := result.Rows[1]
+	if len(bob.Password) != 16 {
+		t.Fatalf("邀请用户应该返回初始密码: %+v", bob)
+	}
+	user, err := driver.GetUser(ctx, "bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := crypto.VerifyPassword(bob.Password, user.PasswordHash); !ok {
+		t.Error("初始密码与存储的哈希不匹配")
+	}
+	if result.Rows[2].Password == "" || result.Rows[2].Password == bob.Password {
+		t.Error("空密码也应该生成不同的初始密码")
+	}
+}
+
+func TestImportUsersCSVHeader(t *testing.T) {
+	driver := newTestDriver(t)
+	for _, data := range []string{"", "password,quota\nsecret123,1GB\n", "email,nickname\na@example.com,a\n"} {
+		if _, err := ImportUsersCSV(context.Background(), driver, strings.NewReader(data), false); err == nil {
+			t.Errorf("无效的表头应该返回错误: %q", data)
+		}
+	}
+}
+
+func TestExportUsersCSV(t *testing.T) {
+	driver := newTestDriver(t)
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Quota: 1000, Active: true, IsAdmin: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.StoreMail(ctx, &storage.Mail{ID: "m1", UserEmail: "alice@example.com", Folder: "INBOX", Size: 250}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportUsersCSV(ctx, driver, &buf); err != nil {
+		t.Fatalf("ExportUsersCSV() 失败: %v", err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatalf("解析导出的 CSV 失败: %v", err)
+	}
+	if len(records) != 2 || strings.Join(records[0], ",") != "email,quota,used,active,admin,created_at" {
+		t.Fatalf("导出内容不正确: %v", records)
+	}
+	if got := strings.Join(records[1][:5], ","); got != "alice@example.com,1000,250,true,true" {
+		t.Errorf("导出的用户 = %s", got)
+	}
+
+	// 导出的文件可以直接导入（用户已存在）
+	result, err := ImportUsersCSV(ctx, driver, bytes.NewReader(buf.Bytes()), true)
+	if err != nil {
+		t.Fatalf("导入导出的文件失败: %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0].Status != RowExists {
+		t.Errorf("导入结果 = %+v", result.Rows)
+	}
+}
@@ -0,0 +1,320 @@
+package provision
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec 声明式初始化数据
+type Spec struct {
+	Domains []DomainSpec `yaml:"domains"`
+	Users   []UserSpec   `yaml:"users"`
+	Aliases []AliasSpec  `yaml:"aliases"`
+}
+
+// DomainSpec 域名
+type DomainSpec struct {
+	Name   string `yaml:"name"`
+	Active *bool  `yaml:"active"` // 留空为 true
+}
+
+// UserSpec 用户
+type UserSpec struct {
+	Email string `yaml:"email"`
+	// Password 明文密码，应用时哈希；已存在的用户仅在密码不匹配时更新
+	Password string `yaml:"password"`
+	// PasswordHash 预先哈希的密码（gmz 的 Argon2id 格式），优先于 Password
+	PasswordHash string `yaml:"password_hash"`
+	Quota        string `yaml:"quota"`  // 配额（如 1GB、500MB），留空或 0 表示无限制
+	Active       *bool  `yaml:"active"` // 留空为 true
+	Admin        bool   `yaml:"admin"`
+}
+
+// AliasSpec 别名
+type AliasSpec struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// Result 应用结果统计
+type Result struct {
+	Created   int
+	Updated   int
+	Unchanged int
+}
+
+// Load 加载初始化数据，path 可以是单个 YAML 文件或包含多个 YAML 文件的目录（按文件名顺序合并）
+func Load(path string) (*Spec, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取初始化数据失败: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取初始化数据目录失败: %w", err)
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".yml" && ext != ".yaml") {
+				continue
+			}
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+		sort.Strings(files)
+	}
+
+	spec := &Spec{}
+	for _, file := range files {
+		// #nosec G304 -- 初始化数据路径来自管理员配置
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取初始化数据失败: %w", err)
+		}
+
+		var part Spec
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&part); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("解析初始化数据 %s 失败: %w", file, err)
+		}
+
+		spec.Domains = append(spec.Domains, part.Domains...)
+		spec.Users = append(spec.Users, part.Users...)
+		spec.Aliases = append(spec.Aliases, part.Aliases...)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// Validate 检查初始化数据
+func (s *Spec) Validate() error {
+	var errs []error
+	for i, domain := range s.Domains {
+		if domain.Name == "" {
+			errs = append(errs, fmt.Errorf("domains[%d]: name 不能为空", i))
+		}
+	}
+	for i, user := range s.Users {
+		if !strings.Contains(user.Email, "@") {
+			errs = append(errs, fmt.Errorf("users[%d]: 无效的邮箱地址 %q", i, user.Email))
+		}
+		if user.Password == "" && user.PasswordHash == "" {
+			errs = append(errs, fmt.Errorf("users[%d]: 必须配置 password 或 password_hash", i))
+		}
+		if _, err := ParseSize(user.Quota); err != nil {
+			errs = append(errs, fmt.Errorf("users[%d]: %w", i, err))
+		}
+	}
+	for i, alias := range s.Aliases {
+		if !strings.Contains(alias.From, "@") || alias.To == "" {
+			errs = append(errs, fmt.Errorf("aliases[%d]: from 必须是邮箱地址且 to 不能为空", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Apply 确保初始化数据中的域名、用户、别名存在（幂等，可在每次启动时执行）
+// 不会删除初始化数据之外的记录
+func Apply(ctx context.Context, driver storage.Driver, spec *Spec) (*Result, error) {
+	result := &Result{}
+
+	for _, d := range spec.Domains {
+		if err := applyDomain(ctx, driver, d, result); err != nil {
+			return result, err
+		}
+	}
+	for _, u := range spec.Users {
+		if err := applyUser(ctx, driver, u, result); err != nil {
+			return result, err
+		}
+	}
+	for _, a := range spec.Aliases {
+		if err := applyAlias(ctx, driver, a, result); err != nil {
+			return result, err
+		}
+	}
+
+	logger.Info().
+		Int("created", result.Created).
+		Int("updated", result.Updated).
+		Int("unchanged", result.Unchanged).
+		Msg("初始化数据已应用")
+	return result, nil
+}
+
+func applyDomain(ctx context.Context, driver storage.Driver, spec DomainSpec, result *Result) error {
+	name := strings.ToLower(spec.Name)
+	active := spec.Active == nil || *spec.Active
+
+	domain, err := driver.GetDomain(ctx, name)
+	if errors.Is(err, storage.ErrNotFound) {
+		if err := driver.CreateDomain(ctx, &storage.Domain{Name: name, Active: active}); err != nil {
+			return fmt.Errorf("创建域名 %s 失败: %w", name, err)
+		}
+		result.Created++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询域名 %s 失败: %w", name, err)
+	}
+
+	if domain.Active == active {
+		result.Unchanged++
+		return nil
+	}
+	domain.Active = active
+	if err := driver.UpdateDomain(ctx, domain); err != nil {
+		return fmt.Errorf("更新域名 %s 失败: %w", name, err)
+	}
+	result.Updated++
+	return nil
+}
+
+func applyUser(ctx context.Context, driver storage.Driver, spec UserSpec, result *Result) error {
+	email := strings.ToLower(spec.Email)
+	active := spec.Active == nil || *spec.Active
+	quota, err := ParseSize(spec.Quota)
+	if err != nil {
+		return fmt.Errorf("用户 %s: %w", email, err)
+	}
+
+	user, err := driver.GetUser(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		passwordHash := spec.PasswordHash
+		if passwordHash == "" {
+			passwordHash, err = crypto.HashPassword(spec.Password)
+			if err != nil {
+				return fmt.Errorf("哈希用户 %s 的密码失败: %w", email, err)
+			}
+		}
+		if err := driver.CreateUser(ctx, &storage.User{
+			Email:        email,
+			PasswordHash: passwordHash,
+			Quota:        quota,
+			Active:       active,
+			IsAdmin:      spec.Admin,
+		}); err != nil {
+			return fmt.Errorf("创建用户 %s 失败: %w", email, err)
+		}
+		result.Created++
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询用户 %s 失败: %w", email, err)
+	}
+
+	changed := false
+	if spec.PasswordHash != "" {
+		if user.PasswordHash != spec.PasswordHash {
+			user.PasswordHash = spec.PasswordHash
+			changed = true
+		}
+	} else if ok, _ := crypto.VerifyPassword(spec.Password, user.PasswordHash); !ok {
+		// 明文密码每次哈希结果不同，只有在现有哈希不匹配时才更新
+		user.PasswordHash, err = crypto.HashPassword(spec.Password)
+		if err != nil {
+			return fmt.Errorf("哈希用户 %s 的密码失败: %w", email, err)
+		}
+		changed = true
+	}
+	if user.Quota != quota || user.Active != active || user.IsAdmin != spec.Admin {
+		user.Quota = quota
+		user.Active = active
+		user.IsAdmin = spec.Admin
+		changed = true
+	}
+
+	if !changed {
+		result.Unchanged++
+		return nil
+	}
+	if err := driver.UpdateUser(ctx, user); err != nil {
+		return fmt.Errorf("更新用户 %s 失败: %w", email, err)
+	}
+	result.Updated++
+	return nil
+}
+
+func applyAlias(ctx context.Context, driver storage.Driver, spec AliasSpec, result *Result) error {
+	from := strings.ToLower(spec.From)
+	to := strings.ToLower(spec.To)
+
+	alias, err := driver.GetAlias(ctx, from)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("查询别名 %s 失败: %w", from, err)
+	}
+	if alias != nil {
+		if alias.To == to {
+			result.Unchanged++
+			return nil
+		}
+		// 别名没有更新接口，目标变化时删除后重建
+		if err := driver.DeleteAlias(ctx, from); err != nil {
+			return fmt.Errorf("删除别名 %s 失败: %w", from, err)
+		}
+	}
+
+	if err := driver.CreateAlias(ctx, &storage.Alias{
+		From:   from,
+		To:     to,
+		Domain: from[strings.LastIndex(from, "@")+1:],
+	}); err != nil {
+		return fmt.Errorf("创建别名 %s 失败: %w", from, err)
+	}
+	if alias != nil {
+		result.Updated++
+	} else {
+		result.Created++
+	}
+	return nil
+}
+
+// ParseSize 解析大小（如 1GB、500MB、1024），空字符串表示 0
+func ParseSize(raw string) (int64, error) {
+	size := strings.ToUpper(strings.TrimSpace(raw))
+	if size == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		value  int64
+	}{
+		{"GB", 1024 * 1024 * 1024},
+		{"MB", 1024 * 1024},
+		{"KB", 1024},
+	} {
+		if strings.HasSuffix(size, unit.suffix) {
+			multiplier = unit.value
+			size = strings.TrimSuffix(size, unit.suffix)
+			break
+		}
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(size), 10, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("无效的配额 %q（格式如 1GB、500MB）", raw)
+	}
+	return value * multiplier, nil
+}
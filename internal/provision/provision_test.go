@@ -0,0 +1,121 @@
+package provision
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const testSpec = `
+domains:
+  - name: example.com
+users:
+  - email: admin@example.com
+    password: secret123
+    quota: 1GB
+    admin: true
+aliases:
+  - from: postmaster@example.com
+    to: admin@example.com
+`
+
+func newTestDriver(t *testing.T) *storage.SQLiteDriver {
+	t.Helper()
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	return driver
+}
+
+func TestApplyIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "provision.yml")
+	if err := os.WriteFile(path, []byte(testSpec), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() 失败: %v", err)
+	}
+
+	driver := newTestDriver(t)
+	ctx := context.Background()
+
+	result, err := Apply(ctx, driver, spec)
+	if err != nil {
+		t.Fatalf("Apply() 失败: %v", err)
+	}
+	if result.Created != 3 {
+		t.Errorf("首次应用应该创建 3 条记录, got %+v", result)
+	}
+
+	user, err := driver.GetUser(ctx, "admin@example.com")
+	if err != nil {
+		t.Fatalf("GetUser() 失败: %v", err)
+	}
+	if ok, _ := crypto.VerifyPassword("secret123", user.PasswordHash); !ok {
+		t.Error("密码应该被哈希存储")
+	}
+	if user.Quota != 1024*1024*1024 || !user.IsAdmin || !user.Active {
+		t.Errorf("用户属性不正确: %+v", user)
+	}
+
+	// 再次应用不应产生变化
+	result, err = Apply(ctx, driver, spec)
+	if err != nil {
+		t.Fatalf("再次 Apply() 失败: %v", err)
+	}
+	if result.Created != 0 || result.Updated != 0 || result.Unchanged != 3 {
+		t.Errorf("再次应用应该没有变化, got %+v", result)
+	}
+
+	// 修改配额和别名目标后应用，应更新已有记录
+	spec.Users[0].Quota = "500MB"
+	spec.Aliases[0].To = "other@example.com"
+	result, err = Apply(ctx, driver, spec)
+	if err != nil {
+		t.Fatalf("Apply() 失败: %v", err)
+	}
+	if result.Updated != 2 {
+		t.Errorf("应该更新 2 条记录, got %+v", result)
+	}
+	alias, err := driver.GetAlias(ctx, "postmaster@example.com")
+	if err != nil || alias.To != "other@example.com" {
+		t.Errorf("别名目标应该被更新, alias = %+v, err = %v", alias, err)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{name: "unknown key", spec: "userz: []\n"},
+		{name: "user without password", spec: "users:\n  - email: a@example.com\n"},
+		{name: "invalid quota", spec: "users:\n  - email: a@example.com\n    password: x\n    quota: lots\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "provision.yml")
+			if err := os.WriteFile(path, []byte(tt.spec), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Error("Load() 应该返回错误")
+			}
+		})
+	}
+}
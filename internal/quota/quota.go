@@ -0,0 +1,251 @@
+// Package quota 配额预警：用户用量超过预警阈值（默认 80% 和 95%，可按域名设置）时
+// 向其收件箱投递一封预警邮件，并提供 WebMail 横幅使用的用量状态和超过阈值的用户数指标
+//
+// 每个用户只记录已预警的最高阈值：同一阈值只预警一次，用量降到阈值以下后再次超过时重新预警。
+package quota
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/mail"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// DefaultThresholds 默认预警阈值（用量百分比）
+var DefaultThresholds = []int{80, 95}
+
+// Observer 接收超过各阈值的用户数（由指标导出器实现）
+type Observer interface {
+	SetQuotaUsersOverThreshold(counts map[int]int)
+}
+
+// Warner 配额预警
+type Warner struct {
+	Storage storage.Driver
+	Maildir *storage.Maildir
+	// Thresholds 域名没有设置阈值时使用的阈值（为空时这些域名不预警）
+	Thresholds []int
+	Observer   Observer
+}
+
+// Status 用户的配额用量状态
+type Status struct {
+	Used    int64 `json:"used"`
+	Limit   int64 `json:"limit"`   // 0 表示无限制
+	Percent int   `json:"percent"` // 已使用的百分比
+	// Threshold 已超过的最高预警阈值，0 表示未超过（WebMail 据此显示横幅）
+	Threshold int `json:"threshold"`
+}
+
+// Status 获取用户的配额用量状态
+func (w *Warner) Status(ctx context.Context, userEmail string) (*Status, error) {
+	q, err := w.Storage.GetQuota(ctx, userEmail)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Used: q.Used, Limit: q.Limit}
+	if q.Limit > 0 {
+		status.Percent = int(q.Used * 100 / q.Limit)
+		status.Threshold = crossed(q, w.thresholds(ctx, userEmail, nil))
+	}
+	return status, nil
+}
+
+// Run 定期检查所有用户的用量，直到 ctx 取消
+func (w *Warner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Check(ctx); err != nil {
+			logger.Warn().Err(err).Msg("检查配额用量失败")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Check 检查所有设置了配额的用户：超过新的阈值时发送预警，并更新超过阈值的用户数
+func (w *Warner) Check(ctx context.Context) error {
+	quotas, err := w.Storage.ListQuotas(ctx)
+	if err != nil {
+		return err
+	}
+
+	domains := make(map[string][]int)
+	counts := make(map[int]int)
+	for _, q := range quotas {
+		thresholds := w.thresholds(ctx, q.UserEmail, domains)
+		for _, t := range thresholds {
+			if _, ok := counts[t]; !ok {
+				counts[t] = 0
+			}
+			if q.Used*100 >= int64(t)*q.Limit {
+				counts[t]++
+			}
+		}
+		if err := w.update(ctx, q, crossed(q, thresholds)); err != nil {
+			logger.Warn().Err(err).Str("user", q.UserEmail).Msg("发送配额预警失败")
+		}
+	}
+
+	if w.Observer != nil {
+		w.Observer.SetQuotaUsersOverThreshold(counts)
+	}
+	return nil
+}
+
+// update 比较用户已超过的阈值和已预警的阈值，需要时发送预警并更新记录
+func (w *Warner) update(ctx context.Context, q *storage.Quota, threshold int) error {
+	warned := 0
+	warning, err := w.Storage.GetQuotaWarning(ctx, q.UserEmail)
+	switch {
+	case err == nil:
+		warned = warning.Threshold
+	case !errors.Is(err, storage.ErrNotFound):
+		return err
+	}
+
+	switch {
+	case threshold == warned:
+		return nil
+	case threshold == 0:
+		return w.Storage.DeleteQuotaWarning(ctx, q.UserEmail)
+	case threshold < warned:
+		// 用量下降，降低记录的阈值，再次超过较高的阈值时重新预警
+		return w.Storage.SaveQuotaWarning(ctx, &storage.QuotaWarning{UserEmail: q.UserEmail, Threshold: threshold})
+	}
+
+	// 空间已满时预警邮件也无法投递，仍然记录（WebMail 横幅会提示用户）
+	if err := w.deliver(ctx, q, threshold); err != nil {
+		var exceeded *storage.QuotaExceededError
+		if !errors.As(err, &exceeded) {
+			return err
+		}
+	}
+	logger.InfoCtx(ctx).
+		Str("user", q.UserEmail).
+		Int("threshold", threshold).
+		Int64("used", q.Used).
+		Int64("limit", q.Limit).
+		Msg("已发送配额预警")
+	return w.Storage.SaveQuotaWarning(ctx, &storage.QuotaWarning{UserEmail: q.UserEmail, Threshold: threshold})
+}
+
+// deliver 向用户的收件箱投递预警邮件
+func (w *Warner) deliver(ctx context.Context, q *storage.Quota, threshold int) error {
+	// 邮件中的时间按用户设置的时区显示
+	data, subject, err := buildWarning(q, threshold, time.Now().In(storage.UserLocation(ctx, w.Storage, q.UserEmail)))
+	if err != nil {
+		return err
+	}
+	from := postmaster(q.UserEmail)
+	m := &storage.Mail{
+		ID:         fmt.Sprintf("quota-%d", time.Now().UnixNano()),
+		UserEmail:  q.UserEmail,
+		Folder:     "INBOX",
+		From:       from,
+		To:         []string{q.UserEmail},
+		Subject:    subject,
+		Size:       int64(len(data)),
+		Flags:      []string{"\\Recent"},
+		ReceivedAt: time.Now(),
+		CreatedAt:  time.Now(),
+	}
+	return storage.NewMailStore(w.Maildir, w.Storage).Deliver(ctx, m, data)
+}
+
+// thresholds 用户所在域名的预警阈值，cache 不为 nil 时按域名缓存
+func (w *Warner) thresholds(ctx context.Context, userEmail string, cache map[string][]int) []int {
+	name := userEmail[strings.LastIndex(userEmail, "@")+1:]
+	if thresholds, ok := cache[name]; ok {
+		return thresholds
+	}
+	thresholds := w.Thresholds
+	if domain, err := w.Storage.GetDomain(ctx, name); err == nil && len(domain.QuotaWarningThresholds) > 0 {
+		thresholds = domain.QuotaWarningThresholds
+	}
+	if cache != nil {
+		cache[name] = thresholds
+	}
+	return thresholds
+}
+
+// crossed 返回用量已超过的最高阈值，未超过任何阈值时返回 0
+func crossed(q *storage.Quota, thresholds []int) int {
+	if q.Limit <= 0 {
+		return 0
+	}
+	highest := 0
+	for _, t := range thresholds {
+		if q.Used*100 >= int64(t)*q.Limit && t > highest {
+			highest = t
+		}
+	}
+	return highest
+}
+
+// postmaster 预警邮件的发件地址
+func postmaster(userEmail string) string {
+	return "postmaster@" + userEmail[strings.LastIndex(userEmail, "@")+1:]
+}
+
+// buildWarning 生成预警邮件，返回邮件和主题（邮件中的时间使用 now 的时区）
+func buildWarning(q *storage.Quota, threshold int, now time.Time) ([]byte, string, error) {
+	subject := fmt.Sprintf("邮箱空间已使用 %d%%", threshold)
+	body := fmt.Sprintf("截至 %s，您的邮箱 %s 已使用 %d%% 的空间（%s / %s）。\r\n\r\n"+
+		"空间用满后将无法接收新邮件。请删除不需要的邮件（尤其是带有大附件的邮件）并清空回收站，或联系管理员增加配额。\r\n",
+		now.Format("2006-01-02 15:04 MST"), q.UserEmail, q.Used*100/q.Limit, formatSize(q.Used), formatSize(q.Limit))
+
+	from := postmaster(q.UserEmail)
+	var h mail.Header
+	h.SetDate(now)
+	h.SetAddressList("From", []*mail.Address{{Name: "Postmaster", Address: from}})
+	h.SetAddressList("To", []*mail.Address{{Address: q.UserEmail}})
+	h.SetSubject(subject)
+	if err := h.GenerateMessageIDWithHostname(from[strings.LastIndex(from, "@")+1:]); err != nil {
+		return nil, "", fmt.Errorf("生成 Message-ID 失败: %w", err)
+	}
+	h.Set("Auto-Submitted", "auto-generated")
+	h.Set("MIME-Version", "1.0")
+	h.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	var buf bytes.Buffer
+	mw, err := message.CreateWriter(&buf, h.Header)
+	if err != nil {
+		return nil, "", fmt.Errorf("生成预警邮件失败: %w", err)
+	}
+	if _, err := mw.Write([]byte(body)); err != nil {
+		return nil, "", fmt.Errorf("生成预警邮件失败: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", fmt.Errorf("生成预警邮件失败: %w", err)
+	}
+	return buf.Bytes(), subject, nil
+}
+
+// formatSize 格式化字节数（如 1.5 GiB）
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exp := float64(n)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
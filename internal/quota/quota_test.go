@@ -0,0 +1,164 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// recordObserver 记录最近一次的用户数
+type recordObserver struct {
+	counts map[int]int
+}
+
+func (o *recordObserver) SetQuotaUsersOverThreshold(counts map[int]int) {
+	o.counts = counts
+}
+
+func TestWarner(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	const limit = 1000000
+	for _, u := range []*storage.User{
+		{Email: "alice@example.com", PasswordHash: "x", Quota: limit, Active: true},
+		{Email: "bob@custom.test", PasswordHash: "x", Quota: limit, Active: true},
+	} {
+		if err := driver.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// custom.test 只在 50% 时预警
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "custom.test", Active: true, QuotaWarningThresholds: []int{50}}); err != nil {
+		t.Fatal(err)
+	}
+
+	observer := &recordObserver{}
+	w := &Warner{Storage: driver, Thresholds: DefaultThresholds, Observer: observer}
+	n := 0
+	store := func(user string, size int64) string {
+		t.Helper()
+		n++
+		id := fmt.Sprintf("m%d", n)
+		if err := driver.StoreMail(ctx, &storage.Mail{ID: id, UserEmail: user, Folder: "Archive", Size: size}); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	warnings := func(user string) []string {
+		t.Helper()
+		mails, err := driver.ListMails(ctx, user, "INBOX", 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var subjects []string
+		for _, m := range mails {
+			subjects = append(subjects, m.Subject)
+		}
+		return subjects
+	}
+	check := func() {
+		t.Helper()
+		if err := w.Check(ctx); err != nil {
+			t.Fatalf("检查配额失败: %v", err)
+		}
+	}
+
+	first := store("alice@example.com", 700000)
+	store("bob@custom.test", 600000)
+	check()
+	if got := warnings("alice@example.com"); len(got) != 0 {
+		t.Errorf("未超过阈值不应该预警: %v", got)
+	}
+	if got := warnings("bob@custom.test"); len(got) != 1 || got[0] != "邮箱空间已使用 50%" {
+		t.Errorf("应该按域名的阈值预警: %v", got)
+	}
+
+	// 超过 80%：预警一次，重复检查不再预警
+	store("alice@example.com", 120000)
+	check()
+	check()
+	if got := warnings("alice@example.com"); len(got) != 1 || got[0] != "邮箱空间已使用 80%" {
+		t.Fatalf("超过 80%% 应该预警一次: %v", got)
+	}
+	if observer.counts[80] != 1 || observer.counts[95] != 0 || observer.counts[50] != 1 {
+		t.Errorf("超过阈值的用户数不正确: %v", observer.counts)
+	}
+
+	status, err := w.Status(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Threshold != 80 || status.Percent < 82 || status.Limit != limit {
+		t.Errorf("用量状态不正确: %+v", status)
+	}
+
+	// 超过 95%
+	store("alice@example.com", 140000)
+	check()
+	if got := warnings("alice@example.com"); len(got) != 2 || !strings.Contains(strings.Join(got, ","), "95%") {
+		t.Errorf("超过 95%% 应该再次预警: %v", got)
+	}
+
+	// 用量降到阈值以下后清除记录，再次超过时重新预警
+	if err := driver.DeleteMail(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	check()
+	if _, err := driver.GetQuotaWarning(ctx, "alice@example.com"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("低于所有阈值后应该清除预警记录: %v", err)
+	}
+	if status, _ := w.Status(ctx, "alice@example.com"); status.Threshold != 0 {
+		t.Errorf("低于阈值时不应该显示横幅: %+v", status)
+	}
+	store("alice@example.com", 600000)
+	check()
+	if got := warnings("alice@example.com"); len(got) != 3 {
+		t.Errorf("再次超过阈值应该重新预警: %v", got)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	cases := map[int64]string{
+		512:               "512 B",
+		1536:              "1.5 KiB",
+		10 << 20:          "10.0 MiB",
+		3 << 30:           "3.0 GiB",
+		int64(2048) << 30: "2.0 TiB",
+	}
+	for n, want := range cases {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestBuildWarningTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 0, 30, 0, 0, time.UTC).In(loc)
+	data, _, err := buildWarning(&storage.Quota{UserEmail: "alice@example.com", Used: 900, Limit: 1000}, 80, now)
+	if err != nil {
+		t.Fatalf("生成预警邮件失败: %v", err)
+	}
+	msg := string(data)
+	if !strings.Contains(msg, "+0800") {
+		t.Errorf("Date 应该使用用户时区: %s", msg)
+	}
+	if !strings.Contains(msg, "2026-03-01 08:30 CST") {
+		t.Errorf("正文中的时间应该使用用户时区: %s", msg)
+	}
+}
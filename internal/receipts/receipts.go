@@ -0,0 +1,120 @@
+// Package receipts 内部邮件回执：本地用户发给本地用户的邮件（WebMail 发送、IMAP 提交文件夹、SMTP 提交），
+// 记录投递时间和收件人首次阅读（设置 \Seen 标志）的时间，发件人在已发送邮件的详情中查看。
+//
+// SMTP 提交的邮件没有服务器保存的已发送邮件，回执关联到客户端保存的副本（发件人邮箱中 Message-ID 相同的邮件）：
+// 投递时副本已经保存则立即记录（SentCopy），否则在客户端保存副本时补记（Saved）。
+//
+// 为了保护收件人的隐私，是否记录由收件人所在域名的回执策略决定（默认不记录）；
+// 策略在投递时生效，之后修改策略不影响已投递的邮件。
+package receipts
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// Recorder 记录内部邮件回执；为空时不记录
+type Recorder struct {
+	Storage storage.Driver
+}
+
+// New 创建内部邮件回执记录器
+func New(driver storage.Driver) *Recorder {
+	return &Recorder{Storage: driver}
+}
+
+// Delivered 记录已发送的邮件 sent 投递为本地用户的邮件 mail，收件人所在域名没有开启回执时不记录；
+// 失败时只记录日志（不影响投递）
+func (r *Recorder) Delivered(ctx context.Context, sent, mail *storage.Mail) {
+	if r == nil || r.Storage == nil || sent == nil || sent.ID == "" {
+		return
+	}
+	policy := r.policy(ctx, mail.UserEmail)
+	if policy == storage.ReceiptsOff {
+		return
+	}
+	receipt := &storage.MailReceipt{
+		SentMailID: sent.ID,
+		Recipient:  mail.UserEmail,
+		MailID:     mail.ID,
+		TrackRead:  policy == storage.ReceiptsRead,
+	}
+	if err := r.Storage.RecordMailReceipt(ctx, receipt); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("sent_mail_id", sent.ID).Str("recipient", mail.UserEmail).Msg("记录内部邮件回执失败")
+	}
+}
+
+// savedWindow 保存已发送邮件的副本时，只补记这段时间内投递的邮件（客户端在发送成功后立即保存副本）
+const savedWindow = time.Hour
+
+// SentCopy 返回发件人 sender 邮箱中 Message-ID 为 messageID 的已发送邮件（客户端保存的副本），
+// 没有时返回 nil（Delivered 不记录）
+func (r *Recorder) SentCopy(ctx context.Context, sender, messageID string) *storage.Mail {
+	if r == nil || r.Storage == nil || messageID == "" {
+		return nil
+	}
+	mails, err := r.Storage.FindMailsByMessageID(ctx, sender, messageID)
+	if err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("sender", sender).Msg("查询已发送邮件失败")
+		return nil
+	}
+	for _, mail := range mails {
+		if mail.Folder == "Sent" {
+			return mail
+		}
+	}
+	return nil
+}
+
+// Saved 发件人把已发送邮件 sent 保存到 Sent 后（SMTP 提交后客户端 APPEND 的副本），补记刚投递给本地收件人的
+// 同一邮件（Message-ID 相同、发件地址为发件人）的回执
+func (r *Recorder) Saved(ctx context.Context, sent *storage.Mail) {
+	if r == nil || r.Storage == nil || sent == nil || sent.MessageID == "" {
+		return
+	}
+	since := time.Now().Add(-savedWindow)
+	seen := map[string]bool{strings.ToLower(sent.UserEmail): true}
+	for _, recipient := range append(append(append([]string{}, sent.To...), sent.Cc...), sent.Bcc...) {
+		recipient = strings.ToLower(recipient)
+		if seen[recipient] {
+			continue
+		}
+		seen[recipient] = true
+
+		mails, err := r.Storage.FindMailsByMessageID(ctx, recipient, sent.MessageID)
+		if err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("recipient", recipient).Msg("查询收件人的邮件失败")
+			continue
+		}
+		for _, mail := range mails {
+			if mail.Folder != "Sent" && mail.ReceivedAt.After(since) && strings.EqualFold(address(mail.From), sent.UserEmail) {
+				r.Delivered(ctx, sent, mail)
+			}
+		}
+	}
+}
+
+// address 返回 "显示名称 <地址>" 格式中的地址
+func address(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		from = strings.TrimSuffix(from[i+1:], ">")
+	}
+	return strings.TrimSpace(from)
+}
+
+// policy 返回用户所在域名的回执策略（域名不存在时不记录）
+func (r *Recorder) policy(ctx context.Context, userEmail string) string {
+	at := strings.LastIndex(userEmail, "@")
+	if at < 0 {
+		return storage.ReceiptsOff
+	}
+	domain, err := r.Storage.GetDomain(ctx, strings.ToLower(userEmail[at+1:]))
+	if err != nil {
+		return storage.ReceiptsOff
+	}
+	return domain.Receipts
+}
@@ -0,0 +1,128 @@
+package receipts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestRecorderDelivered(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	for _, domain := range []*storage.Domain{
+		{Name: "private.test", Active: true},
+		{Name: "delivered.test", Active: true, Receipts: storage.ReceiptsDelivered},
+		{Name: "read.test", Active: true, Receipts: storage.ReceiptsRead},
+	} {
+		if err := driver.CreateDomain(ctx, domain); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := &storage.Mail{ID: "sent", UserEmail: "alice@read.test", Folder: "Sent", From: "alice@read.test", ReceivedAt: time.Now()}
+	mails := []*storage.Mail{
+		{ID: "m1", UserEmail: "bob@private.test", Folder: "INBOX"},
+		{ID: "m2", UserEmail: "carol@delivered.test", Folder: "INBOX"},
+		{ID: "m3", UserEmail: "dave@read.test", Folder: "INBOX"},
+		{ID: "m4", UserEmail: "erin@unknown.test", Folder: "INBOX"},
+	}
+	for _, mail := range append([]*storage.Mail{sent}, mails...) {
+		if err := driver.CreateUser(ctx, &storage.User{Email: mail.UserEmail, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+		mail.From = "alice@read.test"
+		mail.ReceivedAt = time.Now()
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := New(driver)
+	for _, mail := range mails {
+		recorder.Delivered(ctx, sent, mail)
+	}
+	// 为空时不记录
+	var nilRecorder *Recorder
+	nilRecorder.Delivered(ctx, sent, mails[2])
+
+	// 只有开启了回执的域名记录，delivered 策略不记录阅读时间
+	for _, id := range []string{"m2", "m3"} {
+		if err := driver.UpdateMailFlags(ctx, id, []string{"\\Seen"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receipts, err := driver.ListMailReceipts(ctx, "sent")
+	if err != nil || len(receipts) != 2 {
+		t.Fatalf("ListMailReceipts() = %+v, %v", receipts, err)
+	}
+	if receipts[0].Recipient != "carol@delivered.test" || receipts[0].ReadAt != nil {
+		t.Errorf("delivered 策略的回执 = %+v", receipts[0])
+	}
+	if receipts[1].Recipient != "dave@read.test" || receipts[1].ReadAt == nil {
+		t.Errorf("read 策略的回执 = %+v", receipts[1])
+	}
+}
+
+func TestRecorderSaved(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "read.test", Active: true, Receipts: storage.ReceiptsRead}); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"alice@read.test", "bob@read.test", "carol@read.test"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 通过 SMTP 提交投递给 bob 的邮件，以及 carol 收到的其他人发送的同一 Message-ID 的邮件
+	for _, mail := range []*storage.Mail{
+		{ID: "bob-1", UserEmail: "bob@read.test", Folder: "INBOX", From: "Alice <alice@read.test>", MessageID: "m1@read.test"},
+		{ID: "carol-1", UserEmail: "carol@read.test", Folder: "INBOX", From: "mallory@other.test", MessageID: "m1@read.test"},
+	} {
+		mail.ReceivedAt = time.Now()
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recorder := New(driver)
+	if sent := recorder.SentCopy(ctx, "alice@read.test", "m1@read.test"); sent != nil {
+		t.Fatalf("还没有保存副本时 SentCopy() = %+v", sent)
+	}
+
+	// 客户端之后把副本保存到 Sent
+	sent := &storage.Mail{ID: "sent", UserEmail: "alice@read.test", Folder: "Sent", From: "alice@read.test",
+		To: []string{"Bob@read.test"}, Cc: []string{"carol@read.test"}, MessageID: "m1@read.test", ReceivedAt: time.Now()}
+	if err := driver.StoreMail(ctx, sent); err != nil {
+		t.Fatal(err)
+	}
+	if found := recorder.SentCopy(ctx, "alice@read.test", "m1@read.test"); found == nil || found.ID != "sent" {
+		t.Errorf("SentCopy() = %+v", found)
+	}
+	recorder.Saved(ctx, sent)
+
+	// 只补记发件地址为发件人的邮件
+	receipts, err := driver.ListMailReceipts(ctx, "sent")
+	if err != nil || len(receipts) != 1 {
+		t.Fatalf("ListMailReceipts() = %+v, %v", receipts, err)
+	}
+	if receipts[0].Recipient != "bob@read.test" || receipts[0].MailID != "bob-1" {
+		t.Errorf("补记的回执 = %+v", receipts[0])
+	}
+}
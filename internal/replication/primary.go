@@ -0,0 +1,251 @@
+package replication
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// streamBatchSize 每次从变更日志读取的条数
+	streamBatchSize = 200
+	// defaultPollInterval 追上后检查新变更的间隔
+	defaultPollInterval = time.Second
+)
+
+// Primary 主节点复制服务
+type Primary struct {
+	Storage      storage.Driver
+	Maildir      *storage.Maildir
+	Token        string        // 备节点认证令牌
+	PollInterval time.Duration // 追上后检查新变更的间隔（默认 1s）
+}
+
+// Serve 在 listen 上启动复制服务，直到 ctx 取消（tlsConfig 为 nil 时使用明文，仅用于内网）
+func (p *Primary) Serve(ctx context.Context, listen string, tlsConfig *tls.Config) error {
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("监听复制端口失败: %w", err)
+	}
+	server := p.NewServer(tlsConfig)
+	go func() {
+		<-ctx.Done()
+		server.Stop()
+	}()
+
+	logger.Info().Str("listen", listen).Bool("tls", tlsConfig != nil).Msg("Maildir 复制服务启动")
+	if err := server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("复制服务错误: %w", err)
+	}
+	return nil
+}
+
+// NewServer 创建注册了复制服务和令牌认证的 gRPC 服务器
+func (p *Primary) NewServer(tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := p.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := p.authorize(ss.Context()); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&ServiceDesc, p)
+	return server
+}
+
+// authorize 校验请求中的认证令牌（authorization: Bearer <token>）
+func (p *Primary) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token := strings.TrimPrefix(value, "Bearer ")
+		if p.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(p.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "无效的复制令牌")
+}
+
+// Status 返回变更日志状态
+func (p *Primary) Status(ctx context.Context, _ *StatusRequest) (*StatusResponse, error) {
+	first, last, err := p.Storage.GetMaildirJournalBounds(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &StatusResponse{First: first, Last: last}, nil
+}
+
+// Fetch 返回主节点上的文件内容
+func (p *Primary) Fetch(_ context.Context, req *FetchRequest) (*FetchResponse, error) {
+	data, err := p.readFile(req.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, status.Error(codes.NotFound, "文件不存在")
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &FetchResponse{Data: data, SHA256: storage.HashContent(data)}, nil
+}
+
+// Stream 从 req.Since 之后发送变更；备节点落后于保留的日志（或首次同步）时先发送全量同步
+func (p *Primary) Stream(req *StreamRequest, stream grpc.ServerStreamingServer[Change]) error {
+	ctx := stream.Context()
+	since := req.Since
+
+	first, last, err := p.Storage.GetMaildirJournalBounds(ctx)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if since <= 0 || since+1 < first || since > last {
+		logger.Info().Int64("since", since).Int64("first", first).Int64("last", last).Msg("备节点需要全量同步")
+		if since, err = p.snapshot(ctx, stream.Send); err != nil {
+			return err
+		}
+	}
+
+	pollInterval := p.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+	for {
+		entries, err := p.Storage.ListMaildirJournal(ctx, since, streamBatchSize)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		for _, entry := range entries {
+			change := &Change{JournalEntry: *entry}
+			if entry.Op == storage.JournalStore {
+				// 文件之后可能已被移走或删除，此时只发送日志，由后续的 rename/delete 日志处理
+				if data, err := p.readFile(entry.Path); err == nil && storage.HashContent(data) == entry.SHA256 {
+					change.Data = data
+				}
+			}
+			if err := stream.Send(change); err != nil {
+				return err
+			}
+			since = entry.Seq
+		}
+		if len(entries) == streamBatchSize {
+			continue
+		}
+		if !req.Follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// snapshot 发送主节点当前的全部邮件文件，返回同步开始时的日志序号
+// （同步期间发生的变更会在之后的增量日志中重放，应用是幂等的）
+func (p *Primary) snapshot(ctx context.Context, send func(*Change) error) (int64, error) {
+	_, last, err := p.Storage.GetMaildirJournalBounds(ctx)
+	if err != nil {
+		return 0, status.Error(codes.Internal, err.Error())
+	}
+	if err := send(&Change{JournalEntry: storage.JournalEntry{Op: OpSnapshotBegin}}); err != nil {
+		return 0, err
+	}
+
+	root := p.Maildir.Root()
+	err = walkMailFiles(root, func(rel string) error {
+		data, err := p.readFile(rel)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return send(&Change{
+			JournalEntry: storage.JournalEntry{
+				Op:     storage.JournalStore,
+				Path:   rel,
+				SHA256: storage.HashContent(data),
+				Size:   int64(len(data)),
+			},
+			Data: data,
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := send(&Change{JournalEntry: storage.JournalEntry{Op: OpSnapshotEnd, Seq: last}}); err != nil {
+		return 0, err
+	}
+	return last, nil
+}
+
+// readFile 读取 Maildir 内的文件（路径相对于根目录）
+func (p *Primary) readFile(rel string) ([]byte, error) {
+	path, err := localPath(p.Maildir.Root(), rel)
+	if err != nil {
+		return nil, err
+	}
+	// #nosec G304 -- 路径已限制在 Maildir 根目录内
+	return os.ReadFile(path)
+}
+
+// localPath 将日志中的相对路径转换为本地路径（拒绝跳出根目录的路径）
+func localPath(root, rel string) (string, error) {
+	rel = filepath.FromSlash(rel)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("无效的路径: %s", rel)
+	}
+	return filepath.Join(root, rel), nil
+}
+
+// walkMailFiles 遍历 Maildir 中 cur/ 和 new/ 下的邮件文件（不包括 tmp/），fn 的参数为相对路径
+func walkMailFiles(root string, fn func(rel string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "tmp" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if parent := filepath.Base(filepath.Dir(path)); parent != "cur" && parent != "new" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+}
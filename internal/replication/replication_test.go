@@ -0,0 +1,183 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "0123456789abcdef0123"
+
+// mailFiles 列出 Maildir 中的邮件文件及内容
+func mailFiles(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	if err := walkMailFiles(root, func(rel string) error {
+		data, err := os.ReadFile(filepath.Join(root, rel))
+		files[rel] = string(data)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func assertSameFiles(t *testing.T, primary, standby string) {
+	t.Helper()
+	want, got := mailFiles(t, primary), mailFiles(t, standby)
+	if len(want) != len(got) {
+		t.Errorf("备节点文件数 = %d, want %d\nprimary: %v\nstandby: %v", len(got), len(want), want, got)
+	}
+	for path, data := range want {
+		if got[path] != data {
+			t.Errorf("备节点文件 %s 不一致", path)
+		}
+	}
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	maildir.SetJournal(driver)
+	mails := storage.NewMailStore(maildir, driver)
+	deliver := func(folder, body string) string {
+		t.Helper()
+		mail := &storage.Mail{UserEmail: "alice@example.com", Folder: folder, From: "bob@example.com", ReceivedAt: time.Now()}
+		if err := mails.Deliver(ctx, mail, []byte("Subject: "+body+"\r\n\r\n"+body)); err != nil {
+			t.Fatal(err)
+		}
+		return mail.ID
+	}
+
+	// 主节点复制服务（内存连接）
+	lis := bufconn.Listen(1 << 20)
+	primary := &Primary{Storage: driver, Maildir: maildir, Token: testToken, PollInterval: 10 * time.Millisecond}
+	server := primary.NewServer(nil)
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+	dial := func(token string) *Client {
+		t.Helper()
+		conn, err := grpc.NewClient("passthrough:///bufnet",
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithPerRPCCredentials(tokenCredentials{token: token}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return NewClient(conn)
+	}
+
+	standbyRoot := t.TempDir()
+	standby := &Standby{Client: dial(testToken), Root: standbyRoot, StateDir: t.TempDir()}
+
+	// 备节点已有但主节点没有的文件在全量同步时删除
+	stale := filepath.Join(standbyRoot, "alice@example.com", "new", "stale")
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stale, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := deliver("INBOX", "first")
+	if err := maildir.MoveToCur("alice@example.com", "INBOX", first, []string{"\\Seen"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Snapshot", func(t *testing.T) {
+		if _, err := standby.Sync(ctx, false); err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		assertSameFiles(t, maildir.Root(), standbyRoot)
+		_, last, _ := driver.GetMaildirJournalBounds(ctx)
+		if seq, _ := standby.Seq(); seq != last {
+			t.Errorf("复制进度 = %d, want %d", seq, last)
+		}
+	})
+
+	t.Run("CatchUp", func(t *testing.T) {
+		deliver("INBOX", "second")
+		deleted := deliver("INBOX", "deleted")
+		if err := maildir.DeleteMail("alice@example.com", "INBOX", deleted); err != nil {
+			t.Fatal(err)
+		}
+		if err := maildir.MoveMail("alice@example.com", "INBOX", "Archive", first); err != nil {
+			t.Fatal(err)
+		}
+		// 写入日志发送时文件已被移走，备节点需要从主节点读取重命名后的文件
+		moved := deliver("Sent", "moved")
+		if err := maildir.MoveToCur("alice@example.com", "Sent", moved, []string{"\\Seen"}); err != nil {
+			t.Fatal(err)
+		}
+
+		applied, err := standby.Sync(ctx, false)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		if applied != 6 {
+			t.Errorf("应用的变更数 = %d, want 6", applied)
+		}
+		assertSameFiles(t, maildir.Root(), standbyRoot)
+	})
+
+	t.Run("Follow", func(t *testing.T) {
+		followCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			_, err := standby.Sync(followCtx, true)
+			done <- err
+		}()
+		deliver("INBOX", "live")
+		deadline := time.Now().Add(5 * time.Second)
+		for len(mailFiles(t, standbyRoot)) != len(mailFiles(t, maildir.Root())) && time.Now().Before(deadline) {
+			time.Sleep(20 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Sync(follow) error = %v", err)
+		}
+		assertSameFiles(t, maildir.Root(), standbyRoot)
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		if _, err := dial("wrong-token").Status(ctx); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("错误的令牌应该返回 Unauthenticated, got %v", err)
+		}
+		if _, err := dial(testToken).Fetch(ctx, &FetchRequest{Path: "../etc/passwd"}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("跳出根目录的路径应该被拒绝, got %v", err)
+		}
+	})
+
+	t.Run("Promote", func(t *testing.T) {
+		if err := standby.Promote(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := standby.Sync(ctx, false); !errors.Is(err, ErrPromoted) {
+			t.Errorf("提升后继续复制应该返回 ErrPromoted, got %v", err)
+		}
+	})
+}
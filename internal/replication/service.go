@@ -0,0 +1,204 @@
+// Package replication 实现 Maildir 热备复制：主节点记录 Maildir 变更日志（写入、重命名、删除及内容哈希），
+// 备节点通过认证的 gRPC 流拉取变更并应用到本地 Maildir，落后太多或首次同步时自动全量同步。
+//
+// 服务没有使用 protoc 生成代码，消息以 JSON 编码（gRPC content-subtype 为 json），
+// 服务定义见 ServiceDesc。
+package replication
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+)
+
+// 复制流中的控制消息（全量同步开始和结束）
+const (
+	OpSnapshotBegin = "snapshot_begin" // 之后的 store 消息为主节点当前的全部文件
+	OpSnapshotEnd   = "snapshot_end"   // 全量同步结束，Seq 为同步时的日志序号，备节点删除未收到的本地文件
+)
+
+// serviceName gRPC 服务名
+const serviceName = "gmz.replication.v1.Replication"
+
+// StreamRequest 请求从日志序号 since 之后开始复制（since 为 0 时全量同步）
+type StreamRequest struct {
+	Since  int64 `json:"since"`
+	Follow bool  `json:"follow"` // 追上后是否继续等待新的变更（false 时追上即结束）
+}
+
+// Change 复制流中的一条变更
+type Change struct {
+	storage.JournalEntry
+	Data []byte `json:"data,omitempty"` // store 的文件内容（主节点上文件已被移走或删除时为空）
+}
+
+// FetchRequest 读取主节点上的文件（备节点重命名时本地缺少源文件）
+type FetchRequest struct {
+	Path string `json:"path"`
+}
+
+// FetchResponse 文件内容
+type FetchResponse struct {
+	Data   []byte `json:"data"`
+	SHA256 string `json:"sha256"`
+}
+
+// StatusRequest 查询主节点变更日志状态
+type StatusRequest struct{}
+
+// StatusResponse 主节点变更日志状态
+type StatusResponse struct {
+	First int64 `json:"first"` // 仍保留的最早日志序号
+	Last  int64 `json:"last"`  // 最新日志序号
+}
+
+// ReplicationServer 复制服务（主节点实现）
+type ReplicationServer interface {
+	Stream(req *StreamRequest, stream grpc.ServerStreamingServer[Change]) error
+	Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error)
+	Status(ctx context.Context, req *StatusRequest) (*StatusResponse, error)
+}
+
+// ServiceDesc 复制服务定义
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*ReplicationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Fetch", Handler: fetchHandler},
+		{MethodName: "Status", Handler: statusHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Stream", Handler: streamHandler, ServerStreams: true},
+	},
+}
+
+func streamHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(StreamRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(ReplicationServer).Stream(req, &grpc.GenericServerStream[StreamRequest, Change]{ServerStream: stream})
+}
+
+func fetchHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(FetchRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).Fetch(ctx, req.(*FetchRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Fetch"}, handler)
+}
+
+func statusHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(StatusRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReplicationServer).Status(ctx, req.(*StatusRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Status"}, handler)
+}
+
+// Client 复制服务客户端（备节点使用）
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient 创建复制服务客户端
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// Dial 连接主节点（tlsConfig 为 nil 时使用明文，仅用于内网），请求携带认证令牌
+func Dial(addr, token string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(tokenCredentials{token: token, secure: tlsConfig != nil}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("连接主节点失败: %w", err)
+	}
+	return conn, nil
+}
+
+// tokenCredentials 在请求中携带认证令牌
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+// Stream 打开复制流
+func (c *Client) Stream(ctx context.Context, req *StreamRequest) (grpc.ServerStreamingClient[Change], error) {
+	stream, err := c.cc.NewStream(ctx, &ServiceDesc.Streams[0], "/"+serviceName+"/Stream", grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, Change]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Fetch 读取主节点上的文件
+func (c *Client) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	resp := new(FetchResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Fetch", req, resp, grpc.CallContentSubtype(codecName)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Status 查询主节点变更日志状态
+func (c *Client) Status(ctx context.Context) (*StatusResponse, error) {
+	resp := new(StatusResponse)
+	if err := c.cc.Invoke(ctx, "/"+serviceName+"/Status", &StatusRequest{}, resp, grpc.CallContentSubtype(codecName)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// codecName JSON 编解码器名称
+const codecName = "json"
+
+// jsonCodec gRPC 的 JSON 编解码器
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return codecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
@@ -0,0 +1,287 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// stateFile 备节点已应用的日志序号
+	stateFile = "replication.seq"
+	// promotedFile 备节点已提升为主节点的标记（存在时拒绝继续复制，避免双主）
+	promotedFile = "replication.promoted"
+	// maxRetryDelay 复制断开后重连的最大间隔
+	maxRetryDelay = time.Minute
+)
+
+// ErrPromoted 备节点已提升为主节点
+var ErrPromoted = errors.New("本节点已提升为主节点，不能继续复制（如需重新作为备节点，请删除 " + promotedFile + "）")
+
+// Standby 备节点：从主节点拉取变更并应用到本地 Maildir
+type Standby struct {
+	Client   *Client
+	Root     string // 本地 Maildir 根目录
+	StateDir string // 保存复制进度和提升标记的目录（工作目录）
+
+	// 全量同步期间收到的文件，同步结束时删除其他本地文件
+	snapshot map[string]bool
+}
+
+// Seq 返回本地已应用的日志序号（从未同步时为 0）
+func (s *Standby) Seq() (int64, error) {
+	// #nosec G304 -- 状态文件路径由配置决定
+	data, err := os.ReadFile(filepath.Join(s.StateDir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("读取复制进度失败: %w", err)
+	}
+	seq, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无效的复制进度: %w", err)
+	}
+	return seq, nil
+}
+
+// saveSeq 保存已应用的日志序号
+func (s *Standby) saveSeq(seq int64) error {
+	path := filepath.Join(s.StateDir, stateFile)
+	// #nosec G306 -- 复制进度不包含敏感信息
+	if err := os.WriteFile(path+".tmp", []byte(strconv.FormatInt(seq, 10)+"\n"), 0644); err != nil {
+		return fmt.Errorf("保存复制进度失败: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("保存复制进度失败: %w", err)
+	}
+	return nil
+}
+
+// Promoted 本节点是否已提升为主节点
+func (s *Standby) Promoted() bool {
+	_, err := os.Stat(filepath.Join(s.StateDir, promotedFile))
+	return err == nil
+}
+
+// Promote 将本节点标记为主节点，之后的复制会返回 ErrPromoted（正在运行的 follow 会在下一条变更时退出）
+func (s *Standby) Promote() error {
+	path := filepath.Join(s.StateDir, promotedFile)
+	// #nosec G306 -- 标记文件不包含敏感信息
+	if err := os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644); err != nil {
+		return fmt.Errorf("写入提升标记失败: %w", err)
+	}
+	return nil
+}
+
+// Sync 从上次的进度开始复制，返回应用的变更数。follow 为 false 时追上主节点即返回，
+// 为 true 时持续复制直到 ctx 取消或连接断开
+func (s *Standby) Sync(ctx context.Context, follow bool) (int, error) {
+	if s.Promoted() {
+		return 0, ErrPromoted
+	}
+	since, err := s.Seq()
+	if err != nil {
+		return 0, err
+	}
+
+	stream, err := s.Client.Stream(ctx, &StreamRequest{Since: since, Follow: follow})
+	if err != nil {
+		return 0, fmt.Errorf("打开复制流失败: %w", err)
+	}
+	applied := 0
+	for {
+		change, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return applied, nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return applied, nil
+			}
+			return applied, fmt.Errorf("接收变更失败: %w", err)
+		}
+		if s.Promoted() {
+			return applied, ErrPromoted
+		}
+		if err := s.apply(ctx, change); err != nil {
+			return applied, err
+		}
+		applied++
+		if change.Seq > 0 {
+			if err := s.saveSeq(change.Seq); err != nil {
+				return applied, err
+			}
+		}
+	}
+}
+
+// Follow 持续复制，断开后按指数退避重连，直到 ctx 取消或本节点被提升为主节点
+func (s *Standby) Follow(ctx context.Context) error {
+	delay := time.Second
+	for {
+		start := time.Now()
+		applied, err := s.Sync(ctx, true)
+		if errors.Is(err, ErrPromoted) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			logger.Warn().Err(err).Int("applied", applied).Dur("retry_in", delay).Msg("Maildir 复制中断，稍后重连")
+		}
+		if time.Since(start) > maxRetryDelay {
+			delay = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// apply 应用一条变更（幂等：重复应用或乱序到达的文件操作不会出错）
+func (s *Standby) apply(ctx context.Context, change *Change) error {
+	switch change.Op {
+	case OpSnapshotBegin:
+		s.snapshot = make(map[string]bool)
+		return nil
+	case OpSnapshotEnd:
+		return s.finishSnapshot()
+	case storage.JournalStore:
+		if s.snapshot != nil {
+			s.snapshot[change.Path] = true
+		}
+		if change.Data == nil {
+			return nil
+		}
+		if storage.HashContent(change.Data) != change.SHA256 {
+			return fmt.Errorf("文件内容哈希不匹配: %s", change.Path)
+		}
+		return s.writeFile(change.Path, change.Data)
+	case storage.JournalRename:
+		return s.rename(ctx, change.Path, change.NewPath)
+	case storage.JournalDelete:
+		path, err := localPath(s.Root, change.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("删除文件失败: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("未知的变更类型: %s", change.Op)
+	}
+}
+
+// rename 重命名本地文件；本地缺少源文件时（写入日志到达时主节点上的文件已被移走）从主节点读取目标文件
+func (s *Standby) rename(ctx context.Context, from, to string) error {
+	src, err := localPath(s.Root, from)
+	if err != nil {
+		return err
+	}
+	dst, err := localPath(s.Root, to)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(src); err == nil {
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return fmt.Errorf("重命名文件失败: %w", err)
+		}
+		return nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+
+	resp, err := s.Client.Fetch(ctx, &FetchRequest{Path: to})
+	if status.Code(err) == codes.NotFound {
+		// 主节点上的文件之后又被移走或删除，由后续日志处理
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("从主节点读取文件失败: %w", err)
+	}
+	if storage.HashContent(resp.Data) != resp.SHA256 {
+		return fmt.Errorf("文件内容哈希不匹配: %s", to)
+	}
+	return s.writeFile(to, resp.Data)
+}
+
+// writeFile 写入本地文件（先写入同一文件夹的 tmp/，再重命名，读取方不会看到写了一半的文件）
+func (s *Standby) writeFile(rel string, data []byte) error {
+	path, err := localPath(s.Root, rel)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.ReadFile(path); err == nil && storage.HashContent(existing) == storage.HashContent(data) {
+		return nil
+	}
+
+	dir := filepath.Dir(filepath.Dir(path))
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+	}
+	tmp := filepath.Join(dir, "tmp", filepath.Base(path))
+	// #nosec G306 -- 0644 权限允许组和其他用户读取，这是 Maildir 的标准权限
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	return nil
+}
+
+// finishSnapshot 全量同步结束：删除主节点上已不存在的本地文件
+func (s *Standby) finishSnapshot() error {
+	if s.snapshot == nil {
+		return nil
+	}
+	seen := s.snapshot
+	s.snapshot = nil
+
+	removed := 0
+	err := walkMailFiles(s.Root, func(rel string) error {
+		if seen[rel] {
+			return nil
+		}
+		path, err := localPath(s.Root, rel)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("删除文件失败: %w", err)
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info().Int("files", len(seen)).Int("removed", removed).Msg("Maildir 全量同步完成")
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package saslauth 实现 SMTP 和 IMAP 共用的额外 SASL 机制：
+// CRAM-MD5（RFC 2195，兼容旧客户端）、OAUTHBEARER（RFC 7628）和 XOAUTH2（令牌认证）
+//
+// PLAIN 由各协议自己实现。这里的机制只验证凭据，验证结果交给调用方的 Login 回调，
+// 由调用方处理 IP 封禁、失败计数和会话状态，与 PLAIN 保持一致。
+package saslauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 机制名称
+const (
+	CRAMMD5     = "CRAM-MD5"
+	OAuthBearer = sasl.OAuthBearer
+	XOAuth2     = "XOAUTH2"
+)
+
+// ErrAuthFailed 凭据无效（不区分用户不存在、密钥失效和令牌无效，避免泄露用户是否存在）
+var ErrAuthFailed = errors.New("认证失败")
+
+// Config 机制配置
+type Config struct {
+	Storage     storage.Driver
+	Hostname    string // CRAM-MD5 挑战中的主机名
+	CRAMMD5     bool
+	OAuthBearer bool
+	XOAuth2     bool
+	// JWT 接受内置 JWT 管理器签发的令牌（WebMail 登录令牌，为空时不接受）
+	JWT *auth.JWTManager
+	// OAuth 接受外部身份提供方签发的令牌（为空时不接受）
+	OAuth *auth.OAuthVerifier
+}
+
+// Mechanisms 额外的 SASL 机制
+//
+// 方法可以在 nil 上调用（没有额外机制），调用方不需要判断是否启用。
+type Mechanisms struct {
+	config Config
+	totp   *auth.TOTPManager
+}
+
+// Login 凭据验证完成后的回调：成功时 user 为认证的用户，失败时 err 为原因；
+// 返回值作为认证结果（返回错误时认证失败）
+type Login func(user *storage.User, err error) error
+
+// New 创建机制集合
+func New(cfg Config) *Mechanisms {
+	return &Mechanisms{config: cfg, totp: auth.NewTOTPManager(cfg.Storage)}
+}
+
+// Names 启用的机制名称（按 AUTH 能力中的顺序）
+func (m *Mechanisms) Names() []string {
+	if m == nil {
+		return nil
+	}
+	var names []string
+	if m.config.CRAMMD5 {
+		names = append(names, CRAMMD5)
+	}
+	if m.config.OAuthBearer {
+		names = append(names, OAuthBearer)
+	}
+	if m.config.XOAuth2 {
+		names = append(names, XOAuth2)
+	}
+	return names
+}
+
+// Enabled 机制是否启用
+func (m *Mechanisms) Enabled(mech string) bool {
+	for _, name := range m.Names() {
+		if strings.EqualFold(name, mech) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewServer 创建机制的服务端，机制未启用时返回 nil
+func (m *Mechanisms) NewServer(mech string, login Login) sasl.Server {
+	if !m.Enabled(mech) {
+		return nil
+	}
+	switch strings.ToUpper(mech) {
+	case CRAMMD5:
+		return &cramMD5Server{mechanisms: m, login: login}
+	case OAuthBearer:
+		s := &oauthBearerServer{}
+		s.Server = sasl.NewOAuthBearerServer(func(opts sasl.OAuthBearerOptions) *sasl.OAuthBearerError {
+			user, err := m.verifyToken(context.Background(), opts.Username, opts.Token)
+			if s.err = login(user, err); s.err != nil {
+				return &sasl.OAuthBearerError{Status: "invalid_token", Schemes: "bearer"}
+			}
+			return nil
+		})
+		return s
+	case XOAuth2:
+		return &xoauth2Server{mechanisms: m, login: login}
+	}
+	return nil
+}
+
+// RememberPassword 明文密码认证成功后保存 CRAM-MD5 密钥
+//
+// 未启用 CRAM-MD5、用户启用了 TOTP（CRAM-MD5 无法携带验证码）或已有的密钥仍然有效时不保存。
+// 保存前重新验证密码，避免把 "密码:验证码" 格式的输入当作密码。
+func (m *Mechanisms) RememberPassword(ctx context.Context, user *storage.User, password string) {
+	if m == nil || !m.config.CRAMMD5 || user == nil {
+		return
+	}
+	if secret, err := m.config.Storage.GetCRAMMD5Secret(ctx, user.Email); err == nil && secret.PasswordHash == user.PasswordHash {
+		return
+	}
+	if enabled, err := m.totp.IsEnabled(ctx, user.Email); err != nil || enabled {
+		return
+	}
+	if valid, err := crypto.VerifyPassword(password, user.PasswordHash); err != nil || !valid {
+		return
+	}
+
+	secret, err := crypto.CRAMMD5Secret(password)
+	if err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("user", user.Email).Msg("计算 CRAM-MD5 密钥失败")
+		return
+	}
+	if err := m.config.Storage.SaveCRAMMD5Secret(ctx, &storage.CRAMMD5Secret{
+		UserEmail:    user.Email,
+		Secret:       secret,
+		PasswordHash: user.PasswordHash,
+	}); err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("user", user.Email).Msg("保存 CRAM-MD5 密钥失败")
+	}
+}
+
+// activeUser 获取仍然存在且未被禁用的用户
+func (m *Mechanisms) activeUser(ctx context.Context, email string) (*storage.User, error) {
+	user, err := m.config.Storage.GetUser(ctx, email)
+	if err != nil || !user.Active {
+		logger.Warn().Str("username", email).Msg("用户不存在或未激活")
+		return nil, ErrAuthFailed
+	}
+	return user, nil
+}
+
+// verifyCRAMMD5 验证 CRAM-MD5 响应
+func (m *Mechanisms) verifyCRAMMD5(ctx context.Context, username, challenge, digest string) (*storage.User, error) {
+	user, err := m.activeUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := m.config.Storage.GetCRAMMD5Secret(ctx, user.Email)
+	if err != nil || secret.PasswordHash != user.PasswordHash {
+		logger.Warn().Str("username", username).Msg("没有有效的 CRAM-MD5 密钥（需要先用明文密码登录一次）")
+		return nil, ErrAuthFailed
+	}
+	if enabled, err := m.totp.IsEnabled(ctx, user.Email); err != nil || enabled {
+		logger.Warn().Str("username", username).Msg("启用了 TOTP 的用户不能使用 CRAM-MD5")
+		return nil, ErrAuthFailed
+	}
+	valid, err := crypto.VerifyCRAMMD5(secret.Secret, challenge, digest)
+	if err != nil || !valid {
+		logger.Warn().Str("username", username).Msg("CRAM-MD5 摘要错误")
+		return nil, ErrAuthFailed
+	}
+	logger.Info().Str("username", username).Str("mechanism", CRAMMD5).Msg("用户认证成功")
+	return user, nil
+}
+
+// verifyToken 验证访问令牌（先尝试内置 JWT，再尝试外部身份提供方），username 不为空时必须与令牌中的用户一致
+func (m *Mechanisms) verifyToken(ctx context.Context, username, token string) (*storage.User, error) {
+	email := ""
+	if m.config.JWT != nil {
+		if claims, err := m.config.JWT.Validate(ctx, token); err == nil {
+			email = claims.Email
+		}
+	}
+	if email == "" && m.config.OAuth != nil {
+		var err error
+		if email, err = m.config.OAuth.Verify(ctx, token); err != nil {
+			logger.Debug().Err(err).Msg("外部令牌验证失败")
+		}
+	}
+	if email == "" {
+		logger.Warn().Str("username", username).Msg("无效的访问令牌")
+		return nil, ErrAuthFailed
+	}
+	if username != "" && !strings.EqualFold(username, email) {
+		logger.Warn().Str("username", username).Str("token_user", email).Msg("访问令牌不属于该用户")
+		return nil, ErrAuthFailed
+	}
+
+	user, err := m.activeUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info().Str("username", user.Email).Msg("用户令牌认证成功")
+	return user, nil
+}
+
+// cramMD5Server CRAM-MD5 服务端：发送挑战，客户端回复 "用户名 十六进制摘要"
+type cramMD5Server struct {
+	mechanisms *Mechanisms
+	login      Login
+	challenge  string
+}
+
+func (s *cramMD5Server) Next(response []byte) ([]byte, bool, error) {
+	if s.challenge == "" {
+		if len(response) > 0 {
+			return nil, true, fmt.Errorf("CRAM-MD5 不支持初始响应")
+		}
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, true, fmt.Errorf("生成挑战失败: %w", err)
+		}
+		hostname := s.mechanisms.config.Hostname
+		if hostname == "" {
+			hostname, _ = os.Hostname()
+		}
+		s.challenge = fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(b), time.Now().Unix(), hostname)
+		return []byte(s.challenge), false, nil
+	}
+
+	username, digest, ok := strings.Cut(strings.TrimSpace(string(response)), " ")
+	if !ok {
+		return nil, true, s.login(nil, ErrAuthFailed)
+	}
+	user, err := s.mechanisms.verifyCRAMMD5(context.Background(), username, s.challenge, digest)
+	return nil, true, s.login(user, err)
+}
+
+// oauthBearerServer 包装 go-sasl 的 OAUTHBEARER 服务端，失败时返回 Login 回调的错误（如 IP 被封禁）
+type oauthBearerServer struct {
+	sasl.Server
+	err error
+}
+
+func (s *oauthBearerServer) Next(response []byte) ([]byte, bool, error) {
+	challenge, done, err := s.Server.Next(response)
+	if err != nil && s.err != nil {
+		err = s.err
+	}
+	return challenge, done, err
+}
+
+// xoauth2Error XOAUTH2 失败时发送给客户端的错误（客户端回复空行后结束认证）
+const xoauth2Error = `{"status":"401","schemes":"bearer"}`
+
+// xoauth2Server XOAUTH2 服务端：客户端发送 "user=用户名\x01auth=Bearer 令牌\x01\x01"
+type xoauth2Server struct {
+	mechanisms *Mechanisms
+	login      Login
+	started    bool
+	err        error
+}
+
+func (s *xoauth2Server) Next(response []byte) ([]byte, bool, error) {
+	// 已发送错误，客户端的任何回复都结束认证
+	if s.err != nil {
+		return nil, true, s.err
+	}
+	if len(response) == 0 && !s.started {
+		s.started = true
+		return []byte{}, false, nil
+	}
+	s.started = true
+
+	var username, token string
+	for _, field := range strings.Split(string(response), "\x01") {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "user":
+			username = value
+		case "auth":
+			if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+				token = value[7:]
+			}
+		}
+	}
+
+	var user *storage.User
+	err := ErrAuthFailed
+	if username != "" && token != "" {
+		user, err = s.mechanisms.verifyToken(context.Background(), username, token)
+	}
+	if s.err = s.login(user, err); s.err != nil {
+		return []byte(xoauth2Error), false, nil
+	}
+	return nil, true, nil
+}
@@ -0,0 +1,213 @@
+package saslauth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// newTestMechanisms 创建启用全部机制的测试环境，用户 alice 的密码为 secret
+func newTestMechanisms(t *testing.T) (*Mechanisms, storage.Driver, *auth.JWTManager) {
+	t.Helper()
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	hash, err := crypto.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: hash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := auth.NewJWTManager("test-secret", "example.com")
+	m := New(Config{
+		Storage:     driver,
+		Hostname:    "mx.example.com",
+		CRAMMD5:     true,
+		OAuthBearer: true,
+		XOAuth2:     true,
+		JWT:         jwt,
+	})
+	return m, driver, jwt
+}
+
+// recordLogin 记录 Login 回调的结果，验证失败时返回 ErrAuthFailed
+func recordLogin(user **storage.User) Login {
+	return func(u *storage.User, err error) error {
+		*user = u
+		return err
+	}
+}
+
+func TestNames(t *testing.T) {
+	var m *Mechanisms
+	if names := m.Names(); len(names) != 0 {
+		t.Errorf("nil 不应该有机制: %v", names)
+	}
+	if m.NewServer(CRAMMD5, nil) != nil {
+		t.Error("未启用的机制不应该创建服务端")
+	}
+
+	m = New(Config{XOAuth2: true})
+	if names := m.Names(); len(names) != 1 || names[0] != XOAuth2 {
+		t.Errorf("Names() = %v", names)
+	}
+	if !m.Enabled("xoauth2") || m.Enabled(CRAMMD5) {
+		t.Error("Enabled() 结果错误")
+	}
+}
+
+func TestCRAMMD5(t *testing.T) {
+	ctx := context.Background()
+	m, driver, _ := newTestMechanisms(t)
+	user, err := driver.GetUser(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	respond := func(password string) (*storage.User, error) {
+		var got *storage.User
+		server := m.NewServer("cram-md5", recordLogin(&got))
+		challenge, done, err := server.Next(nil)
+		if err != nil || done {
+			t.Fatalf("发送挑战失败: %v", err)
+		}
+		if !strings.HasPrefix(string(challenge), "<") || !strings.HasSuffix(string(challenge), "@mx.example.com>") {
+			t.Errorf("挑战格式错误: %q", challenge)
+		}
+		mac := hmac.New(md5.New, []byte(password))
+		mac.Write(challenge)
+		_, done, err = server.Next([]byte("alice@example.com " + hex.EncodeToString(mac.Sum(nil))))
+		if !done {
+			t.Error("回复摘要后应该结束认证")
+		}
+		return got, err
+	}
+
+	// 没有用明文密码登录过时没有密钥
+	if _, err := respond("secret"); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("没有密钥时应该失败: %v", err)
+	}
+
+	// "密码:验证码" 格式的输入不是真正的密码，不保存
+	m.RememberPassword(ctx, user, "secret:123456")
+	if _, err := driver.GetCRAMMD5Secret(ctx, user.Email); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("错误的密码不应该保存密钥: %v", err)
+	}
+
+	m.RememberPassword(ctx, user, "secret")
+	if got, err := respond("secret"); err != nil || got == nil || got.Email != user.Email {
+		t.Fatalf("CRAM-MD5 认证失败: %v, %v", got, err)
+	}
+	if _, err := respond("wrong"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("错误的密码应该失败: %v", err)
+	}
+
+	// 不支持初始响应
+	if _, _, err := m.NewServer(CRAMMD5, recordLogin(new(*storage.User))).Next([]byte("alice@example.com 00")); err == nil {
+		t.Error("初始响应应该被拒绝")
+	}
+
+	// 启用 TOTP 后不能使用 CRAM-MD5
+	if err := driver.SaveTOTPSecret(ctx, user.Email, "totp"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := respond("secret"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("启用 TOTP 后应该失败: %v", err)
+	}
+	if err := driver.DeleteTOTPSecret(ctx, user.Email); err != nil {
+		t.Fatal(err)
+	}
+
+	// 修改密码后旧密钥失效
+	hash, _ := crypto.HashPassword("changed")
+	user.PasswordHash = hash
+	if err := driver.UpdateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := respond("secret"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("修改密码后旧密钥应该失效: %v", err)
+	}
+	m.RememberPassword(ctx, user, "changed")
+	if _, err := respond("changed"); err != nil {
+		t.Errorf("新密码应该认证成功: %v", err)
+	}
+}
+
+func TestOAuthBearer(t *testing.T) {
+	m, _, jwt := newTestMechanisms(t)
+	token, err := jwt.GenerateToken("alice@example.com", 1, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got *storage.User
+	server := m.NewServer(OAuthBearer, recordLogin(&got))
+	ir := sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: "alice@example.com", Token: token})
+	_, resp, _ := ir.Start()
+	if _, done, err := server.Next(resp); err != nil || !done || got == nil {
+		t.Fatalf("OAUTHBEARER 认证失败: %v, %v", done, err)
+	}
+
+	// 令牌不属于 authzid 中的用户
+	got = nil
+	server = m.NewServer(OAuthBearer, recordLogin(&got))
+	_, resp, _ = sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: "bob@example.com", Token: token}).Start()
+	challenge, done, err := server.Next(resp)
+	if err != nil || done || !strings.Contains(string(challenge), "invalid_token") {
+		t.Fatalf("失败时应该发送错误挑战: %q, %v, %v", challenge, done, err)
+	}
+	if _, _, err := server.Next([]byte{0x01}); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("应该返回 Login 回调的错误: %v", err)
+	}
+}
+
+func TestXOAuth2(t *testing.T) {
+	m, _, jwt := newTestMechanisms(t)
+	token, err := jwt.GenerateToken("alice@example.com", 1, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got *storage.User
+	server := m.NewServer(XOAuth2, recordLogin(&got))
+	if challenge, done, err := server.Next(nil); err != nil || done || len(challenge) != 0 {
+		t.Fatalf("没有初始响应时应该发送空挑战: %q, %v, %v", challenge, done, err)
+	}
+	if _, done, err := server.Next([]byte("user=alice@example.com\x01auth=Bearer " + token + "\x01\x01")); err != nil || !done || got == nil {
+		t.Fatalf("XOAUTH2 认证失败: %v, %v", done, err)
+	}
+
+	for name, resp := range map[string]string{
+		"invalid token": "user=alice@example.com\x01auth=Bearer invalid\x01\x01",
+		"other user":    "user=bob@example.com\x01auth=Bearer " + token + "\x01\x01",
+		"no token":      "user=alice@example.com\x01\x01",
+	} {
+		server := m.NewServer(XOAuth2, recordLogin(&got))
+		challenge, done, err := server.Next([]byte(resp))
+		if err != nil || done || string(challenge) != xoauth2Error {
+			t.Errorf("%s: 失败时应该发送错误挑战: %q, %v, %v", name, challenge, done, err)
+			continue
+		}
+		if _, done, err := server.Next(nil); !done || !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("%s: 客户端回复后应该失败: %v, %v", name, done, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package search
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-message"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// ExtractAttachments 从原始邮件中提取附件元数据
+// 带有 Content-Disposition: attachment 或文件名的 MIME 部分视为附件
+func ExtractAttachments(raw []byte) []*storage.Attachment {
+	msg, err := message.Read(bytes.NewReader(raw))
+	if msg == nil || (err != nil && !message.IsUnknownCharset(err) && !message.IsUnknownEncoding(err)) {
+		return nil
+	}
+
+	var attachments []*storage.Attachment
+	_ = msg.Walk(func(path []int, entity *message.Entity, err error) error {
+		if err != nil {
+			return nil
+		}
+		filename, ok := attachmentName(entity.Header)
+		if !ok {
+			return nil
+		}
+		mediaType, _, _ := entity.Header.ContentType()
+		size, _ := io.Copy(io.Discard, entity.Body)
+		attachments = append(attachments, &storage.Attachment{
+			Part:        partNumber(path),
+			Filename:    filename,
+			ContentType: strings.ToLower(mediaType),
+			Size:        size,
+		})
+		return nil
+	})
+	return attachments
+}
+
+// attachmentName 判断单个 MIME 部分是否为附件，返回文件名
+func attachmentName(h message.Header) (string, bool) {
+	mediaType, ctParams, ctErr := h.ContentType()
+	if ctErr == nil && strings.HasPrefix(mediaType, "multipart/") {
+		return "", false
+	}
+	if disp, params, err := h.ContentDisposition(); err == nil {
+		if params["filename"] != "" {
+			return params["filename"], true
+		}
+		if strings.EqualFold(disp, "attachment") {
+			return ctParams["name"], true
+		}
+	}
+	if ctErr == nil && ctParams["name"] != "" {
+		return ctParams["name"], true
+	}
+	return "", false
+}
+
+// partNumber 将 Walk 路径转换为 IMAP 部分编号（单部分邮件为 1）
+func partNumber(path []int) string {
+	if len(path) == 0 {
+		return "1"
+	}
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strconv.Itoa(p + 1)
+	}
+	return strings.Join(parts, ".")
+}
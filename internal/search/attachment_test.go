@@ -0,0 +1,46 @@
+package search
+
+import "testing"
+
+func TestExtractAttachments(t *testing.T) {
+	plain := "From: a@example.com\r\nTo: b@example.com\r\nSubject: hi\r\n\r\nhello\r\n"
+	if atts := ExtractAttachments([]byte(plain)); len(atts) != 0 {
+		t.Errorf("纯文本邮件不应该有附件: %v", atts)
+	}
+
+	multipart := "From: a@example.com\r\n" +
+		"To: b@example.com\r\n" +
+		"Subject: report\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=XYZ\r\n" +
+		"\r\n" +
+		"--XYZ\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"see attached\r\n" +
+		"--XYZ\r\n" +
+		"Content-Type: application/pdf\r\n" +
+		"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"JVBERi0=\r\n" +
+		"--XYZ\r\n" +
+		"Content-Type: image/png; name=\"logo.png\"\r\n" +
+		"\r\n" +
+		"PNG\r\n" +
+		"--XYZ--\r\n"
+	atts := ExtractAttachments([]byte(multipart))
+	if len(atts) != 2 {
+		t.Fatalf("应该提取到 2 个附件, got %d", len(atts))
+	}
+	if atts[0].Filename != "report.pdf" || atts[0].ContentType != "application/pdf" || atts[0].Part != "2" || atts[0].Size != 5 {
+		t.Errorf("第一个附件不正确: %+v", atts[0])
+	}
+	if atts[1].Filename != "logo.png" || atts[1].ContentType != "image/png" || atts[1].Part != "3" {
+		t.Errorf("第二个附件不正确: %+v", atts[1])
+	}
+
+	if atts := ExtractAttachments([]byte("not a mail")); len(atts) != 0 {
+		t.Errorf("无法解析的邮件不应该有附件: %v", atts)
+	}
+}
@@ -0,0 +1,160 @@
+// Package search 解析邮件搜索查询语言，供 WebMail 等搜索入口共用
+//
+// 语法：空格分隔的条件之间为 AND 关系，值可以用双引号包含空格。
+//
+//	from:alice to:bob subject:"weekly report" has:attachment
+//	before:2024-01-31 after:2024-01-01 larger:1M smaller:10MB label:work category:social 其他文本
+//
+// 不认识的 key:value 按普通文本处理。
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// dateLayouts 支持的日期格式（按本地时区解析）
+var dateLayouts = []string{"2006-01-02", "2006/01/02"}
+
+// Parse 解析查询字符串
+func Parse(q string) (*storage.SearchQuery, error) {
+	query := &storage.SearchQuery{}
+	for _, tok := range tokenize(q) {
+		key, value, ok := splitOperator(tok)
+		if !ok {
+			query.Text = append(query.Text, tok)
+			continue
+		}
+		if value == "" {
+			return nil, fmt.Errorf("搜索条件 %s: 缺少值", key)
+		}
+
+		switch key {
+		case "from":
+			query.From = append(query.From, value)
+		case "to":
+			query.To = append(query.To, value)
+		case "subject":
+			query.Subject = append(query.Subject, value)
+		case "label":
+			query.Labels = append(query.Labels, value)
+		case "category":
+			value = strings.ToLower(value)
+			if !category.Valid(value) {
+				return nil, fmt.Errorf("不支持的搜索条件 category:%s", value)
+			}
+			query.Labels = append(query.Labels, category.Keyword(value))
+		case "has":
+			if strings.ToLower(value) != "attachment" {
+				return nil, fmt.Errorf("不支持的搜索条件 has:%s", value)
+			}
+			query.HasAttachment = true
+		case "before", "after":
+			t, err := ParseDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("搜索条件 %s: %w", key, err)
+			}
+			if key == "before" {
+				query.Before = &t
+			} else {
+				query.After = &t
+			}
+		case "larger", "smaller":
+			n, err := ParseSize(value)
+			if err != nil {
+				return nil, fmt.Errorf("搜索条件 %s: %w", key, err)
+			}
+			if key == "larger" {
+				query.Larger = n
+			} else {
+				query.Smaller = n
+			}
+		default:
+			query.Text = append(query.Text, tok)
+		}
+	}
+	return query, nil
+}
+
+// Empty 判断查询是否没有任何条件
+func Empty(q *storage.SearchQuery) bool {
+	return q == nil || (len(q.Text) == 0 && len(q.From) == 0 && len(q.To) == 0 && len(q.Subject) == 0 &&
+		!q.HasAttachment && q.Before == nil && q.After == nil && q.Larger == 0 && q.Smaller == 0 && len(q.Labels) == 0)
+}
+
+// tokenize 按空白分词，双引号内的空白保留（引号本身去掉）
+func tokenize(q string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote, quoted := false, false
+	flush := func() {
+		if cur.Len() > 0 || quoted {
+			tokens = append(tokens, cur.String())
+		}
+		cur.Reset()
+		quoted = false
+	}
+	for _, r := range q {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			quoted = true
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// splitOperator 拆分 key:value，key 统一转小写
+func splitOperator(tok string) (string, string, bool) {
+	i := strings.IndexByte(tok, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	key := strings.ToLower(tok[:i])
+	switch key {
+	case "from", "to", "subject", "label", "category", "has", "before", "after", "larger", "smaller":
+		return key, tok[i+1:], true
+	}
+	return "", "", false
+}
+
+// ParseDate 解析日期（YYYY-MM-DD 或 YYYY/MM/DD，本地时区）
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的日期 %q（格式 YYYY-MM-DD）", s)
+}
+
+// ParseSize 解析大小，支持 K/M/G 及 KB/MB/GB 后缀（1024 进制）
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(v, "B")
+	mult := int64(1)
+	switch {
+	case strings.HasSuffix(v, "K"):
+		mult, v = 1<<10, strings.TrimSuffix(v, "K")
+	case strings.HasSuffix(v, "M"):
+		mult, v = 1<<20, strings.TrimSuffix(v, "M")
+	case strings.HasSuffix(v, "G"):
+		mult, v = 1<<30, strings.TrimSuffix(v, "G")
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的大小 %q", s)
+	}
+	return n * mult, nil
+}
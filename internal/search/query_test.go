@@ -0,0 +1,79 @@
+package search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`From:alice to:bob subject:"weekly report" has:attachment label:work larger:1M smaller:2mb before:2024-01-31 after:2024/01/01 hello foo:bar`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(q.From) != 1 || q.From[0] != "alice" {
+		t.Errorf("From = %v", q.From)
+	}
+	if len(q.To) != 1 || q.To[0] != "bob" {
+		t.Errorf("To = %v", q.To)
+	}
+	if len(q.Subject) != 1 || q.Subject[0] != "weekly report" {
+		t.Errorf("Subject = %v", q.Subject)
+	}
+	if !q.HasAttachment {
+		t.Error("HasAttachment = false")
+	}
+	if len(q.Labels) != 1 || q.Labels[0] != "work" {
+		t.Errorf("Labels = %v", q.Labels)
+	}
+	if q.Larger != 1<<20 || q.Smaller != 2<<20 {
+		t.Errorf("Larger = %d, Smaller = %d", q.Larger, q.Smaller)
+	}
+	if q.Before == nil || !q.Before.Equal(time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("Before = %v", q.Before)
+	}
+	if q.After == nil || !q.After.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("After = %v", q.After)
+	}
+	// 不认识的 key:value 按普通文本处理
+	if len(q.Text) != 2 || q.Text[0] != "hello" || q.Text[1] != "foo:bar" {
+		t.Errorf("Text = %v", q.Text)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"from:",
+		"has:pdf",
+		"before:yesterday",
+		"larger:big",
+		"smaller:-1",
+		"category:spam",
+	}
+	for _, q := range tests {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q) 应该返回错误", q)
+		}
+	}
+}
+
+func TestParseCategory(t *testing.T) {
+	q, err := Parse("category:Social")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(q.Labels) != 1 || q.Labels[0] != "$Social" {
+		t.Errorf("Labels = %v", q.Labels)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	q, _ := Parse("   ")
+	if !Empty(q) {
+		t.Error("空白查询应该为空")
+	}
+	q, _ = Parse("has:attachment")
+	if Empty(q) {
+		t.Error("has:attachment 不应该为空")
+	}
+}
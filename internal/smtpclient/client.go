@@ -3,20 +3,78 @@ package smtpclient
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/smtp"
 	"os"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/netguard"
 )
 
 // Client SMTP 客户端
 type Client struct {
-	timeout  time.Duration
-	hostname string // EHLO 主机名
+	timeout   time.Duration
+	hostname  string      // EHLO 主机名
+	tlsConfig *tls.Config // TLS 配置模板（共享会话缓存），为空时使用默认配置
+	// control 连接前检查目标地址（连接用户填写的服务器时只允许公网地址）
+	control func(network, address string, c syscall.RawConn) error
+	// onReject 收件人被对方服务器拒收（RCPT TO 失败）时调用
+	onReject func(recipient string, err error)
+	// certificate 连接中继服务器时出示的客户端证书（双向 TLS 认证，可选）
+	certificate *tls.Certificate
+	// transcripts 外发会话记录（可选，只记录直接投递到 MX 的会话）
+	transcripts TranscriptRecorder
+}
+
+// ErrNoMX 收件人域名没有 MX 记录
+var ErrNoMX = errors.New("没有 MX 记录")
+
+// ErrRecipientRejected 收件人被拒收导致发送失败（每个收件人的错误已通过 OnReject 报告）
+var ErrRecipientRejected = errors.New("收件人被拒收")
+
+// DeliveryError 发送到某个域名失败（SendMail 按域名发送，返回的错误由各域名的 DeliveryError 组合而成）
+type DeliveryError struct {
+	Domain     string
+	Recipients []string
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("发送到 %s 失败: %v", e.Domain, e.Err)
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// lookupMX 查询 MX 记录（测试时替换）
+var lookupMX = net.LookupMX
+
+// ContextDialer 外发连接的拨号器
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// dialerWrapper 包装外发连接的拨号器（故障注入测试时设置，默认为空）
+var dialerWrapper func(ContextDialer) ContextDialer
+
+// SetDialerWrapper 设置外发连接拨号器的包装（用于故障注入测试，需要在发送邮件之前调用）
+func SetDialerWrapper(wrap func(ContextDialer) ContextDialer) {
+	dialerWrapper = wrap
+}
+
+// dial 建立外发 TCP 连接
+func dial(ctx context.Context, dialer *net.Dialer, addr string) (net.Conn, error) {
+	var d ContextDialer = dialer
+	if dialerWrapper != nil {
+		d = dialerWrapper(d)
+	}
+	return d.DialContext(ctx, "tcp", addr)
 }
 
 // NewClient 创建 SMTP 客户端
@@ -36,6 +94,61 @@ func NewClient(hostname string) *Client {
 	}
 }
 
+// NewClientWithTLS 创建使用指定 TLS 配置模板的 SMTP 客户端
+// 模板中的 ClientSessionCache 会在所有连接间共享，用于 TLS 会话恢复
+func NewClientWithTLS(hostname string, tlsConfig *tls.Config) *Client {
+	c := NewClient(hostname)
+	c.tlsConfig = tlsConfig
+	return c
+}
+
+// PublicOnly 只允许连接公网地址（中继服务器由用户填写时使用）
+func (c *Client) PublicOnly() *Client {
+	c.control = netguard.PublicOnly
+	return c
+}
+
+// OnReject 设置收件人被拒收时的回调（用于记录退信）
+func (c *Client) OnReject(fn func(recipient string, err error)) *Client {
+	c.onReject = fn
+	return c
+}
+
+// WithCertificate 设置连接中继服务器时出示的客户端证书（中继要求双向 TLS 认证时使用，不向 MX 出示）
+func (c *Client) WithCertificate(cert *tls.Certificate) *Client {
+	c.certificate = cert
+	return c
+}
+
+// reject 报告被拒收的收件人
+func (c *Client) reject(recipient string, err error) {
+	if c.onReject != nil {
+		c.onReject(recipient, err)
+	}
+}
+
+// newTLSConfig 为指定服务器生成 TLS 配置
+func (c *Client) newTLSConfig(serverName string) *tls.Config {
+	if c.tlsConfig == nil {
+		return &tls.Config{
+			ServerName: serverName,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	config := c.tlsConfig.Clone()
+	config.ServerName = serverName
+	return config
+}
+
+// relayTLSConfig 为中继服务器生成 TLS 配置（配置了客户端证书时在握手中出示）
+func (c *Client) relayTLSConfig(relayHost string) *tls.Config {
+	config := c.newTLSConfig(relayHost)
+	if c.certificate != nil {
+		config.Certificates = []tls.Certificate{*c.certificate}
+	}
+	return config
+}
+
 // getEHLOHostname 获取 EHLO 主机名
 // 如果配置了 hostname 就使用，否则从邮箱地址提取域名
 func (c *Client) getEHLOHostname(fromEmail string) string {
@@ -70,7 +183,7 @@ func (c *Client) SendMail(ctx context.Context, from string, to []string, data []
 	}
 
 	// 为每个域名发送邮件
-	var lastErr error
+	var errs []error
 	for domain, recipients := range domainRecipients {
 		if err := c.sendToDomain(ctx, from, domain, recipients, data); err != nil {
 			logger.ErrorCtx(ctx).
@@ -78,7 +191,7 @@ func (c *Client) SendMail(ctx context.Context, from string, to []string, data []
 				Str("domain", domain).
 				Strs("recipients", recipients).
 				Msg("发送邮件到域名失败")
-			lastErr = err
+			errs = append(errs, &DeliveryError{Domain: domain, Recipients: recipients, Err: err})
 			// 继续尝试其他域名
 		} else {
 			logger.InfoCtx(ctx).
@@ -88,23 +201,36 @@ func (c *Client) SendMail(ctx context.Context, from string, to []string, data []
 		}
 	}
 
-	return lastErr
+	return errors.Join(errs...)
 }
 
 // sendToDomain 发送邮件到指定域名的 MX 服务器
-func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipients []string, data []byte) error {
+func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipients []string, data []byte) (err error) {
+	// 要求记录会话时，无论成功与否都保存记录（包括找不到 MX 和连接失败）
+	var transcript *transcriptLog
+	if c.transcripts != nil && c.transcripts.Capture(ctx, domain, data) {
+		transcript = newTranscriptLog(domain, recipients)
+		defer func() {
+			c.transcripts.SaveTranscript(ctx, from, data, transcript.finish(err))
+		}()
+	}
+
 	// 查找 MX 记录
-	mxRecords, err := net.LookupMX(domain)
+	mxRecords, err := lookupMX(domain)
 	if err != nil {
 		return fmt.Errorf("查找 MX 记录失败: %w", err)
 	}
 
 	if len(mxRecords) == 0 {
-		return fmt.Errorf("域名 %s 没有 MX 记录", domain)
+		return fmt.Errorf("域名 %s %w", domain, ErrNoMX)
 	}
 
 	// 使用优先级最高的 MX 记录
 	mxHost := strings.TrimSuffix(mxRecords[0].Host, ".")
+	if transcript != nil {
+		transcript.t.MXHost = mxHost
+		transcript.note("MX 记录: %s", formatMX(mxRecords))
+	}
 
 	// 尝试连接到 MX 服务器（端口 25）
 	addr := net.JoinHostPort(mxHost, "25")
@@ -120,14 +246,19 @@ func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipien
 		Timeout: c.timeout,
 	}
 
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	conn, err := dial(ctx, dialer, addr)
 	if err != nil {
+		transcript.note("连接 %s 失败: %v", addr, err)
 		return fmt.Errorf("连接 MX 服务器失败: %w", err)
 	}
 	defer conn.Close()
+	if transcript != nil {
+		transcript.t.RemoteAddr = conn.RemoteAddr().String()
+		transcript.note("已连接 %s (%s)", addr, transcript.t.RemoteAddr)
+	}
 
 	// 创建 SMTP 客户端
-	client, err := smtp.NewClient(conn, mxHost)
+	client, err := smtp.NewClient(transcript.wrap(conn), mxHost)
 	if err != nil {
 		return fmt.Errorf("创建 SMTP 客户端失败: %w", err)
 	}
@@ -141,13 +272,14 @@ func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipien
 
 	// 检查是否支持 STARTTLS
 	if ok, _ := client.Extension("STARTTLS"); ok {
-		config := &tls.Config{
-			ServerName:         mxHost,
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: false,
+		var tlsErr error
+		if transcript != nil {
+			tlsErr = transcript.startTLS(ctx, client, conn, c.newTLSConfig(mxHost), ehloHostname)
+		} else {
+			tlsErr = client.StartTLS(c.newTLSConfig(mxHost))
 		}
-		if err := client.StartTLS(config); err != nil {
-			logger.WarnCtx(ctx).Err(err).Str("mx_host", mxHost).Msg("STARTTLS 失败，继续发送")
+		if tlsErr != nil {
+			logger.WarnCtx(ctx).Err(tlsErr).Str("mx_host", mxHost).Msg("STARTTLS 失败，继续发送")
 			// STARTTLS 失败不影响发送，继续
 		}
 	}
@@ -158,12 +290,18 @@ func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipien
 	}
 
 	// RCPT TO
+	accepted := 0
 	for _, recipient := range recipients {
 		if err := client.Rcpt(recipient); err != nil {
 			logger.WarnCtx(ctx).Err(err).Str("recipient", recipient).Msg("RCPT TO 失败")
+			c.reject(recipient, err)
 			// 继续尝试其他收件人
 			continue
 		}
+		accepted++
+	}
+	if accepted == 0 {
+		return fmt.Errorf("全部 %d 个%w", len(recipients), ErrRecipientRejected)
 	}
 
 	// DATA
@@ -171,6 +309,7 @@ func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipien
 	if err != nil {
 		return fmt.Errorf("DATA 失败: %w", err)
 	}
+	transcript.omitBody()
 
 	// 写入邮件数据
 	if _, err := writer.Write(data); err != nil {
@@ -192,6 +331,15 @@ func (c *Client) sendToDomain(ctx context.Context, from, domain string, recipien
 	return nil
 }
 
+// formatMX 格式化 MX 记录（优先级和主机名）
+func formatMX(records []*net.MX) string {
+	parts := make([]string, len(records))
+	for i, mx := range records {
+		parts[i] = fmt.Sprintf("%d %s", mx.Pref, strings.TrimSuffix(mx.Host, "."))
+	}
+	return strings.Join(parts, ", ")
+}
+
 // SendMailToRelay 通过中继服务器发送邮件（如果配置了中继服务器）
 func (c *Client) SendMailToRelay(ctx context.Context, relayHost string, relayPort int, username, password string, useTLS bool, from string, to []string, data []byte) error {
 	addr := fmt.Sprintf("%s:%d", relayHost, relayPort)
@@ -205,27 +353,30 @@ func (c *Client) SendMailToRelay(ctx context.Context, relayHost string, relayPor
 	// 创建带超时的连接
 	dialer := &net.Dialer{
 		Timeout: c.timeout,
+		Control: c.control,
 	}
 
 	var conn net.Conn
 	var err error
 
-	// 如果使用 TLS，直接建立 TLS 连接
-	if useTLS && relayPort == 465 {
-		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{
-			ServerName:         relayHost,
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: false,
-		})
-	} else {
-		conn, err = dialer.DialContext(ctx, "tcp", addr)
-	}
-
+	conn, err = dial(ctx, dialer, addr)
 	if err != nil {
 		return fmt.Errorf("连接中继服务器失败: %w", err)
 	}
 	defer conn.Close()
 
+	// 如果使用 TLS，直接建立 TLS 连接
+	if useTLS && relayPort == 465 {
+		tlsConn := tls.Client(conn, c.relayTLSConfig(relayHost))
+		handshakeCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := tlsConn.HandshakeContext(handshakeCtx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("连接中继服务器失败: %w", err)
+		}
+		conn = tlsConn
+	}
+
 	// 创建 SMTP 客户端
 	client, err := smtp.NewClient(conn, relayHost)
 	if err != nil {
@@ -242,18 +393,13 @@ func (c *Client) SendMailToRelay(ctx context.Context, relayHost string, relayPor
 	// 如果使用 TLS（端口 587），启动 STARTTLS
 	if useTLS && relayPort != 465 {
 		if ok, _ := client.Extension("STARTTLS"); ok {
-			config := &tls.Config{
-				ServerName:         relayHost,
-				MinVersion:         tls.VersionTLS12,
-				InsecureSkipVerify: false,
-			}
-			if err := client.StartTLS(config); err != nil {
+			// StartTLS 成功后会重新发送 EHLO 获取新的扩展列表（不能再调用 Hello）
+			if err := client.StartTLS(c.relayTLSConfig(relayHost)); err != nil {
 				return fmt.Errorf("STARTTLS 失败: %w", err)
 			}
-			// STARTTLS 后需要重新发送 EHLO 以获取新的扩展列表
-			if err := client.Hello(ehloHostname); err != nil {
-				return fmt.Errorf("STARTTLS 后 EHLO 失败: %w", err)
-			}
+		} else if c.certificate != nil {
+			// 没有 TLS 就无法出示客户端证书，继续发送只会被中继拒绝
+			return fmt.Errorf("中继服务器不支持 STARTTLS，无法使用客户端证书认证")
 		}
 	}
 
@@ -296,7 +442,8 @@ func (c *Client) SendMailToRelay(ctx context.Context, relayHost string, relayPor
 	// RCPT TO
 	for _, recipient := range to {
 		if err := client.Rcpt(recipient); err != nil {
-			return fmt.Errorf("RCPT TO 失败 (%s): %w", recipient, err)
+			c.reject(recipient, err)
+			return fmt.Errorf("RCPT TO 失败 (%s): %w: %w", recipient, ErrRecipientRejected, err)
 		}
 	}
 
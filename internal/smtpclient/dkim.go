@@ -1,6 +1,9 @@
 package smtpclient
 
 import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
@@ -12,8 +15,37 @@ import (
 	"github.com/gomailzero/gmz/internal/antispam"
 	"github.com/gomailzero/gmz/internal/config"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
 )
 
+// LoadOutboundDKIM 加载外发邮件使用的 DKIM 签名：启用中继时按中继的设置不签名（skip_dkim）
+// 或使用中继专用的签名（smtp.relay.dkim），否则使用 smtp.dkim；未启用签名时返回 nil
+func LoadOutboundDKIM(cfg *config.SMTPConfig, domain, workDir string) (*antispam.DKIM, error) {
+	if cfg.Relay.Enabled {
+		if cfg.Relay.SkipDKIM {
+			logger.Info().Msg("通过中继外发，由中继服务器签名 DKIM")
+			return nil, nil
+		}
+		if cfg.Relay.DKIM.Enabled {
+			return LoadDKIM(&cfg.Relay.DKIM, domain, workDir)
+		}
+	}
+	return LoadDKIM(&cfg.DKIM, domain, workDir)
+}
+
+// LoadOutboundSigners 创建外发邮件的签名器集合：全局签名器 global（见 LoadOutboundDKIM，可以为 nil）
+// 加上通过管理 API 为各域名生成的密钥；通过中继外发且由中继签名（skip_dkim）时返回 nil
+func LoadOutboundSigners(ctx context.Context, cfg *config.SMTPConfig, global *antispam.DKIM, workDir string, driver storage.Driver) (*antispam.DKIMSigners, error) {
+	if cfg.Relay.Enabled && cfg.Relay.SkipDKIM {
+		return nil, nil
+	}
+	signers := antispam.NewDKIMSigners(global)
+	if err := loadDomainDKIM(ctx, driver, workDir, signers); err != nil {
+		return nil, err
+	}
+	return signers, nil
+}
+
 // LoadDKIM 加载 DKIM 配置
 func LoadDKIM(cfg *config.DKIMConfig, domain, workDir string) (*antispam.DKIM, error) {
 	if !cfg.Enabled {
@@ -24,8 +56,51 @@ func LoadDKIM(cfg *config.DKIMConfig, domain, workDir string) (*antispam.DKIM, e
 		return nil, fmt.Errorf("DKIM 已启用但未配置私钥文件")
 	}
 
-	// 读取私钥文件
-	keyPath := cfg.PrivateKey
+	privateKey, err := LoadPrivateKey(cfg.PrivateKey, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// 确定域名
+	dkimDomain := cfg.Domain
+	if dkimDomain == "" {
+		dkimDomain = domain
+	}
+	if dkimDomain == "" {
+		return nil, fmt.Errorf("DKIM 域名未配置")
+	}
+
+	// 确定选择器
+	selector := cfg.Selector
+	if selector == "" {
+		selector = "default"
+	}
+
+	// 创建 DKIM 实例
+	dkim, err := antispam.NewDKIM(dkimDomain, selector, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("创建 DKIM 实例失败: %w", err)
+	}
+	if cfg.Canonicalization != "" {
+		if err := dkim.SetCanonicalization(cfg.Canonicalization); err != nil {
+			return nil, err
+		}
+	}
+
+	// 注意：这里没有 context，使用普通 logger（初始化时）
+	logger.Info().
+		Str("domain", dkimDomain).
+		Str("selector", selector).
+		Str("algorithm", dkim.Algorithm()).
+		Msg("DKIM 签名已启用")
+
+	return dkim, nil
+}
+
+// LoadPrivateKey 读取 PEM 格式的 DKIM 私钥文件（PKCS#1 RSA 或 PKCS#8 RSA/Ed25519），
+// 相对路径基于工作目录解析
+func LoadPrivateKey(path, workDir string) (crypto.PrivateKey, error) {
+	keyPath := path
 	if !filepath.IsAbs(keyPath) {
 		// 相对路径，基于工作目录解析
 		keyPath = filepath.Join(workDir, keyPath)
@@ -62,38 +137,38 @@ func LoadDKIM(cfg *config.DKIMConfig, domain, workDir string) (*antispam.DKIM, e
 		return nil, fmt.Errorf("解析私钥失败: %w", err)
 	}
 
-	// 转换为 RSA 私钥
-	rsaKey, ok := privateKey.(*rsa.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("私钥不是 RSA 格式")
-	}
-
-	// 确定域名
-	dkimDomain := cfg.Domain
-	if dkimDomain == "" {
-		dkimDomain = domain
-	}
-	if dkimDomain == "" {
-		return nil, fmt.Errorf("DKIM 域名未配置")
-	}
-
-	// 确定选择器
-	selector := cfg.Selector
-	if selector == "" {
-		selector = "default"
+	// 支持 RSA（rsa-sha256）和 Ed25519（ed25519-sha256，PKCS#8）私钥
+	switch privateKey.(type) {
+	case *rsa.PrivateKey, ed25519.PrivateKey:
+	default:
+		return nil, fmt.Errorf("私钥不是 RSA 或 Ed25519 格式")
 	}
+	return privateKey, nil
+}
 
-	// 创建 DKIM 实例
-	dkim, err := antispam.NewDKIM(dkimDomain, selector, rsaKey)
+// loadDomainDKIM 加载各域名的 DKIM 密钥并注册到签名器集合（单个域名的密钥加载失败时记录日志并跳过）
+func loadDomainDKIM(ctx context.Context, driver storage.Driver, workDir string, signers *antispam.DKIMSigners) error {
+	keys, err := driver.ListDKIMKeys(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("创建 DKIM 实例失败: %w", err)
+		return err
 	}
-
-	// 注意：这里没有 context，使用普通 logger（初始化时）
-	logger.Info().
-		Str("domain", dkimDomain).
-		Str("selector", selector).
-		Msg("DKIM 签名已启用")
-
-	return dkim, nil
+	for _, key := range keys {
+		privateKey, err := LoadPrivateKey(key.KeyFile, workDir)
+		if err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("domain", key.Domain).Msg("加载域名 DKIM 密钥失败")
+			continue
+		}
+		dkim, err := antispam.NewDKIM(key.Domain, key.Selector, privateKey)
+		if err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("domain", key.Domain).Msg("加载域名 DKIM 密钥失败")
+			continue
+		}
+		signers.Set(key.Domain, dkim)
+		logger.InfoCtx(ctx).
+			Str("domain", key.Domain).
+			Str("selector", key.Selector).
+			Str("algorithm", dkim.Algorithm()).
+			Msg("域名 DKIM 签名已启用")
+	}
+	return nil
 }
@@ -0,0 +1,184 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// BounceRecorder 记录外发失败（被拒收的收件人），用于退信统计
+type BounceRecorder interface {
+	RecordError(ctx context.Context, from string, to []string, err error)
+}
+
+// DeliveryRecorder 记录外发结果（投递审计日志）：err 为空时收件人已发出，否则按错误记录退信或推迟
+type DeliveryRecorder interface {
+	RecordDelivery(ctx context.Context, from string, to []string, data []byte, err error)
+}
+
+// ErrWarmupDeferred 超过外发预热的当天上限，收件人推迟到之后发送
+var ErrWarmupDeferred = errors.New("超过外发预热的当天上限，推迟发送")
+
+// Throttle 外发限流：返回可以立即发送的收件人，其余收件人的邮件由限流方推迟发送
+type Throttle interface {
+	Admit(ctx context.Context, from string, to []string, data []byte) ([]string, error)
+}
+
+// SenderSigner 签名外发邮件的信封发件人（BATV），不需要签名的地址原样返回
+type SenderSigner interface {
+	Sign(sender string) string
+}
+
+// Outbound 外发邮件：配置了中继时通过中继发送，否则直接投递到收件人域名的 MX
+type Outbound struct {
+	SMTP      *config.SMTPConfig // 外发配置（EHLO 主机名和中继），为空时直接投递
+	ClientTLS *tls.Config        // 外发 TLS 配置模板（可选）
+	Bounces   BounceRecorder     // 退信记录（可选）
+	Warmup    Throttle           // 外发预热限流（可选，超过当天上限的收件人推迟发送）
+	// Deliveries 投递审计日志（可选）
+	Deliveries DeliveryRecorder
+	// Signer 信封发件人签名（可选，退信地址验证）
+	Signer SenderSigner
+	// DKIM 按发件域名（From 头）选择的 DKIM 签名器（可选）
+	DKIM *antispam.DKIMSigners
+	// Transcripts 外发会话记录（可选，只记录直接投递到 MX 的会话）
+	Transcripts TranscriptRecorder
+}
+
+// Send 发送邮件
+func (o *Outbound) Send(ctx context.Context, from string, to []string, data []byte) error {
+	if o.Warmup != nil {
+		admitted, err := o.Warmup.Admit(ctx, from, to, data)
+		if err != nil {
+			return err
+		}
+		if o.Deliveries != nil && len(admitted) < len(to) {
+			o.Deliveries.RecordDelivery(ctx, from, without(to, admitted), data, ErrWarmupDeferred)
+		}
+		if len(admitted) == 0 {
+			return nil
+		}
+		to = admitted
+	}
+	data = o.sign(ctx, data)
+
+	hostname := ""
+	if o.SMTP != nil {
+		hostname = o.SMTP.Hostname
+	}
+	client := NewClientWithTLS(hostname, o.ClientTLS)
+	if o.Transcripts != nil {
+		client.WithTranscripts(o.Transcripts)
+	}
+	var rejected []string
+	if o.Bounces != nil || o.Deliveries != nil {
+		client.OnReject(func(recipient string, err error) {
+			rejected = append(rejected, recipient)
+			if o.Bounces != nil {
+				o.Bounces.RecordError(ctx, from, []string{recipient}, err)
+			}
+			if o.Deliveries != nil {
+				o.Deliveries.RecordDelivery(ctx, from, []string{recipient}, data, err)
+			}
+		})
+	}
+
+	// 只签名信封发件人，退信统计和投递日志仍记录原地址
+	envelopeFrom := from
+	if o.Signer != nil {
+		envelopeFrom = o.Signer.Sign(from)
+	}
+	var err error
+	if o.SMTP != nil && o.SMTP.Relay.Enabled {
+		err = client.SendMailViaRelay(ctx, &o.SMTP.Relay, envelopeFrom, to, data)
+	} else {
+		err = client.SendMail(ctx, envelopeFrom, to, data)
+	}
+	if o.Deliveries != nil {
+		// 被拒收的收件人已逐个记录
+		o.Deliveries.RecordDelivery(ctx, from, without(to, rejected), data, err)
+	}
+	if err != nil {
+		if o.Bounces != nil {
+			o.Bounces.RecordError(ctx, from, to, err)
+		}
+		return err
+	}
+	logger.InfoCtx(ctx).Str("from", from).Strs("to", to).Msg("邮件已发送到外部地址")
+	return nil
+}
+
+// sign 按 From 头的域名对邮件做 DKIM 签名：通过中继外发且由中继签名（skip_dkim）或邮件已经带有
+// 该域名的签名时不签名，签名失败时发送未签名的邮件
+func (o *Outbound) sign(ctx context.Context, data []byte) []byte {
+	if o.SMTP != nil && o.SMTP.Relay.Enabled && o.SMTP.Relay.SkipDKIM {
+		return data
+	}
+	from := headerFrom(data)
+	if from == "" {
+		return data
+	}
+	// WebMail 提交时已签名、预热推迟后再次发送的邮件不重复签名
+	signer := o.DKIM.For(from)
+	if signer == nil || signer.HasSignature(data) {
+		return data
+	}
+	signed, err := signer.SignMessage(data)
+	if err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("from", from).Msg("DKIM 签名失败，发送未签名的邮件")
+		return data
+	}
+	return signed
+}
+
+// headerFrom 返回邮件 From 头中的地址，无法解析时返回空
+func headerFrom(data []byte) string {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	addr, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return ""
+	}
+	return addr.Address
+}
+
+// SendMailViaRelay 按中继配置发送邮件：使用用户名和密码认证，配置了客户端证书时在 TLS 握手中出示
+// （每次发送时读取证书文件，证书续期后无需重启）
+func (c *Client) SendMailViaRelay(ctx context.Context, relay *config.RelayConfig, from string, to []string, data []byte) error {
+	if relay.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(relay.ClientCert, relay.ClientKey)
+		if err != nil {
+			return fmt.Errorf("加载中继客户端证书失败: %w", err)
+		}
+		c.WithCertificate(&cert)
+	}
+	return c.SendMailToRelay(ctx, relay.Host, relay.Port, relay.Username, relay.Password, relay.UseTLS, from, to, data)
+}
+
+// without 返回 to 中不在 exclude 中的收件人
+func without(to, exclude []string) []string {
+	if len(exclude) == 0 {
+		return to
+	}
+	skip := make(map[string]bool, len(exclude))
+	for _, r := range exclude {
+		skip[strings.ToLower(r)] = true
+	}
+	var rest []string
+	for _, r := range to {
+		if !skip[strings.ToLower(r)] {
+			rest = append(rest, r)
+		}
+	}
+	return rest
+}
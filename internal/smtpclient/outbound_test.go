@@ -0,0 +1,111 @@
+package smtpclient
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/config"
+)
+
+// startRelay 启动测试用的中继服务器，返回收到的邮件和中继配置
+func startRelay(t *testing.T) (*mxBackend, config.RelayConfig) {
+	t.Helper()
+	backend := &mxBackend{}
+	server := smtp.NewServer(backend)
+	server.Domain = "relay.test"
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return backend, config.RelayConfig{Enabled: true, Host: "127.0.0.1", Port: addr.Port}
+}
+
+func TestOutboundDKIM(t *testing.T) {
+	privateKey, publicKey, err := antispam.GenerateKeyPair("ed25519")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dkim, err := antispam.NewDKIM("example.com", "s1", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := antispam.NewDKIMSigners(nil)
+	signers.Set("example.com", dkim)
+
+	backend, relay := startRelay(t)
+	smtpConfig := &config.SMTPConfig{Hostname: "mail.example.com", Relay: relay}
+	outbound := &Outbound{SMTP: smtpConfig, DKIM: signers}
+	data := []byte("From: Alice <alice@example.com>\r\nTo: bob@remote.test\r\nSubject: hi\r\n\r\nhello\r\n")
+	ctx := context.Background()
+
+	// 经中继外发的邮件按 From 头的域名签名
+	if err := outbound.Send(ctx, "alice@example.com", []string{"bob@remote.test"}, data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.HasPrefix(string(backend.data), "DKIM-Signature: ") || !strings.Contains(string(backend.data), "d=example.com") {
+		t.Fatalf("中继收到的邮件没有 example.com 的 DKIM 签名:\n%s", backend.data)
+	}
+	if ok, err := antispam.VerifyMessage(backend.data, publicKey); !ok || err != nil {
+		t.Errorf("DKIM 签名验证失败: %v", err)
+	}
+
+	// 没有签名器的域名不签名
+	other := []byte("From: carol@other.test\r\nSubject: hi\r\n\r\nhello\r\n")
+	if err := outbound.Send(ctx, "carol@other.test", []string{"bob@remote.test"}, other); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if strings.Contains(string(backend.data), "DKIM-Signature") {
+		t.Errorf("没有签名器的域名不应该签名:\n%s", backend.data)
+	}
+
+	// 由中继签名（skip_dkim）时不签名
+	smtpConfig.Relay.SkipDKIM = true
+	if err := outbound.Send(ctx, "alice@example.com", []string{"bob@remote.test"}, data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if strings.Contains(string(backend.data), "DKIM-Signature") {
+		t.Errorf("skip_dkim 时不应该签名:\n%s", backend.data)
+	}
+}
+
+func TestOutboundDomainDKIM(t *testing.T) {
+	backend, relay := startRelay(t)
+	signers := antispam.NewDKIMSigners(nil)
+	outbound := &Outbound{SMTP: &config.SMTPConfig{Relay: relay}, DKIM: signers}
+	data := []byte("From: alice@example.com\r\nSubject: hi\r\n\r\nhello\r\n")
+	ctx := context.Background()
+
+	// 通过管理 API 生成域名密钥后，之后外发的邮件立即按该域名签名
+	privateKey, _, err := antispam.GenerateKeyPair("ed25519")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dkim, err := antispam.NewDKIM("example.com", "s2", privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers.Set("example.com", dkim)
+	if err := outbound.Send(ctx, "alice@example.com", []string{"bob@remote.test"}, data); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if strings.Count(string(backend.data), "DKIM-Signature") != 1 || !strings.Contains(string(backend.data), "s=s2") {
+		t.Fatalf("应该有一个 example.com 的 DKIM 签名:\n%s", backend.data)
+	}
+
+	// 已经签名的邮件（如预热推迟后再次发送）不重复签名
+	signed := backend.data
+	if err := outbound.Send(ctx, "alice@example.com", []string{"bob@remote.test"}, signed); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if n := strings.Count(string(backend.data), "DKIM-Signature"); n != 1 {
+		t.Errorf("已签名的邮件不应该重复签名, got %d 个签名", n)
+	}
+}
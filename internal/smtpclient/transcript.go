@@ -0,0 +1,200 @@
+package smtpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// maxTranscriptSize 一次会话记录的最大字节数（邮件内容不记录，正常会话远小于该值）
+const maxTranscriptSize = 256 << 10
+
+// Transcript 一次外发 SMTP 会话的记录（发往一个域名的 MX）
+type Transcript struct {
+	Domain     string
+	Recipients []string
+	MXHost     string
+	RemoteAddr string
+	TLSVersion string // 没有执行 STARTTLS 时为空
+	TLSCipher  string
+	StartedAt  time.Time
+	Duration   time.Duration
+	// Log 命令和回复，每行带相对开始时间和方向（C: 本机发出，S: 对方回复，*: 连接和 TLS 信息），
+	// 邮件内容只记录字节数
+	Log string
+	Err error
+}
+
+// TranscriptRecorder 保存外发会话记录（收件方否认收到邮件时举证）：Capture 返回 true 时记录发往 domain 的会话，
+// 会话结束后（无论成功与否）调用 SaveTranscript。只记录直接投递到 MX 的会话，不记录经中继发送的会话
+type TranscriptRecorder interface {
+	Capture(ctx context.Context, domain string, data []byte) bool
+	SaveTranscript(ctx context.Context, from string, data []byte, t *Transcript)
+}
+
+// WithTranscripts 设置外发会话记录（可选）
+func (c *Client) WithTranscripts(r TranscriptRecorder) *Client {
+	c.transcripts = r
+	return c
+}
+
+// transcriptLog 记录一次会话，方法在接收者为空时不做任何事（没有要求记录的域名）
+type transcriptLog struct {
+	t         *Transcript
+	buf       strings.Builder
+	pending   [2][]byte // 尚未换行的客户端和服务器数据
+	omitting  bool      // 正在发送邮件内容
+	omitted   int
+	truncated bool
+}
+
+// 数据方向（pending 的下标）
+const (
+	fromClient = iota
+	fromServer
+)
+
+// newTranscriptLog 开始记录发往 domain 的会话
+func newTranscriptLog(domain string, recipients []string) *transcriptLog {
+	return &transcriptLog{t: &Transcript{Domain: domain, Recipients: recipients, StartedAt: time.Now()}}
+}
+
+// line 追加一行记录
+func (l *transcriptLog) line(prefix, text string) {
+	if l.truncated {
+		return
+	}
+	if l.buf.Len()+len(text) > maxTranscriptSize {
+		l.truncated = true
+		text, prefix = fmt.Sprintf("[会话记录超过 %d 字节，之后的内容没有记录]", maxTranscriptSize), "*"
+	}
+	fmt.Fprintf(&l.buf, "%8.3fs %s %s\n", time.Since(l.t.StartedAt).Seconds(), prefix, text)
+}
+
+// note 记录连接和 TLS 信息
+func (l *transcriptLog) note(format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.line("*", fmt.Sprintf(format, args...))
+}
+
+// record 记录连接上收发的数据，按行写入
+func (l *transcriptLog) record(dir int, b []byte) {
+	if dir == fromClient && l.omitting {
+		l.omitted += len(b)
+		return
+	}
+	if dir == fromServer && l.omitting {
+		// 对方开始回复说明邮件内容已发送完
+		l.omitting = false
+		l.line("C:", fmt.Sprintf("[邮件内容 %d 字节，没有记录]", l.omitted))
+	}
+	prefix := "C:"
+	if dir == fromServer {
+		prefix = "S:"
+	}
+	l.pending[dir] = append(l.pending[dir], b...)
+	for {
+		i := bytes.IndexByte(l.pending[dir], '\n')
+		if i < 0 {
+			break
+		}
+		l.line(prefix, strings.TrimRight(string(l.pending[dir][:i]), "\r"))
+		l.pending[dir] = l.pending[dir][i+1:]
+	}
+}
+
+// omitBody 开始发送邮件内容（DATA 命令得到 354 之后调用），内容不写入记录
+func (l *transcriptLog) omitBody() {
+	if l == nil {
+		return
+	}
+	l.omitting = true
+	l.omitted = 0
+}
+
+// wrap 包装连接，记录经过连接的明文数据
+func (l *transcriptLog) wrap(conn net.Conn) net.Conn {
+	if l == nil {
+		return conn
+	}
+	return &transcriptConn{Conn: conn, log: l}
+}
+
+// startTLS 执行 STARTTLS 并从 TLS 连接上继续记录：net/smtp 的 StartTLS 在内部替换连接，
+// 之后的明文无法记录，因此手动发送命令并替换 client.Text
+func (l *transcriptLog) startTLS(ctx context.Context, client *smtp.Client, conn net.Conn, config *tls.Config, hostname string) error {
+	if err := command(client.Text, 220, "STARTTLS"); err != nil {
+		return err
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		l.note("TLS 握手失败: %v", err)
+		return err
+	}
+	state := tlsConn.ConnectionState()
+	l.t.TLSVersion = tls.VersionName(state.Version)
+	l.t.TLSCipher = tls.CipherSuiteName(state.CipherSuite)
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		l.note("TLS 握手完成: %s %s，证书 CN=%s（签发者 %s，有效期至 %s）", l.t.TLSVersion, l.t.TLSCipher,
+			cert.Subject.CommonName, cert.Issuer.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+	} else {
+		l.note("TLS 握手完成: %s %s", l.t.TLSVersion, l.t.TLSCipher)
+	}
+
+	// net/smtp 不知道连接已切换到 TLS，需要手动重新发送 EHLO（之后的命令沿用 STARTTLS 之前的扩展列表）
+	client.Text = textproto.NewConn(l.wrap(tlsConn))
+	return command(client.Text, 250, "EHLO %s", hostname)
+}
+
+// finish 结束记录
+func (l *transcriptLog) finish(err error) *Transcript {
+	for dir, prefix := range []string{"C:", "S:"} {
+		if len(l.pending[dir]) > 0 {
+			l.line(prefix, strings.TrimRight(string(l.pending[dir]), "\r\n"))
+		}
+	}
+	l.t.Duration = time.Since(l.t.StartedAt)
+	l.t.Log = l.buf.String()
+	l.t.Err = err
+	return l.t
+}
+
+// transcriptConn 记录收发数据的连接
+type transcriptConn struct {
+	net.Conn
+	log *transcriptLog
+}
+
+func (c *transcriptConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.log.record(fromServer, b[:n])
+	}
+	return n, err
+}
+
+func (c *transcriptConn) Write(b []byte) (int, error) {
+	c.log.record(fromClient, b)
+	return c.Conn.Write(b)
+}
+
+// command 发送命令并检查回复码
+func command(text *textproto.Conn, expectCode int, format string, args ...interface{}) error {
+	id, err := text.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	_, _, err = text.ReadResponse(expectCode)
+	return err
+}
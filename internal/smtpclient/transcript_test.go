@@ -0,0 +1,164 @@
+package smtpclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-smtp"
+)
+
+// mxBackend 测试用的 MX 服务器，拒收 nobody@ 开头的收件人
+type mxBackend struct {
+	data []byte
+}
+
+func (b *mxBackend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	return &mxSession{backend: b}, nil
+}
+
+type mxSession struct {
+	backend *mxBackend
+}
+
+func (s *mxSession) Mail(from string, opts *smtp.MailOptions) error { return nil }
+
+func (s *mxSession) Rcpt(to string, opts *smtp.RcptOptions) error {
+	if strings.HasPrefix(to, "nobody@") {
+		return &smtp.SMTPError{Code: 550, EnhancedCode: smtp.EnhancedCode{5, 1, 1}, Message: "No such user"}
+	}
+	return nil
+}
+
+func (s *mxSession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	s.backend.data = data
+	return err
+}
+
+func (s *mxSession) Reset()        {}
+func (s *mxSession) Logout() error { return nil }
+
+// transcriptSink 保存会话记录的测试实现
+type transcriptSink struct {
+	domain      string
+	transcripts []*Transcript
+}
+
+func (r *transcriptSink) Capture(ctx context.Context, domain string, data []byte) bool {
+	return domain == r.domain
+}
+
+func (r *transcriptSink) SaveTranscript(ctx context.Context, from string, data []byte, t *Transcript) {
+	r.transcripts = append(r.transcripts, t)
+}
+
+// redirectDialer 把所有外发连接转到测试服务器
+type redirectDialer struct {
+	addr string
+}
+
+func (d redirectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, d.addr)
+}
+
+// selfSignedConfig 生成测试服务器的自签名证书
+func selfSignedConfig(t *testing.T, hostname string) *tls.Config {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{hostname},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+}
+
+func TestSendMailTranscript(t *testing.T) {
+	backend := &mxBackend{}
+	server := smtp.NewServer(backend)
+	server.Domain = "mx.remote.test"
+	server.TLSConfig = selfSignedConfig(t, "mx.remote.test")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	lookupMX = func(domain string) ([]*net.MX, error) {
+		if domain == "missing.test" {
+			return nil, errors.New("no such host")
+		}
+		return []*net.MX{{Host: "mx." + domain + ".", Pref: 10}}, nil
+	}
+	dialerWrapper = func(ContextDialer) ContextDialer { return redirectDialer{addr: ln.Addr().String()} }
+	t.Cleanup(func() {
+		lookupMX = net.LookupMX
+		dialerWrapper = nil
+	})
+
+	sink := &transcriptSink{domain: "remote.test"}
+	client := NewClientWithTLS("mail.example.com", &tls.Config{InsecureSkipVerify: true}).WithTranscripts(sink)
+	data := []byte("Message-ID: <m1@example.com>\r\nSubject: secret\r\n\r\nconfidential body\r\n")
+	ctx := context.Background()
+	if err := client.SendMail(ctx, "alice@example.com", []string{"bob@remote.test", "nobody@remote.test"}, data); err != nil {
+		t.Fatalf("SendMail() error = %v", err)
+	}
+	if !strings.Contains(string(backend.data), "confidential body") {
+		t.Fatal("测试服务器没有收到邮件")
+	}
+
+	if len(sink.transcripts) != 1 {
+		t.Fatalf("应该保存 1 条会话记录，实际 %d", len(sink.transcripts))
+	}
+	tr := sink.transcripts[0]
+	if tr.Err != nil || tr.MXHost != "mx.remote.test" || tr.RemoteAddr != ln.Addr().String() || tr.TLSVersion == "" || tr.TLSCipher == "" {
+		t.Errorf("会话信息不正确: %+v", tr)
+	}
+	// TLS 之后的命令和回复以明文记录，邮件内容只记录字节数
+	for _, want := range []string{"S: 220 mx.remote.test", "C: STARTTLS", "TLS 握手完成", "CN=mx.remote.test",
+		"C: MAIL FROM:<alice@example.com>", "C: RCPT TO:<nobody@remote.test>", "S: 550 5.1.1 No such user",
+		"C: DATA", "S: 354", "字节，没有记录", "C: QUIT"} {
+		if !strings.Contains(tr.Log, want) {
+			t.Errorf("会话记录缺少 %q:\n%s", want, tr.Log)
+		}
+	}
+	if strings.Contains(tr.Log, "confidential") || strings.Contains(tr.Log, "secret") {
+		t.Errorf("会话记录不应该包含邮件内容:\n%s", tr.Log)
+	}
+	if strings.Index(tr.Log, "字节，没有记录") > strings.LastIndex(tr.Log, "S: 250") {
+		t.Errorf("邮件内容应该记录在对方的回复之前:\n%s", tr.Log)
+	}
+
+	// 找不到 MX 时也保存记录，其他域名不记录
+	sink.transcripts = nil
+	sink.domain = "missing.test"
+	if err := client.SendMail(ctx, "alice@example.com", []string{"bob@missing.test", "carol@other.test"}, data); err == nil {
+		t.Fatal("找不到 MX 时应该返回错误")
+	}
+	if len(sink.transcripts) != 1 || sink.transcripts[0].Err == nil || sink.transcripts[0].Domain != "missing.test" {
+		t.Errorf("应该只保存 missing.test 的失败记录: %+v", sink.transcripts)
+	}
+}
@@ -4,15 +4,36 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/emersion/go-message"
+	"github.com/emersion/go-sasl"
 	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/autoreply"
+	"github.com/gomailzero/gmz/internal/batv"
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/deliverylog"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/newsletter"
+	"github.com/gomailzero/gmz/internal/receipts"
+	"github.com/gomailzero/gmz/internal/saslauth"
+	"github.com/gomailzero/gmz/internal/search"
 	"github.com/gomailzero/gmz/internal/storage"
 )
 
@@ -21,23 +42,53 @@ type Backend struct {
 	storage storage.Driver
 	maildir *storage.Maildir
 	auth    Authenticator
+
+	allowInsecureLocalhost bool         // 允许本机明文连接认证
+	requireTLSPorts        map[int]bool // 要求 STARTTLS 后才能 MAIL FROM 的端口
+	tarpit                 *antispam.Tarpit
+	limiter                *limits.Limiter
+	hostnames              map[int]string                    // 端口 -> 主机名（横幅、Received 头）
+	upstreams              map[int]*antispam.TrustedUpstream // 端口 -> 受信任的上游网关
+	listeners              map[int]ListenerConfig            // 端口 -> 监听配置（按端口关闭 TLS、认证、减速和限流）
+	forwarder              *forward.Forwarder
+	batv                   *batv.Signer // 退信地址验证（可选）
+	submissionPorts        map[int]bool // 提交端口（必须认证，发件人必须属于认证用户）
+	outbound               Sender       // 提交端口上外部收件人的外发路径
+	classifier             *category.Classifier
+	identities             *identity.Manager // 提交端口上外部发件身份的外发路径（可选）
+	autoResponder          *autoreply.Responder
+	sasl                   *saslauth.Mechanisms // PLAIN 以外的认证机制（可选）
+	bounces                *bounce.Recorder     // 记录本地用户收到的退信报告（可选）
+	antispam               *antispam.Engine     // 投递前检查外部来信（可选）
+	deliveries             *deliverylog.Log     // 投递审计日志（可选）
+	activity               *activity.Recorder   // 账户活动记录：认证用户的登录和发信（可选）
+	hostname               string               // 没有按端口配置主机名时使用的主机名（LMTP）
 }
 
 // NewBackend 创建后端
 func NewBackend(storage storage.Driver, maildir *storage.Maildir, auth Authenticator) *Backend {
 	return &Backend{
-		storage: storage,
-		maildir: maildir,
-		auth:    auth,
+		storage:    storage,
+		maildir:    maildir,
+		auth:       auth,
+		classifier: category.NewClassifier(storage),
 	}
 }
 
 // NewSession 创建新会话
 func (b *Backend) NewSession(c *smtp.Conn) (smtp.Session, error) {
-	return &Session{
-		backend: b,
-		conn:    c,
-	}, nil
+	s := &Session{
+		backend:     b,
+		conn:        c,
+		lmtp:        c.Server().LMTP,
+		earlyTalker: antispam.EarlyTalker(c.Conn()),
+	}
+	setVRFYSession(c.Conn(), s)
+	// 抢先发送的客户端同样计入连接减速的失败次数
+	if s.earlyTalker {
+		s.recordFailure()
+	}
+	return s, nil
 }
 
 // Session SMTP 会话
@@ -46,41 +97,602 @@ type Session struct {
 	conn       *smtp.Conn
 	from       string
 	recipients []string
+	external   []string          // 提交端口上的外部收件人（经外发路径投递）
+	user       *storage.User     // 已认证用户
+	identity   *storage.Identity // 发件地址对应的外部发件身份（外部收件人经其 SMTP 服务器提交）
+	size       int64             // MAIL FROM 的 SIZE 参数（客户端声明的邮件大小，未声明时为 0）
+	lmtp       bool              // LMTP 连接（外部 MTA 投递到本地邮箱，见 LMTPServer）
+	// earlyTalker 客户端在欢迎横幅之前发送了数据（见 antispam.GreetDelay），邮件标记为垃圾邮件
+	earlyTalker bool
+}
+
+// errEncryptionRequired 明文连接上请求认证（RFC 4954）
+var errEncryptionRequired = &smtp.SMTPError{
+	Code:         538,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 11},
+	Message:      "Encryption required for requested authentication mechanism",
+}
+
+// errStartTLSRequired 要求 STARTTLS 的端口上未加密就发送 MAIL FROM
+var errStartTLSRequired = &smtp.SMTPError{
+	Code:         530,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 0},
+	Message:      "Must issue a STARTTLS command first",
+}
+
+// errAuthRequired 提交端口上未认证就发送 MAIL FROM
+var errAuthRequired = &smtp.SMTPError{
+	Code:         530,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 0},
+	Message:      "Authentication required",
+}
+
+// errSenderNotOwned 提交端口上的发件人不属于认证用户
+var errSenderNotOwned = &smtp.SMTPError{
+	Code:         553,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 1},
+	Message:      "Sender address not owned by authenticated user",
+}
+
+// errRelayDenied 收件人不是本地域名且没有外发路径
+var errRelayDenied = &smtp.SMTPError{
+	Code:         550,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 1},
+	Message:      "Relay access denied",
+}
+
+// errOutboundFailed 外部收件人投递失败（客户端稍后重试）
+var errOutboundFailed = &smtp.SMTPError{
+	Code:         451,
+	EnhancedCode: smtp.EnhancedCode{4, 4, 0},
+	Message:      "Unable to deliver to external recipients, try again later",
+}
+
+// errMailboxUnavailable 收件地址不可用（已过期或已作废的别名、不存在的用户）
+var errMailboxUnavailable = &smtp.SMTPError{
+	Code:         550,
+	EnhancedCode: smtp.EnhancedCode{5, 1, 1},
+	Message:      "Mailbox unavailable",
+}
+
+// errBounceRejected 退信发往未签名或签名无效的地址（BATV：不是本域发出的邮件的退信，多为伪造发件人产生的反向散射）
+var errBounceRejected = &smtp.SMTPError{
+	Code:         550,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 1},
+	Message:      "Bounce rejected: address was not used as an envelope sender",
+}
+
+// errMailLoop 邮件经过的跳数过多或转发形成循环（RFC 3463 X.4.6）
+var errMailLoop = &smtp.SMTPError{
+	Code:         554,
+	EnhancedCode: smtp.EnhancedCode{5, 4, 6},
+	Message:      "Routing loop detected",
+}
+
+// errSpamRejected 反垃圾检查拒收
+var errSpamRejected = &smtp.SMTPError{
+	Code:         550,
+	EnhancedCode: smtp.EnhancedCode{5, 7, 1},
+	Message:      "Message rejected as spam",
+}
+
+// errSpamDeferred 反垃圾检查临时拒绝（如 Rspamd 的 soft reject、greylist 动作）
+var errSpamDeferred = &smtp.SMTPError{
+	Code:         451,
+	EnhancedCode: smtp.EnhancedCode{4, 7, 1},
+	Message:      "Message deferred, try again later",
+}
+
+// maxReceivedHops 邮件最多经过的跳数（Received 头数，RFC 5321 6.3 建议至少 100）
+const maxReceivedHops = 100
+
+// errMailboxFull 收件人的存储配额已用尽（RFC 3463 X.2.2）
+var errMailboxFull = &smtp.SMTPError{
+	Code:         552,
+	EnhancedCode: smtp.EnhancedCode{5, 2, 2},
+	Message:      "Mailbox full",
+}
+
+// errStorageFailed 存储邮件失败（客户端稍后重试）
+var errStorageFailed = &smtp.SMTPError{
+	Code:         451,
+	EnhancedCode: smtp.EnhancedCode{4, 3, 0},
+	Message:      "Unable to store message, try again later",
+}
+
+// storageError 把存储错误映射为 SMTP 回复：超出配额为永久错误，收件人不存在为邮箱不可用，其他为临时错误
+func storageError(err error) error {
+	var smtpErr *smtp.SMTPError
+	switch {
+	case errors.As(err, &smtpErr):
+		return smtpErr
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		return errMailboxFull
+	case errors.Is(err, storage.ErrNotFound), errors.Is(err, storage.ErrInvalidInput):
+		return errMailboxUnavailable
+	default:
+		return errStorageFailed
+	}
+}
+
+// isTLS 当前连接是否已加密（465 端口或 STARTTLS 之后）
+func (s *Session) isTLS() bool {
+	_, ok := s.conn.TLSConnectionState()
+	return ok
+}
+
+// isSubmission 当前连接是否在提交端口上
+func (s *Session) isSubmission() bool {
+	return s.backend.submissionPorts[s.localPort()]
+}
+
+// ownsSender 发件地址是否属于认证用户（本人地址或指向本人的有效别名）
+func (s *Session) ownsSender(ctx context.Context, from string) bool {
+	addr := strings.ToLower(strings.TrimSpace(from))
+	if addr == "" {
+		return false
+	}
+	if addr == strings.ToLower(s.user.Email) {
+		return true
+	}
+	alias, err := s.backend.storage.GetAlias(ctx, addr)
+	if err != nil || !alias.Active(time.Now()) {
+		return false
+	}
+	for _, to := range alias.Destinations {
+		if strings.EqualFold(to, s.user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// senderIdentity 发件地址对应的认证用户的外部发件身份（未启用或不存在时返回 nil）
+func (s *Session) senderIdentity(ctx context.Context, from string) *storage.Identity {
+	if s.backend.identities == nil {
+		return nil
+	}
+	found, err := s.backend.identities.Find(ctx, s.user.Email, strings.TrimSpace(from))
+	if err != nil {
+		return nil
+	}
+	return found
+}
+
+// errTooManyAuthFailures IP 因认证失败次数过多被临时封禁
+var errTooManyAuthFailures = &smtp.SMTPError{
+	Code:         454,
+	EnhancedCode: smtp.EnhancedCode{4, 7, 0},
+	Message:      "Too many authentication failures, try again later",
+}
+
+// isLocalhost 对端是否为回环地址
+func (s *Session) isLocalhost() bool {
+	host, _, err := net.SplitHostPort(s.conn.Conn().RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// remoteIP 对端 IP
+func (s *Session) remoteIP() string {
+	return antispam.RemoteIP(s.conn.Conn().RemoteAddr())
+}
+
+// listener 当前端口的监听配置
+func (s *Session) listener() ListenerConfig {
+	return s.backend.listeners[s.localPort()]
+}
+
+// tarpit 当前端口使用的连接减速（端口关闭减速时和 LMTP 连接为 nil）
+func (s *Session) tarpit() *antispam.Tarpit {
+	if s.lmtp || s.listener().DisableTarpit {
+		return nil
+	}
+	return s.backend.tarpit
+}
+
+// limiter 当前端口使用的 IP 限制（端口关闭限流时和 LMTP 连接为 nil）
+func (s *Session) limiter() *limits.Limiter {
+	if s.lmtp || s.listener().DisableRateLimit {
+		return nil
+	}
+	return s.backend.limiter
+}
+
+// recordFailure 记录失败，用于连接减速
+func (s *Session) recordFailure() {
+	if tarpit := s.tarpit(); tarpit != nil {
+		tarpit.RecordFailure(s.remoteIP())
+	}
+}
+
+// localPort 连接的本地端口
+func (s *Session) localPort() int {
+	if addr, ok := s.conn.Conn().LocalAddr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// hostname 当前监听端口使用的主机名
+func (s *Session) hostname() string {
+	if hostname, ok := s.backend.hostnames[s.localPort()]; ok {
+		return hostname
+	}
+	if s.backend.hostname != "" {
+		return s.backend.hostname
+	}
+	return "localhost"
 }
 
-// Auth 认证（在 Session 中不需要实现，由 Server 处理）
+// checkUpstream 来自受信任的上游网关的邮件记录网关的验证结果（网关已按原始来源检查 SPF、DKIM 和 DMARC）；
+// 其他来源的邮件删除带有网关 authserv-id 的 Authentication-Results 头，避免伪造的验证结果被后续处理信任
+func (s *Session) checkUpstream(upstream *antispam.TrustedUpstream, rawData []byte) []byte {
+	if !upstream.Contains(net.ParseIP(s.remoteIP())) {
+		stripped := upstream.StripForged(rawData)
+		if len(stripped) != len(rawData) {
+			logger.Warn().Str("ip", s.remoteIP()).Str("from", s.from).Msg("删除非上游网关来源的邮件中伪造的验证结果")
+		}
+		return stripped
+	}
+
+	results := upstream.Results(rawData)
+	if results == nil {
+		logger.Warn().Str("ip", s.remoteIP()).Str("from", s.from).Msg("上游网关转来的邮件没有受信任的验证结果")
+		return rawData
+	}
+	logger.Debug().
+		Str("authserv_id", results.AuthServID).
+		Str("spf", results.SPF.String()).
+		Str("dkim", results.DKIM.String()).
+		Str("dmarc", results.DMARC.String()).
+		Msg("使用上游网关的验证结果")
+	return rawData
+}
+
+// receivedHeader 生成 Received 头（RFC 5321 4.4），使用接收连接的监听端口对应的主机名：
+// 包括客户端的 HELO 名称和 IP、队列 ID、TLS 版本和加密套件，只有一个收件人时包括收件人（避免泄露密送地址）
+func (s *Session) receivedHeader(queueID string) string {
+	protocol := "ESMTP"
+	if s.lmtp {
+		protocol = "LMTP"
+	}
+	if s.isTLS() {
+		protocol += "S"
+	}
+	if s.user != nil {
+		protocol += "A"
+	}
+	// Unix 套接字上的 LMTP 连接没有对端 IP
+	from := s.conn.Hostname()
+	if ip := s.remoteIP(); ip != "" && ip != "@" {
+		from += " ([" + ip + "])"
+	}
+	header := fmt.Sprintf("Received: from %s\r\n\tby %s with %s id %s", from, s.hostname(), protocol, queueID)
+	if state, ok := s.conn.TLSConnectionState(); ok {
+		header += fmt.Sprintf("\r\n\t(using %s with cipher %s)", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	}
+	if recipients := append(append([]string(nil), s.recipients...), s.external...); len(recipients) == 1 {
+		header += "\r\n\tfor <" + recipients[0] + ">"
+	}
+	return header + "; " + time.Now().Format(time.RFC1123Z) + "\r\n"
+}
+
+// returnPath 最终投递时添加的 Return-Path 头（RFC 5321 4.4，空发件人为 <>）
+func (s *Session) returnPath() string {
+	return "Return-Path: <" + s.from + ">\r\n"
+}
+
+// withDeliveredTo 在邮件前添加 Delivered-To 头（邮件转发回来时据此发现循环）
+func withDeliveredTo(address string, data []byte) []byte {
+	return append([]byte("Delivered-To: "+address+"\r\n"), data...)
+}
+
+// withOriginalTo 在邮件前添加 X-Original-To 头，记录投递到邮箱 mailbox 的全部原收件人
+// （别名展开之前的地址）；原收件人只有邮箱本身时不添加
+func withOriginalTo(mailbox string, originals []string, data []byte) []byte {
+	if len(originals) == 0 || (len(originals) == 1 && strings.EqualFold(originals[0], mailbox)) {
+		return data
+	}
+	var header []byte
+	for _, original := range originals {
+		header = append(header, "X-Original-To: "+original+"\r\n"...)
+	}
+	return append(header, data...)
+}
+
+// deliveredBefore 邮件是否已经投递给 address（Delivered-To 头中有该地址，说明转发形成了循环）
+func deliveredBefore(msg *mailparse.Message, address string) bool {
+	if msg == nil {
+		return false
+	}
+	for _, v := range msg.Header.Values("Delivered-To") {
+		if strings.EqualFold(strings.TrimSpace(v), address) {
+			return true
+		}
+	}
+	return false
+}
+
+// recordReceived 记录接收的邮件（每个收件人一条，包括提交端口上的外部收件人）
+func (s *Session) recordReceived(ctx context.Context, messageID string) {
+	if s.backend.deliveries == nil {
+		return
+	}
+	detail := fmt.Sprintf("from %s [%s] port %d", s.conn.Hostname(), s.remoteIP(), s.localPort())
+	if s.lmtp {
+		detail = fmt.Sprintf("from %s [%s] via LMTP", s.conn.Hostname(), s.remoteIP())
+	}
+	if s.user != nil {
+		detail += " user " + s.user.Email
+	}
+	for _, recipient := range s.envelopeRecipients() {
+		s.backend.deliveries.Record(ctx, &storage.DeliveryEvent{
+			MessageID: messageID,
+			Event:     storage.DeliveryReceived,
+			Sender:    s.from,
+			Recipient: recipient,
+			Code:      250,
+			Detail:    detail,
+		})
+	}
+}
+
+// envelopeRecipients 返回事务的全部信封收件人（包括提交端口上的外部收件人，不带尖括号）
+func (s *Session) envelopeRecipients() []string {
+	recipients := make([]string, 0, len(s.recipients)+len(s.external))
+	for _, recipient := range append(append([]string(nil), s.recipients...), s.external...) {
+		recipients = append(recipients, strings.Trim(strings.TrimSpace(recipient), "<>"))
+	}
+	return recipients
+}
+
+// authAllowed 是否允许在当前连接上认证
+func (s *Session) authAllowed() bool {
+	if s.listener().DisableAuth {
+		return false
+	}
+	return s.isTLS() || (s.backend.allowInsecureLocalhost && s.isLocalhost())
+}
 
-// Mail 设置发件人
+// AuthMechanisms 返回可用的认证机制（明文连接上不提供 AUTH）
+func (s *Session) AuthMechanisms() []string {
+	if s.backend.auth == nil || !s.authAllowed() {
+		return nil
+	}
+	return append([]string{sasl.Plain}, s.backend.sasl.Names()...)
+}
+
+// Auth 认证（明文连接上拒绝，防止降级攻击窃取密码）
+func (s *Session) Auth(mech string) (sasl.Server, error) {
+	if s.backend.auth == nil || s.listener().DisableAuth {
+		return nil, smtp.ErrAuthUnsupported
+	}
+	if !s.authAllowed() {
+		logger.Warn().
+			Str("remote", s.conn.Conn().RemoteAddr().String()).
+			Msg("拒绝明文连接上的 SMTP 认证")
+		return nil, errEncryptionRequired
+	}
+	if mech != sasl.Plain {
+		if server := s.backend.sasl.NewServer(mech, s.login); server != nil {
+			return server, nil
+		}
+		return nil, smtp.ErrAuthUnknownMechanism
+	}
+
+	return sasl.NewPlainServer(func(identity, username, password string) error {
+		if identity != "" && identity != username {
+			return fmt.Errorf("不支持以其他身份认证")
+		}
+		// 同一连接上反复尝试时，封禁在认证过程中生效
+		if err := s.limiter().Allow("smtp", s.remoteIP()); err != nil {
+			return errTooManyAuthFailures
+		}
+		user, err := s.backend.auth.Authenticate(context.Background(), username, password)
+		if err := s.login(user, err); err != nil {
+			return err
+		}
+		s.backend.sasl.RememberPassword(context.Background(), user, password)
+		return nil
+	}), nil
+}
+
+// login 完成认证：记录失败用于减速和封禁，成功时设置会话用户（所有认证机制共用）
+func (s *Session) login(user *storage.User, err error) error {
+	if err := s.limiter().Allow("smtp", s.remoteIP()); err != nil {
+		return errTooManyAuthFailures
+	}
+	if err != nil {
+		s.recordFailure()
+		s.limiter().Failure("smtp", s.remoteIP())
+		return smtp.ErrAuthFailed
+	}
+	if tarpit := s.tarpit(); tarpit != nil {
+		tarpit.Reset(s.remoteIP())
+	}
+	s.limiter().Success(s.remoteIP())
+	s.user = user
+	s.backend.activity.Login(context.Background(), user.Email, activity.ProtocolSMTP, s.remoteIP(), "")
+	if s.conn != nil {
+		drain.SetUser(s.conn.Conn(), user.Email)
+	}
+	return nil
+}
+
+// Mail 开始邮件事务：事务完成（Reset）之前服务器停止时不会断开连接
 func (s *Session) Mail(from string, opts *smtp.MailOptions) error {
+	s.setBusy(true)
+	if err := s.mail(from, opts); err != nil {
+		s.setBusy(false)
+		return err
+	}
+	return nil
+}
+
+// setBusy 标记连接是否处于邮件事务中，同时更新连接列表中的状态（mail 或事务外的状态）
+func (s *Session) setBusy(busy bool) {
+	switch {
+	case busy:
+		s.setState("mail")
+	case s.user != nil:
+		s.setState("authenticated")
+	default:
+		s.setState(drain.StateConnected)
+	}
+	if s.conn != nil {
+		drain.SetBusy(s.conn.Conn(), busy)
+	}
+}
+
+// setState 设置连接列表中显示的会话状态
+func (s *Session) setState(state string) {
+	if s.conn != nil {
+		drain.SetState(s.conn.Conn(), state)
+	}
+}
+
+// mail 设置发件人
+func (s *Session) mail(from string, opts *smtp.MailOptions) error {
+	if s.backend.requireTLSPorts[s.localPort()] && !s.isTLS() {
+		return errStartTLSRequired
+	}
+	if s.isSubmission() {
+		if s.user == nil {
+			return errAuthRequired
+		}
+		ctx := context.Background()
+		if !s.ownsSender(ctx, from) {
+			s.identity = s.senderIdentity(ctx, from)
+			if s.identity == nil {
+				logger.Warn().
+					Str("user", s.user.Email).
+					Str("from", from).
+					Msg("拒绝不属于认证用户的发件地址")
+				return errSenderNotOwned
+			}
+		}
+	}
+
 	s.from = from
+	if opts != nil {
+		s.size = opts.Size
+	}
 	logger.Debug().Str("from", from).Msg("MAIL FROM")
 	return nil
 }
 
 // Rcpt 设置收件人（检查中继）
 func (s *Session) Rcpt(to string, opts *smtp.RcptOptions) error {
+	rcpt := to
+	if !s.isSubmission() {
+		original, err := s.checkBATV(to)
+		if err != nil {
+			return err
+		}
+		to = original
+	}
+
 	// 提取域名
 	parts := strings.Split(to, "@")[1]
 
-	// 检查域名是否存在
+	// 检查域名是否存在（提交端口上的外部收件人经外发路径投递）
 	ctx := context.Background()
 	_, err := s.backend.storage.GetDomain(ctx, parts)
+	if err != nil && s.isSubmission() {
+		if s.backend.outbound == nil && s.identity == nil {
+			return errRelayDenied
+		}
+		s.external = append(s.external, to)
+		logger.Debug().Str("to", to).Msg("RCPT TO（外部）")
+		return nil
+	}
 	if err != nil {
+		s.recordFailure()
 		return fmt.Errorf("无效的邮箱地址: %s", to)
 	}
 
-	s.recipients = append(s.recipients, to)
+	// 已过期或已作废的别名（如临时别名）直接拒收；有效别名检查全部本地目标
+	mailboxes, isAlias := []string{to}, false
+	if alias, err := s.backend.storage.GetAlias(ctx, strings.ToLower(to)); err == nil {
+		if !alias.Active(time.Now()) {
+			return errMailboxUnavailable
+		}
+		mailboxes, _ = forward.Destinations(ctx, s.backend.storage, alias)
+		isAlias = true
+	}
+
+	// LMTP 由外部 MTA 转交，不存在的用户在 RCPT TO 拒收，由 MTA 生成退信（SRS 退信地址除外）
+	if s.lmtp && !isAlias && !isSRSAddress(to) {
+		if _, err := s.backend.storage.GetUser(ctx, to); errors.Is(err, storage.ErrNotFound) {
+			return errMailboxUnavailable
+		}
+	}
+
+	// 配额已用尽的邮箱直接拒收（MAIL FROM 带 SIZE 参数时按声明的大小检查），
+	// 别名的任一本地目标配额已用尽时拒收，由发件人得知投递失败
+	for _, mailbox := range mailboxes {
+		if err := storage.NewMailStore(nil, s.backend.storage).CheckQuota(ctx, mailbox, s.size); err != nil {
+			logger.Info().Err(err).Str("to", to).Str("mailbox", mailbox).Msg("拒收：配额已用尽")
+			return storageError(err)
+		}
+	}
+
+	s.recipients = append(s.recipients, rcpt)
 	logger.Debug().Str("to", to).Msg("RCPT TO")
 	return nil
 }
 
+// checkBATV 验证 BATV 签名的收件地址并返回原地址；发往启用 BATV 的域名的退信（空发件人）
+// 必须使用有效的签名地址（SRS 退信地址除外，由 SRS 验证）
+func (s *Session) checkBATV(to string) (string, error) {
+	if s.backend.batv == nil {
+		return to, nil
+	}
+	original, err := s.backend.batv.Verify(to)
+	if err == nil {
+		return original, nil
+	}
+	if !errors.Is(err, batv.ErrNotSigned) {
+		logger.Info().Err(err).Str("from", s.from).Str("to", to).Msg("拒收：BATV 签名无效")
+		return "", errBounceRejected
+	}
+	if s.from == "" && !isSRSAddress(to) {
+		if at := strings.LastIndex(to, "@"); at >= 0 && s.backend.batv.Enabled(to[at+1:]) {
+			logger.Info().Str("to", to).Msg("拒收：退信发往未签名的地址（BATV）")
+			return "", errBounceRejected
+		}
+	}
+	return to, nil
+}
+
 // Data 接收邮件数据
 func (s *Session) Data(r io.Reader) error {
+	return s.data(r, nil)
+}
+
+// LMTPData 接收邮件数据并按收件人返回投递结果（RFC 2033，实现 smtp.LMTPSession）
+func (s *Session) LMTPData(r io.Reader, status smtp.StatusCollector) error {
+	return s.data(r, status)
+}
+
+// data 接收邮件数据并投递；status 不为空时（LMTP）逐个设置收件人的投递结果，
+// 未设置结果的收件人使用返回值
+func (s *Session) data(r io.Reader, status smtp.StatusCollector) error {
+	s.setState("data")
 	// 限制读取大小以防 OOM
 	const MaxMailSize = 50 * 1024 * 1024 // 50 MiB
 	limited := io.LimitReader(r, MaxMailSize+1)
 	rawData, err := io.ReadAll(limited)
 	if err != nil {
+		// 超过端口的大小上限时原样返回 552，而不是事务失败
+		var smtpErr *smtp.SMTPError
+		if errors.As(err, &smtpErr) {
+			return smtpErr
+		}
 		return fmt.Errorf("读取邮件数据失败: %w", err)
 	}
 	if int64(len(rawData)) > MaxMailSize {
@@ -88,14 +700,12 @@ func (s *Session) Data(r io.Reader) error {
 		return fmt.Errorf("552 Message size exceeds fixed maximum message size")
 	}
 
-	// 尝试解析邮件
-	msg, err := message.Read(bytes.NewReader(rawData))
+	// 解析邮件头
+	msg, err := mailparse.ParseHeader(rawData)
 	if err != nil {
-		previewLen := 1024
-		logger.Warn().Err(err).Hex("preview", rawData[:previewLen]).Msg("邮件解析失败，尝试重新构建邮件头")
+		logger.Warn().Err(err).Hex("preview", rawData[:min(len(rawData), 1024)]).Msg("邮件解析失败，尝试重新构建邮件头")
 	}
 
-	// 解析邮件头
 	var fromHeader, to, subject string
 	var hasHeaders bool
 	if msg != nil {
@@ -114,83 +724,365 @@ func (s *Session) Data(r io.Reader) error {
 		logger.Debug().Msg("邮件缺少邮件头，已重新构建完整邮件")
 	}
 
+	// 经过的跳数过多说明邮件在服务器之间循环
+	if msg != nil && len(msg.Header.Values("Received")) >= maxReceivedHops {
+		logger.Warn().Str("from", s.from).Strs("to", s.recipients).Msg("拒收：邮件经过的跳数过多")
+		return errMailLoop
+	}
+
+	// 受信任的上游网关：使用网关的验证结果，其他来源的邮件删除伪造的网关验证结果
+	if upstream := s.backend.upstreams[s.localPort()]; upstream != nil {
+		rawData = s.checkUpstream(upstream, rawData)
+	}
+
+	if s.earlyTalker {
+		rawData = append([]byte("X-Spam-Early-Talker: yes\r\n"), rawData...)
+	}
+
+	// 添加 Received 头（同一事务的投递日志和 Received 头使用相同的队列 ID）
+	queueID := deliverylog.NewQueueID()
+	rawData = append([]byte(s.receivedHeader(queueID)), rawData...)
+
+	// 解码后的元数据（无法解析的邮件仍然投递，只是没有元数据）
+	parsed, err := mailparse.ParseHeader(rawData)
+	if err != nil {
+		logger.Warn().Err(err).Msg("解析邮件失败")
+		parsed = &mailparse.Message{}
+	}
+
+	// 订阅邮件（List-Unsubscribe）标记关键字并按用户设置归档
+	var unsubscribe *storage.Unsubscribe
+	if msg != nil {
+		unsubscribe = newsletter.Detect(msg.Header)
+	}
+
+	ctx := deliverylog.WithQueueID(context.Background(), queueID)
+	messageID := deliverylog.MessageID(rawData)
+	s.recordReceived(ctx, messageID)
+	// 认证用户提交的邮件记入账户活动（外发失败的同样记录，账户被盗用时的发信尝试也能看到）
+	if s.user != nil {
+		s.backend.activity.Sent(ctx, s.user.Email, activity.ProtocolSMTP, s.remoteIP(), s.envelopeRecipients(), rawData)
+	}
+
+	// 反垃圾检查：拒收时不投递，疑似垃圾邮件标记为垃圾邮件
+	rawData, junk, err := s.checkSpam(ctx, rawData, parsed)
+	if err != nil {
+		return err
+	}
+
+	// 外部收件人先发出，失败时返回临时错误，避免重试时本地收件人收到重复邮件
+	if len(s.external) > 0 {
+		// 使用外部发件身份时通过其 SMTP 服务器提交
+		var err error
+		if s.identity != nil {
+			err = s.backend.identities.Send(ctx, s.identity, s.external, rawData)
+			s.backend.deliveries.RecordDelivery(ctx, s.from, s.external, rawData, err)
+		} else {
+			err = s.backend.outbound.Send(ctx, s.from, s.external, rawData)
+		}
+		if err != nil {
+			logger.Warn().Err(err).
+				Str("from", s.from).
+				Strs("to", s.external).
+				Msg("提交的邮件外发失败")
+			return errOutboundFailed
+		}
+	}
+
+	// 认证用户提交给本地用户的邮件记录回执，关联客户端已保存的已发送邮件（之后保存的由 IMAP 补记）
+	var sent *storage.Mail
+	if s.user != nil {
+		sent = receipts.New(s.backend.storage).SentCopy(ctx, s.user.Email, messageID)
+	}
+
 	// 存储邮件到 Maildir
-	ctx := context.Background()
-	for _, recipient := range s.recipients {
-		// 提取用户邮箱（去除显示名称）
-		userEmail := recipient
-		if idx := strings.Index(recipient, "<"); idx >= 0 {
-			if idx2 := strings.Index(recipient, ">"); idx2 > idx {
-				userEmail = recipient[idx+1 : idx2]
+	// DATA 只有一个回复：全部本地收件人都存储失败时才返回错误，部分失败时返回成功，
+	// 避免客户端重试时已投递的收件人收到重复邮件
+	var deliverErr error
+	failed, targets := 0, len(s.recipients)
+	// 同一事务中发往别名和别名目标（或同一用户的多个别名）的收件人，每个本地邮箱只投递一份
+	resolved, originals := s.resolveRecipients(ctx)
+	results := make(map[string]error) // 已处理的本地邮箱（小写）及其投递结果
+	for i, recipient := range s.recipients {
+		userEmail := resolved[i].address
+
+		// 发往 SRS 地址的退信还原后投递给原发件人
+		if s.backend.forwarder != nil {
+			err := s.backend.forwarder.Bounce(ctx, userEmail, rawData)
+			if err == nil {
+				setStatus(status, recipient, nil)
+				continue
+			}
+			if !errors.Is(err, forward.ErrNotSRS) {
+				logger.Warn().Err(err).Str("to", userEmail).Msg("SRS 退信投递失败")
+				setStatus(status, recipient, nil)
+				continue
 			}
 		}
-		userEmail = strings.TrimSpace(userEmail)
 
-		// 存储到 Maildir
-		if s.backend.maildir != nil {
-			if err := s.backend.maildir.EnsureUserMaildir(userEmail); err != nil {
-				logger.Warn().Err(err).Str("user", userEmail).Msg("创建用户 Maildir 失败")
+		// 别名可以有多个目标：本地邮箱逐个投递，外部地址经 SRS 重写后转发
+		address := userEmail
+		mailboxes, external := resolved[i].mailboxes, resolved[i].external
+		if !resolved[i].resolved {
+			mailboxes, external = s.resolveMailboxes(ctx, userEmail)
+		}
+		targets += len(mailboxes) + len(external) - 1
+		// 收件人的全部本地邮箱都存储失败时，该收件人投递失败（LMTP）
+		var rcptErr error
+		rcptFailed := 0
+		if len(external) > 0 && deliveredBefore(msg, address) {
+			logger.Warn().Str("alias", address).Strs("to", external).Msg("别名转发形成循环，不再转发")
+			external = nil
+		}
+		s.backend.forwarder.ForwardAlias(ctx, address, s.from, external, rawData)
+
+		for _, userEmail := range mailboxes {
+			// 邮箱已经在本事务中处理过（重复的收件人只记录在 X-Original-To 中），沿用第一次的投递结果
+			key := strings.ToLower(userEmail)
+			if err, done := results[key]; done {
+				logger.Debug().Str("user", userEmail).Str("recipient", address).Msg("同一邮箱的重复收件人，合并投递")
+				targets--
+				if err != nil {
+					rcptErr = err
+					rcptFailed++
+				}
 				continue
 			}
-			filename, err := s.backend.maildir.StoreMail(userEmail, "INBOX", rawData)
-			if err != nil {
-				logger.Warn().Err(err).Str("user", userEmail).Msg("存储邮件到 Maildir 失败")
+			results[key] = nil
+
+			// 邮件已投递给该邮箱：转发规则把邮件转回了本服务器
+			if deliveredBefore(msg, userEmail) {
+				logger.Warn().Str("user", userEmail).Str("from", s.from).Msg("拒收：转发形成循环")
+				deliverErr = errMailLoop
+				rcptErr = errMailLoop
+				results[key] = errMailLoop
+				failed++
+				rcptFailed++
+				s.backend.deliveries.Record(ctx, &storage.DeliveryEvent{
+					MessageID: messageID,
+					Event:     storage.DeliveryFailed,
+					Sender:    s.from,
+					Recipient: userEmail,
+					Detail:    "转发形成循环（邮件已有该收件人的 Delivered-To 头）",
+				})
 				continue
 			}
+			delivered := withDeliveredTo(userEmail, withOriginalTo(userEmail, originals[key], rawData))
 
-			// 解析邮件头以获取元数据
-			msg, err := message.Read(bytes.NewReader(rawData))
-			if err != nil {
-				logger.Warn().Err(err).Str("user", userEmail).Msg("解析邮件失败")
-				continue
+			// 退信报告（空发件人）记录被退回的收件人，用于退信统计
+			if s.from == "" && s.backend.bounces != nil {
+				s.backend.bounces.RecordDSN(ctx, userEmail, rawData)
 			}
 
-			header := msg.Header
-			from := header.Get("From")
-			toStr := header.Get("To")
-			subject := header.Get("Subject")
-
-			// 解析收件人列表
-			var toList []string
-			if toStr != "" {
-				toList = []string{toStr}
-			} else {
-				toList = []string{userEmail}
+			// 自动回复（在转发之前，转发且不保留副本的用户同样回复；失败不影响投递）
+			if msg != nil {
+				if _, err := s.backend.autoResponder.Respond(ctx, userEmail, address, s.from, msg.Header); err != nil {
+					logger.Warn().Err(err).Str("user", userEmail).Str("sender", s.from).Msg("自动回复失败")
+				}
 			}
 
-			// 存储邮件元数据到数据库
-			mail := &storage.Mail{
-				ID:         filename,
-				UserEmail:  userEmail,
-				Folder:     "INBOX",
-				From:       from,
-				To:         toList,
-				Subject:    subject,
-				Size:       int64(len(rawData)),
-				Flags:      []string{"\\Recent"},
-				ReceivedAt: time.Now(),
-				CreatedAt:  time.Now(),
+			// 外部转发（转发失败时保留本地副本，避免丢信）
+			if s.forward(ctx, userEmail, fromHeader, subject, delivered) {
+				continue
 			}
 
-			if err := s.backend.storage.StoreMail(ctx, mail); err != nil {
-				logger.Warn().Err(err).Str("user", userEmail).Msg("存储邮件元数据失败")
-			} else {
-				logger.Info().
-					Str("user", userEmail).
-					Str("from", from).
-					Str("subject", subject).
-					Msg("邮件已存储")
+			folder, flags := "INBOX", []string{"\\Recent"}
+			if unsubscribe != nil {
+				folder = s.newsletterFolder(ctx, userEmail)
+				flags = append(flags, newsletter.Keyword)
+			}
+			if s.earlyTalker || junk {
+				flags = append(flags, antispam.JunkKeyword)
+			}
+
+			// 存储到 Maildir（文件和数据库记录一起写入）
+			if s.backend.maildir != nil {
+				from := mailparse.FormatAddress(parsed.From)
+				subject := parsed.Subject
+
+				// 收件箱自动分类（以关键字标志保存）
+				cat, tokens := s.backend.classifier.Classify(ctx, userEmail, parsed.Header)
+
+				// 解析收件人列表
+				toList := mailparse.Emails(parsed.To)
+				if len(toList) == 0 {
+					toList = []string{userEmail}
+				}
+
+				// 最终投递时添加 Return-Path 头
+				stored := append([]byte(s.returnPath()), delivered...)
+
+				// 邮件元数据
+				mail := &storage.Mail{
+					UserEmail:  userEmail,
+					Folder:     folder,
+					From:       from,
+					To:         toList,
+					Subject:    subject,
+					Size:       int64(len(stored)),
+					Flags:      append(flags, category.Keyword(cat)),
+					ReceivedAt: time.Now(),
+					CreatedAt:  time.Now(),
+
+					Attachments: search.ExtractAttachments(rawData),
+					Unsubscribe: unsubscribe,
+				}
+
+				if err := storage.NewMailStore(s.backend.maildir, s.backend.storage).Deliver(ctx, mail, stored); err != nil {
+					logger.Warn().Err(err).Str("user", userEmail).Msg("存储邮件失败")
+					deliverErr = err
+					rcptErr = err
+					results[key] = err
+					failed++
+					rcptFailed++
+					s.backend.deliveries.Record(ctx, &storage.DeliveryEvent{
+						MessageID: messageID,
+						Event:     storage.DeliveryFailed,
+						Sender:    s.from,
+						Recipient: userEmail,
+						Folder:    folder,
+						Detail:    err.Error(),
+					})
+				} else {
+					s.backend.deliveries.Record(ctx, &storage.DeliveryEvent{
+						MessageID: messageID,
+						Event:     storage.DeliveryDelivered,
+						Sender:    s.from,
+						Recipient: userEmail,
+						Folder:    folder,
+					})
+					if err := s.backend.storage.SaveMailCategory(ctx, &storage.MailCategory{MailID: mail.ID, Category: cat, Tokens: tokens}); err != nil {
+						logger.Warn().Err(err).Str("user", userEmail).Msg("保存邮件分类失败")
+					}
+					if s.user != nil {
+						receipts.New(s.backend.storage).Delivered(ctx, sent, mail)
+					}
+					logger.Info().
+						Str("user", userEmail).
+						Str("from", from).
+						Str("subject", subject).
+						Msg("邮件已存储")
+				}
 			}
 		}
+		if rcptFailed > 0 && rcptFailed == len(mailboxes) && len(external) == 0 {
+			setStatus(status, recipient, storageError(rcptErr))
+		} else {
+			setStatus(status, recipient, nil)
+		}
 	}
 
+	if failed > 0 && failed == targets && len(s.external) == 0 {
+		return storageError(deliverErr)
+	}
 	return nil
 }
 
-// Reset 重置会话
+// checkSpam 投递前用反垃圾引擎检查外部来信（认证用户提交的邮件不检查）：拒收或临时拒绝时返回 SMTP 错误，
+// 接受时在邮件开头添加 X-Spam 头，疑似垃圾邮件时 junk 为 true。检查出错时放行邮件
+func (s *Session) checkSpam(ctx context.Context, rawData []byte, parsed *mailparse.Message) (_ []byte, junk bool, _ error) {
+	engine := s.backend.antispam
+	if engine == nil || s.user != nil {
+		return rawData, false, nil
+	}
+
+	headers := make(map[string]string)
+	fields := parsed.Header.Fields()
+	for fields.Next() {
+		if _, ok := headers[fields.Key()]; !ok {
+			headers[fields.Key()] = fields.Value()
+		}
+	}
+	headers["Message-ID"] = parsed.Header.Get("Message-ID")
+	var body []byte
+	if i := bytes.Index(rawData, []byte("\r\n\r\n")); i >= 0 {
+		body = rawData[i+4:]
+	}
+	req := &antispam.CheckRequest{
+		IP:            net.ParseIP(s.remoteIP()),
+		From:          s.from,
+		HELO:          s.conn.Hostname(),
+		Headers:       headers,
+		Body:          body,
+		DKIMSignature: parsed.Header.Get("DKIM-Signature"),
+		Raw:           rawData,
+	}
+	if i := strings.LastIndex(s.from, "@"); i >= 0 {
+		req.Domain = s.from[i+1:]
+	}
+	// 按第一个收件人的模型评分（如贝叶斯分类器）
+	if len(s.recipients) > 0 {
+		req.To = s.recipients[0]
+	}
+
+	result, err := engine.Check(ctx, req)
+	if err != nil {
+		logger.Warn().Err(err).Str("from", s.from).Msg("反垃圾检查失败，放行邮件")
+		return rawData, false, nil
+	}
+	switch result.Decision {
+	case antispam.DecisionReject:
+		logger.Info().Str("from", s.from).Strs("to", s.recipients).Int("score", result.Score).Strs("reasons", result.Reasons).Msg("拒收：垃圾邮件")
+		s.recordFailure()
+		return nil, false, errSpamRejected
+	case antispam.DecisionTempReject:
+		logger.Info().Str("from", s.from).Strs("to", s.recipients).Int("score", result.Score).Strs("reasons", result.Reasons).Msg("临时拒绝：疑似垃圾邮件")
+		return nil, false, errSpamDeferred
+	}
+
+	junk = result.Decision == antispam.DecisionQuarantine
+	return append(spamHeaders(result, junk), rawData...), junk, nil
+}
+
+// spamHeaders 接受邮件时添加的 X-Spam 头：规则返回的邮件头（如 Rspamd 的分数），
+// 没有 X-Spam-Status 时按引擎的分数和决策添加
+func spamHeaders(result *antispam.CheckResult, junk bool) []byte {
+	headers := make(map[string]string, len(result.Headers)+2)
+	for name, value := range result.Headers {
+		headers[name] = value
+	}
+	if _, ok := headers["X-Spam-Status"]; !ok {
+		status := "No"
+		if junk {
+			status = "Yes"
+		}
+		headers["X-Spam"] = status
+		headers["X-Spam-Status"] = fmt.Sprintf("%s, score=%d", status, result.Score)
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		value := strings.NewReplacer("\r", " ", "\n", " ").Replace(headers[name])
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	return buf.Bytes()
+}
+
+// isSRSAddress 地址是否为 SRS 重写后的发件人地址（转发邮件的退信）
+func isSRSAddress(address string) bool {
+	return len(address) >= 5 && strings.EqualFold(address[:5], "SRS0=")
+}
+
+// setStatus 设置 LMTP 收件人的投递结果（status 为空时为 SMTP 连接，不设置）
+func setStatus(status smtp.StatusCollector, recipient string, err error) {
+	if status != nil {
+		status.SetStatus(recipient, err)
+	}
+}
+
+// Reset 重置会话（邮件事务结束）
 func (s *Session) Reset() {
 	s.from = ""
 	s.recipients = nil
+	s.external = nil
+	s.identity = nil
+	s.size = 0
+	s.setBusy(false)
 }
 
 // buildCompleteEmail 构建完整的邮件（包含邮件头）
@@ -248,43 +1140,9 @@ func (s *Session) buildCompleteEmail(fromHeader, to, subject string, body []byte
 	buf.WriteString(fmt.Sprintf("Subject: %s\r\n", subject))
 	buf.WriteString("MIME-Version: 1.0\r\n")
 	
-	// 检查邮件体是否已经是 MIME 格式
-	bodyStr := string(body)
-	if strings.HasPrefix(strings.TrimSpace(bodyStr), "This is a multi-part message in MIME format.") {
-		// 已经是 MIME 格式，直接添加 Content-Type
-		buf.WriteString("Content-Type: multipart/alternative; boundary=\"")
-		// 尝试从邮件体中提取 boundary
-		// 格式通常是: ------=_001_NextPart111350263035_=----
-		// boundary 值是: _001_NextPart111350263035_=
-		lines := strings.Split(bodyStr, "\n")
-		boundaryFound := false
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			// 匹配格式: ------=<boundary>=----
-			if strings.HasPrefix(line, "------=") && strings.Contains(line, "=") && strings.HasSuffix(line, "----") {
-				// 提取 boundary 值
-				// 去掉开头的 "------=" 和结尾的 "----"
-				boundary := strings.TrimPrefix(line, "------=")
-				boundary = strings.TrimSuffix(boundary, "----")
-				// 确保 boundary 不为空
-				if boundary != "" {
-					buf.WriteString(boundary)
-					boundaryFound = true
-					break
-				}
-			}
-		}
-		// 如果没找到 boundary，生成一个
-		if !boundaryFound {
-			randomBytes := make([]byte, 8)
-			if _, err := rand.Read(randomBytes); err != nil { // #nosec G104 -- 随机数生成失败不影响功能
-				// 如果随机数生成失败，使用时间戳作为后备
-				randomBytes = []byte(fmt.Sprintf("%d", time.Now().UnixNano()))
-			}
-			random := hex.EncodeToString(randomBytes)
-			buf.WriteString(fmt.Sprintf("_%s_", random))
-		}
-		buf.WriteString("\"\r\n")
+	// 检查邮件体是否已经是 MIME 格式（有分隔行和对应的结束行）
+	if boundary := mailparse.FindBoundary(body); boundary != "" {
+		buf.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n", boundary))
 	} else {
 		// 普通文本，添加 Content-Type
 		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
@@ -309,15 +1167,101 @@ func (s *Session) generateMessageID() string {
 	}
 	random := hex.EncodeToString(randomBytes)
 	
-	// 获取主机名
-	hostname := "localhost"
-	// 如果将来需要从 maildir 配置中获取域名，可以在这里添加逻辑
-	_ = s.backend.maildir // 避免未使用变量警告
-	
+	// 使用监听端口对应的主机名
+	hostname := s.hostname()
+
 	timestamp := time.Now().UnixNano()
 	return fmt.Sprintf("<%d.%s@%s>", timestamp, random, hostname)
 }
 
+// forward 按用户的转发规则转发邮件，返回是否已转发且不需要保留本地副本
+func (s *Session) forward(ctx context.Context, mailbox, from, subject string, data []byte) bool {
+	if s.backend.forwarder == nil {
+		return false
+	}
+	rule, err := s.backend.storage.GetForwardingRule(ctx, mailbox)
+	if err != nil || !rule.Verified || !forward.Match(rule, from, subject) {
+		return false
+	}
+	if allowed, err := s.backend.forwarder.Allowed(ctx, mailbox); err != nil || !allowed {
+		return false
+	}
+
+	if err := s.backend.forwarder.Forward(ctx, rule, s.from, data); err != nil {
+		logger.Warn().Err(err).Str("user", mailbox).Str("to", rule.Address).Msg("转发邮件失败，保留本地副本")
+		return false
+	}
+	return !rule.KeepCopy
+}
+
+// newsletterFolder 订阅邮件投递的文件夹（用户设置了归档文件夹时使用该文件夹，否则为收件箱）
+func (s *Session) newsletterFolder(ctx context.Context, userEmail string) string {
+	settings, err := s.backend.storage.GetNewsletterSettings(ctx, userEmail)
+	if err != nil || settings.Folder == "" {
+		return "INBOX"
+	}
+	return settings.Folder
+}
+
+// recipientAddress 提取信封收件人的地址（去除显示名称和尖括号），BATV 签名地址还原为原地址
+// （签名在 RCPT TO 已验证）
+func (s *Session) recipientAddress(recipient string) string {
+	address := recipient
+	if idx := strings.Index(recipient, "<"); idx >= 0 {
+		if idx2 := strings.Index(recipient, ">"); idx2 > idx {
+			address = recipient[idx+1 : idx2]
+		}
+	}
+	address = strings.TrimSpace(address)
+	if s.backend.batv != nil {
+		if original, err := s.backend.batv.Verify(address); err == nil {
+			address = original
+		}
+	}
+	return address
+}
+
+// resolvedRecipient 信封收件人解析后的本地邮箱和外部地址
+type resolvedRecipient struct {
+	address             string
+	mailboxes, external []string
+	resolved            bool // 是否已经解析（SRS 地址在投递时先尝试还原退信，不是退信时再解析）
+}
+
+// resolveRecipients 在投递之前解析全部信封收件人，并按本地邮箱（小写）汇总投递到该邮箱的原收件人，
+// 同一封邮件因别名和别名目标（或多个别名）重复发往同一邮箱时只投递一份
+func (s *Session) resolveRecipients(ctx context.Context) ([]resolvedRecipient, map[string][]string) {
+	resolved := make([]resolvedRecipient, len(s.recipients))
+	originals := make(map[string][]string)
+	for i, recipient := range s.recipients {
+		r := resolvedRecipient{address: s.recipientAddress(recipient)}
+		if s.backend.forwarder == nil || !isSRSAddress(r.address) {
+			r.mailboxes, r.external = s.resolveMailboxes(ctx, r.address)
+			r.resolved = true
+		}
+		for _, mailbox := range r.mailboxes {
+			key := strings.ToLower(mailbox)
+			if !slices.ContainsFunc(originals[key], func(v string) bool { return strings.EqualFold(v, r.address) }) {
+				originals[key] = append(originals[key], r.address)
+			}
+		}
+		resolved[i] = r
+	}
+	return resolved, originals
+}
+
+// resolveMailboxes 解析收件人对应的本地邮箱和外部地址：有效别名投递到全部目标（见 forward.ResolveAlias），
+// 其他收件人投递到本身
+func (s *Session) resolveMailboxes(ctx context.Context, recipient string) (mailboxes, external []string) {
+	if _, err := s.backend.storage.GetUser(ctx, recipient); err == nil {
+		return []string{recipient}, nil
+	}
+	if mailboxes, external, ok := forward.ResolveAlias(ctx, s.backend.storage, recipient); ok {
+		return mailboxes, external
+	}
+	return []string{recipient}, nil
+}
+
 // Logout 登出
 func (s *Session) Logout() error {
 	return nil
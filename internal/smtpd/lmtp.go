@@ -0,0 +1,157 @@
+package smtpd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/autoreply"
+	"github.com/gomailzero/gmz/internal/batv"
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/deliverylog"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// LMTPServer LMTP 服务器（RFC 2033）：Postfix、Exim 等外部 MTA 负责收发，
+// 通过 LMTP 把邮件投递到本地邮箱，投递路径（别名、转发、自动回复、订阅邮件归档、分类）与 SMTP 接收相同，
+// 每个收件人单独返回投递结果
+type LMTPServer struct {
+	config  *LMTPConfig
+	backend *Backend
+	server  *smtp.Server
+	conns   drain.Tracker
+	wg      sync.WaitGroup
+}
+
+// LMTPConfig LMTP 配置
+type LMTPConfig struct {
+	// Socket Unix 套接字路径（为空时不监听）
+	Socket string
+	// Address TCP 监听地址（如 127.0.0.1:24，为空时不监听）；LMTP 没有认证，只应监听本机或内网地址
+	Address  string
+	Hostname string
+	MaxSize  int64
+	Storage  storage.Driver
+	Maildir  *storage.Maildir
+	// Forwarder 外部转发和 SRS 退信处理（为空时不转发）
+	Forwarder *forward.Forwarder
+	// BATV 退信地址验证（为空时不验证）
+	BATV *batv.Signer
+	// AutoResponder 投递时按用户设置自动回复发件人（为空时不回复）
+	AutoResponder *autoreply.Responder
+	// Bounces 记录本地用户收到的退信报告（为空时不记录）
+	Bounces *bounce.Recorder
+	// DeliveryLog 投递审计日志（为空时不记录）
+	DeliveryLog *deliverylog.Log
+}
+
+// NewLMTPServer 创建 LMTP 服务器
+func NewLMTPServer(cfg *LMTPConfig) *LMTPServer {
+	backend := NewBackend(cfg.Storage, cfg.Maildir, nil)
+	backend.hostname = cfg.Hostname
+	backend.forwarder = cfg.Forwarder
+	backend.batv = cfg.BATV
+	backend.autoResponder = cfg.AutoResponder
+	backend.bounces = cfg.Bounces
+	backend.deliveries = cfg.DeliveryLog
+
+	s := smtp.NewServer(backend)
+	s.LMTP = true
+	s.Domain = cfg.Hostname
+	if s.Domain == "" {
+		s.Domain = "localhost"
+	}
+	s.MaxMessageBytes = cfg.MaxSize
+	s.MaxRecipients = 100
+
+	srv := &LMTPServer{
+		config:  cfg,
+		backend: backend,
+		server:  s,
+	}
+	srv.conns.Protocol = "lmtp"
+	return srv
+}
+
+// Start 启动服务器（监听 Unix 套接字和/或 TCP 地址）
+func (s *LMTPServer) Start(ctx context.Context) error {
+	var listeners []net.Listener
+	if s.config.Socket != "" {
+		// 删除上次运行留下的套接字文件
+		if err := os.Remove(s.config.Socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("删除旧的 LMTP 套接字失败: %w", err)
+		}
+		listener, err := net.Listen("unix", s.config.Socket)
+		if err != nil {
+			return fmt.Errorf("监听 LMTP 套接字失败: %w", err)
+		}
+		// MTA 通常以其他用户运行，套接字需要允许同组用户连接
+		// #nosec G302 -- 套接字文件需要组写权限
+		if err := os.Chmod(s.config.Socket, 0660); err != nil {
+			_ = listener.Close()
+			return fmt.Errorf("设置 LMTP 套接字权限失败: %w", err)
+		}
+		listeners = append(listeners, listener)
+	}
+	if s.config.Address != "" {
+		listener, err := net.Listen("tcp", s.config.Address)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return fmt.Errorf("监听 LMTP 地址失败: %w", err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	for _, listener := range listeners {
+		addr := listener.Addr().String()
+		listener = s.conns.Listener(listener)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			logger.Info().Str("listen", addr).Msg("LMTP 服务器启动")
+			if err := s.server.Serve(listener); err != nil && !s.conns.Draining() {
+				logger.Error().Err(err).Str("listen", addr).Msg("LMTP 服务器错误")
+			}
+		}()
+	}
+	return nil
+}
+
+// Connections 返回当前的 LMTP 连接
+func (s *LMTPServer) Connections() []drain.Info {
+	return s.conns.List()
+}
+
+// Disconnect 强制断开 LMTP 连接，连接不存在时返回 false
+func (s *LMTPServer) Disconnect(id uint64) bool {
+	return s.conns.Disconnect(id)
+}
+
+// Stop 停止服务器：进行中的投递完成后断开，ctx 截止时仍未完成的连接被强制关闭
+func (s *LMTPServer) Stop(ctx context.Context) error {
+	s.conns.Drain()
+	err := s.conns.Wait(ctx)
+	if err != nil {
+		logger.Warn().Int("connections", s.conns.Active()).Msg("等待 LMTP 投递完成超时，强制关闭连接")
+		err = fmt.Errorf("等待 LMTP 投递完成超时: %w", err)
+	}
+
+	if err := s.server.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Error().Err(err).Msg("关闭 LMTP 服务器失败")
+	}
+	s.wg.Wait()
+	if s.config.Socket != "" {
+		_ = os.Remove(s.config.Socket)
+	}
+	logger.Info().Msg("LMTP 服务器已停止")
+	return err
+}
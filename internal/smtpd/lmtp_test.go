@@ -0,0 +1,99 @@
+package smtpd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestLMTPDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "carol@example.com", PasswordHash: "x", Quota: 200, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "lmtp.sock")
+	server := NewLMTPServer(&LMTPConfig{
+		Socket:   socket,
+		Hostname: "mx.example.com",
+		Storage:  driver,
+		Maildir:  maildir,
+	})
+	if err := server.Start(ctx); err != nil {
+		t.Fatalf("启动 LMTP 服务器失败: %v", err)
+	}
+	t.Cleanup(func() {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		_ = server.Stop(stopCtx)
+	})
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("连接 LMTP 套接字失败: %v", err)
+	}
+	client := smtp.NewClientLMTP(conn)
+	defer client.Close()
+	if err := client.Hello("postfix.example.com"); err != nil {
+		t.Fatalf("LHLO 失败: %v", err)
+	}
+
+	if err := client.Mail("bob@example.net", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rcpt("nobody@example.com", nil); smtpCode(err) != 550 {
+		t.Errorf("不存在的收件人应该返回 550, got %v", err)
+	}
+	for _, rcpt := range []string{"alice@example.com", "carol@example.com"} {
+		if err := client.Rcpt(rcpt, nil); err != nil {
+			t.Fatalf("RCPT TO %s 失败: %v", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := "From: bob@example.net\r\nSubject: lmtp\r\n\r\n" + strings.Repeat("x", 300) + "\r\n"
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 每个收件人单独返回结果：carol 超出配额，alice 投递成功
+	_, err = w.CloseWithLMTPResponse()
+	var lmtpErr smtp.LMTPDataError
+	if !errors.As(err, &lmtpErr) {
+		t.Fatalf("期望按收件人返回的错误, got %v", err)
+	}
+	if status := lmtpErr["carol@example.com"]; status == nil || status.Code != 552 {
+		t.Errorf("超出配额的收件人应该返回 552, got %v", status)
+	}
+	if status, ok := lmtpErr["alice@example.com"]; ok {
+		t.Errorf("alice 应该投递成功, got %v", status)
+	}
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mails) != 1 {
+		t.Fatalf("alice 收件箱邮件数 = %d，期望 1", len(mails))
+	}
+	data, err := maildir.ReadMail("alice@example.com", "INBOX", mails[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "with LMTP") {
+		t.Errorf("Received 头应该标明 LMTP:\n%s", data)
+	}
+}
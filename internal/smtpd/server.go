@@ -3,12 +3,25 @@ package smtpd
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/autoreply"
+	"github.com/gomailzero/gmz/internal/batv"
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/deliverylog"
+	"github.com/gomailzero/gmz/internal/drain"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/saslauth"
 	"github.com/gomailzero/gmz/internal/storage"
 )
 
@@ -16,7 +29,8 @@ import (
 type Server struct {
 	config  *Config
 	backend *Backend
-	servers []*smtp.Server
+	servers map[int]*smtp.Server // 端口 -> 服务器（每个监听端口可以使用不同的主机名和横幅）
+	conns   drain.Tracker        // 跟踪连接，停止时等待进行中的邮件事务完成
 	wg      sync.WaitGroup
 }
 
@@ -30,31 +44,180 @@ type Config struct {
 	Storage  storage.Driver
 	Maildir  *storage.Maildir
 	Auth     Authenticator
+	// AllowInsecureAuthLocalhost 允许本机明文连接认证（其他明文连接不提供 AUTH）
+	AllowInsecureAuthLocalhost bool
+	// RequireTLSPorts 要求先执行 STARTTLS 才能 MAIL FROM 的端口
+	RequireTLSPorts []int
+	// Tarpit 对多次认证失败或被拒收的 IP 减速（为空时不减速）
+	Tarpit *antispam.Tarpit
+	// Limiter 按 IP 限制并发连接数，认证失败次数过多时封禁 IP（为空时不限制）
+	Limiter *limits.Limiter
+	// Banner 欢迎横幅文本（跟在主机名之后）
+	Banner string
+	// Listeners 按端口覆盖主机名和横幅（同一台服务器服务多个品牌）
+	Listeners map[int]ListenerConfig
+	// VRFY VRFY/EXPN 策略：VRFYDeny（默认，始终返回 252）或 VRFYAdmin（认证的管理员可以查询，见 vrfyListener）
+	VRFY string
+	// Forwarder 外部转发和 SRS 退信处理（为空时不转发）
+	Forwarder *forward.Forwarder
+	// BATV 退信地址验证：发往启用域名的退信必须使用有效的签名地址（为空时不验证）
+	BATV *batv.Signer
+	// SubmissionPorts 提交端口：必须认证，发件人必须属于认证用户，外部收件人经 Outbound 发出
+	SubmissionPorts []int
+	// Outbound 提交端口上发往外部收件人的外发路径（为空时提交端口只能发往本地收件人）
+	Outbound Sender
+	// Identities 外部发件身份：提交端口上用户可以使用自己的外部地址发信，外部收件人经该地址的 SMTP 服务器提交（可选）
+	Identities *identity.Manager
+	// AutoResponder 投递时按用户设置自动回复发件人（为空时不回复）
+	AutoResponder *autoreply.Responder
+	// SASL PLAIN 以外的认证机制：CRAM-MD5、OAUTHBEARER、XOAUTH2（为空时只支持 PLAIN）
+	SASL *saslauth.Mechanisms
+	// AntiSpam 投递前检查外部来信（Rspamd 等规则）：拒收或临时拒绝，接受的邮件添加 X-Spam 头，
+	// 疑似垃圾邮件标记为垃圾邮件（为空时不检查）
+	AntiSpam *antispam.Engine
+	// Bounces 记录本地用户收到的退信报告，用于退信统计（为空时不记录）
+	Bounces *bounce.Recorder
+	// DeliveryLog 投递审计日志：记录接收、投递到文件夹和投递失败（为空时不记录）
+	DeliveryLog *deliverylog.Log
+	// Activity 账户活动记录：认证用户的登录和提交的邮件（为空时不记录）
+	Activity *activity.Recorder
+	// CommandTimeout 等待客户端下一条命令的时间，超时断开连接（0 不限制）
+	CommandTimeout time.Duration
+	// DataTimeout DATA 期间等待下一块数据的时间（0 不限制）
+	DataTimeout time.Duration
+	// IdleTimeout 连接没有开始邮件事务的最长时间，期间的 NOOP、RSET 等命令不会延长（0 不限制）
+	IdleTimeout time.Duration
+	// TimeoutObserver 超时断开的会话计数（可选）
+	TimeoutObserver drain.Observer
+}
+
+// Sender 外发邮件（中继或直接投递）
+type Sender interface {
+	Send(ctx context.Context, from string, to []string, data []byte) error
+}
+
+// ListenerConfig 单个监听端口的配置
+type ListenerConfig struct {
+	Hostname string // 横幅、EHLO 和 Received 头使用的主机名（留空使用 Config.Hostname）
+	Banner   string // 横幅文本（留空使用 Config.Banner）
+	MaxSize  int64  // 最大邮件大小（0 使用 Config.MaxSize）
+
+	DisableTLS       bool // 不提供 TLS（465 不使用隐式 TLS，其他端口不提供 STARTTLS）
+	DisableAuth      bool // 不提供 AUTH（只接收外部邮件的 MX 端口）
+	DisableTarpit    bool // 不对失败的连接减速
+	DisableRateLimit bool // 不按 IP 限制连接数，不拒绝被封禁的 IP
+	// Upstream 受信任的上游过滤网关（可选）：来自网关的邮件使用网关的验证结果，
+	// 其他来源的邮件中伪造的网关验证结果会被删除
+	Upstream *antispam.TrustedUpstream
+	// GreetDelay 欢迎横幅延迟和抢先发送检测（可选，只用于 MX 端口，隐式 TLS 端口不使用）
+	GreetDelay *antispam.GreetDelay
 }
 
 // NewServer 创建 SMTP 服务器
 func NewServer(cfg *Config) *Server {
 	backend := NewBackend(cfg.Storage, cfg.Maildir, cfg.Auth)
-
-	s := smtp.NewServer(backend)
-	s.Addr = fmt.Sprintf(":%d", cfg.Ports[0])
-	s.Domain = cfg.Hostname
-	if s.Domain == "" {
-		s.Domain = "localhost"
+	backend.allowInsecureLocalhost = cfg.AllowInsecureAuthLocalhost
+	backend.tarpit = cfg.Tarpit
+	backend.limiter = cfg.Limiter
+	backend.forwarder = cfg.Forwarder
+	backend.batv = cfg.BATV
+	backend.requireTLSPorts = make(map[int]bool)
+	for _, port := range cfg.RequireTLSPorts {
+		backend.requireTLSPorts[port] = true
+	}
+	backend.outbound = cfg.Outbound
+	backend.identities = cfg.Identities
+	backend.autoResponder = cfg.AutoResponder
+	backend.sasl = cfg.SASL
+	backend.bounces = cfg.Bounces
+	backend.antispam = cfg.AntiSpam
+	backend.deliveries = cfg.DeliveryLog
+	backend.activity = cfg.Activity
+	backend.submissionPorts = make(map[int]bool)
+	for _, port := range cfg.SubmissionPorts {
+		backend.submissionPorts[port] = true
 	}
-	s.MaxMessageBytes = int64(cfg.MaxSize)
-	s.MaxRecipients = 100
 
-	if cfg.TLS != nil {
-		s.TLSConfig = cfg.TLS
-		// TODO: 实现认证支持
+	servers := make(map[int]*smtp.Server)
+	backend.hostnames = make(map[int]string)
+	backend.upstreams = make(map[int]*antispam.TrustedUpstream)
+	backend.listeners = cfg.Listeners
+	for _, port := range cfg.Ports {
+		hostname, banner := cfg.listener(port)
+		backend.hostnames[port] = hostname
+		l := cfg.Listeners[port]
+		if l.Upstream != nil {
+			backend.upstreams[port] = l.Upstream
+		}
+
+		s := smtp.NewServer(backend)
+		s.Addr = fmt.Sprintf(":%d", port)
+		// go-smtp 的横幅格式为 "220 <Domain> ESMTP Service Ready"，横幅文本放在主机名之后
+		s.Domain = hostname
+		if banner != "" {
+			s.Domain = hostname + " " + banner
+		}
+		s.MaxMessageBytes = int64(cfg.MaxSize)
+		if l.MaxSize > 0 {
+			s.MaxMessageBytes = l.MaxSize
+		}
+		s.MaxRecipients = 100
+
+		if cfg.TLS != nil && !l.DisableTLS {
+			s.TLSConfig = cfg.TLS
+		}
+		// 由会话按连接决定是否提供 AUTH（加密连接或允许的本机连接），见 Session.AuthMechanisms
+		s.AllowInsecureAuth = true
+
+		servers[port] = s
 	}
 
-	return &Server{
+	srv := &Server{
 		config:  cfg,
 		backend: backend,
-		servers: []*smtp.Server{s},
+		servers: servers,
 	}
+	srv.conns.Protocol = "smtp"
+	srv.conns.IdleTimeouts = map[string]time.Duration{
+		drain.StateConnected: cfg.CommandTimeout,
+		"authenticated":      cfg.CommandTimeout,
+		"mail":               cfg.CommandTimeout,
+		"data":               cfg.DataTimeout,
+	}
+	// 邮件事务进行中的连接是忙碌的，不受 IdleTimeout 限制
+	srv.conns.StateTimeouts = map[string]time.Duration{
+		drain.StateConnected: cfg.IdleTimeout,
+		"authenticated":      cfg.IdleTimeout,
+	}
+	srv.conns.Observer = cfg.TimeoutObserver
+	return srv
+}
+
+// commandListener 包装明文端口的监听器：按策略应答 VRFY/EXPN，欢迎横幅延迟包装在最外层，
+// 会话可以取得连接的检测结果
+func (s *Server) commandListener(port int, listener net.Listener) net.Listener {
+	listener = &vrfyListener{Listener: listener, backend: s.backend, policy: s.config.VRFY}
+	if greet := s.config.Listeners[port].GreetDelay; greet != nil {
+		listener = greet.Listener(listener)
+	}
+	return listener
+}
+
+// listener 返回端口使用的主机名和横幅文本
+func (cfg *Config) listener(port int) (string, string) {
+	hostname, banner := cfg.Hostname, cfg.Banner
+	if l, ok := cfg.Listeners[port]; ok {
+		if l.Hostname != "" {
+			hostname = l.Hostname
+		}
+		if l.Banner != "" {
+			banner = l.Banner
+		}
+	}
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	return hostname, banner
 }
 
 // Start 启动服务器
@@ -76,14 +239,27 @@ func (s *Server) Start(ctx context.Context) error {
 				return
 			}
 
-			// 如果是 465 端口，使用 TLS
-			if p == 465 && s.config.TLS != nil {
+			// 拒绝被封禁 IP 和超过并发上限的连接
+			l := s.config.Listeners[p]
+			if !l.DisableRateLimit {
+				listener = s.config.Limiter.Listener("smtp", listener)
+			}
+			// 减速滥用 IP 的连接（在 TLS 之下包装，握手同样被延迟）
+			if s.config.Tarpit != nil && !l.DisableTarpit {
+				listener = s.config.Tarpit.Listener(listener)
+			}
+			listener = s.conns.Listener(listener)
+
+			// 如果是 465 端口，使用 TLS（TLS 握手由客户端先发送，不能检测抢先发送，也不能拦截 VRFY/EXPN）
+			if p == 465 && s.config.TLS != nil && !l.DisableTLS {
 				listener = tls.NewListener(listener, s.config.TLS)
+			} else {
+				listener = s.commandListener(p, listener)
 			}
 
 			logger.Info().Int("port", p).Msg("SMTP 服务器启动")
 
-			if err := s.servers[0].Serve(listener); err != nil {
+			if err := s.servers[p].Serve(listener); err != nil && !s.conns.Draining() {
 				logger.Error().Err(err).Int("port", p).Msg("SMTP 服务器错误")
 			}
 		}(port)
@@ -92,15 +268,33 @@ func (s *Server) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop 停止服务器
+// Connections 返回当前的 SMTP 连接
+func (s *Server) Connections() []drain.Info {
+	return s.conns.List()
+}
+
+// Disconnect 强制断开 SMTP 连接（进行中的邮件事务被中断，客户端稍后重试），连接不存在时返回 false
+func (s *Server) Disconnect(id uint64) bool {
+	return s.conns.Disconnect(id)
+}
+
+// Stop 停止服务器：不再接受新连接，空闲连接立即断开，进行中的邮件事务完成后断开；
+// ctx 截止时仍未完成的连接被强制关闭
 func (s *Server) Stop(ctx context.Context) error {
+	s.conns.Drain()
+	err := s.conns.Wait(ctx)
+	if err != nil {
+		logger.Warn().Int("connections", s.conns.Active()).Msg("等待 SMTP 邮件事务完成超时，强制关闭连接")
+		err = fmt.Errorf("等待 SMTP 邮件事务完成超时: %w", err)
+	}
+
 	for _, server := range s.servers {
-		if err := server.Close(); err != nil {
+		if err := server.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			logger.Error().Err(err).Msg("关闭 SMTP 服务器失败")
 		}
 	}
 
 	s.wg.Wait()
 	logger.Info().Msg("SMTP 服务器已停止")
-	return nil
+	return err
 }
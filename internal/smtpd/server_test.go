@@ -0,0 +1,1615 @@
+package smtpd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/autoreply"
+	"github.com/gomailzero/gmz/internal/batv"
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/deliverylog"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/limits"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/saslauth"
+	"github.com/gomailzero/gmz/internal/storage"
+	tlsconfig "github.com/gomailzero/gmz/internal/tls"
+)
+
+// mockAuthenticator 密码为 secret 时认证成功
+type mockAuthenticator struct{}
+
+func (mockAuthenticator) Authenticate(ctx context.Context, username, password string) (*storage.User, error) {
+	if password != "secret" {
+		return nil, fmt.Errorf("认证失败")
+	}
+	return &storage.User{Email: username, Active: true}, nil
+}
+
+// startTestServer 在回环地址上启动 SMTP 服务器，返回监听地址
+func startTestServer(t *testing.T, allowLocalhost, requireTLS bool, opts ...func(cfg *Config, port int)) string {
+	t.Helper()
+
+	cert, err := tlsconfig.LoadOrCreateSelfSigned(t.TempDir(), []string{"localhost"})
+	if err != nil {
+		t.Fatalf("生成测试证书失败: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	cfg := &Config{
+		Enabled:                    true,
+		Ports:                      []int{port},
+		Hostname:                   "localhost",
+		TLS:                        &tls.Config{Certificates: []tls.Certificate{*cert}, MinVersion: tls.VersionTLS12},
+		Auth:                       mockAuthenticator{},
+		AllowInsecureAuthLocalhost: allowLocalhost,
+	}
+	if requireTLS {
+		cfg.RequireTLSPorts = []int{port}
+	}
+	for _, opt := range opts {
+		opt(cfg, port)
+	}
+
+	server := NewServer(cfg)
+	// 与 Start 相同，拦截 VRFY/EXPN，欢迎横幅延迟包装在最外层
+	go server.servers[port].Serve(server.commandListener(port, listener)) // #nosec G104 -- 测试服务器，关闭时返回错误
+	t.Cleanup(func() { server.servers[port].Close() })
+
+	return listener.Addr().String()
+}
+
+func dialTest(t *testing.T, addr string, startTLS bool) *smtp.Client {
+	t.Helper()
+
+	var client *smtp.Client
+	var err error
+	if startTLS {
+		// #nosec G402 -- 测试使用自签名证书
+		client, err = smtp.DialStartTLS(addr, &tls.Config{InsecureSkipVerify: true})
+	} else {
+		client, err = smtp.Dial(addr)
+	}
+	if err != nil {
+		t.Fatalf("连接 SMTP 服务器失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.Hello("client.test"); err != nil {
+		t.Fatalf("EHLO 失败: %v", err)
+	}
+	return client
+}
+
+func smtpCode(err error) int {
+	var smtpErr *smtp.SMTPError
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code
+	}
+	return 0
+}
+
+func TestAuthRequiresTLS(t *testing.T) {
+	addr := startTestServer(t, false, false)
+
+	// 明文连接：不提供 AUTH，强行认证也被拒绝
+	client := dialTest(t, addr, false)
+	if ok, _ := client.Extension("AUTH"); ok {
+		t.Error("明文连接不应该提供 AUTH")
+	}
+	err := client.Auth(sasl.NewPlainClient("", "user@example.com", "secret"))
+	if smtpCode(err) != 538 {
+		t.Errorf("明文连接认证应该返回 538, got %v", err)
+	}
+
+	// STARTTLS 之后提供 AUTH 并可以认证
+	client = dialTest(t, addr, true)
+	if !client.SupportsAuth(sasl.Plain) {
+		t.Error("加密连接应该提供 AUTH PLAIN")
+	}
+	if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "secret")); err != nil {
+		t.Errorf("加密连接认证失败: %v", err)
+	}
+}
+
+func TestAuthInsecureLocalhost(t *testing.T) {
+	addr := startTestServer(t, true, false)
+
+	client := dialTest(t, addr, false)
+	if !client.SupportsAuth(sasl.Plain) {
+		t.Error("允许本机明文认证时应该提供 AUTH PLAIN")
+	}
+	if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "wrong")); err == nil {
+		t.Error("错误的密码不应该认证成功")
+	}
+}
+
+func TestAuthBan(t *testing.T) {
+	limiter, err := limits.New(limits.Config{MaxAuthFailures: 2, Window: time.Minute, BanDuration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Limiter = limiter
+	})
+
+	client := dialTest(t, addr, false)
+	for i := 0; i < 2; i++ {
+		if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "wrong")); smtpCode(err) != 535 {
+			t.Errorf("错误的密码应该返回 535, got %v", err)
+		}
+	}
+
+	// 失败次数达到上限后封禁 IP，正确的密码也被拒绝
+	client = dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "secret")); smtpCode(err) != 454 {
+		t.Errorf("IP 被封禁后认证应该返回 454, got %v", err)
+	}
+}
+
+// cramMD5Client CRAM-MD5 客户端（go-sasl 没有提供）
+type cramMD5Client struct {
+	username, password string
+}
+
+func (c *cramMD5Client) Start() (string, []byte, error) {
+	return saslauth.CRAMMD5, nil, nil
+}
+
+func (c *cramMD5Client) Next(challenge []byte) ([]byte, error) {
+	mac := hmac.New(md5.New, []byte(c.password))
+	mac.Write(challenge)
+	return []byte(c.username + " " + hex.EncodeToString(mac.Sum(nil))), nil
+}
+
+func TestAuthSASLMechanisms(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	hash, err := crypto.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: hash, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	jwt := auth.NewJWTManager("test-secret", "example.com")
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Storage = driver
+		cfg.Maildir = maildir
+		cfg.Auth = NewDefaultAuthenticator(driver)
+		cfg.SASL = saslauth.New(saslauth.Config{Storage: driver, CRAMMD5: true, OAuthBearer: true, JWT: jwt})
+	})
+
+	client := dialTest(t, addr, false)
+	for _, mech := range []string{sasl.Plain, saslauth.CRAMMD5, sasl.OAuthBearer} {
+		if !client.SupportsAuth(mech) {
+			t.Errorf("应该提供 AUTH %s", mech)
+		}
+	}
+	if client.SupportsAuth(saslauth.XOAuth2) {
+		t.Error("未启用的机制不应该提供")
+	}
+
+	// 没有用明文密码登录过时 CRAM-MD5 失败，PLAIN 登录后保存密钥
+	if err := client.Auth(&cramMD5Client{"alice@example.com", "secret"}); smtpCode(err) != 535 {
+		t.Errorf("没有密钥时 CRAM-MD5 应该返回 535, got %v", err)
+	}
+	if err := client.Auth(sasl.NewPlainClient("", "alice@example.com", "secret")); err != nil {
+		t.Fatalf("PLAIN 认证失败: %v", err)
+	}
+	client = dialTest(t, addr, false)
+	if err := client.Auth(&cramMD5Client{"alice@example.com", "secret"}); err != nil {
+		t.Errorf("CRAM-MD5 认证失败: %v", err)
+	}
+
+	token, err := jwt.GenerateToken("alice@example.com", 1, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: "alice@example.com", Token: "invalid"})); err == nil {
+		t.Error("无效的令牌不应该认证成功")
+	}
+	if err := client.Auth(sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: "alice@example.com", Token: token})); err != nil {
+		t.Errorf("OAUTHBEARER 认证失败: %v", err)
+	}
+}
+
+func TestRequireTLSBeforeMail(t *testing.T) {
+	addr := startTestServer(t, false, true)
+
+	// 未 STARTTLS 时拒绝 MAIL FROM
+	client := dialTest(t, addr, false)
+	if err := client.Mail("sender@example.com", nil); smtpCode(err) != 530 {
+		t.Errorf("未加密时 MAIL FROM 应该返回 530, got %v", err)
+	}
+
+	// STARTTLS 之后允许
+	client = dialTest(t, addr, true)
+	if err := client.Mail("sender@example.com", nil); err != nil {
+		t.Errorf("加密后 MAIL FROM 失败: %v", err)
+	}
+}
+
+// rawCommand 在明文连接上发送一条命令，返回响应码
+func rawCommand(t *testing.T, addr, cmd string) int {
+	t.Helper()
+
+	conn, err := textproto.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, _, err := conn.ReadResponse(220); err != nil {
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if err := conn.PrintfLine("EHLO client.test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.ReadResponse(250); err != nil {
+		t.Fatalf("EHLO 失败: %v", err)
+	}
+	if err := conn.PrintfLine("%s", cmd); err != nil {
+		t.Fatal(err)
+	}
+	code, _, _ := conn.ReadResponse(0)
+	return code
+}
+
+func TestVRFYAndEXPNDoNotLeakUsers(t *testing.T) {
+	addr := startTestServer(t, false, false)
+
+	if code := rawCommand(t, addr, "VRFY user@example.com"); code != 252 {
+		t.Errorf("VRFY 应该返回 252, got %d", code)
+	}
+	if code := rawCommand(t, addr, "EXPN staff@example.com"); code != 252 {
+		t.Errorf("EXPN 应该返回 252, got %d", code)
+	}
+
+	// 认证后同样不泄露
+	client := dialTest(t, addr, true)
+	if err := client.Auth(sasl.NewPlainClient("", "admin@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.Verify("user@example.com"); smtpCode(err) != 252 {
+		t.Errorf("认证后 VRFY 应该返回 252, got %v", err)
+	}
+}
+
+// adminAuthenticator 测试认证：admin@ 开头的用户是管理员
+type adminAuthenticator struct{}
+
+func (adminAuthenticator) Authenticate(ctx context.Context, username, password string) (*storage.User, error) {
+	user, err := mockAuthenticator{}.Authenticate(ctx, username, password)
+	if err == nil {
+		user.IsAdmin = strings.HasPrefix(username, "admin@")
+	}
+	return user, err
+}
+
+func TestVRFYAdminPolicy(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "bob@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateAlias(ctx, &storage.Alias{From: "team@example.com", Destinations: []string{"bob@example.com", "carol@remote.test"}, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Storage = driver
+		cfg.Maildir = maildir
+		cfg.Auth = adminAuthenticator{}
+		cfg.VRFY = VRFYAdmin
+	})
+
+	// login 在明文连接上认证（允许本机明文认证），返回连接
+	login := func(username string) *textproto.Conn {
+		conn, err := textproto.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		if _, _, err := conn.ReadResponse(220); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.PrintfLine("EHLO client.test"); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := conn.ReadResponse(250); err != nil {
+			t.Fatal(err)
+		}
+		if username == "" {
+			return conn
+		}
+		ir := base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00secret"))
+		if err := conn.PrintfLine("AUTH PLAIN %s", ir); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := conn.ReadResponse(235); err != nil {
+			t.Fatalf("认证失败: %v", err)
+		}
+		return conn
+	}
+	command := func(conn *textproto.Conn, cmd string) (int, string) {
+		t.Helper()
+		if err := conn.PrintfLine("%s", cmd); err != nil {
+			t.Fatal(err)
+		}
+		code, msg, _ := conn.ReadResponse(0)
+		return code, msg
+	}
+
+	// 未认证和非管理员用户不确认地址
+	for _, username := range []string{"", "bob@example.com"} {
+		conn := login(username)
+		if code, _ := command(conn, "VRFY bob@example.com"); code != 252 {
+			t.Errorf("%q: VRFY 应该返回 252, got %d", username, code)
+		}
+		if code, _ := command(conn, "EXPN team@example.com"); code != 252 {
+			t.Errorf("%q: EXPN 应该返回 252, got %d", username, code)
+		}
+	}
+
+	// 管理员可以查询地址和展开别名
+	conn := login("admin@example.com")
+	if code, msg := command(conn, "VRFY <bob@example.com>"); code != 250 || !strings.Contains(msg, "<bob@example.com>") {
+		t.Errorf("管理员 VRFY 存在的用户 = %d %q", code, msg)
+	}
+	if code, _ := command(conn, "VRFY nobody@example.com"); code != 550 {
+		t.Errorf("管理员 VRFY 不存在的地址应该返回 550, got %d", code)
+	}
+	if code, msg := command(conn, "EXPN team@example.com"); code != 250 || msg != "2.1.5 <bob@example.com>\n2.1.5 <carol@remote.test>" {
+		t.Errorf("管理员 EXPN 别名 = %d %q", code, msg)
+	}
+
+	// 流水线中的回复保持命令的顺序
+	if _, err := conn.W.WriteString("NOOP\r\nVRFY bob@example.com\r\nNOOP\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.W.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var replies []string
+	for range 3 {
+		_, msg, err := conn.ReadResponse(250)
+		if err != nil {
+			t.Fatalf("流水线回复错误: %v", err)
+		}
+		replies = append(replies, msg)
+	}
+	if strings.Contains(replies[0], "bob") || !strings.Contains(replies[1], "<bob@example.com>") || strings.Contains(replies[2], "bob") {
+		t.Errorf("流水线回复顺序错误: %q", replies)
+	}
+
+	// 邮件内容中以 VRFY 开头的行不被替换
+	if code, _ := command(conn, "MAIL FROM:<admin@example.com>"); code != 250 {
+		t.Fatalf("MAIL FROM 失败: %d", code)
+	}
+	if code, _ := command(conn, "RCPT TO:<bob@example.com>"); code != 250 {
+		t.Fatalf("RCPT TO 失败: %d", code)
+	}
+	if code, _ := command(conn, "DATA"); code != 354 {
+		t.Fatalf("DATA 失败: %d", code)
+	}
+	if code, _ := command(conn, "Subject: hi\r\n\r\nVRFY bob@example.com\r\n."); code != 250 {
+		t.Fatalf("邮件投递失败: %d", code)
+	}
+	mails, err := driver.ListMails(ctx, "bob@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("bob 应该收到 1 封邮件: %d, %v", len(mails), err)
+	}
+}
+
+// newTestStorage 创建包含 example.com 域名的内存存储和临时 Maildir
+func newTestStorage(t *testing.T) (*storage.SQLiteDriver, *storage.Maildir) {
+	t.Helper()
+
+	ctx := context.Background()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return driver, maildir
+}
+
+func TestListenerHostnameAndBanner(t *testing.T) {
+	driver, maildir := newTestStorage(t)
+
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Banner = "Default Mail"
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.Listeners = map[int]ListenerConfig{port: {Hostname: "mail.brand-a.test", Banner: "Brand A"}}
+	})
+
+	// 横幅使用监听端口的主机名和横幅文本
+	conn, err := textproto.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, msg, err := conn.ReadResponse(220)
+	conn.Close()
+	if err != nil {
+		t.Fatalf("读取欢迎信息失败: %v", err)
+	}
+	if !strings.HasPrefix(msg, "mail.brand-a.test Brand A ESMTP") {
+		t.Errorf("欢迎信息 = %q", msg)
+	}
+
+	// Received 头使用相同的主机名
+	client := dialTest(t, addr, true)
+	if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.SendMail("user@example.com", []string{"rcpt@example.com"},
+		strings.NewReader("From: user@example.com\r\nSubject: test\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(maildir.GetUserMaildir("rcpt@example.com"), "new", "*"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("未找到投递的邮件: %v", err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 最终投递时在 Received 头之前添加 Return-Path 和 Delivered-To
+	if !strings.HasPrefix(string(data), "Return-Path: <user@example.com>\r\nDelivered-To: rcpt@example.com\r\n"+
+		"Received: from client.test ([127.0.0.1])\r\n\tby mail.brand-a.test with ESMTPSA id ") {
+		t.Errorf("投递的邮件头不正确: %q", string(data))
+	}
+	for _, want := range []string{"\r\n\t(using TLS 1.3 with cipher ", "\r\n\tfor <rcpt@example.com>; "} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Received 头缺少 %q: %q", want, string(data))
+		}
+	}
+}
+
+func TestMailLoops(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	// 跳数过多的邮件被拒收
+	hops := strings.Repeat("Received: from relay.test by relay.test; Mon, 1 Jan 2024 00:00:00 +0000\r\n", maxReceivedHops)
+	client := dialTest(t, addr, false)
+	err := client.SendMail("sender@remote.test", []string{"alice@example.com"},
+		strings.NewReader(hops+"From: sender@remote.test\r\nSubject: loop\r\n\r\nhello\r\n"))
+	if smtpCode(err) != 554 {
+		t.Errorf("跳数过多应该返回 554, got %v", err)
+	}
+
+	// 已经投递给 alice 的邮件（转发回来）不再投递给 alice，其他收件人正常投递
+	client = dialTest(t, addr, false)
+	if err := client.SendMail("sender@remote.test", []string{"alice@example.com", "bob@example.com"},
+		strings.NewReader("Delivered-To: Alice@example.com\r\nFrom: sender@remote.test\r\nSubject: loop\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("部分收件人投递成功时应该返回成功: %v", err)
+	}
+	for email, want := range map[string]int{"alice@example.com": 0, "bob@example.com": 1} {
+		mails, err := driver.ListMails(ctx, email, "INBOX", 10, 0)
+		if err != nil || len(mails) != want {
+			t.Errorf("%s 收件箱邮件数 = %d, want %d (err = %v)", email, len(mails), want, err)
+		}
+	}
+
+	client = dialTest(t, addr, false)
+	err = client.SendMail("sender@remote.test", []string{"alice@example.com"},
+		strings.NewReader("Delivered-To: alice@example.com\r\nFrom: sender@remote.test\r\nSubject: loop\r\n\r\nhello\r\n"))
+	if smtpCode(err) != 554 {
+		t.Errorf("唯一的收件人形成循环时应该返回 554, got %v", err)
+	}
+}
+
+func TestEarlyTalker(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		greet, err := antispam.NewGreetDelay(100*time.Millisecond, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Listeners = map[int]ListenerConfig{port: {GreetDelay: greet}}
+	})
+
+	// 正常等待横幅的客户端不受影响
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("sender@remote.test", []string{"alice@example.com"},
+		strings.NewReader("From: sender@remote.test\r\nSubject: normal\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	// 在横幅之前发送 EHLO 的客户端：邮件照常接收，但标记为垃圾邮件
+	conn, err := textproto.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	steps := []struct {
+		cmd  string
+		code int
+	}{
+		{"EHLO spammer.test", 220},
+		{"", 250},
+		{"MAIL FROM:<sender@remote.test>", 250},
+		{"RCPT TO:<alice@example.com>", 250},
+		{"DATA", 354},
+		{"From: sender@remote.test\r\nSubject: early\r\n\r\nhello\r\n.", 250},
+	}
+	for _, step := range steps {
+		if step.cmd != "" {
+			if err := conn.PrintfLine("%s", step.cmd); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, _, err := conn.ReadResponse(step.code); err != nil {
+			t.Fatalf("%q: %v", step.cmd, err)
+		}
+	}
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 2 {
+		t.Fatalf("收件箱应该有 2 封邮件: %d, %v", len(mails), err)
+	}
+	for _, mail := range mails {
+		junk := slices.Contains(mail.Flags, antispam.JunkKeyword)
+		if junk != (mail.Subject == "early") {
+			t.Errorf("%s: $Junk = %v", mail.Subject, junk)
+		}
+		data, err := maildir.ReadMail("alice@example.com", "INBOX", mail.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tagged := strings.Contains(string(data), "X-Spam-Early-Talker: yes"); tagged != (mail.Subject == "early") {
+			t.Errorf("%s: X-Spam-Early-Talker = %v", mail.Subject, tagged)
+		}
+	}
+}
+
+func TestRspamdDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rspamd：按主题返回动作
+	var mu sync.Mutex
+	var checked []string
+	rspamd := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		checked = append(checked, r.Header.Get("Rcpt"))
+		mu.Unlock()
+		action, score := "no action", 1.0
+		switch {
+		case strings.Contains(string(body), "Subject: spam"):
+			action, score = "add header", 8
+		case strings.Contains(string(body), "Subject: reject"):
+			action, score = "reject", 20
+		}
+		fmt.Fprintf(w, `{"score": %g, "required_score": 15, "action": %q}`, score, action)
+	}))
+	defer rspamd.Close()
+
+	engine := antispam.NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(antispam.NewRspamdRule(rspamd.URL, time.Second))
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.AntiSpam = engine
+	})
+
+	send := func(subject string) error {
+		client := dialTest(t, addr, false)
+		return client.SendMail("sender@remote.test", []string{"alice@example.com"},
+			strings.NewReader("From: sender@remote.test\r\nSubject: "+subject+"\r\n\r\nhello\r\n"))
+	}
+	if err := send("normal"); err != nil {
+		t.Fatalf("发送正常邮件失败: %v", err)
+	}
+	if err := send("spam"); err != nil {
+		t.Fatalf("发送疑似垃圾邮件失败: %v", err)
+	}
+	if err := send("reject"); smtpCode(err) != 550 {
+		t.Errorf("Rspamd 拒绝的邮件应该返回 550, got %v", err)
+	}
+	mu.Lock()
+	if len(checked) != 3 || checked[0] != "alice@example.com" {
+		t.Errorf("Rspamd 检查的收件人 = %v", checked)
+	}
+	mu.Unlock()
+
+	// 认证用户提交的邮件不检查
+	client := dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "alice@example.com", "secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SendMail("alice@example.com", []string{"alice@example.com"},
+		strings.NewReader("From: alice@example.com\r\nSubject: reject\r\n\r\nnote to self\r\n")); err != nil {
+		t.Fatalf("认证用户发送失败: %v", err)
+	}
+	mu.Lock()
+	if len(checked) != 3 {
+		t.Errorf("认证用户提交的邮件不应该提交给 Rspamd")
+	}
+	mu.Unlock()
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 3 {
+		t.Fatalf("收件箱应该有 3 封邮件: %d, %v", len(mails), err)
+	}
+	for _, mail := range mails {
+		data, err := maildir.ReadMail("alice@example.com", "INBOX", mail.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		junk := slices.Contains(mail.Flags, antispam.JunkKeyword)
+		switch mail.Subject {
+		case "normal":
+			if junk || !strings.Contains(string(data), "X-Spam: No\r\n") || !strings.Contains(string(data), "X-Spam-Score: 1.00\r\n") {
+				t.Errorf("正常邮件: $Junk = %v\n%s", junk, data)
+			}
+		case "spam":
+			if !junk || !strings.Contains(string(data), "X-Spam: Yes\r\n") {
+				t.Errorf("疑似垃圾邮件: $Junk = %v\n%s", junk, data)
+			}
+		case "reject":
+			if junk || strings.Contains(string(data), "X-Spam") {
+				t.Errorf("认证用户提交的邮件: $Junk = %v\n%s", junk, data)
+			}
+		}
+	}
+}
+
+func TestFingerprintDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	offer := func(to string) string {
+		return "From: promo@spam.test\r\nTo: " + to + "\r\nSubject: Limited offer\r\n\r\n" +
+			"Dear " + to + ",\r\n\r\nCongratulations! You have been selected to receive an exclusive reward.\r\n" +
+			"Claim your prize now at https://promo.example/claim before the offer expires.\r\n"
+	}
+
+	// 其他用户投诉过的垃圾邮件活动
+	fingerprint := antispam.NewFingerprintRule(driver, 0, 0)
+	fingerprint.ReportComplaint(ctx, "bob@example.com", []byte(offer("bob@example.com")))
+
+	engine := antispam.NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(fingerprint)
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.AntiSpam = engine
+	})
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("promo@spam.test", []string{"alice@example.com"}, strings.NewReader(offer("alice@example.com"))); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("收件箱应该有 1 封邮件: %d, %v", len(mails), err)
+	}
+	data, err := maildir.ReadMail("alice@example.com", "INBOX", mails[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 相似内容提高分数
+	if !strings.Contains(string(data), "X-Spam-Fingerprint: ") || !strings.Contains(string(data), "X-Spam-Status: No, score=20\r\n") {
+		t.Errorf("命中指纹的邮件应该带 X-Spam-Fingerprint 头和分数:\n%s", data)
+	}
+}
+
+func TestBayesDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	offer := func(id int) string {
+		return fmt.Sprintf("From: promo@spam.test\r\nSubject: Limited offer %d\r\n\r\n"+
+			"Congratulations! You have been selected to receive an exclusive reward.\r\n"+
+			"Claim your prize now at https://promo.example/claim before the offer expires.\r\n", id)
+	}
+	minutes := func(id int) string {
+		return fmt.Sprintf("From: bob@team.test\r\nSubject: Meeting minutes %d\r\n\r\n"+
+			"Hi team, attached are the minutes from the planning meeting. Please review the action items\r\n"+
+			"and the release schedule before Friday.\r\n", id)
+	}
+
+	// alice 把活动邮件标记为垃圾邮件，把会议纪要标记为正常邮件
+	bayes := antispam.NewBayes(driver, 3)
+	for i := range 3 {
+		if err := bayes.Train(ctx, "alice@example.com", []byte(offer(i)), true); err != nil {
+			t.Fatal(err)
+		}
+		if err := bayes.Train(ctx, "alice@example.com", []byte(minutes(i)), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	engine := antispam.NewEngine(nil, nil, nil, nil, nil)
+	engine.AddRule(bayes)
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.AntiSpam = engine
+	})
+	for _, data := range []string{offer(99), minutes(99)} {
+		client := dialTest(t, addr, false)
+		if err := client.SendMail("sender@remote.test", []string{"alice@example.com"}, strings.NewReader(data)); err != nil {
+			t.Fatalf("发送邮件失败: %v", err)
+		}
+	}
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 2 {
+		t.Fatalf("收件箱应该有 2 封邮件: %d, %v", len(mails), err)
+	}
+	for _, mail := range mails {
+		data, err := maildir.ReadMail("alice@example.com", "INBOX", mail.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := mailparse.ParseHeader(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, junk := msg.Header.Get("X-Spam-Status"), slices.Contains(mail.Flags, antispam.JunkKeyword)
+		var score int
+		_, value, _ := strings.Cut(status, "score=")
+		if _, err := fmt.Sscanf(value, "%d", &score); err != nil {
+			t.Fatalf("%s: X-Spam-Status = %q", mail.Subject, status)
+		}
+		if msg.Header.Get("X-Spam-Bayes") == "" {
+			t.Errorf("%s: 缺少 X-Spam-Bayes 头", mail.Subject)
+		}
+		// 与训练过的垃圾邮件相似的邮件分数升高，标记为垃圾邮件
+		if spam := strings.HasPrefix(mail.Subject, "Limited offer"); spam && (score < 50 || !junk) {
+			t.Errorf("%s: score = %d, $Junk = %v, want >= 50 并标记为垃圾邮件", mail.Subject, score, junk)
+		} else if !spam && (score >= 0 || junk) {
+			t.Errorf("%s: score = %d, $Junk = %v, want < 0", mail.Subject, score, junk)
+		}
+	}
+}
+
+func TestInboundListenerSettings(t *testing.T) {
+	driver, maildir := newTestStorage(t)
+
+	// 接收端口：不提供 AUTH 和 STARTTLS，邮件大小使用端口自己的上限
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.MaxSize = 1024 * 1024
+		cfg.Listeners = map[int]ListenerConfig{port: {MaxSize: 200, DisableTLS: true, DisableAuth: true}}
+	})
+
+	client := dialTest(t, addr, false)
+	if ok, _ := client.Extension("AUTH"); ok {
+		t.Error("接收端口不应该提供 AUTH")
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		t.Error("关闭 TLS 的端口不应该提供 STARTTLS")
+	}
+	if ok, size := client.Extension("SIZE"); !ok || size != "200" {
+		t.Errorf("SIZE = %q，应该使用端口的上限", size)
+	}
+	if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "secret")); err == nil {
+		t.Error("接收端口上的认证应该失败")
+	}
+
+	err := client.SendMail("sender@remote.test", []string{"rcpt@example.com"},
+		strings.NewReader("From: sender@remote.test\r\nSubject: big\r\n\r\n"+strings.Repeat("x", 500)+"\r\n"))
+	if code := smtpCode(err); code != 552 {
+		t.Errorf("超过端口大小上限的邮件应该返回 552，实际 %v", err)
+	}
+}
+
+func TestUntrustedSourceForgedAuthResultsStripped(t *testing.T) {
+	driver, maildir := newTestStorage(t)
+	upstream, err := antispam.NewTrustedUpstream([]string{"192.0.2.10"}, []string{"gw.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.Listeners = map[int]ListenerConfig{port: {Upstream: upstream}}
+	})
+
+	// 来自 127.0.0.1（不是上游网关）的邮件中带有网关 authserv-id 的验证结果是伪造的
+	client := dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "user@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.SendMail("user@example.com", []string{"rcpt@example.com"},
+		strings.NewReader("Authentication-Results: gw.example.com;\r\n\tspf=pass smtp.mailfrom=bank.test\r\n"+
+			"Authentication-Results: mx.other.test; spf=fail\r\n"+
+			"From: user@example.com\r\nSubject: test\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(maildir.GetUserMaildir("rcpt@example.com"), "new", "*"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("未找到投递的邮件: %v", err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "gw.example.com") {
+		t.Errorf("伪造的网关验证结果应该被删除: %q", string(data))
+	}
+	if !strings.Contains(string(data), "Authentication-Results: mx.other.test; spf=fail\r\n") {
+		t.Errorf("其他主机的验证结果应该保留: %q", string(data))
+	}
+}
+
+func TestAliasDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour)
+	for _, a := range []*storage.Alias{
+		{From: "shop-ab12@example.com", To: "alice@example.com", Domain: "example.com", Disposable: true, ExpiresAt: &expiresAt},
+		{From: "old-cd34@example.com", To: "alice@example.com", Domain: "example.com", Disposable: true, ExpiresAt: &expiresAt},
+	} {
+		if err := driver.CreateAlias(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := driver.BurnAlias(ctx, "old-cd34@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	// 作废的别名被拒收
+	client := dialTest(t, addr, false)
+	if err := client.Mail("shop@store.test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rcpt("old-cd34@example.com", nil); smtpCode(err) != 550 {
+		t.Errorf("作废的别名应该返回 550, got %v", err)
+	}
+
+	// 有效的别名投递到目标用户并计数
+	client = dialTest(t, addr, false)
+	if err := client.SendMail("shop@store.test", []string{"shop-ab12@example.com"},
+		strings.NewReader("From: shop@store.test\r\nSubject: welcome\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Errorf("别名邮件应该投递到 alice 的收件箱, got %d, err = %v", len(mails), err)
+	}
+	a, err := driver.GetAlias(ctx, "shop-ab12@example.com")
+	if err != nil || a.ReceivedCount != 1 {
+		t.Errorf("别名接收计数应该为 1: %+v, err = %v", a, err)
+	}
+}
+
+func TestAliasMultipleDestinations(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := driver.CreateAlias(ctx, &storage.Alias{From: "team@example.com", Destinations: []string{"alice@example.com", "bob@example.com"}, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("shop@store.test", []string{"team@example.com"},
+		strings.NewReader("From: shop@store.test\r\nSubject: hi team\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		mails, err := driver.ListMails(ctx, email, "INBOX", 10, 0)
+		if err != nil || len(mails) != 1 {
+			t.Errorf("别名邮件应该投递到 %s 的收件箱, got %d, err = %v", email, len(mails), err)
+		}
+	}
+}
+
+func TestAliasFanOutDeduplication(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, a := range []*storage.Alias{
+		{From: "team@example.com", Destinations: []string{"alice@example.com", "bob@example.com"}, Domain: "example.com"},
+		{From: "info@example.com", To: "alice@example.com", Domain: "example.com"},
+	} {
+		if err := driver.CreateAlias(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	// alice 同时通过本人地址和两个别名收到，bob 只通过 team 别名收到
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("shop@store.test", []string{"alice@example.com", "team@example.com", "Info@example.com"},
+		strings.NewReader("From: shop@store.test\r\nSubject: hi\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	for email, originals := range map[string][]string{
+		"alice@example.com": {"alice@example.com", "team@example.com", "Info@example.com"},
+		"bob@example.com":   {"team@example.com"},
+	} {
+		mails, err := driver.ListMails(ctx, email, "INBOX", 10, 0)
+		if err != nil || len(mails) != 1 {
+			t.Fatalf("%s 应该只收到一份邮件, got %d, err = %v", email, len(mails), err)
+		}
+		data, err := maildir.ReadMail(email, "INBOX", mails[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := mailparse.ParseHeader(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := msg.Header.Values("X-Original-To"); !slices.Equal(got, originals) {
+			t.Errorf("%s 的 X-Original-To = %v, want %v", email, got, originals)
+		}
+	}
+}
+
+func TestDeliveryDecodesHeaders(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("zhang@store.test", []string{"alice@example.com"}, strings.NewReader(
+		"From: =?UTF-8?B?5byg5LiJ?= <zhang@store.test>\r\n"+
+			"To: Alice <alice@example.com>, bob@example.com\r\n"+
+			"Subject: =?UTF-8?Q?=E4=BD=A0=E5=A5=BD?=\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("应该投递一封邮件, got %d, err = %v", len(mails), err)
+	}
+	m := mails[0]
+	if m.Subject != "你好" || m.From != "张三 <zhang@store.test>" {
+		t.Errorf("邮件头应该解码: subject = %q, from = %q", m.Subject, m.From)
+	}
+	if len(m.To) != 2 || m.To[0] != "alice@example.com" || m.To[1] != "bob@example.com" {
+		t.Errorf("收件人应该拆分为地址列表: %v", m.To)
+	}
+}
+
+// fakeSender 记录外发的邮件
+type fakeSender struct {
+	from string
+	to   []string
+	err  error
+}
+
+func (f *fakeSender) Send(ctx context.Context, from string, to []string, data []byte) error {
+	f.from, f.to = from, to
+	return f.err
+}
+
+func TestSubmission(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := driver.CreateAlias(ctx, &storage.Alias{From: "sales@example.com", To: "alice@example.com", Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &fakeSender{}
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.SubmissionPorts = []int{port}
+		cfg.Outbound = sender
+	})
+
+	// 未认证时拒绝 MAIL FROM
+	client := dialTest(t, addr, false)
+	if err := client.Mail("alice@example.com", nil); smtpCode(err) != 530 {
+		t.Errorf("未认证时 MAIL FROM 应该返回 530, got %v", err)
+	}
+
+	// 发件人必须是认证用户本人或其别名
+	client = dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "alice@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.Mail("bob@example.com", nil); smtpCode(err) != 553 {
+		t.Errorf("冒用他人地址应该返回 553, got %v", err)
+	}
+	if err := client.Mail("Sales@example.com", nil); err != nil {
+		t.Errorf("使用自己的别名发信失败: %v", err)
+	}
+	if err := client.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 外部收件人经外发路径，本地收件人直接投递
+	if err := client.SendMail("alice@example.com", []string{"friend@remote.test", "bob@example.com"},
+		strings.NewReader("From: alice@example.com\r\nSubject: hi\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	if sender.from != "alice@example.com" || len(sender.to) != 1 || sender.to[0] != "friend@remote.test" {
+		t.Errorf("外发不正确: from=%s to=%v", sender.from, sender.to)
+	}
+	mails, err := driver.ListMails(ctx, "bob@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Errorf("本地收件人应该收到邮件, got %d, err = %v", len(mails), err)
+	}
+
+	// 外发失败时返回临时错误，本地收件人不投递
+	sender.err = fmt.Errorf("连接失败")
+	err = client.SendMail("alice@example.com", []string{"friend@remote.test", "bob@example.com"},
+		strings.NewReader("From: alice@example.com\r\nSubject: again\r\n\r\nhello\r\n"))
+	if smtpCode(err) != 451 {
+		t.Errorf("外发失败应该返回 451, got %v", err)
+	}
+	if mails, _ := driver.ListMails(ctx, "bob@example.com", "INBOX", 10, 0); len(mails) != 1 {
+		t.Errorf("外发失败时不应该投递本地副本, got %d", len(mails))
+	}
+}
+
+func TestSubmissionReceipts(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	domain, err := driver.GetDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain.Receipts = storage.ReceiptsRead
+	if err := driver.UpdateDomain(ctx, domain); err != nil {
+		t.Fatal(err)
+	}
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 客户端在发送前已经保存的已发送邮件
+	raw := "From: alice@example.com\r\nTo: bob@example.com\r\nMessage-ID: <m1@example.com>\r\nSubject: hi\r\n\r\nhello\r\n"
+	sent := &storage.Mail{UserEmail: "alice@example.com", Folder: "Sent", From: "alice@example.com", To: []string{"bob@example.com"}, ReceivedAt: time.Now()}
+	if err := storage.NewMailStore(maildir, driver).Deliver(ctx, sent, []byte(raw)); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.SubmissionPorts = []int{port}
+	})
+	client := dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "alice@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.SendMail("alice@example.com", []string{"bob@example.com"}, strings.NewReader(raw)); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	// 投递给本地收件人时记录回执，首次设置 \Seen 时记录阅读时间
+	mails, err := driver.ListMails(ctx, "bob@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("本地收件人应该收到邮件, got %d, err = %v", len(mails), err)
+	}
+	if err := driver.UpdateMailFlags(ctx, mails[0].ID, []string{"\\Seen"}); err != nil {
+		t.Fatal(err)
+	}
+	receipts, err := driver.ListMailReceipts(ctx, sent.ID)
+	if err != nil || len(receipts) != 1 {
+		t.Fatalf("ListMailReceipts() = %+v, %v", receipts, err)
+	}
+	if receipts[0].Recipient != "bob@example.com" || receipts[0].MailID != mails[0].ID || receipts[0].ReadAt == nil {
+		t.Errorf("回执 = %+v", receipts[0])
+	}
+}
+
+func TestSubmissionWithIdentity(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	identities := &identity.Manager{Storage: driver, Passphrase: "test"}
+	password, err := identities.EncryptPassword("app-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 127.0.0.1 不是公网地址，经发件身份提交时连接被拒绝
+	if err := driver.CreateIdentity(ctx, &storage.Identity{
+		UserEmail: "alice@example.com", Address: "alice@gmail.com", SMTPHost: "127.0.0.1", SMTPPort: 587,
+		SMTPUsername: "alice@gmail.com", SMTPPassword: password,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &fakeSender{}
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.SubmissionPorts = []int{port}
+		cfg.Outbound = sender
+		cfg.Identities = identities
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "alice@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.Mail("bob@gmail.com", nil); smtpCode(err) != 553 {
+		t.Errorf("未配置的外部地址应该返回 553, got %v", err)
+	}
+
+	// 外部收件人经发件身份的 SMTP 服务器提交，不走默认外发路径
+	err = client.SendMail("Alice@Gmail.com", []string{"friend@remote.test"},
+		strings.NewReader("From: alice@gmail.com\r\nSubject: hi\r\n\r\nhello\r\n"))
+	if smtpCode(err) != 451 {
+		t.Errorf("发件身份提交失败应该返回 451, got %v", err)
+	}
+	if sender.from != "" {
+		t.Errorf("使用发件身份时不应该走默认外发路径, got from=%s", sender.from)
+	}
+}
+
+func TestSubmissionWithoutOutbound(t *testing.T) {
+	driver, maildir := newTestStorage(t)
+	addr := startTestServer(t, true, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.SubmissionPorts = []int{port}
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.Auth(sasl.NewPlainClient("", "alice@example.com", "secret")); err != nil {
+		t.Fatalf("认证失败: %v", err)
+	}
+	if err := client.Mail("alice@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rcpt("friend@remote.test", nil); smtpCode(err) != 550 {
+		t.Errorf("没有外发路径时外部收件人应该返回 550, got %v", err)
+	}
+}
+
+func TestNewsletterDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := driver.SaveNewsletterSettings(ctx, &storage.NewsletterSettings{UserEmail: "bob@example.com", Folder: "Newsletters"}); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("news@shop.test", []string{"alice@example.com", "bob@example.com"},
+		strings.NewReader("From: news@shop.test\r\nSubject: deals\r\n"+
+			"List-Unsubscribe: <https://shop.test/u/1>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	// 未设置归档文件夹时留在收件箱，并标记订阅邮件关键字
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("alice 应该在收件箱收到邮件, got %d, err = %v", len(mails), err)
+	}
+	hasKeyword := false
+	for _, f := range mails[0].Flags {
+		hasKeyword = hasKeyword || f == "$Newsletter"
+	}
+	if !hasKeyword {
+		t.Errorf("订阅邮件应该标记 $Newsletter: %v", mails[0].Flags)
+	}
+	u, err := driver.GetUnsubscribe(ctx, mails[0].ID)
+	if err != nil || !u.OneClick || u.URL != "https://shop.test/u/1" {
+		t.Errorf("退订信息不正确: %+v, err = %v", u, err)
+	}
+
+	// 设置了归档文件夹时投递到该文件夹
+	mails, err = driver.ListMails(ctx, "bob@example.com", "Newsletters", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Errorf("bob 的订阅邮件应该归档到 Newsletters, got %d, err = %v", len(mails), err)
+	}
+}
+
+func TestBounceReportDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.Bounces = &bounce.Recorder{Storage: driver}
+	})
+
+	report := "From: MAILER-DAEMON@mx.remote.test\r\nTo: alice@example.com\r\nSubject: Undelivered Mail\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: multipart/report; report-type=delivery-status; boundary=b1\r\n\r\n" +
+		"--b1\r\nContent-Type: text/plain\r\n\r\nfailed\r\n" +
+		"--b1\r\nContent-Type: message/delivery-status\r\n\r\nReporting-MTA: dns; mx.remote.test\r\n\r\n" +
+		"Final-Recipient: rfc822; friend@remote.test\r\nAction: failed\r\nStatus: 5.2.2\r\n\r\n" +
+		"--b1--\r\n"
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("", []string{"alice@example.com"}, strings.NewReader(report)); err != nil {
+		t.Fatalf("发送退信报告失败: %v", err)
+	}
+
+	// 退信报告照常投递，并记录被退回的收件人
+	if mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0); err != nil || len(mails) != 1 {
+		t.Errorf("退信报告应该投递到收件箱, got %d, err = %v", len(mails), err)
+	}
+	stats, err := driver.GetBounceStats(ctx, time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Total != 1 || stats.Categories["mailbox_full"] != 1 || stats.Senders[0].Key != "alice@example.com" || stats.Domains[0].Key != "remote.test" {
+		t.Errorf("退信统计 = %+v", stats)
+	}
+}
+
+func TestBATVBounces(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := batv.NewSigner([]batv.Key{{ID: 1, Secret: []byte("secret")}}, []string{"example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.BATV = signer
+	})
+
+	// 发往未签名地址或签名无效地址的退信被拒收
+	client := dialTest(t, addr, false)
+	if err := client.Mail("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rcpt("alice@example.com", nil); smtpCode(err) != 550 {
+		t.Errorf("发往未签名地址的退信应该返回 550, got %v", err)
+	}
+	if err := client.Rcpt("prvs=1000abcdef=alice@example.com", nil); smtpCode(err) != 550 {
+		t.Errorf("签名无效的退信应该返回 550, got %v", err)
+	}
+
+	// 发往签名地址的退信和普通邮件投递到原地址
+	report := "From: MAILER-DAEMON@mx.remote.test\r\nSubject: Undelivered Mail\r\n\r\nfailed\r\n"
+	if err := dialTest(t, addr, false).SendMail("", []string{signer.Sign("alice@example.com")}, strings.NewReader(report)); err != nil {
+		t.Fatalf("发往签名地址的退信应该接收: %v", err)
+	}
+	if err := dialTest(t, addr, false).SendMail("bob@remote.test", []string{"alice@example.com"},
+		strings.NewReader("From: bob@remote.test\r\nSubject: hi\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("普通邮件不需要签名地址: %v", err)
+	}
+	if mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0); err != nil || len(mails) != 2 {
+		t.Errorf("alice 收件箱邮件数 = %d，期望 2, err = %v", len(mails), err)
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.DeliveryLog = deliverylog.New(driver)
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.SendMail("sender@remote.test", []string{"alice@example.com"},
+		strings.NewReader("From: sender@remote.test\r\nMessage-ID: <trace-1@remote.test>\r\nSubject: hi\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	// 接收和投递到文件夹的记录使用同一个队列 ID，可以按 Message-ID 追踪
+	events, err := driver.GetDeliveryTrace(ctx, "trace-1@remote.test")
+	if err != nil {
+		t.Fatalf("追踪邮件失败: %v", err)
+	}
+	if len(events) != 2 || events[0].Event != storage.DeliveryReceived || events[1].Event != storage.DeliveryDelivered {
+		t.Fatalf("投递日志 = %d 条", len(events))
+	}
+	if events[0].QueueID == "" || events[0].QueueID != events[1].QueueID || events[1].Folder != "INBOX" || events[0].Code != 250 {
+		t.Errorf("投递日志不正确: %+v %+v", events[0], events[1])
+	}
+}
+
+func TestAutoReplyDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.SaveAutoResponder(ctx, &storage.AutoResponder{UserEmail: "alice@example.com", Enabled: true, Body: "休假中"}); err != nil {
+		t.Fatal(err)
+	}
+
+	outbound := &fakeSender{}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+		cfg.AutoResponder = &autoreply.Responder{Storage: driver, Outbound: outbound}
+	})
+
+	send := func(from, data string) {
+		t.Helper()
+		client := dialTest(t, addr, false)
+		if err := client.SendMail(from, []string{"alice@example.com"}, strings.NewReader(data)); err != nil {
+			t.Fatalf("发送邮件失败: %v", err)
+		}
+	}
+	mail := "From: bob@example.net\r\nTo: alice@example.com\r\nSubject: hi\r\n\r\nhello\r\n"
+
+	// 投递后以空信封发件人回复
+	send("bob@example.net", mail)
+	if len(outbound.to) != 1 || outbound.to[0] != "bob@example.net" || outbound.from != "" {
+		t.Fatalf("应该向发件人发送自动回复: from=%q to=%v", outbound.from, outbound.to)
+	}
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Fatalf("邮件应该正常投递, got %d, err = %v", len(mails), err)
+	}
+
+	// 同一发件人在间隔内不再回复，邮件列表和退信不回复
+	outbound.to = nil
+	send("bob@example.net", mail)
+	send("dev-owner@lists.example.net", "From: dev@lists.example.net\r\nTo: alice@example.com\r\nList-Id: <dev.lists.example.net>\r\n\r\nhello\r\n")
+	send("", "From: MAILER-DAEMON@example.net\r\nTo: alice@example.com\r\n\r\nbounce\r\n")
+	if outbound.to != nil {
+		t.Errorf("不应该发送自动回复: %v", outbound.to)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Quota: 200, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	// 超出配额的邮件在 DATA 阶段拒收
+	client := dialTest(t, addr, false)
+	large := "From: bob@example.net\r\nTo: alice@example.com\r\n\r\n" + strings.Repeat("x", 300) + "\r\n"
+	if err := client.SendMail("bob@example.net", []string{"alice@example.com"}, strings.NewReader(large)); smtpCode(err) != 552 {
+		t.Errorf("超出配额的邮件应该返回 552, got %v", err)
+	}
+
+	// 配额已用尽后在 RCPT TO 阶段拒收
+	if err := driver.UpdateQuota(ctx, "alice@example.com", &storage.Quota{Limit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.StoreMail(ctx, &storage.Mail{ID: "m1", UserEmail: "alice@example.com", Folder: "INBOX", Size: 10, ReceivedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	client = dialTest(t, addr, false)
+	if err := client.Mail("bob@example.net", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rcpt("alice@example.com", nil); smtpCode(err) != 552 {
+		t.Errorf("配额已用尽的邮箱应该在 RCPT TO 返回 552, got %v", err)
+	}
+}
+
+func TestAliasQuotaExceeded(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &storage.User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := driver.CreateAlias(ctx, &storage.Alias{From: "team@example.com", Destinations: []string{"alice@example.com", "bob@example.com"}, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	// 第二个目标的配额已用尽
+	if err := driver.UpdateQuota(ctx, "bob@example.com", &storage.Quota{Limit: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.StoreMail(ctx, &storage.Mail{ID: "m1", UserEmail: "bob@example.com", Folder: "INBOX", Size: 10, ReceivedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	addr := startTestServer(t, false, false, func(cfg *Config, port int) {
+		cfg.Maildir = maildir
+		cfg.Storage = driver
+	})
+
+	client := dialTest(t, addr, false)
+	if err := client.Mail("shop@store.test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Rcpt("team@example.com", nil); smtpCode(err) != 552 {
+		t.Errorf("别名的任一目标配额已用尽时应该在 RCPT TO 返回 552, got %v", err)
+	}
+	if mails, _ := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0); len(mails) != 0 {
+		t.Errorf("拒收的邮件不应该投递到 alice, got %d", len(mails))
+	}
+}
+
+func TestGracefulStop(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newTestStorage(t)
+	if err := driver.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	server := NewServer(&Config{
+		Enabled:  true,
+		Ports:    []int{port},
+		Hostname: "localhost",
+		Auth:     mockAuthenticator{},
+		Storage:  driver,
+		Maildir:  maildir,
+	})
+	go server.servers[port].Serve(server.conns.Listener(listener)) // #nosec G104 -- 测试服务器，关闭时返回错误
+	addr := listener.Addr().String()
+
+	idle := dialTest(t, addr, false)
+	busy := dialTest(t, addr, false)
+	if err := busy.Mail("bob@remote.test", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := busy.Rcpt("alice@example.com", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		stopped <- server.Stop(stopCtx)
+	}()
+	for !server.conns.Draining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 空闲连接被断开，不再接受新连接
+	if err := idle.Noop(); err == nil {
+		t.Error("停止后空闲连接应该被断开")
+	}
+	if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("停止后不应该接受新连接")
+	}
+
+	// 进行中的邮件事务可以完成
+	select {
+	case err := <-stopped:
+		t.Fatalf("邮件事务完成之前 Stop 不应该返回: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	w, err := busy.Data()
+	if err != nil {
+		t.Fatalf("DATA 失败: %v", err)
+	}
+	if _, err := w.Write([]byte("From: bob@remote.test\r\nSubject: late\r\n\r\nhello\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("停止期间进行中的投递失败: %v", err)
+	}
+
+	if err := <-stopped; err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+	if mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0); err != nil || len(mails) != 1 {
+		t.Errorf("进行中的邮件应该投递成功, got %d, err = %v", len(mails), err)
+	}
+}
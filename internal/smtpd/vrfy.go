@@ -0,0 +1,317 @@
+package smtpd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/forward"
+)
+
+// VRFY/EXPN 策略
+const (
+	// VRFYDeny VRFY 和 EXPN 始终返回 252，不确认地址是否存在（默认）
+	VRFYDeny = "deny"
+	// VRFYAdmin 认证的管理员可以查询地址（VRFY）和展开别名（EXPN），其他会话返回 252
+	VRFYAdmin = "admin"
+)
+
+const (
+	// vrfyDenied 不确认地址时的回复（RFC 5321 3.5.3：不验证但会接收邮件）
+	vrfyDenied = "252 2.5.0 Cannot VRFY user, but will accept message\r\n"
+	// expnDenied 不展开别名时的回复
+	expnDenied = "252 2.5.0 Cannot EXPN list, but will accept message\r\n"
+	// noopReply go-smtp 对 NOOP 的回复（VRFY/EXPN 替换为 NOOP，只替换这个回复）
+	noopReply = "250 2.0.0 I have successfully done nothing\r\n"
+	// maxCommandLine 命令行的最大长度，超过时不再检查该行
+	maxCommandLine = 4096
+)
+
+// vrfyListener 按策略应答 VRFY/EXPN 的监听器
+//
+// go-smtp 直接应答 VRFY（252）和 EXPN（502），不经过 Session，因此在连接层拦截：
+// 客户端的 VRFY/EXPN 命令替换为 NOOP 交给 go-smtp，go-smtp 对它的回复再换成策略的回复，
+// 流水线中的回复顺序保持不变。STARTTLS 之后命令是加密的，不再拦截，由 go-smtp 应答（同样不泄露地址）；
+// 管理员查询因此只能在明文连接上进行（如允许本机明文认证时的本机管理工具）
+type vrfyListener struct {
+	net.Listener
+	backend *Backend
+	policy  string
+}
+
+// Accept 接受连接
+func (l *vrfyListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &vrfyConn{Conn: conn, backend: l.backend, policy: l.policy}, nil
+}
+
+// vrfyConn 拦截 VRFY/EXPN 的连接：读出的命令逐行检查，DATA/BDAT 的邮件内容和 AUTH 的交互原样读出
+type vrfyConn struct {
+	net.Conn
+	backend *Backend
+	policy  string
+
+	mu       sync.Mutex
+	session  *Session      // 连接的会话（NewSession 时设置，用于判断是否为认证的管理员）
+	line     []byte        // 尚未读完的命令行
+	ready    []byte        // 已检查、等待读出的数据
+	written  []byte        // 尚未写完的回复行
+	commands []vrfyCommand // 已交给 go-smtp、等待回复的命令（按顺序，流水线中可以有多个）
+	greeted  bool          // 已写出欢迎横幅（第一个回复不对应命令）
+	midLine  bool          // 当前行过长，剩余部分不检查
+	bdat     int64         // BDAT 块剩余的字节数
+	data     bool          // DATA 邮件内容（直到单独一行的 "."）
+	auth     bool          // AUTH 的 SASL 交互（服务器回复 334 之后）
+	tls      bool          // 已开始 TLS，之后不再拦截
+}
+
+// vrfyCommand 等待回复的命令
+type vrfyCommand struct {
+	verb string
+	arg  string // VRFY/EXPN 的参数（替换为 NOOP，回复时按策略生成 VRFY/EXPN 的回复）
+}
+
+// NetConn 返回包装的连接
+func (c *vrfyConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// Read 读出客户端数据，VRFY/EXPN 命令替换为 NOOP
+func (c *vrfyConn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if len(c.ready) > 0 {
+			n := copy(b, c.ready)
+			c.ready = c.ready[n:]
+			c.mu.Unlock()
+			return n, nil
+		}
+		c.mu.Unlock()
+
+		n, err := c.Conn.Read(b)
+		if n > 0 {
+			c.mu.Lock()
+			c.filter(b[:n])
+			c.mu.Unlock()
+		}
+		if err != nil {
+			c.mu.Lock()
+			// 连接结束时未读完的数据原样交出
+			c.ready = append(c.ready, c.line...)
+			c.line = nil
+			if len(c.ready) > 0 {
+				n := copy(b, c.ready)
+				c.ready = c.ready[n:]
+				c.mu.Unlock()
+				return n, nil
+			}
+			c.mu.Unlock()
+			return 0, err
+		}
+	}
+}
+
+// filter 检查客户端数据，结果追加到 ready
+func (c *vrfyConn) filter(data []byte) {
+	for len(data) > 0 {
+		switch {
+		case c.tls:
+			c.ready = append(c.ready, data...)
+			return
+		case c.bdat > 0:
+			n := min(int64(len(data)), c.bdat)
+			c.ready = append(c.ready, data[:n]...)
+			c.bdat -= n
+			data = data[n:]
+			continue
+		}
+
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			c.line = append(c.line, data...)
+			if len(c.line) > maxCommandLine {
+				if !c.data && !c.auth {
+					c.commands = append(c.commands, vrfyCommand{})
+				}
+				c.ready = append(c.ready, c.line...)
+				c.line = nil
+				c.midLine = true
+			}
+			return
+		}
+		line := append(c.line, data[:end+1]...)
+		c.line = nil
+		data = data[end+1:]
+		if c.midLine {
+			c.midLine = false
+			c.ready = append(c.ready, line...)
+			continue
+		}
+		c.ready = append(c.ready, c.command(line)...)
+	}
+}
+
+// command 检查一行完整的客户端数据，返回交给 go-smtp 的数据
+func (c *vrfyConn) command(line []byte) []byte {
+	if c.data {
+		if text := strings.TrimRight(string(line), "\r\n"); text == "." {
+			c.data = false
+		}
+		return line
+	}
+	if c.auth {
+		return line // SASL 交互
+	}
+
+	verb, arg, _ := strings.Cut(strings.TrimRight(string(line), "\r\n"), " ")
+	cmd := vrfyCommand{verb: strings.ToUpper(verb)}
+	switch cmd.verb {
+	case "VRFY", "EXPN":
+		cmd.arg = strings.TrimSpace(arg)
+		line = []byte("NOOP\r\n")
+	case "BDAT":
+		// 块的内容紧跟在命令之后（go-smtp 拒绝 BDAT 时同样读出丢弃）
+		if fields := strings.Fields(arg); len(fields) > 0 {
+			if size, err := strconv.ParseInt(fields[0], 10, 64); err == nil && size > 0 {
+				c.bdat = size
+			}
+		}
+	}
+	c.commands = append(c.commands, cmd)
+	return line
+}
+
+// Write 写出 go-smtp 的回复，NOOP 的回复换回 VRFY/EXPN 的回复
+func (c *vrfyConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.tls {
+		c.mu.Unlock()
+		return c.Conn.Write(b)
+	}
+	c.written = append(c.written, b...)
+	var out []byte
+	for {
+		end := bytes.IndexByte(c.written, '\n')
+		if end < 0 {
+			break
+		}
+		line := c.written[:end+1]
+		c.written = c.written[end+1:]
+		out = append(out, c.response(line)...)
+	}
+	c.mu.Unlock()
+
+	if len(out) > 0 {
+		if _, err := c.Conn.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// response 检查 go-smtp 的一行回复，返回写给客户端的数据
+func (c *vrfyConn) response(line []byte) []byte {
+	// 多行回复的中间行（"250-..."）不结束命令
+	if len(line) < 4 || line[3] == '-' {
+		return line
+	}
+	if !c.greeted {
+		c.greeted = true
+		return line
+	}
+	if len(c.commands) == 0 {
+		return line
+	}
+	cmd := c.commands[0]
+	code := string(line[:3])
+	if cmd.verb == "AUTH" && code == "334" {
+		c.auth = true
+		return line
+	}
+	c.commands = c.commands[1:]
+	switch cmd.verb {
+	case "AUTH":
+		c.auth = false
+	case "DATA":
+		c.data = code == "354"
+	case "STARTTLS":
+		c.tls = code == "220"
+	}
+	// 回复时生成（流水线中之前的 AUTH 已经处理完）
+	if (cmd.verb == "VRFY" || cmd.verb == "EXPN") && string(line) == noopReply {
+		return []byte(c.reply(cmd.verb, cmd.arg))
+	}
+	return line
+}
+
+// reply 按策略生成 VRFY/EXPN 的回复
+func (c *vrfyConn) reply(verb, arg string) string {
+	denied := vrfyDenied
+	if verb == "EXPN" {
+		denied = expnDenied
+	}
+	if c.policy != VRFYAdmin || c.session == nil || c.session.user == nil || !c.session.user.IsAdmin {
+		return denied
+	}
+
+	address := strings.ToLower(strings.Trim(arg, "<> "))
+	if at := strings.LastIndex(arg, "<"); at >= 0 {
+		address = strings.ToLower(strings.Trim(arg[at:], "<> "))
+	}
+	if !strings.Contains(address, "@") {
+		return "501 5.5.4 Syntax: " + verb + " <address>\r\n"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := c.backend.storage.GetUser(ctx, address); err == nil {
+		return fmt.Sprintf("250 2.1.5 <%s>\r\n", address)
+	}
+	alias, err := c.backend.storage.GetAlias(ctx, address)
+	if err != nil || !alias.Active(time.Now()) {
+		return fmt.Sprintf("550 5.1.1 <%s>: User unknown\r\n", address)
+	}
+	if verb == "VRFY" {
+		return fmt.Sprintf("250 2.1.5 <%s>\r\n", address)
+	}
+	// EXPN 列出别名的全部目标（包括外部地址）
+	mailboxes, external := forward.Destinations(ctx, c.backend.storage, alias)
+	destinations := append(mailboxes, external...)
+	if len(destinations) == 0 {
+		return fmt.Sprintf("250 2.1.5 <%s>\r\n", address)
+	}
+	var b strings.Builder
+	for i, to := range destinations {
+		sep := "-"
+		if i == len(destinations)-1 {
+			sep = " "
+		}
+		fmt.Fprintf(&b, "250%s2.1.5 <%s>\r\n", sep, to)
+	}
+	return b.String()
+}
+
+// setVRFYSession 记录连接的会话（连接经过 vrfyListener 时），VRFY/EXPN 据此判断是否为认证的管理员
+func setVRFYSession(conn net.Conn, s *Session) {
+	for conn != nil {
+		if c, ok := conn.(*vrfyConn); ok {
+			c.mu.Lock()
+			c.session = s
+			c.mu.Unlock()
+			return
+		}
+		wrapped, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return
+		}
+		conn = wrapped.NetConn()
+	}
+}
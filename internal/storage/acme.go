@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SaveACMEAccount 保存 ACME 账户（按邮箱和目录 URL 唯一）
+func (d *SQLiteDriver) SaveACMEAccount(ctx context.Context, account *ACMEAccount) error {
+	query := `
+		INSERT INTO acme_accounts (email, directory_url, account_url, private_key, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(email, directory_url) DO UPDATE SET
+			account_url = excluded.account_url,
+			private_key = excluded.private_key,
+			updated_at = excluded.updated_at
+	`
+	now := utcNow()
+	_, err := d.db.ExecContext(ctx, query,
+		account.Email,
+		account.DirectoryURL,
+		account.AccountURL,
+		account.PrivateKey,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存 ACME 账户失败: %w", err)
+	}
+	return nil
+}
+
+// GetACMEAccount 获取 ACME 账户
+func (d *SQLiteDriver) GetACMEAccount(ctx context.Context, email, directoryURL string) (*ACMEAccount, error) {
+	query := `
+		SELECT id, email, directory_url, COALESCE(account_url, ''), private_key, created_at, updated_at
+		FROM acme_accounts
+		WHERE email = ? AND directory_url = ?
+	`
+	var account ACMEAccount
+	err := d.db.QueryRowContext(ctx, query, email, directoryURL).Scan(
+		&account.ID,
+		&account.Email,
+		&account.DirectoryURL,
+		&account.AccountURL,
+		&account.PrivateKey,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("ACME 账户不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 ACME 账户失败: %w", err)
+	}
+	return &account, nil
+}
+
+// SaveACMEOrder 保存 ACME 订单（每个域名保留最近一次）
+func (d *SQLiteDriver) SaveACMEOrder(ctx context.Context, order *ACMEOrder) error {
+	query := `
+		INSERT INTO acme_orders (domain, order_url, status, error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(domain) DO UPDATE SET
+			order_url = excluded.order_url,
+			status = excluded.status,
+			error = excluded.error,
+			updated_at = excluded.updated_at
+	`
+	now := utcNow()
+	_, err := d.db.ExecContext(ctx, query,
+		order.Domain,
+		order.OrderURL,
+		order.Status,
+		order.Error,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存 ACME 订单失败: %w", err)
+	}
+	return nil
+}
+
+// GetACMEOrder 获取域名最近一次的 ACME 订单
+func (d *SQLiteDriver) GetACMEOrder(ctx context.Context, domain string) (*ACMEOrder, error) {
+	query := `
+		SELECT id, domain, COALESCE(order_url, ''), status, COALESCE(error, ''), created_at, updated_at
+		FROM acme_orders
+		WHERE domain = ?
+	`
+	var order ACMEOrder
+	err := d.db.QueryRowContext(ctx, query, domain).Scan(
+		&order.ID,
+		&order.Domain,
+		&order.OrderURL,
+		&order.Status,
+		&order.Error,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("ACME 订单不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 ACME 订单失败: %w", err)
+	}
+	return &order, nil
+}
+
+// SaveACMECertificate 保存 ACME 证书
+func (d *SQLiteDriver) SaveACMECertificate(ctx context.Context, cert *ACMECertificate) error {
+	query := `
+		INSERT INTO acme_certificates (domain, cert_pem, private_key, not_after, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(domain) DO UPDATE SET
+			cert_pem = excluded.cert_pem,
+			private_key = excluded.private_key,
+			not_after = excluded.not_after,
+			updated_at = excluded.updated_at
+	`
+	now := utcNow()
+	_, err := d.db.ExecContext(ctx, query,
+		cert.Domain,
+		cert.CertPEM,
+		cert.PrivateKey,
+		cert.NotAfter,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存 ACME 证书失败: %w", err)
+	}
+	return nil
+}
+
+// GetACMECertificate 获取域名的 ACME 证书
+func (d *SQLiteDriver) GetACMECertificate(ctx context.Context, domain string) (*ACMECertificate, error) {
+	query := `
+		SELECT domain, cert_pem, private_key, not_after, created_at, updated_at
+		FROM acme_certificates
+		WHERE domain = ?
+	`
+	var cert ACMECertificate
+	err := d.db.QueryRowContext(ctx, query, domain).Scan(
+		&cert.Domain,
+		&cert.CertPEM,
+		&cert.PrivateKey,
+		&cert.NotAfter,
+		&cert.CreatedAt,
+		&cert.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("ACME 证书不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 ACME 证书失败: %w", err)
+	}
+	return &cert, nil
+}
+
+// ListACMECertificates 列出所有 ACME 证书
+func (d *SQLiteDriver) ListACMECertificates(ctx context.Context) ([]*ACMECertificate, error) {
+	query := `
+		SELECT domain, cert_pem, private_key, not_after, created_at, updated_at
+		FROM acme_certificates
+		ORDER BY domain
+	`
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("查询 ACME 证书列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var certs []*ACMECertificate
+	for rows.Next() {
+		var cert ACMECertificate
+		if err := rows.Scan(
+			&cert.Domain,
+			&cert.CertPEM,
+			&cert.PrivateKey,
+			&cert.NotAfter,
+			&cert.CreatedAt,
+			&cert.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("扫描 ACME 证书失败: %w", err)
+		}
+		certs = append(certs, &cert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历 ACME 证书失败: %w", err)
+	}
+	return certs, nil
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_ACMEOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// 账户：同一邮箱和目录重复保存时覆盖
+	account := &ACMEAccount{Email: "admin@example.com", DirectoryURL: "https://acme.test/dir", PrivateKey: []byte("key1")}
+	if err := driver.SaveACMEAccount(ctx, account); err != nil {
+		t.Fatalf("保存 ACME 账户失败: %v", err)
+	}
+	account.PrivateKey = []byte("key2")
+	account.AccountURL = "https://acme.test/acct/1"
+	if err := driver.SaveACMEAccount(ctx, account); err != nil {
+		t.Fatalf("更新 ACME 账户失败: %v", err)
+	}
+	got, err := driver.GetACMEAccount(ctx, "admin@example.com", "https://acme.test/dir")
+	if err != nil {
+		t.Fatalf("获取 ACME 账户失败: %v", err)
+	}
+	if string(got.PrivateKey) != "key2" || got.AccountURL != "https://acme.test/acct/1" {
+		t.Errorf("ACME 账户未更新: %+v", got)
+	}
+	if _, err := driver.GetACMEAccount(ctx, "other@example.com", "https://acme.test/dir"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("期望 ErrNotFound，得到 %v", err)
+	}
+
+	// 订单
+	if err := driver.SaveACMEOrder(ctx, &ACMEOrder{Domain: "mail.example.com", Status: "pending"}); err != nil {
+		t.Fatalf("保存 ACME 订单失败: %v", err)
+	}
+	if err := driver.SaveACMEOrder(ctx, &ACMEOrder{Domain: "mail.example.com", Status: "invalid", Error: "挑战失败"}); err != nil {
+		t.Fatalf("更新 ACME 订单失败: %v", err)
+	}
+	order, err := driver.GetACMEOrder(ctx, "mail.example.com")
+	if err != nil {
+		t.Fatalf("获取 ACME 订单失败: %v", err)
+	}
+	if order.Status != "invalid" || order.Error != "挑战失败" {
+		t.Errorf("ACME 订单未更新: %+v", order)
+	}
+
+	// 证书
+	notAfter := time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second)
+	cert := &ACMECertificate{Domain: "mail.example.com", CertPEM: []byte("cert"), PrivateKey: []byte("secret"), NotAfter: notAfter}
+	if err := driver.SaveACMECertificate(ctx, cert); err != nil {
+		t.Fatalf("保存 ACME 证书失败: %v", err)
+	}
+	gotCert, err := driver.GetACMECertificate(ctx, "mail.example.com")
+	if err != nil {
+		t.Fatalf("获取 ACME 证书失败: %v", err)
+	}
+	if string(gotCert.CertPEM) != "cert" || !gotCert.NotAfter.Equal(notAfter) {
+		t.Errorf("ACME 证书不匹配: %+v", gotCert)
+	}
+	certs, err := driver.ListACMECertificates(ctx)
+	if err != nil {
+		t.Fatalf("列出 ACME 证书失败: %v", err)
+	}
+	if len(certs) != 1 {
+		t.Errorf("期望 1 张证书，得到 %d", len(certs))
+	}
+}
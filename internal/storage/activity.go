@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecordLogin 保存一次成功的登录
+func (d *SQLiteDriver) RecordLogin(ctx context.Context, record *LoginRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	record.CreatedAt = record.CreatedAt.UTC()
+	result, err := d.db.ExecContext(ctx, `
+		INSERT INTO login_history (user_email, protocol, remote_ip, user_agent, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, strings.ToLower(record.UserEmail), record.Protocol, record.RemoteIP, record.UserAgent, record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("保存登录记录失败: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		record.ID = id
+	}
+	return nil
+}
+
+// ListLoginHistory 查询用户 since 之后的登录记录（按时间倒序，最多 limit 条）
+func (d *SQLiteDriver) ListLoginHistory(ctx context.Context, userEmail string, since time.Time, limit int) ([]*LoginRecord, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, user_email, protocol, remote_ip, user_agent, created_at
+		FROM login_history WHERE user_email = ? AND created_at >= ?
+		ORDER BY created_at DESC, id DESC LIMIT ?
+	`, strings.ToLower(userEmail), since.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询登录记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*LoginRecord{}
+	for rows.Next() {
+		var r LoginRecord
+		if err := rows.Scan(&r.ID, &r.UserEmail, &r.Protocol, &r.RemoteIP, &r.UserAgent, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描登录记录失败: %w", err)
+		}
+		records = append(records, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询登录记录失败: %w", err)
+	}
+	return records, nil
+}
+
+// RecordSentMail 保存一条发信记录
+func (d *SQLiteDriver) RecordSentMail(ctx context.Context, record *SentRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	record.CreatedAt = record.CreatedAt.UTC()
+	record.MessageID = normalizeMessageID(record.MessageID)
+	result, err := d.db.ExecContext(ctx, `
+		INSERT INTO sent_log (user_email, message_id, subject, recipients, protocol, remote_ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, strings.ToLower(record.UserEmail), record.MessageID, record.Subject,
+		strings.Join(record.Recipients, ","), record.Protocol, record.RemoteIP, record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("保存发信记录失败: %w", err)
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		record.ID = id
+	}
+	return nil
+}
+
+// ListSentLog 查询用户 since 之后的发信记录（按时间倒序，最多 limit 条）
+func (d *SQLiteDriver) ListSentLog(ctx context.Context, userEmail string, since time.Time, limit int) ([]*SentRecord, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, user_email, message_id, subject, recipients, protocol, remote_ip, created_at
+		FROM sent_log WHERE user_email = ? AND created_at >= ?
+		ORDER BY created_at DESC, id DESC LIMIT ?
+	`, strings.ToLower(userEmail), since.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询发信记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	records := []*SentRecord{}
+	for rows.Next() {
+		var r SentRecord
+		var recipients string
+		if err := rows.Scan(&r.ID, &r.UserEmail, &r.MessageID, &r.Subject, &recipients, &r.Protocol, &r.RemoteIP, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描发信记录失败: %w", err)
+		}
+		r.Recipients = []string{}
+		if recipients != "" {
+			r.Recipients = strings.Split(recipients, ",")
+		}
+		records = append(records, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询发信记录失败: %w", err)
+	}
+	return records, nil
+}
+
+// PruneAccountActivity 删除 before 之前的登录记录和发信记录，返回删除的条数
+func (d *SQLiteDriver) PruneAccountActivity(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, table := range []string{"login_history", "sent_log"} {
+		result, err := d.db.ExecContext(ctx, `DELETE FROM `+table+` WHERE created_at < ?`, before.UTC())
+		if err != nil {
+			return total, fmt.Errorf("清理账户活动记录失败: %w", err)
+		}
+		n, _ := result.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAccountActivity(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, r := range []*LoginRecord{
+		{UserEmail: "Alice@example.com", Protocol: "webmail", RemoteIP: "192.0.2.1", UserAgent: "Firefox", CreatedAt: now.Add(-time.Hour)},
+		{UserEmail: "alice@example.com", Protocol: "imap", RemoteIP: "198.51.100.7", CreatedAt: now},
+		{UserEmail: "bob@example.com", Protocol: "smtp", RemoteIP: "192.0.2.9", CreatedAt: now},
+		{UserEmail: "alice@example.com", Protocol: "imap", RemoteIP: "192.0.2.1", CreatedAt: now.Add(-100 * 24 * time.Hour)},
+	} {
+		if err := driver.RecordLogin(ctx, r); err != nil {
+			t.Fatalf("保存登录记录失败: %v", err)
+		}
+		if r.ID == 0 {
+			t.Errorf("保存后应该设置 ID: %+v", r)
+		}
+	}
+	sent := &SentRecord{UserEmail: "alice@example.com", MessageID: "<m1@example.com>", Subject: "报价",
+		Recipients: []string{"carol@remote.test", "dave@remote.test"}, Protocol: "smtp", RemoteIP: "198.51.100.7"}
+	if err := driver.RecordSentMail(ctx, sent); err != nil {
+		t.Fatalf("保存发信记录失败: %v", err)
+	}
+
+	since := now.Add(-30 * 24 * time.Hour)
+	logins, err := driver.ListLoginHistory(ctx, "ALICE@example.com", since, 100)
+	if err != nil {
+		t.Fatalf("查询登录记录失败: %v", err)
+	}
+	if len(logins) != 2 || logins[0].Protocol != "imap" || logins[1].UserAgent != "Firefox" {
+		t.Errorf("登录记录 = %+v, want 最近 30 天的 2 条，按时间倒序", logins)
+	}
+
+	log, err := driver.ListSentLog(ctx, "alice@example.com", since, 100)
+	if err != nil {
+		t.Fatalf("查询发信记录失败: %v", err)
+	}
+	if len(log) != 1 || log[0].MessageID != "m1@example.com" || log[0].Subject != "报价" || len(log[0].Recipients) != 2 {
+		t.Errorf("发信记录 = %+v", log)
+	}
+	if log, _ := driver.ListSentLog(ctx, "bob@example.com", since, 100); len(log) != 0 {
+		t.Errorf("其他用户的发信记录 = %+v, want 空", log)
+	}
+
+	n, err := driver.PruneAccountActivity(ctx, since)
+	if err != nil || n != 1 {
+		t.Errorf("PruneAccountActivity() = %d, %v, want 1", n, err)
+	}
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// aliasColumns 别名查询的列（与 scanAlias 对应）
+const aliasColumns = `id, from_addr, to_addr, domain, COALESCE(owner, ''), COALESCE(disposable, 0),
+	expires_at, burned_at, COALESCE(received_count, 0), last_received_at, created_at, COALESCE(destinations, '')`
+
+// rowScanner *sql.Row 和 *sql.Rows 共有的扫描方法
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAlias 扫描一行别名
+func scanAlias(row rowScanner) (*Alias, error) {
+	var alias Alias
+	var disposable int
+	var expiresAt, burnedAt, lastReceivedAt sql.NullTime
+	var destinations string
+	if err := row.Scan(
+		&alias.ID,
+		&alias.From,
+		&alias.To,
+		&alias.Domain,
+		&alias.Owner,
+		&disposable,
+		&expiresAt,
+		&burnedAt,
+		&alias.ReceivedCount,
+		&lastReceivedAt,
+		&alias.CreatedAt,
+		&destinations,
+	); err != nil {
+		return nil, err
+	}
+
+	// 没有 destinations 的旧别名只有一个目标
+	alias.Destinations = []string{alias.To}
+	if destinations != "" {
+		alias.Destinations = strings.Split(destinations, ",")
+	}
+
+	alias.Disposable = disposable == 1
+	if expiresAt.Valid {
+		alias.ExpiresAt = &expiresAt.Time
+	}
+	if burnedAt.Valid {
+		alias.BurnedAt = &burnedAt.Time
+	}
+	if lastReceivedAt.Valid {
+		alias.LastReceivedAt = &lastReceivedAt.Time
+	}
+	return &alias, nil
+}
+
+// Active 别名是否可以接收邮件（未作废且未过期）
+func (a *Alias) Active(now time.Time) bool {
+	if a.BurnedAt != nil {
+		return false
+	}
+	return a.ExpiresAt == nil || now.Before(*a.ExpiresAt)
+}
+
+// UpdateAliasDestinations 更新别名的目标地址（第一个目标同时保存为 to_addr）
+func (d *SQLiteDriver) UpdateAliasDestinations(ctx context.Context, from string, destinations []string) error {
+	destinations, err := normalizeDestinations(destinations)
+	if err != nil {
+		return fmt.Errorf("更新别名失败: %w", err)
+	}
+	query := `UPDATE aliases SET to_addr = ?, destinations = ? WHERE from_addr = ?`
+	result, err := d.db.ExecContext(ctx, query, destinations[0], strings.Join(destinations, ","), from)
+	if err != nil {
+		return fmt.Errorf("更新别名失败: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("别名不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// normalizeDestinations 检查并规范化别名的目标地址：去除空白、转为小写、去重，至少需要一个
+func normalizeDestinations(destinations []string) ([]string, error) {
+	seen := make(map[string]bool, len(destinations))
+	var result []string
+	for _, addr := range destinations {
+		addr = strings.ToLower(strings.TrimSpace(addr))
+		if addr == "" || seen[addr] {
+			continue
+		}
+		at := strings.LastIndex(addr, "@")
+		if at <= 0 || at == len(addr)-1 || strings.ContainsAny(addr, ", \r\n<>") {
+			return nil, fmt.Errorf("无效的目标地址 %q: %w", addr, ErrInvalidInput)
+		}
+		seen[addr] = true
+		result = append(result, addr)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("别名至少需要一个目标地址: %w", ErrInvalidInput)
+	}
+	return result, nil
+}
+
+// ListAliasesByOwner 列出用户自助创建的别名（包括已过期和已作废的临时别名）
+func (d *SQLiteDriver) ListAliasesByOwner(ctx context.Context, owner string) ([]*Alias, error) {
+	query := `SELECT ` + aliasColumns + ` FROM aliases WHERE owner = ? ORDER BY created_at DESC, from_addr`
+	rows, err := d.db.QueryContext(ctx, query, owner)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户别名失败: %w", err)
+	}
+	defer rows.Close()
+
+	var aliases []*Alias
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描别名失败: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, rows.Err()
+}
+
+// BurnAlias 作废别名，之后发往该地址的邮件将被拒收
+func (d *SQLiteDriver) BurnAlias(ctx context.Context, from string) error {
+	query := `UPDATE aliases SET burned_at = ? WHERE from_addr = ? AND burned_at IS NULL`
+	if _, err := d.db.ExecContext(ctx, query, utcNow(), from); err != nil {
+		return fmt.Errorf("作废别名失败: %w", err)
+	}
+	return nil
+}
+
+// RecordAliasDelivery 记录一次经由别名的投递
+func (d *SQLiteDriver) RecordAliasDelivery(ctx context.Context, from string) error {
+	query := `UPDATE aliases SET received_count = COALESCE(received_count, 0) + 1, last_received_at = ? WHERE from_addr = ?`
+	if _, err := d.db.ExecContext(ctx, query, utcNow(), from); err != nil {
+		return fmt.Errorf("记录别名投递失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteInactiveDisposableAliases 删除在 before 之前已过期或作废的临时别名，返回删除数量
+func (d *SQLiteDriver) DeleteInactiveDisposableAliases(ctx context.Context, before time.Time) (int, error) {
+	query := `SELECT ` + aliasColumns + ` FROM aliases WHERE disposable = 1`
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("查询临时别名失败: %w", err)
+	}
+
+	// 过期时间在应用层比较，避免依赖数据库中的时间格式
+	var stale []string
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("扫描别名失败: %w", err)
+		}
+		if (alias.BurnedAt != nil && alias.BurnedAt.Before(before)) ||
+			(alias.ExpiresAt != nil && alias.ExpiresAt.Before(before)) {
+			stale = append(stale, alias.From)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("查询临时别名失败: %w", err)
+	}
+
+	for _, from := range stale {
+		if err := d.DeleteAlias(ctx, from); err != nil {
+			return 0, err
+		}
+	}
+	return len(stale), nil
+}
+
+// GetAliasPolicy 获取域名的自助别名策略
+func (d *SQLiteDriver) GetAliasPolicy(ctx context.Context, domain string) (*AliasPolicy, error) {
+	query := `
+		SELECT domain, max_aliases, COALESCE(patterns, ''), updated_at
+		FROM alias_policies
+		WHERE domain = ?
+	`
+	var policy AliasPolicy
+	var patterns string
+	err := d.db.QueryRowContext(ctx, query, domain).Scan(
+		&policy.Domain,
+		&policy.MaxAliases,
+		&patterns,
+		&policy.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("别名策略不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询别名策略失败: %w", err)
+	}
+
+	// 解析 patterns（用逗号分割）
+	if patterns != "" {
+		policy.Patterns = strings.Split(patterns, ",")
+	}
+	return &policy, nil
+}
+
+// SaveAliasPolicy 保存域名的自助别名策略（按域名唯一）
+func (d *SQLiteDriver) SaveAliasPolicy(ctx context.Context, policy *AliasPolicy) error {
+	query := `
+		INSERT INTO alias_policies (domain, max_aliases, patterns, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(domain) DO UPDATE SET
+			max_aliases = excluded.max_aliases,
+			patterns = excluded.patterns,
+			updated_at = excluded.updated_at
+	`
+	_, err := d.db.ExecContext(ctx, query,
+		policy.Domain,
+		policy.MaxAliases,
+		strings.Join(policy.Patterns, ","),
+		utcNow(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存别名策略失败: %w", constraintError(err))
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_AliasPolicyOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// 未配置策略
+	if _, err := driver.GetAliasPolicy(ctx, "example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未配置策略时应该返回 ErrNotFound, got %v", err)
+	}
+
+	policy := &AliasPolicy{Domain: "example.com", MaxAliases: 3, Patterns: []string{"{user}+*", "team-*"}}
+	if err := driver.SaveAliasPolicy(ctx, policy); err != nil {
+		t.Fatalf("保存别名策略失败: %v", err)
+	}
+	policy.MaxAliases = 5
+	if err := driver.SaveAliasPolicy(ctx, policy); err != nil {
+		t.Fatalf("更新别名策略失败: %v", err)
+	}
+
+	got, err := driver.GetAliasPolicy(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("获取别名策略失败: %v", err)
+	}
+	if got.MaxAliases != 5 || len(got.Patterns) != 2 || got.Patterns[1] != "team-*" {
+		t.Errorf("别名策略不正确: %+v", got)
+	}
+
+	// 别名归属
+	aliases := []*Alias{
+		{From: "alice+shop@example.com", To: "alice@example.com", Domain: "example.com", Owner: "alice@example.com"},
+		{From: "sales@example.com", To: "alice@example.com", Domain: "example.com"},
+	}
+	for _, alias := range aliases {
+		if err := driver.CreateAlias(ctx, alias); err != nil {
+			t.Fatalf("创建别名失败: %v", err)
+		}
+	}
+
+	owned, err := driver.ListAliasesByOwner(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("查询用户别名失败: %v", err)
+	}
+	if len(owned) != 1 || owned[0].From != "alice+shop@example.com" {
+		t.Errorf("应该只返回用户自助创建的别名, got %d", len(owned))
+	}
+
+	alias, err := driver.GetAlias(ctx, "sales@example.com")
+	if err != nil || alias.Owner != "" {
+		t.Errorf("管理员创建的别名不应该有归属: %+v, err = %v", alias, err)
+	}
+}
+
+func TestSQLiteDriver_DisposableAliases(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	expired := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	aliases := []*Alias{
+		{From: "shop-ab12@example.com", To: "alice@example.com", Domain: "example.com", Owner: "alice@example.com", Disposable: true, ExpiresAt: &future},
+		{From: "old-cd34@example.com", To: "alice@example.com", Domain: "example.com", Owner: "alice@example.com", Disposable: true, ExpiresAt: &expired},
+		{From: "sales@example.com", To: "alice@example.com", Domain: "example.com"},
+	}
+	for _, alias := range aliases {
+		if err := driver.CreateAlias(ctx, alias); err != nil {
+			t.Fatalf("创建别名失败: %v", err)
+		}
+	}
+
+	// 接收计数
+	for i := 0; i < 2; i++ {
+		if err := driver.RecordAliasDelivery(ctx, "shop-ab12@example.com"); err != nil {
+			t.Fatalf("记录别名投递失败: %v", err)
+		}
+	}
+	alias, err := driver.GetAlias(ctx, "shop-ab12@example.com")
+	if err != nil {
+		t.Fatalf("获取别名失败: %v", err)
+	}
+	if !alias.Disposable || alias.ReceivedCount != 2 || alias.LastReceivedAt == nil || !alias.Active(now) {
+		t.Errorf("临时别名不正确: %+v", alias)
+	}
+
+	// 过期
+	alias, err = driver.GetAlias(ctx, "old-cd34@example.com")
+	if err != nil || alias.Active(now) {
+		t.Errorf("过期的别名不应该有效: %+v, err = %v", alias, err)
+	}
+
+	// 作废
+	if err := driver.BurnAlias(ctx, "shop-ab12@example.com"); err != nil {
+		t.Fatalf("作废别名失败: %v", err)
+	}
+	alias, err = driver.GetAlias(ctx, "shop-ab12@example.com")
+	if err != nil || alias.BurnedAt == nil || alias.Active(now) {
+		t.Errorf("作废的别名不应该有效: %+v, err = %v", alias, err)
+	}
+
+	// 保留期内不删除，之后只删除失效的临时别名
+	if n, err := driver.DeleteInactiveDisposableAliases(ctx, now.Add(-24*time.Hour)); err != nil || n != 0 {
+		t.Errorf("保留期内不应该删除, n = %d, err = %v", n, err)
+	}
+	if n, err := driver.DeleteInactiveDisposableAliases(ctx, now.Add(time.Minute)); err != nil || n != 2 {
+		t.Errorf("应该删除 2 个临时别名, n = %d, err = %v", n, err)
+	}
+	if _, err := driver.GetAlias(ctx, "sales@example.com"); err != nil {
+		t.Errorf("普通别名不应该被删除: %v", err)
+	}
+}
+
+func TestSQLiteDriver_AliasDestinations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// 只指定 to 时目标地址为 to
+	if err := driver.CreateAlias(ctx, &Alias{From: "sales@example.com", To: "alice@example.com", Domain: "example.com"}); err != nil {
+		t.Fatalf("创建别名失败: %v", err)
+	}
+	alias, err := driver.GetAlias(ctx, "sales@example.com")
+	if err != nil || len(alias.Destinations) != 1 || alias.Destinations[0] != "alice@example.com" {
+		t.Errorf("目标地址应该为 to: %+v, err = %v", alias, err)
+	}
+
+	// 多个目标地址去重并转为小写，第一个作为 to
+	team := &Alias{From: "team@example.com", Destinations: []string{"Alice@example.com", "bob@example.com", "alice@example.com", "friend@other.test"}, Domain: "example.com"}
+	if err := driver.CreateAlias(ctx, team); err != nil {
+		t.Fatalf("创建别名失败: %v", err)
+	}
+	alias, err = driver.GetAlias(ctx, "team@example.com")
+	if err != nil {
+		t.Fatalf("获取别名失败: %v", err)
+	}
+	if alias.To != "alice@example.com" || len(alias.Destinations) != 3 || alias.Destinations[2] != "friend@other.test" {
+		t.Errorf("目标地址不正确: to = %q, destinations = %v", alias.To, alias.Destinations)
+	}
+
+	// 更新目标地址
+	if err := driver.UpdateAliasDestinations(ctx, "team@example.com", []string{"bob@example.com"}); err != nil {
+		t.Fatalf("更新目标地址失败: %v", err)
+	}
+	alias, err = driver.GetAlias(ctx, "team@example.com")
+	if err != nil || alias.To != "bob@example.com" || len(alias.Destinations) != 1 {
+		t.Errorf("更新后的目标地址不正确: %+v, err = %v", alias, err)
+	}
+
+	if err := driver.UpdateAliasDestinations(ctx, "team@example.com", []string{"not-an-address"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的目标地址应该返回 ErrInvalidInput, got %v", err)
+	}
+	if err := driver.UpdateAliasDestinations(ctx, "team@example.com", nil); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("空的目标地址应该返回 ErrInvalidInput, got %v", err)
+	}
+	if err := driver.UpdateAliasDestinations(ctx, "missing@example.com", []string{"bob@example.com"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的别名应该返回 ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	// AnnotationComment 邮件备注条目
+	AnnotationComment = "/comment"
+	// AnnotationColor 邮件颜色标签条目
+	AnnotationColor = "/vendor/gomailzero/color"
+
+	// MaxAnnotationSize 单个注解值的最大字节数
+	MaxAnnotationSize = 64 * 1024
+)
+
+// ValidAnnotationEntry 校验注解条目名称（以 / 开头，不含通配符、连续或结尾的 /）
+func ValidAnnotationEntry(entry string) bool {
+	if !strings.HasPrefix(entry, "/") || len(entry) < 2 {
+		return false
+	}
+	if strings.HasSuffix(entry, "/") || strings.Contains(entry, "//") || strings.ContainsAny(entry, "*%") {
+		return false
+	}
+	for _, r := range entry {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// ListAnnotations 列出邮件的全部注解
+func (d *SQLiteDriver) ListAnnotations(ctx context.Context, mailID string) ([]*Annotation, error) {
+	query := `
+		SELECT mail_id, entry, shared, value, updated_at
+		FROM message_annotations
+		WHERE mail_id = ?
+		ORDER BY entry, shared
+	`
+	rows, err := d.db.QueryContext(ctx, query, mailID)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件注解失败: %w", err)
+	}
+	defer rows.Close()
+
+	var annotations []*Annotation
+	for rows.Next() {
+		var annotation Annotation
+		var shared int
+		if err := rows.Scan(&annotation.MailID, &annotation.Entry, &shared, &annotation.Value, &annotation.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("扫描邮件注解失败: %w", err)
+		}
+		annotation.Shared = shared == 1
+		annotations = append(annotations, &annotation)
+	}
+
+	return annotations, rows.Err()
+}
+
+// SetAnnotation 设置邮件注解（已存在时覆盖）
+func (d *SQLiteDriver) SetAnnotation(ctx context.Context, annotation *Annotation) error {
+	query := `
+		INSERT INTO message_annotations (mail_id, entry, shared, value, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(mail_id, entry, shared) DO UPDATE SET
+			value = excluded.value,
+			updated_at = excluded.updated_at
+	`
+	shared := 0
+	if annotation.Shared {
+		shared = 1
+	}
+	if _, err := d.db.ExecContext(ctx, query, annotation.MailID, annotation.Entry, shared, annotation.Value, utcNow()); err != nil {
+		return fmt.Errorf("保存邮件注解失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteAnnotation 删除邮件注解（注解不存在时不报错）
+func (d *SQLiteDriver) DeleteAnnotation(ctx context.Context, mailID, entry string, shared bool) error {
+	query := `DELETE FROM message_annotations WHERE mail_id = ? AND entry = ? AND shared = ?`
+	sharedValue := 0
+	if shared {
+		sharedValue = 1
+	}
+	if _, err := d.db.ExecContext(ctx, query, mailID, entry, sharedValue); err != nil {
+		return fmt.Errorf("删除邮件注解失败: %w", err)
+	}
+	return nil
+}
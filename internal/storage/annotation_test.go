@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_AnnotationOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	mail := &Mail{
+		ID:         "mail-1",
+		UserEmail:  "alice@example.com",
+		Folder:     "INBOX",
+		From:       "bob@example.com",
+		To:         []string{"alice@example.com"},
+		Subject:    "Hello",
+		Size:       100,
+		ReceivedAt: time.Now(),
+	}
+	if err := driver.StoreMail(ctx, mail); err != nil {
+		t.Fatalf("存储邮件失败: %v", err)
+	}
+
+	annotations := []*Annotation{
+		{MailID: "mail-1", Entry: AnnotationComment, Value: "稍后回复"},
+		{MailID: "mail-1", Entry: AnnotationComment, Shared: true, Value: "shared"},
+		{MailID: "mail-1", Entry: AnnotationColor, Value: "red"},
+		{MailID: "mail-1", Entry: AnnotationColor, Value: "blue"},
+	}
+	for _, a := range annotations {
+		if err := driver.SetAnnotation(ctx, a); err != nil {
+			t.Fatalf("保存邮件注解失败: %v", err)
+		}
+	}
+
+	got, err := driver.ListAnnotations(ctx, "mail-1")
+	if err != nil {
+		t.Fatalf("列出邮件注解失败: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("应该有 3 个注解, got %d", len(got))
+	}
+	if got[0].Entry != AnnotationComment || got[0].Shared || got[1].Value != "shared" || got[2].Value != "blue" {
+		t.Errorf("邮件注解不正确: %+v %+v %+v", got[0], got[1], got[2])
+	}
+
+	if err := driver.DeleteAnnotation(ctx, "mail-1", AnnotationComment, true); err != nil {
+		t.Fatalf("删除邮件注解失败: %v", err)
+	}
+	got, _ = driver.ListAnnotations(ctx, "mail-1")
+	if len(got) != 2 {
+		t.Errorf("删除后应该剩余 2 个注解, got %d", len(got))
+	}
+
+	// 删除邮件时一并删除注解
+	if err := driver.DeleteMail(ctx, "mail-1"); err != nil {
+		t.Fatalf("删除邮件失败: %v", err)
+	}
+	got, _ = driver.ListAnnotations(ctx, "mail-1")
+	if len(got) != 0 {
+		t.Errorf("删除邮件后注解应该被删除, got %d", len(got))
+	}
+}
+
+func TestValidAnnotationEntry(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  bool
+	}{
+		{"/comment", true},
+		{"/vendor/gomailzero/color", true},
+		{"comment", false},
+		{"/", false},
+		{"/comment/", false},
+		{"/a//b", false},
+		{"/a/*", false},
+	}
+	for _, tt := range tests {
+		if got := ValidAnnotationEntry(tt.entry); got != tt.want {
+			t.Errorf("ValidAnnotationEntry(%q) = %v, want %v", tt.entry, got, tt.want)
+		}
+	}
+}
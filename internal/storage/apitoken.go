@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// apiTokenColumns 个人访问令牌查询的列（与 scanAPIToken 对应）
+const apiTokenColumns = `id, user_email, name, prefix, token_hash, scope, expires_at, last_used_at, created_at`
+
+// scanAPIToken 扫描一行个人访问令牌
+func scanAPIToken(row rowScanner) (*APIToken, error) {
+	var token APIToken
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&token.ID,
+		&token.UserEmail,
+		&token.Name,
+		&token.Prefix,
+		&token.TokenHash,
+		&token.Scope,
+		&expiresAt,
+		&lastUsedAt,
+		&token.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+	return &token, nil
+}
+
+// CreateAPIToken 创建个人访问令牌，成功后设置 token.ID
+func (d *SQLiteDriver) CreateAPIToken(ctx context.Context, token *APIToken) error {
+	query := `
+		INSERT INTO api_tokens (user_email, name, prefix, token_hash, scope, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, query,
+		token.UserEmail,
+		token.Name,
+		token.Prefix,
+		token.TokenHash,
+		token.Scope,
+		token.ExpiresAt,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("创建访问令牌失败: %w", constraintError(err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取访问令牌 ID 失败: %w", err)
+	}
+	token.ID = id
+	token.CreatedAt = now
+	return nil
+}
+
+// GetAPITokenByHash 按令牌哈希获取个人访问令牌
+func (d *SQLiteDriver) GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE token_hash = ?`
+	token, err := scanAPIToken(d.db.QueryRowContext(ctx, query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("访问令牌不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询访问令牌失败: %w", err)
+	}
+	return token, nil
+}
+
+// ListAPITokens 列出用户的个人访问令牌（按创建时间倒序）
+func (d *SQLiteDriver) ListAPITokens(ctx context.Context, userEmail string) ([]*APIToken, error) {
+	query := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE user_email = ? ORDER BY created_at DESC, id DESC`
+	rows, err := d.db.QueryContext(ctx, query, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询访问令牌失败: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描访问令牌失败: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// DeleteAPIToken 吊销用户的个人访问令牌
+func (d *SQLiteDriver) DeleteAPIToken(ctx context.Context, userEmail string, id int64) error {
+	query := `DELETE FROM api_tokens WHERE id = ? AND user_email = ?`
+	result, err := d.db.ExecContext(ctx, query, id, userEmail)
+	if err != nil {
+		return fmt.Errorf("删除访问令牌失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("访问令牌不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// TouchAPIToken 记录个人访问令牌的最近使用时间
+func (d *SQLiteDriver) TouchAPIToken(ctx context.Context, id int64, at time.Time) error {
+	query := `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`
+	if _, err := d.db.ExecContext(ctx, query, at, id); err != nil {
+		return fmt.Errorf("更新访问令牌使用时间失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserAPITokens 吊销用户的所有个人访问令牌，返回吊销的数量
+func (d *SQLiteDriver) DeleteUserAPITokens(ctx context.Context, userEmail string) (int64, error) {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_email = ?`, userEmail)
+	if err != nil {
+		return 0, fmt.Errorf("删除访问令牌失败: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// storeAttachments 写入邮件的附件元数据
+func (d *SQLiteDriver) storeAttachments(ctx context.Context, mail *Mail) error {
+	if len(mail.Attachments) == 0 {
+		return nil
+	}
+	mail.HasAttachment = true
+
+	query := `
+		INSERT INTO mail_attachments (mail_id, user_email, part, filename, content_type, size)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	for _, att := range mail.Attachments {
+		if _, err := d.db.ExecContext(ctx, query, mail.ID, mail.UserEmail, att.Part, att.Filename, att.ContentType, att.Size); err != nil {
+			return fmt.Errorf("存储附件元数据失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListMailAttachments 列出单封邮件的附件
+func (d *SQLiteDriver) ListMailAttachments(ctx context.Context, mailID string) ([]*Attachment, error) {
+	query := `
+		SELECT id, mail_id, user_email, part, filename, content_type, size
+		FROM mail_attachments
+		WHERE mail_id = ?
+		ORDER BY id
+	`
+	rows, err := d.db.QueryContext(ctx, query, mailID)
+	if err != nil {
+		return nil, fmt.Errorf("查询附件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		var att Attachment
+		if err := rows.Scan(&att.ID, &att.MailID, &att.UserEmail, &att.Part, &att.Filename, &att.ContentType, &att.Size); err != nil {
+			return nil, fmt.Errorf("扫描附件失败: %w", err)
+		}
+		attachments = append(attachments, &att)
+	}
+	return attachments, rows.Err()
+}
+
+// ListAttachments 列出用户全部邮件中的附件（按接收时间倒序），附带所属邮件信息
+func (d *SQLiteDriver) ListAttachments(ctx context.Context, userEmail string, filter *AttachmentFilter) ([]*Attachment, error) {
+	query := `
+		SELECT a.id, a.mail_id, a.user_email, a.part, a.filename, a.content_type, a.size,
+			m.folder, m.from_addr, m.subject, m.received_at
+		FROM mail_attachments a
+		JOIN mails m ON m.id = a.mail_id
+		WHERE a.user_email = ?
+	`
+	args := []interface{}{userEmail}
+
+	if filter == nil {
+		filter = &AttachmentFilter{}
+	}
+	if t := strings.ToLower(strings.TrimSpace(filter.Type)); t != "" {
+		if strings.Contains(t, "/") {
+			query += " AND a.content_type LIKE ?"
+			args = append(args, t+"%")
+		} else {
+			// 扩展名：匹配文件名后缀或 MIME 子类型（如 pdf 匹配 application/pdf）
+			ext := strings.TrimPrefix(t, ".")
+			query += " AND (LOWER(a.filename) LIKE ? OR a.content_type LIKE ?)"
+			args = append(args, "%."+ext, "%/"+ext)
+		}
+	}
+	if filter.MinSize > 0 {
+		query += " AND a.size >= ?"
+		args = append(args, filter.MinSize)
+	}
+	if filter.MaxSize > 0 {
+		query += " AND a.size <= ?"
+		args = append(args, filter.MaxSize)
+	}
+	if filter.From != "" {
+		query += " AND m.from_addr LIKE ?"
+		args = append(args, "%"+filter.From+"%")
+	}
+	if filter.After != nil {
+		query += " AND m.received_at >= ?"
+		args = append(args, *filter.After)
+	}
+	if filter.Before != nil {
+		query += " AND m.received_at < ?"
+		args = append(args, *filter.Before)
+	}
+
+	query += " ORDER BY m.received_at DESC, a.id"
+	if filter.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit, filter.Offset)
+	}
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询附件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		var att Attachment
+		if err := rows.Scan(&att.ID, &att.MailID, &att.UserEmail, &att.Part, &att.Filename, &att.ContentType, &att.Size,
+			&att.Folder, &att.From, &att.Subject, &att.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("扫描附件失败: %w", err)
+		}
+		attachments = append(attachments, &att)
+	}
+	return attachments, rows.Err()
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_ListAttachments(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	jan := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	mails := []*Mail{
+		{ID: "m1", From: "alice@example.com", Subject: "report", ReceivedAt: jan, Attachments: []*Attachment{
+			{Part: "2", Filename: "report.pdf", ContentType: "application/pdf", Size: 5000},
+			{Part: "3", Filename: "chart.png", ContentType: "image/png", Size: 200},
+		}},
+		{ID: "m2", From: "bob@example.com", Subject: "photo", ReceivedAt: jan.AddDate(0, 1, 0), Attachments: []*Attachment{
+			{Part: "2", Filename: "IMG_1.JPG", ContentType: "image/jpeg", Size: 90000},
+		}},
+		{ID: "m3", From: "bob@example.com", Subject: "plain", ReceivedAt: jan},
+	}
+	for _, m := range mails {
+		m.UserEmail = "me@example.com"
+		m.Folder = "INBOX"
+		m.To = []string{"me@example.com"}
+		if err := driver.StoreMail(ctx, m); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+
+	got, err := driver.GetMail(ctx, "m1")
+	if err != nil || !got.HasAttachment {
+		t.Errorf("带附件的邮件应该标记 has_attachment: %v", err)
+	}
+
+	all, err := driver.ListAttachments(ctx, "me@example.com", nil)
+	if err != nil {
+		t.Fatalf("列出附件失败: %v", err)
+	}
+	if len(all) != 3 || all[0].MailID != "m2" || all[0].Subject != "photo" || all[0].ReceivedAt.IsZero() {
+		t.Fatalf("附件列表不正确: %+v", all)
+	}
+
+	feb := jan.AddDate(0, 0, 20)
+	tests := []struct {
+		name   string
+		filter *AttachmentFilter
+		want   int
+	}{
+		{"MIME 前缀", &AttachmentFilter{Type: "image/"}, 2},
+		{"扩展名", &AttachmentFilter{Type: "jpg"}, 1},
+		{"子类型", &AttachmentFilter{Type: "pdf"}, 1},
+		{"大小", &AttachmentFilter{MinSize: 1000, MaxSize: 10000}, 1},
+		{"发件人", &AttachmentFilter{From: "bob"}, 1},
+		{"日期", &AttachmentFilter{After: &feb}, 1},
+		{"分页", &AttachmentFilter{Limit: 2, Offset: 2}, 1},
+	}
+	for _, tt := range tests {
+		got, err := driver.ListAttachments(ctx, "me@example.com", tt.filter)
+		if err != nil {
+			t.Fatalf("%s: 列出附件失败: %v", tt.name, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("%s: 附件数量 = %d, want %d", tt.name, len(got), tt.want)
+		}
+	}
+
+	// 删除邮件后附件元数据一并删除
+	if err := driver.DeleteMail(ctx, "m1"); err != nil {
+		t.Fatalf("删除邮件失败: %v", err)
+	}
+	if atts, _ := driver.ListMailAttachments(ctx, "m1"); len(atts) != 0 {
+		t.Errorf("删除邮件后附件应该被删除, got %d", len(atts))
+	}
+}
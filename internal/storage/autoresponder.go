@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultAutoReplyDays 同一发件人的默认回复间隔（天，RFC 5230 建议 7 天）
+const DefaultAutoReplyDays = 7
+
+// autoResponderColumns 自动回复查询的列（与 scanAutoResponder 对应）
+const autoResponderColumns = `user_email, COALESCE(enabled, 0), subject, body, starts_at, ends_at, interval_days, updated_at`
+
+// scanAutoResponder 扫描一行自动回复设置
+func scanAutoResponder(row rowScanner) (*AutoResponder, error) {
+	var responder AutoResponder
+	var enabled int
+	var startsAt, endsAt sql.NullTime
+	if err := row.Scan(
+		&responder.UserEmail,
+		&enabled,
+		&responder.Subject,
+		&responder.Body,
+		&startsAt,
+		&endsAt,
+		&responder.IntervalDays,
+		&responder.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	responder.Enabled = enabled == 1
+	if startsAt.Valid {
+		responder.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		responder.EndsAt = &endsAt.Time
+	}
+	return &responder, nil
+}
+
+// Active 自动回复在指定时间是否生效
+func (r *AutoResponder) Active(now time.Time) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.StartsAt != nil && now.Before(*r.StartsAt) {
+		return false
+	}
+	return r.EndsAt == nil || now.Before(*r.EndsAt)
+}
+
+// Interval 同一发件人的最小回复间隔（未设置时使用默认值）
+func (r *AutoResponder) Interval() time.Duration {
+	days := r.IntervalDays
+	if days <= 0 {
+		days = DefaultAutoReplyDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GetAutoResponder 获取用户的自动回复设置
+func (d *SQLiteDriver) GetAutoResponder(ctx context.Context, userEmail string) (*AutoResponder, error) {
+	query := `SELECT ` + autoResponderColumns + ` FROM auto_responders WHERE user_email = ?`
+	responder, err := scanAutoResponder(d.db.QueryRowContext(ctx, query, userEmail))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("自动回复不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询自动回复失败: %w", err)
+	}
+	return responder, nil
+}
+
+// SaveAutoResponder 保存用户的自动回复设置（按用户唯一），并清除已回复记录，新的设置对所有发件人重新回复一次
+func (d *SQLiteDriver) SaveAutoResponder(ctx context.Context, responder *AutoResponder) error {
+	if responder.IntervalDays <= 0 {
+		responder.IntervalDays = DefaultAutoReplyDays
+	}
+	enabled := 0
+	if responder.Enabled {
+		enabled = 1
+	}
+	responder.UpdatedAt = utcNow()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("保存自动回复失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO auto_responders (user_email, enabled, subject, body, starts_at, ends_at, interval_days, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			enabled = excluded.enabled,
+			subject = excluded.subject,
+			body = excluded.body,
+			starts_at = excluded.starts_at,
+			ends_at = excluded.ends_at,
+			interval_days = excluded.interval_days,
+			updated_at = excluded.updated_at
+	`,
+		responder.UserEmail,
+		enabled,
+		responder.Subject,
+		responder.Body,
+		responder.StartsAt,
+		responder.EndsAt,
+		responder.IntervalDays,
+		responder.UpdatedAt,
+	); err != nil {
+		return fmt.Errorf("保存自动回复失败: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM auto_reply_log WHERE user_email = ?`, responder.UserEmail); err != nil {
+		return fmt.Errorf("清除自动回复记录失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("保存自动回复失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteAutoResponder 删除用户的自动回复设置和已回复记录
+func (d *SQLiteDriver) DeleteAutoResponder(ctx context.Context, userEmail string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("删除自动回复失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM auto_responders WHERE user_email = ?`, userEmail); err != nil {
+		return fmt.Errorf("删除自动回复失败: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM auto_reply_log WHERE user_email = ?`, userEmail); err != nil {
+		return fmt.Errorf("清除自动回复记录失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("删除自动回复失败: %w", err)
+	}
+	return nil
+}
+
+// RecordAutoReply 记录向发件人发送了自动回复；距上次回复不足 interval 时不更新记录并返回 false
+//
+// 插入和间隔判断在同一条语句中完成，同一发件人的并发投递只有一个会得到 true。
+func (d *SQLiteDriver) RecordAutoReply(ctx context.Context, userEmail, sender string, interval time.Duration, now time.Time) (bool, error) {
+	result, err := d.db.ExecContext(ctx, `
+		INSERT INTO auto_reply_log (user_email, sender, replied_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_email, sender) DO UPDATE SET
+			replied_at = excluded.replied_at
+		WHERE auto_reply_log.replied_at <= ?
+	`,
+		userEmail,
+		strings.ToLower(sender),
+		now,
+		now.Add(-interval),
+	)
+	if err != nil {
+		return false, fmt.Errorf("记录自动回复失败: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("记录自动回复失败: %w", err)
+	}
+	return affected > 0, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_AutoResponderOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &User{Email: "alice@example.com", PasswordHash: "test_hash", Active: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	if _, err := driver.GetAutoResponder(ctx, "alice@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未设置自动回复时应该返回 ErrNotFound, got %v", err)
+	}
+
+	now := time.Now()
+	endsAt := now.Add(72 * time.Hour)
+	responder := &AutoResponder{UserEmail: "alice@example.com", Enabled: true, Subject: "休假中", Body: "下周回来", EndsAt: &endsAt}
+	if err := driver.SaveAutoResponder(ctx, responder); err != nil {
+		t.Fatalf("保存自动回复失败: %v", err)
+	}
+	got, err := driver.GetAutoResponder(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("获取自动回复失败: %v", err)
+	}
+	if !got.Enabled || got.Subject != "休假中" || got.Body != "下周回来" || got.EndsAt == nil || got.StartsAt != nil {
+		t.Errorf("自动回复不正确: %+v", got)
+	}
+	if got.IntervalDays != DefaultAutoReplyDays {
+		t.Errorf("未设置间隔时应该使用默认值, got %d", got.IntervalDays)
+	}
+	if !got.Active(now) || got.Active(endsAt) {
+		t.Errorf("生效时间判断不正确: %+v", got)
+	}
+
+	// 同一发件人在间隔内只回复一次（发件人不区分大小写）
+	interval := got.Interval()
+	if ok, err := driver.RecordAutoReply(ctx, "alice@example.com", "bob@example.net", interval, now); err != nil || !ok {
+		t.Fatalf("第一次应该回复: %v, %v", ok, err)
+	}
+	if ok, err := driver.RecordAutoReply(ctx, "alice@example.com", "Bob@Example.net", interval, now.Add(time.Hour)); err != nil || ok {
+		t.Errorf("间隔内不应该再次回复: %v, %v", ok, err)
+	}
+	if ok, err := driver.RecordAutoReply(ctx, "alice@example.com", "carol@example.net", interval, now.Add(time.Hour)); err != nil || !ok {
+		t.Errorf("其他发件人应该回复: %v, %v", ok, err)
+	}
+	if ok, err := driver.RecordAutoReply(ctx, "alice@example.com", "bob@example.net", interval, now.Add(interval+time.Second)); err != nil || !ok {
+		t.Errorf("超过间隔后应该再次回复: %v, %v", ok, err)
+	}
+
+	// 修改设置后重新回复所有发件人
+	if err := driver.SaveAutoResponder(ctx, got); err != nil {
+		t.Fatalf("保存自动回复失败: %v", err)
+	}
+	if ok, err := driver.RecordAutoReply(ctx, "alice@example.com", "carol@example.net", interval, now.Add(2*time.Hour)); err != nil || !ok {
+		t.Errorf("修改设置后应该重新回复: %v, %v", ok, err)
+	}
+
+	if err := driver.DeleteAutoResponder(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("删除自动回复失败: %v", err)
+	}
+	if _, err := driver.GetAutoResponder(ctx, "alice@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后应该返回 ErrNotFound, got %v", err)
+	}
+}
+
+func TestAutoResponder_Active(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		responder AutoResponder
+		want      bool
+	}{
+		{"disabled", AutoResponder{}, false},
+		{"open ended", AutoResponder{Enabled: true}, true},
+		{"not started", AutoResponder{Enabled: true, StartsAt: &later}, false},
+		{"started", AutoResponder{Enabled: true, StartsAt: &earlier, EndsAt: &later}, true},
+		{"ended", AutoResponder{Enabled: true, EndsAt: &earlier}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.responder.Active(now); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
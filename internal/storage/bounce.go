@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BounceRetention 退信记录的保留时间
+const BounceRetention = 90 * 24 * time.Hour
+
+// RecordBounce 记录一条退信，同时清理超过保留时间的记录
+func (d *SQLiteDriver) RecordBounce(ctx context.Context, bounce *Bounce) error {
+	now := utcNow()
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM bounces WHERE created_at < ?`, now.Add(-BounceRetention)); err != nil {
+		return fmt.Errorf("清理过期退信记录失败: %w", err)
+	}
+
+	if bounce.Domain == "" {
+		bounce.Domain = strings.ToLower(bounce.Recipient[strings.LastIndex(bounce.Recipient, "@")+1:])
+	}
+	query := `
+		INSERT INTO bounces (sender, recipient, domain, category, status, diagnostic, source, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := d.db.ExecContext(ctx, query,
+		bounce.Sender,
+		bounce.Recipient,
+		bounce.Domain,
+		bounce.Category,
+		bounce.Status,
+		bounce.Diagnostic,
+		bounce.Source,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("记录退信失败: %w", constraintError(err))
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		bounce.ID = id
+	}
+	bounce.CreatedAt = now
+	return nil
+}
+
+// GetBounceStats 统计 since 之后的退信：总数、各分类的数量，以及退信最多的 limit 个目标域名和发件人
+func (d *SQLiteDriver) GetBounceStats(ctx context.Context, since time.Time, limit int) (*BounceStats, error) {
+	query := `
+		SELECT domain, sender, category, COUNT(*)
+		FROM bounces
+		WHERE created_at >= ?
+		GROUP BY domain, sender, category
+	`
+	rows, err := d.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("查询退信统计失败: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &BounceStats{Since: since, Categories: make(map[string]int)}
+	domains := make(map[string]*BounceCount)
+	senders := make(map[string]*BounceCount)
+	add := func(counts map[string]*BounceCount, key, category string, n int) {
+		c, ok := counts[key]
+		if !ok {
+			c = &BounceCount{Key: key, Categories: make(map[string]int)}
+			counts[key] = c
+		}
+		c.Total += n
+		c.Categories[category] += n
+	}
+	for rows.Next() {
+		var domain, sender, category string
+		var n int
+		if err := rows.Scan(&domain, &sender, &category, &n); err != nil {
+			return nil, fmt.Errorf("扫描退信统计失败: %w", err)
+		}
+		stats.Total += n
+		stats.Categories[category] += n
+		add(domains, domain, category, n)
+		add(senders, sender, category, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询退信统计失败: %w", err)
+	}
+
+	stats.Domains = topBounceCounts(domains, limit)
+	stats.Senders = topBounceCounts(senders, limit)
+	return stats, nil
+}
+
+// topBounceCounts 按退信数降序（相同时按名称）返回前 limit 项
+func topBounceCounts(counts map[string]*BounceCount, limit int) []*BounceCount {
+	list := make([]*BounceCount, 0, len(counts))
+	for _, c := range counts {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Key < list[j].Key
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
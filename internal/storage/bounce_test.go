@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBounceStats(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, b := range []*Bounce{
+		{Sender: "alice@example.com", Recipient: "a@gmail.test", Category: "user_unknown", Source: "smtp"},
+		{Sender: "alice@example.com", Recipient: "b@gmail.test", Category: "spam_block", Source: "dsn"},
+		{Sender: "bob@example.com", Recipient: "c@Gmail.test", Category: "spam_block", Source: "smtp"},
+		{Sender: "bob@example.com", Recipient: "d@corp.test", Category: "mailbox_full", Source: "dsn"},
+	} {
+		if err := driver.RecordBounce(ctx, b); err != nil {
+			t.Fatalf("记录退信失败: %v", err)
+		}
+		if b.ID == 0 || b.CreatedAt.IsZero() {
+			t.Errorf("记录后应该设置 ID 和时间: %+v", b)
+		}
+	}
+	// 超过保留时间的记录在下一次记录时清理
+	if _, err := driver.db.ExecContext(ctx, `INSERT INTO bounces (sender, recipient, domain, category, source, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		"old@example.com", "x@old.test", "old.test", "other", "smtp", time.Now().Add(-BounceRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.RecordBounce(ctx, &Bounce{Sender: "bob@example.com", Recipient: "e@corp.test", Category: "mailbox_full", Source: "smtp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := driver.GetBounceStats(ctx, time.Now().Add(-365*24*time.Hour), 1)
+	if err != nil {
+		t.Fatalf("统计退信失败: %v", err)
+	}
+	if stats.Total != 5 || stats.Categories["spam_block"] != 2 || stats.Categories["mailbox_full"] != 2 {
+		t.Errorf("退信统计 = %d %v", stats.Total, stats.Categories)
+	}
+	// 域名统一为小写，limit 只返回退信最多的一项
+	if len(stats.Domains) != 1 || stats.Domains[0].Key != "gmail.test" || stats.Domains[0].Total != 3 || stats.Domains[0].Categories["spam_block"] != 2 {
+		t.Errorf("按域名统计 = %+v", stats.Domains)
+	}
+	if len(stats.Senders) != 1 || stats.Senders[0].Key != "bob@example.com" || stats.Senders[0].Total != 3 {
+		t.Errorf("按发件人统计 = %+v", stats.Senders)
+	}
+
+	if stats, err := driver.GetBounceStats(ctx, time.Now().Add(time.Hour), 10); err != nil || stats.Total != 0 || len(stats.Domains) != 0 {
+		t.Errorf("时间范围外的统计 = %+v, %v", stats, err)
+	}
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// GetMailCategory 获取邮件的分类
+func (d *SQLiteDriver) GetMailCategory(ctx context.Context, mailID string) (*MailCategory, error) {
+	var c MailCategory
+	var tokens string
+	var trained int
+	err := d.db.QueryRowContext(ctx,
+		"SELECT mail_id, category, tokens, trained FROM mail_categories WHERE mail_id = ?", mailID,
+	).Scan(&c.MailID, &c.Category, &tokens, &trained)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("邮件分类不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件分类失败: %w", err)
+	}
+	c.Tokens = strings.Fields(tokens)
+	c.Trained = trained == 1
+	return &c, nil
+}
+
+// SaveMailCategory 保存邮件的分类（已存在时覆盖）
+func (d *SQLiteDriver) SaveMailCategory(ctx context.Context, c *MailCategory) error {
+	query := `
+		INSERT INTO mail_categories (mail_id, category, tokens, trained)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(mail_id) DO UPDATE SET
+			category = excluded.category,
+			tokens = excluded.tokens,
+			trained = excluded.trained
+	`
+	trained := 0
+	if c.Trained {
+		trained = 1
+	}
+	if _, err := d.db.ExecContext(ctx, query, c.MailID, c.Category, strings.Join(c.Tokens, " "), trained); err != nil {
+		return fmt.Errorf("保存邮件分类失败: %w", err)
+	}
+	return nil
+}
+
+// GetCategoryModel 获取用户的分类模型中与指定特征相关的部分
+func (d *SQLiteDriver) GetCategoryModel(ctx context.Context, userEmail string, tokens []string) (*CategoryModel, error) {
+	model := &CategoryModel{
+		Docs:   make(map[string]int),
+		Tokens: make(map[string]int),
+		Counts: make(map[string]map[string]int),
+	}
+
+	rows, err := d.db.QueryContext(ctx, "SELECT category, docs, tokens FROM category_docs WHERE user_email = ?", userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询分类模型失败: %w", err)
+	}
+	for rows.Next() {
+		var category string
+		var docs, total int
+		if err := rows.Scan(&category, &docs, &total); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("扫描分类模型失败: %w", err)
+		}
+		model.Docs[category] = docs
+		model.Tokens[category] = total
+		model.Counts[category] = make(map[string]int)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询分类模型失败: %w", err)
+	}
+
+	if err := d.db.QueryRowContext(ctx,
+		"SELECT COUNT(DISTINCT token) FROM category_tokens WHERE user_email = ? AND count > 0", userEmail,
+	).Scan(&model.Vocabulary); err != nil {
+		return nil, fmt.Errorf("查询分类模型失败: %w", err)
+	}
+
+	if len(tokens) == 0 || len(model.Docs) == 0 {
+		return model, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tokens)), ",")
+	args := []interface{}{userEmail}
+	for _, t := range tokens {
+		args = append(args, t)
+	}
+	rows, err = d.db.QueryContext(ctx,
+		"SELECT category, token, count FROM category_tokens WHERE user_email = ? AND token IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询分类特征失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category, token string
+		var count int
+		if err := rows.Scan(&category, &token, &count); err != nil {
+			return nil, fmt.Errorf("扫描分类特征失败: %w", err)
+		}
+		if model.Counts[category] == nil {
+			model.Counts[category] = make(map[string]int)
+		}
+		model.Counts[category][token] = count
+	}
+	return model, rows.Err()
+}
+
+// TrainCategory 将一封邮件的特征计入（delta 为 1）或移出（delta 为 -1）用户的分类模型
+func (d *SQLiteDriver) TrainCategory(ctx context.Context, userEmail, category string, tokens []string, delta int) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("训练分类模型失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO category_docs (user_email, category, docs, tokens)
+		VALUES (?, ?, MAX(?, 0), MAX(?, 0))
+		ON CONFLICT(user_email, category) DO UPDATE SET
+			docs = MAX(docs + ?, 0),
+			tokens = MAX(tokens + ?, 0)
+	`, userEmail, category, delta, delta*len(tokens), delta, delta*len(tokens)); err != nil {
+		return fmt.Errorf("训练分类模型失败: %w", err)
+	}
+	for _, token := range tokens {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO category_tokens (user_email, category, token, count)
+			VALUES (?, ?, ?, MAX(?, 0))
+			ON CONFLICT(user_email, category, token) DO UPDATE SET
+				count = MAX(count + ?, 0)
+		`, userEmail, category, token, delta, delta); err != nil {
+			return fmt.Errorf("训练分类模型失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("训练分类模型失败: %w", err)
+	}
+	return nil
+}
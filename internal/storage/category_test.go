@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_CategoryModel(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	user := "me@example.com"
+	if err := driver.TrainCategory(ctx, user, "social", []string{"from:a@x.test", "subject:hike"}, 1); err != nil {
+		t.Fatalf("训练失败: %v", err)
+	}
+	if err := driver.TrainCategory(ctx, user, "social", []string{"from:a@x.test"}, 1); err != nil {
+		t.Fatalf("训练失败: %v", err)
+	}
+	if err := driver.TrainCategory(ctx, user, "primary", []string{"subject:hike"}, 1); err != nil {
+		t.Fatalf("训练失败: %v", err)
+	}
+
+	model, err := driver.GetCategoryModel(ctx, user, []string{"from:a@x.test", "subject:none"})
+	if err != nil {
+		t.Fatalf("查询模型失败: %v", err)
+	}
+	if model.Docs["social"] != 2 || model.Tokens["social"] != 3 || model.Docs["primary"] != 1 {
+		t.Errorf("样本统计不正确: %+v %+v", model.Docs, model.Tokens)
+	}
+	if model.Counts["social"]["from:a@x.test"] != 2 || model.Counts["primary"]["from:a@x.test"] != 0 {
+		t.Errorf("特征计数不正确: %+v", model.Counts)
+	}
+	if model.Vocabulary != 2 {
+		t.Errorf("Vocabulary = %d, want 2", model.Vocabulary)
+	}
+
+	// 撤销样本，计数不会小于 0
+	for i := 0; i < 3; i++ {
+		if err := driver.TrainCategory(ctx, user, "social", []string{"from:a@x.test"}, -1); err != nil {
+			t.Fatalf("撤销训练失败: %v", err)
+		}
+	}
+	model, _ = driver.GetCategoryModel(ctx, user, []string{"from:a@x.test"})
+	if model.Docs["social"] != 0 || model.Counts["social"]["from:a@x.test"] != 0 {
+		t.Errorf("撤销后模型不正确: %+v %+v", model.Docs, model.Counts)
+	}
+
+	// 邮件分类
+	if _, err := driver.GetMailCategory(ctx, "m1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的邮件分类应该返回 ErrNotFound, got %v", err)
+	}
+	if err := driver.StoreMail(ctx, &Mail{ID: "m1", UserEmail: user, Folder: "INBOX", ReceivedAt: time.Now()}); err != nil {
+		t.Fatalf("存储邮件失败: %v", err)
+	}
+	if err := driver.SaveMailCategory(ctx, &MailCategory{MailID: "m1", Category: "promotions", Tokens: []string{"a", "b"}}); err != nil {
+		t.Fatalf("保存邮件分类失败: %v", err)
+	}
+	if err := driver.SaveMailCategory(ctx, &MailCategory{MailID: "m1", Category: "primary", Tokens: []string{"a", "b"}, Trained: true}); err != nil {
+		t.Fatalf("保存邮件分类失败: %v", err)
+	}
+	c, err := driver.GetMailCategory(ctx, "m1")
+	if err != nil || c.Category != "primary" || !c.Trained || len(c.Tokens) != 2 {
+		t.Errorf("邮件分类不正确: %+v %v", c, err)
+	}
+
+	// 删除邮件时一并删除分类
+	if err := driver.DeleteMail(ctx, "m1"); err != nil {
+		t.Fatalf("删除邮件失败: %v", err)
+	}
+	if _, err := driver.GetMailCategory(ctx, "m1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除邮件后分类应该不存在, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetCRAMMD5Secret 获取用户的 CRAM-MD5 密钥
+func (d *SQLiteDriver) GetCRAMMD5Secret(ctx context.Context, userEmail string) (*CRAMMD5Secret, error) {
+	query := `SELECT user_email, secret, password_hash, updated_at FROM cram_md5_secrets WHERE user_email = ?`
+	var secret CRAMMD5Secret
+	err := d.db.QueryRowContext(ctx, query, userEmail).Scan(
+		&secret.UserEmail,
+		&secret.Secret,
+		&secret.PasswordHash,
+		&secret.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("CRAM-MD5 密钥不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 CRAM-MD5 密钥失败: %w", err)
+	}
+	return &secret, nil
+}
+
+// SaveCRAMMD5Secret 保存用户的 CRAM-MD5 密钥（按用户唯一）
+func (d *SQLiteDriver) SaveCRAMMD5Secret(ctx context.Context, secret *CRAMMD5Secret) error {
+	query := `
+		INSERT INTO cram_md5_secrets (user_email, secret, password_hash, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			secret = excluded.secret,
+			password_hash = excluded.password_hash,
+			updated_at = excluded.updated_at
+	`
+	secret.UpdatedAt = utcNow()
+	if _, err := d.db.ExecContext(ctx, query, secret.UserEmail, secret.Secret, secret.PasswordHash, secret.UpdatedAt); err != nil {
+		return fmt.Errorf("保存 CRAM-MD5 密钥失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DeliveryLogRetention 投递审计日志的保留时间
+const DeliveryLogRetention = 30 * 24 * time.Hour
+
+// deliveryLogColumns 查询投递审计日志的列
+const deliveryLogColumns = `id, queue_id, message_id, event, sender, recipient, folder, code, status, detail, created_at`
+
+// RecordDeliveryEvent 记录投递审计日志，同时清理超过保留时间的记录
+func (d *SQLiteDriver) RecordDeliveryEvent(ctx context.Context, event *DeliveryEvent) error {
+	now := utcNow()
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM delivery_log WHERE created_at < ?`, now.Add(-DeliveryLogRetention)); err != nil {
+		return fmt.Errorf("清理过期投递日志失败: %w", err)
+	}
+
+	event.MessageID = normalizeMessageID(event.MessageID)
+	query := `
+		INSERT INTO delivery_log (queue_id, message_id, event, sender, recipient, folder, code, status, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := d.db.ExecContext(ctx, query,
+		event.QueueID,
+		event.MessageID,
+		event.Event,
+		strings.ToLower(event.Sender),
+		strings.ToLower(event.Recipient),
+		event.Folder,
+		event.Code,
+		event.Status,
+		event.Detail,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("记录投递日志失败: %w", constraintError(err))
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		event.ID = id
+	}
+	event.CreatedAt = now
+	return nil
+}
+
+// SearchDeliveryLog 查询 since 之后的投递日志（按时间倒序，最多 limit 条）：
+// query 可以是队列 ID、Message-ID 或发件人、收件人地址的一部分，为空时返回全部
+func (d *SQLiteDriver) SearchDeliveryLog(ctx context.Context, query string, since time.Time, limit int) ([]*DeliveryEvent, error) {
+	sqlQuery := `SELECT ` + deliveryLogColumns + ` FROM delivery_log WHERE created_at >= ?`
+	args := []interface{}{since}
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + strings.ToLower(query) + "%"
+		sqlQuery += ` AND (queue_id = ? OR message_id = ? OR sender LIKE ? OR recipient LIKE ?)`
+		args = append(args, query, normalizeMessageID(query), pattern, pattern)
+	}
+	sqlQuery += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+	return d.queryDeliveryLog(ctx, sqlQuery, args...)
+}
+
+// GetDeliveryTrace 查询一封邮件的全部投递日志（按时间顺序）：id 为队列 ID 或 Message-ID，
+// 同时包含同一 Message-ID 在其他事务中的记录（如推迟后再发出）；没有记录时返回 ErrNotFound
+func (d *SQLiteDriver) GetDeliveryTrace(ctx context.Context, id string) ([]*DeliveryEvent, error) {
+	id = strings.TrimSpace(id)
+	query := `
+		SELECT ` + deliveryLogColumns + ` FROM delivery_log
+		WHERE queue_id IN (SELECT queue_id FROM delivery_log WHERE queue_id != '' AND (queue_id = ? OR message_id = ?))
+		   OR message_id IN (SELECT message_id FROM delivery_log WHERE message_id != '' AND (queue_id = ? OR message_id = ?))
+		ORDER BY created_at, id
+	`
+	messageID := normalizeMessageID(id)
+	events, err := d.queryDeliveryLog(ctx, query, id, messageID, id, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("投递日志不存在: %w", ErrNotFound)
+	}
+	return events, nil
+}
+
+// queryDeliveryLog 执行查询并扫描投递日志
+func (d *SQLiteDriver) queryDeliveryLog(ctx context.Context, query string, args ...interface{}) ([]*DeliveryEvent, error) {
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询投递日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*DeliveryEvent
+	for rows.Next() {
+		e := &DeliveryEvent{}
+		if err := rows.Scan(&e.ID, &e.QueueID, &e.MessageID, &e.Event, &e.Sender, &e.Recipient,
+			&e.Folder, &e.Code, &e.Status, &e.Detail, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描投递日志失败: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询投递日志失败: %w", err)
+	}
+	return events, nil
+}
+
+// normalizeMessageID 去掉 Message-ID 的空白和尖括号
+func normalizeMessageID(id string) string {
+	return strings.Trim(strings.TrimSpace(id), "<>")
+}
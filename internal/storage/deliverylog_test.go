@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeliveryLog(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, e := range []*DeliveryEvent{
+		{QueueID: "Q1", MessageID: "<m1@example.com>", Event: DeliveryReceived, Sender: "Alice@example.com", Recipient: "bob@example.com", Code: 250},
+		{QueueID: "Q1", MessageID: "m1@example.com", Event: DeliveryDelivered, Sender: "alice@example.com", Recipient: "bob@example.com", Folder: "INBOX"},
+		{QueueID: "Q1", MessageID: "m1@example.com", Event: DeliveryDeferred, Sender: "alice@example.com", Recipient: "carol@gmail.test", Detail: "预热限额"},
+		// 推迟后再发出的记录没有队列 ID，按 Message-ID 关联
+		{MessageID: "m1@example.com", Event: DeliveryRelayed, Sender: "alice@example.com", Recipient: "carol@gmail.test", Code: 250},
+		{QueueID: "Q2", MessageID: "m2@example.com", Event: DeliveryReceived, Sender: "dave@remote.test", Recipient: "bob@example.com", Code: 250},
+	} {
+		if err := driver.RecordDeliveryEvent(ctx, e); err != nil {
+			t.Fatalf("记录投递日志失败: %v", err)
+		}
+		if e.ID == 0 || e.CreatedAt.IsZero() {
+			t.Errorf("记录后应该设置 ID 和时间: %+v", e)
+		}
+	}
+	// 超过保留时间的记录在下一次记录时清理
+	if _, err := driver.db.ExecContext(ctx, `INSERT INTO delivery_log (queue_id, event, created_at) VALUES (?, ?, ?)`,
+		"OLD", DeliveryReceived, time.Now().Add(-DeliveryLogRetention-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.RecordDeliveryEvent(ctx, &DeliveryEvent{QueueID: "Q3", Event: DeliveryReceived}); err != nil {
+		t.Fatal(err)
+	}
+
+	since := time.Now().Add(-365 * 24 * time.Hour)
+	all, err := driver.SearchDeliveryLog(ctx, "", since, 100)
+	if err != nil {
+		t.Fatalf("查询投递日志失败: %v", err)
+	}
+	if len(all) != 6 || all[0].QueueID != "Q3" {
+		t.Fatalf("应该返回 6 条未过期的记录（最新的在前），实际 %d", len(all))
+	}
+
+	// 按地址的一部分、队列 ID 或 Message-ID（带或不带尖括号）查询
+	for query, want := range map[string]int{"gmail.test": 2, "ALICE@": 4, "Q2": 1, "<m2@example.com>": 1, "nobody": 0} {
+		events, err := driver.SearchDeliveryLog(ctx, query, since, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != want {
+			t.Errorf("查询 %q 返回 %d 条，want %d", query, len(events), want)
+		}
+	}
+	if events, _ := driver.SearchDeliveryLog(ctx, "", since, 2); len(events) != 2 {
+		t.Errorf("limit 应该限制返回的数量: %d", len(events))
+	}
+
+	// 按队列 ID 追踪时包含同一 Message-ID 推迟后发出的记录
+	trace, err := driver.GetDeliveryTrace(ctx, "Q1")
+	if err != nil {
+		t.Fatalf("追踪邮件失败: %v", err)
+	}
+	if len(trace) != 4 || trace[0].Event != DeliveryReceived || trace[3].Event != DeliveryRelayed {
+		t.Errorf("追踪结果不正确: %d 条", len(trace))
+	}
+	if trace[0].MessageID != "m1@example.com" || trace[0].Sender != "alice@example.com" {
+		t.Errorf("Message-ID 和地址应该规范化: %+v", trace[0])
+	}
+	if trace, err := driver.GetDeliveryTrace(ctx, "<m1@example.com>"); err != nil || len(trace) != 4 {
+		t.Errorf("按 Message-ID 追踪应该返回 4 条: %d, %v", len(trace), err)
+	}
+	if _, err := driver.GetDeliveryTrace(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("没有记录时应该返回 ErrNotFound: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DigestFolder 统计文件夹中 since 之后收到的邮件（unseen 为 true 时只统计未读邮件），返回邮件数和最近的 limit 封
+func (d *SQLiteDriver) DigestFolder(ctx context.Context, userEmail, folder string, unseen bool, since time.Time, limit int) (*FolderDigest, error) {
+	where := `user_email = ? AND folder = ? AND received_at >= ?`
+	if unseen {
+		where += ` AND (',' || COALESCE(flags, '') || ',') NOT LIKE '%,\Seen,%'`
+	}
+	args := []any{userEmail, folder, since}
+
+	digest := &FolderDigest{Folder: folder, Mails: []*Mail{}}
+	if err := d.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM mails WHERE `+where, args...).Scan(&digest.Count); err != nil {
+		return nil, fmt.Errorf("统计邮件失败: %w", err)
+	}
+	if digest.Count == 0 || limit <= 0 {
+		return digest, nil
+	}
+
+	query := `SELECT id, from_addr, subject, received_at FROM mails WHERE ` + where + ` ORDER BY received_at DESC, id LIMIT ?`
+	rows, err := d.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		mail := &Mail{UserEmail: userEmail, Folder: folder}
+		var subject sql.NullString
+		if err := rows.Scan(&mail.ID, &mail.From, &subject, &mail.ReceivedAt); err != nil {
+			return nil, fmt.Errorf("扫描邮件失败: %w", err)
+		}
+		mail.Subject = subject.String
+		digest.Mails = append(digest.Mails, mail)
+	}
+	return digest, rows.Err()
+}
+
+// GetDigestSentAt 获取用户最近一次发送邮件摘要的时间
+func (d *SQLiteDriver) GetDigestSentAt(ctx context.Context, userEmail string) (time.Time, error) {
+	var sentAt time.Time
+	err := d.db.QueryRowContext(ctx, `SELECT sent_at FROM digest_log WHERE user_email = ?`, userEmail).Scan(&sentAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("摘要发送记录不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("查询摘要发送记录失败: %w", err)
+	}
+	return sentAt, nil
+}
+
+// SaveDigestSentAt 记录用户最近一次发送邮件摘要的时间
+func (d *SQLiteDriver) SaveDigestSentAt(ctx context.Context, userEmail string, at time.Time) error {
+	query := `
+		INSERT INTO digest_log (user_email, sent_at)
+		VALUES (?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET sent_at = excluded.sent_at
+	`
+	if _, err := d.db.ExecContext(ctx, query, userEmail, at); err != nil {
+		return fmt.Errorf("保存摘要发送记录失败: %w", constraintError(err))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDigest(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+	const user = "alice@example.com"
+	if err := driver.CreateUser(ctx, &User{Email: user, PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	for _, m := range []*Mail{
+		{ID: "m1", Folder: "INBOX", Subject: "旧邮件", ReceivedAt: now.Add(-48 * time.Hour)},
+		{ID: "m2", Folder: "INBOX", Subject: "新邮件", ReceivedAt: now.Add(-time.Hour)},
+		{ID: "m3", Folder: "INBOX", Subject: "已读", Flags: []string{"\\Seen"}, ReceivedAt: now.Add(-time.Hour)},
+		{ID: "m4", Folder: "INBOX", Subject: "最新", Flags: []string{"\\Flagged"}, ReceivedAt: now},
+		{ID: "m5", Folder: "Spam", Subject: "广告", ReceivedAt: now},
+	} {
+		m.UserEmail = user
+		if err := driver.StoreMail(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 未读邮件按接收时间倒序，limit 只限制列出的邮件
+	digest, err := driver.DigestFolder(ctx, user, "INBOX", true, time.Time{}, 2)
+	if err != nil {
+		t.Fatalf("统计文件夹失败: %v", err)
+	}
+	if digest.Count != 3 || len(digest.Mails) != 2 || digest.Mails[0].ID != "m4" || digest.Mails[1].ID != "m2" {
+		t.Errorf("未读邮件 = %d %v", digest.Count, digest.Mails)
+	}
+	digest, err = driver.DigestFolder(ctx, user, "INBOX", false, now.Add(-2*time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest.Count != 3 || len(digest.Mails) != 3 {
+		t.Errorf("since 之后的邮件 = %d", digest.Count)
+	}
+	if digest, _ := driver.DigestFolder(ctx, user, "Archive", false, time.Time{}, 10); digest.Count != 0 || digest.Mails == nil {
+		t.Errorf("空文件夹 = %+v", digest)
+	}
+
+	if _, err := driver.GetDigestSentAt(ctx, user); !errors.Is(err, ErrNotFound) {
+		t.Errorf("没有发送记录时应该返回 ErrNotFound: %v", err)
+	}
+	for _, at := range []time.Time{now.Add(-time.Hour), now} {
+		if err := driver.SaveDigestSentAt(ctx, user, at); err != nil {
+			t.Fatalf("保存发送记录失败: %v", err)
+		}
+	}
+	if sentAt, err := driver.GetDigestSentAt(ctx, user); err != nil || !sentAt.Equal(now) {
+		t.Errorf("发送时间 = %v, %v, want %v", sentAt, err, now)
+	}
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SaveDKIMKey 保存域名的 DKIM 密钥（每个域名一个，重新生成时替换）
+func (d *SQLiteDriver) SaveDKIMKey(ctx context.Context, key *DKIMKey) error {
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	key.Domain = strings.ToLower(key.Domain)
+	key.CreatedAt = key.CreatedAt.UTC()
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO dkim_keys (domain, selector, algorithm, key_file, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(domain) DO UPDATE SET
+			selector = excluded.selector,
+			algorithm = excluded.algorithm,
+			key_file = excluded.key_file,
+			created_at = excluded.created_at
+	`, key.Domain, key.Selector, key.Algorithm, key.KeyFile, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("保存 DKIM 密钥失败: %w", err)
+	}
+	return nil
+}
+
+// GetDKIMKey 获取域名的 DKIM 密钥
+func (d *SQLiteDriver) GetDKIMKey(ctx context.Context, domain string) (*DKIMKey, error) {
+	var key DKIMKey
+	err := d.db.QueryRowContext(ctx, `
+		SELECT domain, selector, algorithm, key_file, created_at
+		FROM dkim_keys WHERE domain = ?
+	`, strings.ToLower(domain)).Scan(&key.Domain, &key.Selector, &key.Algorithm, &key.KeyFile, &key.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("DKIM 密钥不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询 DKIM 密钥失败: %w", err)
+	}
+	return &key, nil
+}
+
+// ListDKIMKeys 列出所有域名的 DKIM 密钥
+func (d *SQLiteDriver) ListDKIMKeys(ctx context.Context) ([]*DKIMKey, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT domain, selector, algorithm, key_file, created_at
+		FROM dkim_keys ORDER BY domain
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询 DKIM 密钥列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*DKIMKey{}
+	for rows.Next() {
+		var key DKIMKey
+		if err := rows.Scan(&key.Domain, &key.Selector, &key.Algorithm, &key.KeyFile, &key.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描 DKIM 密钥失败: %w", err)
+		}
+		keys = append(keys, &key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("遍历 DKIM 密钥失败: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSQLiteDriver_DKIMKeys(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+
+	if _, err := driver.GetDKIMKey(ctx, "example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("没有密钥时应该返回 ErrNotFound: %v", err)
+	}
+
+	if err := driver.SaveDKIMKey(ctx, &DKIMKey{Domain: "Example.com", Selector: "default", Algorithm: "rsa", KeyFile: "dkim/example.com.default.key"}); err != nil {
+		t.Fatalf("保存 DKIM 密钥失败: %v", err)
+	}
+	// 重新生成时替换
+	if err := driver.SaveDKIMKey(ctx, &DKIMKey{Domain: "example.com", Selector: "s2", Algorithm: "ed25519", KeyFile: "dkim/example.com.s2.key"}); err != nil {
+		t.Fatalf("更新 DKIM 密钥失败: %v", err)
+	}
+	if err := driver.SaveDKIMKey(ctx, &DKIMKey{Domain: "another.org", Selector: "default", Algorithm: "rsa", KeyFile: "dkim/another.org.default.key"}); err != nil {
+		t.Fatalf("保存 DKIM 密钥失败: %v", err)
+	}
+
+	key, err := driver.GetDKIMKey(ctx, "EXAMPLE.COM")
+	if err != nil {
+		t.Fatalf("获取 DKIM 密钥失败: %v", err)
+	}
+	if key.Selector != "s2" || key.Algorithm != "ed25519" || key.KeyFile != "dkim/example.com.s2.key" || key.CreatedAt.IsZero() {
+		t.Errorf("DKIM 密钥未更新: %+v", key)
+	}
+
+	keys, err := driver.ListDKIMKeys(ctx)
+	if err != nil {
+		t.Fatalf("列出 DKIM 密钥失败: %v", err)
+	}
+	if len(keys) != 2 || keys[0].Domain != "another.org" || keys[1].Domain != "example.com" {
+		t.Errorf("DKIM 密钥列表不正确: %+v", keys)
+	}
+}
@@ -24,23 +24,235 @@ type Driver interface {
 	// 别名管理
 	CreateAlias(ctx context.Context, alias *Alias) error
 	GetAlias(ctx context.Context, from string) (*Alias, error)
+	UpdateAliasDestinations(ctx context.Context, from string, destinations []string) error
 	DeleteAlias(ctx context.Context, from string) error
 	ListAliases(ctx context.Context, domain string) ([]*Alias, error)
+	ListAliasesByOwner(ctx context.Context, owner string) ([]*Alias, error)
+	BurnAlias(ctx context.Context, from string) error
+	RecordAliasDelivery(ctx context.Context, from string) error
+	DeleteInactiveDisposableAliases(ctx context.Context, before time.Time) (int, error)
+	GetAliasPolicy(ctx context.Context, domain string) (*AliasPolicy, error)
+	SaveAliasPolicy(ctx context.Context, policy *AliasPolicy) error
 
 	// 邮件管理
 	StoreMail(ctx context.Context, mail *Mail) error
 	GetMail(ctx context.Context, id string) (*Mail, error)
 	GetMailBody(ctx context.Context, userEmail string, folder string, mailID string) ([]byte, error)
 	ListMails(ctx context.Context, userEmail string, folder string, limit, offset int) ([]*Mail, error)
+	CountMails(ctx context.Context, userEmail string, folder string) (int, error)
+	// FindMailsByMessageID 查找用户邮箱中 Message-ID 相同的邮件（同一邮件在各文件夹中的副本）
+	FindMailsByMessageID(ctx context.Context, userEmail, messageID string) ([]*Mail, error)
+	// ListMailIDs 按 UID 游标分页列出邮件（UID 大于 afterUID），用于遍历大邮箱
+	ListMailIDs(ctx context.Context, userEmail string, folder string, afterUID uint32, limit int) ([]*Mail, error)
 	DeleteMail(ctx context.Context, id string) error
 	UpdateMailFlags(ctx context.Context, id string, flags []string) error
-	SearchMails(ctx context.Context, userEmail string, query string, folder string, limit, offset int) ([]*Mail, error)
+	MoveMail(ctx context.Context, id, folder string) (uint32, error)
+	// GetMailChanges 增量同步：获取自修改序号 since 以来新增、更新和删除的邮件
+	GetMailChanges(ctx context.Context, userEmail string, since uint64, limit int) (*MailChanges, error)
+	// GetHighestModSeq 获取文件夹的最大修改序号（IMAP HIGHESTMODSEQ）
+	GetHighestModSeq(ctx context.Context, userEmail, folder string) (uint64, error)
+	// ListExpungedUIDs 列出自修改序号 since 以来从文件夹中删除或移出的邮件的 UID（IMAP VANISHED）
+	ListExpungedUIDs(ctx context.Context, userEmail, folder string, since uint64) ([]uint32, error)
+	SearchMails(ctx context.Context, userEmail string, query *SearchQuery, folder string, limit, offset int) ([]*Mail, error)
+	// ListThreads 列出包含 folder 中邮件的会话（folder 为空时列出所有会话），按最新邮件时间倒序
+	ListThreads(ctx context.Context, userEmail, folder string, limit, offset int) ([]*Thread, error)
+	// ListThreadMails 列出会话中的所有邮件（包括所有文件夹），按接收时间升序
+	ListThreadMails(ctx context.Context, userEmail, threadID string) ([]*Mail, error)
 	ListFolders(ctx context.Context, userEmail string) ([]string, error)
+	// RenameFolder 重命名文件夹及其子文件夹（oldName 为 INBOX 时只移动收件箱中的邮件）
+	RenameFolder(ctx context.Context, userEmail, oldName, newName string) error
+	// DeleteFolder 删除文件夹中的邮件和 UID 状态（不包括子文件夹）
+	DeleteFolder(ctx context.Context, userEmail, folder string) error
 	GetNextUID(ctx context.Context, userEmail, folder string) (uint32, error)
+	AllocateUID(ctx context.Context, userEmail, folder string) (uint32, error)
+	GetMailboxUIDs(ctx context.Context, userEmail, folder string) (*MailboxUIDs, error)
+
+	// 转发规则管理
+	GetForwardingRule(ctx context.Context, userEmail string) (*ForwardingRule, error)
+	SaveForwardingRule(ctx context.Context, rule *ForwardingRule) error
+	DeleteForwardingRule(ctx context.Context, userEmail string) error
+
+	// 外出状态管理
+	GetVacation(ctx context.Context, userEmail string) (*Vacation, error)
+	SaveVacation(ctx context.Context, vacation *Vacation) error
+	ListVacations(ctx context.Context) ([]*Vacation, error)
+
+	// 自动回复管理
+	GetAutoResponder(ctx context.Context, userEmail string) (*AutoResponder, error)
+	SaveAutoResponder(ctx context.Context, responder *AutoResponder) error
+	DeleteAutoResponder(ctx context.Context, userEmail string) error
+	// RecordAutoReply 记录向发件人发送了自动回复，距上次回复不足 interval 时返回 false（不应再回复）
+	RecordAutoReply(ctx context.Context, userEmail, sender string, interval time.Duration, now time.Time) (bool, error)
+
+	// IMAP METADATA 条目管理
+	ListMetadata(ctx context.Context, userEmail, mailbox string) ([]*MetadataEntry, error)
+	SetMetadata(ctx context.Context, entry *MetadataEntry) error
+	DeleteMetadata(ctx context.Context, userEmail, mailbox, name string) error
+
+	// IMAP 订阅列表（新用户默认订阅 INBOX 和特殊文件夹）
+	ListSubscriptions(ctx context.Context, userEmail string) ([]string, error)
+	SetSubscribed(ctx context.Context, userEmail, folder string, subscribed bool) error
+
+	// 邮件注解管理
+	ListAnnotations(ctx context.Context, mailID string) ([]*Annotation, error)
+	SetAnnotation(ctx context.Context, annotation *Annotation) error
+	DeleteAnnotation(ctx context.Context, mailID, entry string, shared bool) error
+
+	// 邮件附件元数据
+	ListAttachments(ctx context.Context, userEmail string, filter *AttachmentFilter) ([]*Attachment, error)
+	ListMailAttachments(ctx context.Context, mailID string) ([]*Attachment, error)
+
+	// 订阅邮件退订和归档设置
+	GetUnsubscribe(ctx context.Context, mailID string) (*Unsubscribe, error)
+	MarkUnsubscribed(ctx context.Context, mailID string, at time.Time) error
+	GetNewsletterSettings(ctx context.Context, userEmail string) (*NewsletterSettings, error)
+	SaveNewsletterSettings(ctx context.Context, settings *NewsletterSettings) error
+
+	// 收件箱自动分类
+	GetMailCategory(ctx context.Context, mailID string) (*MailCategory, error)
+	SaveMailCategory(ctx context.Context, category *MailCategory) error
+	GetCategoryModel(ctx context.Context, userEmail string, tokens []string) (*CategoryModel, error)
+	TrainCategory(ctx context.Context, userEmail, category string, tokens []string, delta int) error
+
+	// 外部邮箱账户（密码由调用方加密后存储）
+	CreateExternalAccount(ctx context.Context, account *ExternalAccount) error
+	GetExternalAccount(ctx context.Context, id int64) (*ExternalAccount, error)
+	ListExternalAccounts(ctx context.Context, userEmail string) ([]*ExternalAccount, error)
+	UpdateExternalAccount(ctx context.Context, account *ExternalAccount) error
+	DeleteExternalAccount(ctx context.Context, id int64) error
+	RecordExternalFetch(ctx context.Context, id int64, at time.Time, count int, fetchErr string) error
+	ListFetchedUIDs(ctx context.Context, accountID int64) (map[string]bool, error)
+	AddFetchedUID(ctx context.Context, accountID int64, uid string) error
+
+	// 个人访问令牌（只存储令牌哈希）
+	CreateAPIToken(ctx context.Context, token *APIToken) error
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*APIToken, error)
+	ListAPITokens(ctx context.Context, userEmail string) ([]*APIToken, error)
+	DeleteAPIToken(ctx context.Context, userEmail string, id int64) error
+	TouchAPIToken(ctx context.Context, id int64, at time.Time) error
+	DeleteUserAPITokens(ctx context.Context, userEmail string) (int64, error)
+
+	// 登录会话（吊销后对应的 JWT 失效）
+	CreateSession(ctx context.Context, session *Session) error
+	GetSession(ctx context.Context, id string) (*Session, error)
+	ListSessions(ctx context.Context, userEmail string) ([]*Session, error)
+	TouchSession(ctx context.Context, id string, at time.Time) error
+	RotateSessionRefresh(ctx context.Context, id, oldHash, newHash string) error
+	DeleteSession(ctx context.Context, userEmail, id string) error
+	DeleteUserSessions(ctx context.Context, userEmail string) (int64, error)
+
+	// CRAM-MD5 密钥（密码修改后由调用方比较 PasswordHash 判断是否失效）
+	GetCRAMMD5Secret(ctx context.Context, userEmail string) (*CRAMMD5Secret, error)
+	SaveCRAMMD5Secret(ctx context.Context, secret *CRAMMD5Secret) error
+
+	// Maildir 变更日志（热备复制）
+	AppendMaildirJournal(ctx context.Context, entry *JournalEntry) error
+	ListMaildirJournal(ctx context.Context, since int64, limit int) ([]*JournalEntry, error)
+	GetMaildirJournalBounds(ctx context.Context) (first, last int64, err error)
+	PruneMaildirJournal(ctx context.Context, before time.Time) (int64, error)
+
+	// 发件身份（SMTP 密码由调用方加密后存储）
+	CreateIdentity(ctx context.Context, identity *Identity) error
+	GetIdentity(ctx context.Context, id int64) (*Identity, error)
+	ListIdentities(ctx context.Context, userEmail string) ([]*Identity, error)
+	UpdateIdentity(ctx context.Context, identity *Identity) error
+	DeleteIdentity(ctx context.Context, id int64) error
 
 	// 配额管理
 	GetQuota(ctx context.Context, userEmail string) (*Quota, error)
 	UpdateQuota(ctx context.Context, userEmail string, quota *Quota) error
+	// ListQuotas 列出设置了配额的用户的用量
+	ListQuotas(ctx context.Context) ([]*Quota, error)
+	GetQuotaWarning(ctx context.Context, userEmail string) (*QuotaWarning, error)
+	SaveQuotaWarning(ctx context.Context, warning *QuotaWarning) error
+	DeleteQuotaWarning(ctx context.Context, userEmail string) error
+
+	// 邮件摘要
+	DigestFolder(ctx context.Context, userEmail, folder string, unseen bool, since time.Time, limit int) (*FolderDigest, error)
+	GetDigestSentAt(ctx context.Context, userEmail string) (time.Time, error)
+	SaveDigestSentAt(ctx context.Context, userEmail string, at time.Time) error
+
+	// 账户设置（域名默认设置和用户覆盖）
+	GetDomainDefaults(ctx context.Context, domain string) (*DomainDefaults, error)
+	SaveDomainDefaults(ctx context.Context, defaults *DomainDefaults) error
+	GetUserSettings(ctx context.Context, userEmail string) (*UserSettings, error)
+	SaveUserSettings(ctx context.Context, settings *UserSettings) error
+
+	// 存储用量统计
+	GetFolderUsage(ctx context.Context, userEmail string) ([]*FolderUsage, error)
+	RecordDailyUsage(ctx context.Context, day time.Time) (int, error)
+	ListDailyUsage(ctx context.Context, userEmail string, since time.Time) ([]*DailyUsage, error)
+
+	// 邮件统计汇总
+	RecordDailyStats(ctx context.Context, day time.Time) (int, error)
+	ListDailyStats(ctx context.Context, userEmail string, since time.Time) ([]*DailyStats, error)
+	TopContacts(ctx context.Context, userEmail, direction string, since time.Time, limit int) ([]*ContactCount, error)
+
+	// 退信统计
+	RecordBounce(ctx context.Context, bounce *Bounce) error
+	GetBounceStats(ctx context.Context, since time.Time, limit int) (*BounceStats, error)
+
+	// 投递审计日志
+	RecordDeliveryEvent(ctx context.Context, event *DeliveryEvent) error
+	SearchDeliveryLog(ctx context.Context, query string, since time.Time, limit int) ([]*DeliveryEvent, error)
+	GetDeliveryTrace(ctx context.Context, id string) ([]*DeliveryEvent, error)
+
+	// 内部邮件回执
+	RecordMailReceipt(ctx context.Context, receipt *MailReceipt) error
+	ListMailReceipts(ctx context.Context, sentMailID string) ([]*MailReceipt, error)
+
+	// 外发会话记录
+	SaveSMTPTranscript(ctx context.Context, t *SMTPTranscript) error
+	SearchSMTPTranscripts(ctx context.Context, query string, limit int) ([]*SMTPTranscript, error)
+	GetSMTPTranscript(ctx context.Context, id int64) (*SMTPTranscript, error)
+	PruneSMTPTranscripts(ctx context.Context, before time.Time) (int64, error)
+
+	// 账户活动（登录历史和发信记录）
+	RecordLogin(ctx context.Context, record *LoginRecord) error
+	ListLoginHistory(ctx context.Context, userEmail string, since time.Time, limit int) ([]*LoginRecord, error)
+	RecordSentMail(ctx context.Context, record *SentRecord) error
+	ListSentLog(ctx context.Context, userEmail string, since time.Time, limit int) ([]*SentRecord, error)
+	PruneAccountActivity(ctx context.Context, before time.Time) (int64, error)
+
+	// 域名 DKIM 密钥
+	SaveDKIMKey(ctx context.Context, key *DKIMKey) error
+	GetDKIMKey(ctx context.Context, domain string) (*DKIMKey, error)
+	ListDKIMKeys(ctx context.Context) ([]*DKIMKey, error)
+
+	// 使用条款接受记录
+	AcceptTerms(ctx context.Context, acceptance *TermsAcceptance) error
+	GetTermsAcceptance(ctx context.Context, userEmail, version string) (*TermsAcceptance, error)
+	ListTermsAcceptances(ctx context.Context, userEmail string) ([]*TermsAcceptance, error)
+
+	// 外发预热
+	ReserveWarmup(ctx context.Context, day, provider string, n, limit int) (int, error)
+	ListWarmupCounts(ctx context.Context, day string) (map[string]int, error)
+	DeferMail(ctx context.Context, mail *DeferredMail) error
+	ListDueDeferredMails(ctx context.Context, now time.Time, limit int) ([]*DeferredMail, error)
+	RescheduleDeferredMail(ctx context.Context, id int64, notBefore time.Time) error
+	DeleteDeferredMail(ctx context.Context, id int64) error
+	CountDeferredMails(ctx context.Context) (map[string]int, error)
+
+	// 垃圾邮件内容指纹
+	ReportSpamFingerprint(ctx context.Context, fingerprint *SpamFingerprint, source string) error
+	RecordSpamFingerprintHit(ctx context.Context, id int64, source string) (int, error)
+	ListSpamFingerprints(ctx context.Context, since time.Time, limit int) ([]*SpamFingerprint, error)
+	DeleteSpamFingerprint(ctx context.Context, id int64) error
+
+	// 垃圾邮件贝叶斯分类器（userEmail 为空字符串时为全局模型）
+	GetSpamModel(ctx context.Context, userEmail string, tokens []string) (*SpamModel, error)
+	TrainSpam(ctx context.Context, userEmail, digest string, tokens []string, spam bool) (bool, error)
+
+	// 零访问存储密钥（私钥由用户密码和恢复密钥加密后存储）
+	SaveZeroAccessKey(ctx context.Context, key *ZeroAccessKey) error
+	GetZeroAccessKey(ctx context.Context, userEmail string) (*ZeroAccessKey, error)
+
+	// WebPush 订阅（同一浏览器重新订阅时按 endpoint 更新）
+	SavePushSubscription(ctx context.Context, sub *PushSubscription) error
+	ListPushSubscriptions(ctx context.Context, userEmail string) ([]*PushSubscription, error)
+	DeletePushSubscription(ctx context.Context, userEmail string, id int64) error
+	DeletePushSubscriptionByEndpoint(ctx context.Context, endpoint string) error
+	RecordPush(ctx context.Context, id int64, at time.Time) error
 
 	// TOTP 管理
 	SaveTOTPSecret(ctx context.Context, userEmail string, secret string) error
@@ -48,6 +260,21 @@ type Driver interface {
 	DeleteTOTPSecret(ctx context.Context, userEmail string) error
 	IsTOTPEnabled(ctx context.Context, userEmail string) (bool, error)
 
+	// WebAuthn 凭证（通行密钥或安全密钥，作为登录的第二因素）
+	CreateWebAuthnCredential(ctx context.Context, cred *WebAuthnCredential) error
+	ListWebAuthnCredentials(ctx context.Context, userEmail string) ([]*WebAuthnCredential, error)
+	UpdateWebAuthnCredential(ctx context.Context, userEmail string, credentialID, data []byte) error
+	DeleteWebAuthnCredential(ctx context.Context, userEmail string, id int64) error
+
+	// ACME 账户、订单和证书管理（私钥由调用方加密后存储）
+	SaveACMEAccount(ctx context.Context, account *ACMEAccount) error
+	GetACMEAccount(ctx context.Context, email, directoryURL string) (*ACMEAccount, error)
+	SaveACMEOrder(ctx context.Context, order *ACMEOrder) error
+	GetACMEOrder(ctx context.Context, domain string) (*ACMEOrder, error)
+	SaveACMECertificate(ctx context.Context, cert *ACMECertificate) error
+	GetACMECertificate(ctx context.Context, domain string) (*ACMECertificate, error)
+	ListACMECertificates(ctx context.Context) ([]*ACMECertificate, error)
+
 	// 关闭连接
 	Close() error
 }
@@ -66,38 +293,329 @@ type User struct {
 
 // Domain 域名
 type Domain struct {
-	ID        int64     `json:"id"`
-	Name      string    `json:"name"`
-	Active    bool      `json:"active"`
+	ID                 int64  `json:"id"`
+	Name               string `json:"name"`
+	Active             bool   `json:"active"`
+	ForwardingDisabled bool   `json:"forwarding_disabled"` // 禁止用户转发到外部地址
+	// QuotaWarningThresholds 配额预警阈值（用量百分比，升序），为空时使用全局默认值
+	QuotaWarningThresholds []int `json:"quota_warning_thresholds"`
+	// Receipts 内部邮件回执策略（见 ReceiptsOff 等常量）：本域用户收到的内部邮件向发件人公开哪些时间
+	Receipts  string    `json:"receipts"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// 域名的内部邮件回执策略
+const (
+	ReceiptsOff       = ""          // 不记录（默认）
+	ReceiptsDelivered = "delivered" // 只记录投递时间
+	ReceiptsRead      = "read"      // 记录投递时间和首次阅读时间（设置 \Seen 标志）
+)
+
+// MailReceipt 内部邮件回执：发件人已发送的邮件投递给本地收件人的时间和收件人首次阅读的时间
+type MailReceipt struct {
+	ID          int64      `json:"-"`
+	SentMailID  string     `json:"-"`         // 发件人已发送的邮件
+	Recipient   string     `json:"recipient"` // 本地收件人（别名展开后的用户）
+	MailID      string     `json:"-"`         // 收件人邮箱中的邮件
+	TrackRead   bool       `json:"-"`         // 投递时收件人所在域名开启了阅读回执
+	DeliveredAt time.Time  `json:"delivered_at"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+}
+
 // Alias 别名
 type Alias struct {
-	ID        int64     `json:"id"`
-	From      string    `json:"from"` // 源地址
-	To        string    `json:"to"`   // 目标地址
-	Domain    string    `json:"domain"`
+	ID             int64      `json:"id"`
+	From           string     `json:"from"`         // 源地址
+	To             string     `json:"to"`           // 目标地址（多个目标时为第一个）
+	Destinations   []string   `json:"destinations"` // 全部目标地址：本地用户投递到收件箱，外部地址经 SRS 重写后转发
+	Domain         string     `json:"domain"`
+	Owner          string     `json:"owner,omitempty"`      // 自助创建别名的用户（管理员创建时为空）
+	Disposable     bool       `json:"disposable"`           // 临时别名（自动过期或手动作废）
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // 过期时间，为空表示永不过期
+	BurnedAt       *time.Time `json:"burned_at,omitempty"`  // 作废时间
+	ReceivedCount  int64      `json:"received_count"`       // 经由该别名投递的邮件数
+	LastReceivedAt *time.Time `json:"last_received_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// AliasPolicy 域名的自助别名策略
+type AliasPolicy struct {
+	Domain     string    `json:"domain"`
+	MaxAliases int       `json:"max_aliases"` // 每个用户最多可创建的别名数，0 表示不允许自助创建
+	Patterns   []string  `json:"patterns"`    // 允许的本地部分模式（如 "{user}+*"、"*"），为空时只允许 "{user}+*"
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ForwardingRule 用户的外部转发规则（每个用户一条，验证目标地址后生效）
+type ForwardingRule struct {
+	UserEmail     string    `json:"user_email"`
+	Address       string    `json:"address"`       // 转发目标地址
+	KeepCopy      bool      `json:"keep_copy"`     // 转发后是否在本地保留副本
+	MatchFrom     string    `json:"match_from"`    // 条件：发件人包含（为空表示不限）
+	MatchSubject  string    `json:"match_subject"` // 条件：主题包含（为空表示不限）
+	Verified      bool      `json:"verified"`      // 目标地址是否已验证
+	CodeHash      string    `json:"-"`             // 验证码哈希
+	CodeExpiresAt time.Time `json:"-"`             // 验证码过期时间
+	Attempts      int       `json:"-"`             // 验证码错误次数
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Vacation 用户的外出状态（在目录和 IMAP METADATA 中向同域用户展示）
+type Vacation struct {
+	UserEmail string     `json:"user_email"`
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message"`   // 外出说明
+	StartsAt  *time.Time `json:"starts_at"` // 开始时间，nil 表示立即开始
+	EndsAt    *time.Time `json:"ends_at"`   // 结束时间，nil 表示直到手动关闭
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// AutoResponder 用户的自动回复设置（投递时向发件人回复，同一发件人在间隔天数内只回复一次）
+type AutoResponder struct {
+	UserEmail    string     `json:"user_email"`
+	Enabled      bool       `json:"enabled"`
+	Subject      string     `json:"subject"`       // 回复主题，为空时使用 "自动回复: " 加原邮件主题
+	Body         string     `json:"body"`          // 回复正文（纯文本）
+	StartsAt     *time.Time `json:"starts_at"`     // 开始时间，nil 表示立即开始
+	EndsAt       *time.Time `json:"ends_at"`       // 结束时间，nil 表示直到手动关闭
+	IntervalDays int        `json:"interval_days"` // 同一发件人的最小回复间隔（天，RFC 5230 默认 7）
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CRAMMD5Secret 用户的 CRAM-MD5 密钥（HMAC-MD5 中间状态，与密码同等敏感）
+type CRAMMD5Secret struct {
+	UserEmail    string    `json:"user_email"`
+	Secret       string    `json:"-"`
+	PasswordHash string    `json:"-"` // 计算密钥时的密码哈希，与用户当前的密码哈希不同时密钥已失效
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MetadataEntry IMAP METADATA 条目（RFC 5464）
+type MetadataEntry struct {
+	UserEmail string    `json:"user_email"`
+	Mailbox   string    `json:"mailbox"` // 邮箱名称，空字符串表示服务器级条目
+	Name      string    `json:"name"`    // 条目名称（如 /private/comment）
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Annotation 邮件注解（IMAP ANNOTATE，RFC 5257），如颜色标签、备注
+type Annotation struct {
+	MailID    string    `json:"mail_id"`
+	Entry     string    `json:"entry"`  // 条目名称（如 /comment）
+	Shared    bool      `json:"shared"` // true 为 value.shared，false 为 value.priv
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Attachment 邮件附件元数据（投递时从 MIME 结构中提取）
+type Attachment struct {
+	ID          int64  `json:"id"`
+	MailID      string `json:"mail_id"`
+	UserEmail   string `json:"-"`
+	Part        string `json:"part"` // MIME 部分编号（如 2 或 1.2）
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"` // 解码后的字节数
+
+	// 所属邮件信息（仅 ListAttachments 填充）
+	Folder     string    `json:"folder,omitempty"`
+	From       string    `json:"from,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// AttachmentFilter 附件列表过滤条件（零值表示不过滤）
+type AttachmentFilter struct {
+	Type    string // MIME 类型（含 / 时按前缀匹配，如 image/）或扩展名（如 pdf）
+	MinSize int64
+	MaxSize int64
+	From    string // 发件人包含
+	After   *time.Time
+	Before  *time.Time
+	Limit   int
+	Offset  int
+}
+
+// Unsubscribe 订阅邮件的退订方式（来自 List-Unsubscribe 头，RFC 2369 / RFC 8058）
+type Unsubscribe struct {
+	MailID         string     `json:"mail_id"`
+	ListID         string     `json:"list_id,omitempty"` // List-Id 头
+	Mailto         string     `json:"mailto,omitempty"`  // mailto: 退订地址（含查询参数）
+	URL            string     `json:"url,omitempty"`     // HTTPS 退订链接
+	OneClick       bool       `json:"one_click"`         // 支持一键退订（List-Unsubscribe-Post）
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty"`
+}
+
+// NewsletterSettings 用户的订阅邮件设置
+type NewsletterSettings struct {
+	UserEmail string    `json:"-"`
+	Folder    string    `json:"folder"` // 自动归档的文件夹，为空表示留在收件箱
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MailCategory 邮件的自动分类结果和分类特征
+type MailCategory struct {
+	MailID   string   `json:"mail_id"`
+	Category string   `json:"category"`
+	Tokens   []string `json:"-"`       // 分类特征（用于重新分类时训练）
+	Trained  bool     `json:"trained"` // 是否已作为训练样本（用户手动分类）
+}
+
+// CategoryModel 用户的朴素贝叶斯分类模型（只包含查询的特征）
+type CategoryModel struct {
+	Docs       map[string]int            // 分类 -> 训练样本数
+	Tokens     map[string]int            // 分类 -> 特征总数
+	Counts     map[string]map[string]int // 分类 -> 特征 -> 出现次数
+	Vocabulary int                       // 不同特征数
+}
+
+// ExternalAccount 用户的外部邮箱账户（定期拉取远程邮件到本地文件夹）
+type ExternalAccount struct {
+	ID            int64      `json:"id"`
+	UserEmail     string     `json:"user_email"`
+	Protocol      string     `json:"protocol"` // pop3 或 imap
+	Host          string     `json:"host"`
+	Port          int        `json:"port"`
+	TLS           bool       `json:"tls"` // 使用隐式 TLS（POP3S 995 / IMAPS 993）
+	Username      string     `json:"username"`
+	Password      []byte     `json:"-"`           // 加密后的密码
+	Folder        string     `json:"folder"`      // 投递到的本地文件夹
+	KeepRemote    bool       `json:"keep_remote"` // 拉取后是否保留远程邮件
+	Enabled       bool       `json:"enabled"`
+	LastFetchAt   *time.Time `json:"last_fetch_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastError     string     `json:"last_error"`  // 最近一次拉取的错误（成功时为空）
+	LastCount     int        `json:"last_count"`  // 最近一次拉取的邮件数
+	TotalCount    int64      `json:"total_count"` // 累计拉取的邮件数
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// APIToken 个人访问令牌（用于脚本和第三方集成访问 WebMail API）
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserEmail  string     `json:"user_email"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`               // 令牌开头几位，用于在列表中辨认令牌
+	TokenHash  string     `json:"-"`                    // 令牌的 SHA-256 哈希（十六进制）
+	Scope      string     `json:"scope"`                // read, send 或 full
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Session 登录会话（WebMail 或管理后台签发的 JWT）
+type Session struct {
+	ID         string     `json:"id"`
+	UserEmail  string     `json:"user_email"`
+	Source     string     `json:"source"` // webmail 或 admin
+	RemoteIP   string     `json:"remote_ip,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"` // 会话（刷新令牌）的过期时间
+	// RefreshHash 刷新令牌的 SHA-256 哈希（十六进制），为空表示会话不能刷新
+	RefreshHash string `json:"-"`
+}
+
+// JournalEntry Maildir 变更日志条目（路径相对于 Maildir 根目录，如 alice@example.com/.Sent/cur/xxx:2,S）
+type JournalEntry struct {
+	Seq       int64     `json:"seq"`
+	Op        string    `json:"op"`                 // store, rename 或 delete
+	Path      string    `json:"path"`               // 文件路径（rename 为源路径）
+	NewPath   string    `json:"new_path,omitempty"` // rename 的目标路径
+	SHA256    string    `json:"sha256,omitempty"`   // store 的文件内容哈希（十六进制）
+	Size      int64     `json:"size,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Identity 用户的发件身份（以外部地址发信时通过该地址的 SMTP 服务器提交）
+type Identity struct {
+	ID           int64     `json:"id"`
+	UserEmail    string    `json:"user_email"`
+	Address      string    `json:"address"` // 发件地址（如 Gmail 地址）
+	DisplayName  string    `json:"display_name"`
+	SMTPHost     string    `json:"smtp_host"`
+	SMTPPort     int       `json:"smtp_port"` // 465 使用隐式 TLS，其他端口使用 STARTTLS
+	SMTPUsername string    `json:"smtp_username"`
+	SMTPPassword []byte    `json:"-"` // 加密后的密码
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
 // Mail 邮件
 type Mail struct {
-	ID         string    `json:"id"`
-	UserEmail  string    `json:"user_email"`
-	Folder     string    `json:"folder"` // INBOX, Sent, Drafts, etc.
-	From       string    `json:"from"`
-	To         []string  `json:"to"`
-	Cc         []string  `json:"cc"`
-	Bcc        []string  `json:"bcc"`
-	Subject    string    `json:"subject"`
-	Body       []byte    `json:"-"` // 邮件体（加密存储）
-	Size       int64     `json:"size"`
-	Flags      []string  `json:"flags"` // \Seen, \Answered, \Flagged, etc.
-	UID        uint32    `json:"uid"` // IMAP UID（唯一标识符，单调递增）
-	ReceivedAt time.Time `json:"received_at"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID            string    `json:"id"`
+	UserEmail     string    `json:"user_email"`
+	Folder        string    `json:"folder"` // INBOX, Sent, Drafts, etc.
+	From          string    `json:"from"`
+	To            []string  `json:"to"`
+	Cc            []string  `json:"cc"`
+	Bcc           []string  `json:"bcc"`
+	Subject       string    `json:"subject"`
+	Body          []byte    `json:"-"` // 邮件体（加密存储）
+	Size          int64     `json:"size"`
+	Flags         []string  `json:"flags"`          // \Seen, \Answered, \Flagged, etc.
+	UID           uint32    `json:"uid"`            // IMAP UID（唯一标识符，单调递增）
+	HasAttachment bool      `json:"has_attachment"` // 是否包含附件（用于 has:attachment 搜索）
+	ReceivedAt    time.Time `json:"received_at"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	// 附件元数据和退订方式（StoreMail 时写入，查询邮件时不加载）
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	Unsubscribe *Unsubscribe  `json:"-"`
+
+	// 收件箱分类（由分类关键字标志得出，邮件列表接口填充）
+	Category string `json:"category,omitempty"`
+
+	// 修改序号（新增、标志变化或移动时递增，IMAP CONDSTORE 和增量同步接口使用）
+	ModSeq uint64 `json:"modseq,omitempty"`
+
+	// 会话：邮件头中的 Message-ID（不带尖括号，MailStore 投递时从邮件头解析）和所属会话
+	// （StoreMail 时根据引用的邮件确定，为空时开始新会话）
+	MessageID  string   `json:"message_id,omitempty"`
+	InReplyTo  string   `json:"in_reply_to,omitempty"`
+	References []string `json:"references,omitempty"`
+	ThreadID   string   `json:"thread_id,omitempty"`
+}
+
+// Thread 邮件会话（同一 thread_id 的邮件，包括所有文件夹）
+type Thread struct {
+	ID            string    `json:"id"`
+	Subject       string    `json:"subject"`      // 会话中第一封邮件的主题
+	Participants  []string  `json:"participants"` // 发件人（按第一次出现的顺序）
+	Messages      int       `json:"messages"`
+	Unread        int       `json:"unread"` // 没有 \Seen 标志的邮件数
+	HasAttachment bool      `json:"has_attachment"`
+	Folders       []string  `json:"folders"`
+	LatestMailID  string    `json:"latest_mail_id"`
+	LatestAt      time.Time `json:"latest_at"` // 最新邮件的接收时间
+}
+
+// SearchQuery 结构化搜索条件（由 search.Parse 从查询语言解析），各条件之间为 AND 关系
+type SearchQuery struct {
+	Text          []string   `json:"text,omitempty"`           // 自由文本，匹配主题、发件人或收件人
+	From          []string   `json:"from,omitempty"`           // 发件人包含
+	To            []string   `json:"to,omitempty"`             // 收件人或抄送包含
+	Subject       []string   `json:"subject,omitempty"`        // 主题包含
+	HasAttachment bool       `json:"has_attachment,omitempty"` // 只匹配带附件的邮件
+	Before        *time.Time `json:"before,omitempty"`         // 接收时间早于
+	After         *time.Time `json:"after,omitempty"`          // 接收时间不早于
+	Larger        int64      `json:"larger,omitempty"`         // 大小大于（字节），0 表示不限
+	Smaller       int64      `json:"smaller,omitempty"`        // 大小小于（字节），0 表示不限
+	Labels        []string   `json:"labels,omitempty"`         // 包含的标签（IMAP 关键字标志）
+}
+
+// MailboxUIDs 邮箱的 UID 状态（IMAP UIDVALIDITY 和 UIDNEXT）
+type MailboxUIDs struct {
+	UserEmail   string    `json:"user_email"`
+	Folder      string    `json:"folder"`
+	UIDValidity uint32    `json:"uid_validity"` // 邮箱创建时确定，之后不变
+	UIDNext     uint32    `json:"uid_next"`     // 下一个分配的 UID，只增不减（删除邮件后 UID 不会被复用）
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 // Quota 配额
@@ -106,3 +624,305 @@ type Quota struct {
 	Used      int64  `json:"used"`  // 已使用字节数
 	Limit     int64  `json:"limit"` // 限制字节数，0 表示无限制
 }
+
+// FolderDigest 邮件摘要中一个文件夹的统计：符合条件的邮件数和最近的几封邮件
+type FolderDigest struct {
+	Folder string `json:"folder"`
+	Count  int    `json:"count"`
+	// Mails 最近的邮件（按接收时间倒序，只填充 ID、文件夹、发件人、主题和接收时间）
+	Mails []*Mail `json:"mails"`
+}
+
+// QuotaWarning 用户已发送预警的最高阈值
+type QuotaWarning struct {
+	UserEmail string    `json:"user_email"`
+	Threshold int       `json:"threshold"` // 用量百分比
+	WarnedAt  time.Time `json:"warned_at"`
+}
+
+// FolderUsage 文件夹用量
+type FolderUsage struct {
+	Folder string `json:"folder"`
+	Count  int64  `json:"count"` // 邮件数
+	Used   int64  `json:"used"`  // 已使用字节数
+}
+
+// DailyUsage 每日用量汇总
+type DailyUsage struct {
+	Day  string `json:"day"`  // 日期（YYYY-MM-DD）
+	Used int64  `json:"used"` // 当日汇总时的已使用字节数
+}
+
+// 往来联系人方向
+const (
+	ContactIncoming = "in"  // 发件人（收到的邮件）
+	ContactOutgoing = "out" // 收件人（发出的邮件）
+)
+
+// DailyStats 每日邮件统计汇总
+type DailyStats struct {
+	Day             string `json:"day"`              // 日期（YYYY-MM-DD）
+	Received        int    `json:"received"`         // 收到的邮件数
+	Sent            int    `json:"sent"`             // 发出的邮件数
+	Attachments     int    `json:"attachments"`      // 附件数
+	AttachmentBytes int64  `json:"attachment_bytes"` // 附件字节数
+	Replies         int    `json:"replies"`          // 可计算回复耗时的发信数
+	ReplySeconds    int64  `json:"reply_seconds"`    // 回复耗时总和（秒）
+}
+
+// ContactCount 往来联系人邮件数
+type ContactCount struct {
+	Address string `json:"address"`
+	Count   int    `json:"count"`
+}
+
+// Bounce 退信记录：外发时被拒收的收件人，或本地用户收到的退信报告中的收件人
+type Bounce struct {
+	ID         int64     `json:"id"`
+	Sender     string    `json:"sender"`     // 被退信的发件人
+	Recipient  string    `json:"recipient"`  // 被拒收的收件人
+	Domain     string    `json:"domain"`     // 收件人域名
+	Category   string    `json:"category"`   // 退信原因分类（见 bounce 包）
+	Status     string    `json:"status"`     // 增强状态码（如 5.1.1）或 SMTP 回复码
+	Diagnostic string    `json:"diagnostic"` // 对方服务器的诊断信息
+	Source     string    `json:"source"`     // 来源：smtp（外发时被拒收）或 dsn（收到的退信报告）
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BounceCount 按目标域名或发件人汇总的退信数
+type BounceCount struct {
+	Key        string         `json:"key"`
+	Total      int            `json:"total"`
+	Categories map[string]int `json:"categories"`
+}
+
+// BounceStats 退信统计
+type BounceStats struct {
+	Since      time.Time      `json:"since"`
+	Total      int            `json:"total"`
+	Categories map[string]int `json:"categories"`
+	Domains    []*BounceCount `json:"domains"` // 按退信数降序
+	Senders    []*BounceCount `json:"senders"` // 按退信数降序
+}
+
+// DeliveryEvent 投递审计日志中的一条记录：邮件在某个阶段的状态
+type DeliveryEvent struct {
+	ID        int64     `json:"id"`
+	QueueID   string    `json:"queue_id"`   // 接收时分配的队列 ID（同一次 SMTP 事务的记录相同，推迟后再发出的记录为空）
+	MessageID string    `json:"message_id"` // 不带尖括号
+	Event     string    `json:"event"`      // 阶段（见 DeliveryReceived 等常量）
+	Sender    string    `json:"sender"`     // 信封发件人
+	Recipient string    `json:"recipient"`  // 信封收件人（反垃圾评分时为空或第一个收件人）
+	Folder    string    `json:"folder"`     // 投递到的文件夹（delivered）
+	Code      int       `json:"code"`       // SMTP 回复码（外发时为对方服务器的回复码，没有时为 0）
+	Status    string    `json:"status"`     // 增强状态码（如 5.1.1）
+	Detail    string    `json:"detail"`     // 诊断信息、反垃圾分数等
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 投递审计日志的阶段
+const (
+	DeliveryReceived   = "received"    // SMTP 接收或 WebMail 提交
+	DeliverySpamScored = "spam_scored" // 反垃圾检查
+	DeliveryDelivered  = "delivered"   // 存入本地用户的文件夹
+	DeliveryRelayed    = "relayed"     // 发往外部服务器（对方已接受）
+	DeliveryBounced    = "bounced"     // 被对方永久拒收
+	DeliveryDeferred   = "deferred"    // 临时失败或超过外发预热上限，稍后重试
+	DeliveryFailed     = "failed"      // 本地存储失败
+)
+
+// DeferredMail 预热期间超过当天外发配额、推迟发送的邮件（同一目标服务商的收件人）
+type DeferredMail struct {
+	ID         int64     `json:"id"`
+	Sender     string    `json:"sender"`
+	Recipients []string  `json:"recipients"`
+	Provider   string    `json:"provider"` // 目标邮件服务商
+	Data       []byte    `json:"-"`
+	NotBefore  time.Time `json:"not_before"` // 最早发送时间
+	Attempts   int       `json:"attempts"`   // 因配额不足被推迟的次数
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SpamFingerprint 垃圾邮件内容指纹：按内容被拒收或被用户投诉的邮件正文的局部敏感哈希
+type SpamFingerprint struct {
+	ID        int64     `json:"id"`
+	Hash      string    `json:"hash"`
+	Reason    string    `json:"reason"`  // 首次记录的原因：rejected（被拒收）或 complaint（用户投诉）
+	Sample    string    `json:"sample"`  // 规范化后的正文摘录
+	Reports   int       `json:"reports"` // 被拒收和投诉的次数
+	Hits      int       `json:"hits"`    // 之后到达的相似邮件数
+	Sources   int       `json:"sources"` // 出现过的不同来源（发件域名）数
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// SpamModel 垃圾邮件贝叶斯模型（只包含查询的特征）
+type SpamModel struct {
+	SpamDocs int            // 垃圾邮件训练样本数
+	HamDocs  int            // 正常邮件训练样本数
+	Spam     map[string]int // 特征 -> 出现该特征的垃圾邮件数
+	Ham      map[string]int // 特征 -> 出现该特征的正常邮件数
+}
+
+// ZeroAccessKey 用户的零访问存储密钥：投递的邮件用公钥加密，私钥只以加密形式保存
+type ZeroAccessKey struct {
+	UserEmail       string    `json:"user_email"`
+	PublicKey       []byte    `json:"-"` // X25519 公钥
+	PasswordWrapped []byte    `json:"-"` // 用户密码加密的私钥
+	RecoveryWrapped []byte    `json:"-"` // 恢复密钥加密的私钥
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SMTPTranscript 一次外发 SMTP 会话的完整记录（发往一个域名的 MX），收件方否认收到邮件时举证
+type SMTPTranscript struct {
+	ID         int64     `json:"id"`
+	QueueID    string    `json:"queue_id"`
+	MessageID  string    `json:"message_id"` // 不带尖括号
+	Sender     string    `json:"sender"`     // 信封发件人
+	Domain     string    `json:"domain"`     // 收件人域名
+	Recipients []string  `json:"recipients"`
+	MXHost     string    `json:"mx_host"`
+	RemoteAddr string    `json:"remote_addr"` // 实际连接的 IP 和端口
+	TLSVersion string    `json:"tls_version"` // 没有执行 STARTTLS 时为空
+	TLSCipher  string    `json:"tls_cipher"`
+	Error      string    `json:"error"`                // 发送失败的原因，成功时为空
+	Transcript string    `json:"transcript,omitempty"` // 会话记录（每行带相对开始时间和方向，列表查询时不返回）
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// LoginRecord 账户的一次成功登录
+type LoginRecord struct {
+	ID        int64     `json:"id"`
+	UserEmail string    `json:"user_email"`
+	Protocol  string    `json:"protocol"` // webmail、admin、imap 或 smtp
+	RemoteIP  string    `json:"remote_ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"` // 浏览器的 User-Agent（IMAP、SMTP 登录为空）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SentRecord 从账户发出的一封邮件（与 Sent 文件夹无关，用户删除已发送邮件后仍然保留）
+type SentRecord struct {
+	ID         int64     `json:"id"`
+	UserEmail  string    `json:"user_email"`
+	MessageID  string    `json:"message_id"` // 不带尖括号
+	Subject    string    `json:"subject"`
+	Recipients []string  `json:"recipients"`
+	Protocol   string    `json:"protocol"` // webmail 或 smtp
+	RemoteIP   string    `json:"remote_ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DKIMKey 域名的 DKIM 签名密钥（私钥保存在文件中）
+type DKIMKey struct {
+	Domain    string    `json:"domain"`
+	Selector  string    `json:"selector"`
+	Algorithm string    `json:"algorithm"` // rsa 或 ed25519
+	KeyFile   string    `json:"key_file"`  // 私钥文件路径（相对路径基于工作目录）
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TermsAcceptance 用户接受某个版本使用条款的记录
+type TermsAcceptance struct {
+	UserEmail  string    `json:"user_email"`
+	Version    string    `json:"version"`
+	RemoteIP   string    `json:"remote_ip,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// PushSubscription 浏览器（或 PWA）的 WebPush 订阅（RFC 8030），通知用订阅的公钥加密（RFC 8291）
+type PushSubscription struct {
+	ID         int64      `json:"id"`
+	UserEmail  string     `json:"user_email"`
+	Endpoint   string     `json:"endpoint"` // 推送服务的地址
+	P256DH     []byte     `json:"-"`        // 浏览器的 P-256 公钥（未压缩格式）
+	Auth       []byte     `json:"-"`        // 认证密钥（16 字节）
+	Privacy    string     `json:"privacy"`  // 通知包含的内容（为空时使用系统默认值）
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastPushAt *time.Time `json:"last_push_at,omitempty"` // 最近一次推送成功的时间
+}
+
+// WebAuthnCredential 用户注册的 WebAuthn 凭证（通行密钥或安全密钥）
+type WebAuthnCredential struct {
+	ID           int64      `json:"id"`
+	UserEmail    string     `json:"user_email"`
+	CredentialID []byte     `json:"-"`    // 认证器生成的凭证 ID
+	Name         string     `json:"name"` // 用户为凭证起的名称（如 "YubiKey"）
+	Data         []byte     `json:"-"`    // 序列化的凭证（JSON，包括公钥和签名计数）
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"` // 最近一次用于登录的时间
+}
+
+// ACMEAccount ACME 账户
+type ACMEAccount struct {
+	ID           int64     `json:"id"`
+	Email        string    `json:"email"`
+	DirectoryURL string    `json:"directory_url"`
+	AccountURL   string    `json:"account_url"`
+	PrivateKey   []byte    `json:"-"` // 账户私钥（加密存储）
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ACMEOrder ACME 证书订单（每个域名保留最近一次）
+type ACMEOrder struct {
+	ID        int64     `json:"id"`
+	Domain    string    `json:"domain"`
+	OrderURL  string    `json:"order_url"`
+	Status    string    `json:"status"` // pending, valid, invalid
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ACMECertificate ACME 签发的证书
+type ACMECertificate struct {
+	Domain     string    `json:"domain"`
+	CertPEM    []byte    `json:"-"` // 证书链（PEM）
+	PrivateKey []byte    `json:"-"` // 证书私钥（加密存储）
+	NotAfter   time.Time `json:"not_after"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AccountSettings 可以由域名统一设置、由用户单独覆盖的账户设置
+// 字段为 nil（Features 中未列出的功能）表示未设置，继承上一级（域名默认设置或系统默认值）
+type AccountSettings struct {
+	Features          map[string]bool `json:"features,omitempty"`           // 功能开关（见 AccountFeatures）
+	RetentionDays     *int            `json:"retention_days,omitempty"`     // 邮件保留天数，0 表示永久保留
+	SpamThreshold     *float64        `json:"spam_threshold,omitempty"`     // 垃圾邮件判定分数阈值
+	SignatureTemplate *string         `json:"signature_template,omitempty"` // 签名模板，支持 {name}、{email}、{domain} 占位符
+	Timezone          *string         `json:"timezone,omitempty"`           // IANA 时区名（如 Asia/Shanghai），用于通知邮件中的时间显示
+	Digest            *string         `json:"digest,omitempty"`             // 邮件摘要频率：off、daily 或 weekly
+}
+
+// DomainDefaults 域名的默认账户设置
+type DomainDefaults struct {
+	Domain string `json:"domain"`
+	Quota  *int64 `json:"quota,omitempty"` // 新用户的默认配额（字节），只在创建时未指定配额的用户上应用
+	AccountSettings
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserSettings 用户对域名默认设置的覆盖
+type UserSettings struct {
+	UserEmail string `json:"user_email"`
+	AccountSettings
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// EffectiveSettings 账户的生效设置，Sources 记录每项设置的来源（system、domain、user）
+type EffectiveSettings struct {
+	Email             string            `json:"email"`
+	Quota             int64             `json:"quota"`
+	Features          map[string]bool   `json:"features"`
+	RetentionDays     int               `json:"retention_days"`
+	SpamThreshold     float64           `json:"spam_threshold"`
+	SignatureTemplate string            `json:"signature_template"`
+	Signature         string            `json:"signature"` // 展开占位符后的签名
+	Timezone          string            `json:"timezone"`
+	Digest            string            `json:"digest"`
+	Sources           map[string]string `json:"sources"`
+}
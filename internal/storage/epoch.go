@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// 时间统一按 UTC Unix 秒（INTEGER）存储：
+//   - 写入时 time.Time 参数转换为 Unix 秒，SQL 中可以直接比较和排序（不再依赖 julianday 和字符串格式）
+//   - 读取时 DATETIME 列中的整数转换回 UTC time.Time；旧数据中的文本时间（迁移前写入或列默认值
+//     CURRENT_TIMESTAMP）由 SQLite 驱动解析，同样转换为 UTC
+//
+// 迁移 00026_normalize_timestamps 把已有的文本时间转换为 Unix 秒。
+
+// epochConnector 打开包装后的 SQLite 连接
+type epochConnector struct {
+	dsn    string
+	driver *sqlite.Driver
+}
+
+func newEpochConnector(dsn string) driver.Connector {
+	return &epochConnector{dsn: dsn, driver: &sqlite.Driver{}}
+}
+
+func (c *epochConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &epochConn{conn.(sqliteConn)}, nil
+}
+
+func (c *epochConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqliteConn SQLite 驱动连接实现的接口
+type sqliteConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// epochConn 转换参数和查询结果中的时间
+type epochConn struct {
+	sqliteConn
+}
+
+// CheckNamedValue 在默认参数转换之后把 time.Time 转换为 Unix 秒
+func (c *epochConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if t, ok := value.(time.Time); ok {
+		value = t.Unix()
+	}
+	nv.Value = value
+	return nil
+}
+
+func (c *epochConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.sqliteConn.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return newEpochRows(rows), nil
+}
+
+func (c *epochConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *epochConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.sqliteConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &epochStmt{stmt.(sqliteStmt)}, nil
+}
+
+// sqliteStmt SQLite 驱动预编译语句实现的接口
+type sqliteStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+type epochStmt struct {
+	sqliteStmt
+}
+
+func (s *epochStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.sqliteStmt.QueryContext(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return newEpochRows(rows), nil
+}
+
+// sqliteRows SQLite 驱动查询结果实现的接口
+type sqliteRows interface {
+	driver.Rows
+	driver.RowsColumnTypeDatabaseTypeName
+}
+
+// epochRows 把时间列（声明类型为 DATE、DATETIME 或 TIMESTAMP）的值转换为 UTC time.Time
+type epochRows struct {
+	sqliteRows
+	timeColumns []bool
+}
+
+func newEpochRows(rows driver.Rows) driver.Rows {
+	r, ok := rows.(sqliteRows)
+	if !ok {
+		return rows
+	}
+	columns := make([]bool, len(r.Columns()))
+	for i := range columns {
+		switch r.ColumnTypeDatabaseTypeName(i) {
+		case "DATE", "DATETIME", "TIMESTAMP":
+			columns[i] = true
+		}
+	}
+	return &epochRows{sqliteRows: r, timeColumns: columns}
+}
+
+func (r *epochRows) Next(dest []driver.Value) error {
+	if err := r.sqliteRows.Next(dest); err != nil {
+		return err
+	}
+	for i, value := range dest {
+		if i >= len(r.timeColumns) || !r.timeColumns[i] {
+			continue
+		}
+		switch v := value.(type) {
+		case int64:
+			dest[i] = time.Unix(v, 0).UTC()
+		case time.Time:
+			dest[i] = v.UTC()
+		}
+	}
+	return nil
+}
+
+// utcNow 当前时间（UTC，截断到秒，与存储精度一致），写入后返回给调用方的时间与再次读取的相同
+func utcNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
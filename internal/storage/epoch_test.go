@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/migrate"
+)
+
+func TestEpochTimestamps(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	before := time.Now().Truncate(time.Second)
+	if err := driver.CreateUser(ctx, &User{Email: "alice@example.com", PasswordHash: "x", Active: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	var storedType string
+	if err := driver.db.QueryRowContext(ctx, `SELECT typeof(created_at) FROM users`).Scan(&storedType); err != nil {
+		t.Fatal(err)
+	}
+	if storedType != "integer" {
+		t.Errorf("时间的存储类型 = %s, want integer", storedType)
+	}
+
+	user, err := driver.GetUser(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("获取用户失败: %v", err)
+	}
+	if user.CreatedAt.Before(before) || user.CreatedAt.Location() != time.UTC {
+		t.Errorf("读取的时间 = %v, want UTC 且不早于 %v", user.CreatedAt, before)
+	}
+
+	// 时间参数按 Unix 秒比较，与时区无关
+	local := user.CreatedAt.In(time.FixedZone("CST", 8*3600))
+	var count int
+	if err := driver.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE created_at = ?`, local).Scan(&count); err != nil || count != 1 {
+		t.Errorf("按本地时间查询 = %d, %v", count, err)
+	}
+}
+
+func TestNormalizeTimestampsMigration(t *testing.T) {
+	driver, err := NewSQLiteDriver(filepath.Join(t.TempDir(), "gmz.db"))
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	migrations := filepath.Join("..", "..", "migrations")
+	if err := migrate.MigrateTo(ctx, driver.db, migrations, 25); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	// 迁移前的各种文本格式，都表示 2026-03-01 00:30:00 UTC
+	want := time.Date(2026, 3, 1, 0, 30, 0, 0, time.UTC)
+	legacy := map[string]string{
+		"a@example.com": "2026-03-01 00:30:00",
+		"b@example.com": "2026-03-01T08:30:00+08:00",
+		"c@example.com": "2026-03-01T00:30:00Z",
+		"d@example.com": "2026-03-01 08:30:00.123456789 +0800 CST m=+0.012345",
+		"e@example.com": "2026-02-28 19:30:00 -0500 EST",
+	}
+	for email, value := range legacy {
+		if _, err := driver.db.ExecContext(ctx, `INSERT INTO users (email, password_hash, created_at) VALUES (?, 'x', ?)`, email, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := driver.db.ExecContext(ctx, `INSERT INTO users (email, password_hash, created_at) VALUES ('f@example.com', 'x', 'garbage')`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate.Migrate(ctx, driver.db, migrations, "up"); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	for email := range legacy {
+		var storedType string
+		var stored time.Time
+		if err := driver.db.QueryRowContext(ctx, `SELECT typeof(created_at), created_at FROM users WHERE email = ?`, email).Scan(&storedType, &stored); err != nil {
+			t.Fatalf("%s: %v", email, err)
+		}
+		if storedType != "integer" || !stored.Equal(want) {
+			t.Errorf("%s 迁移后 = %s %v, want integer %v", email, storedType, stored, want)
+		}
+	}
+	var garbage string
+	if err := driver.db.QueryRowContext(ctx, `SELECT created_at FROM users WHERE email = 'f@example.com'`).Scan(&garbage); err != nil || garbage != "garbage" {
+		t.Errorf("无法解析的值应该保持不变: %q, %v", garbage, err)
+	}
+}
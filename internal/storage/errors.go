@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// 存储错误：调用方用 errors.Is 区分，映射为 HTTP 状态码和 SMTP 回复码
+var (
+	// ErrNotFound 记录不存在
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists 记录已存在（唯一约束冲突，如重复的用户、域名或别名）
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrQuotaExceeded 用户的存储配额已用尽
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrInvalidInput 输入无效（缺少必填字段、引用的记录不存在等）
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrMailboxLocked 邮件已加密（零访问存储），用户的密钥未解锁（需要用密码登录）或不是在用户的会话中读取
+	ErrMailboxLocked = errors.New("mailbox locked")
+)
+
+// QuotaExceededError 投递后会超出配额（errors.Is(err, ErrQuotaExceeded) 为 true）
+type QuotaExceededError struct {
+	UserEmail string
+	Used      int64 // 已用字节数
+	Limit     int64 // 配额字节数
+	Size      int64 // 本次投递的字节数
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("用户 %s 的存储配额已用尽（已用 %d / %d 字节，邮件 %d 字节）", e.UserEmail, e.Used, e.Limit, e.Size)
+}
+
+// Is 与 ErrQuotaExceeded 匹配
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// constraintError 把数据库约束冲突转换为存储错误：唯一约束为 ErrAlreadyExists，
+// 外键、非空和检查约束为 ErrInvalidInput，其他错误原样返回
+func constraintError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	switch sqliteErr.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		return fmt.Errorf("%w: %w", ErrAlreadyExists, err)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY, sqlite3.SQLITE_CONSTRAINT_NOTNULL, sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStorageErrors(t *testing.T) {
+	ctx := context.Background()
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	user := &User{Email: "alice@example.com", PasswordHash: "x", Active: true}
+	if err := driver.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateUser(ctx, &User{Email: "alice@example.com", PasswordHash: "y"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复的用户应该返回 ErrAlreadyExists, got %v", err)
+	}
+	if err := driver.CreateUser(ctx, &User{Email: "alice", PasswordHash: "y"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的邮箱应该返回 ErrInvalidInput, got %v", err)
+	}
+
+	if err := driver.CreateDomain(ctx, &Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateDomain(ctx, &Domain{Name: "example.com"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复的域名应该返回 ErrAlreadyExists, got %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &Domain{}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("空域名应该返回 ErrInvalidInput, got %v", err)
+	}
+
+	alias := &Alias{From: "sales@example.com", To: "alice@example.com", Domain: "example.com"}
+	if err := driver.CreateAlias(ctx, alias); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.CreateAlias(ctx, alias); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复的别名应该返回 ErrAlreadyExists, got %v", err)
+	}
+
+	if err := driver.UpdateQuota(ctx, user.Email, &Quota{Limit: -1}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("负数配额应该返回 ErrInvalidInput, got %v", err)
+	}
+	if err := driver.UpdateQuota(ctx, "nobody@example.com", &Quota{Limit: 1}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的用户应该返回 ErrNotFound, got %v", err)
+	}
+
+	// 外键约束：引用不存在的用户
+	if err := driver.SaveForwardingRule(ctx, &ForwardingRule{UserEmail: "nobody@example.com", Address: "x@example.net"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("引用不存在的用户应该返回 ErrInvalidInput, got %v", err)
+	}
+}
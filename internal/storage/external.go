@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// externalAccountColumns 外部邮箱账户查询的列（与 scanExternalAccount 对应）
+const externalAccountColumns = `id, user_email, protocol, host, port, tls, username, password, folder,
+	keep_remote, enabled, last_fetch_at, last_success_at, last_error, last_count, total_count, created_at, updated_at`
+
+// scanExternalAccount 扫描一行外部邮箱账户
+func scanExternalAccount(row rowScanner) (*ExternalAccount, error) {
+	var account ExternalAccount
+	var tls, keepRemote, enabled int
+	var lastFetchAt, lastSuccessAt sql.NullTime
+	if err := row.Scan(
+		&account.ID,
+		&account.UserEmail,
+		&account.Protocol,
+		&account.Host,
+		&account.Port,
+		&tls,
+		&account.Username,
+		&account.Password,
+		&account.Folder,
+		&keepRemote,
+		&enabled,
+		&lastFetchAt,
+		&lastSuccessAt,
+		&account.LastError,
+		&account.LastCount,
+		&account.TotalCount,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	account.TLS = tls == 1
+	account.KeepRemote = keepRemote == 1
+	account.Enabled = enabled == 1
+	if lastFetchAt.Valid {
+		account.LastFetchAt = &lastFetchAt.Time
+	}
+	if lastSuccessAt.Valid {
+		account.LastSuccessAt = &lastSuccessAt.Time
+	}
+	return &account, nil
+}
+
+// boolInt 将布尔值转换为 SQLite 整数
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// CreateExternalAccount 创建外部邮箱账户，成功后设置 account.ID
+func (d *SQLiteDriver) CreateExternalAccount(ctx context.Context, account *ExternalAccount) error {
+	query := `
+		INSERT INTO external_accounts (user_email, protocol, host, port, tls, username, password,
+			folder, keep_remote, enabled, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, query,
+		account.UserEmail,
+		account.Protocol,
+		account.Host,
+		account.Port,
+		boolInt(account.TLS),
+		account.Username,
+		account.Password,
+		account.Folder,
+		boolInt(account.KeepRemote),
+		boolInt(account.Enabled),
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("创建外部邮箱账户失败: %w", constraintError(err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取外部邮箱账户 ID 失败: %w", err)
+	}
+	account.ID = id
+	account.CreatedAt = now
+	account.UpdatedAt = now
+	return nil
+}
+
+// GetExternalAccount 获取外部邮箱账户
+func (d *SQLiteDriver) GetExternalAccount(ctx context.Context, id int64) (*ExternalAccount, error) {
+	query := `SELECT ` + externalAccountColumns + ` FROM external_accounts WHERE id = ?`
+	account, err := scanExternalAccount(d.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("外部邮箱账户不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询外部邮箱账户失败: %w", err)
+	}
+	return account, nil
+}
+
+// ListExternalAccounts 列出用户的外部邮箱账户，userEmail 为空时列出所有用户的账户
+func (d *SQLiteDriver) ListExternalAccounts(ctx context.Context, userEmail string) ([]*ExternalAccount, error) {
+	query := `SELECT ` + externalAccountColumns + ` FROM external_accounts`
+	var args []any
+	if userEmail != "" {
+		query += ` WHERE user_email = ?`
+		args = append(args, userEmail)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询外部邮箱账户失败: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*ExternalAccount
+	for rows.Next() {
+		account, err := scanExternalAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描外部邮箱账户失败: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// UpdateExternalAccount 更新外部邮箱账户的连接设置（不修改拉取状态）
+func (d *SQLiteDriver) UpdateExternalAccount(ctx context.Context, account *ExternalAccount) error {
+	query := `
+		UPDATE external_accounts
+		SET protocol = ?, host = ?, port = ?, tls = ?, username = ?, password = ?,
+			folder = ?, keep_remote = ?, enabled = ?, updated_at = ?
+		WHERE id = ?
+	`
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, query,
+		account.Protocol,
+		account.Host,
+		account.Port,
+		boolInt(account.TLS),
+		account.Username,
+		account.Password,
+		account.Folder,
+		boolInt(account.KeepRemote),
+		boolInt(account.Enabled),
+		now,
+		account.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新外部邮箱账户失败: %w", constraintError(err))
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("外部邮箱账户不存在: %w", ErrNotFound)
+	}
+	account.UpdatedAt = now
+	return nil
+}
+
+// DeleteExternalAccount 删除外部邮箱账户（已拉取记录级联删除，本地邮件保留）
+func (d *SQLiteDriver) DeleteExternalAccount(ctx context.Context, id int64) error {
+	query := `DELETE FROM external_accounts WHERE id = ?`
+	if _, err := d.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("删除外部邮箱账户失败: %w", err)
+	}
+	return nil
+}
+
+// RecordExternalFetch 记录一次拉取的结果，fetchErr 为空表示成功
+func (d *SQLiteDriver) RecordExternalFetch(ctx context.Context, id int64, at time.Time, count int, fetchErr string) error {
+	query := `
+		UPDATE external_accounts
+		SET last_fetch_at = ?,
+			last_success_at = CASE WHEN ? = '' THEN ? ELSE last_success_at END,
+			last_error = ?,
+			last_count = ?,
+			total_count = total_count + ?
+		WHERE id = ?
+	`
+	if _, err := d.db.ExecContext(ctx, query, at, fetchErr, at, fetchErr, count, count, id); err != nil {
+		return fmt.Errorf("记录拉取状态失败: %w", err)
+	}
+	return nil
+}
+
+// ListFetchedUIDs 获取账户已拉取的远程邮件标识
+func (d *SQLiteDriver) ListFetchedUIDs(ctx context.Context, accountID int64) (map[string]bool, error) {
+	query := `SELECT uid FROM external_account_uids WHERE account_id = ?`
+	rows, err := d.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("查询已拉取邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	uids := make(map[string]bool)
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, fmt.Errorf("扫描已拉取邮件失败: %w", err)
+		}
+		uids[uid] = true
+	}
+	return uids, rows.Err()
+}
+
+// AddFetchedUID 记录已拉取的远程邮件
+func (d *SQLiteDriver) AddFetchedUID(ctx context.Context, accountID int64, uid string) error {
+	query := `INSERT OR IGNORE INTO external_account_uids (account_id, uid, fetched_at) VALUES (?, ?, ?)`
+	if _, err := d.db.ExecContext(ctx, query, accountID, uid, utcNow()); err != nil {
+		return fmt.Errorf("记录已拉取邮件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_ExternalAccountOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &User{Email: email, PasswordHash: "test_hash", Active: true}); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+
+	account := &ExternalAccount{
+		UserEmail: "alice@example.com", Protocol: "pop3", Host: "pop.example.net", Port: 995, TLS: true,
+		Username: "alice", Password: []byte("encrypted"), Folder: "INBOX", KeepRemote: true, Enabled: true,
+	}
+	if err := driver.CreateExternalAccount(ctx, account); err != nil {
+		t.Fatalf("创建外部邮箱账户失败: %v", err)
+	}
+	if account.ID == 0 {
+		t.Fatal("创建后应该设置账户 ID")
+	}
+	other := &ExternalAccount{UserEmail: "bob@example.com", Protocol: "imap", Host: "imap.example.net", Port: 993, Username: "bob", Password: []byte("x"), Folder: "Other"}
+	if err := driver.CreateExternalAccount(ctx, other); err != nil {
+		t.Fatalf("创建外部邮箱账户失败: %v", err)
+	}
+
+	accounts, err := driver.ListExternalAccounts(ctx, "alice@example.com")
+	if err != nil || len(accounts) != 1 {
+		t.Fatalf("列出外部邮箱账户失败: %v %d", err, len(accounts))
+	}
+	if got := accounts[0]; got.Host != "pop.example.net" || !got.TLS || !got.KeepRemote || string(got.Password) != "encrypted" {
+		t.Errorf("外部邮箱账户不正确: %+v", got)
+	}
+	if all, _ := driver.ListExternalAccounts(ctx, ""); len(all) != 2 {
+		t.Errorf("应该列出所有用户的 2 个账户, got %d", len(all))
+	}
+
+	account.KeepRemote = false
+	account.Folder = "Fetched"
+	if err := driver.UpdateExternalAccount(ctx, account); err != nil {
+		t.Fatalf("更新外部邮箱账户失败: %v", err)
+	}
+	if err := driver.UpdateExternalAccount(ctx, &ExternalAccount{ID: 999}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("更新不存在的账户应该返回 ErrNotFound, got %v", err)
+	}
+
+	// 拉取状态：成功累计邮件数，失败保留上次成功时间
+	first := time.Now().Add(-time.Hour).Truncate(time.Second) // 时间按秒存储
+	if err := driver.RecordExternalFetch(ctx, account.ID, first, 3, ""); err != nil {
+		t.Fatalf("记录拉取状态失败: %v", err)
+	}
+	if err := driver.RecordExternalFetch(ctx, account.ID, time.Now(), 0, "连接超时"); err != nil {
+		t.Fatalf("记录拉取状态失败: %v", err)
+	}
+	got, err := driver.GetExternalAccount(ctx, account.ID)
+	if err != nil {
+		t.Fatalf("获取外部邮箱账户失败: %v", err)
+	}
+	if got.Folder != "Fetched" || got.KeepRemote {
+		t.Errorf("更新后的设置不正确: %+v", got)
+	}
+	if got.LastError != "连接超时" || got.TotalCount != 3 || got.LastCount != 0 {
+		t.Errorf("拉取状态不正确: %+v", got)
+	}
+	if got.LastSuccessAt == nil || !got.LastSuccessAt.Equal(first) || got.LastFetchAt == nil || !got.LastFetchAt.After(first) {
+		t.Errorf("拉取时间不正确: %v %v", got.LastSuccessAt, got.LastFetchAt)
+	}
+
+	// 已拉取记录（重复记录忽略）
+	for _, uid := range []string{"a1", "b2", "a1"} {
+		if err := driver.AddFetchedUID(ctx, account.ID, uid); err != nil {
+			t.Fatalf("记录已拉取邮件失败: %v", err)
+		}
+	}
+	uids, err := driver.ListFetchedUIDs(ctx, account.ID)
+	if err != nil || len(uids) != 2 || !uids["a1"] || !uids["b2"] {
+		t.Errorf("已拉取记录不正确: %v %v", uids, err)
+	}
+
+	if err := driver.DeleteExternalAccount(ctx, account.ID); err != nil {
+		t.Fatalf("删除外部邮箱账户失败: %v", err)
+	}
+	if _, err := driver.GetExternalAccount(ctx, account.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后应该返回 ErrNotFound, got %v", err)
+	}
+	if uids, _ := driver.ListFetchedUIDs(ctx, account.ID); len(uids) != 0 {
+		t.Errorf("删除账户后已拉取记录应该级联删除, got %v", uids)
+	}
+}
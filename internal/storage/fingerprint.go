@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SpamFingerprintRetention 垃圾邮件指纹在最后一次出现后的保留时间
+const SpamFingerprintRetention = 90 * 24 * time.Hour
+
+// ReportSpamFingerprint 记录一次被拒收或被投诉的邮件指纹（相同哈希时累加次数），同时清理过期的指纹
+func (d *SQLiteDriver) ReportSpamFingerprint(ctx context.Context, fingerprint *SpamFingerprint, source string) error {
+	now := utcNow()
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM spam_fingerprints WHERE last_seen < ?`, now.Add(-SpamFingerprintRetention)); err != nil {
+		return fmt.Errorf("清理过期垃圾邮件指纹失败: %w", err)
+	}
+
+	query := `
+		INSERT INTO spam_fingerprints (hash, reason, sample, reports, hits, first_seen, last_seen)
+		VALUES (?, ?, ?, 1, 0, ?, ?)
+		ON CONFLICT(hash) DO UPDATE SET
+			reports = spam_fingerprints.reports + 1,
+			last_seen = excluded.last_seen
+	`
+	if _, err := d.db.ExecContext(ctx, query, fingerprint.Hash, fingerprint.Reason, fingerprint.Sample, now, now); err != nil {
+		return fmt.Errorf("记录垃圾邮件指纹失败: %w", constraintError(err))
+	}
+	if err := d.db.QueryRowContext(ctx, `SELECT id FROM spam_fingerprints WHERE hash = ?`, fingerprint.Hash).Scan(&fingerprint.ID); err != nil {
+		return fmt.Errorf("查询垃圾邮件指纹失败: %w", err)
+	}
+	if source != "" {
+		if _, err := d.addSpamFingerprintSource(ctx, fingerprint.ID, source, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordSpamFingerprintHit 记录一封与指纹相似的新邮件，返回指纹出现过的不同来源数
+func (d *SQLiteDriver) RecordSpamFingerprintHit(ctx context.Context, id int64, source string) (int, error) {
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, `UPDATE spam_fingerprints SET hits = hits + 1, last_seen = ? WHERE id = ?`, now, id)
+	if err != nil {
+		return 0, fmt.Errorf("记录垃圾邮件指纹命中失败: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return 0, fmt.Errorf("垃圾邮件指纹不存在: %w", ErrNotFound)
+	}
+	if source == "" {
+		return d.countSpamFingerprintSources(ctx, id)
+	}
+	return d.addSpamFingerprintSource(ctx, id, source, now)
+}
+
+// addSpamFingerprintSource 记录指纹的来源（已记录时忽略），返回不同来源数
+func (d *SQLiteDriver) addSpamFingerprintSource(ctx context.Context, id int64, source string, now time.Time) (int, error) {
+	query := `
+		INSERT INTO spam_fingerprint_sources (fingerprint_id, source, first_seen)
+		VALUES (?, ?, ?)
+		ON CONFLICT(fingerprint_id, source) DO NOTHING
+	`
+	if _, err := d.db.ExecContext(ctx, query, id, source, now); err != nil {
+		return 0, fmt.Errorf("记录垃圾邮件指纹来源失败: %w", err)
+	}
+	return d.countSpamFingerprintSources(ctx, id)
+}
+
+// countSpamFingerprintSources 指纹的不同来源数
+func (d *SQLiteDriver) countSpamFingerprintSources(ctx context.Context, id int64) (int, error) {
+	var n int
+	if err := d.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM spam_fingerprint_sources WHERE fingerprint_id = ?`, id).Scan(&n); err != nil {
+		return 0, fmt.Errorf("查询垃圾邮件指纹来源失败: %w", err)
+	}
+	return n, nil
+}
+
+// ListSpamFingerprints 列出 since 之后出现过的指纹（活跃的垃圾邮件活动），按最后出现时间倒序
+func (d *SQLiteDriver) ListSpamFingerprints(ctx context.Context, since time.Time, limit int) ([]*SpamFingerprint, error) {
+	query := `
+		SELECT f.id, f.hash, f.reason, f.sample, f.reports, f.hits,
+			(SELECT COUNT(*) FROM spam_fingerprint_sources s WHERE s.fingerprint_id = f.id),
+			f.first_seen, f.last_seen
+		FROM spam_fingerprints f
+		WHERE f.last_seen >= ?
+		ORDER BY f.last_seen DESC, f.id DESC
+		LIMIT ?
+	`
+	rows, err := d.db.QueryContext(ctx, query, since.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询垃圾邮件指纹失败: %w", err)
+	}
+	defer rows.Close()
+
+	var fingerprints []*SpamFingerprint
+	for rows.Next() {
+		var f SpamFingerprint
+		if err := rows.Scan(&f.ID, &f.Hash, &f.Reason, &f.Sample, &f.Reports, &f.Hits, &f.Sources, &f.FirstSeen, &f.LastSeen); err != nil {
+			return nil, fmt.Errorf("扫描垃圾邮件指纹失败: %w", err)
+		}
+		fingerprints = append(fingerprints, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询垃圾邮件指纹失败: %w", err)
+	}
+	return fingerprints, nil
+}
+
+// DeleteSpamFingerprint 删除指纹（如误判的正常邮件）
+func (d *SQLiteDriver) DeleteSpamFingerprint(ctx context.Context, id int64) error {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM spam_fingerprints WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("删除垃圾邮件指纹失败: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("垃圾邮件指纹不存在: %w", ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSpamFingerprints(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+	since := time.Now().Add(-time.Hour)
+
+	fp := &SpamFingerprint{Hash: "aa", Reason: "complaint", Sample: "claim your prize"}
+	if err := driver.ReportSpamFingerprint(ctx, fp, "spam.test"); err != nil {
+		t.Fatalf("记录指纹失败: %v", err)
+	}
+	if fp.ID == 0 {
+		t.Fatal("记录后应该设置 ID")
+	}
+	// 相同哈希累加次数，不重复记录来源
+	again := &SpamFingerprint{Hash: "aa", Reason: "rejected"}
+	if err := driver.ReportSpamFingerprint(ctx, again, "spam.test"); err != nil || again.ID != fp.ID {
+		t.Fatalf("相同哈希应该合并: id = %d, err = %v", again.ID, err)
+	}
+
+	sources, err := driver.RecordSpamFingerprintHit(ctx, fp.ID, "other.test")
+	if err != nil || sources != 2 {
+		t.Fatalf("命中后来源数 = %d, err = %v", sources, err)
+	}
+	if sources, err := driver.RecordSpamFingerprintHit(ctx, fp.ID, ""); err != nil || sources != 2 {
+		t.Errorf("空来源不应该增加来源数: %d, err = %v", sources, err)
+	}
+	if _, err := driver.RecordSpamFingerprintHit(ctx, 999, "x.test"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的指纹应该返回 ErrNotFound: %v", err)
+	}
+
+	list, err := driver.ListSpamFingerprints(ctx, since, 10)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("列出指纹: %d, err = %v", len(list), err)
+	}
+	if got := list[0]; got.Reason != "complaint" || got.Sample != "claim your prize" || got.Reports != 2 || got.Hits != 2 || got.Sources != 2 {
+		t.Errorf("指纹 = %+v", got)
+	}
+	if list, _ := driver.ListSpamFingerprints(ctx, time.Now().Add(time.Hour), 10); len(list) != 0 {
+		t.Errorf("since 之后没有出现的指纹不应该列出: %d", len(list))
+	}
+
+	if err := driver.DeleteSpamFingerprint(ctx, fp.ID); err != nil {
+		t.Fatalf("删除指纹失败: %v", err)
+	}
+	if err := driver.DeleteSpamFingerprint(ctx, fp.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("重复删除应该返回 ErrNotFound: %v", err)
+	}
+	if n, _ := driver.countSpamFingerprintSources(ctx, fp.ID); n != 0 {
+		t.Errorf("删除指纹后应该删除来源: %d", n)
+	}
+}
@@ -0,0 +1,419 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FolderDelimiter 文件夹层级分隔符（IMAP 层级分隔符，如 Projects/2026）
+const FolderDelimiter = "/"
+
+// FolderDirName 返回文件夹的 Maildir++ 目录名：. 前缀，层级之间用 . 分隔（Projects/2026 为 .Projects.2026），
+// 名称中的 . 和 % 转义为 %2E 和 %25（v1.0 为 .v1%2E0），任意层级都映射为用户目录下的一个目录
+func FolderDirName(folder string) string {
+	parts := strings.Split(folder, FolderDelimiter)
+	for i, part := range parts {
+		parts[i] = folderNameEscaper.Replace(part)
+	}
+	return "." + strings.Join(parts, ".")
+}
+
+// FolderFromDirName 从 Maildir++ 目录名还原文件夹名称（FolderDirName 的逆操作）
+func FolderFromDirName(name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "."), ".")
+	for i, part := range parts {
+		parts[i] = folderNameUnescaper.Replace(part)
+	}
+	return strings.Join(parts, FolderDelimiter)
+}
+
+var (
+	folderNameEscaper   = strings.NewReplacer("%", "%25", ".", "%2E")
+	folderNameUnescaper = strings.NewReplacer("%25", "%", "%2E", ".", "%2e", ".")
+)
+
+// ValidateFolderName 检查文件夹名称能否映射为 Maildir++ 目录：每一级不能为空，
+// 不能包含反斜杠或控制字符
+func ValidateFolderName(folder string) error {
+	if folder == "" {
+		return fmt.Errorf("文件夹名称不能为空: %w", ErrInvalidInput)
+	}
+	for _, part := range strings.Split(folder, FolderDelimiter) {
+		if part == "" {
+			return fmt.Errorf("文件夹名称 %q 包含空的层级: %w", folder, ErrInvalidInput)
+		}
+		if strings.Contains(part, "\\") || strings.ContainsFunc(part, func(r rune) bool { return r < 0x20 || r == 0x7f }) {
+			return fmt.Errorf("文件夹名称 %q 包含不允许的字符: %w", folder, ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// ParentFolders 返回文件夹的所有上级文件夹，由近及远（A/B/C 为 A/B、A）
+func ParentFolders(folder string) []string {
+	var parents []string
+	for i := strings.LastIndex(folder, FolderDelimiter); i > 0; i = strings.LastIndex(folder, FolderDelimiter) {
+		folder = folder[:i]
+		parents = append(parents, folder)
+	}
+	return parents
+}
+
+// IsSubfolder folder 是否为 parent 的子文件夹（任意层级）
+func IsSubfolder(folder, parent string) bool {
+	return strings.HasPrefix(folder, parent+FolderDelimiter)
+}
+
+// ListFolders 列出用户 Maildir 中存在的文件夹目录（不包括 INBOX），包括还没有邮件的文件夹
+func (m *Maildir) ListFolders(userEmail string) ([]string, error) {
+	entries, err := os.ReadDir(m.GetUserMaildir(userEmail))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取用户 Maildir 失败: %w", err)
+	}
+	var folders []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), ".") || len(entry.Name()) < 2 {
+			continue
+		}
+		folders = append(folders, FolderFromDirName(entry.Name()))
+	}
+	return folders, nil
+}
+
+// CreateFolder 创建文件夹目录（cur、new、tmp），已存在时不报错
+func (m *Maildir) CreateFolder(userEmail, folder string) error {
+	dir := m.folderDir(userEmail, folder)
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return fmt.Errorf("创建文件夹 %s 失败: %w", folder, err)
+		}
+	}
+	return nil
+}
+
+// DeleteFolder 删除文件夹目录及其中的邮件文件（子文件夹是独立的目录，不受影响）
+func (m *Maildir) DeleteFolder(userEmail, folder string) error {
+	if folder == "INBOX" || folder == "" {
+		return fmt.Errorf("不能删除收件箱: %w", ErrInvalidInput)
+	}
+	dir := m.folderDir(userEmail, folder)
+	// 逐个删除邮件文件并记录变更日志，备节点据此删除对应文件
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		m.record(JournalDelete, path, "")
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除文件夹 %s 失败: %w", folder, err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("删除文件夹 %s 失败: %w", folder, err)
+	}
+	return nil
+}
+
+// RenameFolder 重命名文件夹目录，子文件夹随之重命名（.A.B 改名为 .C.B）；
+// 重命名 INBOX 时把收件箱中的邮件移动到新文件夹，收件箱保留为空，子文件夹不受影响（RFC 3501）
+func (m *Maildir) RenameFolder(userEmail, oldName, newName string) error {
+	if err := m.CreateFolder(userEmail, newName); err != nil {
+		return err
+	}
+	if oldName == "INBOX" {
+		return m.moveFolderMails(userEmail, oldName, newName)
+	}
+
+	folders, err := m.ListFolders(userEmail)
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		if folder != oldName && !IsSubfolder(folder, oldName) {
+			continue
+		}
+		target := newName + strings.TrimPrefix(folder, oldName)
+		src, dst := m.folderDir(userEmail, folder), m.folderDir(userEmail, target)
+		if folder == oldName {
+			// 目标目录是刚创建的空目录
+			if err := os.RemoveAll(dst); err != nil {
+				return fmt.Errorf("重命名文件夹 %s 失败: %w", folder, err)
+			}
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return fmt.Errorf("重命名文件夹 %s 失败: %w", folder, err)
+		}
+		m.record(JournalRename, src, dst)
+	}
+	return nil
+}
+
+// moveFolderMails 把文件夹 new/ 和 cur/ 中的邮件文件移动到另一个文件夹（保留 new/cur 位置和标志后缀）
+func (m *Maildir) moveFolderMails(userEmail, srcFolder, dstFolder string) error {
+	srcDir, dstDir := m.folderDir(userEmail, srcFolder), m.folderDir(userEmail, dstFolder)
+	for _, sub := range []string{"cur", "new"} {
+		entries, err := os.ReadDir(filepath.Join(srcDir, sub))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("读取邮件目录失败: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			src := filepath.Join(srcDir, sub, entry.Name())
+			dst := filepath.Join(dstDir, sub, entry.Name())
+			if err := os.Rename(src, dst); err != nil {
+				return fmt.Errorf("移动邮件文件失败: %w", err)
+			}
+			m.record(JournalRename, src, dst)
+		}
+	}
+	return nil
+}
+
+// MigrateFolderLayout 把旧版本创建的文件夹目录迁移为 Maildir++ 格式，返回迁移的文件夹数量：
+// 多级文件夹曾经保存为嵌套的子目录（Projects/2024 为 .Projects/2024），需要展开为用户目录下的
+// .Projects.2024；名称中包含 . 或 % 的文件夹目录按 FolderDirName 重新转义。目标目录已存在时合并邮件文件
+func (m *Maildir) MigrateFolderLayout() (int, error) {
+	users, err := m.ListUsers()
+	if err != nil {
+		return 0, err
+	}
+	migrated := 0
+	for _, user := range users {
+		n, err := m.migrateUserFolders(user)
+		migrated += n
+		if err != nil {
+			return migrated, err
+		}
+	}
+	return migrated, nil
+}
+
+// folderMove 需要迁移的文件夹目录
+type folderMove struct {
+	src, folder string
+}
+
+// migrateUserFolders 迁移一个用户的文件夹目录（子目录先于上级目录迁移）
+func (m *Maildir) migrateUserFolders(userEmail string) (int, error) {
+	userDir := m.GetUserMaildir(userEmail)
+	entries, err := os.ReadDir(userDir)
+	if err != nil {
+		return 0, fmt.Errorf("读取用户 Maildir 失败: %w", err)
+	}
+	var moves []folderMove
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), ".") || len(entry.Name()) < 2 {
+			continue
+		}
+		dir, folder := filepath.Join(userDir, entry.Name()), FolderFromDirName(entry.Name())
+		nested, err := nestedFolders(dir, folder)
+		if err != nil {
+			return 0, err
+		}
+		moves = append(moves, nested...)
+		if FolderDirName(folder) != entry.Name() || (len(nested) > 0 && !isMaildirFolder(dir)) {
+			moves = append(moves, folderMove{src: dir, folder: folder})
+		}
+	}
+
+	migrated := 0
+	for _, move := range moves {
+		if !isMaildirFolder(move.src) {
+			// 只用于容纳子文件夹的中间目录，子文件夹迁移后为空
+			_ = os.Remove(move.src)
+			continue
+		}
+		if err := m.mergeFolderDir(move.src, m.folderDir(userEmail, move.folder)); err != nil {
+			return migrated, fmt.Errorf("迁移文件夹 %s 失败: %w", move.folder, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// nestedFolders 递归查找文件夹目录中嵌套的旧格式子文件夹目录（cur、new、tmp 以外的子目录），由深到浅排列
+func nestedFolders(dir, folder string) ([]folderMove, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件夹目录失败: %w", err)
+	}
+	var moves []folderMove
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == "cur" || name == "new" || name == "tmp" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		child := folderMove{src: filepath.Join(dir, name), folder: folder + FolderDelimiter + name}
+		nested, err := nestedFolders(child.src, child.folder)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(append(moves, nested...), child)
+	}
+	return moves, nil
+}
+
+// isMaildirFolder 目录是否为邮件文件夹（包含 cur 或 new）
+func isMaildirFolder(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFolderDir 把文件夹目录移动到 dst；dst 已存在时逐个移动 cur、new、tmp 中的文件，然后删除原目录
+func (m *Maildir) mergeFolderDir(src, dst string) error {
+	if _, err := os.Stat(dst); os.IsNotExist(err) {
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+		m.record(JournalRename, src, dst)
+		return nil
+	}
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		entries, err := os.ReadDir(filepath.Join(src, sub))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Join(dst, sub), 0755); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			from, to := filepath.Join(src, sub, entry.Name()), filepath.Join(dst, sub, entry.Name())
+			if err := os.Rename(from, to); err != nil {
+				return err
+			}
+			m.record(JournalRename, from, to)
+		}
+	}
+	return os.RemoveAll(src)
+}
+
+// RenameFolder 重命名文件夹：文件夹中的邮件、UID 状态、IMAP METADATA 条目、订阅状态以及引用该文件夹的
+// 订阅邮件归档和外部邮箱设置随之更新，子文件夹一起重命名；重命名 INBOX 时只移动收件箱中的邮件
+// （邮件保留原有 UID，新文件夹使用新的 UIDVALIDITY）
+func (d *SQLiteDriver) RenameFolder(ctx context.Context, userEmail, oldName, newName string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("重命名文件夹失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 新文件夹的 UIDVALIDITY 必须与之前使用过同一名称的文件夹不同
+	uidValidity := uint32(time.Now().Unix()) // #nosec G115 -- 时间戳截断为 32 位，只要求非零且与之前不同
+	if oldName == "INBOX" {
+		statements := []struct {
+			query string
+			args  []interface{}
+		}{
+			{`DELETE FROM mailbox_uids WHERE user_email = ? AND folder = ?`, []interface{}{userEmail, newName}},
+			{`INSERT INTO mailbox_uids (user_email, folder, uid_validity, uid_next, created_at)
+				SELECT user_email, ?, MAX(uid_validity + 1, ?), uid_next, ? FROM mailbox_uids
+				WHERE user_email = ? AND folder = 'INBOX'`, []interface{}{newName, uidValidity, utcNow(), userEmail}},
+			{`UPDATE mails SET folder = ? WHERE user_email = ? AND folder = 'INBOX'`, []interface{}{newName, userEmail}},
+		}
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt.query, stmt.args...); err != nil {
+				return fmt.Errorf("重命名文件夹失败: %w", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("重命名文件夹失败: %w", err)
+		}
+		return nil
+	}
+
+	// 清理目标名称上残留的 UID 状态、METADATA 条目和订阅（如选择或订阅过不存在的文件夹）
+	for _, query := range []string{
+		`DELETE FROM mailbox_uids WHERE user_email = ? AND (folder = ? OR instr(folder, ?) = 1)`,
+		`DELETE FROM metadata WHERE user_email = ? AND (mailbox = ? OR instr(mailbox, ?) = 1)`,
+		`DELETE FROM subscriptions WHERE user_email = ? AND (folder = ? OR instr(folder, ?) = 1)`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userEmail, newName, newName+FolderDelimiter); err != nil {
+			return fmt.Errorf("重命名文件夹失败: %w", err)
+		}
+	}
+
+	// 文件夹本身及其子文件夹（名称以 oldName/ 开头）替换名称前缀
+	updates := []struct {
+		table, column, extra string
+	}{
+		{"mails", "folder", ""},
+		{"mailbox_uids", "folder", ", uid_validity = MAX(uid_validity + 1, ?)"},
+		{"metadata", "mailbox", ""},
+		{"subscriptions", "folder", ""},
+		{"newsletter_settings", "folder", ""},
+		{"external_accounts", "folder", ""},
+	}
+	for _, u := range updates {
+		// #nosec G202 -- 表名和列名为常量
+		query := "UPDATE " + u.table + " SET " + u.column + " = ? || substr(" + u.column + ", length(?) + 1)" + u.extra +
+			" WHERE user_email = ? AND (" + u.column + " = ? OR instr(" + u.column + ", ?) = 1)"
+		args := []interface{}{newName, oldName}
+		if u.extra != "" {
+			args = append(args, uidValidity)
+		}
+		args = append(args, userEmail, oldName, oldName+FolderDelimiter)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("重命名文件夹失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("重命名文件夹失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteFolder 删除文件夹中的邮件、UID 状态和 IMAP METADATA 条目（不包括子文件夹）
+func (d *SQLiteDriver) DeleteFolder(ctx context.Context, userEmail, folder string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("删除文件夹失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM mails WHERE user_email = ? AND folder = ?`,
+		`DELETE FROM mailbox_uids WHERE user_email = ? AND folder = ?`,
+		`DELETE FROM metadata WHERE user_email = ? AND mailbox = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userEmail, folder); err != nil {
+			return fmt.Errorf("删除文件夹失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("删除文件夹失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFolderNames(t *testing.T) {
+	if got := FolderDirName("Projects/2026"); got != ".Projects.2026" {
+		t.Errorf("FolderDirName = %q", got)
+	}
+	if got := FolderFromDirName(".Projects.2026"); got != "Projects/2026" {
+		t.Errorf("FolderFromDirName = %q", got)
+	}
+	for folder, dir := range map[string]string{
+		"Projects/2024/ClientA": ".Projects.2024.ClientA",
+		"Releases/v1.0":         ".Releases.v1%2E0",
+		"50%/..":                ".50%25.%2E%2E",
+		"%2E":                   ".%252E",
+	} {
+		if got := FolderDirName(folder); got != dir {
+			t.Errorf("FolderDirName(%q) = %q, want %q", folder, got, dir)
+		}
+		if got := FolderFromDirName(dir); got != folder {
+			t.Errorf("FolderFromDirName(%q) = %q, want %q", dir, got, folder)
+		}
+	}
+	if got := ParentFolders("A/B/C"); !slices.Equal(got, []string{"A/B", "A"}) {
+		t.Errorf("ParentFolders = %v", got)
+	}
+	for _, name := range []string{"Projects", "项目/2026", "A/B/C", "v1.0", ".."} {
+		if err := ValidateFolderName(name); err != nil {
+			t.Errorf("ValidateFolderName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "A//B", "/A", "A\\B", "A\nB"} {
+		if err := ValidateFolderName(name); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("ValidateFolderName(%q) = %v，期望 ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestMaildirFolders(t *testing.T) {
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const user = "alice@example.com"
+	if err := maildir.EnsureUserMaildir(user); err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []string{"Projects", "Projects/2026"} {
+		if err := maildir.CreateFolder(user, folder); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nested, err := maildir.StoreMail(user, "Projects/2026", []byte("Subject: nested\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inbox, err := maildir.StoreMail(user, "INBOX", []byte("Subject: inbox\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(maildir.GetUserMaildir(user), ".Projects.2026", "new", nested)); err != nil {
+		t.Fatalf("子文件夹目录不符合 Maildir++ 格式: %v", err)
+	}
+
+	// 子文件夹随之重命名
+	if err := maildir.RenameFolder(user, "Projects", "Work"); err != nil {
+		t.Fatal(err)
+	}
+	folders, err := maildir.ListFolders(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(folders, "Projects") || !slices.Contains(folders, "Work") || !slices.Contains(folders, "Work/2026") {
+		t.Errorf("重命名后的文件夹 = %v", folders)
+	}
+	if _, err := maildir.ReadMail(user, "Work/2026", nested); err != nil {
+		t.Errorf("读取重命名后子文件夹中的邮件失败: %v", err)
+	}
+
+	// 重命名 INBOX 只移动邮件
+	if err := maildir.RenameFolder(user, "INBOX", "Old"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := maildir.ReadMail(user, "Old", inbox); err != nil {
+		t.Errorf("收件箱中的邮件没有移动: %v", err)
+	}
+	if _, err := maildir.ReadMail(user, "INBOX", inbox); err == nil {
+		t.Error("收件箱应为空")
+	}
+
+	// 删除文件夹不影响子文件夹
+	if err := maildir.DeleteFolder(user, "Work"); err != nil {
+		t.Fatal(err)
+	}
+	folders, _ = maildir.ListFolders(user)
+	if slices.Contains(folders, "Work") || !slices.Contains(folders, "Work/2026") {
+		t.Errorf("删除后的文件夹 = %v", folders)
+	}
+	if err := maildir.DeleteFolder(user, "INBOX"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("删除收件箱应失败，got %v", err)
+	}
+}
+
+func TestSQLiteDriver_RenameFolder(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	const user = "alice@example.com"
+	if err := driver.CreateUser(ctx, &User{Email: user, PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	for id, folder := range map[string]string{"m1": "INBOX", "m2": "Projects", "m3": "Projects/2026", "m4": "Projects2"} {
+		mail := &Mail{ID: id, UserEmail: user, Folder: folder, From: "bob@example.com", ReceivedAt: time.Now()}
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+	if err := driver.SetMetadata(ctx, &MetadataEntry{UserEmail: user, Mailbox: "Projects/2026", Name: "/private/comment", Value: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := driver.GetMailboxUIDs(ctx, user, "Projects")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := driver.RenameFolder(ctx, user, "Projects", "Work"); err != nil {
+		t.Fatalf("重命名文件夹失败: %v", err)
+	}
+	want := map[string]string{"m1": "INBOX", "m2": "Work", "m3": "Work/2026", "m4": "Projects2"}
+	for id, folder := range want {
+		mail, err := driver.GetMail(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mail.Folder != folder {
+			t.Errorf("邮件 %s 的文件夹 = %q，期望 %q", id, mail.Folder, folder)
+		}
+	}
+	after, err := driver.GetMailboxUIDs(ctx, user, "Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.UIDValidity == before.UIDValidity || after.UIDNext != before.UIDNext {
+		t.Errorf("重命名后的 UID 状态 = %+v，之前 %+v", after, before)
+	}
+	entries, err := driver.ListMetadata(ctx, user, "Work/2026")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("METADATA 条目没有随文件夹重命名: %v %v", entries, err)
+	}
+
+	// 重命名 INBOX：邮件移动到新文件夹，INBOX 之后分配的 UID 不与已有邮件重复
+	if err := driver.RenameFolder(ctx, user, "INBOX", "Old"); err != nil {
+		t.Fatal(err)
+	}
+	if mail, _ := driver.GetMail(ctx, "m1"); mail == nil || mail.Folder != "Old" {
+		t.Errorf("收件箱中的邮件没有移动: %+v", mail)
+	}
+	next := &Mail{ID: "m5", UserEmail: user, Folder: "INBOX", From: "bob@example.com", ReceivedAt: time.Now()}
+	if err := driver.StoreMail(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if next.UID <= 1 {
+		t.Errorf("重命名后收件箱分配的 UID = %d，不应复用", next.UID)
+	}
+
+	if err := driver.DeleteFolder(ctx, user, "Work"); err != nil {
+		t.Fatalf("删除文件夹失败: %v", err)
+	}
+	if _, err := driver.GetMail(ctx, "m2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除文件夹后邮件应不存在，got %v", err)
+	}
+	if _, err := driver.GetMail(ctx, "m3"); err != nil {
+		t.Errorf("子文件夹中的邮件不应被删除: %v", err)
+	}
+}
+
+func TestMigrateFolderLayout(t *testing.T) {
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const user = "alice@example.com"
+	if err := maildir.EnsureUserMaildir(user); err != nil {
+		t.Fatal(err)
+	}
+	userDir := maildir.GetUserMaildir(user)
+	write := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("Subject: test\r\n\r\nbody\r\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 旧格式：多级文件夹保存为嵌套的子目录，Clients 只是容纳子文件夹的目录
+	write(filepath.Join(userDir, ".Projects", "cur", "1.a:2,S"))
+	write(filepath.Join(userDir, ".Projects", "2024", "new", "2.b"))
+	write(filepath.Join(userDir, ".Projects", "2024", "ClientA", "cur", "3.c:2,"))
+	write(filepath.Join(userDir, ".Clients", "Acme", "new", "4.d"))
+	// 目标目录已存在时合并
+	write(filepath.Join(userDir, ".Clients.Acme", "cur", "5.e:2,S"))
+	// 名称中的 % 需要转义
+	write(filepath.Join(userDir, ".50%", "new", "6.f"))
+
+	migrated, err := maildir.MigrateFolderLayout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated != 4 {
+		t.Errorf("迁移的文件夹数量 = %d, want 4", migrated)
+	}
+
+	folders, err := maildir.ListFolders(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(folders)
+	want := []string{"50%", "Archive", "Clients/Acme", "Drafts", "Projects", "Projects/2024", "Projects/2024/ClientA", "Sent", "Spam", "Trash"}
+	if !slices.Equal(folders, want) {
+		t.Errorf("迁移后的文件夹 = %v, want %v", folders, want)
+	}
+	for folder, filename := range map[string]string{
+		"Projects":              "1.a",
+		"Projects/2024":         "2.b",
+		"Projects/2024/ClientA": "3.c",
+		"Clients/Acme":          "4.d",
+		"50%":                   "6.f",
+	} {
+		if _, err := maildir.ReadMail(user, folder, filename); err != nil {
+			t.Errorf("读取 %s 中的邮件失败: %v", folder, err)
+		}
+	}
+	if _, err := maildir.ReadMail(user, "Clients/Acme", "5.e"); err != nil {
+		t.Errorf("合并后原有的邮件丢失: %v", err)
+	}
+
+	// 已经是 Maildir++ 格式时不做任何迁移
+	if migrated, err := maildir.MigrateFolderLayout(); err != nil || migrated != 0 {
+		t.Errorf("再次迁移 = %d, %v", migrated, err)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetForwardingRule 获取用户的转发规则
+func (d *SQLiteDriver) GetForwardingRule(ctx context.Context, userEmail string) (*ForwardingRule, error) {
+	query := `
+		SELECT user_email, address, COALESCE(keep_copy, 1), COALESCE(match_from, ''), COALESCE(match_subject, ''),
+			COALESCE(verified, 0), COALESCE(code_hash, ''), code_expires_at, COALESCE(attempts, 0), created_at, updated_at
+		FROM forwarding_rules
+		WHERE user_email = ?
+	`
+	var rule ForwardingRule
+	var keepCopy, verified int
+	var codeExpiresAt sql.NullTime
+	err := d.db.QueryRowContext(ctx, query, userEmail).Scan(
+		&rule.UserEmail,
+		&rule.Address,
+		&keepCopy,
+		&rule.MatchFrom,
+		&rule.MatchSubject,
+		&verified,
+		&rule.CodeHash,
+		&codeExpiresAt,
+		&rule.Attempts,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("转发规则不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询转发规则失败: %w", err)
+	}
+
+	rule.KeepCopy = keepCopy == 1
+	rule.Verified = verified == 1
+	if codeExpiresAt.Valid {
+		rule.CodeExpiresAt = codeExpiresAt.Time
+	}
+	return &rule, nil
+}
+
+// SaveForwardingRule 保存用户的转发规则（按用户唯一）
+func (d *SQLiteDriver) SaveForwardingRule(ctx context.Context, rule *ForwardingRule) error {
+	query := `
+		INSERT INTO forwarding_rules (user_email, address, keep_copy, match_from, match_subject,
+			verified, code_hash, code_expires_at, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			address = excluded.address,
+			keep_copy = excluded.keep_copy,
+			match_from = excluded.match_from,
+			match_subject = excluded.match_subject,
+			verified = excluded.verified,
+			code_hash = excluded.code_hash,
+			code_expires_at = excluded.code_expires_at,
+			attempts = excluded.attempts,
+			updated_at = excluded.updated_at
+	`
+	keepCopy := 0
+	if rule.KeepCopy {
+		keepCopy = 1
+	}
+	verified := 0
+	if rule.Verified {
+		verified = 1
+	}
+	now := utcNow()
+	_, err := d.db.ExecContext(ctx, query,
+		rule.UserEmail,
+		rule.Address,
+		keepCopy,
+		rule.MatchFrom,
+		rule.MatchSubject,
+		verified,
+		rule.CodeHash,
+		rule.CodeExpiresAt,
+		rule.Attempts,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存转发规则失败: %w", constraintError(err))
+	}
+	return nil
+}
+
+// DeleteForwardingRule 删除用户的转发规则
+func (d *SQLiteDriver) DeleteForwardingRule(ctx context.Context, userEmail string) error {
+	query := `DELETE FROM forwarding_rules WHERE user_email = ?`
+	if _, err := d.db.ExecContext(ctx, query, userEmail); err != nil {
+		return fmt.Errorf("删除转发规则失败: %w", err)
+	}
+	return nil
+}
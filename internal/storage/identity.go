@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// identityColumns 发件身份查询的列（与 scanIdentity 对应）
+const identityColumns = `id, user_email, address, display_name, smtp_host, smtp_port, smtp_username, smtp_password,
+	created_at, updated_at`
+
+// scanIdentity 扫描一行发件身份
+func scanIdentity(row rowScanner) (*Identity, error) {
+	var identity Identity
+	if err := row.Scan(
+		&identity.ID,
+		&identity.UserEmail,
+		&identity.Address,
+		&identity.DisplayName,
+		&identity.SMTPHost,
+		&identity.SMTPPort,
+		&identity.SMTPUsername,
+		&identity.SMTPPassword,
+		&identity.CreatedAt,
+		&identity.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// CreateIdentity 创建发件身份，成功后设置 identity.ID（同一用户的地址不能重复）
+func (d *SQLiteDriver) CreateIdentity(ctx context.Context, identity *Identity) error {
+	query := `
+		INSERT INTO identities (user_email, address, display_name, smtp_host, smtp_port, smtp_username,
+			smtp_password, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, query,
+		identity.UserEmail,
+		identity.Address,
+		identity.DisplayName,
+		identity.SMTPHost,
+		identity.SMTPPort,
+		identity.SMTPUsername,
+		identity.SMTPPassword,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("创建发件身份失败: %w", constraintError(err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取发件身份 ID 失败: %w", err)
+	}
+	identity.ID = id
+	identity.CreatedAt = now
+	identity.UpdatedAt = now
+	return nil
+}
+
+// GetIdentity 获取发件身份
+func (d *SQLiteDriver) GetIdentity(ctx context.Context, id int64) (*Identity, error) {
+	query := `SELECT ` + identityColumns + ` FROM identities WHERE id = ?`
+	identity, err := scanIdentity(d.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("发件身份不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询发件身份失败: %w", err)
+	}
+	return identity, nil
+}
+
+// ListIdentities 列出用户的发件身份
+func (d *SQLiteDriver) ListIdentities(ctx context.Context, userEmail string) ([]*Identity, error) {
+	query := `SELECT ` + identityColumns + ` FROM identities WHERE user_email = ? ORDER BY address`
+	rows, err := d.db.QueryContext(ctx, query, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询发件身份失败: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*Identity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描发件身份失败: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// UpdateIdentity 更新发件身份
+func (d *SQLiteDriver) UpdateIdentity(ctx context.Context, identity *Identity) error {
+	query := `
+		UPDATE identities
+		SET address = ?, display_name = ?, smtp_host = ?, smtp_port = ?, smtp_username = ?,
+			smtp_password = ?, updated_at = ?
+		WHERE id = ?
+	`
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, query,
+		identity.Address,
+		identity.DisplayName,
+		identity.SMTPHost,
+		identity.SMTPPort,
+		identity.SMTPUsername,
+		identity.SMTPPassword,
+		now,
+		identity.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("更新发件身份失败: %w", constraintError(err))
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("发件身份不存在: %w", ErrNotFound)
+	}
+	identity.UpdatedAt = now
+	return nil
+}
+
+// DeleteIdentity 删除发件身份
+func (d *SQLiteDriver) DeleteIdentity(ctx context.Context, id int64) error {
+	query := `DELETE FROM identities WHERE id = ?`
+	if _, err := d.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("删除发件身份失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSQLiteDriver_IdentityOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &User{Email: "alice@example.com", PasswordHash: "test_hash", Active: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	identity := &Identity{
+		UserEmail: "alice@example.com", Address: "alice@gmail.com", DisplayName: "Alice",
+		SMTPHost: "smtp.gmail.com", SMTPPort: 587, SMTPUsername: "alice@gmail.com", SMTPPassword: []byte("encrypted"),
+	}
+	if err := driver.CreateIdentity(ctx, identity); err != nil {
+		t.Fatalf("创建发件身份失败: %v", err)
+	}
+	if identity.ID == 0 {
+		t.Fatal("创建后应该设置发件身份 ID")
+	}
+	duplicate := *identity
+	if err := driver.CreateIdentity(ctx, &duplicate); err == nil {
+		t.Error("同一用户的地址重复时应该失败")
+	}
+
+	identity.SMTPPort = 465
+	if err := driver.UpdateIdentity(ctx, identity); err != nil {
+		t.Fatalf("更新发件身份失败: %v", err)
+	}
+	if err := driver.UpdateIdentity(ctx, &Identity{ID: 999}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("更新不存在的发件身份应该返回 ErrNotFound, got %v", err)
+	}
+
+	identities, err := driver.ListIdentities(ctx, "alice@example.com")
+	if err != nil || len(identities) != 1 {
+		t.Fatalf("列出发件身份失败: %v %d", err, len(identities))
+	}
+	if got := identities[0]; got.SMTPPort != 465 || got.DisplayName != "Alice" || string(got.SMTPPassword) != "encrypted" {
+		t.Errorf("发件身份不正确: %+v", got)
+	}
+
+	if err := driver.DeleteIdentity(ctx, identity.ID); err != nil {
+		t.Fatalf("删除发件身份失败: %v", err)
+	}
+	if _, err := driver.GetIdentity(ctx, identity.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后应该返回 ErrNotFound, got %v", err)
+	}
+}
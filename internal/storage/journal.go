@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// Maildir 变更日志的操作类型
+const (
+	JournalStore  = "store"  // 写入新文件（投递到 new/）
+	JournalRename = "rename" // 重命名（new/ 到 cur/、设置标志或移动到其他文件夹）
+	JournalDelete = "delete" // 删除文件
+)
+
+// MaildirJournal Maildir 变更日志（SQLiteDriver 实现）
+type MaildirJournal interface {
+	AppendMaildirJournal(ctx context.Context, entry *JournalEntry) error
+}
+
+// SetJournal 设置变更日志，之后 Maildir 的文件写入、重命名和删除都会记录到日志中（用于热备复制）
+func (m *Maildir) SetJournal(journal MaildirJournal) {
+	m.journal = journal
+}
+
+// Root 返回 Maildir 根目录
+func (m *Maildir) Root() string {
+	return m.root
+}
+
+// RecordExternal 记录外部投递代理（procmail、OpenSMTPD 等）直接写入的邮件文件，供备节点复制
+func (m *Maildir) RecordExternal(path string) {
+	m.record(JournalStore, path, "")
+}
+
+// record 记录一条变更日志（文件操作已经完成，记录失败只记录警告，由备节点全量同步修复）
+func (m *Maildir) record(op, path, newPath string) {
+	if m.journal == nil {
+		return
+	}
+	entry := &JournalEntry{Op: op}
+	var err error
+	if entry.Path, err = filepath.Rel(m.root, path); err != nil {
+		logger.Warn().Err(err).Str("path", path).Msg("记录 Maildir 变更日志失败")
+		return
+	}
+	entry.Path = filepath.ToSlash(entry.Path)
+	if newPath != "" {
+		rel, err := filepath.Rel(m.root, newPath)
+		if err != nil {
+			logger.Warn().Err(err).Str("path", newPath).Msg("记录 Maildir 变更日志失败")
+			return
+		}
+		entry.NewPath = filepath.ToSlash(rel)
+	}
+	if op == JournalStore {
+		// #nosec G304 -- 路径为 Maildir 内刚写入的文件
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger.Warn().Err(err).Str("path", entry.Path).Msg("记录 Maildir 变更日志失败")
+			return
+		}
+		entry.SHA256 = HashContent(data)
+		entry.Size = int64(len(data))
+	}
+	if err := m.journal.AppendMaildirJournal(context.Background(), entry); err != nil {
+		logger.Warn().Err(err).Str("op", op).Str("path", entry.Path).Msg("记录 Maildir 变更日志失败")
+	}
+}
+
+// HashContent 邮件文件内容的 SHA-256 哈希（十六进制）
+func HashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AppendMaildirJournal 追加一条 Maildir 变更日志，成功后设置 entry.Seq
+func (d *SQLiteDriver) AppendMaildirJournal(ctx context.Context, entry *JournalEntry) error {
+	query := `
+		INSERT INTO maildir_journal (op, path, new_path, sha256, size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx, query, entry.Op, entry.Path, entry.NewPath, entry.SHA256, entry.Size, now)
+	if err != nil {
+		return fmt.Errorf("记录 Maildir 变更日志失败: %w", err)
+	}
+	seq, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取变更日志序号失败: %w", err)
+	}
+	entry.Seq = seq
+	entry.CreatedAt = now
+	return nil
+}
+
+// ListMaildirJournal 列出序号大于 since 的变更日志（按序号升序，最多 limit 条）
+func (d *SQLiteDriver) ListMaildirJournal(ctx context.Context, since int64, limit int) ([]*JournalEntry, error) {
+	query := `
+		SELECT seq, op, path, new_path, sha256, size, created_at
+		FROM maildir_journal WHERE seq > ? ORDER BY seq LIMIT ?
+	`
+	rows, err := d.db.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询 Maildir 变更日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		if err := rows.Scan(&entry.Seq, &entry.Op, &entry.Path, &entry.NewPath, &entry.SHA256, &entry.Size, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描 Maildir 变更日志失败: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetMaildirJournalBounds 返回仍保留的最早日志序号和已分配的最大序号
+// （日志为空时 first 为 last+1；清理过的日志不会重用序号）
+func (d *SQLiteDriver) GetMaildirJournalBounds(ctx context.Context) (first, last int64, err error) {
+	query := `SELECT seq FROM sqlite_sequence WHERE name = 'maildir_journal'`
+	if err := d.db.QueryRowContext(ctx, query).Scan(&last); err != nil && err != sql.ErrNoRows {
+		return 0, 0, fmt.Errorf("查询 Maildir 变更日志序号失败: %w", err)
+	}
+	var minSeq sql.NullInt64
+	if err := d.db.QueryRowContext(ctx, `SELECT MIN(seq) FROM maildir_journal`).Scan(&minSeq); err != nil {
+		return 0, 0, fmt.Errorf("查询 Maildir 变更日志序号失败: %w", err)
+	}
+	if !minSeq.Valid {
+		return last + 1, last, nil
+	}
+	return minSeq.Int64, last, nil
+}
+
+// PruneMaildirJournal 删除 before 之前的变更日志，返回删除的条数
+func (d *SQLiteDriver) PruneMaildirJournal(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM maildir_journal WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("清理 Maildir 变更日志失败: %w", err)
+	}
+	return result.RowsAffected()
+}
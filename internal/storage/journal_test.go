@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMaildirJournal(t *testing.T) {
+	ctx := context.Background()
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	if first, last, err := driver.GetMaildirJournalBounds(ctx); err != nil || first != 1 || last != 0 {
+		t.Errorf("空日志的序号范围 = %d, %d, %v, want 1, 0", first, last, err)
+	}
+
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	maildir.SetJournal(driver)
+	data := []byte("Subject: Hi\r\n\r\nBody")
+	filename, err := maildir.StoreMail("alice@example.com", "INBOX", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := maildir.MoveToCur("alice@example.com", "INBOX", filename, []string{"\\Seen"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := maildir.DeleteMail("alice@example.com", "INBOX", filename); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := driver.ListMaildirJournal(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("ListMaildirJournal() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("日志条数 = %d, want 3", len(entries))
+	}
+	store, rename, del := entries[0], entries[1], entries[2]
+	if store.Op != JournalStore || store.Path != "alice@example.com/new/"+filename || store.SHA256 != HashContent(data) || store.Size != int64(len(data)) {
+		t.Errorf("写入日志不正确: %+v", store)
+	}
+	if rename.Op != JournalRename || rename.Path != store.Path || rename.NewPath != "alice@example.com/cur/"+filename+":2,S" {
+		t.Errorf("重命名日志不正确: %+v", rename)
+	}
+	if del.Op != JournalDelete || del.Path != rename.NewPath {
+		t.Errorf("删除日志不正确: %+v", del)
+	}
+
+	if entries, _ := driver.ListMaildirJournal(ctx, store.Seq, 1); len(entries) != 1 || entries[0].Seq != rename.Seq {
+		t.Errorf("应该只返回 since 之后的日志: %+v", entries)
+	}
+
+	// 清理后序号不会重用
+	if n, err := driver.PruneMaildirJournal(ctx, time.Now().Add(time.Minute)); err != nil || n != 3 {
+		t.Errorf("PruneMaildirJournal() = %d, %v, want 3", n, err)
+	}
+	if first, last, err := driver.GetMaildirJournalBounds(ctx); err != nil || first != del.Seq+1 || last != del.Seq {
+		t.Errorf("清理后的序号范围 = %d, %d, %v, want %d, %d", first, last, err, del.Seq+1, del.Seq)
+	}
+}
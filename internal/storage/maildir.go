@@ -1,18 +1,29 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+// maxHeaderSize ReadMailHeader 读取的邮件头大小上限
+const maxHeaderSize = 1 << 20
+
 // Maildir 实现 Maildir++ 格式存储
 type Maildir struct {
-	root string
+	root       string
+	journal    MaildirJournal // 变更日志（可选，用于热备复制）
+	cipher     MailCipher     // 邮件文件加密（可选，零访问存储）
+	opener     MailOpener     // 解密邮件文件（只在用户会话使用的 Maildir 中设置，见 WithOpener）
+	shardDepth int            // 用户目录的分片层数（0 为不分片，见 SetSharding）
 }
 
 // NewMaildir 创建 Maildir 实例
@@ -27,7 +38,18 @@ func NewMaildir(root string) (*Maildir, error) {
 
 // GetUserMaildir 获取用户的 Maildir 路径
 func (m *Maildir) GetUserMaildir(userEmail string) string {
-	return filepath.Join(m.root, userEmail)
+	flat := filepath.Join(m.root, userEmail)
+	if m.shardDepth == 0 {
+		return flat
+	}
+	dir := filepath.Join(m.root, shardPath(userEmail, m.shardDepth), userEmail)
+	// 启用分片之前创建、还没有迁移的用户目录
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if info, err := os.Stat(flat); err == nil && info.IsDir() {
+			return flat
+		}
+	}
+	return dir
 }
 
 // EnsureUserMaildir 确保用户的 Maildir 目录结构存在
@@ -45,14 +67,14 @@ func (m *Maildir) EnsureUserMaildir(userEmail string) error {
 	}
 
 	// 创建特殊文件夹
-	specialFolders := []string{"Sent", "Drafts", "Trash", "Spam"}
+	specialFolders := []string{"Sent", "Drafts", "Trash", "Spam", "Archive"}
 	for _, folder := range specialFolders {
-		path := filepath.Join(userDir, "."+folder, "cur")
+		path := filepath.Join(userDir, FolderDirName(folder), "cur")
 		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return fmt.Errorf("创建特殊文件夹 %s 失败: %w", folder, err)
 		}
-		path = filepath.Join(userDir, "."+folder, "new")
+		path = filepath.Join(userDir, FolderDirName(folder), "new")
 		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
 		if err := os.MkdirAll(path, 0755); err != nil {
 			return fmt.Errorf("创建特殊文件夹 %s 失败: %w", folder, err)
@@ -83,57 +105,95 @@ func (m *Maildir) GenerateUniqueName() (string, error) {
 	return fmt.Sprintf("%d.%d.%s.%s", timestamp, pid, random, hostname), nil
 }
 
-// StoreMail 存储邮件到 Maildir
+// StoreMail 存储邮件到 Maildir（先写入 tmp/，再重命名到 new/）
 func (m *Maildir) StoreMail(userEmail string, folder string, data []byte) (string, error) {
+	uniqueName, err := m.writeTmp(userEmail, folder, data)
+	if err != nil {
+		return "", err
+	}
+	if err := m.commitTmp(userEmail, folder, uniqueName); err != nil {
+		m.removeTmp(userEmail, folder, uniqueName)
+		return "", err
+	}
+	return uniqueName, nil
+}
+
+// writeTmp 将邮件写入文件夹的 tmp/ 目录并刷盘，返回文件名
+// （tmp/ 中的文件对读取方不可见，需要 commitTmp 后才算投递完成）
+func (m *Maildir) writeTmp(userEmail string, folder string, data []byte) (string, error) {
 	// 确保用户目录存在
 	if err := m.EnsureUserMaildir(userEmail); err != nil {
 		return "", err
 	}
 
+	// 启用零访问存储的用户写入加密后的数据
+	if m.cipher != nil {
+		sealed, err := m.cipher.Seal(userEmail, data)
+		if err != nil {
+			return "", fmt.Errorf("加密邮件失败: %w", err)
+		}
+		data = sealed
+	}
+
 	// 生成唯一文件名
 	uniqueName, err := m.GenerateUniqueName()
 	if err != nil {
 		return "", err
 	}
 
-	// 确定目标文件夹
-	var targetDir string
-	if folder == "INBOX" || folder == "" {
-		targetDir = filepath.Join(m.GetUserMaildir(userEmail), "new")
-	} else {
-		// 特殊文件夹使用 . 前缀
-		targetDir = filepath.Join(m.GetUserMaildir(userEmail), "."+folder, "new")
+	// 自定义文件夹（如订阅邮件归档文件夹）按需创建，特殊文件夹也需要补建 tmp/
+	dir := m.folderDir(userEmail, folder)
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return "", fmt.Errorf("创建文件夹 %s 失败: %w", folder, err)
+		}
 	}
 
-	// 写入文件
-	filePath := filepath.Join(targetDir, uniqueName)
-	// #nosec G306 -- 0644 权限允许组和其他用户读取，这是 Maildir 的标准权限
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	filePath := filepath.Join(dir, "tmp", uniqueName)
+	// #nosec G302 G304 -- 0644 权限允许组和其他用户读取，这是 Maildir 的标准权限；路径由唯一文件名生成
+	f, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", fmt.Errorf("写入邮件文件失败: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = os.Remove(filePath)
+		return "", fmt.Errorf("写入邮件文件失败: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(filePath)
+		return "", fmt.Errorf("写入邮件文件失败: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(filePath)
 		return "", fmt.Errorf("写入邮件文件失败: %w", err)
 	}
 
 	return uniqueName, nil
 }
 
-// MoveToCur 将邮件从 new 移动到 cur（标记为已读）
-func (m *Maildir) MoveToCur(userEmail string, folder string, filename string, flags []string) error {
-	userDir := m.GetUserMaildir(userEmail)
-
-	// 确定源文件夹
-	var srcDir string
-	if folder == "INBOX" || folder == "" {
-		srcDir = filepath.Join(userDir, "new")
-	} else {
-		srcDir = filepath.Join(userDir, "."+folder, "new")
+// commitTmp 将 tmp/ 中的邮件重命名到 new/（同一文件系统内的重命名是原子的）
+func (m *Maildir) commitTmp(userEmail string, folder string, filename string) error {
+	dir := m.folderDir(userEmail, folder)
+	dstPath := filepath.Join(dir, "new", filename)
+	if err := os.Rename(filepath.Join(dir, "tmp", filename), dstPath); err != nil {
+		return fmt.Errorf("移动邮件文件到 new 失败: %w", err)
 	}
+	m.record(JournalStore, dstPath, "")
+	return nil
+}
 
-	// 确定目标文件夹
-	var dstDir string
-	if folder == "INBOX" || folder == "" {
-		dstDir = filepath.Join(userDir, "cur")
-	} else {
-		dstDir = filepath.Join(userDir, "."+folder, "cur")
-	}
+// removeTmp 删除 tmp/ 中未完成投递的邮件
+func (m *Maildir) removeTmp(userEmail string, folder string, filename string) {
+	_ = os.Remove(filepath.Join(m.folderDir(userEmail, folder), "tmp", filename))
+}
+
+// MoveToCur 将邮件从 new 移动到 cur（标记为已读）
+func (m *Maildir) MoveToCur(userEmail string, folder string, filename string, flags []string) error {
+	srcDir := filepath.Join(m.folderDir(userEmail, folder), "new")
+	dstDir := filepath.Join(m.folderDir(userEmail, folder), "cur")
 
 	// 构建标志后缀
 	flagSuffix := ":2,"
@@ -159,21 +219,14 @@ func (m *Maildir) MoveToCur(userEmail string, folder string, filename string, fl
 	if err := os.Rename(srcPath, dstPath); err != nil {
 		return fmt.Errorf("移动邮件文件失败: %w", err)
 	}
+	m.record(JournalRename, srcPath, dstPath)
 
 	return nil
 }
 
 // ReadMail 读取邮件内容
 func (m *Maildir) ReadMail(userEmail string, folder string, filename string) ([]byte, error) {
-	userDir := m.GetUserMaildir(userEmail)
-
-	// 确定文件夹路径
-	var folderDir string
-	if folder == "INBOX" || folder == "" {
-		folderDir = userDir
-	} else {
-		folderDir = filepath.Join(userDir, "."+folder)
-	}
+	folderDir := m.folderDir(userEmail, folder)
 
 	// 尝试从 cur 读取（文件名可能包含标志后缀，如 :2,S）
 	curDir := filepath.Join(folderDir, "cur")
@@ -227,35 +280,149 @@ func (m *Maildir) ReadMail(userEmail string, folder string, filename string) ([]
 	if err != nil {
 		return nil, fmt.Errorf("读取邮件文件失败: %w", err)
 	}
+	if m.opener != nil {
+		return m.opener.Open(userEmail, data)
+	}
+	if m.cipher != nil && m.cipher.Sealed(data) {
+		return nil, fmt.Errorf("用户 %s 的邮件已加密，只能在用户的会话中读取: %w", userEmail, ErrMailboxLocked)
+	}
 
 	return data, nil
 }
 
-// DeleteMail 删除邮件
-func (m *Maildir) DeleteMail(userEmail string, folder string, filename string) error {
-	userDir := m.GetUserMaildir(userEmail)
+// OpenMail 打开邮件用于流式读取，返回邮件大小，调用方负责关闭。
+// 启用了邮件加密时需要解密整个文件，返回的是内存中的内容
+func (m *Maildir) OpenMail(userEmail string, folder string, filename string) (io.ReadCloser, int64, error) {
+	if m.cipher != nil || m.opener != nil {
+		data, err := m.ReadMail(userEmail, folder, filename)
+		if err != nil {
+			return nil, 0, err
+		}
+		return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+	}
 
-	// 尝试从 cur 删除
-	var filePath string
-	if folder == "INBOX" || folder == "" {
-		filePath = filepath.Join(userDir, "cur", filename)
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			filePath = filepath.Join(userDir, "new", filename)
+	filePath, err := m.findMail(userEmail, folder, filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取邮件文件失败: %w", err)
+	}
+	// #nosec G304 -- findMail 拒绝包含路径分隔符的文件名，路径在用户的 Maildir 目录下
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取邮件文件失败: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("读取邮件文件失败: %w", err)
+	}
+	return f, info.Size(), nil
+}
+
+// ReadMailHeader 只读取邮件头（到第一个空行为止，包括空行），不读取正文
+func (m *Maildir) ReadMailHeader(userEmail string, folder string, filename string) ([]byte, error) {
+	rc, _, err := m.OpenMail(userEmail, folder, filename)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var header bytes.Buffer
+	br := bufio.NewReader(io.LimitReader(rc, maxHeaderSize))
+	continued := false // 上一次读取的是超过缓冲区的长行的前一部分
+	for {
+		line, err := br.ReadSlice('\n')
+		header.Write(line)
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continued = true
+			continue
+		case err == io.EOF:
+			// 没有正文的邮件
+			if header.Len() >= maxHeaderSize {
+				return nil, fmt.Errorf("邮件头超过 %d 字节", maxHeaderSize)
+			}
+			return header.Bytes(), nil
+		case err != nil:
+			return nil, fmt.Errorf("读取邮件头失败: %w", err)
 		}
-	} else {
-		filePath = filepath.Join(userDir, "."+folder, "cur", filename)
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			filePath = filepath.Join(userDir, "."+folder, "new", filename)
+		if !continued && len(bytes.TrimRight(line, "\r\n")) == 0 {
+			return header.Bytes(), nil
 		}
+		continued = false
+	}
+}
+
+// DeleteMail 删除邮件（文件名可以不带标志后缀）
+func (m *Maildir) DeleteMail(userEmail string, folder string, filename string) error {
+	filePath, err := m.findMail(userEmail, folder, filename)
+	if err != nil {
+		return fmt.Errorf("删除邮件文件失败: %w", err)
 	}
 
 	if err := os.Remove(filePath); err != nil {
 		return fmt.Errorf("删除邮件文件失败: %w", err)
 	}
+	m.record(JournalDelete, filePath, "")
+
+	return nil
+}
+
+// MoveMail 将邮件文件移动到另一个文件夹（保留 new/cur 位置和标志后缀，同一文件系统内的重命名是原子的）
+func (m *Maildir) MoveMail(userEmail string, srcFolder, dstFolder string, filename string) error {
+	srcPath, err := m.findMail(userEmail, srcFolder, filename)
+	if err != nil {
+		return fmt.Errorf("移动邮件文件失败: %w", err)
+	}
 
+	dstDir := m.folderDir(userEmail, dstFolder)
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Join(dstDir, sub), 0755); err != nil {
+			return fmt.Errorf("创建文件夹 %s 失败: %w", dstFolder, err)
+		}
+	}
+
+	sub := filepath.Base(filepath.Dir(srcPath))
+	dstPath := filepath.Join(dstDir, sub, filepath.Base(srcPath))
+	if err := os.Rename(srcPath, dstPath); err != nil {
+		return fmt.Errorf("移动邮件文件失败: %w", err)
+	}
+	m.record(JournalRename, srcPath, dstPath)
 	return nil
 }
 
+// folderDir 返回文件夹目录（INBOX 为用户目录本身，其他文件夹见 FolderDirName）
+func (m *Maildir) folderDir(userEmail, folder string) string {
+	if folder == "INBOX" || folder == "" {
+		return m.GetUserMaildir(userEmail)
+	}
+	return filepath.Join(m.GetUserMaildir(userEmail), FolderDirName(folder))
+}
+
+// findMail 在文件夹的 cur 和 new 中查找邮件文件（cur 中的文件名可能带有 :2, 标志后缀）
+func (m *Maildir) findMail(userEmail, folder, filename string) (string, error) {
+	if filename == "" || strings.ContainsAny(filename, "/\\") {
+		return "", fmt.Errorf("无效的文件名: %s", filename)
+	}
+	dir := m.folderDir(userEmail, folder)
+	for _, sub := range []string{"cur", "new"} {
+		path := filepath.Join(dir, sub, filename)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), filename+":") {
+				return filepath.Join(dir, sub, entry.Name()), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("邮件文件不存在: %s: %w", filename, os.ErrNotExist)
+}
+
 // ListMails 列出邮件
 func (m *Maildir) ListMails(userEmail string, folder string) ([]string, error) {
 	userDir := m.GetUserMaildir(userEmail)
@@ -265,7 +432,7 @@ func (m *Maildir) ListMails(userEmail string, folder string) ([]string, error) {
 		dir = filepath.Join(userDir, "cur")
 		// 也包含 new 文件夹中的邮件
 	} else {
-		dir = filepath.Join(userDir, "."+folder, "cur")
+		dir = filepath.Join(userDir, FolderDirName(folder), "cur")
 	}
 
 	var files []string
@@ -1,6 +1,8 @@
 package storage
 
 import (
+	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -71,6 +73,42 @@ func TestMaildir(t *testing.T) {
 		}
 	})
 
+	t.Run("OpenMail", func(t *testing.T) {
+		data := []byte("From: test@example.com\r\nSubject: Test\r\n\r\nBody\r\n\r\nmore")
+		filename, err := maildir.StoreMail("test@example.com", "INBOX", data)
+		if err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+		if err := maildir.MoveToCur("test@example.com", "INBOX", filename, []string{"\\Seen"}); err != nil {
+			t.Fatal(err)
+		}
+
+		rc, size, err := maildir.OpenMail("test@example.com", "INBOX", filename)
+		if err != nil {
+			t.Fatalf("打开邮件失败: %v", err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(content) != string(data) || size != int64(len(data)) {
+			t.Errorf("OpenMail() = %q (%d), %v", content, size, err)
+		}
+
+		header, err := maildir.ReadMailHeader("test@example.com", "INBOX", filename)
+		if err != nil || string(header) != "From: test@example.com\r\nSubject: Test\r\n\r\n" {
+			t.Errorf("ReadMailHeader() = %q, %v", header, err)
+		}
+
+		// 没有正文的邮件返回整个文件
+		filename, _ = maildir.StoreMail("test@example.com", "INBOX", []byte("Subject: Test\n"))
+		if header, err := maildir.ReadMailHeader("test@example.com", "INBOX", filename); err != nil || string(header) != "Subject: Test\n" {
+			t.Errorf("ReadMailHeader() = %q, %v", header, err)
+		}
+
+		if _, _, err := maildir.OpenMail("test@example.com", "INBOX", "missing"); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("不存在的邮件应该返回 os.ErrNotExist: %v", err)
+		}
+	})
+
 	t.Run("MoveToCur", func(t *testing.T) {
 		data := []byte("From: test@example.com\nTo: user@example.com\nSubject: Test\n\nBody")
 		filename, err := maildir.StoreMail("test@example.com", "INBOX", data)
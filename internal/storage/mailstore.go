@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gomailzero/gmz/internal/mailparse"
+)
+
+// MailStore 邮件存储：保证 Maildir 文件和数据库记录一起写入
+//
+// 投递顺序为：写入 tmp/ → 插入数据库记录 → 重命名到 new/，任一步失败都会回滚前面的步骤，
+// 因此运行中不会产生没有数据库记录的邮件文件或没有文件的数据库记录；
+// 进程在两步之间崩溃或回滚失败时，残留的 tmp/ 文件由启动时的 Recover 处理
+type MailStore struct {
+	Maildir *Maildir
+	Driver  Driver
+}
+
+// NewMailStore 创建邮件存储（maildir 为 nil 时只写入数据库）
+func NewMailStore(maildir *Maildir, driver Driver) *MailStore {
+	return &MailStore{
+		Maildir: maildir,
+		Driver:  driver,
+	}
+}
+
+// Deliver 投递邮件到 mail.UserEmail 的 mail.Folder 文件夹，成功后 mail.ID 为 Maildir 文件名
+// （未配置 Maildir 时只写入数据库，由调用方设置 mail.ID）；超出用户配额时返回 *QuotaExceededError
+func (s *MailStore) Deliver(ctx context.Context, mail *Mail, data []byte) error {
+	size := mail.Size
+	if size == 0 {
+		size = int64(len(data))
+	}
+	if err := s.CheckQuota(ctx, mail.UserEmail, size); err != nil {
+		return err
+	}
+	setThreadHeaders(mail, data)
+
+	if s.Maildir == nil {
+		return s.Driver.StoreMail(ctx, mail)
+	}
+
+	filename, err := s.Maildir.writeTmp(mail.UserEmail, mail.Folder, data)
+	if err != nil {
+		return fmt.Errorf("存储邮件到 Maildir 失败: %w", err)
+	}
+
+	mail.ID = filename
+	if err := s.Driver.StoreMail(ctx, mail); err != nil {
+		// 出错时记录可能已经写入（如提交后连接断开），按文件名删除，文件名唯一不会误删；
+		// 删除也失败时保留 tmp/ 文件，由 Recover 根据记录是否存在完成投递或删除
+		if delErr := s.Driver.DeleteMail(ctx, filename); delErr != nil && !errors.Is(delErr, ErrNotFound) {
+			return fmt.Errorf("存储邮件元数据失败: %w（回滚数据库记录失败: %v）", err, delErr)
+		}
+		s.Maildir.removeTmp(mail.UserEmail, mail.Folder, filename)
+		return fmt.Errorf("存储邮件元数据失败: %w", err)
+	}
+
+	if err := s.Maildir.commitTmp(mail.UserEmail, mail.Folder, filename); err != nil {
+		if delErr := s.Driver.DeleteMail(ctx, filename); delErr != nil {
+			return fmt.Errorf("%w（回滚数据库记录失败: %v）", err, delErr)
+		}
+		s.Maildir.removeTmp(mail.UserEmail, mail.Folder, filename)
+		return err
+	}
+	return nil
+}
+
+// setThreadHeaders 从邮件头解析会话相关的 Message-ID（调用方已设置时不覆盖）
+func setThreadHeaders(mail *Mail, data []byte) {
+	if mail.MessageID != "" || mail.InReplyTo != "" || len(mail.References) > 0 {
+		return
+	}
+	msg, err := mailparse.ParseHeader(data)
+	if err != nil {
+		return
+	}
+	mail.MessageID = msg.MessageID
+	mail.InReplyTo = msg.InReplyTo
+	mail.References = msg.References
+}
+
+// CheckQuota 检查用户还能否存入 size 字节（配额为 0 表示无限制）
+//
+// 只在确认超出配额时返回 *QuotaExceededError；查询配额失败时不阻止投递，避免数据库故障时丢信
+func (s *MailStore) CheckQuota(ctx context.Context, userEmail string, size int64) error {
+	quota, err := s.Driver.GetQuota(ctx, userEmail)
+	if err != nil || quota.Limit <= 0 {
+		return nil
+	}
+	if quota.Used+size > quota.Limit {
+		return &QuotaExceededError{UserEmail: userEmail, Used: quota.Used, Limit: quota.Limit, Size: size}
+	}
+	return nil
+}
+
+// Recover 处理上次进程崩溃时残留在 tmp/ 中的邮件：已有数据库记录的完成投递（重命名到 new/），
+// 没有数据库记录的删除。应在启动时、开始接收邮件之前调用，返回完成投递和删除的文件数
+func (s *MailStore) Recover(ctx context.Context) (recovered, removed int, err error) {
+	if s.Maildir == nil {
+		return 0, 0, nil
+	}
+
+	err = filepath.WalkDir(s.Maildir.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || d.Name() != "tmp" {
+			return nil
+		}
+
+		// 用户目录下的 tmp/ 属于 INBOX，.Folder/tmp/ 属于对应文件夹
+		rel, err := filepath.Rel(s.Maildir.root, path)
+		if err != nil {
+			return err
+		}
+		userEmail, folder, rest, ok := s.Maildir.ParsePath(rel)
+		if !ok || len(rest) != 1 {
+			return filepath.SkipDir
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			filename := entry.Name()
+			mail, err := s.Driver.GetMail(ctx, filename)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err == nil && mail.UserEmail == userEmail && mail.Folder == folder {
+				if err := s.Maildir.commitTmp(userEmail, folder, filename); err != nil {
+					return err
+				}
+				recovered++
+				continue
+			}
+			if err := os.Remove(filepath.Join(path, filename)); err != nil {
+				return fmt.Errorf("删除残留邮件文件失败: %w", err)
+			}
+			removed++
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return recovered, removed, fmt.Errorf("恢复未完成投递的邮件失败: %w", err)
+	}
+	return recovered, removed, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMailStore(t *testing.T) {
+	ctx := context.Background()
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 Maildir 失败: %v", err)
+	}
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	store := NewMailStore(maildir, driver)
+	data := []byte("From: bob@example.com\r\nSubject: Hi\r\n\r\nBody")
+
+	t.Run("Deliver", func(t *testing.T) {
+		mail := &Mail{UserEmail: "alice@example.com", Folder: "Archive", From: "bob@example.com", Size: int64(len(data)), ReceivedAt: time.Now()}
+		if err := store.Deliver(ctx, mail, data); err != nil {
+			t.Fatalf("Deliver() error = %v", err)
+		}
+		if _, err := driver.GetMail(ctx, mail.ID); err != nil {
+			t.Errorf("数据库中应该有邮件记录: %v", err)
+		}
+		if got, err := maildir.ReadMail("alice@example.com", "Archive", mail.ID); err != nil || string(got) != string(data) {
+			t.Errorf("读取邮件文件失败: %v", err)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(maildir.folderDir("alice@example.com", "Archive"), "tmp")); len(entries) != 0 {
+			t.Errorf("投递完成后 tmp/ 应该为空, got %d", len(entries))
+		}
+	})
+
+	t.Run("Recover", func(t *testing.T) {
+		// 模拟崩溃：一个文件已写入数据库但未移动到 new/，一个文件没有数据库记录
+		committed, err := maildir.writeTmp("alice@example.com", "INBOX", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := driver.StoreMail(ctx, &Mail{ID: committed, UserEmail: "alice@example.com", Folder: "INBOX", From: "x", ReceivedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		orphan, err := maildir.writeTmp("alice@example.com", "Sent", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recovered, removed, err := store.Recover(ctx)
+		if err != nil {
+			t.Fatalf("Recover() error = %v", err)
+		}
+		if recovered != 1 || removed != 1 {
+			t.Errorf("Recover() = %d, %d, want 1, 1", recovered, removed)
+		}
+		if _, err := maildir.ReadMail("alice@example.com", "INBOX", committed); err != nil {
+			t.Errorf("已有数据库记录的邮件应该完成投递: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(maildir.folderDir("alice@example.com", "Sent"), "tmp", orphan)); !os.IsNotExist(err) {
+			t.Error("没有数据库记录的残留文件应该被删除")
+		}
+	})
+
+	t.Run("QuotaExceeded", func(t *testing.T) {
+		if err := driver.CreateUser(ctx, &User{Email: "dave@example.com", PasswordHash: "x", Quota: int64(len(data)) + 10, Active: true}); err != nil {
+			t.Fatal(err)
+		}
+		mail := &Mail{UserEmail: "dave@example.com", Folder: "INBOX", From: "x", Size: int64(len(data)), ReceivedAt: time.Now()}
+		if err := store.Deliver(ctx, mail, data); err != nil {
+			t.Fatalf("配额内的投递应该成功: %v", err)
+		}
+
+		mail = &Mail{UserEmail: "dave@example.com", Folder: "INBOX", From: "x", Size: int64(len(data)), ReceivedAt: time.Now()}
+		err := store.Deliver(ctx, mail, data)
+		var quotaErr *QuotaExceededError
+		if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &quotaErr) || quotaErr.Limit != int64(len(data))+10 {
+			t.Fatalf("超出配额应该返回 QuotaExceededError, got %v", err)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(maildir.GetUserMaildir("dave@example.com"), "tmp")); len(entries) != 0 {
+			t.Error("超出配额时不应该写入邮件文件")
+		}
+	})
+
+	t.Run("RollbackOnDatabaseError", func(t *testing.T) {
+		closed, err := NewSQLiteDriver(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		closed.Close()
+
+		mail := &Mail{UserEmail: "carol@example.com", Folder: "INBOX", From: "x", ReceivedAt: time.Now()}
+		if err := NewMailStore(maildir, closed).Deliver(ctx, mail, data); err == nil {
+			t.Fatal("数据库写入失败时应该返回错误")
+		}
+		// 无法确认数据库记录是否已写入，保留 tmp/ 文件，由 Recover 处理
+		dir := maildir.GetUserMaildir("carol@example.com")
+		for _, sub := range []string{"new", "cur"} {
+			if entries, _ := os.ReadDir(filepath.Join(dir, sub)); len(entries) != 0 {
+				t.Errorf("数据库写入失败后 %s/ 不应该有邮件文件", sub)
+			}
+		}
+		if _, removed, err := NewMailStore(maildir, driver).Recover(ctx); err != nil || removed != 1 {
+			t.Errorf("Recover() removed = %d, %v, want 1", removed, err)
+		}
+		if entries, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(entries) != 0 {
+			t.Error("Recover 后 tmp/ 不应该残留邮件文件")
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListMetadata 列出邮箱的全部 METADATA 条目（mailbox 为空字符串时为服务器级条目）
+func (d *SQLiteDriver) ListMetadata(ctx context.Context, userEmail, mailbox string) ([]*MetadataEntry, error) {
+	query := `
+		SELECT user_email, mailbox, name, value, updated_at
+		FROM metadata
+		WHERE user_email = ? AND mailbox = ?
+		ORDER BY name
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, mailbox)
+	if err != nil {
+		return nil, fmt.Errorf("查询 METADATA 条目失败: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*MetadataEntry
+	for rows.Next() {
+		var entry MetadataEntry
+		if err := rows.Scan(&entry.UserEmail, &entry.Mailbox, &entry.Name, &entry.Value, &entry.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("扫描 METADATA 条目失败: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// SetMetadata 设置 METADATA 条目（已存在时覆盖）
+func (d *SQLiteDriver) SetMetadata(ctx context.Context, entry *MetadataEntry) error {
+	query := `
+		INSERT INTO metadata (user_email, mailbox, name, value, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_email, mailbox, name) DO UPDATE SET
+			value = excluded.value,
+			updated_at = excluded.updated_at
+	`
+	if _, err := d.db.ExecContext(ctx, query, entry.UserEmail, entry.Mailbox, entry.Name, entry.Value, utcNow()); err != nil {
+		return fmt.Errorf("保存 METADATA 条目失败: %w", err)
+	}
+	return nil
+}
+
+// DeleteMetadata 删除 METADATA 条目（条目不存在时不报错）
+func (d *SQLiteDriver) DeleteMetadata(ctx context.Context, userEmail, mailbox, name string) error {
+	query := `DELETE FROM metadata WHERE user_email = ? AND mailbox = ? AND name = ?`
+	if _, err := d.db.ExecContext(ctx, query, userEmail, mailbox, name); err != nil {
+		return fmt.Errorf("删除 METADATA 条目失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// storeUnsubscribe 写入邮件的退订方式
+func (d *SQLiteDriver) storeUnsubscribe(ctx context.Context, mail *Mail) error {
+	u := mail.Unsubscribe
+	if u == nil {
+		return nil
+	}
+	oneClick := 0
+	if u.OneClick {
+		oneClick = 1
+	}
+	query := `
+		INSERT INTO mail_unsubscribe (mail_id, list_id, mailto, url, one_click)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	if _, err := d.db.ExecContext(ctx, query, mail.ID, u.ListID, u.Mailto, u.URL, oneClick); err != nil {
+		return fmt.Errorf("存储退订信息失败: %w", err)
+	}
+	return nil
+}
+
+// GetUnsubscribe 获取邮件的退订方式
+func (d *SQLiteDriver) GetUnsubscribe(ctx context.Context, mailID string) (*Unsubscribe, error) {
+	query := `
+		SELECT mail_id, list_id, mailto, url, one_click, unsubscribed_at
+		FROM mail_unsubscribe
+		WHERE mail_id = ?
+	`
+	var u Unsubscribe
+	var oneClick int
+	var unsubscribedAt sql.NullTime
+	err := d.db.QueryRowContext(ctx, query, mailID).Scan(&u.MailID, &u.ListID, &u.Mailto, &u.URL, &oneClick, &unsubscribedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("退订信息不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询退订信息失败: %w", err)
+	}
+	u.OneClick = oneClick == 1
+	if unsubscribedAt.Valid {
+		u.UnsubscribedAt = &unsubscribedAt.Time
+	}
+	return &u, nil
+}
+
+// MarkUnsubscribed 记录已退订
+func (d *SQLiteDriver) MarkUnsubscribed(ctx context.Context, mailID string, at time.Time) error {
+	result, err := d.db.ExecContext(ctx, "UPDATE mail_unsubscribe SET unsubscribed_at = ? WHERE mail_id = ?", at, mailID)
+	if err != nil {
+		return fmt.Errorf("更新退订状态失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("退订信息不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// GetNewsletterSettings 获取用户的订阅邮件设置（未设置时返回默认值）
+func (d *SQLiteDriver) GetNewsletterSettings(ctx context.Context, userEmail string) (*NewsletterSettings, error) {
+	settings := &NewsletterSettings{UserEmail: userEmail}
+	err := d.db.QueryRowContext(ctx,
+		"SELECT folder, updated_at FROM newsletter_settings WHERE user_email = ?", userEmail,
+	).Scan(&settings.Folder, &settings.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("查询订阅邮件设置失败: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveNewsletterSettings 保存用户的订阅邮件设置
+func (d *SQLiteDriver) SaveNewsletterSettings(ctx context.Context, settings *NewsletterSettings) error {
+	query := `
+		INSERT INTO newsletter_settings (user_email, folder, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			folder = excluded.folder,
+			updated_at = excluded.updated_at
+	`
+	settings.UpdatedAt = utcNow()
+	if _, err := d.db.ExecContext(ctx, query, settings.UserEmail, settings.Folder, settings.UpdatedAt); err != nil {
+		return fmt.Errorf("保存订阅邮件设置失败: %w", err)
+	}
+	return nil
+}
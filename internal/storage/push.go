@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// pushSubscriptionColumns WebPush 订阅查询的列（与 scanPushSubscription 对应）
+const pushSubscriptionColumns = `id, user_email, endpoint, p256dh, auth, privacy, user_agent, created_at, last_push_at`
+
+// scanPushSubscription 扫描一行 WebPush 订阅
+func scanPushSubscription(row rowScanner) (*PushSubscription, error) {
+	var sub PushSubscription
+	var lastPushAt sql.NullTime
+	if err := row.Scan(
+		&sub.ID,
+		&sub.UserEmail,
+		&sub.Endpoint,
+		&sub.P256DH,
+		&sub.Auth,
+		&sub.Privacy,
+		&sub.UserAgent,
+		&sub.CreatedAt,
+		&lastPushAt,
+	); err != nil {
+		return nil, err
+	}
+	if lastPushAt.Valid {
+		sub.LastPushAt = &lastPushAt.Time
+	}
+	return &sub, nil
+}
+
+// SavePushSubscription 保存 WebPush 订阅，成功后设置 sub.ID。
+// endpoint 已存在时（同一浏览器重新订阅或换了登录用户）更新密钥、用户和通知内容
+func (d *SQLiteDriver) SavePushSubscription(ctx context.Context, sub *PushSubscription) error {
+	query := `
+		INSERT INTO push_subscriptions (user_email, endpoint, p256dh, auth, privacy, user_agent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(endpoint) DO UPDATE SET
+			user_email = excluded.user_email,
+			p256dh = excluded.p256dh,
+			auth = excluded.auth,
+			privacy = excluded.privacy,
+			user_agent = excluded.user_agent
+	`
+	if _, err := d.db.ExecContext(ctx, query,
+		sub.UserEmail,
+		sub.Endpoint,
+		sub.P256DH,
+		sub.Auth,
+		sub.Privacy,
+		sub.UserAgent,
+		utcNow(),
+	); err != nil {
+		return fmt.Errorf("保存推送订阅失败: %w", constraintError(err))
+	}
+
+	saved, err := scanPushSubscription(d.db.QueryRowContext(ctx,
+		`SELECT `+pushSubscriptionColumns+` FROM push_subscriptions WHERE endpoint = ?`, sub.Endpoint))
+	if err != nil {
+		return fmt.Errorf("查询推送订阅失败: %w", err)
+	}
+	*sub = *saved
+	return nil
+}
+
+// ListPushSubscriptions 列出用户的 WebPush 订阅（按创建时间排序）
+func (d *SQLiteDriver) ListPushSubscriptions(ctx context.Context, userEmail string) ([]*PushSubscription, error) {
+	query := `SELECT ` + pushSubscriptionColumns + ` FROM push_subscriptions WHERE user_email = ? ORDER BY id`
+	rows, err := d.db.QueryContext(ctx, query, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询推送订阅失败: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*PushSubscription
+	for rows.Next() {
+		sub, err := scanPushSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描推送订阅失败: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// DeletePushSubscription 删除用户的 WebPush 订阅
+func (d *SQLiteDriver) DeletePushSubscription(ctx context.Context, userEmail string, id int64) error {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE id = ? AND user_email = ?`, id, userEmail)
+	if err != nil {
+		return fmt.Errorf("删除推送订阅失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("推送订阅不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// DeletePushSubscriptionByEndpoint 删除推送服务报告已失效的订阅（不存在时不报错）
+func (d *SQLiteDriver) DeletePushSubscriptionByEndpoint(ctx context.Context, endpoint string) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM push_subscriptions WHERE endpoint = ?`, endpoint); err != nil {
+		return fmt.Errorf("删除推送订阅失败: %w", err)
+	}
+	return nil
+}
+
+// RecordPush 记录推送成功的时间
+func (d *SQLiteDriver) RecordPush(ctx context.Context, id int64, at time.Time) error {
+	if _, err := d.db.ExecContext(ctx, `UPDATE push_subscriptions SET last_push_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("更新推送时间失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_PushSubscriptions(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	sub := &PushSubscription{
+		UserEmail: "alice@example.com", Endpoint: "https://push.example.net/a",
+		P256DH: []byte("key"), Auth: []byte("auth"), Privacy: "full", UserAgent: "Firefox",
+	}
+	if err := driver.SavePushSubscription(ctx, sub); err != nil {
+		t.Fatalf("保存推送订阅失败: %v", err)
+	}
+	if sub.ID == 0 || sub.CreatedAt.IsZero() {
+		t.Fatalf("保存后应该设置 ID 和创建时间: %+v", sub)
+	}
+
+	// 同一浏览器重新订阅时更新，ID 不变
+	again := &PushSubscription{
+		UserEmail: "alice@example.com", Endpoint: "https://push.example.net/a",
+		P256DH: []byte("key2"), Auth: []byte("auth2"), Privacy: "none",
+	}
+	if err := driver.SavePushSubscription(ctx, again); err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != sub.ID || string(again.P256DH) != "key2" || again.Privacy != "none" {
+		t.Errorf("重新订阅应该更新已有订阅: %+v", again)
+	}
+
+	other := &PushSubscription{UserEmail: "bob@example.com", Endpoint: "https://push.example.net/b", P256DH: []byte("k"), Auth: []byte("a")}
+	if err := driver.SavePushSubscription(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := driver.RecordPush(ctx, sub.ID, now); err != nil {
+		t.Fatal(err)
+	}
+	subs, err := driver.ListPushSubscriptions(ctx, "alice@example.com")
+	if err != nil || len(subs) != 1 || subs[0].LastPushAt == nil || !subs[0].LastPushAt.Equal(now) {
+		t.Fatalf("ListPushSubscriptions() = %+v, %v", subs, err)
+	}
+
+	// 不能删除其他用户的订阅
+	if err := driver.DeletePushSubscription(ctx, "alice@example.com", other.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除其他用户的订阅应该返回 ErrNotFound: %v", err)
+	}
+	if err := driver.DeletePushSubscription(ctx, "alice@example.com", sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.DeletePushSubscriptionByEndpoint(ctx, other.Endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if subs, _ := driver.ListPushSubscriptions(ctx, "bob@example.com"); len(subs) != 0 {
+		t.Errorf("订阅应该已删除: %+v", subs)
+	}
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListQuotas 列出设置了配额的用户的用量
+func (d *SQLiteDriver) ListQuotas(ctx context.Context) ([]*Quota, error) {
+	query := `
+		SELECT users.email, quota, COALESCE(SUM(size), 0) as used
+		FROM users
+		LEFT JOIN mails ON users.email = mails.user_email
+		WHERE users.quota > 0
+		GROUP BY users.email, users.quota
+		ORDER BY users.email
+	`
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("查询配额列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var quotas []*Quota
+	for rows.Next() {
+		var quota Quota
+		if err := rows.Scan(&quota.UserEmail, &quota.Limit, &quota.Used); err != nil {
+			return nil, fmt.Errorf("扫描配额失败: %w", err)
+		}
+		quotas = append(quotas, &quota)
+	}
+	return quotas, rows.Err()
+}
+
+// GetQuotaWarning 获取用户已发送预警的最高阈值
+func (d *SQLiteDriver) GetQuotaWarning(ctx context.Context, userEmail string) (*QuotaWarning, error) {
+	query := `SELECT user_email, threshold, warned_at FROM quota_warnings WHERE user_email = ?`
+	var warning QuotaWarning
+	err := d.db.QueryRowContext(ctx, query, userEmail).Scan(&warning.UserEmail, &warning.Threshold, &warning.WarnedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("配额预警不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询配额预警失败: %w", err)
+	}
+	return &warning, nil
+}
+
+// SaveQuotaWarning 保存用户已发送预警的最高阈值（按用户唯一）
+func (d *SQLiteDriver) SaveQuotaWarning(ctx context.Context, warning *QuotaWarning) error {
+	query := `
+		INSERT INTO quota_warnings (user_email, threshold, warned_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			threshold = excluded.threshold,
+			warned_at = excluded.warned_at
+	`
+	if warning.WarnedAt.IsZero() {
+		warning.WarnedAt = utcNow()
+	}
+	if _, err := d.db.ExecContext(ctx, query, warning.UserEmail, warning.Threshold, warning.WarnedAt); err != nil {
+		return fmt.Errorf("保存配额预警失败: %w", constraintError(err))
+	}
+	return nil
+}
+
+// DeleteQuotaWarning 清除用户的配额预警记录
+func (d *SQLiteDriver) DeleteQuotaWarning(ctx context.Context, userEmail string) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM quota_warnings WHERE user_email = ?`, userEmail); err != nil {
+		return fmt.Errorf("删除配额预警失败: %w", err)
+	}
+	return nil
+}
+
+// formatThresholds 校验预警阈值（1 ~ 100 的百分比）并格式化为升序、去重的逗号分隔列表
+func formatThresholds(thresholds []int) (string, error) {
+	sorted := append([]int(nil), thresholds...)
+	sort.Ints(sorted)
+	parts := make([]string, 0, len(sorted))
+	for i, t := range sorted {
+		if t < 1 || t > 100 {
+			return "", fmt.Errorf("无效的配额预警阈值 %d（需要 1 ~ 100）: %w", t, ErrInvalidInput)
+		}
+		if i > 0 && sorted[i-1] == t {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(t))
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// parseThresholds 解析 formatThresholds 生成的列表（忽略无效项）
+func parseThresholds(s string) []int {
+	var thresholds []int
+	for _, part := range strings.Split(s, ",") {
+		if t, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			thresholds = append(thresholds, t)
+		}
+	}
+	return thresholds
+}
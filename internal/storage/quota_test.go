@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSQLiteDriver_QuotaWarnings(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// 域名预警阈值去重并排序，无效值被拒绝
+	domain := &Domain{Name: "example.com", Active: true, QuotaWarningThresholds: []int{95, 80, 95}}
+	if err := driver.CreateDomain(ctx, domain); err != nil {
+		t.Fatalf("创建域名失败: %v", err)
+	}
+	got, err := driver.GetDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("获取域名失败: %v", err)
+	}
+	if !reflect.DeepEqual(got.QuotaWarningThresholds, []int{80, 95}) {
+		t.Errorf("预警阈值 = %v", got.QuotaWarningThresholds)
+	}
+	got.QuotaWarningThresholds = []int{0}
+	if err := driver.UpdateDomain(ctx, got); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的阈值应该返回 ErrInvalidInput: %v", err)
+	}
+	got.QuotaWarningThresholds = nil
+	if err := driver.UpdateDomain(ctx, got); err != nil {
+		t.Fatalf("更新域名失败: %v", err)
+	}
+	if domains, err := driver.ListDomains(ctx); err != nil || len(domains) != 1 || domains[0].QuotaWarningThresholds != nil {
+		t.Errorf("清空后应该使用默认阈值: %+v, err = %v", domains, err)
+	}
+
+	// 只列出设置了配额的用户
+	for _, u := range []*User{
+		{Email: "limited@example.com", PasswordHash: "x", Quota: 1000, Active: true},
+		{Email: "unlimited@example.com", PasswordHash: "x", Active: true},
+	} {
+		if err := driver.CreateUser(ctx, u); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	if err := driver.StoreMail(ctx, &Mail{ID: "m1", UserEmail: "limited@example.com", Folder: "INBOX", Size: 850}); err != nil {
+		t.Fatalf("存储邮件失败: %v", err)
+	}
+	quotas, err := driver.ListQuotas(ctx)
+	if err != nil {
+		t.Fatalf("查询配额列表失败: %v", err)
+	}
+	if len(quotas) != 1 || quotas[0].UserEmail != "limited@example.com" || quotas[0].Used != 850 || quotas[0].Limit != 1000 {
+		t.Errorf("配额列表不正确: %+v", quotas)
+	}
+
+	// 预警记录
+	if _, err := driver.GetQuotaWarning(ctx, "limited@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("没有预警时应该返回 ErrNotFound: %v", err)
+	}
+	for _, threshold := range []int{80, 95} {
+		if err := driver.SaveQuotaWarning(ctx, &QuotaWarning{UserEmail: "limited@example.com", Threshold: threshold}); err != nil {
+			t.Fatalf("保存配额预警失败: %v", err)
+		}
+	}
+	warning, err := driver.GetQuotaWarning(ctx, "limited@example.com")
+	if err != nil || warning.Threshold != 95 || warning.WarnedAt.IsZero() {
+		t.Errorf("配额预警不正确: %+v, err = %v", warning, err)
+	}
+	if err := driver.SaveQuotaWarning(ctx, &QuotaWarning{UserEmail: "nobody@example.com", Threshold: 80}); err == nil {
+		t.Error("不存在的用户不应该保存预警")
+	}
+	if err := driver.DeleteQuotaWarning(ctx, "limited@example.com"); err != nil {
+		t.Fatalf("删除配额预警失败: %v", err)
+	}
+	if _, err := driver.GetQuotaWarning(ctx, "limited@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后应该返回 ErrNotFound: %v", err)
+	}
+}
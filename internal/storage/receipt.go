@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// validReceipts 检查域名的回执策略
+func validReceipts(policy string) bool {
+	switch policy {
+	case ReceiptsOff, ReceiptsDelivered, ReceiptsRead:
+		return true
+	}
+	return false
+}
+
+// RecordMailReceipt 记录已发送邮件投递给本地收件人，成功后设置 receipt.ID 和投递时间
+func (d *SQLiteDriver) RecordMailReceipt(ctx context.Context, receipt *MailReceipt) error {
+	now := utcNow()
+	trackRead := 0
+	if receipt.TrackRead {
+		trackRead = 1
+	}
+	query := `
+		INSERT INTO mail_receipts (sent_mail_id, recipient, mail_id, track_read, delivered_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := d.db.ExecContext(ctx, query,
+		receipt.SentMailID,
+		strings.ToLower(receipt.Recipient),
+		receipt.MailID,
+		trackRead,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("记录邮件回执失败: %w", constraintError(err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取邮件回执 ID 失败: %w", err)
+	}
+	receipt.ID = id
+	receipt.DeliveredAt = now
+	return nil
+}
+
+// ListMailReceipts 列出已发送邮件的回执（按投递时间排序）
+func (d *SQLiteDriver) ListMailReceipts(ctx context.Context, sentMailID string) ([]*MailReceipt, error) {
+	query := `
+		SELECT id, sent_mail_id, recipient, mail_id, track_read, delivered_at, read_at
+		FROM mail_receipts
+		WHERE sent_mail_id = ?
+		ORDER BY delivered_at, id
+	`
+	rows, err := d.db.QueryContext(ctx, query, sentMailID)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件回执失败: %w", err)
+	}
+	defer rows.Close()
+
+	var receipts []*MailReceipt
+	for rows.Next() {
+		var receipt MailReceipt
+		var trackRead int
+		var readAt sql.NullTime
+		if err := rows.Scan(
+			&receipt.ID,
+			&receipt.SentMailID,
+			&receipt.Recipient,
+			&receipt.MailID,
+			&trackRead,
+			&receipt.DeliveredAt,
+			&readAt,
+		); err != nil {
+			return nil, fmt.Errorf("扫描邮件回执失败: %w", err)
+		}
+		receipt.TrackRead = trackRead == 1
+		if readAt.Valid {
+			receipt.ReadAt = &readAt.Time
+		}
+		receipts = append(receipts, &receipt)
+	}
+	return receipts, rows.Err()
+}
+
+// markReceiptRead 记录收件人邮件 mailID 的首次阅读时间（只记录投递时开启了阅读回执的记录）
+func (d *SQLiteDriver) markReceiptRead(ctx context.Context, mailID string) error {
+	query := `UPDATE mail_receipts SET read_at = ? WHERE mail_id = ? AND track_read = 1 AND read_at IS NULL`
+	if _, err := d.db.ExecContext(ctx, query, utcNow(), mailID); err != nil {
+		return fmt.Errorf("记录邮件阅读时间失败: %w", err)
+	}
+	return nil
+}
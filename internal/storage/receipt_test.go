@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_MailReceipts(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	// 域名的回执策略
+	if err := driver.CreateDomain(ctx, &Domain{Name: "example.com", Active: true, Receipts: "always"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的回执策略应该返回 ErrInvalidInput: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &Domain{Name: "example.com", Active: true, Receipts: ReceiptsRead}); err != nil {
+		t.Fatal(err)
+	}
+	domain, err := driver.GetDomain(ctx, "example.com")
+	if err != nil || domain.Receipts != ReceiptsRead {
+		t.Fatalf("GetDomain() = %+v, %v", domain, err)
+	}
+	domain.Receipts = ReceiptsDelivered
+	if err := driver.UpdateDomain(ctx, domain); err != nil {
+		t.Fatal(err)
+	}
+	if domains, err := driver.ListDomains(ctx); err != nil || len(domains) != 1 || domains[0].Receipts != ReceiptsDelivered {
+		t.Fatalf("ListDomains() = %+v, %v", domains, err)
+	}
+
+	for _, email := range []string{"alice@example.com", "bob@example.com", "carol@example.com"} {
+		if err := driver.CreateUser(ctx, &User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, mail := range []*Mail{
+		{ID: "sent", UserEmail: "alice@example.com", Folder: "Sent"},
+		{ID: "bob-inbox", UserEmail: "bob@example.com", Folder: "INBOX"},
+		{ID: "carol-inbox", UserEmail: "carol@example.com", Folder: "INBOX"},
+	} {
+		mail.From = "alice@example.com"
+		mail.ReceivedAt = time.Now()
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// bob 的域名开启了阅读回执，carol 的只记录投递时间
+	bob := &MailReceipt{SentMailID: "sent", Recipient: "Bob@example.com", MailID: "bob-inbox", TrackRead: true}
+	if err := driver.RecordMailReceipt(ctx, bob); err != nil {
+		t.Fatalf("记录邮件回执失败: %v", err)
+	}
+	if bob.ID == 0 || bob.DeliveredAt.IsZero() {
+		t.Fatalf("记录后应该设置 ID 和投递时间: %+v", bob)
+	}
+	if err := driver.RecordMailReceipt(ctx, &MailReceipt{SentMailID: "sent", Recipient: "carol@example.com", MailID: "carol-inbox"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 设置 \Seen 时记录首次阅读时间
+	if err := driver.UpdateMailFlags(ctx, "bob-inbox", []string{"\\Flagged"}); err != nil {
+		t.Fatal(err)
+	}
+	receipts, err := driver.ListMailReceipts(ctx, "sent")
+	if err != nil || len(receipts) != 2 || receipts[0].ReadAt != nil {
+		t.Fatalf("未读时 ListMailReceipts() = %+v, %v", receipts, err)
+	}
+	for _, id := range []string{"bob-inbox", "carol-inbox"} {
+		if err := driver.UpdateMailFlags(ctx, id, []string{"\\Seen"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	receipts, err = driver.ListMailReceipts(ctx, "sent")
+	if err != nil || len(receipts) != 2 {
+		t.Fatalf("ListMailReceipts() = %+v, %v", receipts, err)
+	}
+	if receipts[0].Recipient != "bob@example.com" || receipts[0].ReadAt == nil {
+		t.Errorf("bob 的回执应该有阅读时间: %+v", receipts[0])
+	}
+	if receipts[1].Recipient != "carol@example.com" || receipts[1].ReadAt != nil {
+		t.Errorf("carol 的回执不应该有阅读时间: %+v", receipts[1])
+	}
+
+	// 只记录首次阅读的时间
+	readAt := *receipts[0].ReadAt
+	time.Sleep(10 * time.Millisecond)
+	if err := driver.UpdateMailFlags(ctx, "bob-inbox", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.UpdateMailFlags(ctx, "bob-inbox", []string{"\\Seen"}); err != nil {
+		t.Fatal(err)
+	}
+	if receipts, _ := driver.ListMailReceipts(ctx, "sent"); !receipts[0].ReadAt.Equal(readAt) {
+		t.Errorf("再次设置 \\Seen 后阅读时间 = %v, want %v", receipts[0].ReadAt, readAt)
+	}
+
+	// 删除已发送的邮件时一并删除回执
+	if err := driver.DeleteMail(ctx, "sent"); err != nil {
+		t.Fatal(err)
+	}
+	if receipts, _ := driver.ListMailReceipts(ctx, "sent"); len(receipts) != 0 {
+		t.Errorf("删除邮件后回执应该已删除: %+v", receipts)
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// minFTSTermLength FTS5 trigram 分词器要求的最短词长，更短的词退回 LIKE 扫描
+const minFTSTermLength = 3
+
+// searchConditions 将结构化搜索条件转换为 SQL 条件（以 " AND ..." 形式追加到 WHERE 之后）
+func searchConditions(q *SearchQuery) (string, []interface{}) {
+	if q == nil {
+		return "", nil
+	}
+
+	var conds []string
+	var args []interface{}
+
+	// 文本条件：能走全文索引的词合并为一个 MATCH 表达式，其余逐个 LIKE
+	var match []string
+	addText := func(columns []string, term string) {
+		if utf8.RuneCountInString(term) >= minFTSTermLength {
+			match = append(match, ftsColumnFilter(columns, term))
+			return
+		}
+		likes := make([]string, len(columns))
+		for i, col := range columns {
+			likes[i] = col + " LIKE ?"
+			args = append(args, "%"+term+"%")
+		}
+		conds = append(conds, "("+strings.Join(likes, " OR ")+")")
+	}
+
+	for _, term := range q.Text {
+		addText([]string{"subject", "from_addr", "to_addrs"}, term)
+	}
+	for _, term := range q.From {
+		addText([]string{"from_addr"}, term)
+	}
+	for _, term := range q.To {
+		addText([]string{"to_addrs", "cc_addrs"}, term)
+	}
+	for _, term := range q.Subject {
+		addText([]string{"subject"}, term)
+	}
+
+	if len(match) > 0 {
+		conds = append(conds, "rowid IN (SELECT rowid FROM mails_fts WHERE mails_fts MATCH ?)")
+		args = append(args, strings.Join(match, " AND "))
+	}
+
+	if q.HasAttachment {
+		conds = append(conds, "has_attachment = 1")
+	}
+	if q.Before != nil {
+		conds = append(conds, "received_at < ?")
+		args = append(args, *q.Before)
+	}
+	if q.After != nil {
+		conds = append(conds, "received_at >= ?")
+		args = append(args, *q.After)
+	}
+	if q.Larger > 0 {
+		conds = append(conds, "size > ?")
+		args = append(args, q.Larger)
+	}
+	if q.Smaller > 0 {
+		conds = append(conds, "size < ?")
+		args = append(args, q.Smaller)
+	}
+	for _, label := range q.Labels {
+		conds = append(conds, "(',' || COALESCE(flags, '') || ',') LIKE ?")
+		args = append(args, "%,"+label+",%")
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " AND " + strings.Join(conds, " AND "), args
+}
+
+// ftsColumnFilter 生成限定列的 FTS5 短语查询，如 {subject to_addrs} : "term"
+func ftsColumnFilter(columns []string, term string) string {
+	phrase := `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	if len(columns) == 1 {
+		return columns[0] + " : " + phrase
+	}
+	return "{" + strings.Join(columns, " ") + "} : " + phrase
+}
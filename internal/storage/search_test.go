@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_SearchMails(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	jan := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	mails := []*Mail{
+		{ID: "m1", From: "alice@example.com", To: []string{"me@example.com"}, Subject: "Weekly report",
+			Size: 5000, Flags: []string{"work"}, ReceivedAt: jan, HasAttachment: true},
+		{ID: "m2", From: "bob@example.com", To: []string{"me@example.com"}, Cc: []string{"carol@example.com"},
+			Subject: "Lunch", Size: 100, Flags: []string{"\\Seen"}, ReceivedAt: jan.AddDate(0, 1, 0)},
+		{ID: "m3", From: "alice@example.com", To: []string{"me@example.com"}, Subject: "周报 草稿",
+			Size: 300, Flags: []string{"\\Seen", "work"}, ReceivedAt: jan.AddDate(0, 2, 0)},
+	}
+	for _, m := range mails {
+		m.UserEmail = "me@example.com"
+		m.Folder = "INBOX"
+		if err := driver.StoreMail(ctx, m); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+
+	feb := jan.AddDate(0, 1, -5)
+	tests := []struct {
+		name  string
+		query *SearchQuery
+		want  []string
+	}{
+		{"全文", &SearchQuery{Text: []string{"report"}}, []string{"m1"}},
+		{"短词回退 LIKE", &SearchQuery{Text: []string{"周报"}}, []string{"m3"}},
+		{"发件人", &SearchQuery{From: []string{"alice"}}, []string{"m1", "m3"}},
+		{"抄送也匹配 to", &SearchQuery{To: []string{"carol"}}, []string{"m2"}},
+		{"主题", &SearchQuery{Subject: []string{"lunch"}}, []string{"m2"}},
+		{"附件", &SearchQuery{HasAttachment: true}, []string{"m1"}},
+		{"日期", &SearchQuery{Before: &feb}, []string{"m1"}},
+		{"日期之后", &SearchQuery{After: &feb}, []string{"m2", "m3"}},
+		{"大小", &SearchQuery{Larger: 200, Smaller: 1000}, []string{"m3"}},
+		{"标签", &SearchQuery{Labels: []string{"work"}}, []string{"m1", "m3"}},
+		{"组合", &SearchQuery{From: []string{"alice"}, Labels: []string{"work"}, HasAttachment: true}, []string{"m1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := driver.SearchMails(ctx, "me@example.com", tt.query, "", 50, 0)
+			if err != nil {
+				t.Fatalf("搜索失败: %v", err)
+			}
+			ids := map[string]bool{}
+			for _, m := range got {
+				ids[m.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("结果数量 = %d, want %v", len(got), tt.want)
+			}
+			for _, id := range tt.want {
+				if !ids[id] {
+					t.Errorf("缺少邮件 %s", id)
+				}
+			}
+		})
+	}
+
+	// 更新主题后全文索引同步
+	if _, err := driver.db.ExecContext(ctx, "UPDATE mails SET subject = 'Monthly summary' WHERE id = 'm1'"); err != nil {
+		t.Fatalf("更新主题失败: %v", err)
+	}
+	got, err := driver.SearchMails(ctx, "me@example.com", &SearchQuery{Subject: []string{"summary"}}, "", 50, 0)
+	if err != nil || len(got) != 1 {
+		t.Errorf("更新后的主题应该可以搜索到: %v %d", err, len(got))
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// sessionColumns 登录会话查询的列（与 scanSession 对应）
+const sessionColumns = `id, user_email, source, remote_ip, user_agent, created_at, last_used_at, expires_at, refresh_hash`
+
+// scanSession 扫描一行登录会话
+func scanSession(row rowScanner) (*Session, error) {
+	var session Session
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&session.ID,
+		&session.UserEmail,
+		&session.Source,
+		&session.RemoteIP,
+		&session.UserAgent,
+		&session.CreatedAt,
+		&lastUsedAt,
+		&session.ExpiresAt,
+		&session.RefreshHash,
+	); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		session.LastUsedAt = &lastUsedAt.Time
+	}
+	return &session, nil
+}
+
+// CreateSession 创建登录会话，同时清理用户已过期的会话
+func (d *SQLiteDriver) CreateSession(ctx context.Context, session *Session) error {
+	now := utcNow()
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_email = ? AND expires_at <= ?`, session.UserEmail, now); err != nil {
+		return fmt.Errorf("清理过期会话失败: %w", err)
+	}
+
+	query := `
+		INSERT INTO sessions (id, user_email, source, remote_ip, user_agent, created_at, expires_at, refresh_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := d.db.ExecContext(ctx, query,
+		session.ID,
+		session.UserEmail,
+		session.Source,
+		session.RemoteIP,
+		session.UserAgent,
+		now,
+		session.ExpiresAt,
+		session.RefreshHash,
+	); err != nil {
+		return fmt.Errorf("创建会话失败: %w", constraintError(err))
+	}
+	session.CreatedAt = now
+	return nil
+}
+
+// GetSession 获取登录会话（已过期的会话按不存在处理）
+func (d *SQLiteDriver) GetSession(ctx context.Context, id string) (*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE id = ? AND expires_at > ?`
+	session, err := scanSession(d.db.QueryRowContext(ctx, query, id, utcNow()))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("会话不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询会话失败: %w", err)
+	}
+	return session, nil
+}
+
+// ListSessions 列出用户未过期的登录会话（按创建时间倒序）
+func (d *SQLiteDriver) ListSessions(ctx context.Context, userEmail string) ([]*Session, error) {
+	query := `SELECT ` + sessionColumns + ` FROM sessions WHERE user_email = ? AND expires_at > ? ORDER BY created_at DESC, id`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, utcNow())
+	if err != nil {
+		return nil, fmt.Errorf("查询会话失败: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描会话失败: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession 记录登录会话的最近活动时间
+func (d *SQLiteDriver) TouchSession(ctx context.Context, id string, at time.Time) error {
+	if _, err := d.db.ExecContext(ctx, `UPDATE sessions SET last_used_at = ? WHERE id = ?`, at, id); err != nil {
+		return fmt.Errorf("更新会话活动时间失败: %w", err)
+	}
+	return nil
+}
+
+// RotateSessionRefresh 轮换登录会话的刷新令牌：只有会话未过期且当前刷新令牌的哈希为 oldHash 时才更新，
+// 否则返回 ErrNotFound（会话已吊销、已过期，或刷新令牌已经被使用过）
+func (d *SQLiteDriver) RotateSessionRefresh(ctx context.Context, id, oldHash, newHash string) error {
+	now := utcNow()
+	result, err := d.db.ExecContext(ctx,
+		`UPDATE sessions SET refresh_hash = ?, last_used_at = ? WHERE id = ? AND refresh_hash = ? AND refresh_hash != '' AND expires_at > ?`,
+		newHash, now, id, oldHash, now)
+	if err != nil {
+		return fmt.Errorf("更新刷新令牌失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("会话不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteSession 吊销用户的登录会话
+func (d *SQLiteDriver) DeleteSession(ctx context.Context, userEmail, id string) error {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ? AND user_email = ?`, id, userEmail)
+	if err != nil {
+		return fmt.Errorf("删除会话失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("会话不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteUserSessions 吊销用户的所有登录会话，返回吊销的数量
+func (d *SQLiteDriver) DeleteUserSessions(ctx context.Context, userEmail string) (int64, error) {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_email = ?`, userEmail)
+	if err != nil {
+		return 0, fmt.Errorf("删除会话失败: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessions(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expires := time.Now().Add(time.Hour)
+	sessions := []*Session{
+		{ID: "s1", UserEmail: "alice@example.com", Source: "webmail", ExpiresAt: expires},
+		{ID: "s2", UserEmail: "alice@example.com", Source: "admin", RemoteIP: "192.0.2.1", ExpiresAt: expires},
+		{ID: "s3", UserEmail: "bob@example.com", Source: "webmail", ExpiresAt: expires, RefreshHash: "h1"},
+		{ID: "old", UserEmail: "alice@example.com", Source: "webmail", ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	for _, s := range sessions {
+		if err := driver.CreateSession(ctx, s); err != nil {
+			t.Fatalf("创建会话失败: %v", err)
+		}
+	}
+
+	// 过期的会话不可见
+	list, err := driver.ListSessions(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("列出会话失败: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("会话数 = %d, want 2: %+v", len(list), list)
+	}
+	if _, err := driver.GetSession(ctx, "old"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("过期的会话应该返回 ErrNotFound, got %v", err)
+	}
+
+	now := utcNow()
+	if err := driver.TouchSession(ctx, "s2", now); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := driver.GetSession(ctx, "s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.LastUsedAt == nil || !s2.LastUsedAt.Equal(now) || s2.RemoteIP != "192.0.2.1" || !s2.ExpiresAt.Equal(expires.Truncate(time.Second)) {
+		t.Errorf("会话 = %+v", s2)
+	}
+
+	// 刷新令牌只能使用一次，没有刷新令牌的会话不能刷新
+	if err := driver.RotateSessionRefresh(ctx, "s3", "h1", "h2"); err != nil {
+		t.Fatalf("轮换刷新令牌失败: %v", err)
+	}
+	if err := driver.RotateSessionRefresh(ctx, "s3", "h1", "h3"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("使用过的刷新令牌应该返回 ErrNotFound, got %v", err)
+	}
+	if err := driver.RotateSessionRefresh(ctx, "s1", "", "h4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("没有刷新令牌的会话应该返回 ErrNotFound, got %v", err)
+	}
+	if s3, err := driver.GetSession(ctx, "s3"); err != nil || s3.RefreshHash != "h2" || s3.LastUsedAt == nil {
+		t.Errorf("轮换后的会话 = %+v, %v", s3, err)
+	}
+
+	// 只能吊销自己的会话
+	if err := driver.DeleteSession(ctx, "bob@example.com", "s1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("吊销其他用户的会话应该返回 ErrNotFound, got %v", err)
+	}
+	if err := driver.DeleteSession(ctx, "alice@example.com", "s1"); err != nil {
+		t.Errorf("吊销会话失败: %v", err)
+	}
+	n, err := driver.DeleteUserSessions(ctx, "alice@example.com")
+	if err != nil || n != 2 {
+		t.Errorf("DeleteUserSessions() = %d, %v, want 2（包括过期的会话）", n, err)
+	}
+	if _, err := driver.GetSession(ctx, "s3"); err != nil {
+		t.Errorf("其他用户的会话不应该被吊销: %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内嵌时区数据库，系统没有安装 tzdata 时也能校验和使用时区设置
+)
+
+// AccountFeatures 可以按域名或用户开关的功能
+var AccountFeatures = []string{"imap", "smtp", "webmail", "forwarding", "aliases"}
+
+// 设置来源
+const (
+	SettingSourceSystem = "system" // 系统默认值
+	SettingSourceDomain = "domain" // 域名默认设置
+	SettingSourceUser   = "user"   // 用户覆盖
+)
+
+// 系统默认值（域名和用户都未设置时使用）
+const (
+	DefaultRetentionDays = 0 // 永久保留
+	DefaultSpamThreshold = 5.0
+	DefaultTimezone      = "UTC"
+	DefaultDigest        = DigestOff
+)
+
+// 邮件摘要频率
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// validate 检查设置的取值
+func (s *AccountSettings) validate() error {
+	for feature := range s.Features {
+		if !slices.Contains(AccountFeatures, feature) {
+			return fmt.Errorf("未知的功能 %q（可选: %s）: %w", feature, strings.Join(AccountFeatures, ", "), ErrInvalidInput)
+		}
+	}
+	if s.RetentionDays != nil && *s.RetentionDays < 0 {
+		return fmt.Errorf("保留天数不能为负数: %w", ErrInvalidInput)
+	}
+	if s.SpamThreshold != nil && *s.SpamThreshold <= 0 {
+		return fmt.Errorf("垃圾邮件阈值必须大于 0: %w", ErrInvalidInput)
+	}
+	if s.Timezone != nil {
+		// 空字符串和 Local 会被解析为 UTC 和服务器时区，不接受
+		if _, err := time.LoadLocation(*s.Timezone); err != nil || *s.Timezone == "" || *s.Timezone == "Local" {
+			return fmt.Errorf("未知的时区 %q: %w", *s.Timezone, ErrInvalidInput)
+		}
+	}
+	if s.Digest != nil {
+		switch *s.Digest {
+		case DigestOff, DigestDaily, DigestWeekly:
+		default:
+			return fmt.Errorf("未知的摘要频率 %q（可选: off, daily, weekly）: %w", *s.Digest, ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// GetDomainDefaults 获取域名的默认账户设置
+func (d *SQLiteDriver) GetDomainDefaults(ctx context.Context, domain string) (*DomainDefaults, error) {
+	defaults := &DomainDefaults{Domain: domain}
+	var quota sql.NullInt64
+	var settings string
+	err := d.db.QueryRowContext(ctx,
+		"SELECT quota, settings, updated_at FROM domain_defaults WHERE domain = ?", domain,
+	).Scan(&quota, &settings, &defaults.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("域名默认设置不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询域名默认设置失败: %w", err)
+	}
+	if quota.Valid {
+		defaults.Quota = &quota.Int64
+	}
+	if err := json.Unmarshal([]byte(settings), &defaults.AccountSettings); err != nil {
+		return nil, fmt.Errorf("解析域名默认设置失败: %w", err)
+	}
+	return defaults, nil
+}
+
+// SaveDomainDefaults 保存域名的默认账户设置（整体替换）
+func (d *SQLiteDriver) SaveDomainDefaults(ctx context.Context, defaults *DomainDefaults) error {
+	if defaults.Quota != nil && *defaults.Quota < 0 {
+		return fmt.Errorf("保存域名默认设置失败: 配额不能为负数: %w", ErrInvalidInput)
+	}
+	if err := defaults.validate(); err != nil {
+		return fmt.Errorf("保存域名默认设置失败: %w", err)
+	}
+	settings, err := json.Marshal(&defaults.AccountSettings)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO domain_defaults (domain, quota, settings, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(domain) DO UPDATE SET
+			quota = excluded.quota,
+			settings = excluded.settings,
+			updated_at = excluded.updated_at
+	`
+	defaults.UpdatedAt = utcNow()
+	if _, err := d.db.ExecContext(ctx, query, defaults.Domain, defaults.Quota, string(settings), defaults.UpdatedAt); err != nil {
+		return fmt.Errorf("保存域名默认设置失败: %w", constraintError(err))
+	}
+	return nil
+}
+
+// GetUserSettings 获取用户对域名默认设置的覆盖
+func (d *SQLiteDriver) GetUserSettings(ctx context.Context, userEmail string) (*UserSettings, error) {
+	settings := &UserSettings{UserEmail: userEmail}
+	var data string
+	err := d.db.QueryRowContext(ctx,
+		"SELECT settings, updated_at FROM user_settings WHERE user_email = ?", userEmail,
+	).Scan(&data, &settings.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("用户设置不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询用户设置失败: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &settings.AccountSettings); err != nil {
+		return nil, fmt.Errorf("解析用户设置失败: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveUserSettings 保存用户对域名默认设置的覆盖（整体替换）
+func (d *SQLiteDriver) SaveUserSettings(ctx context.Context, settings *UserSettings) error {
+	if err := settings.validate(); err != nil {
+		return fmt.Errorf("保存用户设置失败: %w", err)
+	}
+	data, err := json.Marshal(&settings.AccountSettings)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_settings (user_email, settings, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			settings = excluded.settings,
+			updated_at = excluded.updated_at
+	`
+	settings.UpdatedAt = utcNow()
+	if _, err := d.db.ExecContext(ctx, query, settings.UserEmail, string(data), settings.UpdatedAt); err != nil {
+		return fmt.Errorf("保存用户设置失败: %w", constraintError(err))
+	}
+	return nil
+}
+
+// defaultQuota 用户所在域名的默认配额（未设置时返回 0）
+func (d *SQLiteDriver) defaultQuota(ctx context.Context, email string) (int64, error) {
+	var quota sql.NullInt64
+	err := d.db.QueryRowContext(ctx,
+		"SELECT quota FROM domain_defaults WHERE domain = ?", emailDomain(email),
+	).Scan(&quota)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("查询域名默认设置失败: %w", err)
+	}
+	return quota.Int64, nil
+}
+
+// LoadEffectiveSettings 读取用户、域名默认设置和用户覆盖，计算账户的生效设置
+func LoadEffectiveSettings(ctx context.Context, driver Driver, email string) (*EffectiveSettings, error) {
+	user, err := driver.GetUser(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := driver.GetDomainDefaults(ctx, emailDomain(user.Email))
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	overrides, err := driver.GetUserSettings(ctx, user.Email)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	return ResolveSettings(user, defaults, overrides), nil
+}
+
+// ResolveSettings 按 用户覆盖 > 域名默认设置 > 系统默认值 计算生效设置（defaults、overrides 可以为 nil）
+func ResolveSettings(user *User, defaults *DomainDefaults, overrides *UserSettings) *EffectiveSettings {
+	effective := &EffectiveSettings{
+		Email:         user.Email,
+		Quota:         user.Quota, // 域名默认配额在创建用户时已经写入用户记录
+		Features:      make(map[string]bool, len(AccountFeatures)),
+		RetentionDays: DefaultRetentionDays,
+		SpamThreshold: DefaultSpamThreshold,
+		Timezone:      DefaultTimezone,
+		Digest:        DefaultDigest,
+		Sources:       map[string]string{"quota": SettingSourceUser},
+	}
+	for _, feature := range AccountFeatures {
+		effective.Features[feature] = true
+		effective.Sources["features."+feature] = SettingSourceSystem
+	}
+	effective.Sources["retention_days"] = SettingSourceSystem
+	effective.Sources["spam_threshold"] = SettingSourceSystem
+	effective.Sources["signature_template"] = SettingSourceSystem
+	effective.Sources["timezone"] = SettingSourceSystem
+	effective.Sources["digest"] = SettingSourceSystem
+
+	// 依次应用域名默认设置和用户覆盖，后者优先
+	levels := []struct {
+		source   string
+		settings *AccountSettings
+	}{{source: SettingSourceDomain}, {source: SettingSourceUser}}
+	if defaults != nil {
+		levels[0].settings = &defaults.AccountSettings
+	}
+	if overrides != nil {
+		levels[1].settings = &overrides.AccountSettings
+	}
+	for _, level := range levels {
+		s := level.settings
+		if s == nil {
+			continue
+		}
+		for feature, enabled := range s.Features {
+			if _, ok := effective.Features[feature]; ok {
+				effective.Features[feature] = enabled
+				effective.Sources["features."+feature] = level.source
+			}
+		}
+		if s.RetentionDays != nil {
+			effective.RetentionDays = *s.RetentionDays
+			effective.Sources["retention_days"] = level.source
+		}
+		if s.SpamThreshold != nil {
+			effective.SpamThreshold = *s.SpamThreshold
+			effective.Sources["spam_threshold"] = level.source
+		}
+		if s.SignatureTemplate != nil {
+			effective.SignatureTemplate = *s.SignatureTemplate
+			effective.Sources["signature_template"] = level.source
+		}
+		if s.Timezone != nil {
+			effective.Timezone = *s.Timezone
+			effective.Sources["timezone"] = level.source
+		}
+		if s.Digest != nil {
+			effective.Digest = *s.Digest
+			effective.Sources["digest"] = level.source
+		}
+	}
+
+	effective.Signature = renderSignature(effective.SignatureTemplate, user.Email)
+	return effective
+}
+
+// Location 生效时区（无法加载时使用 UTC）
+func (e *EffectiveSettings) Location() *time.Location {
+	if loc, err := time.LoadLocation(e.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// UserLocation 用户的时区，用于在通知等邮件中显示本地时间（读取设置失败时使用 UTC）
+func UserLocation(ctx context.Context, driver Driver, email string) *time.Location {
+	effective, err := LoadEffectiveSettings(ctx, driver, email)
+	if err != nil {
+		return time.UTC
+	}
+	return effective.Location()
+}
+
+// renderSignature 展开签名模板中的占位符
+func renderSignature(template, email string) string {
+	if template == "" {
+		return ""
+	}
+	name, domain, _ := strings.Cut(email, "@")
+	return strings.NewReplacer("{name}", name, "{email}", email, "{domain}", domain).Replace(template)
+}
+
+// emailDomain 邮箱地址的域名部分（小写）
+func emailDomain(email string) string {
+	return strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_AccountSettings(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := driver.GetDomainDefaults(ctx, "example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未设置时应该返回 ErrNotFound: %v", err)
+	}
+
+	quota, retention, threshold, signature := int64(1<<30), 365, 6.5, "-- \n{name} @ {domain}"
+	defaults := &DomainDefaults{
+		Domain: "example.com",
+		Quota:  &quota,
+		AccountSettings: AccountSettings{
+			Features:          map[string]bool{"webmail": false, "forwarding": false},
+			RetentionDays:     &retention,
+			SpamThreshold:     &threshold,
+			SignatureTemplate: &signature,
+		},
+	}
+	if err := driver.SaveDomainDefaults(ctx, defaults); err != nil {
+		t.Fatalf("保存域名默认设置失败: %v", err)
+	}
+	got, err := driver.GetDomainDefaults(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("获取域名默认设置失败: %v", err)
+	}
+	if *got.Quota != quota || *got.RetentionDays != retention || got.Features["webmail"] || len(got.Features) != 2 {
+		t.Errorf("域名默认设置 = %+v", got)
+	}
+
+	// 无效的设置被拒绝
+	unknownZone, localZone, hourly := "Mars/Olympus_Mons", "Local", "hourly"
+	for _, invalid := range []AccountSettings{
+		{Features: map[string]bool{"telepathy": true}},
+		{RetentionDays: new(int)},
+		{SpamThreshold: new(float64)},
+		{Timezone: &unknownZone},
+		{Timezone: &localZone},
+		{Digest: &hourly},
+	} {
+		if invalid.RetentionDays != nil {
+			*invalid.RetentionDays = -1
+		}
+		if err := driver.SaveUserSettings(ctx, &UserSettings{UserEmail: "alice@example.com", AccountSettings: invalid}); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("无效的设置 %+v 应该返回 ErrInvalidInput: %v", invalid, err)
+		}
+	}
+
+	// 未指定配额的新用户使用域名默认配额，指定了配额的保留自己的配额
+	alice := &User{Email: "alice@example.com", PasswordHash: "x", Active: true}
+	bob := &User{Email: "bob@example.com", PasswordHash: "x", Quota: 1000, Active: true}
+	carol := &User{Email: "carol@other.com", PasswordHash: "x", Active: true}
+	for _, u := range []*User{alice, bob, carol} {
+		if err := driver.CreateUser(ctx, u); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	if u, _ := driver.GetUser(ctx, "alice@example.com"); u.Quota != quota {
+		t.Errorf("alice 的配额 = %d, want %d", u.Quota, quota)
+	}
+	if u, _ := driver.GetUser(ctx, "bob@example.com"); u.Quota != 1000 {
+		t.Errorf("bob 的配额 = %d", u.Quota)
+	}
+	if u, _ := driver.GetUser(ctx, "carol@other.com"); u.Quota != 0 {
+		t.Errorf("carol 的配额 = %d", u.Quota)
+	}
+
+	// 用户覆盖部分设置
+	override, timezone, digest := 3.0, "Asia/Shanghai", DigestWeekly
+	if err := driver.SaveUserSettings(ctx, &UserSettings{
+		UserEmail: "alice@example.com",
+		AccountSettings: AccountSettings{
+			Features:      map[string]bool{"webmail": true},
+			SpamThreshold: &override,
+			Timezone:      &timezone,
+			Digest:        &digest,
+		},
+	}); err != nil {
+		t.Fatalf("保存用户设置失败: %v", err)
+	}
+
+	effective, err := LoadEffectiveSettings(ctx, driver, "alice@example.com")
+	if err != nil {
+		t.Fatalf("计算生效设置失败: %v", err)
+	}
+	if !effective.Features["webmail"] || effective.Sources["features.webmail"] != SettingSourceUser {
+		t.Errorf("webmail 应该由用户覆盖: %+v", effective)
+	}
+	if effective.Features["forwarding"] || effective.Sources["features.forwarding"] != SettingSourceDomain {
+		t.Errorf("forwarding 应该继承域名默认设置: %+v", effective)
+	}
+	if !effective.Features["imap"] || effective.Sources["features.imap"] != SettingSourceSystem {
+		t.Errorf("imap 应该使用系统默认值: %+v", effective)
+	}
+	if effective.SpamThreshold != 3.0 || effective.RetentionDays != 365 || effective.Sources["retention_days"] != SettingSourceDomain {
+		t.Errorf("生效设置 = %+v", effective)
+	}
+	if effective.Location().String() != "Asia/Shanghai" || effective.Sources["timezone"] != SettingSourceUser {
+		t.Errorf("时区 = %q (%s)", effective.Timezone, effective.Sources["timezone"])
+	}
+	if effective.Digest != DigestWeekly || effective.Sources["digest"] != SettingSourceUser {
+		t.Errorf("摘要频率 = %q (%s)", effective.Digest, effective.Sources["digest"])
+	}
+	if effective.Signature != "-- \nalice @ example.com" {
+		t.Errorf("签名 = %q", effective.Signature)
+	}
+
+	// 没有域名默认设置的用户使用系统默认值
+	effective, err = LoadEffectiveSettings(ctx, driver, "carol@other.com")
+	if err != nil {
+		t.Fatalf("计算生效设置失败: %v", err)
+	}
+	if effective.SpamThreshold != DefaultSpamThreshold || effective.Signature != "" || effective.Location() != time.UTC || effective.Digest != DigestOff || effective.Sources["spam_threshold"] != SettingSourceSystem {
+		t.Errorf("生效设置 = %+v", effective)
+	}
+	if _, err := LoadEffectiveSettings(ctx, driver, "nobody@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("用户不存在时应该返回 ErrNotFound: %v", err)
+	}
+
+	// 删除用户时删除用户设置
+	if err := driver.DeleteUser(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("删除用户失败: %v", err)
+	}
+	if _, err := driver.GetUserSettings(ctx, "alice@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除用户后用户设置应该被删除: %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxShardDepth 用户目录分片的最大层数
+const MaxShardDepth = 3
+
+// SetSharding 设置用户目录的分片层数：0 为不分片（用户目录直接位于根目录下），
+// n 层时按用户邮箱 SHA-256 的前 n 个字节分为 n 级子目录（如 2 层为 root/ab/cd/user@example.com），
+// 避免用户数量很大时根目录下的条目过多。尚未迁移（见 Reshard）的用户目录仍按原位置访问
+func (m *Maildir) SetSharding(depth int) {
+	m.shardDepth = depth
+}
+
+// shardPath 返回用户目录所在的分片子目录（不分片时为空）
+func shardPath(userEmail string, depth int) string {
+	if depth <= 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userEmail))
+	parts := make([]string, depth)
+	for i := range parts {
+		parts[i] = hex.EncodeToString(sum[i : i+1])
+	}
+	return filepath.Join(parts...)
+}
+
+// isShardDir 目录名是否为分片子目录（两位小写十六进制）
+func isShardDir(name string) bool {
+	if len(name) != 2 {
+		return false
+	}
+	for _, c := range name {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// userDirs 列出根目录下所有用户目录（相对于根目录），包括按其他分片层数存放的目录
+func (m *Maildir) userDirs() ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(m.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == m.root {
+			return nil
+		}
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(d.Name(), "@") {
+			dirs = append(dirs, rel)
+			return filepath.SkipDir
+		}
+		if !isShardDir(d.Name()) || strings.Count(filepath.ToSlash(rel), "/") >= MaxShardDepth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取 Maildir 根目录失败: %w", err)
+	}
+	return dirs, nil
+}
+
+// ListUsers 列出 Maildir 中有目录的用户
+func (m *Maildir) ListUsers() ([]string, error) {
+	dirs, err := m.userDirs()
+	if err != nil {
+		return nil, err
+	}
+	users := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		users = append(users, filepath.Base(dir))
+	}
+	return users, nil
+}
+
+// Reshard 把用户目录移动到当前分片层数对应的位置（包括从分片恢复为不分片），并删除空的分片子目录，
+// 返回移动的用户目录数量。应在服务停止时执行，移动期间投递的邮件可能写入旧位置
+func (m *Maildir) Reshard() (int, error) {
+	dirs, err := m.userDirs()
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, rel := range dirs {
+		userEmail := filepath.Base(rel)
+		src := filepath.Join(m.root, rel)
+		dst := filepath.Join(m.root, shardPath(userEmail, m.shardDepth), userEmail)
+		if src == dst {
+			continue
+		}
+		if _, err := os.Stat(dst); err == nil {
+			return moved, fmt.Errorf("用户 %s 的目录同时存在于 %s 和 %s", userEmail, src, dst)
+		}
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return moved, fmt.Errorf("创建分片目录失败: %w", err)
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return moved, fmt.Errorf("移动用户 %s 的目录失败: %w", userEmail, err)
+		}
+		m.record(JournalRename, src, dst)
+		moved++
+
+		// 删除移空的分片子目录
+		for dir := filepath.Dir(src); dir != m.root && isShardDir(filepath.Base(dir)); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return moved, nil
+}
+
+// ParsePath 从相对于根目录的路径解析用户和文件夹（INBOX 为用户目录本身，其他文件夹见 FolderDirName），
+// rest 为文件夹目录下剩余的部分（如 new 和文件名）。不分片和按当前层数分片的用户目录都能解析
+func (m *Maildir) ParsePath(rel string) (userEmail, folder string, rest []string, ok bool) {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	i := 0
+	if len(parts) > m.shardDepth && !strings.Contains(parts[0], "@") {
+		i = m.shardDepth
+		for _, part := range parts[:i] {
+			if !isShardDir(part) {
+				return "", "", nil, false
+			}
+		}
+	}
+	if i >= len(parts) || !strings.Contains(parts[i], "@") {
+		return "", "", nil, false
+	}
+	userEmail, folder, rest = parts[i], "INBOX", parts[i+1:]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], ".") && len(rest[0]) > 1 {
+		folder, rest = FolderFromDirName(rest[0]), rest[1:]
+	}
+	return userEmail, folder, rest, true
+}
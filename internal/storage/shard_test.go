@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMaildirSharding(t *testing.T) {
+	root := t.TempDir()
+	maildir, err := NewMaildir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 启用分片之前创建的用户目录
+	if err := maildir.EnsureUserMaildir("old@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	oldMail, err := maildir.StoreMail("old@example.com", "Sent", []byte("Subject: old\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maildir.SetSharding(2)
+	if got := maildir.GetUserMaildir("old@example.com"); got != filepath.Join(root, "old@example.com") {
+		t.Errorf("未迁移的用户目录 = %s", got)
+	}
+	if err := maildir.EnsureUserMaildir("new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	sharded := filepath.Join(root, shardPath("new@example.com", 2), "new@example.com")
+	if got := maildir.GetUserMaildir("new@example.com"); got != sharded || len(shardPath("new@example.com", 2)) != 5 {
+		t.Errorf("分片的用户目录 = %s", got)
+	}
+
+	users, err := maildir.ListUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(users)
+	if !slices.Equal(users, []string{"new@example.com", "old@example.com"}) {
+		t.Errorf("ListUsers = %v", users)
+	}
+
+	moved, err := maildir.Reshard()
+	if err != nil || moved != 1 {
+		t.Fatalf("Reshard = %d, %v", moved, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "old@example.com")); !os.IsNotExist(err) {
+		t.Error("迁移后根目录下仍有用户目录")
+	}
+	if _, err := maildir.ReadMail("old@example.com", "Sent", oldMail); err != nil {
+		t.Errorf("迁移后读取邮件失败: %v", err)
+	}
+
+	// 恢复为不分片时移回根目录，并删除空的分片子目录
+	maildir.SetSharding(0)
+	if moved, err := maildir.Reshard(); err != nil || moved != 2 {
+		t.Fatalf("Reshard = %d, %v", moved, err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("不分片时根目录的条目 = %v", entries)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	maildir, err := NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	maildir.SetSharding(1)
+	for _, tt := range []struct {
+		rel    string
+		user   string
+		folder string
+		rest   []string
+		ok     bool
+	}{
+		{"ab/alice@example.com/new/1.a", "alice@example.com", "INBOX", []string{"new", "1.a"}, true},
+		{"ab/alice@example.com/.Projects.2024/cur/1.a:2,S", "alice@example.com", "Projects/2024", []string{"cur", "1.a:2,S"}, true},
+		{"alice@example.com/tmp", "alice@example.com", "INBOX", []string{"tmp"}, true},
+		{"xy/alice@example.com/new/1.a", "", "", nil, false},
+		{"ab/cd/alice@example.com/new/1.a", "", "", nil, false},
+		{"ab", "", "", nil, false},
+	} {
+		user, folder, rest, ok := maildir.ParsePath(tt.rel)
+		if user != tt.user || folder != tt.folder || !slices.Equal(rest, tt.rest) || ok != tt.ok {
+			t.Errorf("ParsePath(%q) = %q, %q, %v, %v", tt.rel, user, folder, rest, ok)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GetSpamModel 获取垃圾邮件贝叶斯模型中与指定特征相关的部分（userEmail 为空字符串时为全局模型）
+func (d *SQLiteDriver) GetSpamModel(ctx context.Context, userEmail string, tokens []string) (*SpamModel, error) {
+	model := &SpamModel{
+		Spam: make(map[string]int),
+		Ham:  make(map[string]int),
+	}
+	err := d.db.QueryRowContext(ctx,
+		"SELECT spam, ham FROM spam_bayes_docs WHERE user_email = ?", userEmail,
+	).Scan(&model.SpamDocs, &model.HamDocs)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("查询垃圾邮件模型失败: %w", err)
+	}
+	if len(tokens) == 0 || model.SpamDocs+model.HamDocs == 0 {
+		return model, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tokens)), ",")
+	args := []interface{}{userEmail}
+	for _, t := range tokens {
+		args = append(args, t)
+	}
+	rows, err := d.db.QueryContext(ctx,
+		"SELECT token, spam, ham FROM spam_bayes_tokens WHERE user_email = ? AND token IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询垃圾邮件特征失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var token string
+		var spam, ham int
+		if err := rows.Scan(&token, &spam, &ham); err != nil {
+			return nil, fmt.Errorf("扫描垃圾邮件特征失败: %w", err)
+		}
+		model.Spam[token] = spam
+		model.Ham[token] = ham
+	}
+	return model, rows.Err()
+}
+
+// TrainSpam 将一封邮件（按内容摘要 digest 识别）作为垃圾邮件或正常邮件计入用户的模型和全局模型：
+// 同一封邮件已按相同结果训练过时跳过并返回 false，之前按相反结果训练过时先撤销旧样本
+func (d *SQLiteDriver) TrainSpam(ctx context.Context, userEmail, digest string, tokens []string, spam bool) (bool, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("训练垃圾邮件模型失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous int
+	err = tx.QueryRowContext(ctx,
+		"SELECT spam FROM spam_bayes_trained WHERE user_email = ? AND digest = ?", userEmail, digest,
+	).Scan(&previous)
+	trained := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("查询训练记录失败: %w", err)
+	}
+	if trained && (previous == 1) == spam {
+		return false, nil
+	}
+
+	models := []string{userEmail}
+	if userEmail != "" {
+		models = append(models, "")
+	}
+	for _, model := range models {
+		if trained {
+			if err := trainSpamModel(ctx, tx, model, tokens, previous == 1, -1); err != nil {
+				return false, err
+			}
+		}
+		if err := trainSpamModel(ctx, tx, model, tokens, spam, 1); err != nil {
+			return false, err
+		}
+	}
+
+	isSpam := 0
+	if spam {
+		isSpam = 1
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO spam_bayes_trained (user_email, digest, spam, trained_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_email, digest) DO UPDATE SET
+			spam = excluded.spam,
+			trained_at = excluded.trained_at
+	`, userEmail, digest, isSpam, utcNow()); err != nil {
+		return false, fmt.Errorf("保存训练记录失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("训练垃圾邮件模型失败: %w", err)
+	}
+	return true, nil
+}
+
+// trainSpamModel 将特征计入（delta 为 1）或移出（delta 为 -1）模型的垃圾邮件或正常邮件计数
+func trainSpamModel(ctx context.Context, tx *sql.Tx, userEmail string, tokens []string, spam bool, delta int) error {
+	column := "ham"
+	if spam {
+		column = "spam"
+	}
+	// #nosec G202 -- 列名为常量
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO spam_bayes_docs (user_email, `+column+`)
+		VALUES (?, MAX(?, 0))
+		ON CONFLICT(user_email) DO UPDATE SET `+column+` = MAX(`+column+` + ?, 0)
+	`, userEmail, delta, delta); err != nil {
+		return fmt.Errorf("训练垃圾邮件模型失败: %w", err)
+	}
+	for _, token := range tokens {
+		// #nosec G202 -- 列名为常量
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO spam_bayes_tokens (user_email, token, `+column+`)
+			VALUES (?, ?, MAX(?, 0))
+			ON CONFLICT(user_email, token) DO UPDATE SET `+column+` = MAX(`+column+` + ?, 0)
+		`, userEmail, token, delta, delta); err != nil {
+			return fmt.Errorf("训练垃圾邮件模型失败: %w", err)
+		}
+	}
+	return nil
+}
@@ -6,11 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gomailzero/gmz/internal/migrate"
-	_ "modernc.org/sqlite"
 )
 
 // SQLiteDriver SQLite 存储驱动
@@ -30,10 +30,8 @@ func NewSQLiteDriver(dsn string) (*SQLiteDriver, error) {
 		}
 	}
 
-	db, err := sql.Open("sqlite", dsn+"?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(ON)")
-	if err != nil {
-		return nil, fmt.Errorf("打开数据库失败: %w", err)
-	}
+	// 时间参数和时间列按 UTC Unix 秒读写（见 epoch.go）
+	db := sql.OpenDB(newEpochConnector(dsn + "?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=foreign_keys(ON)"))
 
 	// 设置连接参数
 	db.SetMaxOpenConns(25)
@@ -88,6 +86,9 @@ func (d *SQLiteDriver) initSchema() error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT UNIQUE NOT NULL,
 		active INTEGER DEFAULT 1,
+		forwarding_disabled INTEGER DEFAULT 0,
+		quota_warning_thresholds TEXT DEFAULT '',
+		receipts TEXT DEFAULT '',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -97,6 +98,13 @@ func (d *SQLiteDriver) initSchema() error {
 		from_addr TEXT UNIQUE NOT NULL,
 		to_addr TEXT NOT NULL,
 		domain TEXT NOT NULL,
+		owner TEXT,
+		disposable INTEGER DEFAULT 0,
+		expires_at DATETIME,
+		burned_at DATETIME,
+		received_count INTEGER DEFAULT 0,
+		last_received_at DATETIME,
+		destinations TEXT DEFAULT '',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -112,6 +120,13 @@ func (d *SQLiteDriver) initSchema() error {
 		size INTEGER NOT NULL,
 		flags TEXT,
 		uid INTEGER,
+		has_attachment INTEGER DEFAULT 0,
+		modseq INTEGER NOT NULL DEFAULT 0,
+		create_modseq INTEGER NOT NULL DEFAULT 0,
+		message_id TEXT NOT NULL DEFAULT '',
+		in_reply_to TEXT NOT NULL DEFAULT '',
+		refs TEXT NOT NULL DEFAULT '',
+		thread_id TEXT NOT NULL DEFAULT '',
 		received_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -124,11 +139,600 @@ func (d *SQLiteDriver) initSchema() error {
 		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
 	);
 
+	CREATE TABLE IF NOT EXISTS acme_accounts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		email TEXT NOT NULL,
+		directory_url TEXT NOT NULL,
+		account_url TEXT,
+		private_key BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (email, directory_url)
+	);
+
+	CREATE TABLE IF NOT EXISTS acme_orders (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		domain TEXT UNIQUE NOT NULL,
+		order_url TEXT,
+		status TEXT NOT NULL,
+		error TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS acme_certificates (
+		domain TEXT PRIMARY KEY,
+		cert_pem BLOB NOT NULL,
+		private_key BLOB NOT NULL,
+		not_after DATETIME NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS usage_daily (
+		user_email TEXT NOT NULL,
+		day TEXT NOT NULL,
+		used INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_email, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_stats_daily (
+		user_email TEXT NOT NULL,
+		day TEXT NOT NULL,
+		received INTEGER NOT NULL DEFAULT 0,
+		sent INTEGER NOT NULL DEFAULT 0,
+		attachments INTEGER NOT NULL DEFAULT 0,
+		attachment_bytes INTEGER NOT NULL DEFAULT 0,
+		replies INTEGER NOT NULL DEFAULT 0,
+		reply_seconds INTEGER NOT NULL DEFAULT 0,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_email, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_contacts_daily (
+		user_email TEXT NOT NULL,
+		day TEXT NOT NULL,
+		address TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_email, day, address, direction)
+	);
+
+	CREATE TABLE IF NOT EXISTS alias_policies (
+		domain TEXT PRIMARY KEY,
+		max_aliases INTEGER NOT NULL DEFAULT 0,
+		patterns TEXT,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS forwarding_rules (
+		user_email TEXT PRIMARY KEY,
+		address TEXT NOT NULL,
+		keep_copy INTEGER DEFAULT 1,
+		match_from TEXT,
+		match_subject TEXT,
+		verified INTEGER DEFAULT 0,
+		code_hash TEXT,
+		code_expires_at DATETIME,
+		attempts INTEGER DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS vacations (
+		user_email TEXT PRIMARY KEY,
+		enabled INTEGER DEFAULT 0,
+		message TEXT,
+		starts_at DATETIME,
+		ends_at DATETIME,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS metadata (
+		user_email TEXT NOT NULL,
+		mailbox TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_email, mailbox, name),
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS subscriptions (
+		user_email TEXT NOT NULL,
+		folder TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_email, folder),
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS message_annotations (
+		mail_id TEXT NOT NULL,
+		entry TEXT NOT NULL,
+		shared INTEGER NOT NULL DEFAULT 0,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (mail_id, entry, shared),
+		FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_unsubscribe (
+		mail_id TEXT PRIMARY KEY,
+		list_id TEXT NOT NULL DEFAULT '',
+		mailto TEXT NOT NULL DEFAULT '',
+		url TEXT NOT NULL DEFAULT '',
+		one_click INTEGER NOT NULL DEFAULT 0,
+		unsubscribed_at DATETIME,
+		FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS newsletter_settings (
+		user_email TEXT PRIMARY KEY,
+		folder TEXT NOT NULL DEFAULT '',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_categories (
+		mail_id TEXT PRIMARY KEY,
+		category TEXT NOT NULL,
+		tokens TEXT NOT NULL DEFAULT '',
+		trained INTEGER NOT NULL DEFAULT 0,
+		FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS category_docs (
+		user_email TEXT NOT NULL,
+		category TEXT NOT NULL,
+		docs INTEGER NOT NULL DEFAULT 0,
+		tokens INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_email, category)
+	);
+
+	CREATE TABLE IF NOT EXISTS category_tokens (
+		user_email TEXT NOT NULL,
+		category TEXT NOT NULL,
+		token TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_email, category, token)
+	);
+
+	CREATE TABLE IF NOT EXISTS external_accounts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		protocol TEXT NOT NULL,
+		host TEXT NOT NULL,
+		port INTEGER NOT NULL,
+		tls INTEGER NOT NULL DEFAULT 1,
+		username TEXT NOT NULL,
+		password BLOB NOT NULL,
+		folder TEXT NOT NULL DEFAULT 'INBOX',
+		keep_remote INTEGER NOT NULL DEFAULT 1,
+		enabled INTEGER NOT NULL DEFAULT 1,
+		last_fetch_at DATETIME,
+		last_success_at DATETIME,
+		last_error TEXT NOT NULL DEFAULT '',
+		last_count INTEGER NOT NULL DEFAULT 0,
+		total_count INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS external_account_uids (
+		account_id INTEGER NOT NULL,
+		uid TEXT NOT NULL,
+		fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (account_id, uid),
+		FOREIGN KEY (account_id) REFERENCES external_accounts(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS identities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		address TEXT NOT NULL,
+		display_name TEXT NOT NULL DEFAULT '',
+		smtp_host TEXT NOT NULL,
+		smtp_port INTEGER NOT NULL,
+		smtp_username TEXT NOT NULL,
+		smtp_password BLOB NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_email, address),
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		scope TEXT NOT NULL,
+		expires_at DATETIME,
+		last_used_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS user_modseq (
+		user_email TEXT PRIMARY KEY,
+		modseq INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_expunges (
+		user_email TEXT NOT NULL,
+		mail_id TEXT NOT NULL,
+		folder TEXT NOT NULL,
+		modseq INTEGER NOT NULL,
+		expunged_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		uid INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS maildir_journal (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		op TEXT NOT NULL,
+		path TEXT NOT NULL,
+		new_path TEXT NOT NULL DEFAULT '',
+		sha256 TEXT NOT NULL DEFAULT '',
+		size INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS auto_responders (
+		user_email TEXT PRIMARY KEY,
+		enabled INTEGER DEFAULT 0,
+		subject TEXT NOT NULL DEFAULT '',
+		body TEXT NOT NULL DEFAULT '',
+		starts_at DATETIME,
+		ends_at DATETIME,
+		interval_days INTEGER NOT NULL DEFAULT 7,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS auto_reply_log (
+		user_email TEXT NOT NULL,
+		sender TEXT NOT NULL,
+		replied_at DATETIME NOT NULL,
+		PRIMARY KEY (user_email, sender),
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS cram_md5_secrets (
+		user_email TEXT PRIMARY KEY,
+		secret TEXT NOT NULL,
+		password_hash TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS quota_warnings (
+		user_email TEXT PRIMARY KEY,
+		threshold INTEGER NOT NULL,
+		warned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS domain_defaults (
+		domain TEXT PRIMARY KEY,
+		quota INTEGER,
+		settings TEXT NOT NULL DEFAULT '{}',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS user_settings (
+		user_email TEXT PRIMARY KEY,
+		settings TEXT NOT NULL DEFAULT '{}',
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		user_email TEXT NOT NULL,
+		source TEXT NOT NULL,
+		remote_ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_used_at DATETIME,
+		expires_at DATETIME NOT NULL,
+		refresh_hash TEXT NOT NULL DEFAULT '',
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS digest_log (
+		user_email TEXT PRIMARY KEY,
+		sent_at DATETIME NOT NULL,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS bounces (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sender TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		domain TEXT NOT NULL,
+		category TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT '',
+		diagnostic TEXT NOT NULL DEFAULT '',
+		source TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS delivery_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		queue_id TEXT NOT NULL DEFAULT '',
+		message_id TEXT NOT NULL DEFAULT '',
+		event TEXT NOT NULL,
+		sender TEXT NOT NULL DEFAULT '',
+		recipient TEXT NOT NULL DEFAULT '',
+		folder TEXT NOT NULL DEFAULT '',
+		code INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT '',
+		detail TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS warmup_counts (
+		day TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		sent INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (day, provider)
+	);
+
+	CREATE TABLE IF NOT EXISTS deferred_mails (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sender TEXT NOT NULL,
+		recipients TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		data BLOB NOT NULL,
+		not_before DATETIME NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS spam_fingerprints (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		hash TEXT NOT NULL UNIQUE,
+		reason TEXT NOT NULL,
+		sample TEXT NOT NULL DEFAULT '',
+		reports INTEGER NOT NULL DEFAULT 0,
+		hits INTEGER NOT NULL DEFAULT 0,
+		first_seen DATETIME NOT NULL,
+		last_seen DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS spam_fingerprint_sources (
+		fingerprint_id INTEGER NOT NULL,
+		source TEXT NOT NULL,
+		first_seen DATETIME NOT NULL,
+		PRIMARY KEY (fingerprint_id, source),
+		FOREIGN KEY (fingerprint_id) REFERENCES spam_fingerprints(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS spam_bayes_docs (
+		user_email TEXT PRIMARY KEY,
+		spam INTEGER NOT NULL DEFAULT 0,
+		ham INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS spam_bayes_tokens (
+		user_email TEXT NOT NULL,
+		token TEXT NOT NULL,
+		spam INTEGER NOT NULL DEFAULT 0,
+		ham INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (user_email, token)
+	);
+
+	CREATE TABLE IF NOT EXISTS spam_bayes_trained (
+		user_email TEXT NOT NULL,
+		digest TEXT NOT NULL,
+		spam INTEGER NOT NULL,
+		trained_at DATETIME NOT NULL,
+		PRIMARY KEY (user_email, digest)
+	);
+
+	CREATE TABLE IF NOT EXISTS zero_access_keys (
+		user_email TEXT PRIMARY KEY,
+		public_key BLOB NOT NULL,
+		password_wrapped BLOB NOT NULL,
+		recovery_wrapped BLOB NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS push_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		endpoint TEXT NOT NULL UNIQUE,
+		p256dh BLOB NOT NULL,
+		auth BLOB NOT NULL,
+		privacy TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		last_push_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS smtp_transcripts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		queue_id TEXT NOT NULL DEFAULT '',
+		message_id TEXT NOT NULL DEFAULT '',
+		sender TEXT NOT NULL DEFAULT '',
+		domain TEXT NOT NULL,
+		recipients TEXT NOT NULL DEFAULT '',
+		mx_host TEXT NOT NULL DEFAULT '',
+		remote_addr TEXT NOT NULL DEFAULT '',
+		tls_version TEXT NOT NULL DEFAULT '',
+		tls_cipher TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		transcript TEXT NOT NULL DEFAULT '',
+		started_at DATETIME NOT NULL,
+		duration_ms INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE TABLE IF NOT EXISTS login_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		protocol TEXT NOT NULL,
+		remote_ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS sent_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		message_id TEXT NOT NULL DEFAULT '',
+		subject TEXT NOT NULL DEFAULT '',
+		recipients TEXT NOT NULL DEFAULT '',
+		protocol TEXT NOT NULL,
+		remote_ip TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS dkim_keys (
+		domain TEXT PRIMARY KEY,
+		selector TEXT NOT NULL,
+		algorithm TEXT NOT NULL,
+		key_file TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS terms_acceptances (
+		user_email TEXT NOT NULL,
+		version TEXT NOT NULL,
+		remote_ip TEXT NOT NULL DEFAULT '',
+		accepted_at DATETIME NOT NULL,
+		PRIMARY KEY (user_email, version)
+	);
+
+	CREATE TABLE IF NOT EXISTS webauthn_credentials (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_email TEXT NOT NULL,
+		credential_id BLOB NOT NULL UNIQUE,
+		name TEXT NOT NULL DEFAULT '',
+		data BLOB NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_used_at DATETIME,
+		FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_receipts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sent_mail_id TEXT NOT NULL,
+		recipient TEXT NOT NULL,
+		mail_id TEXT NOT NULL,
+		track_read INTEGER NOT NULL DEFAULT 0,
+		delivered_at DATETIME NOT NULL,
+		read_at DATETIME,
+		FOREIGN KEY (sent_mail_id) REFERENCES mails(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS mailbox_uids (
+		user_email TEXT NOT NULL,
+		folder TEXT NOT NULL,
+		uid_validity INTEGER NOT NULL,
+		uid_next INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_email, folder)
+	);
+
+	CREATE TABLE IF NOT EXISTS mail_attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		mail_id TEXT NOT NULL,
+		user_email TEXT NOT NULL,
+		part TEXT NOT NULL,
+		filename TEXT NOT NULL DEFAULT '',
+		content_type TEXT NOT NULL DEFAULT '',
+		size INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_mails_user_folder ON mails(user_email, folder);
 	CREATE INDEX IF NOT EXISTS idx_mails_received_at ON mails(received_at);
 	CREATE INDEX IF NOT EXISTS idx_mails_uid ON mails(user_email, folder, uid);
+	CREATE INDEX IF NOT EXISTS idx_mails_message_id ON mails(user_email, message_id);
+	CREATE INDEX IF NOT EXISTS idx_mails_thread ON mails(user_email, thread_id);
+	CREATE INDEX IF NOT EXISTS idx_mails_in_reply_to ON mails(user_email, in_reply_to);
 	CREATE INDEX IF NOT EXISTS idx_aliases_from ON aliases(from_addr);
 	CREATE INDEX IF NOT EXISTS idx_aliases_domain ON aliases(domain);
+	CREATE INDEX IF NOT EXISTS idx_aliases_owner ON aliases(owner);
+	CREATE INDEX IF NOT EXISTS idx_mail_attachments_user ON mail_attachments(user_email, content_type);
+	CREATE INDEX IF NOT EXISTS idx_mail_attachments_mail ON mail_attachments(mail_id);
+	CREATE INDEX IF NOT EXISTS idx_external_accounts_user ON external_accounts(user_email);
+	CREATE INDEX IF NOT EXISTS idx_mails_modseq ON mails(user_email, modseq);
+	CREATE INDEX IF NOT EXISTS idx_mail_expunges_modseq ON mail_expunges(user_email, modseq);
+	CREATE INDEX IF NOT EXISTS idx_mail_expunges_folder ON mail_expunges(user_email, folder, modseq);
+	CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_email);
+	CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_email);
+	CREATE INDEX IF NOT EXISTS idx_bounces_created ON bounces(created_at);
+	CREATE INDEX IF NOT EXISTS idx_delivery_log_created ON delivery_log(created_at);
+	CREATE INDEX IF NOT EXISTS idx_delivery_log_queue ON delivery_log(queue_id);
+	CREATE INDEX IF NOT EXISTS idx_delivery_log_message ON delivery_log(message_id);
+	CREATE INDEX IF NOT EXISTS idx_deferred_mails_not_before ON deferred_mails(not_before);
+	CREATE INDEX IF NOT EXISTS idx_spam_fingerprints_last_seen ON spam_fingerprints(last_seen);
+	CREATE INDEX IF NOT EXISTS idx_maildir_journal_created ON maildir_journal(created_at);
+	CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_email);
+	CREATE INDEX IF NOT EXISTS idx_smtp_transcripts_started ON smtp_transcripts(started_at);
+	CREATE INDEX IF NOT EXISTS idx_smtp_transcripts_message ON smtp_transcripts(message_id);
+	CREATE INDEX IF NOT EXISTS idx_login_history_user ON login_history(user_email, created_at);
+	CREATE INDEX IF NOT EXISTS idx_sent_log_user ON sent_log(user_email, created_at);
+	CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_email);
+	CREATE INDEX IF NOT EXISTS idx_mail_receipts_sent ON mail_receipts(sent_mail_id);
+	CREATE INDEX IF NOT EXISTS idx_mail_receipts_mail ON mail_receipts(mail_id);
+
+	CREATE VIRTUAL TABLE IF NOT EXISTS mails_fts USING fts5(
+		subject, from_addr, to_addrs, cc_addrs,
+		content='mails', content_rowid='rowid', tokenize='trigram'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS mails_fts_insert AFTER INSERT ON mails BEGIN
+		INSERT INTO mails_fts(rowid, subject, from_addr, to_addrs, cc_addrs)
+		VALUES (new.rowid, new.subject, new.from_addr, new.to_addrs, new.cc_addrs);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS mails_fts_delete AFTER DELETE ON mails BEGIN
+		INSERT INTO mails_fts(mails_fts, rowid, subject, from_addr, to_addrs, cc_addrs)
+		VALUES ('delete', old.rowid, old.subject, old.from_addr, old.to_addrs, old.cc_addrs);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS mails_fts_update AFTER UPDATE OF subject, from_addr, to_addrs, cc_addrs ON mails BEGIN
+		INSERT INTO mails_fts(mails_fts, rowid, subject, from_addr, to_addrs, cc_addrs)
+		VALUES ('delete', old.rowid, old.subject, old.from_addr, old.to_addrs, old.cc_addrs);
+		INSERT INTO mails_fts(rowid, subject, from_addr, to_addrs, cc_addrs)
+		VALUES (new.rowid, new.subject, new.from_addr, new.to_addrs, new.cc_addrs);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS mails_modseq_insert AFTER INSERT ON mails BEGIN
+		INSERT INTO user_modseq (user_email, modseq) VALUES (new.user_email, 1)
+		ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+		UPDATE mails SET
+			modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email),
+			create_modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email)
+		WHERE rowid = new.rowid;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS mails_modseq_update AFTER UPDATE OF folder, flags ON mails BEGIN
+		INSERT INTO user_modseq (user_email, modseq) VALUES (new.user_email, 1)
+		ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+		UPDATE mails SET modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email)
+		WHERE rowid = new.rowid;
+		INSERT INTO mail_expunges (user_email, mail_id, folder, uid, modseq)
+		SELECT old.user_email, old.id, old.folder, COALESCE(old.uid, 0), modseq FROM user_modseq
+		WHERE user_email = old.user_email AND old.folder != new.folder;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS mails_modseq_delete AFTER DELETE ON mails BEGIN
+		INSERT INTO user_modseq (user_email, modseq) VALUES (old.user_email, 1)
+		ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+		INSERT INTO mail_expunges (user_email, mail_id, folder, uid, modseq)
+		SELECT old.user_email, old.id, old.folder, COALESCE(old.uid, 0), modseq FROM user_modseq
+		WHERE user_email = old.user_email;
+	END;
 	`
 
 	_, err := d.db.Exec(schema)
@@ -137,11 +741,22 @@ func (d *SQLiteDriver) initSchema() error {
 
 // CreateUser 创建用户
 func (d *SQLiteDriver) CreateUser(ctx context.Context, user *User) error {
+	if !strings.Contains(user.Email, "@") {
+		return fmt.Errorf("创建用户失败: 无效的邮箱地址 %q: %w", user.Email, ErrInvalidInput)
+	}
+	// 未指定配额时使用域名的默认配额
+	if user.Quota == 0 {
+		quota, err := d.defaultQuota(ctx, user.Email)
+		if err != nil {
+			return fmt.Errorf("创建用户失败: %w", err)
+		}
+		user.Quota = quota
+	}
 	query := `
 		INSERT INTO users (email, password_hash, quota, active, is_admin, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
-	now := time.Now()
+	now := utcNow()
 	active := 0
 	if user.Active {
 		active = 1
@@ -160,6 +775,9 @@ func (d *SQLiteDriver) CreateUser(ctx context.Context, user *User) error {
 		now,
 	)
 	if err != nil {
+		return fmt.Errorf("创建用户失败: %w", constraintError(err))
+	}
+	if err := d.subscribeDefaults(ctx, user.Email); err != nil {
 		return fmt.Errorf("创建用户失败: %w", err)
 	}
 	return nil
@@ -219,11 +837,11 @@ func (d *SQLiteDriver) UpdateUser(ctx context.Context, user *User) error {
 		user.Quota,
 		active,
 		isAdmin,
-		time.Now(),
+		utcNow(),
 		user.ID,
 	)
 	if err != nil {
-		return fmt.Errorf("更新用户失败: %w", err)
+		return fmt.Errorf("更新用户失败: %w", constraintError(err))
 	}
 	return nil
 }
@@ -278,23 +896,40 @@ func (d *SQLiteDriver) ListUsers(ctx context.Context, limit, offset int) ([]*Use
 
 // CreateDomain 创建域名
 func (d *SQLiteDriver) CreateDomain(ctx context.Context, domain *Domain) error {
+	if domain.Name == "" || strings.ContainsAny(domain.Name, "@ ") {
+		return fmt.Errorf("创建域名失败: 无效的域名 %q: %w", domain.Name, ErrInvalidInput)
+	}
+	if !validReceipts(domain.Receipts) {
+		return fmt.Errorf("创建域名失败: 无效的回执策略 %q: %w", domain.Receipts, ErrInvalidInput)
+	}
+	thresholds, err := formatThresholds(domain.QuotaWarningThresholds)
+	if err != nil {
+		return fmt.Errorf("创建域名失败: %w", err)
+	}
 	query := `
-		INSERT INTO domains (name, active, created_at, updated_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO domains (name, active, forwarding_disabled, quota_warning_thresholds, receipts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
 	`
-	now := time.Now()
+	now := utcNow()
 	active := 0
 	if domain.Active {
 		active = 1
 	}
-	_, err := d.db.ExecContext(ctx, query,
+	forwardingDisabled := 0
+	if domain.ForwardingDisabled {
+		forwardingDisabled = 1
+	}
+	_, err = d.db.ExecContext(ctx, query,
 		domain.Name,
 		active,
+		forwardingDisabled,
+		thresholds,
+		domain.Receipts,
 		now,
 		now,
 	)
 	if err != nil {
-		return fmt.Errorf("创建域名失败: %w", err)
+		return fmt.Errorf("创建域名失败: %w", constraintError(err))
 	}
 	return nil
 }
@@ -302,18 +937,22 @@ func (d *SQLiteDriver) CreateDomain(ctx context.Context, domain *Domain) error {
 // GetDomain 获取域名
 func (d *SQLiteDriver) GetDomain(ctx context.Context, name string) (*Domain, error) {
 	query := `
-		SELECT id, name, active, created_at, updated_at
+		SELECT id, name, active, COALESCE(forwarding_disabled, 0), COALESCE(quota_warning_thresholds, ''), COALESCE(receipts, ''), created_at, updated_at
 		FROM domains
 		WHERE name = ?
 	`
 	row := d.db.QueryRowContext(ctx, query, name)
 
 	var domain Domain
-	var active int
+	var active, forwardingDisabled int
+	var thresholds string
 	err := row.Scan(
 		&domain.ID,
 		&domain.Name,
 		&active,
+		&forwardingDisabled,
+		&thresholds,
+		&domain.Receipts,
 		&domain.CreatedAt,
 		&domain.UpdatedAt,
 	)
@@ -325,28 +964,44 @@ func (d *SQLiteDriver) GetDomain(ctx context.Context, name string) (*Domain, err
 	}
 
 	domain.Active = active == 1
+	domain.ForwardingDisabled = forwardingDisabled == 1
+	domain.QuotaWarningThresholds = parseThresholds(thresholds)
 	return &domain, nil
 }
 
 // UpdateDomain 更新域名
 func (d *SQLiteDriver) UpdateDomain(ctx context.Context, domain *Domain) error {
+	if !validReceipts(domain.Receipts) {
+		return fmt.Errorf("更新域名失败: 无效的回执策略 %q: %w", domain.Receipts, ErrInvalidInput)
+	}
+	thresholds, err := formatThresholds(domain.QuotaWarningThresholds)
+	if err != nil {
+		return fmt.Errorf("更新域名失败: %w", err)
+	}
 	query := `
 		UPDATE domains
-		SET name = ?, active = ?, updated_at = ?
+		SET name = ?, active = ?, forwarding_disabled = ?, quota_warning_thresholds = ?, receipts = ?, updated_at = ?
 		WHERE id = ?
 	`
 	active := 0
 	if domain.Active {
 		active = 1
 	}
-	_, err := d.db.ExecContext(ctx, query,
+	forwardingDisabled := 0
+	if domain.ForwardingDisabled {
+		forwardingDisabled = 1
+	}
+	_, err = d.db.ExecContext(ctx, query,
 		domain.Name,
 		active,
-		time.Now(),
+		forwardingDisabled,
+		thresholds,
+		domain.Receipts,
+		utcNow(),
 		domain.ID,
 	)
 	if err != nil {
-		return fmt.Errorf("更新域名失败: %w", err)
+		return fmt.Errorf("更新域名失败: %w", constraintError(err))
 	}
 	return nil
 }
@@ -364,7 +1019,7 @@ func (d *SQLiteDriver) DeleteDomain(ctx context.Context, name string) error {
 // ListDomains 列出域名
 func (d *SQLiteDriver) ListDomains(ctx context.Context) ([]*Domain, error) {
 	query := `
-		SELECT id, name, active, created_at, updated_at
+		SELECT id, name, active, COALESCE(forwarding_disabled, 0), COALESCE(quota_warning_thresholds, ''), COALESCE(receipts, ''), created_at, updated_at
 		FROM domains
 		ORDER BY name
 	`
@@ -377,58 +1032,68 @@ func (d *SQLiteDriver) ListDomains(ctx context.Context) ([]*Domain, error) {
 	var domains []*Domain
 	for rows.Next() {
 		var domain Domain
-		var active int
+		var active, forwardingDisabled int
+		var thresholds string
 		if err := rows.Scan(
 			&domain.ID,
 			&domain.Name,
 			&active,
+			&forwardingDisabled,
+			&thresholds,
+			&domain.Receipts,
 			&domain.CreatedAt,
 			&domain.UpdatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("扫描域名失败: %w", err)
 		}
 		domain.Active = active == 1
+		domain.ForwardingDisabled = forwardingDisabled == 1
+		domain.QuotaWarningThresholds = parseThresholds(thresholds)
 		domains = append(domains, &domain)
 	}
 
 	return domains, nil
 }
 
-// CreateAlias 创建别名
+// CreateAlias 创建别名（Destinations 为空时只投递到 To，否则 To 为第一个目标）
 func (d *SQLiteDriver) CreateAlias(ctx context.Context, alias *Alias) error {
+	if len(alias.Destinations) == 0 {
+		alias.Destinations = []string{alias.To}
+	}
+	destinations, err := normalizeDestinations(alias.Destinations)
+	if err != nil || !strings.Contains(alias.From, "@") {
+		return fmt.Errorf("创建别名失败: 无效的地址: %w", ErrInvalidInput)
+	}
+	alias.Destinations = destinations
+	alias.To = destinations[0]
 	query := `
-		INSERT INTO aliases (from_addr, to_addr, domain, created_at)
-		VALUES (?, ?, ?, ?)
+		INSERT INTO aliases (from_addr, to_addr, destinations, domain, owner, disposable, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
-	_, err := d.db.ExecContext(ctx, query,
+	disposable := 0
+	if alias.Disposable {
+		disposable = 1
+	}
+	_, err = d.db.ExecContext(ctx, query,
 		alias.From,
 		alias.To,
+		strings.Join(alias.Destinations, ","),
 		alias.Domain,
-		time.Now(),
+		alias.Owner,
+		disposable,
+		alias.ExpiresAt,
+		utcNow(),
 	)
 	if err != nil {
-		return fmt.Errorf("创建别名失败: %w", err)
+		return fmt.Errorf("创建别名失败: %w", constraintError(err))
 	}
 	return nil
 }
 
-// GetAlias 获取别名
+// GetAlias 获取别名（包括已过期和已作废的别名，路由前需要检查 Active）
 func (d *SQLiteDriver) GetAlias(ctx context.Context, from string) (*Alias, error) {
-	query := `
-		SELECT id, from_addr, to_addr, domain, created_at
-		FROM aliases
-		WHERE from_addr = ?
-	`
-	row := d.db.QueryRowContext(ctx, query, from)
-
-	var alias Alias
-	err := row.Scan(
-		&alias.ID,
-		&alias.From,
-		&alias.To,
-		&alias.Domain,
-		&alias.CreatedAt,
-	)
+	query := `SELECT ` + aliasColumns + ` FROM aliases WHERE from_addr = ?`
+	alias, err := scanAlias(d.db.QueryRowContext(ctx, query, from))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("别名不存在: %w", ErrNotFound)
 	}
@@ -436,7 +1101,7 @@ func (d *SQLiteDriver) GetAlias(ctx context.Context, from string) (*Alias, error
 		return nil, fmt.Errorf("查询别名失败: %w", err)
 	}
 
-	return &alias, nil
+	return alias, nil
 }
 
 // DeleteAlias 删除别名
@@ -451,12 +1116,7 @@ func (d *SQLiteDriver) DeleteAlias(ctx context.Context, from string) error {
 
 // ListAliases 列出别名
 func (d *SQLiteDriver) ListAliases(ctx context.Context, domain string) ([]*Alias, error) {
-	query := `
-		SELECT id, from_addr, to_addr, domain, created_at
-		FROM aliases
-		WHERE domain = ?
-		ORDER BY from_addr
-	`
+	query := `SELECT ` + aliasColumns + ` FROM aliases WHERE domain = ? ORDER BY from_addr`
 	rows, err := d.db.QueryContext(ctx, query, domain)
 	if err != nil {
 		return nil, fmt.Errorf("查询别名列表失败: %w", err)
@@ -465,49 +1125,33 @@ func (d *SQLiteDriver) ListAliases(ctx context.Context, domain string) ([]*Alias
 
 	var aliases []*Alias
 	for rows.Next() {
-		var alias Alias
-		if err := rows.Scan(
-			&alias.ID,
-			&alias.From,
-			&alias.To,
-			&alias.Domain,
-			&alias.CreatedAt,
-		); err != nil {
+		alias, err := scanAlias(rows)
+		if err != nil {
 			return nil, fmt.Errorf("扫描别名失败: %w", err)
 		}
-		aliases = append(aliases, &alias)
+		aliases = append(aliases, alias)
 	}
 
 	return aliases, nil
 }
 
-// GetNextUID 获取下一个 UID（为指定邮箱）
-func (d *SQLiteDriver) GetNextUID(ctx context.Context, userEmail, folder string) (uint32, error) {
-	// 获取当前最大 UID
-	query := `SELECT COALESCE(MAX(uid), 0) FROM mails WHERE user_email = ? AND folder = ?`
-	var maxUID uint32
-	err := d.db.QueryRowContext(ctx, query, userEmail, folder).Scan(&maxUID)
-	if err != nil && err != sql.ErrNoRows {
-		return 0, fmt.Errorf("查询最大 UID 失败: %w", err)
-	}
-	// 返回下一个 UID（最大 UID + 1）
-	return maxUID + 1, nil
-}
-
 // StoreMail 存储邮件（仅元数据，邮件体由 Maildir 存储）
 func (d *SQLiteDriver) StoreMail(ctx context.Context, mail *Mail) error {
-	// 如果 UID 为 0，自动分配下一个 UID
+	// 如果 UID 为 0，自动分配下一个 UID；否则确保之后分配的 UID 大于它
 	if mail.UID == 0 {
-		nextUID, err := d.GetNextUID(ctx, mail.UserEmail, mail.Folder)
+		nextUID, err := d.AllocateUID(ctx, mail.UserEmail, mail.Folder)
 		if err != nil {
 			return fmt.Errorf("获取下一个 UID 失败: %w", err)
 		}
 		mail.UID = nextUID
+	} else if err := d.reserveUID(ctx, mail.UserEmail, mail.Folder, mail.UID); err != nil {
+		return fmt.Errorf("更新 UIDNEXT 失败: %w", err)
 	}
 
 	query := `
-		INSERT INTO mails (id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, received_at, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO mails (id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, has_attachment,
+			message_id, in_reply_to, refs, thread_id, received_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	// 将切片转换为字符串（简单实现，实际应该使用 JSON）
@@ -527,10 +1171,18 @@ func (d *SQLiteDriver) StoreMail(ctx context.Context, mail *Mail) error {
 		}
 	}
 
-	now := time.Now()
-	// 将时间格式化为 SQLite 兼容的格式（RFC3339）
-	receivedAtStr := mail.ReceivedAt.Format(time.RFC3339)
-	createdAtStr := now.Format(time.RFC3339)
+	hasAttachment := 0
+	if mail.HasAttachment || len(mail.Attachments) > 0 {
+		hasAttachment = 1
+	}
+
+	if mail.ThreadID == "" {
+		threadID, err := d.findThread(ctx, mail)
+		if err != nil {
+			return err
+		}
+		mail.ThreadID = threadID
+	}
 
 	_, err := d.db.ExecContext(ctx, query,
 		mail.ID,
@@ -538,34 +1190,46 @@ func (d *SQLiteDriver) StoreMail(ctx context.Context, mail *Mail) error {
 		mail.Folder,
 		mail.From,
 		toAddrs,
-		"", // cc_addrs
-		"", // bcc_addrs
+		strings.Join(mail.Cc, ","), // cc_addrs（用于 to: 搜索）
+		"",                         // bcc_addrs
 		mail.Subject,
 		mail.Size,
 		flags,
 		mail.UID,
-		receivedAtStr,
-		createdAtStr,
+		hasAttachment,
+		mail.MessageID,
+		mail.InReplyTo,
+		strings.Join(mail.References, ","),
+		mail.ThreadID,
+		mail.ReceivedAt,
+		utcNow(),
 	)
 	if err != nil {
 		return fmt.Errorf("存储邮件失败: %w", err)
 	}
-	return nil
+	if err := d.mergeThreads(ctx, mail); err != nil {
+		return err
+	}
+	if err := d.storeAttachments(ctx, mail); err != nil {
+		return err
+	}
+	return d.storeUnsubscribe(ctx, mail)
 }
 
 // GetMail 获取邮件
 func (d *SQLiteDriver) GetMail(ctx context.Context, id string) (*Mail, error) {
 	query := `
-		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, received_at, created_at
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, COALESCE(has_attachment, 0), received_at, created_at,
+			message_id, in_reply_to, refs, thread_id, modseq
 		FROM mails
 		WHERE id = ?
 	`
 	row := d.db.QueryRowContext(ctx, query, id)
 
 	var mail Mail
-	var toAddrs, ccAddrs, bccAddrs, flags string
-	var receivedAtStr, createdAtStr string
+	var toAddrs, ccAddrs, bccAddrs, flags, refs string
 	var uid sql.NullInt64 // UID 可能为 NULL（旧邮件）
+	var hasAttachment int
 	err := row.Scan(
 		&mail.ID,
 		&mail.UserEmail,
@@ -578,8 +1242,14 @@ func (d *SQLiteDriver) GetMail(ctx context.Context, id string) (*Mail, error) {
 		&mail.Size,
 		&flags,
 		&uid,
-		&receivedAtStr,
-		&createdAtStr,
+		&hasAttachment,
+		&mail.ReceivedAt,
+		&mail.CreatedAt,
+		&mail.MessageID,
+		&mail.InReplyTo,
+		&refs,
+		&mail.ThreadID,
+		&mail.ModSeq,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("邮件不存在: %w", ErrNotFound)
@@ -590,6 +1260,8 @@ func (d *SQLiteDriver) GetMail(ctx context.Context, id string) (*Mail, error) {
 	if uid.Valid {
 		mail.UID = uint32(uid.Int64)
 	}
+	mail.HasAttachment = hasAttachment == 1
+	mail.References = splitList(refs)
 
 	// 解析 to_addrs（用逗号分割）
 	if toAddrs != "" {
@@ -624,18 +1296,6 @@ func (d *SQLiteDriver) GetMail(ctx context.Context, id string) (*Mail, error) {
 		}
 	}
 
-	// 解析时间字符串
-	if receivedAtStr != "" {
-		if t := parseTimeString(receivedAtStr); !t.IsZero() {
-			mail.ReceivedAt = t
-		}
-	}
-	if createdAtStr != "" {
-		if t := parseTimeString(createdAtStr); !t.IsZero() {
-			mail.CreatedAt = t
-		}
-	}
-
 	return &mail, nil
 }
 
@@ -654,7 +1314,8 @@ func (d *SQLiteDriver) GetMailBody(ctx context.Context, userEmail string, folder
 // ListMails 列出邮件
 func (d *SQLiteDriver) ListMails(ctx context.Context, userEmail string, folder string, limit, offset int) ([]*Mail, error) {
 	query := `
-		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, received_at, created_at
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, COALESCE(has_attachment, 0), received_at, created_at,
+			message_id, in_reply_to, refs, thread_id, modseq
 		FROM mails
 		WHERE user_email = ? AND folder = ?
 		ORDER BY COALESCE(uid, 0) ASC, received_at DESC
@@ -666,133 +1327,80 @@ func (d *SQLiteDriver) ListMails(ctx context.Context, userEmail string, folder s
 	}
 	defer rows.Close()
 
-	mails := make([]*Mail, 0) // 初始化为空切片，而不是 nil
-	for rows.Next() {
-		var mail Mail
-		var toAddrs, ccAddrs, bccAddrs, flags string
-		var receivedAtStr, createdAtStr string
-		var uid sql.NullInt64 // UID 可能为 NULL（旧邮件）
-		if err := rows.Scan(
-			&mail.ID,
-			&mail.UserEmail,
-			&mail.Folder,
-			&mail.From,
-			&toAddrs,
-			&ccAddrs,
-			&bccAddrs,
-			&mail.Subject,
-			&mail.Size,
-			&flags,
-			&uid,
-			&receivedAtStr,
-			&createdAtStr,
-		); err != nil {
-			return nil, fmt.Errorf("扫描邮件失败: %w", err)
-		}
-		if uid.Valid {
-			mail.UID = uint32(uid.Int64)
-		}
-
-		// 解析 to_addrs（用逗号分割）
-		if toAddrs != "" {
-			mail.To = strings.Split(toAddrs, ",")
-			// 去除空格
-			for i := range mail.To {
-				mail.To[i] = strings.TrimSpace(mail.To[i])
-			}
-		}
-		// 解析 cc_addrs（用逗号分割）
-		if ccAddrs != "" {
-			mail.Cc = strings.Split(ccAddrs, ",")
-			// 去除空格
-			for i := range mail.Cc {
-				mail.Cc[i] = strings.TrimSpace(mail.Cc[i])
-			}
-		}
-		// 解析 bcc_addrs（用逗号分割）
-		if bccAddrs != "" {
-			mail.Bcc = strings.Split(bccAddrs, ",")
-			// 去除空格
-			for i := range mail.Bcc {
-				mail.Bcc[i] = strings.TrimSpace(mail.Bcc[i])
-			}
-		}
-		// 解析 flags（用逗号分割）
-		if flags != "" {
-			mail.Flags = strings.Split(flags, ",")
-			// 去除空格
-			for i := range mail.Flags {
-				mail.Flags[i] = strings.TrimSpace(mail.Flags[i])
-			}
-		}
-
-		// 解析时间字符串
-		if receivedAtStr != "" {
-			if t := parseTimeString(receivedAtStr); !t.IsZero() {
-				mail.ReceivedAt = t
-			}
-		}
-		if createdAtStr != "" {
-			if t := parseTimeString(createdAtStr); !t.IsZero() {
-				mail.CreatedAt = t
-			}
-		}
-
-		mails = append(mails, &mail)
-	}
-
-	return mails, nil
+	return scanMailList(rows)
 }
 
-// parseTimeString 解析时间字符串，支持多种格式（向后兼容）
-func parseTimeString(timeStr string) time.Time {
-	// 尝试 RFC3339 格式（标准格式）
-	if t, err := time.Parse(time.RFC3339, timeStr); err == nil {
-		return t
+// FindMailsByMessageID 查找用户邮箱中 Message-ID（不带尖括号）相同的邮件，按接收时间排序
+func (d *SQLiteDriver) FindMailsByMessageID(ctx context.Context, userEmail, messageID string) ([]*Mail, error) {
+	if messageID == "" {
+		return nil, nil
 	}
-
-	// 尝试 RFC3339Nano 格式
-	if t, err := time.Parse(time.RFC3339Nano, timeStr); err == nil {
-		return t
+	query := `
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, COALESCE(has_attachment, 0), received_at, created_at,
+			message_id, in_reply_to, refs, thread_id, modseq
+		FROM mails
+		WHERE user_email = ? AND message_id = ?
+		ORDER BY received_at, id
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件失败: %w", err)
 	}
+	defer rows.Close()
 
-	// 尝试 Go time.Time.String() 格式（包含 m=+xxx 调试信息）
-	// 格式：2006-01-02 15:04:05.999999999 -0700 MST m=+xxx
-	if idx := strings.Index(timeStr, " m="); idx > 0 {
-		timeStr = timeStr[:idx]
-		if t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", timeStr); err == nil {
-			return t
-		}
-	}
+	return scanMailList(rows)
+}
 
-	// 尝试标准格式（无时区）
-	if t, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", timeStr); err == nil {
-		return t
+// CountMails 统计文件夹中的邮件数
+func (d *SQLiteDriver) CountMails(ctx context.Context, userEmail string, folder string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM mails WHERE user_email = ? AND folder = ?`
+	if err := d.db.QueryRowContext(ctx, query, userEmail, folder).Scan(&count); err != nil {
+		return 0, fmt.Errorf("统计邮件数失败: %w", err)
 	}
+	return count, nil
+}
 
-	// 尝试简单格式
-	if t, err := time.Parse("2006-01-02 15:04:05", timeStr); err == nil {
-		return t
+// ListMailIDs 按 UID 顺序列出文件夹中 UID 大于 afterUID 的邮件（最多 limit 封，不包括邮件内容）。
+// 使用 UID 作为游标分页（keyset），翻页期间有邮件新增或删除时不会跳过或重复；
+// 没有 UID 的旧邮件不包括在内，需要先调用 GetMailboxUIDs 分配
+func (d *SQLiteDriver) ListMailIDs(ctx context.Context, userEmail string, folder string, afterUID uint32, limit int) ([]*Mail, error) {
+	query := `
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, COALESCE(has_attachment, 0), received_at, created_at,
+			message_id, in_reply_to, refs, thread_id, modseq
+		FROM mails
+		WHERE user_email = ? AND folder = ? AND uid > ?
+		ORDER BY uid ASC
+		LIMIT ?
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, folder, afterUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件列表失败: %w", err)
 	}
+	defer rows.Close()
 
-	// 如果所有格式都失败，返回零值时间
-	return time.Time{}
+	return scanMailList(rows)
 }
 
 // SearchMails 搜索邮件
-func (d *SQLiteDriver) SearchMails(ctx context.Context, userEmail string, query string, folder string, limit, offset int) ([]*Mail, error) {
+func (d *SQLiteDriver) SearchMails(ctx context.Context, userEmail string, query *SearchQuery, folder string, limit, offset int) ([]*Mail, error) {
 	sqlQuery := `
-		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, received_at, created_at
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, COALESCE(has_attachment, 0), received_at, created_at,
+			message_id, in_reply_to, refs, thread_id, modseq
 		FROM mails
-		WHERE user_email = ? AND (subject LIKE ? OR from_addr LIKE ? OR to_addrs LIKE ?)
+		WHERE user_email = ?
 	`
-	args := []interface{}{userEmail, "%" + query + "%", "%" + query + "%", "%" + query + "%"}
+	args := []interface{}{userEmail}
 
 	if folder != "" {
 		sqlQuery += " AND folder = ?"
 		args = append(args, folder)
 	}
 
+	where, whereArgs := searchConditions(query)
+	sqlQuery += where
+	args = append(args, whereArgs...)
+
 	sqlQuery += " ORDER BY COALESCE(uid, 0) ASC, received_at DESC LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
@@ -802,12 +1410,17 @@ func (d *SQLiteDriver) SearchMails(ctx context.Context, userEmail string, query
 	}
 	defer rows.Close()
 
+	return scanMailList(rows)
+}
+
+// scanMailList 扫描邮件列表查询的结果（列与 ListMails 的查询对应）
+func scanMailList(rows *sql.Rows) ([]*Mail, error) {
 	mails := make([]*Mail, 0) // 初始化为空切片，而不是 nil
 	for rows.Next() {
 		var mail Mail
-		var toAddrs, ccAddrs, bccAddrs, flags string
-		var receivedAtStr, createdAtStr string
+		var toAddrs, ccAddrs, bccAddrs, flags, refs string
 		var uid sql.NullInt64 // UID 可能为 NULL（旧邮件）
+		var hasAttachment int
 		if err := rows.Scan(
 			&mail.ID,
 			&mail.UserEmail,
@@ -820,14 +1433,22 @@ func (d *SQLiteDriver) SearchMails(ctx context.Context, userEmail string, query
 			&mail.Size,
 			&flags,
 			&uid,
-			&receivedAtStr,
-			&createdAtStr,
+			&hasAttachment,
+			&mail.ReceivedAt,
+			&mail.CreatedAt,
+			&mail.MessageID,
+			&mail.InReplyTo,
+			&refs,
+			&mail.ThreadID,
+			&mail.ModSeq,
 		); err != nil {
 			return nil, fmt.Errorf("扫描邮件失败: %w", err)
 		}
 		if uid.Valid {
 			mail.UID = uint32(uid.Int64)
 		}
+		mail.HasAttachment = hasAttachment == 1
+		mail.References = splitList(refs)
 
 		// 解析 to_addrs（用逗号分割）
 		if toAddrs != "" {
@@ -862,22 +1483,10 @@ func (d *SQLiteDriver) SearchMails(ctx context.Context, userEmail string, query
 			}
 		}
 
-		// 解析时间字符串
-		if receivedAtStr != "" {
-			if t := parseTimeString(receivedAtStr); !t.IsZero() {
-				mail.ReceivedAt = t
-			}
-		}
-		if createdAtStr != "" {
-			if t := parseTimeString(createdAtStr); !t.IsZero() {
-				mail.CreatedAt = t
-			}
-		}
-
 		mails = append(mails, &mail)
 	}
 
-	return mails, nil
+	return mails, rows.Err()
 }
 
 // ListFolders 列出文件夹
@@ -896,7 +1505,7 @@ func (d *SQLiteDriver) ListFolders(ctx context.Context, userEmail string) ([]str
 
 	var folders []string
 	// 添加默认文件夹
-	folders = append(folders, "INBOX", "Sent", "Drafts", "Trash", "Spam")
+	folders = append(folders, "INBOX", "Sent", "Drafts", "Trash", "Spam", "Archive")
 
 	folderMap := make(map[string]bool)
 	for _, f := range folders {
@@ -927,7 +1536,7 @@ func (d *SQLiteDriver) DeleteMail(ctx context.Context, id string) error {
 	return nil
 }
 
-// UpdateMailFlags 更新邮件标志
+// UpdateMailFlags 更新邮件标志（首次设置 \Seen 时记录内部邮件回执的阅读时间）
 func (d *SQLiteDriver) UpdateMailFlags(ctx context.Context, id string, flags []string) error {
 	flagsStr := ""
 	if len(flags) > 0 {
@@ -942,9 +1551,52 @@ func (d *SQLiteDriver) UpdateMailFlags(ctx context.Context, id string, flags []s
 	if err != nil {
 		return fmt.Errorf("更新邮件标志失败: %w", err)
 	}
+	if slices.Contains(flags, "\\Seen") {
+		if err := d.markReceiptRead(ctx, id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// MoveMail 将邮件移动到另一个文件夹，在目标文件夹分配新的 UID 并返回
+// （邮件 ID 不变，附件、注解等关联数据随邮件保留）
+func (d *SQLiteDriver) MoveMail(ctx context.Context, id, folder string) (uint32, error) {
+	var userEmail string
+	if err := d.db.QueryRowContext(ctx, "SELECT user_email FROM mails WHERE id = ?", id).Scan(&userEmail); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("邮件不存在: %w", ErrNotFound)
+		}
+		return 0, fmt.Errorf("查询邮件失败: %w", err)
+	}
+	if err := d.ensureMailboxUIDs(ctx, userEmail, folder); err != nil {
+		return 0, err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("移动邮件失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var uid uint32
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE mailbox_uids SET uid_next = uid_next + 1
+		WHERE user_email = ? AND folder = ?
+		RETURNING uid_next - 1
+	`, userEmail, folder).Scan(&uid); err != nil {
+		return 0, fmt.Errorf("分配 UID 失败: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE mails SET folder = ?, uid = ? WHERE id = ?", folder, uid, id); err != nil {
+		return 0, fmt.Errorf("移动邮件失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("移动邮件失败: %w", err)
+	}
+	return uid, nil
+}
+
 // GetQuota 获取配额
 func (d *SQLiteDriver) GetQuota(ctx context.Context, userEmail string) (*Quota, error) {
 	query := `
@@ -971,11 +1623,17 @@ func (d *SQLiteDriver) GetQuota(ctx context.Context, userEmail string) (*Quota,
 
 // UpdateQuota 更新配额
 func (d *SQLiteDriver) UpdateQuota(ctx context.Context, userEmail string, quota *Quota) error {
+	if quota.Limit < 0 {
+		return fmt.Errorf("更新配额失败: 配额不能为负数: %w", ErrInvalidInput)
+	}
 	query := `UPDATE users SET quota = ? WHERE email = ?`
-	_, err := d.db.ExecContext(ctx, query, quota.Limit, userEmail)
+	result, err := d.db.ExecContext(ctx, query, quota.Limit, userEmail)
 	if err != nil {
 		return fmt.Errorf("更新配额失败: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("用户不存在: %w", ErrNotFound)
+	}
 	return nil
 }
 
@@ -983,6 +1641,3 @@ func (d *SQLiteDriver) UpdateQuota(ctx context.Context, userEmail string, quota
 func (d *SQLiteDriver) Close() error {
 	return d.db.Close()
 }
-
-// ErrNotFound 未找到错误
-var ErrNotFound = fmt.Errorf("not found")
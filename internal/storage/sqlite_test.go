@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestSQLiteDriver(t *testing.T) {
@@ -139,6 +140,35 @@ func TestSQLiteDriver(t *testing.T) {
 			t.Errorf("配额限制不匹配: got %d, want %d", quota.Limit, 1024*1024*100)
 		}
 	})
+
+	t.Run("CountMails_ListMailIDs", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			mail := &Mail{ID: fmt.Sprintf("page-%d", i), UserEmail: "test@example.com", Folder: "Archive", Subject: "hi", ReceivedAt: time.Now()}
+			if err := driver.StoreMail(ctx, mail); err != nil {
+				t.Fatalf("保存邮件失败: %v", err)
+			}
+		}
+
+		if count, err := driver.CountMails(ctx, "test@example.com", "Archive"); err != nil || count != 5 {
+			t.Fatalf("CountMails() = %d, %v，期望 5", count, err)
+		}
+
+		// 按 UID 游标分页，两页之间删除一封已读过的邮件不影响后面的页
+		first, err := driver.ListMailIDs(ctx, "test@example.com", "Archive", 0, 2)
+		if err != nil || len(first) != 2 || first[0].UID >= first[1].UID {
+			t.Fatalf("第一页 = %+v, %v", first, err)
+		}
+		if err := driver.DeleteMail(ctx, first[0].ID); err != nil {
+			t.Fatal(err)
+		}
+		rest, err := driver.ListMailIDs(ctx, "test@example.com", "Archive", first[1].UID, 10)
+		if err != nil || len(rest) != 3 {
+			t.Fatalf("剩余的页 = %d 封, %v，期望 3 封", len(rest), err)
+		}
+		if rest[0].UID <= first[1].UID {
+			t.Errorf("下一页应该从游标之后开始: %d <= %d", rest[0].UID, first[1].UID)
+		}
+	})
 }
 
 func TestSQLiteDriver_Concurrent(t *testing.T) {
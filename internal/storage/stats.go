@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// replyWindow 回复耗时统计的最大间隔（超过视为新邮件而非回复）
+const replyWindow = 7 * 24 * time.Hour
+
+// dayStats 汇总过程中单个用户一天的统计
+type dayStats struct {
+	DailyStats
+	contacts map[[2]string]int // {地址, 方向} -> 邮件数
+}
+
+// RecordDailyStats 重新汇总所有用户在指定日期（本地时区）的邮件统计，返回有邮件的用户数
+func (d *SQLiteDriver) RecordDailyStats(ctx context.Context, day time.Time) (int, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	dayKey := start.Format(usageDayFormat)
+
+	stats, err := d.collectDayStats(ctx, start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("记录邮件统计失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mail_stats_daily WHERE day = ?", dayKey); err != nil {
+		return 0, fmt.Errorf("清除邮件统计失败: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM mail_contacts_daily WHERE day = ?", dayKey); err != nil {
+		return 0, fmt.Errorf("清除联系人统计失败: %w", err)
+	}
+
+	now := utcNow()
+	for userEmail, s := range stats {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO mail_stats_daily (user_email, day, received, sent, attachments, attachment_bytes, replies, reply_seconds, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, userEmail, dayKey, s.Received, s.Sent, s.Attachments, s.AttachmentBytes, s.Replies, s.ReplySeconds, now); err != nil {
+			return 0, fmt.Errorf("记录邮件统计失败: %w", err)
+		}
+		for key, count := range s.contacts {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO mail_contacts_daily (user_email, day, address, direction, count)
+				VALUES (?, ?, ?, ?, ?)
+			`, userEmail, dayKey, key[0], key[1], count); err != nil {
+				return 0, fmt.Errorf("记录联系人统计失败: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("记录邮件统计失败: %w", err)
+	}
+	return len(stats), nil
+}
+
+// collectDayStats 从邮件和附件表统计 [start, end) 内的数据（草稿不计入）
+func (d *SQLiteDriver) collectDayStats(ctx context.Context, start, end time.Time) (map[string]*dayStats, error) {
+	rangeArgs := []interface{}{start, end}
+	stats := make(map[string]*dayStats)
+	get := func(userEmail string) *dayStats {
+		s, ok := stats[userEmail]
+		if !ok {
+			s = &dayStats{contacts: make(map[[2]string]int)}
+			stats[userEmail] = s
+		}
+		return s
+	}
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT user_email, folder, COALESCE(from_addr, ''), COALESCE(to_addrs, ''), COALESCE(cc_addrs, ''), received_at
+		FROM mails
+		WHERE folder != 'Drafts'
+			AND received_at >= ? AND received_at < ?
+	`, rangeArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件统计失败: %w", err)
+	}
+
+	type sentMail struct {
+		userEmail  string
+		recipients []string
+		sentAt     time.Time
+	}
+	var sent []sentMail
+	for rows.Next() {
+		var userEmail, folder, from, to, cc string
+		var receivedAt time.Time
+		if err := rows.Scan(&userEmail, &folder, &from, &to, &cc, &receivedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("扫描邮件统计失败: %w", err)
+		}
+		s := get(userEmail)
+		if folder != "Sent" {
+			s.Received++
+			if addr := statsAddress(from); addr != "" {
+				s.contacts[[2]string{addr, ContactIncoming}]++
+			}
+			continue
+		}
+
+		s.Sent++
+		var recipients []string
+		for _, raw := range strings.Split(to+","+cc, ",") {
+			if addr := statsAddress(raw); addr != "" {
+				recipients = append(recipients, addr)
+				s.contacts[[2]string{addr, ContactOutgoing}]++
+			}
+		}
+		sent = append(sent, sentMail{userEmail: userEmail, recipients: recipients, sentAt: receivedAt})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询邮件统计失败: %w", err)
+	}
+
+	// 回复耗时：发信时间 - 之前最近一封来自任一收件人的邮件的接收时间
+	for _, m := range sent {
+		if m.sentAt.IsZero() {
+			continue
+		}
+		var latest time.Time
+		for _, addr := range m.recipients {
+			var receivedAt time.Time
+			err := d.db.QueryRowContext(ctx, `
+				SELECT received_at FROM mails
+				WHERE user_email = ? AND folder NOT IN ('Sent', 'Drafts') AND LOWER(from_addr) LIKE ?
+					AND received_at < ? AND received_at >= ?
+				ORDER BY received_at DESC
+				LIMIT 1
+			`, m.userEmail, "%"+addr+"%", m.sentAt, m.sentAt.Add(-replyWindow)).Scan(&receivedAt)
+			if err != nil {
+				continue
+			}
+			if receivedAt.After(latest) {
+				latest = receivedAt
+			}
+		}
+		if !latest.IsZero() {
+			s := get(m.userEmail)
+			s.Replies++
+			s.ReplySeconds += int64(m.sentAt.Sub(latest).Seconds())
+		}
+	}
+
+	attRows, err := d.db.QueryContext(ctx, `
+		SELECT a.user_email, COUNT(*), COALESCE(SUM(a.size), 0)
+		FROM mail_attachments a
+		JOIN mails m ON m.id = a.mail_id
+		WHERE m.folder != 'Drafts'
+			AND m.received_at >= ? AND m.received_at < ?
+		GROUP BY a.user_email
+	`, rangeArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("查询附件统计失败: %w", err)
+	}
+	defer attRows.Close()
+	for attRows.Next() {
+		var userEmail string
+		var count int
+		var size int64
+		if err := attRows.Scan(&userEmail, &count, &size); err != nil {
+			return nil, fmt.Errorf("扫描附件统计失败: %w", err)
+		}
+		s := get(userEmail)
+		s.Attachments = count
+		s.AttachmentBytes = size
+	}
+	return stats, attRows.Err()
+}
+
+// statsAddress 提取并规范化邮件地址（去掉显示名，转小写）
+func statsAddress(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if addr, err := mail.ParseAddress(raw); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.Trim(raw, "<>"))
+}
+
+// ListDailyStats 列出用户从指定日期起的每日邮件统计（按日期升序，没有邮件的日期不返回）
+func (d *SQLiteDriver) ListDailyStats(ctx context.Context, userEmail string, since time.Time) ([]*DailyStats, error) {
+	query := `
+		SELECT day, received, sent, attachments, attachment_bytes, replies, reply_seconds
+		FROM mail_stats_daily
+		WHERE user_email = ? AND day >= ?
+		ORDER BY day
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, since.Format(usageDayFormat))
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件统计失败: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*DailyStats
+	for rows.Next() {
+		var s DailyStats
+		if err := rows.Scan(&s.Day, &s.Received, &s.Sent, &s.Attachments, &s.AttachmentBytes, &s.Replies, &s.ReplySeconds); err != nil {
+			return nil, fmt.Errorf("扫描邮件统计失败: %w", err)
+		}
+		stats = append(stats, &s)
+	}
+	return stats, rows.Err()
+}
+
+// TopContacts 列出用户从指定日期起往来最多的联系人（direction 为 ContactIncoming 或 ContactOutgoing）
+func (d *SQLiteDriver) TopContacts(ctx context.Context, userEmail, direction string, since time.Time, limit int) ([]*ContactCount, error) {
+	query := `
+		SELECT address, SUM(count) AS total
+		FROM mail_contacts_daily
+		WHERE user_email = ? AND direction = ? AND day >= ?
+		GROUP BY address
+		ORDER BY total DESC, address
+		LIMIT ?
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, direction, since.Format(usageDayFormat), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询往来联系人失败: %w", err)
+	}
+	defer rows.Close()
+
+	var contacts []*ContactCount
+	for rows.Next() {
+		var c ContactCount
+		if err := rows.Scan(&c.Address, &c.Count); err != nil {
+			return nil, fmt.Errorf("扫描往来联系人失败: %w", err)
+		}
+		contacts = append(contacts, &c)
+	}
+	return contacts, rows.Err()
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_DailyStats(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	day := time.Date(2024, 3, 5, 9, 0, 0, 0, time.Local)
+	mails := []*Mail{
+		{ID: "in1", Folder: "INBOX", From: "Alice <Alice@example.com>", To: []string{"me@example.com"}, ReceivedAt: day},
+		{ID: "in2", Folder: "INBOX", From: "alice@example.com", To: []string{"me@example.com"}, ReceivedAt: day.Add(time.Hour),
+			Attachments: []*Attachment{{Part: "2", Filename: "a.pdf", ContentType: "application/pdf", Size: 1000}}},
+		{ID: "in3", Folder: "INBOX", From: "bob@example.com", To: []string{"me@example.com"}, ReceivedAt: day.Add(2 * time.Hour)},
+		// 30 分钟后回复 alice
+		{ID: "out1", Folder: "Sent", From: "me@example.com", To: []string{"alice@example.com"}, Cc: []string{"carol@example.com"},
+			ReceivedAt: day.Add(90 * time.Minute)},
+		{ID: "draft", Folder: "Drafts", From: "me@example.com", To: []string{"dave@example.com"}, ReceivedAt: day},
+		{ID: "old", Folder: "INBOX", From: "bob@example.com", To: []string{"me@example.com"}, ReceivedAt: day.AddDate(0, 0, -1)},
+	}
+	for _, m := range mails {
+		m.UserEmail = "me@example.com"
+		if err := driver.StoreMail(ctx, m); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+
+	n, err := driver.RecordDailyStats(ctx, day)
+	if err != nil {
+		t.Fatalf("汇总邮件统计失败: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("汇总用户数 = %d, want 1", n)
+	}
+	// 重复汇总同一天结果不变
+	if _, err := driver.RecordDailyStats(ctx, day); err != nil {
+		t.Fatalf("重复汇总邮件统计失败: %v", err)
+	}
+
+	stats, err := driver.ListDailyStats(ctx, "me@example.com", day)
+	if err != nil {
+		t.Fatalf("获取邮件统计失败: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("统计天数 = %d, want 1", len(stats))
+	}
+	s := stats[0]
+	if s.Day != "2024-03-05" || s.Received != 3 || s.Sent != 1 || s.Attachments != 1 || s.AttachmentBytes != 1000 {
+		t.Errorf("每日统计不正确: %+v", s)
+	}
+	if s.Replies != 1 || s.ReplySeconds != 1800 {
+		t.Errorf("回复耗时不正确: replies=%d seconds=%d", s.Replies, s.ReplySeconds)
+	}
+
+	senders, err := driver.TopContacts(ctx, "me@example.com", ContactIncoming, day, 10)
+	if err != nil {
+		t.Fatalf("获取往来联系人失败: %v", err)
+	}
+	if len(senders) != 2 || senders[0].Address != "alice@example.com" || senders[0].Count != 2 {
+		t.Errorf("发件人排行不正确: %+v", senders)
+	}
+	recipients, err := driver.TopContacts(ctx, "me@example.com", ContactOutgoing, day, 10)
+	if err != nil {
+		t.Fatalf("获取往来联系人失败: %v", err)
+	}
+	if len(recipients) != 2 {
+		t.Errorf("收件人排行不正确: %+v", recipients)
+	}
+}
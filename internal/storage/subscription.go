@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultSubscriptions 新用户默认订阅的文件夹
+var DefaultSubscriptions = []string{"INBOX", "Sent", "Drafts", "Trash", "Spam"}
+
+// ListSubscriptions 列出用户订阅的文件夹（可能包括已删除的文件夹，RFC 3501 要求删除文件夹时不自动取消订阅）
+func (d *SQLiteDriver) ListSubscriptions(ctx context.Context, userEmail string) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT folder FROM subscriptions WHERE user_email = ? ORDER BY folder`, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询订阅列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	var folders []string
+	for rows.Next() {
+		var folder string
+		if err := rows.Scan(&folder); err != nil {
+			return nil, fmt.Errorf("扫描订阅列表失败: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+// SetSubscribed 订阅或取消订阅文件夹（重复订阅或取消未订阅的文件夹不报错）
+func (d *SQLiteDriver) SetSubscribed(ctx context.Context, userEmail, folder string, subscribed bool) error {
+	query := `DELETE FROM subscriptions WHERE user_email = ? AND folder = ?`
+	args := []interface{}{userEmail, folder}
+	if subscribed {
+		query = `INSERT OR IGNORE INTO subscriptions (user_email, folder, created_at) VALUES (?, ?, ?)`
+		args = append(args, utcNow())
+	}
+	if _, err := d.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("保存订阅状态失败: %w", err)
+	}
+	return nil
+}
+
+// subscribeDefaults 为新用户订阅默认文件夹
+func (d *SQLiteDriver) subscribeDefaults(ctx context.Context, userEmail string) error {
+	for _, folder := range DefaultSubscriptions {
+		if err := d.SetSubscribed(ctx, userEmail, folder, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
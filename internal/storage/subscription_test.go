@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/migrate"
+)
+
+func TestSQLiteDriver_Subscriptions(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	const user = "alice@example.com"
+	if err := driver.CreateUser(ctx, &User{Email: user, PasswordHash: "x", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	folders, err := driver.ListSubscriptions(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != len(DefaultSubscriptions) {
+		t.Errorf("新用户的订阅列表 = %v，期望 %v", folders, DefaultSubscriptions)
+	}
+
+	// 重复订阅和取消未订阅的文件夹不报错
+	for _, step := range []struct {
+		folder     string
+		subscribed bool
+	}{{"Projects", true}, {"Projects", true}, {"Spam", false}, {"Spam", false}} {
+		if err := driver.SetSubscribed(ctx, user, step.folder, step.subscribed); err != nil {
+			t.Fatalf("设置 %s 订阅状态失败: %v", step.folder, err)
+		}
+	}
+	folders, _ = driver.ListSubscriptions(ctx, user)
+	if !slices.Contains(folders, "Projects") || slices.Contains(folders, "Spam") {
+		t.Errorf("订阅列表 = %v", folders)
+	}
+
+	// 删除用户时订阅随之删除
+	if err := driver.DeleteUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if folders, _ := driver.ListSubscriptions(ctx, user); len(folders) != 0 {
+		t.Errorf("删除用户后仍有订阅: %v", folders)
+	}
+}
+
+func TestSubscriptionsMigration(t *testing.T) {
+	driver, err := NewSQLiteDriver(filepath.Join(t.TempDir(), "gmz.db"))
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	ctx := context.Background()
+	migrations := filepath.Join("..", "..", "migrations")
+	if err := migrate.MigrateTo(ctx, driver.db, migrations, 35); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+	if _, err := driver.db.ExecContext(ctx, `INSERT INTO users (email, password_hash) VALUES ('old@example.com', 'x')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.Migrate(ctx, driver.db, migrations, "up"); err != nil {
+		t.Fatalf("执行迁移失败: %v", err)
+	}
+
+	// 已有用户默认订阅 INBOX 和特殊文件夹
+	folders, err := driver.ListSubscriptions(ctx, "old@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != len(DefaultSubscriptions) {
+		t.Errorf("迁移后的订阅列表 = %v，期望 %v", folders, DefaultSubscriptions)
+	}
+}
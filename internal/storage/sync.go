@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MailChanges 用户邮件自某个修改序号以来的变化（增量同步）
+type MailChanges struct {
+	ModSeq  uint64          `json:"modseq"`  // 本次返回的变化截至的修改序号（下次同步的 since）
+	Created []*Mail         `json:"created"` // 新增的邮件（按修改序号升序）
+	Updated []*Mail         `json:"updated"` // 标志变化或移动到其他文件夹的邮件
+	Deleted []*ExpungedMail `json:"deleted"` // 已删除的邮件
+	Folders []string        `json:"folders"` // 内容有变化的文件夹（包括邮件移出的文件夹）
+	More    bool            `json:"more"`    // 还有更多变化，以 ModSeq 为 since 继续同步
+	Reset   bool            `json:"reset"`   // since 大于服务器的修改序号（如数据库已重建），返回的是完整数据
+}
+
+// ExpungedMail 已删除的邮件
+type ExpungedMail struct {
+	ID     string `json:"id"`
+	Folder string `json:"folder"`
+}
+
+// GetMailChanges 获取用户自 since 以来的邮件变化，最多返回 limit 封新增或更新的邮件
+// （since 为 0 时返回所有邮件）
+func (d *SQLiteDriver) GetMailChanges(ctx context.Context, userEmail string, since uint64, limit int) (*MailChanges, error) {
+	var highest uint64
+	err := d.db.QueryRowContext(ctx, `SELECT modseq FROM user_modseq WHERE user_email = ?`, userEmail).Scan(&highest)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("查询修改序号失败: %w", err)
+	}
+
+	changes := &MailChanges{
+		ModSeq:  highest,
+		Created: []*Mail{},
+		Updated: []*Mail{},
+		Deleted: []*ExpungedMail{},
+		Folders: []string{},
+	}
+	if since > highest {
+		since = 0
+		changes.Reset = true
+	}
+	if since == highest {
+		return changes, nil
+	}
+
+	// 只读取 highest 之前的变化，查询期间新产生的变化留到下次同步
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid,
+			COALESCE(has_attachment, 0), received_at, created_at, modseq, create_modseq
+		FROM mails
+		WHERE user_email = ? AND modseq > ? AND modseq <= ?
+		ORDER BY modseq ASC
+		LIMIT ?
+	`, userEmail, since, highest, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮件变化失败: %w", err)
+	}
+	defer rows.Close()
+
+	folders := make(map[string]bool)
+	count := 0
+	for rows.Next() {
+		mail, createModSeq, err := scanChangedMail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描邮件失败: %w", err)
+		}
+		count++
+		if count > limit {
+			changes.More = true
+			break
+		}
+		changes.ModSeq = mail.ModSeq
+		if createModSeq > since {
+			changes.Created = append(changes.Created, mail)
+		} else {
+			changes.Updated = append(changes.Updated, mail)
+		}
+		folders[mail.Folder] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询邮件变化失败: %w", err)
+	}
+	rows.Close()
+	if !changes.More {
+		changes.ModSeq = highest
+	}
+
+	// 删除和移出文件夹的记录（完整同步时不需要）
+	if since > 0 {
+		if err := d.collectExpunges(ctx, userEmail, since, changes, folders); err != nil {
+			return nil, err
+		}
+	}
+
+	for folder := range folders {
+		changes.Folders = append(changes.Folders, folder)
+	}
+	sort.Strings(changes.Folders)
+	return changes, nil
+}
+
+// collectExpunges 将 (since, changes.ModSeq] 之间删除的邮件加入 changes.Deleted，
+// 删除或移出邮件的文件夹加入 folders（移动后仍然存在的邮件已作为更新返回）
+func (d *SQLiteDriver) collectExpunges(ctx context.Context, userEmail string, since uint64, changes *MailChanges, folders map[string]bool) error {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT e.mail_id, e.folder, EXISTS (SELECT 1 FROM mails m WHERE m.id = e.mail_id)
+		FROM mail_expunges e
+		WHERE e.user_email = ? AND e.modseq > ? AND e.modseq <= ?
+		ORDER BY e.modseq ASC
+	`, userEmail, since, changes.ModSeq)
+	if err != nil {
+		return fmt.Errorf("查询已删除邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	deleted := make(map[string]bool)
+	for rows.Next() {
+		var expunged ExpungedMail
+		var exists bool
+		if err := rows.Scan(&expunged.ID, &expunged.Folder, &exists); err != nil {
+			return fmt.Errorf("扫描已删除邮件失败: %w", err)
+		}
+		folders[expunged.Folder] = true
+		if !exists && !deleted[expunged.ID] {
+			deleted[expunged.ID] = true
+			changes.Deleted = append(changes.Deleted, &expunged)
+		}
+	}
+	return rows.Err()
+}
+
+// GetHighestModSeq 获取文件夹的最大修改序号（邮件的修改序号和邮件移出或删除时的修改序号中的最大值，
+// 文件夹没有任何变化时为 1），文件夹中任何邮件新增、标志变化、移出或删除后都会增大
+func (d *SQLiteDriver) GetHighestModSeq(ctx context.Context, userEmail, folder string) (uint64, error) {
+	var highest uint64
+	err := d.db.QueryRowContext(ctx, `
+		SELECT MAX(
+			COALESCE((SELECT MAX(modseq) FROM mails WHERE user_email = ? AND folder = ?), 0),
+			COALESCE((SELECT MAX(modseq) FROM mail_expunges WHERE user_email = ? AND folder = ?), 0)
+		)
+	`, userEmail, folder, userEmail, folder).Scan(&highest)
+	if err != nil {
+		return 0, fmt.Errorf("查询修改序号失败: %w", err)
+	}
+	return max(highest, 1), nil
+}
+
+// ListExpungedUIDs 列出自修改序号 since 以来从文件夹中删除或移出的邮件的 UID（升序，去重）
+func (d *SQLiteDriver) ListExpungedUIDs(ctx context.Context, userEmail, folder string, since uint64) ([]uint32, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT DISTINCT uid FROM mail_expunges
+		WHERE user_email = ? AND folder = ? AND modseq > ? AND uid > 0
+		ORDER BY uid ASC
+	`, userEmail, folder, since)
+	if err != nil {
+		return nil, fmt.Errorf("查询已删除邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var uids []uint32
+	for rows.Next() {
+		var uid uint32
+		if err := rows.Scan(&uid); err != nil {
+			return nil, fmt.Errorf("扫描已删除邮件失败: %w", err)
+		}
+		uids = append(uids, uid)
+	}
+	return uids, rows.Err()
+}
+
+// scanChangedMail 扫描增量同步查询的一行邮件，同时返回邮件新增时的修改序号
+func scanChangedMail(row rowScanner) (*Mail, uint64, error) {
+	var mail Mail
+	var toAddrs, ccAddrs, bccAddrs, flags string
+	var uid sql.NullInt64 // UID 可能为 NULL（旧邮件）
+	var hasAttachment int
+	var createModSeq uint64
+	if err := row.Scan(
+		&mail.ID,
+		&mail.UserEmail,
+		&mail.Folder,
+		&mail.From,
+		&toAddrs,
+		&ccAddrs,
+		&bccAddrs,
+		&mail.Subject,
+		&mail.Size,
+		&flags,
+		&uid,
+		&hasAttachment,
+		&mail.ReceivedAt,
+		&mail.CreatedAt,
+		&mail.ModSeq,
+		&createModSeq,
+	); err != nil {
+		return nil, 0, err
+	}
+	if uid.Valid {
+		mail.UID = uint32(uid.Int64)
+	}
+	mail.HasAttachment = hasAttachment == 1
+	mail.To = splitList(toAddrs)
+	mail.Cc = splitList(ccAddrs)
+	mail.Bcc = splitList(bccAddrs)
+	mail.Flags = splitList(flags)
+	return &mail, createModSeq, nil
+}
+
+// splitList 解析逗号分隔的列表（去除空格，空字符串返回 nil）
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	items := strings.Split(s, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_GetMailChanges(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	store := func(id, folder string) {
+		t.Helper()
+		mail := &Mail{
+			ID: id, UserEmail: "alice@example.com", Folder: folder, From: "bob@example.com",
+			To: []string{"alice@example.com"}, Subject: id, Size: 10, ReceivedAt: time.Now(),
+		}
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+	store("m1", "INBOX")
+	store("m2", "INBOX")
+	store("m3", "INBOX")
+	if err := driver.StoreMail(ctx, &Mail{ID: "other", UserEmail: "bob@example.com", Folder: "INBOX", From: "x", ReceivedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 完整同步
+	changes, err := driver.GetMailChanges(ctx, "alice@example.com", 0, 100)
+	if err != nil {
+		t.Fatalf("GetMailChanges() error = %v", err)
+	}
+	if len(changes.Created) != 3 || len(changes.Updated) != 0 || changes.More || changes.ModSeq != 3 {
+		t.Fatalf("完整同步结果不正确: created=%d updated=%d more=%v modseq=%d",
+			len(changes.Created), len(changes.Updated), changes.More, changes.ModSeq)
+	}
+	since := changes.ModSeq
+
+	// 分页
+	page, err := driver.GetMailChanges(ctx, "alice@example.com", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Created) != 2 || !page.More || page.ModSeq != 2 {
+		t.Errorf("分页结果不正确: created=%d more=%v modseq=%d", len(page.Created), page.More, page.ModSeq)
+	}
+
+	// 标志变化、移动、删除和新邮件
+	if err := driver.UpdateMailFlags(ctx, "m1", []string{"\\Seen"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := driver.MoveMail(ctx, "m2", "Archive"); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.DeleteMail(ctx, "m3"); err != nil {
+		t.Fatal(err)
+	}
+	store("m4", "INBOX")
+
+	changes, err = driver.GetMailChanges(ctx, "alice@example.com", since, 100)
+	if err != nil {
+		t.Fatalf("GetMailChanges() error = %v", err)
+	}
+	if len(changes.Created) != 1 || changes.Created[0].ID != "m4" {
+		t.Errorf("新增邮件不正确: %+v", changes.Created)
+	}
+	if len(changes.Updated) != 2 || changes.Updated[0].ID != "m1" || changes.Updated[1].Folder != "Archive" {
+		t.Errorf("更新邮件不正确: %+v", changes.Updated)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0].ID != "m3" {
+		t.Errorf("删除邮件不正确: %+v", changes.Deleted)
+	}
+	if len(changes.Folders) != 2 || changes.Folders[0] != "Archive" || changes.Folders[1] != "INBOX" {
+		t.Errorf("变化的文件夹不正确: %v", changes.Folders)
+	}
+
+	// 没有变化
+	unchanged, err := driver.GetMailChanges(ctx, "alice@example.com", changes.ModSeq, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unchanged.Created)+len(unchanged.Updated)+len(unchanged.Deleted) != 0 || unchanged.ModSeq != changes.ModSeq {
+		t.Errorf("没有变化时不应该返回邮件: %+v", unchanged)
+	}
+
+	// since 超过服务器的修改序号时返回完整数据
+	reset, err := driver.GetMailChanges(ctx, "alice@example.com", changes.ModSeq+100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reset.Reset || len(reset.Created) != 3 {
+		t.Errorf("应该返回完整数据: reset=%v created=%d", reset.Reset, len(reset.Created))
+	}
+}
+
+func TestSQLiteDriver_ModSeqForIMAP(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	highest, err := driver.GetHighestModSeq(ctx, "alice@example.com", "INBOX")
+	if err != nil || highest != 1 {
+		t.Fatalf("空文件夹的修改序号应为 1: %d, %v", highest, err)
+	}
+
+	for i, id := range []string{"m1", "m2", "m3"} {
+		mail := &Mail{
+			ID: id, UserEmail: "alice@example.com", Folder: "INBOX", From: "bob@example.com",
+			Subject: id, UID: uint32(i + 1), ReceivedAt: time.Now(),
+		}
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+	if highest, _ = driver.GetHighestModSeq(ctx, "alice@example.com", "INBOX"); highest != 3 {
+		t.Errorf("HIGHESTMODSEQ = %d, want 3", highest)
+	}
+
+	// 标志变化后邮件的修改序号增大，列表中可以读到
+	if err := driver.UpdateMailFlags(ctx, "m1", []string{"\\Seen"}); err != nil {
+		t.Fatal(err)
+	}
+	mail, err := driver.GetMail(ctx, "m1")
+	if err != nil || mail.ModSeq != 4 {
+		t.Fatalf("标志变化后的修改序号不正确: %+v, %v", mail, err)
+	}
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 3 || mails[0].ModSeq != 4 || mails[1].ModSeq != 2 {
+		t.Fatalf("ListMails 返回的修改序号不正确: %v", err)
+	}
+
+	// 移出和删除的邮件记录原 UID
+	if _, err := driver.MoveMail(ctx, "m2", "Archive"); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.DeleteMail(ctx, "m3"); err != nil {
+		t.Fatal(err)
+	}
+	uids, err := driver.ListExpungedUIDs(ctx, "alice@example.com", "INBOX", 4)
+	if err != nil || len(uids) != 2 || uids[0] != 2 || uids[1] != 3 {
+		t.Fatalf("ListExpungedUIDs() = %v, %v", uids, err)
+	}
+	if uids, _ = driver.ListExpungedUIDs(ctx, "alice@example.com", "INBOX", 5); len(uids) != 1 || uids[0] != 3 {
+		t.Errorf("since 之后删除的 UID 不正确: %v", uids)
+	}
+	if highest, _ = driver.GetHighestModSeq(ctx, "alice@example.com", "INBOX"); highest != 6 {
+		t.Errorf("删除后 HIGHESTMODSEQ = %d, want 6", highest)
+	}
+	if highest, _ = driver.GetHighestModSeq(ctx, "alice@example.com", "Archive"); highest != 5 {
+		t.Errorf("Archive HIGHESTMODSEQ = %d, want 5", highest)
+	}
+}
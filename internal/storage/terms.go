@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AcceptTerms 记录用户接受某个版本的使用条款（同一版本重复接受时保留第一次的记录）
+func (d *SQLiteDriver) AcceptTerms(ctx context.Context, acceptance *TermsAcceptance) error {
+	if acceptance.AcceptedAt.IsZero() {
+		acceptance.AcceptedAt = time.Now()
+	}
+	acceptance.UserEmail = strings.ToLower(acceptance.UserEmail)
+	acceptance.AcceptedAt = acceptance.AcceptedAt.UTC()
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO terms_acceptances (user_email, version, remote_ip, accepted_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_email, version) DO NOTHING
+	`, acceptance.UserEmail, acceptance.Version, acceptance.RemoteIP, acceptance.AcceptedAt)
+	if err != nil {
+		return fmt.Errorf("保存使用条款接受记录失败: %w", err)
+	}
+	return nil
+}
+
+// GetTermsAcceptance 获取用户接受某个版本使用条款的记录
+func (d *SQLiteDriver) GetTermsAcceptance(ctx context.Context, userEmail, version string) (*TermsAcceptance, error) {
+	var a TermsAcceptance
+	err := d.db.QueryRowContext(ctx, `
+		SELECT user_email, version, remote_ip, accepted_at
+		FROM terms_acceptances WHERE user_email = ? AND version = ?
+	`, strings.ToLower(userEmail), version).Scan(&a.UserEmail, &a.Version, &a.RemoteIP, &a.AcceptedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("使用条款接受记录不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询使用条款接受记录失败: %w", err)
+	}
+	return &a, nil
+}
+
+// ListTermsAcceptances 列出用户接受过的所有版本（按接受时间倒序）
+func (d *SQLiteDriver) ListTermsAcceptances(ctx context.Context, userEmail string) ([]*TermsAcceptance, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT user_email, version, remote_ip, accepted_at
+		FROM terms_acceptances WHERE user_email = ?
+		ORDER BY accepted_at DESC, version
+	`, strings.ToLower(userEmail))
+	if err != nil {
+		return nil, fmt.Errorf("查询使用条款接受记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	acceptances := []*TermsAcceptance{}
+	for rows.Next() {
+		var a TermsAcceptance
+		if err := rows.Scan(&a.UserEmail, &a.Version, &a.RemoteIP, &a.AcceptedAt); err != nil {
+			return nil, fmt.Errorf("扫描使用条款接受记录失败: %w", err)
+		}
+		acceptances = append(acceptances, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询使用条款接受记录失败: %w", err)
+	}
+	return acceptances, nil
+}
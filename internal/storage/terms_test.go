@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_TermsAcceptances(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := driver.GetTermsAcceptance(ctx, "alice@example.com", "2024-01"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未接受时应该返回 ErrNotFound: %v", err)
+	}
+
+	first := time.Now().Add(-time.Hour)
+	if err := driver.AcceptTerms(ctx, &TermsAcceptance{UserEmail: "Alice@example.com", Version: "2024-01", RemoteIP: "192.0.2.1", AcceptedAt: first}); err != nil {
+		t.Fatalf("保存接受记录失败: %v", err)
+	}
+	// 同一版本重复接受时保留第一次的记录
+	if err := driver.AcceptTerms(ctx, &TermsAcceptance{UserEmail: "alice@example.com", Version: "2024-01", RemoteIP: "192.0.2.2"}); err != nil {
+		t.Fatalf("重复接受失败: %v", err)
+	}
+	if err := driver.AcceptTerms(ctx, &TermsAcceptance{UserEmail: "alice@example.com", Version: "2024-06", RemoteIP: "192.0.2.3"}); err != nil {
+		t.Fatalf("保存接受记录失败: %v", err)
+	}
+
+	a, err := driver.GetTermsAcceptance(ctx, "ALICE@example.com", "2024-01")
+	if err != nil {
+		t.Fatalf("获取接受记录失败: %v", err)
+	}
+	if a.RemoteIP != "192.0.2.1" || a.AcceptedAt.Sub(first).Abs() > time.Second {
+		t.Errorf("应该保留第一次的接受记录: %+v", a)
+	}
+
+	list, err := driver.ListTermsAcceptances(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("列出接受记录失败: %v", err)
+	}
+	if len(list) != 2 || list[0].Version != "2024-06" || list[1].Version != "2024-01" {
+		t.Errorf("接受记录 = %+v", list)
+	}
+}
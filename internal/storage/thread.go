@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// findThread 新邮件所属的会话：引用的邮件（In-Reply-To、References）或同一邮件的副本已在用户邮箱中时
+// 加入其会话，否则以新邮件的 ID 开始新会话
+func (d *SQLiteDriver) findThread(ctx context.Context, mail *Mail) (string, error) {
+	ids := threadMessageIDs(mail)
+	if len(ids) == 0 {
+		return mail.ID, nil
+	}
+
+	query := `
+		SELECT thread_id FROM mails
+		WHERE user_email = ? AND message_id IN (?` + strings.Repeat(", ?", len(ids)-1) + `) AND thread_id != ''
+		ORDER BY received_at DESC
+		LIMIT 1
+	`
+	args := []any{mail.UserEmail}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	var threadID string
+	err := d.db.QueryRowContext(ctx, query, args...).Scan(&threadID)
+	if err == sql.ErrNoRows {
+		return mail.ID, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询邮件会话失败: %w", err)
+	}
+	return threadID, nil
+}
+
+// mergeThreads 新邮件连接起来的其他会话合并到新邮件的会话：新邮件引用的邮件属于不同会话，
+// 或回复先于原邮件到达（回复的 In-Reply-To 是新邮件）
+func (d *SQLiteDriver) mergeThreads(ctx context.Context, mail *Mail) error {
+	ids := threadMessageIDs(mail)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		UPDATE mails SET thread_id = ?
+		WHERE user_email = ? AND thread_id != ? AND thread_id IN (
+			SELECT thread_id FROM mails
+			WHERE user_email = ? AND (message_id IN (?` + strings.Repeat(", ?", len(ids)-1) + `) OR in_reply_to = ?)
+		)
+	`
+	args := []any{mail.ThreadID, mail.UserEmail, mail.ThreadID, mail.UserEmail}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, mail.MessageID)
+	if _, err := d.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("合并邮件会话失败: %w", err)
+	}
+	return nil
+}
+
+// threadMessageIDs 用于查找会话的 Message-ID：邮件自身、In-Reply-To 和 References（去重）
+func threadMessageIDs(mail *Mail) []string {
+	var ids []string
+	for _, id := range append([]string{mail.MessageID, mail.InReplyTo}, mail.References...) {
+		if id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ListThreads 列出包含 folder 中邮件的会话（folder 为空时列出所有会话），按最新邮件时间倒序；
+// 会话的统计包括所有文件夹中的邮件（如已发送的回复）
+func (d *SQLiteDriver) ListThreads(ctx context.Context, userEmail, folder string, limit, offset int) ([]*Thread, error) {
+	query := `SELECT thread_id FROM mails WHERE user_email = ?`
+	args := []any{userEmail}
+	if folder != "" {
+		query += ` AND thread_id IN (SELECT thread_id FROM mails WHERE user_email = ? AND folder = ?)`
+		args = append(args, userEmail, folder)
+	}
+	query += ` GROUP BY thread_id ORDER BY MAX(received_at) DESC, thread_id LIMIT ? OFFSET ?`
+	args = append(args, limit, offset)
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询会话列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	threads := make([]*Thread, 0)
+	byID := make(map[string]*Thread)
+	for rows.Next() {
+		t := &Thread{Participants: []string{}, Folders: []string{}}
+		if err := rows.Scan(&t.ID); err != nil {
+			return nil, fmt.Errorf("扫描会话失败: %w", err)
+		}
+		threads = append(threads, t)
+		byID[t.ID] = t
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询会话列表失败: %w", err)
+	}
+	rows.Close()
+	if len(threads) == 0 {
+		return threads, nil
+	}
+
+	// 汇总会话中的邮件
+	query = `
+		SELECT thread_id, id, from_addr, subject, flags, COALESCE(has_attachment, 0), folder, received_at
+		FROM mails
+		WHERE user_email = ? AND thread_id IN (?` + strings.Repeat(", ?", len(threads)-1) + `)
+		ORDER BY received_at ASC, rowid ASC
+	`
+	args = []any{userEmail}
+	for _, t := range threads {
+		args = append(args, t.ID)
+	}
+	rows, err = d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询会话邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var threadID, id, from, subject, flags, folder string
+		var hasAttachment int
+		var receivedAt time.Time
+		if err := rows.Scan(&threadID, &id, &from, &subject, &flags, &hasAttachment, &folder, &receivedAt); err != nil {
+			return nil, fmt.Errorf("扫描会话邮件失败: %w", err)
+		}
+		t := byID[threadID]
+		if t.Messages == 0 {
+			t.Subject = subject
+		}
+		t.Messages++
+		if !slices.Contains(splitList(flags), `\Seen`) {
+			t.Unread++
+		}
+		t.HasAttachment = t.HasAttachment || hasAttachment == 1
+		if from != "" && !slices.Contains(t.Participants, from) {
+			t.Participants = append(t.Participants, from)
+		}
+		if !slices.Contains(t.Folders, folder) {
+			t.Folders = append(t.Folders, folder)
+		}
+		t.LatestMailID, t.LatestAt = id, receivedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询会话邮件失败: %w", err)
+	}
+	return threads, nil
+}
+
+// ListThreadMails 列出会话中的所有邮件（包括所有文件夹），按接收时间升序，会话不存在时返回空列表
+func (d *SQLiteDriver) ListThreadMails(ctx context.Context, userEmail, threadID string) ([]*Mail, error) {
+	query := `
+		SELECT id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, COALESCE(has_attachment, 0),
+			received_at, created_at, message_id, in_reply_to, refs, thread_id
+		FROM mails
+		WHERE user_email = ? AND thread_id = ?
+		ORDER BY received_at ASC, rowid ASC
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("查询会话邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	mails := make([]*Mail, 0)
+	for rows.Next() {
+		var mail Mail
+		var toAddrs, ccAddrs, bccAddrs, flags, refs string
+		var uid sql.NullInt64 // UID 可能为 NULL（旧邮件）
+		var hasAttachment int
+		if err := rows.Scan(
+			&mail.ID, &mail.UserEmail, &mail.Folder, &mail.From, &toAddrs, &ccAddrs, &bccAddrs, &mail.Subject,
+			&mail.Size, &flags, &uid, &hasAttachment, &mail.ReceivedAt, &mail.CreatedAt,
+			&mail.MessageID, &mail.InReplyTo, &refs, &mail.ThreadID,
+		); err != nil {
+			return nil, fmt.Errorf("扫描邮件失败: %w", err)
+		}
+		if uid.Valid {
+			mail.UID = uint32(uid.Int64)
+		}
+		mail.HasAttachment = hasAttachment == 1
+		mail.To = splitList(toAddrs)
+		mail.Cc = splitList(ccAddrs)
+		mail.Bcc = splitList(bccAddrs)
+		mail.Flags = splitList(flags)
+		mail.References = splitList(refs)
+		mails = append(mails, &mail)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询会话邮件失败: %w", err)
+	}
+	return mails, nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestThreads(t *testing.T) {
+	ctx := context.Background()
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	store := NewMailStore(nil, driver)
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	deliver := func(id, folder, header string, minutes int, flags ...string) *Mail {
+		t.Helper()
+		mail := &Mail{ID: id, UserEmail: "alice@example.com", Folder: folder, From: id + "@example.com", Subject: id, Flags: flags, ReceivedAt: base.Add(time.Duration(minutes) * time.Minute)}
+		if err := store.Deliver(ctx, mail, []byte(header+"\r\nbody")); err != nil {
+			t.Fatalf("投递 %s 失败: %v", id, err)
+		}
+		return mail
+	}
+
+	// 回复先于原邮件到达，原邮件到达后合并到同一会话
+	reply := deliver("reply", "INBOX", "Message-ID: <b@x>\r\nIn-Reply-To: <a@x>\r\nReferences: <a@x>\r\n", 2)
+	root := deliver("root", "INBOX", "Message-ID: <a@x>\r\n", 1, `\Seen`)
+	if reply.InReplyTo != "a@x" || len(reply.References) != 1 || root.MessageID != "a@x" {
+		t.Fatalf("投递时应该解析会话邮件头: %+v", reply)
+	}
+	sent := deliver("sent", "Sent", "Message-ID: <c@x>\r\nIn-Reply-To: <b@x>\r\nReferences: <a@x> <b@x>\r\n", 3, `\Seen`)
+	other := deliver("other", "INBOX", "Message-ID: <z@x>\r\n", 0)
+	if sent.ThreadID != root.ThreadID || other.ThreadID == root.ThreadID {
+		t.Fatalf("会话不正确: root = %s, sent = %s, other = %s", root.ThreadID, sent.ThreadID, other.ThreadID)
+	}
+	if got, _ := driver.GetMail(ctx, "reply"); got.ThreadID != root.ThreadID || got.MessageID != "b@x" {
+		t.Errorf("先到达的回复应该合并到原邮件的会话: %+v", got)
+	}
+
+	threads, err := driver.ListThreads(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil || len(threads) != 2 {
+		t.Fatalf("列出会话: %d, err = %v", len(threads), err)
+	}
+	th := threads[0]
+	if th.ID != root.ThreadID || th.Subject != "root" || th.Messages != 3 || th.Unread != 1 || th.LatestMailID != "sent" ||
+		len(th.Participants) != 3 || len(th.Folders) != 2 {
+		t.Errorf("会话 = %+v", th)
+	}
+	if threads[1].ID != other.ThreadID || threads[1].Messages != 1 {
+		t.Errorf("会话 = %+v", threads[1])
+	}
+	if threads, _ := driver.ListThreads(ctx, "alice@example.com", "Sent", 10, 0); len(threads) != 1 || threads[0].Messages != 3 {
+		t.Errorf("Sent 中的会话应该包括其他文件夹的邮件: %+v", threads)
+	}
+
+	mails, err := driver.ListThreadMails(ctx, "alice@example.com", root.ThreadID)
+	if err != nil || len(mails) != 3 || mails[0].ID != "root" || mails[2].ID != "sent" {
+		t.Fatalf("会话邮件: %v, err = %v", mails, err)
+	}
+	if len(mails[2].References) != 2 {
+		t.Errorf("References = %v", mails[2].References)
+	}
+	if mails, _ := driver.ListThreadMails(ctx, "bob@example.com", root.ThreadID); len(mails) != 0 {
+		t.Errorf("不应该列出其他用户的会话: %d", len(mails))
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"time"
 )
 
 // SaveTOTPSecret 保存 TOTP 密钥
@@ -16,7 +15,7 @@ func (d *SQLiteDriver) SaveTOTPSecret(ctx context.Context, userEmail string, sec
 			secret = excluded.secret,
 			updated_at = excluded.updated_at
 	`
-	now := time.Now()
+	now := utcNow()
 	_, err := d.db.ExecContext(ctx, query, userEmail, secret, now, now)
 	if err != nil {
 		return fmt.Errorf("保存 TOTP 密钥失败: %w", err)
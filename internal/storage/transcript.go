@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// smtpTranscriptColumns 查询外发会话记录列表的列（不含会话内容）
+const smtpTranscriptColumns = `id, queue_id, message_id, sender, domain, recipients, mx_host, remote_addr, tls_version, tls_cipher, error, started_at, duration_ms`
+
+// SaveSMTPTranscript 保存外发会话记录
+func (d *SQLiteDriver) SaveSMTPTranscript(ctx context.Context, t *SMTPTranscript) error {
+	t.MessageID = normalizeMessageID(t.MessageID)
+	t.StartedAt = t.StartedAt.UTC()
+	query := `
+		INSERT INTO smtp_transcripts (queue_id, message_id, sender, domain, recipients, mx_host, remote_addr,
+			tls_version, tls_cipher, error, transcript, started_at, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := d.db.ExecContext(ctx, query,
+		t.QueueID,
+		t.MessageID,
+		strings.ToLower(t.Sender),
+		strings.ToLower(t.Domain),
+		strings.ToLower(strings.Join(t.Recipients, ",")),
+		t.MXHost,
+		t.RemoteAddr,
+		t.TLSVersion,
+		t.TLSCipher,
+		t.Error,
+		t.Transcript,
+		t.StartedAt,
+		t.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("保存外发会话记录失败: %w", constraintError(err))
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		t.ID = id
+	}
+	return nil
+}
+
+// SearchSMTPTranscripts 查询外发会话记录（按时间倒序，最多 limit 条，不含会话内容）：
+// query 可以是队列 ID、Message-ID、收件人域名或发件人、收件人地址的一部分，为空时返回全部
+func (d *SQLiteDriver) SearchSMTPTranscripts(ctx context.Context, query string, limit int) ([]*SMTPTranscript, error) {
+	sqlQuery := `SELECT ` + smtpTranscriptColumns + ` FROM smtp_transcripts`
+	var args []interface{}
+	if query = strings.TrimSpace(query); query != "" {
+		pattern := "%" + strings.ToLower(query) + "%"
+		sqlQuery += ` WHERE queue_id = ? OR message_id = ? OR domain = ? OR sender LIKE ? OR recipients LIKE ?`
+		args = append(args, query, normalizeMessageID(query), strings.ToLower(query), pattern, pattern)
+	}
+	sqlQuery += ` ORDER BY started_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询外发会话记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var transcripts []*SMTPTranscript
+	for rows.Next() {
+		t, err := scanSMTPTranscript(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描外发会话记录失败: %w", err)
+		}
+		transcripts = append(transcripts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询外发会话记录失败: %w", err)
+	}
+	return transcripts, nil
+}
+
+// GetSMTPTranscript 获取外发会话记录（包含会话内容）
+func (d *SQLiteDriver) GetSMTPTranscript(ctx context.Context, id int64) (*SMTPTranscript, error) {
+	row := d.db.QueryRowContext(ctx, `SELECT `+smtpTranscriptColumns+`, transcript FROM smtp_transcripts WHERE id = ?`, id)
+	var transcript string
+	t, err := scanSMTPTranscript(row, &transcript)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("外发会话记录不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Transcript = transcript
+	return t, nil
+}
+
+// PruneSMTPTranscripts 删除 before 之前的外发会话记录，返回删除的条数
+func (d *SQLiteDriver) PruneSMTPTranscripts(ctx context.Context, before time.Time) (int64, error) {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM smtp_transcripts WHERE started_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("清理外发会话记录失败: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// scanSMTPTranscript 扫描一行外发会话记录，extra 为 smtpTranscriptColumns 之后的其他列
+func scanSMTPTranscript(row rowScanner, extra ...any) (*SMTPTranscript, error) {
+	var t SMTPTranscript
+	var recipients string
+	dest := []any{&t.ID, &t.QueueID, &t.MessageID, &t.Sender, &t.Domain, &recipients, &t.MXHost, &t.RemoteAddr,
+		&t.TLSVersion, &t.TLSCipher, &t.Error, &t.StartedAt, &t.DurationMs}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if recipients != "" {
+		t.Recipients = strings.Split(recipients, ",")
+	}
+	return &t, nil
+}
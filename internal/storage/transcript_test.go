@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSMTPTranscripts(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, tr := range []*SMTPTranscript{
+		{QueueID: "Q1", MessageID: "<m1@example.com>", Sender: "Alice@example.com", Domain: "Gmail.test",
+			Recipients: []string{"carol@gmail.test", "dave@gmail.test"}, MXHost: "mx.gmail.test", RemoteAddr: "192.0.2.1:25",
+			TLSVersion: "TLS 1.3", TLSCipher: "TLS_AES_128_GCM_SHA256", Transcript: "   0.000s S: 220 mx.gmail.test ESMTP\n",
+			StartedAt: now.Add(-time.Hour), DurationMs: 420},
+		{MessageID: "m2@example.com", Sender: "alice@example.com", Domain: "remote.test", Recipients: []string{"erin@remote.test"},
+			Error: "连接 MX 服务器失败", StartedAt: now},
+		{QueueID: "OLD", Domain: "remote.test", StartedAt: now.Add(-100 * 24 * time.Hour)},
+	} {
+		if err := driver.SaveSMTPTranscript(ctx, tr); err != nil {
+			t.Fatalf("保存外发会话记录失败: %v", err)
+		}
+		if tr.ID == 0 {
+			t.Errorf("保存后应该设置 ID: %+v", tr)
+		}
+	}
+
+	all, err := driver.SearchSMTPTranscripts(ctx, "", 100)
+	if err != nil {
+		t.Fatalf("查询外发会话记录失败: %v", err)
+	}
+	if len(all) != 3 || all[0].MessageID != "m2@example.com" {
+		t.Fatalf("应该返回 3 条记录（最新的在前），实际 %d", len(all))
+	}
+	if all[1].Transcript != "" {
+		t.Error("列表不应该包含会话内容")
+	}
+
+	// 按队列 ID、Message-ID、域名或地址的一部分查询
+	for query, want := range map[string]int{"Q1": 1, "<m1@example.com>": 1, "REMOTE.TEST": 2, "dave@": 1, "alice@": 2, "nobody": 0} {
+		transcripts, err := driver.SearchSMTPTranscripts(ctx, query, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(transcripts) != want {
+			t.Errorf("查询 %q 应该返回 %d 条，实际 %d", query, want, len(transcripts))
+		}
+	}
+
+	got, err := driver.GetSMTPTranscript(ctx, all[1].ID)
+	if err != nil {
+		t.Fatalf("获取外发会话记录失败: %v", err)
+	}
+	if got.Domain != "gmail.test" || len(got.Recipients) != 2 || got.TLSVersion != "TLS 1.3" ||
+		got.DurationMs != 420 || got.Transcript == "" {
+		t.Errorf("外发会话记录不正确: %+v", got)
+	}
+	if _, err := driver.GetSMTPTranscript(ctx, 999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的记录应该返回 ErrNotFound, got %v", err)
+	}
+
+	n, err := driver.PruneSMTPTranscripts(ctx, now.Add(-90*24*time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("应该清理 1 条过期记录: %d, %v", n, err)
+	}
+}
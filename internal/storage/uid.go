@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetMailboxUIDs 获取邮箱的 UID 状态，邮箱首次访问时初始化
+func (d *SQLiteDriver) GetMailboxUIDs(ctx context.Context, userEmail, folder string) (*MailboxUIDs, error) {
+	if err := d.ensureMailboxUIDs(ctx, userEmail, folder); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT user_email, folder, uid_validity, uid_next, created_at
+		FROM mailbox_uids
+		WHERE user_email = ? AND folder = ?
+	`
+	var uids MailboxUIDs
+	err := d.db.QueryRowContext(ctx, query, userEmail, folder).Scan(
+		&uids.UserEmail,
+		&uids.Folder,
+		&uids.UIDValidity,
+		&uids.UIDNext,
+		&uids.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("查询邮箱 UID 状态失败: %w", err)
+	}
+	return &uids, nil
+}
+
+// GetNextUID 获取下一个 UID（为指定邮箱，只读取不分配）
+func (d *SQLiteDriver) GetNextUID(ctx context.Context, userEmail, folder string) (uint32, error) {
+	uids, err := d.GetMailboxUIDs(ctx, userEmail, folder)
+	if err != nil {
+		return 0, err
+	}
+	return uids.UIDNext, nil
+}
+
+// AllocateUID 为指定邮箱分配一个新的 UID（单调递增，删除邮件后不会复用）
+func (d *SQLiteDriver) AllocateUID(ctx context.Context, userEmail, folder string) (uint32, error) {
+	if err := d.ensureMailboxUIDs(ctx, userEmail, folder); err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE mailbox_uids SET uid_next = uid_next + 1
+		WHERE user_email = ? AND folder = ?
+		RETURNING uid_next - 1
+	`
+	var uid uint32
+	if err := d.db.QueryRowContext(ctx, query, userEmail, folder).Scan(&uid); err != nil {
+		return 0, fmt.Errorf("分配 UID 失败: %w", err)
+	}
+	return uid, nil
+}
+
+// reserveUID 确保邮箱之后分配的 UID 大于指定 UID（用于写入已带 UID 的邮件）
+func (d *SQLiteDriver) reserveUID(ctx context.Context, userEmail, folder string, uid uint32) error {
+	if err := d.ensureMailboxUIDs(ctx, userEmail, folder); err != nil {
+		return err
+	}
+
+	query := `UPDATE mailbox_uids SET uid_next = MAX(uid_next, ?) WHERE user_email = ? AND folder = ?`
+	if _, err := d.db.ExecContext(ctx, query, int64(uid)+1, userEmail, folder); err != nil {
+		return fmt.Errorf("更新 UIDNEXT 失败: %w", err)
+	}
+	return nil
+}
+
+// ensureMailboxUIDs 初始化邮箱的 UID 状态：为没有 UID 的旧邮件按接收时间补分配 UID，
+// UIDNEXT 从现有最大 UID 之后开始，UIDVALIDITY 使用初始化时间
+func (d *SQLiteDriver) ensureMailboxUIDs(ctx context.Context, userEmail, folder string) error {
+	var exists int
+	err := d.db.QueryRowContext(ctx,
+		`SELECT 1 FROM mailbox_uids WHERE user_email = ? AND folder = ?`, userEmail, folder).Scan(&exists)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("查询邮箱 UID 状态失败: %w", err)
+	}
+
+	backfill := `
+		WITH base AS (
+			SELECT COALESCE(MAX(uid), 0) AS max_uid FROM mails WHERE user_email = ? AND folder = ?
+		), numbered AS (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY received_at, id) AS rn
+			FROM mails
+			WHERE user_email = ? AND folder = ? AND COALESCE(uid, 0) = 0
+		)
+		UPDATE mails SET uid = (SELECT max_uid FROM base) + numbered.rn
+		FROM numbered
+		WHERE mails.id = numbered.id
+	`
+	if _, err := d.db.ExecContext(ctx, backfill, userEmail, folder, userEmail, folder); err != nil {
+		return fmt.Errorf("补分配邮件 UID 失败: %w", err)
+	}
+
+	// UIDVALIDITY 不能为 0，使用秒级时间戳
+	uidValidity := uint32(time.Now().Unix()) // #nosec G115 -- 时间戳截断为 32 位，只要求非零且初始化后不变
+	if uidValidity == 0 {
+		uidValidity = 1
+	}
+	insert := `
+		INSERT OR IGNORE INTO mailbox_uids (user_email, folder, uid_validity, uid_next, created_at)
+		SELECT ?, ?, ?, COALESCE(MAX(uid), 0) + 1, ? FROM mails WHERE user_email = ? AND folder = ?
+	`
+	if _, err := d.db.ExecContext(ctx, insert, userEmail, folder, uidValidity, utcNow(), userEmail, folder); err != nil {
+		return fmt.Errorf("初始化邮箱 UID 状态失败: %w", err)
+	}
+	return nil
+}
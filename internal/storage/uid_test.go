@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_UIDAllocation(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	store := func(id, folder string, uid uint32) *Mail {
+		mail := &Mail{
+			ID:         id,
+			UserEmail:  "alice@example.com",
+			Folder:     folder,
+			From:       "bob@example.com",
+			To:         []string{"alice@example.com"},
+			Size:       10,
+			UID:        uid,
+			ReceivedAt: time.Now(),
+		}
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+		return mail
+	}
+
+	first := store("m1", "INBOX", 0)
+	second := store("m2", "INBOX", 0)
+	other := store("m3", "Sent", 0)
+	if first.UID != 1 || second.UID != 2 || other.UID != 1 {
+		t.Errorf("UID 分配不正确: %d %d %d", first.UID, second.UID, other.UID)
+	}
+
+	before, err := driver.GetMailboxUIDs(ctx, "alice@example.com", "INBOX")
+	if err != nil {
+		t.Fatalf("获取邮箱 UID 状态失败: %v", err)
+	}
+	if before.UIDValidity == 0 || before.UIDNext != 3 {
+		t.Errorf("邮箱 UID 状态不正确: %+v", before)
+	}
+
+	// 删除最大 UID 的邮件后，UID 不会被复用
+	if err := driver.DeleteMail(ctx, "m2"); err != nil {
+		t.Fatalf("删除邮件失败: %v", err)
+	}
+	if third := store("m4", "INBOX", 0); third.UID != 3 {
+		t.Errorf("删除后应该分配 UID 3, got %d", third.UID)
+	}
+
+	// 写入已带 UID 的邮件后，之后分配的 UID 大于它
+	store("m5", "INBOX", 10)
+	if next, _ := driver.GetNextUID(ctx, "alice@example.com", "INBOX"); next != 11 {
+		t.Errorf("UIDNEXT 应该为 11, got %d", next)
+	}
+
+	after, err := driver.GetMailboxUIDs(ctx, "alice@example.com", "INBOX")
+	if err != nil {
+		t.Fatalf("获取邮箱 UID 状态失败: %v", err)
+	}
+	if after.UIDValidity != before.UIDValidity {
+		t.Errorf("UIDVALIDITY 不应该改变: %d -> %d", before.UIDValidity, after.UIDValidity)
+	}
+}
+
+func TestSQLiteDriver_UIDBackfill(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	// 模拟升级前没有 UID 的邮件
+	base := time.Now().Add(-time.Hour)
+	for i, uid := range []any{nil, 5, nil} {
+		_, err := driver.db.ExecContext(ctx, `
+			INSERT INTO mails (id, user_email, folder, from_addr, to_addrs, cc_addrs, bcc_addrs, subject, size, flags, uid, received_at)
+			VALUES (?, 'alice@example.com', 'INBOX', 'bob@example.com', 'alice@example.com', '', '', '', 10, '', ?, ?)
+		`, fmt.Sprintf("legacy-%d", i), uid, base.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("插入邮件失败: %v", err)
+		}
+	}
+
+	uids, err := driver.GetMailboxUIDs(ctx, "alice@example.com", "INBOX")
+	if err != nil {
+		t.Fatalf("获取邮箱 UID 状态失败: %v", err)
+	}
+	if uids.UIDNext != 8 {
+		t.Errorf("UIDNEXT 应该为 8, got %d", uids.UIDNext)
+	}
+
+	mails, err := driver.ListMails(ctx, "alice@example.com", "INBOX", 10, 0)
+	if err != nil {
+		t.Fatalf("列出邮件失败: %v", err)
+	}
+	got := make(map[string]uint32)
+	for _, mail := range mails {
+		got[mail.ID] = mail.UID
+	}
+	if got["legacy-0"] != 6 || got["legacy-1"] != 5 || got["legacy-2"] != 7 {
+		t.Errorf("补分配的 UID 不正确: %v", got)
+	}
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// usageDayFormat 每日用量汇总的日期格式
+const usageDayFormat = "2006-01-02"
+
+// GetFolderUsage 按文件夹统计用户的邮件数和已使用字节数
+func (d *SQLiteDriver) GetFolderUsage(ctx context.Context, userEmail string) ([]*FolderUsage, error) {
+	query := `
+		SELECT folder, COUNT(*), COALESCE(SUM(size), 0)
+		FROM mails
+		WHERE user_email = ?
+		GROUP BY folder
+		ORDER BY folder
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件夹用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []*FolderUsage
+	for rows.Next() {
+		var u FolderUsage
+		if err := rows.Scan(&u.Folder, &u.Count, &u.Used); err != nil {
+			return nil, fmt.Errorf("扫描文件夹用量失败: %w", err)
+		}
+		usage = append(usage, &u)
+	}
+	return usage, rows.Err()
+}
+
+// RecordDailyUsage 记录所有用户在指定日期的用量快照（同一天重复执行会覆盖），返回记录的用户数
+func (d *SQLiteDriver) RecordDailyUsage(ctx context.Context, day time.Time) (int, error) {
+	query := `
+		INSERT INTO usage_daily (user_email, day, used, updated_at)
+		SELECT users.email, ?, COALESCE(SUM(mails.size), 0), ?
+		FROM users
+		LEFT JOIN mails ON users.email = mails.user_email
+		GROUP BY users.email
+		ON CONFLICT(user_email, day) DO UPDATE SET
+			used = excluded.used,
+			updated_at = excluded.updated_at
+	`
+	result, err := d.db.ExecContext(ctx, query, day.Format(usageDayFormat), utcNow())
+	if err != nil {
+		return 0, fmt.Errorf("记录每日用量失败: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("记录每日用量失败: %w", err)
+	}
+	return int(n), nil
+}
+
+// ListDailyUsage 列出用户从指定日期起的每日用量（按日期升序）
+func (d *SQLiteDriver) ListDailyUsage(ctx context.Context, userEmail string, since time.Time) ([]*DailyUsage, error) {
+	query := `
+		SELECT day, used
+		FROM usage_daily
+		WHERE user_email = ? AND day >= ?
+		ORDER BY day
+	`
+	rows, err := d.db.QueryContext(ctx, query, userEmail, since.Format(usageDayFormat))
+	if err != nil {
+		return nil, fmt.Errorf("查询每日用量失败: %w", err)
+	}
+	defer rows.Close()
+
+	var usage []*DailyUsage
+	for rows.Next() {
+		var u DailyUsage
+		if err := rows.Scan(&u.Day, &u.Used); err != nil {
+			return nil, fmt.Errorf("扫描每日用量失败: %w", err)
+		}
+		usage = append(usage, &u)
+	}
+	return usage, rows.Err()
+}
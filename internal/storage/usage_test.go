@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_UsageOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+
+	for _, email := range []string{"test@example.com", "empty@example.com"} {
+		if err := driver.CreateUser(ctx, &User{Email: email, PasswordHash: "test_hash", Active: true}); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+
+	mails := []*Mail{
+		{ID: "m1", UserEmail: "test@example.com", Folder: "INBOX", From: "a@example.com", To: []string{"test@example.com"}, Size: 100},
+		{ID: "m2", UserEmail: "test@example.com", Folder: "INBOX", From: "b@example.com", To: []string{"test@example.com"}, Size: 200},
+		{ID: "m3", UserEmail: "test@example.com", Folder: "Sent", From: "test@example.com", To: []string{"a@example.com"}, Size: 50},
+	}
+	for _, mail := range mails {
+		if err := driver.StoreMail(ctx, mail); err != nil {
+			t.Fatalf("存储邮件失败: %v", err)
+		}
+	}
+
+	// 按文件夹统计
+	folders, err := driver.GetFolderUsage(ctx, "test@example.com")
+	if err != nil {
+		t.Fatalf("查询文件夹用量失败: %v", err)
+	}
+	if len(folders) != 2 || folders[0].Folder != "INBOX" || folders[0].Count != 2 || folders[0].Used != 300 || folders[1].Used != 50 {
+		t.Errorf("文件夹用量不正确: %+v, %+v", folders[0], folders[len(folders)-1])
+	}
+
+	// 每日汇总：同一天重复执行覆盖之前的快照
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if n, err := driver.RecordDailyUsage(ctx, yesterday); err != nil || n != 2 {
+		t.Fatalf("记录每日用量失败: n = %d, err = %v", n, err)
+	}
+	if err := driver.DeleteMail(ctx, "m3"); err != nil {
+		t.Fatalf("删除邮件失败: %v", err)
+	}
+	today := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := driver.RecordDailyUsage(ctx, today); err != nil {
+			t.Fatalf("记录每日用量失败: %v", err)
+		}
+	}
+
+	usage, err := driver.ListDailyUsage(ctx, "test@example.com", today.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("查询每日用量失败: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Fatalf("应该有 2 天的用量, got %d", len(usage))
+	}
+	if usage[0].Day != yesterday.Format("2006-01-02") || usage[0].Used != 350 || usage[1].Used != 300 {
+		t.Errorf("每日用量不正确: %+v, %+v", usage[0], usage[1])
+	}
+
+	// 起始日期之前的记录不返回
+	usage, err = driver.ListDailyUsage(ctx, "test@example.com", today)
+	if err != nil || len(usage) != 1 {
+		t.Errorf("应该只返回今天的用量, got %d, err = %v", len(usage), err)
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// vacationColumns 外出状态查询的列（与 scanVacation 对应）
+const vacationColumns = `user_email, COALESCE(enabled, 0), COALESCE(message, ''), starts_at, ends_at, updated_at`
+
+// scanVacation 扫描一行外出状态
+func scanVacation(row rowScanner) (*Vacation, error) {
+	var vacation Vacation
+	var enabled int
+	var startsAt, endsAt sql.NullTime
+	if err := row.Scan(
+		&vacation.UserEmail,
+		&enabled,
+		&vacation.Message,
+		&startsAt,
+		&endsAt,
+		&vacation.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	vacation.Enabled = enabled == 1
+	if startsAt.Valid {
+		vacation.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		vacation.EndsAt = &endsAt.Time
+	}
+	return &vacation, nil
+}
+
+// Away 用户在指定时间是否处于外出状态
+func (v *Vacation) Away(now time.Time) bool {
+	if !v.Enabled {
+		return false
+	}
+	if v.StartsAt != nil && now.Before(*v.StartsAt) {
+		return false
+	}
+	return v.EndsAt == nil || now.Before(*v.EndsAt)
+}
+
+// GetVacation 获取用户的外出状态
+func (d *SQLiteDriver) GetVacation(ctx context.Context, userEmail string) (*Vacation, error) {
+	query := `SELECT ` + vacationColumns + ` FROM vacations WHERE user_email = ?`
+	vacation, err := scanVacation(d.db.QueryRowContext(ctx, query, userEmail))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("外出状态不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询外出状态失败: %w", err)
+	}
+	return vacation, nil
+}
+
+// SaveVacation 保存用户的外出状态（按用户唯一）
+func (d *SQLiteDriver) SaveVacation(ctx context.Context, vacation *Vacation) error {
+	query := `
+		INSERT INTO vacations (user_email, enabled, message, starts_at, ends_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			enabled = excluded.enabled,
+			message = excluded.message,
+			starts_at = excluded.starts_at,
+			ends_at = excluded.ends_at,
+			updated_at = excluded.updated_at
+	`
+	enabled := 0
+	if vacation.Enabled {
+		enabled = 1
+	}
+	_, err := d.db.ExecContext(ctx, query,
+		vacation.UserEmail,
+		enabled,
+		vacation.Message,
+		vacation.StartsAt,
+		vacation.EndsAt,
+		utcNow(),
+	)
+	if err != nil {
+		return fmt.Errorf("保存外出状态失败: %w", err)
+	}
+	return nil
+}
+
+// ListVacations 列出所有已启用的外出状态（是否在生效时间内由调用方通过 Away 判断）
+func (d *SQLiteDriver) ListVacations(ctx context.Context) ([]*Vacation, error) {
+	query := `SELECT ` + vacationColumns + ` FROM vacations WHERE enabled = 1 ORDER BY user_email`
+	rows, err := d.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("查询外出状态失败: %w", err)
+	}
+	defer rows.Close()
+
+	var vacations []*Vacation
+	for rows.Next() {
+		vacation, err := scanVacation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描外出状态失败: %w", err)
+		}
+		vacations = append(vacations, vacation)
+	}
+
+	return vacations, rows.Err()
+}
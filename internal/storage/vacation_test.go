@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSQLiteDriver_VacationOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &User{Email: email, PasswordHash: "test_hash", Active: true}); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+
+	if _, err := driver.GetVacation(ctx, "alice@example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未设置外出状态时应该返回 ErrNotFound, got %v", err)
+	}
+
+	now := time.Now()
+	endsAt := now.Add(72 * time.Hour)
+	vacation := &Vacation{UserEmail: "alice@example.com", Enabled: true, Message: "出差", EndsAt: &endsAt}
+	if err := driver.SaveVacation(ctx, vacation); err != nil {
+		t.Fatalf("保存外出状态失败: %v", err)
+	}
+	if err := driver.SaveVacation(ctx, &Vacation{UserEmail: "bob@example.com"}); err != nil {
+		t.Fatalf("保存外出状态失败: %v", err)
+	}
+
+	got, err := driver.GetVacation(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("获取外出状态失败: %v", err)
+	}
+	if !got.Enabled || got.Message != "出差" || got.EndsAt == nil || got.StartsAt != nil {
+		t.Errorf("外出状态不正确: %+v", got)
+	}
+	if !got.Away(now) || got.Away(endsAt) {
+		t.Errorf("外出时间判断不正确: %+v", got)
+	}
+
+	// 只列出已启用的外出状态
+	vacations, err := driver.ListVacations(ctx)
+	if err != nil {
+		t.Fatalf("列出外出状态失败: %v", err)
+	}
+	if len(vacations) != 1 || vacations[0].UserEmail != "alice@example.com" {
+		t.Errorf("外出状态列表不正确: %+v", vacations)
+	}
+}
+
+func TestVacation_Away(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		vacation Vacation
+		want     bool
+	}{
+		{"disabled", Vacation{}, false},
+		{"open ended", Vacation{Enabled: true}, true},
+		{"not started", Vacation{Enabled: true, StartsAt: &later}, false},
+		{"started", Vacation{Enabled: true, StartsAt: &earlier, EndsAt: &later}, true},
+		{"ended", Vacation{Enabled: true, EndsAt: &earlier}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.vacation.Away(now); got != tt.want {
+				t.Errorf("Away() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSQLiteDriver_MetadataOperations(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := driver.CreateUser(ctx, &User{Email: "alice@example.com", PasswordHash: "test_hash", Active: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	entries := []*MetadataEntry{
+		{UserEmail: "alice@example.com", Mailbox: "", Name: "/private/comment", Value: "server"},
+		{UserEmail: "alice@example.com", Mailbox: "INBOX", Name: "/private/comment", Value: "inbox"},
+		{UserEmail: "alice@example.com", Mailbox: "INBOX", Name: "/private/comment", Value: "inbox updated"},
+		{UserEmail: "alice@example.com", Mailbox: "INBOX", Name: "/shared/color", Value: "red"},
+	}
+	for _, entry := range entries {
+		if err := driver.SetMetadata(ctx, entry); err != nil {
+			t.Fatalf("设置 METADATA 条目失败: %v", err)
+		}
+	}
+
+	got, err := driver.ListMetadata(ctx, "alice@example.com", "INBOX")
+	if err != nil {
+		t.Fatalf("列出 METADATA 条目失败: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "/private/comment" || got[0].Value != "inbox updated" {
+		t.Errorf("METADATA 条目不正确: %+v", got)
+	}
+
+	if err := driver.DeleteMetadata(ctx, "alice@example.com", "INBOX", "/shared/color"); err != nil {
+		t.Fatalf("删除 METADATA 条目失败: %v", err)
+	}
+	got, err = driver.ListMetadata(ctx, "alice@example.com", "INBOX")
+	if err != nil {
+		t.Fatalf("列出 METADATA 条目失败: %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("删除后应该剩余 1 个条目, got %d", len(got))
+	}
+
+	server, err := driver.ListMetadata(ctx, "alice@example.com", "")
+	if err != nil {
+		t.Fatalf("列出服务器 METADATA 条目失败: %v", err)
+	}
+	if len(server) != 1 || server[0].Value != "server" {
+		t.Errorf("服务器 METADATA 条目不正确: %+v", server)
+	}
+}
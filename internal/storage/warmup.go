@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReserveWarmup 为目标服务商预留当天的外发配额：最多预留 n 封，不超过 limit（limit <= 0 表示不限制），返回实际预留的数量
+func (d *SQLiteDriver) ReserveWarmup(ctx context.Context, day, provider string, n, limit int) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("预留外发配额失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var sent int
+	err = tx.QueryRowContext(ctx, `SELECT sent FROM warmup_counts WHERE day = ? AND provider = ?`, day, provider).Scan(&sent)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("查询外发数量失败: %w", err)
+	}
+
+	granted := n
+	if limit > 0 && sent+n > limit {
+		granted = max(limit-sent, 0)
+	}
+	if granted == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO warmup_counts (day, provider, sent)
+		VALUES (?, ?, ?)
+		ON CONFLICT(day, provider) DO UPDATE SET
+			sent = warmup_counts.sent + excluded.sent
+	`, day, provider, granted)
+	if err != nil {
+		return 0, fmt.Errorf("预留外发配额失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("预留外发配额失败: %w", err)
+	}
+	return granted, nil
+}
+
+// ListWarmupCounts 查询某天各目标服务商的外发数量
+func (d *SQLiteDriver) ListWarmupCounts(ctx context.Context, day string) (map[string]int, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT provider, sent FROM warmup_counts WHERE day = ?`, day)
+	if err != nil {
+		return nil, fmt.Errorf("查询外发数量失败: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var provider string
+		var sent int
+		if err := rows.Scan(&provider, &sent); err != nil {
+			return nil, fmt.Errorf("扫描外发数量失败: %w", err)
+		}
+		counts[provider] = sent
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询外发数量失败: %w", err)
+	}
+	return counts, nil
+}
+
+// DeferMail 保存推迟发送的邮件
+func (d *SQLiteDriver) DeferMail(ctx context.Context, mail *DeferredMail) error {
+	now := utcNow()
+	query := `
+		INSERT INTO deferred_mails (sender, recipients, provider, data, not_before, attempts, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := d.db.ExecContext(ctx, query,
+		mail.Sender,
+		strings.Join(mail.Recipients, ","),
+		mail.Provider,
+		mail.Data,
+		mail.NotBefore.UTC(),
+		mail.Attempts,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存推迟发送的邮件失败: %w", constraintError(err))
+	}
+	if id, err := result.LastInsertId(); err == nil {
+		mail.ID = id
+	}
+	mail.CreatedAt = now
+	return nil
+}
+
+// ListDueDeferredMails 列出已到发送时间的推迟邮件（按保存顺序）
+func (d *SQLiteDriver) ListDueDeferredMails(ctx context.Context, now time.Time, limit int) ([]*DeferredMail, error) {
+	query := `
+		SELECT id, sender, recipients, provider, data, not_before, attempts, created_at
+		FROM deferred_mails
+		WHERE not_before <= ?
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := d.db.QueryContext(ctx, query, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询推迟发送的邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	var mails []*DeferredMail
+	for rows.Next() {
+		var m DeferredMail
+		var recipients string
+		if err := rows.Scan(&m.ID, &m.Sender, &recipients, &m.Provider, &m.Data, &m.NotBefore, &m.Attempts, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("扫描推迟发送的邮件失败: %w", err)
+		}
+		m.Recipients = strings.Split(recipients, ",")
+		mails = append(mails, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询推迟发送的邮件失败: %w", err)
+	}
+	return mails, nil
+}
+
+// RescheduleDeferredMail 配额仍然不足时把推迟邮件改到之后发送
+func (d *SQLiteDriver) RescheduleDeferredMail(ctx context.Context, id int64, notBefore time.Time) error {
+	result, err := d.db.ExecContext(ctx,
+		`UPDATE deferred_mails SET not_before = ?, attempts = attempts + 1 WHERE id = ?`,
+		notBefore.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("更新推迟发送的邮件失败: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("推迟发送的邮件不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteDeferredMail 删除已发出的推迟邮件
+func (d *SQLiteDriver) DeleteDeferredMail(ctx context.Context, id int64) error {
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM deferred_mails WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("删除推迟发送的邮件失败: %w", err)
+	}
+	return nil
+}
+
+// CountDeferredMails 按目标服务商统计等待发送的推迟邮件数
+func (d *SQLiteDriver) CountDeferredMails(ctx context.Context) (map[string]int, error) {
+	rows, err := d.db.QueryContext(ctx, `SELECT provider, COUNT(*) FROM deferred_mails GROUP BY provider`)
+	if err != nil {
+		return nil, fmt.Errorf("统计推迟发送的邮件失败: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var provider string
+		var n int
+		if err := rows.Scan(&provider, &n); err != nil {
+			return nil, fmt.Errorf("扫描推迟发送的邮件失败: %w", err)
+		}
+		counts[provider] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("统计推迟发送的邮件失败: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWarmupCounts(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+
+	// 上限 5：先预留 3，再请求 4 只能得到 2，之后为 0
+	for _, tt := range []struct{ n, want int }{{3, 3}, {4, 2}, {1, 0}} {
+		granted, err := driver.ReserveWarmup(ctx, "2026-03-02", "gmail", tt.n, 5)
+		if err != nil || granted != tt.want {
+			t.Errorf("ReserveWarmup(%d) = %d, want %d, err = %v", tt.n, granted, tt.want, err)
+		}
+	}
+	// 不限制
+	if granted, err := driver.ReserveWarmup(ctx, "2026-03-02", "microsoft", 100, 0); err != nil || granted != 100 {
+		t.Errorf("不限制时应该全部预留: %d, err = %v", granted, err)
+	}
+
+	counts, err := driver.ListWarmupCounts(ctx, "2026-03-02")
+	if err != nil || counts["gmail"] != 5 || counts["microsoft"] != 100 {
+		t.Errorf("外发数量不正确: %v, err = %v", counts, err)
+	}
+	if counts, err := driver.ListWarmupCounts(ctx, "2026-03-03"); err != nil || len(counts) != 0 {
+		t.Errorf("新的一天应该重新计数: %v, err = %v", counts, err)
+	}
+}
+
+func TestDeferredMails(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	ctx := context.Background()
+	tomorrow := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	mail := &DeferredMail{
+		Sender:     "alice@example.com",
+		Recipients: []string{"a@gmail.com", "b@gmail.com"},
+		Provider:   "gmail",
+		Data:       []byte("hello"),
+		NotBefore:  tomorrow,
+	}
+	if err := driver.DeferMail(ctx, mail); err != nil {
+		t.Fatalf("保存推迟邮件失败: %v", err)
+	}
+	if mail.ID == 0 {
+		t.Error("保存后应该设置 ID")
+	}
+
+	if due, err := driver.ListDueDeferredMails(ctx, tomorrow.Add(-time.Minute), 10); err != nil || len(due) != 0 {
+		t.Errorf("未到发送时间的邮件不应该返回: %d, err = %v", len(due), err)
+	}
+	due, err := driver.ListDueDeferredMails(ctx, tomorrow, 10)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("应该返回到期的推迟邮件: %d, err = %v", len(due), err)
+	}
+	if m := due[0]; len(m.Recipients) != 2 || m.Recipients[1] != "b@gmail.com" || string(m.Data) != "hello" {
+		t.Errorf("推迟邮件不正确: %+v", m)
+	}
+
+	if err := driver.RescheduleDeferredMail(ctx, mail.ID, tomorrow.Add(24*time.Hour)); err != nil {
+		t.Fatalf("推迟邮件失败: %v", err)
+	}
+	if due, err := driver.ListDueDeferredMails(ctx, tomorrow, 10); err != nil || len(due) != 0 {
+		t.Errorf("改期后不应该到期: %d, err = %v", len(due), err)
+	}
+	if err := driver.RescheduleDeferredMail(ctx, 999, tomorrow); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的推迟邮件应该返回 ErrNotFound, got %v", err)
+	}
+
+	counts, err := driver.CountDeferredMails(ctx)
+	if err != nil || counts["gmail"] != 1 {
+		t.Errorf("推迟邮件数不正确: %v, err = %v", counts, err)
+	}
+	if err := driver.DeleteDeferredMail(ctx, mail.ID); err != nil {
+		t.Fatalf("删除推迟邮件失败: %v", err)
+	}
+	if counts, err := driver.CountDeferredMails(ctx); err != nil || len(counts) != 0 {
+		t.Errorf("删除后不应该有推迟邮件: %v, err = %v", counts, err)
+	}
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// webAuthnCredentialColumns WebAuthn 凭证查询的列（与 scanWebAuthnCredential 对应）
+const webAuthnCredentialColumns = `id, user_email, credential_id, name, data, created_at, last_used_at`
+
+// scanWebAuthnCredential 扫描一行 WebAuthn 凭证
+func scanWebAuthnCredential(row rowScanner) (*WebAuthnCredential, error) {
+	var cred WebAuthnCredential
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(
+		&cred.ID,
+		&cred.UserEmail,
+		&cred.CredentialID,
+		&cred.Name,
+		&cred.Data,
+		&cred.CreatedAt,
+		&lastUsedAt,
+	); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		cred.LastUsedAt = &lastUsedAt.Time
+	}
+	return &cred, nil
+}
+
+// CreateWebAuthnCredential 保存新注册的 WebAuthn 凭证，成功后设置 cred.ID。
+// 凭证 ID 已存在时（同一认证器重复注册）返回 ErrAlreadyExists
+func (d *SQLiteDriver) CreateWebAuthnCredential(ctx context.Context, cred *WebAuthnCredential) error {
+	now := utcNow()
+	query := `
+		INSERT INTO webauthn_credentials (user_email, credential_id, name, data, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	result, err := d.db.ExecContext(ctx, query,
+		cred.UserEmail,
+		cred.CredentialID,
+		cred.Name,
+		cred.Data,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("保存 WebAuthn 凭证失败: %w", constraintError(err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("获取 WebAuthn 凭证 ID 失败: %w", err)
+	}
+	cred.ID = id
+	cred.CreatedAt = now
+	return nil
+}
+
+// ListWebAuthnCredentials 列出用户的 WebAuthn 凭证（按注册时间排序）
+func (d *SQLiteDriver) ListWebAuthnCredentials(ctx context.Context, userEmail string) ([]*WebAuthnCredential, error) {
+	query := `SELECT ` + webAuthnCredentialColumns + ` FROM webauthn_credentials WHERE user_email = ? ORDER BY id`
+	rows, err := d.db.QueryContext(ctx, query, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("查询 WebAuthn 凭证失败: %w", err)
+	}
+	defer rows.Close()
+
+	var creds []*WebAuthnCredential
+	for rows.Next() {
+		cred, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("扫描 WebAuthn 凭证失败: %w", err)
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+// UpdateWebAuthnCredential 登录成功后更新凭证（签名计数等）和最近使用时间
+func (d *SQLiteDriver) UpdateWebAuthnCredential(ctx context.Context, userEmail string, credentialID, data []byte) error {
+	result, err := d.db.ExecContext(ctx,
+		`UPDATE webauthn_credentials SET data = ?, last_used_at = ? WHERE user_email = ? AND credential_id = ?`,
+		data, utcNow(), userEmail, credentialID)
+	if err != nil {
+		return fmt.Errorf("更新 WebAuthn 凭证失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("WebAuthn 凭证不存在: %w", ErrNotFound)
+	}
+	return nil
+}
+
+// DeleteWebAuthnCredential 删除用户的 WebAuthn 凭证
+func (d *SQLiteDriver) DeleteWebAuthnCredential(ctx context.Context, userEmail string, id int64) error {
+	result, err := d.db.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE id = ? AND user_email = ?`, id, userEmail)
+	if err != nil {
+		return fmt.Errorf("删除 WebAuthn 凭证失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("WebAuthn 凭证不存在: %w", ErrNotFound)
+	}
+	return nil
+}
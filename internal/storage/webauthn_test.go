@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSQLiteDriver_WebAuthnCredentials(t *testing.T) {
+	driver, err := NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	defer driver.Close()
+
+	if err := driver.initSchema(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, email := range []string{"alice@example.com", "bob@example.com"} {
+		if err := driver.CreateUser(ctx, &User{Email: email, PasswordHash: "x", Active: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cred := &WebAuthnCredential{UserEmail: "alice@example.com", CredentialID: []byte{1, 2, 3}, Name: "YubiKey", Data: []byte(`{"signCount":0}`)}
+	if err := driver.CreateWebAuthnCredential(ctx, cred); err != nil {
+		t.Fatalf("保存 WebAuthn 凭证失败: %v", err)
+	}
+	if cred.ID == 0 || cred.CreatedAt.IsZero() {
+		t.Fatalf("保存后应该设置 ID 和创建时间: %+v", cred)
+	}
+
+	// 同一凭证 ID 不能重复注册（包括其他用户）
+	dup := &WebAuthnCredential{UserEmail: "bob@example.com", CredentialID: []byte{1, 2, 3}, Data: []byte(`{}`)}
+	if err := driver.CreateWebAuthnCredential(ctx, dup); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复的凭证 ID 应该返回 ErrAlreadyExists: %v", err)
+	}
+	other := &WebAuthnCredential{UserEmail: "bob@example.com", CredentialID: []byte{4, 5, 6}, Data: []byte(`{}`)}
+	if err := driver.CreateWebAuthnCredential(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := driver.UpdateWebAuthnCredential(ctx, "alice@example.com", []byte{1, 2, 3}, []byte(`{"signCount":5}`)); err != nil {
+		t.Fatalf("更新 WebAuthn 凭证失败: %v", err)
+	}
+	if err := driver.UpdateWebAuthnCredential(ctx, "alice@example.com", []byte{4, 5, 6}, []byte(`{}`)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("更新其他用户的凭证应该返回 ErrNotFound: %v", err)
+	}
+	creds, err := driver.ListWebAuthnCredentials(ctx, "alice@example.com")
+	if err != nil || len(creds) != 1 || string(creds[0].Data) != `{"signCount":5}` || creds[0].LastUsedAt == nil || creds[0].Name != "YubiKey" {
+		t.Fatalf("ListWebAuthnCredentials() = %+v, %v", creds, err)
+	}
+
+	// 不能删除其他用户的凭证
+	if err := driver.DeleteWebAuthnCredential(ctx, "alice@example.com", other.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除其他用户的凭证应该返回 ErrNotFound: %v", err)
+	}
+	if err := driver.DeleteWebAuthnCredential(ctx, "alice@example.com", cred.ID); err != nil {
+		t.Fatal(err)
+	}
+	if creds, _ := driver.ListWebAuthnCredentials(ctx, "alice@example.com"); len(creds) != 0 {
+		t.Errorf("凭证应该已删除: %+v", creds)
+	}
+
+	// 删除用户时一并删除凭证
+	if err := driver.DeleteUser(ctx, "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if creds, _ := driver.ListWebAuthnCredentials(ctx, "bob@example.com"); len(creds) != 0 {
+		t.Errorf("删除用户后凭证应该已删除: %+v", creds)
+	}
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MailOpener 解密邮件文件（零访问存储），未加密的数据原样返回
+type MailOpener interface {
+	Open(userEmail string, data []byte) ([]byte, error)
+}
+
+// MailCipher 邮件文件加密（零访问存储）：Maildir 写入前调用 Seal，未启用加密的用户和已经加密的数据原样返回；
+// Sealed 判断数据是否已加密。只有用户会话使用的 Maildir（见 WithOpener）在读取后调用 Open
+type MailCipher interface {
+	MailOpener
+	Seal(userEmail string, data []byte) ([]byte, error)
+	Sealed(data []byte) bool
+}
+
+// SetCipher 设置邮件文件加密，之后 Maildir 写入的邮件按用户设置加密。
+// 读取加密的邮件时返回 ErrMailboxLocked（管理员、备份和后台任务看不到明文）
+func (m *Maildir) SetCipher(cipher MailCipher) {
+	m.cipher = cipher
+}
+
+// WithOpener 返回用户会话（IMAP、WebMail）读取邮件使用的 Maildir：读取加密的邮件时用 opener 解密。
+// opener 为空时返回 m 本身
+func (m *Maildir) WithOpener(opener MailOpener) *Maildir {
+	if m == nil || opener == nil {
+		return m
+	}
+	session := *m
+	session.opener = opener
+	return &session
+}
+
+// SealExisting 加密用户 Maildir 中已有的未加密邮件（启用零访问存储时调用），返回加密的文件数。
+// 每个文件先写入所在文件夹的 tmp/，再重命名覆盖原文件，文件名（包括标志后缀）不变
+func (m *Maildir) SealExisting(userEmail string) (int, error) {
+	if m.cipher == nil {
+		return 0, nil
+	}
+	sealed := 0
+	err := filepath.WalkDir(m.GetUserMaildir(userEmail), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if base := filepath.Base(dir); base != "cur" && base != "new" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path) // #nosec G304 -- 路径来自用户 Maildir 目录的遍历
+		if err != nil {
+			return fmt.Errorf("读取邮件文件失败: %w", err)
+		}
+		encrypted, err := m.cipher.Seal(userEmail, data)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(encrypted, data) {
+			return nil
+		}
+
+		tmpPath := filepath.Join(filepath.Dir(dir), "tmp", d.Name())
+		// #nosec G301 -- 0755 权限允许组和其他用户读取，这是 Maildir 的标准权限
+		if err := os.MkdirAll(filepath.Dir(tmpPath), 0755); err != nil {
+			return fmt.Errorf("创建 tmp 目录失败: %w", err)
+		}
+		// #nosec G306 -- 0644 权限与 Maildir 中的其他邮件文件一致
+		if err := os.WriteFile(tmpPath, encrypted, 0644); err != nil {
+			return fmt.Errorf("写入加密邮件失败: %w", err)
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("替换邮件文件失败: %w", err)
+		}
+		m.record(JournalStore, path, "")
+		sealed++
+		return nil
+	})
+	if err != nil {
+		return sealed, fmt.Errorf("加密已有邮件失败: %w", err)
+	}
+	return sealed, nil
+}
+
+// SaveZeroAccessKey 保存用户的零访问存储密钥（按用户唯一，更新时保留创建时间）
+func (d *SQLiteDriver) SaveZeroAccessKey(ctx context.Context, key *ZeroAccessKey) error {
+	query := `
+		INSERT INTO zero_access_keys (user_email, public_key, password_wrapped, recovery_wrapped, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_email) DO UPDATE SET
+			public_key = excluded.public_key,
+			password_wrapped = excluded.password_wrapped,
+			recovery_wrapped = excluded.recovery_wrapped,
+			updated_at = excluded.updated_at
+	`
+	now := utcNow()
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = now
+	}
+	key.UpdatedAt = now
+	if _, err := d.db.ExecContext(ctx, query, key.UserEmail, key.PublicKey, key.PasswordWrapped, key.RecoveryWrapped, key.CreatedAt, key.UpdatedAt); err != nil {
+		return fmt.Errorf("保存零访问存储密钥失败: %w", err)
+	}
+	return nil
+}
+
+// GetZeroAccessKey 获取用户的零访问存储密钥，未启用时返回 ErrNotFound
+func (d *SQLiteDriver) GetZeroAccessKey(ctx context.Context, userEmail string) (*ZeroAccessKey, error) {
+	query := `
+		SELECT user_email, public_key, password_wrapped, recovery_wrapped, created_at, updated_at
+		FROM zero_access_keys
+		WHERE user_email = ?
+	`
+	var key ZeroAccessKey
+	err := d.db.QueryRowContext(ctx, query, userEmail).Scan(
+		&key.UserEmail,
+		&key.PublicKey,
+		&key.PasswordWrapped,
+		&key.RecoveryWrapped,
+		&key.CreatedAt,
+		&key.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("零访问存储密钥不存在: %w", ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询零访问存储密钥失败: %w", err)
+	}
+	return &key, nil
+}
@@ -0,0 +1,203 @@
+package storagerpc
+
+//go:generate go run ./gen
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"reflect"
+
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// maxMessageSize 单次调用的最大消息大小
+const maxMessageSize = 64 << 20
+
+// 存储错误的类型
+const (
+	kindNotFound      = "not_found"
+	kindAlreadyExists = "already_exists"
+	kindQuotaExceeded = "quota_exceeded"
+	kindInvalidInput  = "invalid_input"
+	kindMailboxLocked = "mailbox_locked"
+)
+
+// sentinels 错误类型对应的存储错误
+var sentinels = map[string]error{
+	kindNotFound:      storage.ErrNotFound,
+	kindAlreadyExists: storage.ErrAlreadyExists,
+	kindQuotaExceeded: storage.ErrQuotaExceeded,
+	kindInvalidInput:  storage.ErrInvalidInput,
+	kindMailboxLocked: storage.ErrMailboxLocked,
+}
+
+// RemoteDriver 通过存储服务访问存储节点的 storage.Driver
+type RemoteDriver struct {
+	cc *grpc.ClientConn
+}
+
+var _ storage.Driver = (*RemoteDriver)(nil)
+
+// NewRemoteDriver 使用已建立的连接创建 RemoteDriver（Close 时关闭连接）
+func NewRemoteDriver(cc *grpc.ClientConn) *RemoteDriver {
+	return &RemoteDriver{cc: cc}
+}
+
+// Dial 连接存储节点
+func Dial(addr, token string, tlsConfig *tls.Config) (*RemoteDriver, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithPerRPCCredentials(tokenCredentials{token: token, secure: tlsConfig != nil}),
+		grpc.WithDefaultCallOptions(
+			grpc.CallContentSubtype(codecName),
+			grpc.MaxCallRecvMsgSize(maxMessageSize),
+			grpc.MaxCallSendMsgSize(maxMessageSize),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("连接存储节点失败: %w", err)
+	}
+	return NewRemoteDriver(conn), nil
+}
+
+// tokenCredentials 在请求中携带认证令牌
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + t.token}, nil
+}
+
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return t.secure
+}
+
+// Close 关闭到存储节点的连接（不关闭存储节点上的存储）
+func (d *RemoteDriver) Close() error {
+	return d.cc.Close()
+}
+
+// call 调用存储节点上的 Driver 方法：args 为 context 之后的参数，results 为 error 之前的返回值的指针
+func (d *RemoteDriver) call(ctx context.Context, method string, args []any, results []any) error {
+	req := &CallRequest{Method: method}
+	for _, arg := range args {
+		value, err := encodeValue(arg)
+		if err != nil {
+			return err
+		}
+		req.Args = append(req.Args, value)
+	}
+	resp := new(CallResponse)
+	if err := d.cc.Invoke(ctx, "/"+serviceName+"/Call", req, resp, grpc.CallContentSubtype(codecName)); err != nil {
+		return fmt.Errorf("调用存储节点 %s 失败: %w", method, err)
+	}
+
+	if len(resp.Results) != len(results) || len(resp.Args) != len(args) {
+		return fmt.Errorf("存储节点 %s 的响应不完整", method)
+	}
+	for i, result := range results {
+		target := reflect.ValueOf(result).Elem()
+		value, err := decodeValue(resp.Results[i], target.Type())
+		if err != nil {
+			return fmt.Errorf("解析 %s 的返回值失败: %w", method, err)
+		}
+		target.Set(value)
+	}
+	for i, arg := range args {
+		if err := copyBack(arg, resp.Args[i]); err != nil {
+			return fmt.Errorf("解析 %s 的参数失败: %w", method, err)
+		}
+	}
+	if resp.Error != nil {
+		return resp.Error.err()
+	}
+	return nil
+}
+
+// copyBack 把存储节点修改后的指针参数复制回调用方的参数
+func copyBack(arg any, value Value) error {
+	orig := reflect.ValueOf(arg)
+	if value.Nil || !orig.IsValid() || !mutable(orig.Type()) || isNil(arg) {
+		return nil
+	}
+	updated, err := decodeValue(value, orig.Type())
+	if err != nil {
+		return err
+	}
+	if orig.Kind() == reflect.Pointer {
+		orig.Elem().Set(updated.Elem())
+		return nil
+	}
+	for i := 0; i < orig.Len() && i < updated.Len(); i++ {
+		if !orig.Index(i).IsNil() && !updated.Index(i).IsNil() {
+			orig.Index(i).Elem().Set(updated.Index(i).Elem())
+		}
+	}
+	return nil
+}
+
+// mutable 方法可以修改的参数类型：结构体指针和结构体指针的切片
+func mutable(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Pointer && typ.Elem().Kind() == reflect.Struct
+}
+
+// decodeValue 把编码的值解码为 typ 类型
+func decodeValue(value Value, typ reflect.Type) (reflect.Value, error) {
+	ptr := reflect.New(typ)
+	switch {
+	case value.Nil:
+		return ptr.Elem(), nil
+	case value.Empty && typ.Kind() == reflect.Slice:
+		return reflect.MakeSlice(typ, 0, 0), nil
+	case value.Empty && typ.Kind() == reflect.Map:
+		return reflect.MakeMap(typ), nil
+	}
+	if err := (gobCodec{}).Unmarshal(value.Data, ptr.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("解码 %s 失败: %w", typ, err)
+	}
+	return ptr.Elem(), nil
+}
+
+// isNil 判断 nil 指针、切片或映射
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// err 恢复存储错误（errors.Is 与对应的存储错误匹配）
+func (e *Error) err() error {
+	if e.Kind == kindQuotaExceeded && e.Quota != nil {
+		return &storage.QuotaExceededError{UserEmail: e.Quota.UserEmail, Used: e.Quota.Used, Limit: e.Quota.Limit, Size: e.Quota.Size}
+	}
+	return &remoteError{message: e.Message, sentinel: sentinels[e.Kind]}
+}
+
+// remoteError 存储节点返回的错误
+type remoteError struct {
+	message  string
+	sentinel error
+}
+
+func (e *remoteError) Error() string { return e.message }
+
+func (e *remoteError) Unwrap() error { return e.sentinel }
@@ -0,0 +1,171 @@
+// gen 根据 internal/storage/driver.go 中的 Driver 接口生成 RemoteDriver 的方法（driver_gen.go）：
+// 每个方法把参数交给 RemoteDriver.call，在存储节点上调用同名的 Driver 方法。
+//
+// 在 internal/storagerpc 目录中运行 go generate 更新
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+)
+
+const (
+	source = "../storage/driver.go"
+	output = "driver_gen.go"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, source, nil, 0)
+	if err != nil {
+		log.Fatalf("解析 %s 失败: %v", source, err)
+	}
+	driver := findDriver(file)
+	if driver == nil {
+		log.Fatalf("%s 中没有 Driver 接口", source)
+	}
+
+	var body bytes.Buffer
+	for _, field := range driver.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			continue
+		}
+		params := expand(fn.Params, "a")
+		// 没有 context 参数的方法（Close）在 client.go 中实现
+		if len(params) == 0 || typeString(fset, params[0].typ) != "context.Context" {
+			continue
+		}
+		writeMethod(&body, fset, field.Names[0].Name, params, expand(fn.Results, "r"))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen/main.go from internal/storage/driver.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package storagerpc\n\nimport (\n\t\"context\"\n")
+	if bytes.Contains(body.Bytes(), []byte("time.")) {
+		buf.WriteString("\t\"time\"\n")
+	}
+	buf.WriteString("\n\t\"github.com/gomailzero/gmz/internal/storage\"\n)\n")
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("格式化生成的代码失败: %v\n%s", err, buf.String())
+	}
+	if err := os.WriteFile(output, src, 0o644); err != nil {
+		log.Fatalf("写入 %s 失败: %v", output, err)
+	}
+}
+
+// findDriver 查找 Driver 接口
+func findDriver(file *ast.File) *ast.InterfaceType {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if iface, ok := ts.Type.(*ast.InterfaceType); ok && ts.Name.Name == "Driver" {
+				return iface
+			}
+		}
+	}
+	return nil
+}
+
+type param struct {
+	name string
+	typ  ast.Expr
+}
+
+// expand 展开参数列表（合并声明的参数逐个列出，没有名称的参数按位置命名）
+func expand(list *ast.FieldList, prefix string) []param {
+	if list == nil {
+		return nil
+	}
+	var params []param
+	for _, field := range list.List {
+		if len(field.Names) == 0 {
+			params = append(params, param{name: fmt.Sprintf("%s%d", prefix, len(params)), typ: field.Type})
+			continue
+		}
+		for _, name := range field.Names {
+			params = append(params, param{name: name.Name, typ: field.Type})
+		}
+	}
+	return params
+}
+
+// typeString 输出类型表达式，storage 包中声明的类型加上包名
+func typeString(fset *token.FileSet, expr ast.Expr) string {
+	expr = qualify(expr)
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, expr); err != nil {
+		log.Fatal(err)
+	}
+	return buf.String()
+}
+
+// qualify 复制类型表达式，导出的未限定标识符加上 storage 包名
+func qualify(expr ast.Expr) ast.Expr {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if ast.IsExported(e.Name) {
+			return &ast.SelectorExpr{X: ast.NewIdent("storage"), Sel: ast.NewIdent(e.Name)}
+		}
+		return e
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualify(e.X)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: e.Len, Elt: qualify(e.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualify(e.Key), Value: qualify(e.Value)}
+	case *ast.SelectorExpr:
+		return e
+	}
+	log.Fatalf("不支持的参数类型 %T", expr)
+	return nil
+}
+
+func writeMethod(buf *bytes.Buffer, fset *token.FileSet, name string, params, results []param) {
+	var decl, args []string
+	for i, p := range params {
+		decl = append(decl, p.name+" "+typeString(fset, p.typ))
+		if i > 0 {
+			args = append(args, p.name)
+		}
+	}
+	var resultTypes []string
+	for _, r := range results {
+		resultTypes = append(resultTypes, typeString(fset, r.typ))
+	}
+	if len(resultTypes) == 0 || resultTypes[len(resultTypes)-1] != "error" {
+		log.Fatalf("方法 %s 的最后一个返回值不是 error", name)
+	}
+	values := results[:len(results)-1]
+
+	fmt.Fprintf(buf, "\n// %s 调用存储节点的 Driver.%s\n", name, name)
+	fmt.Fprintf(buf, "func (d *RemoteDriver) %s(%s) (%s) {\n", name, strings.Join(decl, ", "), strings.Join(resultTypes, ", "))
+	var outs, rets []string
+	for i, v := range values {
+		fmt.Fprintf(buf, "\tvar r%d %s\n", i, typeString(fset, v.typ))
+		outs = append(outs, fmt.Sprintf("&r%d", i))
+		rets = append(rets, fmt.Sprintf("r%d", i))
+	}
+	call := fmt.Sprintf("d.call(%s, %q, []any{%s}, %s)", params[0].name, name, strings.Join(args, ", "), "[]any{"+strings.Join(outs, ", ")+"}")
+	if len(values) == 0 {
+		fmt.Fprintf(buf, "\treturn %s\n}\n", call)
+		return
+	}
+	fmt.Fprintf(buf, "\terr := %s\n", call)
+	fmt.Fprintf(buf, "\treturn %s, err\n}\n", strings.Join(rets, ", "))
+}
@@ -0,0 +1,145 @@
+package storagerpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Server 存储节点：把前端的调用转发给本地的 Driver
+type Server struct {
+	Storage storage.Driver
+	Token   string // 前端连接使用的认证令牌
+}
+
+// Serve 在 listen 地址上提供存储服务，直到 ctx 取消
+func (s *Server) Serve(ctx context.Context, listen string, tlsConfig *tls.Config) error {
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("监听存储服务端口失败: %w", err)
+	}
+	server := s.NewServer(tlsConfig)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	logger.Info().Str("listen", listen).Bool("tls", tlsConfig != nil).Msg("存储服务启动")
+	if err := server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("存储服务错误: %w", err)
+	}
+	return nil
+}
+
+// NewServer 创建注册了存储服务和令牌认证的 gRPC 服务器
+func (s *Server) NewServer(tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.authorize(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		// GetMailBody 返回整封邮件，放宽默认的 4MB 限制
+		grpc.MaxRecvMsgSize(maxMessageSize),
+		grpc.MaxSendMsgSize(maxMessageSize),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&ServiceDesc, s)
+	return server
+}
+
+func (s *Server) authorize(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		token := strings.TrimPrefix(value, "Bearer ")
+		if s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "无效的存储服务令牌")
+}
+
+// Call 调用本地 Driver 的方法（只能调用 Driver 接口中的方法，不能关闭存储）
+func (s *Server) Call(ctx context.Context, req *CallRequest) (*CallResponse, error) {
+	driver := reflect.ValueOf(&s.Storage).Elem()
+	method := driver.MethodByName(req.Method)
+	if !method.IsValid() || req.Method == "Close" {
+		return nil, status.Errorf(codes.Unimplemented, "未知的存储方法 %s", req.Method)
+	}
+	typ := method.Type()
+	if typ.NumIn() != len(req.Args)+1 {
+		return nil, status.Errorf(codes.InvalidArgument, "%s 需要 %d 个参数，收到 %d 个", req.Method, typ.NumIn()-1, len(req.Args))
+	}
+
+	in := make([]reflect.Value, typ.NumIn())
+	in[0] = reflect.ValueOf(ctx)
+	for i, arg := range req.Args {
+		value, err := decodeValue(arg, typ.In(i+1))
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%s 的第 %d 个参数: %v", req.Method, i+1, err)
+		}
+		in[i+1] = value
+	}
+	out := method.Call(in)
+
+	resp := &CallResponse{}
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		resp.Error = wireError(err)
+	}
+	for _, value := range out[:len(out)-1] {
+		encoded, err := encodeValue(value.Interface())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%s 的返回值: %v", req.Method, err)
+		}
+		resp.Results = append(resp.Results, encoded)
+	}
+	// 方法可能修改指针参数（设置 ID、创建时间等），返回给前端
+	for i, value := range in[1:] {
+		if !mutable(value.Type()) {
+			resp.Args = append(resp.Args, Value{Nil: true})
+			continue
+		}
+		encoded, err := encodeValue(value.Interface())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%s 的第 %d 个参数: %v", req.Method, i+1, err)
+		}
+		resp.Args = append(resp.Args, encoded)
+	}
+	return resp, nil
+}
+
+// wireError 转换方法返回的错误，保留存储错误的类型
+func wireError(err error) *Error {
+	e := &Error{Message: err.Error()}
+	var quota *storage.QuotaExceededError
+	switch {
+	case errors.As(err, &quota):
+		e.Kind = kindQuotaExceeded
+		e.Quota = &QuotaError{UserEmail: quota.UserEmail, Used: quota.Used, Limit: quota.Limit, Size: quota.Size}
+	default:
+		for kind, sentinel := range sentinels {
+			if errors.Is(err, sentinel) {
+				e.Kind = kind
+				break
+			}
+		}
+	}
+	return e
+}
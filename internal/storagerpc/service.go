@@ -0,0 +1,127 @@
+// Package storagerpc 通过 gRPC 提供存储节点的 storage.Driver：协议前端（SMTP、IMAP、WebMail）
+// 配置 storage.driver: remote 后在单独的进程或容器中运行，用 RemoteDriver 访问存储节点的数据库，
+// 前端可以横向扩展。
+//
+// 邮件文件仍然直接读写 Maildir，前端和存储节点需要挂载同一个 storage.maildir_root（共享存储）。
+//
+// 服务没有使用 protoc 生成代码，服务定义见 ServiceDesc：Call 方法按名称调用
+// Driver 的方法，参数和返回值用 gob 编码（保留 JSON 编码时忽略的字段，如密码哈希）。
+// RemoteDriver 的方法由 gen 根据 Driver 接口生成（go generate）。
+package storagerpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// serviceName gRPC 服务名
+const serviceName = "gmz.storage.v1.Storage"
+
+// Value 一个 gob 编码的参数或返回值（gob 不能编码 nil 指针，也不区分 nil 和空的切片、映射）
+type Value struct {
+	Nil   bool // nil 指针、切片或映射
+	Empty bool // 空的切片或映射
+	Data  []byte
+}
+
+// CallRequest 调用 Driver 的方法（参数不包括 context）
+type CallRequest struct {
+	Method string
+	Args   []Value
+}
+
+// CallResponse 调用结果：Results 为 error 之前的返回值，Args 为调用后的指针参数
+// （Driver 的方法会设置参数中的 ID、创建时间等字段，客户端复制回原来的参数），Error 为方法返回的错误
+type CallResponse struct {
+	Results []Value
+	Args    []Value
+	Error   *Error
+}
+
+// Error 方法返回的错误（Kind 为存储错误的类型，客户端据此恢复 errors.Is 判断）
+type Error struct {
+	Kind    string
+	Message string
+	Quota   *QuotaError // Kind 为 quota_exceeded 时超出配额的详情
+}
+
+// QuotaError storage.QuotaExceededError 的内容
+type QuotaError struct {
+	UserEmail string
+	Used      int64
+	Limit     int64
+	Size      int64
+}
+
+// StorageServer 存储服务（存储节点实现）
+type StorageServer interface {
+	Call(ctx context.Context, req *CallRequest) (*CallResponse, error)
+}
+
+// ServiceDesc 存储服务定义
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*StorageServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Call", Handler: callHandler},
+	},
+}
+
+func callHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(CallRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Call(ctx, req.(*CallRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Call"}, handler)
+}
+
+// codecName gob 编解码器名称
+const codecName = "gob"
+
+// gobCodec gRPC 的 gob 编解码器
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (gobCodec) Name() string { return codecName }
+
+func init() {
+	encoding.RegisterCodec(gobCodec{})
+}
+
+// encodeValue 编码一个参数或返回值
+func encodeValue(v interface{}) (Value, error) {
+	if isNil(v) {
+		return Value{Nil: true}, nil
+	}
+	if rv := reflect.ValueOf(v); (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map) && rv.Len() == 0 {
+		return Value{Empty: true}, nil
+	}
+	data, err := gobCodec{}.Marshal(v)
+	if err != nil {
+		return Value{}, fmt.Errorf("编码 %T 失败: %w", v, err)
+	}
+	return Value{Data: data}, nil
+}
@@ -0,0 +1,145 @@
+package storagerpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testToken = "storage-token"
+
+func newTestRemote(t *testing.T, token string) (*RemoteDriver, *storage.SQLiteDriver) {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// 存储节点（内存连接）
+	lis := bufconn.Listen(1 << 20)
+	server := (&Server{Storage: driver, Token: testToken}).NewServer(nil)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(tokenCredentials{token: token}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := NewRemoteDriver(conn)
+	t.Cleanup(func() { remote.Close() })
+	return remote, driver
+}
+
+func TestRemoteDriver(t *testing.T) {
+	remote, driver := newTestRemote(t, testToken)
+	ctx := context.Background()
+
+	quota := int64(1 << 20)
+	if err := driver.SaveDomainDefaults(ctx, &storage.DomainDefaults{Domain: "example.com", Quota: &quota}); err != nil {
+		t.Fatal(err)
+	}
+	user := &storage.User{Email: "alice@example.com", PasswordHash: "hash", Active: true}
+	if err := remote.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser 失败: %v", err)
+	}
+	// 存储节点设置的字段（域名的默认配额）复制回参数
+	if user.Quota != quota {
+		t.Errorf("CreateUser 后 Quota = %d, want %d", user.Quota, quota)
+	}
+	got, err := remote.GetUser(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetUser 失败: %v", err)
+	}
+	// JSON 编码时忽略的字段也要传输
+	if got.PasswordHash != "hash" || !got.Active {
+		t.Errorf("GetUser = %+v", got)
+	}
+
+	// 存储错误可以用 errors.Is 判断
+	if _, err := remote.GetUser(ctx, "nobody@example.com"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("GetUser 不存在的用户: %v, want ErrNotFound", err)
+	}
+	if err := remote.CreateUser(ctx, &storage.User{Email: "alice@example.com", PasswordHash: "x"}); !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Errorf("重复的 CreateUser: %v, want ErrAlreadyExists", err)
+	}
+
+	cred := &storage.WebAuthnCredential{UserEmail: "alice@example.com", CredentialID: []byte{1, 2, 3}, Name: "key", Data: []byte("{}")}
+	if err := remote.CreateWebAuthnCredential(ctx, cred); err != nil {
+		t.Fatalf("CreateWebAuthnCredential 失败: %v", err)
+	}
+	creds, err := driver.ListWebAuthnCredentials(ctx, "alice@example.com")
+	if err != nil || len(creds) != 1 {
+		t.Fatalf("存储节点上的凭证: %v, %v", creds, err)
+	}
+	if cred.ID == 0 || cred.ID != creds[0].ID || !bytes.Equal(creds[0].CredentialID, []byte{1, 2, 3}) {
+		t.Errorf("凭证 ID = %d, CredentialID = %v", cred.ID, creds[0].CredentialID)
+	}
+
+	// 空列表不是 nil（API 返回 [] 而不是 null）
+	aliases, err := remote.ListAliases(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, _ := driver.ListAliases(ctx, "example.com")
+	if (aliases == nil) != (local == nil) {
+		t.Errorf("ListAliases = %#v, 存储节点返回 %#v", aliases, local)
+	}
+}
+
+func TestRemoteDriverRejectsToken(t *testing.T) {
+	remote, _ := newTestRemote(t, "wrong")
+	if _, err := remote.GetUser(context.Background(), "alice@example.com"); err == nil || errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("错误的令牌: %v, want 认证失败", err)
+	}
+}
+
+func TestDriverTypesEncode(t *testing.T) {
+	// Driver 接口中的所有参数和返回值类型都能用 gob 编码
+	driver := reflect.TypeOf((*storage.Driver)(nil)).Elem()
+	for i := 0; i < driver.NumMethod(); i++ {
+		method := driver.Method(i)
+		var types []reflect.Type
+		for j := 1; j < method.Type.NumIn(); j++ {
+			types = append(types, method.Type.In(j))
+		}
+		for j := 0; j < method.Type.NumOut()-1; j++ {
+			types = append(types, method.Type.Out(j))
+		}
+		for _, typ := range types {
+			if _, err := encodeValue(sample(typ).Interface()); err != nil {
+				t.Errorf("%s: %v", method.Name, err)
+			}
+		}
+	}
+}
+
+// sample 构造 typ 类型的非 nil 值
+func sample(typ reflect.Type) reflect.Value {
+	switch typ.Kind() {
+	case reflect.Pointer:
+		return reflect.New(typ.Elem())
+	case reflect.Slice:
+		return reflect.Append(reflect.MakeSlice(typ, 0, 1), sample(typ.Elem()))
+	case reflect.Map:
+		m := reflect.MakeMap(typ)
+		m.SetMapIndex(reflect.Zero(typ.Key()), sample(typ.Elem()))
+		return m
+	}
+	return reflect.Zero(typ)
+}
@@ -0,0 +1,76 @@
+// Package terms 登录提示和使用条款：登录页面显示管理员配置的提示（如授权使用声明），
+// 配置了使用条款时，用户需要接受当前版本后才会签发登录令牌。
+//
+// 每个用户按版本记录接受时间和来源 IP；管理员修改条款后更新版本，所有用户在下次登录时需要重新接受。
+package terms
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// ErrAcceptanceRequired 用户还没有接受当前版本的使用条款
+var ErrAcceptanceRequired = errors.New("需要接受使用条款")
+
+// Banner 登录前显示的提示和使用条款（未认证的 /api/banner 返回）
+type Banner struct {
+	Notice       string `json:"notice,omitempty"`        // 登录提示（为空时不显示）
+	Terms        string `json:"terms,omitempty"`         // 使用条款全文（为空时不要求接受）
+	TermsVersion string `json:"terms_version,omitempty"` // 使用条款版本，登录时在 accept_terms 中回传表示接受
+	// RequiresAcceptance 登录前是否需要接受使用条款
+	RequiresAcceptance bool `json:"requires_acceptance"`
+}
+
+// Gate 登录提示和使用条款；为空时没有提示，也不要求接受条款
+type Gate struct {
+	storage storage.Driver
+	banner  Banner
+}
+
+// New 创建登录提示和使用条款（terms 为空时不要求接受）
+func New(driver storage.Driver, notice, terms, version string) *Gate {
+	return &Gate{
+		storage: driver,
+		banner: Banner{
+			Notice:             notice,
+			Terms:              terms,
+			TermsVersion:       version,
+			RequiresAcceptance: terms != "",
+		},
+	}
+}
+
+// Banner 返回登录提示和使用条款
+func (g *Gate) Banner() *Banner {
+	if g == nil {
+		return &Banner{}
+	}
+	banner := g.banner
+	return &banner
+}
+
+// Check 签发令牌前检查用户是否已接受当前版本的使用条款；accepted 为登录请求中用户确认接受的版本，
+// 与当前版本一致时记录接受。未接受时返回 ErrAcceptanceRequired
+func (g *Gate) Check(ctx context.Context, userEmail, accepted, remoteIP string) error {
+	if g == nil || !g.banner.RequiresAcceptance {
+		return nil
+	}
+	version := g.banner.TermsVersion
+	_, err := g.storage.GetTermsAcceptance(ctx, userEmail, version)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if accepted != version {
+		return ErrAcceptanceRequired
+	}
+	return g.storage.AcceptTerms(ctx, &storage.TermsAcceptance{
+		UserEmail: userEmail,
+		Version:   version,
+		RemoteIP:  remoteIP,
+	})
+}
@@ -0,0 +1,55 @@
+package terms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func TestCheck(t *testing.T) {
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建 SQLite 驱动失败: %v", err)
+	}
+	t.Cleanup(func() { _ = driver.Close() })
+	ctx := context.Background()
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	gate := New(driver, "仅限授权人员使用", "条款全文", "v1")
+	if banner := gate.Banner(); !banner.RequiresAcceptance || banner.TermsVersion != "v1" || banner.Notice != "仅限授权人员使用" {
+		t.Errorf("Banner() = %+v", banner)
+	}
+
+	if err := gate.Check(ctx, "alice@example.com", "", "192.0.2.1"); !errors.Is(err, ErrAcceptanceRequired) {
+		t.Errorf("未接受条款时应该返回 ErrAcceptanceRequired: %v", err)
+	}
+	if err := gate.Check(ctx, "alice@example.com", "v0", "192.0.2.1"); !errors.Is(err, ErrAcceptanceRequired) {
+		t.Errorf("接受旧版本时应该返回 ErrAcceptanceRequired: %v", err)
+	}
+	if err := gate.Check(ctx, "alice@example.com", "v1", "192.0.2.1"); err != nil {
+		t.Fatalf("接受当前版本失败: %v", err)
+	}
+	// 已接受的用户之后登录不需要再次确认
+	if err := gate.Check(ctx, "Alice@example.com", "", "192.0.2.9"); err != nil {
+		t.Errorf("已接受条款的用户不应该再次要求接受: %v", err)
+	}
+
+	// 条款更新版本后需要重新接受
+	updated := New(driver, "", "新条款", "v2")
+	if err := updated.Check(ctx, "alice@example.com", "", "192.0.2.1"); !errors.Is(err, ErrAcceptanceRequired) {
+		t.Errorf("条款更新后应该要求重新接受: %v", err)
+	}
+
+	// 没有配置条款或为空时不要求接受
+	if err := New(driver, "提示", "", "").Check(ctx, "bob@example.com", "", ""); err != nil {
+		t.Errorf("没有条款时不应该要求接受: %v", err)
+	}
+	var none *Gate
+	if err := none.Check(ctx, "bob@example.com", "", ""); err != nil || none.Banner().RequiresAcceptance {
+		t.Errorf("为空时不应该要求接受: %v", err)
+	}
+}
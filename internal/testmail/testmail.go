@@ -0,0 +1,295 @@
+package testmail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 阶段状态
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// lookupMX MX 查询（测试中替换）
+var lookupMX = net.DefaultResolver.LookupMX
+
+// Stage 投递流程中的一个阶段
+type Stage struct {
+	Name       string `json:"name"`   // build, dkim, queue, route, deliver
+	Status     string `json:"status"` // ok, failed, skipped
+	Detail     string `json:"detail,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report 测试邮件投递报告
+type Report struct {
+	MessageID string   `json:"message_id"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Route     string   `json:"route"` // local, relay, mx
+	Success   bool     `json:"success"`
+	Stages    []*Stage `json:"stages"`
+}
+
+// Sender 测试邮件发送器，走与 WebMail 外发相同的流程（DKIM 签名、本地投递或中继/MX）
+type Sender struct {
+	Domain    string
+	Storage   storage.Driver
+	Maildir   *storage.Maildir
+	SMTP      *config.SMTPConfig
+	DKIM      *antispam.DKIMSigners // DKIM 签名器，按发件域名选择（可选）
+	ClientTLS *tls.Config
+	Signer    smtpclient.SenderSigner // 信封发件人签名（可选，与正式外发一致）
+}
+
+// Send 发送测试邮件并报告每个阶段的结果，from 为空时使用 postmaster@<主域名>
+func (s *Sender) Send(ctx context.Context, from, to string) *Report {
+	if from == "" {
+		from = "postmaster@" + s.Domain
+	}
+	report := &Report{From: from, To: to}
+
+	// 构建邮件
+	headers, body, err := s.build(report)
+	if !report.run("build", func() (string, error) {
+		return report.MessageID, err
+	}) {
+		return report
+	}
+
+	// DKIM 签名
+	signer := s.DKIM.For(from)
+	if signer == nil {
+		report.skip("dkim", "未启用 DKIM")
+	} else {
+		report.run("dkim", func() (string, error) {
+			signature, err := signer.Sign(headers, body)
+			if err != nil {
+				return "", err
+			}
+			headers["DKIM-Signature"] = signature
+			return "已签名", nil
+		})
+	}
+	data := encode(headers, body)
+
+	// 外发为同步投递，没有持久化队列
+	report.skip("queue", "同步投递，未经过队列")
+
+	// 选择路由
+	var user *storage.User
+	var mxHost string
+	if !report.run("route", func() (string, error) {
+		user, err = s.findLocalUser(ctx, to)
+		if err != nil {
+			return "", err
+		}
+		if user != nil {
+			report.Route = "local"
+			return "本地用户 " + user.Email, nil
+		}
+		if s.SMTP != nil && s.SMTP.Relay.Enabled {
+			report.Route = "relay"
+			return fmt.Sprintf("中继 %s:%d", s.SMTP.Relay.Host, s.SMTP.Relay.Port), nil
+		}
+		report.Route = "mx"
+		mxHost, err = s.resolveMX(ctx, to)
+		if err != nil {
+			return "", err
+		}
+		return "MX " + mxHost, nil
+	}) {
+		return report
+	}
+
+	// 投递
+	envelopeFrom := from
+	if s.Signer != nil {
+		envelopeFrom = s.Signer.Sign(from)
+	}
+	report.Success = report.run("deliver", func() (string, error) {
+		switch report.Route {
+		case "local":
+			return s.deliverLocal(ctx, user, report, data)
+		case "relay":
+			return "中继已接收", s.client().SendMailViaRelay(ctx, &s.SMTP.Relay, envelopeFrom, []string{to}, data)
+		default:
+			return mxHost + " 已接收", s.client().SendMail(ctx, envelopeFrom, []string{to}, data)
+		}
+	})
+
+	logger.InfoCtx(ctx).
+		Str("from", from).
+		Str("to", to).
+		Str("route", report.Route).
+		Bool("success", report.Success).
+		Msg("测试邮件投递完成")
+	return report
+}
+
+// build 构建测试邮件头和正文
+func (s *Sender) build(report *Report) (map[string]string, []byte, error) {
+	if !strings.Contains(report.To, "@") {
+		return nil, nil, fmt.Errorf("无效的收件人地址: %q", report.To)
+	}
+
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, nil, fmt.Errorf("生成 Message-ID 失败: %w", err)
+	}
+	domain := report.From[strings.LastIndex(report.From, "@")+1:]
+	report.MessageID = fmt.Sprintf("<%d.%s@%s>", time.Now().Unix(), hex.EncodeToString(token), domain)
+
+	now := time.Now()
+	headers := map[string]string{
+		"From":         report.From,
+		"To":           report.To,
+		"Subject":      "GoMailZero 测试邮件",
+		"Date":         now.Format(time.RFC1123Z),
+		"Message-ID":   report.MessageID,
+		"MIME-Version": "1.0",
+		"Content-Type": "text/plain; charset=UTF-8",
+		"X-GMZ-Test":   "1",
+	}
+	body := fmt.Sprintf("这是一封由 GoMailZero 生成的测试邮件，用于验证投递流程。\r\n\r\n发送时间: %s\r\nMessage-ID: %s\r\n",
+		now.Format(time.RFC3339), report.MessageID)
+	return headers, []byte(body), nil
+}
+
+// findLocalUser 查找本地收件人（支持别名），非本地收件人返回 nil
+func (s *Sender) findLocalUser(ctx context.Context, recipient string) (*storage.User, error) {
+	if s.Storage == nil {
+		return nil, nil
+	}
+
+	user, err := s.Storage.GetUser(ctx, recipient)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+
+	alias, err := s.Storage.GetAlias(ctx, recipient)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !alias.Active(time.Now()) {
+		return nil, nil
+	}
+	user, err = s.Storage.GetUser(ctx, alias.To)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// resolveMX 查询收件人域名优先级最高的 MX 主机
+func (s *Sender) resolveMX(ctx context.Context, recipient string) (string, error) {
+	domain := recipient[strings.LastIndex(recipient, "@")+1:]
+	records, err := lookupMX(ctx, domain)
+	if err != nil {
+		return "", fmt.Errorf("查找 %s 的 MX 记录失败: %w", domain, err)
+	}
+	if len(records) == 0 {
+		return "", fmt.Errorf("域名 %s 没有 MX 记录", domain)
+	}
+	return strings.TrimSuffix(records[0].Host, "."), nil
+}
+
+// deliverLocal 投递到本地用户收件箱
+func (s *Sender) deliverLocal(ctx context.Context, user *storage.User, report *Report, data []byte) (string, error) {
+	if s.Maildir == nil {
+		return "", fmt.Errorf("Maildir 未配置，无法投递本地邮件")
+	}
+	now := time.Now()
+	if err := storage.NewMailStore(s.Maildir, s.Storage).Deliver(ctx, &storage.Mail{
+		UserEmail:  user.Email,
+		Folder:     "INBOX",
+		From:       report.From,
+		To:         []string{report.To},
+		Subject:    "GoMailZero 测试邮件",
+		Size:       int64(len(data)),
+		Flags:      []string{"\\Recent"},
+		ReceivedAt: now,
+		CreatedAt:  now,
+	}, data); err != nil {
+		return "", err
+	}
+	return "已投递到 " + user.Email + " 的收件箱", nil
+}
+
+// client 创建外发 SMTP 客户端
+func (s *Sender) client() *smtpclient.Client {
+	hostname := ""
+	if s.SMTP != nil {
+		hostname = s.SMTP.Hostname
+	}
+	return smtpclient.NewClientWithTLS(hostname, s.ClientTLS)
+}
+
+// run 执行一个阶段并记录结果，返回是否成功
+func (r *Report) run(name string, fn func() (string, error)) bool {
+	start := time.Now()
+	detail, err := fn()
+	stage := &Stage{
+		Name:       name,
+		Status:     StatusOK,
+		Detail:     detail,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		stage.Status = StatusFailed
+		stage.Detail = err.Error()
+	}
+	r.Stages = append(r.Stages, stage)
+	return err == nil
+}
+
+// skip 记录跳过的阶段
+func (r *Report) skip(name, detail string) {
+	r.Stages = append(r.Stages, &Stage{Name: name, Status: StatusSkipped, Detail: detail})
+}
+
+// encode 按固定顺序写出邮件头（DKIM-Signature 在最前），再写正文
+func encode(headers map[string]string, body []byte) []byte {
+	var buf bytes.Buffer
+	if signature, ok := headers["DKIM-Signature"]; ok {
+		fmt.Fprintf(&buf, "DKIM-Signature: %s\r\n", signature)
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		if key != "DKIM-Signature" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, headers[key])
+	}
+
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
@@ -0,0 +1,99 @@
+package testmail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func newTestSender(t *testing.T) *Sender {
+	t.Helper()
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatalf("创建 Maildir 失败: %v", err)
+	}
+
+	return &Sender{
+		Domain:  "example.com",
+		Storage: driver,
+		Maildir: maildir,
+		SMTP:    &config.SMTPConfig{},
+	}
+}
+
+func stageStatus(report *Report, name string) string {
+	for _, stage := range report.Stages {
+		if stage.Name == name {
+			return stage.Status
+		}
+	}
+	return ""
+}
+
+func TestSendLocal(t *testing.T) {
+	sender := newTestSender(t)
+	ctx := context.Background()
+
+	if err := sender.Storage.CreateUser(ctx, &storage.User{Email: "admin@example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	report := sender.Send(ctx, "", "admin@example.com")
+	if !report.Success {
+		t.Fatalf("本地投递应该成功: %+v", report.Stages)
+	}
+	if report.From != "postmaster@example.com" || report.Route != "local" {
+		t.Errorf("From = %q, Route = %q", report.From, report.Route)
+	}
+	if stageStatus(report, "dkim") != StatusSkipped {
+		t.Error("未配置 DKIM 时应该跳过签名")
+	}
+
+	mails, err := sender.Storage.ListMails(ctx, "admin@example.com", "INBOX", 10, 0)
+	if err != nil || len(mails) != 1 {
+		t.Errorf("收件箱应该有 1 封邮件, got %d, err = %v", len(mails), err)
+	}
+}
+
+func TestSendMXLookupFailed(t *testing.T) {
+	sender := newTestSender(t)
+
+	lookupMX = func(ctx context.Context, name string) ([]*net.MX, error) {
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() { lookupMX = net.DefaultResolver.LookupMX })
+
+	report := sender.Send(context.Background(), "", "user@unknown.invalid")
+	if report.Success {
+		t.Fatal("MX 查询失败时不应该报告成功")
+	}
+	if report.Route != "mx" || stageStatus(report, "route") != StatusFailed {
+		t.Errorf("route 阶段应该失败: %+v", report.Stages)
+	}
+	if stageStatus(report, "deliver") != "" {
+		t.Error("路由失败后不应该执行投递")
+	}
+}
+
+func TestSendInvalidRecipient(t *testing.T) {
+	sender := newTestSender(t)
+
+	report := sender.Send(context.Background(), "", "not-an-address")
+	if report.Success || stageStatus(report, "build") != StatusFailed {
+		t.Errorf("无效收件人应该在 build 阶段失败: %+v", report.Stages)
+	}
+}
@@ -0,0 +1,179 @@
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// acmeALPNProto ACME TLS-ALPN-01 验证使用的 ALPN 协议（RFC 8737）
+const acmeALPNProto = "acme-tls/1"
+
+// CertificateSource 按 ClientHello 提供证书（ACME 管理器实现此接口，包括 TLS-ALPN-01 验证证书）
+type CertificateSource interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+}
+
+// CertStore 按 SNI 主机名选择证书，供 SMTP、IMAP、HTTP 监听共享
+type CertStore struct {
+	mu           sync.RWMutex
+	certificates map[string]*tls.Certificate // 主机名（小写，支持 *.example.com）-> 证书
+	acmeHosts    map[string]bool             // 由 ACME 管理的主机名
+	acme         CertificateSource
+	fallback     *tls.Certificate // 未匹配到主机名时使用的默认证书
+	files        []*certFiles     // 从文件加载的证书（用于热更新）
+	reloadMu     sync.Mutex       // 串行化 Reload
+}
+
+// NewCertStore 创建证书存储并加载配置中的默认证书和 SNI 证书
+func NewCertStore(cfg *config.TLSConfig) (*CertStore, error) {
+	s := &CertStore{
+		certificates: make(map[string]*tls.Certificate),
+		acmeHosts:    make(map[string]bool),
+	}
+
+	if cfg.CertFile != "" && cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载证书失败: %w", err)
+		}
+		s.fallback = &cert
+		s.files = append(s.files, newCertFiles("", cfg.CertFile, cfg.KeyFile))
+		logger.Info().
+			Str("cert_file", cfg.CertFile).
+			Str("key_file", cfg.KeyFile).
+			Msg("加载 TLS 证书")
+	}
+
+	for _, sni := range cfg.SNI {
+		if sni.ACME {
+			s.acmeHosts[normalizeHostname(sni.Hostname)] = true
+			continue
+		}
+
+		cert, err := tls.LoadX509KeyPair(sni.CertFile, sni.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载 %s 的证书失败: %w", sni.Hostname, err)
+		}
+		s.AddCertificate(sni.Hostname, &cert)
+		s.files = append(s.files, newCertFiles(sni.Hostname, sni.CertFile, sni.KeyFile))
+		logger.Info().
+			Str("hostname", sni.Hostname).
+			Str("cert_file", sni.CertFile).
+			Msg("加载 SNI 证书")
+	}
+
+	return s, nil
+}
+
+// AddCertificate 为主机名设置证书（覆盖已有证书）
+func (s *CertStore) AddCertificate(hostname string, cert *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certificates[normalizeHostname(hostname)] = cert
+}
+
+// SetDefault 设置默认证书
+func (s *CertStore) SetDefault(cert *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fallback = cert
+}
+
+// SetACME 设置 ACME 证书来源，hostnames 为额外交给 ACME 管理的主机名
+func (s *CertStore) SetACME(source CertificateSource, hostnames []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acme = source
+	for _, hostname := range hostnames {
+		s.acmeHosts[normalizeHostname(hostname)] = true
+	}
+}
+
+// ACMEHostnames 返回需要通过 ACME 申请证书的主机名
+func (s *CertStore) ACMEHostnames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hostnames := make([]string, 0, len(s.acmeHosts))
+	for hostname := range s.acmeHosts {
+		hostnames = append(hostnames, hostname)
+	}
+	return hostnames
+}
+
+// Empty 是否没有任何可用证书
+func (s *CertStore) Empty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.fallback == nil && len(s.certificates) == 0 && len(s.acmeHosts) == 0
+}
+
+// GetCertificate 按 SNI 主机名选择证书（实现 tls.Config.GetCertificate）
+// 匹配顺序：精确主机名、通配符、ACME 主机名、默认证书
+func (s *CertStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	hostname := normalizeHostname(hello.ServerName)
+
+	s.mu.RLock()
+	cert, ok := s.certificates[hostname]
+	if !ok {
+		if i := strings.IndexByte(hostname, '.'); i > 0 {
+			cert, ok = s.certificates["*"+hostname[i:]]
+		}
+	}
+	acmeSource := s.acme
+	isACMEHost := s.acmeHosts[hostname]
+	if i := strings.IndexByte(hostname, '.'); !isACMEHost && i > 0 {
+		isACMEHost = s.acmeHosts["*"+hostname[i:]]
+	}
+	fallback := s.fallback
+	s.mu.RUnlock()
+
+	if ok {
+		return cert, nil
+	}
+
+	if isACMEHost && acmeSource != nil {
+		acmeCert, err := acmeSource.GetCertificate(hello)
+		if err == nil {
+			return acmeCert, nil
+		}
+		logger.Warn().Err(err).Str("hostname", hostname).Msg("获取 ACME 证书失败，使用默认证书")
+	}
+
+	if fallback != nil {
+		return fallback, nil
+	}
+
+	return nil, fmt.Errorf("没有可用于 %q 的证书", hello.ServerName)
+}
+
+// GetConfigForClient 处理 ACME TLS-ALPN-01 验证连接（实现 tls.Config.GetConfigForClient）
+// 验证连接只协商 acme-tls/1 协议，由 ACME 来源返回验证证书；其他连接使用原配置
+func (s *CertStore) GetConfigForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	if !slices.Contains(hello.SupportedProtos, acmeALPNProto) {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	acmeSource := s.acme
+	s.mu.RUnlock()
+	if acmeSource == nil {
+		return nil, nil
+	}
+
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{acmeALPNProto},
+		GetCertificate: acmeSource.GetCertificate,
+	}, nil
+}
+
+// normalizeHostname 统一主机名格式（小写、去除末尾的点）
+func normalizeHostname(hostname string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+}
@@ -0,0 +1,113 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/config"
+)
+
+func newTestCertificate(t *testing.T, hostname string) *tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: hostname},
+		DNSNames:     []string{hostname},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCertStoreGetCertificate(t *testing.T) {
+	store, err := NewCertStore(&config.TLSConfig{})
+	if err != nil {
+		t.Fatalf("NewCertStore() 失败: %v", err)
+	}
+
+	certA := newTestCertificate(t, "mail.a.com")
+	certB := newTestCertificate(t, "*.b.com")
+	fallback := newTestCertificate(t, "default")
+	store.AddCertificate("Mail.A.com", certA)
+	store.AddCertificate("*.b.com", certB)
+
+	tests := []struct {
+		name       string
+		serverName string
+		want       *tls.Certificate
+		wantError  bool
+	}{
+		{name: "exact match", serverName: "mail.a.com", want: certA},
+		{name: "case insensitive", serverName: "MAIL.A.COM.", want: certA},
+		{name: "wildcard match", serverName: "mail.b.com", want: certB},
+		{name: "unknown without default", serverName: "mail.c.com", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+			if (err != nil) != tt.wantError {
+				t.Fatalf("GetCertificate() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("GetCertificate(%q) 返回了错误的证书", tt.serverName)
+			}
+		})
+	}
+
+	store.SetDefault(fallback)
+	got, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "mail.c.com"})
+	if err != nil || got != fallback {
+		t.Errorf("未匹配的主机名应该使用默认证书, err = %v", err)
+	}
+}
+
+// staticSource 总是返回同一个证书的 ACME 来源
+type staticSource struct{ cert *tls.Certificate }
+
+func (s staticSource) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return s.cert, nil
+}
+
+func TestCertStoreACME(t *testing.T) {
+	store, err := NewCertStore(&config.TLSConfig{SNI: []config.SNICertConfig{{Hostname: "*.c.com", ACME: true}}})
+	if err != nil {
+		t.Fatalf("NewCertStore() 失败: %v", err)
+	}
+	acmeCert := newTestCertificate(t, "*.c.com")
+
+	challenge := &tls.ClientHelloInfo{ServerName: "mail.c.com", SupportedProtos: []string{acmeALPNProto}}
+	if cfg, err := store.GetConfigForClient(challenge); err != nil || cfg != nil {
+		t.Errorf("未设置 ACME 来源时应该使用原配置: %v, %v", cfg, err)
+	}
+
+	store.SetACME(staticSource{acmeCert}, nil)
+	if got, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "mail.c.com"}); err != nil || got != acmeCert {
+		t.Errorf("通配符 ACME 主机名应该使用 ACME 证书, err = %v", err)
+	}
+
+	// TLS-ALPN-01 验证连接只协商 acme-tls/1
+	cfg, err := store.GetConfigForClient(challenge)
+	if err != nil || cfg == nil || len(cfg.NextProtos) != 1 || cfg.NextProtos[0] != acmeALPNProto {
+		t.Fatalf("验证连接应该使用 acme-tls/1 配置: %v, %v", cfg, err)
+	}
+	if cfg, _ := store.GetConfigForClient(&tls.ClientHelloInfo{ServerName: "mail.c.com", SupportedProtos: []string{"imap"}}); cfg != nil {
+		t.Error("普通连接应该使用原配置")
+	}
+}
@@ -8,72 +8,191 @@ import (
 	"github.com/gomailzero/gmz/internal/logger"
 )
 
+// defaultCipherSuites TLS 1.2 默认加密套件（TLS 1.3 套件由 Go 自动选择）
+var defaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+}
+
+// ConnectionObserver 记录每个连接协商出的 TLS 参数（用于策略审计）
+type ConnectionObserver interface {
+	ObserveTLSConnection(protocol string, state tls.ConnectionState)
+}
+
 // LoadTLSConfig 加载 TLS 配置
-func LoadTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+// 证书通过 store 的 GetCertificate 按 SNI 选择；store 为空时根据配置创建
+func LoadTLSConfig(cfg *config.TLSConfig, store *CertStore) (*tls.Config, error) {
 	if !cfg.Enabled {
 		return nil, nil
 	}
 
+	if store == nil {
+		var err error
+		store, err = NewCertStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	tlsConfig := &tls.Config{
-		MinVersion:               tls.VersionTLS12,
+		MinVersion:               parseVersion(cfg.MinVersion, tls.VersionTLS12),
 		MaxVersion:               tls.VersionTLS13,
 		PreferServerCipherSuites: true,
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-		},
-	}
-
-	// 设置最低 TLS 版本
-	switch cfg.MinVersion {
-	case "1.3":
-		tlsConfig.MinVersion = tls.VersionTLS13
-	case "1.2":
-		tlsConfig.MinVersion = tls.VersionTLS12
-	default:
-		// 默认使用 TLS 1.2（兼容更多客户端）
-		tlsConfig.MinVersion = tls.VersionTLS12
+		CipherSuites:             defaultCipherSuites,
+		// 会话票据用于 TLS 会话恢复，减少重复握手的开销
+		SessionTicketsDisabled: !cfg.SessionTickets,
+		GetCertificate:         store.GetCertificate,
+		// ACME TLS-ALPN-01 验证连接使用单独的配置
+		GetConfigForClient: store.GetConfigForClient,
+	}
+
+	if cfg.EarlyData {
+		logger.Warn().Msg("当前 TLS 实现不支持 0-RTT early data，已忽略 tls.early_data，仅启用会话恢复")
 	}
 
-	// 如果启用了 ACME，证书将由 ACME 客户端管理
+	// 如果启用了 ACME，证书将由 ACME 管理器通过 store 提供
 	if cfg.ACME.Enabled {
-		// TODO: 从 ACME 客户端获取证书
 		logger.Info().Msg("使用 ACME 证书")
 		return tlsConfig, nil
 	}
 
-	// 加载手动配置的证书
-	if cfg.CertFile != "" && cfg.KeyFile != "" {
-		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
-		if err != nil {
-			return nil, fmt.Errorf("加载证书失败: %w", err)
-		}
+	if store.Empty() {
+		return nil, fmt.Errorf("TLS 已启用但未配置证书")
+	}
 
-		tlsConfig.Certificates = []tls.Certificate{cert}
-		logger.Info().
-			Str("cert_file", cfg.CertFile).
-			Str("key_file", cfg.KeyFile).
-			Msg("加载 TLS 证书")
-		return tlsConfig, nil
+	return tlsConfig, nil
+}
+
+// MailTLSConfig 基于基础配置生成 SMTP/IMAP 使用的 TLS 配置
+func MailTLSConfig(base *tls.Config, cfg *config.TLSConfig) (*tls.Config, error) {
+	if base == nil {
+		return nil, nil
 	}
+	return applyPolicy(base, cfg.Mail, cfg.MinVersion)
+}
 
-	return nil, fmt.Errorf("TLS 已启用但未配置证书")
+// HTTPTLSConfig 基于基础配置生成 WebMail/管理 API 使用的 TLS 配置
+// 未启用 tls.http.enabled 时返回 nil，HTTP 服务继续使用明文（通常由反向代理终止 TLS）
+func HTTPTLSConfig(base *tls.Config, cfg *config.TLSConfig) (*tls.Config, error) {
+	if base == nil || !cfg.HTTP.Enabled {
+		return nil, nil
+	}
+	tlsConfig, err := applyPolicy(base, cfg.HTTP.TLSPolicyConfig, cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+	return tlsConfig, nil
 }
 
-// ReloadCertificate 重新加载证书（用于热更新）
-func ReloadCertificate(tlsConfig *tls.Config, certFile, keyFile string) error {
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// ClientTLSConfig 生成外发连接（MX 投递、中继）使用的 TLS 配置模板
+// 所有外发连接共享同一个会话缓存，对同一服务器的后续连接可以恢复会话
+func ClientTLSConfig(cfg *config.TLSConfig) (*tls.Config, error) {
+	// 外发投递需要兼容对端 MTA，未配置邮件策略时默认 TLS 1.2
+	minVersion := cfg.Mail.MinVersion
+	if minVersion == "" {
+		minVersion = "1.2"
+	}
+
+	cipherSuites, err := parseCipherSuites(cfg.Mail.CipherSuites)
 	if err != nil {
-		return fmt.Errorf("重新加载证书失败: %w", err)
+		return nil, err
 	}
 
-	tlsConfig.Certificates = []tls.Certificate{cert}
-	logger.Info().Msg("TLS 证书已重新加载")
-	return nil
+	tlsConfig := &tls.Config{
+		MinVersion:   parseVersion(minVersion, tls.VersionTLS12),
+		CipherSuites: cipherSuites,
+	}
+	if cfg.SessionTickets {
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(cfg.ClientSessionCacheSize)
+	}
+	return tlsConfig, nil
+}
+
+// WithObserver 返回在每次握手完成后通知 observer 的 TLS 配置副本
+func WithObserver(tlsConfig *tls.Config, protocol string, observer ConnectionObserver) *tls.Config {
+	if tlsConfig == nil || observer == nil {
+		return tlsConfig
+	}
+
+	cloned := tlsConfig.Clone()
+	next := cloned.VerifyConnection
+	cloned.VerifyConnection = func(state tls.ConnectionState) error {
+		if next != nil {
+			if err := next(state); err != nil {
+				return err
+			}
+		}
+		observer.ObserveTLSConnection(protocol, state)
+		return nil
+	}
+	return cloned
+}
+
+// applyPolicy 复制基础配置并应用协议版本和加密套件策略
+func applyPolicy(base *tls.Config, policy config.TLSPolicyConfig, fallbackVersion string) (*tls.Config, error) {
+	tlsConfig := base.Clone()
+
+	version := policy.MinVersion
+	if version == "" {
+		version = fallbackVersion
+	}
+	tlsConfig.MinVersion = parseVersion(version, tls.VersionTLS12)
+
+	if len(policy.CipherSuites) > 0 {
+		cipherSuites, err := parseCipherSuites(policy.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.CipherSuites = cipherSuites
+	}
+
+	return tlsConfig, nil
+}
+
+// parseVersion 解析 TLS 版本字符串
+func parseVersion(version string, fallback uint16) uint16 {
+	switch version {
+	case "1.3":
+		return tls.VersionTLS13
+	case "1.2":
+		return tls.VersionTLS12
+	case "1.1":
+		return tls.VersionTLS11 // #nosec G402 -- 仅在管理员显式配置时使用，兼容老旧客户端
+	case "1.0":
+		return tls.VersionTLS10 // #nosec G402 -- 仅在管理员显式配置时使用，兼容老旧客户端
+	default:
+		return fallback
+	}
+}
+
+// parseCipherSuites 按名称解析加密套件，留空返回默认列表
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return defaultCipherSuites, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("未知的加密套件: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 // GetCertificate 获取证书（用于 ACME）
@@ -0,0 +1,135 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// reloadDelay 证书文件变化后等待的时间（证书和私钥通常先后写入，合并为一次加载）
+var reloadDelay = time.Second
+
+// certFiles 从文件加载的证书
+type certFiles struct {
+	hostname string // 为空表示默认证书
+	certFile string
+	keyFile  string
+	stamp    string // 上次加载时两个文件的修改时间和大小
+}
+
+func newCertFiles(hostname, certFile, keyFile string) *certFiles {
+	return &certFiles{hostname: hostname, certFile: certFile, keyFile: keyFile, stamp: fileStamp(certFile, keyFile)}
+}
+
+// fileStamp 文件的修改时间和大小（跟随符号链接，文件不存在时记为 -）
+func fileStamp(paths ...string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			b.WriteString("-;")
+			continue
+		}
+		fmt.Fprintf(&b, "%d/%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return b.String()
+}
+
+// Reload 重新加载文件已变化的证书，返回更新的证书数
+// 新证书立即对所有使用 GetCertificate 的监听生效；加载失败（如只更新了证书或私钥之一）时继续使用原证书
+func (s *CertStore) Reload() (int, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	var errs []error
+	reloaded := 0
+	for _, f := range s.files {
+		stamp := fileStamp(f.certFile, f.keyFile)
+		if stamp == f.stamp {
+			continue
+		}
+		cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("重新加载证书 %s 失败: %w", f.certFile, err))
+			continue
+		}
+		f.stamp = stamp
+		if f.hostname == "" {
+			s.SetDefault(&cert)
+		} else {
+			s.AddCertificate(f.hostname, &cert)
+		}
+		reloaded++
+		logger.Info().
+			Str("hostname", f.hostname).
+			Str("cert_file", f.certFile).
+			Msg("TLS 证书已重新加载")
+	}
+	return reloaded, errors.Join(errs...)
+}
+
+// Watch 监听证书文件变化并自动重新加载，直到 ctx 取消
+// 监听文件所在目录而不是文件本身，以支持通过重命名或替换符号链接更新证书（如 certbot、Kubernetes Secret）
+func (s *CertStore) Watch(ctx context.Context) error {
+	if len(s.files) == 0 {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建证书文件监听失败: %w", err)
+	}
+	watched := make(map[string]bool)
+	for _, f := range s.files {
+		for _, path := range []string{f.certFile, f.keyFile} {
+			dir := filepath.Dir(path)
+			if watched[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err != nil {
+				_ = watcher.Close()
+				return fmt.Errorf("监听证书目录 %s 失败: %w", dir, err)
+			}
+			watched[dir] = true
+		}
+	}
+
+	go s.watch(ctx, watcher)
+	return nil
+}
+
+// watch 处理文件变化事件，合并短时间内的多次变化后重新加载
+func (s *CertStore) watch(ctx context.Context, watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			pending = time.After(reloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn().Err(err).Msg("监听证书文件出错")
+		case <-pending:
+			pending = nil
+			if _, err := s.Reload(); err != nil {
+				logger.Warn().Err(err).Msg("重新加载 TLS 证书失败，继续使用原证书")
+			}
+		}
+	}
+}
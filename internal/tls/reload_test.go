@@ -0,0 +1,123 @@
+package tls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/config"
+)
+
+// writeCertFiles 生成证书并写入 certFile/keyFile，返回证书 DER
+func writeCertFiles(t *testing.T, certFile, keyFile, hostname string) []byte {
+	t.Helper()
+	certPEM, keyPEM, err := generateSelfSigned([]string{hostname})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Certificate[0]
+}
+
+// servedCertificate GetCertificate 为 serverName 返回的证书 DER
+func servedCertificate(t *testing.T, store *CertStore, serverName string) []byte {
+	t.Helper()
+	cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	if err != nil {
+		t.Fatalf("GetCertificate(%q) 失败: %v", serverName, err)
+	}
+	return cert.Certificate[0]
+}
+
+func TestCertStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	defaultCert, defaultKey := filepath.Join(dir, "default.crt"), filepath.Join(dir, "default.key")
+	sniCert, sniKey := filepath.Join(dir, "a.crt"), filepath.Join(dir, "a.key")
+	writeCertFiles(t, defaultCert, defaultKey, "mail.example.com")
+	oldSNI := writeCertFiles(t, sniCert, sniKey, "mail.a.com")
+
+	store, err := NewCertStore(&config.TLSConfig{
+		CertFile: defaultCert,
+		KeyFile:  defaultKey,
+		SNI:      []config.SNICertConfig{{Hostname: "mail.a.com", CertFile: sniCert, KeyFile: sniKey}},
+	})
+	if err != nil {
+		t.Fatalf("NewCertStore() 失败: %v", err)
+	}
+	if n, err := store.Reload(); n != 0 || err != nil {
+		t.Errorf("文件未变化时 Reload() = %d, %v", n, err)
+	}
+
+	// 只更新了证书、私钥还是旧的：加载失败，继续使用原证书
+	certPEM, _, err := generateSelfSigned([]string{"mail.a.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sniCert, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.Reload(); n != 0 || err == nil {
+		t.Errorf("证书和私钥不匹配时 Reload() = %d, %v", n, err)
+	}
+	if !bytes.Equal(servedCertificate(t, store, "mail.a.com"), oldSNI) {
+		t.Error("加载失败时应该继续使用原证书")
+	}
+
+	// 证书和私钥都更新后生效
+	newSNI := writeCertFiles(t, sniCert, sniKey, "mail.a.com")
+	if n, err := store.Reload(); n != 1 || err != nil {
+		t.Fatalf("Reload() = %d, %v", n, err)
+	}
+	if !bytes.Equal(servedCertificate(t, store, "mail.a.com"), newSNI) {
+		t.Error("重新加载后应该使用新证书")
+	}
+}
+
+func TestCertStoreWatch(t *testing.T) {
+	reloadDelay = 10 * time.Millisecond
+	defer func() { reloadDelay = time.Second }()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	writeCertFiles(t, certFile, keyFile, "mail.example.com")
+	store, err := NewCertStore(&config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("NewCertStore() 失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := store.Watch(ctx); err != nil {
+		t.Fatalf("Watch() 失败: %v", err)
+	}
+
+	// 通过重命名替换文件（certbot 等工具的更新方式）
+	staging := t.TempDir()
+	want := writeCertFiles(t, filepath.Join(staging, "tls.crt"), filepath.Join(staging, "tls.key"), "mail.example.com")
+	for _, name := range []string{"tls.key", "tls.crt"} {
+		if err := os.Rename(filepath.Join(staging, name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !bytes.Equal(servedCertificate(t, store, "mail.example.com"), want) {
+		if time.Now().After(deadline) {
+			t.Fatal("证书文件更新后没有自动重新加载")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
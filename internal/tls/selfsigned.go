@@ -0,0 +1,119 @@
+package tls
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+const (
+	selfSignedCertName = "selfsigned.crt"
+	selfSignedKeyName  = "selfsigned.key"
+	selfSignedValidity = 365 * 24 * time.Hour
+	// selfSignedRenewBefore 距离过期不足该时长时重新生成
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// LoadOrCreateSelfSigned 加载 dir 下的自签名证书，不存在或即将过期时重新生成并保存
+// 仅用于实验/开发环境：客户端需要手动信任该证书
+func LoadOrCreateSelfSigned(dir string, hostnames []string) (*tls.Certificate, error) {
+	certFile := filepath.Join(dir, selfSignedCertName)
+	keyFile := filepath.Join(dir, selfSignedKeyName)
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err == nil && time.Until(leaf.NotAfter) > selfSignedRenewBefore {
+			logger.Warn().Str("cert_file", certFile).Msg("使用自签名证书（仅用于实验环境，客户端需要信任该证书）")
+			return &cert, nil
+		}
+		logger.Info().Str("cert_file", certFile).Msg("自签名证书即将过期，重新生成")
+	}
+
+	certPEM, keyPEM, err := generateSelfSigned(hostnames)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("创建证书目录失败: %w", err)
+	}
+	// #nosec G306 -- 证书文件需要可读权限，0644 是标准权限
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("保存自签名证书失败: %w", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("保存自签名证书私钥失败: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("加载自签名证书失败: %w", err)
+	}
+
+	logger.Warn().
+		Str("cert_file", certFile).
+		Strs("hostnames", hostnames).
+		Msg("已生成自签名证书（仅用于实验环境，客户端需要信任该证书）")
+	return &cert, nil
+}
+
+// generateSelfSigned 生成自签名证书和私钥（PEM）
+func generateSelfSigned(hostnames []string) ([]byte, []byte, error) {
+	// 使用 RSA 以兼容较老的邮件客户端
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成私钥失败: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成证书序列号失败: %w", err)
+	}
+
+	dnsNames := []string{"localhost"}
+	for _, hostname := range hostnames {
+		if hostname != "" && hostname != "localhost" {
+			dnsNames = append(dnsNames, hostname)
+		}
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   dnsNames[len(dnsNames)-1],
+			Organization: []string{"GoMailZero Self-Signed"},
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		DNSNames:              dnsNames,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建证书失败: %w", err)
+	}
+
+	privBytes, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编码私钥失败: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,33 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"testing"
+)
+
+func TestLoadOrCreateSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+
+	cert, err := LoadOrCreateSelfSigned(dir, []string{"mail.example.com"})
+	if err != nil {
+		t.Fatalf("LoadOrCreateSelfSigned() 失败: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("解析证书失败: %v", err)
+	}
+	if err := leaf.VerifyHostname("mail.example.com"); err != nil {
+		t.Errorf("证书应该包含配置的主机名: %v", err)
+	}
+
+	// 再次调用应该复用已保存的证书
+	again, err := LoadOrCreateSelfSigned(dir, []string{"mail.example.com"})
+	if err != nil {
+		t.Fatalf("LoadOrCreateSelfSigned() 失败: %v", err)
+	}
+	if !bytes.Equal(cert.Certificate[0], again.Certificate[0]) {
+		t.Error("重复调用应该复用已保存的自签名证书")
+	}
+}
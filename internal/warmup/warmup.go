@@ -0,0 +1,342 @@
+// Package warmup 外发预热：新 IP 或域名开始外发时，限制每天发往每个目标邮件服务商的数量，
+// 按周逐步提高上限，避免短时间内大量外发损害发件信誉
+//
+// 从 Start 开始的 Weeks 周内，每个服务商的每日上限从 InitialLimit 按周几何增长到 MaxLimit，
+// 预热结束后不再限制。超过当天上限的收件人的邮件保存为推迟邮件，第二天（UTC）起由 Run 在配额允许时发出。
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// maxAttempts 推迟邮件因配额不足或发送失败被推迟的最多次数，超过后放弃
+const maxAttempts = 30
+
+// retryDelay 推迟邮件发送失败后的重试间隔
+const retryDelay = time.Hour
+
+// batchSize 每次检查处理的最多推迟邮件数
+const batchSize = 100
+
+// DefaultProviders 内置的邮件服务商及其域名，其他域名按域名各自计数
+var DefaultProviders = map[string][]string{
+	"gmail":     {"gmail.com", "googlemail.com"},
+	"microsoft": {"outlook.com", "hotmail.com", "live.com", "msn.com"},
+	"yahoo":     {"yahoo.com", "ymail.com", "aol.com"},
+	"apple":     {"icloud.com", "me.com", "mac.com"},
+	"qq":        {"qq.com", "foxmail.com"},
+	"netease":   {"163.com", "126.com", "yeah.net"},
+}
+
+// Sender 外发邮件（发送推迟邮件时使用，不能再经过预热限流）
+type Sender interface {
+	Send(ctx context.Context, from string, to []string, data []byte) error
+}
+
+// Scheduler 外发预热调度
+type Scheduler struct {
+	Storage      storage.Driver
+	Outbound     Sender    // 发送推迟邮件
+	Start        time.Time // 预热开始日期（UTC）
+	Weeks        int       // 预热周数
+	InitialLimit int       // 第一周每个服务商的每日上限
+	MaxLimit     int       // 最后一周每个服务商的每日上限
+
+	providers map[string]string // 域名 -> 服务商
+	now       func() time.Time  // 测试时替换当前时间
+}
+
+// New 创建预热调度，extra 为补充内置列表的服务商及其域名
+func New(driver storage.Driver, outbound Sender, start time.Time, weeks, initialLimit, maxLimit int, extra map[string][]string) *Scheduler {
+	s := &Scheduler{
+		Storage:      driver,
+		Outbound:     outbound,
+		Start:        start.UTC(),
+		Weeks:        weeks,
+		InitialLimit: initialLimit,
+		MaxLimit:     maxLimit,
+		providers:    make(map[string]string),
+	}
+	for _, list := range []map[string][]string{DefaultProviders, extra} {
+		for provider, domains := range list {
+			for _, domain := range domains {
+				s.providers[strings.ToLower(domain)] = provider
+			}
+		}
+	}
+	return s
+}
+
+// Provider 收件人所属的邮件服务商（不在列表中时为收件人域名）
+func (s *Scheduler) Provider(recipient string) string {
+	domain := strings.ToLower(recipient[strings.LastIndex(recipient, "@")+1:])
+	if provider, ok := s.providers[domain]; ok {
+		return provider
+	}
+	return domain
+}
+
+// Week 预热的第几周（从 0 开始，开始日期之前为 0），预热结束后返回 Weeks
+func (s *Scheduler) Week(now time.Time) int {
+	days := int(now.UTC().Sub(s.Start) / (24 * time.Hour))
+	return min(max(days/7, 0), s.Weeks)
+}
+
+// Limit 当天每个服务商的外发上限，预热结束后返回 0（不限制）
+func (s *Scheduler) Limit(now time.Time) int {
+	week := s.Week(now)
+	if week >= s.Weeks {
+		return 0
+	}
+	if s.Weeks == 1 || s.MaxLimit <= s.InitialLimit {
+		return s.InitialLimit
+	}
+	ratio := float64(s.MaxLimit) / float64(s.InitialLimit)
+	return int(math.Round(float64(s.InitialLimit) * math.Pow(ratio, float64(week)/float64(s.Weeks-1))))
+}
+
+// Admit 预留当天的外发配额，返回可以立即发送的收件人；
+// 其余收件人的邮件保存为推迟邮件，第二天起发出（实现 smtpclient.Throttle）
+func (s *Scheduler) Admit(ctx context.Context, from string, to []string, data []byte) ([]string, error) {
+	now := s.currentTime()
+	limit := s.Limit(now)
+	if limit == 0 {
+		return to, nil
+	}
+
+	var allowed []string
+	for _, group := range s.group(to) {
+		granted, err := s.Storage.ReserveWarmup(ctx, day(now), group.provider, len(group.recipients), limit)
+		if err != nil {
+			return nil, err
+		}
+		allowed = append(allowed, group.recipients[:granted]...)
+		if granted == len(group.recipients) {
+			continue
+		}
+
+		deferred := &storage.DeferredMail{
+			Sender:     from,
+			Recipients: group.recipients[granted:],
+			Provider:   group.provider,
+			Data:       data,
+			NotBefore:  nextDay(now),
+		}
+		if err := s.Storage.DeferMail(ctx, deferred); err != nil {
+			return nil, err
+		}
+		logger.InfoCtx(ctx).
+			Str("from", from).
+			Strs("to", deferred.Recipients).
+			Str("provider", group.provider).
+			Int("limit", limit).
+			Msg("超过预热期间的每日外发上限，推迟到第二天发送")
+	}
+	return allowed, nil
+}
+
+// Run 定期发送到期的推迟邮件，直到 ctx 取消
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Release(ctx); err != nil {
+			logger.Warn().Err(err).Msg("发送推迟邮件失败")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Release 在当天配额允许时发送到期的推迟邮件，配额不足的部分推迟到第二天，返回发出的收件人数
+func (s *Scheduler) Release(ctx context.Context) (int, error) {
+	now := s.currentTime()
+	mails, err := s.Storage.ListDueDeferredMails(ctx, now, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, m := range mails {
+		granted := len(m.Recipients)
+		if limit := s.Limit(now); limit > 0 {
+			if granted, err = s.Storage.ReserveWarmup(ctx, day(now), m.Provider, len(m.Recipients), limit); err != nil {
+				return sent, err
+			}
+		}
+		if granted == 0 {
+			s.retry(ctx, m, nextDay(now), "配额不足")
+			continue
+		}
+
+		if err := s.Outbound.Send(ctx, m.Sender, m.Recipients[:granted], m.Data); err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("from", m.Sender).Strs("to", m.Recipients[:granted]).Msg("发送推迟邮件失败")
+			s.retry(ctx, m, now.Add(retryDelay), "发送失败")
+			continue
+		}
+		sent += granted
+
+		// 只发出了部分收件人时，其余收件人作为新的推迟邮件
+		if granted < len(m.Recipients) {
+			rest := &storage.DeferredMail{
+				Sender:     m.Sender,
+				Recipients: m.Recipients[granted:],
+				Provider:   m.Provider,
+				Data:       m.Data,
+				NotBefore:  nextDay(now),
+				Attempts:   m.Attempts + 1,
+			}
+			if err := s.Storage.DeferMail(ctx, rest); err != nil {
+				return sent, err
+			}
+		}
+		if err := s.Storage.DeleteDeferredMail(ctx, m.ID); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// retry 推迟邮件改到 notBefore 之后发送，推迟次数过多时放弃
+func (s *Scheduler) retry(ctx context.Context, m *storage.DeferredMail, notBefore time.Time, reason string) {
+	if m.Attempts+1 >= maxAttempts {
+		logger.ErrorCtx(ctx).Str("from", m.Sender).Strs("to", m.Recipients).Str("reason", reason).
+			Msgf("推迟邮件超过 %d 次仍未发出，已放弃", maxAttempts)
+		if err := s.Storage.DeleteDeferredMail(ctx, m.ID); err != nil {
+			logger.WarnCtx(ctx).Err(err).Int64("id", m.ID).Msg("删除推迟邮件失败")
+		}
+		return
+	}
+	if err := s.Storage.RescheduleDeferredMail(ctx, m.ID, notBefore); err != nil {
+		logger.WarnCtx(ctx).Err(err).Int64("id", m.ID).Msg("推迟邮件失败")
+	}
+}
+
+// ProviderProgress 一个目标服务商当天的外发情况
+type ProviderProgress struct {
+	Provider string `json:"provider"`
+	Sent     int    `json:"sent"`     // 当天已发出
+	Deferred int    `json:"deferred"` // 等待发送的推迟邮件数
+}
+
+// Progress 预热进度
+type Progress struct {
+	Enabled    bool                `json:"enabled"`
+	StartDate  string              `json:"start_date"`
+	Week       int                 `json:"week"` // 当前是第几周（从 1 开始）
+	Weeks      int                 `json:"weeks"`
+	Completed  bool                `json:"completed"`   // 预热已结束，不再限制
+	DailyLimit int                 `json:"daily_limit"` // 当天每个服务商的上限（预热结束后为 0）
+	Day        string              `json:"day"`
+	Providers  []*ProviderProgress `json:"providers"` // 按当天已发出数量降序
+}
+
+// Progress 查询预热进度和当天各服务商的外发数量
+func (s *Scheduler) Progress(ctx context.Context) (*Progress, error) {
+	now := s.currentTime()
+	week := s.Week(now)
+	p := &Progress{
+		Enabled:    true,
+		StartDate:  s.Start.Format(time.DateOnly),
+		Week:       min(week+1, s.Weeks),
+		Weeks:      s.Weeks,
+		Completed:  week >= s.Weeks,
+		DailyLimit: s.Limit(now),
+		Day:        day(now),
+	}
+
+	counts, err := s.Storage.ListWarmupCounts(ctx, p.Day)
+	if err != nil {
+		return nil, err
+	}
+	deferred, err := s.Storage.CountDeferredMails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byProvider := make(map[string]*ProviderProgress)
+	get := func(provider string) *ProviderProgress {
+		pp, ok := byProvider[provider]
+		if !ok {
+			pp = &ProviderProgress{Provider: provider}
+			byProvider[provider] = pp
+			p.Providers = append(p.Providers, pp)
+		}
+		return pp
+	}
+	for provider, n := range counts {
+		get(provider).Sent = n
+	}
+	for provider, n := range deferred {
+		get(provider).Deferred = n
+	}
+	sort.Slice(p.Providers, func(i, j int) bool {
+		if p.Providers[i].Sent != p.Providers[j].Sent {
+			return p.Providers[i].Sent > p.Providers[j].Sent
+		}
+		return p.Providers[i].Provider < p.Providers[j].Provider
+	})
+	return p, nil
+}
+
+// recipientGroup 同一服务商的收件人
+type recipientGroup struct {
+	provider   string
+	recipients []string
+}
+
+// group 按服务商分组收件人（保持收件人的顺序）
+func (s *Scheduler) group(to []string) []*recipientGroup {
+	var groups []*recipientGroup
+	index := make(map[string]*recipientGroup)
+	for _, recipient := range to {
+		provider := s.Provider(recipient)
+		g, ok := index[provider]
+		if !ok {
+			g = &recipientGroup{provider: provider}
+			index[provider] = g
+			groups = append(groups, g)
+		}
+		g.recipients = append(g.recipients, recipient)
+	}
+	return groups
+}
+
+func (s *Scheduler) currentTime() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// day 计数使用的日期（UTC）
+func day(now time.Time) string {
+	return now.UTC().Format(time.DateOnly)
+}
+
+// nextDay 第二天 0 点（UTC）
+func nextDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseStart 解析预热开始日期（YYYY-MM-DD）
+func ParseStart(date string) (time.Time, error) {
+	start, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的预热开始日期 %q: %w", date, err)
+	}
+	return start, nil
+}
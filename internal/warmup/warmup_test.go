@@ -0,0 +1,166 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// fakeSender 记录发出的推迟邮件
+type fakeSender struct {
+	sent [][]string
+	err  error
+}
+
+func (f *fakeSender) Send(ctx context.Context, from string, to []string, data []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, to)
+	return nil
+}
+
+func newTestScheduler(t *testing.T, now *time.Time) (*Scheduler, *fakeSender) {
+	t.Helper()
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+
+	sender := &fakeSender{}
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	s := New(driver, sender, start, 4, 2, 16, map[string][]string{"fastmail": {"fastmail.com"}})
+	s.now = func() time.Time { return *now }
+	return s, sender
+}
+
+func TestLimit(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s, _ := newTestScheduler(t, &now)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want int
+	}{
+		{"开始之前", time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC), 2},
+		{"第一周", time.Date(2026, 3, 8, 23, 0, 0, 0, time.UTC), 2},
+		{"第二周", time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), 4},
+		{"第三周", time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), 8},
+		{"最后一周", time.Date(2026, 3, 23, 0, 0, 0, 0, time.UTC), 16},
+		{"预热结束", time.Date(2026, 3, 30, 0, 0, 0, 0, time.UTC), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Limit(tt.at); got != tt.want {
+				t.Errorf("Limit() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider(t *testing.T) {
+	now := time.Now()
+	s, _ := newTestScheduler(t, &now)
+
+	for recipient, want := range map[string]string{
+		"a@gmail.com":      "gmail",
+		"b@GoogleMail.com": "gmail",
+		"c@hotmail.com":    "microsoft",
+		"d@fastmail.com":   "fastmail",
+		"e@corp.example":   "corp.example",
+	} {
+		if got := s.Provider(recipient); got != want {
+			t.Errorf("Provider(%q) = %q, want %q", recipient, got, want)
+		}
+	}
+}
+
+func TestAdmitAndRelease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s, sender := newTestScheduler(t, &now)
+
+	// 第一周每个服务商每天 2 封：gmail 超出的 1 个收件人推迟，其他服务商不受影响
+	to := []string{"a@gmail.com", "b@gmail.com", "c@gmail.com", "d@hotmail.com"}
+	admitted, err := s.Admit(ctx, "alice@example.com", to, []byte("hello"))
+	if err != nil {
+		t.Fatalf("Admit() 失败: %v", err)
+	}
+	if len(admitted) != 3 || admitted[0] != "a@gmail.com" || admitted[2] != "d@hotmail.com" {
+		t.Errorf("可以立即发送的收件人不正确: %v", admitted)
+	}
+
+	// 当天配额用完后全部推迟
+	admitted, err = s.Admit(ctx, "alice@example.com", []string{"e@gmail.com"}, []byte("again"))
+	if err != nil || len(admitted) != 0 {
+		t.Errorf("配额用完后应该全部推迟: %v, err = %v", admitted, err)
+	}
+
+	progress, err := s.Progress(ctx)
+	if err != nil {
+		t.Fatalf("查询进度失败: %v", err)
+	}
+	if !progress.Enabled || progress.Week != 1 || progress.DailyLimit != 2 || len(progress.Providers) != 2 {
+		t.Fatalf("进度不正确: %+v", progress)
+	}
+	if gmail := progress.Providers[0]; gmail.Provider != "gmail" || gmail.Sent != 2 || gmail.Deferred != 2 {
+		t.Errorf("gmail 的进度不正确: %+v", gmail)
+	}
+
+	// 当天不发送推迟邮件
+	if n, err := s.Release(ctx); err != nil || n != 0 || len(sender.sent) != 0 {
+		t.Errorf("推迟邮件不应该当天发出: n = %d, err = %v", n, err)
+	}
+
+	// 第二天配额 2 封：两封推迟邮件各发出 1 个收件人
+	now = now.Add(24 * time.Hour)
+	if n, err := s.Release(ctx); err != nil || n != 2 {
+		t.Fatalf("第二天应该发出 2 个收件人: n = %d, err = %v", n, err)
+	}
+	if len(sender.sent) != 2 || sender.sent[0][0] != "c@gmail.com" || sender.sent[1][0] != "e@gmail.com" {
+		t.Errorf("发出的推迟邮件不正确: %v", sender.sent)
+	}
+	if counts, err := s.Storage.CountDeferredMails(ctx); err != nil || len(counts) != 0 {
+		t.Errorf("推迟邮件发出后应该删除: %v, err = %v", counts, err)
+	}
+}
+
+func TestReleaseRetry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	s, sender := newTestScheduler(t, &now)
+
+	if _, err := s.Admit(ctx, "alice@example.com", []string{"a@gmail.com", "b@gmail.com", "c@gmail.com"}, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 发送失败时稍后重试，不删除推迟邮件
+	now = now.Add(24 * time.Hour)
+	sender.err = errors.New("连接失败")
+	if n, err := s.Release(ctx); err != nil || n != 0 {
+		t.Fatalf("发送失败时不应该计为发出: n = %d, err = %v", n, err)
+	}
+	mails, err := s.Storage.ListDueDeferredMails(ctx, now.Add(retryDelay), 10)
+	if err != nil || len(mails) != 1 || mails[0].Attempts != 1 {
+		t.Fatalf("发送失败的推迟邮件应该稍后重试: %v, err = %v", mails, err)
+	}
+
+	// 预热结束后不再限制，推迟邮件全部发出
+	now = time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	sender.err = nil
+	if n, err := s.Release(ctx); err != nil || n != 1 {
+		t.Errorf("预热结束后应该发出推迟邮件: n = %d, err = %v", n, err)
+	}
+	if admitted, err := s.Admit(ctx, "alice@example.com", []string{"a@gmail.com", "b@gmail.com", "c@gmail.com"}, nil); err != nil || len(admitted) != 3 {
+		t.Errorf("预热结束后不应该限制: %v, err = %v", admitted, err)
+	}
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// activityDefaultDays 账户活动默认时间范围（天）
+	activityDefaultDays = 30
+	// activityMaxDays 账户活动最大时间范围（天，更早的记录按 activity.retention_days 清理）
+	activityMaxDays = 365
+	// activityLimit 登录记录和发信记录各自的最大条数
+	activityLimit = 1000
+)
+
+// deviceView 登录过账户的设备：浏览器按 User-Agent 区分，邮件客户端（IMAP、SMTP）按 IP 区分
+type deviceView struct {
+	Protocol  string    `json:"protocol"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	LastIP    string    `json:"last_ip,omitempty"`
+	IPs       []string  `json:"ips"` // 使用过的 IP（按最近使用排序）
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Logins    int       `json:"logins"`
+}
+
+// activityHandler 当前用户的账户活动：登录历史、登录过的设备和从账户发出的邮件（时间、收件人、主题），
+// 用户据此发现不认识的设备或不是自己发出的邮件
+func activityHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(activityDefaultDays)))
+		if err != nil || days < 1 || days > activityMaxDays {
+			respondError(c, http.StatusBadRequest, "invalid_days", activityMaxDays)
+			return
+		}
+
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+		since := time.Now().AddDate(0, 0, -days)
+
+		logins, err := driver.ListLoginHistory(ctx, email, since, activityLimit)
+		if err != nil {
+			storageError(c, err, "activity_get_failed")
+			return
+		}
+		sent, err := driver.ListSentLog(ctx, email, since, activityLimit)
+		if err != nil {
+			storageError(c, err, "activity_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"since":   since.UTC(),
+			"logins":  logins,
+			"devices": devices(logins),
+			"sent":    sent,
+		})
+	}
+}
+
+// devices 按设备汇总登录记录（记录按时间倒序，因此最近使用的设备在前）
+func devices(logins []*storage.LoginRecord) []*deviceView {
+	byKey := make(map[string]*deviceView)
+	result := []*deviceView{}
+	for _, login := range logins {
+		key := login.Protocol + "\x00" + login.UserAgent
+		if login.UserAgent == "" {
+			key += "\x00" + login.RemoteIP
+		}
+		d, ok := byKey[key]
+		if !ok {
+			d = &deviceView{
+				Protocol:  login.Protocol,
+				UserAgent: login.UserAgent,
+				LastIP:    login.RemoteIP,
+				IPs:       []string{},
+				LastSeen:  login.CreatedAt,
+			}
+			byKey[key] = d
+			result = append(result, d)
+		}
+		d.FirstSeen = login.CreatedAt
+		d.Logins++
+		if login.RemoteIP != "" && !slices.Contains(d.IPs, login.RemoteIP) {
+			d.IPs = append(d.IPs, login.RemoteIP)
+		}
+	}
+	return result
+}
@@ -0,0 +1,292 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/alias"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// userAliasPolicy 获取用户所在域名的自助别名策略（未配置时返回 nil）
+func userAliasPolicy(c *gin.Context, driver storage.Driver, email string) (*storage.AliasPolicy, error) {
+	domain := email[strings.LastIndex(email, "@")+1:]
+	policy, err := driver.GetAliasPolicy(c.Request.Context(), domain)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	return policy, err
+}
+
+// activeAliases 统计仍然有效的别名数（过期和作废的临时别名不占用名额）
+func activeAliases(aliases []*storage.Alias) int {
+	now := time.Now()
+	n := 0
+	for _, a := range aliases {
+		if a.Active(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// listMyAliasesHandler 列出当前用户自助创建的别名及域名策略
+func listMyAliasesHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		aliases, err := driver.ListAliasesByOwner(c.Request.Context(), email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_get_failed")
+			return
+		}
+		policy, err := userAliasPolicy(c, driver, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_policy_get_failed")
+			return
+		}
+
+		maxAliases, patterns := 0, alias.DefaultPatterns
+		if policy != nil {
+			maxAliases = policy.MaxAliases
+			if len(policy.Patterns) > 0 {
+				patterns = policy.Patterns
+			}
+		}
+		if aliases == nil {
+			aliases = []*storage.Alias{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"aliases": aliases,
+			"policy": gin.H{
+				"max_aliases": maxAliases,
+				"patterns":    patterns,
+				"remaining":   max(maxAliases-activeAliases(aliases), 0),
+			},
+		})
+	}
+}
+
+// createMyAliasHandler 在域名策略允许的范围内为当前用户创建别名
+func createMyAliasHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		var req struct {
+			Address string `json:"address" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		address := strings.ToLower(strings.TrimSpace(req.Address))
+
+		ctx := c.Request.Context()
+		owned, err := driver.ListAliasesByOwner(ctx, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_get_failed")
+			return
+		}
+		policy, err := userAliasPolicy(c, driver, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_policy_get_failed")
+			return
+		}
+
+		if err := alias.Check(policy, address, email, activeAliases(owned)); err != nil {
+			status := http.StatusForbidden
+			if errors.Is(err, alias.ErrInvalidAddress) {
+				status = http.StatusBadRequest
+			}
+			respondErrorDetail(c, status, "alias_not_allowed", err)
+			return
+		}
+
+		// 地址不能与已有用户或别名冲突
+		if _, err := driver.GetUser(ctx, address); err == nil {
+			respondError(c, http.StatusConflict, "address_in_use")
+			return
+		}
+		if _, err := driver.GetAlias(ctx, address); err == nil {
+			respondError(c, http.StatusConflict, "address_in_use")
+			return
+		}
+
+		a := &storage.Alias{
+			From:   address,
+			To:     email,
+			Domain: address[strings.LastIndex(address, "@")+1:],
+			Owner:  email,
+		}
+		if err := driver.CreateAlias(ctx, a); err != nil {
+			storageError(c, err, "alias_create_failed")
+			return
+		}
+
+		c.JSON(http.StatusCreated, a)
+	}
+}
+
+// deleteMyAliasHandler 删除当前用户自助创建的别名（管理员创建的别名不能删除）
+func deleteMyAliasHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		address := strings.ToLower(c.Param("address"))
+		ctx := c.Request.Context()
+		existing, err := driver.GetAlias(ctx, address)
+		if err != nil || existing.Owner != email {
+			respondError(c, http.StatusNotFound, "alias_not_found")
+			return
+		}
+
+		if err := driver.DeleteAlias(ctx, address); err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "alias_deleted"),
+		})
+	}
+}
+
+// maxDisposableAttempts 生成临时别名地址冲突时的最大重试次数
+const maxDisposableAttempts = 5
+
+// createDisposableAliasHandler 为当前用户生成有时效的随机临时别名（如 shop-x7f2@example.com）
+func createDisposableAliasHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		var req struct {
+			Label    string `json:"label"`
+			TTLHours int    `json:"ttl_hours"` // 有效期（小时），0 使用默认值
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		ttl := alias.DefaultDisposableTTL
+		if req.TTLHours != 0 {
+			ttl = time.Duration(req.TTLHours) * time.Hour
+		}
+		if ttl <= 0 || ttl > alias.MaxDisposableTTL {
+			respondError(c, http.StatusBadRequest, "alias_ttl_invalid")
+			return
+		}
+
+		ctx := c.Request.Context()
+		owned, err := driver.ListAliasesByOwner(ctx, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_get_failed")
+			return
+		}
+		policy, err := userAliasPolicy(c, driver, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_policy_get_failed")
+			return
+		}
+
+		// 临时别名由服务器随机生成，不受模式限制，但与普通别名共享数量上限
+		if policy == nil || policy.MaxAliases <= 0 {
+			respondError(c, http.StatusForbidden, "alias_not_allowed")
+			return
+		}
+		if activeAliases(owned) >= policy.MaxAliases {
+			respondError(c, http.StatusForbidden, "alias_limit_reached")
+			return
+		}
+
+		domain := email[strings.LastIndex(email, "@")+1:]
+		expiresAt := time.Now().Add(ttl)
+		for attempt := 0; attempt < maxDisposableAttempts; attempt++ {
+			address, err := alias.NewDisposableAddress(req.Label, domain)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "disposable_alias_generate_failed")
+				return
+			}
+			if _, err := driver.GetUser(ctx, address); err == nil {
+				continue
+			}
+			if _, err := driver.GetAlias(ctx, address); err == nil {
+				continue
+			}
+
+			a := &storage.Alias{
+				From:       address,
+				To:         email,
+				Domain:     domain,
+				Owner:      email,
+				Disposable: true,
+				ExpiresAt:  &expiresAt,
+			}
+			if err := driver.CreateAlias(ctx, a); err != nil {
+				storageError(c, err, "disposable_alias_create_failed")
+				return
+			}
+			c.JSON(http.StatusCreated, a)
+			return
+		}
+
+		respondError(c, http.StatusConflict, "disposable_alias_retry")
+	}
+}
+
+// burnAliasHandler 作废当前用户的别名，之后发往该地址的邮件被拒收（保留统计，稍后自动删除临时别名）
+func burnAliasHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		address := strings.ToLower(c.Param("address"))
+		ctx := c.Request.Context()
+		existing, err := driver.GetAlias(ctx, address)
+		if err != nil || existing.Owner != email {
+			respondError(c, http.StatusNotFound, "alias_not_found")
+			return
+		}
+
+		if err := driver.BurnAlias(ctx, address); err != nil {
+			respondError(c, http.StatusInternalServerError, "alias_burn_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "alias_burned"),
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// mailForUser 获取当前用户的邮件，邮件不存在或不属于当前用户时写入错误响应并返回 nil
+func mailForUser(c *gin.Context, driver storage.Driver) *storage.Mail {
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return nil
+	}
+
+	mail, err := driver.GetMail(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, "mail_not_found")
+		return nil
+	}
+	if mail.UserEmail != userEmail {
+		respondError(c, http.StatusForbidden, "mail_access_forbidden")
+		return nil
+	}
+	return mail
+}
+
+// listAnnotationsHandler 获取邮件注解（颜色标签、备注等，与 IMAP ANNOTATE 共享）
+func listAnnotationsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mail := mailForUser(c, driver)
+		if mail == nil {
+			return
+		}
+
+		annotations, err := driver.ListAnnotations(c.Request.Context(), mail.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "annotation_get_failed")
+			return
+		}
+		if annotations == nil {
+			annotations = []*storage.Annotation{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"annotations": annotations,
+		})
+	}
+}
+
+// updateAnnotationsHandler 设置邮件注解，value 为 null 时删除该注解
+func updateAnnotationsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mail := mailForUser(c, driver)
+		if mail == nil {
+			return
+		}
+
+		var req struct {
+			Annotations []struct {
+				Entry  string  `json:"entry" binding:"required"`
+				Shared bool    `json:"shared"`
+				Value  *string `json:"value"`
+			} `json:"annotations" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		// 先整体校验，避免部分注解写入后失败
+		for i := range req.Annotations {
+			a := &req.Annotations[i]
+			a.Entry = strings.ToLower(a.Entry)
+			if !storage.ValidAnnotationEntry(a.Entry) {
+				respondError(c, http.StatusBadRequest, "invalid_annotation_entry", a.Entry)
+				return
+			}
+			if a.Value != nil && len(*a.Value) > storage.MaxAnnotationSize {
+				respondError(c, http.StatusBadRequest, "annotation_too_large")
+				return
+			}
+		}
+
+		ctx := c.Request.Context()
+		for _, a := range req.Annotations {
+			var err error
+			if a.Value == nil {
+				err = driver.DeleteAnnotation(ctx, mail.ID, a.Entry, a.Shared)
+			} else {
+				err = driver.SetAnnotation(ctx, &storage.Annotation{
+					MailID: mail.ID,
+					Entry:  a.Entry,
+					Shared: a.Shared,
+					Value:  *a.Value,
+				})
+			}
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "annotation_save_failed")
+				return
+			}
+		}
+
+		annotations, err := driver.ListAnnotations(ctx, mail.ID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "annotation_get_failed")
+			return
+		}
+		if annotations == nil {
+			annotations = []*storage.Annotation{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"annotations": annotations,
+		})
+	}
+}
@@ -1,91 +1,161 @@
 package web
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/activity"
 	"github.com/gomailzero/gmz/internal/antispam"
 	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/bounce"
+	"github.com/gomailzero/gmz/internal/category"
 	"github.com/gomailzero/gmz/internal/config"
 	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/deliverylog"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/quota"
+	"github.com/gomailzero/gmz/internal/receipts"
+	"github.com/gomailzero/gmz/internal/search"
 	"github.com/gomailzero/gmz/internal/smtpclient"
 	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/terms"
+	"github.com/gomailzero/gmz/internal/zeroaccess"
 )
 
 // loginHandler 登录处理器
-func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManager *auth.TOTPManager) gin.HandlerFunc {
+func loginHandler(driver storage.Driver, jwtManager *auth.JWTManager, totpManager *auth.TOTPManager, webAuthn *auth.WebAuthnManager, limiter *limits.Limiter, keyring *zeroaccess.Keyring, recorder *activity.Recorder, gate *terms.Gate, accessTTL, sessionTTL time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
-			Email    string `json:"email" binding:"required"`
-			Password string `json:"password" binding:"required"`
-			TOTPCode string `json:"totp_code"`
+			Email       string `json:"email" binding:"required"`
+			Password    string `json:"password" binding:"required"`
+			TOTPCode    string `json:"totp_code"`
+			AcceptTerms string `json:"accept_terms"` // 用户接受的使用条款版本（见 GET /api/banner）
+			// 通行密钥登录：POST /api/login/webauthn 返回的流程 ID 和浏览器返回的断言（代替 TOTP 代码）
+			WebAuthnCeremony string          `json:"webauthn_ceremony"`
+			WebAuthnResponse json.RawMessage `json:"webauthn_response"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		// 认证失败次数过多的 IP 被临时封禁
+		ip := c.ClientIP()
+		if err := limiter.Allow("webmail", ip); err != nil {
+			respondError(c, http.StatusTooManyRequests, "too_many_attempts")
 			return
 		}
 
 		ctx := c.Request.Context()
 		user, err := driver.GetUser(ctx, req.Email)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "认证失败",
-			})
+			limiter.Failure("webmail", ip)
+			respondError(c, http.StatusUnauthorized, "auth_failed")
 			return
 		}
 
 		// 验证密码
 		valid, err := crypto.VerifyPassword(req.Password, user.PasswordHash)
 		if err != nil || !valid {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "认证失败",
-			})
+			limiter.Failure("webmail", ip)
+			respondError(c, http.StatusUnauthorized, "auth_failed")
 			return
 		}
 
-		// 验证 TOTP（如果启用）
+		// 验证第二因素（启用了 TOTP 或注册了通行密钥时需要其中之一）
+		totpEnabled := false
 		if totpManager != nil {
-			totpEnabled, err := totpManager.IsEnabled(ctx, req.Email)
-			if err == nil && totpEnabled {
-				// 如果启用了 TOTP，必须提供 TOTP 代码
-				if req.TOTPCode == "" {
-					c.JSON(http.StatusUnauthorized, gin.H{
-						"error":        "需要 TOTP 代码",
-						"requires_2fa": true,
-					})
-					return
-				}
+			enabled, err := totpManager.IsEnabled(ctx, req.Email)
+			totpEnabled = err == nil && enabled
+		}
+		hasPasskeys := false
+		if webAuthn != nil {
+			hasPasskeys, err = webAuthn.HasCredentials(ctx, user.Email)
+			if err != nil {
+				storageError(c, err, "webauthn_get_failed")
+				return
+			}
+		}
+		switch {
+		case hasPasskeys && req.WebAuthnCeremony != "":
+			// 验证通行密钥的断言
+			if err := webAuthn.FinishLogin(ctx, user.Email, req.WebAuthnCeremony, req.WebAuthnResponse); err != nil {
+				logger.WarnCtx(ctx).Err(err).Str("user", user.Email).Msg("通行密钥登录失败")
+				limiter.Failure("webmail", ip)
+				respondError(c, http.StatusUnauthorized, "webauthn_invalid")
+				return
+			}
+		case totpEnabled && req.TOTPCode != "":
+			// 验证 TOTP 代码
+			valid, err := totpManager.Verify(ctx, req.Email, req.TOTPCode)
+			if err != nil || !valid {
+				limiter.Failure("webmail", ip)
+				respondError(c, http.StatusUnauthorized, "totp_invalid")
+				return
+			}
+		case totpEnabled || hasPasskeys:
+			// 需要第二因素，返回可用的方式
+			code := "totp_required"
+			if !totpEnabled {
+				code = "webauthn_required"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":         code,
+				"error":        localize(c, code),
+				"requires_2fa": true,
+				"totp":         totpEnabled,
+				"webauthn":     hasPasskeys,
+			})
+			return
+		}
 
-				// 验证 TOTP 代码
-				valid, err := totpManager.Verify(ctx, req.Email, req.TOTPCode)
-				if err != nil || !valid {
-					c.JSON(http.StatusUnauthorized, gin.H{
-						"error": "TOTP 代码错误",
-					})
-					return
-				}
+		// 配置了使用条款时，需要接受当前版本才签发令牌
+		if err := gate.Check(ctx, user.Email, req.AcceptTerms, ip); err != nil {
+			if errors.Is(err, terms.ErrAcceptanceRequired) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"code":           "terms_required",
+					"error":          localize(c, "terms_required"),
+					"requires_terms": true,
+					"terms_version":  gate.Banner().TermsVersion,
+				})
+				return
 			}
+			storageError(c, err, "terms_check_failed")
+			return
+		}
+
+		// 解锁零访问存储的私钥（失败时仍然允许登录，加密的邮件需要用恢复密钥找回后才能读取）
+		if err := keyring.Unlock(ctx, user.Email, req.Password); err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("user", user.Email).Msg("解锁零访问存储密钥失败")
 		}
 
-		// 生成 JWT token
-		token, err := jwtManager.GenerateToken(user.Email, user.ID, false, 24*time.Hour)
+		// 签发短期访问令牌和刷新令牌
+		tokens, err := jwtManager.IssueSession(ctx, user, false, accessTTL, sessionTTL, sessionInfo(c))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "生成令牌失败",
-			})
+			respondError(c, http.StatusInternalServerError, "token_generate_failed")
 			return
 		}
 
+		limiter.Success(ip)
+		recorder.Login(ctx, user.Email, activity.ProtocolWebMail, ip, c.Request.UserAgent())
 		c.JSON(http.StatusOK, gin.H{
-			"token": token,
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    int(tokens.ExpiresIn.Seconds()),
 			"user": gin.H{
 				"email": user.Email,
 				"quota": user.Quota,
@@ -100,9 +170,7 @@ func listMailsHandler(driver storage.Driver) gin.HandlerFunc {
 		// 从 JWT 获取用户邮箱
 		userEmail, exists := c.Get("user_email")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
@@ -112,14 +180,25 @@ func listMailsHandler(driver storage.Driver) gin.HandlerFunc {
 		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
+		// 按收件箱分类过滤（分类以关键字标志保存）
+		cat := c.Query("category")
+		if cat != "" && !category.Valid(cat) {
+			respondError(c, http.StatusBadRequest, "invalid_category")
+			return
+		}
+
 		ctx := c.Request.Context()
-		mails, err := driver.ListMails(ctx, email, folder, limit, offset)
+		var mails []*storage.Mail
+		var err error
+		if cat != "" {
+			mails, err = driver.SearchMails(ctx, email, &storage.SearchQuery{Labels: []string{category.Keyword(cat)}}, folder, limit, offset)
+		} else {
+			mails, err = driver.ListMails(ctx, email, folder, limit, offset)
+		}
 		if err != nil {
 			// 记录详细错误信息
 			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			storageError(c, err, "mail_list_failed")
 			return
 		}
 
@@ -127,6 +206,9 @@ func listMailsHandler(driver storage.Driver) gin.HandlerFunc {
 		if mails == nil {
 			mails = []*storage.Mail{}
 		}
+		for _, mail := range mails {
+			mail.Category = category.FromFlags(mail.Flags)
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"mails": mails,
@@ -142,18 +224,14 @@ func getMailHandler(driver storage.Driver, maildir *storage.Maildir) gin.Handler
 
 		mail, err := driver.GetMail(ctx, id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "邮件不存在",
-			})
+			respondError(c, http.StatusNotFound, "mail_not_found")
 			return
 		}
 
 		// 检查权限（只能访问自己的邮件）
 		userEmail, _ := c.Get("user_email")
 		if mail.UserEmail != userEmail {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "无权访问此邮件",
-			})
+			respondError(c, http.StatusForbidden, "mail_access_forbidden")
 			return
 		}
 
@@ -163,41 +241,17 @@ func getMailHandler(driver storage.Driver, maildir *storage.Maildir) gin.Handler
 		if maildir != nil {
 			// 邮件 ID 就是 Maildir 中的文件名
 			body, err := maildir.ReadMail(mail.UserEmail, mail.Folder, id)
+			if errors.Is(err, storage.ErrMailboxLocked) {
+				respondError(c, http.StatusLocked, "mailbox_locked")
+				return
+			}
 			if err == nil {
-				// 解析邮件体（简单实现：查找 text/plain 和 text/html 部分）
-				bodyStr := string(body)
-
-				// 检查是否是 MIME 格式
-				if strings.Contains(bodyStr, "Content-Type:") {
-					// 简单的 MIME 解析
-					// 查找 text/plain 部分
-					if idx := strings.Index(bodyStr, "Content-Type: text/plain"); idx >= 0 {
-						// 找到正文开始位置
-						bodyStart := strings.Index(bodyStr[idx:], "\r\n\r\n")
-						if bodyStart >= 0 {
-							plainText := bodyStr[idx+bodyStart+4:]
-							// 移除后续的 MIME 部分
-							if nextBoundary := strings.Index(plainText, "\r\n--"); nextBoundary >= 0 {
-								plainText = plainText[:nextBoundary]
-							}
-							bodyText = strings.TrimSpace(plainText)
-						}
-					}
-
-					// 查找 text/html 部分
-					if idx := strings.Index(bodyStr, "Content-Type: text/html"); idx >= 0 {
-						bodyStart := strings.Index(bodyStr[idx:], "\r\n\r\n")
-						if bodyStart >= 0 {
-							htmlText := bodyStr[idx+bodyStart+4:]
-							if nextBoundary := strings.Index(htmlText, "\r\n--"); nextBoundary >= 0 {
-								htmlText = htmlText[:nextBoundary]
-							}
-							bodyHTML = strings.TrimSpace(htmlText)
-						}
-					}
+				if msg, err := mailparse.Parse(body); err == nil {
+					bodyText = msg.Text
+					bodyHTML = msg.HTML
 				} else {
-					// 纯文本邮件
-					bodyText = bodyStr
+					// 没有邮件头，整体作为纯文本
+					bodyText = string(body)
 				}
 			}
 			// 如果读取失败，忽略错误（可能邮件体不存在）
@@ -221,82 +275,130 @@ func getMailHandler(driver storage.Driver, maildir *storage.Maildir) gin.Handler
 			"created_at":  mail.CreatedAt,
 		}
 
+		// 发给本地收件人的已发送邮件：投递和首次阅读时间（收件人所在域名开启回执时才有记录）
+		mailReceipts, err := driver.ListMailReceipts(ctx, mail.ID)
+		if err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("mail_id", mail.ID).Msg("查询邮件回执失败")
+		} else if len(mailReceipts) > 0 {
+			response["receipts"] = mailReceipts
+		}
+
 		c.JSON(http.StatusOK, response)
 	}
 }
 
 // sendMailHandler 发送邮件
-func sendMailHandler(driver storage.Driver, maildir *storage.Maildir, relayConfig *config.SMTPConfig, dkim *antispam.DKIM) gin.HandlerFunc {
+func sendMailHandler(driver storage.Driver, maildir *storage.Maildir, relayConfig *config.SMTPConfig, dkim *antispam.DKIMSigners, clientTLS *tls.Config, identities *identity.Manager, warmup smtpclient.Throttle, senderSigner smtpclient.SenderSigner, forwarder *forward.Forwarder, recorder *activity.Recorder) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 JWT 获取用户邮箱
 		userEmail, exists := c.Get("user_email")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
 
+		// 支持 JSON（附件内容为 base64）和 multipart/form-data（附件为 attachments 文件字段）
 		var req struct {
-			To              []string `json:"to" binding:"required"`
-			Cc              []string `json:"cc"`
-			Bcc             []string `json:"bcc"`
-			Subject         string   `json:"subject" binding:"required"`
-			Body            string   `json:"body" binding:"required"`
-			FromDisplayName string   `json:"from_display_name"` // 可选的发件人显示名称
+			To              []string `json:"to" form:"to" binding:"required"`
+			Cc              []string `json:"cc" form:"cc"`
+			Bcc             []string `json:"bcc" form:"bcc"`
+			Subject         string   `json:"subject" form:"subject" binding:"required"`
+			Body            string   `json:"body" form:"body" binding:"required"`
+			FromDisplayName string   `json:"from_display_name" form:"from_display_name"` // 可选的发件人显示名称
+			IdentityID      int64    `json:"identity_id" form:"identity_id"`             // 可选：以外部发件身份发送
+			InReplyTo       string   `json:"in_reply_to" form:"in_reply_to"`             // 可选：回复的邮件 ID（设置 In-Reply-To 和 References，加入其会话）
+			Attachments     []struct {
+				Filename    string `json:"filename"`
+				ContentType string `json:"content_type"`
+				Content     []byte `json:"content"` // base64
+			} `json:"attachments" form:"-"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
+		if err := c.ShouldBind(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		var attachments []mailAttachment
+		for _, att := range req.Attachments {
+			attachments = append(attachments, newMailAttachment(att.Filename, att.ContentType, att.Content))
+		}
+		if c.ContentType() == gin.MIMEMultipartPOSTForm {
+			files, err := uploadedAttachments(c)
+			if err != nil {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_attachment", err)
+				return
+			}
+			attachments = append(attachments, files...)
+		}
+		var total int
+		for _, att := range attachments {
+			total += len(att.Data)
+		}
+		if total > maxAttachmentSize {
+			respondError(c, http.StatusRequestEntityTooLarge, "attachments_too_large", maxAttachmentSize>>20)
 			return
 		}
 
-		// 构建邮件（使用 buildMailMessage 以支持 DKIM 签名和显示名称）
 		from := userEmail.(string)
-		mailData, err := buildMailMessage(from, req.FromDisplayName, req.To, req.Cc, req.Bcc, req.Subject, req.Body, dkim)
+
+		// 以外部发件身份发送时，发件地址为身份地址，外部收件人通过身份的 SMTP 服务器提交
+		// （不使用本域名的 DKIM 签名，由外部服务器签名）
+		sender := from
+		signer := dkim.For(from)
+		var sendIdentity *storage.Identity
+		if req.IdentityID != 0 {
+			if identities == nil {
+				respondError(c, http.StatusServiceUnavailable, "identities_disabled")
+				return
+			}
+			found, err := driver.GetIdentity(c.Request.Context(), req.IdentityID)
+			if err != nil || found.UserEmail != from {
+				respondError(c, http.StatusNotFound, "identity_not_found")
+				return
+			}
+			sendIdentity = found
+			sender = found.Address
+			signer = nil
+			if req.FromDisplayName == "" {
+				req.FromDisplayName = found.DisplayName
+			}
+		}
+
+		var replyTo *storage.Mail
+		if req.InReplyTo != "" {
+			found, err := driver.GetMail(c.Request.Context(), req.InReplyTo)
+			if err != nil || found.UserEmail != from {
+				respondError(c, http.StatusNotFound, "mail_not_found")
+				return
+			}
+			replyTo = found
+		}
+
+		// 构建邮件（使用 buildMailMessage 以支持 DKIM 签名和显示名称）
+		mailData, err := buildMailMessage(sender, req.FromDisplayName, req.To, req.Cc, req.Bcc, req.Subject, req.Body, attachments, replyTo, signer)
 		if err != nil {
 			logger.ErrorCtx(c.Request.Context()).
 				Err(err).
 				Str("from", from).
 				Msg("构建邮件失败")
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "构建邮件失败",
-			})
+			respondError(c, http.StatusInternalServerError, "mail_build_failed")
 			return
 		}
 
 		// 存储到 Sent 文件夹
 		ctx := c.Request.Context()
 
-		// 先存储到 Maildir，获取文件名作为邮件 ID
-		var mailID string
-		if maildir != nil {
-			if err := maildir.EnsureUserMaildir(from); err == nil {
-				filename, err := maildir.StoreMail(from, "Sent", mailData)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error": "保存邮件到 Maildir 失败",
-					})
-					return
-				}
-				mailID = filename
-			} else {
-				// 如果无法创建 Maildir，使用时间戳作为 ID
-				mailID = fmt.Sprintf("sent-%d", time.Now().UnixNano())
-			}
-		} else {
-			// 如果没有 Maildir，使用时间戳作为 ID
-			mailID = fmt.Sprintf("sent-%d", time.Now().UnixNano())
-		}
-
+		// Maildir 文件和数据库记录一起写入，文件名作为邮件 ID
+		// （如果没有 Maildir，使用时间戳作为 ID）
+		mailStore := storage.NewMailStore(maildir, driver)
 		mail := &storage.Mail{
-			ID:         mailID,
+			ID:         fmt.Sprintf("sent-%d", time.Now().UnixNano()),
 			UserEmail:  from,
 			Folder:     "Sent",
-			From:       from,
+			From:       sender,
 			To:         req.To,
 			Cc:         req.Cc,
 			Bcc:        req.Bcc,
@@ -306,12 +408,13 @@ func sendMailHandler(driver storage.Driver, maildir *storage.Maildir, relayConfi
 			Flags:      []string{},
 			ReceivedAt: time.Now(),
 			CreatedAt:  time.Now(),
+
+			Attachments: search.ExtractAttachments(mailData),
 		}
 
-		if err := driver.StoreMail(ctx, mail); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "保存邮件失败",
-			})
+		if err := mailStore.Deliver(ctx, mail, mailData); err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("from", from).Msg("保存邮件到 Sent 失败")
+			storageError(c, err, "mail_save_failed")
 			return
 		}
 
@@ -321,154 +424,270 @@ func sendMailHandler(driver storage.Driver, maildir *storage.Maildir, relayConfi
 		allRecipients = append(allRecipients, req.Cc...)
 		allRecipients = append(allRecipients, req.Bcc...)
 
-		// 分离本地和外部收件人
-		var localRecipients []string
-		var externalRecipients []string
+		sub := &mailSubmitter{driver: driver, maildir: maildir, relayConfig: relayConfig, clientTLS: clientTLS,
+			identities: identities, warmup: warmup, senderSigner: senderSigner, forwarder: forwarder, activity: recorder, remoteIP: c.ClientIP()}
+		result := sub.submit(ctx, from, sender, sendIdentity, mail, allRecipients, mailData)
 
-		for _, recipient := range allRecipients {
-			// 检查是否是本地用户
-			user, err := driver.GetUser(ctx, recipient)
-			if err != nil {
-				// 检查别名
-				alias, err := driver.GetAlias(ctx, recipient)
-				if err != nil {
-					// 不是本地用户，是外部收件人
-					externalRecipients = append(externalRecipients, recipient)
-					continue
-				}
-				user, err = driver.GetUser(ctx, alias.To)
-				if err != nil {
-					// 别名目标不存在，作为外部收件人
-					externalRecipients = append(externalRecipients, recipient)
-					continue
-				}
+		c.JSON(http.StatusOK, gin.H{
+			"message":            localize(c, "mail_sent"),
+			"id":                 mail.ID,
+			"local_delivered":    result.localDelivered,
+			"external_delivered": result.externalDelivered,
+			"external_deferred":  result.externalDeferred,
+			"total_recipients":   len(allRecipients),
+		})
+	}
+}
+
+// mailSubmitter 投递 WebMail 提交的邮件：本地收件人直接投递到收件箱（别名投递到全部本地目标，
+// 外部目标经 SRS 重写后转发），外部收件人经预热限流后通过外部发件身份、中继或直接投递发出
+type mailSubmitter struct {
+	driver       storage.Driver
+	maildir      *storage.Maildir
+	relayConfig  *config.SMTPConfig
+	clientTLS    *tls.Config
+	identities   *identity.Manager
+	warmup       smtpclient.Throttle
+	senderSigner smtpclient.SenderSigner
+	forwarder    *forward.Forwarder // 别名外部目标的转发（可选）
+	activity     *activity.Recorder // 账户活动记录（可选）
+	remoteIP     string             // 提交邮件的客户端 IP（账户活动记录）
+}
+
+// submitResult 投递结果（各类收件人数）
+type submitResult struct {
+	localDelivered    int
+	externalDelivered int
+	externalDeferred  int
+}
+
+// submit 投递已保存到 Sent 的邮件 sent（原文为 mailData）：from 为当前用户，sender 为发件地址
+// （以外部发件身份 sendIdentity 发送时为身份地址）；同一次提交的投递日志使用新的队列 ID
+func (s *mailSubmitter) submit(ctx context.Context, from, sender string, sendIdentity *storage.Identity, sent *storage.Mail, allRecipients []string, mailData []byte) submitResult {
+	mailStore := storage.NewMailStore(s.maildir, s.driver)
+
+	// 投递审计日志（同一次提交使用相同的队列 ID）
+	deliveries := deliverylog.New(s.driver)
+	ctx = deliverylog.WithQueueID(ctx, deliverylog.NewQueueID())
+	messageID := deliverylog.MessageID(mailData)
+	for _, recipient := range allRecipients {
+		deliveries.Record(ctx, &storage.DeliveryEvent{
+			MessageID: messageID,
+			Event:     storage.DeliveryReceived,
+			Sender:    from,
+			Recipient: recipient,
+			Detail:    "webmail",
+		})
+	}
+	s.activity.Sent(ctx, from, activity.ProtocolWebMail, s.remoteIP, allRecipients, mailData)
+
+	// 分离本地和外部收件人
+	var localRecipients []string
+	var externalRecipients []string
+
+	for _, recipient := range allRecipients {
+		// 本地用户直接投递；别名投递到全部本地目标，外部目标经 SRS 重写后转发（与 SMTP 投递相同）
+		var mailboxes []string
+		if user, err := s.driver.GetUser(ctx, recipient); err == nil {
+			mailboxes = []string{user.Email}
+		} else {
+			local, external, ok := forward.ResolveAlias(ctx, s.driver, recipient)
+			if !ok {
+				// 不是本地用户（或别名已失效），是外部收件人
+				externalRecipients = append(externalRecipients, recipient)
+				continue
 			}
+			mailboxes = local
+			s.forwarder.ForwardAlias(ctx, recipient, from, external, mailData)
+		}
 
-			// 是本地用户，投递到收件箱
-			localRecipients = append(localRecipients, recipient)
-			if maildir != nil {
-				if err := maildir.EnsureUserMaildir(user.Email); err != nil {
-					logger.ErrorCtx(ctx).
-						Err(err).
-						Str("recipient", recipient).
-						Str("user_email", user.Email).
-						Msg("创建用户 Maildir 失败")
-					continue
-				}
-					filename, err := maildir.StoreMail(user.Email, "INBOX", mailData)
-				if err != nil {
-					logger.ErrorCtx(ctx).
-						Err(err).
-						Str("recipient", recipient).
-						Str("user_email", user.Email).
-						Msg("存储邮件到 Maildir 失败")
-					continue
-				}
-						// 存储邮件元数据到数据库
-						inboxMail := &storage.Mail{
-							ID:         filename,
-							UserEmail:  user.Email,
-							Folder:     "INBOX",
-							From:       from,
-							To:         []string{recipient},
-							Cc:         req.Cc,
-							Bcc:        req.Bcc,
-							Subject:    req.Subject,
-							Size:       int64(len(mailData)),
-					Flags:      []string{"\\Recent"}, // 新邮件设置 \Recent 标志
-							ReceivedAt: time.Now(),
-							CreatedAt:  time.Now(),
-						}
-				if err := driver.StoreMail(ctx, inboxMail); err != nil {
-					logger.ErrorCtx(ctx).
-						Err(err).
-						Str("recipient", recipient).
-						Str("user_email", user.Email).
-						Msg("存储邮件元数据到数据库失败")
-				} else {
-					logger.InfoCtx(ctx).
-						Str("from", from).
-						Str("to", recipient).
-						Msg("内部邮件投递成功")
-						}
-			} else {
-				logger.WarnCtx(ctx).
-					Str("recipient", recipient).
-					Msg("Maildir 未配置，无法投递内部邮件")
+		localRecipients = append(localRecipients, recipient)
+		for _, mailbox := range mailboxes {
+			s.deliverLocal(ctx, mailStore, deliveries, from, sender, sent, mailbox, recipient, mailData)
+		}
+	}
+
+	// 预热期间超过当天外发上限的收件人推迟发送（外部发件身份通过其 SMTP 服务器提交，不受限制）
+	externalDeferredCount := 0
+	if s.warmup != nil && sendIdentity == nil && len(externalRecipients) > 0 {
+		admitted, err := s.warmup.Admit(ctx, from, externalRecipients, mailData)
+		if err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("from", from).Msg("外发预热限流失败，直接发送")
+		} else {
+			externalDeferredCount = len(externalRecipients) - len(admitted)
+			if externalDeferredCount > 0 {
+				deliveries.RecordDelivery(ctx, from, excludeRecipients(externalRecipients, admitted), mailData, smtpclient.ErrWarmupDeferred)
 			}
+			externalRecipients = admitted
+		}
+	}
+
+	// 发送邮件到外部服务器
+	externalDeliveredCount := 0
+	if len(externalRecipients) > 0 {
+		// 获取 EHLO 主机名（从配置中获取，如果未配置则使用邮箱域名）
+		hostname := ""
+		if s.relayConfig != nil {
+			hostname = s.relayConfig.Hostname
 		}
+		// 信封发件人签名（BATV），退信统计和投递日志仍记录原地址
+		envelopeFrom := from
+		if s.senderSigner != nil {
+			envelopeFrom = s.senderSigner.Sign(from)
+		}
+		// 被拒收的收件人记入退信统计和投递日志
+		bounces := &bounce.Recorder{Storage: s.driver}
+		var rejected []string
+		smtpClient := smtpclient.NewClientWithTLS(hostname, s.clientTLS).OnReject(func(recipient string, err error) {
+			rejected = append(rejected, recipient)
+			bounces.RecordError(ctx, from, []string{recipient}, err)
+			deliveries.RecordDelivery(ctx, from, []string{recipient}, mailData, err)
+		})
+		var err error
 
-		// 发送邮件到外部服务器
-		externalDeliveredCount := 0
-		if len(externalRecipients) > 0 {
-			// 获取 EHLO 主机名（从配置中获取，如果未配置则使用邮箱域名）
-			hostname := ""
-			if relayConfig != nil {
-				hostname = relayConfig.Hostname
+		// 外部发件身份通过其 SMTP 服务器提交，否则如果配置了中继服务器，优先使用中继服务器
+		if sendIdentity != nil {
+			err = s.identities.Send(ctx, sendIdentity, externalRecipients, mailData)
+			if err != nil {
+				logger.ErrorCtx(ctx).
+					Err(err).
+					Str("from", sender).
+					Strs("to", externalRecipients).
+					Str("smtp_host", sendIdentity.SMTPHost).
+					Msg("通过外部发件身份发送邮件失败")
+			} else {
+				externalDeliveredCount = len(externalRecipients)
+				logger.InfoCtx(ctx).
+					Str("from", sender).
+					Strs("to", externalRecipients).
+					Str("smtp_host", sendIdentity.SMTPHost).
+					Msg("通过外部发件身份成功发送邮件")
 			}
-			smtpClient := smtpclient.NewClient(hostname)
-			var err error
-
-			// 如果配置了中继服务器，优先使用中继服务器
-			if relayConfig != nil && relayConfig.Relay.Enabled {
-				err = smtpClient.SendMailToRelay(
-					ctx,
-					relayConfig.Relay.Host,
-					relayConfig.Relay.Port,
-					relayConfig.Relay.Username,
-					relayConfig.Relay.Password,
-					relayConfig.Relay.UseTLS,
-					from,
-					externalRecipients,
-					mailData,
-				)
-				if err != nil {
-					logger.ErrorCtx(ctx).
-						Err(err).
-						Str("from", from).
-						Strs("to", externalRecipients).
-						Str("relay", relayConfig.Relay.Host).
-						Msg("通过中继服务器发送外部邮件失败")
-				} else {
-					externalDeliveredCount = len(externalRecipients)
-					logger.InfoCtx(ctx).
-						Str("from", from).
-						Strs("to", externalRecipients).
-						Str("relay", relayConfig.Relay.Host).
-						Msg("通过中继服务器成功发送外部邮件")
-				}
+		} else if s.relayConfig != nil && s.relayConfig.Relay.Enabled {
+			err = smtpClient.SendMailViaRelay(ctx, &s.relayConfig.Relay, envelopeFrom, externalRecipients, mailData)
+			if err != nil {
+				logger.ErrorCtx(ctx).
+					Err(err).
+					Str("from", from).
+					Strs("to", externalRecipients).
+					Str("relay", s.relayConfig.Relay.Host).
+					Msg("通过中继服务器发送外部邮件失败")
+				bounces.RecordError(ctx, from, externalRecipients, err)
 			} else {
-				// 没有配置中继服务器，直接发送到目标服务器
-				err = smtpClient.SendMail(ctx, from, externalRecipients, mailData)
-				if err != nil {
-					logger.ErrorCtx(ctx).
-						Err(err).
-						Str("from", from).
-						Strs("to", externalRecipients).
-						Msg("直接发送外部邮件失败（建议配置 SMTP 中继服务器）")
-					// 发送失败不影响响应，但记录错误
-				} else {
-					externalDeliveredCount = len(externalRecipients)
-					logger.InfoCtx(ctx).
-						Str("from", from).
-						Strs("to", externalRecipients).
-						Msg("直接发送外部邮件成功")
-				}
+				externalDeliveredCount = len(externalRecipients)
+				logger.InfoCtx(ctx).
+					Str("from", from).
+					Strs("to", externalRecipients).
+					Str("relay", s.relayConfig.Relay.Host).
+					Msg("通过中继服务器成功发送外部邮件")
+			}
+		} else {
+			// 没有配置中继服务器，直接发送到目标服务器
+			err = smtpClient.SendMail(ctx, envelopeFrom, externalRecipients, mailData)
+			if err != nil {
+				logger.ErrorCtx(ctx).
+					Err(err).
+					Str("from", from).
+					Strs("to", externalRecipients).
+					Msg("直接发送外部邮件失败（建议配置 SMTP 中继服务器）")
+				bounces.RecordError(ctx, from, externalRecipients, err)
+				// 发送失败不影响响应，但记录错误
+			} else {
+				externalDeliveredCount = len(externalRecipients)
+				logger.InfoCtx(ctx).
+					Str("from", from).
+					Strs("to", externalRecipients).
+					Msg("直接发送外部邮件成功")
 			}
 		}
+		deliveries.RecordDelivery(ctx, from, excludeRecipients(externalRecipients, rejected), mailData, err)
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"message":            "邮件已发送",
-			"id":                 mail.ID,
-			"local_delivered":    len(localRecipients),
-			"external_delivered": externalDeliveredCount,
-			"total_recipients":   len(allRecipients),
+	return submitResult{
+		localDelivered:    len(localRecipients),
+		externalDelivered: externalDeliveredCount,
+		externalDeferred:  externalDeferredCount,
+	}
+}
+
+// deliverLocal 将提交的邮件投递到本地邮箱 mailbox 的收件箱（recipient 为信封收件人，如别名地址）
+func (s *mailSubmitter) deliverLocal(ctx context.Context, mailStore *storage.MailStore, deliveries *deliverylog.Log, from, sender string, sent *storage.Mail, mailbox, recipient string, mailData []byte) {
+	if s.maildir == nil {
+		logger.WarnCtx(ctx).
+			Str("recipient", recipient).
+			Msg("Maildir 未配置，无法投递内部邮件")
+		return
+	}
+	user, err := s.driver.GetUser(ctx, mailbox)
+	if err != nil {
+		logger.WarnCtx(ctx).Err(err).Str("recipient", recipient).Str("user_email", mailbox).Msg("别名目标不存在，跳过投递")
+		return
+	}
+
+	// 存储邮件到收件箱
+	inboxMail := &storage.Mail{
+		UserEmail:   user.Email,
+		Folder:      "INBOX",
+		From:        sender,
+		To:          []string{recipient},
+		Cc:          sent.Cc,
+		Bcc:         sent.Bcc,
+		Subject:     sent.Subject,
+		Size:        int64(len(mailData)),
+		Flags:       []string{"\\Recent"}, // 新邮件设置 \Recent 标志
+		Attachments: sent.Attachments,
+		ReceivedAt:  time.Now(),
+		CreatedAt:   time.Now(),
+	}
+	messageID := deliverylog.MessageID(mailData)
+	if err := mailStore.Deliver(ctx, inboxMail, mailData); err != nil {
+		logger.ErrorCtx(ctx).
+			Err(err).
+			Str("recipient", recipient).
+			Str("user_email", user.Email).
+			Msg("存储邮件到收件箱失败")
+		deliveries.Record(ctx, &storage.DeliveryEvent{
+			MessageID: messageID,
+			Event:     storage.DeliveryFailed,
+			Sender:    from,
+			Recipient: user.Email,
+			Folder:    "INBOX",
+			Detail:    err.Error(),
 		})
+		return
+	}
+	deliveries.Record(ctx, &storage.DeliveryEvent{
+		MessageID: messageID,
+		Event:     storage.DeliveryDelivered,
+		Sender:    from,
+		Recipient: user.Email,
+		Folder:    "INBOX",
+	})
+	receipts.New(s.driver).Delivered(ctx, sent, inboxMail)
+	logger.InfoCtx(ctx).
+		Str("from", from).
+		Str("to", recipient).
+		Str("user_email", user.Email).
+		Msg("内部邮件投递成功")
+}
+
+// excludeRecipients 返回 to 中不在 exclude 中的收件人
+func excludeRecipients(to, exclude []string) []string {
+	skip := make(map[string]bool, len(exclude))
+	for _, r := range exclude {
+		skip[strings.ToLower(r)] = true
 	}
+	var rest []string
+	for _, r := range to {
+		if !skip[strings.ToLower(r)] {
+			rest = append(rest, r)
+		}
+	}
+	return rest
 }
 
 // updateMailFlagsHandler 更新邮件标志
-func updateMailFlagsHandler(driver storage.Driver) gin.HandlerFunc {
+func updateMailFlagsHandler(driver storage.Driver, maildir *storage.Maildir, bayes *antispam.Bayes) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		var req struct {
@@ -476,26 +695,62 @@ func updateMailFlagsHandler(driver storage.Driver) gin.HandlerFunc {
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
 			return
 		}
 
 		ctx := c.Request.Context()
+		mail, err := driver.GetMail(ctx, id)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "mail_not_found")
+			return
+		}
+
+		// 检查权限（只能修改自己的邮件，标记已读会记录已读回执）
+		userEmail, _ := c.Get("user_email")
+		if mail.UserEmail != userEmail {
+			respondError(c, http.StatusForbidden, "mail_access_forbidden")
+			return
+		}
+
 		if err := driver.UpdateMailFlags(ctx, id, req.Flags); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			storageError(c, err, "flags_update_failed")
 			return
 		}
+		// 训练垃圾邮件分类器需要比较修改前的标志
+		if bayes != nil {
+			trainJunkFlags(c, maildir, bayes, mail, req.Flags)
+		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"message": "标志已更新",
+			"message": localize(c, "flags_updated"),
 		})
 	}
 }
 
+// trainJunkFlags 用户新标记邮件为 $Junk 或 $NotJunk 时，在后台用邮件原文训练垃圾邮件分类器
+func trainJunkFlags(c *gin.Context, maildir *storage.Maildir, bayes *antispam.Bayes, mail *storage.Mail, flags []string) {
+	userEmail, _ := c.Get("user_email")
+	if maildir == nil || mail.UserEmail != userEmail {
+		return
+	}
+	added := func(keyword string) bool {
+		has := func(flags []string) bool {
+			return slices.ContainsFunc(flags, func(flag string) bool { return strings.EqualFold(flag, keyword) })
+		}
+		return has(flags) && !has(mail.Flags)
+	}
+	junk, notJunk := added(antispam.JunkKeyword), added(antispam.NotJunkKeyword)
+	if junk == notJunk {
+		return
+	}
+	raw, err := maildir.ReadMail(mail.UserEmail, mail.Folder, mail.ID)
+	if err != nil {
+		return
+	}
+	bayes.Learn(mail.UserEmail, raw, junk)
+}
+
 // deleteMailHandler 删除邮件
 func deleteMailHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -505,29 +760,23 @@ func deleteMailHandler(driver storage.Driver) gin.HandlerFunc {
 		// 检查权限
 		mail, err := driver.GetMail(ctx, id)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "邮件不存在",
-			})
+			respondError(c, http.StatusNotFound, "mail_not_found")
 			return
 		}
 
 		userEmail, _ := c.Get("user_email")
 		if mail.UserEmail != userEmail {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "无权删除此邮件",
-			})
+			respondError(c, http.StatusForbidden, "mail_delete_forbidden")
 			return
 		}
 
 		if err := driver.DeleteMail(ctx, id); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			storageError(c, err, "mail_delete_failed")
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"message": "邮件已删除",
+			"message": localize(c, "mail_deleted"),
 		})
 	}
 }
@@ -537,9 +786,7 @@ func searchMailsHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userEmail, exists := c.Get("user_email")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
@@ -551,18 +798,24 @@ func searchMailsHandler(driver storage.Driver) gin.HandlerFunc {
 		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
 		if query == "" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "搜索查询不能为空",
-			})
+			respondError(c, http.StatusBadRequest, "search_query_required")
+			return
+		}
+
+		parsed, err := search.Parse(query)
+		if err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_search_query", err)
+			return
+		}
+		if search.Empty(parsed) {
+			respondError(c, http.StatusBadRequest, "search_query_required")
 			return
 		}
 
 		ctx := c.Request.Context()
-		mails, err := driver.SearchMails(ctx, email, query, folder, limit, offset)
+		mails, err := driver.SearchMails(ctx, email, parsed, folder, limit, offset)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			storageError(c, err, "search_failed")
 			return
 		}
 
@@ -572,14 +825,15 @@ func searchMailsHandler(driver storage.Driver) gin.HandlerFunc {
 	}
 }
 
-// getCurrentUserHandler 获取当前用户信息
-func getCurrentUserHandler(driver storage.Driver) gin.HandlerFunc {
+// getCurrentUserHandler 获取当前用户信息（quota_warning 为 true 时 WebMail 显示配额预警横幅）
+func getCurrentUserHandler(driver storage.Driver, warner *quota.Warner) gin.HandlerFunc {
+	if warner == nil {
+		warner = &quota.Warner{Storage: driver}
+	}
 	return func(c *gin.Context) {
 		userEmail, exists := c.Get("user_email")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
@@ -588,18 +842,24 @@ func getCurrentUserHandler(driver storage.Driver) gin.HandlerFunc {
 		ctx := c.Request.Context()
 		user, err := driver.GetUser(ctx, email)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "获取用户信息失败",
-			})
+			respondError(c, http.StatusInternalServerError, "user_get_failed")
+			return
+		}
+
+		status, err := warner.Status(ctx, email)
+		if err != nil {
+			storageError(c, err, "quota_usage_get_failed")
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"user": gin.H{
-				"email":    user.Email,
-				"quota":    user.Quota,
-				"active":   user.Active,
-				"is_admin": user.IsAdmin,
+				"email":         user.Email,
+				"quota":         user.Quota,
+				"active":        user.Active,
+				"is_admin":      user.IsAdmin,
+				"quota_usage":   status,
+				"quota_warning": status.Threshold > 0,
 			},
 		})
 	}
@@ -610,9 +870,7 @@ func listFoldersHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userEmail, exists := c.Get("user_email")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
@@ -621,9 +879,7 @@ func listFoldersHandler(driver storage.Driver) gin.HandlerFunc {
 		ctx := c.Request.Context()
 		folders, err := driver.ListFolders(ctx, email)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			storageError(c, err, "folder_list_failed")
 			return
 		}
 
@@ -633,6 +889,67 @@ func listFoldersHandler(driver storage.Driver) gin.HandlerFunc {
 	}
 }
 
+// usageTrendDays 用量趋势的天数
+const usageTrendDays = 30
+
+// usageWarnPercent 用量达到配额的该百分比时提示即将用尽（超出配额后邮件会被退回）
+const usageWarnPercent = 90
+
+// usageHandler 获取当前用户的存储用量、配额、按文件夹统计和最近 30 天趋势
+func usageHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+		quota, err := driver.GetQuota(ctx, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "quota_get_failed")
+			return
+		}
+
+		folders, err := driver.GetFolderUsage(ctx, email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "folder_usage_get_failed")
+			return
+		}
+
+		since := time.Now().AddDate(0, 0, -(usageTrendDays - 1))
+		trend, err := driver.ListDailyUsage(ctx, email, since)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "usage_history_get_failed")
+			return
+		}
+
+		// 配额为 0 表示无限制
+		var percent float64
+		if quota.Limit > 0 {
+			percent = float64(quota.Used) * 100 / float64(quota.Limit)
+		}
+
+		if folders == nil {
+			folders = []*storage.FolderUsage{}
+		}
+		if trend == nil {
+			trend = []*storage.DailyUsage{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"used":       quota.Used,
+			"limit":      quota.Limit,
+			"percent":    percent,
+			"near_limit": quota.Limit > 0 && percent >= usageWarnPercent,
+			"folders":    folders,
+			"trend":      trend,
+		})
+	}
+}
+
 // checkInitHandler 检查系统是否需要初始化
 func checkInitHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -641,9 +958,7 @@ func checkInitHandler(driver storage.Driver) gin.HandlerFunc {
 		// 检查是否有用户
 		users, err := driver.ListUsers(ctx, 1, 0)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "检查初始化状态失败",
-			})
+			respondError(c, http.StatusInternalServerError, "init_check_failed")
 			return
 		}
 
@@ -655,7 +970,7 @@ func checkInitHandler(driver storage.Driver) gin.HandlerFunc {
 }
 
 // initSystemHandler 初始化系统（创建 admin 账户和域名）
-func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domain string) gin.HandlerFunc {
+func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domain string, accessTTL, sessionTTL time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req struct {
 			Email    string `json:"email" binding:"required"`
@@ -663,9 +978,7 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
 			return
 		}
 
@@ -674,41 +987,31 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		// 检查是否已有用户
 		users, err := driver.ListUsers(ctx, 1, 0)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "检查用户列表失败",
-			})
+			respondError(c, http.StatusInternalServerError, "user_list_check_failed")
 			return
 		}
 
 		if len(users) > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "系统已初始化，无法重复初始化",
-			})
+			respondError(c, http.StatusBadRequest, "already_initialized")
 			return
 		}
 
 		// 验证邮箱格式
 		if !strings.Contains(req.Email, "@") {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "邮箱格式无效",
-			})
+			respondError(c, http.StatusBadRequest, "invalid_email")
 			return
 		}
 
 		// 验证密码长度
 		if len(req.Password) < 8 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "密码长度至少为 8 位",
-			})
+			respondError(c, http.StatusBadRequest, "password_too_short")
 			return
 		}
 
 		// 哈希密码
 		passwordHash, err := crypto.HashPassword(req.Password)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "密码哈希失败",
-			})
+			respondError(c, http.StatusInternalServerError, "password_hash_failed")
 			return
 		}
 
@@ -721,9 +1024,7 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		}
 
 		if err := driver.CreateUser(ctx, adminUser); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": fmt.Sprintf("创建用户失败: %v", err),
-			})
+			storageError(c, err, fmt.Sprintf("创建用户失败: %v", err))
 			return
 		}
 
@@ -753,21 +1054,22 @@ func initSystemHandler(driver storage.Driver, jwtManager *auth.JWTManager, domai
 		}
 
 		// 生成 JWT token（自动登录）
-		token, err := jwtManager.GenerateToken(adminUser.Email, adminUser.ID, false, 24*time.Hour)
-		if err != nil {
-			// Token 生成失败不影响初始化，但需要用户手动登录
-			token = ""
+		var token, refreshToken string
+		if tokens, err := jwtManager.IssueSession(ctx, adminUser, false, accessTTL, sessionTTL, sessionInfo(c)); err == nil {
+			token, refreshToken = tokens.AccessToken, tokens.RefreshToken
 		}
+		// Token 生成失败不影响初始化，但需要用户手动登录
 
 		// 返回初始化结果和密码（仅此一次显示）
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
-			"message": "系统初始化成功",
+			"message": localize(c, "init_succeeded"),
 			"user": gin.H{
 				"email": adminUser.Email,
 			},
-			"password": req.Password, // 返回明文密码（仅此一次）
-			"token":    token,        // 如果生成成功，自动登录
+			"password":      req.Password, // 返回明文密码（仅此一次）
+			"token":         token,        // 如果生成成功，自动登录
+			"refresh_token": refreshToken, // 访问令牌过期后用于刷新
 		})
 	}
 }
@@ -777,9 +1079,7 @@ func saveDraftHandler(driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userEmail, exists := c.Get("user_email")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
@@ -794,9 +1094,7 @@ func saveDraftHandler(driver storage.Driver) gin.HandlerFunc {
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": err.Error(),
-			})
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
 			return
 		}
 
@@ -825,14 +1123,12 @@ func saveDraftHandler(driver storage.Driver) gin.HandlerFunc {
 		}
 
 		if err := driver.StoreMail(ctx, mail); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "保存草稿失败",
-			})
+			respondError(c, http.StatusInternalServerError, "draft_save_failed")
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"message": "草稿已保存",
+			"message": localize(c, "draft_saved"),
 			"id":      mailID,
 		})
 	}
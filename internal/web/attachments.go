@@ -0,0 +1,254 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/search"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// maxAttachmentSize 发送邮件时附件的总大小上限
+	maxAttachmentSize = 25 << 20
+	// maxUploadSize 发送邮件请求体的大小上限（base64 编码后约为原大小的 4/3）
+	maxUploadSize = maxAttachmentSize/3*4 + 1<<20
+)
+
+// attachmentItem 附件列表项，附带所属邮件的链接
+type attachmentItem struct {
+	*storage.Attachment
+	MailURL string `json:"mail_url"`
+}
+
+// listAttachmentsHandler 列出当前用户全部邮件中的附件
+// 支持过滤：type（MIME 类型前缀或扩展名）、min_size/max_size（如 1M）、from、after/before（YYYY-MM-DD）
+func listAttachmentsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		filter := &storage.AttachmentFilter{
+			Type:   c.Query("type"),
+			From:   c.Query("from"),
+			Limit:  limit,
+			Offset: offset,
+		}
+
+		var err error
+		if v := c.Query("min_size"); v != "" {
+			if filter.MinSize, err = search.ParseSize(v); err != nil {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_parameter", fmt.Errorf("min_size: %w", err))
+				return
+			}
+		}
+		if v := c.Query("max_size"); v != "" {
+			if filter.MaxSize, err = search.ParseSize(v); err != nil {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_parameter", fmt.Errorf("max_size: %w", err))
+				return
+			}
+		}
+		if v := c.Query("after"); v != "" {
+			t, err := search.ParseDate(v)
+			if err != nil {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_parameter", fmt.Errorf("after: %w", err))
+				return
+			}
+			filter.After = &t
+		}
+		if v := c.Query("before"); v != "" {
+			t, err := search.ParseDate(v)
+			if err != nil {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_parameter", fmt.Errorf("before: %w", err))
+				return
+			}
+			filter.Before = &t
+		}
+
+		attachments, err := driver.ListAttachments(c.Request.Context(), userEmail.(string), filter)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "attachment_list_get_failed")
+			return
+		}
+
+		items := make([]attachmentItem, 0, len(attachments))
+		for _, att := range attachments {
+			items = append(items, attachmentItem{
+				Attachment: att,
+				MailURL:    "/api/mails/" + att.MailID,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"attachments": items,
+		})
+	}
+}
+
+// mailAttachmentItem 单封邮件的附件列表项
+type mailAttachmentItem struct {
+	Index       int    `json:"index"`
+	Part        string `json:"part"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	URL         string `json:"url"`
+}
+
+// listMailAttachmentsHandler 列出单封邮件的附件
+func listMailAttachmentsHandler(driver storage.Driver, maildir *storage.Maildir) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msg, ok := readOwnMessage(c, driver, maildir)
+		if !ok {
+			return
+		}
+
+		id := c.Param("id")
+		items := make([]mailAttachmentItem, 0, len(msg.Attachments))
+		for i, att := range msg.Attachments {
+			items = append(items, mailAttachmentItem{
+				Index:       i,
+				Part:        att.Number,
+				Filename:    att.Filename,
+				ContentType: att.ContentType,
+				Size:        len(att.Data),
+				URL:         fmt.Sprintf("/api/mails/%s/attachments/%d", id, i),
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"attachments": items,
+		})
+	}
+}
+
+// downloadAttachmentHandler 下载单个附件（已解码传输编码）
+func downloadAttachmentHandler(driver storage.Driver, maildir *storage.Maildir) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		index, err := strconv.Atoi(c.Param("index"))
+		if err != nil || index < 0 {
+			respondError(c, http.StatusBadRequest, "invalid_attachment_index")
+			return
+		}
+
+		msg, ok := readOwnMessage(c, driver, maildir)
+		if !ok {
+			return
+		}
+		if index >= len(msg.Attachments) {
+			respondError(c, http.StatusNotFound, "attachment_not_found")
+			return
+		}
+
+		att := msg.Attachments[index]
+		filename := att.Filename
+		if filename == "" {
+			filename = "attachment-" + att.Number
+		}
+		// 禁止浏览器嗅探内容类型，避免附件中的 HTML/脚本在 WebMail 的源中执行
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		c.Data(http.StatusOK, att.ContentType, att.Data)
+	}
+}
+
+// readOwnMessage 读取并解析当前用户的邮件，失败时已写入响应
+func readOwnMessage(c *gin.Context, driver storage.Driver, maildir *storage.Maildir) (*mailparse.Message, bool) {
+	_, raw, ok := readOwnMail(c, driver, maildir)
+	if !ok {
+		return nil, false
+	}
+	msg, err := mailparse.Parse(raw)
+	if err != nil {
+		// 没有邮件头的邮件没有附件
+		msg = &mailparse.Message{}
+	}
+	return msg, true
+}
+
+// readOwnMail 读取当前用户的邮件记录和原文，失败时已写入响应
+func readOwnMail(c *gin.Context, driver storage.Driver, maildir *storage.Maildir) (*storage.Mail, []byte, bool) {
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return nil, nil, false
+	}
+
+	id := c.Param("id")
+	mail, err := driver.GetMail(c.Request.Context(), id)
+	if err != nil {
+		storageError(c, err, "mail_get_failed")
+		return nil, nil, false
+	}
+	if mail.UserEmail != userEmail {
+		respondError(c, http.StatusForbidden, "mail_access_forbidden")
+		return nil, nil, false
+	}
+	if maildir == nil {
+		respondError(c, http.StatusServiceUnavailable, "maildir_unavailable")
+		return nil, nil, false
+	}
+
+	raw, err := maildir.ReadMail(mail.UserEmail, mail.Folder, id)
+	if errors.Is(err, storage.ErrMailboxLocked) {
+		respondError(c, http.StatusLocked, "mailbox_locked")
+		return nil, nil, false
+	}
+	if err != nil {
+		respondError(c, http.StatusNotFound, "mail_content_not_found")
+		return nil, nil, false
+	}
+	return mail, raw, true
+}
+
+// newMailAttachment 创建待发送的附件，内容类型无效时按扩展名推断
+func newMailAttachment(filename, contentType string, data []byte) mailAttachment {
+	filename = filepath.Base(filepath.Clean("/" + filename))
+	if filename == "/" || filename == "." {
+		filename = "attachment"
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	} else if byExt := mime.TypeByExtension(filepath.Ext(filename)); byExt != "" {
+		contentType, _, _ = mime.ParseMediaType(byExt)
+	} else {
+		contentType = "application/octet-stream"
+	}
+	return mailAttachment{Filename: filename, ContentType: contentType, Data: data}
+}
+
+// uploadedAttachments 读取 multipart/form-data 中的 attachments 文件字段
+func uploadedAttachments(c *gin.Context) ([]mailAttachment, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, fmt.Errorf("解析上传的附件失败: %w", err)
+	}
+	var attachments []mailAttachment
+	for _, fh := range form.File["attachments"] {
+		f, err := fh.Open()
+		if err != nil {
+			return nil, fmt.Errorf("读取附件 %s 失败: %w", fh.Filename, err)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("读取附件 %s 失败: %w", fh.Filename, err)
+		}
+		attachments = append(attachments, newMailAttachment(fh.Filename, fh.Header.Get("Content-Type"), data))
+	}
+	return attachments, nil
+}
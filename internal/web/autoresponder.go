@@ -0,0 +1,135 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// maxAutoReplySubject 自动回复主题最大长度（字符）
+	maxAutoReplySubject = 200
+	// maxAutoReplyBody 自动回复正文最大长度（字符）
+	maxAutoReplyBody = 5000
+	// maxAutoReplyDays 同一发件人回复间隔的最大天数
+	maxAutoReplyDays = 365
+)
+
+// getAutoResponderHandler 获取当前用户的自动回复设置
+func getAutoResponderHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		responder, err := driver.GetAutoResponder(c.Request.Context(), email)
+		if errors.Is(err, storage.ErrNotFound) {
+			responder = &storage.AutoResponder{UserEmail: email, IntervalDays: storage.DefaultAutoReplyDays}
+		} else if err != nil {
+			respondError(c, http.StatusInternalServerError, "autoresponder_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"autoresponder": responder,
+			"active":        responder.Active(time.Now()),
+		})
+	}
+}
+
+// updateAutoResponderHandler 设置当前用户的自动回复（投递时回复发件人，同一发件人在间隔天数内只回复一次）
+func updateAutoResponderHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		var req struct {
+			Enabled      bool       `json:"enabled"`
+			Subject      string     `json:"subject"`
+			Body         string     `json:"body"`
+			StartsAt     *time.Time `json:"starts_at"`
+			EndsAt       *time.Time `json:"ends_at"`
+			IntervalDays int        `json:"interval_days"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		req.Subject = strings.TrimSpace(req.Subject)
+		req.Body = strings.TrimSpace(req.Body)
+		if strings.ContainsAny(req.Subject, "\r\n") || len([]rune(req.Subject)) > maxAutoReplySubject {
+			respondError(c, http.StatusBadRequest, "invalid_reply_subject")
+			return
+		}
+		if len([]rune(req.Body)) > maxAutoReplyBody {
+			respondError(c, http.StatusBadRequest, "autoresponder_body_too_long")
+			return
+		}
+		if req.Enabled && req.Body == "" {
+			respondError(c, http.StatusBadRequest, "autoresponder_body_required")
+			return
+		}
+		if req.IntervalDays < 0 || req.IntervalDays > maxAutoReplyDays {
+			respondError(c, http.StatusBadRequest, "autoresponder_interval_invalid")
+			return
+		}
+		if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+			respondError(c, http.StatusBadRequest, "end_before_start")
+			return
+		}
+
+		responder := &storage.AutoResponder{
+			UserEmail:    email,
+			Enabled:      req.Enabled,
+			Subject:      req.Subject,
+			Body:         req.Body,
+			StartsAt:     req.StartsAt,
+			EndsAt:       req.EndsAt,
+			IntervalDays: req.IntervalDays,
+		}
+		if err := driver.SaveAutoResponder(c.Request.Context(), responder); err != nil {
+			respondError(c, http.StatusInternalServerError, "autoresponder_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"autoresponder": responder,
+			"active":        responder.Active(time.Now()),
+		})
+	}
+}
+
+// deleteAutoResponderHandler 删除当前用户的自动回复设置
+func deleteAutoResponderHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		if err := driver.DeleteAutoResponder(c.Request.Context(), userEmail.(string)); err != nil {
+			respondError(c, http.StatusInternalServerError, "autoresponder_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "autoresponder_deleted"),
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/terms"
+)
+
+// bannerHandler 登录前显示的提示和使用条款（不需要认证）；requires_acceptance 为 true 时，
+// 登录请求需要在 accept_terms 中回传 terms_version
+func bannerHandler(gate *terms.Gate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gate.Banner())
+	}
+}
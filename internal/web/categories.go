@@ -0,0 +1,50 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// updateMailCategoryHandler 手动调整邮件的收件箱分类，并用该邮件训练当前用户的分类模型
+func updateMailCategoryHandler(driver storage.Driver) gin.HandlerFunc {
+	classifier := category.NewClassifier(driver)
+	return func(c *gin.Context) {
+		var req struct {
+			Category string `json:"category" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		if !category.Valid(req.Category) {
+			respondError(c, http.StatusBadRequest, "invalid_category")
+			return
+		}
+
+		mail := mailForUser(c, driver)
+		if mail == nil {
+			return
+		}
+
+		ctx := c.Request.Context()
+		flags := category.SetFlags(mail.Flags, req.Category)
+		if err := driver.UpdateMailFlags(ctx, mail.ID, flags); err != nil {
+			respondError(c, http.StatusInternalServerError, "category_update_failed")
+			return
+		}
+
+		if err := classifier.Recategorize(ctx, mail.UserEmail, mail.ID, req.Category); err != nil {
+			// 标志已更新，训练失败只影响后续自动分类
+			logger.WarnCtx(ctx).Err(err).Str("mail_id", mail.ID).Msg("训练分类模型失败")
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"category": req.Category,
+			"flags":    flags,
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/contactform"
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// maxContactFormOverhead 联系表单请求中留言之外的字段的最大字节数
+const maxContactFormOverhead = 16 << 10
+
+// contactFormRequest 联系表单提交的字段（JSON 或普通 HTML 表单）
+type contactFormRequest struct {
+	Name    string `json:"name" form:"name"`
+	Email   string `json:"email" form:"email"`
+	Subject string `json:"subject" form:"subject"`
+	Message string `json:"message" form:"message"`
+	// 人机验证令牌：JSON 请求使用 captcha_token，HTML 表单使用验证组件自动添加的字段
+	CaptchaToken   string `json:"captcha_token" form:"captcha_token"`
+	TurnstileToken string `json:"-" form:"cf-turnstile-response"`
+	HCaptchaToken  string `json:"-" form:"h-captcha-response"`
+}
+
+// token 人机验证令牌
+func (req *contactFormRequest) token() string {
+	switch {
+	case req.CaptchaToken != "":
+		return req.CaptchaToken
+	case req.TurnstileToken != "":
+		return req.TurnstileToken
+	default:
+		return req.HCaptchaToken
+	}
+}
+
+// contactForm 获取请求的联系表单并检查来源，失败时写入响应并返回 nil
+func contactForm(c *gin.Context, forms *contactform.Service) *contactform.Form {
+	if forms == nil {
+		respondError(c, http.StatusNotFound, "contact_form_not_found")
+		return nil
+	}
+	form, ok := forms.Form(c.Param("domain"))
+	if !ok {
+		respondError(c, http.StatusNotFound, "contact_form_not_found")
+		return nil
+	}
+	// 浏览器跨域请求带有 Origin 头，只允许表单配置的网站提交
+	if origin := c.GetHeader("Origin"); origin != "" {
+		if !form.AllowOrigin(origin) {
+			respondError(c, http.StatusForbidden, "contact_form_origin_not_allowed")
+			return nil
+		}
+		c.Header("Access-Control-Allow-Origin", origin)
+		c.Header("Vary", "Origin")
+	}
+	return form
+}
+
+// contactFormPreflightHandler 响应跨域预检请求（网站用 fetch 提交 JSON 时浏览器先发送 OPTIONS）
+func contactFormPreflightHandler(forms *contactform.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if contactForm(c, forms) == nil {
+			return
+		}
+		c.Header("Access-Control-Allow-Methods", "POST")
+		c.Header("Access-Control-Allow-Headers", "Content-Type")
+		c.Header("Access-Control-Max-Age", "86400")
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// contactFormHandler 提交联系表单（公开端点，不需要登录）
+func contactFormHandler(forms *contactform.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form := contactForm(c, forms)
+		if form == nil {
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(forms.MaxMessageSize+maxContactFormOverhead))
+		var req contactFormRequest
+		if err := c.ShouldBind(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		err := forms.Submit(ctx, form.Domain, &contactform.Submission{
+			Name:         req.Name,
+			Email:        req.Email,
+			Subject:      req.Subject,
+			Message:      req.Message,
+			Page:         c.GetHeader("Referer"),
+			RemoteIP:     c.ClientIP(),
+			CaptchaToken: req.token(),
+		})
+		switch {
+		case errors.Is(err, contactform.ErrInvalid):
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_contact_form", err)
+			return
+		case errors.Is(err, contactform.ErrCaptcha):
+			respondError(c, http.StatusForbidden, "contact_form_captcha_failed")
+			return
+		case errors.Is(err, contactform.ErrRateLimited):
+			respondError(c, http.StatusTooManyRequests, "contact_form_rate_limited")
+			return
+		case err != nil:
+			logger.ErrorCtx(ctx).Err(err).Str("domain", form.Domain).Msg("投递联系表单留言失败")
+			respondError(c, http.StatusInternalServerError, "contact_form_failed")
+			return
+		}
+
+		// 普通 HTML 表单提交后跳转到网站的感谢页面
+		if form.RedirectURL != "" && c.ContentType() != gin.MIMEJSON {
+			c.Redirect(http.StatusSeeOther, form.RedirectURL)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "contact_form_sent"),
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// getDigestHandler 获取当前用户的邮件摘要频率（off、daily、weekly）及其来源（system、domain、user）
+func getDigestHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		effective, err := storage.LoadEffectiveSettings(c.Request.Context(), driver, userEmail.(string))
+		if err != nil {
+			storageError(c, err, "digest_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"digest": effective.Digest,
+			"source": effective.Sources["digest"],
+		})
+	}
+}
+
+// updateDigestHandler 设置当前用户的邮件摘要频率（为空时继承域名默认设置），摘要按用户的时区发送
+// 只修改用户设置中的摘要频率，其他覆盖项保持不变
+func updateDigestHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		var req struct {
+			Digest string `json:"digest"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := userEmail.(string)
+		settings, err := driver.GetUserSettings(ctx, email)
+		if errors.Is(err, storage.ErrNotFound) {
+			settings, err = &storage.UserSettings{UserEmail: email}, nil
+		}
+		if err != nil {
+			storageError(c, err, "digest_save_failed")
+			return
+		}
+		settings.Digest = nil
+		if digest := strings.TrimSpace(req.Digest); digest != "" {
+			settings.Digest = &digest
+		}
+		if err := driver.SaveUserSettings(ctx, settings); err != nil {
+			if errors.Is(err, storage.ErrInvalidInput) {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_digest", err)
+				return
+			}
+			storageError(c, err, "digest_save_failed")
+			return
+		}
+
+		effective, err := storage.LoadEffectiveSettings(ctx, driver, email)
+		if err != nil {
+			storageError(c, err, "digest_get_failed")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"digest": effective.Digest,
+			"source": effective.Sources["digest"],
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+)
+
+// eventsKeepAlive 没有事件时发送注释行的间隔（避免代理断开空闲连接）
+const eventsKeepAlive = 30 * time.Second
+
+// mailEventsHandler 推送当前用户的新邮件事件（Server-Sent Events，事件名 new_mail），
+// 客户端收到后通过增量同步接口获取新邮件
+func mailEventsHandler(watcher *maildirwatch.Watcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if watcher == nil {
+			respondError(c, http.StatusServiceUnavailable, "mail_events_disabled")
+			return
+		}
+
+		events, cancel := watcher.Subscribe(userEmail.(string))
+		defer cancel()
+
+		// 长连接不受服务器写超时限制，每次写入前延长写入期限
+		rc := http.NewResponseController(c.Writer)
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+		c.Stream(func(w io.Writer) bool {
+			_ = rc.SetWriteDeadline(time.Now().Add(eventsKeepAlive + 10*time.Second))
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case e := <-events:
+				c.SSEvent("new_mail", e)
+			case <-keepAlive.C:
+				_, _ = io.WriteString(w, ": keepalive\n\n")
+			}
+			return true
+		})
+	}
+}
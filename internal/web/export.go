@@ -0,0 +1,58 @@
+package web
+
+import (
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/mailexport"
+	"github.com/gomailzero/gmz/internal/mailparse"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// exportMailHandler 导出单封邮件：format=eml（默认）下载邮件原文，format=pdf 生成包含邮件头、正文和附件列表的 PDF，
+// 日期按用户时区显示。inline=true 时 PDF 在浏览器中打开（用于打印），否则作为附件下载
+func exportMailHandler(driver storage.Driver, maildir *storage.Maildir) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "eml")
+		if format != "eml" && format != "pdf" {
+			respondError(c, http.StatusBadRequest, "invalid_export_format")
+			return
+		}
+
+		mail, raw, ok := readOwnMail(c, driver, maildir)
+		if !ok {
+			return
+		}
+
+		if format == "eml" {
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+				"filename": mailexport.Filename(mail.Subject, "eml"),
+			}))
+			c.Data(http.StatusOK, "message/rfc822", raw)
+			return
+		}
+
+		msg, err := mailparse.Parse(raw)
+		if err != nil {
+			// 无法解析时按纯文本输出原文
+			msg = &mailparse.Message{Subject: mail.Subject, Text: string(raw)}
+		}
+		loc := time.UTC
+		if effective, err := storage.LoadEffectiveSettings(c.Request.Context(), driver, mail.UserEmail); err == nil {
+			loc = effective.Location()
+		}
+
+		disposition := "attachment"
+		if c.Query("inline") == "true" {
+			disposition = "inline"
+		}
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+			"filename": mailexport.Filename(msg.Subject, "pdf"),
+		}))
+		c.Data(http.StatusOK, "application/pdf", mailexport.PDF(msg, loc, time.Now()))
+	}
+}
@@ -0,0 +1,231 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/fetchmail"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// externalAccountRequest 外部邮箱账户设置
+type externalAccountRequest struct {
+	Protocol   string `json:"protocol" binding:"required"`
+	Host       string `json:"host" binding:"required"`
+	Port       int    `json:"port"`
+	TLS        *bool  `json:"tls"` // 默认使用 TLS
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password"` // 更新时为空表示不修改密码
+	Folder     string `json:"folder"`
+	KeepRemote *bool  `json:"keep_remote"` // 默认保留远程邮件
+	Enabled    *bool  `json:"enabled"`     // 默认启用
+}
+
+// apply 将请求应用到账户（未提供的可选项保持原值）
+func (req *externalAccountRequest) apply(account *storage.ExternalAccount) {
+	account.Protocol = req.Protocol
+	account.Host = req.Host
+	account.Port = req.Port
+	account.Username = req.Username
+	account.Folder = req.Folder
+	if req.TLS != nil {
+		account.TLS = *req.TLS
+	}
+	if req.KeepRemote != nil {
+		account.KeepRemote = *req.KeepRemote
+	}
+	if req.Enabled != nil {
+		account.Enabled = *req.Enabled
+	}
+}
+
+// externalAccountForUser 获取当前用户的外部邮箱账户，失败时写入响应并返回 nil
+func externalAccountForUser(c *gin.Context, driver storage.Driver, fetcher *fetchmail.Fetcher) *storage.ExternalAccount {
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return nil
+	}
+	if fetcher == nil {
+		respondError(c, http.StatusServiceUnavailable, "fetchmail_disabled")
+		return nil
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_account_id")
+		return nil
+	}
+	account, err := driver.GetExternalAccount(c.Request.Context(), id)
+	if err != nil || account.UserEmail != userEmail {
+		respondError(c, http.StatusNotFound, "external_account_not_found")
+		return nil
+	}
+	return account
+}
+
+// listExternalAccountsHandler 列出当前用户的外部邮箱账户（包含最近一次拉取的状态和错误）
+func listExternalAccountsHandler(driver storage.Driver, fetcher *fetchmail.Fetcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		accounts, err := driver.ListExternalAccounts(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "external_account_get_failed")
+			return
+		}
+		if accounts == nil {
+			accounts = []*storage.ExternalAccount{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":  fetcher != nil,
+			"accounts": accounts,
+		})
+	}
+}
+
+// createExternalAccountHandler 添加外部邮箱账户（密码加密后存储）
+func createExternalAccountHandler(driver storage.Driver, fetcher *fetchmail.Fetcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if fetcher == nil {
+			respondError(c, http.StatusServiceUnavailable, "fetchmail_disabled")
+			return
+		}
+
+		var req externalAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		if req.Password == "" {
+			respondError(c, http.StatusBadRequest, "password_required")
+			return
+		}
+
+		account := &storage.ExternalAccount{
+			UserEmail:  userEmail.(string),
+			TLS:        true,
+			KeepRemote: true,
+			Enabled:    true,
+		}
+		req.apply(account)
+		if err := fetchmail.Validate(account); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_external_account", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		encrypted, err := fetcher.EncryptPassword(req.Password)
+		if err != nil {
+			logger.ErrorCtx(ctx).Err(err).Msg("加密外部邮箱密码失败")
+			respondError(c, http.StatusInternalServerError, "external_account_save_failed")
+			return
+		}
+		account.Password = encrypted
+		if err := driver.CreateExternalAccount(ctx, account); err != nil {
+			storageError(c, err, "external_account_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"account": account,
+		})
+	}
+}
+
+// updateExternalAccountHandler 更新外部邮箱账户设置
+func updateExternalAccountHandler(driver storage.Driver, fetcher *fetchmail.Fetcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account := externalAccountForUser(c, driver, fetcher)
+		if account == nil {
+			return
+		}
+
+		var req externalAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		req.apply(account)
+		if err := fetchmail.Validate(account); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_external_account", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if req.Password != "" {
+			encrypted, err := fetcher.EncryptPassword(req.Password)
+			if err != nil {
+				logger.ErrorCtx(ctx).Err(err).Msg("加密外部邮箱密码失败")
+				respondError(c, http.StatusInternalServerError, "external_account_save_failed")
+				return
+			}
+			account.Password = encrypted
+		}
+		if err := driver.UpdateExternalAccount(ctx, account); err != nil {
+			storageError(c, err, "external_account_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"account": account,
+		})
+	}
+}
+
+// deleteExternalAccountHandler 删除外部邮箱账户（已拉取的邮件保留）
+func deleteExternalAccountHandler(driver storage.Driver, fetcher *fetchmail.Fetcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account := externalAccountForUser(c, driver, fetcher)
+		if account == nil {
+			return
+		}
+
+		if err := driver.DeleteExternalAccount(c.Request.Context(), account.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, "external_account_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "external_account_deleted"),
+		})
+	}
+}
+
+// fetchExternalAccountHandler 立即拉取外部邮箱（后台执行，结果通过账户列表的状态查看）
+func fetchExternalAccountHandler(driver storage.Driver, fetcher *fetchmail.Fetcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		account := externalAccountForUser(c, driver, fetcher)
+		if account == nil {
+			return
+		}
+
+		// 拉取可能超过请求超时，不随请求取消
+		ctx := context.WithoutCancel(c.Request.Context())
+		if err := fetcher.FetchNow(ctx, account); errors.Is(err, fetchmail.ErrBusy) {
+			respondErrorDetail(c, http.StatusConflict, "fetch_in_progress", err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": localize(c, "fetch_started"),
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// getForwardingHandler 获取当前用户的转发规则以及域名是否允许外部转发
+func getForwardingHandler(driver storage.Driver, forwarder *forward.Forwarder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+
+		allowed := false
+		if forwarder != nil {
+			var err error
+			allowed, err = forwarder.Allowed(ctx, email)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "domain_get_failed")
+				return
+			}
+		}
+
+		rule, err := driver.GetForwardingRule(ctx, email)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			respondError(c, http.StatusInternalServerError, "forwarding_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"allowed": allowed,
+			"rule":    rule,
+		})
+	}
+}
+
+// updateForwardingHandler 设置转发规则，新的目标地址需要通过发送到该地址的验证码确认后才生效
+func updateForwardingHandler(forwarder *forward.Forwarder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		if forwarder == nil {
+			respondError(c, http.StatusServiceUnavailable, "forwarding_disabled")
+			return
+		}
+
+		var req struct {
+			Address      string `json:"address" binding:"required"`
+			KeepCopy     *bool  `json:"keep_copy"` // 默认保留本地副本
+			MatchFrom    string `json:"match_from"`
+			MatchSubject string `json:"match_subject"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		rule := &storage.ForwardingRule{
+			UserEmail:    email,
+			Address:      req.Address,
+			KeepCopy:     req.KeepCopy == nil || *req.KeepCopy,
+			MatchFrom:    req.MatchFrom,
+			MatchSubject: req.MatchSubject,
+		}
+		ctx := c.Request.Context()
+		codeSent, err := forwarder.Request(ctx, rule)
+		switch {
+		case errors.Is(err, forward.ErrDisabled):
+			respondErrorDetail(c, http.StatusForbidden, "forwarding_blocked", err)
+			return
+		case errors.Is(err, forward.ErrInvalidAddress):
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_forwarding_address", err)
+			return
+		case err != nil:
+			logger.ErrorCtx(ctx).Err(err).Str("user", email).Msg("设置转发规则失败")
+			respondError(c, http.StatusInternalServerError, "forwarding_save_failed")
+			return
+		}
+
+		if codeSent {
+			c.JSON(http.StatusAccepted, gin.H{
+				"message": localize(c, "verification_code_sent"),
+				"rule":    rule,
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "forwarding_updated"),
+			"rule":    rule,
+		})
+	}
+}
+
+// verifyForwardingHandler 校验转发地址的验证码
+func verifyForwardingHandler(forwarder *forward.Forwarder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		if forwarder == nil {
+			respondError(c, http.StatusServiceUnavailable, "forwarding_disabled")
+			return
+		}
+
+		var req struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		err := forwarder.Verify(c.Request.Context(), email, req.Code)
+		switch {
+		case errors.Is(err, forward.ErrNoPending), errors.Is(err, forward.ErrCodeExpired), errors.Is(err, forward.ErrCodeInvalid):
+			respondErrorDetail(c, http.StatusBadRequest, "verification_code_invalid", err)
+			return
+		case err != nil:
+			respondError(c, http.StatusInternalServerError, "verification_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "forwarding_verified"),
+		})
+	}
+}
+
+// deleteForwardingHandler 删除当前用户的转发规则
+func deleteForwardingHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		if err := driver.DeleteForwardingRule(c.Request.Context(), userEmail.(string)); err != nil {
+			respondError(c, http.StatusInternalServerError, "forwarding_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "forwarding_deleted"),
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/alias"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/fetchmail"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/i18n"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/newsletter"
+)
+
+// errorCodes 各模块的错误对应的错误码
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{alias.ErrNotAllowed, "alias_not_allowed"},
+	{alias.ErrLimitReached, "alias_limit_reached"},
+	{alias.ErrPatternMismatch, "alias_pattern_mismatch"},
+	{alias.ErrInvalidAddress, "invalid_alias_address"},
+	{category.ErrInvalid, "invalid_category"},
+	{forward.ErrDisabled, "forwarding_blocked"},
+	{forward.ErrInvalidAddress, "invalid_forwarding_address"},
+	{forward.ErrNoPending, "no_pending_verification"},
+	{forward.ErrCodeExpired, "verification_code_expired"},
+	{forward.ErrCodeInvalid, "verification_code_invalid"},
+	{fetchmail.ErrBusy, "fetch_in_progress"},
+	{fetchmail.ErrInvalidAccount, "invalid_external_account"},
+	{newsletter.ErrManual, "unsubscribe_manual"},
+	{newsletter.ErrNoMethod, "unsubscribe_no_method"},
+	{newsletter.ErrInvalidFolder, "invalid_folder"},
+	{auth.ErrInvalidScope, "invalid_scope"},
+	{identity.ErrInvalidIdentity, "invalid_identity"},
+	{identity.ErrLocalAddress, "identity_local_address"},
+	{identity.ErrNotFound, "identity_not_found"},
+}
+
+// language 请求使用的语言（按 Accept-Language 协商，同一请求内缓存）
+func language(c *gin.Context) string {
+	if lang := c.GetString("language"); lang != "" {
+		return lang
+	}
+	lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+	c.Set("language", lang)
+	return lang
+}
+
+// localize 返回错误码或提示码在请求语言中的消息
+func localize(c *gin.Context, code string, args ...any) string {
+	lang := language(c)
+	c.Header("Content-Language", lang)
+	return i18n.Message(lang, code, args...)
+}
+
+// respondError 返回机器可读的错误码和本地化的错误信息
+func respondError(c *gin.Context, status int, code string, args ...any) {
+	c.JSON(status, gin.H{
+		"code":  code,
+		"error": localize(c, code, args...),
+	})
+}
+
+// respondErrorDetail 同 respondError，并在 detail 中返回原始错误（请求解析、参数校验失败的原因）
+// err 属于已知的模块错误时使用其对应的错误码
+func respondErrorDetail(c *gin.Context, status int, code string, err error) {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			code = known.code
+			break
+		}
+	}
+	c.JSON(status, gin.H{
+		"code":   code,
+		"error":  localize(c, code),
+		"detail": err.Error(),
+	})
+}
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// identityRequest 外部发件身份设置
+type identityRequest struct {
+	Address      string `json:"address" binding:"required"`
+	DisplayName  string `json:"display_name"`
+	SMTPHost     string `json:"smtp_host" binding:"required"`
+	SMTPPort     int    `json:"smtp_port"`     // 默认 587
+	SMTPUsername string `json:"smtp_username"` // 默认使用发件地址
+	SMTPPassword string `json:"smtp_password"` // 更新时为空表示不修改密码
+}
+
+// apply 将请求应用到发件身份
+func (req *identityRequest) apply(id *storage.Identity) {
+	id.Address = req.Address
+	id.DisplayName = req.DisplayName
+	id.SMTPHost = req.SMTPHost
+	id.SMTPPort = req.SMTPPort
+	id.SMTPUsername = req.SMTPUsername
+}
+
+// identityStatus 发件身份校验错误对应的状态码
+func identityStatus(err error) int {
+	if errors.Is(err, identity.ErrLocalAddress) || errors.Is(err, identity.ErrInvalidIdentity) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// identityForUser 获取当前用户的发件身份，失败时写入响应并返回 nil
+func identityForUser(c *gin.Context, driver storage.Driver, identities *identity.Manager) *storage.Identity {
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return nil
+	}
+	if identities == nil {
+		respondError(c, http.StatusServiceUnavailable, "identities_disabled")
+		return nil
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_identity_id")
+		return nil
+	}
+	found, err := driver.GetIdentity(c.Request.Context(), id)
+	if err != nil || found.UserEmail != userEmail {
+		respondError(c, http.StatusNotFound, "identity_not_found")
+		return nil
+	}
+	return found
+}
+
+// listIdentitiesHandler 列出当前用户的外部发件身份
+func listIdentitiesHandler(driver storage.Driver, identities *identity.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		list, err := driver.ListIdentities(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "identity_get_failed")
+			return
+		}
+		if list == nil {
+			list = []*storage.Identity{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":    identities != nil,
+			"identities": list,
+		})
+	}
+}
+
+// createIdentityHandler 添加外部发件身份（SMTP 密码加密后存储）
+func createIdentityHandler(driver storage.Driver, identities *identity.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if identities == nil {
+			respondError(c, http.StatusServiceUnavailable, "identities_disabled")
+			return
+		}
+
+		var req identityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		if req.SMTPPassword == "" {
+			respondError(c, http.StatusBadRequest, "smtp_password_required")
+			return
+		}
+
+		ctx := c.Request.Context()
+		id := &storage.Identity{UserEmail: userEmail.(string)}
+		req.apply(id)
+		if err := identities.Validate(ctx, id); err != nil {
+			respondErrorDetail(c, identityStatus(err), "invalid_identity", err)
+			return
+		}
+
+		encrypted, err := identities.EncryptPassword(req.SMTPPassword)
+		if err != nil {
+			logger.ErrorCtx(ctx).Err(err).Msg("加密发件身份密码失败")
+			respondError(c, http.StatusInternalServerError, "identity_save_failed")
+			return
+		}
+		id.SMTPPassword = encrypted
+		if err := driver.CreateIdentity(ctx, id); err != nil {
+			storageError(c, err, "identity_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"identity": id,
+		})
+	}
+}
+
+// updateIdentityHandler 更新外部发件身份
+func updateIdentityHandler(driver storage.Driver, identities *identity.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := identityForUser(c, driver, identities)
+		if id == nil {
+			return
+		}
+
+		var req identityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		req.apply(id)
+		if err := identities.Validate(ctx, id); err != nil {
+			respondErrorDetail(c, identityStatus(err), "invalid_identity", err)
+			return
+		}
+		if req.SMTPPassword != "" {
+			encrypted, err := identities.EncryptPassword(req.SMTPPassword)
+			if err != nil {
+				logger.ErrorCtx(ctx).Err(err).Msg("加密发件身份密码失败")
+				respondError(c, http.StatusInternalServerError, "identity_save_failed")
+				return
+			}
+			id.SMTPPassword = encrypted
+		}
+		if err := driver.UpdateIdentity(ctx, id); err != nil {
+			storageError(c, err, "identity_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"identity": id,
+		})
+	}
+}
+
+// deleteIdentityHandler 删除外部发件身份
+func deleteIdentityHandler(driver storage.Driver, identities *identity.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := identityForUser(c, driver, identities)
+		if id == nil {
+			return
+		}
+
+		if err := driver.DeleteIdentity(c.Request.Context(), id.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, "identity_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "identity_deleted"),
+		})
+	}
+}
@@ -1,14 +1,20 @@
 package web
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"mime"
 	"strings"
 	"time"
 
+	"github.com/emersion/go-message/textproto"
 	"github.com/gomailzero/gmz/internal/antispam"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
 )
 
 // formatEmailAddress 格式化邮件地址（支持显示名称）
@@ -23,9 +29,18 @@ func formatEmailAddress(email, displayName string) string {
 	return fmt.Sprintf("%s <%s>", encodedName, email)
 }
 
+// mailAttachment 发送邮件时上传的附件
+type mailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
 // buildMailMessage 构建邮件消息（包含 DKIM 签名）
 // fromDisplayName 是可选的显示名称，如果为空则只使用邮箱地址
-func buildMailMessage(from, fromDisplayName string, to, cc, bcc []string, subject, body string, dkim *antispam.DKIM) ([]byte, error) {
+// 有附件时构建 multipart/mixed 邮件，正文作为第一个部分
+// replyTo 不为 nil 时作为回复：设置 In-Reply-To 和 References（原邮件的 References 加上原邮件）
+func buildMailMessage(from, fromDisplayName string, to, cc, bcc []string, subject, body string, attachments []mailAttachment, replyTo *storage.Mail, dkim *antispam.DKIM) ([]byte, error) {
 	var buf bytes.Buffer
 
 	// 生成 Message-ID
@@ -44,8 +59,24 @@ func buildMailMessage(from, fromDisplayName string, to, cc, bcc []string, subjec
 	headers["Subject"] = subject
 	headers["Date"] = time.Now().Format(time.RFC1123Z)
 	headers["Message-ID"] = messageID
+	if replyTo != nil && replyTo.MessageID != "" {
+		references := make([]string, 0, len(replyTo.References)+1)
+		for _, id := range replyTo.References {
+			references = append(references, "<"+id+">")
+		}
+		headers["In-Reply-To"] = "<" + replyTo.MessageID + ">"
+		headers["References"] = strings.Join(append(references, headers["In-Reply-To"]), " ")
+	}
 	headers["MIME-Version"] = "1.0"
 	headers["Content-Type"] = "text/plain; charset=UTF-8"
+	if len(attachments) > 0 {
+		boundary, err := newBoundary()
+		if err != nil {
+			return nil, err
+		}
+		headers["Content-Type"] = mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": boundary})
+		body = buildMultipartBody(boundary, body, attachments)
+	}
 
 	// 如果启用了 DKIM，添加签名
 	if dkim != nil {
@@ -72,6 +103,88 @@ func buildMailMessage(from, fromDisplayName string, to, cc, bcc []string, subjec
 	return buf.Bytes(), nil
 }
 
+// resendMessage 生成重新发送的邮件：去掉原有的 DKIM 签名，使用新的 Date 和 Message-ID，
+// recipients 不为 nil 时替换 To、Cc 和 Bcc（依次为 recipients[0..2]），正文和其余邮件头保持不变
+func resendMessage(raw []byte, sender string, recipients *[3][]string, dkim *antispam.DKIM) ([]byte, error) {
+	br := bufio.NewReader(bytes.NewReader(raw))
+	header, err := textproto.ReadHeader(br)
+	if err != nil {
+		return nil, fmt.Errorf("解析原邮件头失败: %w", err)
+	}
+
+	header.Del("DKIM-Signature")
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", generateMessageID(sender))
+	if recipients != nil {
+		for i, key := range []string{"To", "Cc", "Bcc"} {
+			header.Del(key)
+			if len(recipients[i]) > 0 {
+				header.Set(key, strings.Join(recipients[i], ", "))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := textproto.WriteHeader(&buf, header); err != nil {
+		return nil, fmt.Errorf("写入邮件头失败: %w", err)
+	}
+	if _, err := buf.ReadFrom(br); err != nil {
+		return nil, fmt.Errorf("读取原邮件正文失败: %w", err)
+	}
+	data := buf.Bytes()
+
+	if dkim != nil {
+		signed, err := dkim.SignMessage(data)
+		if err != nil {
+			logger.Warn().Err(err).Msg("DKIM 签名失败，继续发送未签名的邮件")
+		} else {
+			data = signed
+		}
+	}
+	return data, nil
+}
+
+// buildMultipartBody 构建 multipart/mixed 邮件体，附件使用 base64 编码
+func buildMultipartBody(boundary, text string, attachments []mailAttachment) string {
+	var buf strings.Builder
+	buf.WriteString("This is a multi-part message in MIME format.\r\n")
+
+	buf.WriteString("\r\n--" + boundary + "\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(text)
+
+	for _, att := range attachments {
+		contentType := att.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		// 非 ASCII 文件名按 RFC 2231 编码
+		buf.WriteString("\r\n--" + boundary + "\r\n")
+		buf.WriteString("Content-Type: " + mime.FormatMediaType(contentType, map[string]string{"name": att.Filename}) + "\r\n")
+		buf.WriteString("Content-Disposition: " + mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}) + "\r\n")
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		encoded := base64.StdEncoding.EncodeToString(att.Data)
+		for len(encoded) > 76 {
+			buf.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		buf.WriteString(encoded + "\r\n")
+	}
+
+	buf.WriteString("--" + boundary + "--\r\n")
+	return buf.String()
+}
+
+// newBoundary 生成随机的 MIME 边界
+func newBoundary() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成 MIME 边界失败: %w", err)
+	}
+	return "=_gmz_" + hex.EncodeToString(b), nil
+}
+
 // generateMessageID 生成 Message-ID
 func generateMessageID(from string) string {
 	// 格式: <timestamp.random@domain>
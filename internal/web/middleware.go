@@ -44,15 +44,13 @@ func generateTraceID() string {
 	return hex.EncodeToString(b)
 }
 
-// jwtMiddleware JWT 认证中间件
-func jwtMiddleware(jwtManager *auth.JWTManager, driver storage.Driver) gin.HandlerFunc {
+// jwtMiddleware JWT 认证中间件（同时接受个人访问令牌，按令牌的权限范围限制可访问的接口）
+func jwtMiddleware(jwtManager *auth.JWTManager, apiTokens *auth.APITokenManager, driver storage.Driver) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从 Header 获取 token
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "未授权",
-			})
+			respondError(c, http.StatusUnauthorized, "unauthorized")
 			c.Abort()
 			return
 		}
@@ -60,51 +58,56 @@ func jwtMiddleware(jwtManager *auth.JWTManager, driver storage.Driver) gin.Handl
 		// 提取 Bearer token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "无效的认证格式",
-			})
+			respondError(c, http.StatusUnauthorized, "invalid_auth_format")
 			c.Abort()
 			return
 		}
 
 		token := parts[1]
+		ctx := c.Request.Context()
 
-		// 验证 token
-		claims, err := jwtManager.ValidateToken(token)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "无效的令牌",
-			})
-			c.Abort()
+		// 个人访问令牌
+		if auth.IsAPIToken(token) {
+			apiToken, err := apiTokens.Authenticate(ctx, token)
+			if err != nil {
+				if !errors.Is(err, auth.ErrInvalidToken) && !errors.Is(err, auth.ErrExpiredToken) {
+					_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+				}
+				respondError(c, http.StatusUnauthorized, "invalid_token")
+				c.Abort()
+				return
+			}
+			if !auth.ScopeAllows(apiToken.Scope, c.Request.Method, c.FullPath()) {
+				respondError(c, http.StatusForbidden, "token_scope_denied")
+				c.Abort()
+				return
+			}
+			user := activeUser(c, driver, apiToken.UserEmail)
+			if user == nil {
+				return
+			}
+
+			// 访问令牌不具有管理员权限
+			c.Set("user_email", user.Email)
+			c.Set("user_id", user.ID)
+			c.Set("is_admin", false)
+			c.Set("api_token_id", apiToken.ID)
+
+			c.Next()
 			return
 		}
 
-		// 验证用户是否仍然存在于数据库中
-		ctx := c.Request.Context()
-		user, err := driver.GetUser(ctx, claims.Email)
+		// 验证 token
+		claims, err := jwtManager.Validate(ctx, token)
 		if err != nil {
-			// 检查是否是用户不存在的错误
-			if errors.Is(err, storage.ErrNotFound) || strings.Contains(err.Error(), "用户不存在") {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "用户不存在或已被删除",
-				})
-			} else {
-				// 其他错误（如数据库连接错误）返回 500
+			if !errors.Is(err, auth.ErrInvalidToken) && !errors.Is(err, auth.ErrExpiredToken) {
 				_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "验证用户失败",
-				})
 			}
+			respondError(c, http.StatusUnauthorized, "invalid_token")
 			c.Abort()
 			return
 		}
-
-		// 检查用户是否被禁用
-		if !user.Active {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "用户已被禁用",
-			})
-			c.Abort()
+		if activeUser(c, driver, claims.Email) == nil {
 			return
 		}
 
@@ -112,7 +115,62 @@ func jwtMiddleware(jwtManager *auth.JWTManager, driver storage.Driver) gin.Handl
 		c.Set("user_email", claims.Email)
 		c.Set("user_id", claims.UserID)
 		c.Set("is_admin", claims.IsAdmin)
+		c.Set("session_id", claims.ID)
 
 		c.Next()
 	}
 }
+
+// sessionInfo 登录会话的来源信息（WebMail 登录）
+func sessionInfo(c *gin.Context) auth.SessionInfo {
+	return auth.SessionInfo{
+		Source:    "webmail",
+		RemoteIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// activeUser 获取仍然存在且未被禁用的用户，失败时写入响应并返回 nil
+func activeUser(c *gin.Context, driver storage.Driver, email string) *storage.User {
+	// 验证用户是否仍然存在于数据库中
+	user, err := driver.GetUser(c.Request.Context(), email)
+	if err != nil {
+		// 检查是否是用户不存在的错误
+		if errors.Is(err, storage.ErrNotFound) || strings.Contains(err.Error(), "用户不存在") {
+			respondError(c, http.StatusUnauthorized, "user_gone")
+		} else {
+			// 其他错误（如数据库连接错误）返回 500
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			respondError(c, http.StatusInternalServerError, "user_check_failed")
+		}
+		c.Abort()
+		return nil
+	}
+
+	// 检查用户是否被禁用
+	if !user.Active {
+		respondError(c, http.StatusForbidden, "user_disabled")
+		c.Abort()
+		return nil
+	}
+	return user
+}
+
+// storageError 按存储错误的类型写入响应状态码（已存在 409、输入无效 400、不存在 404、配额已用尽 507），
+// 其他错误返回 500；提示使用错误码 code 对应的消息，不暴露内部错误
+func storageError(c *gin.Context, err error, code string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, storage.ErrAlreadyExists):
+		status = http.StatusConflict
+	case errors.Is(err, storage.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, storage.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		status, code = http.StatusInsufficientStorage, "quota_exceeded"
+	case errors.Is(err, storage.ErrMailboxLocked):
+		status, code = http.StatusLocked, "mailbox_locked"
+	}
+	respondError(c, status, code)
+}
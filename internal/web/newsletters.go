@@ -0,0 +1,149 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/newsletter"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// getUnsubscribeHandler 获取邮件的退订方式（用于显示“退订”按钮）
+func getUnsubscribeHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		mail := mailForUser(c, driver)
+		if mail == nil {
+			return
+		}
+
+		info, err := driver.GetUnsubscribe(c.Request.Context(), mail.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusOK, gin.H{
+				"available": false,
+			})
+			return
+		}
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "unsubscribe_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"available":   true,
+			"unsubscribe": info,
+		})
+	}
+}
+
+// unsubscribeHandler 在服务端执行退订（一键退订或 mailto），只有普通链接时返回链接由用户打开
+func unsubscribeHandler(driver storage.Driver, unsubscriber *newsletter.Unsubscriber) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if unsubscriber == nil {
+			respondError(c, http.StatusServiceUnavailable, "unsubscribe_disabled")
+			return
+		}
+
+		mail := mailForUser(c, driver)
+		if mail == nil {
+			return
+		}
+
+		ctx := c.Request.Context()
+		info, err := driver.GetUnsubscribe(ctx, mail.ID)
+		if err != nil {
+			respondError(c, http.StatusNotFound, "not_newsletter")
+			return
+		}
+
+		method, err := unsubscriber.Unsubscribe(ctx, mail.UserEmail, info)
+		if errors.Is(err, newsletter.ErrManual) {
+			c.JSON(http.StatusOK, gin.H{
+				"manual": true,
+				"url":    info.URL,
+			})
+			return
+		}
+		if errors.Is(err, newsletter.ErrNoMethod) {
+			respondErrorDetail(c, http.StatusBadRequest, "unsubscribe_no_method", err)
+			return
+		}
+		if err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("mail_id", mail.ID).Msg("退订失败")
+			respondError(c, http.StatusBadGateway, "unsubscribe_failed")
+			return
+		}
+
+		if err := driver.MarkUnsubscribed(ctx, mail.ID, time.Now()); err != nil {
+			logger.WarnCtx(ctx).Err(err).Str("mail_id", mail.ID).Msg("记录退订状态失败")
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "unsubscribed"),
+			"method":  method,
+		})
+	}
+}
+
+// getNewsletterSettingsHandler 获取订阅邮件设置
+func getNewsletterSettingsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		settings, err := driver.GetNewsletterSettings(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "newsletter_settings_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"settings": settings,
+		})
+	}
+}
+
+// updateNewsletterSettingsHandler 更新订阅邮件设置（folder 为空时订阅邮件留在收件箱）
+func updateNewsletterSettingsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		var req struct {
+			Folder string `json:"folder"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		req.Folder = strings.TrimSpace(req.Folder)
+		if err := newsletter.ValidFolder(req.Folder); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_folder", err)
+			return
+		}
+
+		settings := &storage.NewsletterSettings{
+			UserEmail: userEmail.(string),
+			Folder:    req.Folder,
+		}
+		if err := driver.SaveNewsletterSettings(c.Request.Context(), settings); err != nil {
+			respondError(c, http.StatusInternalServerError, "newsletter_settings_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"settings": settings,
+		})
+	}
+}
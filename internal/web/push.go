@@ -0,0 +1,195 @@
+package web
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/webpush"
+)
+
+const (
+	// maxPushSubscriptionsPerUser 每个用户最多的推送订阅数（每个浏览器或设备一个）
+	maxPushSubscriptionsPerUser = 20
+	// maxPushEndpointLength 推送地址的最大长度
+	maxPushEndpointLength = 2048
+)
+
+// getPushHandler 获取推送通知设置：是否启用、VAPID 公钥和当前用户的订阅
+func getPushHandler(driver storage.Driver, notifier *webpush.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if notifier == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+
+		subs, err := driver.ListPushSubscriptions(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "push_get_failed")
+			return
+		}
+		if subs == nil {
+			subs = []*storage.PushSubscription{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":         true,
+			"public_key":      notifier.PublicKey(),
+			"default_privacy": notifier.Privacy,
+			"subscriptions":   subs,
+		})
+	}
+}
+
+// createPushSubscriptionHandler 保存浏览器的推送订阅（PushSubscription.toJSON() 的内容）
+func createPushSubscriptionHandler(driver storage.Driver, notifier *webpush.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if notifier == nil {
+			respondError(c, http.StatusServiceUnavailable, "push_disabled")
+			return
+		}
+
+		var req struct {
+			Endpoint string `json:"endpoint" binding:"required"`
+			Keys     struct {
+				P256DH string `json:"p256dh" binding:"required"`
+				Auth   string `json:"auth" binding:"required"`
+			} `json:"keys"`
+			Privacy string `json:"privacy"` // full、sender 或 none，为空时使用服务器默认值
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		// 推送地址必须是 https（发送时还会拒绝连接内网地址）
+		endpoint, err := url.Parse(req.Endpoint)
+		if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" || len(req.Endpoint) > maxPushEndpointLength {
+			respondError(c, http.StatusBadRequest, "invalid_push_subscription")
+			return
+		}
+		// 浏览器使用 base64url 编码，部分实现带填充
+		p256dh, err1 := base64.RawURLEncoding.DecodeString(strings.TrimRight(req.Keys.P256DH, "="))
+		authSecret, err2 := base64.RawURLEncoding.DecodeString(strings.TrimRight(req.Keys.Auth, "="))
+		if err1 != nil || err2 != nil || len(p256dh) != 65 || len(authSecret) != 16 {
+			respondError(c, http.StatusBadRequest, "invalid_push_subscription")
+			return
+		}
+		if req.Privacy != "" && !webpush.ValidPrivacy(req.Privacy) {
+			respondError(c, http.StatusBadRequest, "invalid_push_privacy")
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := userEmail.(string)
+		existing, err := driver.ListPushSubscriptions(ctx, email)
+		if err == nil && len(existing) >= maxPushSubscriptionsPerUser {
+			isUpdate := false
+			for _, sub := range existing {
+				if sub.Endpoint == req.Endpoint {
+					isUpdate = true
+					break
+				}
+			}
+			if !isUpdate {
+				respondError(c, http.StatusConflict, "push_subscription_limit_reached")
+				return
+			}
+		}
+
+		userAgent := c.Request.UserAgent()
+		if len(userAgent) > 255 {
+			userAgent = userAgent[:255]
+		}
+		sub := &storage.PushSubscription{
+			UserEmail: email,
+			Endpoint:  req.Endpoint,
+			P256DH:    p256dh,
+			Auth:      authSecret,
+			Privacy:   req.Privacy,
+			UserAgent: userAgent,
+		}
+		if err := driver.SavePushSubscription(ctx, sub); err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("user", email).Msg("保存推送订阅失败")
+			respondError(c, http.StatusInternalServerError, "push_subscription_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"subscription": sub,
+		})
+	}
+}
+
+// deletePushSubscriptionHandler 删除推送订阅（浏览器取消订阅或在其他设备上移除）
+func deletePushSubscriptionHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid_push_subscription_id")
+			return
+		}
+		if err := driver.DeletePushSubscription(c.Request.Context(), userEmail.(string), id); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				respondError(c, http.StatusNotFound, "push_subscription_not_found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "push_subscription_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "push_subscription_deleted"),
+		})
+	}
+}
+
+// testPushHandler 向当前用户的所有订阅发送测试通知
+func testPushHandler(notifier *webpush.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if notifier == nil {
+			respondError(c, http.StatusServiceUnavailable, "push_disabled")
+			return
+		}
+
+		sent, err := notifier.Notify(c.Request.Context(), userEmail.(string), webpush.Notification{Type: webpush.TypeTest})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "push_test_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sent": sent,
+		})
+	}
+}
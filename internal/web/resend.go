@@ -0,0 +1,137 @@
+package web
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/activity"
+	"github.com/gomailzero/gmz/internal/antispam"
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/search"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// resendMailHandler 重新发送 Sent 中的邮件（例如退信后再试一次，不需要重新编辑）：
+// 使用新的 Message-ID 和 Date，作为一次新的投递保存到 Sent 并记录投递日志。
+// 请求体可选，提供 to/cc/bcc 时替换原邮件的收件人
+func resendMailHandler(driver storage.Driver, maildir *storage.Maildir, relayConfig *config.SMTPConfig, dkim *antispam.DKIMSigners, clientTLS *tls.Config, identities *identity.Manager, warmup smtpclient.Throttle, senderSigner smtpclient.SenderSigner, forwarder *forward.Forwarder, recorder *activity.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			To  []string `json:"to"`
+			Cc  []string `json:"cc"`
+			Bcc []string `json:"bcc"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		original, raw, ok := readOwnMail(c, driver, maildir)
+		if !ok {
+			return
+		}
+		if original.Folder != "Sent" {
+			respondError(c, http.StatusBadRequest, "mail_not_sent")
+			return
+		}
+
+		// 没有提供收件人时发给原邮件的收件人
+		var recipients *[3][]string
+		to, cc, bcc := original.To, original.Cc, original.Bcc
+		if len(req.To)+len(req.Cc)+len(req.Bcc) > 0 {
+			to, cc, bcc = req.To, req.Cc, req.Bcc
+			recipients = &[3][]string{to, cc, bcc}
+		}
+		allRecipients := make([]string, 0, len(to)+len(cc)+len(bcc))
+		allRecipients = append(allRecipients, to...)
+		allRecipients = append(allRecipients, cc...)
+		allRecipients = append(allRecipients, bcc...)
+		if len(allRecipients) == 0 {
+			respondError(c, http.StatusBadRequest, "mail_no_recipients")
+			return
+		}
+
+		ctx := c.Request.Context()
+		from := original.UserEmail
+
+		// 原邮件以外部发件身份发送时，仍通过该身份发送（不使用本域名的 DKIM 签名）
+		sender := from
+		signer := dkim.For(from)
+		var sendIdentity *storage.Identity
+		if original.From != "" && !strings.EqualFold(original.From, from) {
+			if identities == nil {
+				respondError(c, http.StatusNotFound, "identity_not_found")
+				return
+			}
+			found, err := identities.Find(ctx, from, original.From)
+			if err != nil {
+				respondError(c, http.StatusNotFound, "identity_not_found")
+				return
+			}
+			sendIdentity = found
+			sender = found.Address
+			signer = nil
+		}
+
+		mailData, err := resendMessage(raw, sender, recipients, signer)
+		if err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("from", from).Str("mail_id", original.ID).Msg("构建重新发送的邮件失败")
+			respondError(c, http.StatusInternalServerError, "mail_build_failed")
+			return
+		}
+
+		mail := &storage.Mail{
+			ID:         fmt.Sprintf("sent-%d", time.Now().UnixNano()),
+			UserEmail:  from,
+			Folder:     "Sent",
+			From:       sender,
+			To:         to,
+			Cc:         cc,
+			Bcc:        bcc,
+			Subject:    original.Subject,
+			Body:       original.Body,
+			Size:       int64(len(mailData)),
+			Flags:      []string{},
+			ReceivedAt: time.Now(),
+			CreatedAt:  time.Now(),
+
+			Attachments: search.ExtractAttachments(mailData),
+		}
+		if err := storage.NewMailStore(maildir, driver).Deliver(ctx, mail, mailData); err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("from", from).Msg("保存邮件到 Sent 失败")
+			storageError(c, err, "mail_save_failed")
+			return
+		}
+
+		sub := &mailSubmitter{driver: driver, maildir: maildir, relayConfig: relayConfig, clientTLS: clientTLS,
+			identities: identities, warmup: warmup, senderSigner: senderSigner, forwarder: forwarder, activity: recorder, remoteIP: c.ClientIP()}
+		result := sub.submit(ctx, from, sender, sendIdentity, mail, allRecipients, mailData)
+
+		logger.InfoCtx(ctx).
+			Str("from", from).
+			Str("original_id", original.ID).
+			Str("id", mail.ID).
+			Int("recipients", len(allRecipients)).
+			Msg("重新发送邮件")
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":            localize(c, "mail_resent"),
+			"id":                 mail.ID,
+			"resent_from":        original.ID,
+			"local_delivered":    result.localDelivered,
+			"external_delivered": result.externalDelivered,
+			"external_deferred":  result.externalDeferred,
+			"total_recipients":   len(allRecipients),
+		})
+	}
+}
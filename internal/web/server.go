@@ -2,6 +2,7 @@ package web
 
 import (
 	"context"
+	"crypto/tls"
 	"embed"
 	"fmt"
 	"io/fs"
@@ -12,11 +13,25 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/activity"
 	"github.com/gomailzero/gmz/internal/antispam"
 	"github.com/gomailzero/gmz/internal/auth"
 	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/contactform"
+	"github.com/gomailzero/gmz/internal/fetchmail"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/identity"
+	"github.com/gomailzero/gmz/internal/jmapd"
+	"github.com/gomailzero/gmz/internal/limits"
 	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+	"github.com/gomailzero/gmz/internal/newsletter"
+	"github.com/gomailzero/gmz/internal/quota"
+	"github.com/gomailzero/gmz/internal/smtpclient"
 	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/terms"
+	"github.com/gomailzero/gmz/internal/webpush"
+	"github.com/gomailzero/gmz/internal/zeroaccess"
 )
 
 //go:embed static/*
@@ -41,9 +56,32 @@ type Config struct {
 	JWTSecret   string
 	JWTIssuer   string
 	TOTPManager *auth.TOTPManager
-	AdminPort   int                // 管理 API 端口，用于代理管理界面
-	SMTPConfig  *config.SMTPConfig // SMTP 配置，用于外发邮件
-	DKIM        *antispam.DKIM     // DKIM 签名器（可选）
+	WebAuthn    *auth.WebAuthnManager // 通行密钥（可选，为空时不能注册和用于登录）
+	AdminPort   int                   // 管理 API 端口，用于代理管理界面
+	SMTPConfig  *config.SMTPConfig    // SMTP 配置，用于外发邮件
+	DKIM        *antispam.DKIMSigners // DKIM 签名器，按发件域名选择（可选）
+	TLS         *tls.Config           // HTTPS 配置（为空时使用 HTTP）
+	ClientTLS   *tls.Config           // 外发邮件使用的 TLS 配置模板（可选）
+	Forwarder   *forward.Forwarder    // 外部转发（可选，为空时不提供转发设置）
+
+	Unsubscriber *newsletter.Unsubscriber // 订阅邮件服务端退订（可选）
+	Fetcher      *fetchmail.Fetcher       // 外部邮箱拉取（可选，为空时不能添加外部账户）
+	Identities   *identity.Manager        // 外部发件身份（可选，为空时不能添加发件身份）
+	Limiter      *limits.Limiter          // 登录失败次数过多时封禁 IP（可选）
+	QuotaWarner  *quota.Warner            // 配额预警阈值（可选，为空时不显示预警横幅）
+	Warmup       smtpclient.Throttle      // 外发预热限流（可选）
+	BATV         smtpclient.SenderSigner  // 外发信封发件人签名（可选，退信地址验证）
+	NewMail      *maildirwatch.Watcher    // 新邮件事件（可选，为空时不提供实时通知）
+	Bayes        *antispam.Bayes          // 垃圾邮件分类器（可选，标记 $Junk/$NotJunk 时训练）
+	ZeroAccess   *zeroaccess.Keyring      // 零访问存储（可选，为空时用户不能启用）
+	WebPush      *webpush.Notifier        // 推送通知（可选，为空时不能订阅）
+	ContactForms *contactform.Service     // 公开联系表单（可选）
+	JMAP         bool                     // 提供 JMAP 接口（/.well-known/jmap 和 /jmap/）
+	Activity     *activity.Recorder       // 账户活动记录（可选，为空时不记录登录和发信）
+	Terms        *terms.Gate              // 登录提示和使用条款（可选，为空时不显示提示、不要求接受条款）
+
+	AccessTokenTTL time.Duration // 访问令牌有效期（默认 15m）
+	SessionTTL     time.Duration // 登录会话（刷新令牌）有效期（默认 720h）
 }
 
 // NewServer 创建 WebMail 服务器
@@ -63,8 +101,16 @@ func NewServer(cfg *Config) *Server {
 		router.StaticFS("/assets", http.FS(assetsFS))
 	}
 
+	if cfg.AccessTokenTTL <= 0 {
+		cfg.AccessTokenTTL = 15 * time.Minute
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = 720 * time.Hour
+	}
+
 	// 创建 JWT 管理器
-	jwtManager := auth.NewJWTManager(cfg.JWTSecret, cfg.JWTIssuer)
+	jwtManager := auth.NewJWTManager(cfg.JWTSecret, cfg.JWTIssuer).UseSessions(cfg.Storage)
+	apiTokens := auth.NewAPITokenManager(cfg.Storage)
 
 	// 管理界面代理（代理到管理 API 服务器）
 	// 注意：必须在 WebMail API 路由之前注册，确保 /api/v1 优先匹配
@@ -96,29 +142,118 @@ func NewServer(cfg *Config) *Server {
 		}
 	}
 
+	// 用户会话读取邮件使用的 Maildir：零访问存储的邮件在用户解锁后解密
+	mailReader := cfg.Maildir
+	if cfg.ZeroAccess != nil {
+		mailReader = cfg.Maildir.WithOpener(cfg.ZeroAccess)
+	}
+
 	// API 路由（WebMail API，注意 /api/v1 已经在上面被代理了）
 	api := router.Group("/api")
 	{
 		// 公开端点（不需要认证）
 		api.GET("/init/check", checkInitHandler(cfg.Storage))
-		api.POST("/init", initSystemHandler(cfg.Storage, jwtManager, cfg.Domain))
-		api.POST("/login", loginHandler(cfg.Storage, jwtManager, cfg.TOTPManager))
+		api.POST("/init", initSystemHandler(cfg.Storage, jwtManager, cfg.Domain, cfg.AccessTokenTTL, cfg.SessionTTL))
+		api.GET("/banner", bannerHandler(cfg.Terms))
+		api.POST("/login", loginHandler(cfg.Storage, jwtManager, cfg.TOTPManager, cfg.WebAuthn, cfg.Limiter, cfg.ZeroAccess, cfg.Activity, cfg.Terms, cfg.AccessTokenTTL, cfg.SessionTTL))
+		api.POST("/login/webauthn", beginWebAuthnLoginHandler(cfg.Storage, cfg.WebAuthn, cfg.Limiter))
+		api.POST("/auth/refresh", refreshTokenHandler(jwtManager, cfg.AccessTokenTTL))
+		api.OPTIONS("/public/contact/:domain", contactFormPreflightHandler(cfg.ContactForms))
+		api.POST("/public/contact/:domain", contactFormHandler(cfg.ContactForms))
 
 		// 需要认证的端点
-		api.Use(jwtMiddleware(jwtManager, cfg.Storage))
+		api.Use(jwtMiddleware(jwtManager, apiTokens, cfg.Storage))
 		{
-			api.GET("/me", getCurrentUserHandler(cfg.Storage)) // 获取当前用户信息
+			api.GET("/me", getCurrentUserHandler(cfg.Storage, cfg.QuotaWarner)) // 获取当前用户信息
 			api.GET("/mails", listMailsHandler(cfg.Storage))
 			api.GET("/mails/search", searchMailsHandler(cfg.Storage))
-			api.GET("/mails/:id", getMailHandler(cfg.Storage, cfg.Maildir))
-			api.POST("/mails", sendMailHandler(cfg.Storage, cfg.Maildir, cfg.SMTPConfig, cfg.DKIM))
+			api.GET("/mails/threads", listThreadsHandler(cfg.Storage))
+			api.GET("/mails/threads/:id", getThreadHandler(cfg.Storage))
+			api.GET("/mails/events", mailEventsHandler(cfg.NewMail))
+			api.GET("/attachments", listAttachmentsHandler(cfg.Storage))
+			api.GET("/mails/autoresponder", getAutoResponderHandler(cfg.Storage))
+			api.PUT("/mails/autoresponder", updateAutoResponderHandler(cfg.Storage))
+			api.DELETE("/mails/autoresponder", deleteAutoResponderHandler(cfg.Storage))
+			api.GET("/mails/:id", getMailHandler(cfg.Storage, mailReader))
+			api.GET("/mails/:id/export", exportMailHandler(cfg.Storage, mailReader))
+			api.GET("/mails/:id/attachments", listMailAttachmentsHandler(cfg.Storage, mailReader))
+			api.GET("/mails/:id/attachments/:index", downloadAttachmentHandler(cfg.Storage, mailReader))
+			api.POST("/mails", sendMailHandler(cfg.Storage, cfg.Maildir, cfg.SMTPConfig, cfg.DKIM, cfg.ClientTLS, cfg.Identities, cfg.Warmup, cfg.BATV, cfg.Forwarder, cfg.Activity))
+			api.POST("/mails/:id/resend", resendMailHandler(cfg.Storage, mailReader, cfg.SMTPConfig, cfg.DKIM, cfg.ClientTLS, cfg.Identities, cfg.Warmup, cfg.BATV, cfg.Forwarder, cfg.Activity))
 			api.POST("/mails/drafts", saveDraftHandler(cfg.Storage))
 			api.DELETE("/mails/:id", deleteMailHandler(cfg.Storage))
-			api.PUT("/mails/:id/flags", updateMailFlagsHandler(cfg.Storage))
+			api.PUT("/mails/:id/flags", updateMailFlagsHandler(cfg.Storage, mailReader, cfg.Bayes))
+			api.PUT("/mails/:id/category", updateMailCategoryHandler(cfg.Storage))
+			api.GET("/mails/:id/annotations", listAnnotationsHandler(cfg.Storage))
+			api.PUT("/mails/:id/annotations", updateAnnotationsHandler(cfg.Storage))
+			api.GET("/mails/:id/unsubscribe", getUnsubscribeHandler(cfg.Storage))
+			api.POST("/mails/:id/unsubscribe", unsubscribeHandler(cfg.Storage, cfg.Unsubscriber))
+			api.GET("/newsletters/settings", getNewsletterSettingsHandler(cfg.Storage))
+			api.PUT("/newsletters/settings", updateNewsletterSettingsHandler(cfg.Storage))
+			api.GET("/settings/timezone", getTimezoneHandler(cfg.Storage))
+			api.PUT("/settings/timezone", updateTimezoneHandler(cfg.Storage))
+			api.GET("/settings/digest", getDigestHandler(cfg.Storage))
+			api.PUT("/settings/digest", updateDigestHandler(cfg.Storage))
 			api.GET("/folders", listFoldersHandler(cfg.Storage))
+			api.GET("/sync", syncHandler(cfg.Storage))
+			api.GET("/usage", usageHandler(cfg.Storage))
+			api.GET("/stats", statsHandler(cfg.Storage))
+			api.GET("/aliases", listMyAliasesHandler(cfg.Storage))
+			api.POST("/aliases", createMyAliasHandler(cfg.Storage))
+			api.DELETE("/aliases/:address", deleteMyAliasHandler(cfg.Storage))
+			api.POST("/aliases/disposable", createDisposableAliasHandler(cfg.Storage))
+			api.POST("/aliases/:address/burn", burnAliasHandler(cfg.Storage))
+			api.GET("/forwarding", getForwardingHandler(cfg.Storage, cfg.Forwarder))
+			api.PUT("/forwarding", updateForwardingHandler(cfg.Forwarder))
+			api.POST("/forwarding/verify", verifyForwardingHandler(cfg.Forwarder))
+			api.DELETE("/forwarding", deleteForwardingHandler(cfg.Storage))
+			api.GET("/external-accounts", listExternalAccountsHandler(cfg.Storage, cfg.Fetcher))
+			api.POST("/external-accounts", createExternalAccountHandler(cfg.Storage, cfg.Fetcher))
+			api.PUT("/external-accounts/:id", updateExternalAccountHandler(cfg.Storage, cfg.Fetcher))
+			api.DELETE("/external-accounts/:id", deleteExternalAccountHandler(cfg.Storage, cfg.Fetcher))
+			api.POST("/external-accounts/:id/fetch", fetchExternalAccountHandler(cfg.Storage, cfg.Fetcher))
+			api.GET("/identities", listIdentitiesHandler(cfg.Storage, cfg.Identities))
+			api.POST("/identities", createIdentityHandler(cfg.Storage, cfg.Identities))
+			api.PUT("/identities/:id", updateIdentityHandler(cfg.Storage, cfg.Identities))
+			api.DELETE("/identities/:id", deleteIdentityHandler(cfg.Storage, cfg.Identities))
+			api.GET("/tokens", listAPITokensHandler(cfg.Storage))
+			api.POST("/tokens", createAPITokenHandler(cfg.Storage, apiTokens))
+			api.DELETE("/tokens/:id", deleteAPITokenHandler(cfg.Storage))
+			api.GET("/sessions", listSessionsHandler(cfg.Storage))
+			api.GET("/activity", activityHandler(cfg.Storage))
+			api.DELETE("/sessions/:id", revokeSessionHandler(cfg.Storage))
+			api.POST("/sessions/revoke-all", revokeAllSessionsHandler(cfg.Storage))
+			api.GET("/webauthn", getWebAuthnHandler(cfg.Storage, cfg.WebAuthn))
+			api.POST("/webauthn/register/begin", beginWebAuthnRegistrationHandler(cfg.WebAuthn))
+			api.POST("/webauthn/register/finish", finishWebAuthnRegistrationHandler(cfg.WebAuthn))
+			api.DELETE("/webauthn/credentials/:id", deleteWebAuthnCredentialHandler(cfg.Storage))
+			api.GET("/zero-access", getZeroAccessHandler(cfg.ZeroAccess))
+			api.POST("/zero-access/enable", enableZeroAccessHandler(cfg.Storage, cfg.Maildir, cfg.ZeroAccess))
+			api.POST("/zero-access/recover", recoverZeroAccessHandler(cfg.Storage, cfg.ZeroAccess))
+			api.GET("/push", getPushHandler(cfg.Storage, cfg.WebPush))
+			api.POST("/push/subscriptions", createPushSubscriptionHandler(cfg.Storage, cfg.WebPush))
+			api.DELETE("/push/subscriptions/:id", deletePushSubscriptionHandler(cfg.Storage))
+			api.POST("/push/test", testPushHandler(cfg.WebPush))
+			api.GET("/vacation", getVacationHandler(cfg.Storage))
+			api.PUT("/vacation", updateVacationHandler(cfg.Storage))
+			api.GET("/directory", directoryHandler(cfg.Storage))
+			api.GET("/directory/:email", directoryEntryHandler(cfg.Storage))
 		}
 	}
 
+	// JMAP 接口（与 WebMail 共用登录令牌和个人访问令牌）
+	if cfg.JMAP {
+		j := gin.WrapH(jmapd.New(&jmapd.Config{
+			Storage:   cfg.Storage,
+			Maildir:   mailReader,
+			JWT:       jwtManager,
+			APITokens: apiTokens,
+			NewMail:   cfg.NewMail,
+		}))
+		router.GET("/.well-known/jmap", j)
+		router.Any("/jmap/*path", j)
+	}
+
 	// 根路径返回 index.html
 	router.GET("/", func(c *gin.Context) {
 		data, err := staticFiles.ReadFile("static/index.html")
@@ -133,7 +268,7 @@ func NewServer(cfg *Config) *Server {
 	router.NoRoute(func(c *gin.Context) {
 		// 排除 API、静态资源和管理界面路径
 		path := c.Request.URL.Path
-		if strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/static") || strings.HasPrefix(path, "/assets") || strings.HasPrefix(path, "/admin") {
+		if strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/static") || strings.HasPrefix(path, "/assets") || strings.HasPrefix(path, "/admin") || strings.HasPrefix(path, "/jmap") {
 			c.Status(http.StatusNotFound)
 			return
 		}
@@ -165,9 +300,16 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:       60 * time.Second,
 	}
 
-	logger.Info().Int("port", s.config.Port).Str("path", s.config.Path).Msg("WebMail 服务器启动")
+	logger.Info().Int("port", s.config.Port).Str("path", s.config.Path).Bool("tls", s.config.TLS != nil).Msg("WebMail 服务器启动")
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	var err error
+	if s.config.TLS != nil {
+		s.server.TLSConfig = s.config.TLS
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("WebMail 服务器错误: %w", err)
 	}
 
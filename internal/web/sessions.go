@@ -0,0 +1,145 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// sessionView 登录会话，current 标记发起请求的会话
+type sessionView struct {
+	*storage.Session
+	Current bool `json:"current"`
+}
+
+// refreshTokenHandler 用刷新令牌换取新的访问令牌（公开端点）：同时返回轮换后的刷新令牌，旧的刷新令牌随即失效；
+// 会话被吊销或过期后返回 401，客户端需要重新登录
+func refreshTokenHandler(jwtManager *auth.JWTManager, accessTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		tokens, err := jwtManager.Refresh(c.Request.Context(), req.RefreshToken, accessTTL)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			respondError(c, http.StatusUnauthorized, "invalid_token")
+			return
+		}
+		if err != nil {
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			respondError(c, http.StatusInternalServerError, "token_generate_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"token":         tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    int(tokens.ExpiresIn.Seconds()),
+		})
+	}
+}
+
+// listSessionsHandler 列出当前用户的登录会话和个人访问令牌（包括最近活动时间）
+func listSessionsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := userEmail.(string)
+		sessions, err := driver.ListSessions(ctx, email)
+		if err != nil {
+			storageError(c, err, "session_get_failed")
+			return
+		}
+		tokens, err := driver.ListAPITokens(ctx, email)
+		if err != nil {
+			storageError(c, err, "session_get_failed")
+			return
+		}
+		if tokens == nil {
+			tokens = []*storage.APIToken{}
+		}
+
+		current := c.GetString("session_id")
+		views := make([]sessionView, 0, len(sessions))
+		for _, session := range sessions {
+			views = append(views, sessionView{Session: session, Current: session.ID == current})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sessions": views,
+			"tokens":   tokens,
+		})
+	}
+}
+
+// revokeSessionHandler 吊销当前用户的登录会话（吊销当前会话即退出登录）
+func revokeSessionHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		if err := driver.DeleteSession(c.Request.Context(), userEmail.(string), c.Param("id")); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				respondError(c, http.StatusNotFound, "session_not_found")
+				return
+			}
+			storageError(c, err, "session_revoke_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "session_revoked"),
+		})
+	}
+}
+
+// revokeAllSessionsHandler 在所有地方退出登录：吊销当前用户的全部登录会话（包括当前会话）和个人访问令牌
+func revokeAllSessionsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := userEmail.(string)
+		sessions, err := driver.DeleteUserSessions(ctx, email)
+		if err != nil {
+			storageError(c, err, "session_revoke_failed")
+			return
+		}
+		tokens, err := driver.DeleteUserAPITokens(ctx, email)
+		if err != nil {
+			storageError(c, err, "session_revoke_failed")
+			return
+		}
+
+		logger.InfoCtx(ctx).Str("user", email).Int64("sessions", sessions).Int64("tokens", tokens).Msg("用户在所有地方退出登录")
+		c.JSON(http.StatusOK, gin.H{
+			"message":  localize(c, "sessions_revoked"),
+			"sessions": sessions,
+			"tokens":   tokens,
+		})
+	}
+}
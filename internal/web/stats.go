@@ -0,0 +1,124 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// statsDefaultDays 统计页默认时间范围（天）
+	statsDefaultDays = 30
+	// statsMaxDays 统计页最大时间范围（天）
+	statsMaxDays = 365
+	// statsTopContacts 往来联系人排行数量
+	statsTopContacts = 10
+)
+
+// weeklyStats 每周邮件统计
+type weeklyStats struct {
+	Week     string `json:"week"` // ISO 周（如 2024-W05）
+	Received int    `json:"received"`
+	Sent     int    `json:"sent"`
+}
+
+// statsHandler 获取当前用户的邮件统计（来自每小时刷新的汇总表）：
+// 每日/每周收发量、往来最多的联系人、平均回复耗时和附件量
+func statsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(statsDefaultDays)))
+		if err != nil || days < 1 || days > statsMaxDays {
+			respondError(c, http.StatusBadRequest, "invalid_days", statsMaxDays)
+			return
+		}
+
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+		since := time.Now().AddDate(0, 0, -(days - 1))
+
+		daily, err := driver.ListDailyStats(ctx, email, since)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "mail_stats_get_failed")
+			return
+		}
+		senders, err := driver.TopContacts(ctx, email, storage.ContactIncoming, since, statsTopContacts)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "correspondents_get_failed")
+			return
+		}
+		recipients, err := driver.TopContacts(ctx, email, storage.ContactOutgoing, since, statsTopContacts)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "correspondents_get_failed")
+			return
+		}
+
+		var received, sent, attachments, replies int
+		var attachmentBytes, replySeconds int64
+		weekly := []*weeklyStats{}
+		for _, d := range daily {
+			received += d.Received
+			sent += d.Sent
+			attachments += d.Attachments
+			attachmentBytes += d.AttachmentBytes
+			replies += d.Replies
+			replySeconds += d.ReplySeconds
+
+			week := isoWeek(d.Day)
+			if len(weekly) == 0 || weekly[len(weekly)-1].Week != week {
+				weekly = append(weekly, &weeklyStats{Week: week})
+			}
+			weekly[len(weekly)-1].Received += d.Received
+			weekly[len(weekly)-1].Sent += d.Sent
+		}
+
+		// 没有可计算的回复时返回 0
+		var avgReply int64
+		if replies > 0 {
+			avgReply = replySeconds / int64(replies)
+		}
+
+		if daily == nil {
+			daily = []*storage.DailyStats{}
+		}
+		if senders == nil {
+			senders = []*storage.ContactCount{}
+		}
+		if recipients == nil {
+			recipients = []*storage.ContactCount{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"days":                  days,
+			"received":              received,
+			"sent":                  sent,
+			"attachments":           attachments,
+			"attachment_bytes":      attachmentBytes,
+			"average_reply_seconds": avgReply,
+			"daily":                 daily,
+			"weekly":                weekly,
+			"top_senders":           senders,
+			"top_recipients":        recipients,
+		})
+	}
+}
+
+// isoWeek 返回日期（YYYY-MM-DD）所在的 ISO 周
+func isoWeek(day string) string {
+	t, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return day
+	}
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
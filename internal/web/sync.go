@@ -0,0 +1,89 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// defaultSyncLimit 每次增量同步默认返回的最大邮件数
+	defaultSyncLimit = 500
+	// maxSyncLimit 每次增量同步最多返回的邮件数
+	maxSyncLimit = 2000
+)
+
+// syncHandler 增量同步：返回自修改序号 since 以来新增、更新和删除的邮件元数据及有变化的文件夹
+// （since 为 0 或省略时返回所有邮件；more 为 true 时以返回的 modseq 继续同步）
+func syncHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		since, err := strconv.ParseUint(c.DefaultQuery("since", "0"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid_since")
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSyncLimit)))
+		if err != nil || limit < 1 {
+			respondError(c, http.StatusBadRequest, "invalid_limit")
+			return
+		}
+		if limit > maxSyncLimit {
+			limit = maxSyncLimit
+		}
+
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+		changes, err := driver.GetMailChanges(ctx, email, since, limit)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "mail_changes_get_failed")
+			return
+		}
+		for _, mail := range changes.Created {
+			mail.Category = category.FromFlags(mail.Flags)
+		}
+		for _, mail := range changes.Updated {
+			mail.Category = category.FromFlags(mail.Flags)
+		}
+
+		// 有变化的文件夹附带当前邮件数（邮件全部移出或删除的文件夹邮件数为 0）
+		folders := make([]*storage.FolderUsage, 0, len(changes.Folders))
+		if len(changes.Folders) > 0 {
+			usage, err := driver.GetFolderUsage(ctx, email)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "folder_usage_get_failed")
+				return
+			}
+			byFolder := make(map[string]*storage.FolderUsage, len(usage))
+			for _, u := range usage {
+				byFolder[u.Folder] = u
+			}
+			for _, folder := range changes.Folders {
+				if u, ok := byFolder[folder]; ok {
+					folders = append(folders, u)
+				} else {
+					folders = append(folders, &storage.FolderUsage{Folder: folder})
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"modseq":  changes.ModSeq,
+			"more":    changes.More,
+			"reset":   changes.Reset,
+			"created": changes.Created,
+			"updated": changes.Updated,
+			"deleted": changes.Deleted,
+			"folders": folders,
+		})
+	}
+}
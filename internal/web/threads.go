@@ -0,0 +1,69 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/category"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// listThreadsHandler 会话列表：包含 folder 中邮件的会话（folder 为空时列出所有会话），按最新邮件时间倒序，
+// 每个会话的统计包括所有文件夹中的邮件（如已发送的回复）
+func listThreadsHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		folder := c.DefaultQuery("folder", "INBOX")
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+		threads, err := driver.ListThreads(c.Request.Context(), userEmail.(string), folder, limit, offset)
+		if err != nil {
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			storageError(c, err, "thread_list_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"threads": threads,
+		})
+	}
+}
+
+// getThreadHandler 会话中的所有邮件（包括所有文件夹），按接收时间升序
+func getThreadHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		mails, err := driver.ListThreadMails(c.Request.Context(), userEmail.(string), c.Param("id"))
+		if err != nil {
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			storageError(c, err, "mail_list_failed")
+			return
+		}
+		if len(mails) == 0 {
+			respondError(c, http.StatusNotFound, "thread_not_found")
+			return
+		}
+		for _, mail := range mails {
+			mail.Category = category.FromFlags(mail.Flags)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"id":    c.Param("id"),
+			"mails": mails,
+		})
+	}
+}
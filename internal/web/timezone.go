@@ -0,0 +1,87 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// getTimezoneHandler 获取当前用户的生效时区及其来源（system、domain、user）
+func getTimezoneHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		effective, err := storage.LoadEffectiveSettings(c.Request.Context(), driver, userEmail.(string))
+		if err != nil {
+			storageError(c, err, "timezone_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"timezone": effective.Timezone,
+			"source":   effective.Sources["timezone"],
+		})
+	}
+}
+
+// updateTimezoneHandler 设置当前用户的时区（IANA 时区名，为空时继承域名默认设置），用于通知邮件中的时间显示
+// 只修改用户设置中的时区，其他覆盖项保持不变
+func updateTimezoneHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		var req struct {
+			Timezone string `json:"timezone"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := userEmail.(string)
+		settings, err := driver.GetUserSettings(ctx, email)
+		if errors.Is(err, storage.ErrNotFound) {
+			settings, err = &storage.UserSettings{UserEmail: email}, nil
+		}
+		if err != nil {
+			storageError(c, err, "timezone_save_failed")
+			return
+		}
+		settings.Timezone = nil
+		if timezone := strings.TrimSpace(req.Timezone); timezone != "" {
+			settings.Timezone = &timezone
+		}
+		if err := driver.SaveUserSettings(ctx, settings); err != nil {
+			if errors.Is(err, storage.ErrInvalidInput) {
+				respondErrorDetail(c, http.StatusBadRequest, "invalid_timezone", err)
+				return
+			}
+			storageError(c, err, "timezone_save_failed")
+			return
+		}
+
+		effective, err := storage.LoadEffectiveSettings(ctx, driver, email)
+		if err != nil {
+			storageError(c, err, "timezone_get_failed")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"timezone": effective.Timezone,
+			"source":   effective.Sources["timezone"],
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// maxAPITokensPerUser 每个用户最多的个人访问令牌数
+	maxAPITokensPerUser = 50
+	// maxAPITokenDays 个人访问令牌的最长有效天数（0 表示永不过期）
+	maxAPITokenDays = 365
+)
+
+// listAPITokensHandler 列出当前用户的个人访问令牌（不包含令牌明文）
+func listAPITokensHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		tokens, err := driver.ListAPITokens(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "token_get_failed")
+			return
+		}
+		if tokens == nil {
+			tokens = []*storage.APIToken{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"tokens": tokens,
+		})
+	}
+}
+
+// createAPITokenHandler 创建个人访问令牌（令牌明文只在响应中返回一次）
+func createAPITokenHandler(driver storage.Driver, apiTokens *auth.APITokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		var req struct {
+			Name          string `json:"name" binding:"required"`
+			Scope         string `json:"scope" binding:"required"` // read, send 或 full
+			ExpiresInDays int    `json:"expires_in_days"`          // 有效天数，0 表示永不过期
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > 100 {
+			respondError(c, http.StatusBadRequest, "token_name_invalid")
+			return
+		}
+		if req.ExpiresInDays < 0 || req.ExpiresInDays > maxAPITokenDays {
+			respondError(c, http.StatusBadRequest, "token_expiry_invalid")
+			return
+		}
+
+		ctx := c.Request.Context()
+		email := userEmail.(string)
+		existing, err := driver.ListAPITokens(ctx, email)
+		if err == nil && len(existing) >= maxAPITokensPerUser {
+			respondError(c, http.StatusConflict, "token_limit_reached")
+			return
+		}
+
+		var expiresAt *time.Time
+		if req.ExpiresInDays > 0 {
+			t := time.Now().AddDate(0, 0, req.ExpiresInDays)
+			expiresAt = &t
+		}
+		plaintext, token, err := apiTokens.Create(ctx, email, req.Name, req.Scope, expiresAt)
+		if errors.Is(err, auth.ErrInvalidScope) {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_scope", err)
+			return
+		}
+		if err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("user", email).Msg("创建访问令牌失败")
+			respondError(c, http.StatusInternalServerError, "token_create_failed")
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"token":     plaintext,
+			"api_token": token,
+		})
+	}
+}
+
+// deleteAPITokenHandler 吊销个人访问令牌
+func deleteAPITokenHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid_token_id")
+			return
+		}
+		if err := driver.DeleteAPIToken(c.Request.Context(), userEmail.(string), id); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				respondError(c, http.StatusNotFound, "token_not_found")
+				return
+			}
+			respondError(c, http.StatusInternalServerError, "token_revoke_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "token_revoked"),
+		})
+	}
+}
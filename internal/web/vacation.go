@@ -0,0 +1,202 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+const (
+	// maxVacationMessage 外出说明最大长度（字符）
+	maxVacationMessage = 500
+	// directoryLimit 目录查询最多返回的用户数
+	directoryLimit = 20
+	// directoryScanLimit 目录查询扫描的用户数上限
+	directoryScanLimit = 10000
+)
+
+// directoryEntry 目录中的用户及其外出状态
+type directoryEntry struct {
+	Email     string     `json:"email"`
+	Away      bool       `json:"away"`
+	AwayUntil *time.Time `json:"away_until,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// newDirectoryEntry 创建目录条目（只有处于外出时间内才展示外出状态）
+func newDirectoryEntry(email string, vacation *storage.Vacation, now time.Time) *directoryEntry {
+	entry := &directoryEntry{Email: email}
+	if vacation != nil && vacation.Away(now) {
+		entry.Away = true
+		entry.AwayUntil = vacation.EndsAt
+		entry.Message = vacation.Message
+	}
+	return entry
+}
+
+// sameDomain 判断两个邮箱地址是否属于同一域名
+func sameDomain(a, b string) bool {
+	da := a[strings.LastIndex(a, "@")+1:]
+	db := b[strings.LastIndex(b, "@")+1:]
+	return strings.EqualFold(da, db)
+}
+
+// getVacationHandler 获取当前用户的外出状态
+func getVacationHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		vacation, err := driver.GetVacation(c.Request.Context(), email)
+		if errors.Is(err, storage.ErrNotFound) {
+			vacation = &storage.Vacation{UserEmail: email}
+		} else if err != nil {
+			respondError(c, http.StatusInternalServerError, "vacation_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"vacation": vacation,
+			"away":     vacation.Away(time.Now()),
+		})
+	}
+}
+
+// updateVacationHandler 设置当前用户的外出状态（同域用户可在目录和 IMAP METADATA 中看到）
+func updateVacationHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+
+		var req struct {
+			Enabled  bool       `json:"enabled"`
+			Message  string     `json:"message"`
+			StartsAt *time.Time `json:"starts_at"`
+			EndsAt   *time.Time `json:"ends_at"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		req.Message = strings.TrimSpace(req.Message)
+		if len([]rune(req.Message)) > maxVacationMessage {
+			respondError(c, http.StatusBadRequest, "vacation_message_too_long")
+			return
+		}
+		if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+			respondError(c, http.StatusBadRequest, "end_before_start")
+			return
+		}
+
+		vacation := &storage.Vacation{
+			UserEmail: email,
+			Enabled:   req.Enabled,
+			Message:   req.Message,
+			StartsAt:  req.StartsAt,
+			EndsAt:    req.EndsAt,
+		}
+		if err := driver.SaveVacation(c.Request.Context(), vacation); err != nil {
+			respondError(c, http.StatusInternalServerError, "vacation_save_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"vacation": vacation,
+			"away":     vacation.Away(time.Now()),
+		})
+	}
+}
+
+// directoryHandler 按关键字查询同域用户及其外出状态（用于写信时提示收件人外出）
+func directoryHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+		q := strings.ToLower(strings.TrimSpace(c.Query("q")))
+
+		users, err := driver.ListUsers(ctx, directoryScanLimit, 0)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "user_list_get_failed")
+			return
+		}
+		vacations, err := driver.ListVacations(ctx)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "vacation_get_failed")
+			return
+		}
+		byEmail := make(map[string]*storage.Vacation, len(vacations))
+		for _, vacation := range vacations {
+			byEmail[strings.ToLower(vacation.UserEmail)] = vacation
+		}
+
+		now := time.Now()
+		entries := make([]*directoryEntry, 0)
+		for _, user := range users {
+			if !user.Active || !sameDomain(user.Email, email) {
+				continue
+			}
+			if q != "" && !strings.Contains(strings.ToLower(user.Email), q) {
+				continue
+			}
+			entries = append(entries, newDirectoryEntry(user.Email, byEmail[strings.ToLower(user.Email)], now))
+			if len(entries) >= directoryLimit {
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"users": entries,
+		})
+	}
+}
+
+// directoryEntryHandler 获取单个同域用户的外出状态
+func directoryEntryHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		email := userEmail.(string)
+		ctx := c.Request.Context()
+		target := strings.ToLower(strings.TrimSpace(c.Param("email")))
+
+		// 只能查询同域用户，其他域名按不存在处理，避免泄露用户是否存在
+		user, err := driver.GetUser(ctx, target)
+		if err != nil || !user.Active || !sameDomain(user.Email, email) {
+			respondError(c, http.StatusNotFound, "user_not_found")
+			return
+		}
+
+		vacation, err := driver.GetVacation(ctx, user.Email)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			respondError(c, http.StatusInternalServerError, "vacation_get_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, newDirectoryEntry(user.Email, vacation, time.Now()))
+	}
+}
@@ -0,0 +1,208 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/limits"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// maxWebAuthnNameLength 凭证名称的最大长度
+const maxWebAuthnNameLength = 64
+
+// getWebAuthnHandler 返回是否启用了通行密钥和当前用户注册的凭证
+func getWebAuthnHandler(driver storage.Driver, manager *auth.WebAuthnManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if manager == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+
+		creds, err := driver.ListWebAuthnCredentials(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			storageError(c, err, "webauthn_get_failed")
+			return
+		}
+		if creds == nil {
+			creds = []*storage.WebAuthnCredential{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":     true,
+			"credentials": creds,
+		})
+	}
+}
+
+// beginWebAuthnRegistrationHandler 开始注册通行密钥：返回流程 ID 和传给 navigator.credentials.create() 的参数
+func beginWebAuthnRegistrationHandler(manager *auth.WebAuthnManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if manager == nil {
+			respondError(c, http.StatusServiceUnavailable, "webauthn_disabled")
+			return
+		}
+
+		id, options, err := manager.BeginRegistration(c.Request.Context(), userEmail.(string))
+		if err != nil {
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			respondError(c, http.StatusInternalServerError, "webauthn_begin_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"ceremony_id": id,
+			"options":     options,
+		})
+	}
+}
+
+// finishWebAuthnRegistrationHandler 完成注册通行密钥：验证并保存浏览器返回的凭证（PublicKeyCredential.toJSON() 的内容）
+func finishWebAuthnRegistrationHandler(manager *auth.WebAuthnManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if manager == nil {
+			respondError(c, http.StatusServiceUnavailable, "webauthn_disabled")
+			return
+		}
+
+		var req struct {
+			CeremonyID string          `json:"ceremony_id" binding:"required"`
+			Name       string          `json:"name"`
+			Response   json.RawMessage `json:"response" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		if len(req.Name) > maxWebAuthnNameLength {
+			respondError(c, http.StatusBadRequest, "webauthn_name_too_long")
+			return
+		}
+
+		cred, err := manager.FinishRegistration(c.Request.Context(), userEmail.(string), req.CeremonyID, req.Name, req.Response)
+		if err != nil {
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			switch {
+			case errors.Is(err, auth.ErrInvalidCeremony):
+				respondError(c, http.StatusBadRequest, "webauthn_ceremony_invalid")
+			case errors.Is(err, storage.ErrAlreadyExists):
+				respondError(c, http.StatusConflict, "webauthn_credential_exists")
+			default:
+				respondError(c, http.StatusBadRequest, "webauthn_registration_failed")
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, cred)
+	}
+}
+
+// deleteWebAuthnCredentialHandler 删除当前用户的通行密钥
+func deleteWebAuthnCredentialHandler(driver storage.Driver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userEmail, exists := c.Get("user_email")
+		if !exists {
+			respondError(c, http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, "invalid_webauthn_credential_id")
+			return
+		}
+		if err := driver.DeleteWebAuthnCredential(c.Request.Context(), userEmail.(string), id); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				respondError(c, http.StatusNotFound, "webauthn_credential_not_found")
+				return
+			}
+			storageError(c, err, "webauthn_credential_delete_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": localize(c, "webauthn_credential_deleted"),
+		})
+	}
+}
+
+// beginWebAuthnLoginHandler 开始通行密钥登录（公开端点）：验证密码后返回流程 ID 和传给 navigator.credentials.get() 的参数，
+// 浏览器返回的断言随 POST /api/login 提交（webauthn_ceremony 和 webauthn_response），代替 TOTP 代码
+func beginWebAuthnLoginHandler(driver storage.Driver, manager *auth.WebAuthnManager, limiter *limits.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if manager == nil {
+			respondError(c, http.StatusServiceUnavailable, "webauthn_disabled")
+			return
+		}
+
+		var req struct {
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+
+		// 与登录共享认证失败封禁
+		ip := c.ClientIP()
+		if err := limiter.Allow("webmail", ip); err != nil {
+			respondError(c, http.StatusTooManyRequests, "too_many_attempts")
+			return
+		}
+
+		ctx := c.Request.Context()
+		user, err := driver.GetUser(ctx, req.Email)
+		if err != nil {
+			limiter.Failure("webmail", ip)
+			respondError(c, http.StatusUnauthorized, "auth_failed")
+			return
+		}
+		valid, err := crypto.VerifyPassword(req.Password, user.PasswordHash)
+		if err != nil || !valid {
+			limiter.Failure("webmail", ip)
+			respondError(c, http.StatusUnauthorized, "auth_failed")
+			return
+		}
+
+		id, options, err := manager.BeginLogin(ctx, user.Email)
+		if errors.Is(err, auth.ErrNoWebAuthnCredentials) {
+			respondError(c, http.StatusBadRequest, "webauthn_not_registered")
+			return
+		}
+		if err != nil {
+			_ = c.Error(err) // #nosec G104 -- c.Error 用于记录错误，返回值不需要检查
+			respondError(c, http.StatusInternalServerError, "webauthn_begin_failed")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"ceremony_id": id,
+			"options":     options,
+		})
+	}
+}
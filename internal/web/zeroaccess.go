@@ -0,0 +1,142 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/zeroaccess"
+)
+
+// zeroAccessUser 获取当前用户并检查是否启用了零访问存储功能，失败时写入响应并返回空字符串
+func zeroAccessUser(c *gin.Context, keyring *zeroaccess.Keyring) string {
+	userEmail, exists := c.Get("user_email")
+	if !exists {
+		respondError(c, http.StatusUnauthorized, "unauthorized")
+		c.Abort()
+		return ""
+	}
+	if keyring == nil {
+		respondError(c, http.StatusServiceUnavailable, "zero_access_disabled")
+		return ""
+	}
+	return userEmail.(string)
+}
+
+// verifyUserPassword 重新验证当前用户的密码（启用和恢复零访问存储需要密码），失败时写入响应
+func verifyUserPassword(c *gin.Context, driver storage.Driver, email, password string) bool {
+	user, err := driver.GetUser(c.Request.Context(), email)
+	if err != nil {
+		storageError(c, err, "user_get_failed")
+		return false
+	}
+	if valid, err := crypto.VerifyPassword(password, user.PasswordHash); err != nil || !valid {
+		respondError(c, http.StatusUnauthorized, "auth_failed")
+		return false
+	}
+	return true
+}
+
+// getZeroAccessHandler 获取当前用户的零访问存储状态
+func getZeroAccessHandler(keyring *zeroaccess.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := zeroAccessUser(c, keyring)
+		if email == "" {
+			return
+		}
+		enabled, err := keyring.Enabled(c.Request.Context(), email)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "zero_access_get_failed")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":  enabled,
+			"unlocked": keyring.Unlocked(email),
+		})
+	}
+}
+
+// enableZeroAccessHandler 为当前用户启用零访问存储：之后投递的邮件和已有的邮件都加密保存，
+// 返回只显示一次的恢复密钥（管理员重置密码后用于找回邮件）
+func enableZeroAccessHandler(driver storage.Driver, maildir *storage.Maildir, keyring *zeroaccess.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := zeroAccessUser(c, keyring)
+		if email == "" {
+			return
+		}
+		var req struct {
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		if !verifyUserPassword(c, driver, email, req.Password) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		recoveryKey, err := keyring.Enable(ctx, email, req.Password)
+		if errors.Is(err, zeroaccess.ErrAlreadyEnabled) {
+			respondError(c, http.StatusConflict, "zero_access_already_enabled")
+			return
+		}
+		if err != nil {
+			logger.ErrorCtx(ctx).Err(err).Str("user", email).Msg("启用零访问存储失败")
+			respondError(c, http.StatusInternalServerError, "zero_access_enable_failed")
+			return
+		}
+
+		// 加密已有的邮件（失败时已加密的部分保持加密，未加密的邮件仍然可以读取，再次启用不会重复加密）
+		sealed := 0
+		if maildir != nil {
+			if sealed, err = maildir.SealExisting(email); err != nil {
+				logger.ErrorCtx(ctx).Err(err).Str("user", email).Int("sealed", sealed).Msg("加密已有邮件失败")
+			}
+		}
+		logger.InfoCtx(ctx).Str("user", email).Int("sealed", sealed).Msg("已启用零访问存储")
+		c.JSON(http.StatusOK, gin.H{
+			"recovery_key": recoveryKey,
+			"sealed":       sealed,
+		})
+	}
+}
+
+// recoverZeroAccessHandler 用恢复密钥找回零访问存储的私钥，并改为用当前密码加密（管理员重置密码后使用）
+func recoverZeroAccessHandler(driver storage.Driver, keyring *zeroaccess.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email := zeroAccessUser(c, keyring)
+		if email == "" {
+			return
+		}
+		var req struct {
+			Password    string `json:"password" binding:"required"`
+			RecoveryKey string `json:"recovery_key" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondErrorDetail(c, http.StatusBadRequest, "invalid_request", err)
+			return
+		}
+		if !verifyUserPassword(c, driver, email, req.Password) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		err := keyring.Recover(ctx, email, req.RecoveryKey, req.Password)
+		switch {
+		case errors.Is(err, zeroaccess.ErrNotEnabled):
+			respondError(c, http.StatusNotFound, "zero_access_not_enabled")
+		case errors.Is(err, zeroaccess.ErrInvalidRecoveryKey):
+			respondError(c, http.StatusBadRequest, "recovery_key_invalid")
+		case err != nil:
+			logger.ErrorCtx(ctx).Err(err).Str("user", email).Msg("恢复零访问存储密钥失败")
+			respondError(c, http.StatusInternalServerError, "zero_access_recover_failed")
+		default:
+			logger.InfoCtx(ctx).Str("user", email).Msg("已用恢复密钥找回零访问存储密钥")
+			c.JSON(http.StatusOK, gin.H{"unlocked": true})
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+)
+
+// tokenLifetime VAPID 令牌的有效期（RFC 8292 要求不超过 24 小时）
+const tokenLifetime = 12 * time.Hour
+
+// LoadOrCreateKey 加载 VAPID 私钥（PEM 格式的 P-256 EC 私钥），文件不存在时生成并保存。
+// 浏览器订阅时记录了公钥，更换密钥后已有的订阅都会失效
+func LoadOrCreateKey(path string) (*ecdsa.PrivateKey, error) {
+	// #nosec G304 -- 密钥文件路径来自配置
+	data, err := os.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("VAPID 私钥文件 %s 不是 PEM 格式", path)
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析 VAPID 私钥失败: %w", err)
+		}
+		if key.Curve != elliptic.P256() {
+			return nil, errors.New("VAPID 私钥必须是 P-256 密钥")
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("读取 VAPID 私钥失败: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成 VAPID 私钥失败: %w", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("创建 VAPID 私钥目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, fmt.Errorf("保存 VAPID 私钥失败: %w", err)
+	}
+	logger.Info().Str("key_file", path).Msg("已生成 VAPID 私钥")
+	return key, nil
+}
+
+// publicKeyBytes VAPID 公钥（未压缩格式，浏览器订阅时作为 applicationServerKey）
+func publicKeyBytes(key *ecdsa.PrivateKey) []byte {
+	public, err := key.PublicKey.ECDH()
+	if err != nil {
+		// P-256 密钥总是可以转换
+		panic(err)
+	}
+	return public.Bytes()
+}
+
+// authorization 生成推送请求的 Authorization 头（RFC 8292）：
+// ES256 签名的 JWT，aud 为推送服务的源，sub 为联系方式
+func authorization(key *ecdsa.PrivateKey, endpoint, subject string, now time.Time) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("推送地址无效: %w", err)
+	}
+	header, _ := json.Marshal(map[string]string{"typ": "JWT", "alg": "ES256"})
+	claims, err := json.Marshal(map[string]any{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": now.Add(tokenLifetime).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("签名 VAPID 令牌失败: %w", err)
+	}
+	// JWS 的 ES256 签名是定长的 r || s（各 32 字节）
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	token := signingInput + "." + enc.EncodeToString(signature)
+	return "vapid t=" + token + ", k=" + enc.EncodeToString(publicKeyBytes(key)), nil
+}
@@ -0,0 +1,249 @@
+// Package webpush WebPush 新邮件通知：浏览器和 PWA 通过 Push API 订阅（applicationServerKey 为 VAPID 公钥），
+// 收件箱收到新邮件时向每个订阅的推送服务发送加密的通知（RFC 8030、RFC 8291、RFC 8292）。
+//
+// 通知内容是 JSON，由前端的 Service Worker 显示。推送服务只能看到密文，但通知会显示在设备的锁屏上，
+// 因此每个订阅可以选择通知包含的内容：发件人和主题（full）、只有发件人（sender）或只提示有新邮件（none）。
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	netmail "net/mail"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/logger"
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+	"github.com/gomailzero/gmz/internal/netguard"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// 通知包含的内容
+const (
+	PrivacyFull   = "full"   // 发件人和主题
+	PrivacySender = "sender" // 只有发件人
+	PrivacyNone   = "none"   // 只提示有新邮件
+)
+
+// 通知类型
+const (
+	TypeNewMail = "new_mail"
+	TypeTest    = "test"
+)
+
+// maxSubjectRunes 通知中主题的最大字符数
+const maxSubjectRunes = 120
+
+// notifyFolder 只通知收件箱的新邮件（已发送、垃圾邮件等文件夹的邮件不通知）
+const notifyFolder = "INBOX"
+
+// ErrGone 推送服务报告订阅已失效（404、410），订阅应该删除
+var ErrGone = errors.New("推送订阅已失效")
+
+// ValidPrivacy 是否为有效的通知内容设置
+func ValidPrivacy(privacy string) bool {
+	return privacy == PrivacyFull || privacy == PrivacySender || privacy == PrivacyNone
+}
+
+// Notification 通知内容（Service Worker 收到后显示）
+type Notification struct {
+	Type    string `json:"type"`
+	MailID  string `json:"mail_id,omitempty"`
+	Folder  string `json:"folder,omitempty"`
+	From    string `json:"from,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// withPrivacy 按通知内容设置去掉发件人和主题
+func (msg Notification) withPrivacy(privacy string) Notification {
+	switch privacy {
+	case PrivacyFull:
+	case PrivacySender:
+		msg.Subject = ""
+	default:
+		msg.From = ""
+		msg.Subject = ""
+	}
+	return msg
+}
+
+// publicClient 只允许连接公网地址的 HTTP 客户端（推送地址由浏览器提交，防止借服务器访问内网服务）
+var publicClient = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: netguard.PublicOnly,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Notifier WebPush 通知发送器
+type Notifier struct {
+	Storage storage.Driver
+	Key     *ecdsa.PrivateKey // VAPID 私钥
+	// Subject VAPID 联系方式（mailto: 或 https:）
+	Subject string
+	// Privacy 订阅没有指定时通知包含的内容
+	Privacy string
+	// TTL 设备离线时推送服务保存通知的时长
+	TTL time.Duration
+	// Client 发送推送请求的 HTTP 客户端，为空时使用只允许连接公网地址的客户端
+	Client *http.Client
+}
+
+// PublicKey VAPID 公钥（base64url），浏览器订阅时作为 applicationServerKey
+func (n *Notifier) PublicKey() string {
+	return base64.RawURLEncoding.EncodeToString(publicKeyBytes(n.Key))
+}
+
+// Run 订阅新邮件事件，收件箱收到新邮件时通知用户的所有订阅，直到 ctx 取消。
+// 推送较慢时监视器会丢弃事件（不阻塞投递），此时部分新邮件没有通知
+func (n *Notifier) Run(ctx context.Context, watcher *maildirwatch.Watcher) {
+	events, cancel := watcher.Subscribe("")
+	defer cancel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-events:
+			n.notifyMail(ctx, e)
+		}
+	}
+}
+
+// notifyMail 通知新邮件（只通知收件箱中的未读邮件）
+func (n *Notifier) notifyMail(ctx context.Context, e maildirwatch.Event) {
+	if e.Folder != notifyFolder {
+		return
+	}
+	mail, err := n.Storage.GetMail(ctx, e.MailID)
+	if err != nil {
+		logger.Warn().Err(err).Str("user", e.UserEmail).Str("mail_id", e.MailID).Msg("查询新邮件失败，不发送推送通知")
+		return
+	}
+	if slices.Contains(mail.Flags, "\\Seen") {
+		return
+	}
+	if _, err := n.Notify(ctx, mail.UserEmail, Notification{
+		Type:    TypeNewMail,
+		MailID:  mail.ID,
+		Folder:  mail.Folder,
+		From:    displayName(mail.From),
+		Subject: truncate(mail.Subject, maxSubjectRunes),
+	}); err != nil {
+		logger.Warn().Err(err).Str("user", mail.UserEmail).Msg("发送推送通知失败")
+	}
+}
+
+// Notify 向用户的所有订阅发送通知（按每个订阅的设置去掉发件人和主题），返回发送成功的数量。
+// 推送服务报告已失效的订阅会被删除
+func (n *Notifier) Notify(ctx context.Context, userEmail string, msg Notification) (int, error) {
+	subs, err := n.Storage.ListPushSubscriptions(ctx, userEmail)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, sub := range subs {
+		privacy := sub.Privacy
+		if privacy == "" {
+			privacy = n.Privacy
+		}
+		payload, err := json.Marshal(msg.withPrivacy(privacy))
+		if err != nil {
+			return sent, err
+		}
+		err = n.Send(ctx, sub, payload)
+		switch {
+		case errors.Is(err, ErrGone):
+			logger.Info().Str("user", userEmail).Int64("subscription", sub.ID).Msg("推送订阅已失效，删除订阅")
+			if err := n.Storage.DeletePushSubscriptionByEndpoint(ctx, sub.Endpoint); err != nil {
+				logger.Warn().Err(err).Int64("subscription", sub.ID).Msg("删除失效的推送订阅失败")
+			}
+		case err != nil:
+			logger.Warn().Err(err).Str("user", userEmail).Int64("subscription", sub.ID).Msg("推送通知失败")
+		default:
+			sent++
+			if err := n.Storage.RecordPush(ctx, sub.ID, time.Now().UTC()); err != nil {
+				logger.Warn().Err(err).Int64("subscription", sub.ID).Msg("记录推送时间失败")
+			}
+		}
+	}
+	return sent, nil
+}
+
+// Send 加密通知内容并发送到订阅的推送服务，订阅失效时返回 ErrGone
+func (n *Notifier) Send(ctx context.Context, sub *storage.PushSubscription, payload []byte) error {
+	body, err := encrypt(payload, sub.P256DH, sub.Auth)
+	if err != nil {
+		return err
+	}
+	auth, err := authorization(n.Key, sub.Endpoint, n.Subject, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建推送请求失败: %w", err)
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(n.TTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+
+	client := n.Client
+	if client == nil {
+		client = publicClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送推送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("推送服务返回 %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+// displayName 发件人的显示名称，没有名称时为地址
+func displayName(from string) string {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return from
+	}
+	if addr.Name != "" {
+		return addr.Name
+	}
+	return addr.Address
+}
+
+// truncate 截断到最多 n 个字符
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
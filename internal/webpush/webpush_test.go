@@ -0,0 +1,297 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/maildirwatch"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// decrypt 浏览器一侧的解密（RFC 8291），用于验证 encrypt
+func decrypt(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+
+	if len(body) < 21 {
+		t.Fatalf("密文太短: %d", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Errorf("记录大小 = %d, want %d", rs, recordSize)
+	}
+	idlen := int(body[20])
+	asPublic := body[21 : 21+idlen]
+	ciphertext := body[21+idlen:]
+
+	asKey, err := ecdh.P256().NewPublicKey(asPublic)
+	if err != nil {
+		t.Fatalf("头部的临时公钥无效: %v", err)
+	}
+	shared, err := uaPrivate.ECDH(asKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic := uaPrivate.PublicKey().Bytes()
+	prkKey, _ := hkdf.Extract(sha256.New, shared, authSecret)
+	ikm, _ := hkdf.Expand(sha256.New, prkKey, "WebPush: info\x00"+string(uaPublic)+string(asPublic), 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("最后一条记录应该以 0x02 结束: %x", plaintext)
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+// newBrowserKeys 生成浏览器订阅时的密钥
+func newBrowserKeys(t *testing.T) (*ecdh.PrivateKey, []byte) {
+	t.Helper()
+	uaPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+	return uaPrivate, authSecret
+}
+
+func TestEncrypt(t *testing.T) {
+	uaPrivate, authSecret := newBrowserKeys(t)
+	payload := []byte(`{"type":"new_mail","subject":"你好"}`)
+
+	body, err := encrypt(payload, uaPrivate.PublicKey().Bytes(), authSecret)
+	if err != nil {
+		t.Fatalf("encrypt() error = %v", err)
+	}
+	if got := decrypt(t, body, uaPrivate, authSecret); !bytes.Equal(got, payload) {
+		t.Errorf("解密结果 = %q, want %q", got, payload)
+	}
+
+	if _, err := encrypt(make([]byte, maxPayload+1), uaPrivate.PublicKey().Bytes(), authSecret); err == nil {
+		t.Error("超过一条记录的内容应该报错")
+	}
+	if _, err := encrypt(payload, []byte("bad"), authSecret); err == nil {
+		t.Error("无效的订阅公钥应该报错")
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	key, err := LoadOrCreateKey(filepath.Join(t.TempDir(), "vapid.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_800_000_000, 0)
+	header, err := authorization(key, "https://push.example.net/send/abc?x=1", "mailto:admin@example.com", now)
+	if err != nil {
+		t.Fatalf("authorization() error = %v", err)
+	}
+
+	token, k, ok := strings.Cut(strings.TrimPrefix(header, "vapid t="), ", k=")
+	if !ok || !strings.HasPrefix(header, "vapid t=") {
+		t.Fatalf("Authorization 格式错误: %s", header)
+	}
+	if want := base64.RawURLEncoding.EncodeToString(publicKeyBytes(key)); k != want {
+		t.Errorf("k = %s, want %s", k, want)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT 应该有 3 段: %s", token)
+	}
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Aud string `json:"aud"`
+		Exp int64  `json:"exp"`
+		Sub string `json:"sub"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Aud != "https://push.example.net" || claims.Sub != "mailto:admin@example.com" || claims.Exp != now.Add(tokenLifetime).Unix() {
+		t.Errorf("claims = %+v", claims)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if len(signature) != 64 {
+		t.Fatalf("签名长度 = %d, want 64", len(signature))
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("JWT 签名验证失败")
+	}
+}
+
+func TestLoadOrCreateKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webpush", "vapid.key")
+	key, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	again, err := LoadOrCreateKey(path)
+	if err != nil {
+		t.Fatalf("加载密钥失败: %v", err)
+	}
+	if !key.Equal(again) {
+		t.Error("再次加载应该得到同一个密钥")
+	}
+}
+
+// pushService 模拟推送服务，记录收到的请求
+type pushService struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (p *pushService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	p.mu.Lock()
+	p.requests = append(p.requests, r)
+	p.bodies = append(p.bodies, body)
+	status := p.status
+	p.mu.Unlock()
+	w.WriteHeader(status)
+}
+
+func newTestNotifier(t *testing.T, service *pushService) (*Notifier, *storage.SQLiteDriver, *httptest.Server) {
+	t.Helper()
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建测试驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	key, err := LoadOrCreateKey(filepath.Join(t.TempDir(), "vapid.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewTLSServer(service)
+	t.Cleanup(server.Close)
+	return &Notifier{
+		Storage: driver,
+		Key:     key,
+		Subject: "mailto:admin@example.com",
+		Privacy: PrivacySender,
+		TTL:     time.Hour,
+		Client:  server.Client(),
+	}, driver, server
+}
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	service := &pushService{status: http.StatusCreated}
+	n, driver, server := newTestNotifier(t, service)
+
+	uaPrivate, authSecret := newBrowserKeys(t)
+	full := &storage.PushSubscription{
+		UserEmail: "alice@example.com", Endpoint: server.URL + "/full",
+		P256DH: uaPrivate.PublicKey().Bytes(), Auth: authSecret, Privacy: PrivacyFull,
+	}
+	// 没有指定通知内容，使用默认值（只有发件人）
+	def := &storage.PushSubscription{
+		UserEmail: "alice@example.com", Endpoint: server.URL + "/default",
+		P256DH: uaPrivate.PublicKey().Bytes(), Auth: authSecret,
+	}
+	for _, sub := range []*storage.PushSubscription{full, def} {
+		if err := driver.SavePushSubscription(ctx, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mail := &storage.Mail{
+		ID: "mail-1", UserEmail: "alice@example.com", Folder: "INBOX",
+		From: "Bob <bob@example.com>", To: []string{"alice@example.com"}, Subject: "周报",
+		ReceivedAt: time.Now(),
+	}
+	if err := driver.StoreMail(ctx, mail); err != nil {
+		t.Fatalf("保存邮件失败: %v", err)
+	}
+
+	// 已发送文件夹的邮件不通知
+	n.notifyMail(ctx, maildirwatch.Event{UserEmail: "alice@example.com", Folder: "Sent", MailID: mail.ID})
+	if len(service.requests) != 0 {
+		t.Fatalf("非收件箱的邮件不应该通知: %d", len(service.requests))
+	}
+
+	n.notifyMail(ctx, maildirwatch.Event{UserEmail: "alice@example.com", Folder: "INBOX", MailID: mail.ID})
+	if len(service.requests) != 2 {
+		t.Fatalf("应该向 2 个订阅推送，实际 %d", len(service.requests))
+	}
+	got := map[string]Notification{}
+	for i, r := range service.requests {
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") != "3600" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "vapid t=") {
+			t.Errorf("推送请求头错误: %v", r.Header)
+		}
+		var msg Notification
+		if err := json.Unmarshal(decrypt(t, service.bodies[i], uaPrivate, authSecret), &msg); err != nil {
+			t.Fatal(err)
+		}
+		got[r.URL.Path] = msg
+	}
+	if want := (Notification{Type: TypeNewMail, MailID: "mail-1", Folder: "INBOX", From: "Bob", Subject: "周报"}); got["/full"] != want {
+		t.Errorf("full 通知 = %+v, want %+v", got["/full"], want)
+	}
+	if want := (Notification{Type: TypeNewMail, MailID: "mail-1", Folder: "INBOX", From: "Bob"}); got["/default"] != want {
+		t.Errorf("默认通知 = %+v, want %+v", got["/default"], want)
+	}
+
+	subs, _ := driver.ListPushSubscriptions(ctx, "alice@example.com")
+	for _, sub := range subs {
+		if sub.LastPushAt == nil {
+			t.Errorf("推送成功后应该记录时间: %+v", sub)
+		}
+	}
+
+	// 推送服务报告订阅已失效时删除订阅
+	service.status = http.StatusGone
+	sent, err := n.Notify(ctx, "alice@example.com", Notification{Type: TypeTest})
+	if err != nil || sent != 0 {
+		t.Fatalf("Notify() = %d, %v", sent, err)
+	}
+	if subs, _ := driver.ListPushSubscriptions(ctx, "alice@example.com"); len(subs) != 0 {
+		t.Errorf("失效的订阅应该已删除: %+v", subs)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("你好世界", 3); got != "你好…" {
+		t.Errorf("truncate() = %q", got)
+	}
+	if got := truncate("hello", 5); got != "hello" {
+		t.Errorf("truncate() = %q", got)
+	}
+}
@@ -0,0 +1,307 @@
+// Package zeroaccess 实现零访问存储：用户启用后，投递到其邮箱的邮件用用户的 X25519 公钥加密
+// （每封邮件使用新的临时密钥派生出独立的数据密钥），私钥只以用户密码和恢复密钥加密的形式保存。
+// 服务器只在用户用密码登录（IMAP LOGIN、WebMail 登录）之后的一段时间内在内存中持有解密的私钥，
+// 并且只在用户自己的 IMAP 和 WebMail 会话中解密邮件（storage.Maildir.WithOpener）；
+// 管理员、备份和后台任务始终只能看到密文。
+//
+// 代价：邮件正文无法在服务器端处理——IMAP SEARCH BODY/TEXT 只能在会话期间逐封解密，
+// 垃圾邮件训练、附件索引等后台任务读取不到内容；邮件头元数据（主题、发件人、收件人）仍然保存在数据库中
+// 用于列表和搜索。管理员重置密码后旧密码加密的私钥无法解开，需要用户用恢复密钥重新设置，
+// 恢复密钥也丢失时邮件无法恢复。
+package zeroaccess
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/crypto"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// DefaultUnlockTTL 解锁的私钥在最后一次使用后保留的默认时间
+const DefaultUnlockTTL = time.Hour
+
+// magic 加密邮件文件的前缀（不是合法的邮件开头，用于与启用前的未加密邮件区分）
+var magic = []byte("\x00GMZ-ZA1\x00")
+
+var (
+	// ErrAlreadyEnabled 用户已启用零访问存储
+	ErrAlreadyEnabled = errors.New("已启用零访问存储")
+	// ErrNotEnabled 用户未启用零访问存储
+	ErrNotEnabled = errors.New("未启用零访问存储")
+	// ErrWrongPassword 密码无法解开私钥（如管理员重置了密码）
+	ErrWrongPassword = errors.New("密码无法解开零访问存储密钥")
+	// ErrInvalidRecoveryKey 恢复密钥错误
+	ErrInvalidRecoveryKey = errors.New("恢复密钥错误")
+)
+
+// unlockedKey 已解锁的私钥
+type unlockedKey struct {
+	private []byte
+	expires time.Time
+}
+
+// Keyring 零访问存储的密钥管理，实现 storage.MailCipher
+type Keyring struct {
+	storage storage.Driver
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	public   map[string][]byte       // 已启用用户的公钥（启用后不再变化）
+	unlocked map[string]*unlockedKey // 已解锁的私钥
+}
+
+// New 创建密钥管理（ttl 为 0 时使用 DefaultUnlockTTL）
+func New(driver storage.Driver, ttl time.Duration) *Keyring {
+	if ttl <= 0 {
+		ttl = DefaultUnlockTTL
+	}
+	return &Keyring{
+		storage:  driver,
+		ttl:      ttl,
+		now:      time.Now,
+		public:   make(map[string][]byte),
+		unlocked: make(map[string]*unlockedKey),
+	}
+}
+
+// Enabled 用户是否启用了零访问存储
+func (k *Keyring) Enabled(ctx context.Context, email string) (bool, error) {
+	if k == nil {
+		return false, nil
+	}
+	public, err := k.publicKey(ctx, email)
+	return public != nil, err
+}
+
+// Enable 为用户启用零访问存储：生成密钥对，私钥分别用密码和新生成的恢复密钥加密后保存，返回恢复密钥
+// （只返回这一次，服务器不保存明文）。启用后私钥处于解锁状态
+func (k *Keyring) Enable(ctx context.Context, email, password string) (string, error) {
+	if _, err := k.storage.GetZeroAccessKey(ctx, email); err == nil {
+		return "", ErrAlreadyEnabled
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+
+	public, private, err := crypto.GenerateKeyPair()
+	if err != nil {
+		return "", err
+	}
+	recoveryKey, err := newRecoveryKey()
+	if err != nil {
+		return "", err
+	}
+	key := &storage.ZeroAccessKey{UserEmail: email, PublicKey: public}
+	if key.PasswordWrapped, err = crypto.EncryptWithPassphrase(password, private); err != nil {
+		return "", err
+	}
+	if key.RecoveryWrapped, err = crypto.EncryptWithPassphrase(normalizeRecoveryKey(recoveryKey), private); err != nil {
+		return "", err
+	}
+	if err := k.storage.SaveZeroAccessKey(ctx, key); err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	k.public[email] = public
+	k.mu.Unlock()
+	k.remember(email, private)
+	return recoveryKey, nil
+}
+
+// Unlock 用户用密码认证成功后解锁私钥（未启用零访问存储时什么也不做）。
+// password 可以是 "密码:TOTP 代码" 格式（IMAP 登录携带验证码的方式）
+func (k *Keyring) Unlock(ctx context.Context, email, password string) error {
+	if k == nil {
+		return nil
+	}
+	if k.touch(email) {
+		return nil
+	}
+	key, err := k.storage.GetZeroAccessKey(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	private, err := crypto.DecryptWithPassphrase(password, key.PasswordWrapped)
+	if err != nil && strings.Contains(password, ":") {
+		private, err = crypto.DecryptWithPassphrase(strings.SplitN(password, ":", 2)[0], key.PasswordWrapped)
+	}
+	if err != nil {
+		return ErrWrongPassword
+	}
+	k.remember(email, private)
+	return nil
+}
+
+// Recover 用恢复密钥解开私钥，并改为用当前密码加密（管理员重置密码后使用），之后私钥处于解锁状态
+func (k *Keyring) Recover(ctx context.Context, email, recoveryKey, password string) error {
+	key, err := k.storage.GetZeroAccessKey(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	private, err := crypto.DecryptWithPassphrase(normalizeRecoveryKey(recoveryKey), key.RecoveryWrapped)
+	if err != nil {
+		return ErrInvalidRecoveryKey
+	}
+	if key.PasswordWrapped, err = crypto.EncryptWithPassphrase(password, private); err != nil {
+		return err
+	}
+	if err := k.storage.SaveZeroAccessKey(ctx, key); err != nil {
+		return err
+	}
+	k.remember(email, private)
+	return nil
+}
+
+// Unlocked 用户的私钥当前是否已解锁
+func (k *Keyring) Unlocked(email string) bool {
+	if k == nil {
+		return false
+	}
+	return k.privateKey(email, false) != nil
+}
+
+// Lock 立即丢弃用户已解锁的私钥
+func (k *Keyring) Lock(email string) {
+	if k == nil {
+		return
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.unlocked[email]; ok {
+		clear(key.private)
+		delete(k.unlocked, email)
+	}
+}
+
+// Seal 加密启用了零访问存储的用户的邮件（未启用或已经加密时原样返回）
+func (k *Keyring) Seal(email string, data []byte) ([]byte, error) {
+	if k.Sealed(data) {
+		return data, nil
+	}
+	public, err := k.publicKey(context.Background(), email)
+	if err != nil {
+		return nil, err
+	}
+	if public == nil {
+		return data, nil
+	}
+	sealed, err := crypto.SealToPublicKey(public, data)
+	if err != nil {
+		return nil, err
+	}
+	return append(bytes.Clone(magic), sealed...), nil
+}
+
+// Open 解密加密的邮件（未加密的邮件原样返回），私钥未解锁时返回 storage.ErrMailboxLocked
+func (k *Keyring) Open(email string, data []byte) ([]byte, error) {
+	if !k.Sealed(data) {
+		return data, nil
+	}
+	private := k.privateKey(email, true)
+	if private == nil {
+		return nil, fmt.Errorf("用户 %s 的邮件已加密，需要用密码登录后读取: %w", email, storage.ErrMailboxLocked)
+	}
+	return crypto.OpenWithPrivateKey(private, data[len(magic):])
+}
+
+// Sealed 数据是否是加密的邮件
+func (k *Keyring) Sealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// publicKey 获取用户的公钥，未启用时返回 nil（只缓存已启用用户的公钥，未启用的用户每次查询，
+// 使其他节点上启用后立即生效）
+func (k *Keyring) publicKey(ctx context.Context, email string) ([]byte, error) {
+	k.mu.Lock()
+	public, ok := k.public[email]
+	k.mu.Unlock()
+	if ok {
+		return public, nil
+	}
+
+	key, err := k.storage.GetZeroAccessKey(ctx, email)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	k.public[email] = key.PublicKey
+	k.mu.Unlock()
+	return key.PublicKey, nil
+}
+
+// remember 保存解锁的私钥
+func (k *Keyring) remember(email string, private []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.unlocked[email] = &unlockedKey{private: private, expires: k.now().Add(k.ttl)}
+}
+
+// touch 私钥已解锁时延长保留时间
+func (k *Keyring) touch(email string) bool {
+	return k.privateKey(email, true) != nil
+}
+
+// privateKey 获取已解锁的私钥（过期的私钥被丢弃），extend 为 true 时延长保留时间
+func (k *Keyring) privateKey(email string, extend bool) []byte {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.unlocked[email]
+	if !ok {
+		return nil
+	}
+	now := k.now()
+	if now.After(key.expires) {
+		clear(key.private)
+		delete(k.unlocked, email)
+		return nil
+	}
+	if extend {
+		key.expires = now.Add(k.ttl)
+	}
+	// 返回副本：Lock 和过期时会清零保存的私钥
+	return bytes.Clone(key.private)
+}
+
+// newRecoveryKey 生成恢复密钥（160 位随机数的 base32 编码，每 4 个字符一组）
+func newRecoveryKey() (string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("生成恢复密钥失败: %w", err)
+	}
+	encoded := base32.StdEncoding.EncodeToString(raw)
+	groups := make([]string, 0, len(encoded)/4)
+	for i := 0; i < len(encoded); i += 4 {
+		groups = append(groups, encoded[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeRecoveryKey 去掉恢复密钥中的分隔符和空白并转为大写（用户输入时可以不带分隔符）
+func normalizeRecoveryKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t', '\r', '\n':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(key))
+}
@@ -0,0 +1,154 @@
+package zeroaccess
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+func newKeyring(t *testing.T) (*Keyring, *storage.SQLiteDriver) {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化 schema 失败: %v", err)
+	}
+	return New(driver, time.Hour), driver
+}
+
+func TestKeyring(t *testing.T) {
+	ctx := context.Background()
+	k, driver := newKeyring(t)
+	mail := []byte("Subject: hi\r\n\r\nsecret body\r\n")
+
+	// 未启用的用户原样写入和读取
+	if sealed, err := k.Seal("alice@example.com", mail); err != nil || !bytes.Equal(sealed, mail) {
+		t.Fatalf("未启用时 Seal() = %q, %v", sealed, err)
+	}
+
+	recoveryKey, err := k.Enable(ctx, "alice@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Enable(ctx, "alice@example.com", "password"); !errors.Is(err, ErrAlreadyEnabled) {
+		t.Errorf("重复启用应该返回 ErrAlreadyEnabled, got %v", err)
+	}
+
+	sealed, err := k.Seal("alice@example.com", mail)
+	if err != nil || bytes.Contains(sealed, []byte("secret body")) {
+		t.Fatalf("Seal() = %q, %v", sealed, err)
+	}
+	if again, _ := k.Seal("alice@example.com", sealed); !bytes.Equal(again, sealed) {
+		t.Error("已加密的数据不应该重复加密")
+	}
+	if opened, err := k.Open("alice@example.com", sealed); err != nil || !bytes.Equal(opened, mail) {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+
+	// 锁定后无法读取，用密码（可以带 TOTP 代码）登录后重新解锁
+	k.Lock("alice@example.com")
+	if _, err := k.Open("alice@example.com", sealed); !errors.Is(err, storage.ErrMailboxLocked) {
+		t.Fatalf("锁定后应该返回 ErrMailboxLocked, got %v", err)
+	}
+	if err := k.Unlock(ctx, "alice@example.com", "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("错误的密码应该返回 ErrWrongPassword, got %v", err)
+	}
+	if err := k.Unlock(ctx, "alice@example.com", "password:123456"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Open("alice@example.com", sealed); err != nil {
+		t.Errorf("解锁后应该可以读取: %v", err)
+	}
+
+	// 最后一次使用后超过保留时间自动锁定
+	k.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if k.Unlocked("alice@example.com") {
+		t.Error("超过保留时间后应该自动锁定")
+	}
+	k.now = time.Now
+
+	// 新的 Keyring（如重启后）从数据库读取公钥，用恢复密钥改为新密码加密私钥
+	restarted := New(driver, time.Hour)
+	if err := restarted.Unlock(ctx, "alice@example.com", "reset-by-admin"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("管理员重置密码后应该无法解锁, got %v", err)
+	}
+	if err := restarted.Recover(ctx, "alice@example.com", "AAAA-BBBB", "reset-by-admin"); !errors.Is(err, ErrInvalidRecoveryKey) {
+		t.Errorf("错误的恢复密钥应该返回 ErrInvalidRecoveryKey, got %v", err)
+	}
+	// 恢复密钥可以不带分隔符、使用小写
+	if err := restarted.Recover(ctx, "alice@example.com", string(bytes.ToLower([]byte(recoveryKey))), "reset-by-admin"); err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := restarted.Open("alice@example.com", sealed); err != nil || !bytes.Equal(opened, mail) {
+		t.Errorf("恢复后 Open() = %q, %v", opened, err)
+	}
+	restarted.Lock("alice@example.com")
+	if err := restarted.Unlock(ctx, "alice@example.com", "reset-by-admin"); err != nil {
+		t.Errorf("恢复后应该可以用新密码解锁: %v", err)
+	}
+
+	// 未启用的用户解锁什么也不做，读取未加密的邮件不受影响
+	if err := restarted.Unlock(ctx, "bob@example.com", "x"); err != nil {
+		t.Error(err)
+	}
+	if opened, err := restarted.Open("bob@example.com", mail); err != nil || !bytes.Equal(opened, mail) {
+		t.Errorf("未加密的邮件 Open() = %q, %v", opened, err)
+	}
+}
+
+func TestMaildirCipher(t *testing.T) {
+	ctx := context.Background()
+	k, _ := newKeyring(t)
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 启用前的邮件在启用时加密
+	before, err := maildir.StoreMail("alice@example.com", "INBOX", []byte("Subject: before\r\n\r\nold\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	maildir.SetCipher(k)
+	if _, err := k.Enable(ctx, "alice@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := maildir.SealExisting("alice@example.com"); err != nil || n != 1 {
+		t.Fatalf("SealExisting() = %d, %v", n, err)
+	}
+	if n, _ := maildir.SealExisting("alice@example.com"); n != 0 {
+		t.Errorf("再次调用不应该重复加密, got %d", n)
+	}
+
+	after, err := maildir.StoreMail("alice@example.com", "Archive", []byte("Subject: after\r\n\r\nnew\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 用户会话使用的 Maildir 解密邮件
+	session := maildir.WithOpener(k)
+	for _, tt := range []struct{ folder, id, want string }{
+		{"INBOX", before, "old"},
+		{"Archive", after, "new"},
+	} {
+		data, err := session.ReadMail("alice@example.com", tt.folder, tt.id)
+		if err != nil || !bytes.Contains(data, []byte(tt.want)) {
+			t.Errorf("ReadMail(%s) = %q, %v", tt.folder, data, err)
+		}
+	}
+	// 共享的 Maildir 在用户解锁期间也不解密
+	if data, err := maildir.ReadMail("alice@example.com", "Archive", after); !errors.Is(err, storage.ErrMailboxLocked) || bytes.Contains(data, []byte("new")) {
+		t.Errorf("共享的 Maildir 不应该解密, got %q, %v", data, err)
+	}
+
+	k.Lock("alice@example.com")
+	if _, err := session.ReadMail("alice@example.com", "Archive", after); !errors.Is(err, storage.ErrMailboxLocked) {
+		t.Errorf("锁定后读取应该返回 ErrMailboxLocked, got %v", err)
+	}
+}
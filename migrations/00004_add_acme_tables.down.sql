@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除 ACME 相关表
+
+DROP TABLE IF EXISTS acme_certificates;
+DROP TABLE IF EXISTS acme_orders;
+DROP TABLE IF EXISTS acme_accounts;
+
+-- +goose StatementEnd
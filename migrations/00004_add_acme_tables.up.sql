@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加 ACME 账户、订单和证书表（支持无共享目录的多节点/容器部署）
+
+CREATE TABLE IF NOT EXISTS acme_accounts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	email TEXT NOT NULL,
+	directory_url TEXT NOT NULL,
+	account_url TEXT,
+	private_key BLOB NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (email, directory_url)
+);
+
+CREATE TABLE IF NOT EXISTS acme_orders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	domain TEXT UNIQUE NOT NULL,
+	order_url TEXT,
+	status TEXT NOT NULL,
+	error TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS acme_certificates (
+	domain TEXT PRIMARY KEY,
+	cert_pem BLOB NOT NULL,
+	private_key BLOB NOT NULL,
+	not_after DATETIME NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
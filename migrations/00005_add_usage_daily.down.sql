@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除每日存储用量汇总表
+
+DROP TABLE IF EXISTS usage_daily;
+
+-- +goose StatementEnd
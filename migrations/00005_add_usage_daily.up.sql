@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加每日存储用量汇总表（用于用量趋势）
+
+CREATE TABLE IF NOT EXISTS usage_daily (
+	user_email TEXT NOT NULL,
+	day TEXT NOT NULL,
+	used INTEGER NOT NULL DEFAULT 0,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_email, day)
+);
+
+-- +goose StatementEnd
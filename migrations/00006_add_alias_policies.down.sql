@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除别名归属和自助别名策略
+
+DROP TABLE IF EXISTS alias_policies;
+DROP INDEX IF EXISTS idx_aliases_owner;
+ALTER TABLE aliases DROP COLUMN owner;
+
+-- +goose StatementEnd
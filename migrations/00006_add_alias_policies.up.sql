@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加别名归属和按域名的自助别名策略（用户在 WebMail 中自行创建别名）
+
+-- owner 为空表示管理员创建的别名
+ALTER TABLE aliases ADD COLUMN owner TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_aliases_owner ON aliases(owner);
+
+CREATE TABLE IF NOT EXISTS alias_policies (
+	domain TEXT PRIMARY KEY,
+	max_aliases INTEGER NOT NULL DEFAULT 0,
+	patterns TEXT,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除临时别名字段
+
+ALTER TABLE aliases DROP COLUMN last_received_at;
+ALTER TABLE aliases DROP COLUMN received_count;
+ALTER TABLE aliases DROP COLUMN burned_at;
+ALTER TABLE aliases DROP COLUMN expires_at;
+ALTER TABLE aliases DROP COLUMN disposable;
+
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加临时别名字段（自动过期、手动作废和接收计数）
+
+ALTER TABLE aliases ADD COLUMN disposable INTEGER DEFAULT 0;
+ALTER TABLE aliases ADD COLUMN expires_at DATETIME;
+ALTER TABLE aliases ADD COLUMN burned_at DATETIME;
+ALTER TABLE aliases ADD COLUMN received_count INTEGER DEFAULT 0;
+ALTER TABLE aliases ADD COLUMN last_received_at DATETIME;
+
+-- +goose StatementEnd
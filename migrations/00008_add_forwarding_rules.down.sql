@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除外部转发规则
+
+DROP TABLE IF EXISTS forwarding_rules;
+ALTER TABLE domains DROP COLUMN forwarding_disabled;
+
+-- +goose StatementEnd
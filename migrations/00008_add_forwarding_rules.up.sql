@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加外部转发规则表和按域名禁止外部转发的开关
+
+ALTER TABLE domains ADD COLUMN forwarding_disabled INTEGER DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS forwarding_rules (
+	user_email TEXT PRIMARY KEY,
+	address TEXT NOT NULL,
+	keep_copy INTEGER DEFAULT 1,
+	match_from TEXT,
+	match_subject TEXT,
+	verified INTEGER DEFAULT 0,
+	code_hash TEXT,
+	code_expires_at DATETIME,
+	attempts INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除用户外出状态表和 IMAP METADATA 条目表
+
+DROP TABLE IF EXISTS metadata;
+DROP TABLE IF EXISTS vacations;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加用户外出状态表和 IMAP METADATA（RFC 5464）条目表
+
+CREATE TABLE IF NOT EXISTS vacations (
+	user_email TEXT PRIMARY KEY,
+	enabled INTEGER DEFAULT 0,
+	message TEXT,
+	starts_at DATETIME,
+	ends_at DATETIME,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS metadata (
+	user_email TEXT NOT NULL,
+	mailbox TEXT NOT NULL DEFAULT '',
+	name TEXT NOT NULL,
+	value TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_email, mailbox, name),
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件注解表
+
+DROP TABLE IF EXISTS message_annotations;
+
+-- +goose StatementEnd
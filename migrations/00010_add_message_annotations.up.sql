@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件注解表（IMAP ANNOTATE，RFC 5257），用于颜色标签、备注等
+
+CREATE TABLE IF NOT EXISTS message_annotations (
+	mail_id TEXT NOT NULL,
+	entry TEXT NOT NULL,
+	shared INTEGER NOT NULL DEFAULT 0,
+	value TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (mail_id, entry, shared),
+	FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮箱 UID 分配表
+
+DROP TABLE IF EXISTS mailbox_uids;
+
+-- +goose StatementEnd
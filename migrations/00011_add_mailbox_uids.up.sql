@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮箱 UID 分配表：每个邮箱单调递增的 UIDNEXT 和稳定的 UIDVALIDITY
+-- （已有邮箱在首次访问时按现有最大 UID 初始化，并为没有 UID 的邮件补分配）
+
+CREATE TABLE IF NOT EXISTS mailbox_uids (
+	user_email TEXT NOT NULL,
+	folder TEXT NOT NULL,
+	uid_validity INTEGER NOT NULL,
+	uid_next INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_email, folder)
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件搜索索引
+
+DROP TRIGGER IF EXISTS mails_fts_update;
+DROP TRIGGER IF EXISTS mails_fts_delete;
+DROP TRIGGER IF EXISTS mails_fts_insert;
+DROP TABLE IF EXISTS mails_fts;
+ALTER TABLE mails DROP COLUMN has_attachment;
+
+-- +goose StatementEnd
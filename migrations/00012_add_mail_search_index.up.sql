@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件搜索索引：附件标记和主题/发件人/收件人的全文索引（trigram，支持中文子串匹配）
+
+ALTER TABLE mails ADD COLUMN has_attachment INTEGER DEFAULT 0;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS mails_fts USING fts5(
+	subject, from_addr, to_addrs, cc_addrs,
+	content='mails', content_rowid='rowid', tokenize='trigram'
+);
+
+CREATE TRIGGER IF NOT EXISTS mails_fts_insert AFTER INSERT ON mails BEGIN
+	INSERT INTO mails_fts(rowid, subject, from_addr, to_addrs, cc_addrs)
+	VALUES (new.rowid, new.subject, new.from_addr, new.to_addrs, new.cc_addrs);
+END;
+
+CREATE TRIGGER IF NOT EXISTS mails_fts_delete AFTER DELETE ON mails BEGIN
+	INSERT INTO mails_fts(mails_fts, rowid, subject, from_addr, to_addrs, cc_addrs)
+	VALUES ('delete', old.rowid, old.subject, old.from_addr, old.to_addrs, old.cc_addrs);
+END;
+
+CREATE TRIGGER IF NOT EXISTS mails_fts_update AFTER UPDATE OF subject, from_addr, to_addrs, cc_addrs ON mails BEGIN
+	INSERT INTO mails_fts(mails_fts, rowid, subject, from_addr, to_addrs, cc_addrs)
+	VALUES ('delete', old.rowid, old.subject, old.from_addr, old.to_addrs, old.cc_addrs);
+	INSERT INTO mails_fts(rowid, subject, from_addr, to_addrs, cc_addrs)
+	VALUES (new.rowid, new.subject, new.from_addr, new.to_addrs, new.cc_addrs);
+END;
+
+-- 为已有邮件建立索引
+INSERT INTO mails_fts(mails_fts) VALUES ('rebuild');
+
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件附件元数据表
+
+DROP INDEX IF EXISTS idx_mail_attachments_mail;
+DROP INDEX IF EXISTS idx_mail_attachments_user;
+DROP TABLE IF EXISTS mail_attachments;
+
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件附件元数据表（投递时从 MIME 结构中提取，用于附件浏览）
+
+CREATE TABLE IF NOT EXISTS mail_attachments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	mail_id TEXT NOT NULL,
+	user_email TEXT NOT NULL,
+	part TEXT NOT NULL,
+	filename TEXT NOT NULL DEFAULT '',
+	content_type TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_attachments_user ON mail_attachments(user_email, content_type);
+CREATE INDEX IF NOT EXISTS idx_mail_attachments_mail ON mail_attachments(mail_id);
+
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件统计汇总表
+
+DROP TABLE IF EXISTS mail_contacts_daily;
+DROP TABLE IF EXISTS mail_stats_daily;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件统计汇总表（按天汇总收发量、附件量、回复耗时和往来联系人，用于 WebMail 统计页）
+
+CREATE TABLE IF NOT EXISTS mail_stats_daily (
+	user_email TEXT NOT NULL,
+	day TEXT NOT NULL,
+	received INTEGER NOT NULL DEFAULT 0,
+	sent INTEGER NOT NULL DEFAULT 0,
+	attachments INTEGER NOT NULL DEFAULT 0,
+	attachment_bytes INTEGER NOT NULL DEFAULT 0,
+	replies INTEGER NOT NULL DEFAULT 0,
+	reply_seconds INTEGER NOT NULL DEFAULT 0,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_email, day)
+);
+
+CREATE TABLE IF NOT EXISTS mail_contacts_daily (
+	user_email TEXT NOT NULL,
+	day TEXT NOT NULL,
+	address TEXT NOT NULL,
+	direction TEXT NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_email, day, address, direction)
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除订阅邮件退订信息和归档设置
+
+DROP TABLE IF EXISTS newsletter_settings;
+DROP TABLE IF EXISTS mail_unsubscribe;
+
+-- +goose StatementEnd
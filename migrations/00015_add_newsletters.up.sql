@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加订阅邮件（List-Unsubscribe）退订信息和用户的订阅邮件归档设置
+
+CREATE TABLE IF NOT EXISTS mail_unsubscribe (
+	mail_id TEXT PRIMARY KEY,
+	list_id TEXT NOT NULL DEFAULT '',
+	mailto TEXT NOT NULL DEFAULT '',
+	url TEXT NOT NULL DEFAULT '',
+	one_click INTEGER NOT NULL DEFAULT 0,
+	unsubscribed_at DATETIME,
+	FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS newsletter_settings (
+	user_email TEXT PRIMARY KEY,
+	folder TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除收件箱自动分类
+
+DROP TABLE IF EXISTS category_tokens;
+DROP TABLE IF EXISTS category_docs;
+DROP TABLE IF EXISTS mail_categories;
+
+-- +goose StatementEnd
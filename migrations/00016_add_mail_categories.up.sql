@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加收件箱自动分类：每封邮件的分类和特征，以及按用户训练的朴素贝叶斯模型
+
+CREATE TABLE IF NOT EXISTS mail_categories (
+	mail_id TEXT PRIMARY KEY,
+	category TEXT NOT NULL,
+	tokens TEXT NOT NULL DEFAULT '',
+	trained INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (mail_id) REFERENCES mails(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS category_docs (
+	user_email TEXT NOT NULL,
+	category TEXT NOT NULL,
+	docs INTEGER NOT NULL DEFAULT 0,
+	tokens INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_email, category)
+);
+
+CREATE TABLE IF NOT EXISTS category_tokens (
+	user_email TEXT NOT NULL,
+	category TEXT NOT NULL,
+	token TEXT NOT NULL,
+	count INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_email, category, token)
+);
+
+-- +goose StatementEnd
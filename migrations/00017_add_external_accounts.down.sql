@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除外部邮箱账户
+
+DROP TABLE IF EXISTS external_account_uids;
+DROP INDEX IF EXISTS idx_external_accounts_user;
+DROP TABLE IF EXISTS external_accounts;
+
+-- +goose StatementEnd
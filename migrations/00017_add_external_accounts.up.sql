@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加外部邮箱账户：定期从远程 POP3/IMAP 账户拉取邮件到本地文件夹
+
+CREATE TABLE IF NOT EXISTS external_accounts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_email TEXT NOT NULL,
+	protocol TEXT NOT NULL,
+	host TEXT NOT NULL,
+	port INTEGER NOT NULL,
+	tls INTEGER NOT NULL DEFAULT 1,
+	username TEXT NOT NULL,
+	password BLOB NOT NULL,
+	folder TEXT NOT NULL DEFAULT 'INBOX',
+	keep_remote INTEGER NOT NULL DEFAULT 1,
+	enabled INTEGER NOT NULL DEFAULT 1,
+	last_fetch_at DATETIME,
+	last_success_at DATETIME,
+	last_error TEXT NOT NULL DEFAULT '',
+	last_count INTEGER NOT NULL DEFAULT 0,
+	total_count INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_accounts_user ON external_accounts(user_email);
+
+-- 已拉取的远程邮件（POP3 UIDL 或 IMAP UIDVALIDITY:UID），避免重复拉取
+CREATE TABLE IF NOT EXISTS external_account_uids (
+	account_id INTEGER NOT NULL,
+	uid TEXT NOT NULL,
+	fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (account_id, uid),
+	FOREIGN KEY (account_id) REFERENCES external_accounts(id) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除发件身份
+
+DROP TABLE IF EXISTS identities;
+
+-- +goose StatementEnd
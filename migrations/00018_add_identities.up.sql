@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加发件身份：用户以外部地址发信时通过该地址的 SMTP 服务器提交
+
+CREATE TABLE IF NOT EXISTS identities (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_email TEXT NOT NULL,
+	address TEXT NOT NULL,
+	display_name TEXT NOT NULL DEFAULT '',
+	smtp_host TEXT NOT NULL,
+	smtp_port INTEGER NOT NULL,
+	smtp_username TEXT NOT NULL,
+	smtp_password BLOB NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_email, address),
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
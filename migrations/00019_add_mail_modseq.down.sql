@@ -0,0 +1,15 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件修改序号
+
+DROP TRIGGER IF EXISTS mails_modseq_delete;
+DROP TRIGGER IF EXISTS mails_modseq_update;
+DROP TRIGGER IF EXISTS mails_modseq_insert;
+DROP INDEX IF EXISTS idx_mail_expunges_modseq;
+DROP INDEX IF EXISTS idx_mails_modseq;
+DROP TABLE IF EXISTS mail_expunges;
+DROP TABLE IF EXISTS user_modseq;
+ALTER TABLE mails DROP COLUMN create_modseq;
+ALTER TABLE mails DROP COLUMN modseq;
+
+-- +goose StatementEnd
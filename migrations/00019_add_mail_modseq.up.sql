@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件修改序号（MODSEQ）：每个用户单调递增，邮件新增、标志变化、移动和删除时递增，
+-- 删除和移出文件夹的邮件记录在 mail_expunges 中，供增量同步返回
+
+ALTER TABLE mails ADD COLUMN modseq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mails ADD COLUMN create_modseq INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS user_modseq (
+	user_email TEXT PRIMARY KEY,
+	modseq INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mail_expunges (
+	user_email TEXT NOT NULL,
+	mail_id TEXT NOT NULL,
+	folder TEXT NOT NULL,
+	modseq INTEGER NOT NULL,
+	expunged_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_mails_modseq ON mails(user_email, modseq);
+CREATE INDEX IF NOT EXISTS idx_mail_expunges_modseq ON mail_expunges(user_email, modseq);
+
+-- 已有邮件的修改序号为 1
+UPDATE mails SET modseq = 1, create_modseq = 1;
+INSERT INTO user_modseq (user_email, modseq) SELECT DISTINCT user_email, 1 FROM mails;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_insert AFTER INSERT ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (new.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	UPDATE mails SET
+		modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email),
+		create_modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email)
+	WHERE rowid = new.rowid;
+END;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_update AFTER UPDATE OF folder, flags ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (new.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	UPDATE mails SET modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email)
+	WHERE rowid = new.rowid;
+	INSERT INTO mail_expunges (user_email, mail_id, folder, modseq)
+	SELECT old.user_email, old.id, old.folder, modseq FROM user_modseq
+	WHERE user_email = old.user_email AND old.folder != new.folder;
+END;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_delete AFTER DELETE ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (old.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	INSERT INTO mail_expunges (user_email, mail_id, folder, modseq)
+	SELECT old.user_email, old.id, old.folder, modseq FROM user_modseq
+	WHERE user_email = old.user_email;
+END;
+
+-- +goose StatementEnd
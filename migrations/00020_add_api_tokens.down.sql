@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除个人访问令牌
+
+DROP INDEX IF EXISTS idx_api_tokens_user;
+DROP TABLE IF EXISTS api_tokens;
+
+-- +goose StatementEnd
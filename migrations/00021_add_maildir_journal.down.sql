@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除 Maildir 变更日志
+
+DROP INDEX IF EXISTS idx_maildir_journal_created;
+DROP TABLE IF EXISTS maildir_journal;
+
+-- +goose StatementEnd
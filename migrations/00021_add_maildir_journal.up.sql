@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加 Maildir 变更日志：记录邮件文件的写入、重命名和删除，供热备节点复制
+
+CREATE TABLE IF NOT EXISTS maildir_journal (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	op TEXT NOT NULL,
+	path TEXT NOT NULL,
+	new_path TEXT NOT NULL DEFAULT '',
+	sha256 TEXT NOT NULL DEFAULT '',
+	size INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_maildir_journal_created ON maildir_journal(created_at);
+
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除自动回复
+
+DROP TABLE IF EXISTS auto_reply_log;
+DROP TABLE IF EXISTS auto_responders;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加自动回复：用户的自动回复设置和已回复的发件人（按间隔天数只回复一次）
+
+CREATE TABLE IF NOT EXISTS auto_responders (
+	user_email TEXT PRIMARY KEY,
+	enabled INTEGER DEFAULT 0,
+	subject TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	starts_at DATETIME,
+	ends_at DATETIME,
+	interval_days INTEGER NOT NULL DEFAULT 7,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS auto_reply_log (
+	user_email TEXT NOT NULL,
+	sender TEXT NOT NULL,
+	replied_at DATETIME NOT NULL,
+	PRIMARY KEY (user_email, sender),
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除 CRAM-MD5 密钥
+
+DROP TABLE IF EXISTS cram_md5_secrets;
+
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加 CRAM-MD5 密钥：用户用明文密码登录成功时保存，password_hash 记录对应的密码哈希，密码修改后密钥失效
+
+CREATE TABLE IF NOT EXISTS cram_md5_secrets (
+	user_email TEXT PRIMARY KEY,
+	secret TEXT NOT NULL,
+	password_hash TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
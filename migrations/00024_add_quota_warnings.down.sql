@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除配额预警
+
+DROP TABLE IF EXISTS quota_warnings;
+ALTER TABLE domains DROP COLUMN quota_warning_thresholds;
+
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加配额预警：域名的预警阈值（逗号分隔的用量百分比，为空时使用全局默认值），
+-- 以及每个用户已发送预警的最高阈值（用量降到阈值以下后清除，再次超过时重新预警）
+
+ALTER TABLE domains ADD COLUMN quota_warning_thresholds TEXT DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS quota_warnings (
+	user_email TEXT PRIMARY KEY,
+	threshold INTEGER NOT NULL,
+	warned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除账户设置继承
+
+DROP TABLE IF EXISTS user_settings;
+DROP TABLE IF EXISTS domain_defaults;
+
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加账户设置继承：域名默认设置（新用户的默认配额以及功能开关、保留策略、垃圾邮件阈值、签名模板），
+-- 以及用户对域名默认设置的覆盖（settings 为 JSON，未设置的字段继承上一级）
+
+CREATE TABLE IF NOT EXISTS domain_defaults (
+	domain TEXT PRIMARY KEY,
+	quota INTEGER,
+	settings TEXT NOT NULL DEFAULT '{}',
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+	user_email TEXT PRIMARY KEY,
+	settings TEXT NOT NULL DEFAULT '{}',
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,90 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 时间恢复为 UTC 文本格式（YYYY-MM-DD HH:MM:SS）
+
+UPDATE users SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE users SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE domains SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE domains SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE aliases SET expires_at = datetime(expires_at, 'unixepoch') WHERE typeof(expires_at) = 'integer';
+UPDATE aliases SET burned_at = datetime(burned_at, 'unixepoch') WHERE typeof(burned_at) = 'integer';
+UPDATE aliases SET last_received_at = datetime(last_received_at, 'unixepoch') WHERE typeof(last_received_at) = 'integer';
+UPDATE aliases SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+
+UPDATE mails SET received_at = datetime(received_at, 'unixepoch') WHERE typeof(received_at) = 'integer';
+UPDATE mails SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+
+UPDATE totp_secrets SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE totp_secrets SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE acme_accounts SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE acme_accounts SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE acme_orders SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE acme_orders SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE acme_certificates SET not_after = datetime(not_after, 'unixepoch') WHERE typeof(not_after) = 'integer';
+UPDATE acme_certificates SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE acme_certificates SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE usage_daily SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE mail_stats_daily SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE alias_policies SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE forwarding_rules SET code_expires_at = datetime(code_expires_at, 'unixepoch') WHERE typeof(code_expires_at) = 'integer';
+UPDATE forwarding_rules SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE forwarding_rules SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE vacations SET starts_at = datetime(starts_at, 'unixepoch') WHERE typeof(starts_at) = 'integer';
+UPDATE vacations SET ends_at = datetime(ends_at, 'unixepoch') WHERE typeof(ends_at) = 'integer';
+UPDATE vacations SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE metadata SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE message_annotations SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE mail_unsubscribe SET unsubscribed_at = datetime(unsubscribed_at, 'unixepoch') WHERE typeof(unsubscribed_at) = 'integer';
+
+UPDATE newsletter_settings SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE external_accounts SET last_fetch_at = datetime(last_fetch_at, 'unixepoch') WHERE typeof(last_fetch_at) = 'integer';
+UPDATE external_accounts SET last_success_at = datetime(last_success_at, 'unixepoch') WHERE typeof(last_success_at) = 'integer';
+UPDATE external_accounts SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE external_accounts SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE external_account_uids SET fetched_at = datetime(fetched_at, 'unixepoch') WHERE typeof(fetched_at) = 'integer';
+
+UPDATE identities SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+UPDATE identities SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE api_tokens SET expires_at = datetime(expires_at, 'unixepoch') WHERE typeof(expires_at) = 'integer';
+UPDATE api_tokens SET last_used_at = datetime(last_used_at, 'unixepoch') WHERE typeof(last_used_at) = 'integer';
+UPDATE api_tokens SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+
+UPDATE mail_expunges SET expunged_at = datetime(expunged_at, 'unixepoch') WHERE typeof(expunged_at) = 'integer';
+
+UPDATE maildir_journal SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+
+UPDATE auto_responders SET starts_at = datetime(starts_at, 'unixepoch') WHERE typeof(starts_at) = 'integer';
+UPDATE auto_responders SET ends_at = datetime(ends_at, 'unixepoch') WHERE typeof(ends_at) = 'integer';
+UPDATE auto_responders SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE auto_reply_log SET replied_at = datetime(replied_at, 'unixepoch') WHERE typeof(replied_at) = 'integer';
+
+UPDATE cram_md5_secrets SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE quota_warnings SET warned_at = datetime(warned_at, 'unixepoch') WHERE typeof(warned_at) = 'integer';
+
+UPDATE domain_defaults SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE user_settings SET updated_at = datetime(updated_at, 'unixepoch') WHERE typeof(updated_at) = 'integer';
+
+UPDATE mailbox_uids SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+
+UPDATE mail_attachments SET created_at = datetime(created_at, 'unixepoch') WHERE typeof(created_at) = 'integer';
+
+-- +goose StatementEnd
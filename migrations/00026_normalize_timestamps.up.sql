@@ -0,0 +1,309 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 时间统一按 UTC Unix 秒（INTEGER）存储：把已有的文本时间转换为 Unix 秒
+-- 支持的旧格式：SQLite 时间格式（CURRENT_TIMESTAMP 默认值、RFC3339，带或不带时区偏移）
+-- 以及 Go time.Time.String() 格式（如 2006-01-02 15:04:05.999 +0800 CST m=+0.1，把时区偏移改写为 +HH:MM 后解析）；
+-- 无法解析的值保持不变
+
+UPDATE users SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE users SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE domains SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE domains SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE aliases SET expires_at = COALESCE(
+	unixepoch(expires_at),
+	unixepoch(substr(expires_at, 1, 19) || substr(expires_at, 20 + instr(substr(expires_at, 20), ' '), 3) || ':' || substr(expires_at, 23 + instr(substr(expires_at, 20), ' '), 2)),
+	expires_at
+) WHERE typeof(expires_at) = 'text';
+UPDATE aliases SET burned_at = COALESCE(
+	unixepoch(burned_at),
+	unixepoch(substr(burned_at, 1, 19) || substr(burned_at, 20 + instr(substr(burned_at, 20), ' '), 3) || ':' || substr(burned_at, 23 + instr(substr(burned_at, 20), ' '), 2)),
+	burned_at
+) WHERE typeof(burned_at) = 'text';
+UPDATE aliases SET last_received_at = COALESCE(
+	unixepoch(last_received_at),
+	unixepoch(substr(last_received_at, 1, 19) || substr(last_received_at, 20 + instr(substr(last_received_at, 20), ' '), 3) || ':' || substr(last_received_at, 23 + instr(substr(last_received_at, 20), ' '), 2)),
+	last_received_at
+) WHERE typeof(last_received_at) = 'text';
+UPDATE aliases SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+
+UPDATE mails SET received_at = COALESCE(
+	unixepoch(received_at),
+	unixepoch(substr(received_at, 1, 19) || substr(received_at, 20 + instr(substr(received_at, 20), ' '), 3) || ':' || substr(received_at, 23 + instr(substr(received_at, 20), ' '), 2)),
+	received_at
+) WHERE typeof(received_at) = 'text';
+UPDATE mails SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+
+UPDATE totp_secrets SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE totp_secrets SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE acme_accounts SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE acme_accounts SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE acme_orders SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE acme_orders SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE acme_certificates SET not_after = COALESCE(
+	unixepoch(not_after),
+	unixepoch(substr(not_after, 1, 19) || substr(not_after, 20 + instr(substr(not_after, 20), ' '), 3) || ':' || substr(not_after, 23 + instr(substr(not_after, 20), ' '), 2)),
+	not_after
+) WHERE typeof(not_after) = 'text';
+UPDATE acme_certificates SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE acme_certificates SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE usage_daily SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE mail_stats_daily SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE alias_policies SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE forwarding_rules SET code_expires_at = COALESCE(
+	unixepoch(code_expires_at),
+	unixepoch(substr(code_expires_at, 1, 19) || substr(code_expires_at, 20 + instr(substr(code_expires_at, 20), ' '), 3) || ':' || substr(code_expires_at, 23 + instr(substr(code_expires_at, 20), ' '), 2)),
+	code_expires_at
+) WHERE typeof(code_expires_at) = 'text';
+UPDATE forwarding_rules SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE forwarding_rules SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE vacations SET starts_at = COALESCE(
+	unixepoch(starts_at),
+	unixepoch(substr(starts_at, 1, 19) || substr(starts_at, 20 + instr(substr(starts_at, 20), ' '), 3) || ':' || substr(starts_at, 23 + instr(substr(starts_at, 20), ' '), 2)),
+	starts_at
+) WHERE typeof(starts_at) = 'text';
+UPDATE vacations SET ends_at = COALESCE(
+	unixepoch(ends_at),
+	unixepoch(substr(ends_at, 1, 19) || substr(ends_at, 20 + instr(substr(ends_at, 20), ' '), 3) || ':' || substr(ends_at, 23 + instr(substr(ends_at, 20), ' '), 2)),
+	ends_at
+) WHERE typeof(ends_at) = 'text';
+UPDATE vacations SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE metadata SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE message_annotations SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE mail_unsubscribe SET unsubscribed_at = COALESCE(
+	unixepoch(unsubscribed_at),
+	unixepoch(substr(unsubscribed_at, 1, 19) || substr(unsubscribed_at, 20 + instr(substr(unsubscribed_at, 20), ' '), 3) || ':' || substr(unsubscribed_at, 23 + instr(substr(unsubscribed_at, 20), ' '), 2)),
+	unsubscribed_at
+) WHERE typeof(unsubscribed_at) = 'text';
+
+UPDATE newsletter_settings SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE external_accounts SET last_fetch_at = COALESCE(
+	unixepoch(last_fetch_at),
+	unixepoch(substr(last_fetch_at, 1, 19) || substr(last_fetch_at, 20 + instr(substr(last_fetch_at, 20), ' '), 3) || ':' || substr(last_fetch_at, 23 + instr(substr(last_fetch_at, 20), ' '), 2)),
+	last_fetch_at
+) WHERE typeof(last_fetch_at) = 'text';
+UPDATE external_accounts SET last_success_at = COALESCE(
+	unixepoch(last_success_at),
+	unixepoch(substr(last_success_at, 1, 19) || substr(last_success_at, 20 + instr(substr(last_success_at, 20), ' '), 3) || ':' || substr(last_success_at, 23 + instr(substr(last_success_at, 20), ' '), 2)),
+	last_success_at
+) WHERE typeof(last_success_at) = 'text';
+UPDATE external_accounts SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE external_accounts SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE external_account_uids SET fetched_at = COALESCE(
+	unixepoch(fetched_at),
+	unixepoch(substr(fetched_at, 1, 19) || substr(fetched_at, 20 + instr(substr(fetched_at, 20), ' '), 3) || ':' || substr(fetched_at, 23 + instr(substr(fetched_at, 20), ' '), 2)),
+	fetched_at
+) WHERE typeof(fetched_at) = 'text';
+
+UPDATE identities SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+UPDATE identities SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE api_tokens SET expires_at = COALESCE(
+	unixepoch(expires_at),
+	unixepoch(substr(expires_at, 1, 19) || substr(expires_at, 20 + instr(substr(expires_at, 20), ' '), 3) || ':' || substr(expires_at, 23 + instr(substr(expires_at, 20), ' '), 2)),
+	expires_at
+) WHERE typeof(expires_at) = 'text';
+UPDATE api_tokens SET last_used_at = COALESCE(
+	unixepoch(last_used_at),
+	unixepoch(substr(last_used_at, 1, 19) || substr(last_used_at, 20 + instr(substr(last_used_at, 20), ' '), 3) || ':' || substr(last_used_at, 23 + instr(substr(last_used_at, 20), ' '), 2)),
+	last_used_at
+) WHERE typeof(last_used_at) = 'text';
+UPDATE api_tokens SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+
+UPDATE mail_expunges SET expunged_at = COALESCE(
+	unixepoch(expunged_at),
+	unixepoch(substr(expunged_at, 1, 19) || substr(expunged_at, 20 + instr(substr(expunged_at, 20), ' '), 3) || ':' || substr(expunged_at, 23 + instr(substr(expunged_at, 20), ' '), 2)),
+	expunged_at
+) WHERE typeof(expunged_at) = 'text';
+
+UPDATE maildir_journal SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+
+UPDATE auto_responders SET starts_at = COALESCE(
+	unixepoch(starts_at),
+	unixepoch(substr(starts_at, 1, 19) || substr(starts_at, 20 + instr(substr(starts_at, 20), ' '), 3) || ':' || substr(starts_at, 23 + instr(substr(starts_at, 20), ' '), 2)),
+	starts_at
+) WHERE typeof(starts_at) = 'text';
+UPDATE auto_responders SET ends_at = COALESCE(
+	unixepoch(ends_at),
+	unixepoch(substr(ends_at, 1, 19) || substr(ends_at, 20 + instr(substr(ends_at, 20), ' '), 3) || ':' || substr(ends_at, 23 + instr(substr(ends_at, 20), ' '), 2)),
+	ends_at
+) WHERE typeof(ends_at) = 'text';
+UPDATE auto_responders SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE auto_reply_log SET replied_at = COALESCE(
+	unixepoch(replied_at),
+	unixepoch(substr(replied_at, 1, 19) || substr(replied_at, 20 + instr(substr(replied_at, 20), ' '), 3) || ':' || substr(replied_at, 23 + instr(substr(replied_at, 20), ' '), 2)),
+	replied_at
+) WHERE typeof(replied_at) = 'text';
+
+UPDATE cram_md5_secrets SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE quota_warnings SET warned_at = COALESCE(
+	unixepoch(warned_at),
+	unixepoch(substr(warned_at, 1, 19) || substr(warned_at, 20 + instr(substr(warned_at, 20), ' '), 3) || ':' || substr(warned_at, 23 + instr(substr(warned_at, 20), ' '), 2)),
+	warned_at
+) WHERE typeof(warned_at) = 'text';
+
+UPDATE domain_defaults SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE user_settings SET updated_at = COALESCE(
+	unixepoch(updated_at),
+	unixepoch(substr(updated_at, 1, 19) || substr(updated_at, 20 + instr(substr(updated_at, 20), ' '), 3) || ':' || substr(updated_at, 23 + instr(substr(updated_at, 20), ' '), 2)),
+	updated_at
+) WHERE typeof(updated_at) = 'text';
+
+UPDATE mailbox_uids SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+
+UPDATE mail_attachments SET created_at = COALESCE(
+	unixepoch(created_at),
+	unixepoch(substr(created_at, 1, 19) || substr(created_at, 20 + instr(substr(created_at, 20), ' '), 3) || ':' || substr(created_at, 23 + instr(substr(created_at, 20), ' '), 2)),
+	created_at
+) WHERE typeof(created_at) = 'text';
+
+-- +goose StatementEnd
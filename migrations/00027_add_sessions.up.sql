@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加登录会话：每次登录（WebMail、管理后台）签发的 JWT 对应一条会话记录，
+-- 用于列出已登录的设备和吊销会话（吊销后 JWT 立即失效）
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id TEXT PRIMARY KEY,
+	user_email TEXT NOT NULL,
+	source TEXT NOT NULL,
+	remote_ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_used_at DATETIME,
+	expires_at DATETIME NOT NULL,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_email);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件摘要的发送记录
+
+DROP TABLE IF EXISTS digest_log;
+
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件摘要的发送记录：每个用户最近一次发送摘要的时间，用于判断下一次摘要是否到期
+-- 以及摘要覆盖的时间范围（摘要频率在账户设置中，见 user_settings 和 domain_defaults）
+
+CREATE TABLE IF NOT EXISTS digest_log (
+	user_email TEXT PRIMARY KEY,
+	sent_at DATETIME NOT NULL,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+-- +goose StatementEnd
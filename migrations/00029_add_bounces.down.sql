@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除退信记录
+
+DROP INDEX IF EXISTS idx_bounces_created;
+DROP TABLE IF EXISTS bounces;
+
+-- +goose StatementEnd
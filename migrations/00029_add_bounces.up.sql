@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加退信记录：外发时被对方拒收的收件人和本地用户收到的退信报告（DSN），
+-- 按退信原因分类，用于按目标域名和发件人统计投递问题（记录保留 90 天）
+
+CREATE TABLE IF NOT EXISTS bounces (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	sender TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	domain TEXT NOT NULL,
+	category TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT '',
+	diagnostic TEXT NOT NULL DEFAULT '',
+	source TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bounces_created ON bounces(created_at);
+
+-- +goose StatementEnd
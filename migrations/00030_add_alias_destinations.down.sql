@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除别名的多个目标地址
+
+ALTER TABLE aliases DROP COLUMN destinations;
+
+-- +goose StatementEnd
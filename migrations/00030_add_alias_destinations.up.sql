@@ -0,0 +1,8 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 别名支持多个目标地址：destinations 保存全部目标（逗号分隔，可以是本地用户或外部地址），
+-- to_addr 保留第一个目标；destinations 为空的旧别名只投递到 to_addr
+
+ALTER TABLE aliases ADD COLUMN destinations TEXT DEFAULT '';
+
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除外发预热
+
+DROP INDEX IF EXISTS idx_deferred_mails_not_before;
+DROP TABLE IF EXISTS deferred_mails;
+DROP TABLE IF EXISTS warmup_counts;
+
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加外发预热：按天和目标邮件服务商统计的外发数量，以及超过当天配额、推迟到之后发送的邮件
+
+CREATE TABLE IF NOT EXISTS warmup_counts (
+	day TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	sent INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (day, provider)
+);
+
+CREATE TABLE IF NOT EXISTS deferred_mails (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	sender TEXT NOT NULL,
+	recipients TEXT NOT NULL,
+	provider TEXT NOT NULL,
+	data BLOB NOT NULL,
+	not_before DATETIME NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_deferred_mails_not_before ON deferred_mails(not_before);
+
+-- +goose StatementEnd
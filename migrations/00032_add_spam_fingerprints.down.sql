@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除垃圾邮件内容指纹
+
+DROP INDEX IF EXISTS idx_spam_fingerprints_last_seen;
+DROP TABLE IF EXISTS spam_fingerprint_sources;
+DROP TABLE IF EXISTS spam_fingerprints;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加垃圾邮件内容指纹：按内容被拒收或被用户移入垃圾邮件文件夹的邮件的正文指纹（Nilsimsa），
+-- 以及每个指纹出现过的来源（发件域名），用于识别从不同来源重复发送的垃圾邮件活动
+
+CREATE TABLE IF NOT EXISTS spam_fingerprints (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	hash TEXT NOT NULL UNIQUE,
+	reason TEXT NOT NULL,
+	sample TEXT NOT NULL DEFAULT '',
+	reports INTEGER NOT NULL DEFAULT 0,
+	hits INTEGER NOT NULL DEFAULT 0,
+	first_seen DATETIME NOT NULL,
+	last_seen DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS spam_fingerprint_sources (
+	fingerprint_id INTEGER NOT NULL,
+	source TEXT NOT NULL,
+	first_seen DATETIME NOT NULL,
+	PRIMARY KEY (fingerprint_id, source),
+	FOREIGN KEY (fingerprint_id) REFERENCES spam_fingerprints(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_spam_fingerprints_last_seen ON spam_fingerprints(last_seen);
+
+-- +goose StatementEnd
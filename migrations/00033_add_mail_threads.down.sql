@@ -0,0 +1,13 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除邮件会话
+
+DROP INDEX IF EXISTS idx_mails_in_reply_to;
+DROP INDEX IF EXISTS idx_mails_thread;
+DROP INDEX IF EXISTS idx_mails_message_id;
+ALTER TABLE mails DROP COLUMN thread_id;
+ALTER TABLE mails DROP COLUMN refs;
+ALTER TABLE mails DROP COLUMN in_reply_to;
+ALTER TABLE mails DROP COLUMN message_id;
+
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加邮件会话：投递时保存 Message-ID、In-Reply-To 和 References（逗号分隔，不带尖括号），
+-- 以及邮件所属的会话 ID（同一会话的邮件相同）
+
+ALTER TABLE mails ADD COLUMN message_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE mails ADD COLUMN in_reply_to TEXT NOT NULL DEFAULT '';
+ALTER TABLE mails ADD COLUMN refs TEXT NOT NULL DEFAULT '';
+ALTER TABLE mails ADD COLUMN thread_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_mails_message_id ON mails(user_email, message_id);
+CREATE INDEX IF NOT EXISTS idx_mails_thread ON mails(user_email, thread_id);
+CREATE INDEX IF NOT EXISTS idx_mails_in_reply_to ON mails(user_email, in_reply_to);
+
+-- 已有邮件没有保存邮件头，各自作为单独的会话
+UPDATE mails SET thread_id = id;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除删除记录中的 UID
+
+DROP TRIGGER IF EXISTS mails_modseq_update;
+DROP TRIGGER IF EXISTS mails_modseq_delete;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_update AFTER UPDATE OF folder, flags ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (new.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	UPDATE mails SET modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email)
+	WHERE rowid = new.rowid;
+	INSERT INTO mail_expunges (user_email, mail_id, folder, modseq)
+	SELECT old.user_email, old.id, old.folder, modseq FROM user_modseq
+	WHERE user_email = old.user_email AND old.folder != new.folder;
+END;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_delete AFTER DELETE ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (old.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	INSERT INTO mail_expunges (user_email, mail_id, folder, modseq)
+	SELECT old.user_email, old.id, old.folder, modseq FROM user_modseq
+	WHERE user_email = old.user_email;
+END;
+
+DROP INDEX IF EXISTS idx_mail_expunges_folder;
+ALTER TABLE mail_expunges DROP COLUMN uid;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 删除和移出文件夹的邮件记录原 UID，供 IMAP QRESYNC 返回 VANISHED
+
+ALTER TABLE mail_expunges ADD COLUMN uid INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_mail_expunges_folder ON mail_expunges(user_email, folder, modseq);
+
+DROP TRIGGER IF EXISTS mails_modseq_update;
+DROP TRIGGER IF EXISTS mails_modseq_delete;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_update AFTER UPDATE OF folder, flags ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (new.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	UPDATE mails SET modseq = (SELECT modseq FROM user_modseq WHERE user_email = new.user_email)
+	WHERE rowid = new.rowid;
+	INSERT INTO mail_expunges (user_email, mail_id, folder, uid, modseq)
+	SELECT old.user_email, old.id, old.folder, COALESCE(old.uid, 0), modseq FROM user_modseq
+	WHERE user_email = old.user_email AND old.folder != new.folder;
+END;
+
+CREATE TRIGGER IF NOT EXISTS mails_modseq_delete AFTER DELETE ON mails BEGIN
+	INSERT INTO user_modseq (user_email, modseq) VALUES (old.user_email, 1)
+	ON CONFLICT (user_email) DO UPDATE SET modseq = modseq + 1;
+	INSERT INTO mail_expunges (user_email, mail_id, folder, uid, modseq)
+	SELECT old.user_email, old.id, old.folder, COALESCE(old.uid, 0), modseq FROM user_modseq
+	WHERE user_email = old.user_email;
+END;
+
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除投递审计日志
+
+DROP INDEX IF EXISTS idx_delivery_log_message;
+DROP INDEX IF EXISTS idx_delivery_log_queue;
+DROP INDEX IF EXISTS idx_delivery_log_created;
+DROP TABLE IF EXISTS delivery_log;
+
+-- +goose StatementEnd
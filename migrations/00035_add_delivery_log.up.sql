@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加投递审计日志：记录每封邮件经过的各个阶段（接收、反垃圾评分、投递到文件夹、外发、退信、推迟），
+-- 按队列 ID（同一次 SMTP 事务）和 Message-ID 关联，供管理员追踪邮件去向（记录保留 30 天）
+
+CREATE TABLE IF NOT EXISTS delivery_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	queue_id TEXT NOT NULL DEFAULT '',
+	message_id TEXT NOT NULL DEFAULT '',
+	event TEXT NOT NULL,
+	sender TEXT NOT NULL DEFAULT '',
+	recipient TEXT NOT NULL DEFAULT '',
+	folder TEXT NOT NULL DEFAULT '',
+	code INTEGER NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_log_created ON delivery_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_delivery_log_queue ON delivery_log(queue_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_log_message ON delivery_log(message_id);
+
+-- +goose StatementEnd
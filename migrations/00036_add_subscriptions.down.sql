@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除 IMAP 订阅列表
+
+DROP TABLE IF EXISTS subscriptions;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加 IMAP 订阅列表（SUBSCRIBE/UNSUBSCRIBE/LSUB），已有用户默认订阅 INBOX 和特殊文件夹
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+	user_email TEXT NOT NULL,
+	folder TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_email, folder),
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+INSERT OR IGNORE INTO subscriptions (user_email, folder)
+SELECT users.email, defaults.column1
+FROM users, (VALUES ('INBOX'), ('Sent'), ('Drafts'), ('Trash'), ('Spam')) AS defaults;
+
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除垃圾邮件贝叶斯分类器
+
+DROP TABLE IF EXISTS spam_bayes_trained;
+DROP TABLE IF EXISTS spam_bayes_tokens;
+DROP TABLE IF EXISTS spam_bayes_docs;
+
+-- +goose StatementEnd
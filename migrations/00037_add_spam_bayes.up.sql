@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加垃圾邮件贝叶斯分类器：用户移入、移出垃圾邮件文件夹的邮件训练按用户的模型和全局模型（user_email 为空），
+-- 已训练的邮件按内容摘要记录，重复训练时跳过，改判时撤销旧的样本
+
+CREATE TABLE IF NOT EXISTS spam_bayes_docs (
+	user_email TEXT PRIMARY KEY,
+	spam INTEGER NOT NULL DEFAULT 0,
+	ham INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS spam_bayes_tokens (
+	user_email TEXT NOT NULL,
+	token TEXT NOT NULL,
+	spam INTEGER NOT NULL DEFAULT 0,
+	ham INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_email, token)
+);
+
+CREATE TABLE IF NOT EXISTS spam_bayes_trained (
+	user_email TEXT NOT NULL,
+	digest TEXT NOT NULL,
+	spam INTEGER NOT NULL,
+	trained_at DATETIME NOT NULL,
+	PRIMARY KEY (user_email, digest)
+);
+
+-- +goose StatementEnd
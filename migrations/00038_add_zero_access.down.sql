@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除零访问存储密钥（已加密的邮件将无法解密）
+
+DROP TABLE IF EXISTS zero_access_keys;
+
+-- +goose StatementEnd
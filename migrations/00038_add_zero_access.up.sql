@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加零访问存储：用户启用后投递的邮件用用户的公钥加密，私钥分别用用户密码和恢复密钥加密后保存，
+-- 服务器只在用户用密码登录后的会话期间持有解密的私钥
+
+CREATE TABLE IF NOT EXISTS zero_access_keys (
+	user_email TEXT PRIMARY KEY,
+	public_key BLOB NOT NULL,
+	password_wrapped BLOB NOT NULL,
+	recovery_wrapped BLOB NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除 WebPush 订阅
+
+DROP TABLE IF EXISTS push_subscriptions;
+
+-- +goose StatementEnd
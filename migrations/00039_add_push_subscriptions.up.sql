@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加 WebPush 订阅：浏览器和 PWA 订阅后，收件箱收到新邮件时推送加密的通知
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_email TEXT NOT NULL,
+	endpoint TEXT NOT NULL UNIQUE,
+	p256dh BLOB NOT NULL,
+	auth BLOB NOT NULL,
+	privacy TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	last_push_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_email);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除外发会话记录
+
+DROP TABLE IF EXISTS smtp_transcripts;
+
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加外发会话记录：保留发往指定域名或指定 Message-ID 的邮件的完整 SMTP 会话（命令和回复、TLS 信息、耗时），
+-- 收件方否认收到邮件时由管理员查询举证（邮件内容不记录）
+
+CREATE TABLE IF NOT EXISTS smtp_transcripts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	queue_id TEXT NOT NULL DEFAULT '',
+	message_id TEXT NOT NULL DEFAULT '',
+	sender TEXT NOT NULL DEFAULT '',
+	domain TEXT NOT NULL,
+	recipients TEXT NOT NULL DEFAULT '',
+	mx_host TEXT NOT NULL DEFAULT '',
+	remote_addr TEXT NOT NULL DEFAULT '',
+	tls_version TEXT NOT NULL DEFAULT '',
+	tls_cipher TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	transcript TEXT NOT NULL DEFAULT '',
+	started_at DATETIME NOT NULL,
+	duration_ms INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_smtp_transcripts_started ON smtp_transcripts(started_at);
+CREATE INDEX IF NOT EXISTS idx_smtp_transcripts_message ON smtp_transcripts(message_id);
+
+-- +goose StatementEnd
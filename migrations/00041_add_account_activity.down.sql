@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除账户活动记录
+
+DROP TABLE IF EXISTS sent_log;
+DROP TABLE IF EXISTS login_history;
+
+-- +goose StatementEnd
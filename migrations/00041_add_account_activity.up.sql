@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加账户活动记录：用户的登录历史和从账户发出的邮件（时间、收件人、主题），
+-- 用户可以自行查看以发现账户被盗用
+
+CREATE TABLE IF NOT EXISTS login_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_email TEXT NOT NULL,
+	protocol TEXT NOT NULL,
+	remote_ip TEXT NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sent_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_email TEXT NOT NULL,
+	message_id TEXT NOT NULL DEFAULT '',
+	subject TEXT NOT NULL DEFAULT '',
+	recipients TEXT NOT NULL DEFAULT '',
+	protocol TEXT NOT NULL,
+	remote_ip TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_history_user ON login_history(user_email, created_at);
+CREATE INDEX IF NOT EXISTS idx_sent_log_user ON sent_log(user_email, created_at);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除域名 DKIM 密钥
+
+DROP TABLE IF EXISTS dkim_keys;
+
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加域名 DKIM 密钥：通过管理 API 为域名生成的签名密钥（私钥文件保存在工作目录下），
+-- 启动时加载，按发件域名签名外发邮件
+
+CREATE TABLE IF NOT EXISTS dkim_keys (
+	domain TEXT PRIMARY KEY,
+	selector TEXT NOT NULL,
+	algorithm TEXT NOT NULL,
+	key_file TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除使用条款接受记录
+
+DROP TABLE IF EXISTS terms_acceptances;
+
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加使用条款接受记录：配置了使用条款时，用户登录前需要接受当前版本，
+-- 每个版本记录一次接受时间和来源 IP
+
+CREATE TABLE IF NOT EXISTS terms_acceptances (
+	user_email TEXT NOT NULL,
+	version TEXT NOT NULL,
+	remote_ip TEXT NOT NULL DEFAULT '',
+	accepted_at DATETIME NOT NULL,
+	PRIMARY KEY (user_email, version)
+);
+
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除登录会话的刷新令牌
+
+ALTER TABLE sessions DROP COLUMN refresh_hash;
+
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 登录会话保存刷新令牌的哈希：WebMail 签发短期访问令牌，过期后用刷新令牌换取新的访问令牌，
+-- 每次刷新轮换刷新令牌，吊销会话后刷新令牌一起失效
+
+ALTER TABLE sessions ADD COLUMN refresh_hash TEXT NOT NULL DEFAULT '';
+
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加 WebAuthn 凭证（通行密钥或安全密钥）：用户注册后可以代替 TOTP 作为登录的第二因素，
+-- data 为序列化的凭证（公钥、签名计数等），每次登录后更新签名计数
+
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_email TEXT NOT NULL,
+	credential_id BLOB NOT NULL UNIQUE,
+	name TEXT NOT NULL DEFAULT '',
+	data BLOB NOT NULL,
+	created_at DATETIME NOT NULL,
+	last_used_at DATETIME,
+	FOREIGN KEY (user_email) REFERENCES users(email) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_email);
+
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Down
+-- +goose StatementBegin
+-- 移除内部邮件回执
+
+DROP INDEX IF EXISTS idx_mail_receipts_mail;
+DROP INDEX IF EXISTS idx_mail_receipts_sent;
+DROP TABLE IF EXISTS mail_receipts;
+ALTER TABLE domains DROP COLUMN receipts;
+
+-- +goose StatementEnd
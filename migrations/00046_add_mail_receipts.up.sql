@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 添加内部邮件回执：域名的回执策略（为空时不记录，delivered 只记录投递时间，read 同时记录首次阅读时间），
+-- 以及发件人已发送邮件（sent_mail_id）投递给本地收件人的记录（mail_id 为收件人邮箱中的邮件，设置 \Seen 时记录阅读时间）
+
+ALTER TABLE domains ADD COLUMN receipts TEXT DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS mail_receipts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	sent_mail_id TEXT NOT NULL,
+	recipient TEXT NOT NULL,
+	mail_id TEXT NOT NULL,
+	track_read INTEGER NOT NULL DEFAULT 0,
+	delivered_at DATETIME NOT NULL,
+	read_at DATETIME,
+	FOREIGN KEY (sent_mail_id) REFERENCES mails(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_receipts_sent ON mail_receipts(sent_mail_id);
+CREATE INDEX IF NOT EXISTS idx_mail_receipts_mail ON mail_receipts(mail_id);
+
+-- +goose StatementEnd
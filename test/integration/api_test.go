@@ -22,11 +22,7 @@ func TestAPILogin(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// 创建测试存储
-	driver, err := storage.NewSQLiteDriver(":memory:")
-	if err != nil {
-		t.Fatalf("创建存储驱动失败: %v", err)
-	}
-	defer driver.Close()
+	driver := newTestDriver(t)
 
 	ctx := context.Background()
 
@@ -36,10 +32,12 @@ func TestAPILogin(t *testing.T) {
 		t.Fatalf("哈希密码失败: %v", err)
 	}
 
+	// 只有管理员才能登录管理后台
 	user := &storage.User{
 		Email:        "test@example.com",
 		PasswordHash: passwordHash,
 		Active:       true,
+		IsAdmin:      true,
 	}
 	if err := driver.CreateUser(ctx, user); err != nil {
 		t.Fatalf("创建用户失败: %v", err)
@@ -90,11 +88,7 @@ func TestAPICreateUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// 创建测试存储
-	driver, err := storage.NewSQLiteDriver(":memory:")
-	if err != nil {
-		t.Fatalf("创建存储驱动失败: %v", err)
-	}
-	defer driver.Close()
+	driver := newTestDriver(t)
 
 	// 创建 API 服务器
 	jwtManager := auth.NewJWTManager("test-secret", "test")
@@ -143,11 +137,7 @@ func TestAPIGetUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	// 创建测试存储
-	driver, err := storage.NewSQLiteDriver(":memory:")
-	if err != nil {
-		t.Fatalf("创建存储驱动失败: %v", err)
-	}
-	defer driver.Close()
+	driver := newTestDriver(t)
 
 	ctx := context.Background()
 
@@ -0,0 +1,200 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/gomailzero/gmz/internal/chaos"
+	"github.com/gomailzero/gmz/internal/imapd"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// newChaosStorage 创建测试数据库和 Maildir
+func newChaosStorage(t *testing.T) (*storage.SQLiteDriver, *storage.Maildir) {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建存储驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化 Maildir 失败: %v", err)
+	}
+	return driver, maildir
+}
+
+// assertConsistent 检查文件夹中的数据库记录和 Maildir 文件一一对应，tmp/ 中没有残留
+func assertConsistent(t *testing.T, driver storage.Driver, maildir *storage.Maildir, userEmail, folder string, want int) {
+	t.Helper()
+	mails, err := driver.ListMails(context.Background(), userEmail, folder, 1000, 0)
+	if err != nil {
+		t.Fatalf("查询邮件失败: %v", err)
+	}
+	if len(mails) != want {
+		t.Errorf("%s 数据库记录数 = %d, want %d", folder, len(mails), want)
+	}
+	for _, mail := range mails {
+		if _, err := maildir.ReadMail(userEmail, folder, mail.ID); err != nil {
+			t.Errorf("数据库记录 %s 没有对应的邮件文件: %v", mail.ID, err)
+		}
+	}
+
+	dir := maildir.GetUserMaildir(userEmail)
+	if folder != "INBOX" {
+		dir = filepath.Join(dir, "."+folder)
+	}
+	files := 0
+	for _, sub := range []string{"new", "cur"} {
+		entries, _ := os.ReadDir(filepath.Join(dir, sub))
+		files += len(entries)
+	}
+	if files != len(mails) {
+		t.Errorf("%s 邮件文件数 = %d, 数据库记录数 = %d", folder, files, len(mails))
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "tmp")); len(entries) != 0 {
+		t.Errorf("%s/tmp 残留 %d 个文件", folder, len(entries))
+	}
+}
+
+// TestChaosDelivery 存储故障（错误和提交后返回错误）下投递不会产生不一致的文件或记录
+func TestChaosDelivery(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newChaosStorage(t)
+	injector := chaos.New(chaos.Config{ErrorRate: 0.3, PartialWriteRate: 0.3, Seed: 7})
+	mailStore := storage.NewMailStore(maildir, chaos.WrapDriver(driver, injector))
+
+	delivered, failed := 0, 0
+	for i := 0; i < 100; i++ {
+		mail := &storage.Mail{UserEmail: "alice@example.com", Folder: "INBOX", From: "bob@example.com", ReceivedAt: time.Now()}
+		data := []byte(fmt.Sprintf("Subject: %d\r\n\r\nBody %d", i, i))
+		if err := mailStore.Deliver(ctx, mail, data); err != nil {
+			if !errors.Is(err, chaos.ErrInjected) {
+				t.Fatalf("Deliver() 返回非注入的错误: %v", err)
+			}
+			failed++
+			continue
+		}
+		delivered++
+	}
+	if failed == 0 || delivered == 0 {
+		t.Fatalf("故障注入没有生效: delivered=%d failed=%d", delivered, failed)
+	}
+
+	// 模拟重启：故障消失后处理残留的 tmp/ 文件
+	injector.SetEnabled(false)
+	recovered, _, err := mailStore.Recover(ctx)
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	assertConsistent(t, driver, maildir, "alice@example.com", "INBOX", delivered+recovered)
+}
+
+// TestChaosIMAPAppend 存储故障下 IMAP APPEND 失败时返回错误，故障消失后邮箱内容与成功的 APPEND 一致
+func TestChaosIMAPAppend(t *testing.T) {
+	ctx := context.Background()
+	driver, maildir := newChaosStorage(t)
+	user := &storage.User{Email: "alice@example.com", Active: true}
+	if err := driver.CreateUser(ctx, user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	injector := chaos.New(chaos.Config{ErrorRate: 0.2, PartialWriteRate: 0.2, Latency: time.Millisecond, Seed: 11})
+	imapUser := imapd.NewUser(chaos.WrapDriver(driver, injector), maildir, user)
+
+	appended := 0
+	for i := 0; i < 50; i++ {
+		mailbox, err := imapUser.GetMailbox("Drafts")
+		if err != nil {
+			t.Fatalf("GetMailbox() error = %v", err)
+		}
+		body := fmt.Sprintf("From: alice@example.com\r\nSubject: Draft %d\r\n\r\nDraft %d", i, i)
+		if err := mailbox.CreateMessage([]string{imap.DraftFlag}, time.Now(), bytes.NewBufferString(body)); err != nil {
+			if !errors.Is(err, chaos.ErrInjected) {
+				t.Fatalf("CreateMessage() 返回非注入的错误: %v", err)
+			}
+			continue
+		}
+		appended++
+	}
+	if appended == 0 || appended == 50 {
+		t.Fatalf("故障注入没有生效: appended=%d", appended)
+	}
+
+	injector.SetEnabled(false)
+	recovered, _, err := storage.NewMailStore(maildir, driver).Recover(ctx)
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	assertConsistent(t, driver, maildir, "alice@example.com", "Drafts", appended+recovered)
+
+	mailbox, err := imapUser.GetMailbox("Drafts")
+	if err != nil {
+		t.Fatalf("GetMailbox() error = %v", err)
+	}
+	status, err := mailbox.Status([]imap.StatusItem{imap.StatusMessages})
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if int(status.Messages) != appended+recovered {
+		t.Errorf("邮箱邮件数 = %d, want %d", status.Messages, appended+recovered)
+	}
+}
+
+// TestChaosOutbound 外发连接断开或部分写入时发送返回错误，中继服务器不会收到不完整的邮件
+func TestChaosOutbound(t *testing.T) {
+	backend, port := startRelay(t)
+
+	injector := chaos.New(chaos.Config{ErrorRate: 0.05, PartialWriteRate: 0.05, Seed: 3})
+	smtpclient.SetDialerWrapper(func(d smtpclient.ContextDialer) smtpclient.ContextDialer {
+		return chaos.WrapDialer(d, injector)
+	})
+	defer smtpclient.SetDialerWrapper(nil)
+
+	client := smtpclient.NewClient("client.test")
+	data := []byte("Subject: chaos\r\n\r\n" + strings.Repeat("line\r\n", 100))
+	sent := 0
+	for i := 0; i < 30; i++ {
+		err := client.SendMailToRelay(context.Background(), "127.0.0.1", port, "", "", false, "alice@example.com", []string{"bob@example.net"}, data)
+		if err == nil {
+			sent++
+		}
+	}
+	if sent == 30 {
+		t.Fatal("故障注入没有生效")
+	}
+
+	// 故障消失后可以正常发送
+	injector.SetEnabled(false)
+	if err := client.SendMailToRelay(context.Background(), "127.0.0.1", port, "", "", false, "alice@example.com", []string{"bob@example.net"}, data); err != nil {
+		t.Fatalf("故障消失后发送失败: %v", err)
+	}
+	sent++
+
+	// 等待服务器处理完已断开的连接
+	time.Sleep(100 * time.Millisecond)
+	received := backend.Received()
+	// 邮件发送完成后读取响应时断开，客户端会认为失败（重试时对方可能收到重复邮件，SMTP 允许）
+	if len(received) < sent {
+		t.Errorf("中继服务器收到 %d 封邮件, 至少应该有 %d 封", len(received), sent)
+	}
+	for _, got := range received {
+		if !bytes.Equal(got.Data, data) {
+			t.Errorf("中继服务器收到不完整的邮件（%d 字节）", len(got.Data))
+		}
+	}
+}
@@ -0,0 +1,401 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/storage"
+)
+
+// webMail WebMail 接口返回的邮件
+type webMail struct {
+	ID      string   `json:"id"`
+	Folder  string   `json:"folder"`
+	From    string   `json:"from"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	Flags   []string `json:"flags"`
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// listWebMails 通过 WebMail 列出文件夹中的邮件
+func (s *stack) listWebMails(t *testing.T, header http.Header, folder string) []webMail {
+	t.Helper()
+	var resp struct {
+		Mails []webMail `json:"mails"`
+	}
+	if code := doJSON(t, http.MethodGet, s.WebURL+"/api/mails?folder="+folder, header, nil, &resp); code != http.StatusOK {
+		t.Fatalf("列出 %s 邮件失败: %d", folder, code)
+	}
+	return resp.Mails
+}
+
+// imapLogin 登录 IMAP 服务器
+func (s *stack) imapLogin(t *testing.T, email, password string) *client.Client {
+	t.Helper()
+	c, err := client.Dial(s.IMAPAddr)
+	if err != nil {
+		t.Fatalf("连接 IMAP 服务器失败: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Logout() })
+	if err := c.Login(email, password); err != nil {
+		t.Fatalf("IMAP 登录失败: %v", err)
+	}
+	return c
+}
+
+// fetchAll 获取邮箱中所有邮件的标志和信封，items 为额外获取的数据项
+func fetchAll(t *testing.T, c *client.Client, mailbox string, items ...imap.FetchItem) []*imap.Message {
+	t.Helper()
+	status, err := c.Select(mailbox, false)
+	if err != nil {
+		t.Fatalf("选择邮箱 %s 失败: %v", mailbox, err)
+	}
+	if status.Messages == 0 {
+		return nil
+	}
+	seqSet := new(imap.SeqSet)
+	seqSet.AddRange(1, status.Messages)
+	messages := make(chan *imap.Message, status.Messages)
+	items = append([]imap.FetchItem{imap.FetchUid, imap.FetchFlags, imap.FetchEnvelope}, items...)
+	if err := c.Fetch(seqSet, items, messages); err != nil {
+		t.Fatalf("获取 %s 邮件失败: %v", mailbox, err)
+	}
+	var result []*imap.Message
+	for msg := range messages {
+		result = append(result, msg)
+	}
+	return result
+}
+
+// waitFor 等待条件成立（用于异步投递的结果）
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// TestEndToEnd 投递 → IMAP 读取 → WebMail 阅读 → 回复 → 中继发送，
+// 验证各模块之间对同一封邮件的状态（特别是 \Seen 标志）保持一致
+func TestEndToEnd(t *testing.T) {
+	s := startStack(t)
+	s.CreateUser(t, "alice@example.com", "alice-password")
+
+	// 外部发件人通过 SMTP 投递到 alice
+	message := "From: bob@remote.test\r\n" +
+		"To: alice@example.com\r\n" +
+		"Subject: Lunch\r\n" +
+		"\r\n" +
+		"Lunch at noon?\r\n"
+	sc, err := smtp.Dial(s.SMTPAddr)
+	if err != nil {
+		t.Fatalf("连接 SMTP 服务器失败: %v", err)
+	}
+	if err := sc.SendMail("bob@remote.test", []string{"alice@example.com"}, strings.NewReader(message)); err != nil {
+		t.Fatalf("SMTP 投递失败: %v", err)
+	}
+	_ = sc.Quit()
+
+	// IMAP：新邮件未读，读取正文后标记为已读
+	c := s.imapLogin(t, "alice@example.com", "alice-password")
+	messages := fetchAll(t, c, imap.InboxName)
+	if len(messages) != 1 {
+		t.Fatalf("INBOX 应该有 1 封邮件, 实际 %d 封", len(messages))
+	}
+	if messages[0].Envelope.Subject != "Lunch" {
+		t.Errorf("主题 = %q, 期望 Lunch", messages[0].Envelope.Subject)
+	}
+	if hasFlag(messages[0].Flags, imap.SeenFlag) {
+		t.Errorf("新投递的邮件不应该是已读: %v", messages[0].Flags)
+	}
+
+	section := &imap.BodySectionName{}
+	messages = fetchAll(t, c, imap.InboxName, section.FetchItem())
+	body := messages[0].GetBody(section)
+	if body == nil {
+		t.Fatal("IMAP 没有返回邮件正文")
+	}
+	messages = fetchAll(t, c, imap.InboxName)
+	if !hasFlag(messages[0].Flags, imap.SeenFlag) {
+		t.Errorf("读取正文后应该标记为已读: %v", messages[0].Flags)
+	}
+
+	// WebMail：看到同一封邮件，已读状态与 IMAP 一致
+	web := s.WebLogin(t, "alice@example.com", "alice-password")
+	inbox := s.listWebMails(t, web, "INBOX")
+	if len(inbox) != 1 {
+		t.Fatalf("WebMail 收件箱应该有 1 封邮件, 实际 %d 封", len(inbox))
+	}
+	if !hasFlag(inbox[0].Flags, imap.SeenFlag) {
+		t.Errorf("WebMail 中的邮件应该是已读: %v", inbox[0].Flags)
+	}
+	var mail webMail
+	if code := doJSON(t, http.MethodGet, s.WebURL+"/api/mails/"+inbox[0].ID, web, nil, &mail); code != http.StatusOK {
+		t.Fatalf("WebMail 读取邮件失败: %d", code)
+	}
+	if !strings.Contains(mail.Body, "Lunch at noon?") {
+		t.Errorf("WebMail 邮件正文 = %q", mail.Body)
+	}
+
+	// WebMail 标记为未读：数据库中去掉 \Seen，但 IMAP 把既没有 \Seen 也没有 \Recent 的邮件
+	// 当作旧邮件自动设为已读（兼容 Foxmail），所以 IMAP 获取标志后又变回已读
+	if code := doJSON(t, http.MethodPut, s.WebURL+"/api/mails/"+mail.ID+"/flags", web, map[string][]string{"flags": {}}, nil); code != http.StatusOK {
+		t.Fatalf("WebMail 更新标志失败: %d", code)
+	}
+	if code := doJSON(t, http.MethodGet, s.WebURL+"/api/mails/"+mail.ID, web, nil, &mail); code != http.StatusOK || hasFlag(mail.Flags, imap.SeenFlag) {
+		t.Errorf("WebMail 标记未读后仍然是已读: %d %v", code, mail.Flags)
+	}
+	messages = fetchAll(t, c, imap.InboxName)
+	if !hasFlag(messages[0].Flags, imap.SeenFlag) {
+		t.Errorf("IMAP 应该把没有 \\Recent 的未读邮件自动设为已读: %v", messages[0].Flags)
+	}
+	if code := doJSON(t, http.MethodGet, s.WebURL+"/api/mails/"+mail.ID, web, nil, &mail); code != http.StatusOK || !hasFlag(mail.Flags, imap.SeenFlag) {
+		t.Errorf("IMAP 自动设置的已读标志应该同步到 WebMail: %d %v", code, mail.Flags)
+	}
+
+	// WebMail 回复外部发件人，经中继发出，并保存到 Sent
+	reply := map[string]interface{}{
+		"to":      []string{"bob@remote.test"},
+		"subject": "Re: Lunch",
+		"body":    "Sounds good.",
+	}
+	var sendResp struct {
+		ExternalDelivered int `json:"external_delivered"`
+	}
+	if code := doJSON(t, http.MethodPost, s.WebURL+"/api/mails", web, reply, &sendResp); code != http.StatusOK {
+		t.Fatalf("WebMail 发送邮件失败: %d", code)
+	}
+	if sendResp.ExternalDelivered != 1 {
+		t.Errorf("external_delivered = %d, 期望 1", sendResp.ExternalDelivered)
+	}
+	waitFor(t, "中继收到回复", func() bool { return len(s.Relay.Received()) == 1 })
+	relayed := s.Relay.Received()[0]
+	if relayed.From != "alice@example.com" || len(relayed.To) != 1 || relayed.To[0] != "bob@remote.test" {
+		t.Errorf("中继收到的信封 = %s -> %v", relayed.From, relayed.To)
+	}
+	if !strings.Contains(string(relayed.Data), "Sounds good.") {
+		t.Errorf("中继收到的邮件缺少正文: %s", relayed.Data)
+	}
+
+	sent := fetchAll(t, c, "Sent")
+	if len(sent) != 1 || sent[0].Envelope.Subject != "Re: Lunch" {
+		t.Fatalf("IMAP Sent 中应该有回复邮件, 实际 %d 封", len(sent))
+	}
+}
+
+// TestEndToEndLocalDelivery WebMail 发给本地用户的邮件直接投递，收件人在 IMAP 中看到未读新邮件
+func TestEndToEndLocalDelivery(t *testing.T) {
+	s := startStack(t)
+	s.CreateUser(t, "alice@example.com", "alice-password")
+	s.CreateUser(t, "carol@example.com", "carol-password")
+
+	web := s.WebLogin(t, "alice@example.com", "alice-password")
+	req := map[string]interface{}{
+		"to":      []string{"carol@example.com"},
+		"subject": "Hello",
+		"body":    "Hi Carol",
+	}
+	var resp struct {
+		LocalDelivered int `json:"local_delivered"`
+	}
+	if code := doJSON(t, http.MethodPost, s.WebURL+"/api/mails", web, req, &resp); code != http.StatusOK {
+		t.Fatalf("WebMail 发送邮件失败: %d", code)
+	}
+	if resp.LocalDelivered != 1 {
+		t.Errorf("local_delivered = %d, 期望 1", resp.LocalDelivered)
+	}
+	if n := len(s.Relay.Received()); n != 0 {
+		t.Errorf("本地邮件不应该经过中继, 中继收到 %d 封", n)
+	}
+
+	c := s.imapLogin(t, "carol@example.com", "carol-password")
+	messages := fetchAll(t, c, imap.InboxName)
+	if len(messages) != 1 {
+		t.Fatalf("carol 的 INBOX 应该有 1 封邮件, 实际 %d 封", len(messages))
+	}
+	if messages[0].Envelope.Subject != "Hello" {
+		t.Errorf("主题 = %q, 期望 Hello", messages[0].Envelope.Subject)
+	}
+	if hasFlag(messages[0].Flags, imap.SeenFlag) {
+		t.Errorf("本地投递的新邮件不应该是已读: %v", messages[0].Flags)
+	}
+}
+
+// TestEndToEndAliasFanOut WebMail 发给多目标别名的邮件与 SMTP 投递相同：投递到全部本地目标，外部目标经中继转发
+func TestEndToEndAliasFanOut(t *testing.T) {
+	s := startStack(t)
+	s.CreateUser(t, "alice@example.com", "alice-password")
+	s.CreateUser(t, "carol@example.com", "carol-password")
+	s.CreateUser(t, "dave@example.com", "dave-password")
+	alias := &storage.Alias{
+		From:         "team@example.com",
+		Destinations: []string{"carol@example.com", "dave@example.com", "erin@remote.test"},
+		Domain:       "example.com",
+	}
+	if err := s.Storage.CreateAlias(context.Background(), alias); err != nil {
+		t.Fatal(err)
+	}
+
+	web := s.WebLogin(t, "alice@example.com", "alice-password")
+	req := map[string]interface{}{
+		"to":      []string{"team@example.com"},
+		"subject": "Standup",
+		"body":    "Hi team",
+	}
+	if code := doJSON(t, http.MethodPost, s.WebURL+"/api/mails", web, req, nil); code != http.StatusOK {
+		t.Fatalf("WebMail 发送邮件失败: %d", code)
+	}
+
+	for _, user := range []struct{ email, password string }{
+		{"carol@example.com", "carol-password"},
+		{"dave@example.com", "dave-password"},
+	} {
+		c := s.imapLogin(t, user.email, user.password)
+		messages := fetchAll(t, c, imap.InboxName)
+		if len(messages) != 1 || messages[0].Envelope.Subject != "Standup" {
+			t.Errorf("%s 的 INBOX 应该收到别名邮件, 实际 %d 封", user.email, len(messages))
+		}
+	}
+
+	waitFor(t, "别名外部目标的转发", func() bool { return len(s.Relay.Received()) == 1 })
+	forwarded := s.Relay.Received()[0]
+	if len(forwarded.To) != 1 || forwarded.To[0] != "erin@remote.test" {
+		t.Errorf("转发收件人 = %v, 期望 erin@remote.test", forwarded.To)
+	}
+	// 本域发件人经 SRS 重写后保持不变
+	if forwarded.From != "alice@example.com" || !strings.HasPrefix(string(forwarded.Data), "Delivered-To: team@example.com\r\n") {
+		t.Errorf("转发的邮件 = %s -> %v:\n%s", forwarded.From, forwarded.To, forwarded.Data)
+	}
+}
+
+// TestEndToEndMailFlagsForbidden 其他用户不能修改邮件的标志，也就不能伪造已读回执
+func TestEndToEndMailFlagsForbidden(t *testing.T) {
+	s := startStack(t)
+	ctx := context.Background()
+	s.CreateUser(t, "alice@example.com", "alice-password")
+	s.CreateUser(t, "bob@example.com", "bob-password")
+	s.CreateUser(t, "carol@example.com", "carol-password")
+	domain, err := s.Storage.GetDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain.Receipts = storage.ReceiptsRead
+	if err := s.Storage.UpdateDomain(ctx, domain); err != nil {
+		t.Fatal(err)
+	}
+
+	req := map[string]interface{}{
+		"to":      []string{"carol@example.com"},
+		"subject": "Budget",
+		"body":    "Please review",
+	}
+	if code := doJSON(t, http.MethodPost, s.WebURL+"/api/mails", s.WebLogin(t, "alice@example.com", "alice-password"), req, nil); code != http.StatusOK {
+		t.Fatalf("WebMail 发送邮件失败: %d", code)
+	}
+	sent, err := s.Storage.ListMails(ctx, "alice@example.com", "Sent", 10, 0)
+	if err != nil || len(sent) != 1 {
+		t.Fatalf("alice 的已发送邮件 = %d 封: %v", len(sent), err)
+	}
+	inbox, err := s.Storage.ListMails(ctx, "carol@example.com", "INBOX", 10, 0)
+	if err != nil || len(inbox) != 1 {
+		t.Fatalf("carol 的 INBOX = %d 封: %v", len(inbox), err)
+	}
+
+	readAt := func() *time.Time {
+		t.Helper()
+		receipts, err := s.Storage.ListMailReceipts(ctx, sent[0].ID)
+		if err != nil || len(receipts) != 1 {
+			t.Fatalf("回执 = %d 条: %v", len(receipts), err)
+		}
+		return receipts[0].ReadAt
+	}
+
+	flags := map[string]interface{}{"flags": []string{"\\Seen"}}
+	url := s.WebURL + "/api/mails/" + inbox[0].ID + "/flags"
+	if code := doJSON(t, http.MethodPut, url, s.WebLogin(t, "bob@example.com", "bob-password"), flags, nil); code != http.StatusForbidden {
+		t.Errorf("bob 修改 carol 的邮件标志: %d, 期望 403", code)
+	}
+	if readAt() != nil {
+		t.Error("其他用户不应该能记录已读回执")
+	}
+
+	if code := doJSON(t, http.MethodPut, url, s.WebLogin(t, "carol@example.com", "carol-password"), flags, nil); code != http.StatusOK {
+		t.Fatalf("carol 标记已读失败: %d", code)
+	}
+	if readAt() == nil {
+		t.Error("收件人标记已读后应该记录阅读时间")
+	}
+}
+
+// TestEndToEndAutoReply WebMail 设置自动回复后，外部邮件投递时经中继向发件人回复一次
+func TestEndToEndAutoReply(t *testing.T) {
+	s := startStack(t)
+	s.CreateUser(t, "alice@example.com", "alice-password")
+
+	web := s.WebLogin(t, "alice@example.com", "alice-password")
+	req := map[string]interface{}{
+		"enabled": true,
+		"subject": "Out of office",
+		"body":    "Back on Monday",
+	}
+	if code := doJSON(t, http.MethodPut, s.WebURL+"/api/mails/autoresponder", web, req, nil); code != http.StatusOK {
+		t.Fatalf("设置自动回复失败: %d", code)
+	}
+
+	send := func() {
+		t.Helper()
+		sc, err := smtp.Dial(s.SMTPAddr)
+		if err != nil {
+			t.Fatalf("连接 SMTP 服务器失败: %v", err)
+		}
+		message := "From: bob@remote.test\r\nTo: alice@example.com\r\nSubject: Lunch\r\n\r\nLunch at noon?\r\n"
+		if err := sc.SendMail("bob@remote.test", []string{"alice@example.com"}, strings.NewReader(message)); err != nil {
+			t.Fatalf("SMTP 投递失败: %v", err)
+		}
+		_ = sc.Quit()
+	}
+
+	send()
+	waitFor(t, "中继收到自动回复", func() bool { return len(s.Relay.Received()) == 1 })
+	reply := s.Relay.Received()[0]
+	if reply.From != "" || len(reply.To) != 1 || reply.To[0] != "bob@remote.test" {
+		t.Errorf("自动回复的信封不正确: from=%q to=%v", reply.From, reply.To)
+	}
+	if !strings.Contains(string(reply.Data), "Subject: Out of office") || !strings.Contains(string(reply.Data), "Auto-Submitted: auto-replied") {
+		t.Errorf("自动回复内容不正确:\n%s", reply.Data)
+	}
+
+	// 同一发件人的第二封邮件不再回复（自动回复在 DATA 应答之前发出）
+	send()
+	if n := len(s.Relay.Received()); n != 1 {
+		t.Errorf("中继应该只收到 1 封自动回复, 实际 %d 封", n)
+	}
+	if messages := s.listWebMails(t, web, "INBOX"); len(messages) != 2 {
+		t.Errorf("两封邮件都应该投递到收件箱, 实际 %d 封", len(messages))
+	}
+
+	if code := doJSON(t, http.MethodDelete, s.WebURL+"/api/mails/autoresponder", web, nil, nil); code != http.StatusOK {
+		t.Errorf("删除自动回复失败: %d", code)
+	}
+}
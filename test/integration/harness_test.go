@@ -0,0 +1,341 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-smtp"
+	"github.com/gomailzero/gmz/internal/api"
+	"github.com/gomailzero/gmz/internal/auth"
+	"github.com/gomailzero/gmz/internal/autoreply"
+	"github.com/gomailzero/gmz/internal/config"
+	"github.com/gomailzero/gmz/internal/forward"
+	"github.com/gomailzero/gmz/internal/imapd"
+	"github.com/gomailzero/gmz/internal/smtpclient"
+	"github.com/gomailzero/gmz/internal/smtpd"
+	"github.com/gomailzero/gmz/internal/storage"
+	"github.com/gomailzero/gmz/internal/web"
+)
+
+// stackAPIKey 测试服务的管理 API 密钥
+const stackAPIKey = "integration-test-key"
+
+// stack 在随机端口上运行的完整 gmz 服务（SMTP、IMAP、管理 API、WebMail），
+// 外发邮件经中继发送到本地的测试 SMTP 服务器
+type stack struct {
+	Storage *storage.SQLiteDriver
+	Maildir *storage.Maildir
+	Relay   *relayBackend // 收到的外发邮件
+
+	SMTPAddr string
+	IMAPAddr string
+	APIURL   string
+	WebURL   string
+}
+
+// freePort 返回一个空闲的本地端口
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// waitListening 等待端口开始监听
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("服务没有在 %s 上启动: %v", addr, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// startRelay 启动记录邮件的测试 SMTP 服务器，返回其端口
+func startRelay(t *testing.T) (*relayBackend, int) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &relayBackend{}
+	server := smtp.NewServer(backend)
+	server.Domain = "relay.test"
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(func() { server.Close() })
+	return backend, lis.Addr().(*net.TCPAddr).Port
+}
+
+// newTestDriver 创建已初始化表结构的内存数据库
+func newTestDriver(t *testing.T) *storage.SQLiteDriver {
+	t.Helper()
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建存储驱动失败: %v", err)
+	}
+	t.Cleanup(func() { driver.Close() })
+	if err := driver.RunMigrations(context.Background(), "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	return driver
+}
+
+// startStack 使用临时数据库和 Maildir 启动完整服务，测试结束时按 gmz 停止的顺序优雅停止
+func startStack(t *testing.T) *stack {
+	t.Helper()
+	ctx := context.Background()
+
+	driver, err := storage.NewSQLiteDriver(":memory:")
+	if err != nil {
+		t.Fatalf("创建存储驱动失败: %v", err)
+	}
+	if err := driver.RunMigrations(ctx, "", false); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	if err := driver.CreateDomain(ctx, &storage.Domain{Name: "example.com", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	maildir, err := storage.NewMaildir(t.TempDir())
+	if err != nil {
+		t.Fatalf("初始化 Maildir 失败: %v", err)
+	}
+
+	relay, relayPort := startRelay(t)
+	smtpConfig := &config.SMTPConfig{
+		Hostname: "mx.example.com",
+		Relay:    config.RelayConfig{Enabled: true, Host: "127.0.0.1", Port: relayPort},
+	}
+
+	// 别名的外部目标经 SRS 重写后通过中继转发
+	forwarder := &forward.Forwarder{
+		Domain:  "example.com",
+		Storage: driver,
+		SMTP:    smtpConfig,
+		SRS:     forward.NewSRS([]byte("integration-srs"), "example.com"),
+	}
+
+	smtpPort, imapPort, apiPort, webPort := freePort(t), freePort(t), freePort(t), freePort(t)
+	jwtManager := auth.NewJWTManager("integration-secret", "example.com")
+	totpManager := auth.NewTOTPManager(driver)
+
+	smtpServer := smtpd.NewServer(&smtpd.Config{
+		Enabled:  true,
+		Ports:    []int{smtpPort},
+		Hostname: smtpConfig.Hostname,
+		MaxSize:  10 << 20,
+		Storage:  driver,
+		Maildir:  maildir,
+		Auth:     smtpd.NewDefaultAuthenticator(driver),
+
+		Forwarder: forwarder,
+
+		AutoResponder: &autoreply.Responder{Storage: driver, Outbound: &smtpclient.Outbound{SMTP: smtpConfig}, Hostname: smtpConfig.Hostname},
+	})
+	imapServer := imapd.NewServer(&imapd.Config{
+		Enabled: true,
+		Port:    imapPort,
+		Storage: driver,
+		Maildir: maildir,
+		Auth:    imapd.NewDefaultAuthenticator(driver),
+
+		Forwarder: forwarder,
+	})
+	apiServer := api.NewServer(&api.Config{
+		Port:        apiPort,
+		APIKey:      stackAPIKey,
+		Domain:      "example.com",
+		Storage:     driver,
+		JWTManager:  jwtManager,
+		TOTPManager: totpManager,
+	})
+	webServer := web.NewServer(&web.Config{
+		Path:        "/",
+		Port:        webPort,
+		Domain:      "example.com",
+		Storage:     driver,
+		Maildir:     maildir,
+		JWTSecret:   "integration-secret",
+		JWTIssuer:   "example.com",
+		TOTPManager: totpManager,
+		AdminPort:   apiPort,
+		SMTPConfig:  smtpConfig,
+		Forwarder:   forwarder,
+	})
+
+	for name, start := range map[string]func(context.Context) error{
+		"smtp":    smtpServer.Start,
+		"imap":    imapServer.Start,
+		"api":     apiServer.Start,
+		"webmail": webServer.Start,
+	} {
+		go func() {
+			if err := start(ctx); err != nil {
+				t.Errorf("%s 服务器启动失败: %v", name, err)
+			}
+		}()
+	}
+
+	s := &stack{
+		Storage:  driver,
+		Maildir:  maildir,
+		Relay:    relay,
+		SMTPAddr: fmt.Sprintf("127.0.0.1:%d", smtpPort),
+		IMAPAddr: fmt.Sprintf("127.0.0.1:%d", imapPort),
+		APIURL:   fmt.Sprintf("http://127.0.0.1:%d", apiPort),
+		WebURL:   fmt.Sprintf("http://127.0.0.1:%d", webPort),
+	}
+	for _, addr := range []string{s.SMTPAddr, s.IMAPAddr, fmt.Sprintf("127.0.0.1:%d", apiPort), fmt.Sprintf("127.0.0.1:%d", webPort)} {
+		waitListening(t, addr)
+	}
+
+	t.Cleanup(func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for name, stop := range map[string]func(context.Context) error{
+			"smtp":    smtpServer.Stop,
+			"imap":    imapServer.Stop,
+			"api":     apiServer.Stop,
+			"webmail": webServer.Stop,
+		} {
+			if err := stop(stopCtx); err != nil {
+				t.Errorf("%s 服务器停止失败: %v", name, err)
+			}
+		}
+		driver.Close()
+	})
+	return s
+}
+
+// doJSON 发送 JSON 请求，解析 JSON 响应到 out（out 为 nil 时忽略响应体），返回状态码
+func doJSON(t *testing.T, method, url string, header http.Header, in, out interface{}) int {
+	t.Helper()
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s 失败: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s 响应不是 JSON（%d）: %s", method, url, resp.StatusCode, data)
+		}
+	}
+	return resp.StatusCode
+}
+
+// CreateUser 通过管理 API 创建用户
+func (s *stack) CreateUser(t *testing.T, email, password string) {
+	t.Helper()
+	header := http.Header{"X-Api-Key": []string{stackAPIKey}}
+	req := map[string]interface{}{"email": email, "password": password, "active": true}
+	var resp map[string]interface{}
+	if code := doJSON(t, http.MethodPost, s.APIURL+"/api/v1/users", header, req, &resp); code != http.StatusCreated {
+		t.Fatalf("创建用户 %s 失败: %d %v", email, code, resp)
+	}
+}
+
+// WebLogin 登录 WebMail，返回带认证令牌的请求头
+func (s *stack) WebLogin(t *testing.T, email, password string) http.Header {
+	t.Helper()
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if code := doJSON(t, http.MethodPost, s.WebURL+"/api/login", nil, map[string]string{"email": email, "password": password}, &resp); code != http.StatusOK || resp.Token == "" {
+		t.Fatalf("WebMail 登录失败: %d", code)
+	}
+	return http.Header{"Authorization": []string{"Bearer " + resp.Token}}
+}
+
+// relayBackend 记录收到的邮件的 SMTP 服务器
+type relayBackend struct {
+	mu       sync.Mutex
+	received []relayedMail
+}
+
+// relayedMail 中继收到的邮件
+type relayedMail struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// Received 返回收到的邮件
+func (b *relayBackend) Received() []relayedMail {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]relayedMail(nil), b.received...)
+}
+
+func (b *relayBackend) NewSession(*smtp.Conn) (smtp.Session, error) {
+	return &relaySession{backend: b}, nil
+}
+
+type relaySession struct {
+	backend *relayBackend
+	mail    relayedMail
+}
+
+func (s *relaySession) Reset()        { s.mail = relayedMail{} }
+func (s *relaySession) Logout() error { return nil }
+
+func (s *relaySession) Mail(from string, _ *smtp.MailOptions) error {
+	s.mail.From = from
+	return nil
+}
+
+func (s *relaySession) Rcpt(to string, _ *smtp.RcptOptions) error {
+	s.mail.To = append(s.mail.To, to)
+	return nil
+}
+
+func (s *relaySession) Data(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.mail.Data = data
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+	s.backend.received = append(s.backend.received, s.mail)
+	return nil
+}
@@ -13,11 +13,7 @@ import (
 // TestIMAPLogin 测试 IMAP 登录
 func TestIMAPLogin(t *testing.T) {
 	// 创建测试存储
-	driver, err := storage.NewSQLiteDriver(":memory:")
-	if err != nil {
-		t.Fatalf("创建存储驱动失败: %v", err)
-	}
-	defer driver.Close()
+	driver := newTestDriver(t)
 
 	ctx := context.Background()
 
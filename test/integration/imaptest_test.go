@@ -0,0 +1,171 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// imaptest Dovecot 的 IMAP 协议一致性测试工具（https://imapwiki.org/ImapTest），
+// 对 IMAP 服务器做随机压力测试并检查每个响应是否符合协议。
+//
+// 环境变量：
+//
+//	IMAPTEST           imaptest 路径（默认在 PATH 中查找）
+//	IMAPTEST_REQUIRED  为 1 时找不到 imaptest 视为失败（make test-imaptest 设置），否则跳过
+//	IMAPTEST_SECS      压力测试时长（秒，默认 10）
+//	IMAPTEST_TESTS     imaptest 源码中的脚本测试目录（如 imaptest/src/tests，可选）
+//
+// 只启用 imapd 支持的命令；新增命令支持后在 imaptestCommands 中加入对应的权重。
+
+// imaptestCommands 压力测试中各命令的权重（imaptest 的参数名）
+var imaptestCommands = map[string]int{
+	"login":   100,
+	"select":  100,
+	"status":  50,
+	"fetch":   100,
+	"fetch2":  100,
+	"search":  30,
+	"copy":    5,
+	"store":   50,
+	"delete":  100,
+	"expunge": 100,
+	"append":  100,
+	"logout":  100,
+	"list":    50,
+	"sort":    0, // SORT 扩展不支持
+	"thread":  0, // THREAD 扩展不支持
+}
+
+// imaptestErrorPattern imaptest 报告的协议错误
+var imaptestErrorPattern = regexp.MustCompile(`(?m)^(Error|Panic|Fatal): .*$`)
+
+// imaptestGroupsPattern 脚本测试的汇总行（"12 test groups: 1 failed, 2 skipped due to missing capabilities"）
+var imaptestGroupsPattern = regexp.MustCompile(`(\d+) test groups: (\d+) failed`)
+
+// imaptestBinary 返回 imaptest 路径，找不到时跳过（或在要求运行时失败）
+func imaptestBinary(t *testing.T) string {
+	t.Helper()
+	name := os.Getenv("IMAPTEST")
+	if name == "" {
+		name = "imaptest"
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		if os.Getenv("IMAPTEST_REQUIRED") == "1" {
+			t.Fatalf("找不到 imaptest（设置 IMAPTEST 为其路径）: %v", err)
+		}
+		t.Skipf("找不到 imaptest，跳过 IMAP 协议一致性测试: %v", err)
+	}
+	return path
+}
+
+// writeMbox 生成 APPEND 使用的 mbox 文件
+func writeMbox(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&buf, "From sender@example.net Thu Jan  1 00:00:00 2026\n")
+		fmt.Fprintf(&buf, "From: sender%d@example.net\n", i)
+		fmt.Fprintf(&buf, "To: alice@example.com\n")
+		fmt.Fprintf(&buf, "Subject: imaptest message %d\n", i)
+		fmt.Fprintf(&buf, "Message-ID: <imaptest-%d@example.net>\n", i)
+		fmt.Fprintf(&buf, "Date: Thu, 01 Jan 2026 00:00:%02d +0000\n", i)
+		fmt.Fprintf(&buf, "\n")
+		fmt.Fprintf(&buf, "%s\n\n", strings.Repeat("conformance test body line\n", i))
+	}
+	path := filepath.Join(t.TempDir(), "imaptest.mbox")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runIMAPTest 运行 imaptest，返回合并的输出和退出错误（有测试失败时 imaptest 以非零状态退出）
+func runIMAPTest(t *testing.T, binary string, timeout time.Duration, args ...string) (string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	output, err := cmd.CombinedOutput()
+	t.Logf("imaptest %s\n%s", strings.Join(args, " "), output)
+	if ctx.Err() != nil {
+		t.Fatalf("imaptest 超时（%v）", timeout)
+	}
+	return string(output), err
+}
+
+// TestIMAPConformance 使用 imaptest 检查 imapd 对支持命令集的响应没有协议错误
+func TestIMAPConformance(t *testing.T) {
+	binary := imaptestBinary(t)
+
+	s := startStack(t)
+	s.CreateUser(t, "alice@example.com", "alice-password")
+	host, port, err := net.SplitHostPort(s.IMAPAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connArgs := []string{
+		"host=" + host,
+		"port=" + port,
+		"user=alice@example.com",
+		"pass=alice-password",
+		"mbox=" + writeMbox(t),
+	}
+
+	t.Run("stress", func(t *testing.T) {
+		secs := 10
+		if v := os.Getenv("IMAPTEST_SECS"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				t.Fatalf("无效的 IMAPTEST_SECS: %q", v)
+			}
+			secs = n
+		}
+		args := append([]string{}, connArgs...)
+		args = append(args, "clients=5", "secs="+strconv.Itoa(secs), "seed=1", "no_pipelining")
+		for _, command := range slices.Sorted(maps.Keys(imaptestCommands)) {
+			args = append(args, fmt.Sprintf("%s=%d", command, imaptestCommands[command]))
+		}
+
+		output, err := runIMAPTest(t, binary, time.Duration(secs)*time.Second+time.Minute, args...)
+		if err != nil {
+			t.Errorf("imaptest 运行失败: %v", err)
+		}
+		if errs := imaptestErrorPattern.FindAllString(output, -1); len(errs) > 0 {
+			t.Errorf("imaptest 报告了 %d 个协议错误:\n%s", len(errs), strings.Join(errs, "\n"))
+		}
+	})
+
+	t.Run("scripted", func(t *testing.T) {
+		dir := os.Getenv("IMAPTEST_TESTS")
+		if dir == "" {
+			t.Skip("未设置 IMAPTEST_TESTS，跳过脚本测试")
+		}
+		args := append([]string{}, connArgs...)
+		args = append(args, "test="+dir)
+
+		output, err := runIMAPTest(t, binary, 5*time.Minute, args...)
+		match := imaptestGroupsPattern.FindStringSubmatch(output)
+		if match == nil {
+			t.Fatalf("imaptest 输出中没有测试汇总: %v", err)
+		}
+		if match[2] != "0" {
+			t.Errorf("imaptest 脚本测试: %s 组中 %s 组失败", match[1], match[2])
+		}
+	})
+}
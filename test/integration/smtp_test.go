@@ -13,11 +13,7 @@ import (
 // TestSMTPBasicFlow 测试基本的 SMTP 流程
 func TestSMTPBasicFlow(t *testing.T) {
 	// 创建测试存储
-	driver, err := storage.NewSQLiteDriver(":memory:")
-	if err != nil {
-		t.Fatalf("创建存储驱动失败: %v", err)
-	}
-	defer driver.Close()
+	driver := newTestDriver(t)
 
 	ctx := context.Background()
 
@@ -16,13 +16,42 @@ apiClient.interceptors.request.use((config) => {
   return config
 })
 
-// 响应拦截器：处理错误
+// 刷新访问令牌（多个请求同时收到 401 时只刷新一次，刷新令牌每次使用后轮换）
+let refreshing: Promise<string> | null = null
+
+const refreshAccessToken = (): Promise<string> => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refresh_token')
+    refreshing = (refreshToken
+      ? axios.post('/api/auth/refresh', { refresh_token: refreshToken }).then((response) => {
+          localStorage.setItem('token', response.data.token)
+          localStorage.setItem('refresh_token', response.data.refresh_token)
+          return response.data.token as string
+        })
+      : Promise.reject(new Error('no refresh token'))
+    ).finally(() => {
+      refreshing = null
+    })
+  }
+  return refreshing
+}
+
+// 响应拦截器：访问令牌过期时用刷新令牌换取新的访问令牌并重试，会话失效时回到登录页
 apiClient.interceptors.response.use(
   (response) => response.data,
-  (error) => {
-    if (error.response?.status === 401) {
-      localStorage.removeItem('token')
-      window.location.href = '/login'
+  async (error) => {
+    const config = error.config
+    if (error.response?.status === 401 && config && !config._retried && !config.url?.startsWith('/login')) {
+      config._retried = true
+      try {
+        const token = await refreshAccessToken()
+        config.headers.Authorization = `Bearer ${token}`
+        return apiClient(config)
+      } catch {
+        localStorage.removeItem('token')
+        localStorage.removeItem('refresh_token')
+        window.location.href = '/login'
+      }
     }
     return Promise.reject(error)
   }
@@ -30,8 +59,24 @@ apiClient.interceptors.response.use(
 
 export const api = {
   // 登录
-  login: (data: { email: string; password: string; totp_code?: string }) =>
-    apiClient.post('/login', data),
+  login: (data: {
+    email: string
+    password: string
+    totp_code?: string
+    webauthn_ceremony?: string
+    webauthn_response?: unknown
+  }) => apiClient.post('/login', data),
+
+  // 开始通行密钥登录（返回 ceremony_id 和传给浏览器的 options）
+  beginWebAuthnLogin: (data: { email: string; password: string }) =>
+    apiClient.post('/login/webauthn', data),
+
+  // 通行密钥管理
+  getWebAuthn: () => apiClient.get('/webauthn'),
+  beginWebAuthnRegistration: () => apiClient.post('/webauthn/register/begin'),
+  finishWebAuthnRegistration: (data: { ceremony_id: string; name?: string; response: unknown }) =>
+    apiClient.post('/webauthn/register/finish', data),
+  deleteWebAuthnCredential: (id: number) => apiClient.delete(`/webauthn/credentials/${id}`),
 
   // 获取当前用户信息
   getCurrentUser: () => apiClient.get('/me'),
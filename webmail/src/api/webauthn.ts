@@ -0,0 +1,67 @@
+// 通行密钥：服务器返回的参数和浏览器返回的凭证中的二进制字段使用 base64url 编码
+
+const toBuffer = (value: string): ArrayBuffer => {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/')
+  const padded = base64 + '='.repeat((4 - (base64.length % 4)) % 4)
+  return Uint8Array.from(atob(padded), (c) => c.charCodeAt(0)).buffer
+}
+
+const toBase64URL = (buffer: ArrayBuffer): string =>
+  btoa(String.fromCharCode(...new Uint8Array(buffer)))
+    .replace(/\+/g, '-')
+    .replace(/\//g, '_')
+    .replace(/=+$/, '')
+
+const toDescriptors = (list: any[] | undefined) =>
+  (list || []).map((item) => ({ ...item, id: toBuffer(item.id) }))
+
+// 浏览器是否支持通行密钥
+export const webAuthnSupported = () => typeof window !== 'undefined' && !!window.PublicKeyCredential
+
+// 用已注册的通行密钥签名（options 为服务器返回的 options），返回提交给登录接口的断言
+export const getAssertion = async (options: any) => {
+  const publicKey = options.publicKey
+  const credential = (await navigator.credentials.get({
+    publicKey: {
+      ...publicKey,
+      challenge: toBuffer(publicKey.challenge),
+      allowCredentials: toDescriptors(publicKey.allowCredentials)
+    }
+  })) as PublicKeyCredential
+  const response = credential.response as AuthenticatorAssertionResponse
+  return {
+    id: credential.id,
+    rawId: toBase64URL(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: toBase64URL(response.clientDataJSON),
+      authenticatorData: toBase64URL(response.authenticatorData),
+      signature: toBase64URL(response.signature),
+      userHandle: response.userHandle ? toBase64URL(response.userHandle) : undefined
+    }
+  }
+}
+
+// 注册新的通行密钥（options 为服务器返回的 options），返回提交给注册接口的凭证
+export const createCredential = async (options: any) => {
+  const publicKey = options.publicKey
+  const credential = (await navigator.credentials.create({
+    publicKey: {
+      ...publicKey,
+      challenge: toBuffer(publicKey.challenge),
+      user: { ...publicKey.user, id: toBuffer(publicKey.user.id) },
+      excludeCredentials: toDescriptors(publicKey.excludeCredentials)
+    }
+  })) as PublicKeyCredential
+  const response = credential.response as AuthenticatorAttestationResponse
+  return {
+    id: credential.id,
+    rawId: toBase64URL(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: toBase64URL(response.clientDataJSON),
+      attestationObject: toBase64URL(response.attestationObject),
+      transports: response.getTransports ? response.getTransports() : undefined
+    }
+  }
+}